@@ -0,0 +1,151 @@
+// Copyright 2025 The GoGPU Authors
+// SPDX-License-Identifier: MIT
+
+package analysis
+
+import (
+	"github.com/gogpu/naga/ir"
+)
+
+// Metrics is a static estimate of what one function or entry point costs.
+// The counts are of operations written in the code, not executed: an
+// operation inside a loop counts once.
+//
+// ALUOps, TextureOps, MemoryOps and LoopDepth include the functions called,
+// once per call site, as if they were inlined. Expressions, Statements and
+// Calls cover the function's own body only.
+type Metrics struct {
+	Name string
+
+	// Expressions is the size of the function's expression arena.
+	Expressions int
+
+	// Statements counts statements at every nesting level, leaving out
+	// the Emit bookkeeping statements.
+	Statements int
+
+	// ALUOps counts unary, binary, select, math, relational, derivative
+	// and conversion operations.
+	ALUOps int
+
+	// TextureOps counts texture samples, loads, stores, atomics and
+	// queries.
+	TextureOps int
+
+	// MemoryOps counts loads, stores and atomics on variables and
+	// buffers, local variables included.
+	MemoryOps int
+
+	// Calls counts the function calls in the body.
+	Calls int
+
+	// LoopDepth is the deepest nesting of loops, 0 without any.
+	LoopDepth int
+}
+
+// ComplexityReport holds the Metrics of a module's functions, in handle
+// order, and of its entry points, in declaration order.
+type ComplexityReport struct {
+	Functions   []Metrics
+	EntryPoints []Metrics
+}
+
+// Complexity estimates the cost of every function and entry point of
+// module, so expensive shaders stand out before they are profiled on a
+// device. Only expressions a statement uses are counted as operations;
+// ones a lowering left behind unused are not.
+func Complexity(module *ir.Module) *ComplexityReport {
+	c := &complexity{module: module, funcs: make([]*Metrics, len(module.Functions))}
+	report := &ComplexityReport{
+		Functions:   make([]Metrics, len(module.Functions)),
+		EntryPoints: make([]Metrics, len(module.EntryPoints)),
+	}
+	for i := range module.Functions {
+		report.Functions[i] = *c.function(ir.FunctionHandle(i))
+	}
+	for i := range module.EntryPoints {
+		m := c.measure(&module.EntryPoints[i].Function)
+		m.Name = module.EntryPoints[i].Name
+		report.EntryPoints[i] = *m
+	}
+	return report
+}
+
+type complexity struct {
+	module *ir.Module
+	funcs  []*Metrics // memoized by handle
+}
+
+// function returns the memoized Metrics of the function h.
+func (c *complexity) function(h ir.FunctionHandle) *Metrics {
+	if int(h) >= len(c.funcs) {
+		return &Metrics{}
+	}
+	if c.funcs[h] == nil {
+		// WGSL forbids recursion; the placeholder keeps a malformed
+		// module from looping.
+		c.funcs[h] = &Metrics{}
+		m := c.measure(&c.module.Functions[h])
+		m.Name = c.module.Functions[h].Name
+		c.funcs[h] = m
+	}
+	return c.funcs[h]
+}
+
+func (c *complexity) measure(f *ir.Function) *Metrics {
+	m := &Metrics{Expressions: len(f.Expressions)}
+	live := ir.LiveExpressions(f)
+	for i, expr := range f.Expressions {
+		if !live[i] {
+			continue
+		}
+		switch expr.Kind.(type) {
+		case ir.ExprUnary, ir.ExprBinary, ir.ExprSelect, ir.ExprMath,
+			ir.ExprRelational, ir.ExprDerivative, ir.ExprAs:
+			m.ALUOps++
+		case ir.ExprImageSample, ir.ExprImageLoad, ir.ExprImageQuery:
+			m.TextureOps++
+		case ir.ExprLoad:
+			m.MemoryOps++
+		}
+	}
+	c.block(m, f.Body, 0)
+	return m
+}
+
+func (c *complexity) block(m *Metrics, block ir.Block, depth int) {
+	for _, stmt := range block {
+		if _, ok := stmt.Kind.(ir.StmtEmit); ok {
+			continue
+		}
+		m.Statements++
+		switch s := stmt.Kind.(type) {
+		case ir.StmtBlock:
+			c.block(m, s.Block, depth)
+		case ir.StmtIf:
+			c.block(m, s.Accept, depth)
+			c.block(m, s.Reject, depth)
+		case ir.StmtSwitch:
+			for _, cs := range s.Cases {
+				c.block(m, cs.Body, depth)
+			}
+		case ir.StmtLoop:
+			m.LoopDepth = max(m.LoopDepth, depth+1)
+			c.block(m, s.Body, depth+1)
+			c.block(m, s.Continuing, depth+1)
+		case ir.StmtStore, ir.StmtAtomic, ir.StmtWorkGroupUniformLoad:
+			m.MemoryOps++
+		case ir.StmtImageStore, ir.StmtImageAtomic:
+			m.TextureOps++
+		case ir.StmtCall:
+			m.Calls++
+			callee := c.function(s.Function)
+			m.ALUOps += callee.ALUOps
+			m.TextureOps += callee.TextureOps
+			m.MemoryOps += callee.MemoryOps
+			if callee.LoopDepth > 0 {
+				m.LoopDepth = max(m.LoopDepth, depth+callee.LoopDepth)
+			}
+		}
+	}
+}
@@ -0,0 +1,67 @@
+// Copyright 2025 The GoGPU Authors
+// SPDX-License-Identifier: MIT
+
+package analysis
+
+import (
+	"testing"
+
+	"github.com/gogpu/naga/internal/testutil"
+)
+
+const complexityShader = `
+@group(0) @binding(0) var tex: texture_2d<f32>;
+@group(0) @binding(1) var samp: sampler;
+@group(0) @binding(2) var<storage, read_write> out: array<f32>;
+
+fn blur(uv: vec2<f32>) -> vec4<f32> {
+    var sum = vec4<f32>(0.0);
+    for (var i = 0; i < 4; i++) {
+        sum += textureSample(tex, samp, uv + f32(i) * 0.01);
+    }
+    return sum * 0.25;
+}
+
+@fragment
+fn fs(@location(0) uv: vec2<f32>) -> @location(0) vec4<f32> {
+    return blur(uv) + blur(uv.yx);
+}
+
+@compute @workgroup_size(64)
+fn cs(@builtin(global_invocation_id) id: vec3<u32>) {
+    for (var y = 0u; y < 4u; y++) {
+        for (var x = 0u; x < 4u; x++) {
+            out[id.x] += f32(x * y);
+        }
+    }
+}
+`
+
+func TestComplexity(t *testing.T) {
+	report := Complexity(testutil.LowerWGSL(t, complexityShader))
+	if len(report.Functions) != 1 || len(report.EntryPoints) != 2 {
+		t.Fatalf("got %d functions and %d entry points, want 1 and 2", len(report.Functions), len(report.EntryPoints))
+	}
+	blur, fs, cs := report.Functions[0], report.EntryPoints[0], report.EntryPoints[1]
+	if blur.Name != "blur" || fs.Name != "fs" || cs.Name != "cs" {
+		t.Fatalf("names = %q, %q, %q; want blur, fs, cs", blur.Name, fs.Name, cs.Name)
+	}
+	if blur.TextureOps != 1 || blur.LoopDepth != 1 || blur.Calls != 0 || blur.ALUOps == 0 || blur.MemoryOps == 0 {
+		t.Errorf("blur = %+v, want 1 texture op in 1 loop, with ALU and memory ops", blur)
+	}
+
+	// fs inlines blur twice and adds the results.
+	if fs.Calls != 2 || fs.TextureOps != 2 || fs.LoopDepth != 1 {
+		t.Errorf("fs = %+v, want 2 calls, 2 texture ops, loop depth 1", fs)
+	}
+	if fs.ALUOps != 2*blur.ALUOps+1 || fs.MemoryOps != 2*blur.MemoryOps {
+		t.Errorf("fs ALU/memory ops = %d/%d, want %d/%d", fs.ALUOps, fs.MemoryOps, 2*blur.ALUOps+1, 2*blur.MemoryOps)
+	}
+	if fs.Expressions >= blur.Expressions || fs.Statements >= blur.Statements {
+		t.Errorf("fs counts %d expressions and %d statements, want its own body only", fs.Expressions, fs.Statements)
+	}
+
+	if cs.LoopDepth != 2 || cs.TextureOps != 0 || cs.Calls != 0 {
+		t.Errorf("cs = %+v, want loop depth 2 and no texture ops or calls", cs)
+	}
+}
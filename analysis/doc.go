@@ -0,0 +1,31 @@
+// Copyright 2025 The GoGPU Authors
+// SPDX-License-Identifier: MIT
+
+// Package analysis answers questions about how a naga IR module uses its
+// resources that are shared by several backends and by validation.
+//
+// # Sampling Pairs
+//
+// SamplingPairs lists the (texture, sampler) pairs an entry point samples
+// with, and whether each pair is used for comparison sampling, plain
+// sampling, or both:
+//
+//	pairs, err := analysis.SamplingPairs(module, "fs_main")
+//
+// Targets without separate textures and samplers (GLSL combined samplers,
+// Metal argument buffers that pack a texture next to its sampler) need one
+// slot per pair rather than per binding. Textures and samplers passed to
+// helper functions are traced back to the globals the entry point passes.
+//
+// # Complexity
+//
+// Complexity estimates what each function and entry point costs:
+// expression and statement counts, ALU, texture and memory operations,
+// calls, and loop nesting depth. Entry points count the operations of the
+// functions they call once per call site, as if inlined, so tech artists
+// can spot expensive shaders before profiling on a device:
+//
+//	report := analysis.Complexity(module)
+//
+// nagac -stats prints the report as a table.
+package analysis
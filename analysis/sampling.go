@@ -0,0 +1,184 @@
+// Copyright 2025 The GoGPU Authors
+// SPDX-License-Identifier: MIT
+
+package analysis
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/gogpu/naga/ir"
+)
+
+// SampleKind is the set of ways a texture is sampled with a sampler.
+type SampleKind uint8
+
+const (
+	// SampleRegular marks sampling or gathering without a depth reference.
+	SampleRegular SampleKind = 1 << iota
+
+	// SampleCompare marks sampling or gathering against a depth reference.
+	SampleCompare
+)
+
+// String returns "regular", "compare", "regular+compare", or "none".
+func (k SampleKind) String() string {
+	switch k {
+	case SampleRegular:
+		return "regular"
+	case SampleCompare:
+		return "compare"
+	case SampleRegular | SampleCompare:
+		return "regular+compare"
+	}
+	return "none"
+}
+
+// SamplingPair is a texture global sampled with a sampler global. For a
+// binding array, Texture or Sampler is the array global, whatever element
+// is indexed.
+type SamplingPair struct {
+	Texture ir.GlobalVariableHandle
+	Sampler ir.GlobalVariableHandle
+	Kinds   SampleKind
+}
+
+// SamplingPairs returns the pairs sampled by the entry point named entry,
+// directly or through the functions it calls, ordered by texture handle and
+// then sampler handle. A sample whose texture or sampler cannot be traced
+// to a global is left out.
+func SamplingPairs(module *ir.Module, entry string) ([]SamplingPair, error) {
+	var ep *ir.EntryPoint
+	for i := range module.EntryPoints {
+		if module.EntryPoints[i].Name == entry {
+			ep = &module.EntryPoints[i]
+			break
+		}
+	}
+	if ep == nil {
+		return nil, fmt.Errorf("analysis: entry point %q not found", entry)
+	}
+
+	a := &pairAnalysis{module: module, funcs: make([]map[pairKey]SampleKind, len(module.Functions))}
+	var pairs []SamplingPair
+	for key, kinds := range a.function(&ep.Function) {
+		if !key.texture.global || !key.sampler.global {
+			continue // entry points have no texture or sampler arguments
+		}
+		pairs = append(pairs, SamplingPair{
+			Texture: ir.GlobalVariableHandle(key.texture.index),
+			Sampler: ir.GlobalVariableHandle(key.sampler.index),
+			Kinds:   kinds,
+		})
+	}
+	sort.Slice(pairs, func(i, j int) bool {
+		if pairs[i].Texture != pairs[j].Texture {
+			return pairs[i].Texture < pairs[j].Texture
+		}
+		return pairs[i].Sampler < pairs[j].Sampler
+	})
+	return pairs, nil
+}
+
+// handleSource is where a texture or sampler handle inside a function comes
+// from: a global variable, or an argument of the function.
+type handleSource struct {
+	global bool
+	index  uint32
+}
+
+type pairKey struct {
+	texture, sampler handleSource
+}
+
+type pairAnalysis struct {
+	module *ir.Module
+	funcs  []map[pairKey]SampleKind // memoized by handle
+}
+
+// function returns the pairs f samples, in terms of its own globals and
+// arguments.
+func (a *pairAnalysis) function(f *ir.Function) map[pairKey]SampleKind {
+	pairs := make(map[pairKey]SampleKind)
+	for _, expr := range f.Expressions {
+		sample, ok := expr.Kind.(ir.ExprImageSample)
+		if !ok {
+			continue
+		}
+		texture, ok1 := handleRoot(f.Expressions, sample.Image)
+		sampler, ok2 := handleRoot(f.Expressions, sample.Sampler)
+		if !ok1 || !ok2 {
+			continue
+		}
+		kind := SampleRegular
+		if sample.DepthRef != nil {
+			kind = SampleCompare
+		}
+		pairs[pairKey{texture, sampler}] |= kind
+	}
+	ir.WalkStatements(f.Body, func(stmt *ir.Statement) bool {
+		call, ok := stmt.Kind.(ir.StmtCall)
+		if !ok {
+			return true
+		}
+		callee := a.callee(call.Function)
+		// mapSource resolves a source in the callee to one in f.
+		mapSource := func(s handleSource) (handleSource, bool) {
+			if s.global {
+				return s, true
+			}
+			if int(s.index) >= len(call.Arguments) {
+				return handleSource{}, false
+			}
+			return handleRoot(f.Expressions, call.Arguments[s.index])
+		}
+		for key, kinds := range callee {
+			texture, ok1 := mapSource(key.texture)
+			sampler, ok2 := mapSource(key.sampler)
+			if ok1 && ok2 {
+				pairs[pairKey{texture, sampler}] |= kinds
+			}
+		}
+		return true
+	}, nil)
+	return pairs
+}
+
+// callee returns the pairs of the function h, analyzing it on first use.
+// Recursion is invalid, so a function being analyzed is treated as sampling
+// nothing rather than looping.
+func (a *pairAnalysis) callee(h ir.FunctionHandle) map[pairKey]SampleKind {
+	if int(h) >= len(a.funcs) {
+		return nil
+	}
+	if a.funcs[h] == nil {
+		a.funcs[h] = map[pairKey]SampleKind{}
+		a.funcs[h] = a.function(&a.module.Functions[h])
+	}
+	return a.funcs[h]
+}
+
+// handleRoot follows loads and binding array indexing from expression h to
+// the global or argument the texture or sampler handle comes from.
+func handleRoot(exprs []ir.Expression, h ir.ExpressionHandle) (handleSource, bool) {
+	for range exprs {
+		if int(h) >= len(exprs) {
+			return handleSource{}, false
+		}
+		switch e := exprs[h].Kind.(type) {
+		case ir.ExprLoad:
+			h = e.Pointer
+		case ir.ExprAccess:
+			h = e.Base
+		case ir.ExprAccessIndex:
+			h = e.Base
+		case ir.ExprGlobalVariable:
+			return handleSource{global: true, index: uint32(e.Variable)}, true
+		case ir.ExprFunctionArgument:
+			return handleSource{index: e.Index}, true
+		default:
+			return handleSource{}, false
+		}
+	}
+	return handleSource{}, false
+}
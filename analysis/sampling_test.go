@@ -0,0 +1,67 @@
+// Copyright 2025 The GoGPU Authors
+// SPDX-License-Identifier: MIT
+
+package analysis
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/gogpu/naga/internal/testutil"
+	"github.com/gogpu/naga/ir"
+)
+
+const samplingShader = `
+@group(0) @binding(0) var color: texture_2d<f32>;
+@group(0) @binding(1) var shadow: texture_depth_2d;
+@group(0) @binding(2) var linear: sampler;
+@group(0) @binding(3) var cmp: sampler_comparison;
+@group(0) @binding(4) var layers: binding_array<texture_2d<f32>, 4>;
+
+fn tap(t: texture_2d<f32>, s: sampler, uv: vec2<f32>) -> vec4<f32> {
+    return textureSample(t, s, uv);
+}
+
+@fragment
+fn fs_main(@location(0) uv: vec2<f32>) -> @location(0) vec4<f32> {
+    let lit = textureSampleCompare(shadow, cmp, uv, 0.5);
+    let raw = textureSample(shadow, linear, uv);
+    let layer = tap(layers[1], linear, uv);
+    return tap(color, linear, uv) * lit * raw + layer;
+}
+
+@fragment
+fn fs_plain() -> @location(0) vec4<f32> {
+    return vec4<f32>(1.0);
+}
+`
+
+func TestSamplingPairs(t *testing.T) {
+	module := testutil.LowerWGSL(t, samplingShader)
+	handles := map[string]ir.GlobalVariableHandle{}
+	for i, g := range module.GlobalVariables {
+		handles[g.Name] = ir.GlobalVariableHandle(i)
+	}
+
+	pairs, err := SamplingPairs(module, "fs_main")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []SamplingPair{
+		{Texture: handles["color"], Sampler: handles["linear"], Kinds: SampleRegular},
+		{Texture: handles["shadow"], Sampler: handles["linear"], Kinds: SampleRegular},
+		{Texture: handles["shadow"], Sampler: handles["cmp"], Kinds: SampleCompare},
+		{Texture: handles["layers"], Sampler: handles["linear"], Kinds: SampleRegular},
+	}
+	if !reflect.DeepEqual(pairs, want) {
+		t.Errorf("fs_main pairs = %+v, want %+v", pairs, want)
+	}
+
+	pairs, err = SamplingPairs(module, "fs_plain")
+	if err != nil || len(pairs) != 0 {
+		t.Errorf("fs_plain pairs = %+v, %v; want none", pairs, err)
+	}
+	if _, err := SamplingPairs(module, "missing"); err == nil {
+		t.Error("unknown entry point succeeded")
+	}
+}
@@ -0,0 +1,222 @@
+package naga
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"sync"
+	"testing"
+
+	"github.com/gogpu/naga/glsl"
+	"github.com/gogpu/naga/hlsl"
+	"github.com/gogpu/naga/ir"
+	"github.com/gogpu/naga/msl"
+	"github.com/gogpu/naga/spirv"
+	"github.com/gogpu/naga/wgsl"
+)
+
+// ---------------------------------------------------------------------------
+// Stage-by-stage benchmarks over small, medium and water-sized shaders
+// ---------------------------------------------------------------------------
+//
+// Compare two revisions with scripts/bench-compare.sh, which runs these
+// benchmarks on both and summarizes the difference with benchstat.
+
+// benchInputDir holds the snapshot inputs reused as large benchmark shaders.
+const benchInputDir = "snapshot/testdata/in"
+
+// benchStageShaders returns the shaders the stage benchmarks run on: a
+// small and a medium inline shader, the water shader of the wgpu water
+// example, and the ~300 line terrain shader from the same example.
+func benchStageShaders(b *testing.B) []shaderCase {
+	b.Helper()
+	return []shaderCase{
+		{"small", shaderTriangleVertexFragment},
+		{"medium", shaderLargeFragment},
+		{"water", shaderWater},
+		{"terrain", readBenchInput(b, "debug-symbol-terrain.wgsl")},
+	}
+}
+
+// readBenchInput reads a shader from the snapshot inputs.
+func readBenchInput(b *testing.B, file string) string {
+	b.Helper()
+	data, err := os.ReadFile(filepath.Join(benchInputDir, file))
+	if err != nil {
+		b.Fatalf("read %s: %v", file, err)
+	}
+	return string(data)
+}
+
+// benchLower parses and lowers source, failing the benchmark on error.
+func benchLower(b *testing.B, source string) *ir.Module {
+	b.Helper()
+	ast, err := Parse(source)
+	if err != nil {
+		b.Fatalf("parse failed: %v", err)
+	}
+	module, err := LowerWithSource(ast, source)
+	if err != nil {
+		b.Fatalf("lower failed: %v", err)
+	}
+	return module
+}
+
+// BenchmarkTokenize benchmarks the WGSL lexer alone. The "comments" case is
+// the terrain shader preceded by ~7000 lines of non-ASCII comments.
+func BenchmarkTokenize(b *testing.B) {
+	cases := append(benchStageShaders(b),
+		shaderCase{"comments", readBenchInput(b, "debug-symbol-large-source.wgsl")})
+	for _, sc := range cases {
+		b.Run(sc.name, func(b *testing.B) {
+			b.ReportAllocs()
+			b.SetBytes(int64(len(sc.source)))
+			b.ResetTimer()
+
+			for i := 0; i < b.N; i++ {
+				tokens, err := wgsl.NewLexer(sc.source).Tokenize()
+				if err != nil {
+					b.Fatalf("tokenize failed: %v", err)
+				}
+				runtime.KeepAlive(tokens)
+			}
+		})
+	}
+}
+
+// BenchmarkStages benchmarks each pipeline stage separately on the same
+// shader, so a regression can be attributed to the stage that caused it.
+// Every stage reuses the output of the previous one, prepared outside the
+// timed loop.
+func BenchmarkStages(b *testing.B) {
+	for _, sc := range benchStageShaders(b) {
+		module := benchLower(b, sc.source)
+		ast, err := Parse(sc.source)
+		if err != nil {
+			b.Fatalf("parse failed: %v", err)
+		}
+
+		stages := []struct {
+			name string
+			run  func() (any, error)
+		}{
+			{"parse", func() (any, error) { return Parse(sc.source) }},
+			{"lower", func() (any, error) { return LowerWithSource(ast, sc.source) }},
+			{"validate", func() (any, error) { return Validate(module) }},
+			{"spirv", func() (any, error) {
+				return spirv.NewBackend(spirv.DefaultOptions()).Compile(module)
+			}},
+			{"msl", func() (any, error) {
+				code, _, err := msl.Compile(module, msl.DefaultOptions())
+				return code, err
+			}},
+			{"hlsl", func() (any, error) {
+				code, _, err := hlsl.Compile(module, hlsl.DefaultOptions())
+				return code, err
+			}},
+			{"glsl", func() (any, error) { return compileBenchGLSL(module) }},
+		}
+		for _, stage := range stages {
+			b.Run(sc.name+"/"+stage.name, func(b *testing.B) {
+				b.ReportAllocs()
+				b.SetBytes(int64(len(sc.source)))
+				b.ResetTimer()
+
+				for i := 0; i < b.N; i++ {
+					out, err := stage.run()
+					if err != nil {
+						b.Fatalf("%s failed: %v", stage.name, err)
+					}
+					runtime.KeepAlive(out)
+				}
+			})
+		}
+	}
+}
+
+// compileBenchGLSL compiles every entry point of module to GLSL, using
+// GLSL 4.30 for compute entry points.
+func compileBenchGLSL(module *ir.Module) ([]string, error) {
+	out := make([]string, 0, len(module.EntryPoints))
+	for i := range module.EntryPoints {
+		opts := glsl.DefaultOptions()
+		opts.EntryPoint = module.EntryPoints[i].Name
+		if module.EntryPoints[i].Stage == ir.StageCompute {
+			opts.LangVersion = glsl.Version430
+		}
+		code, _, err := glsl.Compile(module, opts)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, code)
+	}
+	return out, nil
+}
+
+// BenchmarkCompileBatch compiles a batch of shaders to SPIR-V, one at a
+// time and spread over GOMAXPROCS goroutines. The parallel variant catches
+// regressions from shared state or lock contention that single-shader
+// benchmarks do not show.
+func BenchmarkCompileBatch(b *testing.B) {
+	const copies = 16
+	var batch []string
+	for range copies {
+		for _, sc := range shadersByComplexity {
+			batch = append(batch, sc.source)
+		}
+	}
+	var batchBytes int64
+	for _, src := range batch {
+		batchBytes += int64(len(src))
+	}
+
+	b.Run("serial", func(b *testing.B) {
+		b.ReportAllocs()
+		b.SetBytes(batchBytes)
+		b.ResetTimer()
+
+		for i := 0; i < b.N; i++ {
+			for _, src := range batch {
+				if _, err := Compile(src); err != nil {
+					b.Fatalf("compile failed: %v", err)
+				}
+			}
+		}
+	})
+
+	b.Run("parallel", func(b *testing.B) {
+		workers := runtime.GOMAXPROCS(0)
+		b.ReportAllocs()
+		b.SetBytes(batchBytes)
+		b.ResetTimer()
+
+		for i := 0; i < b.N; i++ {
+			jobs := make(chan string)
+			errs := make(chan error, workers)
+			var wg sync.WaitGroup
+			for range workers {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					for src := range jobs {
+						if _, err := Compile(src); err != nil {
+							select {
+							case errs <- err:
+							default:
+							}
+						}
+					}
+				}()
+			}
+			for _, src := range batch {
+				jobs <- src
+			}
+			close(jobs)
+			wg.Wait()
+			close(errs)
+			if err := <-errs; err != nil {
+				b.Fatalf("compile failed: %v", err)
+			}
+		}
+	})
+}
@@ -0,0 +1,119 @@
+//go:build cgo
+
+// libnaga is a C ABI facade over the compiler, built as a shared library so
+// engines written in C, C++ or Rust can compile WGSL without a Go toolchain
+// at runtime.
+//
+// Build (the header libnaga.h is written next to the library):
+//
+//	go build -buildmode=c-shared -o libnaga.so ./cmd/libnaga    # Linux
+//	go build -buildmode=c-shared -o libnaga.dylib ./cmd/libnaga # macOS
+//	go build -buildmode=c-shared -o naga.dll ./cmd/libnaga      # Windows
+//
+// Every compile function takes NUL-terminated WGSL source and JSON options
+// (NULL or "" for defaults; see the fields below) and returns 0 on success
+// and 1 on failure. On success *out receives the result; on failure *error
+// receives the message. Both are allocated by the library and must be
+// released with naga_free. Output pointers may be NULL when the caller does
+// not need them.
+//
+//	int naga_compile_wgsl_to_spirv(char* source, char* options,
+//	                               unsigned char** out, size_t* out_len, char** error);
+//	int naga_compile_wgsl_to_msl(char* source, char* options, char** out, char** error);
+//	int naga_compile_wgsl_to_hlsl(char* source, char* options, char** out, char** error);
+//	int naga_compile_wgsl_to_glsl(char* source, char* options, char** out, char** error);
+//	void naga_free(void* p);
+//
+// testdata/example.c shows the calls in context.
+//
+// Options fields: entry_point, debug, validate, spirv_version ("1.3"),
+// msl_version ("2.1"), shader_model ("5.1") and glsl_version ("330",
+// "300es").
+package main
+
+/*
+#include <stdlib.h>
+*/
+import "C"
+
+import (
+	"unsafe"
+
+	"github.com/gogpu/naga/internal/facade"
+)
+
+// main is required by -buildmode=c-shared and never runs.
+func main() {}
+
+//export naga_compile_wgsl_to_spirv
+func naga_compile_wgsl_to_spirv(source, options *C.char, out **C.uchar, outLen *C.size_t, errOut **C.char) C.int {
+	code, ok := compile(source, options, "spirv", errOut)
+	if !ok {
+		return 1
+	}
+	if out != nil {
+		*out = (*C.uchar)(C.CBytes(code))
+	}
+	if outLen != nil {
+		*outLen = C.size_t(len(code))
+	}
+	return 0
+}
+
+//export naga_compile_wgsl_to_msl
+func naga_compile_wgsl_to_msl(source, options *C.char, out **C.char, errOut **C.char) C.int {
+	return compileText(source, options, "msl", out, errOut)
+}
+
+//export naga_compile_wgsl_to_hlsl
+func naga_compile_wgsl_to_hlsl(source, options *C.char, out **C.char, errOut **C.char) C.int {
+	return compileText(source, options, "hlsl", out, errOut)
+}
+
+//export naga_compile_wgsl_to_glsl
+func naga_compile_wgsl_to_glsl(source, options *C.char, out **C.char, errOut **C.char) C.int {
+	return compileText(source, options, "glsl", out, errOut)
+}
+
+//export naga_free
+func naga_free(p unsafe.Pointer) {
+	C.free(p)
+}
+
+// compileText runs compile for a text target and hands the result to C as
+// a NUL-terminated string.
+func compileText(source, options *C.char, target string, out **C.char, errOut **C.char) C.int {
+	code, ok := compile(source, options, target, errOut)
+	if !ok {
+		return 1
+	}
+	if out != nil {
+		*out = C.CString(string(code))
+	}
+	return 0
+}
+
+// compile converts the C arguments, compiles, and stores any error message
+// in *errOut.
+func compile(source, options *C.char, target string, errOut **C.char) ([]byte, bool) {
+	if source == nil {
+		setError(errOut, "source is NULL")
+		return nil, false
+	}
+	optionsJSON := ""
+	if options != nil {
+		optionsJSON = C.GoString(options)
+	}
+	out, err := facade.Compile(C.GoString(source), target, optionsJSON)
+	if err != nil {
+		setError(errOut, err.Error())
+		return nil, false
+	}
+	return out.Code, true
+}
+
+func setError(errOut **C.char, msg string) {
+	if errOut != nil {
+		*errOut = C.CString(msg)
+	}
+}
@@ -0,0 +1,50 @@
+//go:build cgo
+
+package main
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+// TestCABI builds the shared library and links testdata/example.c against it.
+func TestCABI(t *testing.T) {
+	if testing.Short() {
+		t.Skip("builds a shared library")
+	}
+	if runtime.GOOS != "linux" && runtime.GOOS != "darwin" {
+		t.Skip("example link line is for Unix")
+	}
+	cc, err := exec.LookPath("cc")
+	if err != nil {
+		t.Skip("no C compiler")
+	}
+	dir := t.TempDir()
+	lib := filepath.Join(dir, "libnaga.so")
+	if runtime.GOOS == "darwin" {
+		lib = filepath.Join(dir, "libnaga.dylib")
+	}
+	if out, err := exec.Command("go", "build", "-buildmode=c-shared", "-o", lib, ".").CombinedOutput(); err != nil {
+		t.Fatalf("go build: %v\n%s", err, out)
+	}
+	example := filepath.Join(dir, "example")
+	if out, err := exec.Command(cc, "-o", example, "testdata/example.c", "-I", dir, "-L", dir, "-lnaga",
+		"-Wl,-rpath,"+dir).CombinedOutput(); err != nil {
+		t.Fatalf("cc: %v\n%s", err, out)
+	}
+	cmd := exec.Command(example)
+	cmd.Env = append(os.Environ(), "LD_LIBRARY_PATH="+dir)
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("example: %v\n%s", err, out)
+	}
+	for _, want := range []string{"#include <metal_stdlib>", "spirv: ", "magic 07230203", "error: parse error"} {
+		if !strings.Contains(string(out), want) {
+			t.Errorf("output does not contain %q:\n%s", want, out)
+		}
+	}
+}
@@ -0,0 +1,43 @@
+// Example use of libnaga. Build the library first, then:
+//
+//	cc -o example example.c -I<dir> -L<dir> -lnaga
+#include <stdio.h>
+#include <string.h>
+
+#include "libnaga.h"
+
+static char source[] =
+    "@fragment\n"
+    "fn main() -> @location(0) vec4<f32> {\n"
+    "    return vec4<f32>(1.0, 0.0, 0.0, 1.0);\n"
+    "}\n";
+
+int main(void) {
+    char *msl = NULL, *error = NULL;
+    if (naga_compile_wgsl_to_msl(source, "{\"msl_version\": \"2.1\"}", &msl, &error) != 0) {
+        fprintf(stderr, "msl: %s\n", error);
+        naga_free(error);
+        return 1;
+    }
+    printf("%s", msl);
+    naga_free(msl);
+
+    unsigned char *spirv = NULL;
+    size_t spirv_len = 0;
+    if (naga_compile_wgsl_to_spirv(source, NULL, &spirv, &spirv_len, &error) != 0) {
+        fprintf(stderr, "spirv: %s\n", error);
+        naga_free(error);
+        return 1;
+    }
+    printf("spirv: %zu bytes, magic %02x%02x%02x%02x\n", spirv_len, spirv[3], spirv[2], spirv[1], spirv[0]);
+    naga_free(spirv);
+
+    char bad[] = "fn main( {";
+    if (naga_compile_wgsl_to_hlsl(bad, "", NULL, &error) == 0) {
+        fprintf(stderr, "expected an error\n");
+        return 1;
+    }
+    printf("error: %s\n", error);
+    naga_free(error);
+    return 0;
+}
@@ -0,0 +1,483 @@
+// Copyright 2025 The GoGPU Authors
+// SPDX-License-Identifier: MIT
+
+// Package gen implements naga-bindgen: Go struct and binding-constant
+// generation from the host-shareable globals of an IR module.
+//
+// Layout is taken verbatim from the IR (StructMember.Offset, StructType.Span,
+// ArrayType.Stride), which the lowerer already computes with WGSL alignment
+// rules. The generator only has to express that layout in Go: gaps become
+// blank `_ [N]byte` fields, matrix columns are widened to their aligned
+// stride, and vec3 array elements are widened to the array stride. Every
+// emitted struct gets a compile-time size assertion against the WGSL span.
+package gen
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/gogpu/naga/ir"
+)
+
+// Options configures Go binding generation.
+type Options struct {
+	// Package is the Go package name of the generated file.
+	// Defaults to "shaders" if empty.
+	Package string
+
+	// Source is the input file name recorded in the generated header.
+	Source string
+}
+
+// Generate produces a gofmt'ed Go source file containing struct definitions
+// for every struct type reachable from uniform, storage, and push-constant
+// globals, plus group/binding constants for every bound resource.
+func Generate(module *ir.Module, opts Options) ([]byte, error) {
+	g := &generator{
+		module:    module,
+		typeNames: make(map[ir.TypeHandle]string),
+		usedNames: make(map[string]bool),
+	}
+	if err := g.collect(); err != nil {
+		return nil, err
+	}
+
+	pkg := opts.Package
+	if pkg == "" {
+		pkg = "shaders"
+	}
+
+	var buf bytes.Buffer
+	if opts.Source != "" {
+		fmt.Fprintf(&buf, "// Code generated by naga-bindgen from %s. DO NOT EDIT.\n\n", opts.Source)
+	} else {
+		buf.WriteString("// Code generated by naga-bindgen. DO NOT EDIT.\n\n")
+	}
+	fmt.Fprintf(&buf, "package %s\n\n", pkg)
+	if len(g.structs) > 0 {
+		buf.WriteString("import \"unsafe\"\n\n")
+	}
+
+	if err := g.writeConstants(&buf); err != nil {
+		return nil, err
+	}
+	for _, h := range g.structs {
+		if err := g.writeStruct(&buf, h); err != nil {
+			return nil, err
+		}
+	}
+
+	out, err := format.Source(buf.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("naga-bindgen: formatting generated code: %w", err)
+	}
+	return out, nil
+}
+
+// generator holds per-module generation state.
+type generator struct {
+	module *ir.Module
+
+	// globals are the bound or host-shareable globals, in declaration order.
+	globals []ir.GlobalVariableHandle
+
+	// structs are the struct types to emit, sorted by handle.
+	structs []ir.TypeHandle
+
+	// typeNames maps struct type handles to their Go names.
+	typeNames map[ir.TypeHandle]string
+
+	// usedNames tracks top-level Go identifiers to keep them unique.
+	usedNames map[string]bool
+}
+
+// isBufferSpace reports whether globals in space have a CPU-visible layout.
+func isBufferSpace(space ir.AddressSpace) bool {
+	switch space {
+	case ir.SpaceUniform, ir.SpaceStorage, ir.SpacePushConstant, ir.SpaceImmediate:
+		return true
+	default:
+		return false
+	}
+}
+
+// collect selects globals and the struct types reachable from buffer globals,
+// then assigns unique Go names to both.
+func (g *generator) collect() error {
+	seen := make(map[ir.TypeHandle]bool)
+	var visit func(h ir.TypeHandle) error
+	visit = func(h ir.TypeHandle) error {
+		if int(h) >= len(g.module.Types) {
+			return fmt.Errorf("naga-bindgen: type handle %d out of range", h)
+		}
+		switch t := g.module.Types[h].Inner.(type) {
+		case ir.StructType:
+			if seen[h] {
+				return nil
+			}
+			seen[h] = true
+			for _, m := range t.Members {
+				if err := visit(m.Type); err != nil {
+					return err
+				}
+			}
+		case ir.ArrayType:
+			return visit(t.Base)
+		}
+		return nil
+	}
+
+	for i := range g.module.GlobalVariables {
+		gv := &g.module.GlobalVariables[i]
+		if gv.Binding == nil && !isBufferSpace(gv.Space) {
+			continue
+		}
+		g.globals = append(g.globals, ir.GlobalVariableHandle(i))
+		if isBufferSpace(gv.Space) {
+			if err := visit(gv.Type); err != nil {
+				return err
+			}
+		}
+	}
+
+	for h := range seen {
+		g.structs = append(g.structs, h)
+	}
+	sort.Slice(g.structs, func(i, j int) bool { return g.structs[i] < g.structs[j] })
+
+	for _, h := range g.structs {
+		name := g.module.Types[h].Name
+		if name == "" {
+			name = fmt.Sprintf("Struct%d", h)
+		}
+		g.typeNames[h] = g.unique(exportedName(name))
+	}
+	return nil
+}
+
+// unique returns name, or name with a numeric suffix if it is already taken.
+func (g *generator) unique(name string) string {
+	candidate := name
+	for i := 1; g.usedNames[candidate]; i++ {
+		candidate = fmt.Sprintf("%s%d", name, i)
+	}
+	g.usedNames[candidate] = true
+	return candidate
+}
+
+// writeConstants emits one const block per global with group/binding
+// constants and, for buffers, size information.
+func (g *generator) writeConstants(buf *bytes.Buffer) error {
+	for _, h := range g.globals {
+		gv := &g.module.GlobalVariables[h]
+		base := exportedName(gv.Name)
+		if base == "" {
+			base = fmt.Sprintf("Global%d", h)
+		}
+		wgslType := g.wgslTypeName(gv.Type)
+
+		fmt.Fprintf(buf, "// %s is var<%s> %s: %s.\n", base, spaceName(gv), gv.Name, wgslType)
+		buf.WriteString("const (\n")
+		if gv.Binding != nil {
+			fmt.Fprintf(buf, "%s = %d\n", g.unique(base+"Group"), gv.Binding.Group)
+			fmt.Fprintf(buf, "%s = %d\n", g.unique(base+"Binding"), gv.Binding.Binding)
+		}
+		if isBufferSpace(gv.Space) {
+			size, stride, err := g.bufferSize(gv.Type)
+			if err != nil {
+				return fmt.Errorf("naga-bindgen: global %q: %w", gv.Name, err)
+			}
+			fmt.Fprintf(buf, "%s = %d\n", g.unique(base+"Size"), size)
+			if stride != 0 {
+				fmt.Fprintf(buf, "// %s is the stride of the trailing runtime-sized array.\n", base+"Stride")
+				fmt.Fprintf(buf, "%s = %d\n", g.unique(base+"Stride"), stride)
+			}
+		}
+		buf.WriteString(")\n\n")
+	}
+	return nil
+}
+
+// bufferSize returns the fixed-size part of a buffer type and, if the type
+// ends in a runtime-sized array, that array's stride.
+func (g *generator) bufferSize(h ir.TypeHandle) (size, stride uint32, err error) {
+	switch t := g.module.Types[h].Inner.(type) {
+	case ir.ArrayType:
+		if t.Size.Constant == nil {
+			return 0, t.Stride, nil
+		}
+	case ir.StructType:
+		if n := len(t.Members); n > 0 {
+			last := t.Members[n-1]
+			if arr, ok := g.module.Types[last.Type].Inner.(ir.ArrayType); ok && arr.Size.Constant == nil {
+				return last.Offset, arr.Stride, nil
+			}
+		}
+	}
+	return ir.TypeSize(g.module, h), 0, nil
+}
+
+// writeStruct emits a Go struct mirroring the WGSL struct at handle h,
+// followed by a compile-time size assertion.
+func (g *generator) writeStruct(buf *bytes.Buffer, h ir.TypeHandle) error {
+	st := g.module.Types[h].Inner.(ir.StructType)
+	name := g.typeNames[h]
+
+	// A runtime-sized tail is not part of the Go struct, which then ends at
+	// the tail's offset: the buffer's Size constant.
+	span := st.Span
+	if n := len(st.Members); n > 0 {
+		if arr, ok := g.module.Types[st.Members[n-1].Type].Inner.(ir.ArrayType); ok && arr.Size.Constant == nil {
+			span = st.Members[n-1].Offset
+		}
+	}
+	if span != st.Span {
+		fmt.Fprintf(buf, "// %s mirrors the %d-byte fixed part of WGSL struct %s.\n", name, span, g.module.Types[h].Name)
+	} else {
+		fmt.Fprintf(buf, "// %s mirrors WGSL struct %s (%d bytes).\n", name, g.module.Types[h].Name, st.Span)
+	}
+	fmt.Fprintf(buf, "type %s struct {\n", name)
+
+	fieldNames := make(map[string]bool)
+	var cursor uint32
+	for i, m := range st.Members {
+		if m.Offset > cursor {
+			fmt.Fprintf(buf, "_ [%d]byte\n", m.Offset-cursor)
+			cursor = m.Offset
+		}
+
+		fieldName := exportedName(m.Name)
+		if fieldName == "" {
+			fieldName = fmt.Sprintf("Field%d", i)
+		}
+		for base, n := fieldName, 1; fieldNames[fieldName]; n++ {
+			fieldName = fmt.Sprintf("%s%d", base, n)
+		}
+		fieldNames[fieldName] = true
+
+		if arr, ok := g.module.Types[m.Type].Inner.(ir.ArrayType); ok && arr.Size.Constant == nil {
+			if i != len(st.Members)-1 {
+				return fmt.Errorf("naga-bindgen: struct %s: runtime-sized member %q is not last", name, m.Name)
+			}
+			elem, _, err := g.arrayElem(arr)
+			if err != nil {
+				return fmt.Errorf("naga-bindgen: struct %s member %q: %w", name, m.Name, err)
+			}
+			fmt.Fprintf(buf, "// %s []%s at offset %d is runtime-sized (stride %d) and not part of this struct.\n",
+				fieldName, elem, m.Offset, arr.Stride)
+			continue
+		}
+
+		goType, size, err := g.goType(m.Type)
+		if err != nil {
+			return fmt.Errorf("naga-bindgen: struct %s member %q: %w", name, m.Name, err)
+		}
+		fmt.Fprintf(buf, "%s %s // offset %d, %s\n", fieldName, goType, m.Offset, g.wgslTypeName(m.Type))
+		cursor = m.Offset + size
+	}
+
+	if span > cursor {
+		fmt.Fprintf(buf, "_ [%d]byte\n", span-cursor)
+	}
+	buf.WriteString("}\n\n")
+
+	fmt.Fprintf(buf, "var _ = [1]struct{}{}[unsafe.Sizeof(%s{})-%d]\n\n", name, span)
+	return nil
+}
+
+// goType returns the Go type expression for a host-shareable IR type and
+// its size in bytes under the WGSL layout.
+func (g *generator) goType(h ir.TypeHandle) (string, uint32, error) {
+	switch t := g.module.Types[h].Inner.(type) {
+	case ir.ScalarType:
+		s, err := goScalar(t)
+		return s, uint32(t.Width), err
+	case ir.AtomicType:
+		s, err := goScalar(t.Scalar)
+		return s, uint32(t.Scalar.Width), err
+	case ir.VectorType:
+		s, err := goScalar(t.Scalar)
+		return fmt.Sprintf("[%d]%s", t.Size, s), uint32(t.Size) * uint32(t.Scalar.Width), err
+	case ir.MatrixType:
+		s, err := goScalar(t.Scalar)
+		rows := uint32(t.Rows)
+		if rows == 3 {
+			rows = 4
+		}
+		return fmt.Sprintf("[%d][%d]%s", t.Columns, rows, s), ir.TypeSize(g.module, h), err
+	case ir.ArrayType:
+		if t.Size.Constant == nil {
+			return "", 0, fmt.Errorf("runtime-sized array must be the last member of a struct")
+		}
+		elem, _, err := g.arrayElem(t)
+		if err != nil {
+			return "", 0, err
+		}
+		return fmt.Sprintf("[%d]%s", *t.Size.Constant, elem), *t.Size.Constant * t.Stride, nil
+	case ir.StructType:
+		return g.typeNames[h], t.Span, nil
+	default:
+		return "", 0, fmt.Errorf("type %s is not host-shareable", g.wgslTypeName(h))
+	}
+}
+
+// arrayElem returns the Go element type of an array, widening vector
+// elements whose array stride exceeds their size (vec3 in a 16-byte stride).
+func (g *generator) arrayElem(arr ir.ArrayType) (string, uint32, error) {
+	elem, size, err := g.goType(arr.Base)
+	if err != nil {
+		return "", 0, err
+	}
+	if size == arr.Stride {
+		return elem, size, nil
+	}
+	if vec, ok := g.module.Types[arr.Base].Inner.(ir.VectorType); ok && arr.Stride%uint32(vec.Scalar.Width) == 0 {
+		s, err := goScalar(vec.Scalar)
+		return fmt.Sprintf("[%d]%s", arr.Stride/uint32(vec.Scalar.Width), s), arr.Stride, err
+	}
+	return "", 0, fmt.Errorf("array element %s (%d bytes) does not fill stride %d",
+		g.wgslTypeName(arr.Base), size, arr.Stride)
+}
+
+// goScalar maps an IR scalar to its Go equivalent. f16 has no Go type and
+// is exposed as its raw uint16 bit pattern.
+func goScalar(s ir.ScalarType) (string, error) {
+	switch s.Kind {
+	case ir.ScalarFloat:
+		switch s.Width {
+		case 2:
+			return "uint16", nil
+		case 4:
+			return "float32", nil
+		case 8:
+			return "float64", nil
+		}
+	case ir.ScalarSint:
+		switch s.Width {
+		case 4:
+			return "int32", nil
+		case 8:
+			return "int64", nil
+		}
+	case ir.ScalarUint:
+		switch s.Width {
+		case 4:
+			return "uint32", nil
+		case 8:
+			return "uint64", nil
+		}
+	case ir.ScalarBool:
+		return "", fmt.Errorf("bool is not host-shareable")
+	}
+	return "", fmt.Errorf("unsupported scalar kind %d width %d", s.Kind, s.Width)
+}
+
+// wgslTypeName renders a type in WGSL syntax for generated comments.
+func (g *generator) wgslTypeName(h ir.TypeHandle) string {
+	if int(h) >= len(g.module.Types) {
+		return "?"
+	}
+	ty := g.module.Types[h]
+	switch t := ty.Inner.(type) {
+	case ir.ScalarType:
+		return wgslScalar(t)
+	case ir.AtomicType:
+		return "atomic<" + wgslScalar(t.Scalar) + ">"
+	case ir.VectorType:
+		return fmt.Sprintf("vec%d<%s>", t.Size, wgslScalar(t.Scalar))
+	case ir.MatrixType:
+		return fmt.Sprintf("mat%dx%d<%s>", t.Columns, t.Rows, wgslScalar(t.Scalar))
+	case ir.ArrayType:
+		if t.Size.Constant == nil {
+			return "array<" + g.wgslTypeName(t.Base) + ">"
+		}
+		return fmt.Sprintf("array<%s, %d>", g.wgslTypeName(t.Base), *t.Size.Constant)
+	case ir.BindingArrayType:
+		if t.Size == nil {
+			return "binding_array<" + g.wgslTypeName(t.Base) + ">"
+		}
+		return fmt.Sprintf("binding_array<%s, %d>", g.wgslTypeName(t.Base), *t.Size)
+	case ir.SamplerType:
+		if t.Comparison {
+			return "sampler_comparison"
+		}
+		return "sampler"
+	case ir.ImageType:
+		return "texture"
+	case ir.AccelerationStructureType:
+		return "acceleration_structure"
+	}
+	if ty.Name != "" {
+		return ty.Name
+	}
+	return fmt.Sprintf("type%d", h)
+}
+
+func wgslScalar(s ir.ScalarType) string {
+	switch s.Kind {
+	case ir.ScalarFloat:
+		return fmt.Sprintf("f%d", s.Width*8)
+	case ir.ScalarSint:
+		return fmt.Sprintf("i%d", s.Width*8)
+	case ir.ScalarUint:
+		return fmt.Sprintf("u%d", s.Width*8)
+	case ir.ScalarBool:
+		return "bool"
+	default:
+		return "abstract"
+	}
+}
+
+// spaceName returns the WGSL address-space spelling used in comments.
+func spaceName(gv *ir.GlobalVariable) string {
+	switch gv.Space {
+	case ir.SpaceUniform:
+		return "uniform"
+	case ir.SpaceStorage:
+		if gv.Access == ir.StorageRead {
+			return "storage, read"
+		}
+		return "storage, read_write"
+	case ir.SpacePushConstant:
+		return "push_constant"
+	case ir.SpaceImmediate:
+		return "immediate"
+	case ir.SpaceHandle:
+		return "handle"
+	case ir.SpaceWorkGroup:
+		return "workgroup"
+	default:
+		return "private"
+	}
+}
+
+// exportedName converts a WGSL identifier (snake_case or camelCase) into an
+// exported Go identifier: "view_proj" → "ViewProj", "lightDir" → "LightDir".
+// Returns "" for identifiers with no letters or digits.
+func exportedName(name string) string {
+	var sb strings.Builder
+	upper := true
+	for _, r := range name {
+		if r == '_' {
+			upper = true
+			continue
+		}
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			continue
+		}
+		if upper {
+			r = unicode.ToUpper(r)
+			upper = false
+		}
+		sb.WriteRune(r)
+	}
+	out := sb.String()
+	if out != "" && !unicode.IsLetter([]rune(out)[0]) {
+		out = "X" + out
+	}
+	return out
+}
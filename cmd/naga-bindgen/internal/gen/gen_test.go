@@ -0,0 +1,240 @@
+package gen
+
+import (
+	"bytes"
+	"go/ast"
+	"go/importer"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gogpu/naga/internal/testutil"
+)
+
+// typeCheck type-checks generated code with amd64 gc sizes, which also
+// evaluates the unsafe.Sizeof assertions emitted for each struct.
+func typeCheck(t *testing.T, code []byte) {
+	t.Helper()
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, "gen.go", code, 0)
+	if err != nil {
+		t.Fatalf("generated code does not parse: %v\n%s", err, code)
+	}
+	conf := types.Config{Importer: importer.Default(), Sizes: types.SizesFor("gc", "amd64")}
+	if _, err := conf.Check("shaders", fset, []*ast.File{file}, nil); err != nil {
+		t.Fatalf("generated code does not type-check: %v\n%s", err, code)
+	}
+}
+
+const sceneShader = `
+struct Light {
+    position: vec3<f32>,
+    intensity: f32,
+    color: vec3<f32>,
+}
+
+struct Camera {
+    view_proj: mat4x4<f32>,
+    normal_mat: mat3x3<f32>,
+    eye: vec3<f32>,
+    exposure: f32,
+    jitter: vec2<f32>,
+}
+
+struct Lights {
+    count: u32,
+    items: array<Light, 4>,
+    dirs: array<vec3<f32>, 2>,
+}
+
+struct Particles {
+    time: f32,
+    data: array<vec4<f32>>,
+}
+
+struct Push {
+    model: u32,
+    tint: vec4<f32>,
+}
+
+@group(0) @binding(0) var<uniform> camera: Camera;
+@group(0) @binding(1) var<uniform> lights: Lights;
+@group(1) @binding(0) var<storage, read_write> particles: Particles;
+@group(1) @binding(1) var tex: texture_2d<f32>;
+@group(1) @binding(2) var samp: sampler;
+var<push_constant> pc: Push;
+
+@fragment
+fn main() -> @location(0) vec4<f32> {
+    let c = camera.eye.x + lights.items[0].intensity + particles.data[0].x + f32(pc.model);
+    return textureSample(tex, samp, vec2<f32>(c)) + pc.tint;
+}
+`
+
+func TestGenerateSceneLayout(t *testing.T) {
+	module := testutil.LowerWGSL(t, sceneShader)
+	code, err := Generate(module, Options{Package: "shaders", Source: "scene.wgsl"})
+	if err != nil {
+		t.Fatalf("Generate: %v", err)
+	}
+	typeCheck(t, code)
+
+	out := string(code)
+	wants := []string{
+		"// Code generated by naga-bindgen from scene.wgsl. DO NOT EDIT.",
+		"package shaders",
+		"CameraGroup   = 0",
+		"LightsBinding = 1",
+		"ParticlesGroup   = 1",
+		"ParticlesStride = 16",
+		"TexBinding = 1",
+		"SampBinding = 2",
+		"PcSize = 32",
+		"ViewProj  [4][4]float32",
+		"NormalMat [3][4]float32",
+		"Dirs  [2][4]float32",
+		"Items [4]Light",
+		"// Data []",
+		"unsafe.Sizeof(Camera{})-",
+	}
+	for _, want := range wants {
+		if !strings.Contains(out, want) {
+			t.Errorf("generated code missing %q\n%s", want, out)
+		}
+	}
+	if strings.Contains(out, "PcGroup") {
+		t.Errorf("push constants have no binding, unexpected PcGroup constant\n%s", out)
+	}
+}
+
+func TestGeneratePaddedRuntimeTail(t *testing.T) {
+	module := testutil.LowerWGSL(t, `
+struct Vertex {
+    pos: vec3<f32>,
+    uv: vec2<f32>,
+}
+
+struct Grid {
+    dims: vec3<u32>,
+    cells: array<vec4<f32>>,
+}
+
+struct Mesh {
+    scale: f32,
+    verts: array<Vertex>,
+}
+
+@group(0) @binding(0) var<storage, read> grid: Grid;
+@group(0) @binding(1) var<storage, read> mesh: Mesh;
+
+@compute @workgroup_size(1)
+fn main() {
+    _ = grid.cells[grid.dims.x];
+    _ = mesh.verts[0].uv.x * mesh.scale;
+}
+`)
+	code, err := Generate(module, Options{})
+	if err != nil {
+		t.Fatalf("Generate: %v", err)
+	}
+	typeCheck(t, code)
+
+	// The Go structs end at the runtime-sized member, padded up to its
+	// offset so that unsafe.Sizeof matches the buffer's Size constant.
+	out := string(code)
+	for _, want := range []string{
+		"GridSize    = 16",
+		"unsafe.Sizeof(Grid{})-16]",
+		"MeshSize    = 16",
+		"unsafe.Sizeof(Mesh{})-16]",
+		"// Grid mirrors the 16-byte fixed part of WGSL struct Grid.",
+		"_    [4]byte\n\t// Cells [][4]float32 at offset 16",
+		"_     [12]byte\n\t// Verts []Vertex at offset 16",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("generated code missing %q\n%s", want, out)
+		}
+	}
+}
+
+func TestGenerateNoStructsOmitsUnsafe(t *testing.T) {
+	module := testutil.LowerWGSL(t, `
+@group(0) @binding(0) var<uniform> time: f32;
+@group(0) @binding(1) var<storage, read> values: array<u32>;
+
+@compute @workgroup_size(1)
+fn main() {
+    _ = time;
+    _ = values[0];
+}
+`)
+	code, err := Generate(module, Options{})
+	if err != nil {
+		t.Fatalf("Generate: %v", err)
+	}
+	typeCheck(t, code)
+
+	out := string(code)
+	if strings.Contains(out, "unsafe") {
+		t.Errorf("unexpected unsafe import without structs\n%s", out)
+	}
+	for _, want := range []string{"package shaders", "TimeSize    = 4", "ValuesStride = 4"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("generated code missing %q\n%s", want, out)
+		}
+	}
+}
+
+func TestExportedName(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"camera", "Camera"},
+		{"view_proj", "ViewProj"},
+		{"lightDir", "LightDir"},
+		{"_private", "Private"},
+		{"a__b", "AB"},
+		{"_2d", "X2d"},
+		{"_", ""},
+	}
+	for _, tt := range tests {
+		if got := exportedName(tt.in); got != tt.want {
+			t.Errorf("exportedName(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestMainWritesOutput(t *testing.T) {
+	dir := t.TempDir()
+	input := filepath.Join(dir, "scene.wgsl")
+	if err := os.WriteFile(input, []byte(sceneShader), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	output := filepath.Join(dir, "scene_gen.go")
+
+	var stdout, stderr bytes.Buffer
+	if code := Main([]string{"-pkg", "render", "-o", output, input}, &stdout, &stderr); code != ExitOK {
+		t.Fatalf("exit code %d, stderr=%s", code, stderr.String())
+	}
+	data, err := os.ReadFile(output)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), "package render") {
+		t.Errorf("output missing package clause:\n%s", data)
+	}
+}
+
+func TestMainUsage(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	if code := Main(nil, &stdout, &stderr); code != ExitUsage {
+		t.Errorf("exit code: got %d, want %d", code, ExitUsage)
+	}
+	if !strings.Contains(stderr.String(), "Usage: naga-bindgen") {
+		t.Errorf("stderr missing usage banner:\n%s", stderr.String())
+	}
+}
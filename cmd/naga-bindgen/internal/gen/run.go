@@ -0,0 +1,88 @@
+// Copyright 2025 The GoGPU Authors
+// SPDX-License-Identifier: MIT
+
+package gen
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/gogpu/naga"
+)
+
+// Exit codes.
+const (
+	ExitOK    = 0
+	ExitFail  = 1
+	ExitUsage = 2
+)
+
+// Main parses args, generates bindings, and writes them to -o or stdout.
+// Returns a process exit code.
+func Main(args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("naga-bindgen", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+
+	var (
+		pkg    string
+		output string
+	)
+	fs.StringVar(&pkg, "pkg", "shaders", "Go package name of the generated file")
+	fs.StringVar(&output, "o", "", "output file (default: stdout)")
+
+	fs.Usage = func() {
+		fmt.Fprintf(stderr, "Usage: naga-bindgen [flags] <input.wgsl>\n\n")
+		fmt.Fprintf(stderr, "Generates Go structs and binding constants for the uniform,\n")
+		fmt.Fprintf(stderr, "storage, and push-constant globals of a WGSL shader.\n\n")
+		fmt.Fprintf(stderr, "Flags:\n")
+		fs.PrintDefaults()
+	}
+
+	if err := fs.Parse(args); err != nil {
+		return ExitUsage
+	}
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return ExitUsage
+	}
+
+	inputPath := fs.Arg(0)
+	source, err := os.ReadFile(inputPath)
+	if err != nil {
+		fmt.Fprintf(stderr, "naga-bindgen: %v\n", err)
+		return ExitFail
+	}
+
+	ast, err := naga.Parse(string(source))
+	if err != nil {
+		fmt.Fprintf(stderr, "naga-bindgen: %s: %v\n", inputPath, err)
+		return ExitFail
+	}
+	module, err := naga.LowerWithSource(ast, string(source))
+	if err != nil {
+		fmt.Fprintf(stderr, "naga-bindgen: %s: %v\n", inputPath, err)
+		return ExitFail
+	}
+
+	code, err := Generate(module, Options{Package: pkg, Source: filepath.Base(inputPath)})
+	if err != nil {
+		fmt.Fprintf(stderr, "%v\n", err)
+		return ExitFail
+	}
+
+	if output == "" {
+		if _, err := stdout.Write(code); err != nil {
+			fmt.Fprintf(stderr, "naga-bindgen: %v\n", err)
+			return ExitFail
+		}
+		return ExitOK
+	}
+	if err := os.WriteFile(output, code, 0o644); err != nil { //nolint:gosec // generated source is meant to be world-readable
+		fmt.Fprintf(stderr, "naga-bindgen: %v\n", err)
+		return ExitFail
+	}
+	return ExitOK
+}
@@ -0,0 +1,29 @@
+// Command naga-bindgen generates Go struct definitions and binding constants
+// from the uniform, storage, and push-constant declarations of a WGSL shader.
+//
+// Usage:
+//
+//	naga-bindgen [options] <input.wgsl>
+//
+// Examples:
+//
+//	naga-bindgen -pkg shaders -o bindings_gen.go scene.wgsl
+//
+// The generated structs reproduce the WGSL host-shareable memory layout
+// byte-for-byte (explicit padding fields, widened vec3 array elements and
+// matrix columns), and each struct carries a compile-time size assertion so
+// CPU-side code fails to build when it drifts out of sync with the shader.
+// Typical use is a go:generate directive next to the shader source:
+//
+//	//go:generate go run github.com/gogpu/naga/cmd/naga-bindgen -pkg shaders -o scene_gen.go scene.wgsl
+package main
+
+import (
+	"os"
+
+	"github.com/gogpu/naga/cmd/naga-bindgen/internal/gen"
+)
+
+func main() {
+	os.Exit(gen.Main(os.Args[1:], os.Stdout, os.Stderr))
+}
@@ -0,0 +1,80 @@
+//go:build js && wasm
+
+// naga-wasm exposes the compiler to JavaScript for in-browser use, such as a
+// shader playground.
+//
+// Build:
+//
+//	GOOS=js GOARCH=wasm go build -o naga.wasm ./cmd/naga-wasm
+//
+// Load naga.wasm with Go's wasm_exec.js. Once it runs, it defines a global
+// naga object with one function:
+//
+//	naga.compile(source, target, options) → {code, warnings, error}
+//
+// target is "spirv", "msl", "hlsl" or "glsl". options is an optional object
+// or JSON string with the fields entry_point, debug, validate,
+// spirv_version, msl_version, shader_model and glsl_version. code is a
+// Uint8Array for SPIR-V and a string otherwise. On failure, error holds the
+// message and code is null.
+package main
+
+import (
+	"syscall/js"
+
+	"github.com/gogpu/naga/internal/facade"
+)
+
+func main() {
+	api := js.Global().Get("Object").New()
+	api.Set("compile", js.FuncOf(compile))
+	api.Set("targets", js.ValueOf(toAny(facade.Targets)))
+	js.Global().Set("naga", api)
+
+	// Keep the Go runtime alive so the callbacks stay valid.
+	select {}
+}
+
+// compile implements naga.compile(source, target, options).
+func compile(_ js.Value, args []js.Value) any {
+	result := map[string]any{"code": nil, "warnings": []any{}, "error": nil}
+	if len(args) < 2 || args[0].Type() != js.TypeString || args[1].Type() != js.TypeString {
+		result["error"] = "usage: naga.compile(source, target, options)"
+		return result
+	}
+
+	optionsJSON := ""
+	if len(args) > 2 {
+		switch opts := args[2]; opts.Type() {
+		case js.TypeString:
+			optionsJSON = opts.String()
+		case js.TypeObject:
+			optionsJSON = js.Global().Get("JSON").Call("stringify", opts).String()
+		}
+	}
+
+	target := args[1].String()
+	out, err := facade.Compile(args[0].String(), target, optionsJSON)
+	if err != nil {
+		result["error"] = err.Error()
+		return result
+	}
+	result["warnings"] = toAny(out.Warnings)
+	if target == "spirv" {
+		code := js.Global().Get("Uint8Array").New(len(out.Code))
+		js.CopyBytesToJS(code, out.Code)
+		result["code"] = code
+	} else {
+		result["code"] = string(out.Code)
+	}
+	return result
+}
+
+// toAny converts a string slice for js.ValueOf, which accepts only []any.
+func toAny(ss []string) []any {
+	out := make([]any, len(ss))
+	for i, s := range ss {
+		out[i] = s
+	}
+	return out
+}
@@ -0,0 +1,251 @@
+// Copyright 2025 The GoGPU Authors
+// SPDX-License-Identifier: MIT
+
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/gogpu/naga"
+	"github.com/gogpu/naga/ir"
+	"github.com/gogpu/naga/wgsl"
+)
+
+// Exit statuses. They are part of nagac's contract with build systems:
+// new ones may be added, existing ones keep their meaning.
+const (
+	exitOK         = 0
+	exitError      = 1 // bad flags, unreadable input, unwritable output, anything else
+	exitParse      = 2 // WGSL syntax errors and unavailable language features
+	exitValidation = 3 // lowering, IR validation and -link errors, and warnings with -Werror
+	exitBackend    = 4 // code generation failures
+)
+
+// Diagnostic codes of -format json, stable like the exit statuses.
+const (
+	codeError      = "error"      // an error outside the stages below, such as I/O
+	codeParse      = "parse"      // WGSL syntax error
+	codeLower      = "lower"      // WGSL semantic error: names, types, declarations
+	codeValidation = "validation" // IR validation error
+	codeLink       = "link"       // vertex output and fragment input mismatch
+	codeBackend    = "backend"    // code generation failure
+	codeWarning    = "warning"    // lowering warning; an error with -Werror
+)
+
+// jsonReportVersion is the version of the -format json document. It is
+// bumped whenever a field is removed, renamed, or changes meaning.
+const jsonReportVersion = 1
+
+// jsonReport is the document -format json writes to stderr once nagac
+// is done, whether it succeeded or not.
+type jsonReport struct {
+	Version     int              `json:"version"`
+	ExitCode    int              `json:"exitCode"`
+	Diagnostics []jsonDiagnostic `json:"diagnostics"`
+}
+
+// jsonDiagnostic is one error or warning. Lines and columns are 1-based,
+// with columns counted in runes; they are 0 when the message has no
+// location. Related lists other places the message refers to, such as
+// the second of two aliasing arguments.
+type jsonDiagnostic struct {
+	File      string         `json:"file"`
+	Line      int            `json:"line"`
+	Column    int            `json:"column"`
+	EndLine   int            `json:"endLine,omitempty"`
+	EndColumn int            `json:"endColumn,omitempty"`
+	Severity  string         `json:"severity"`
+	Code      string         `json:"code"`
+	Message   string         `json:"message"`
+	Related   []jsonLocation `json:"related,omitempty"`
+}
+
+// jsonLocation is a related place in the source of a jsonDiagnostic.
+type jsonLocation struct {
+	File      string `json:"file"`
+	Line      int    `json:"line"`
+	Column    int    `json:"column"`
+	EndLine   int    `json:"endLine,omitempty"`
+	EndColumn int    `json:"endColumn,omitempty"`
+	Message   string `json:"message,omitempty"`
+}
+
+// failure is an error with the exit status and diagnostic code of the
+// stage it stopped nagac in. File, when set, names the source the error
+// is located in instead of the input.
+type failure struct {
+	status int
+	code   string
+	file   string
+	err    error
+}
+
+func (f *failure) Error() string { return f.err.Error() }
+func (f *failure) Unwrap() error { return f.err }
+
+// passFailure classifies an error returned by the compile pass pipeline
+// from the name of the pass that failed.
+func passFailure(pass string, err error) *failure {
+	switch pass {
+	case naga.PassParse:
+		return &failure{status: exitParse, code: codeParse, err: err}
+	case naga.PassLower:
+		return &failure{status: exitValidation, code: codeLower, err: err}
+	case naga.PassValidate:
+		return &failure{status: exitValidation, code: codeValidation, err: err}
+	case naga.PassLink:
+		return &failure{status: exitValidation, code: codeLink, err: err}
+	case naga.PassSPIRV:
+		return &failure{status: exitBackend, code: codeBackend, err: err}
+	}
+	// Among others, an -entry name the shader does not have.
+	return &failure{status: exitError, code: codeError, err: err}
+}
+
+// reporter writes nagac's diagnostics to stderr in the -format format:
+// text as they happen, or json as one document when nagac exits.
+type reporter struct {
+	w      io.Writer
+	format string
+	file   string
+	source string
+	diags  []jsonDiagnostic
+}
+
+// warn reports lowering warnings: in text format as lines printed at
+// once, with -format json as diagnostics of the document.
+func (r *reporter) warn(warnings []wgsl.Warning) {
+	for _, w := range warnings {
+		if r.format == "json" {
+			r.diags = append(r.diags, r.located("warning", codeWarning, w.Message, w.Span))
+			continue
+		}
+		fmt.Fprintf(r.w, "%s:%d:%d: warning: %s\n", r.file, w.Span.Start.Line, w.Span.Start.Column, w.Message)
+	}
+}
+
+// fail reports err, prefixed with what failed in text format, and exits
+// with the status of the stage it comes from.
+func (r *reporter) fail(prefix string, err error) {
+	var f *failure
+	if !errors.As(err, &f) {
+		f = &failure{status: exitError, code: codeError, err: err}
+	}
+	if r.format != "json" {
+		fmt.Fprintf(r.w, "%s: %v\n", prefix, err)
+		os.Exit(f.status)
+	}
+	if errors.As(err, new(*naga.WarningsError)) {
+		// The warnings come back as errors; do not list them twice.
+		r.diags = nil
+	}
+	r.diags = append(r.diags, r.errorDiagnostics(f)...)
+	r.exit(f.status)
+}
+
+// exit writes the json document, if any, and exits with status.
+func (r *reporter) exit(status int) {
+	if r.format == "json" {
+		diags := r.diags
+		if diags == nil {
+			diags = []jsonDiagnostic{}
+		}
+		data, err := json.Marshal(jsonReport{Version: jsonReportVersion, ExitCode: status, Diagnostics: diags})
+		if err == nil {
+			fmt.Fprintf(r.w, "%s\n", data)
+		}
+	}
+	os.Exit(status)
+}
+
+// errorDiagnostics returns the diagnostics f carries: one for each error
+// the frontend located, the spans of a validation error, each stage
+// mismatch or warning, or else one unlocated diagnostic.
+func (r *reporter) errorDiagnostics(f *failure) []jsonDiagnostic {
+	file, source := r.file, r.source
+	if f.file != "" {
+		file, source = f.file, ""
+	}
+	at := func(msg string, span wgsl.Span) jsonDiagnostic {
+		d := r.located("error", f.code, msg, span)
+		d.File = file
+		return d
+	}
+
+	var (
+		warnErr    *naga.WarningsError
+		linkErr    *naga.LinkError
+		featureErr *naga.LanguageFeatureError
+		validErr   *ir.ValidationError
+	)
+	switch {
+	case errors.As(f.err, &warnErr):
+		diags := make([]jsonDiagnostic, len(warnErr.Warnings))
+		for i, w := range warnErr.Warnings {
+			diags[i] = at(w.Message, w.Span)
+			diags[i].Code = codeWarning
+		}
+		return diags
+	case errors.As(f.err, &linkErr):
+		diags := make([]jsonDiagnostic, len(linkErr.Mismatches))
+		for i, m := range linkErr.Mismatches {
+			diags[i] = at(m.Message, m.FragmentSpan)
+			if m.VertexSpan.Start.Line > 0 {
+				diags[i].Related = []jsonLocation{location(file, m.VertexSpan, "vertex output declared here")}
+			}
+		}
+		return diags
+	case errors.As(f.err, &featureErr):
+		pos := wgsl.Position{Line: featureErr.Line, Column: featureErr.Column}
+		msg := fmt.Sprintf("shader requires language feature '%s', which is not enabled", featureErr.Feature)
+		return []jsonDiagnostic{at(msg, wgsl.Span{Start: pos, End: pos})}
+	case errors.As(f.err, &validErr):
+		d := at(validErr.Error(), wgsl.Span{})
+		for i, span := range validErr.Spans {
+			s := wgsl.SourceSpan(source, span)
+			if i == 0 {
+				d = at(validErr.Error(), s)
+			} else {
+				d.Related = append(d.Related, location(file, s, ""))
+			}
+		}
+		return []jsonDiagnostic{d}
+	}
+	if located := wgsl.Errors(f.err); len(located) > 0 {
+		diags := make([]jsonDiagnostic, len(located))
+		for i, e := range located {
+			diags[i] = at(e.Message, e.Span)
+		}
+		return diags
+	}
+	return []jsonDiagnostic{at(f.err.Error(), wgsl.Span{})}
+}
+
+// located returns a diagnostic in the input file at span.
+func (r *reporter) located(severity, code, msg string, span wgsl.Span) jsonDiagnostic {
+	loc := location(r.file, span, "")
+	return jsonDiagnostic{
+		File:      loc.File,
+		Line:      loc.Line,
+		Column:    loc.Column,
+		EndLine:   loc.EndLine,
+		EndColumn: loc.EndColumn,
+		Severity:  severity,
+		Code:      code,
+		Message:   msg,
+	}
+}
+
+// location returns span of file as a jsonLocation, leaving out an end
+// that is not after the start.
+func location(file string, span wgsl.Span, msg string) jsonLocation {
+	loc := jsonLocation{File: file, Line: span.Start.Line, Column: span.Start.Column, Message: msg}
+	if end := span.End; end.Line > span.Start.Line || end.Line == span.Start.Line && end.Column > span.Start.Column {
+		loc.EndLine, loc.EndColumn = end.Line, end.Column
+	}
+	return loc
+}
@@ -0,0 +1,89 @@
+// Copyright 2025 The GoGPU Authors
+// SPDX-License-Identifier: MIT
+
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/gogpu/naga"
+)
+
+// compileFailure runs the compile pipeline on source and classifies its
+// error as main does.
+func compileFailure(t *testing.T, source string) *failure {
+	t.Helper()
+	_, stats, err := naga.NewPassManager().Run(source, naga.DefaultOptions())
+	if err == nil {
+		t.Fatal("compilation succeeded")
+	}
+	return passFailure(stats.Passes[len(stats.Passes)-1].Name, err)
+}
+
+func TestFailureStatus(t *testing.T) {
+	tests := []struct {
+		name, source string
+		status       int
+		code         string
+	}{
+		{"parse", "fn f( {", exitParse, codeParse},
+		{"lower", "fn f() -> u32 { return nope; }", exitValidation, codeLower},
+		{"validation", `
+@group(0) @binding(0) var<storage> buf: array<u32>;
+@compute @workgroup_size(1)
+fn main() { buf[0] = 1u; }
+`, exitValidation, codeValidation},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := compileFailure(t, tt.source)
+			if f.status != tt.status || f.code != tt.code {
+				t.Errorf("status %d, code %q; want %d, %q", f.status, f.code, tt.status, tt.code)
+			}
+		})
+	}
+}
+
+func TestErrorDiagnostics(t *testing.T) {
+	const source = `fn swap(x: ptr<function, f32>, y: ptr<function, f32>) { *x = *y; }
+
+@compute @workgroup_size(1)
+fn main() {
+    var a: array<f32, 2>;
+    swap(&a[1], &a[1]);
+}
+`
+	r := &reporter{format: "json", file: "s.wgsl", source: source}
+	diags := r.errorDiagnostics(compileFailure(t, source))
+	if len(diags) != 1 {
+		t.Fatalf("got %d diagnostics, want 1: %+v", len(diags), diags)
+	}
+	d := diags[0]
+	if d.Severity != "error" || d.Code != codeValidation || !strings.Contains(d.Message, "may overlap") {
+		t.Errorf("diagnostic = %+v", d)
+	}
+	if strings.Contains(d.Message, "(at [") {
+		t.Errorf("message %q repeats the byte spans", d.Message)
+	}
+	if d.File != "s.wgsl" || d.Line != 6 || d.Column != 11 || d.EndColumn != 15 {
+		t.Errorf("location = %s:%d:%d-%d, want s.wgsl:6:11-15", d.File, d.Line, d.Column, d.EndColumn)
+	}
+	if len(d.Related) != 1 || d.Related[0].Line != 6 || d.Related[0].Column != 18 {
+		t.Errorf("related = %+v, want the second argument at 6:18", d.Related)
+	}
+}
+
+func TestErrorDiagnosticsLowering(t *testing.T) {
+	const source = "fn f() -> u32 { return nope; }\nfn g() -> u32 { return nope2; }\n"
+	r := &reporter{format: "json", file: "s.wgsl", source: source}
+	diags := r.errorDiagnostics(compileFailure(t, source))
+	if len(diags) != 2 {
+		t.Fatalf("got %d diagnostics, want one per function: %+v", len(diags), diags)
+	}
+	for i, d := range diags {
+		if d.Code != codeLower || d.Line != i+1 || d.Column != 24 {
+			t.Errorf("diagnostic %d = %+v", i, d)
+		}
+	}
+}
@@ -9,23 +9,94 @@
 //	nagac shader.wgsl                    # Parse and validate
 //	nagac -o shader.spv shader.wgsl      # Compile to SPIR-V
 //	nagac -debug shader.wgsl             # Compile with debug info
+//	nagac -debug -debug-names shader.wgsl  # Also name every intermediate result
+//	nagac -vertex-layout vs_main shader.wgsl  # Print vertex buffer layout as JSON
+//	nagac -Werror shader.wgsl            # Fail on any warning
+//	nagac -link vs_main:fs_main shader.wgsl  # Check vertex outputs against fragment inputs
+//	nagac -stats -o shader.spv shader.wgsl    # Print pass timings and counters to stderr
+//	nagac -report-features msl shader.wgsl    # Print the GPU features the shader needs on MSL
+//	nagac -reflect shader.json shader.wgsl    # Write reflection JSON (bindings, layouts, ...)
+//	nagac -watch shaders/ -target msl -outdir build/  # Recompile WGSL files as they change
+//	nagac -features SHADOWS,FOG -o lit.spv lit.wgsl  # Keep @if(SHADOWS) and @if(FOG) code
+//	nagac -sourcemap -o shader.spv shader.wgsl  # Also write shader.spv.map.json
+//	nagac -O -o shader.spv shader.wgsl   # Turn select chains into min/max/clamp
+//	nagac -share-arrays 16 -o post.spv post.wgsl  # Write repeated lookup tables once
+//	nagac -format json -o shader.spv shader.wgsl  # Errors and warnings as one JSON document
+//	nagac -entry vs_main -o vs.spv shader.wgsl  # Keep only vs_main and what it uses
+//
+// # Exit status
+//
+// nagac exits 0 on success, 2 when the WGSL does not parse or requires a
+// language feature that is not enabled, 3 when it does not lower,
+// validate, or link (-link), or has warnings with -Werror, 4 when code
+// generation fails, and 1 for any other failure, such as bad flags, an
+// unreadable input, or an -entry name the shader does not have. -watch
+// exits 1 on any error.
+//
+// # Diagnostics
+//
+// With -format json, nagac writes one JSON document to stderr when it
+// is done, instead of text messages as they happen:
+//
+//	{"version": 1, "exitCode": 3, "diagnostics": [{
+//	    "file": "shader.wgsl", "line": 12, "column": 5, "endLine": 12, "endColumn": 14,
+//	    "severity": "error", "code": "validation", "message": "...",
+//	    "related": [{"file": "shader.wgsl", "line": 12, "column": 16, ...}]}]}
+//
+// severity is error or warning. code is one of parse, lower, validation,
+// link, backend, warning (a lowering warning, an error with -Werror), or
+// error for anything else. Lines and columns are 1-based, columns counted
+// in runes, and 0 when a message has no location; related lists other
+// places a message refers to. Warnings are included unless -w. The
+// version is bumped whenever a field is removed, renamed, or changes
+// meaning; codes and exit statuses may be added but keep their meaning.
 package main
 
 import (
+	"encoding/json"
+	"errors"
 	"flag"
 	"fmt"
+	"io"
 	"os"
+	"path/filepath"
 	"runtime/debug"
+	"strings"
 
 	"github.com/gogpu/naga"
+	"github.com/gogpu/naga/analysis"
+	"github.com/gogpu/naga/ir"
+	"github.com/gogpu/naga/reflection"
+	"github.com/gogpu/naga/sourcemap"
 	"github.com/gogpu/naga/spirv"
 )
 
 var (
-	output      = flag.String("o", "", "output file (default: stdout)")
-	debugFlag   = flag.Bool("debug", false, "include debug info")
-	validate    = flag.Bool("validate", true, "validate IR")
-	versionFlag = flag.Bool("version", false, "print version")
+	output        = flag.String("o", "", "output file (default: stdout)")
+	debugFlag     = flag.Bool("debug", false, "include debug info")
+	debugNames    = flag.Bool("debug-names", false, "with -debug, name intermediate results after their WGSL expression text")
+	validate      = flag.Bool("validate", true, "validate IR")
+	versionFlag   = flag.Bool("version", false, "print version")
+	vertexLayout  = flag.String("vertex-layout", "", "print the vertex buffer layout of this entry point as JSON instead of compiling")
+	vertexPacking = flag.String("vertex-packing", "interleaved", "vertex layout packing: interleaved or separate")
+	noWarn        = flag.Bool("w", false, "do not report warnings")
+	warnError     = flag.Bool("Werror", false, "treat warnings as errors")
+	strictFlag    = flag.Bool("strict", false, "reject source the WGSL spec does not allow instead of warning and lowering it to a default")
+	statsFlag     = flag.Bool("stats", false, "print per-pass timing and module/binary counters to stderr")
+	linkStages    = flag.String("link", "", "check that vertex outputs match fragment inputs, as vertex:fragment entry point names")
+	reportTarget  = flag.String("report-features", "", "print the GPU features the shader needs on this target (spirv, msl, hlsl, glsl) instead of compiling")
+	watchDir      = flag.String("watch", "", "watch this directory and recompile its WGSL files as they change")
+	watchTarget   = flag.String("target", "spirv", "-watch output language: spirv, msl, hlsl, or glsl")
+	outDir        = flag.String("outdir", "", "-watch output directory (default: next to the sources)")
+	reflectPath   = flag.String("reflect", "", "write reflection JSON to this file (- for stdout); compiles too only when -o is set")
+	diffPath      = flag.String("diff", "", "print how the reflection of the input differs from this older version of it, and whether pipelines need rebuilding, instead of compiling")
+	featureList   = flag.String("features", "", "comma-separated feature names that @if conditions treat as set")
+	optimize      = flag.Bool("O", false, "replace comparison and select chains with min, max and clamp (integer patterns only without -finite-math)")
+	finiteMath    = flag.Bool("finite-math", false, "with -O, also rewrite float patterns, assuming no NaN reaches them")
+	shareArrays   = flag.Uint("share-arrays", 0, "move constant arrays of at least this many elements built in functions into shared module constants (0 disables)")
+	sourceMapFlag = flag.Bool("sourcemap", false, "also write a JSON source map back to the WGSL next to each output, as <output>.map.json")
+	entryList     = flag.String("entry", "", "comma-separated entry point names to keep in the output, with the functions and globals they use; the others are stripped")
+	diagFormat    = flag.String("format", "text", "diagnostics output on stderr: text, or json for one document with every error and warning")
 )
 
 // version returns the module version from build info.
@@ -47,49 +118,352 @@ func main() {
 		return
 	}
 
+	if *diagFormat != "text" && *diagFormat != "json" {
+		fmt.Fprintf(os.Stderr, "Error: unknown diagnostics format %q (want text or json)\n", *diagFormat)
+		os.Exit(exitError)
+	}
+
+	if *watchDir != "" {
+		if err := runWatch(*watchDir, *watchTarget, *outDir); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(exitError)
+		}
+		return
+	}
+
+	r := &reporter{w: os.Stderr, format: *diagFormat}
 	args := flag.Args()
 	if len(args) < 1 {
-		fmt.Fprintln(os.Stderr, "Error: no input file specified")
-		usage()
-		os.Exit(1)
+		if r.format == "text" {
+			fmt.Fprintln(os.Stderr, "Error: no input file specified")
+			usage()
+			os.Exit(exitError)
+		}
+		r.fail("Error", errors.New("no input file specified"))
 	}
 
 	inputPath := args[0]
+	r.file = inputPath
 
 	// Read input file
 	source, err := os.ReadFile(inputPath)
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "Error reading file: %v\n", err)
-		os.Exit(1)
+		r.fail("Error reading file", err)
+	}
+	r.source = string(source)
+
+	if *vertexLayout != "" {
+		if err := writeVertexLayout(string(source), *vertexLayout, *vertexPacking); err != nil {
+			r.fail("Error", err)
+		}
+		r.exit(exitOK)
+	}
+
+	if *reportTarget != "" {
+		if err := writeFeatureReport(os.Stdout, string(source), reflection.Target(*reportTarget)); err != nil {
+			r.fail("Error", err)
+		}
+		r.exit(exitOK)
+	}
+
+	if *diffPath != "" {
+		oldSource, err := os.ReadFile(*diffPath)
+		if err != nil {
+			r.fail("Error reading file", err)
+		}
+		if err := writeReflectionDiff(os.Stdout, *diffPath, string(oldSource), string(source)); err != nil {
+			r.fail("Error", err)
+		}
+		r.exit(exitOK)
+	}
+
+	if *reflectPath != "" {
+		if err := writeReflection(*reflectPath, string(source)); err != nil {
+			r.fail("Error", err)
+		}
+		if *output == "" {
+			r.exit(exitOK)
+		}
+	}
+
+	if *sourceMapFlag && *output == "" {
+		r.fail("Error", errors.New("-sourcemap needs -o"))
 	}
 
 	// Compile WGSL to SPIR-V
 	opts := naga.CompileOptions{
-		SPIRVVersion: spirv.Version1_3,
-		Debug:        *debugFlag,
-		Validate:     *validate,
+		SPIRVVersion:         spirv.Version1_3,
+		Debug:                *debugFlag,
+		DebugExpressionNames: *debugNames,
+		Validate:             *validate,
+		WarningsAsErrors:     *warnError,
+		Strict:               *strictFlag,
+		Features:             featureSet(),
+		SourceMap:            *sourceMapFlag,
+		Peephole:             peepholeOptions(),
+		ShareConstantArrays:  uint32(*shareArrays),
+		EntryPoints:          splitList(*entryList),
+	}
+	if *linkStages != "" {
+		vs, fs, ok := strings.Cut(*linkStages, ":")
+		if !ok || vs == "" || fs == "" {
+			r.fail("Error", fmt.Errorf("-link wants vertex:fragment, got %q", *linkStages))
+		}
+		opts.LinkStages = &naga.StageLink{Vertex: vs, Fragment: fs}
+	}
+	state, stats, err := naga.NewPassManager().Run(string(source), opts)
+	// Warnings come from the lower pass of this run, so they reflect -strict
+	// and -features. They are reported even when a later pass fails; with
+	// -Werror the lower pass fails with them instead.
+	if !*noWarn {
+		r.warn(state.Warnings)
+	}
+	if *statsFlag {
+		writeStats(os.Stderr, stats, state.Module)
 	}
-	spirvBytes, err := naga.CompileWithOptions(string(source), opts)
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "Compilation error: %v\n", err)
-		os.Exit(1)
+		// The last pass that ran is the one that failed.
+		var pass string
+		if n := len(stats.Passes); n > 0 {
+			pass = stats.Passes[n-1].Name
+		}
+		r.fail("Compilation error", passFailure(pass, err))
 	}
+	spirvBytes := state.SPIRV
 
 	// Write output
 	if *output != "" {
 		err = os.WriteFile(*output, spirvBytes, 0644)
 		if err != nil {
-			fmt.Fprintf(os.Stderr, "Error writing output: %v\n", err)
-			os.Exit(1)
+			r.fail("Error writing output", err)
+		}
+		if *sourceMapFlag {
+			data, err := sourceMapJSON(naga.TargetSPIRV, *output, inputPath, string(source), state.SourceMap)
+			if err == nil {
+				err = os.WriteFile(*output+".map.json", data, 0644)
+			}
+			if err != nil {
+				r.fail("Error writing source map", err)
+			}
 		}
 		fmt.Printf("Successfully compiled %s to %s (%d bytes)\n", inputPath, *output, len(spirvBytes))
 	} else {
 		_, err = os.Stdout.Write(spirvBytes)
 		if err != nil {
-			fmt.Fprintf(os.Stderr, "Error writing output: %v\n", err)
-			os.Exit(1)
+			r.fail("Error writing output", err)
+		}
+	}
+	r.exit(exitOK)
+}
+
+// peepholeOptions returns the ir.Peephole options -O selects, or nil
+// without -O.
+func peepholeOptions() *ir.PeepholeOptions {
+	if !*optimize {
+		return nil
+	}
+	return &ir.PeepholeOptions{FiniteMath: *finiteMath}
+}
+
+// featureSet returns the -features names as the set @if conditions test.
+func featureSet() map[string]bool {
+	set := make(map[string]bool)
+	for _, name := range splitList(*featureList) {
+		set[name] = true
+	}
+	return set
+}
+
+// splitList returns the names of a comma-separated flag value, leaving
+// out empty ones.
+func splitList(list string) []string {
+	var names []string
+	for _, name := range strings.Split(list, ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// sourceMapJSON returns the source map of the target file output, compiled
+// from the WGSL file input, as indented JSON. File names are recorded
+// relative to the map, which is written next to output.
+func sourceMapJSON(target naga.Target, output, input, source string, spans []ir.OutputSpan) ([]byte, error) {
+	m := sourcemap.New(target.String(), source, spans)
+	m.File = filepath.Base(output)
+	m.Source = input
+	if abs, err := filepath.Abs(input); err == nil {
+		if dir, err := filepath.Abs(filepath.Dir(output)); err == nil {
+			if rel, err := filepath.Rel(dir, abs); err == nil {
+				m.Source = filepath.ToSlash(rel)
+			}
+		}
+	}
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append(data, '\n'), nil
+}
+
+// writeStats prints per-pass timings and counters in aligned columns,
+// then the complexity metrics of each entry point and function of module.
+func writeStats(w io.Writer, stats *naga.Stats, module *ir.Module) {
+	for _, p := range stats.Passes {
+		fmt.Fprintf(w, "%-12s %10s\n", p.Name, p.Duration)
+	}
+	fmt.Fprintf(w, "%-12s %10s\n", "total", stats.Total)
+	fmt.Fprintf(w, "functions %d, entry points %d, types %d, constants %d, globals %d\n",
+		stats.Functions, stats.EntryPoints, stats.Types, stats.Constants, stats.GlobalVariables)
+	fmt.Fprintf(w, "expressions %d, global expressions %d\n", stats.Expressions, stats.GlobalExpressions)
+	fmt.Fprintf(w, "spir-v instructions %d, words %d\n", stats.SPIRVInstructions, stats.SPIRVWords)
+	if module == nil {
+		return
+	}
+
+	// Op counts and loop depth include called functions, as if inlined.
+	report := analysis.Complexity(module)
+	fmt.Fprintf(w, "%-24s %6s %6s %6s %6s %6s %6s %6s\n", "function", "exprs", "stmts", "alu", "tex", "mem", "calls", "loops")
+	row := func(name string, m analysis.Metrics) {
+		fmt.Fprintf(w, "%-24s %6d %6d %6d %6d %6d %6d %6d\n",
+			name, m.Expressions, m.Statements, m.ALUOps, m.TextureOps, m.MemoryOps, m.Calls, m.LoopDepth)
+	}
+	for _, m := range report.EntryPoints {
+		row(m.Name+" (entry)", m)
+	}
+	for _, m := range report.Functions {
+		row(m.Name, m)
+	}
+}
+
+// writeVertexLayout lowers source and writes the vertex buffer layout of
+// entryPoint as indented JSON to -o or stdout.
+func writeVertexLayout(source, entryPoint, packing string) error {
+	var opts reflection.VertexLayoutOptions
+	switch packing {
+	case "interleaved":
+		opts.Packing = reflection.PackingInterleaved
+	case "separate":
+		opts.Packing = reflection.PackingSeparate
+	default:
+		return fmt.Errorf("unknown vertex packing %q (want interleaved or separate)", packing)
+	}
+
+	module, err := lowerSource(source)
+	if err != nil {
+		return err
+	}
+	layout, err := reflection.VertexLayout(module, entryPoint, opts)
+	if err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(layout, "", "  ")
+	if err != nil {
+		return err
+	}
+	data = append(data, '\n')
+
+	if *output != "" {
+		return os.WriteFile(*output, data, 0644)
+	}
+	_, err = os.Stdout.Write(data)
+	return err
+}
+
+// writeReflection lowers source and writes its reflection document as
+// indented JSON to path, or to stdout when path is "-".
+func writeReflection(path, source string) error {
+	doc, err := describeSource(source)
+	if err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return err
+	}
+	data = append(data, '\n')
+
+	if path == "-" {
+		_, err = os.Stdout.Write(data)
+		return err
+	}
+	return os.WriteFile(path, data, 0644)
+}
+
+// describeSource lowers source and builds its reflection Document.
+func describeSource(source string) (*reflection.Document, error) {
+	module, err := lowerSource(source)
+	if err != nil {
+		return nil, err
+	}
+	return reflection.Describe(module)
+}
+
+// lowerSource parses source with the -features set and lowers it. Its
+// errors are failures of the stage they come from.
+func lowerSource(source string) (*ir.Module, error) {
+	ast, err := naga.ParseWithFeatures(source, featureSet())
+	if err != nil {
+		return nil, passFailure(naga.PassParse, err)
+	}
+	module, err := naga.LowerWithSource(ast, source)
+	if err != nil {
+		return nil, passFailure(naga.PassLower, err)
+	}
+	return module, nil
+}
+
+// writeReflectionDiff writes the reflection changes from oldSource, read
+// from oldPath, to source, one per line, followed by what a hot reload of
+// source needs.
+func writeReflectionDiff(w io.Writer, oldPath, oldSource, source string) error {
+	oldDoc, err := describeSource(oldSource)
+	if err != nil {
+		var f *failure
+		if errors.As(err, &f) {
+			f.file = oldPath
+		}
+		return fmt.Errorf("old version: %w", err)
+	}
+	doc, err := describeSource(source)
+	if err != nil {
+		return err
+	}
+	diff := reflection.Diff(oldDoc, doc)
+	verdict := "shader module swap"
+	switch {
+	case diff.Empty():
+		verdict = "no interface changes; shader module swap"
+	case diff.NeedsPipelineRebuild():
+		verdict = "pipeline rebuild"
+	}
+	_, err = fmt.Fprintf(w, "%sreload: %s\n", diff, verdict)
+	return err
+}
+
+// writeFeatureReport lowers source and writes one line per feature it
+// needs on target: the feature, its detail if any, what the target
+// requires for it, and the entry points using it.
+func writeFeatureReport(w io.Writer, source string, target reflection.Target) error {
+	module, err := lowerSource(source)
+	if err != nil {
+		return err
+	}
+	reqs, err := reflection.RequiredFeatures(module, target)
+	if err != nil {
+		return err
+	}
+	for _, r := range reqs {
+		name := string(r.Feature)
+		if r.Detail != "" {
+			name += " " + r.Detail
+		}
+		if _, err := fmt.Fprintf(w, "%-32s %s [%s]\n", name, r.Requirement, strings.Join(r.EntryPoints, ", ")); err != nil {
+			return err
 		}
 	}
+	return nil
 }
 
 func usage() {
@@ -100,4 +474,20 @@ func usage() {
 	fmt.Fprintf(os.Stderr, "  nagac shader.wgsl               Compile to stdout\n")
 	fmt.Fprintf(os.Stderr, "  nagac -o shader.spv shader.wgsl Compile to file\n")
 	fmt.Fprintf(os.Stderr, "  nagac -debug shader.wgsl        Include debug info\n")
+	fmt.Fprintf(os.Stderr, "  nagac -debug -debug-names shader.wgsl  Name intermediate results after their WGSL text\n")
+	fmt.Fprintf(os.Stderr, "  nagac -vertex-layout vs_main shader.wgsl  Print vertex buffer layout JSON\n")
+	fmt.Fprintf(os.Stderr, "  nagac -Werror shader.wgsl       Fail on any warning\n")
+	fmt.Fprintf(os.Stderr, "  nagac -link vs_main:fs_main shader.wgsl  Check vertex/fragment interface\n")
+	fmt.Fprintf(os.Stderr, "  nagac -stats -o shader.spv shader.wgsl  Print pass timings and counters\n")
+	fmt.Fprintf(os.Stderr, "  nagac -report-features msl shader.wgsl  List required GPU features\n")
+	fmt.Fprintf(os.Stderr, "  nagac -features SHADOWS,FOG shader.wgsl  Compile the variant with these @if features\n")
+	fmt.Fprintf(os.Stderr, "  nagac -reflect shader.json -o shader.spv shader.wgsl  Compile and write reflection JSON\n")
+	fmt.Fprintf(os.Stderr, "  nagac -diff old/shader.wgsl shader.wgsl  Show what a hot reload of shader.wgsl changes\n")
+	fmt.Fprintf(os.Stderr, "  nagac -watch shaders/ -target spirv -outdir build/  Recompile on change\n")
+	fmt.Fprintf(os.Stderr, "  nagac -sourcemap -o shader.spv shader.wgsl  Also write shader.spv.map.json\n")
+	fmt.Fprintf(os.Stderr, "  nagac -O -finite-math -o shader.spv shader.wgsl  Turn select chains into min/max/clamp\n")
+	fmt.Fprintf(os.Stderr, "  nagac -share-arrays 16 -o post.spv post.wgsl  Write repeated lookup tables once\n")
+	fmt.Fprintf(os.Stderr, "  nagac -format json -o shader.spv shader.wgsl  Report errors and warnings as JSON\n")
+	fmt.Fprintf(os.Stderr, "  nagac -entry vs_main -o vs.spv shader.wgsl  Strip every entry point but vs_main\n")
+	fmt.Fprintf(os.Stderr, "\nExit status: 0 success, 2 parse error, 3 lowering/validation/link error, 4 code generation error, 1 other\n")
 }
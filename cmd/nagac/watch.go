@@ -0,0 +1,291 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gogpu/naga"
+	"github.com/gogpu/naga/glsl"
+	"github.com/gogpu/naga/hlsl"
+	"github.com/gogpu/naga/ir"
+	"github.com/gogpu/naga/msl"
+	"github.com/gogpu/naga/spirv"
+)
+
+// watchInterval is how often -watch polls the source tree for changes.
+const watchInterval = 300 * time.Millisecond
+
+// watcher recompiles the WGSL files under a directory as they change.
+// Files are polled by modification time and size, so it works on every
+// platform and file system without OS notification APIs.
+type watcher struct {
+	dir    string
+	outDir string
+	target naga.Target
+	log    io.Writer
+
+	// stamps records the last seen state of every source file.
+	stamps map[string]fileStamp
+}
+
+type fileStamp struct {
+	modTime time.Time
+	size    int64
+}
+
+// runWatch compiles every WGSL file under dir once, then recompiles files
+// as they change until interrupted. Each WGSL file is a self-contained
+// module, so a change never requires recompiling another file.
+func runWatch(dir, target, outDir string) error {
+	t, err := parseTarget(target)
+	if err != nil {
+		return err
+	}
+	if outDir == "" {
+		outDir = dir
+	}
+	w := &watcher{dir: dir, outDir: outDir, target: t, log: os.Stderr, stamps: make(map[string]fileStamp)}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	fmt.Fprintf(w.log, "watching %s for WGSL changes (%s -> %s), Ctrl-C to stop\n", dir, t, outDir)
+	ticker := time.NewTicker(watchInterval)
+	defer ticker.Stop()
+	for {
+		changed, err := w.scan()
+		if err != nil {
+			return err
+		}
+		for _, path := range changed {
+			w.compile(path)
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+func parseTarget(name string) (naga.Target, error) {
+	for _, t := range []naga.Target{naga.TargetSPIRV, naga.TargetMSL, naga.TargetHLSL, naga.TargetGLSL} {
+		if t.String() == name {
+			return t, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown target %q (want spirv, msl, hlsl, or glsl)", name)
+}
+
+// scan walks the source directory and returns, sorted, the WGSL files that
+// are new or changed since the last scan. Deleted files are forgotten.
+func (w *watcher) scan() ([]string, error) {
+	seen := make(map[string]bool, len(w.stamps))
+	var changed []string
+	err := filepath.WalkDir(w.dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() || filepath.Ext(path) != ".wgsl" {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			// Removed between listing and stat; the next scan drops it.
+			return nil
+		}
+		seen[path] = true
+		stamp := fileStamp{modTime: info.ModTime(), size: info.Size()}
+		if old, ok := w.stamps[path]; !ok || old != stamp {
+			w.stamps[path] = stamp
+			changed = append(changed, path)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	for path := range w.stamps {
+		if !seen[path] {
+			delete(w.stamps, path)
+		}
+	}
+	sort.Strings(changed)
+	return changed, nil
+}
+
+// compile builds one source file and prints a one-line result, or the
+// error, to the log. Failures do not stop the watcher.
+func (w *watcher) compile(path string) {
+	start := time.Now()
+	outputs, err := w.build(path)
+	if err != nil {
+		fmt.Fprintf(w.log, "%s: error: %v\n", path, err)
+		return
+	}
+	fmt.Fprintf(w.log, "%s: ok -> %s (%s)\n", path, strings.Join(outputs, ", "), time.Since(start).Round(time.Millisecond))
+}
+
+// build compiles path for the watcher's target and writes the results
+// under outDir, mirroring the source tree. It returns the written files.
+func (w *watcher) build(path string) ([]string, error) {
+	source, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	rel, err := filepath.Rel(w.dir, path)
+	if err != nil {
+		return nil, err
+	}
+	base := filepath.Join(w.outDir, strings.TrimSuffix(rel, ".wgsl"))
+
+	files := make(map[string][]byte)
+	// add records one output file, and its source map with -sourcemap.
+	add := func(name string, code []byte, spans []ir.OutputSpan) error {
+		files[name] = code
+		if !*sourceMapFlag {
+			return nil
+		}
+		data, err := sourceMapJSON(w.target, name, path, string(source), spans)
+		if err != nil {
+			return err
+		}
+		files[name+".map.json"] = data
+		return nil
+	}
+
+	if w.target == naga.TargetSPIRV {
+		state, _, err := naga.NewPassManager().Run(string(source), naga.CompileOptions{
+			SPIRVVersion:         spirv.Version1_3,
+			Debug:                *debugFlag,
+			DebugExpressionNames: *debugNames,
+			Validate:             *validate,
+			Features:             featureSet(),
+			SourceMap:            *sourceMapFlag,
+			Peephole:             peepholeOptions(),
+			ShareConstantArrays:  uint32(*shareArrays),
+		})
+		if err != nil {
+			return nil, err
+		}
+		if err := add(base+".spv", state.SPIRV, state.SourceMap); err != nil {
+			return nil, err
+		}
+		return writeOutputs(files)
+	}
+
+	ast, err := naga.ParseWithFeatures(string(source), featureSet())
+	if err != nil {
+		return nil, err
+	}
+	module, err := naga.LowerWithSource(ast, string(source))
+	if err != nil {
+		return nil, err
+	}
+	if *validate {
+		if errs, err := naga.Validate(module); err != nil {
+			return nil, err
+		} else if len(errs) > 0 {
+			return nil, fmt.Errorf("validation failed: %v", errs[0])
+		}
+	}
+	if *shareArrays > 0 {
+		module = ir.ShareConstantArrays(module, uint32(*shareArrays))
+		if *validate {
+			if errs, err := naga.Validate(module); err != nil {
+				return nil, err
+			} else if len(errs) > 0 {
+				return nil, fmt.Errorf("validation failed after constant array sharing: %v", errs[0])
+			}
+		}
+	}
+	if opts := peepholeOptions(); opts != nil {
+		module = ir.Peephole(module, *opts)
+		if *validate {
+			if errs, err := naga.Validate(module); err != nil {
+				return nil, err
+			} else if len(errs) > 0 {
+				return nil, fmt.Errorf("validation failed after peephole: %v", errs[0])
+			}
+		}
+	}
+
+	switch w.target {
+	case naga.TargetMSL:
+		opts := msl.DefaultOptions()
+		opts.SourceMap = *sourceMapFlag
+		code, info, err := msl.Compile(module, opts)
+		if err != nil {
+			return nil, err
+		}
+		if err := add(base+".metal", []byte(code), info.SourceMap); err != nil {
+			return nil, err
+		}
+	case naga.TargetHLSL:
+		opts := hlsl.DefaultOptions()
+		opts.SourceMap = *sourceMapFlag
+		code, info, err := hlsl.Compile(module, opts)
+		if err != nil {
+			return nil, err
+		}
+		if err := add(base+".hlsl", []byte(code), info.SourceMap); err != nil {
+			return nil, err
+		}
+	case naga.TargetGLSL:
+		// GLSL has one entry point per shader, so each gets its own file.
+		for _, ep := range module.EntryPoints {
+			opts := glsl.DefaultOptions()
+			opts.EntryPoint = ep.Name
+			opts.SourceMap = *sourceMapFlag
+			code, info, err := glsl.Compile(module, opts)
+			if err != nil {
+				return nil, fmt.Errorf("entry point %q: %w", ep.Name, err)
+			}
+			if err := add(base+"."+ep.Name+glslExtension(ep.Stage), []byte(code), info.SourceMap); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return writeOutputs(files)
+}
+
+// glslExtension returns the conventional GLSL file extension for stage.
+func glslExtension(stage ir.ShaderStage) string {
+	switch stage {
+	case ir.StageVertex:
+		return ".vert"
+	case ir.StageFragment:
+		return ".frag"
+	case ir.StageTask:
+		return ".task"
+	case ir.StageMesh:
+		return ".mesh"
+	default:
+		return ".comp"
+	}
+}
+
+// writeOutputs writes files, creating directories as needed, and returns
+// their paths sorted.
+func writeOutputs(files map[string][]byte) ([]string, error) {
+	paths := make([]string, 0, len(files))
+	for path, data := range files {
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			return nil, err
+		}
+		if err := os.WriteFile(path, data, 0644); err != nil {
+			return nil, err
+		}
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	return paths, nil
+}
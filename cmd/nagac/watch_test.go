@@ -0,0 +1,165 @@
+// Copyright 2025 The GoGPU Authors
+// SPDX-License-Identifier: MIT
+
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gogpu/naga"
+	"github.com/gogpu/naga/ir"
+)
+
+const watchShader = `@vertex
+fn vs() -> @builtin(position) vec4<f32> { return vec4<f32>(0.0); }
+
+@fragment
+fn fs() -> @location(0) vec4<f32> { return vec4<f32>(1.0); }
+`
+
+func writeSource(t *testing.T, path, source string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(source), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func newTestWatcher(dir, outDir string, target naga.Target, log *bytes.Buffer) *watcher {
+	return &watcher{dir: dir, outDir: outDir, target: target, log: log, stamps: make(map[string]fileStamp)}
+}
+
+func TestWatcherScan(t *testing.T) {
+	dir := t.TempDir()
+	a := filepath.Join(dir, "a.wgsl")
+	b := filepath.Join(dir, "sub", "b.wgsl")
+	writeSource(t, a, watchShader)
+	writeSource(t, b, watchShader)
+	writeSource(t, filepath.Join(dir, "notes.txt"), "not a shader")
+
+	w := newTestWatcher(dir, dir, naga.TargetSPIRV, &bytes.Buffer{})
+	changed, err := w.scan()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{a, b}; !reflect.DeepEqual(changed, want) {
+		t.Fatalf("first scan = %v, want %v", changed, want)
+	}
+	if changed, _ := w.scan(); len(changed) != 0 {
+		t.Fatalf("unchanged tree reported %v", changed)
+	}
+
+	later := time.Now().Add(time.Minute)
+	if err := os.Chtimes(b, later, later); err != nil {
+		t.Fatal(err)
+	}
+	if changed, _ := w.scan(); !reflect.DeepEqual(changed, []string{b}) {
+		t.Fatalf("after touching b: %v, want [%s]", changed, b)
+	}
+
+	if err := os.Remove(a); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := w.scan(); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := w.stamps[a]; ok {
+		t.Error("deleted file is still tracked")
+	}
+	writeSource(t, a, watchShader)
+	if changed, _ := w.scan(); !reflect.DeepEqual(changed, []string{a}) {
+		t.Fatalf("recreated file: %v, want [%s]", changed, a)
+	}
+}
+
+func TestWatcherBuild(t *testing.T) {
+	tests := []struct {
+		target naga.Target
+		want   []string
+	}{
+		{naga.TargetSPIRV, []string{"sub/s.spv"}},
+		{naga.TargetMSL, []string{"sub/s.metal"}},
+		{naga.TargetHLSL, []string{"sub/s.hlsl"}},
+		{naga.TargetGLSL, []string{"sub/s.fs.frag", "sub/s.vs.vert"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.target.String(), func(t *testing.T) {
+			dir, outDir := t.TempDir(), t.TempDir()
+			path := filepath.Join(dir, "sub", "s.wgsl")
+			writeSource(t, path, watchShader)
+
+			w := newTestWatcher(dir, outDir, tt.target, &bytes.Buffer{})
+			outputs, err := w.build(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var want []string
+			for _, name := range tt.want {
+				want = append(want, filepath.Join(outDir, filepath.FromSlash(name)))
+			}
+			if !reflect.DeepEqual(outputs, want) {
+				t.Fatalf("outputs = %v, want %v", outputs, want)
+			}
+			for _, out := range outputs {
+				if info, err := os.Stat(out); err != nil || info.Size() == 0 {
+					t.Errorf("%s: not written (%v)", out, err)
+				}
+			}
+		})
+	}
+}
+
+func TestWatcherCompileReportsErrors(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "bad.wgsl")
+	writeSource(t, path, "fn f( {")
+
+	var log bytes.Buffer
+	w := newTestWatcher(dir, dir, naga.TargetMSL, &log)
+	w.compile(path)
+	if got := log.String(); !strings.HasPrefix(got, path+": error: ") {
+		t.Errorf("log = %q, want an error line for %s", got, path)
+	}
+
+	writeSource(t, path, watchShader)
+	log.Reset()
+	w.compile(path)
+	if got := log.String(); !strings.HasPrefix(got, path+": ok -> ") {
+		t.Errorf("log = %q, want an ok line for %s", got, path)
+	}
+}
+
+func TestParseTarget(t *testing.T) {
+	for _, name := range []string{"spirv", "msl", "hlsl", "glsl"} {
+		target, err := parseTarget(name)
+		if err != nil || target.String() != name {
+			t.Errorf("parseTarget(%q) = %v, %v", name, target, err)
+		}
+	}
+	if _, err := parseTarget("wgsl"); err == nil {
+		t.Error("parseTarget(\"wgsl\") succeeded")
+	}
+}
+
+func TestGLSLExtension(t *testing.T) {
+	tests := map[ir.ShaderStage]string{
+		ir.StageVertex:   ".vert",
+		ir.StageFragment: ".frag",
+		ir.StageCompute:  ".comp",
+		ir.StageTask:     ".task",
+		ir.StageMesh:     ".mesh",
+	}
+	for stage, want := range tests {
+		if got := glslExtension(stage); got != want {
+			t.Errorf("glslExtension(%v) = %q, want %q", stage, got, want)
+		}
+	}
+}
@@ -0,0 +1,76 @@
+// Command nagad is a shader compile service: build machines POST WGSL and
+// get SPIR-V, MSL, HLSL or GLSL back, so the toolchain lives in one place.
+//
+// Usage:
+//
+//	nagad [-addr :8080] [-concurrency N] [-timeout 30s] [-max-source-bytes 4194304]
+//
+// Endpoints:
+//
+//	POST /v1/compile  {"source": "...", "target": "msl", "options": {"msl_version": "2.1"}}
+//	                  → {"code": "...", "warnings": [...]}  (SPIR-V: "binary", base64)
+//	POST /v1/reflect  {"source": "..."} → {"entryPoints": [...], "bindings": [...]}
+//	GET  /metrics     Prometheus text format
+//	GET  /healthz
+//
+// Options are the facade options: entry_point, debug, validate,
+// spirv_version, msl_version, shader_model, glsl_version. Shader errors are
+// reported as 422, exceeded limits as 413, 503 or 504.
+//
+// The compile and reflect endpoints speak JSON, or protobuf when the
+// request's Content-Type is application/x-protobuf, with the messages of
+// nagad.proto. The same messages are served over gRPC as the
+// naga.v1.ShaderCompiler service; the listener accepts HTTP/2 without TLS
+// for it. Errors map to gRPC status codes: INVALID_ARGUMENT for shader and
+// request errors, RESOURCE_EXHAUSTED, UNAVAILABLE and DEADLINE_EXCEEDED
+// for the limits.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"runtime"
+	"syscall"
+	"time"
+)
+
+func main() {
+	addr := flag.String("addr", ":8080", "listen address")
+	concurrency := flag.Int("concurrency", runtime.GOMAXPROCS(0), "compilations run at once")
+	timeout := flag.Duration("timeout", 30*time.Second, "per-request time limit, including waiting for a slot")
+	maxSource := flag.Int64("max-source-bytes", 4<<20, "maximum request body size")
+	flag.Parse()
+
+	srv := &http.Server{
+		Addr: *addr,
+		Handler: newServer(limits{
+			MaxSourceBytes: *maxSource,
+			Timeout:        *timeout,
+			Concurrency:    *concurrency,
+		}).handler(),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	// gRPC clients dial plain-text HTTP/2.
+	srv.Protocols = new(http.Protocols)
+	srv.Protocols.SetHTTP1(true)
+	srv.Protocols.SetUnencryptedHTTP2(true)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	go func() {
+		<-ctx.Done()
+		shutdown, cancel := context.WithTimeout(context.Background(), *timeout)
+		defer cancel()
+		_ = srv.Shutdown(shutdown)
+	}()
+
+	log.Printf("nagad listening on %s", *addr)
+	if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		log.Fatal(err)
+	}
+}
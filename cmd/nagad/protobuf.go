@@ -0,0 +1,319 @@
+package main
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+
+	"github.com/gogpu/naga/internal/facade"
+)
+
+// The messages of nagad.proto are encoded by hand: they are few and flat,
+// and naga stays free of dependencies.
+
+// wire is the encoding of a request and its response.
+type wire int
+
+const (
+	wireJSON  wire = iota
+	wireProto      // application/x-protobuf over HTTP
+	wireGRPC       // a unary gRPC call
+)
+
+const (
+	protoContentType = "application/x-protobuf"
+	grpcContentType  = "application/grpc"
+)
+
+// requestWire picks the wire from the request's Content-Type; anything
+// not protobuf is read as JSON.
+func requestWire(r *http.Request) wire {
+	mt, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	switch mt {
+	case protoContentType, "application/protobuf":
+		return wireProto
+	case grpcContentType, "application/grpc+proto":
+		return wireGRPC
+	}
+	return wireJSON
+}
+
+// protoMessage is a request that decodes from protobuf.
+type protoMessage interface {
+	unmarshalProto(data []byte) error
+}
+
+// readMessage reads the protobuf message of a protobuf or gRPC request
+// body. A gRPC body holds one length-prefixed, uncompressed message.
+func readMessage(body io.Reader, w wire) ([]byte, error) {
+	if w != wireGRPC {
+		return io.ReadAll(body)
+	}
+	var prefix [5]byte
+	if _, err := io.ReadFull(body, prefix[:]); err != nil {
+		return nil, fmt.Errorf("reading gRPC message prefix: %w", err)
+	}
+	if prefix[0] != 0 {
+		return nil, errors.New("compressed gRPC messages are not supported")
+	}
+	data := make([]byte, binary.BigEndian.Uint32(prefix[1:]))
+	if _, err := io.ReadFull(body, data); err != nil {
+		return nil, fmt.Errorf("reading gRPC message: %w", err)
+	}
+	return data, nil
+}
+
+// writeResponse encodes body, the result of a handler, with the wire of
+// the request.
+func writeResponse(w http.ResponseWriter, wr wire, body any, status int) {
+	switch wr {
+	case wireJSON:
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		_ = json.NewEncoder(w).Encode(body)
+	case wireProto:
+		data, err := marshalProto(body)
+		if err != nil {
+			data, status = errorResponse{Error: err.Error()}.marshalProto(), http.StatusInternalServerError
+		}
+		w.Header().Set("Content-Type", protoContentType)
+		w.WriteHeader(status)
+		_, _ = w.Write(data)
+	case wireGRPC:
+		writeGRPC(w, body, status)
+	}
+}
+
+// writeGRPC answers a gRPC call. The HTTP status is always 200; the
+// outcome is in the grpc-status and grpc-message trailers.
+func writeGRPC(w http.ResponseWriter, body any, status int) {
+	var data []byte
+	if status == http.StatusOK {
+		var err error
+		if data, err = marshalProto(body); err != nil {
+			body, status = errorResponse{Error: err.Error()}, http.StatusInternalServerError
+		}
+	}
+	w.Header().Set("Content-Type", grpcContentType)
+	w.Header().Set("Trailer", "Grpc-Status, Grpc-Message")
+	w.WriteHeader(http.StatusOK)
+	if status == http.StatusOK {
+		var prefix [5]byte
+		binary.BigEndian.PutUint32(prefix[1:], uint32(len(data)))
+		_, _ = w.Write(prefix[:])
+		_, _ = w.Write(data)
+		w.Header().Set("Grpc-Status", "0")
+		return
+	}
+	w.Header().Set("Grpc-Status", strconv.Itoa(grpcCode(status)))
+	if e, ok := body.(errorResponse); ok {
+		w.Header().Set("Grpc-Message", grpcPercentEncode(e.Error))
+	}
+}
+
+// grpcCode maps the HTTP status of a failed request to a gRPC status
+// code.
+func grpcCode(status int) int {
+	switch status {
+	case http.StatusBadRequest, http.StatusUnprocessableEntity:
+		return 3 // INVALID_ARGUMENT
+	case http.StatusRequestEntityTooLarge:
+		return 8 // RESOURCE_EXHAUSTED
+	case http.StatusGatewayTimeout:
+		return 4 // DEADLINE_EXCEEDED
+	case http.StatusServiceUnavailable:
+		return 14 // UNAVAILABLE
+	}
+	return 13 // INTERNAL
+}
+
+// grpcPercentEncode encodes a grpc-message value: bytes outside printable
+// ASCII, and '%', become %XX.
+func grpcPercentEncode(s string) string {
+	var out []byte
+	for i := 0; i < len(s); i++ {
+		if c := s[i]; c < ' ' || c > '~' || c == '%' {
+			out = fmt.Appendf(out, "%%%02X", c)
+		} else {
+			out = append(out, c)
+		}
+	}
+	return string(out)
+}
+
+// marshalProto encodes a handler result. The reflection document goes
+// out as its JSON, in a ReflectResponse.
+func marshalProto(body any) ([]byte, error) {
+	switch b := body.(type) {
+	case compileResponse:
+		return b.marshalProto(), nil
+	case errorResponse:
+		return b.marshalProto(), nil
+	}
+	doc, err := json.Marshal(body)
+	if err != nil {
+		return nil, err
+	}
+	return appendProtoBytes(nil, 1, doc), nil
+}
+
+func (r compileResponse) marshalProto() []byte {
+	var b []byte
+	b = appendProtoBytes(b, 1, []byte(r.Code))
+	b = appendProtoBytes(b, 2, r.Binary)
+	for _, w := range r.Warnings {
+		b = appendProtoField(b, 3, []byte(w))
+	}
+	return b
+}
+
+func (r errorResponse) marshalProto() []byte {
+	return appendProtoBytes(nil, 1, []byte(r.Error))
+}
+
+func (r *compileRequest) unmarshalProto(data []byte) error {
+	return protoFields(data, func(f protoField) error {
+		var err error
+		switch f.num {
+		case 1:
+			r.Source, err = f.string()
+		case 2:
+			r.Target, err = f.string()
+		case 3:
+			if err = f.wantType(protoBytes); err == nil {
+				err = unmarshalOptions(f.bytes, &r.Options)
+			}
+		}
+		return err
+	})
+}
+
+func unmarshalOptions(data []byte, o *facade.Options) error {
+	return protoFields(data, func(f protoField) error {
+		var err error
+		switch f.num {
+		case 1:
+			o.EntryPoint, err = f.string()
+		case 2:
+			o.Debug, err = f.bool()
+		case 3:
+			var v bool
+			v, err = f.bool()
+			o.Validate = &v
+		case 4:
+			o.SPIRVVersion, err = f.string()
+		case 5:
+			o.MSLVersion, err = f.string()
+		case 6:
+			o.ShaderModel, err = f.string()
+		case 7:
+			o.GLSLVersion, err = f.string()
+		}
+		return err
+	})
+}
+
+func (r *reflectRequest) unmarshalProto(data []byte) error {
+	return protoFields(data, func(f protoField) error {
+		var err error
+		if f.num == 1 {
+			r.Source, err = f.string()
+		}
+		return err
+	})
+}
+
+// Protobuf wire types.
+const (
+	protoVarint  = 0
+	protoFixed64 = 1
+	protoBytes   = 2
+	protoFixed32 = 5
+)
+
+// protoField is one field of an encoded message: its number, wire type,
+// and value, in varint or bytes. No message here has fixed-size fields;
+// their values are skipped.
+type protoField struct {
+	num, typ int
+	varint   uint64
+	bytes    []byte
+}
+
+func (f protoField) wantType(typ int) error {
+	if f.typ != typ {
+		return fmt.Errorf("field %d has wire type %d, want %d", f.num, f.typ, typ)
+	}
+	return nil
+}
+
+func (f protoField) string() (string, error) {
+	return string(f.bytes), f.wantType(protoBytes)
+}
+
+func (f protoField) bool() (bool, error) {
+	return f.varint != 0, f.wantType(protoVarint)
+}
+
+// protoFields calls field for each field of the message data, in order.
+// Unknown fields are the caller's to skip, as protobuf requires.
+func protoFields(data []byte, field func(protoField) error) error {
+	for len(data) > 0 {
+		key, n := binary.Uvarint(data)
+		if n <= 0 {
+			return errors.New("bad field key")
+		}
+		data = data[n:]
+		f := protoField{num: int(key >> 3), typ: int(key & 7)}
+		switch f.typ {
+		case protoVarint:
+			if f.varint, n = binary.Uvarint(data); n <= 0 {
+				return fmt.Errorf("bad varint in field %d", f.num)
+			}
+		case protoFixed64, protoFixed32:
+			n = 8
+			if f.typ == protoFixed32 {
+				n = 4
+			}
+			if len(data) < n {
+				return fmt.Errorf("field %d is truncated", f.num)
+			}
+		case protoBytes:
+			size, m := binary.Uvarint(data)
+			if m <= 0 || size > uint64(len(data)-m) {
+				return fmt.Errorf("field %d is truncated", f.num)
+			}
+			f.bytes = data[m : m+int(size)]
+			n = m + int(size)
+		default:
+			return fmt.Errorf("field %d has unsupported wire type %d", f.num, f.typ)
+		}
+		data = data[n:]
+		if err := field(f); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// appendProtoField appends a length-delimited field, even an empty one,
+// as repeated fields need.
+func appendProtoField(b []byte, num int, v []byte) []byte {
+	b = binary.AppendUvarint(b, uint64(num)<<3|protoBytes)
+	b = binary.AppendUvarint(b, uint64(len(v)))
+	return append(b, v...)
+}
+
+// appendProtoBytes appends a string or bytes field, leaving out the
+// empty default as proto3 does.
+func appendProtoBytes(b []byte, num int, v []byte) []byte {
+	if len(v) == 0 {
+		return b
+	}
+	return appendProtoField(b, num, v)
+}
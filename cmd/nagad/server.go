@@ -0,0 +1,260 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gogpu/naga"
+	"github.com/gogpu/naga/internal/facade"
+	"github.com/gogpu/naga/reflection"
+)
+
+// limits bounds the work a single request may cause.
+type limits struct {
+	// MaxSourceBytes caps the request body.
+	MaxSourceBytes int64
+
+	// Timeout caps the time a request may spend waiting for a compile slot
+	// and compiling.
+	Timeout time.Duration
+
+	// Concurrency is the number of compilations run at once; further
+	// requests wait for a slot until their timeout.
+	Concurrency int
+}
+
+// compileRequest is the body of POST /v1/compile.
+type compileRequest struct {
+	Source  string         `json:"source"`
+	Target  string         `json:"target"`
+	Options facade.Options `json:"options"`
+}
+
+// compileResponse is the reply to POST /v1/compile. Code holds text
+// output; Binary holds SPIR-V (base64 in JSON).
+type compileResponse struct {
+	Code     string   `json:"code,omitempty"`
+	Binary   []byte   `json:"binary,omitempty"`
+	Warnings []string `json:"warnings"`
+}
+
+// reflectRequest is the body of POST /v1/reflect.
+type reflectRequest struct {
+	Source string `json:"source"`
+}
+
+type errorResponse struct {
+	Error string `json:"error"`
+}
+
+// server serves the compile service. It is safe for concurrent use.
+type server struct {
+	limits  limits
+	slots   chan struct{}
+	metrics *metrics
+}
+
+func newServer(l limits) *server {
+	if l.Concurrency < 1 {
+		l.Concurrency = 1
+	}
+	return &server{
+		limits:  l,
+		slots:   make(chan struct{}, l.Concurrency),
+		metrics: newMetrics(),
+	}
+}
+
+// handler returns the service's HTTP routes.
+func (s *server) handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /v1/compile", s.instrument("compile", s.handleCompile))
+	mux.HandleFunc("POST /v1/reflect", s.instrument("reflect", s.handleReflect))
+	mux.HandleFunc("POST /naga.v1.ShaderCompiler/Compile", s.instrument("compile", s.handleCompile))
+	mux.HandleFunc("POST /naga.v1.ShaderCompiler/Reflect", s.instrument("reflect", s.handleReflect))
+	mux.HandleFunc("GET /metrics", s.handleMetrics)
+	mux.HandleFunc("GET /healthz", func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = io.WriteString(w, "ok\n")
+	})
+	return mux
+}
+
+// instrument applies the request limits and records metrics around h.
+func (s *server) instrument(endpoint string, h func(context.Context, *http.Request) (any, int)) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		ctx, cancel := context.WithTimeout(r.Context(), s.limits.Timeout)
+		defer cancel()
+		r.Body = http.MaxBytesReader(w, r.Body, s.limits.MaxSourceBytes)
+
+		var body any
+		var status int
+		select {
+		case s.slots <- struct{}{}:
+			s.metrics.inFlight.Add(1)
+			body, status = h(ctx, r)
+			s.metrics.inFlight.Add(-1)
+			<-s.slots
+		case <-ctx.Done():
+			body, status = errorResponse{Error: "server busy: no compile slot before timeout"}, http.StatusServiceUnavailable
+		}
+
+		s.metrics.observe(endpoint, status, time.Since(start))
+		writeResponse(w, requestWire(r), body, status)
+	}
+}
+
+func (s *server) handleCompile(ctx context.Context, r *http.Request) (any, int) {
+	var req compileRequest
+	if body, status := decode(r, &req); status != 0 {
+		return body, status
+	}
+	if req.Target == "" {
+		req.Target = "spirv"
+	}
+	out, err := facade.CompileContext(ctx, req.Source, req.Target, req.Options)
+	if err != nil {
+		return errorResponse{Error: err.Error()}, errorStatus(err)
+	}
+	resp := compileResponse{Warnings: out.Warnings}
+	if resp.Warnings == nil {
+		resp.Warnings = []string{}
+	}
+	if req.Target == "spirv" {
+		resp.Binary = out.Code
+	} else {
+		resp.Code = string(out.Code)
+	}
+	return resp, http.StatusOK
+}
+
+func (s *server) handleReflect(ctx context.Context, r *http.Request) (any, int) {
+	var req reflectRequest
+	if body, status := decode(r, &req); status != 0 {
+		return body, status
+	}
+	pm := naga.NewPassManager()
+	if err := pm.Remove(naga.PassSPIRV); err != nil {
+		return errorResponse{Error: err.Error()}, http.StatusInternalServerError
+	}
+	state, _, err := pm.RunContext(ctx, req.Source, naga.DefaultOptions())
+	if err != nil {
+		return errorResponse{Error: err.Error()}, errorStatus(err)
+	}
+	return reflection.Reflect(state.Module), http.StatusOK
+}
+
+// decode reads a JSON or protobuf request body into v. It returns a zero
+// status on success and an error body and status otherwise.
+func decode(r *http.Request, v protoMessage) (any, int) {
+	var err error
+	if wr := requestWire(r); wr == wireJSON {
+		dec := json.NewDecoder(r.Body)
+		dec.DisallowUnknownFields()
+		err = dec.Decode(v)
+	} else {
+		var data []byte
+		if data, err = readMessage(r.Body, wr); err == nil {
+			if err = v.unmarshalProto(data); err != nil {
+				err = fmt.Errorf("protobuf: %w", err)
+			}
+		}
+	}
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			return errorResponse{Error: fmt.Sprintf("request body exceeds %d bytes", tooLarge.Limit)}, http.StatusRequestEntityTooLarge
+		}
+		return errorResponse{Error: "invalid request: " + err.Error()}, http.StatusBadRequest
+	}
+	return nil, 0
+}
+
+// errorStatus maps a compile error to an HTTP status: timeouts are 504,
+// everything else is a problem with the shader or options.
+func errorStatus(err error) int {
+	switch {
+	case errors.Is(err, context.DeadlineExceeded):
+		return http.StatusGatewayTimeout
+	case errors.Is(err, context.Canceled):
+		return http.StatusServiceUnavailable
+	default:
+		return http.StatusUnprocessableEntity
+	}
+}
+
+func (s *server) handleMetrics(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+	s.metrics.write(w)
+}
+
+// metrics counts requests in the Prometheus text exposition format.
+type metrics struct {
+	mu       sync.Mutex
+	requests map[requestKey]int64
+	seconds  map[string]float64
+	inFlight atomic.Int64
+}
+
+type requestKey struct {
+	endpoint string
+	status   int
+}
+
+func newMetrics() *metrics {
+	return &metrics{
+		requests: make(map[requestKey]int64),
+		seconds:  make(map[string]float64),
+	}
+}
+
+func (m *metrics) observe(endpoint string, status int, d time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.requests[requestKey{endpoint, status}]++
+	m.seconds[endpoint] += d.Seconds()
+}
+
+func (m *metrics) write(w io.Writer) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	keys := make([]requestKey, 0, len(m.requests))
+	for k := range m.requests {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].endpoint != keys[j].endpoint {
+			return keys[i].endpoint < keys[j].endpoint
+		}
+		return keys[i].status < keys[j].status
+	})
+	fmt.Fprintln(w, "# HELP nagad_requests_total Requests by endpoint and HTTP status.")
+	fmt.Fprintln(w, "# TYPE nagad_requests_total counter")
+	for _, k := range keys {
+		fmt.Fprintf(w, "nagad_requests_total{endpoint=%q,status=\"%d\"} %d\n", k.endpoint, k.status, m.requests[k])
+	}
+
+	endpoints := make([]string, 0, len(m.seconds))
+	for e := range m.seconds {
+		endpoints = append(endpoints, e)
+	}
+	sort.Strings(endpoints)
+	fmt.Fprintln(w, "# HELP nagad_request_seconds_total Time spent serving requests by endpoint.")
+	fmt.Fprintln(w, "# TYPE nagad_request_seconds_total counter")
+	for _, e := range endpoints {
+		fmt.Fprintf(w, "nagad_request_seconds_total{endpoint=%q} %g\n", e, m.seconds[e])
+	}
+
+	fmt.Fprintln(w, "# HELP nagad_in_flight Compilations currently running.")
+	fmt.Fprintln(w, "# TYPE nagad_in_flight gauge")
+	fmt.Fprintf(w, "nagad_in_flight %d\n", m.inFlight.Load())
+}
@@ -0,0 +1,277 @@
+package main
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+const fragmentShader = `
+@group(0) @binding(0) var tex: texture_2d<f32>;
+@group(0) @binding(1) var samp: sampler;
+
+@fragment
+fn fs_main(@location(0) uv: vec2<f32>) -> @location(0) vec4<f32> {
+    return textureSample(tex, samp, uv);
+}
+`
+
+func testServer(t *testing.T, l limits) *httptest.Server {
+	t.Helper()
+	if l.MaxSourceBytes == 0 {
+		l.MaxSourceBytes = 1 << 20
+	}
+	if l.Timeout == 0 {
+		l.Timeout = 10 * time.Second
+	}
+	ts := httptest.NewServer(newServer(l).handler())
+	t.Cleanup(ts.Close)
+	return ts
+}
+
+func post(t *testing.T, ts *httptest.Server, path string, body any) (int, map[string]any) {
+	t.Helper()
+	data, err := json.Marshal(body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := http.Post(ts.URL+path, "application/json", strings.NewReader(string(data)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	var out map[string]any
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	return resp.StatusCode, out
+}
+
+func TestCompileEndpoint(t *testing.T) {
+	ts := testServer(t, limits{Concurrency: 2})
+
+	status, out := post(t, ts, "/v1/compile", map[string]any{
+		"source":  fragmentShader,
+		"target":  "msl",
+		"options": map[string]any{"msl_version": "2.1"},
+	})
+	if status != http.StatusOK {
+		t.Fatalf("status = %d, body %v", status, out)
+	}
+	if code, _ := out["code"].(string); !strings.Contains(code, "fragment fs_mainOutput fs_main(") {
+		t.Errorf("MSL output missing entry point:\n%s", code)
+	}
+
+	// SPIR-V is the default target and comes back base64-encoded.
+	resp, err := http.Post(ts.URL+"/v1/compile", "application/json",
+		strings.NewReader(`{"source": `+quote(fragmentShader)+`}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	var spv compileResponse
+	if err := json.NewDecoder(resp.Body).Decode(&spv); err != nil {
+		t.Fatal(err)
+	}
+	if len(spv.Binary) < 20 || binary.LittleEndian.Uint32(spv.Binary) != 0x07230203 {
+		t.Errorf("response is not SPIR-V: %d bytes", len(spv.Binary))
+	}
+}
+
+func TestReflectEndpoint(t *testing.T) {
+	ts := testServer(t, limits{Concurrency: 1})
+	status, out := post(t, ts, "/v1/reflect", map[string]any{"source": fragmentShader})
+	if status != http.StatusOK {
+		t.Fatalf("status = %d, body %v", status, out)
+	}
+	bindings, _ := out["bindings"].([]any)
+	if len(bindings) != 2 {
+		t.Fatalf("bindings = %v, want 2", out["bindings"])
+	}
+	if b := bindings[1].(map[string]any); b["name"] != "samp" || b["type"] != "sampler" {
+		t.Errorf("second binding = %v", b)
+	}
+}
+
+func TestErrorStatuses(t *testing.T) {
+	ts := testServer(t, limits{Concurrency: 1, MaxSourceBytes: 512})
+	tests := []struct {
+		name string
+		body any
+		want int
+	}{
+		{"shader error", map[string]any{"source": "fn main( {", "target": "msl"}, http.StatusUnprocessableEntity},
+		{"unknown target", map[string]any{"source": fragmentShader, "target": "dxbc"}, http.StatusUnprocessableEntity},
+		{"unknown field", map[string]any{"source": fragmentShader, "taget": "msl"}, http.StatusBadRequest},
+		{"too large", map[string]any{"source": strings.Repeat("// x\n", 200)}, http.StatusRequestEntityTooLarge},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status, out := post(t, ts, "/v1/compile", tt.body)
+			if status != tt.want {
+				t.Errorf("status = %d, want %d (body %v)", status, tt.want, out)
+			}
+			if msg, _ := out["error"].(string); msg == "" {
+				t.Errorf("no error message in %v", out)
+			}
+		})
+	}
+}
+
+func TestBusyServer(t *testing.T) {
+	s := newServer(limits{Concurrency: 1, MaxSourceBytes: 1 << 20, Timeout: 50 * time.Millisecond})
+	ts := httptest.NewServer(s.handler())
+	defer ts.Close()
+
+	s.slots <- struct{}{} // occupy the only slot
+	status, out := post(t, ts, "/v1/compile", map[string]any{"source": fragmentShader})
+	<-s.slots
+	if status != http.StatusServiceUnavailable {
+		t.Errorf("status = %d, want 503 (body %v)", status, out)
+	}
+}
+
+func TestMetrics(t *testing.T) {
+	ts := testServer(t, limits{Concurrency: 1})
+	post(t, ts, "/v1/compile", map[string]any{"source": fragmentShader, "target": "glsl"})
+	post(t, ts, "/v1/compile", map[string]any{"source": "fn main( {"})
+
+	resp, err := http.Get(ts.URL + "/metrics")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+	for _, want := range []string{
+		`nagad_requests_total{endpoint="compile",status="200"} 1`,
+		`nagad_requests_total{endpoint="compile",status="422"} 1`,
+		`nagad_request_seconds_total{endpoint="compile"}`,
+		"nagad_in_flight 0",
+	} {
+		if !strings.Contains(string(body), want) {
+			t.Errorf("metrics missing %q:\n%s", want, body)
+		}
+	}
+}
+
+func quote(s string) string {
+	b, _ := json.Marshal(s)
+	return string(b)
+}
+
+// compileRequestProto encodes a CompileRequest of nagad.proto.
+func compileRequestProto(source, target, mslVersion string) []byte {
+	var opts []byte
+	opts = appendProtoBytes(opts, 5, []byte(mslVersion))
+	var b []byte
+	b = appendProtoBytes(b, 1, []byte(source))
+	b = appendProtoBytes(b, 2, []byte(target))
+	return appendProtoField(b, 3, opts)
+}
+
+// decodeProto returns the length-delimited fields of a message by number.
+func decodeProto(t *testing.T, data []byte) map[int][]string {
+	t.Helper()
+	fields := make(map[int][]string)
+	err := protoFields(data, func(f protoField) error {
+		fields[f.num] = append(fields[f.num], string(f.bytes))
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	return fields
+}
+
+func TestProtobufCompile(t *testing.T) {
+	ts := testServer(t, limits{Concurrency: 1})
+	for _, tt := range []struct {
+		name, source string
+		status       int
+		field        int
+		want         string
+	}{
+		{"ok", fragmentShader, http.StatusOK, 1, "fragment fs_mainOutput fs_main("},
+		{"shader error", "fn main( {", http.StatusUnprocessableEntity, 1, "expected"},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, err := http.Post(ts.URL+"/v1/compile", protoContentType,
+				strings.NewReader(string(compileRequestProto(tt.source, "msl", "2.1"))))
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer resp.Body.Close()
+			if ct := resp.Header.Get("Content-Type"); ct != protoContentType {
+				t.Errorf("Content-Type = %q", ct)
+			}
+			body, _ := io.ReadAll(resp.Body)
+			fields := decodeProto(t, body)
+			if resp.StatusCode != tt.status || len(fields[tt.field]) != 1 || !strings.Contains(fields[tt.field][0], tt.want) {
+				t.Errorf("status %d, fields %q; want %d with %q in field %d", resp.StatusCode, fields, tt.status, tt.want, tt.field)
+			}
+		})
+	}
+}
+
+func TestGRPC(t *testing.T) {
+	ts := httptest.NewUnstartedServer(newServer(limits{Concurrency: 1, MaxSourceBytes: 1 << 20, Timeout: 10 * time.Second}).handler())
+	ts.Config.Protocols = new(http.Protocols)
+	ts.Config.Protocols.SetUnencryptedHTTP2(true)
+	ts.Start()
+	defer ts.Close()
+	transport := &http.Transport{Protocols: new(http.Protocols)}
+	transport.Protocols.SetUnencryptedHTTP2(true)
+	client := &http.Client{Transport: transport}
+
+	call := func(method string, msg []byte) (*http.Response, []byte) {
+		t.Helper()
+		frame := binary.BigEndian.AppendUint32([]byte{0}, uint32(len(msg)))
+		req, err := http.NewRequest(http.MethodPost, ts.URL+"/naga.v1.ShaderCompiler/"+method, strings.NewReader(string(append(frame, msg...))))
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Set("Content-Type", "application/grpc")
+		req.Header.Set("TE", "trailers")
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(resp.Body) // the trailers follow the body
+		if resp.ProtoMajor != 2 {
+			t.Errorf("served over HTTP/%d", resp.ProtoMajor)
+		}
+		return resp, body
+	}
+
+	resp, body := call("Compile", compileRequestProto(fragmentShader, "", ""))
+	if got := resp.Trailer.Get("Grpc-Status"); got != "0" {
+		t.Fatalf("grpc-status = %q, message %q", got, resp.Trailer.Get("Grpc-Message"))
+	}
+	if len(body) < 5 || int(binary.BigEndian.Uint32(body[1:5])) != len(body)-5 {
+		t.Fatalf("response is not one gRPC message: % x", body)
+	}
+	spv := decodeProto(t, body[5:])[2]
+	if len(spv) != 1 || binary.LittleEndian.Uint32([]byte(spv[0])) != 0x07230203 {
+		t.Errorf("binary field is not SPIR-V: %q", spv)
+	}
+
+	resp, body = call("Compile", compileRequestProto("fn main( {", "msl", ""))
+	if got := resp.Trailer.Get("Grpc-Status"); got != "3" || len(body) != 0 || resp.Trailer.Get("Grpc-Message") == "" {
+		t.Errorf("shader error: grpc-status %q, message %q, %d body bytes", got, resp.Trailer.Get("Grpc-Message"), len(body))
+	}
+
+	resp, body = call("Reflect", appendProtoBytes(nil, 1, []byte(fragmentShader)))
+	if got := resp.Trailer.Get("Grpc-Status"); got != "0" || len(body) < 5 {
+		t.Fatalf("reflect: grpc-status %q", got)
+	}
+	var doc struct{ Bindings []any }
+	if reflected := decodeProto(t, body[5:])[1]; len(reflected) != 1 || json.Unmarshal([]byte(reflected[0]), &doc) != nil || len(doc.Bindings) != 2 {
+		t.Errorf("reflect document = %q", reflected)
+	}
+}
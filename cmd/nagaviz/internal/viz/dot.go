@@ -0,0 +1,461 @@
+// Copyright 2025 The GoGPU Authors
+// SPDX-License-Identifier: MIT
+
+// Package viz renders naga IR as Graphviz DOT graphs.
+package viz
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/gogpu/naga/ir"
+)
+
+// Function writes the expression DAG and statement tree of the function
+// or entry point called name as a DOT digraph.
+//
+// Each block of the statement tree is a cluster holding its statements,
+// in order, and the expressions its Emit statements evaluate, so
+// branch-local expressions sit inside their branch. Expressions that are
+// never emitted (arguments, variables, constants, literals) sit at the
+// top level, and call, atomic, and other statement results sit in the
+// block of the statement defining them. An operand used outside the
+// block that defines it is drawn as a red "out of scope" edge: that use
+// is not dominated by the definition, which backends emitting SSA reject.
+func Function(w io.Writer, module *ir.Module, name string) error {
+	fn, ok := findFunction(module, name)
+	if !ok {
+		return fmt.Errorf("no function or entry point named %q", name)
+	}
+	g := newFuncGraph(module, fn)
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "digraph %s {\n", quote(name))
+	sb.WriteString("  compound=true;\n")
+	sb.WriteString("  node [fontname=\"monospace\", fontsize=10];\n")
+	sb.WriteString("  edge [fontname=\"monospace\", fontsize=9];\n")
+	g.writeBlock(&sb, 0, "  ")
+	for h := range fn.Expressions {
+		if g.defBlock[h] < 0 {
+			g.writeExpr(&sb, ir.ExpressionHandle(h), "  ")
+		}
+	}
+	for _, e := range g.edges {
+		sb.WriteString("  " + e + "\n")
+	}
+	sb.WriteString("}\n")
+	_, err := io.WriteString(w, sb.String())
+	return err
+}
+
+// CallGraph writes the call graph of module as a DOT digraph. Entry
+// points are drawn bold; a function no entry point or function calls is
+// drawn dashed.
+func CallGraph(w io.Writer, module *ir.Module) error {
+	graph := ir.BuildCallGraph(module)
+	called := make([]bool, len(module.Functions))
+	epCallees := make([][]ir.FunctionHandle, len(module.EntryPoints))
+	for i := range module.EntryPoints {
+		epCallees[i] = callees(module.EntryPoints[i].Function.Body)
+		for _, callee := range epCallees[i] {
+			if int(callee) < len(called) {
+				called[callee] = true
+			}
+		}
+	}
+	for _, list := range graph.Callees {
+		for _, callee := range list {
+			if int(callee) < len(called) {
+				called[callee] = true
+			}
+		}
+	}
+
+	var sb strings.Builder
+	sb.WriteString("digraph calls {\n")
+	sb.WriteString("  node [fontname=\"monospace\", fontsize=10, shape=box];\n")
+	for i := range module.EntryPoints {
+		ep := &module.EntryPoints[i]
+		fmt.Fprintf(&sb, "  ep%d [label=%s, style=bold];\n", i, quote("@"+stageName(ep.Stage)+" "+ep.Name))
+	}
+	for i := range module.Functions {
+		style := ""
+		if !called[i] {
+			style = ", style=dashed"
+		}
+		fmt.Fprintf(&sb, "  f%d [label=%s%s];\n", i, quote(module.Functions[i].Name), style)
+	}
+	for i, list := range epCallees {
+		for _, callee := range list {
+			fmt.Fprintf(&sb, "  ep%d -> f%d;\n", i, callee)
+		}
+	}
+	for i, list := range graph.Callees {
+		for _, callee := range list {
+			fmt.Fprintf(&sb, "  f%d -> f%d;\n", i, callee)
+		}
+	}
+	sb.WriteString("}\n")
+	_, err := io.WriteString(w, sb.String())
+	return err
+}
+
+// findFunction returns the function or entry point called name.
+// Functions win over entry points of the same name.
+func findFunction(module *ir.Module, name string) (*ir.Function, bool) {
+	for i := range module.Functions {
+		if module.Functions[i].Name == name {
+			return &module.Functions[i], true
+		}
+	}
+	for i := range module.EntryPoints {
+		if module.EntryPoints[i].Name == name {
+			return &module.EntryPoints[i].Function, true
+		}
+	}
+	return nil, false
+}
+
+// callees returns the distinct functions block calls, in order of first
+// call.
+func callees(block ir.Block) []ir.FunctionHandle {
+	var list []ir.FunctionHandle
+	seen := map[ir.FunctionHandle]bool{}
+	ir.WalkStatements(block, func(stmt *ir.Statement) bool {
+		if call, ok := stmt.Kind.(ir.StmtCall); ok && !seen[call.Function] {
+			seen[call.Function] = true
+			list = append(list, call.Function)
+		}
+		return true
+	}, nil)
+	return list
+}
+
+// block is one block of a function's statement tree.
+type block struct {
+	parent int
+	label  string
+	stmts  []int
+	exprs  []ir.ExpressionHandle
+}
+
+// stmtNode is one statement of a function's statement tree.
+type stmtNode struct {
+	kind     ir.StatementKind
+	block    int
+	children []int // nested blocks, in field order
+}
+
+// funcGraph holds the statement tree of a function with the block that
+// defines each expression.
+type funcGraph struct {
+	module   *ir.Module
+	fn       *ir.Function
+	blocks   []block
+	stmts    []stmtNode
+	defBlock []int // block defining each expression, or -1 for the top level
+	edges    []string
+}
+
+func newFuncGraph(module *ir.Module, fn *ir.Function) *funcGraph {
+	g := &funcGraph{module: module, fn: fn, defBlock: make([]int, len(fn.Expressions))}
+	for i := range g.defBlock {
+		g.defBlock[i] = -1
+	}
+	g.addBlock(fn.Body, -1, "body")
+	g.link()
+	return g
+}
+
+// addBlock records body as a child block of parent and returns its index.
+func (g *funcGraph) addBlock(body ir.Block, parent int, label string) int {
+	b := len(g.blocks)
+	g.blocks = append(g.blocks, block{parent: parent, label: label})
+	for i := range body {
+		s := len(g.stmts)
+		g.stmts = append(g.stmts, stmtNode{kind: body[i].Kind, block: b})
+		g.blocks[b].stmts = append(g.blocks[b].stmts, s)
+
+		switch kind := body[i].Kind.(type) {
+		case ir.StmtEmit:
+			for h := kind.Range.Start; h < kind.Range.End && int(h) < len(g.defBlock); h++ {
+				g.define(h, b)
+			}
+		case ir.StmtBlock:
+			g.addChild(s, kind.Block, b, "block")
+		case ir.StmtIf:
+			g.addChild(s, kind.Accept, b, "accept")
+			g.addChild(s, kind.Reject, b, "reject")
+		case ir.StmtSwitch:
+			for _, c := range kind.Cases {
+				g.addChild(s, c.Body, b, "case "+switchValueName(c.Value))
+			}
+		case ir.StmtLoop:
+			// The continuing block sees the expressions of the body.
+			body := g.addChild(s, kind.Body, b, "loop body")
+			g.addChild(s, kind.Continuing, body, "continuing")
+		default:
+			// Results of calls, atomics, and the like are defined by
+			// their statement, the first to refer to them.
+			ir.StatementOperands(kind, func(h ir.ExpressionHandle) {
+				if int(h) < len(g.defBlock) && g.defBlock[h] < 0 && isStatementResult(g.fn.Expressions[h].Kind) {
+					g.define(h, b)
+				}
+			})
+		}
+	}
+	return b
+}
+
+func (g *funcGraph) addChild(stmt int, body ir.Block, parent int, label string) int {
+	child := g.addBlock(body, parent, label)
+	g.stmts[stmt].children = append(g.stmts[stmt].children, child)
+	return child
+}
+
+func (g *funcGraph) define(h ir.ExpressionHandle, b int) {
+	if g.defBlock[h] >= 0 {
+		return
+	}
+	g.defBlock[h] = b
+	g.blocks[b].exprs = append(g.blocks[b].exprs, h)
+}
+
+// link records the edges of the graph: statement order, nested blocks,
+// statement operands, and expression operands.
+func (g *funcGraph) link() {
+	for _, b := range g.blocks {
+		for i := 1; i < len(b.stmts); i++ {
+			g.edge(fmt.Sprintf("s%d -> s%d [style=bold];", b.stmts[i-1], b.stmts[i]))
+		}
+	}
+	for s, node := range g.stmts {
+		for _, child := range node.children {
+			if len(g.blocks[child].stmts) == 0 {
+				continue
+			}
+			g.edge(fmt.Sprintf("s%d -> s%d [label=%s, lhead=cluster_b%d];",
+				s, g.blocks[child].stmts[0], quote(g.blocks[child].label), child))
+		}
+		from := node.block
+		switch node.kind.(type) {
+		case ir.StmtEmit:
+			continue
+		case ir.StmtLoop:
+			// break_if is evaluated at the end of the continuing block.
+			from = node.children[1]
+		}
+		ir.StatementOperands(node.kind, func(h ir.ExpressionHandle) {
+			g.use(fmt.Sprintf("s%d", s), from, h, "color=blue, style=dashed")
+		})
+	}
+	for h := range g.fn.Expressions {
+		user := ir.ExpressionHandle(h)
+		ir.ExpressionOperands(g.fn.Expressions[h].Kind, func(operand ir.ExpressionHandle) {
+			g.use(fmt.Sprintf("e%d", user), g.defBlock[user], operand, "color=gray40")
+		})
+	}
+}
+
+// use records an edge from a statement or expression in block from to
+// the expression h it uses, flagging the use when h's defining block
+// does not enclose it.
+func (g *funcGraph) use(node string, from int, h ir.ExpressionHandle, style string) {
+	if int(h) >= len(g.defBlock) {
+		return
+	}
+	if !g.encloses(g.defBlock[h], from) {
+		style = "color=red, fontcolor=red, penwidth=2, label=\"out of scope\""
+	}
+	g.edge(fmt.Sprintf("%s -> e%d [%s];", node, h, style))
+}
+
+// encloses reports whether block outer is inner or one of its ancestors.
+// The top level, -1, encloses every block.
+func (g *funcGraph) encloses(outer, inner int) bool {
+	for b := inner; ; b = g.blocks[b].parent {
+		if b == outer {
+			return true
+		}
+		if b < 0 {
+			return false
+		}
+	}
+}
+
+func (g *funcGraph) edge(e string) {
+	g.edges = append(g.edges, e)
+}
+
+// writeBlock writes block b and its nested blocks as clusters. Empty
+// blocks have nothing to show and are left out.
+func (g *funcGraph) writeBlock(sb *strings.Builder, b int, indent string) {
+	if len(g.blocks[b].stmts) == 0 {
+		return
+	}
+	fmt.Fprintf(sb, "%ssubgraph cluster_b%d {\n", indent, b)
+	inner := indent + "  "
+	fmt.Fprintf(sb, "%slabel=%s;\n", inner, quote(g.blocks[b].label))
+	for _, s := range g.blocks[b].stmts {
+		fmt.Fprintf(sb, "%ss%d [shape=box, style=filled, fillcolor=lightyellow, label=%s];\n",
+			inner, s, quote(g.stmtLabel(g.stmts[s].kind)))
+	}
+	for _, h := range g.blocks[b].exprs {
+		g.writeExpr(sb, h, inner)
+	}
+	for _, s := range g.blocks[b].stmts {
+		for _, child := range g.stmts[s].children {
+			g.writeBlock(sb, child, inner)
+		}
+	}
+	fmt.Fprintf(sb, "%s}\n", indent)
+}
+
+func (g *funcGraph) writeExpr(sb *strings.Builder, h ir.ExpressionHandle, indent string) {
+	label := fmt.Sprintf("[%d] %s", h, g.exprLabel(g.fn.Expressions[h].Kind))
+	if name, ok := g.fn.NamedExpressions[h]; ok {
+		label += "\n" + name
+	}
+	fmt.Fprintf(sb, "%se%d [shape=ellipse, label=%s];\n", indent, h, quote(label))
+}
+
+// exprLabel describes an expression by its kind and the details that are
+// not edges of the graph.
+func (g *funcGraph) exprLabel(kind ir.ExpressionKind) string {
+	m := g.module
+	switch e := kind.(type) {
+	case ir.Literal:
+		return fmt.Sprintf("Literal %s %v", kindName(e.Value, "Literal"), e.Value)
+	case ir.ExprConstant:
+		if int(e.Constant) < len(m.Constants) {
+			return "Constant " + m.Constants[e.Constant].Name
+		}
+	case ir.ExprOverride:
+		if int(e.Override) < len(m.Overrides) {
+			return "Override " + m.Overrides[e.Override].Name
+		}
+	case ir.ExprFunctionArgument:
+		if int(e.Index) < len(g.fn.Arguments) {
+			return fmt.Sprintf("Argument %d %s", e.Index, g.fn.Arguments[e.Index].Name)
+		}
+	case ir.ExprGlobalVariable:
+		if int(e.Variable) < len(m.GlobalVariables) {
+			return "Global " + m.GlobalVariables[e.Variable].Name
+		}
+	case ir.ExprLocalVariable:
+		if int(e.Variable) < len(g.fn.LocalVars) {
+			return "Local " + g.fn.LocalVars[e.Variable].Name
+		}
+	case ir.ExprAccessIndex:
+		return fmt.Sprintf("AccessIndex .%d", e.Index)
+	case ir.ExprBinary:
+		return "Binary " + binaryOps[e.Op]
+	case ir.ExprUnary:
+		return "Unary " + unaryOps[e.Op]
+	case ir.ExprMath:
+		return fmt.Sprintf("Math #%d", e.Fun)
+	case ir.ExprAs:
+		if e.Convert != nil {
+			return fmt.Sprintf("As %s%d", scalarKindName(e.Kind), int(*e.Convert)*8)
+		}
+		return "Bitcast " + scalarKindName(e.Kind)
+	case ir.ExprCallResult:
+		if int(e.Function) < len(m.Functions) {
+			return "CallResult " + m.Functions[e.Function].Name
+		}
+	}
+	return kindName(kind, "Expr")
+}
+
+func (g *funcGraph) stmtLabel(kind ir.StatementKind) string {
+	switch s := kind.(type) {
+	case ir.StmtEmit:
+		return fmt.Sprintf("Emit [%d..%d)", s.Range.Start, s.Range.End)
+	case ir.StmtCall:
+		if int(s.Function) < len(g.module.Functions) {
+			return "Call " + g.module.Functions[s.Function].Name
+		}
+	}
+	return kindName(kind, "Stmt")
+}
+
+// kindName returns the type name of an IR expression or statement kind
+// without its package and prefix.
+func kindName(kind any, prefix string) string {
+	name := fmt.Sprintf("%T", kind)
+	name = strings.TrimPrefix(name, "ir.")
+	return strings.TrimPrefix(name, prefix)
+}
+
+// isStatementResult reports whether kind is the result of a statement
+// rather than an expression evaluated by an Emit.
+func isStatementResult(kind ir.ExpressionKind) bool {
+	switch kind.(type) {
+	case ir.ExprCallResult, ir.ExprAtomicResult, ir.ExprWorkGroupUniformLoadResult,
+		ir.ExprRayQueryProceedResult, ir.ExprSubgroupBallotResult, ir.ExprSubgroupOperationResult:
+		return true
+	}
+	return false
+}
+
+func switchValueName(v ir.SwitchValue) string {
+	switch v := v.(type) {
+	case ir.SwitchValueI32:
+		return fmt.Sprint(int32(v))
+	case ir.SwitchValueU32:
+		return fmt.Sprintf("%du", uint32(v))
+	}
+	return "default"
+}
+
+var binaryOps = map[ir.BinaryOperator]string{
+	ir.BinaryAdd: "+", ir.BinarySubtract: "-", ir.BinaryMultiply: "*",
+	ir.BinaryDivide: "/", ir.BinaryModulo: "%",
+	ir.BinaryEqual: "==", ir.BinaryNotEqual: "!=",
+	ir.BinaryLess: "<", ir.BinaryLessEqual: "<=",
+	ir.BinaryGreater: ">", ir.BinaryGreaterEqual: ">=",
+	ir.BinaryAnd: "&", ir.BinaryExclusiveOr: "^", ir.BinaryInclusiveOr: "|",
+	ir.BinaryLogicalAnd: "&&", ir.BinaryLogicalOr: "||",
+	ir.BinaryShiftLeft: "<<", ir.BinaryShiftRight: ">>",
+}
+
+var unaryOps = map[ir.UnaryOperator]string{
+	ir.UnaryNegate: "-", ir.UnaryLogicalNot: "!", ir.UnaryBitwiseNot: "~",
+}
+
+func scalarKindName(k ir.ScalarKind) string {
+	switch k {
+	case ir.ScalarSint:
+		return "i"
+	case ir.ScalarUint:
+		return "u"
+	case ir.ScalarFloat:
+		return "f"
+	case ir.ScalarBool:
+		return "bool"
+	}
+	return fmt.Sprintf("kind%d", k)
+}
+
+func stageName(s ir.ShaderStage) string {
+	switch s {
+	case ir.StageVertex:
+		return "vertex"
+	case ir.StageTask:
+		return "task"
+	case ir.StageMesh:
+		return "mesh"
+	case ir.StageFragment:
+		return "fragment"
+	case ir.StageCompute:
+		return "compute"
+	}
+	return fmt.Sprintf("stage%d", s)
+}
+
+// quote returns s as a DOT string.
+func quote(s string) string {
+	r := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+	return `"` + r.Replace(s) + `"`
+}
@@ -0,0 +1,141 @@
+package viz
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gogpu/naga/internal/testutil"
+	"github.com/gogpu/naga/ir"
+)
+
+const branchShader = `
+fn helper(x: f32) -> f32 { return x * 2.0; }
+fn unused() {}
+
+@compute @workgroup_size(1)
+fn main(@builtin(global_invocation_id) id: vec3<u32>) {
+    var acc = 0.0;
+    if id.x > 3u {
+        let y = helper(f32(id.y));
+        acc += y;
+    }
+    loop {
+        acc += 1.0;
+        continuing { break if acc > 8.0; }
+    }
+}
+`
+
+func TestCallGraph(t *testing.T) {
+	module := testutil.LowerWGSL(t, branchShader)
+	var buf bytes.Buffer
+	if err := CallGraph(&buf, module); err != nil {
+		t.Fatal(err)
+	}
+	dot := buf.String()
+	for _, want := range []string{
+		`ep0 [label="@compute main", style=bold];`,
+		`f0 [label="helper"];`,
+		`f1 [label="unused", style=dashed];`,
+		`ep0 -> f0;`,
+	} {
+		if !strings.Contains(dot, want) {
+			t.Errorf("call graph missing %q:\n%s", want, dot)
+		}
+	}
+}
+
+func TestFunctionClusters(t *testing.T) {
+	module := testutil.LowerWGSL(t, branchShader)
+	var buf bytes.Buffer
+	if err := Function(&buf, module, "main"); err != nil {
+		t.Fatal(err)
+	}
+	dot := buf.String()
+	for _, want := range []string{
+		`label="accept";`,
+		`label="loop body";`,
+		`label="continuing";`,
+		`label="Call helper"`,
+		`CallResult helper\ny`,
+	} {
+		if !strings.Contains(dot, want) {
+			t.Errorf("function graph missing %q:\n%s", want, dot)
+		}
+	}
+	// Valid IR, including break_if using continuing expressions, has
+	// no out-of-scope uses.
+	if strings.Contains(dot, "out of scope") {
+		t.Errorf("valid function flagged out of scope uses:\n%s", dot)
+	}
+
+	if err := Function(&buf, module, "missing"); err == nil {
+		t.Error("expected an error for an unknown function")
+	}
+}
+
+// TestFunctionOutOfScope draws a function whose return uses an
+// expression emitted inside an if, which no backend can emit as SSA.
+func TestFunctionOutOfScope(t *testing.T) {
+	u32 := ir.TypeHandle(0)
+	result := ir.ExpressionHandle(1)
+	module := &ir.Module{
+		Types: []ir.Type{{Inner: ir.ScalarType{Kind: ir.ScalarUint, Width: 4}}},
+		Functions: []ir.Function{{
+			Name:      "f",
+			Arguments: []ir.FunctionArgument{{Name: "x", Type: u32}},
+			Result:    &ir.FunctionResult{Type: u32},
+			Expressions: []ir.Expression{
+				{Kind: ir.ExprFunctionArgument{Index: 0}},
+				{Kind: ir.ExprBinary{Op: ir.BinaryAdd, Left: 0, Right: 0}},
+				{Kind: ir.Literal{Value: ir.LiteralBool(true)}},
+			},
+			Body: []ir.Statement{
+				{Kind: ir.StmtIf{
+					Condition: 2,
+					Accept:    []ir.Statement{{Kind: ir.StmtEmit{Range: ir.Range{Start: 1, End: 2}}}},
+				}},
+				{Kind: ir.StmtReturn{Value: &result}},
+			},
+		}},
+	}
+
+	var buf bytes.Buffer
+	if err := Function(&buf, module, "f"); err != nil {
+		t.Fatal(err)
+	}
+	dot := buf.String()
+	if !strings.Contains(dot, `-> e1 [color=red, fontcolor=red, penwidth=2, label="out of scope"];`) {
+		t.Errorf("expected the return to use e1 out of scope:\n%s", dot)
+	}
+	if strings.Count(dot, "out of scope") != 1 {
+		t.Errorf("expected exactly one out of scope use:\n%s", dot)
+	}
+}
+
+func TestMainFlags(t *testing.T) {
+	dir := t.TempDir()
+	input := filepath.Join(dir, "shader.wgsl")
+	if err := os.WriteFile(input, []byte(branchShader), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	var stdout, stderr bytes.Buffer
+	if code := Main([]string{"-fn", "helper", input}, &stdout, &stderr); code != ExitOK {
+		t.Fatalf("exit %d: %s", code, stderr.String())
+	}
+	if !strings.HasPrefix(stdout.String(), `digraph "helper" {`) {
+		t.Errorf("unexpected output:\n%s", stdout.String())
+	}
+
+	stderr.Reset()
+	if code := Main([]string{"-fn", "nope", input}, &stdout, &stderr); code != ExitFail {
+		t.Errorf("unknown function: exit %d, want %d", code, ExitFail)
+	}
+	if code := Main(nil, &stdout, &stderr); code != ExitUsage {
+		t.Errorf("no input: exit %d, want %d", code, ExitUsage)
+	}
+}
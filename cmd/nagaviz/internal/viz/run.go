@@ -0,0 +1,121 @@
+// Copyright 2025 The GoGPU Authors
+// SPDX-License-Identifier: MIT
+
+package viz
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+
+	"github.com/gogpu/naga"
+)
+
+// Exit codes.
+const (
+	ExitOK    = 0
+	ExitFail  = 1
+	ExitUsage = 2
+)
+
+// Main parses args, renders the requested graph, and writes it to -o or
+// stdout. Returns a process exit code.
+func Main(args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("nagaviz", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+
+	var (
+		fn     string
+		svg    bool
+		output string
+	)
+	fs.StringVar(&fn, "fn", "", "function or entry point to draw (default: the module call graph)")
+	fs.BoolVar(&svg, "svg", false, "render SVG with Graphviz dot instead of writing DOT")
+	fs.StringVar(&output, "o", "", "output file (default: stdout)")
+
+	fs.Usage = func() {
+		fmt.Fprintf(stderr, "Usage: nagaviz [flags] <input.wgsl>\n\n")
+		fmt.Fprintf(stderr, "Draws the call graph of a WGSL module, or with -fn the expression\n")
+		fmt.Fprintf(stderr, "DAG and statement tree of one function, as Graphviz DOT.\n\n")
+		fmt.Fprintf(stderr, "Flags:\n")
+		fs.PrintDefaults()
+	}
+
+	if err := fs.Parse(args); err != nil {
+		return ExitUsage
+	}
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return ExitUsage
+	}
+
+	inputPath := fs.Arg(0)
+	source, err := os.ReadFile(inputPath)
+	if err != nil {
+		fmt.Fprintf(stderr, "nagaviz: %v\n", err)
+		return ExitFail
+	}
+
+	ast, err := naga.Parse(string(source))
+	if err != nil {
+		fmt.Fprintf(stderr, "nagaviz: %s: %v\n", inputPath, err)
+		return ExitFail
+	}
+	module, err := naga.LowerWithSource(ast, string(source))
+	if err != nil {
+		fmt.Fprintf(stderr, "nagaviz: %s: %v\n", inputPath, err)
+		return ExitFail
+	}
+
+	var dot bytes.Buffer
+	if fn == "" {
+		err = CallGraph(&dot, module)
+	} else {
+		err = Function(&dot, module, fn)
+	}
+	if err != nil {
+		fmt.Fprintf(stderr, "nagaviz: %v\n", err)
+		return ExitFail
+	}
+
+	out := dot.Bytes()
+	if svg {
+		if out, err = renderSVG(out); err != nil {
+			fmt.Fprintf(stderr, "nagaviz: %v\n", err)
+			return ExitFail
+		}
+	}
+
+	if output == "" {
+		if _, err := stdout.Write(out); err != nil {
+			fmt.Fprintf(stderr, "nagaviz: %v\n", err)
+			return ExitFail
+		}
+		return ExitOK
+	}
+	if err := os.WriteFile(output, out, 0o644); err != nil { //nolint:gosec // rendered graphs are meant to be world-readable
+		fmt.Fprintf(stderr, "nagaviz: %v\n", err)
+		return ExitFail
+	}
+	return ExitOK
+}
+
+// renderSVG runs Graphviz dot over a DOT graph.
+func renderSVG(dot []byte) ([]byte, error) {
+	path, err := exec.LookPath("dot")
+	if err != nil {
+		return nil, fmt.Errorf("-svg needs Graphviz dot in PATH: %w", err)
+	}
+	var stdout, stderr bytes.Buffer
+	cmd := exec.Command(path, "-Tsvg")
+	cmd.Stdin = bytes.NewReader(dot)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("dot: %w: %s", err, bytes.TrimSpace(stderr.Bytes()))
+	}
+	return stdout.Bytes(), nil
+}
@@ -0,0 +1,29 @@
+// Command nagaviz renders naga IR as Graphviz graphs for debugging the
+// WGSL lowering and the backends.
+//
+// Usage:
+//
+//	nagaviz [options] <input.wgsl>
+//
+// Examples:
+//
+//	nagaviz shader.wgsl | dot -Tsvg -o calls.svg   # module call graph
+//	nagaviz -fn main shader.wgsl > main.dot        # one function as DOT
+//	nagaviz -fn main -svg -o main.svg shader.wgsl  # rendered with dot
+//
+// A function graph shows its statement tree as nested clusters, one per
+// block, holding the statements and the expressions the block emits, with
+// edges from each statement and expression to its operands. Operands used
+// outside the block that defines them, the cause of SSA dominance errors
+// in the SPIR-V and DXIL backends, are drawn as red edges.
+package main
+
+import (
+	"os"
+
+	"github.com/gogpu/naga/cmd/nagaviz/internal/viz"
+)
+
+func main() {
+	os.Exit(viz.Main(os.Args[1:], os.Stdout, os.Stderr))
+}
@@ -56,17 +56,20 @@ var opcodeNames = map[uint16]string{
 	144: "OpVectorTimesMatrix", 145: "OpMatrixTimesVector",
 	146: "OpMatrixTimesMatrix", 147: "OpOuterProduct", 148: "OpDot",
 	149: "OpIAddCarry", 150: "OpISubBorrow", 151: "OpUMulExtended",
-	152: "OpSMulExtended", 164: "OpAny", 165: "OpAll",
-	166: "OpIsNan", 167: "OpIsInf", 168: "OpIsFinite", 169: "OpIsNormal",
-	170: "OpSignBitSet", 171: "OpLessOrGreater", 172: "OpOrdered",
-	173: "OpUnordered", 174: "OpLogicalEqual", 175: "OpLogicalNotEqual",
-	176: "OpLogicalOr", 177: "OpLogicalAnd", 178: "OpLogicalNot",
-	179: "OpSelect", 180: "OpIEqual", 181: "OpINotEqual",
-	182: "OpUGreaterThan", 183: "OpSGreaterThan", 184: "OpUGreaterThanEqual",
-	185: "OpSGreaterThanEqual", 186: "OpULessThan", 187: "OpSLessThan",
-	188: "OpULessThanEqual", 189: "OpSLessThanEqual",
-	190: "OpFOrdEqual", 191: "OpFUnordEqual", 192: "OpFOrdNotEqual",
-	193: "OpFUnordNotEqual", 194: "OpShiftRightLogical", 195: "OpShiftRightArithmetic",
+	152: "OpSMulExtended", 154: "OpAny", 155: "OpAll",
+	156: "OpIsNan", 157: "OpIsInf", 158: "OpIsFinite", 159: "OpIsNormal",
+	160: "OpSignBitSet", 161: "OpLessOrGreater", 162: "OpOrdered",
+	163: "OpUnordered", 164: "OpLogicalEqual", 165: "OpLogicalNotEqual",
+	166: "OpLogicalOr", 167: "OpLogicalAnd", 168: "OpLogicalNot",
+	169: "OpSelect", 170: "OpIEqual", 171: "OpINotEqual",
+	172: "OpUGreaterThan", 173: "OpSGreaterThan", 174: "OpUGreaterThanEqual",
+	175: "OpSGreaterThanEqual", 176: "OpULessThan", 177: "OpSLessThan",
+	178: "OpULessThanEqual", 179: "OpSLessThanEqual",
+	180: "OpFOrdEqual", 181: "OpFUnordEqual", 182: "OpFOrdNotEqual",
+	183: "OpFUnordNotEqual", 184: "OpFOrdLessThan", 185: "OpFUnordLessThan",
+	186: "OpFOrdGreaterThan", 187: "OpFUnordGreaterThan", 188: "OpFOrdLessThanEqual",
+	189: "OpFUnordLessThanEqual", 190: "OpFOrdGreaterThanEqual", 191: "OpFUnordGreaterThanEqual",
+	194: "OpShiftRightLogical", 195: "OpShiftRightArithmetic",
 	196: "OpShiftLeftLogical", 197: "OpBitwiseOr", 198: "OpBitwiseXor",
 	199: "OpBitwiseAnd", 200: "OpNot", 201: "OpBitFieldInsert",
 	202: "OpBitFieldSExtract", 203: "OpBitFieldUExtract",
@@ -75,6 +78,7 @@ var opcodeNames = map[uint16]string{
 	248: "OpLabel", 249: "OpBranch", 250: "OpBranchConditional",
 	251: "OpSwitch", 252: "OpKill", 253: "OpReturn", 254: "OpReturnValue",
 	255: "OpUnreachable", 256: "OpLifetimeStart", 257: "OpLifetimeStop",
+	332: "OpDecorateId",
 }
 
 var capabilities = map[uint32]string{
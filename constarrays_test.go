@@ -0,0 +1,156 @@
+package naga
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/gogpu/naga/glsl"
+	"github.com/gogpu/naga/ir"
+)
+
+const constArraysShader = `
+const CURVE = array<f32, 4>(0.5, 0.25, 0.125, 0.0625);
+
+fn aces(i: u32) -> f32 {
+    let lut = array<f32, 8>(0.0, 0.1, 0.2, 0.3, 0.4, 0.5, 0.6, 0.7);
+    return lut[i];
+}
+fn filmic(i: u32) -> f32 {
+    const LUT = array<f32, 8>(0.0, 0.1, 0.2, 0.3, 0.4, 0.5, 0.6, 0.7);
+    return LUT[i];
+}
+fn mutable(i: u32) -> f32 {
+    var lut = array<f32, 8>(0.0, 0.1, 0.2, 0.3, 0.4, 0.5, 0.6, 0.7);
+    lut[0] = 1.0;
+    return lut[i];
+}
+fn curve(i: u32) -> f32 {
+    let c = array<f32, 4>(0.5, 0.25, 0.125, 0.0625);
+    return c[i];
+}
+fn grid(i: u32) -> vec2<f32> {
+    let g = array<vec2<f32>, 4>(vec2(0.0, 0.0), vec2(1.0, 0.0), vec2(0.0, 1.0), vec2(1.0, 1.0));
+    return g[i];
+}
+fn small(i: u32) -> f32 {
+    let s = array<f32, 2>(1.0, 2.0);
+    return s[i];
+}
+fn dynamic(i: u32, x: f32) -> f32 {
+    let d = array<f32, 4>(x, 1.0, 2.0, 3.0);
+    return d[i];
+}
+
+@fragment
+fn main(@builtin(position) p: vec4<f32>) -> @location(0) vec4<f32> {
+    let i = u32(p.x);
+    return vec4<f32>(aces(i) + filmic(i), mutable(i) + curve(i), grid(i).x + small(i), dynamic(i, p.y));
+}
+`
+
+// arrayInit returns the initializer of the let or var named name in fn.
+func arrayInit(t *testing.T, fn *ir.Function, name string) ir.ExpressionKind {
+	t.Helper()
+	for h, n := range fn.NamedExpressions {
+		if n == name {
+			return fn.Expressions[h].Kind
+		}
+	}
+	for _, v := range fn.LocalVars {
+		if v.Name == name && v.Init != nil {
+			return fn.Expressions[*v.Init].Kind
+		}
+	}
+	t.Fatalf("%s: nothing named %q", fn.Name, name)
+	return nil
+}
+
+func TestShareConstantArrays(t *testing.T) {
+	ast, err := Parse(constArraysShader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	module, err := Lower(ast)
+	if err != nil {
+		t.Fatal(err)
+	}
+	shared := ir.ShareConstantArrays(module, 4)
+
+	constantOf := func(fnName, name string) (ir.ConstantHandle, bool) {
+		c, ok := arrayInit(t, peepholeFunction(t, shared, fnName), name).(ir.ExprConstant)
+		return c.Constant, ok
+	}
+	curve := ir.ConstantHandle(0)
+	lut, ok := constantOf("aces", "lut")
+	if !ok || lut == curve {
+		t.Fatalf("aces: lut is %#v, want a new constant", arrayInit(t, peepholeFunction(t, shared, "aces"), "lut"))
+	}
+	if c, ok := constantOf("mutable", "lut"); !ok || c != lut {
+		t.Errorf("mutable: lut is not initialized from the constant aces uses (%d)", lut)
+	}
+	filmic := peepholeFunction(t, shared, "filmic")
+	if access, ok := returned(t, filmic).(ir.ExprAccess); !ok || filmic.Expressions[access.Base].Kind != (ir.ExprConstant{Constant: lut}) {
+		t.Errorf("filmic does not index the constant aces uses (%d)", lut)
+	}
+	if c, ok := constantOf("curve", "c"); !ok || c != curve {
+		t.Errorf("curve: c is not CURVE")
+	}
+	if _, ok := constantOf("grid", "g"); !ok {
+		t.Errorf("grid: array of vectors not shared")
+	}
+	for _, use := range []struct{ fn, name string }{{"small", "s"}, {"dynamic", "d"}} {
+		if _, ok := constantOf(use.fn, use.name); ok {
+			t.Errorf("%s: %s shared", use.fn, use.name)
+		}
+	}
+	if got, want := len(shared.Constants), len(module.Constants)+2; got != want {
+		t.Errorf("%d constants, want %d: lut and g added", got, want)
+	}
+	if len(shared.Functions[0].Expressions) >= len(module.Functions[0].Expressions) {
+		t.Errorf("aces keeps %d expressions, want fewer than %d", len(shared.Functions[0].Expressions), len(module.Functions[0].Expressions))
+	}
+	if _, ok := arrayInit(t, peepholeFunction(t, module, "aces"), "lut").(ir.ExprCompose); !ok {
+		t.Error("the input module was modified")
+	}
+
+	if errs, err := Validate(shared); err != nil || len(errs) > 0 {
+		t.Fatalf("shared module does not validate: %v %v", errs, err)
+	}
+	src, _, err := glsl.Compile(shared, glsl.Options{LangVersion: glsl.Version330})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := strings.Count(src, "0.1, 0.2, 0.3"); n != 1 {
+		t.Errorf("GLSL writes the table %d times, want once:\n%s", n, src)
+	}
+
+	opts := DefaultOptions()
+	plain, err := CompileWithOptions(constArraysShader, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	opts.ShareConstantArrays = 4
+	code, err := CompileWithOptions(constArraysShader, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(code) >= len(plain) {
+		t.Errorf("SPIR-V is %d bytes with shared arrays, want less than %d", len(code), len(plain))
+	}
+}
+
+func TestShareConstantArraysPassValidates(t *testing.T) {
+	pm := NewPassManager()
+	if err := pm.InsertAfter(PassValidate, Pass{Name: "break", Run: func(s *PassState) error {
+		s.Module.EntryPoints[0].Function.Result.Type = ir.TypeHandle(len(s.Module.Types))
+		return nil
+	}}); err != nil {
+		t.Fatal(err)
+	}
+	opts := DefaultOptions()
+	opts.ShareConstantArrays = 4
+	_, _, err := pm.Run(constArraysShader, opts)
+	if err == nil || !strings.Contains(err.Error(), "validation failed after constant array sharing") {
+		t.Fatalf("expected a validation error after constant array sharing, got %v", err)
+	}
+}
@@ -0,0 +1,28 @@
+// Copyright 2025 The GoGPU Authors
+// SPDX-License-Identifier: MIT
+
+// Package consteval evaluates the module-scope constant expressions of a
+// naga IR module on the host, so an engine can read the tile sizes, kernel
+// radii and other values a shader declares instead of parsing the WGSL
+// literals again:
+//
+//	tile, err := consteval.Constant(module, "TILE_SIZE")
+//	if err != nil { ... }
+//	n := tile.(uint32)
+//
+// Eval evaluates any expression of Module.GlobalExpressions, such as the
+// initializer of a constant or override. Results are plain Go values:
+//
+//	bool, i32, u32, i64, u64    bool, int32, uint32, int64, uint64
+//	f32, f16                    float32
+//	f64                         float64
+//	abstract int, float         int64, float64
+//	vecN<T>                     []T of the scalar's Go type
+//	matCxR<T>                   [][]T, one slice per column
+//	array<T, N>                 []any
+//	struct                      map[string]any keyed by member name
+//
+// An override evaluates to its default value. To see the values a pipeline
+// sets instead, run ir.ProcessOverrides on a copy of the module first,
+// which turns overrides into constants.
+package consteval
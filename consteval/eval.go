@@ -0,0 +1,560 @@
+// Copyright 2025 The GoGPU Authors
+// SPDX-License-Identifier: MIT
+
+package consteval
+
+import (
+	"errors"
+	"fmt"
+	"math"
+
+	"github.com/gogpu/naga/ir"
+)
+
+// ErrNotFound is returned, wrapped, by Constant for a name the module has
+// no constant of.
+var ErrNotFound = errors.New("constant not found")
+
+// Constant evaluates the module constant named name.
+//
+// A WGSL constant declared without a type whose value is abstract
+// (`const R = 3;`, or `const DIAM = R * 2 + 1;` computed from it) has no
+// concrete type to be stored with: lowering inlines its value where it is
+// used and the module does not keep it. Constant reports ErrNotFound for
+// it; give it a type (`const R: u32 = 3;`) to read it.
+func Constant(module *ir.Module, name string) (any, error) {
+	for _, c := range module.Constants {
+		if c.Name == name {
+			e := evaluator{module: module}
+			v, err := e.eval(c.Init)
+			if err != nil {
+				return nil, fmt.Errorf("consteval: constant %q: %w", name, err)
+			}
+			return v.goValue(), nil
+		}
+	}
+	return nil, fmt.Errorf("consteval: %q: %w (constants of abstract type are inlined and not kept in the module)", name, ErrNotFound)
+}
+
+// Eval evaluates the module-scope expression handle, an index into
+// module.GlobalExpressions. Literals, constants, override defaults, zero
+// values, composites, splats, swizzles, indexing, conversions, selects and
+// the unary and binary operators on scalars and vectors are supported.
+func Eval(module *ir.Module, handle ir.ExpressionHandle) (any, error) {
+	e := evaluator{module: module}
+	v, err := e.eval(handle)
+	if err != nil {
+		return nil, fmt.Errorf("consteval: %w", err)
+	}
+	return v.goValue(), nil
+}
+
+// value is an evaluated expression: a scalar, or the elements of a
+// composite of type inner.
+type value struct {
+	scalar ir.LiteralValue
+	elems  []value
+	inner  ir.TypeInner
+}
+
+// maxDepth bounds the nesting of expressions and constants, so a cycle
+// in a malformed module fails instead of overflowing the stack.
+const maxDepth = 256
+
+type evaluator struct {
+	module *ir.Module
+	depth  int
+}
+
+func (e *evaluator) eval(handle ir.ExpressionHandle) (value, error) {
+	if int(handle) >= len(e.module.GlobalExpressions) {
+		return value{}, fmt.Errorf("expression %d out of range", handle)
+	}
+	if e.depth++; e.depth > maxDepth {
+		return value{}, fmt.Errorf("expressions nested deeper than %d", maxDepth)
+	}
+	defer func() { e.depth-- }()
+
+	switch k := e.module.GlobalExpressions[handle].Kind.(type) {
+	case ir.Literal:
+		return value{scalar: k.Value}, nil
+	case ir.ExprConstant:
+		if int(k.Constant) >= len(e.module.Constants) {
+			return value{}, fmt.Errorf("constant %d out of range", k.Constant)
+		}
+		return e.eval(e.module.Constants[k.Constant].Init)
+	case ir.ExprOverride:
+		if int(k.Override) >= len(e.module.Overrides) {
+			return value{}, fmt.Errorf("override %d out of range", k.Override)
+		}
+		o := e.module.Overrides[k.Override]
+		if o.Init == nil {
+			return value{}, fmt.Errorf("override %q has no default value", o.Name)
+		}
+		return e.eval(*o.Init)
+	case ir.ExprZeroValue:
+		return e.zero(k.Type)
+	case ir.ExprCompose:
+		return e.compose(k)
+	case ir.ExprSplat:
+		v, err := e.eval(k.Value)
+		if err != nil {
+			return value{}, err
+		}
+		if v.scalar == nil {
+			return value{}, fmt.Errorf("splat of a composite")
+		}
+		elems := make([]value, k.Size)
+		for i := range elems {
+			elems[i] = v
+		}
+		return value{elems: elems, inner: ir.VectorType{Size: k.Size, Scalar: scalarType(v.scalar)}}, nil
+	case ir.ExprSwizzle:
+		v, err := e.vector(k.Vector)
+		if err != nil {
+			return value{}, err
+		}
+		elems := make([]value, k.Size)
+		for i := range elems {
+			c := int(k.Pattern[i])
+			if c >= len(v.elems) {
+				return value{}, fmt.Errorf("swizzle component %d out of range", c)
+			}
+			elems[i] = v.elems[c]
+		}
+		return value{elems: elems, inner: ir.VectorType{Size: k.Size, Scalar: v.inner.(ir.VectorType).Scalar}}, nil
+	case ir.ExprAccessIndex:
+		return e.index(k.Base, int64(k.Index))
+	case ir.ExprAccess:
+		i, err := e.eval(k.Index)
+		if err != nil {
+			return value{}, err
+		}
+		n, ok := integer(i.scalar)
+		if !ok {
+			return value{}, fmt.Errorf("index is not an integer")
+		}
+		return e.index(k.Base, n)
+	case ir.ExprUnary:
+		v, err := e.eval(k.Expr)
+		if err != nil {
+			return value{}, err
+		}
+		return componentwise(v, func(s ir.LiteralValue) (ir.LiteralValue, error) {
+			r, ok := ir.FoldUnary(k.Op, s)
+			if !ok {
+				return nil, fmt.Errorf("cannot apply unary operator %d to %T", k.Op, s)
+			}
+			return r, nil
+		})
+	case ir.ExprBinary:
+		return e.binary(k)
+	case ir.ExprSelect:
+		return e.selectValue(k)
+	case ir.ExprAs:
+		v, err := e.eval(k.Expr)
+		if err != nil {
+			return value{}, err
+		}
+		return componentwise(v, func(s ir.LiteralValue) (ir.LiteralValue, error) {
+			if k.Convert == nil {
+				return bitcast(s, k.Kind)
+			}
+			return convert(s, k.Kind, *k.Convert)
+		})
+	}
+	return value{}, fmt.Errorf("unsupported expression %T", e.module.GlobalExpressions[handle].Kind)
+}
+
+// zero returns the zero value of type handle.
+func (e *evaluator) zero(handle ir.TypeHandle) (value, error) {
+	if int(handle) >= len(e.module.Types) {
+		return value{}, fmt.Errorf("type %d out of range", handle)
+	}
+	inner := e.module.Types[handle].Inner
+	switch t := inner.(type) {
+	case ir.ScalarType:
+		return zeroScalar(t)
+	case ir.VectorType:
+		return splatZero(inner, t.Scalar, int(t.Size))
+	case ir.MatrixType:
+		column, err := splatZero(ir.VectorType{Size: t.Rows, Scalar: t.Scalar}, t.Scalar, int(t.Rows))
+		if err != nil {
+			return value{}, err
+		}
+		elems := make([]value, t.Columns)
+		for i := range elems {
+			elems[i] = column
+		}
+		return value{elems: elems, inner: inner}, nil
+	case ir.ArrayType:
+		if t.Size.Constant == nil {
+			return value{}, fmt.Errorf("zero value of a runtime-sized array")
+		}
+		elem, err := e.zero(t.Base)
+		if err != nil {
+			return value{}, err
+		}
+		elems := make([]value, *t.Size.Constant)
+		for i := range elems {
+			elems[i] = elem
+		}
+		return value{elems: elems, inner: inner}, nil
+	case ir.StructType:
+		elems := make([]value, len(t.Members))
+		for i, m := range t.Members {
+			var err error
+			if elems[i], err = e.zero(m.Type); err != nil {
+				return value{}, err
+			}
+		}
+		return value{elems: elems, inner: inner}, nil
+	}
+	return value{}, fmt.Errorf("no zero value for %T", inner)
+}
+
+func zeroScalar(t ir.ScalarType) (value, error) {
+	s, err := convert(ir.LiteralAbstractInt(0), t.Kind, t.Width)
+	return value{scalar: s}, err
+}
+
+func splatZero(inner ir.TypeInner, scalar ir.ScalarType, n int) (value, error) {
+	z, err := zeroScalar(scalar)
+	if err != nil {
+		return value{}, err
+	}
+	elems := make([]value, n)
+	for i := range elems {
+		elems[i] = z
+	}
+	return value{elems: elems, inner: inner}, nil
+}
+
+// compose builds a composite. Vector components that are themselves
+// vectors contribute each of their elements, and a matrix built from
+// scalars takes them column by column.
+func (e *evaluator) compose(c ir.ExprCompose) (value, error) {
+	if int(c.Type) >= len(e.module.Types) {
+		return value{}, fmt.Errorf("type %d out of range", c.Type)
+	}
+	inner := e.module.Types[c.Type].Inner
+	elems := make([]value, 0, len(c.Components))
+	for _, h := range c.Components {
+		v, err := e.eval(h)
+		if err != nil {
+			return value{}, err
+		}
+		if _, isVector := inner.(ir.VectorType); isVector && v.scalar == nil {
+			elems = append(elems, v.elems...)
+			continue
+		}
+		elems = append(elems, v)
+	}
+	if m, ok := inner.(ir.MatrixType); ok && len(elems) == int(m.Columns)*int(m.Rows) {
+		columns := make([]value, m.Columns)
+		for i := range columns {
+			columns[i] = value{
+				elems: elems[i*int(m.Rows) : (i+1)*int(m.Rows)],
+				inner: ir.VectorType{Size: m.Rows, Scalar: m.Scalar},
+			}
+		}
+		elems = columns
+	}
+	return value{elems: elems, inner: inner}, nil
+}
+
+func (e *evaluator) vector(h ir.ExpressionHandle) (value, error) {
+	v, err := e.eval(h)
+	if err != nil {
+		return value{}, err
+	}
+	if _, ok := v.inner.(ir.VectorType); !ok {
+		return value{}, fmt.Errorf("swizzle of a non-vector")
+	}
+	return v, nil
+}
+
+func (e *evaluator) index(base ir.ExpressionHandle, i int64) (value, error) {
+	v, err := e.eval(base)
+	if err != nil {
+		return value{}, err
+	}
+	if v.scalar != nil {
+		return value{}, fmt.Errorf("index into a scalar")
+	}
+	if i < 0 || i >= int64(len(v.elems)) {
+		return value{}, fmt.Errorf("index %d out of range for %d elements", i, len(v.elems))
+	}
+	return v.elems[i], nil
+}
+
+// binary applies a binary operator to scalars or vectors, a scalar
+// operand of a vector operation applying to every element.
+func (e *evaluator) binary(b ir.ExprBinary) (value, error) {
+	left, err := e.eval(b.Left)
+	if err != nil {
+		return value{}, err
+	}
+	right, err := e.eval(b.Right)
+	if err != nil {
+		return value{}, err
+	}
+	fold := func(l, r ir.LiteralValue) (ir.LiteralValue, error) {
+		v, ok := ir.FoldBinary(b.Op, l, r)
+		if !ok {
+			return nil, fmt.Errorf("cannot apply binary operator %d to %T and %T", b.Op, l, r)
+		}
+		return v, nil
+	}
+	switch {
+	case left.scalar != nil && right.scalar != nil:
+		s, err := fold(left.scalar, right.scalar)
+		return value{scalar: s}, err
+	case left.scalar != nil:
+		return componentwise(right, func(r ir.LiteralValue) (ir.LiteralValue, error) { return fold(left.scalar, r) })
+	case right.scalar != nil:
+		return componentwise(left, func(l ir.LiteralValue) (ir.LiteralValue, error) { return fold(l, right.scalar) })
+	}
+	if _, ok := left.inner.(ir.VectorType); !ok || len(left.elems) != len(right.elems) {
+		return value{}, fmt.Errorf("binary operator %d on %T and %T", b.Op, left.inner, right.inner)
+	}
+	elems := make([]value, len(left.elems))
+	for i := range elems {
+		s, err := fold(left.elems[i].scalar, right.elems[i].scalar)
+		if err != nil {
+			return value{}, err
+		}
+		elems[i] = value{scalar: s}
+	}
+	return value{elems: elems, inner: vectorOf(elems)}, nil
+}
+
+// selectValue picks between accept and reject by a bool, or element by element
+// by a vector of bools.
+func (e *evaluator) selectValue(s ir.ExprSelect) (value, error) {
+	cond, err := e.eval(s.Condition)
+	if err != nil {
+		return value{}, err
+	}
+	accept, err := e.eval(s.Accept)
+	if err != nil {
+		return value{}, err
+	}
+	reject, err := e.eval(s.Reject)
+	if err != nil {
+		return value{}, err
+	}
+	if b, ok := cond.scalar.(ir.LiteralBool); ok {
+		if b {
+			return accept, nil
+		}
+		return reject, nil
+	}
+	if len(cond.elems) != len(accept.elems) || len(cond.elems) != len(reject.elems) {
+		return value{}, fmt.Errorf("select condition does not match its operands")
+	}
+	elems := make([]value, len(cond.elems))
+	for i, c := range cond.elems {
+		b, ok := c.scalar.(ir.LiteralBool)
+		if !ok {
+			return value{}, fmt.Errorf("select condition is not a bool")
+		}
+		if b {
+			elems[i] = accept.elems[i]
+		} else {
+			elems[i] = reject.elems[i]
+		}
+	}
+	return value{elems: elems, inner: accept.inner}, nil
+}
+
+// componentwise applies f to a scalar, or to each element of a vector.
+func componentwise(v value, f func(ir.LiteralValue) (ir.LiteralValue, error)) (value, error) {
+	if v.scalar != nil {
+		s, err := f(v.scalar)
+		return value{scalar: s}, err
+	}
+	if _, ok := v.inner.(ir.VectorType); !ok {
+		return value{}, fmt.Errorf("operation on %T", v.inner)
+	}
+	elems := make([]value, len(v.elems))
+	for i, el := range v.elems {
+		s, err := f(el.scalar)
+		if err != nil {
+			return value{}, err
+		}
+		elems[i] = value{scalar: s}
+	}
+	return value{elems: elems, inner: vectorOf(elems)}, nil
+}
+
+func vectorOf(elems []value) ir.VectorType {
+	return ir.VectorType{Size: ir.VectorSize(len(elems)), Scalar: scalarType(elems[0].scalar)}
+}
+
+func scalarType(s ir.LiteralValue) ir.ScalarType {
+	switch s.(type) {
+	case ir.LiteralBool:
+		return ir.ScalarType{Kind: ir.ScalarBool, Width: 1}
+	case ir.LiteralI32:
+		return ir.ScalarType{Kind: ir.ScalarSint, Width: 4}
+	case ir.LiteralU32:
+		return ir.ScalarType{Kind: ir.ScalarUint, Width: 4}
+	case ir.LiteralI64:
+		return ir.ScalarType{Kind: ir.ScalarSint, Width: 8}
+	case ir.LiteralU64:
+		return ir.ScalarType{Kind: ir.ScalarUint, Width: 8}
+	case ir.LiteralF16:
+		return ir.ScalarType{Kind: ir.ScalarFloat, Width: 2}
+	case ir.LiteralF32:
+		return ir.ScalarType{Kind: ir.ScalarFloat, Width: 4}
+	case ir.LiteralF64:
+		return ir.ScalarType{Kind: ir.ScalarFloat, Width: 8}
+	case ir.LiteralAbstractInt:
+		return ir.ScalarType{Kind: ir.ScalarAbstractInt, Width: 8}
+	}
+	return ir.ScalarType{Kind: ir.ScalarAbstractFloat, Width: 8}
+}
+
+// integer returns the value of an integer literal.
+func integer(s ir.LiteralValue) (int64, bool) {
+	switch v := s.(type) {
+	case ir.LiteralI32:
+		return int64(v), true
+	case ir.LiteralU32:
+		return int64(v), true
+	case ir.LiteralI64:
+		return int64(v), true
+	case ir.LiteralU64:
+		return int64(v), v <= math.MaxInt64
+	case ir.LiteralAbstractInt:
+		return int64(v), true
+	}
+	return 0, false
+}
+
+// convert converts a scalar to the scalar type of kind and width.
+func convert(s ir.LiteralValue, kind ir.ScalarKind, width uint8) (ir.LiteralValue, error) {
+	v, ok := ir.FoldConvert(s, kind, width)
+	if !ok {
+		return nil, fmt.Errorf("cannot convert %T to scalar kind %d width %d", s, kind, width)
+	}
+	return v, nil
+}
+
+// bitcast reinterprets the bits of a scalar as kind.
+func bitcast(s ir.LiteralValue, kind ir.ScalarKind) (ir.LiteralValue, error) {
+	v, ok := ir.FoldBitcast(s, kind)
+	if !ok {
+		return nil, fmt.Errorf("cannot bitcast %T to scalar kind %d", s, kind)
+	}
+	return v, nil
+}
+
+// goValue converts v to the Go value Eval returns.
+func (v value) goValue() any {
+	if v.scalar != nil {
+		return goScalar(v.scalar)
+	}
+	switch t := v.inner.(type) {
+	case ir.VectorType:
+		return goVector(v.elems)
+	case ir.MatrixType:
+		columns := make([]any, len(v.elems))
+		for i, c := range v.elems {
+			columns[i] = goVector(c.elems)
+		}
+		return goColumns(columns)
+	case ir.StructType:
+		fields := make(map[string]any, len(v.elems))
+		for i, el := range v.elems {
+			if i < len(t.Members) {
+				fields[t.Members[i].Name] = el.goValue()
+			}
+		}
+		return fields
+	}
+	elems := make([]any, len(v.elems))
+	for i, el := range v.elems {
+		elems[i] = el.goValue()
+	}
+	return elems
+}
+
+func goScalar(s ir.LiteralValue) any {
+	switch v := s.(type) {
+	case ir.LiteralBool:
+		return bool(v)
+	case ir.LiteralI32:
+		return int32(v)
+	case ir.LiteralU32:
+		return uint32(v)
+	case ir.LiteralI64:
+		return int64(v)
+	case ir.LiteralU64:
+		return uint64(v)
+	case ir.LiteralF16:
+		return float32(v)
+	case ir.LiteralF32:
+		return float32(v)
+	case ir.LiteralF64:
+		return float64(v)
+	case ir.LiteralAbstractInt:
+		return int64(v)
+	case ir.LiteralAbstractFloat:
+		return float64(v)
+	}
+	return nil
+}
+
+// goVector returns the elements of a vector as a slice of their Go type.
+func goVector(elems []value) any {
+	if len(elems) == 0 {
+		return []any{}
+	}
+	switch goScalar(elems[0].scalar).(type) {
+	case bool:
+		return typedSlice[bool](elems)
+	case int32:
+		return typedSlice[int32](elems)
+	case uint32:
+		return typedSlice[uint32](elems)
+	case int64:
+		return typedSlice[int64](elems)
+	case uint64:
+		return typedSlice[uint64](elems)
+	case float32:
+		return typedSlice[float32](elems)
+	}
+	return typedSlice[float64](elems)
+}
+
+func typedSlice[T any](elems []value) []T {
+	out := make([]T, len(elems))
+	for i, el := range elems {
+		out[i], _ = goScalar(el.scalar).(T)
+	}
+	return out
+}
+
+// goColumns returns matrix columns as a slice of their vector type.
+func goColumns(columns []any) any {
+	if len(columns) == 0 {
+		return [][]float32{}
+	}
+	switch columns[0].(type) {
+	case []float32:
+		return typedColumns[float32](columns)
+	case []float64:
+		return typedColumns[float64](columns)
+	}
+	return columns
+}
+
+func typedColumns[T any](columns []any) [][]T {
+	out := make([][]T, len(columns))
+	for i, c := range columns {
+		out[i], _ = c.([]T)
+	}
+	return out
+}
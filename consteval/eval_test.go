@@ -0,0 +1,160 @@
+package consteval
+
+import (
+	"errors"
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/gogpu/naga/internal/testutil"
+	"github.com/gogpu/naga/ir"
+)
+
+const constShader = `
+struct Kernel { radius: u32, weights: vec2<f32> }
+
+const TILE = 8u * 2u;
+const HALF = TILE / 2u;
+const ENABLED = TILE > 4u;
+const OFFSET: i32 = -3;
+const SCALE = vec3f(1.0, 2.0, 3.0) * 2.0;
+const KERNEL = Kernel(4u, vec2f(0.25, 0.5));
+const BASIS = mat2x2f(1.0, 2.0, 3.0, 4.0);
+const SIZES = array<u32, 3>(1u, 2u, 4u);
+const R = 3;
+const WIDE = R * 2 + 1;
+const DIAM: i32 = R * 2 + 1;
+override BLOCK: u32 = 64u;
+override UNSET: f32;
+
+@compute @workgroup_size(TILE)
+fn main() {
+    _ = HALF; _ = ENABLED; _ = OFFSET; _ = SCALE; _ = KERNEL; _ = BASIS; _ = SIZES; _ = WIDE; _ = DIAM;
+    _ = BLOCK; _ = UNSET;
+}
+`
+
+func TestConstant(t *testing.T) {
+	module := testutil.LowerWGSL(t, constShader)
+	tests := []struct {
+		name string
+		want any
+	}{
+		{"TILE", uint32(16)},
+		{"HALF", uint32(8)},
+		{"ENABLED", true},
+		{"OFFSET", int32(-3)},
+		{"SCALE", []float32{2, 4, 6}},
+		{"KERNEL", map[string]any{"radius": uint32(4), "weights": []float32{0.25, 0.5}}},
+		{"BASIS", [][]float32{{1, 2}, {3, 4}}},
+		{"SIZES", []any{uint32(1), uint32(2), uint32(4)}},
+		{"DIAM", int32(7)},
+	}
+	for _, tt := range tests {
+		got, err := Constant(module, tt.name)
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s = %#v, want %#v", tt.name, got, tt.want)
+		}
+	}
+
+	// Abstract constants are inlined by lowering, like missing ones.
+	for _, name := range []string{"MISSING", "R", "WIDE"} {
+		if _, err := Constant(module, name); !errors.Is(err, ErrNotFound) {
+			t.Errorf("%s: err = %v, want ErrNotFound", name, err)
+		}
+	}
+}
+
+func TestEvalOverride(t *testing.T) {
+	module := testutil.LowerWGSL(t, constShader)
+	for _, o := range module.Overrides {
+		if o.Init == nil {
+			continue
+		}
+		got, err := Eval(module, *o.Init)
+		if err != nil {
+			t.Fatalf("%s: %v", o.Name, err)
+		}
+		if got != uint32(64) {
+			t.Errorf("%s = %#v, want 64", o.Name, got)
+		}
+	}
+
+	module.GlobalExpressions = append(module.GlobalExpressions, ir.Expression{Kind: ir.ExprOverride{Override: 1}})
+	_, err := Eval(module, ir.ExpressionHandle(len(module.GlobalExpressions)-1))
+	if err == nil || !strings.Contains(err.Error(), `override "UNSET" has no default value`) {
+		t.Errorf("override without default: err = %v", err)
+	}
+}
+
+// TestEvalExpressions evaluates the expression kinds the WGSL lowerer
+// folds away before they reach GlobalExpressions.
+func TestEvalExpressions(t *testing.T) {
+	u32 := ir.ScalarType{Kind: ir.ScalarUint, Width: 4}
+	f32 := ir.ScalarType{Kind: ir.ScalarFloat, Width: 4}
+	four := uint32(4)
+	width4 := uint8(4)
+	module := &ir.Module{
+		Types: []ir.Type{
+			{Inner: u32},
+			{Inner: ir.VectorType{Size: ir.Vec3, Scalar: f32}},
+			{Inner: ir.ArrayType{Base: 0, Size: ir.ArraySize{Constant: &four}}},
+		},
+	}
+	add := func(kind ir.ExpressionKind) ir.ExpressionHandle {
+		module.GlobalExpressions = append(module.GlobalExpressions, ir.Expression{Kind: kind})
+		return ir.ExpressionHandle(len(module.GlobalExpressions) - 1)
+	}
+	lit := func(v ir.LiteralValue) ir.ExpressionHandle { return add(ir.Literal{Value: v}) }
+
+	seven := lit(ir.LiteralU32(7))
+	two := lit(ir.LiteralU32(2))
+	half := lit(ir.LiteralF32(0.5))
+	vec := add(ir.ExprCompose{Type: 1, Components: []ir.ExpressionHandle{half, lit(ir.LiteralF32(1.5)), lit(ir.LiteralF32(-2.75))}})
+	yes := lit(ir.LiteralBool(true))
+
+	tests := []struct {
+		name string
+		expr ir.ExpressionHandle
+		want any
+	}{
+		{"binary", add(ir.ExprBinary{Op: ir.BinaryShiftLeft, Left: seven, Right: two}), uint32(28)},
+		{"unary", add(ir.ExprUnary{Op: ir.UnaryBitwiseNot, Expr: two}), uint32(math.MaxUint32 - 2)},
+		{"splat", add(ir.ExprSplat{Size: ir.Vec2, Value: half}), []float32{0.5, 0.5}},
+		{"vector times scalar", add(ir.ExprBinary{Op: ir.BinaryMultiply, Left: vec, Right: lit(ir.LiteralF32(2))}), []float32{1, 3, -5.5}},
+		{"swizzle", add(ir.ExprSwizzle{Size: ir.Vec2, Vector: vec, Pattern: [4]ir.SwizzleComponent{ir.SwizzleZ, ir.SwizzleX}}), []float32{-2.75, 0.5}},
+		{"access", add(ir.ExprAccess{Base: vec, Index: lit(ir.LiteralI32(1))}), float32(1.5)},
+		{"access index", add(ir.ExprAccessIndex{Base: vec, Index: 2}), float32(-2.75)},
+		{"zero array", add(ir.ExprZeroValue{Type: 2}), []any{uint32(0), uint32(0), uint32(0), uint32(0)}},
+		{"select", add(ir.ExprSelect{Condition: yes, Accept: seven, Reject: two}), uint32(7)},
+		{"convert truncates", add(ir.ExprAs{Expr: vec, Kind: ir.ScalarSint, Convert: &width4}), []int32{0, 1, -2}},
+		{"convert saturates", add(ir.ExprAs{Expr: lit(ir.LiteralF32(-1)), Kind: ir.ScalarUint, Convert: &width4}), uint32(0)},
+		{"bitcast", add(ir.ExprAs{Expr: lit(ir.LiteralF32(1)), Kind: ir.ScalarUint}), uint32(0x3f800000)},
+	}
+	for _, tt := range tests {
+		got, err := Eval(module, tt.expr)
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s = %#v, want %#v", tt.name, got, tt.want)
+		}
+	}
+
+	for name, expr := range map[string]ir.ExpressionHandle{
+		"divide by zero": add(ir.ExprBinary{Op: ir.BinaryDivide, Left: seven, Right: lit(ir.LiteralU32(0))}),
+		"out of range":   add(ir.ExprAccessIndex{Base: vec, Index: 3}),
+		"mixed types":    add(ir.ExprBinary{Op: ir.BinaryAdd, Left: seven, Right: half}),
+		"bad handle":     ir.ExpressionHandle(1000),
+	} {
+		if _, err := Eval(module, expr); err == nil || !strings.HasPrefix(err.Error(), "consteval: ") {
+			t.Errorf("%s: err = %v, want a consteval error", name, err)
+		}
+	}
+}
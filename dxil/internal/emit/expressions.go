@@ -4871,7 +4871,7 @@ func (e *Emitter) phiResultType(fn *ir.Function, phi ir.ExprPhi) (*module.Type,
 	if len(phi.Incoming) == 0 {
 		return nil, fmt.Errorf("phi has no incomings")
 	}
-	resolution, err := ir.ResolveExpressionType(e.ir, fn, phi.Incoming[0].Value)
+	resolution, err := ir.ExpressionType(e.ir, fn, phi.Incoming[0].Value)
 	if err != nil {
 		return nil, err
 	}
@@ -0,0 +1,181 @@
+package naga
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/gogpu/naga/glsl"
+	"github.com/gogpu/naga/hlsl"
+	"github.com/gogpu/naga/ir"
+	"github.com/gogpu/naga/wgsl"
+)
+
+// Frontend translates shader source in one language into IR. WGSL is the
+// built-in frontend; others implement the same interface so tools can
+// accept several source languages without special cases.
+type Frontend interface {
+	// Translate parses and lowers source. file names the source in
+	// diagnostics and may be empty. A failure that can be located in the
+	// source is reported as a *DiagnosticError.
+	Translate(ctx context.Context, file, source string) (*FrontendResult, error)
+}
+
+// FrontendResult is a translated module and the warnings produced on the
+// way.
+type FrontendResult struct {
+	Module   *ir.Module
+	Warnings []Diagnostic
+}
+
+// Diagnostic is one message located in a source file. A zero Span line
+// means the message has no location.
+type Diagnostic struct {
+	File    string
+	Span    wgsl.Span
+	Message string
+}
+
+// String formats d as "file:line:column: message", omitting the parts it
+// does not have.
+func (d Diagnostic) String() string {
+	prefix := d.File
+	switch start := d.Span.Start; {
+	case start.Line > 0 && start.Column > 0:
+		prefix = fmt.Sprintf("%s:%d:%d", d.File, start.Line, start.Column)
+	case start.Line > 0:
+		prefix = fmt.Sprintf("%s:%d", d.File, start.Line)
+	}
+	if prefix == "" {
+		return d.Message
+	}
+	return prefix + ": " + d.Message
+}
+
+// DiagnosticError reports the errors that stopped a frontend, each
+// located in the source where possible. Err is the frontend's original
+// error.
+type DiagnosticError struct {
+	Diagnostics []Diagnostic
+	Err         error
+}
+
+// Error implements the error interface.
+func (e *DiagnosticError) Error() string {
+	switch len(e.Diagnostics) {
+	case 0:
+		return e.Err.Error()
+	case 1:
+		return e.Diagnostics[0].String()
+	default:
+		return fmt.Sprintf("%s (and %d more errors)", e.Diagnostics[0], len(e.Diagnostics)-1)
+	}
+}
+
+// Unwrap returns the frontend's original error.
+func (e *DiagnosticError) Unwrap() error { return e.Err }
+
+// WGSLFrontend is the WGSL Frontend.
+type WGSLFrontend struct{}
+
+// Translate parses and lowers WGSL source; see ParseFile and LowerFile.
+func (WGSLFrontend) Translate(ctx context.Context, file, source string) (*FrontendResult, error) {
+	ast, err := ParseFile(file, source)
+	if err != nil {
+		return nil, err
+	}
+	return lowerFile(ctx, file, ast, source)
+}
+
+// GLSLComputeFrontend is the Frontend for GLSL compute shaders; see
+// glsl.TranslateCompute for the accepted subset. It reports no warnings.
+type GLSLComputeFrontend struct{}
+
+// Translate translates a GLSL compute shader.
+func (GLSLComputeFrontend) Translate(ctx context.Context, file, source string) (*FrontendResult, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	module, err := glsl.TranslateCompute(source)
+	if err != nil {
+		var fe *glsl.FrontendError
+		if !errors.As(err, &fe) {
+			return nil, &DiagnosticError{Err: err}
+		}
+		return nil, locatedError(file, fe.Line, fe.Column, fe.Message, err)
+	}
+	return &FrontendResult{Module: module}, nil
+}
+
+// HLSLFrontend is the Frontend for HLSL shaders; see hlsl.TranslateShader
+// for the accepted subset. Options names the vertex and fragment entry
+// points and may be nil for compute shaders. It reports no warnings.
+type HLSLFrontend struct {
+	Options *hlsl.FrontendOptions
+}
+
+// Translate translates an HLSL shader.
+func (f HLSLFrontend) Translate(ctx context.Context, file, source string) (*FrontendResult, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	module, err := hlsl.TranslateShader(source, f.Options)
+	if err != nil {
+		var fe *hlsl.FrontendError
+		if !errors.As(err, &fe) {
+			return nil, &DiagnosticError{Err: err}
+		}
+		return nil, locatedError(file, fe.Line, fe.Column, fe.Message, err)
+	}
+	return &FrontendResult{Module: module}, nil
+}
+
+// locatedError wraps a frontend error located at line and column, which
+// may be 0 when unknown.
+func locatedError(file string, line, column int, message string, err error) *DiagnosticError {
+	pos := wgsl.Position{Line: line, Column: column}
+	diag := Diagnostic{File: file, Span: wgsl.Span{Start: pos, End: pos}, Message: message}
+	return &DiagnosticError{Diagnostics: []Diagnostic{diag}, Err: err}
+}
+
+// ParseFile is Parse with a file name for diagnostics: errors are
+// *DiagnosticError values whose diagnostics carry file and position.
+func ParseFile(file, source string) (*wgsl.Module, error) {
+	ast, err := Parse(source)
+	if err != nil {
+		return nil, diagnosticError(file, err)
+	}
+	return ast, nil
+}
+
+// LowerFile lowers a parsed WGSL module like LowerWithSource, but also
+// returns the lowering warnings, located in file, and reports errors as a
+// *DiagnosticError listing every failing declaration.
+func LowerFile(file string, ast *wgsl.Module, source string) (*FrontendResult, error) {
+	return lowerFile(context.Background(), file, ast, source)
+}
+
+func lowerFile(ctx context.Context, file string, ast *wgsl.Module, source string) (*FrontendResult, error) {
+	result, err := wgsl.LowerWithWarningsContext(ctx, ast, source)
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, err
+		}
+		return nil, diagnosticError(file, err)
+	}
+	warnings := make([]Diagnostic, len(result.Warnings))
+	for i, w := range result.Warnings {
+		warnings[i] = Diagnostic{File: file, Span: w.Span, Message: w.Message}
+	}
+	return &FrontendResult{Module: result.Module, Warnings: warnings}, nil
+}
+
+// diagnosticError wraps a WGSL frontend error with its located errors.
+func diagnosticError(file string, err error) *DiagnosticError {
+	located := wgsl.Errors(err)
+	diags := make([]Diagnostic, len(located))
+	for i, e := range located {
+		diags[i] = Diagnostic{File: file, Span: e.Span, Message: e.Message}
+	}
+	return &DiagnosticError{Diagnostics: diags, Err: err}
+}
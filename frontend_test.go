@@ -0,0 +1,142 @@
+package naga
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestWGSLFrontendWarnings(t *testing.T) {
+	const source = `
+@fragment
+fn main() -> @location(0) vec4<f32> {
+    var unused = 1.0;
+    return vec4<f32>(1.0);
+}
+`
+	var fe Frontend = WGSLFrontend{}
+	result, err := fe.Translate(context.Background(), "shader.wgsl", source)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Module == nil || len(result.Module.EntryPoints) != 1 {
+		t.Fatal("expected a module with one entry point")
+	}
+	if len(result.Warnings) == 0 {
+		t.Fatal("expected an unused variable warning")
+	}
+	w := result.Warnings[0]
+	if w.File != "shader.wgsl" || w.Span.Start.Line != 4 {
+		t.Errorf("warning at %s:%d, want shader.wgsl:4", w.File, w.Span.Start.Line)
+	}
+	if got := w.String(); !strings.HasPrefix(got, "shader.wgsl:4:") {
+		t.Errorf("warning string = %q, want shader.wgsl:4:... prefix", got)
+	}
+}
+
+func TestWGSLFrontendErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		source   string
+		wantLine []int
+	}{
+		{"parse", "fn main( {}", []int{1}},
+		{"lower", `
+fn a() -> f32 { return missing; }
+
+fn b() { undefined_fn(); }
+`, []int{2, 4}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := WGSLFrontend{}.Translate(context.Background(), "bad.wgsl", tt.source)
+			var de *DiagnosticError
+			if !errors.As(err, &de) {
+				t.Fatalf("error = %v (%T), want *DiagnosticError", err, err)
+			}
+			var lines []int
+			for _, d := range de.Diagnostics {
+				if d.File != "bad.wgsl" {
+					t.Errorf("diagnostic file = %q, want bad.wgsl", d.File)
+				}
+				lines = append(lines, d.Span.Start.Line)
+			}
+			if len(lines) != len(tt.wantLine) {
+				t.Fatalf("diagnostic lines = %v, want %v", lines, tt.wantLine)
+			}
+			for i := range lines {
+				if lines[i] != tt.wantLine[i] {
+					t.Errorf("diagnostic lines = %v, want %v", lines, tt.wantLine)
+				}
+			}
+			if !strings.HasPrefix(err.Error(), "bad.wgsl:") {
+				t.Errorf("error = %q, want a bad.wgsl: prefix", err)
+			}
+		})
+	}
+}
+
+func TestWGSLFrontendCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err := WGSLFrontend{}.Translate(ctx, "", "fn main() {}")
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("error = %v, want context.Canceled", err)
+	}
+}
+
+func TestGLSLComputeFrontend(t *testing.T) {
+	const source = `#version 450
+layout(local_size_x = 64) in;
+layout(std430, binding = 0) buffer Data { float values[]; };
+
+void main() {
+    values[gl_GlobalInvocationID.x] *= 2.0;
+}
+`
+	var fe Frontend = GLSLComputeFrontend{}
+	result, err := fe.Translate(context.Background(), "double.comp", source)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Module.EntryPoints) != 1 || result.Module.EntryPoints[0].Workgroup != [3]uint32{64, 1, 1} {
+		t.Errorf("entry points = %+v, want one with workgroup size 64", result.Module.EntryPoints)
+	}
+
+	_, err = fe.Translate(context.Background(), "bad.comp", "#version 450\nlayout(local_size_x = 1) in;\nvoid main() {\n    y = 1;\n}\n")
+	var de *DiagnosticError
+	if !errors.As(err, &de) || len(de.Diagnostics) != 1 {
+		t.Fatalf("error = %v, want one diagnostic", err)
+	}
+	if got, want := err.Error(), "bad.comp:4:5: undeclared identifier y"; got != want {
+		t.Errorf("error = %q, want %q", got, want)
+	}
+}
+
+func TestHLSLFrontend(t *testing.T) {
+	const source = `RWStructuredBuffer<float> values : register(u0);
+
+[numthreads(64, 1, 1)]
+void main(uint3 id : SV_DispatchThreadID) {
+    values[id.x] *= 2.0;
+}
+`
+	var fe Frontend = HLSLFrontend{}
+	result, err := fe.Translate(context.Background(), "double.hlsl", source)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Module.EntryPoints) != 1 || result.Module.EntryPoints[0].Workgroup != [3]uint32{64, 1, 1} {
+		t.Errorf("entry points = %+v, want one with workgroup size 64", result.Module.EntryPoints)
+	}
+
+	_, err = fe.Translate(context.Background(), "bad.hlsl", "[numthreads(1, 1, 1)]\nvoid main() {\n    y = 1;\n}\n")
+	var de *DiagnosticError
+	if !errors.As(err, &de) || len(de.Diagnostics) != 1 {
+		t.Fatalf("error = %v, want one diagnostic", err)
+	}
+	if got, want := err.Error(), "bad.hlsl:3:5: undeclared identifier y"; got != want {
+		t.Errorf("error = %q, want %q", got, want)
+	}
+}
@@ -1,7 +1,8 @@
 // Copyright 2025 The GoGPU Authors
 // SPDX-License-Identifier: MIT
 
-// Package glsl provides a GLSL (OpenGL Shading Language) backend for naga.
+// Package glsl provides a GLSL (OpenGL Shading Language) backend for naga,
+// and a frontend for GLSL compute shaders.
 //
 // This package generates GLSL source code from naga's IR representation.
 // It supports multiple GLSL versions for different target platforms:
@@ -23,6 +24,18 @@
 // The backend automatically generates combined sampler uniforms
 // for texture-sampler pairs used together.
 //
+// # Compute Shader Frontend
+//
+// TranslateCompute goes the other way for one narrow case: it reads a
+// GLSL compute shader (#version 430 or later) and returns IR, so compute
+// kernels written for OpenGL can be compiled for Vulkan, Metal, or D3D:
+//
+//	module, err := glsl.TranslateCompute(source)
+//
+// The shader is rewritten into WGSL, which ComputeToWGSL returns, and
+// lowered by the WGSL frontend. Buffer and uniform blocks, shared memory,
+// barriers, and atomics are supported; images and samplers are not.
+//
 // # Reserved Words
 //
 // GLSL has over 500 reserved words (including future reserved).
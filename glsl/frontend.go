@@ -0,0 +1,96 @@
+// Copyright 2025 The GoGPU Authors
+// SPDX-License-Identifier: MIT
+
+package glsl
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/gogpu/naga/glsl/internal/frontend"
+	"github.com/gogpu/naga/ir"
+	"github.com/gogpu/naga/wgsl"
+)
+
+// FrontendError is an error in GLSL source given to TranslateCompute or
+// ComputeToWGSL. Column is 0 when only the line is known.
+type FrontendError struct {
+	Line    int
+	Column  int
+	Message string
+}
+
+// Error implements the error interface.
+func (e *FrontendError) Error() string {
+	if e.Column == 0 {
+		return fmt.Sprintf("%d: %s", e.Line, e.Message)
+	}
+	return fmt.Sprintf("%d:%d: %s", e.Line, e.Column, e.Message)
+}
+
+// TranslateCompute translates a GLSL compute shader (#version 430 or
+// later) into IR.
+//
+// The shader is rewritten into WGSL and lowered by the WGSL frontend, so
+// it must stay within what both languages express: buffer and uniform
+// blocks, shared variables, structs, constants, helper functions with in
+// parameters, barriers, atomics, and the built-in functions with a WGSL
+// counterpart. Images, samplers, subroutines, and double precision are
+// rejected. Errors are *FrontendError values located in the GLSL source.
+func TranslateCompute(source string) (*ir.Module, error) {
+	res, err := translateCompute(source)
+	if err != nil {
+		return nil, err
+	}
+	tokens, err := wgsl.NewLexer(res.WGSL).Tokenize()
+	if err != nil {
+		return nil, computeError(res, err)
+	}
+	ast, err := wgsl.NewParser(tokens).Parse()
+	if err != nil {
+		return nil, computeError(res, err)
+	}
+	module, err := wgsl.LowerWithSource(ast, res.WGSL)
+	if err != nil {
+		return nil, computeError(res, err)
+	}
+	return module, nil
+}
+
+// ComputeToWGSL translates a GLSL compute shader into the WGSL source
+// TranslateCompute lowers. It is useful for inspecting the translation.
+func ComputeToWGSL(source string) (string, error) {
+	res, err := translateCompute(source)
+	if err != nil {
+		return "", err
+	}
+	return res.WGSL, nil
+}
+
+func translateCompute(source string) (*frontend.Result, error) {
+	res, err := frontend.Translate(source)
+	if err != nil {
+		var fe *frontend.Error
+		if errors.As(err, &fe) {
+			return nil, &FrontendError{Line: fe.Line, Column: fe.Column, Message: fe.Message}
+		}
+		return nil, err
+	}
+	return res, nil
+}
+
+// computeError maps an error in the translated WGSL back to the GLSL line
+// it came from. The WGSL column says nothing about the GLSL one, so only
+// the line is reported.
+func computeError(res *frontend.Result, err error) error {
+	located := wgsl.Errors(err)
+	if len(located) == 0 {
+		return fmt.Errorf("glsl: translated shader failed to compile: %w", err)
+	}
+	e := located[0]
+	line := 0
+	if l := e.Span.Start.Line; l >= 1 && l <= len(res.Lines) {
+		line = res.Lines[l-1]
+	}
+	return &FrontendError{Line: line, Message: e.Message}
+}
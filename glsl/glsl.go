@@ -4,7 +4,9 @@
 package glsl
 
 import (
+	"context"
 	"fmt"
+	"log/slog"
 
 	"github.com/gogpu/naga/glsl/internal/codegen"
 	"github.com/gogpu/naga/ir"
@@ -17,11 +19,18 @@ type Version struct {
 	ES    bool // true for GLSL ES (OpenGL ES / WebGL)
 }
 
-// String returns the version as a GLSL version directive value.
+// String returns the version as a GLSL version directive value. GLSL ES
+// 1.00 and desktop versions before 1.50 take no suffix.
 func (v Version) String() string {
 	if v.ES {
+		if v.Major < 3 {
+			return v.VersionNumber()
+		}
 		return fmt.Sprintf("%d%02d es", v.Major, v.Minor)
 	}
+	if int(v.Major)*100+int(v.Minor) < 150 {
+		return v.VersionNumber()
+	}
 	return fmt.Sprintf("%d%02d core", v.Major, v.Minor)
 }
 
@@ -72,6 +81,16 @@ var (
 	VersionES300 = Version{Major: 3, Minor: 0, ES: true}  // ES 3.0 / WebGL 2.0
 	VersionES310 = Version{Major: 3, Minor: 10, ES: true} // ES 3.1 (compute shaders)
 	VersionES320 = Version{Major: 3, Minor: 20, ES: true} // ES 3.2
+
+	// Legacy versions for OpenGL 2.1 / OpenGL ES 2.0 / WebGL 1 drivers.
+	// Inputs and outputs become attribute/varying and gl_FragColor (or
+	// gl_FragData[N]), uniform buffers become plain struct uniforms
+	// (UniformInfo.Plain), and sampling uses texture2D/textureCube/shadow2D.
+	// Shaders using unsigned integers, bit operations, switch, storage
+	// buffers, texel loads or texture queries are rejected with an error,
+	// as are loops on VersionES100, which needs constant iteration counts.
+	Version120   = Version{Major: 1, Minor: 20, ES: false} // OpenGL 2.1
+	VersionES100 = Version{Major: 1, Minor: 0, ES: true}   // ES 2.0 / WebGL 1.0
 )
 
 // WriterFlags control output formatting.
@@ -96,8 +115,9 @@ const (
 	// (Y-down, Z in [0,1]) to OpenGL conventions (Y-up, Z in [-1,1]).
 	WriterFlagAdjustCoordinateSpace
 
-	// WriterFlagForcePointSize emits gl_PointSize = 1.0 in vertex shaders.
-	// Required for WebGL compatibility.
+	// WriterFlagForcePointSize writes gl_PointSize (Options.PointSize, 1.0
+	// by default) in vertex shaders that don't output @builtin(point_size).
+	// GLES and WebGL need it to draw point primitives.
 	WriterFlagForcePointSize
 
 	// WriterFlagTextureShadowLod enables GL_EXT_texture_shadow_lod extension
@@ -157,10 +177,34 @@ type Options struct {
 	// WriterFlags control output formatting.
 	WriterFlags WriterFlags
 
+	// PointSize is the value WriterFlagForcePointSize writes to
+	// gl_PointSize. Zero means 1.0.
+	PointSize float32
+
+	// FragmentColorConversion converts the color outputs of a selected
+	// fragment entry point, e.g. linear to sRGB when the swapchain has no
+	// sRGB format (common on GLES2/WebGL1). Other stages are unaffected.
+	FragmentColorConversion ir.ColorConversion
+
 	// ForceHighPrecision forces highp precision for all float types (ES only).
 	// If false, uses default precision qualifiers.
 	ForceHighPrecision bool
 
+	// FastMathSafeFloatChecks emits isnan/isinf as bit-pattern tests that
+	// drivers cannot fold away under relaxed float semantics.
+	FastMathSafeFloatChecks bool
+
+	// FloatPrecision is the number of significant digits written for float
+	// literals. Zero writes the shortest form that reads back as the same
+	// value, such as 0.1 for the f32 nearest 0.1.
+	FloatPrecision int
+
+	// WriteMaskStores writes partial vector assignments, such as a GLSL
+	// v.zx = n or a store of a shuffle of the old and new vector, as
+	// assignments to the changed components rather than whole-vector
+	// stores.
+	WriteMaskStores bool
+
 	// BoundsCheckPolicies controls bounds checking for resource accesses.
 	BoundsCheckPolicies BoundsCheckPolicies
 
@@ -173,6 +217,23 @@ type Options struct {
 	// Values are float64 (NaN means "not set, use default").
 	// If provided, overrides are resolved before compilation.
 	PipelineConstants ir.PipelineConstants
+
+	// SymbolPrefix is prepended to module-scope types, functions,
+	// constants, unbound globals, and generated helper functions
+	// (naga_modf, naga_select, ...) so generated sources can be combined
+	// (e.g. via #include) without collisions. GLSL has no entry point
+	// renaming: the selected entry point is always emitted as "main", and
+	// bound resources keep their _group_G_binding_B_stage names that the
+	// GL HAL relies on.
+	SymbolPrefix string
+
+	// SourceMap fills TranslationInfo.SourceMap.
+	SourceMap bool
+
+	// Logger, if set, receives debug traces of code generation phases and
+	// a warning for each polyfill emitted (e.g. "emulating modulo").
+	// nil disables logging.
+	Logger *slog.Logger
 }
 
 // TextureMapping describes a combined texture-sampler pair generated by the
@@ -206,6 +267,11 @@ type UniformInfo struct {
 
 	// IsStorage is true for storage buffers (SSBO), false for uniform buffers (UBO).
 	IsStorage bool
+
+	// Plain is true for Version120 and VersionES100, which have no uniform
+	// blocks: the buffer is a plain uniform named BlockName whose members
+	// are set with glUniform* at "BlockName.member".
+	Plain bool
 }
 
 // TranslationInfo contains metadata about the translation.
@@ -233,6 +299,10 @@ type TranslationInfo struct {
 	// names and source bindings. Used by GLES HAL for runtime binding
 	// fallback on GL < 4.2. Matches Rust naga ReflectionInfo.uniforms.
 	Uniforms []UniformInfo
+
+	// SourceMap, with Options.SourceMap, maps the 1-based lines where
+	// statements start to the WGSL spans they were generated from.
+	SourceMap []ir.OutputSpan
 }
 
 // DefaultOptions returns sensible default options for GLSL generation.
@@ -246,8 +316,14 @@ func DefaultOptions() Options {
 // Compile generates GLSL source code from an IR module.
 // Returns the GLSL source as a string, translation info, or an error.
 func Compile(module *ir.Module, options Options) (string, TranslationInfo, error) {
+	return CompileContext(context.Background(), module, options)
+}
+
+// CompileContext is like Compile but stops with an error wrapping ctx.Err()
+// once ctx is canceled. Cancellation is checked between functions.
+func CompileContext(ctx context.Context, module *ir.Module, options Options) (string, TranslationInfo, error) {
 	copts := toCodegenOptions(options)
-	src, cinfo, err := codegen.Compile(module, copts)
+	src, cinfo, err := codegen.CompileContext(ctx, module, copts)
 	if err != nil {
 		return "", TranslationInfo{}, err
 	}
@@ -269,19 +345,27 @@ func toCodegenOptions(o Options) codegen.Options {
 			Minor: o.LangVersion.Minor,
 			ES:    o.LangVersion.ES,
 		},
-		EntryPoint:         o.EntryPoint,
-		SamplerBindingBase: o.SamplerBindingBase,
-		TextureBindingBase: o.TextureBindingBase,
-		UniformBindingBase: o.UniformBindingBase,
-		StorageBindingBase: o.StorageBindingBase,
-		WriterFlags:        codegen.WriterFlags(o.WriterFlags),
-		ForceHighPrecision: o.ForceHighPrecision,
+		EntryPoint:              o.EntryPoint,
+		SamplerBindingBase:      o.SamplerBindingBase,
+		TextureBindingBase:      o.TextureBindingBase,
+		UniformBindingBase:      o.UniformBindingBase,
+		StorageBindingBase:      o.StorageBindingBase,
+		WriterFlags:             codegen.WriterFlags(o.WriterFlags),
+		PointSize:               o.PointSize,
+		FragmentColorConversion: o.FragmentColorConversion,
+		ForceHighPrecision:      o.ForceHighPrecision,
+		FastMathSafeFloatChecks: o.FastMathSafeFloatChecks,
+		FloatPrecision:          o.FloatPrecision,
+		WriteMaskStores:         o.WriteMaskStores,
 		BoundsCheckPolicies: codegen.BoundsCheckPolicies{
 			ImageLoad:  codegen.BoundsCheckPolicy(o.BoundsCheckPolicies.ImageLoad),
 			ImageStore: codegen.BoundsCheckPolicy(o.BoundsCheckPolicies.ImageStore),
 		},
 		BindingMap:        bindingMap,
 		PipelineConstants: o.PipelineConstants,
+		SymbolPrefix:      o.SymbolPrefix,
+		SourceMap:         o.SourceMap,
+		Logger:            o.Logger,
 	}
 }
 
@@ -305,6 +389,7 @@ func fromCodegenTranslationInfo(ci codegen.TranslationInfo) TranslationInfo {
 				BlockName: u.BlockName,
 				Binding:   u.Binding,
 				IsStorage: u.IsStorage,
+				Plain:     u.Plain,
 			}
 		}
 	}
@@ -319,5 +404,6 @@ func fromCodegenTranslationInfo(ci codegen.TranslationInfo) TranslationInfo {
 		TextureSamplerPairs: ci.TextureSamplerPairs,
 		TextureMappings:     texMappings,
 		Uniforms:            uniforms,
+		SourceMap:           ci.SourceMap,
 	}
 }
@@ -4,7 +4,9 @@
 package codegen
 
 import (
+	"context"
 	"fmt"
+	"log/slog"
 
 	"github.com/gogpu/naga/ir"
 )
@@ -31,13 +33,24 @@ var (
 	VersionES300 = Version{Major: 3, Minor: 0, ES: true}  // ES 3.0 / WebGL 2.0
 	VersionES310 = Version{Major: 3, Minor: 10, ES: true} // ES 3.1 (compute shaders)
 	VersionES320 = Version{Major: 3, Minor: 20, ES: true} // ES 3.2
+
+	// Legacy versions (attribute/varying, no uniform blocks)
+	Version120   = Version{Major: 1, Minor: 20, ES: false} // OpenGL 2.1
+	VersionES100 = Version{Major: 1, Minor: 0, ES: true}   // ES 2.0 / WebGL 1.0
 )
 
-// String returns the version as a GLSL version directive value.
+// String returns the version as a GLSL version directive value. GLSL ES
+// 1.00 and desktop versions before 1.50 take no suffix.
 func (v Version) String() string {
 	if v.ES {
+		if v.isLegacy() {
+			return v.VersionNumber()
+		}
 		return fmt.Sprintf("%d%02d es", v.Major, v.Minor)
 	}
+	if v.versionLessThan(150) {
+		return v.VersionNumber()
+	}
 	return fmt.Sprintf("%d%02d core", v.Major, v.Minor)
 }
 
@@ -70,6 +83,15 @@ func (v Version) supportsDerivativeControl() bool {
 	return v.Major > 4 || (v.Major == 4 && v.Minor >= 50)
 }
 
+// supportsImageAtomics returns true if imageAtomic* functions are available.
+// Desktop GLSL 420+, ES 310+ (with GL_OES_shader_image_atomic).
+func (v Version) supportsImageAtomics() bool {
+	if v.ES {
+		return v.Major > 3 || (v.Major == 3 && v.Minor >= 10)
+	}
+	return v.Major > 4 || (v.Major == 4 && v.Minor >= 20)
+}
+
 // supportsIOLocations returns true if layout(location=N) is supported for IO.
 // Desktop GLSL 330+, ES 300+.
 func (v Version) supportsIOLocations() bool {
@@ -124,6 +146,16 @@ func (v Version) supportsFma() bool {
 	return v.Major > 4 || (v.Major == 4 && v.Minor >= 0)
 }
 
+// supportsIntegerMix returns true if mix() accepts integer and bool operands
+// with a bvec selector. Float operands take a bvec selector from GLSL 1.30.
+// Desktop GLSL 450+, ES 310+.
+func (v Version) supportsIntegerMix() bool {
+	if v.ES {
+		return v.Major > 3 || (v.Major == 3 && v.Minor >= 10)
+	}
+	return v.Major > 4 || (v.Major == 4 && v.Minor >= 50)
+}
+
 // SupportsStorageBuffers returns true if this version supports storage buffers.
 func (v Version) SupportsStorageBuffers() bool {
 	if v.ES {
@@ -155,8 +187,9 @@ const (
 	// Emits: gl_Position.yz = vec2(-gl_Position.y, gl_Position.z * 2.0 - gl_Position.w);
 	WriterFlagAdjustCoordinateSpace
 
-	// WriterFlagForcePointSize emits gl_PointSize = 1.0 in vertex shaders.
-	// Required for WebGL compatibility.
+	// WriterFlagForcePointSize writes gl_PointSize (Options.PointSize, 1.0
+	// by default) in vertex shaders that don't output @builtin(point_size).
+	// GLES and WebGL need it to draw point primitives.
 	WriterFlagForcePointSize
 
 	// WriterFlagTextureShadowLod enables GL_EXT_texture_shadow_lod extension
@@ -189,10 +222,34 @@ type Options struct {
 	// WriterFlags control output formatting.
 	WriterFlags WriterFlags
 
+	// PointSize is the value WriterFlagForcePointSize writes to
+	// gl_PointSize. Zero means 1.0.
+	PointSize float32
+
+	// FragmentColorConversion converts the color outputs of a selected
+	// fragment entry point, e.g. to sRGB for a swapchain without an sRGB
+	// format (common on GLES2/WebGL1). Applied with ir.ConvertFragmentColors;
+	// other stages are unaffected.
+	FragmentColorConversion ir.ColorConversion
+
 	// ForceHighPrecision forces highp precision for all float types (ES only).
 	// If false, uses default precision qualifiers.
 	ForceHighPrecision bool
 
+	// FastMathSafeFloatChecks emits isnan/isinf as integer tests on the
+	// float's bit pattern. Drivers may assume NaN and Inf never occur and
+	// fold the built-ins (and x != x) to false.
+	FastMathSafeFloatChecks bool
+
+	// FloatPrecision is the number of significant digits written for float
+	// literals; zero writes the shortest digits that round-trip.
+	FloatPrecision int
+
+	// WriteMaskStores writes a store that replaces only some components of
+	// a vector (see ir.StoreWriteMask) as an assignment to those
+	// components, such as v.zx = n.yx, instead of storing the whole vector.
+	WriteMaskStores bool
+
 	// BoundsCheckPolicies controls bounds checking for resource accesses.
 	// Matches Rust naga's proc::BoundsCheckPolicies.
 	BoundsCheckPolicies BoundsCheckPolicies
@@ -207,6 +264,17 @@ type Options struct {
 	// Values are float64 (NaN means "not set, use default").
 	// If provided, overrides are resolved before compilation.
 	PipelineConstants ir.PipelineConstants
+
+	// SymbolPrefix is prepended to module-scope types, functions, constants,
+	// unbound globals, and generated helper functions. The entry point is always "main" and bound
+	// resources keep their _group_G_binding_B_stage names.
+	SymbolPrefix string
+
+	// SourceMap fills TranslationInfo.SourceMap.
+	SourceMap bool
+
+	// Logger receives phase traces and polyfill warnings; nil disables logging.
+	Logger *slog.Logger
 }
 
 // BindingMapKey identifies a resource binding for the BindingMap.
@@ -274,6 +342,11 @@ type UniformInfo struct {
 
 	// IsStorage is true for storage buffers (SSBO), false for uniform buffers (UBO).
 	IsStorage bool
+
+	// Plain is true on GLSL 1.20 / ES 1.00, which have no uniform blocks:
+	// the buffer is then a plain uniform named BlockName whose members are
+	// set with glUniform* at "BlockName.member".
+	Plain bool
 }
 
 // TranslationInfo contains metadata about the translation.
@@ -304,33 +377,66 @@ type TranslationInfo struct {
 	// queries block indices by name and assigns bindings via GL calls.
 	// Matches Rust naga ReflectionInfo.uniforms.
 	Uniforms []UniformInfo
+
+	// SourceMap maps output lines to the WGSL spans they were generated
+	// from, when Options.SourceMap is set.
+	SourceMap []ir.OutputSpan
 }
 
 // Compile generates GLSL source code from an IR module.
 // Returns the GLSL source as a string, translation info, or an error.
 func Compile(module *ir.Module, options Options) (string, TranslationInfo, error) {
+	return CompileContext(context.Background(), module, options)
+}
+
+// CompileContext is like Compile but stops with an error wrapping
+// ctx.Err() once ctx is canceled. Cancellation is checked between functions.
+func CompileContext(ctx context.Context, module *ir.Module, options Options) (string, TranslationInfo, error) {
 	// Apply defaults for zero values
 	if options.LangVersion.Major == 0 {
 		options.LangVersion = Version330
 	}
 
-	// Process overrides if pipeline constants are provided.
+	// Process overrides if pipeline constants are provided or an array
+	// length depends on one.
 	// This resolves all ExprOverride to concrete Literal/Constant values.
 	// Deep-clone mutable parts to avoid mutating shared state.
-	if len(options.PipelineConstants) > 0 && len(module.Overrides) > 0 {
+	if len(module.Overrides) > 0 && (len(options.PipelineConstants) > 0 || ir.HasOverrideSizedArrays(module)) {
 		module = ir.CloneModuleForOverrides(module)
 		if err := ir.ProcessOverrides(module, options.PipelineConstants); err != nil {
 			return "", TranslationInfo{}, fmt.Errorf("glsl: process overrides: %w", err)
 		}
 	}
 
+	// Convert fragment color outputs for targets without sRGB swapchains.
+	if options.FragmentColorConversion != ir.ColorConversionNone {
+		for i := range module.EntryPoints {
+			ep := &module.EntryPoints[i]
+			if options.EntryPoint != "" && ep.Name != options.EntryPoint {
+				continue
+			}
+			if ep.Stage == ir.StageFragment {
+				converted, err := ir.ConvertFragmentColors(module, ep.Name, options.FragmentColorConversion)
+				if err != nil {
+					return "", TranslationInfo{}, fmt.Errorf("glsl: %w", err)
+				}
+				module = converted
+			}
+			break
+		}
+	}
+
 	// Create writer
 	w := newWriter(module, &options)
+	w.ctx = ctx
+	w.SourceMap = options.SourceMap
+	w.notes.Phase("glsl: writing module", "version", options.LangVersion.String(), "entry_point", options.EntryPoint)
 
 	// Generate GLSL code
 	if err := w.writeModule(); err != nil {
 		return "", TranslationInfo{}, fmt.Errorf("glsl: %w", err)
 	}
+	w.notes.Phase("glsl: module written", "bytes", w.Out.Len())
 
 	// Build TextureMappings from combined sampler pairs (matches Rust naga
 	// ReflectionInfo.texture_mapping built from info.sampling_set in writer.rs:4421-4502).
@@ -360,5 +466,7 @@ func Compile(module *ir.Module, options Options) (string, TranslationInfo, error
 		Uniforms:            w.uniformInfos,
 	}
 
-	return w.String(), info, nil
+	code, spans := w.StripMarks(w.String())
+	info.SourceMap = spans
+	return code, info, nil
 }
@@ -4,6 +4,8 @@
 package codegen
 
 import (
+	"context"
+	"errors"
 	"strings"
 	"testing"
 
@@ -400,14 +402,16 @@ func TestFormatFloat(t *testing.T) {
 		input    float32
 		contains string
 	}{
-		{1.0, "."},      // Should have decimal point
-		{0.5, "0.5"},    // Exact value
-		{1.5e10, "e10"}, // Scientific notation (no '+' in exponent)
-		{0.0, "0.0"},    // Zero with decimal
+		{1.0, "."},                // Should have decimal point
+		{0.5, "0.5"},              // Exact value
+		{1.5e10, "15000000000.0"}, // Positional below 1e16
+		{1.5e20, "1.5e20"},        // Scientific notation (no '+' in exponent)
+		{1e-7, "1e-7"},            // No leading zero in the exponent
+		{0.0, "0.0"},              // Zero with decimal
 	}
 
 	for _, tt := range tests {
-		got := formatFloat(tt.input)
+		got := formatFloat(tt.input, 0)
 		if !strings.Contains(got, tt.contains) {
 			t.Errorf("formatFloat(%v) = %q, should contain %q", tt.input, got, tt.contains)
 		}
@@ -421,11 +425,11 @@ func TestFormatFloat64(t *testing.T) {
 	}{
 		{1.0, "."},
 		{0.5, "0.5"},
-		{1.5e100, "e+"},
+		{1.5e100, "1.5e100LF"},
 	}
 
 	for _, tt := range tests {
-		got := formatFloat64(tt.input)
+		got := formatFloat64(tt.input, 0)
 		if !strings.Contains(got, tt.contains) {
 			t.Errorf("formatFloat64(%v) = %q, should contain %q", tt.input, got, tt.contains)
 		}
@@ -1579,6 +1583,7 @@ func TestScanNeedBakeExpressions_RefCounting(t *testing.T) {
 	w := newWriter(module, &Options{LangVersion: Version330})
 
 	// Expression 0 is used twice (in Add left and right) -> should be baked
+	sum := ir.ExpressionHandle(1)
 	fn := &ir.Function{
 		Expressions: []ir.Expression{
 			{Kind: ir.Literal{Value: ir.LiteralF32(1.0)}},              // [0]
@@ -1590,6 +1595,7 @@ func TestScanNeedBakeExpressions_RefCounting(t *testing.T) {
 		},
 		Body: []ir.Statement{
 			{Kind: ir.StmtEmit{Range: ir.Range{Start: 0, End: 2}}},
+			{Kind: ir.StmtReturn{Value: &sum}},
 		},
 	}
 
@@ -2133,3 +2139,21 @@ func mustContainStr(t *testing.T, source, expected string) {
 		t.Errorf("expected output to contain %q.\nOutput:\n%s", expected, source)
 	}
 }
+
+func TestCompileContextCanceled(t *testing.T) {
+	module := &ir.Module{
+		EntryPoints: []ir.EntryPoint{{
+			Name:      "main",
+			Stage:     ir.StageCompute,
+			Workgroup: [3]uint32{1, 1, 1},
+		}},
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, _, err := CompileContext(ctx, module, DefaultOptions()); !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context.Canceled, got %v", err)
+	}
+	if _, _, err := CompileContext(context.Background(), module, DefaultOptions()); err != nil {
+		t.Fatalf("CompileContext failed: %v", err)
+	}
+}
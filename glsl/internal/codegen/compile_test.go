@@ -7,6 +7,7 @@ import (
 	"strings"
 	"testing"
 
+	"github.com/gogpu/naga/ir"
 	"github.com/gogpu/naga/wgsl"
 )
 
@@ -1548,6 +1549,28 @@ fn fs_main(@location(0) x: f32) -> @location(0) vec4<f32> {
 	glslMustContain(t, output, "void main()")
 }
 
+func TestCompileWGSL_OverrideSizedWorkgroupArray(t *testing.T) {
+	source := `
+override N: u32 = 4u;
+@group(0) @binding(0) var<storage, read_write> out: array<f32>;
+var<workgroup> buf: array<f32, N * 2u>;
+@compute @workgroup_size(1)
+fn main(@builtin(local_invocation_index) i: u32) {
+    buf[i] = f32(i);
+    workgroupBarrier();
+    out[i] = buf[i];
+}
+`
+	output := wgslToGLSL(t, source, Options{LangVersion: Version430})
+	glslMustContain(t, output, "shared float buf[8];")
+
+	output = wgslToGLSL(t, source, Options{
+		LangVersion:       Version430,
+		PipelineConstants: map[string]float64{"N": 16},
+	})
+	glslMustContain(t, output, "shared float buf[32];")
+}
+
 // =============================================================================
 // Nested If Tests
 // =============================================================================
@@ -1803,6 +1826,45 @@ fn vs_main(@location(0) pos: vec3<f32>, @location(1) col: vec3<f32>) -> VS_Out {
 	glslMustContain(t, output, "gl_PointSize = 1.0;")
 }
 
+func TestCompileWGSL_ForcePointSize(t *testing.T) {
+	plain := `
+@vertex
+fn vs_main(@location(0) pos: vec4<f32>) -> @builtin(position) vec4<f32> {
+    return pos;
+}
+`
+	output := wgslToGLSL(t, plain, Options{
+		LangVersion: Version{Major: 3, Minor: 0, ES: true},
+		WriterFlags: WriterFlagForcePointSize,
+		PointSize:   4.0,
+	})
+	glslMustContain(t, output, "gl_PointSize = 4.0;")
+
+	// A shader that writes @builtin(point_size) (a naga extension) keeps its
+	// own value.
+	sized := `
+struct VsOut {
+    @builtin(position) pos: vec4<f32>,
+    @builtin(point_size) size: f32,
+};
+
+@vertex
+fn vs_main(@location(0) pos: vec4<f32>, @location(1) size: f32) -> VsOut {
+    return VsOut(pos, size);
+}
+`
+	for _, flags := range []WriterFlags{WriterFlagNone, WriterFlagForcePointSize} {
+		output = wgslToGLSL(t, sized, Options{
+			LangVersion: Version{Major: 3, Minor: 0, ES: true},
+			WriterFlags: flags,
+		})
+		glslMustContain(t, output, "gl_PointSize = _tmp_return.size;")
+		if strings.Contains(output, "gl_PointSize = 1.0;") {
+			t.Errorf("flags %d: forced point size overrides the shader's own:\n%s", flags, output)
+		}
+	}
+}
+
 func TestCompileWGSL_MultipleUniformsBindingMap(t *testing.T) {
 	source := `
 @group(0) @binding(0) var<uniform> a: vec4<f32>;
@@ -2011,3 +2073,26 @@ fn fs_main(@location(0) x: f32) -> @location(0) vec4<f32> {
 	output := wgslToGLSL(t, source, Options{LangVersion: Version330})
 	glslMustContain(t, output, "if (")
 }
+
+func TestCompileWGSL_FragmentColorConversion(t *testing.T) {
+	source := `
+@fragment
+fn fs_main(@location(0) color: vec4<f32>) -> @location(0) vec4<f32> {
+    return color;
+}
+`
+	output := wgslToGLSL(t, source, Options{
+		LangVersion:             Version{Major: 3, Minor: 0, ES: true},
+		FragmentColorConversion: ir.ColorConversionLinearToSRGB,
+	})
+	glslMustContain(t, output, "clamp(")
+	glslMustContain(t, output, "pow(")
+	glslMustContain(t, output, "0.0031308")
+
+	output = wgslToGLSL(t, source, Options{
+		LangVersion: Version{Major: 3, Minor: 0, ES: true},
+	})
+	if strings.Contains(output, "pow(") {
+		t.Errorf("conversion applied without the option:\n%s", output)
+	}
+}
@@ -978,6 +978,30 @@ fn cs_main() {
 	_ = err
 }
 
+func TestImageAtomicVersionError(t *testing.T) {
+	source := `
+@group(0) @binding(0) var img: texture_storage_2d<r32uint, atomic>;
+
+@fragment
+fn fs_main(@builtin(position) p: vec4<f32>) {
+    textureAtomicMax(img, vec2<i32>(p.xy), 1u);
+}
+`
+	for _, v := range []Version{Version330, VersionES300} {
+		_, _, err := compileWGSLHelper(source, Options{LangVersion: v})
+		if err == nil || !strings.Contains(err.Error(), "texture atomics require GLSL 4.20 or ES 3.10") {
+			t.Errorf("%s: error %v, want texture atomics version error", v, err)
+		}
+	}
+	for _, v := range []Version{Version420, VersionES310} {
+		output, _, err := compileWGSLHelper(source, Options{LangVersion: v})
+		if err != nil {
+			t.Fatalf("%s: %v", v, err)
+		}
+		glslMustContain(t, output, "imageAtomicMax(")
+	}
+}
+
 // compileWGSLHelper compiles WGSL to GLSL, returning result and error without failing.
 func compileWGSLHelper(source string, opts Options) (string, TranslationInfo, error) {
 	lexer := wgsl.NewLexer(source)
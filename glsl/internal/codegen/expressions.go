@@ -182,13 +182,13 @@ func (w *Writer) writeLiteral(lit ir.Literal) (string, error) {
 	case ir.LiteralU64:
 		return fmt.Sprintf("%duL", uint64(v)), nil
 	case ir.LiteralF32:
-		return formatFloat(float32(v)), nil
+		return formatFloat(float32(v), w.floatPrecision()), nil
 	case ir.LiteralF64:
-		return formatFloat64(float64(v)), nil
+		return formatFloat64(float64(v), w.floatPrecision()), nil
 	case ir.LiteralAbstractInt:
 		return fmt.Sprintf("%d", int64(v)), nil
 	case ir.LiteralAbstractFloat:
-		return formatFloat64(float64(v)), nil
+		return formatFloat64(float64(v), w.floatPrecision()), nil
 	default:
 		return "0", nil
 	}
@@ -578,9 +578,17 @@ func (w *Writer) writeBinary(b ir.ExprBinary) (string, error) {
 		return fmt.Sprintf("(%s / %s)", left, right), nil
 	case ir.BinaryModulo:
 		// Rust naga: float modulo → (a - b * trunc(a / b)), integer → native %
+		// Legacy profiles have neither trunc nor integer %.
+		legacy := w.isLegacy()
 		if w.isFloatBinaryExpr(b) {
+			if legacy {
+				return fmt.Sprintf("(%s - %s * (sign(%s / %s) * floor(abs(%s / %s))))", left, right, left, right, left, right), nil
+			}
 			return fmt.Sprintf("(%s - %s * trunc(%s / %s))", left, right, left, right), nil
 		}
+		if legacy {
+			return fmt.Sprintf("(%s - %s * (%s / %s))", left, right, left, right), nil
+		}
 		return fmt.Sprintf("(%s %% %s)", left, right), nil
 	case ir.BinaryEqual:
 		if w.isVectorBinaryExpr(b) {
@@ -659,9 +667,31 @@ func (w *Writer) writeSelect(s ir.ExprSelect) (string, error) {
 	if err != nil {
 		return "", err
 	}
+	if _, ok := w.exprTypeInner(s.Condition).(ir.VectorType); ok {
+		return w.writeComponentSelect(s, condition, accept, reject), nil
+	}
 	return fmt.Sprintf("(%s ? %s : %s)", condition, accept, reject), nil
 }
 
+// writeComponentSelect writes select() with a bvecN condition, which GLSL's
+// ?: rejects. mix(reject, accept, cond) picks per component where the
+// version allows the operand type; otherwise the naga_select polyfill
+// does, evaluating each operand once.
+func (w *Writer) writeComponentSelect(s ir.ExprSelect, condition, accept, reject string) string {
+	if vec, ok := w.exprTypeInner(s.Accept).(ir.VectorType); ok && w.needsSelectHelper(vec.Scalar) {
+		return fmt.Sprintf("%s(%s, %s, %s)", w.helperName("naga_select"), reject, accept, condition)
+	}
+	return fmt.Sprintf("mix(%s, %s, %s)", reject, accept, condition)
+}
+
+// needsSelectHelper reports whether mix() cannot select between vectors of
+// scalar with a bvec: before GLSL 1.30 at all, and for integer and bool
+// vectors before GLSL 4.50 and ES 3.10.
+func (w *Writer) needsSelectHelper(scalar ir.ScalarType) bool {
+	version := w.options.LangVersion
+	return version.isLegacy() || (scalar.Kind != ir.ScalarFloat && !version.supportsIntegerMix())
+}
+
 // writeRelational writes a relational expression.
 func (w *Writer) writeRelational(r ir.ExprRelational) (string, error) {
 	argument, err := w.writeExpression(r.Argument)
@@ -670,19 +700,63 @@ func (w *Writer) writeRelational(r ir.ExprRelational) (string, error) {
 	}
 
 	switch r.Fun {
-	case ir.RelationalAll:
-		return fmt.Sprintf("all(%s)", argument), nil
-	case ir.RelationalAny:
+	case ir.RelationalAll, ir.RelationalAny:
+		// GLSL's all/any only accept bvecN; on a scalar bool they are the
+		// identity.
+		if s, ok := w.exprTypeInner(r.Argument).(ir.ScalarType); ok && s.Kind == ir.ScalarBool {
+			return argument, nil
+		}
+		if r.Fun == ir.RelationalAll {
+			return fmt.Sprintf("all(%s)", argument), nil
+		}
 		return fmt.Sprintf("any(%s)", argument), nil
-	case ir.RelationalIsNan:
-		return fmt.Sprintf("isnan(%s)", argument), nil
-	case ir.RelationalIsInf:
+	case ir.RelationalIsNan, ir.RelationalIsInf:
+		if w.options != nil && w.options.FastMathSafeFloatChecks {
+			if check, ok := w.floatBitsCheck(r, argument); ok {
+				return check, nil
+			}
+		}
+		if r.Fun == ir.RelationalIsNan {
+			return fmt.Sprintf("isnan(%s)", argument), nil
+		}
 		return fmt.Sprintf("isinf(%s)", argument), nil
 	default:
 		return "", fmt.Errorf("unsupported relational function: %v", r.Fun)
 	}
 }
 
+// floatBitsCheck writes isnan/isinf for an f32 argument as a test on its
+// bit pattern: with the sign masked off, Inf is exactly 0x7f800000 and NaN
+// is anything above it. Other widths report false and keep the built-in.
+func (w *Writer) floatBitsCheck(r ir.ExprRelational, argument string) (string, bool) {
+	var scalar ir.ScalarType
+	var size ir.VectorSize
+	switch t := w.exprTypeInner(r.Argument).(type) {
+	case ir.ScalarType:
+		scalar = t
+	case ir.VectorType:
+		scalar, size = t.Scalar, t.Size
+	default:
+		return "", false
+	}
+	if scalar.Kind != ir.ScalarFloat || scalar.Width != 4 {
+		return "", false
+	}
+
+	bits := fmt.Sprintf("(floatBitsToUint(%s) & 0x7fffffffu)", argument)
+	if size == 0 {
+		if r.Fun == ir.RelationalIsNan {
+			return fmt.Sprintf("(%s > 0x7f800000u)", bits), true
+		}
+		return fmt.Sprintf("(%s == 0x7f800000u)", bits), true
+	}
+	fun := "equal"
+	if r.Fun == ir.RelationalIsNan {
+		fun = "greaterThan"
+	}
+	return fmt.Sprintf("%s(%s, uvec%d(0x7f800000u))", fun, bits, size), true
+}
+
 // writeMath writes a math function expression.
 func (w *Writer) writeMath(m ir.ExprMath) (string, error) {
 	// Collect arguments
@@ -809,10 +883,10 @@ func (w *Writer) writeMath(m ir.ExprMath) (string, error) {
 		return fmt.Sprintf("(%s * %s + %s)", args[0], args[1], args[2]), nil
 	case ir.MathModf:
 		// modf needs special handling — returns struct
-		return fmt.Sprintf("naga_modf(%s)", args[0]), nil
+		return fmt.Sprintf("%s(%s)", w.helperName("naga_modf"), args[0]), nil
 	case ir.MathFrexp:
 		// frexp needs special handling — returns struct
-		return fmt.Sprintf("naga_frexp(%s)", args[0]), nil
+		return fmt.Sprintf("%s(%s)", w.helperName("naga_frexp"), args[0]), nil
 	case ir.MathLdexp:
 		return fmt.Sprintf("ldexp(%s)", argStr), nil
 	case ir.MathQuantizeF16:
@@ -1171,6 +1245,28 @@ func (w *Writer) writeImageSample(s ir.ExprImageSample) (string, error) {
 		funName = "texture"
 	}
 
+	// Legacy profiles name the function after the sampler type
+	// (texture2D, textureCubeLod, shadow2D, ...); offsets are rejected by
+	// checkLegacy. shadow* functions return a vec4.
+	resultSuffix := ""
+	if w.isLegacy() {
+		name, err := w.legacySampleFunction(s, w.currentFunction, w.currentEntryPointStage())
+		if err != nil {
+			return "", err
+		}
+		funName = name
+		if strings.HasPrefix(name, "shadow") {
+			resultSuffix = ".x"
+		}
+	}
+	// A depth texture sampled without a reference value is declared as a
+	// plain sampler (see registerTextureSamplerPair), which returns a vec4.
+	if s.DepthRef == nil {
+		if imgType := w.resolveImageType(s.Image); imgType != nil && imgType.Class == ir.ImageClassDepth {
+			resultSuffix = ".x"
+		}
+	}
+
 	// Append "Offset" suffix if offset is present
 	offsetSuffix := ""
 	if s.Offset != nil {
@@ -1237,6 +1333,7 @@ func (w *Writer) writeImageSample(s ir.ExprImageSample) (string, error) {
 	}
 
 	b.WriteString(")")
+	b.WriteString(resultSuffix)
 	return b.String(), nil
 }
 
@@ -1261,10 +1358,27 @@ func (w *Writer) getExprVectorSize(handle ir.ExpressionHandle) (int, bool) {
 
 // resolveImageType resolves the image type from an expression handle.
 func (w *Writer) resolveImageType(exprHandle ir.ExpressionHandle) *ir.ImageType {
-	if w.currentFunction == nil {
+	return w.imageTypeOf(w.currentFunction, exprHandle)
+}
+
+// imageTypeOf returns the type of the image global or image argument that
+// exprHandle in fn refers to, or nil if it does not refer to one.
+func (w *Writer) imageTypeOf(fn *ir.Function, exprHandle ir.ExpressionHandle) *ir.ImageType {
+	if fn == nil {
 		return nil
 	}
-	gvHandle := w.resolveGlobalVarHandle(w.currentFunction, exprHandle)
+	if int(exprHandle) < len(fn.Expressions) {
+		if arg, ok := fn.Expressions[exprHandle].Kind.(ir.ExprFunctionArgument); ok {
+			if int(arg.Index) >= len(fn.Arguments) || int(fn.Arguments[arg.Index].Type) >= len(w.module.Types) {
+				return nil
+			}
+			if imgType, ok := w.module.Types[fn.Arguments[arg.Index].Type].Inner.(ir.ImageType); ok {
+				return &imgType
+			}
+			return nil
+		}
+	}
+	gvHandle := w.resolveGlobalVarHandle(fn, exprHandle)
 	if gvHandle == nil {
 		return nil
 	}
@@ -1352,14 +1466,24 @@ func (w *Writer) writeImageLoad(l ir.ExprImageLoad, handle ir.ExpressionHandle)
 	// Determine coordinate vector size for ivecN constructors
 	coordVecSize := w.getCoordVectorSize(imgType, l.ArrayIndex != nil)
 
+	var load string
 	switch policy {
 	case BoundsCheckRestrict:
-		return w.writeImageLoadRestrict(l, handle, image, coordStr, coordVecSize, imgType)
+		load, err = w.writeImageLoadRestrict(l, handle, image, coordStr, coordVecSize, imgType)
 	case BoundsCheckReadZeroSkipWrite:
-		return w.writeImageLoadReadZero(l, image, coordStr, coordVecSize, imgType)
+		load, err = w.writeImageLoadReadZero(l, image, coordStr, coordVecSize, imgType)
 	default:
-		return w.writeImageLoadUnchecked(l, image, coordStr)
+		load, err = w.writeImageLoadUnchecked(l, image, coordStr)
+	}
+	if err != nil {
+		return "", err
+	}
+	// texelFetch returns a vec4 for depth textures too; WGSL's textureLoad
+	// on a depth texture returns the f32 depth.
+	if imgType != nil && imgType.Class == ir.ImageClassDepth {
+		load += ".x"
 	}
+	return load, nil
 }
 
 // writeImageLoadUnchecked writes texelFetch without bounds checking.
@@ -1653,20 +1777,19 @@ func (w *Writer) writeImageQuery(q ir.ExprImageQuery) (string, error) {
 		return fmt.Sprintf("uint(textureQueryLevels(%s))", image), nil
 
 	case ir.ImageQueryNumLayers:
-		// NumLayers uses textureSize/imageSize and takes the NEXT component after spatial dims
+		// NumLayers uses textureSize/imageSize and takes the NEXT component
+		// after spatial dims. imageSize and multisampled textureSize take
+		// no level.
 		funName := "textureSize"
 		if isStorage {
 			funName = "imageSize"
 		}
 		var levelArg string
-		if !isMulti {
+		if !isMulti && !isStorage {
 			levelArg = ", 0"
 		}
 		// Layer component is one beyond the spatial components
-		layerSwizzle := ""
-		if components >= 1 {
-			layerSwizzle = "." + string("xyz"[components])
-		}
+		layerSwizzle := "." + string("xyzw"[components])
 		return fmt.Sprintf("uint(%s(%s%s)%s)", funName, image, levelArg, layerSwizzle), nil
 
 	case ir.ImageQueryNumSamples:
@@ -1789,6 +1912,15 @@ func (w *Writer) writeAtomicResult(_ ir.ExprAtomicResult) (string, error) {
 	return "/* atomic result */", nil
 }
 
+// exprTypeInner returns the type of an expression in the current function,
+// or nil if it cannot be resolved.
+func (w *Writer) exprTypeInner(handle ir.ExpressionHandle) ir.TypeInner {
+	if w.currentFunction == nil || int(handle) >= len(w.currentFunction.ExpressionTypes) {
+		return nil
+	}
+	return w.resolveTypeInner(&w.currentFunction.ExpressionTypes[handle], handle)
+}
+
 // getCoordDim returns the dimensionality of a coordinate expression (1 for scalar, 2-4 for vectors).
 func (w *Writer) getCoordDim(handle ir.ExpressionHandle) uint8 {
 	if w.currentFunction != nil && int(handle) < len(w.currentFunction.ExpressionTypes) {
@@ -2091,5 +2223,5 @@ func (w *Writer) formatConstResult(protoExpr ir.ExpressionHandle, val float64) s
 			}
 		}
 	}
-	return formatFloat(float32(val))
+	return formatFloat(float32(val), w.floatPrecision())
 }
@@ -2092,6 +2092,51 @@ func TestGLSL_ImageQuery_NumSamples(t *testing.T) {
 	mustContainGLSL(t, result, "uint(textureSamples(")
 }
 
+func TestGLSL_ImageQuery_NumLayers(t *testing.T) {
+	source := `
+@group(0) @binding(0) var t2a: texture_2d_array<f32>;
+@group(0) @binding(1) var tsa: texture_storage_2d_array<r32uint, write>;
+@group(0) @binding(2) var<storage, read_write> out: array<u32>;
+
+@compute @workgroup_size(1)
+fn main() {
+    out[0] = textureNumLayers(t2a);
+    out[1] = textureNumLayers(tsa);
+}
+`
+	result := wgslToGLSL(t, source, Options{LangVersion: Version430})
+	mustContainGLSL(t, result, "uint(textureSize(_group_0_binding_0_cs, 0).z)")
+	// imageSize takes no level.
+	mustContainGLSL(t, result, "uint(imageSize(_group_0_binding_1_cs).z)")
+}
+
+func TestGLSL_DepthTextureWithoutComparison(t *testing.T) {
+	source := `
+@group(0) @binding(0) var d: texture_depth_2d;
+@group(0) @binding(1) var dms: texture_depth_multisampled_2d;
+@group(0) @binding(2) var s: sampler;
+
+fn sample_arg(t: texture_depth_2d, smp: sampler, uv: vec2<f32>) -> f32 {
+    return textureSample(t, smp, uv);
+}
+
+@fragment
+fn main(@location(0) uv: vec2<f32>) -> @location(0) vec4<f32> {
+    let a = textureLoad(d, vec2(0, 0), 0);
+    let b = textureLoad(dms, vec2(0, 0), 0);
+    return vec4(a, b, sample_arg(d, s, uv), 1.0);
+}
+`
+	result := wgslToGLSL(t, source, Options{LangVersion: Version430})
+	mustContainGLSL(t, result, "uniform sampler2D _group_0_binding_0_fs;")
+	// The argument is sampled without a reference, so it is a plain
+	// sampler like the global passed to it.
+	mustContainGLSL(t, result, "float sample_arg(sampler2D t, vec2 uv_1)")
+	mustContainGLSL(t, result, "texture(t, vec2(uv_1)).x")
+	mustContainGLSL(t, result, "texelFetch(_group_0_binding_0_fs, ivec2(0, 0), 0).x")
+	mustContainGLSL(t, result, "texelFetch(_group_0_binding_1_fs, ivec2(0, 0), 0).x")
+}
+
 // =============================================================================
 // Test: StmtImageAtomic
 // =============================================================================
@@ -2655,3 +2700,127 @@ func TestGLSL_ShadowLod_NoWorkaround_WithExtension(t *testing.T) {
 func ptrExprHelper(h ir.ExpressionHandle) *ir.ExpressionHandle {
 	return &h
 }
+
+// =============================================================================
+// Test: fast-math-safe isnan/isinf
+// =============================================================================
+
+func TestGLSL_FastMathSafeFloatChecks(t *testing.T) {
+	src := `
+@fragment
+fn main(@location(0) x: f32, @location(1) v: vec3<f32>) -> @location(0) vec4<f32> {
+    return vec4<f32>(select(0.0, 1.0, isnan(x)), select(0.0, 1.0, isinf(v).y),
+                     select(0.0, 1.0, any(isnan(v))), 0.0);
+}
+`
+	plain, _, err := compileWGSLHelper(src, DefaultOptions())
+	if err != nil {
+		t.Fatalf("Compile() error = %v", err)
+	}
+	mustContainGLSL(t, plain, "isnan(x)")
+	mustContainGLSL(t, plain, "isinf(v)")
+
+	opts := DefaultOptions()
+	opts.FastMathSafeFloatChecks = true
+	code, _, err := compileWGSLHelper(src, opts)
+	if err != nil {
+		t.Fatalf("Compile() error = %v", err)
+	}
+	mustContainGLSL(t, code, "((floatBitsToUint(x) & 0x7fffffffu) > 0x7f800000u)")
+	mustContainGLSL(t, code, "equal((floatBitsToUint(v) & 0x7fffffffu), uvec3(0x7f800000u))")
+	mustContainGLSL(t, code, "any(greaterThan((floatBitsToUint(v) & 0x7fffffffu), uvec3(0x7f800000u)))")
+	if strings.Contains(code, "isnan(") || strings.Contains(code, "isinf(") {
+		t.Errorf("built-in isnan/isinf left in output:\n%s", code)
+	}
+}
+
+// =============================================================================
+// Test: any/all on a scalar bool
+// =============================================================================
+
+func TestGLSL_RelationalScalarBool(t *testing.T) {
+	// GLSL's any/all only take bvecN, so a scalar bool argument is written
+	// as-is. The WGSL frontend folds these away; other producers may not.
+	for _, fun := range []ir.RelationalFunction{ir.RelationalAny, ir.RelationalAll} {
+		tBool := ir.TypeHandle(0)
+		retExpr := ir.ExpressionHandle(1)
+		module := &ir.Module{
+			Types: []ir.Type{{Inner: ir.ScalarType{Kind: ir.ScalarBool, Width: 1}}},
+			Functions: []ir.Function{{
+				Name:      "test_fn",
+				Arguments: []ir.FunctionArgument{{Name: "flag", Type: tBool}},
+				Result:    &ir.FunctionResult{Type: tBool},
+				Expressions: []ir.Expression{
+					{Kind: ir.ExprFunctionArgument{Index: 0}},
+					{Kind: ir.ExprRelational{Fun: fun, Argument: 0}},
+				},
+				ExpressionTypes: []ir.TypeResolution{{Handle: &tBool}, {Handle: &tBool}},
+				Body: []ir.Statement{
+					{Kind: ir.StmtEmit{Range: ir.Range{Start: 1, End: 2}}},
+					{Kind: ir.StmtReturn{Value: &retExpr}},
+				},
+			}},
+		}
+		output := compileGLSL(t, module)
+		mustContainGLSL(t, output, "return flag;")
+		if strings.Contains(output, "any(") || strings.Contains(output, "all(") {
+			t.Errorf("relational %v kept a call on a scalar bool:\n%s", fun, output)
+		}
+	}
+}
+
+// =============================================================================
+// Test: select() with a vector condition
+// =============================================================================
+
+func TestGLSL_SelectVectorCondition(t *testing.T) {
+	src := `
+@fragment
+fn main(@location(0) a: vec4<f32>, @location(1) b: vec4<f32>) -> @location(0) vec4<f32> {
+    let m = a < b;
+    let f = select(a, b, m);
+    let i = select(vec4<i32>(a), vec4<i32>(b), m);
+    let s = select(a, b, a.x < b.x);
+    return f + vec4<f32>(i) + s;
+}
+`
+	tests := []struct {
+		name    string
+		version Version
+		want    []string
+	}{
+		{"330", Version330, []string{
+			"mix(a, b, m)",
+			"naga_select(ivec4(a), ivec4(b), m)",
+			"((a.x < b.x) ? b : a)",
+		}},
+		{"450", Version450, []string{"mix(a, b, m)", "mix(ivec4(a), ivec4(b), m)"}},
+		{"es310", VersionES310, []string{"mix(a, b, m)", "mix(ivec4(a), ivec4(b), m)"}},
+		{"es300", VersionES300, []string{
+			"mix(a, b, m)",
+			"naga_select(ivec4(a), ivec4(b), m)",
+			"ivec4 naga_select(ivec4 reject, ivec4 accept, bvec4 condition) {",
+			"return ivec4(condition.x ? accept.x : reject.x, condition.y ? accept.y : reject.y, " +
+				"condition.z ? accept.z : reject.z, condition.w ? accept.w : reject.w);",
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := DefaultOptions()
+			opts.LangVersion = tt.version
+			code, _, err := compileWGSLHelper(src, opts)
+			if err != nil {
+				t.Fatalf("Compile() error = %v", err)
+			}
+			for _, w := range tt.want {
+				mustContainGLSL(t, code, w)
+			}
+			if strings.Contains(code, "(m ? ") {
+				t.Errorf("vector condition written as a ternary:\n%s", code)
+			}
+			if tt.version.supportsIntegerMix() && strings.Contains(code, "naga_select") {
+				t.Errorf("naga_select written for a version with integer mix:\n%s", code)
+			}
+		})
+	}
+}
@@ -38,6 +38,8 @@ const (
 	FeatureSubgroupOperations    Features = 1 << 24
 	FeatureTextureAtomics        Features = 1 << 25
 	FeatureShaderBarycentrics    Features = 1 << 26
+	FeatureShaderTextureLod      Features = 1 << 27
+	FeatureStandardDerivatives   Features = 1 << 28
 )
 
 // featuresManager collects and writes required features.
@@ -145,6 +147,19 @@ func (fm *featuresManager) writeExtensions(w *Writer) {
 	if fm.contains(FeatureShaderBarycentrics) {
 		w.WriteLine("#extension GL_EXT_fragment_shader_barycentric : require")
 	}
+
+	// Legacy (GLSL 1.20 / ES 1.00) extensions, requested by checkLegacy.
+	if fm.contains(FeatureShaderTextureLod) {
+		if opts.LangVersion.ES {
+			w.WriteLine("#extension GL_EXT_shader_texture_lod : require")
+		} else {
+			w.WriteLine("#extension GL_ARB_shader_texture_lod : require")
+		}
+	}
+
+	if fm.contains(FeatureStandardDerivatives) {
+		w.WriteLine("#extension GL_OES_standard_derivatives : require")
+	}
 }
 
 // collectFeatures scans the module and entry point to determine required features.
@@ -313,7 +328,14 @@ func (w *Writer) scanExpressionFeatures(ep *ir.EntryPoint) {
 					}
 				}
 			case ir.ExprImageSample:
-				_ = k // scanned for shadow LOD below
+				// GLSL has no level-zero comparison sample of a cube array
+				// shadow sampler: textureLod with a reference value needs
+				// GL_EXT_texture_shadow_lod.
+				if _, zero := k.Level.(ir.SampleLevelZero); zero && k.DepthRef != nil && k.Gather == nil {
+					if img := w.imageTypeOf(fn, k.Image); img != nil && img.Dim == ir.DimCube && img.Arrayed {
+						w.features.request(FeatureTextureShadowLod)
+					}
+				}
 			default:
 				_ = k
 			}
@@ -111,7 +111,7 @@ var glslKeywords = map[string]struct{}{
 	"gl_SampleID": {}, "gl_SamplePosition": {}, "gl_SampleMaskIn": {},
 	"gl_FragDepth": {}, "gl_SampleMask": {},
 	"gl_Layer": {}, "gl_ViewportIndex": {},
-	"gl_HelperInvocation": {},
+	"gl_HelperInvocation": {}, "gl_FragColor": {}, "gl_FragData": {},
 
 	// Built-in variables (compute)
 	"gl_NumWorkGroups": {}, "gl_WorkGroupSize": {}, "gl_WorkGroupID": {},
@@ -163,6 +163,11 @@ var glslKeywords = map[string]struct{}{
 	"textureProjLod": {}, "textureProjOffset": {}, "textureLodOffset": {}, "textureProjLodOffset": {},
 	"textureGrad": {}, "textureGradOffset": {}, "textureProjGrad": {}, "textureProjGradOffset": {},
 	"textureGather": {}, "textureGatherOffset": {}, "textureGatherOffsets": {},
+	"texture1D": {}, "texture2D": {}, "texture3D": {}, "textureCube": {},
+	"texture1DLod": {}, "texture2DLod": {}, "texture3DLod": {}, "textureCubeLod": {},
+	"shadow1D": {}, "shadow2D": {}, "shadow1DLod": {}, "shadow2DLod": {},
+	"texture2DLodEXT": {}, "textureCubeLodEXT": {}, "texture2DGradEXT": {}, "textureCubeGradEXT": {},
+	"texture2DGradARB": {}, "textureCubeGradARB": {},
 	"dFdx": {}, "dFdy": {}, "dFdxFine": {}, "dFdyFine": {}, "dFdxCoarse": {}, "dFdyCoarse": {},
 	"fwidth": {}, "fwidthFine": {}, "fwidthCoarse": {},
 	"interpolateAtCentroid": {}, "interpolateAtSample": {}, "interpolateAtOffset": {},
@@ -180,6 +185,9 @@ var glslKeywords = map[string]struct{}{
 	"atomicAdd":             {}, "atomicMin": {}, "atomicMax": {}, "atomicAnd": {}, "atomicOr": {}, "atomicXor": {},
 	"atomicExchange": {}, "atomicCompSwap": {},
 	"subpassLoad": {},
+
+	// Polyfill helpers written by this backend
+	"naga_select": {},
 }
 
 // isKeyword checks if a name is a GLSL keyword or reserved word.
@@ -0,0 +1,38 @@
+package codegen
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestReservedIdentifiersEscaped(t *testing.T) {
+	out := wgslToGLSL(t, `
+struct half {
+    texture: f32,
+    sampler2D: f32,
+}
+
+@group(0) @binding(0) var<uniform> params: half;
+
+fn texture(input: f32, output: f32) -> f32 {
+    return input + output;
+}
+
+@fragment
+fn main() -> @location(0) vec4<f32> {
+    return vec4<f32>(texture(params.texture, params.sampler2D));
+}
+`, Options{LangVersion: Version330})
+
+	for _, want := range []string{
+		"struct half_ {",
+		"float texture_;",
+		"float sampler2D_;",
+		"float texture_(float input_, float output_)",
+	} {
+		glslMustContain(t, out, want)
+	}
+	if strings.Contains(out, "struct half {") {
+		t.Errorf("reserved struct name not escaped:\n%s", out)
+	}
+}
@@ -0,0 +1,572 @@
+// Copyright 2025 The GoGPU Authors
+// SPDX-License-Identifier: MIT
+
+package codegen
+
+import (
+	"fmt"
+
+	"github.com/gogpu/naga/ir"
+)
+
+// Legacy profiles: GLSL 1.20 (OpenGL 2.1) and GLSL ES 1.00 (OpenGL ES 2.0 /
+// WebGL 1). They predate in/out qualifiers, uniform blocks, unsigned
+// integers, integer bit operations, switch statements and the overloaded
+// texture() functions, so the writer emits attribute/varying declarations,
+// gl_FragColor/gl_FragData outputs, plain struct uniforms and the
+// dimension-specific texture2D/textureCube/shadow2D family. Constructs with
+// no legacy equivalent are rejected by checkLegacy before any code is
+// written.
+
+// isLegacy reports whether v is a pre-GLSL 1.30 profile: desktop 1.10/1.20
+// or ES 1.00.
+func (v Version) isLegacy() bool {
+	if v.ES {
+		return v.Major < 3
+	}
+	return v.versionLessThan(130)
+}
+
+// isLegacy reports whether the writer targets a legacy profile.
+func (w *Writer) isLegacy() bool {
+	return w.options != nil && w.options.LangVersion.isLegacy()
+}
+
+// legacyName returns the profile name used in legacy error messages.
+func (v Version) legacyName() string {
+	if v.ES {
+		return fmt.Sprintf("GLSL ES %d.%02d", v.Major, v.Minor)
+	}
+	return fmt.Sprintf("GLSL %d.%02d", v.Major, v.Minor)
+}
+
+// legacyUnavailable builds the error for a construct a legacy profile lacks.
+func (w *Writer) legacyUnavailable(format string, args ...any) error {
+	return fmt.Errorf("%s is not available in %s", fmt.Sprintf(format, args...), w.options.LangVersion.legacyName())
+}
+
+// legacyUnavailableMath lists math functions introduced after GLSL 1.20
+// (or never available without extensions).
+var legacyUnavailableMath = map[ir.MathFunction]string{
+	ir.MathSinh: "sinh", ir.MathCosh: "cosh", ir.MathTanh: "tanh",
+	ir.MathAsinh: "asinh", ir.MathAcosh: "acosh", ir.MathAtanh: "atanh",
+	ir.MathRound: "round", ir.MathTrunc: "trunc",
+	ir.MathModf: "modf", ir.MathFrexp: "frexp", ir.MathLdexp: "ldexp",
+	ir.MathFma: "fma", ir.MathInverse: "inverse", ir.MathDeterminant: "determinant",
+	ir.MathQuantizeF16:        "quantizeToF16",
+	ir.MathDot4I8Packed:       "dot4I8Packed",
+	ir.MathDot4U8Packed:       "dot4U8Packed",
+	ir.MathCountTrailingZeros: "countTrailingZeros",
+	ir.MathCountLeadingZeros:  "countLeadingZeros",
+	ir.MathCountOneBits:       "countOneBits",
+	ir.MathReverseBits:        "reverseBits",
+	ir.MathExtractBits:        "extractBits",
+	ir.MathInsertBits:         "insertBits",
+	ir.MathFirstTrailingBit:   "firstTrailingBit",
+	ir.MathFirstLeadingBit:    "firstLeadingBit",
+	ir.MathPack4x8snorm:       "pack4x8snorm",
+	ir.MathPack4x8unorm:       "pack4x8unorm",
+	ir.MathPack2x16snorm:      "pack2x16snorm",
+	ir.MathPack2x16unorm:      "pack2x16unorm",
+	ir.MathPack2x16float:      "pack2x16float",
+	ir.MathPack4xI8:           "pack4xI8",
+	ir.MathPack4xU8:           "pack4xU8",
+	ir.MathPack4xI8Clamp:      "pack4xI8Clamp",
+	ir.MathPack4xU8Clamp:      "pack4xU8Clamp",
+	ir.MathUnpack4x8snorm:     "unpack4x8snorm",
+	ir.MathUnpack4x8unorm:     "unpack4x8unorm",
+	ir.MathUnpack2x16snorm:    "unpack2x16snorm",
+	ir.MathUnpack2x16unorm:    "unpack2x16unorm",
+	ir.MathUnpack2x16float:    "unpack2x16float",
+	ir.MathUnpack4xI8:         "unpack4xI8",
+	ir.MathUnpack4xU8:         "unpack4xU8",
+}
+
+// checkLegacy rejects module features a legacy profile cannot express and
+// requests the extensions it needs. It runs after collectFeatures and before
+// the extensions are written; it does nothing for GLSL 1.30+ / ES 3.00+.
+func (w *Writer) checkLegacy() error {
+	if !w.isLegacy() {
+		return nil
+	}
+	ep := w.getSelectedEntryPoint()
+	if ep == nil {
+		return nil
+	}
+	if ep.Stage != ir.StageVertex && ep.Stage != ir.StageFragment {
+		return w.legacyUnavailable("stages other than vertex and fragment")
+	}
+	if ep.EarlyDepthTest != nil {
+		return w.legacyUnavailable("early depth test")
+	}
+
+	for handle, global := range w.module.GlobalVariables {
+		if w.reachable != nil && !w.reachable.hasGlobal(ir.GlobalVariableHandle(handle)) {
+			continue
+		}
+		if err := w.checkLegacyGlobal(&global); err != nil {
+			return fmt.Errorf("global %q: %w", global.Name, err)
+		}
+	}
+
+	if err := w.checkLegacyIO(ep); err != nil {
+		return fmt.Errorf("entry point %q: %w", ep.Name, err)
+	}
+
+	if err := w.checkLegacyFunction(&ep.Function, ep.Stage); err != nil {
+		return fmt.Errorf("entry point %q: %w", ep.Name, err)
+	}
+	for handle := range w.module.Functions {
+		if w.reachable != nil && !w.reachable.hasFunction(ir.FunctionHandle(handle)) {
+			continue
+		}
+		fn := &w.module.Functions[handle]
+		if err := w.checkLegacyFunction(fn, ep.Stage); err != nil {
+			return fmt.Errorf("function %q: %w", fn.Name, err)
+		}
+	}
+	return nil
+}
+
+func (w *Writer) checkLegacyGlobal(global *ir.GlobalVariable) error {
+	switch global.Space {
+	case ir.SpaceStorage:
+		return w.legacyUnavailable("storage buffer")
+	case ir.SpaceWorkGroup:
+		return w.legacyUnavailable("workgroup memory")
+	}
+	inner := w.module.Types[global.Type].Inner
+	if img, ok := inner.(ir.ImageType); ok {
+		return w.checkLegacyImage(img)
+	}
+	return w.checkLegacyType(inner)
+}
+
+// checkLegacyImage accepts the sampler types of the legacy profiles:
+// float sampler2D/samplerCube everywhere, sampler1D/sampler3D and
+// sampler1DShadow/sampler2DShadow on desktop only.
+func (w *Writer) checkLegacyImage(img ir.ImageType) error {
+	es := w.options.LangVersion.ES
+	switch {
+	case img.Class == ir.ImageClassStorage:
+		return w.legacyUnavailable("storage texture")
+	case img.Class == ir.ImageClassExternal:
+		return w.legacyUnavailable("external texture")
+	case img.Arrayed:
+		return w.legacyUnavailable("texture array")
+	case img.Multisampled:
+		return w.legacyUnavailable("multisampled texture")
+	case img.Class == ir.ImageClassSampled && img.SampledKind != ir.ScalarFloat:
+		return w.legacyUnavailable("integer texture")
+	case img.Class == ir.ImageClassDepth && (es || img.Dim == ir.DimCube || img.Dim == ir.Dim3D):
+		return w.legacyUnavailable("%s depth texture", w.imageToGLSL(img))
+	case es && (img.Dim == ir.Dim1D || img.Dim == ir.Dim3D):
+		return w.legacyUnavailable("%s", w.imageToGLSL(img))
+	}
+	return nil
+}
+
+// checkLegacyType rejects scalar types other than bool, i32 and f32, and the
+// types GLSL ES 1.00 lacks (non-square matrices).
+func (w *Writer) checkLegacyType(inner ir.TypeInner) error {
+	switch t := inner.(type) {
+	case ir.ScalarType:
+		return w.checkLegacyScalar(t)
+	case ir.VectorType:
+		return w.checkLegacyScalar(t.Scalar)
+	case ir.MatrixType:
+		if w.options.LangVersion.ES && t.Columns != t.Rows {
+			return w.legacyUnavailable("non-square matrix %s", matrixToGLSL(t))
+		}
+		return w.checkLegacyScalar(t.Scalar)
+	case ir.ArrayType:
+		if t.Size.Constant == nil {
+			return w.legacyUnavailable("runtime-sized array")
+		}
+		if _, nested := w.module.Types[t.Base].Inner.(ir.ArrayType); nested {
+			return w.legacyUnavailable("array of arrays")
+		}
+		return w.checkLegacyType(w.module.Types[t.Base].Inner)
+	case ir.StructType:
+		for _, m := range t.Members {
+			if err := w.checkLegacyType(w.module.Types[m.Type].Inner); err != nil {
+				return err
+			}
+		}
+	case ir.PointerType:
+		return w.checkLegacyType(w.module.Types[t.Base].Inner)
+	case ir.ValuePointerType:
+		return w.checkLegacyScalar(t.Scalar)
+	case ir.AtomicType:
+		return w.legacyUnavailable("atomic")
+	}
+	return nil
+}
+
+func (w *Writer) checkLegacyScalar(s ir.ScalarType) error {
+	switch {
+	case s.Kind == ir.ScalarUint:
+		return w.legacyUnavailable("unsigned integer type")
+	case s.Kind == ir.ScalarBool:
+		return nil
+	case s.Width != 4:
+		return w.legacyUnavailable("%d-bit %s", s.Width*8, scalarToGLSL(ir.ScalarType{Kind: s.Kind, Width: 4}))
+	}
+	return nil
+}
+
+// checkLegacyIO validates entry point inputs and outputs and decides how
+// fragment outputs are written: gl_FragColor for a single output at
+// location 0, gl_FragData[N] otherwise (desktop only).
+func (w *Writer) checkLegacyIO(ep *ir.EntryPoint) error {
+	var outputs []uint32
+	check := func(binding ir.Binding, typeHandle ir.TypeHandle, isOutput bool) error {
+		switch b := binding.(type) {
+		case ir.BuiltinBinding:
+			return w.checkLegacyBuiltin(b.Builtin)
+		case ir.LocationBinding:
+			if err := w.checkLegacyType(w.module.Types[typeHandle].Inner); err != nil {
+				return err
+			}
+			if b.BlendSrc != nil {
+				return w.legacyUnavailable("dual-source blending")
+			}
+			if b.Interpolation != nil {
+				switch b.Interpolation.Kind {
+				case ir.InterpolationFlat:
+					return w.legacyUnavailable("flat interpolation")
+				case ir.InterpolationLinear:
+					return w.legacyUnavailable("linear interpolation")
+				}
+				switch b.Interpolation.Sampling {
+				case ir.SamplingSample:
+					return w.legacyUnavailable("per-sample interpolation")
+				case ir.SamplingCentroid:
+					if w.options.LangVersion.ES {
+						return w.legacyUnavailable("centroid interpolation")
+					}
+				}
+			}
+			if ep.Stage == ir.StageFragment && isOutput {
+				if vec, ok := w.module.Types[typeHandle].Inner.(ir.VectorType); !ok || vec.Size != ir.Vec4 || vec.Scalar.Kind != ir.ScalarFloat {
+					return fmt.Errorf("fragment output at location %d must be vec4<f32> for gl_FragColor/gl_FragData", b.Location)
+				}
+				outputs = append(outputs, b.Location)
+			}
+		}
+		return nil
+	}
+	visit := func(binding *ir.Binding, typeHandle ir.TypeHandle, isOutput bool) error {
+		if binding != nil {
+			return check(*binding, typeHandle, isOutput)
+		}
+		if st, ok := w.module.Types[typeHandle].Inner.(ir.StructType); ok {
+			for _, m := range st.Members {
+				if m.Binding != nil {
+					if err := check(*m.Binding, m.Type, isOutput); err != nil {
+						return err
+					}
+				}
+			}
+		}
+		return nil
+	}
+
+	fn := &ep.Function
+	for _, arg := range fn.Arguments {
+		if err := visit(arg.Binding, arg.Type, false); err != nil {
+			return err
+		}
+	}
+	if fn.Result != nil {
+		if err := visit(fn.Result.Binding, fn.Result.Type, true); err != nil {
+			return err
+		}
+	}
+
+	w.legacyFragData = len(outputs) > 1 || (len(outputs) == 1 && outputs[0] != 0)
+	if w.legacyFragData && w.options.LangVersion.ES {
+		return w.legacyUnavailable("fragment output other than a single location 0")
+	}
+	return nil
+}
+
+func (w *Writer) checkLegacyBuiltin(b ir.BuiltinValue) error {
+	switch b {
+	case ir.BuiltinPosition, ir.BuiltinFrontFacing, ir.BuiltinPointSize:
+		return nil
+	case ir.BuiltinFragDepth:
+		if !w.options.LangVersion.ES {
+			return nil
+		}
+	}
+	return w.legacyUnavailable("built-in %s", glslBuiltIn(b, false))
+}
+
+// checkLegacyFunction checks the types, expressions and statements of one
+// function body.
+func (w *Writer) checkLegacyFunction(fn *ir.Function, stage ir.ShaderStage) error {
+	es := w.options.LangVersion.ES
+	for _, local := range fn.LocalVars {
+		inner := w.module.Types[local.Type].Inner
+		if err := w.checkLegacyType(inner); err != nil {
+			return fmt.Errorf("local %q: %w", local.Name, err)
+		}
+		if _, isArray := inner.(ir.ArrayType); isArray && es {
+			return w.legacyUnavailable("local array variable %q (array constructors)", local.Name)
+		}
+	}
+	for _, arg := range fn.Arguments {
+		if err := w.checkLegacyType(w.module.Types[arg.Type].Inner); err != nil {
+			return fmt.Errorf("argument %q: %w", arg.Name, err)
+		}
+	}
+
+	for h, expr := range fn.Expressions {
+		handle := ir.ExpressionHandle(h)
+		inner := w.legacyExprType(fn, handle)
+		if err := w.checkLegacyType(inner); err != nil {
+			return err
+		}
+		switch k := expr.Kind.(type) {
+		case ir.ExprCompose, ir.ExprZeroValue:
+			if _, isArray := inner.(ir.ArrayType); isArray && es {
+				return w.legacyUnavailable("array constructor")
+			}
+		case ir.ExprImageSample:
+			if _, err := w.legacySampleFunction(k, fn, stage); err != nil {
+				return err
+			}
+		case ir.ExprImageLoad:
+			return w.legacyUnavailable("textureLoad (texelFetch)")
+		case ir.ExprImageQuery:
+			return w.legacyUnavailable("texture queries")
+		case ir.ExprArrayLength:
+			return w.legacyUnavailable("arrayLength")
+		case ir.ExprDerivative:
+			if k.Control != ir.DerivativeNone {
+				return w.legacyUnavailable("coarse and fine derivatives")
+			}
+			if stage != ir.StageFragment {
+				return w.legacyUnavailable("derivatives outside fragment shaders")
+			}
+			if es {
+				w.features.request(FeatureStandardDerivatives)
+			}
+		case ir.ExprRelational:
+			if k.Fun == ir.RelationalIsNan || k.Fun == ir.RelationalIsInf {
+				return w.legacyUnavailable("isnan/isinf")
+			}
+		case ir.ExprUnary:
+			if k.Op == ir.UnaryBitwiseNot {
+				return w.legacyUnavailable("bitwise operators")
+			}
+		case ir.ExprBinary:
+			switch k.Op {
+			case ir.BinaryExclusiveOr, ir.BinaryShiftLeft, ir.BinaryShiftRight:
+				return w.legacyUnavailable("bitwise operators")
+			case ir.BinaryAnd, ir.BinaryInclusiveOr:
+				if s, ok := legacyScalarOf(w.legacyExprType(fn, k.Left)); ok && s.Kind != ir.ScalarBool {
+					return w.legacyUnavailable("bitwise operators")
+				}
+			}
+		case ir.ExprMath:
+			if name, ok := legacyUnavailableMath[k.Fun]; ok {
+				return w.legacyUnavailable("%s", name)
+			}
+			switch k.Fun {
+			case ir.MathAbs, ir.MathSign, ir.MathMin, ir.MathMax, ir.MathClamp:
+				if s, ok := legacyScalarOf(w.legacyExprType(fn, k.Arg)); ok && s.Kind != ir.ScalarFloat {
+					return w.legacyUnavailable("integer abs/sign/min/max/clamp")
+				}
+			case ir.MathOuter, ir.MathTranspose:
+				if es {
+					return w.legacyUnavailable("outerProduct/transpose")
+				}
+			}
+		case ir.ExprAs:
+			if k.Convert == nil {
+				return w.legacyUnavailable("bitcast")
+			}
+		case ir.ExprAtomicResult, ir.ExprWorkGroupUniformLoadResult,
+			ir.ExprSubgroupBallotResult, ir.ExprSubgroupOperationResult,
+			ir.ExprRayQueryProceedResult, ir.ExprRayQueryGetIntersection:
+			return w.legacyUnavailable("%T", k)
+		}
+	}
+	return w.checkLegacyBlock(fn.Body)
+}
+
+func (w *Writer) checkLegacyBlock(block ir.Block) error {
+	for _, stmt := range block {
+		var err error
+		switch k := stmt.Kind.(type) {
+		case ir.StmtBlock:
+			err = w.checkLegacyBlock(k.Block)
+		case ir.StmtIf:
+			if err = w.checkLegacyBlock(k.Accept); err == nil {
+				err = w.checkLegacyBlock(k.Reject)
+			}
+		case ir.StmtLoop:
+			// GLSL ES 1.00 (Appendix A) only guarantees for loops with a
+			// constant iteration count; the writer's while(true) is not one.
+			if w.options.LangVersion.ES {
+				err = w.legacyUnavailable("loop without a constant iteration count")
+			} else if err = w.checkLegacyBlock(k.Body); err == nil {
+				err = w.checkLegacyBlock(k.Continuing)
+			}
+		case ir.StmtSwitch:
+			err = w.legacyUnavailable("switch statement")
+		case ir.StmtBarrier, ir.StmtImageStore, ir.StmtAtomic, ir.StmtImageAtomic,
+			ir.StmtWorkGroupUniformLoad, ir.StmtRayQuery, ir.StmtSubgroupBallot,
+			ir.StmtSubgroupCollectiveOperation, ir.StmtSubgroupGather:
+			err = w.legacyUnavailable("%T", k)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// legacyExprType resolves the type of an expression outside of function
+// writing, where resolveTypeInner has no current function.
+func (w *Writer) legacyExprType(fn *ir.Function, handle ir.ExpressionHandle) ir.TypeInner {
+	if int(handle) < len(fn.ExpressionTypes) {
+		res := &fn.ExpressionTypes[handle]
+		if res.Handle != nil && int(*res.Handle) < len(w.module.Types) {
+			return w.module.Types[*res.Handle].Inner
+		}
+		if res.Value != nil {
+			return res.Value
+		}
+	}
+	res, err := ir.ExpressionType(w.module, fn, handle)
+	if err != nil {
+		return nil
+	}
+	if res.Handle != nil && int(*res.Handle) < len(w.module.Types) {
+		return w.module.Types[*res.Handle].Inner
+	}
+	return res.Value
+}
+
+func legacyScalarOf(inner ir.TypeInner) (ir.ScalarType, bool) {
+	switch t := inner.(type) {
+	case ir.ScalarType:
+		return t, true
+	case ir.VectorType:
+		return t.Scalar, true
+	}
+	return ir.ScalarType{}, false
+}
+
+// legacySampleFunction returns the legacy name of a sampling call, e.g.
+// texture2D, textureCubeLod or shadow2D. Explicit-LOD sampling in fragment
+// shaders and gradient sampling need GL_ARB_shader_texture_lod (desktop) or
+// GL_EXT_shader_texture_lod (ES, fragment only), which it requests.
+func (w *Writer) legacySampleFunction(s ir.ExprImageSample, fn *ir.Function, stage ir.ShaderStage) (string, error) {
+	img, ok := w.legacyExprType(fn, s.Image).(ir.ImageType)
+	if !ok {
+		return "", fmt.Errorf("cannot resolve sampled image type")
+	}
+	switch {
+	case s.Gather != nil:
+		return "", w.legacyUnavailable("textureGather")
+	case s.Offset != nil:
+		return "", w.legacyUnavailable("texture sampling with offsets")
+	}
+	if err := w.checkLegacyImage(img); err != nil {
+		return "", err
+	}
+
+	name := "texture"
+	if img.Class == ir.ImageClassDepth && s.DepthRef != nil {
+		name = "shadow"
+	}
+	switch img.Dim {
+	case ir.Dim1D:
+		name += "1D"
+	case ir.Dim2D:
+		name += "2D"
+	case ir.Dim3D:
+		name += "3D"
+	case ir.DimCube:
+		name = "textureCube"
+	}
+
+	es := w.options.LangVersion.ES
+	switch s.Level.(type) {
+	case ir.SampleLevelExact, ir.SampleLevelZero:
+		name += "Lod"
+		if stage == ir.StageFragment {
+			w.features.request(FeatureShaderTextureLod)
+			if es {
+				name += "EXT"
+			}
+		}
+	case ir.SampleLevelGradient:
+		if es && stage != ir.StageFragment {
+			return "", w.legacyUnavailable("textureSampleGrad outside fragment shaders")
+		}
+		w.features.request(FeatureShaderTextureLod)
+		name += "Grad"
+		if es {
+			name += "EXT"
+		} else {
+			name += "ARB"
+		}
+	case ir.SampleLevelBias:
+		if stage != ir.StageFragment {
+			return "", w.legacyUnavailable("textureSampleBias outside fragment shaders")
+		}
+	}
+	return name, nil
+}
+
+// legacyMatrixToGLSL returns the GLSL ES 1.00 name of a square matrix;
+// checkLegacy rejects the others.
+func legacyMatrixToGLSL(t ir.MatrixType) string {
+	return fmt.Sprintf("mat%d", t.Columns)
+}
+
+// legacyFragOutput returns the built-in a fragment output at location is
+// written to.
+func (w *Writer) legacyFragOutput(location int) string {
+	if w.legacyFragData {
+		return fmt.Sprintf("gl_FragData[%d]", location)
+	}
+	return "gl_FragColor"
+}
+
+// writeLegacyVarying declares a location-bound input or output with the
+// legacy storage qualifiers: attribute for vertex inputs, varying between
+// stages. Fragment outputs are built-ins and need no declaration.
+func (w *Writer) writeLegacyVarying(loc ir.LocationBinding, typeHandle ir.TypeHandle, stage ir.ShaderStage, isOutput bool) {
+	varName := w.varyingName(int(loc.Location), stage, isOutput)
+	w.varyingNameMap[varyingLookupKey{location: loc.Location, isOutput: isOutput, stage: stage}] = varName
+
+	qualifier := "varying"
+	switch {
+	case stage == ir.StageFragment && isOutput:
+		return
+	case stage == ir.StageVertex && !isOutput:
+		qualifier = "attribute"
+	case loc.Interpolation != nil && loc.Interpolation.Sampling == ir.SamplingCentroid:
+		qualifier = "centroid varying"
+	}
+	w.WriteLine("%s %s %s;", qualifier, w.getTypeName(typeHandle), varName)
+}
+
+// writeLegacyUniform declares a bound uniform buffer as a plain uniform of
+// the buffer's type under the name expressions use for it. Without uniform
+// blocks the host sets each member with glUniform*.
+func (w *Writer) writeLegacyUniform(global ir.GlobalVariable) {
+	_, instanceName := w.getBlockNames(global)
+	w.WriteLine("uniform %s %s%s;", w.getBaseTypeName(global.Type), instanceName, w.getArraySuffix(global.Type))
+	w.uniformInfos = append(w.uniformInfos, UniformInfo{
+		BlockName: instanceName,
+		Binding:   *global.Binding,
+		Plain:     true,
+	})
+}
@@ -0,0 +1,257 @@
+// Copyright 2025 The GoGPU Authors
+// SPDX-License-Identifier: MIT
+
+package codegen
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/gogpu/naga/ir"
+	"github.com/gogpu/naga/wgsl"
+)
+
+const legacySource = `
+struct Camera { view_proj: mat4x4<f32>, tint: vec4<f32> }
+@group(0) @binding(0) var<uniform> camera: Camera;
+@group(0) @binding(1) var tex: texture_2d<f32>;
+@group(0) @binding(2) var samp: sampler;
+@group(0) @binding(3) var sky: texture_cube<f32>;
+
+struct VsOut {
+    @builtin(position) pos: vec4<f32>,
+    @location(0) uv: vec2<f32>,
+}
+
+@vertex
+fn vs(@location(0) p: vec3<f32>, @location(1) uv: vec2<f32>) -> VsOut {
+    return VsOut(camera.view_proj * vec4<f32>(p, 1.0), uv);
+}
+
+@fragment
+fn fs(v: VsOut) -> @location(0) vec4<f32> {
+    let a = textureSample(tex, samp, v.uv);
+    let b = textureSampleLevel(sky, samp, vec3<f32>(v.uv, 1.0), 0.0);
+    return (a + b) * camera.tint * (v.uv.x % 2.0);
+}
+`
+
+func TestLegacyGLSL120(t *testing.T) {
+	vs := wgslToGLSL(t, legacySource, Options{LangVersion: Version120, EntryPoint: "vs"})
+	for _, want := range []string{
+		"#version 120\n",
+		"uniform Camera _group_0_binding_0_vs;",
+		"attribute vec3 _p2vs_location0;",
+		"varying vec2 _vs2fs_location0;",
+		"mat4x4 view_proj;",
+	} {
+		glslMustContain(t, vs, want)
+	}
+	for _, bad := range []string{"layout(", "uniform Camera_block", " in ", " out ", "precision"} {
+		if strings.Contains(vs, bad) {
+			t.Errorf("GLSL 1.20 vertex shader contains %q:\n%s", bad, vs)
+		}
+	}
+
+	fs := wgslToGLSL(t, legacySource, Options{LangVersion: Version120, EntryPoint: "fs"})
+	for _, want := range []string{
+		"#extension GL_ARB_shader_texture_lod : require",
+		"varying vec2 _vs2fs_location0;",
+		"uniform sampler2D _group_0_binding_1_fs;",
+		"texture2D(_group_0_binding_1_fs, ",
+		"textureCubeLod(_group_0_binding_3_fs, ",
+		"gl_FragColor = ",
+		"floor(abs(",
+	} {
+		glslMustContain(t, fs, want)
+	}
+	if strings.Contains(fs, "trunc(") {
+		t.Errorf("GLSL 1.20 has no trunc:\n%s", fs)
+	}
+}
+
+func TestLegacyGLSLES100(t *testing.T) {
+	vs := wgslToGLSL(t, legacySource, Options{LangVersion: VersionES100, EntryPoint: "vs"})
+	glslMustContain(t, vs, "#version 100\n")
+	glslMustContain(t, vs, "precision highp float;")
+	glslMustContain(t, vs, "mat4 view_proj;")
+
+	fs := wgslToGLSL(t, legacySource, Options{LangVersion: VersionES100, EntryPoint: "fs"})
+	for _, want := range []string{
+		"#extension GL_EXT_shader_texture_lod : require",
+		"#ifdef GL_FRAGMENT_PRECISION_HIGH",
+		"precision mediump float;",
+		"uniform sampler2D _group_0_binding_1_fs;",
+		"textureCubeLodEXT(",
+		"gl_FragColor = ",
+	} {
+		glslMustContain(t, fs, want)
+	}
+	if strings.Contains(fs, "highp sampler") {
+		t.Errorf("ES 1.00 samplers should keep default precision:\n%s", fs)
+	}
+}
+
+func TestLegacyUniformInfo(t *testing.T) {
+	module := legacyModule(t, legacySource)
+	_, info, err := Compile(module, Options{LangVersion: Version120, EntryPoint: "vs"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(info.Uniforms) != 1 || !info.Uniforms[0].Plain || info.Uniforms[0].BlockName != "_group_0_binding_0_vs" {
+		t.Errorf("Uniforms = %+v", info.Uniforms)
+	}
+}
+
+func TestLegacyFragData(t *testing.T) {
+	src := `
+struct Out {
+    @location(0) a: vec4<f32>,
+    @location(1) b: vec4<f32>,
+}
+@fragment
+fn fs() -> Out {
+    return Out(vec4<f32>(1.0), vec4<f32>(0.5));
+}
+`
+	out := wgslToGLSL(t, src, Options{LangVersion: Version120})
+	glslMustContain(t, out, "gl_FragData[0] = ")
+	glslMustContain(t, out, "gl_FragData[1] = ")
+
+	_, _, err := Compile(legacyModule(t, src), Options{LangVersion: VersionES100})
+	if err == nil || !strings.Contains(err.Error(), "GLSL ES 1.00") {
+		t.Errorf("ES 1.00 multiple outputs: got %v", err)
+	}
+}
+
+func TestLegacyShadowSampling(t *testing.T) {
+	src := `
+@group(0) @binding(0) var shadow_map: texture_depth_2d;
+@group(0) @binding(1) var cmp: sampler_comparison;
+@fragment
+fn fs(@location(0) uv: vec2<f32>) -> @location(0) vec4<f32> {
+    return vec4<f32>(textureSampleCompare(shadow_map, cmp, uv, 0.5));
+}
+`
+	out := wgslToGLSL(t, src, Options{LangVersion: Version120})
+	glslMustContain(t, out, "uniform sampler2DShadow ")
+	glslMustContain(t, out, "shadow2D(")
+	glslMustContain(t, out, ").x")
+
+	if _, _, err := Compile(legacyModule(t, src), Options{LangVersion: VersionES100}); err == nil {
+		t.Error("ES 1.00 has no shadow samplers; expected an error")
+	}
+}
+
+func TestLegacyUnsupported(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		want string
+	}{
+		{"uint", `
+@fragment
+fn fs(@builtin(position) p: vec4<f32>) -> @location(0) vec4<f32> {
+    let i = u32(p.x);
+    return vec4<f32>(f32(i));
+}`, "unsigned integer type"},
+		{"switch", `
+@fragment
+fn fs(@builtin(position) p: vec4<f32>) -> @location(0) vec4<f32> {
+    var c = vec4<f32>(0.0);
+    switch i32(p.x) {
+        case 0: { c = vec4<f32>(1.0); }
+        default: {}
+    }
+    return c;
+}`, "switch statement"},
+		{"storage", `
+@group(0) @binding(0) var<storage, read> data: array<f32>;
+@fragment
+fn fs() -> @location(0) vec4<f32> {
+    return vec4<f32>(data[0]);
+}`, "storage buffer"},
+		{"flat", `
+@fragment
+fn fs(@location(0) @interpolate(flat) i: i32) -> @location(0) vec4<f32> {
+    return vec4<f32>(f32(i));
+}`, "flat interpolation"},
+		{"vertex index", `
+@vertex
+fn vs(@builtin(vertex_index) i: u32) -> @builtin(position) vec4<f32> {
+    return vec4<f32>(f32(i));
+}`, "gl_VertexID"},
+		{"texel fetch", `
+@group(0) @binding(0) var tex: texture_2d<f32>;
+@fragment
+fn fs() -> @location(0) vec4<f32> {
+    return textureLoad(tex, vec2<i32>(0, 0), 0);
+}`, "texelFetch"},
+		{"output type", `
+@fragment
+fn fs() -> @location(0) vec2<f32> {
+    return vec2<f32>(1.0);
+}`, "must be vec4<f32>"},
+		{"compute", `
+@compute @workgroup_size(1)
+fn cs() {}`, "stages other than vertex and fragment"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, _, err := Compile(legacyModule(t, tt.src), Options{LangVersion: Version120})
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("got %v, want error containing %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestLegacyLoops(t *testing.T) {
+	const src = `
+@fragment
+fn fs(@builtin(position) p: vec4<f32>) -> @location(0) vec4<f32> {
+    var c = 0.0;
+    for (var i = 0; i < i32(p.x); i++) { c += 0.1; }
+    return vec4<f32>(c);
+}`
+	out, _, err := Compile(legacyModule(t, src), Options{LangVersion: Version120})
+	if err != nil {
+		t.Fatal(err)
+	}
+	glslMustContain(t, out, "while(true)")
+
+	_, _, err = Compile(legacyModule(t, src), Options{LangVersion: VersionES100})
+	if want := "loop without a constant iteration count is not available in GLSL ES 1.00"; err == nil || !strings.Contains(err.Error(), want) {
+		t.Errorf("got %v, want error containing %q", err, want)
+	}
+}
+
+func TestLegacyVersionString(t *testing.T) {
+	for v, want := range map[Version]string{
+		Version120:   "120",
+		VersionES100: "100",
+		Version330:   "330 core",
+		VersionES300: "300 es",
+	} {
+		if got := v.String(); got != want {
+			t.Errorf("%+v.String() = %q, want %q", v, got, want)
+		}
+	}
+}
+
+func legacyModule(t *testing.T, source string) *ir.Module {
+	t.Helper()
+	tokens, err := wgsl.NewLexer(source).Tokenize()
+	if err != nil {
+		t.Fatal(err)
+	}
+	ast, err := wgsl.NewParser(tokens).Parse()
+	if err != nil {
+		t.Fatal(err)
+	}
+	module, err := wgsl.Lower(ast)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return module
+}
@@ -5,6 +5,7 @@ package codegen
 
 import (
 	"fmt"
+	"strings"
 
 	"github.com/gogpu/naga/ir"
 )
@@ -34,6 +35,9 @@ func (w *Writer) writeBlock(block ir.Block) error {
 
 // writeStatement writes a single statement.
 func (w *Writer) writeStatement(stmt ir.Statement) error {
+	if w.SourceMap {
+		w.MarkSpan(ir.StatementSpan(w.currentFunction, stmt.Kind))
+	}
 	return w.writeStatementKind(stmt.Kind)
 }
 
@@ -233,7 +237,7 @@ func (w *Writer) maybeEmitExpression(handle ir.ExpressionHandle) error {
 	// Fallback: if ExpressionTypes didn't populate this slot, try resolving
 	// the type dynamically from the expression itself.
 	if baseType == "" && w.module != nil && w.currentFunction != nil {
-		resolved, err := ir.ResolveExpressionType(w.module, w.currentFunction, handle)
+		resolved, err := ir.ExpressionType(w.module, w.currentFunction, handle)
 		if err == nil {
 			if resolved.Handle != nil {
 				baseType = w.getBaseTypeName(*resolved.Handle)
@@ -588,9 +592,7 @@ func (w *Writer) writeDirectReturn(ret ir.StmtReturn) error {
 			if w.options.WriterFlags&WriterFlagAdjustCoordinateSpace != 0 {
 				w.WriteLine("gl_Position.yz = vec2(-gl_Position.y, gl_Position.z * 2.0 - gl_Position.w);")
 			}
-			if w.options.WriterFlags&WriterFlagForcePointSize != 0 {
-				w.WriteLine("gl_PointSize = 1.0;")
-			}
+			w.writeForcedPointSize()
 		}
 		w.WriteLine("return;")
 	case ir.LocationBinding:
@@ -674,14 +676,58 @@ func (w *Writer) writeCoordinateAdjustIfNeeded(info *epStructInfo) {
 			if w.options.WriterFlags&WriterFlagAdjustCoordinateSpace != 0 {
 				w.WriteLine("gl_Position.yz = vec2(-gl_Position.y, gl_Position.z * 2.0 - gl_Position.w);")
 			}
-			if w.options.WriterFlags&WriterFlagForcePointSize != 0 {
-				w.WriteLine("gl_PointSize = 1.0;")
-			}
+			w.writeForcedPointSize()
 			return
 		}
 	}
 }
 
+// writeForcedPointSize writes gl_PointSize when WriterFlagForcePointSize is
+// set. GLES leaves the size of points undefined unless the vertex shader
+// writes it. An entry point with its own @builtin(point_size) output keeps
+// that value.
+func (w *Writer) writeForcedPointSize() {
+	if w.options.WriterFlags&WriterFlagForcePointSize == 0 {
+		return
+	}
+	if ep := w.getSelectedEntryPoint(); ep != nil && hasPointSizeOutput(w.module, &ep.Function) {
+		return
+	}
+	size := w.options.PointSize
+	if size == 0 {
+		size = 1
+	}
+	w.WriteLine("gl_PointSize = %s;", formatFloat(size, w.floatPrecision()))
+}
+
+// hasPointSizeOutput reports whether fn's result, or a member of its struct
+// result, is bound to @builtin(point_size).
+func hasPointSizeOutput(module *ir.Module, fn *ir.Function) bool {
+	if fn.Result == nil {
+		return false
+	}
+	isPointSize := func(binding *ir.Binding) bool {
+		if binding == nil {
+			return false
+		}
+		bb, ok := (*binding).(ir.BuiltinBinding)
+		return ok && bb.Builtin == ir.BuiltinPointSize
+	}
+	if isPointSize(fn.Result.Binding) {
+		return true
+	}
+	if int(fn.Result.Type) < len(module.Types) {
+		if st, ok := module.Types[fn.Result.Type].Inner.(ir.StructType); ok {
+			for _, member := range st.Members {
+				if isPointSize(member.Binding) {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
 // writeBarrier writes a control barrier statement.
 // Matches Rust naga's write_control_barrier: memory barriers first, then barrier().
 // Memory barriers: STORAGE→memoryBarrierBuffer, WORK_GROUP→memoryBarrierShared,
@@ -711,6 +757,13 @@ func (w *Writer) writeStore(store ir.StmtStore) error {
 	if err != nil {
 		return err
 	}
+	if w.options.WriteMaskStores && w.currentFunction != nil {
+		if mask, ok := ir.StoreWriteMask(w.module, w.currentFunction, store); ok {
+			if handled, err := w.writeMaskedStore(pointer, store, mask); handled || err != nil {
+				return err
+			}
+		}
+	}
 	value, err := w.writeExpression(store.Value)
 	if err != nil {
 		return err
@@ -720,6 +773,41 @@ func (w *Writer) writeStore(store ir.StmtStore) error {
 	return nil
 }
 
+// writeMaskedStore writes a store that replaces only the components in
+// mask: v.zx = n.yx when the new values are components of one vector, or
+// one assignment per component when no value reads the stored variable.
+// It reports false when the store must be written whole.
+func (w *Writer) writeMaskedStore(pointer string, store ir.StmtStore, mask ir.WriteMask) (bool, error) {
+	const components = "xyzw"
+	var targets strings.Builder
+	for _, c := range mask.Targets {
+		targets.WriteByte(components[c])
+	}
+	if source, read, ok := mask.Source(w.currentFunction); ok {
+		vector, err := w.writeExpression(source)
+		if err != nil {
+			return true, err
+		}
+		var sources strings.Builder
+		for _, c := range read {
+			sources.WriteByte(components[c])
+		}
+		w.WriteLine("%s.%s = %s.%s;", pointer, targets.String(), vector, sources.String())
+		return true, nil
+	}
+	if mask.ReadsTarget(w.currentFunction, store) {
+		return false, nil
+	}
+	for i, c := range mask.Targets {
+		value, err := w.writeExpression(mask.Values[i])
+		if err != nil {
+			return true, err
+		}
+		w.WriteLine("%s.%c = %s;", pointer, components[c], value)
+	}
+	return true, nil
+}
+
 // writeImageStore writes an image store statement.
 // Matches Rust naga: uses write_texture_coord for coordinate vector construction,
 // including array index merging and uint-to-int conversion.
@@ -747,6 +835,9 @@ func (w *Writer) writeImageStore(imgStore ir.StmtImageStore) error {
 // writeImageAtomic writes an image atomic operation statement.
 // Matches Rust naga's write_image_atomic: imageAtomicFun(image, coord, value);
 func (w *Writer) writeImageAtomic(imgAtomic ir.StmtImageAtomic) error {
+	if !w.options.LangVersion.supportsImageAtomics() {
+		return fmt.Errorf("glsl: texture atomics require GLSL 4.20 or ES 3.10, target is %s", w.options.LangVersion)
+	}
 	image, err := w.writeExpression(imgAtomic.Image)
 	if err != nil {
 		return err
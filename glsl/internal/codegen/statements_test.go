@@ -1027,3 +1027,42 @@ func TestIsPointerExpression(t *testing.T) {
 		}
 	})
 }
+
+func TestGLSL_WriteMaskStores(t *testing.T) {
+	src := `
+@group(0) @binding(0) var<storage, read_write> buf: array<vec4<f32>>;
+
+@compute @workgroup_size(1)
+fn main() {
+    var v = buf[3];
+    let n = buf[1];
+    v = vec4<f32>(n.y, v.y, n.x, v.w);
+    buf[0] = vec4<f32>(buf[0].x, 2.0, buf[0].z, 3.0);
+    buf[2] = vec4<f32>(v.y + 1.0, buf[2].y, buf[2].z, buf[2].w);
+    buf[4] = v;
+    buf[5] = vec4<f32>(buf[5].y, buf[5].x, buf[5].z, buf[5].w);
+}
+`
+	opts := DefaultOptions()
+	opts.LangVersion = Version430
+	plain, _, err := compileWGSLHelper(src, opts)
+	if err != nil {
+		t.Fatalf("Compile() error = %v", err)
+	}
+	if strings.Contains(plain, "v_1.xz =") {
+		t.Errorf("write mask without WriteMaskStores:\n%s", plain)
+	}
+
+	opts.WriteMaskStores = true
+	code, _, err := compileWGSLHelper(src, opts)
+	if err != nil {
+		t.Fatalf("Compile() error = %v", err)
+	}
+	mustContainGLSL(t, code, "v_1.xz = n.yx;")
+	mustContainGLSL(t, code, "_group_0_binding_0_cs[0].y = 2.0;")
+	mustContainGLSL(t, code, "_group_0_binding_0_cs[0].w = 3.0;")
+	mustContainGLSL(t, code, "_group_0_binding_0_cs[2].x = (_e30 + 1.0);")
+	mustContainGLSL(t, code, "_group_0_binding_0_cs[4] = _e48;")
+	// Swapping two components reads what the first store would change.
+	mustContainGLSL(t, code, "_group_0_binding_0_cs[5] = vec4(")
+}
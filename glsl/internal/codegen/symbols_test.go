@@ -0,0 +1,63 @@
+package codegen
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSymbolPrefix(t *testing.T) {
+	source := `
+struct Light {
+    color: vec3<f32>,
+}
+
+const SCALE: f32 = 2.0;
+
+var<private> tint: vec3<f32>;
+
+@group(0) @binding(0) var<uniform> light: Light;
+
+fn shade(c: vec3<f32>) -> vec3<f32> {
+    return c * SCALE + tint;
+}
+
+@fragment
+fn fs_main() -> @location(0) vec4<f32> {
+    return vec4<f32>(shade(light.color), 1.0);
+}
+`
+	opts := DefaultOptions()
+	opts.SymbolPrefix = "lib_"
+	code := wgslToGLSL(t, source, opts)
+
+	for _, want := range []string{"struct lib_Light {", "lib_shade(", "lib_tint", "void main()", "_group_0_binding_0_fs"} {
+		glslMustContain(t, code, want)
+	}
+	if strings.Contains(code, "lib_main") {
+		t.Errorf("GLSL entry point must stay main\n%s", code)
+	}
+}
+
+func TestSymbolPrefixHelpers(t *testing.T) {
+	source := `
+@fragment
+fn fs_main(@location(0) v: vec2<f32>) -> @location(0) vec4<f32> {
+    let m = modf(v.x);
+    let s = select(vec2<i32>(1), vec2<i32>(2), v > vec2<f32>(0.5));
+    return vec4<f32>(m.fract, m.whole, vec2<f32>(s));
+}
+`
+	opts := DefaultOptions()
+	opts.LangVersion = Version{Major: 3, Minor: 0, ES: true}
+	opts.SymbolPrefix = "lib_"
+	code := wgslToGLSL(t, source, opts)
+
+	for _, want := range []string{" lib_naga_modf(float arg) {", "lib_naga_modf(", " lib_naga_select(ivec2 reject,", "lib_naga_select("} {
+		glslMustContain(t, code, want)
+	}
+	for _, bad := range []string{" naga_modf(", " naga_select("} {
+		if strings.Contains(code, bad) {
+			t.Errorf("expected every helper to be prefixed, found %q\n%s", bad, code)
+		}
+	}
+}
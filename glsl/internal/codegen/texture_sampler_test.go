@@ -861,3 +861,64 @@ func TestCompile_NoTextureSamplerPairs(t *testing.T) {
 		t.Error("Expected version directive in output")
 	}
 }
+
+// =============================================================================
+// Test: Depth texture sampling variants
+// =============================================================================
+
+func TestCompile_DepthTextureSampleVariants(t *testing.T) {
+	tests := []struct {
+		name    string
+		body    string
+		want    []string
+		wantNot []string
+	}{
+		{
+			// A depth texture read through a filtering sampler is not a
+			// shadow lookup: it binds as a plain sampler and yields .x.
+			name:    "non-comparison sampler",
+			body:    `return textureSampleLevel(d2, s, p.xy, 1);`,
+			want:    []string{"uniform sampler2D ", "textureLod(", ").x;"},
+			wantNot: []string{"sampler2DShadow"},
+		},
+		{
+			name: "compare with offset",
+			body: `return textureSampleCompare(d2, sc, p.xy, 0.5, vec2i(1, 2));`,
+			want: []string{"uniform sampler2DShadow ", "textureOffset(", "vec3(p.xy, 0.5), ivec2(1, 2))"},
+		},
+		{
+			name: "compare level with offset",
+			body: `return textureSampleCompareLevel(d2, sc, p.xy, 0.5, vec2i(1, 2));`,
+			want: []string{"textureLodOffset(", "vec3(p.xy, 0.5), 0.0, ivec2(1, 2))"},
+		},
+		{
+			name: "cube array compare level",
+			body: `return textureSampleCompareLevel(dca, sc, p, 0, 0.5);`,
+			want: []string{"#extension GL_EXT_texture_shadow_lod : require", "uniform samplerCubeArrayShadow "},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			source, _, err := compileWGSLHelper(`
+@group(0) @binding(0) var d2: texture_depth_2d;
+@group(0) @binding(1) var dca: texture_depth_cube_array;
+@group(1) @binding(0) var s: sampler;
+@group(1) @binding(1) var sc: sampler_comparison;
+
+@fragment
+fn main(@location(0) p: vec3<f32>) -> @location(0) f32 {
+    `+tt.body+`
+}
+`, DefaultOptions())
+			if err != nil {
+				t.Fatalf("Compile() error = %v", err)
+			}
+			for _, w := range tt.want {
+				mustContain(t, source, w)
+			}
+			for _, w := range tt.wantNot {
+				mustNotContain(t, source, w)
+			}
+		})
+	}
+}
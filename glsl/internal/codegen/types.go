@@ -26,6 +26,9 @@ func (w *Writer) typeInnerToGLSL(inner ir.TypeInner) string {
 	case ir.VectorType:
 		return vectorToGLSL(t)
 	case ir.MatrixType:
+		if w.isLegacy() && w.options.LangVersion.ES {
+			return legacyMatrixToGLSL(t)
+		}
 		return matrixToGLSL(t)
 	case ir.ArrayType:
 		return w.arrayToGLSL(t)
@@ -134,6 +137,7 @@ func matrixToGLSL(t ir.MatrixType) string {
 	}
 
 	// Rust naga always writes the full matCxR form, never the shorthand matN.
+	// GLSL ES 1.00 only knows the shorthand (see legacyMatrixToGLSL).
 	switch t.Scalar.Kind {
 	case ir.ScalarFloat:
 		switch t.Scalar.Width {
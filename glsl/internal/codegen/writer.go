@@ -4,11 +4,14 @@
 package codegen
 
 import (
+	"context"
 	"fmt"
 	"math"
 	"sort"
 	"strings"
 
+	"github.com/gogpu/naga/analysis"
+	"github.com/gogpu/naga/internal/backend"
 	"github.com/gogpu/naga/internal/textutil"
 	"github.com/gogpu/naga/ir"
 )
@@ -39,8 +42,10 @@ const (
 type Writer struct {
 	textutil.IndentWriter // provides Out, Indent, WriteLine, WriteIndent, PushIndent, PopIndent
 
+	ctx     context.Context // checked before each function is written
 	module  *ir.Module
 	options *Options
+	notes   *backend.Notes
 
 	// Name management
 	names map[nameKey]string
@@ -96,6 +101,10 @@ type Writer struct {
 	needsModHelper bool
 	needsDivHelper bool
 
+	// selectHelpers are the vector types select() needs a naga_select
+	// overload for, in order of first use.
+	selectHelpers []ir.VectorType
+
 	// Block ID counter for unique interface block names (matches Rust naga's IdGenerator)
 	blockIDCounter uint32
 
@@ -107,6 +116,10 @@ type Writer struct {
 	// Feature detection (matches Rust naga's FeaturesManager)
 	features featuresManager
 
+	// legacyFragData selects gl_FragData[N] over gl_FragColor for fragment
+	// outputs on GLSL 1.20 (set by checkLegacy).
+	legacyFragData bool
+
 	// Continue forwarding for do-while switches inside loops.
 	// In GLSL, only single-body switches (rendered as do-while) need this.
 	continueCtx continueCtx
@@ -170,107 +183,33 @@ type varyingLookupKey struct {
 	stage    ir.ShaderStage
 }
 
-// namer generates unique identifiers, matching Rust naga's Namer.
-// Uses per-name counters (not global) and adds '_' suffix when name ends with digit.
+// namer generates unique identifiers: the shared backend.Namer with the
+// GLSL keyword table.
 type namer struct {
-	// unique maps base name → usage count (0 = first use, 1 = second, etc.)
-	unique map[string]uint32
+	*backend.Namer
 }
 
 func newNamer() *namer {
-	return &namer{
-		unique: make(map[string]uint32),
-	}
+	return &namer{Namer: backend.NewNamer(isKeyword)}
 }
 
 // call generates a unique name based on the given base.
-// Matches Rust naga's Namer::call:
-//   - First use of "foo" → "foo"
-//   - Second use → "foo_1"
-//   - Names ending in digit get trailing '_': "v3" → "v3_"
-//   - Keywords get trailing '_': "main" → "main_"
 func (n *namer) call(base string) string {
-	escaped := sanitizeName(base)
-
-	count, exists := n.unique[escaped]
-	if exists {
-		// Name already used — increment counter and suffix
-		n.unique[escaped] = count + 1
-		return fmt.Sprintf("%s_%d", escaped, count+1)
-	}
-
-	// First use — register it
-	n.unique[escaped] = 0
-
-	// Add '_' suffix if name ends with a digit or is a keyword
-	result := escaped
-	if len(result) > 0 && result[len(result)-1] >= '0' && result[len(result)-1] <= '9' {
-		result += "_"
-	} else if isKeyword(result) {
-		result += "_"
-	}
-
-	return result
+	return n.Call(base)
 }
 
 // sanitizeName cleans a name for use as a GLSL identifier.
-// Matches Rust naga's Namer::sanitize:
-//   - Drop leading digits
-//   - Retain only ASCII alphanumeric and '_'
-//   - Collapse consecutive '__' into single '_'
-//   - Trim trailing '_'
 func sanitizeName(name string) string {
-	if name == "" {
-		return "unnamed"
-	}
-
-	// Trim leading digits
-	start := 0
-	for start < len(name) && name[start] >= '0' && name[start] <= '9' {
-		start++
-	}
-	name = name[start:]
-
-	// Filter and collapse underscores — iterate RUNES (not bytes) for proper Unicode
-	result := make([]byte, 0, len(name))
-	for _, r := range name {
-		if (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || r == '_' || (r >= '0' && r <= '9') {
-			if r == '_' && len(result) > 0 && result[len(result)-1] == '_' {
-				continue
-			}
-			result = append(result, byte(r))
-		} else {
-			switch r {
-			case ':', '<', '>', ',', ' ':
-				if len(result) == 0 || result[len(result)-1] != '_' {
-					result = append(result, '_')
-				}
-			default:
-				// Unicode codepoint escape: u{XXXX}_
-				if len(result) > 0 && result[len(result)-1] != '_' {
-					result = append(result, '_')
-				}
-				result = append(result, []byte(fmt.Sprintf("u%04x_", r))...)
-			}
-		}
-	}
-
-	// Trim trailing underscores
-	for len(result) > 0 && result[len(result)-1] == '_' {
-		result = result[:len(result)-1]
-	}
-
-	if len(result) == 0 {
-		return "unnamed"
-	}
-	return string(result)
+	return backend.SanitizeName(name)
 }
 
 // newWriter creates a new GLSL writer.
 func newWriter(module *ir.Module, options *Options) *Writer {
 	return &Writer{
+		ctx:                context.Background(),
 		module:             module,
 		options:            options,
+		notes:              backend.NewNotes(options.Logger, "glsl"),
 		names:              make(map[nameKey]string),
 		namer:              newNamer(),
 		typeNames:          make(map[ir.TypeHandle]string),
@@ -305,6 +244,9 @@ func (w *Writer) writeModule() error {
 
 	// 1b. Collect required features and write extensions
 	w.collectFeatures()
+	if err := w.checkLegacy(); err != nil {
+		return err
+	}
 	w.features.writeExtensions(w)
 
 	// 2. Write precision qualifiers (ES only)
@@ -325,7 +267,9 @@ func (w *Writer) writeModule() error {
 	}
 
 	// 3b. Scan for texture-sampler pairs (WGSL separate → GLSL combined)
-	w.scanTextureSamplerPairs()
+	if err := w.scanTextureSamplerPairs(); err != nil {
+		return err
+	}
 
 	// 4. Write type definitions (structs)
 	if err := w.writeTypes(); err != nil {
@@ -350,7 +294,8 @@ func (w *Writer) writeModule() error {
 	// Rust naga writes these between globals and functions.
 	w.writeVaryingDeclarations()
 
-	// 7b. Write polyfill helper functions (mod, div) if needed
+	// 7b. Write polyfill helper functions (mod, div, select) if needed
+	w.scanSelectHelpers()
 	w.writeHelperFunctions()
 
 	// 7c. Separator between globals/varyings section and functions.
@@ -401,19 +346,55 @@ func (w *Writer) getSelectedEntryPoint() *ir.EntryPoint {
 
 // writePrecisionQualifiers writes precision qualifiers for ES.
 // Matches Rust naga: blank line, then float and int, then blank line.
+// GLSL ES 1.00 fragment shaders only get highp where the driver supports it.
 func (w *Writer) writePrecisionQualifiers() {
 	if !w.options.LangVersion.ES {
 		return
 	}
 
 	w.WriteLine("")
-	w.WriteLine("precision highp float;")
-	w.WriteLine("precision highp int;")
+	if ep := w.getSelectedEntryPoint(); w.isLegacy() && ep != nil && ep.Stage == ir.StageFragment {
+		w.WriteLine("#ifdef GL_FRAGMENT_PRECISION_HIGH")
+		w.WriteLine("precision highp float;")
+		w.WriteLine("precision highp int;")
+		w.WriteLine("#else")
+		w.WriteLine("precision mediump float;")
+		w.WriteLine("precision mediump int;")
+		w.WriteLine("#endif")
+	} else {
+		w.WriteLine("precision highp float;")
+		w.WriteLine("precision highp int;")
+	}
 	w.WriteLine("")
 }
 
+// samplerPrecision returns the precision qualifier for sampler and image
+// uniforms: highp on ES 3.00+, none on desktop and on ES 1.00, where
+// samplers keep their default lowp.
+func (w *Writer) samplerPrecision() string {
+	if w.options.LangVersion.ES && !w.options.LangVersion.isLegacy() {
+		return "highp "
+	}
+	return ""
+}
+
+// helperName returns the name of a generated helper function, which
+// carries SymbolPrefix like the module's own functions.
+func (w *Writer) helperName(name string) string {
+	if w.options == nil {
+		return name
+	}
+	return w.options.SymbolPrefix + name
+}
+
 // registerNames assigns unique names to all IR entities.
 func (w *Writer) registerNames() error {
+	if w.options.SymbolPrefix != "" {
+		// A prefixed WGSL symbol must not take a prefixed helper's name.
+		for _, name := range []string{"naga_select", "naga_modf", "naga_frexp", "_naga_mod", "_naga_div"} {
+			w.namer.Reserve(w.helperName(name))
+		}
+	}
 	// Register type names
 	for handle, typ := range w.module.Types {
 		var baseName string
@@ -423,7 +404,7 @@ func (w *Writer) registerNames() error {
 			// Rust naga uses "type" as the default name for unnamed types
 			baseName = "type"
 		}
-		name := w.namer.call(baseName)
+		name := w.namer.call(w.options.SymbolPrefix + baseName)
 		w.names[nameKey{kind: nameKeyType, handle1: uint32(handle)}] = name
 		w.typeNames[ir.TypeHandle(handle)] = name
 
@@ -445,7 +426,7 @@ func (w *Writer) registerNames() error {
 	// Matches Rust naga namer order: types → EP names+args+locals → functions → globals → constants.
 	// Register ALL entry points (Rust namer is module-wide, not per-EP)
 	for epIdx, ep := range w.module.EntryPoints {
-		epName := w.namer.call(ep.Name)
+		epName := w.namer.call(w.options.SymbolPrefix + ep.Name)
 		// The selected EP gets "main" as GLSL name
 		if w.options.EntryPoint == "" || ep.Name == w.options.EntryPoint {
 			w.names[nameKey{kind: nameKeyEntryPoint, handle1: uint32(epIdx)}] = "main"
@@ -483,7 +464,7 @@ func (w *Writer) registerNames() error {
 		} else {
 			baseName = fmt.Sprintf("function_%d", handle)
 		}
-		name := w.namer.call(baseName)
+		name := w.namer.call(w.options.SymbolPrefix + baseName)
 		w.names[nameKey{kind: nameKeyFunction, handle1: uint32(handle)}] = name
 
 		for argIdx, arg := range fn.Arguments {
@@ -511,7 +492,7 @@ func (w *Writer) registerNames() error {
 		} else {
 			baseName = fmt.Sprintf("const_%d", handle)
 		}
-		name := w.namer.call(baseName)
+		name := w.namer.call(w.options.SymbolPrefix + baseName)
 		w.names[nameKey{kind: nameKeyConstant, handle1: uint32(handle)}] = name
 	}
 
@@ -527,7 +508,7 @@ func (w *Writer) registerNames() error {
 		} else {
 			baseName = fmt.Sprintf("global_%d", handle)
 		}
-		namerName := w.namer.call(baseName)
+		namerName := w.namer.call(w.options.SymbolPrefix + baseName)
 
 		var name string
 		// Check if this global should get _group_G_binding_B_stage naming.
@@ -578,71 +559,61 @@ func (w *Writer) registerNames() error {
 	return nil
 }
 
-// scanTextureSamplerPairs scans reachable functions and entry points for ExprImageSample
-// expressions to discover which texture globals are paired with which sampler globals.
+// scanTextureSamplerPairs collects the texture-sampler pairs the selected
+// entry points sample, directly or through the functions they call.
 // GLSL has no separate texture/sampler types, so each pair must be emitted as a
 // single "uniform sampler2D" declaration.
-func (w *Writer) scanTextureSamplerPairs() {
-	// Scan regular functions (skip unreachable ones).
-	for i := range w.module.Functions {
-		if w.reachable != nil && !w.reachable.hasFunction(ir.FunctionHandle(i)) {
-			continue
-		}
-		fn := &w.module.Functions[i]
-		w.scanFunctionForPairs(fn)
-	}
-	// Scan entry point functions (stored inline, not in Functions[]).
+func (w *Writer) scanTextureSamplerPairs() error {
 	for i := range w.module.EntryPoints {
 		ep := &w.module.EntryPoints[i]
 		if w.options.EntryPoint != "" && ep.Name != w.options.EntryPoint {
 			continue
 		}
-		w.scanFunctionForPairs(&ep.Function)
-	}
-}
-
-// scanFunctionForPairs scans a single function's expressions for ExprImageSample.
-func (w *Writer) scanFunctionForPairs(fn *ir.Function) {
-	for _, expr := range fn.Expressions {
-		sample, ok := expr.Kind.(ir.ExprImageSample)
-		if !ok {
-			continue
+		pairs, err := analysis.SamplingPairs(w.module, ep.Name)
+		if err != nil {
+			return err
+		}
+		for _, p := range pairs {
+			w.registerTextureSamplerPair(p.Texture, p.Sampler)
 		}
-		w.registerTextureSamplerPair(fn, sample)
 	}
+	return nil
 }
 
-// registerTextureSamplerPair resolves the texture and sampler expression handles
-// back to their GlobalVariableHandles and creates a combined sampler entry.
-func (w *Writer) registerTextureSamplerPair(fn *ir.Function, sample ir.ExprImageSample) {
-	imageHandle := w.resolveGlobalVarHandle(fn, sample.Image)
-	samplerHandle := w.resolveGlobalVarHandle(fn, sample.Sampler)
-	if imageHandle == nil || samplerHandle == nil {
+// registerTextureSamplerPair creates a combined sampler entry for the
+// texture and sampler globals a sample uses. Binding arrays are left
+// alone: their elements are not combined.
+func (w *Writer) registerTextureSamplerPair(imageHandle, samplerHandle ir.GlobalVariableHandle) {
+	texGlobal := &w.module.GlobalVariables[imageHandle]
+	imgType, ok := w.module.Types[texGlobal.Type].Inner.(ir.ImageType)
+	if !ok {
+		return
+	}
+	if _, ok := w.module.Types[w.module.GlobalVariables[samplerHandle].Type].Inner.(ir.SamplerType); !ok {
 		return
 	}
 
-	pairKey := combinedPairKey(*imageHandle, *samplerHandle)
+	pairKey := combinedPairKey(imageHandle, samplerHandle)
 	if _, exists := w.combinedSamplers[pairKey]; exists {
 		return // Already registered
 	}
 
-	texName := w.names[nameKey{kind: nameKeyGlobalVariable, handle1: uint32(*imageHandle)}]
-	samplerName := w.names[nameKey{kind: nameKeyGlobalVariable, handle1: uint32(*samplerHandle)}]
+	texName := w.names[nameKey{kind: nameKeyGlobalVariable, handle1: uint32(imageHandle)}]
+	samplerName := w.names[nameKey{kind: nameKeyGlobalVariable, handle1: uint32(samplerHandle)}]
 	combinedName := texName + "_" + samplerName
 
 	// Determine the GLSL combined sampler type from the texture's ImageType.
-	glslType := "sampler2D" // default
-	texGlobal := &w.module.GlobalVariables[*imageHandle]
-	if int(texGlobal.Type) < len(w.module.Types) {
-		if imgType, ok := w.module.Types[texGlobal.Type].Inner.(ir.ImageType); ok {
-			glslType = w.imageToGLSL(imgType)
-		}
+	// A depth texture paired with a non-comparison sampler is a plain
+	// sampler: shadow samplers cannot be sampled without a reference value.
+	glslType := w.imageToGLSL(imgType)
+	if imgType.Class == ir.ImageClassDepth && !w.isSamplerComparison(samplerHandle) {
+		glslType = strings.TrimSuffix(glslType, "Shadow")
 	}
 
 	info := &combinedSamplerInfo{
 		glslName:      combinedName,
-		textureHandle: *imageHandle,
-		samplerHandle: *samplerHandle,
+		textureHandle: imageHandle,
+		samplerHandle: samplerHandle,
 		glslTypeName:  glslType,
 		binding:       texGlobal.Binding,
 	}
@@ -650,7 +621,7 @@ func (w *Writer) registerTextureSamplerPair(fn *ir.Function, sample ir.ExprImage
 	w.combinedSamplers[pairKey] = info
 	// Only mark the sampler as combined (skipped in writeGlobalVariables).
 	// The texture global stays visible so the combined declaration is emitted in-place.
-	w.globalIsCombined[*samplerHandle] = true
+	w.globalIsCombined[samplerHandle] = true
 }
 
 // resolveGlobalVarHandle traces an expression handle back to its GlobalVariableHandle.
@@ -835,9 +806,9 @@ func (w *Writer) formatLiteral(lit ir.Literal) string {
 	case ir.LiteralU32:
 		return fmt.Sprintf("%du", uint32(v))
 	case ir.LiteralF32:
-		return formatFloat(float32(v))
+		return formatFloat(float32(v), w.floatPrecision())
 	case ir.LiteralF64:
-		return formatFloat64(float64(v)) // formatFloat64 already adds LF suffix
+		return formatFloat64(float64(v), w.floatPrecision()) // formatFloat64 already adds LF suffix
 	case ir.LiteralI64:
 		return fmt.Sprintf("%dl", int64(v))
 	case ir.LiteralU64:
@@ -869,11 +840,11 @@ func (w *Writer) writeScalarValue(v ir.ScalarValue, typeHandle ir.TypeHandle) st
 		}
 		if width == 4 {
 			floatVal := math.Float32frombits(uint32(v.Bits))
-			return formatFloat(floatVal)
+			return formatFloat(floatVal, w.floatPrecision())
 		}
 		// 64-bit float
 		floatVal := math.Float64frombits(v.Bits)
-		return formatFloat64(floatVal)
+		return formatFloat64(floatVal, w.floatPrecision())
 	default:
 		return "0"
 	}
@@ -1011,10 +982,7 @@ func (w *Writer) writeGlobalVariables() error {
 // writeImageGlobalDecl writes a standalone texture/image global declaration.
 func (w *Writer) writeImageGlobalDecl(global ir.GlobalVariable, name, typeName string) {
 	imgType := w.module.Types[global.Type].Inner.(ir.ImageType)
-	highp := ""
-	if w.options.LangVersion.ES {
-		highp = "highp "
-	}
+	highp := w.samplerPrecision()
 	// Build layout qualifier parts
 	var layoutParts []string
 	if binding, ok := w.lookupBinding(global); ok {
@@ -1046,11 +1014,7 @@ func (w *Writer) writeCombinedSamplerDecl(info *combinedSamplerInfo) {
 	// Use the texture global's registered name (which is _group_G_binding_B_stage for bound globals)
 	varName := w.names[nameKey{kind: nameKeyGlobalVariable, handle1: uint32(info.textureHandle)}]
 
-	// Add highp qualifier for ES
-	highp := ""
-	if w.options.LangVersion.ES {
-		highp = "highp "
-	}
+	highp := w.samplerPrecision()
 
 	// Look up the texture global's binding from the BindingMap
 	layoutPrefix := ""
@@ -1073,10 +1037,7 @@ func (w *Writer) writeCombinedSamplerDecl(info *combinedSamplerInfo) {
 // for a texture-sampler pair that is NOT the primary (in-place) pair for its texture.
 // The combined name (texture__sampler) is kept as-is.
 func (w *Writer) writeExtraCombinedSamplerDecl(info *combinedSamplerInfo) {
-	highp := ""
-	if w.options.LangVersion.ES {
-		highp = "highp "
-	}
+	highp := w.samplerPrecision()
 	w.WriteLine("uniform %s%s %s;", highp, info.glslTypeName, info.glslName)
 	w.textureSamplerPairs = append(w.textureSamplerPairs, info.glslName)
 }
@@ -1125,11 +1086,7 @@ func (w *Writer) writeCombinedSamplerDeclarations() {
 		// Use the texture global's registered name (which is _group_G_binding_B_stage for bound globals)
 		varName := w.names[nameKey{kind: nameKeyGlobalVariable, handle1: uint32(info.textureHandle)}]
 
-		// Add highp qualifier for ES
-		highp := ""
-		if w.options.LangVersion.ES {
-			highp = "highp "
-		}
+		highp := w.samplerPrecision()
 
 		// Rust naga: layout(binding) only from binding_map
 		// Look up the texture global's binding from the BindingMap
@@ -1155,6 +1112,11 @@ func (w *Writer) writeCombinedSamplerDeclarations() {
 // Plain "uniform StructType varName;" is set via glUniform*, NOT via
 // glBindBufferRange, so UBO data would never reach the shader.
 func (w *Writer) writeUniformVariable(name, typeName string, global ir.GlobalVariable) {
+	if w.isLegacy() && global.Binding != nil {
+		w.writeLegacyUniform(global)
+		return
+	}
+
 	// Check if the type is a struct — if so, emit as a uniform block (UBO).
 	if int(global.Type) < len(w.module.Types) {
 		if st, ok := w.module.Types[global.Type].Inner.(ir.StructType); ok {
@@ -1455,6 +1417,11 @@ func (w *Writer) writeSingleVarying(binding *ir.Binding, typeHandle ir.TypeHandl
 		return
 	}
 
+	if w.isLegacy() {
+		w.writeLegacyVarying(loc, typeHandle, stage, isOutput)
+		return
+	}
+
 	direction := "in"
 	if isOutput {
 		direction = "out"
@@ -1540,7 +1507,11 @@ func (w *Writer) lookupVaryingNameWithBlend(location uint32, blendSrc *uint32, s
 }
 
 // varyingName generates the Rust naga naming convention for varying variables.
+// Legacy fragment outputs are the gl_FragColor/gl_FragData built-ins.
 func (w *Writer) varyingName(location int, stage ir.ShaderStage, isOutput bool) string {
+	if stage == ir.StageFragment && isOutput && w.isLegacy() {
+		return w.legacyFragOutput(location)
+	}
 	switch stage {
 	case ir.StageVertex:
 		if isOutput {
@@ -1619,7 +1590,7 @@ func (w *Writer) writePredeclaredHelpers() {
 
 		if isModf {
 			w.WriteLine("")
-			w.WriteLine("%s naga_modf(%s arg) {", structName, argType)
+			w.WriteLine("%s %s(%s arg) {", structName, w.helperName("naga_modf"), argType)
 			w.PushIndent()
 			w.WriteLine("%s other;", argType)
 			w.WriteLine("%s fract = modf(arg, other);", argType)
@@ -1628,7 +1599,7 @@ func (w *Writer) writePredeclaredHelpers() {
 			w.WriteLine("}")
 		} else {
 			w.WriteLine("")
-			w.WriteLine("%s naga_frexp(%s arg) {", structName, argType)
+			w.WriteLine("%s %s(%s arg) {", structName, w.helperName("naga_frexp"), argType)
 			w.PushIndent()
 			w.WriteLine("%s other;", otherType)
 			w.WriteLine("%s fract = frexp(arg, other);", argType)
@@ -1658,8 +1629,12 @@ func (w *Writer) scanNeedBakeExpressions(fn *ir.Function) {
 		}
 	}
 
-	// Count refs from expressions
-	for _, expr := range fn.Expressions {
+	// Count refs from live expressions; dead ones are never written.
+	live := ir.LiveExpressions(fn)
+	for i, expr := range fn.Expressions {
+		if !live[i] {
+			continue
+		}
 		switch e := expr.Kind.(type) {
 		case ir.ExprAccess:
 			countRef(e.Base)
@@ -1820,8 +1795,9 @@ func (w *Writer) scanNeedBakeExpressions(fn *ir.Function) {
 // writeHelperFunctions writes any needed polyfill functions.
 func (w *Writer) writeHelperFunctions() {
 	if w.needsModHelper {
+		w.notes.Polyfill("modulo")
 		w.WriteLine("// Safe modulo helper (truncated division semantics)")
-		w.WriteLine("int _naga_mod(int a, int b) {")
+		w.WriteLine("int %s(int a, int b) {", w.helperName("_naga_mod"))
 		w.PushIndent()
 		w.WriteLine("return a - b * (a / b);")
 		w.PopIndent()
@@ -1830,14 +1806,68 @@ func (w *Writer) writeHelperFunctions() {
 	}
 
 	if w.needsDivHelper {
+		w.notes.Polyfill("integer division")
 		w.WriteLine("// Safe division helper (handles zero divisor)")
-		w.WriteLine("int _naga_div(int a, int b) {")
+		w.WriteLine("int %s(int a, int b) {", w.helperName("_naga_div"))
 		w.PushIndent()
 		w.WriteLine("return b != 0 ? a / b : 0;")
 		w.PopIndent()
 		w.WriteLine("}")
 		w.WriteLine("")
 	}
+
+	for _, vec := range w.selectHelpers {
+		w.notes.Polyfill("component-wise select")
+		vecType := w.typeInnerToGLSL(vec)
+		condType := w.typeInnerToGLSL(ir.VectorType{Size: vec.Size, Scalar: ir.ScalarType{Kind: ir.ScalarBool, Width: 1}})
+		components := make([]string, vec.Size)
+		for i := range components {
+			c := "xyzw"[i]
+			components[i] = fmt.Sprintf("condition.%c ? accept.%c : reject.%c", c, c, c)
+		}
+		w.WriteLine("%s %s(%s reject, %s accept, %s condition) {", vecType, w.helperName("naga_select"), vecType, vecType, condType)
+		w.PushIndent()
+		w.WriteLine("return %s(%s);", vecType, strings.Join(components, ", "))
+		w.PopIndent()
+		w.WriteLine("}")
+		w.WriteLine("")
+	}
+}
+
+// scanSelectHelpers collects the vector types of the selects with a bvec
+// condition that mix() cannot write for the target version, in the
+// functions and entry points being written.
+func (w *Writer) scanSelectHelpers() {
+	w.selectHelpers = nil
+	seen := make(map[ir.VectorType]bool)
+	scan := func(fn *ir.Function) {
+		w.currentFunction = fn
+		for _, expr := range fn.Expressions {
+			s, ok := expr.Kind.(ir.ExprSelect)
+			if !ok {
+				continue
+			}
+			if _, ok := w.exprTypeInner(s.Condition).(ir.VectorType); !ok {
+				continue
+			}
+			vec, ok := w.exprTypeInner(s.Accept).(ir.VectorType)
+			if ok && w.needsSelectHelper(vec.Scalar) && !seen[vec] {
+				seen[vec] = true
+				w.selectHelpers = append(w.selectHelpers, vec)
+			}
+		}
+	}
+	for handle := range w.module.Functions {
+		if w.reachable == nil || w.reachable.hasFunction(ir.FunctionHandle(handle)) {
+			scan(&w.module.Functions[handle])
+		}
+	}
+	for i := range w.module.EntryPoints {
+		if ep := &w.module.EntryPoints[i]; w.options.EntryPoint == "" || ep.Name == w.options.EntryPoint {
+			scan(&ep.Function)
+		}
+	}
+	w.currentFunction = nil
 }
 
 // writeFunctions writes regular function definitions.
@@ -1850,6 +1880,9 @@ func (w *Writer) writeFunctions() error {
 		if w.reachable != nil && !w.reachable.hasFunction(ir.FunctionHandle(handle)) {
 			continue
 		}
+		if err := w.ctx.Err(); err != nil {
+			return err
+		}
 		fn := &w.module.Functions[handle]
 		if err := w.writeFunction(ir.FunctionHandle(handle), fn); err != nil {
 			return err
@@ -1914,6 +1947,9 @@ func (w *Writer) writeFunction(handle ir.FunctionHandle, fn *ir.Function) error
 		}
 
 		baseType := w.getBaseTypeName(argType)
+		if sampledWithoutDepthRef(fn, uint32(argIdx)) {
+			baseType = strings.TrimSuffix(baseType, "Shadow")
+		}
 		arraySuffix := w.getArraySuffix(argType)
 		args = append(args, fmt.Sprintf("%s%s%s %s%s", qualifier, precision, baseType, argName, arraySuffix))
 	}
@@ -1936,6 +1972,22 @@ func (w *Writer) writeFunction(handle ir.FunctionHandle, fn *ir.Function) error
 	return nil
 }
 
+// sampledWithoutDepthRef reports whether fn samples its argument argIdx
+// without a depth reference. Such a depth texture argument is a plain
+// sampler, matching the global registerTextureSamplerPair declares for it.
+func sampledWithoutDepthRef(fn *ir.Function, argIdx uint32) bool {
+	for _, expr := range fn.Expressions {
+		sample, ok := expr.Kind.(ir.ExprImageSample)
+		if !ok || sample.DepthRef != nil || int(sample.Image) >= len(fn.Expressions) {
+			continue
+		}
+		if arg, ok := fn.Expressions[sample.Image].Kind.(ir.ExprFunctionArgument); ok && arg.Index == argIdx {
+			return true
+		}
+	}
+	return false
+}
+
 // writeEntryPoints writes entry point functions.
 func (w *Writer) writeEntryPoints() error {
 	for epIdx, ep := range w.module.EntryPoints {
@@ -1943,6 +1995,9 @@ func (w *Writer) writeEntryPoints() error {
 		if w.options.EntryPoint != "" && ep.Name != w.options.EntryPoint {
 			continue
 		}
+		if err := w.ctx.Err(); err != nil {
+			return err
+		}
 
 		if err := w.writeEntryPoint(epIdx, &ep); err != nil {
 			return err
@@ -2655,7 +2710,7 @@ func (w *Writer) resolveTypeInner(res *ir.TypeResolution, handle ir.ExpressionHa
 	}
 	// Fallback: resolve dynamically
 	if w.module != nil && w.currentFunction != nil {
-		resolved, err := ir.ResolveExpressionType(w.module, w.currentFunction, handle)
+		resolved, err := ir.ExpressionType(w.module, w.currentFunction, handle)
 		if err == nil {
 			if resolved.Handle != nil && int(*resolved.Handle) < len(w.module.Types) {
 				return w.module.Types[*resolved.Handle].Inner
@@ -2815,23 +2870,23 @@ func glslStorageAccess(access ir.StorageAccess) string {
 	}
 }
 
-// formatFloat formats a float32 for GLSL output.
-// Matches Rust Debug format: no '+' in exponent (3.4028235e38 not 3.4028235e+38).
-func formatFloat(f float32) string {
-	s := fmt.Sprintf("%g", f)
-	if !strings.ContainsAny(s, ".eE") {
-		s += ".0"
-	}
-	s = strings.Replace(s, "e+", "e", 1)
-	return s
+// formatFloat formats a float32 for GLSL output with precision significant
+// digits, or the shortest round-trip digits if precision is zero.
+// Matches Rust Debug format: 1e-7, and no '+' in exponent (3.4028235e38).
+func formatFloat(f float32, precision int) string {
+	return textutil.FormatFloat(float64(f), 32, precision)
 }
 
 // formatFloat64 formats a float64 for GLSL output.
-func formatFloat64(f float64) string {
-	s := fmt.Sprintf("%g", f)
-	// Ensure it has a decimal point or exponent
-	if !strings.ContainsAny(s, ".eE") {
-		s += ".0"
+func formatFloat64(f float64, precision int) string {
+	return textutil.FormatFloat(f, 64, precision) + "LF" // double literal suffix (uppercase, matching Rust naga)
+}
+
+// floatPrecision returns the significant digits float literals are
+// written with; zero means the shortest round-trip digits.
+func (w *Writer) floatPrecision() int {
+	if w.options == nil {
+		return 0
 	}
-	return s + "LF" // double literal suffix (uppercase, matching Rust naga)
+	return w.options.FloatPrecision
 }
@@ -220,7 +220,7 @@ func TestFormatFloat_EdgeCases(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			got := formatFloat(tt.input)
+			got := formatFloat(tt.input, 0)
 			if !tt.check(got) {
 				t.Errorf("formatFloat(%v) = %q, %s", tt.input, got, tt.describe)
 			}
@@ -257,7 +257,7 @@ func TestFormatFloat64_EdgeCases(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			got := formatFloat64(tt.input)
+			got := formatFloat64(tt.input, 0)
 			if !tt.check(got) {
 				t.Errorf("formatFloat64(%v) = %q, %s", tt.input, got, tt.desc)
 			}
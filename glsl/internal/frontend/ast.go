@@ -0,0 +1,206 @@
+// Copyright 2025 The GoGPU Authors
+// SPDX-License-Identifier: MIT
+
+package frontend
+
+// typeSpec is a type as written: a type name and any array dimensions
+// written after it (float[4]). A nil dimension is unsized (float[]).
+type typeSpec struct {
+	tok  token
+	name string
+	dims []expr
+}
+
+// declarator is one name in a declaration, with the array dimensions
+// written after it (x[4]) and an optional initializer.
+type declarator struct {
+	tok  token
+	name string
+	dims []expr
+	init expr
+}
+
+// layoutQualifier is one id or id = value entry of layout(...).
+type layoutQualifier struct {
+	tok   token
+	name  string
+	value expr
+}
+
+// qualifiers are the storage, memory, and layout qualifiers written
+// before a declaration.
+type qualifiers struct {
+	tok     token
+	storage string // "", "const", "shared", "buffer", "uniform", "in", "out", "inout"
+	memory  []token
+	layout  []layoutQualifier
+}
+
+// Top-level declarations.
+type (
+	// varDecl declares globals or locals of one base type.
+	varDecl struct {
+		quals qualifiers
+		ty    typeSpec
+		names []declarator
+	}
+
+	// structDecl is a struct type declaration.
+	structDecl struct {
+		tok     token
+		name    string
+		members []varDecl
+	}
+
+	// blockDecl is a buffer or uniform interface block.
+	blockDecl struct {
+		quals    qualifiers
+		tok      token
+		name     string
+		members  []varDecl
+		instance *declarator
+	}
+
+	// layoutInDecl is layout(local_size_x = ...) in;.
+	layoutInDecl struct {
+		quals qualifiers
+	}
+
+	// funcDecl is a function definition, or a prototype when body is nil.
+	funcDecl struct {
+		ret    typeSpec
+		tok    token
+		name   string
+		params []param
+		body   *blockStmt
+	}
+)
+
+type param struct {
+	tok  token
+	qual string
+	ty   typeSpec
+	name string
+}
+
+// Statements.
+type (
+	stmt interface{ pos() token }
+
+	blockStmt struct {
+		tok   token
+		stmts []stmt
+	}
+	declStmt struct{ decl *varDecl }
+	exprStmt struct {
+		tok token
+		x   expr
+	}
+	ifStmt struct {
+		tok  token
+		cond expr
+		then stmt
+		els  stmt
+	}
+	forStmt struct {
+		tok  token
+		init stmt
+		cond expr
+		post expr
+		body stmt
+	}
+	whileStmt struct {
+		tok  token
+		cond expr
+		body stmt
+	}
+	doWhileStmt struct {
+		tok  token
+		body stmt
+		cond expr
+	}
+	switchStmt struct {
+		tok      token
+		selector expr
+		body     []stmt
+	}
+	// caseLabel is case value: or default: inside a switch body.
+	caseLabel struct {
+		tok   token
+		value expr // nil for default
+	}
+	jumpStmt struct {
+		tok   token // break, continue, return, or discard
+		value expr
+	}
+	emptyStmt struct{ tok token }
+)
+
+func (s *blockStmt) pos() token   { return s.tok }
+func (s *declStmt) pos() token    { return s.decl.quals.tok }
+func (s *exprStmt) pos() token    { return s.tok }
+func (s *ifStmt) pos() token      { return s.tok }
+func (s *forStmt) pos() token     { return s.tok }
+func (s *whileStmt) pos() token   { return s.tok }
+func (s *doWhileStmt) pos() token { return s.tok }
+func (s *switchStmt) pos() token  { return s.tok }
+func (s *caseLabel) pos() token   { return s.tok }
+func (s *jumpStmt) pos() token    { return s.tok }
+func (s *emptyStmt) pos() token   { return s.tok }
+
+// Expressions.
+type (
+	expr interface{ pos() token }
+
+	identExpr   struct{ tok token }
+	literalExpr struct {
+		tok token // tokInt, tokUint, tokFloat, or an identifier true/false
+	}
+	unaryExpr struct {
+		tok     token
+		op      string
+		x       expr
+		postfix bool
+	}
+	binaryExpr struct {
+		tok  token
+		op   string
+		l, r expr
+	}
+	assignExpr struct {
+		tok  token
+		op   string // "=", "+=", ...
+		l, r expr
+	}
+	ternaryExpr struct {
+		tok             token
+		cond, then, els expr
+	}
+	// callExpr calls a function or constructor. A method call such as
+	// a.length() has recv set.
+	callExpr struct {
+		tok    token
+		callee typeSpec
+		recv   expr
+		args   []expr
+	}
+	indexExpr struct {
+		tok      token
+		x, index expr
+	}
+	memberExpr struct {
+		tok  token
+		x    expr
+		name string
+	}
+)
+
+func (e *identExpr) pos() token   { return e.tok }
+func (e *literalExpr) pos() token { return e.tok }
+func (e *unaryExpr) pos() token   { return e.tok }
+func (e *binaryExpr) pos() token  { return e.tok }
+func (e *assignExpr) pos() token  { return e.tok }
+func (e *ternaryExpr) pos() token { return e.tok }
+func (e *callExpr) pos() token    { return e.tok }
+func (e *indexExpr) pos() token   { return e.tok }
+func (e *memberExpr) pos() token  { return e.tok }
@@ -0,0 +1,760 @@
+// Copyright 2025 The GoGPU Authors
+// SPDX-License-Identifier: MIT
+
+package frontend
+
+import (
+	"fmt"
+	"strings"
+)
+
+func (t *translator) call(e *callExpr) (value, error) {
+	if e.recv != nil {
+		return t.method(e)
+	}
+	name := e.callee.name
+	if len(e.callee.dims) > 0 || builtinTypeNames[name] || t.structs[name] != nil {
+		return t.construct(e)
+	}
+	if fi, ok := t.funcs[name]; ok {
+		return t.callFunc(e, fi)
+	}
+	if b, ok := builtinFuncs[name]; ok {
+		// The WGSL frontend does not evaluate built-in calls in constant
+		// expressions.
+		v, err := b(t, e)
+		v.konst = false
+		return v, err
+	}
+	if strings.HasPrefix(name, "image") || strings.HasPrefix(name, "texture") {
+		return value{}, errorAt(e.tok, "%s: images and textures are not supported by this frontend", name)
+	}
+	return value{}, errorAt(e.tok, "undeclared function %s", name)
+}
+
+// args translates and loads call arguments, checking their count.
+func (t *translator) args(e *callExpr, counts ...int) ([]value, error) {
+	ok := len(counts) == 0
+	for _, n := range counts {
+		ok = ok || n == len(e.args)
+	}
+	if !ok {
+		want := fmt.Sprint(counts[0])
+		if len(counts) > 1 {
+			want = fmt.Sprintf("%d or %d", counts[0], counts[1])
+		}
+		return nil, errorAt(e.tok, "%s takes %s arguments, got %d", e.callee.name, want, len(e.args))
+	}
+	vals := make([]value, len(e.args))
+	for i, a := range e.args {
+		v, err := t.rvalue(a)
+		if err != nil {
+			return nil, err
+		}
+		vals[i] = v
+	}
+	return vals, nil
+}
+
+func joinText(vals []value) string {
+	parts := make([]string, len(vals))
+	for i, v := range vals {
+		parts[i] = v.text
+	}
+	return strings.Join(parts, ", ")
+}
+
+// method translates x.length(), the only method GLSL has.
+func (t *translator) method(e *callExpr) (value, error) {
+	if e.callee.name != "length" || len(e.args) > 0 {
+		return value{}, errorAt(e.tok, "unknown method %s", e.callee.name)
+	}
+	x, err := t.expr(e.recv)
+	if err != nil {
+		return value{}, err
+	}
+	switch x.ty.kind {
+	case kindArray:
+		if x.ty.length > 0 {
+			return constInt(int64(x.ty.length), scalarInt), nil
+		}
+		return value{text: "i32(arrayLength(&" + x.text + "))", ty: intType, pure: x.pure}, nil
+	case kindVector:
+		return constInt(int64(x.ty.size), scalarInt), nil
+	case kindMatrix:
+		return constInt(int64(x.ty.cols), scalarInt), nil
+	}
+	return value{}, errorAt(e.tok, "%s has no length()", x.ty)
+}
+
+func (t *translator) callFunc(e *callExpr, fi *funcInfo) (value, error) {
+	if t.fn != nil {
+		t.fn.calls = append(t.fn.calls, funcCall{tok: e.tok, callee: fi})
+	}
+	if fi.decl.name == "main" {
+		return value{}, errorAt(e.tok, "main cannot be called")
+	}
+	vals, err := t.args(e, len(fi.params))
+	if err != nil {
+		return value{}, err
+	}
+	for i, v := range vals {
+		if vals[i], err = t.convert(v, fi.params[i], e.args[i].pos()); err != nil {
+			return value{}, err
+		}
+	}
+	return value{text: fi.name + "(" + joinText(vals) + ")", ty: fi.ret}, nil
+}
+
+// construct translates a type constructor: a conversion, vector, matrix,
+// array, or struct constructor.
+func (t *translator) construct(e *callExpr) (value, error) {
+	ty, err := t.resolveType(e.callee, nil)
+	if err != nil {
+		return value{}, err
+	}
+	vals, err := t.args(e)
+	if err != nil {
+		return value{}, err
+	}
+	if len(vals) == 0 {
+		return value{}, errorAt(e.tok, "constructor %s needs arguments", e.callee.name)
+	}
+	switch ty.kind {
+	case kindScalar:
+		if len(vals) != 1 || (vals[0].ty.kind != kindScalar && vals[0].ty.kind != kindVector) {
+			return value{}, errorAt(e.tok, "%s constructor takes one scalar or vector", ty)
+		}
+		v := vals[0]
+		if v.ty.kind == kindVector {
+			// Converting a vector takes its first component.
+			v = derived(v.text+".x", scalarType(v.ty.scalar), v)
+		}
+		return castTo(v, ty), nil
+	case kindVector:
+		return t.constructVector(e, ty, vals)
+	case kindMatrix:
+		return t.constructMatrix(e, ty, vals)
+	case kindArray:
+		if ty.length == 0 {
+			ty.length = len(vals)
+		}
+		if len(vals) != ty.length {
+			return value{}, errorAt(e.tok, "%s constructor takes %d elements, got %d", ty, ty.length, len(vals))
+		}
+		for i, v := range vals {
+			if vals[i], err = t.convert(v, ty.elem, e.args[i].pos()); err != nil {
+				return value{}, err
+			}
+		}
+		return derived(ty.wgsl()+"("+joinText(vals)+")", ty, vals...), nil
+	case kindStruct:
+		if len(vals) != len(ty.st.members) {
+			return value{}, errorAt(e.tok, "%s constructor takes %d members, got %d", ty, len(ty.st.members), len(vals))
+		}
+		for i, v := range vals {
+			if vals[i], err = t.convert(v, ty.st.members[i].ty, e.args[i].pos()); err != nil {
+				return value{}, err
+			}
+		}
+		return derived(ty.wgsl()+"("+joinText(vals)+")", ty, vals...), nil
+	}
+	return value{}, errorAt(e.tok, "cannot construct %s", ty)
+}
+
+func (t *translator) constructVector(e *callExpr, ty *glType, vals []value) (value, error) {
+	if len(vals) == 1 {
+		v := vals[0]
+		switch {
+		case v.ty.kind == kindScalar:
+			return derived(ty.wgsl()+"("+castTo(v, scalarType(ty.scalar)).text+")", ty, v), nil
+		case v.ty.kind == kindVector && v.ty.size >= ty.size:
+			if v.ty.size > ty.size {
+				// A longer vector is truncated.
+				v = derived(v.text+"."+"xyzw"[:ty.size], vectorType(v.ty.scalar, ty.size), v)
+			}
+			return castTo(v, ty), nil
+		}
+	}
+	n := 0
+	for i, v := range vals {
+		if v.ty.kind != kindScalar && v.ty.kind != kindVector {
+			return value{}, errorAt(e.args[i].pos(), "%s constructor argument cannot be a %s", ty, v.ty)
+		}
+		vals[i] = castTo(v, v.ty.withScalar(ty.scalar))
+		n += v.ty.vectorSize()
+	}
+	if n != ty.size {
+		return value{}, errorAt(e.tok, "%s constructor needs %d components, got %d", ty, ty.size, n)
+	}
+	v := derived(ty.wgsl()+"("+joinText(vals)+")", ty, vals...)
+	if ty.isInteger() {
+		for _, a := range vals {
+			switch {
+			case a.ival != nil:
+				v.ivec = append(v.ivec, *a.ival)
+			case a.ivec != nil:
+				v.ivec = append(v.ivec, a.ivec...)
+			default:
+				v.ivec = nil
+				return v, nil
+			}
+		}
+	}
+	return v, nil
+}
+
+func (t *translator) constructMatrix(e *callExpr, ty *glType, vals []value) (value, error) {
+	if len(vals) == 1 {
+		v := vals[0]
+		switch {
+		case v.ty.kind == kindScalar:
+			// A scalar fills the diagonal.
+			if !v.pure {
+				return value{}, errorAt(e.tok, "diagonal %s constructor argument must not call functions", ty)
+			}
+			d := castTo(v, floatType)
+			cols := make([]string, ty.cols)
+			for c := range cols {
+				comps := make([]string, ty.size)
+				for r := range comps {
+					comps[r] = "0.0f"
+					if r == c {
+						comps[r] = d.text
+					}
+				}
+				cols[c] = fmt.Sprintf("vec%d<f32>(%s)", ty.size, strings.Join(comps, ", "))
+			}
+			return derived(ty.wgsl()+"("+strings.Join(cols, ", ")+")", ty, v), nil
+		case v.ty.kind == kindMatrix:
+			if !v.ty.equal(ty) {
+				return value{}, errorAt(e.tok, "resizing %s to %s is not supported", v.ty, ty)
+			}
+			return v, nil
+		}
+	}
+	allColumns := len(vals) == ty.cols
+	allScalars := len(vals) == ty.cols*ty.size
+	for i, v := range vals {
+		if !v.ty.isNumeric() {
+			return value{}, errorAt(e.args[i].pos(), "%s constructor argument cannot be a %s", ty, v.ty)
+		}
+		allColumns = allColumns && v.ty.kind == kindVector && v.ty.size == ty.size
+		allScalars = allScalars && v.ty.kind == kindScalar
+		vals[i] = castTo(v, v.ty.withScalar(scalarFloat))
+	}
+	if !allColumns && !allScalars {
+		return value{}, errorAt(e.tok, "%s constructor takes %d column vectors or %d scalars", ty, ty.cols, ty.cols*ty.size)
+	}
+	return derived(ty.wgsl()+"("+joinText(vals)+")", ty, vals...), nil
+}
+
+// builtinFunc translates a call to a GLSL built-in function.
+type builtinFunc func(t *translator, e *callExpr) (value, error)
+
+// builtinFuncs are the GLSL built-in functions with a WGSL counterpart.
+// It is filled in by init, since its functions refer back to it through
+// the expression translator.
+var builtinFuncs map[string]builtinFunc
+
+func init() {
+	builtinFuncs = map[string]builtinFunc{
+		"abs":   numericFunc("abs", 1),
+		"sign":  numericFunc("sign", 1),
+		"min":   numericFunc("min", 2),
+		"max":   numericFunc("max", 2),
+		"clamp": numericFunc("clamp", 3),
+
+		"mod":        modFunc,
+		"mix":        mixFunc,
+		"atan":       atanFunc,
+		"length":     floatReduce("length", 1),
+		"distance":   floatReduce("distance", 2),
+		"dot":        floatReduce("dot", 2),
+		"cross":      floatFunc("cross", 2),
+		"normalize":  floatFunc("normalize", 1),
+		"reflect":    floatFunc("reflect", 2),
+		"refract":    refractFunc,
+		"step":       floatFunc("step", 2),
+		"smoothstep": floatFunc("smoothstep", 3),
+		"fma":        floatFunc("fma", 3),
+
+		"bitCount":        intResultFunc("countOneBits", 1),
+		"findLSB":         intResultFunc("firstTrailingBit", 1),
+		"findMSB":         intResultFunc("firstLeadingBit", 1),
+		"bitfieldReverse": integerFunc("reverseBits"),
+		"bitfieldExtract": bitfieldFunc("extractBits", 1),
+		"bitfieldInsert":  bitfieldFunc("insertBits", 2),
+
+		"floatBitsToInt":  bitcastFunc(scalarFloat, scalarInt),
+		"floatBitsToUint": bitcastFunc(scalarFloat, scalarUint),
+		"intBitsToFloat":  bitcastFunc(scalarInt, scalarFloat),
+		"uintBitsToFloat": bitcastFunc(scalarUint, scalarFloat),
+
+		"packUnorm4x8":    packFunc("pack4x8unorm", 4),
+		"packSnorm4x8":    packFunc("pack4x8snorm", 4),
+		"packUnorm2x16":   packFunc("pack2x16unorm", 2),
+		"packSnorm2x16":   packFunc("pack2x16snorm", 2),
+		"packHalf2x16":    packFunc("pack2x16float", 2),
+		"unpackUnorm4x8":  unpackFunc("unpack4x8unorm", 4),
+		"unpackSnorm4x8":  unpackFunc("unpack4x8snorm", 4),
+		"unpackUnorm2x16": unpackFunc("unpack2x16unorm", 2),
+		"unpackSnorm2x16": unpackFunc("unpack2x16snorm", 2),
+		"unpackHalf2x16":  unpackFunc("unpack2x16float", 2),
+
+		"lessThan":         compareFunc("<"),
+		"lessThanEqual":    compareFunc("<="),
+		"greaterThan":      compareFunc(">"),
+		"greaterThanEqual": compareFunc(">="),
+		"equal":            compareFunc("=="),
+		"notEqual":         compareFunc("!="),
+		"any":              boolVectorFunc("any"),
+		"all":              boolVectorFunc("all"),
+		"not":              boolVectorFunc("!"),
+
+		"transpose":   transposeFunc,
+		"determinant": determinantFunc,
+
+		"barrier":             barrierFunc("workgroupBarrier"),
+		"memoryBarrierShared": barrierFunc("workgroupBarrier"),
+		"groupMemoryBarrier":  barrierFunc("workgroupBarrier"),
+		"memoryBarrier":       barrierFunc("storageBarrier"),
+		"memoryBarrierBuffer": barrierFunc("storageBarrier"),
+
+		"atomicAdd":      atomicFunc("atomicAdd"),
+		"atomicMin":      atomicFunc("atomicMin"),
+		"atomicMax":      atomicFunc("atomicMax"),
+		"atomicAnd":      atomicFunc("atomicAnd"),
+		"atomicOr":       atomicFunc("atomicOr"),
+		"atomicXor":      atomicFunc("atomicXor"),
+		"atomicExchange": atomicFunc("atomicExchange"),
+		"atomicCompSwap": atomicCompSwap,
+	}
+	for glsl, wgsl := range map[string]string{
+		"radians": "radians", "degrees": "degrees",
+		"sin": "sin", "cos": "cos", "tan": "tan", "asin": "asin", "acos": "acos",
+		"sinh": "sinh", "cosh": "cosh", "tanh": "tanh", "asinh": "asinh", "acosh": "acosh", "atanh": "atanh",
+		"exp": "exp", "log": "log", "exp2": "exp2", "log2": "log2", "sqrt": "sqrt", "inversesqrt": "inverseSqrt",
+		"floor": "floor", "ceil": "ceil", "trunc": "trunc", "fract": "fract",
+		"round": "round", "roundEven": "round",
+	} {
+		builtinFuncs[glsl] = floatFunc(wgsl, 1)
+	}
+	builtinFuncs["pow"] = floatFunc("pow", 2)
+}
+
+// shape splats scalar arguments to the widest vector among them, since
+// WGSL built-ins take matching argument types.
+func shape(vals []value) []value {
+	n := 1
+	for _, v := range vals {
+		n = max(n, v.ty.vectorSize())
+	}
+	for i, v := range vals {
+		vals[i] = splat(v, n)
+	}
+	return vals
+}
+
+func sameShape(e *callExpr, vals []value) error {
+	n := 1
+	for i, v := range vals {
+		s := v.ty.vectorSize()
+		if s != 1 && n != 1 && s != n {
+			return errorAt(e.args[i].pos(), "%s arguments have different sizes", e.callee.name)
+		}
+		n = max(n, s)
+	}
+	return nil
+}
+
+// floatArgs translates arguments of a genType function, converting
+// integers to float.
+func (t *translator) floatArgs(e *callExpr, n int) ([]value, error) {
+	vals, err := t.args(e, n)
+	if err != nil {
+		return nil, err
+	}
+	for i, v := range vals {
+		if !v.ty.isNumeric() {
+			return nil, errorAt(e.args[i].pos(), "%s takes float arguments, got %s", e.callee.name, v.ty)
+		}
+		vals[i] = castTo(v, v.ty.withScalar(scalarFloat))
+	}
+	return vals, sameShape(e, vals)
+}
+
+func floatFunc(name string, n int) builtinFunc {
+	return func(t *translator, e *callExpr) (value, error) {
+		vals, err := t.floatArgs(e, n)
+		if err != nil {
+			return value{}, err
+		}
+		vals = shape(vals)
+		ty := vals[len(vals)-1].ty
+		if name == "cross" && ty.vectorSize() != 3 {
+			return value{}, errorAt(e.tok, "cross takes vec3 arguments")
+		}
+		return derived(name+"("+joinText(vals)+")", ty, vals...), nil
+	}
+}
+
+// floatReduce is a float function returning a scalar.
+func floatReduce(name string, n int) builtinFunc {
+	return func(t *translator, e *callExpr) (value, error) {
+		vals, err := t.floatArgs(e, n)
+		if err != nil {
+			return value{}, err
+		}
+		if vals[0].ty.kind == kindScalar {
+			switch name {
+			case "length":
+				return derived("abs("+vals[0].text+")", floatType, vals...), nil
+			case "distance":
+				return derived("abs("+vals[0].text+" - "+vals[1].text+")", floatType, vals...), nil
+			case "dot":
+				return derived("("+vals[0].text+" * "+vals[1].text+")", floatType, vals...), nil
+			}
+		}
+		if n == 2 && !vals[0].ty.equal(vals[1].ty) {
+			return value{}, errorAt(e.tok, "%s arguments have different types %s and %s", name, vals[0].ty, vals[1].ty)
+		}
+		return derived(name+"("+joinText(vals)+")", floatType, vals...), nil
+	}
+}
+
+func numericFunc(name string, n int) builtinFunc {
+	return func(t *translator, e *callExpr) (value, error) {
+		vals, err := t.args(e, n)
+		if err != nil {
+			return value{}, err
+		}
+		vals, s, err := t.commonScalar(e.tok, vals...)
+		if err != nil {
+			return value{}, err
+		}
+		if name == "sign" && s == scalarUint {
+			return value{}, errorAt(e.tok, "sign takes int or float arguments")
+		}
+		if err := sameShape(e, vals); err != nil {
+			return value{}, err
+		}
+		vals = shape(vals)
+		return derived(name+"("+joinText(vals)+")", vals[0].ty, vals...), nil
+	}
+}
+
+// modFunc translates mod, which floors where WGSL's % truncates.
+func modFunc(t *translator, e *callExpr) (value, error) {
+	vals, err := t.floatArgs(e, 2)
+	if err != nil {
+		return value{}, err
+	}
+	x, y := vals[0], vals[1]
+	if !x.pure || !y.pure {
+		return value{}, errorAt(e.tok, "mod arguments must not call functions; assign them to variables first")
+	}
+	ty := x.ty
+	if y.ty.vectorSize() > ty.vectorSize() {
+		ty = y.ty
+	}
+	return derived(fmt.Sprintf("(%s - %s * floor(%s / %s))", x.text, y.text, x.text, y.text), ty, x, y), nil
+}
+
+// mixFunc translates mix, which selects when its weight is a bool.
+func mixFunc(t *translator, e *callExpr) (value, error) {
+	vals, err := t.args(e, 3)
+	if err != nil {
+		return value{}, err
+	}
+	if vals[2].ty.scalar == scalarBool {
+		a, b, c := vals[0], vals[1], vals[2]
+		if !a.ty.equal(b.ty) || c.ty.vectorSize() != a.ty.vectorSize() {
+			return value{}, errorAt(e.tok, "mix with a bool selector needs matching argument types")
+		}
+		return derived("select("+a.text+", "+b.text+", "+c.text+")", a.ty, a, b, c), nil
+	}
+	return floatFunc("mix", 3)(t, e)
+}
+
+// atanFunc translates atan(y_over_x) and atan(y, x).
+func atanFunc(t *translator, e *callExpr) (value, error) {
+	if len(e.args) == 2 {
+		return floatFunc("atan2", 2)(t, e)
+	}
+	return floatFunc("atan", 1)(t, e)
+}
+
+func refractFunc(t *translator, e *callExpr) (value, error) {
+	vals, err := t.floatArgs(e, 3)
+	if err != nil {
+		return value{}, err
+	}
+	if vals[2].ty.kind != kindScalar || !vals[0].ty.equal(vals[1].ty) {
+		return value{}, errorAt(e.tok, "refract takes two vectors and a float")
+	}
+	return derived("refract("+joinText(vals)+")", vals[0].ty, vals...), nil
+}
+
+func (t *translator) integerArg(e *callExpr, n int) ([]value, error) {
+	vals, err := t.args(e, n)
+	if err != nil {
+		return nil, err
+	}
+	if !vals[0].ty.isInteger() {
+		return nil, errorAt(e.args[0].pos(), "%s takes an int or uint argument, got %s", e.callee.name, vals[0].ty)
+	}
+	return vals, nil
+}
+
+func integerFunc(name string) builtinFunc {
+	return func(t *translator, e *callExpr) (value, error) {
+		vals, err := t.integerArg(e, 1)
+		if err != nil {
+			return value{}, err
+		}
+		return derived(name+"("+vals[0].text+")", vals[0].ty, vals[0]), nil
+	}
+}
+
+// intResultFunc is an integer function whose GLSL result is always
+// signed; a uint result is reinterpreted, so no bits are found gives -1.
+func intResultFunc(name string, n int) builtinFunc {
+	return func(t *translator, e *callExpr) (value, error) {
+		vals, err := t.integerArg(e, n)
+		if err != nil {
+			return value{}, err
+		}
+		v := derived(name+"("+vals[0].text+")", vals[0].ty, vals[0])
+		if v.ty.scalar == scalarUint {
+			ty := v.ty.withScalar(scalarInt)
+			v = derived(ty.wgsl()+"("+v.text+")", ty, v)
+		}
+		return v, nil
+	}
+}
+
+// bitfieldFunc translates bitfieldExtract and bitfieldInsert, whose
+// offset and bit count follow the first n arguments.
+func bitfieldFunc(name string, n int) builtinFunc {
+	return func(t *translator, e *callExpr) (value, error) {
+		vals, err := t.integerArg(e, n+2)
+		if err != nil {
+			return value{}, err
+		}
+		for i := 1; i < n; i++ {
+			if !vals[i].ty.equal(vals[0].ty) {
+				return value{}, errorAt(e.args[i].pos(), "%s arguments have different types %s and %s", e.callee.name, vals[0].ty, vals[i].ty)
+			}
+		}
+		for i := n; i < n+2; i++ {
+			if vals[i].ty.kind != kindScalar || !vals[i].ty.isInteger() {
+				return value{}, errorAt(e.args[i].pos(), "%s offset and bits must be int, got %s", e.callee.name, vals[i].ty)
+			}
+			vals[i] = castTo(vals[i], uintType)
+		}
+		return derived(name+"("+joinText(vals)+")", vals[0].ty, vals...), nil
+	}
+}
+
+func bitcastFunc(from, to scalarKind) builtinFunc {
+	return func(t *translator, e *callExpr) (value, error) {
+		vals, err := t.args(e, 1)
+		if err != nil {
+			return value{}, err
+		}
+		v := vals[0]
+		if !v.ty.isNumeric() || v.ty.scalar != from {
+			if v.ty.isNumeric() && implicitlyConverts(v.ty, v.ty.withScalar(from)) {
+				v = castTo(v, v.ty.withScalar(from))
+			} else {
+				return value{}, errorAt(e.args[0].pos(), "%s takes a %s argument, got %s", e.callee.name, v.ty.withScalar(from), v.ty)
+			}
+		}
+		ty := v.ty.withScalar(to)
+		return derived("bitcast<"+ty.wgsl()+">("+v.text+")", ty, v), nil
+	}
+}
+
+func packFunc(name string, n int) builtinFunc {
+	return func(t *translator, e *callExpr) (value, error) {
+		vals, err := t.floatArgs(e, 1)
+		if err != nil {
+			return value{}, err
+		}
+		if vals[0].ty.vectorSize() != n || vals[0].ty.kind != kindVector {
+			return value{}, errorAt(e.tok, "%s takes a vec%d", e.callee.name, n)
+		}
+		return derived(name+"("+vals[0].text+")", uintType, vals...), nil
+	}
+}
+
+func unpackFunc(name string, n int) builtinFunc {
+	return func(t *translator, e *callExpr) (value, error) {
+		vals, err := t.args(e, 1)
+		if err != nil {
+			return value{}, err
+		}
+		v, err := t.convert(vals[0], uintType, e.args[0].pos())
+		if err != nil {
+			return value{}, err
+		}
+		return derived(name+"("+v.text+")", vectorType(scalarFloat, n), v), nil
+	}
+}
+
+// compareFunc translates the component-wise vector comparisons.
+func compareFunc(op string) builtinFunc {
+	return func(t *translator, e *callExpr) (value, error) {
+		vals, err := t.args(e, 2)
+		if err != nil {
+			return value{}, err
+		}
+		a, b := vals[0], vals[1]
+		if a.ty.kind != kindVector || b.ty.kind != kindVector || a.ty.size != b.ty.size {
+			return value{}, errorAt(e.tok, "%s takes two vectors of the same size", e.callee.name)
+		}
+		if a.ty.isNumeric() {
+			if vals, _, err = t.commonScalar(e.tok, a, b); err != nil {
+				return value{}, err
+			}
+			a, b = vals[0], vals[1]
+		}
+		if !a.ty.equal(b.ty) || (a.ty.scalar == scalarBool && op != "==" && op != "!=") {
+			return value{}, errorAt(e.tok, "%s cannot compare %s with %s", e.callee.name, a.ty, b.ty)
+		}
+		return derived("("+a.text+" "+op+" "+b.text+")", vectorType(scalarBool, a.ty.size), a, b), nil
+	}
+}
+
+func boolVectorFunc(name string) builtinFunc {
+	return func(t *translator, e *callExpr) (value, error) {
+		vals, err := t.args(e, 1)
+		if err != nil {
+			return value{}, err
+		}
+		v := vals[0]
+		if v.ty.kind != kindVector || v.ty.scalar != scalarBool {
+			return value{}, errorAt(e.tok, "%s takes a bvec argument, got %s", e.callee.name, v.ty)
+		}
+		if name == "!" {
+			return derived("(!"+v.text+")", v.ty, v), nil
+		}
+		return derived(name+"("+v.text+")", boolType, v), nil
+	}
+}
+
+func transposeFunc(t *translator, e *callExpr) (value, error) {
+	vals, err := t.args(e, 1)
+	if err != nil {
+		return value{}, err
+	}
+	m := vals[0]
+	if m.ty.kind != kindMatrix {
+		return value{}, errorAt(e.tok, "transpose takes a matrix, got %s", m.ty)
+	}
+	return derived("transpose("+m.text+")", matrixType(m.ty.size, m.ty.cols), m), nil
+}
+
+func determinantFunc(t *translator, e *callExpr) (value, error) {
+	vals, err := t.args(e, 1)
+	if err != nil {
+		return value{}, err
+	}
+	m := vals[0]
+	if m.ty.kind != kindMatrix || m.ty.cols != m.ty.size {
+		return value{}, errorAt(e.tok, "determinant takes a square matrix, got %s", m.ty)
+	}
+	return derived("determinant("+m.text+")", floatType, m), nil
+}
+
+func barrierFunc(name string) builtinFunc {
+	return func(t *translator, e *callExpr) (value, error) {
+		if _, err := t.args(e, 0); err != nil {
+			return value{}, err
+		}
+		return value{text: name + "()", ty: voidType}, nil
+	}
+}
+
+// atomicTarget translates the memory argument of an atomic function. When
+// marking, it marks the location atomic; otherwise it must already be.
+func (t *translator) atomicTarget(e *callExpr) (value, error) {
+	if len(e.args) == 0 {
+		return value{}, errorAt(e.tok, "%s needs a memory argument", e.callee.name)
+	}
+	mem, err := t.expr(e.args[0])
+	if err != nil {
+		return value{}, err
+	}
+	at := e.args[0].pos()
+	if !mem.ref || mem.swizzle != nil || mem.ty.kind != kindScalar || !mem.ty.isInteger() {
+		return value{}, errorAt(at, "%s needs an int or uint variable, buffer member, or array element", e.callee.name)
+	}
+	if !mem.mutable {
+		return value{}, errorAt(at, "%s cannot modify read-only memory", e.callee.name)
+	}
+	if t.marking {
+		mem.ty.atomic = true
+	} else if !mem.ty.atomic {
+		return value{}, errorAt(at, "atomic functions need a shared variable or a buffer block member")
+	}
+	return mem, nil
+}
+
+func atomicFunc(name string) builtinFunc {
+	return func(t *translator, e *callExpr) (value, error) {
+		if len(e.args) != 2 {
+			return value{}, errorAt(e.tok, "%s takes 2 arguments, got %d", e.callee.name, len(e.args))
+		}
+		mem, err := t.atomicTarget(e)
+		if err != nil {
+			return value{}, err
+		}
+		ty := mem.ty.nonAtomic()
+		v, err := t.rvalue(e.args[1])
+		if err != nil {
+			return value{}, err
+		}
+		if v, err = t.atomicOperand(v, ty, e.args[1].pos()); err != nil {
+			return value{}, err
+		}
+		return value{text: name + "(&" + mem.text + ", " + v.text + ")", ty: ty}, nil
+	}
+}
+
+// atomicOperand converts an atomic operand to the memory's type. GLSL
+// overloads atomics on int and uint, so int literals are accepted for uint
+// memory.
+func (t *translator) atomicOperand(v value, ty *glType, at token) (value, error) {
+	if v.ty.equal(intType) && ty.equal(uintType) && v.ival != nil && *v.ival >= 0 {
+		return castTo(v, ty), nil
+	}
+	if !v.ty.equal(ty) {
+		return value{}, errorAt(at, "atomic operand must be %s, got %s", ty, v.ty)
+	}
+	return v, nil
+}
+
+func atomicCompSwap(t *translator, e *callExpr) (value, error) {
+	if len(e.args) != 3 {
+		return value{}, errorAt(e.tok, "atomicCompSwap takes 3 arguments, got %d", len(e.args))
+	}
+	mem, err := t.atomicTarget(e)
+	if err != nil {
+		return value{}, err
+	}
+	ty := mem.ty.nonAtomic()
+	ops := make([]string, 2)
+	for i := range ops {
+		v, err := t.rvalue(e.args[i+1])
+		if err != nil {
+			return value{}, err
+		}
+		if v, err = t.atomicOperand(v, ty, e.args[i+1].pos()); err != nil {
+			return value{}, err
+		}
+		ops[i] = v.text
+	}
+	return value{text: "atomicCompareExchangeWeak(&" + mem.text + ", " + ops[0] + ", " + ops[1] + ").old_value", ty: ty}, nil
+}
@@ -0,0 +1,694 @@
+// Copyright 2025 The GoGPU Authors
+// SPDX-License-Identifier: MIT
+
+package frontend
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// value is a translated expression.
+type value struct {
+	text string
+	ty   *glType
+
+	// ref marks an expression naming memory: a variable, or a member,
+	// element, or single component of one. mutable refs may be assigned.
+	// swizzle marks a multi-component swizzle of a ref, which WGSL cannot
+	// assign directly.
+	ref     bool
+	mutable bool
+	swizzle *swizzleRef
+
+	// konst marks a WGSL constant expression; ival and fval hold the
+	// value of a scalar constant, and ivec the components of an integer
+	// vector constant, when known.
+	konst bool
+	ival  *int64
+	fval  *float64
+	ivec  []int64
+
+	// pure is false when evaluating the expression calls a user function
+	// or an atomic, so it must not be evaluated twice or conditionally.
+	pure bool
+
+	// root is the variable or parameter a ref is part of.
+	root *symbol
+}
+
+// swizzleRef is the base vector and components of a swizzle.
+type swizzleRef struct {
+	base       value
+	components string
+}
+
+// rvalue translates e and loads it: an atomic is read with atomicLoad.
+func (t *translator) rvalue(e expr) (value, error) {
+	v, err := t.expr(e)
+	if err != nil {
+		return value{}, err
+	}
+	return t.load(v, e.pos())
+}
+
+func (t *translator) load(v value, at token) (value, error) {
+	if v.ty.kind == kindVoid {
+		return value{}, errorAt(at, "void value used in an expression")
+	}
+	if v.ty.atomic {
+		return value{text: "atomicLoad(&" + v.text + ")", ty: v.ty.nonAtomic(), pure: v.pure}, nil
+	}
+	if v.ty.hasAtomic() {
+		return value{}, errorAt(at, "a %s containing atomics can only be accessed member by member", v.ty)
+	}
+	v.ref, v.mutable, v.swizzle = false, false, nil
+	return v, nil
+}
+
+// expr translates e without loading it.
+func (t *translator) expr(e expr) (value, error) {
+	switch e := e.(type) {
+	case *literalExpr:
+		return literal(e.tok)
+	case *identExpr:
+		return t.ident(e.tok)
+	case *unaryExpr:
+		return t.unary(e)
+	case *binaryExpr:
+		return t.binary(e)
+	case *ternaryExpr:
+		return t.ternary(e)
+	case *indexExpr:
+		return t.index(e)
+	case *memberExpr:
+		return t.member(e)
+	case *callExpr:
+		return t.call(e)
+	case *assignExpr:
+		return value{}, errorAt(e.tok, "assignment inside an expression is not supported; assign in a separate statement")
+	}
+	return value{}, errorAt(e.pos(), "unsupported expression")
+}
+
+// literal translates a numeric or boolean literal.
+func literal(tok token) (value, error) {
+	switch tok.kind {
+	case tokFloat:
+		f, err := strconv.ParseFloat(tok.text, 64)
+		if err != nil || math.IsInf(float64(float32(f)), 0) {
+			return value{}, errorAt(tok, "float literal %s is out of range", tok.text)
+		}
+		return constFloat(f), nil
+	case tokInt, tokUint:
+		u, err := strconv.ParseUint(tok.text, 0, 64)
+		if err != nil || u > math.MaxUint32 {
+			return value{}, errorAt(tok, "integer literal %s is out of range", tok.text)
+		}
+		if tok.kind == tokUint {
+			return constInt(int64(u), scalarUint), nil
+		}
+		// Like GLSL, an int literal keeps its 32-bit pattern, so
+		// 0xFFFFFFFF is -1.
+		return constInt(int64(int32(uint32(u))), scalarInt), nil
+	}
+	return value{text: tok.text, ty: boolType, konst: true, pure: true}, nil
+}
+
+func constInt(v int64, kind scalarKind) value {
+	return value{text: formatInt(v, kind), ty: scalarType(kind), konst: true, ival: &v, pure: true}
+}
+
+func constFloat(f float64) value {
+	f = float64(float32(f))
+	return value{text: formatFloat(f), ty: floatType, konst: true, fval: &f, pure: true}
+}
+
+func formatInt(v int64, kind scalarKind) string {
+	if kind == scalarUint {
+		return strconv.FormatUint(uint64(uint32(v)), 10) + "u"
+	}
+	if v == math.MinInt32 {
+		return "(-2147483647i - 1i)"
+	}
+	if v < 0 {
+		return "(-" + strconv.FormatInt(-v, 10) + "i)"
+	}
+	return strconv.FormatInt(v, 10) + "i"
+}
+
+func formatFloat(f float64) string {
+	s := strconv.FormatFloat(f, 'g', -1, 32)
+	if !strings.ContainsAny(s, ".e") {
+		s += ".0"
+	}
+	if f < 0 {
+		return "(" + s + "f)"
+	}
+	return s + "f"
+}
+
+func (t *translator) ident(tok token) (value, error) {
+	name := tok.text
+	for i := len(t.scopes) - 1; i >= 0; i-- {
+		if sym, ok := t.scopes[i][name]; ok {
+			return symbolValue(sym), nil
+		}
+	}
+	if sym, ok := t.globals[name]; ok {
+		return symbolValue(sym), nil
+	}
+	if name == "gl_WorkGroupSize" {
+		if !t.hasWorkgroup {
+			return value{}, errorAt(tok, "gl_WorkGroupSize used before the workgroup size is declared")
+		}
+		wg := t.workgroup
+		return value{text: fmt.Sprintf("vec3<u32>(%du, %du, %du)", wg[0], wg[1], wg[2]), ty: vectorType(scalarUint, 3), konst: true, ivec: wg[:], pure: true}, nil
+	}
+	if bv, ok := builtinVars[name]; ok {
+		if t.fn == nil {
+			return value{}, errorAt(tok, "%s can only be used inside a function", name)
+		}
+		t.builtins[name] = true
+		if t.fn.decl.name != "main" {
+			t.builtinsInHelper = true
+		}
+		return value{text: name, ty: bv.ty, ref: true, pure: true}, nil
+	}
+	if strings.HasPrefix(name, "gl_") {
+		return value{}, errorAt(tok, "built-in variable %s is not available in compute shaders", name)
+	}
+	if _, ok := t.funcs[name]; ok {
+		return value{}, errorAt(tok, "function %s used as a value", name)
+	}
+	return value{}, errorAt(tok, "undeclared identifier %s", name)
+}
+
+func symbolValue(sym *symbol) value {
+	v := value{text: sym.text, ty: sym.ty, pure: true, root: sym}
+	switch sym.kind {
+	case symConst:
+		v.konst, v.ival = true, sym.ival
+	case symParam:
+		v.ref, v.mutable = true, true
+	default:
+		v.ref, v.mutable = true, !sym.readOnly
+	}
+	return v
+}
+
+func (t *translator) unary(e *unaryExpr) (value, error) {
+	if e.op == "++" || e.op == "--" {
+		return value{}, errorAt(e.tok, "%s inside an expression is not supported; use a separate statement", e.op)
+	}
+	x, err := t.rvalue(e.x)
+	if err != nil {
+		return value{}, err
+	}
+	switch e.op {
+	case "+":
+		if !x.ty.isNumeric() && x.ty.kind != kindMatrix {
+			return value{}, errorAt(e.tok, "unary + needs a numeric operand, got %s", x.ty)
+		}
+		return x, nil
+	case "-":
+		if !x.ty.isNumeric() && x.ty.kind != kindMatrix {
+			return value{}, errorAt(e.tok, "unary - needs a numeric operand, got %s", x.ty)
+		}
+		if x.ival != nil && x.ty.kind == kindScalar {
+			return constInt(-*x.ival, x.ty.scalar), nil
+		}
+		if x.fval != nil {
+			return constFloat(-*x.fval), nil
+		}
+		if x.ty.scalar == scalarUint {
+			// WGSL has no unary minus for u32; wrapping subtraction is
+			// what GLSL computes.
+			return derived("("+zero(x.ty)+" - "+x.text+")", x.ty, x), nil
+		}
+		return derived("(-"+x.text+")", x.ty, x), nil
+	case "!":
+		if !x.ty.equal(boolType) {
+			return value{}, errorAt(e.tok, "! needs a bool operand, got %s; use not() for vectors", x.ty)
+		}
+		return derived("(!"+x.text+")", x.ty, x), nil
+	case "~":
+		if !x.ty.isInteger() {
+			return value{}, errorAt(e.tok, "~ needs an integer operand, got %s", x.ty)
+		}
+		if x.ival != nil && x.ty.kind == kindScalar {
+			return constInt(^*x.ival, x.ty.scalar), nil
+		}
+		return derived("(~"+x.text+")", x.ty, x), nil
+	}
+	return value{}, errorAt(e.tok, "unsupported operator %s", e.op)
+}
+
+// derived builds the value of an operation on operands, which is
+// constant or pure only if all operands are.
+func derived(text string, ty *glType, operands ...value) value {
+	v := value{text: text, ty: ty, konst: true, pure: true}
+	for _, o := range operands {
+		v.konst = v.konst && o.konst
+		v.pure = v.pure && o.pure
+	}
+	return v
+}
+
+// zero spells 0 of a scalar or vector type.
+func zero(ty *glType) string {
+	s := literalOf(0, ty.scalar)
+	if ty.kind == kindVector {
+		return ty.wgsl() + "(" + s + ")"
+	}
+	return s
+}
+
+func literalOf(n int64, s scalarKind) string {
+	switch s {
+	case scalarFloat:
+		return formatFloat(float64(n))
+	case scalarBool:
+		return strconv.FormatBool(n != 0)
+	}
+	return formatInt(n, s)
+}
+
+// convert applies a GLSL implicit conversion of v to ty.
+func (t *translator) convert(v value, ty *glType, at token) (value, error) {
+	if v.ty.equal(ty) {
+		return v, nil
+	}
+	if !implicitlyConverts(v.ty, ty) {
+		return value{}, errorAt(at, "cannot implicitly convert %s to %s", v.ty, ty)
+	}
+	return castTo(v, ty), nil
+}
+
+// castTo converts a scalar or vector to ty with a WGSL value constructor,
+// folding constants.
+func castTo(v value, ty *glType) value {
+	if v.ty.equal(ty) {
+		return v
+	}
+	if ty.kind == kindScalar {
+		switch {
+		case v.ival != nil && ty.scalar == scalarFloat:
+			return constFloat(float64(*v.ival))
+		case v.ival != nil && ty.scalar != scalarBool:
+			return constInt(*v.ival, ty.scalar)
+		case v.fval != nil && ty.scalar != scalarFloat && ty.scalar != scalarBool && !math.IsNaN(*v.fval) && math.Abs(*v.fval) < 1<<31:
+			return constInt(int64(*v.fval), ty.scalar)
+		}
+	}
+	return derived(ty.wgsl()+"("+v.text+")", ty, v)
+}
+
+// splat widens a scalar to a vector of n components.
+func splat(v value, n int) value {
+	if n == 1 || v.ty.kind != kindScalar {
+		return v
+	}
+	return derived(fmt.Sprintf("vec%d<%s>(%s)", n, wgslScalar(v.ty.scalar), v.text), vectorType(v.ty.scalar, n), v)
+}
+
+// commonScalar converts numeric operands to their common component type,
+// the highest in int < uint < float.
+func (t *translator) commonScalar(at token, vals ...value) ([]value, scalarKind, error) {
+	best := scalarInt
+	for _, v := range vals {
+		if !v.ty.isNumeric() {
+			return nil, 0, errorAt(at, "expected a numeric operand, got %s", v.ty)
+		}
+		if rank(v.ty.scalar) > rank(best) {
+			best = v.ty.scalar
+		}
+	}
+	out := make([]value, len(vals))
+	for i, v := range vals {
+		out[i] = castTo(v, v.ty.withScalar(best))
+	}
+	return out, best, nil
+}
+
+func (t *translator) binary(e *binaryExpr) (value, error) {
+	l, err := t.rvalue(e.l)
+	if err != nil {
+		return value{}, err
+	}
+	r, err := t.rvalue(e.r)
+	if err != nil {
+		return value{}, err
+	}
+	return t.binaryValues(e, l, r)
+}
+
+// binaryValues applies the operator of e to translated operands.
+func (t *translator) binaryValues(e *binaryExpr, l, r value) (value, error) {
+	switch e.op {
+	case "&&", "||", "^^":
+		if !l.ty.equal(boolType) || !r.ty.equal(boolType) {
+			return value{}, errorAt(e.tok, "%s needs bool operands, got %s and %s", e.op, l.ty, r.ty)
+		}
+		op := e.op
+		if op == "^^" {
+			op = "!="
+		}
+		return derived("("+l.text+" "+op+" "+r.text+")", boolType, l, r), nil
+	case "==", "!=":
+		return t.equality(e, l, r)
+	case "<", ">", "<=", ">=":
+		if l.ty.kind != kindScalar || r.ty.kind != kindScalar {
+			return value{}, errorAt(e.tok, "%s needs scalar operands, got %s and %s; use lessThan() and friends for vectors", e.op, l.ty, r.ty)
+		}
+		vals, _, err := t.commonScalar(e.tok, l, r)
+		if err != nil {
+			return value{}, err
+		}
+		return derived("("+vals[0].text+" "+e.op+" "+vals[1].text+")", boolType, vals...), nil
+	case "<<", ">>":
+		return t.shift(e, l, r)
+	case "&", "|", "^":
+		if !l.ty.isInteger() || !r.ty.isInteger() {
+			return value{}, errorAt(e.tok, "%s needs integer operands, got %s and %s", e.op, l.ty, r.ty)
+		}
+		return t.componentwise(e, l, r, true)
+	case "%":
+		if !l.ty.isInteger() || !r.ty.isInteger() {
+			return value{}, errorAt(e.tok, "%% needs integer operands, got %s and %s; use mod() for floats", l.ty, r.ty)
+		}
+		return t.componentwise(e, l, r, false)
+	}
+	if l.ty.kind == kindMatrix || r.ty.kind == kindMatrix {
+		return t.matrixOp(e, l, r)
+	}
+	if !l.ty.isNumeric() || !r.ty.isNumeric() {
+		return value{}, errorAt(e.tok, "%s needs numeric operands, got %s and %s", e.op, l.ty, r.ty)
+	}
+	return t.componentwise(e, l, r, false)
+}
+
+// componentwise translates an arithmetic or bitwise operator on scalars
+// and vectors. WGSL mixes a scalar with a vector for arithmetic but not
+// for bitwise operators, so those splat the scalar.
+func (t *translator) componentwise(e *binaryExpr, l, r value, splatScalar bool) (value, error) {
+	vals, _, err := t.commonScalar(e.tok, l, r)
+	if err != nil {
+		return value{}, err
+	}
+	l, r = vals[0], vals[1]
+	ls, rs := l.ty.vectorSize(), r.ty.vectorSize()
+	if ls != rs && ls != 1 && rs != 1 {
+		return value{}, errorAt(e.tok, "%s operands %s and %s have different sizes", e.op, l.ty, r.ty)
+	}
+	ty := l.ty
+	if rs > ls {
+		ty = r.ty
+	}
+	if splatScalar {
+		l, r = splat(l, ty.vectorSize()), splat(r, ty.vectorSize())
+	}
+	if l.ival != nil && r.ival != nil && ty.kind == kindScalar {
+		if v, ok := foldInt(e.op, *l.ival, *r.ival, ty.scalar); ok {
+			return constInt(v, ty.scalar), nil
+		}
+	}
+	return derived("("+l.text+" "+e.op+" "+r.text+")", ty, l, r), nil
+}
+
+// foldInt evaluates an integer operator with GLSL's 32-bit wrapping.
+func foldInt(op string, a, b int64, s scalarKind) (int64, bool) {
+	wrap := func(v int64) int64 {
+		if s == scalarUint {
+			return int64(uint32(v))
+		}
+		return int64(int32(v))
+	}
+	switch op {
+	case "+":
+		return wrap(a + b), true
+	case "-":
+		return wrap(a - b), true
+	case "*":
+		return wrap(a * b), true
+	case "/", "%":
+		if b == 0 || (s == scalarInt && a == math.MinInt32 && b == -1) {
+			return 0, false
+		}
+		if op == "/" {
+			return wrap(a / b), true
+		}
+		return wrap(a % b), true
+	case "&":
+		return wrap(a & b), true
+	case "|":
+		return wrap(a | b), true
+	case "^":
+		return wrap(a ^ b), true
+	case "<<":
+		if b < 0 || b > 31 {
+			return 0, false
+		}
+		return wrap(a << uint(b)), true
+	case ">>":
+		if b < 0 || b > 31 {
+			return 0, false
+		}
+		if s == scalarUint {
+			return int64(uint32(a) >> uint(b)), true
+		}
+		return wrap(a >> uint(b)), true
+	}
+	return 0, false
+}
+
+func (t *translator) shift(e *binaryExpr, l, r value) (value, error) {
+	if !l.ty.isInteger() || !r.ty.isInteger() {
+		return value{}, errorAt(e.tok, "%s needs integer operands, got %s and %s", e.op, l.ty, r.ty)
+	}
+	if l.ty.kind == kindScalar && r.ty.kind == kindVector {
+		return value{}, errorAt(e.tok, "cannot shift a scalar by a vector")
+	}
+	if l.ty.kind == kindVector && r.ty.kind == kindVector && l.ty.size != r.ty.size {
+		return value{}, errorAt(e.tok, "%s operands %s and %s have different sizes", e.op, l.ty, r.ty)
+	}
+	if l.ival != nil && r.ival != nil {
+		if v, ok := foldInt(e.op, *l.ival, *r.ival, l.ty.scalar); ok {
+			return constInt(v, l.ty.scalar), nil
+		}
+	}
+	// WGSL shift amounts are u32 and shaped like the shifted value.
+	r = splat(castTo(r, r.ty.withScalar(scalarUint)), l.ty.vectorSize())
+	return derived("("+l.text+" "+e.op+" "+r.text+")", l.ty, l, r), nil
+}
+
+func (t *translator) equality(e *binaryExpr, l, r value) (value, error) {
+	if l.ty.kind == kindArray || l.ty.kind == kindStruct || l.ty.kind == kindMatrix {
+		return value{}, errorAt(e.tok, "comparing %s values with %s is not supported", l.ty, e.op)
+	}
+	if l.ty.isNumeric() && r.ty.isNumeric() {
+		vals, _, err := t.commonScalar(e.tok, l, r)
+		if err != nil {
+			return value{}, err
+		}
+		l, r = vals[0], vals[1]
+	}
+	if !l.ty.equal(r.ty) {
+		return value{}, errorAt(e.tok, "cannot compare %s with %s", l.ty, r.ty)
+	}
+	text := "(" + l.text + " " + e.op + " " + r.text + ")"
+	if l.ty.kind == kindVector {
+		if e.op == "==" {
+			text = "all" + text
+		} else {
+			text = "any" + text
+		}
+	}
+	return derived(text, boolType, l, r), nil
+}
+
+func (t *translator) matrixOp(e *binaryExpr, l, r value) (value, error) {
+	toFloat := func(v value) value {
+		if v.ty.isNumeric() {
+			return castTo(v, v.ty.withScalar(scalarFloat))
+		}
+		return v
+	}
+	l, r = toFloat(l), toFloat(r)
+	lm, rm := l.ty.kind == kindMatrix, r.ty.kind == kindMatrix
+	var ty *glType
+	switch e.op {
+	case "*":
+		switch {
+		case lm && rm && l.ty.cols == r.ty.size:
+			ty = matrixType(r.ty.cols, l.ty.size)
+		case lm && r.ty.kind == kindVector && r.ty.size == l.ty.cols:
+			ty = vectorType(scalarFloat, l.ty.size)
+		case rm && l.ty.kind == kindVector && l.ty.size == r.ty.size:
+			ty = vectorType(scalarFloat, r.ty.cols)
+		case lm && r.ty.kind == kindScalar:
+			ty = l.ty
+		case rm && l.ty.kind == kindScalar:
+			ty = r.ty
+		}
+	case "+", "-":
+		if lm && rm && l.ty.equal(r.ty) {
+			ty = l.ty
+		}
+	}
+	if ty == nil {
+		return value{}, errorAt(e.tok, "unsupported operands for %s: %s and %s", e.op, l.ty, r.ty)
+	}
+	return derived("("+l.text+" "+e.op+" "+r.text+")", ty, l, r), nil
+}
+
+func (t *translator) ternary(e *ternaryExpr) (value, error) {
+	cond, err := t.rvalue(e.cond)
+	if err != nil {
+		return value{}, err
+	}
+	if !cond.ty.equal(boolType) {
+		return value{}, errorAt(e.tok, "?: condition must be bool, got %s", cond.ty)
+	}
+	a, err := t.rvalue(e.then)
+	if err != nil {
+		return value{}, err
+	}
+	b, err := t.rvalue(e.els)
+	if err != nil {
+		return value{}, err
+	}
+	if !a.pure || !b.pure {
+		return value{}, errorAt(e.tok, "?: operands that call functions are not supported, since both are evaluated; use if/else")
+	}
+	if a.ty.isNumeric() && b.ty.isNumeric() {
+		vals, _, err := t.commonScalar(e.tok, a, b)
+		if err != nil {
+			return value{}, err
+		}
+		a, b = vals[0], vals[1]
+	}
+	if !a.ty.equal(b.ty) {
+		return value{}, errorAt(e.tok, "?: operands have different types %s and %s", a.ty, b.ty)
+	}
+	if a.ty.kind != kindScalar && a.ty.kind != kindVector {
+		return value{}, errorAt(e.tok, "?: on %s values is not supported; use if/else", a.ty)
+	}
+	return derived("select("+b.text+", "+a.text+", "+cond.text+")", a.ty, cond, a, b), nil
+}
+
+func (t *translator) index(e *indexExpr) (value, error) {
+	x, err := t.expr(e.x)
+	if err != nil {
+		return value{}, err
+	}
+	if x.swizzle != nil {
+		if x, err = t.load(x, e.tok); err != nil {
+			return value{}, err
+		}
+	}
+	i, err := t.rvalue(e.index)
+	if err != nil {
+		return value{}, err
+	}
+	if !i.ty.isInteger() || i.ty.kind != kindScalar {
+		return value{}, errorAt(e.index.pos(), "index must be an int or uint, got %s", i.ty)
+	}
+	var ty *glType
+	switch x.ty.kind {
+	case kindArray:
+		ty = x.ty.elem
+		if x.ty.length > 0 && i.ival != nil && (*i.ival < 0 || *i.ival >= int64(x.ty.length)) {
+			return value{}, errorAt(e.index.pos(), "index %d is out of bounds for %s", *i.ival, x.ty)
+		}
+	case kindVector:
+		ty = scalarType(x.ty.scalar)
+	case kindMatrix:
+		ty = vectorType(scalarFloat, x.ty.size)
+	default:
+		return value{}, errorAt(e.tok, "cannot index a %s", x.ty)
+	}
+	return value{text: x.text + "[" + i.text + "]", ty: ty, ref: x.ref, mutable: x.mutable, root: x.root, konst: x.konst && i.konst, pure: x.pure && i.pure}, nil
+}
+
+func (t *translator) member(e *memberExpr) (value, error) {
+	x, err := t.expr(e.x)
+	if err != nil {
+		return value{}, err
+	}
+	if x.swizzle != nil {
+		if x, err = t.load(x, e.tok); err != nil {
+			return value{}, err
+		}
+	}
+	switch x.ty.kind {
+	case kindStruct:
+		_, m := x.ty.st.member(e.name)
+		if m == nil {
+			return value{}, errorAt(e.tok, "%s has no member %s", x.ty, e.name)
+		}
+		return value{text: x.text + "." + m.wgsl, ty: m.ty, ref: x.ref, mutable: x.mutable, root: x.root, pure: x.pure}, nil
+	case kindScalar, kindVector:
+		return t.swizzle(e, x)
+	}
+	return value{}, errorAt(e.tok, "%s has no members", x.ty)
+}
+
+// swizzleSets are the GLSL component name sets; WGSL has no stpq.
+var swizzleSets = []string{"xyzw", "rgba", "stpq"}
+
+func (t *translator) swizzle(e *memberExpr, x value) (value, error) {
+	if x.ty.atomic {
+		return value{}, errorAt(e.tok, "cannot swizzle an atomic")
+	}
+	n := x.ty.vectorSize()
+	var set string
+	for _, s := range swizzleSets {
+		if strings.ContainsRune(s, rune(e.name[0])) {
+			set = s
+		}
+	}
+	if set == "" || len(e.name) > 4 {
+		return value{}, errorAt(e.tok, "invalid swizzle .%s", e.name)
+	}
+	comps := make([]byte, len(e.name))
+	for i := 0; i < len(e.name); i++ {
+		idx := strings.IndexByte(set, e.name[i])
+		if idx < 0 || idx >= n {
+			return value{}, errorAt(e.tok, "invalid swizzle .%s of %s", e.name, x.ty)
+		}
+		comps[i] = "xyzw"[idx]
+	}
+	ty := vectorType(x.ty.scalar, len(comps))
+	if x.ty.kind == kindScalar {
+		// Swizzling a scalar repeats it.
+		v, err := t.load(x, e.tok)
+		if err != nil {
+			return value{}, err
+		}
+		return splat(v, len(comps)), nil
+	}
+	if x.ivec != nil {
+		// Components of a known integer vector fold, so they can size
+		// arrays.
+		vals := make([]int64, len(comps))
+		for i, c := range comps {
+			vals[i] = x.ivec[strings.IndexByte("xyzw", c)]
+		}
+		if len(vals) == 1 {
+			return constInt(vals[0], x.ty.scalar), nil
+		}
+		return value{text: x.text + "." + string(comps), ty: ty, konst: true, ivec: vals, pure: true}, nil
+	}
+	v := value{text: x.text + "." + string(comps), ty: ty, root: x.root, konst: x.konst, pure: x.pure}
+	if x.ref {
+		if len(comps) == 1 {
+			v.ref, v.mutable = true, x.mutable
+		} else {
+			v.swizzle = &swizzleRef{base: x, components: string(comps)}
+		}
+	}
+	return v, nil
+}
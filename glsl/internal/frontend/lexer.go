@@ -0,0 +1,350 @@
+// Copyright 2025 The GoGPU Authors
+// SPDX-License-Identifier: MIT
+
+package frontend
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// tokenKind classifies a GLSL token.
+type tokenKind uint8
+
+const (
+	tokEOF tokenKind = iota
+	tokIdent
+	tokInt   // int literal; text holds the literal without suffix
+	tokUint  // uint literal (u/U suffix); text holds the literal without suffix
+	tokFloat // float literal; text holds the literal without suffix
+	tokPunct
+)
+
+// token is one lexed token. Line and Column are 1-based and, for tokens
+// produced by a macro expansion, locate the macro use.
+type token struct {
+	kind   tokenKind
+	text   string
+	line   int
+	column int
+}
+
+// Error is a frontend error located in the GLSL source.
+type Error struct {
+	Line    int
+	Column  int
+	Message string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("%d:%d: %s", e.Line, e.Column, e.Message)
+}
+
+func errorAt(tok token, format string, args ...any) *Error {
+	return &Error{Line: tok.line, Column: tok.column, Message: fmt.Sprintf(format, args...)}
+}
+
+// punctuators lists GLSL operators, longest first so that lexing is greedy.
+var punctuators = []string{
+	"<<=", ">>=",
+	"++", "--", "<<", ">>", "<=", ">=", "==", "!=", "&&", "||", "^^",
+	"+=", "-=", "*=", "/=", "%=", "&=", "|=", "^=",
+	"(", ")", "[", "]", "{", "}", ".", ",", ";", ":", "?", "=",
+	"+", "-", "*", "/", "%", "<", ">", "!", "~", "&", "|", "^",
+}
+
+// lexer splits GLSL source into tokens and runs the small part of the
+// preprocessor this frontend supports: #version, #pragma, and
+// object-like #define macros.
+type lexer struct {
+	src    string
+	pos    int
+	line   int
+	column int
+
+	version int
+	macros  map[string][]token
+	tokens  []token
+}
+
+// lex tokenizes src, returning the tokens terminated by tokEOF.
+func lex(src string) ([]token, error) {
+	l := &lexer{src: src, line: 1, column: 1, macros: make(map[string][]token)}
+	if err := l.run(); err != nil {
+		return nil, err
+	}
+	if l.version == 0 {
+		return nil, &Error{Line: 1, Column: 1, Message: "missing #version directive; compute shaders need #version 430 or later"}
+	}
+	return l.tokens, nil
+}
+
+func (l *lexer) here() token { return token{line: l.line, column: l.column} }
+
+func (l *lexer) advance(n int) {
+	for i := 0; i < n && l.pos < len(l.src); i++ {
+		if l.src[l.pos] == '\n' {
+			l.line++
+			l.column = 1
+		} else {
+			l.column++
+		}
+		l.pos++
+	}
+}
+
+func (l *lexer) run() error {
+	atLineStart := true
+	for l.pos < len(l.src) {
+		c := l.src[l.pos]
+		switch {
+		case c == '\n':
+			l.advance(1)
+			atLineStart = true
+			continue
+		case c == ' ' || c == '\t' || c == '\r' || c == '\f' || c == '\v':
+			l.advance(1)
+			continue
+		case strings.HasPrefix(l.src[l.pos:], "//"):
+			for l.pos < len(l.src) && l.src[l.pos] != '\n' {
+				l.advance(1)
+			}
+			continue
+		case strings.HasPrefix(l.src[l.pos:], "/*"):
+			start := l.here()
+			end := strings.Index(l.src[l.pos+2:], "*/")
+			if end < 0 {
+				return errorAt(start, "unterminated block comment")
+			}
+			l.advance(end + 4)
+			continue
+		case c == '#':
+			if !atLineStart {
+				return errorAt(l.here(), "'#' must start a preprocessor line")
+			}
+			if err := l.directive(); err != nil {
+				return err
+			}
+			continue
+		}
+		atLineStart = false
+
+		tok, err := l.next()
+		if err != nil {
+			return err
+		}
+		if tok.kind == tokIdent {
+			if body, ok := l.macros[tok.text]; ok {
+				l.expand(tok, body, map[string]bool{tok.text: true})
+				continue
+			}
+		}
+		l.tokens = append(l.tokens, tok)
+	}
+	l.tokens = append(l.tokens, token{kind: tokEOF, line: l.line, column: l.column})
+	return nil
+}
+
+// expand appends a macro body, located at use, expanding nested macros
+// other than those in active.
+func (l *lexer) expand(use token, body []token, active map[string]bool) {
+	for _, t := range body {
+		t.line, t.column = use.line, use.column
+		if t.kind == tokIdent && !active[t.text] {
+			if nested, ok := l.macros[t.text]; ok {
+				active[t.text] = true
+				l.expand(use, nested, active)
+				delete(active, t.text)
+				continue
+			}
+		}
+		l.tokens = append(l.tokens, t)
+	}
+}
+
+// next lexes one token at the current position.
+func (l *lexer) next() (token, error) {
+	tok := l.here()
+	rest := l.src[l.pos:]
+	c := rest[0]
+	switch {
+	case isIdentStart(c):
+		n := 1
+		for n < len(rest) && isIdentPart(rest[n]) {
+			n++
+		}
+		tok.kind, tok.text = tokIdent, rest[:n]
+		l.advance(n)
+		return tok, nil
+	case isDigit(c) || (c == '.' && len(rest) > 1 && isDigit(rest[1])):
+		return l.number(tok)
+	}
+	for _, p := range punctuators {
+		if strings.HasPrefix(rest, p) {
+			tok.kind, tok.text = tokPunct, p
+			l.advance(len(p))
+			return tok, nil
+		}
+	}
+	return tok, errorAt(tok, "unexpected character %q", c)
+}
+
+// number lexes an integer or floating-point literal.
+func (l *lexer) number(tok token) (token, error) {
+	rest := l.src[l.pos:]
+	n := 0
+	isFloat := false
+	if strings.HasPrefix(rest, "0x") || strings.HasPrefix(rest, "0X") {
+		n = 2
+		for n < len(rest) && isHexDigit(rest[n]) {
+			n++
+		}
+	} else {
+		for n < len(rest) && isDigit(rest[n]) {
+			n++
+		}
+		if n < len(rest) && rest[n] == '.' {
+			isFloat = true
+			n++
+			for n < len(rest) && isDigit(rest[n]) {
+				n++
+			}
+		}
+		if n < len(rest) && (rest[n] == 'e' || rest[n] == 'E') {
+			m := n + 1
+			if m < len(rest) && (rest[m] == '+' || rest[m] == '-') {
+				m++
+			}
+			if m < len(rest) && isDigit(rest[m]) {
+				isFloat = true
+				n = m
+				for n < len(rest) && isDigit(rest[n]) {
+					n++
+				}
+			}
+		}
+	}
+	text := rest[:n]
+	suffix := ""
+	for n < len(rest) && isIdentPart(rest[n]) {
+		suffix += string(rest[n])
+		n++
+	}
+	l.advance(n)
+
+	switch {
+	case suffix == "" && isFloat:
+		tok.kind = tokFloat
+	case suffix == "":
+		tok.kind = tokInt
+	case (suffix == "u" || suffix == "U") && !isFloat:
+		tok.kind = tokUint
+	case suffix == "f" || suffix == "F":
+		tok.kind = tokFloat
+	case suffix == "lf" || suffix == "LF":
+		return tok, errorAt(tok, "double precision literal %s%s is not supported", text, suffix)
+	default:
+		return tok, errorAt(tok, "invalid numeric literal %s%s", text, suffix)
+	}
+	tok.text = text
+	return tok, nil
+}
+
+// directive handles one preprocessor line.
+func (l *lexer) directive() error {
+	start := l.here()
+	end := strings.IndexByte(l.src[l.pos:], '\n')
+	if end < 0 {
+		end = len(l.src) - l.pos
+	}
+	line := strings.TrimSpace(l.src[l.pos+1 : l.pos+end])
+	name, args, _ := strings.Cut(line, " ")
+	args = strings.TrimSpace(args)
+
+	switch name {
+	case "version":
+		if l.version != 0 || len(l.tokens) > 0 {
+			return errorAt(start, "#version must be the first directive")
+		}
+		fields := strings.Fields(args)
+		v, err := strconv.Atoi(firstField(fields))
+		if err != nil {
+			return errorAt(start, "invalid #version %q", args)
+		}
+		if v < 430 {
+			return errorAt(start, "#version %d has no compute shaders; use 430 or later", v)
+		}
+		if len(fields) > 1 && fields[1] != "core" {
+			return errorAt(start, "#version profile %q is not supported", fields[1])
+		}
+		l.version = v
+	case "pragma":
+		// Optimization and debug pragmas have no effect on the IR.
+	case "define":
+		if err := l.define(start, args); err != nil {
+			return err
+		}
+	case "undef":
+		delete(l.macros, args)
+	default:
+		return errorAt(start, "preprocessor directive #%s is not supported", name)
+	}
+	l.advance(end)
+	return nil
+}
+
+// define records an object-like macro. Its body is lexed on its own so
+// errors point into the #define line.
+func (l *lexer) define(start token, args string) error {
+	n := 0
+	for n < len(args) && isIdentPart(args[n]) {
+		n++
+	}
+	if n == 0 || !isIdentStart(args[0]) {
+		return errorAt(start, "#define needs a macro name")
+	}
+	name := args[:n]
+	if n < len(args) && args[n] == '(' {
+		return errorAt(start, "function-like macro %s is not supported", name)
+	}
+	if strings.HasPrefix(name, "GL_") || strings.HasPrefix(name, "__") {
+		return errorAt(start, "macro name %s is reserved", name)
+	}
+	body := &lexer{src: strings.TrimSpace(args[n:]), line: start.line, column: start.column, macros: l.macros}
+	for body.pos < len(body.src) {
+		if c := body.src[body.pos]; c == ' ' || c == '\t' || c == '\r' {
+			body.advance(1)
+			continue
+		}
+		if strings.HasPrefix(body.src[body.pos:], "//") {
+			break
+		}
+		tok, err := body.next()
+		if err != nil {
+			return err
+		}
+		body.tokens = append(body.tokens, tok)
+	}
+	l.macros[name] = body.tokens
+	return nil
+}
+
+func firstField(fields []string) string {
+	if len(fields) == 0 {
+		return ""
+	}
+	return fields[0]
+}
+
+func isIdentStart(c byte) bool {
+	return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+func isIdentPart(c byte) bool { return isIdentStart(c) || isDigit(c) }
+
+func isDigit(c byte) bool { return c >= '0' && c <= '9' }
+
+func isHexDigit(c byte) bool {
+	return isDigit(c) || (c >= 'a' && c <= 'f') || (c >= 'A' && c <= 'F')
+}
@@ -0,0 +1,860 @@
+// Copyright 2025 The GoGPU Authors
+// SPDX-License-Identifier: MIT
+
+package frontend
+
+import (
+	"slices"
+	"strings"
+)
+
+// translationUnit is a parsed shader: its top-level declarations in
+// source order.
+type translationUnit struct {
+	decls []any
+}
+
+// parser is a recursive-descent parser for the GLSL subset the frontend
+// accepts. Struct names are tracked so declarations can be told apart from
+// expression statements.
+type parser struct {
+	toks    []token
+	pos     int
+	structs map[string]bool
+}
+
+func parse(toks []token) (*translationUnit, error) {
+	p := &parser{toks: toks, structs: make(map[string]bool)}
+	unit := &translationUnit{}
+	for !p.at(tokEOF, "") {
+		decl, err := p.topLevel()
+		if err != nil {
+			return nil, err
+		}
+		if decl != nil {
+			unit.decls = append(unit.decls, decl)
+		}
+	}
+	return unit, nil
+}
+
+func (p *parser) peek() token { return p.toks[p.pos] }
+
+func (p *parser) peekAt(n int) token {
+	if p.pos+n < len(p.toks) {
+		return p.toks[p.pos+n]
+	}
+	return p.toks[len(p.toks)-1]
+}
+
+func (p *parser) next() token {
+	t := p.toks[p.pos]
+	if t.kind != tokEOF {
+		p.pos++
+	}
+	return t
+}
+
+// at reports whether the next token has kind and, if text is not empty,
+// that text.
+func (p *parser) at(kind tokenKind, text string) bool {
+	t := p.peek()
+	return t.kind == kind && (text == "" || t.text == text)
+}
+
+func (p *parser) atPunct(text string) bool { return p.at(tokPunct, text) }
+
+func (p *parser) atWord(text string) bool { return p.at(tokIdent, text) }
+
+func (p *parser) accept(text string) bool {
+	t := p.peek()
+	if (t.kind == tokPunct || t.kind == tokIdent) && t.text == text {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *parser) expect(text string) (token, error) {
+	t := p.peek()
+	if (t.kind == tokPunct || t.kind == tokIdent) && t.text == text {
+		p.pos++
+		return t, nil
+	}
+	return t, errorAt(t, "expected '%s', found %s", text, describe(t))
+}
+
+func (p *parser) ident() (token, error) {
+	t := p.peek()
+	if t.kind != tokIdent || keywords[t.text] {
+		return t, errorAt(t, "expected identifier, found %s", describe(t))
+	}
+	p.pos++
+	return t, nil
+}
+
+func describe(t token) string {
+	if t.kind == tokEOF {
+		return "end of file"
+	}
+	return "'" + t.text + "'"
+}
+
+// keywords are words that cannot name a variable or function.
+var keywords = map[string]bool{
+	"attribute": true, "const": true, "uniform": true, "varying": true, "buffer": true, "shared": true,
+	"coherent": true, "volatile": true, "restrict": true, "readonly": true, "writeonly": true,
+	"layout": true, "centroid": true, "flat": true, "smooth": true, "noperspective": true,
+	"patch": true, "sample": true, "break": true, "continue": true, "do": true, "for": true,
+	"while": true, "switch": true, "case": true, "default": true, "if": true, "else": true,
+	"subroutine": true, "in": true, "out": true, "inout": true, "true": true, "false": true,
+	"invariant": true, "precise": true, "discard": true, "return": true, "struct": true,
+	"lowp": true, "mediump": true, "highp": true, "precision": true,
+}
+
+// isTypeName reports whether name starts a type: a built-in type or a
+// declared struct.
+func (p *parser) isTypeName(name string) bool {
+	return builtinTypeNames[name] || p.structs[name]
+}
+
+func (p *parser) topLevel() (any, error) {
+	switch {
+	case p.accept(";"):
+		return nil, nil
+	case p.atWord("precision"):
+		// Precision statements have no effect on desktop GLSL.
+		for !p.atPunct(";") && !p.at(tokEOF, "") {
+			p.next()
+		}
+		_, err := p.expect(";")
+		return nil, err
+	case p.atWord("struct"):
+		st, err := p.structDecl()
+		if err != nil {
+			return nil, err
+		}
+		if !p.atPunct(";") {
+			return nil, errorAt(p.peek(), "declaring variables with a struct definition is not supported; declare struct %s separately", st.name)
+		}
+		p.next()
+		return st, nil
+	}
+
+	quals, err := p.qualifiers()
+	if err != nil {
+		return nil, err
+	}
+	if quals.storage == "in" && p.atPunct(";") {
+		p.next()
+		return &layoutInDecl{quals: quals}, nil
+	}
+	if (quals.storage == "buffer" || quals.storage == "uniform") && p.at(tokIdent, "") && p.peekAt(1).kind == tokPunct && p.peekAt(1).text == "{" {
+		return p.blockDecl(quals)
+	}
+
+	ty, err := p.typeSpec()
+	if err != nil {
+		return nil, err
+	}
+	if p.at(tokIdent, "") && p.peekAt(1).kind == tokPunct && p.peekAt(1).text == "(" {
+		if quals.storage != "" || len(quals.layout) > 0 || len(quals.memory) > 0 {
+			return nil, errorAt(quals.tok, "qualifiers on a function declaration are not supported")
+		}
+		return p.funcDecl(ty)
+	}
+	return p.varDeclRest(quals, ty)
+}
+
+func (p *parser) qualifiers() (qualifiers, error) {
+	q := qualifiers{tok: p.peek()}
+	for {
+		t := p.peek()
+		if t.kind != tokIdent {
+			return q, nil
+		}
+		switch t.text {
+		case "layout":
+			p.next()
+			if _, err := p.expect("("); err != nil {
+				return q, err
+			}
+			for {
+				name, err := p.ident()
+				if err != nil {
+					return q, err
+				}
+				lq := layoutQualifier{tok: name, name: name.text}
+				if p.accept("=") {
+					if lq.value, err = p.conditional(); err != nil {
+						return q, err
+					}
+				}
+				q.layout = append(q.layout, lq)
+				if !p.accept(",") {
+					break
+				}
+			}
+			if _, err := p.expect(")"); err != nil {
+				return q, err
+			}
+		case "const", "shared", "buffer", "uniform", "in", "out", "inout":
+			if q.storage != "" {
+				return q, errorAt(t, "'%s' cannot be combined with '%s'", t.text, q.storage)
+			}
+			q.storage = t.text
+			p.next()
+		case "readonly", "writeonly", "coherent", "volatile", "restrict":
+			q.memory = append(q.memory, t)
+			p.next()
+		case "highp", "mediump", "lowp":
+			p.next()
+		case "attribute", "varying", "centroid", "flat", "smooth", "noperspective", "patch", "sample",
+			"invariant", "precise", "subroutine":
+			return q, errorAt(t, "qualifier '%s' is not supported in compute shaders", t.text)
+		default:
+			return q, nil
+		}
+	}
+}
+
+func (p *parser) typeSpec() (typeSpec, error) {
+	t := p.peek()
+	if t.kind == tokIdent && isOpaqueType(t.text) {
+		return typeSpec{}, errorAt(t, "%s: images and samplers are not supported", t.text)
+	}
+	if t.kind != tokIdent || !p.isTypeName(t.text) {
+		return typeSpec{}, errorAt(t, "expected a type, found %s", describe(t))
+	}
+	p.next()
+	ts := typeSpec{tok: t, name: t.text}
+	var err error
+	ts.dims, err = p.arrayDims()
+	return ts, err
+}
+
+// isOpaqueType reports whether name is a GLSL image, sampler, or texture
+// type.
+func isOpaqueType(name string) bool {
+	for _, prefix := range []string{"image", "iimage", "uimage", "sampler", "isampler", "usampler", "texture", "itexture", "utexture"} {
+		if strings.HasPrefix(name, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *parser) arrayDims() ([]expr, error) {
+	var dims []expr
+	for p.atPunct("[") {
+		p.next()
+		if p.accept("]") {
+			dims = append(dims, nil)
+			continue
+		}
+		size, err := p.conditional()
+		if err != nil {
+			return nil, err
+		}
+		if _, err := p.expect("]"); err != nil {
+			return nil, err
+		}
+		dims = append(dims, size)
+	}
+	return dims, nil
+}
+
+func (p *parser) structDecl() (*structDecl, error) {
+	p.next()
+	name, err := p.ident()
+	if err != nil {
+		return nil, err
+	}
+	members, err := p.memberList()
+	if err != nil {
+		return nil, err
+	}
+	p.structs[name.text] = true
+	return &structDecl{tok: name, name: name.text, members: members}, nil
+}
+
+// memberList parses { member declarations } of a struct or block.
+func (p *parser) memberList() ([]varDecl, error) {
+	if _, err := p.expect("{"); err != nil {
+		return nil, err
+	}
+	var members []varDecl
+	for !p.accept("}") {
+		quals, err := p.qualifiers()
+		if err != nil {
+			return nil, err
+		}
+		ty, err := p.typeSpec()
+		if err != nil {
+			return nil, err
+		}
+		decl, err := p.varDeclRest(quals, ty)
+		if err != nil {
+			return nil, err
+		}
+		members = append(members, *decl)
+	}
+	if len(members) == 0 {
+		return nil, errorAt(p.toks[p.pos-1], "empty member list")
+	}
+	return members, nil
+}
+
+func (p *parser) blockDecl(quals qualifiers) (*blockDecl, error) {
+	name := p.next()
+	members, err := p.memberList()
+	if err != nil {
+		return nil, err
+	}
+	block := &blockDecl{quals: quals, tok: name, name: name.text, members: members}
+	if !p.atPunct(";") {
+		inst, err := p.ident()
+		if err != nil {
+			return nil, err
+		}
+		dims, err := p.arrayDims()
+		if err != nil {
+			return nil, err
+		}
+		block.instance = &declarator{tok: inst, name: inst.text, dims: dims}
+	}
+	if _, err := p.expect(";"); err != nil {
+		return nil, err
+	}
+	return block, nil
+}
+
+// varDeclRest parses the declarators after a declaration's type.
+func (p *parser) varDeclRest(quals qualifiers, ty typeSpec) (*varDecl, error) {
+	decl := &varDecl{quals: quals, ty: ty}
+	for {
+		name, err := p.ident()
+		if err != nil {
+			return nil, err
+		}
+		d := declarator{tok: name, name: name.text}
+		if d.dims, err = p.arrayDims(); err != nil {
+			return nil, err
+		}
+		if p.accept("=") {
+			if p.atPunct("{") {
+				return nil, errorAt(p.peek(), "initializer lists are not supported; use a constructor")
+			}
+			if d.init, err = p.assignment(); err != nil {
+				return nil, err
+			}
+		}
+		decl.names = append(decl.names, d)
+		if !p.accept(",") {
+			break
+		}
+	}
+	if _, err := p.expect(";"); err != nil {
+		return nil, err
+	}
+	return decl, nil
+}
+
+func (p *parser) funcDecl(ret typeSpec) (*funcDecl, error) {
+	name := p.next()
+	fn := &funcDecl{ret: ret, tok: name, name: name.text}
+	p.next() // (
+	if p.atWord("void") && p.peekAt(1).kind == tokPunct && p.peekAt(1).text == ")" {
+		p.next()
+	}
+	for !p.atPunct(")") {
+		if len(fn.params) > 0 {
+			if _, err := p.expect(","); err != nil {
+				return nil, err
+			}
+		}
+		quals, err := p.qualifiers()
+		if err != nil {
+			return nil, err
+		}
+		ty, err := p.typeSpec()
+		if err != nil {
+			return nil, err
+		}
+		prm := param{tok: ty.tok, qual: quals.storage, ty: ty}
+		if p.at(tokIdent, "") {
+			n := p.next()
+			prm.tok, prm.name = n, n.text
+			dims, err := p.arrayDims()
+			if err != nil {
+				return nil, err
+			}
+			prm.ty.dims = append(prm.ty.dims, dims...)
+		}
+		fn.params = append(fn.params, prm)
+	}
+	p.next() // )
+	if p.accept(";") {
+		return fn, nil
+	}
+	body, err := p.block()
+	if err != nil {
+		return nil, err
+	}
+	fn.body = body
+	return fn, nil
+}
+
+func (p *parser) block() (*blockStmt, error) {
+	open, err := p.expect("{")
+	if err != nil {
+		return nil, err
+	}
+	b := &blockStmt{tok: open}
+	for !p.accept("}") {
+		if p.at(tokEOF, "") {
+			return nil, errorAt(p.peek(), "unterminated block")
+		}
+		s, err := p.statement()
+		if err != nil {
+			return nil, err
+		}
+		b.stmts = append(b.stmts, s)
+	}
+	return b, nil
+}
+
+// startsDecl reports whether the next tokens begin a declaration rather
+// than an expression statement.
+func (p *parser) startsDecl() bool {
+	t := p.peek()
+	if t.kind != tokIdent {
+		return false
+	}
+	switch t.text {
+	case "const", "highp", "mediump", "lowp", "layout", "shared":
+		return true
+	}
+	if !p.isTypeName(t.text) {
+		return false
+	}
+	// T name or T[...] name, but not a constructor call T(...) or T[..](...).
+	n := p.peekAt(1)
+	if n.kind == tokIdent {
+		return true
+	}
+	if n.kind != tokPunct || n.text != "[" {
+		return false
+	}
+	depth := 0
+	for i := 1; ; i++ {
+		t := p.peekAt(i)
+		switch {
+		case t.kind == tokEOF:
+			return false
+		case t.kind == tokPunct && t.text == "[":
+			depth++
+		case t.kind == tokPunct && t.text == "]":
+			depth--
+			if depth == 0 {
+				after := p.peekAt(i + 1)
+				if after.kind == tokPunct && after.text == "[" {
+					continue
+				}
+				return after.kind == tokIdent
+			}
+		}
+	}
+}
+
+func (p *parser) statement() (stmt, error) {
+	t := p.peek()
+	if t.kind == tokPunct {
+		switch t.text {
+		case "{":
+			return p.block()
+		case ";":
+			p.next()
+			return &emptyStmt{tok: t}, nil
+		}
+	}
+	if t.kind == tokIdent {
+		switch t.text {
+		case "if":
+			return p.ifStmt()
+		case "for":
+			return p.forStmt()
+		case "while":
+			p.next()
+			cond, err := p.parenExpr()
+			if err != nil {
+				return nil, err
+			}
+			body, err := p.statement()
+			if err != nil {
+				return nil, err
+			}
+			return &whileStmt{tok: t, cond: cond, body: body}, nil
+		case "do":
+			p.next()
+			body, err := p.statement()
+			if err != nil {
+				return nil, err
+			}
+			if _, err := p.expect("while"); err != nil {
+				return nil, err
+			}
+			cond, err := p.parenExpr()
+			if err != nil {
+				return nil, err
+			}
+			if _, err := p.expect(";"); err != nil {
+				return nil, err
+			}
+			return &doWhileStmt{tok: t, body: body, cond: cond}, nil
+		case "switch":
+			return p.switchStmt()
+		case "case", "default":
+			p.next()
+			label := &caseLabel{tok: t}
+			if t.text == "case" {
+				v, err := p.conditional()
+				if err != nil {
+					return nil, err
+				}
+				label.value = v
+			}
+			_, err := p.expect(":")
+			return label, err
+		case "break", "continue", "discard":
+			p.next()
+			_, err := p.expect(";")
+			return &jumpStmt{tok: t}, err
+		case "return":
+			p.next()
+			j := &jumpStmt{tok: t}
+			if !p.atPunct(";") {
+				v, err := p.expression()
+				if err != nil {
+					return nil, err
+				}
+				j.value = v
+			}
+			_, err := p.expect(";")
+			return j, err
+		}
+	}
+	if p.startsDecl() {
+		quals, err := p.qualifiers()
+		if err != nil {
+			return nil, err
+		}
+		ty, err := p.typeSpec()
+		if err != nil {
+			return nil, err
+		}
+		decl, err := p.varDeclRest(quals, ty)
+		if err != nil {
+			return nil, err
+		}
+		return &declStmt{decl: decl}, nil
+	}
+	x, err := p.expression()
+	if err != nil {
+		return nil, err
+	}
+	if _, err := p.expect(";"); err != nil {
+		return nil, err
+	}
+	return &exprStmt{tok: t, x: x}, nil
+}
+
+func (p *parser) parenExpr() (expr, error) {
+	if _, err := p.expect("("); err != nil {
+		return nil, err
+	}
+	x, err := p.expression()
+	if err != nil {
+		return nil, err
+	}
+	_, err = p.expect(")")
+	return x, err
+}
+
+func (p *parser) ifStmt() (stmt, error) {
+	t := p.next()
+	cond, err := p.parenExpr()
+	if err != nil {
+		return nil, err
+	}
+	then, err := p.statement()
+	if err != nil {
+		return nil, err
+	}
+	s := &ifStmt{tok: t, cond: cond, then: then}
+	if p.accept("else") {
+		if s.els, err = p.statement(); err != nil {
+			return nil, err
+		}
+	}
+	return s, nil
+}
+
+func (p *parser) forStmt() (stmt, error) {
+	t := p.next()
+	if _, err := p.expect("("); err != nil {
+		return nil, err
+	}
+	s := &forStmt{tok: t}
+	var err error
+	if !p.atPunct(";") {
+		if s.init, err = p.statement(); err != nil {
+			return nil, err
+		}
+	} else {
+		p.next()
+	}
+	if !p.atPunct(";") {
+		if s.cond, err = p.expression(); err != nil {
+			return nil, err
+		}
+	}
+	if _, err := p.expect(";"); err != nil {
+		return nil, err
+	}
+	if !p.atPunct(")") {
+		if s.post, err = p.expression(); err != nil {
+			return nil, err
+		}
+	}
+	if _, err := p.expect(")"); err != nil {
+		return nil, err
+	}
+	s.body, err = p.statement()
+	return s, err
+}
+
+func (p *parser) switchStmt() (stmt, error) {
+	t := p.next()
+	sel, err := p.parenExpr()
+	if err != nil {
+		return nil, err
+	}
+	body, err := p.block()
+	if err != nil {
+		return nil, err
+	}
+	return &switchStmt{tok: t, selector: sel, body: body.stmts}, nil
+}
+
+// expression parses a full expression. The comma operator is not
+// supported.
+func (p *parser) expression() (expr, error) {
+	x, err := p.assignment()
+	if err != nil {
+		return nil, err
+	}
+	if p.atPunct(",") {
+		return nil, errorAt(p.peek(), "the comma operator is not supported")
+	}
+	return x, nil
+}
+
+var assignOps = map[string]bool{
+	"=": true, "+=": true, "-=": true, "*=": true, "/=": true, "%=": true,
+	"<<=": true, ">>=": true, "&=": true, "|=": true, "^=": true,
+}
+
+func (p *parser) assignment() (expr, error) {
+	l, err := p.conditional()
+	if err != nil {
+		return nil, err
+	}
+	if t := p.peek(); t.kind == tokPunct && assignOps[t.text] {
+		p.next()
+		r, err := p.assignment()
+		if err != nil {
+			return nil, err
+		}
+		return &assignExpr{tok: t, op: t.text, l: l, r: r}, nil
+	}
+	return l, nil
+}
+
+func (p *parser) conditional() (expr, error) {
+	cond, err := p.binary(0)
+	if err != nil {
+		return nil, err
+	}
+	if !p.atPunct("?") {
+		return cond, nil
+	}
+	t := p.next()
+	then, err := p.assignment()
+	if err != nil {
+		return nil, err
+	}
+	if _, err := p.expect(":"); err != nil {
+		return nil, err
+	}
+	els, err := p.assignment()
+	if err != nil {
+		return nil, err
+	}
+	return &ternaryExpr{tok: t, cond: cond, then: then, els: els}, nil
+}
+
+// binaryLevels lists binary operators from lowest to highest precedence.
+var binaryLevels = [][]string{
+	{"||"}, {"^^"}, {"&&"}, {"|"}, {"^"}, {"&"},
+	{"==", "!="}, {"<", ">", "<=", ">="}, {"<<", ">>"}, {"+", "-"}, {"*", "/", "%"},
+}
+
+func (p *parser) binary(level int) (expr, error) {
+	if level == len(binaryLevels) {
+		return p.unary()
+	}
+	l, err := p.binary(level + 1)
+	if err != nil {
+		return nil, err
+	}
+	for {
+		t := p.peek()
+		if t.kind != tokPunct || !slices.Contains(binaryLevels[level], t.text) {
+			return l, nil
+		}
+		p.next()
+		r, err := p.binary(level + 1)
+		if err != nil {
+			return nil, err
+		}
+		l = &binaryExpr{tok: t, op: t.text, l: l, r: r}
+	}
+}
+
+func (p *parser) unary() (expr, error) {
+	t := p.peek()
+	if t.kind == tokPunct {
+		switch t.text {
+		case "+", "-", "!", "~", "++", "--":
+			p.next()
+			x, err := p.unary()
+			if err != nil {
+				return nil, err
+			}
+			return &unaryExpr{tok: t, op: t.text, x: x}, nil
+		}
+	}
+	return p.postfix()
+}
+
+func (p *parser) postfix() (expr, error) {
+	x, err := p.primary()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		t := p.peek()
+		if t.kind != tokPunct {
+			return x, nil
+		}
+		switch t.text {
+		case "[":
+			p.next()
+			idx, err := p.expression()
+			if err != nil {
+				return nil, err
+			}
+			if _, err := p.expect("]"); err != nil {
+				return nil, err
+			}
+			x = &indexExpr{tok: t, x: x, index: idx}
+		case ".":
+			p.next()
+			name, err := p.ident()
+			if err != nil {
+				return nil, err
+			}
+			if p.atPunct("(") {
+				args, err := p.arguments()
+				if err != nil {
+					return nil, err
+				}
+				x = &callExpr{tok: name, callee: typeSpec{tok: name, name: name.text}, recv: x, args: args}
+				continue
+			}
+			x = &memberExpr{tok: name, x: x, name: name.text}
+		case "++", "--":
+			p.next()
+			x = &unaryExpr{tok: t, op: t.text, x: x, postfix: true}
+		default:
+			return x, nil
+		}
+	}
+}
+
+func (p *parser) arguments() ([]expr, error) {
+	p.next() // (
+	var args []expr
+	if p.atWord("void") && p.peekAt(1).kind == tokPunct && p.peekAt(1).text == ")" {
+		p.next()
+	}
+	for !p.accept(")") {
+		if len(args) > 0 {
+			if _, err := p.expect(","); err != nil {
+				return nil, err
+			}
+		}
+		a, err := p.assignment()
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, a)
+	}
+	return args, nil
+}
+
+func (p *parser) primary() (expr, error) {
+	t := p.peek()
+	switch t.kind {
+	case tokInt, tokUint, tokFloat:
+		p.next()
+		return &literalExpr{tok: t}, nil
+	case tokPunct:
+		if t.text == "(" {
+			return p.parenExpr()
+		}
+	case tokIdent:
+		if t.text == "true" || t.text == "false" {
+			p.next()
+			return &literalExpr{tok: t}, nil
+		}
+		if p.isTypeName(t.text) {
+			ts, err := p.typeSpec()
+			if err != nil {
+				return nil, err
+			}
+			if !p.atPunct("(") {
+				return nil, errorAt(p.peek(), "expected '(' after type %s", ts.name)
+			}
+			args, err := p.arguments()
+			if err != nil {
+				return nil, err
+			}
+			return &callExpr{tok: t, callee: ts, args: args}, nil
+		}
+		if keywords[t.text] {
+			break
+		}
+		p.next()
+		if p.atPunct("(") {
+			args, err := p.arguments()
+			if err != nil {
+				return nil, err
+			}
+			return &callExpr{tok: t, callee: typeSpec{tok: t, name: t.text}, args: args}, nil
+		}
+		return &identExpr{tok: t}, nil
+	}
+	return nil, errorAt(t, "expected an expression, found %s", describe(t))
+}
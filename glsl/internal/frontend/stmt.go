@@ -0,0 +1,754 @@
+// Copyright 2025 The GoGPU Authors
+// SPDX-License-Identifier: MIT
+
+package frontend
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/gogpu/naga/internal/wgslnames"
+	"github.com/gogpu/naga/internal/wgslwriter"
+)
+
+// markAtomics translates every function body once, discarding the output,
+// to find the memory atomic functions operate on. Reads and writes of that
+// memory translate differently, so this must happen before the real pass.
+func (t *translator) markAtomics(funcs []*funcDecl) error {
+	t.marking = true
+	defer func() { t.marking = false }()
+	for _, fn := range funcs {
+		if _, err := t.function(fn); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// function translates a function definition.
+func (t *translator) function(d *funcDecl) (*wgslwriter.Writer, error) {
+	fi := t.funcs[d.name]
+	t.fn, t.temps = fi, 0
+	defer func() { t.fn, t.scopes = nil, nil }()
+
+	params := make(map[string]*symbol)
+	t.scopes = []map[string]*symbol{params}
+	syms := make([]*symbol, len(d.params))
+	for i, p := range d.params {
+		name := p.name
+		if name == "" {
+			name = fmt.Sprintf("unnamed%d", i)
+		} else if err := t.checkName(p.tok); err != nil {
+			return nil, err
+		}
+		if params[name] != nil {
+			return nil, errorAt(p.tok, "duplicate parameter %s", name)
+		}
+		syms[i] = &symbol{kind: symParam, name: name, text: wgslnames.Mangle(name), ty: fi.params[i]}
+		params[name] = syms[i]
+	}
+
+	body := &wgslwriter.Writer{Indent: 1}
+	t.w = body
+	if err := t.stmts(d.body.stmts); err != nil {
+		return nil, err
+	}
+	if fi.ret.kind != kindVoid && !returns(d.body) {
+		// GLSL leaves falling off the end undefined; WGSL requires a
+		// return on every path.
+		body.Line(d.body.tok.line, "return %s();", fi.ret.wgsl())
+	}
+
+	w := &wgslwriter.Writer{}
+	line := d.tok.line
+	var args, prologue []string
+	if d.name == "main" {
+		for _, name := range t.usedBuiltins() {
+			arg := name
+			if t.builtinsInHelper {
+				arg = name + "_in"
+				prologue = append(prologue, fmt.Sprintf("%s = %s;", name, arg))
+			}
+			args = append(args, fmt.Sprintf("@builtin(%s) %s: %s", builtinVars[name].wgsl, arg, builtinVars[name].ty.wgsl()))
+		}
+		w.Line(line, "@compute @workgroup_size(%d, %d, %d)", t.workgroup[0], t.workgroup[1], t.workgroup[2])
+	}
+	for _, sym := range syms {
+		arg := sym.text
+		if sym.assigned {
+			// WGSL parameters are immutable, so an assigned parameter is
+			// copied into a variable of the same name.
+			arg = sym.text + "_in"
+			prologue = append(prologue, fmt.Sprintf("var %s = %s;", sym.text, arg))
+		}
+		args = append(args, fmt.Sprintf("%s: %s", arg, sym.ty.wgsl()))
+	}
+	ret := ""
+	if fi.ret.kind != kindVoid {
+		ret = " -> " + fi.ret.wgsl()
+	}
+	w.Line(line, "fn %s(%s)%s {", fi.name, strings.Join(args, ", "), ret)
+	w.Indent++
+	for _, s := range prologue {
+		w.Line(line, "%s", s)
+	}
+	w.Append(body)
+	w.Indent--
+	w.Line(d.body.tok.line, "}")
+	return w, nil
+}
+
+// returns reports whether s returns on every path.
+func returns(s stmt) bool {
+	switch s := s.(type) {
+	case *jumpStmt:
+		return s.tok.text == "return"
+	case *blockStmt:
+		return len(s.stmts) > 0 && returns(s.stmts[len(s.stmts)-1])
+	case *ifStmt:
+		return s.els != nil && returns(s.then) && returns(s.els)
+	}
+	return false
+}
+
+func (t *translator) pushScope() { t.scopes = append(t.scopes, make(map[string]*symbol)) }
+func (t *translator) popScope()  { t.scopes = t.scopes[:len(t.scopes)-1] }
+
+func (t *translator) temp(prefix string) string {
+	t.temps++
+	return fmt.Sprintf("%s%d", prefix, t.temps)
+}
+
+func (t *translator) stmts(list []stmt) error {
+	for i, s := range list {
+		// memoryBarrierShared(); barrier(); is the usual GLSL idiom, and
+		// WGSL's workgroupBarrier covers both.
+		if i+1 < len(list) && t.isCall(s, "memoryBarrierShared", "groupMemoryBarrier") && t.isCall(list[i+1], "barrier") {
+			continue
+		}
+		if err := t.stmt(s); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// isCall reports whether s is a call statement of one of the named
+// built-in functions.
+func (t *translator) isCall(s stmt, names ...string) bool {
+	es, ok := s.(*exprStmt)
+	if !ok {
+		return false
+	}
+	c, ok := es.x.(*callExpr)
+	if !ok || c.recv != nil || t.funcs[c.callee.name] != nil {
+		return false
+	}
+	for _, n := range names {
+		if c.callee.name == n {
+			return true
+		}
+	}
+	return false
+}
+
+// block translates a statement as the body of a WGSL compound statement.
+func (t *translator) block(s stmt) error {
+	t.pushScope()
+	defer t.popScope()
+	if b, ok := s.(*blockStmt); ok {
+		return t.stmts(b.stmts)
+	}
+	return t.stmt(s)
+}
+
+func (t *translator) stmt(s stmt) error {
+	w := t.w
+	switch s := s.(type) {
+	case *blockStmt:
+		w.Line(s.tok.line, "{")
+		w.Indent++
+		if err := t.block(s); err != nil {
+			return err
+		}
+		w.Indent--
+		w.Line(s.tok.line, "}")
+	case *emptyStmt:
+	case *declStmt:
+		return t.local(s.decl)
+	case *exprStmt:
+		return t.exprStmt(s.x)
+	case *ifStmt:
+		return t.ifStmt(s, false)
+	case *forStmt:
+		return t.forStmt(s)
+	case *whileStmt:
+		cond, err := t.condition(s.cond)
+		if err != nil {
+			return err
+		}
+		w.Line(s.tok.line, "while %s {", cond)
+		return t.body(s.body, s.tok.line)
+	case *doWhileStmt:
+		w.Line(s.tok.line, "loop {")
+		w.Indent++
+		if err := t.block(s.body); err != nil {
+			return err
+		}
+		cond, err := t.condition(s.cond)
+		if err != nil {
+			return err
+		}
+		w.Line(s.cond.pos().line, "continuing {")
+		w.Indent++
+		w.Line(s.cond.pos().line, "break if !%s;", cond)
+		w.Indent--
+		w.Line(s.cond.pos().line, "}")
+		w.Indent--
+		w.Line(s.tok.line, "}")
+	case *switchStmt:
+		return t.switchStmt(s)
+	case *caseLabel:
+		return errorAt(s.tok, "'%s' outside a switch", s.tok.text)
+	case *jumpStmt:
+		return t.jump(s)
+	default:
+		return errorAt(s.pos(), "unsupported statement")
+	}
+	return nil
+}
+
+// body writes a loop or branch body followed by its closing brace.
+func (t *translator) body(s stmt, line int) error {
+	t.w.Indent++
+	if err := t.block(s); err != nil {
+		return err
+	}
+	t.w.Indent--
+	t.w.Line(line, "}")
+	return nil
+}
+
+func (t *translator) condition(e expr) (string, error) {
+	v, err := t.rvalue(e)
+	if err != nil {
+		return "", err
+	}
+	if !v.ty.equal(boolType) {
+		return "", errorAt(e.pos(), "condition must be bool, got %s", v.ty)
+	}
+	return "(" + unparen(v.text) + ")", nil
+}
+
+// unparen removes the parentheses around a whole expression.
+func unparen(s string) string {
+	if !strings.HasPrefix(s, "(") || !strings.HasSuffix(s, ")") {
+		return s
+	}
+	depth := 0
+	for i, c := range s {
+		switch c {
+		case '(':
+			depth++
+		case ')':
+			depth--
+			if depth == 0 && i != len(s)-1 {
+				return s
+			}
+		}
+	}
+	return s[1 : len(s)-1]
+}
+
+func (t *translator) ifStmt(s *ifStmt, chained bool) error {
+	cond, err := t.condition(s.cond)
+	if err != nil {
+		return err
+	}
+	if chained {
+		t.w.Indent--
+		t.w.Line(s.tok.line, "} else if %s {", cond)
+		t.w.Indent++
+	} else {
+		t.w.Line(s.tok.line, "if %s {", cond)
+		t.w.Indent++
+	}
+	if err := t.block(s.then); err != nil {
+		return err
+	}
+	switch els := s.els.(type) {
+	case nil:
+	case *ifStmt:
+		if err := t.ifStmt(els, true); err != nil {
+			return err
+		}
+		if chained {
+			return nil
+		}
+		t.w.Indent--
+		t.w.Line(s.tok.line, "}")
+		return nil
+	default:
+		t.w.Indent--
+		t.w.Line(els.pos().line, "} else {")
+		t.w.Indent++
+		if err := t.block(els); err != nil {
+			return err
+		}
+	}
+	if !chained {
+		t.w.Indent--
+		t.w.Line(s.tok.line, "}")
+	}
+	return nil
+}
+
+// forStmt translates a for loop, using WGSL's for when its initializer and
+// update are single statements and a loop with a continuing block
+// otherwise.
+func (t *translator) forStmt(s *forStmt) error {
+	t.pushScope()
+	defer t.popScope()
+
+	outer := t.w
+	init := &wgslwriter.Writer{}
+	t.w = init
+	if s.init != nil {
+		if err := t.stmt(s.init); err != nil {
+			t.w = outer
+			return err
+		}
+	}
+	post := &wgslwriter.Writer{}
+	t.w = post
+	if s.post != nil {
+		if err := t.exprStmt(s.post); err != nil {
+			t.w = outer
+			return err
+		}
+	}
+	t.w = outer
+
+	cond := ""
+	if s.cond != nil {
+		var err error
+		if cond, err = t.condition(s.cond); err != nil {
+			return err
+		}
+	}
+	if len(init.Lines()) <= 1 && len(post.Lines()) <= 1 {
+		t.w.Line(s.tok.line, "for (%s; %s; %s) {", singleLine(init), unparen(cond), singleLine(post))
+		return t.body(s.body, s.tok.line)
+	}
+	return t.forLoop(s, init, post, cond)
+}
+
+func singleLine(w *wgslwriter.Writer) string {
+	return strings.TrimSuffix(strings.TrimSpace(w.String()), ";")
+}
+
+func (t *translator) forLoop(s *forStmt, init, post *wgslwriter.Writer, cond string) error {
+	w := t.w
+	line := s.tok.line
+	w.Line(line, "{")
+	w.Indent++
+	appendIndented(w, init)
+	w.Line(line, "loop {")
+	w.Indent++
+	if cond != "" {
+		w.Line(line, "if !%s {", cond)
+		w.Indent++
+		w.Line(line, "break;")
+		w.Indent--
+		w.Line(line, "}")
+	}
+	if err := t.block(s.body); err != nil {
+		return err
+	}
+	if len(post.Lines()) > 0 {
+		w.Line(line, "continuing {")
+		w.Indent++
+		appendIndented(w, post)
+		w.Indent--
+		w.Line(line, "}")
+	}
+	w.Indent--
+	w.Line(line, "}")
+	w.Indent--
+	w.Line(line, "}")
+	return nil
+}
+
+// appendIndented copies the lines of o, written at indent 0, into w at
+// its current indent.
+func appendIndented(w, o *wgslwriter.Writer) {
+	text := strings.TrimSuffix(o.String(), "\n")
+	if text == "" {
+		return
+	}
+	for i, l := range strings.Split(text, "\n") {
+		w.Line(o.Lines()[i], "%s", l)
+	}
+}
+
+func (t *translator) switchStmt(s *switchStmt) error {
+	sel, err := t.rvalue(s.selector)
+	if err != nil {
+		return err
+	}
+	if sel.ty.kind != kindScalar || !sel.ty.isInteger() {
+		return errorAt(s.selector.pos(), "switch selector must be int or uint, got %s", sel.ty)
+	}
+
+	type clause struct {
+		labels []*caseLabel
+		body   []stmt
+	}
+	var clauses []*clause
+	for _, st := range s.body {
+		if l, ok := st.(*caseLabel); ok {
+			if n := len(clauses); n > 0 && len(clauses[n-1].body) == 0 {
+				clauses[n-1].labels = append(clauses[n-1].labels, l)
+			} else {
+				clauses = append(clauses, &clause{labels: []*caseLabel{l}})
+			}
+			continue
+		}
+		if len(clauses) == 0 {
+			return errorAt(st.pos(), "statement before the first case label")
+		}
+		clauses[len(clauses)-1].body = append(clauses[len(clauses)-1].body, st)
+	}
+
+	t.w.Line(s.tok.line, "switch %s {", sel.text)
+	t.w.Indent++
+	seen := make(map[int64]bool)
+	hasDefault := false
+	for i, c := range clauses {
+		var sels []string
+		for _, l := range c.labels {
+			if l.value == nil {
+				if hasDefault {
+					return errorAt(l.tok, "duplicate default label")
+				}
+				hasDefault = true
+				sels = append(sels, "default")
+				continue
+			}
+			n, err := t.constInt(l.value)
+			if err != nil {
+				return err
+			}
+			v := castTo(constInt(n, scalarInt), sel.ty)
+			if seen[*v.ival] {
+				return errorAt(l.tok, "duplicate case %d", n)
+			}
+			seen[*v.ival] = true
+			sels = append(sels, v.text)
+		}
+		body := c.body
+		if n := len(body); n > 0 && isJump(body[n-1], "break") {
+			body = body[:n-1]
+		} else if i < len(clauses)-1 && (n == 0 || !terminates(body[n-1])) {
+			return errorAt(c.labels[0].tok, "falling through to the next case is not supported; end the case with break")
+		}
+		head := "case " + strings.Join(sels, ", ") + ": {"
+		if len(sels) == 1 && sels[0] == "default" {
+			head = "default: {"
+		}
+		t.w.Line(c.labels[0].tok.line, "%s", head)
+		t.w.Indent++
+		t.pushScope()
+		err := t.stmts(body)
+		t.popScope()
+		if err != nil {
+			return err
+		}
+		t.w.Indent--
+		t.w.Line(c.labels[0].tok.line, "}")
+	}
+	if !hasDefault {
+		t.w.Line(s.tok.line, "default: {}")
+	}
+	t.w.Indent--
+	t.w.Line(s.tok.line, "}")
+	return nil
+}
+
+func isJump(s stmt, kind string) bool {
+	j, ok := s.(*jumpStmt)
+	return ok && j.tok.text == kind
+}
+
+// terminates reports whether s always leaves the enclosing case.
+func terminates(s stmt) bool {
+	switch s := s.(type) {
+	case *jumpStmt:
+		return true
+	case *blockStmt:
+		return len(s.stmts) > 0 && terminates(s.stmts[len(s.stmts)-1])
+	case *ifStmt:
+		return s.els != nil && terminates(s.then) && terminates(s.els)
+	}
+	return false
+}
+
+func (t *translator) jump(s *jumpStmt) error {
+	switch s.tok.text {
+	case "discard":
+		return errorAt(s.tok, "discard is not available in compute shaders")
+	case "return":
+		ret := t.fn.ret
+		if s.value == nil {
+			if ret.kind != kindVoid {
+				return errorAt(s.tok, "function %s must return a %s", t.fn.decl.name, ret)
+			}
+			t.w.Line(s.tok.line, "return;")
+			return nil
+		}
+		if ret.kind == kindVoid {
+			return errorAt(s.tok, "function %s returns void", t.fn.decl.name)
+		}
+		v, err := t.initializer(s.value, ret)
+		if err != nil {
+			return err
+		}
+		t.w.Line(s.tok.line, "return %s;", v.text)
+		return nil
+	}
+	t.w.Line(s.tok.line, "%s;", s.tok.text)
+	return nil
+}
+
+// local declares local variables and constants.
+func (t *translator) local(d *varDecl) error {
+	if len(d.quals.layout) > 0 {
+		return errorAt(d.quals.layout[0].tok, "layout qualifiers are not allowed on local variables")
+	}
+	if len(d.quals.memory) > 0 {
+		return errorAt(d.quals.memory[0], "memory qualifiers are not allowed on local variables")
+	}
+	switch d.quals.storage {
+	case "", "const":
+	case "shared":
+		return errorAt(d.quals.tok, "shared variables must be declared at global scope")
+	default:
+		return errorAt(d.quals.tok, "'%s' is not allowed on a local variable", d.quals.storage)
+	}
+	scope := t.scopes[len(t.scopes)-1]
+	for _, n := range d.names {
+		if err := t.checkName(n.tok); err != nil {
+			return err
+		}
+		if scope[n.name] != nil {
+			return errorAt(n.tok, "%s is already declared in this scope", n.name)
+		}
+		ty, err := t.resolveType(d.ty, n.dims)
+		if err != nil {
+			return err
+		}
+		if ty.kind == kindVoid {
+			return errorAt(n.tok, "variable %s has type void", n.name)
+		}
+		if ty.hasRuntimeArray() {
+			return errorAt(n.tok, "runtime-sized arrays are only allowed in buffer blocks")
+		}
+		if ty.hasAtomic() {
+			return errorAt(n.tok, "variable %s cannot hold atomics", n.name)
+		}
+		sym := &symbol{kind: symVar, name: n.name, text: wgslnames.Mangle(n.name), ty: ty}
+		var init value
+		if n.init != nil {
+			if init, err = t.initializer(n.init, ty); err != nil {
+				return err
+			}
+		}
+		line := n.tok.line
+		switch {
+		case d.quals.storage == "const":
+			if n.init == nil {
+				return errorAt(n.tok, "constant %s needs an initializer", n.name)
+			}
+			keyword := "let"
+			sym.readOnly = true
+			if init.konst {
+				keyword = "const"
+				sym.kind, sym.konst, sym.ival = symConst, true, init.ival
+			}
+			t.w.Line(line, "%s %s: %s = %s;", keyword, sym.text, ty.wgsl(), init.text)
+		case n.init != nil:
+			t.w.Line(line, "var %s: %s = %s;", sym.text, ty.wgsl(), init.text)
+		default:
+			t.w.Line(line, "var %s: %s;", sym.text, ty.wgsl())
+		}
+		// The name is in scope only after its initializer.
+		scope[n.name] = sym
+	}
+	return nil
+}
+
+// exprStmt translates an expression evaluated for its effect.
+func (t *translator) exprStmt(x expr) error {
+	line := x.pos().line
+	switch x := x.(type) {
+	case *assignExpr:
+		return t.assign(x)
+	case *unaryExpr:
+		if x.op == "++" || x.op == "--" {
+			return t.increment(x)
+		}
+	}
+	v, err := t.expr(x)
+	if err != nil {
+		return err
+	}
+	if v.ty.kind == kindVoid {
+		t.w.Line(line, "%s;", v.text)
+		return nil
+	}
+	if v, err = t.load(v, x.pos()); err != nil {
+		return err
+	}
+	t.w.Line(line, "_ = %s;", v.text)
+	return nil
+}
+
+// target translates the left side of an assignment.
+func (t *translator) target(e expr) (value, error) {
+	v, err := t.expr(e)
+	if err != nil {
+		return value{}, err
+	}
+	if v.swizzle != nil {
+		base := v.swizzle.base
+		if !base.mutable {
+			return value{}, errorAt(e.pos(), "cannot assign to %s", describeTarget(base))
+		}
+		if !base.pure {
+			return value{}, errorAt(e.pos(), "assigning to a swizzle of an expression that calls functions is not supported")
+		}
+		for i := range v.swizzle.components {
+			if strings.IndexByte(v.swizzle.components[i+1:], v.swizzle.components[i]) >= 0 {
+				return value{}, errorAt(e.pos(), "swizzle assigned to repeats a component")
+			}
+		}
+	} else if !v.ref || !v.mutable {
+		return value{}, errorAt(e.pos(), "cannot assign to %s", describeTarget(v))
+	}
+	if v.ty.kind == kindStruct && v.ty.hasAtomic() {
+		return value{}, errorAt(e.pos(), "a %s containing atomics cannot be assigned as a whole", v.ty)
+	}
+	if v.root != nil && v.root.kind == symParam {
+		v.root.assigned = true
+	}
+	return v, nil
+}
+
+func describeTarget(v value) string {
+	switch {
+	case v.root == nil:
+		return "this expression"
+	case v.root.konst || v.root.kind == symConst:
+		return "constant " + v.root.name
+	}
+	return "read-only " + v.root.name
+}
+
+func (t *translator) assign(e *assignExpr) error {
+	lhs, err := t.target(e.l)
+	if err != nil {
+		return err
+	}
+	r, err := t.rvalue(e.r)
+	if err != nil {
+		return err
+	}
+	ty := lhs.ty.nonAtomic()
+	if e.op == "=" {
+		if r, err = t.convert(r, ty, e.r.pos()); err != nil {
+			return err
+		}
+		return t.store(lhs, r, e.tok)
+	}
+
+	op := strings.TrimSuffix(e.op, "=")
+	cur, err := t.load(lhs, e.tok)
+	if err != nil {
+		return err
+	}
+	if lhs.swizzle == nil && !lhs.ty.atomic && op != "<<" && op != ">>" && r.ty.equal(ty) {
+		t.w.Line(e.tok.line, "%s %s %s;", lhs.text, e.op, r.text)
+		return nil
+	}
+	if !lhs.pure {
+		return errorAt(e.tok, "%s on an expression that calls functions is not supported; use a temporary", e.op)
+	}
+	res, err := t.binaryValues(&binaryExpr{tok: e.tok, op: op, l: e.l, r: e.r}, cur, r)
+	if err != nil {
+		return err
+	}
+	if res, err = t.convert(res, ty, e.tok); err != nil {
+		return err
+	}
+	return t.store(lhs, res, e.tok)
+}
+
+// store writes r, already converted, to a target.
+func (t *translator) store(lhs, r value, at token) error {
+	line := at.line
+	switch {
+	case lhs.ty.atomic:
+		t.w.Line(line, "atomicStore(&%s, %s);", lhs.text, r.text)
+	case lhs.swizzle != nil:
+		// WGSL cannot assign to a multi-component swizzle, so each
+		// component is assigned from a temporary.
+		tmp := t.temp("swizzle")
+		t.w.Line(line, "{")
+		t.w.Indent++
+		t.w.Line(line, "let %s = %s;", tmp, r.text)
+		for i, c := range lhs.swizzle.components {
+			t.w.Line(line, "%s.%c = %s.%c;", lhs.swizzle.base.text, c, tmp, "xyzw"[i])
+		}
+		t.w.Indent--
+		t.w.Line(line, "}")
+	default:
+		t.w.Line(line, "%s = %s;", lhs.text, r.text)
+	}
+	return nil
+}
+
+// increment translates ++ and -- used as statements.
+func (t *translator) increment(e *unaryExpr) error {
+	lhs, err := t.target(e.x)
+	if err != nil {
+		return err
+	}
+	ty := lhs.ty.nonAtomic()
+	if !ty.isNumeric() {
+		return errorAt(e.tok, "%s needs a numeric operand, got %s", e.op, ty)
+	}
+	line := e.tok.line
+	if ty.kind == kindScalar && ty.scalar != scalarFloat && lhs.swizzle == nil && !lhs.ty.atomic {
+		t.w.Line(line, "%s%s;", lhs.text, e.op)
+		return nil
+	}
+	op := e.op[:1]
+	one := literalOf(1, ty.scalar)
+	if lhs.ty.atomic {
+		name := "atomicAdd"
+		if op == "-" {
+			name = "atomicSub"
+		}
+		t.w.Line(line, "%s(&%s, %s);", name, lhs.text, one)
+		return nil
+	}
+	if lhs.swizzle == nil {
+		t.w.Line(line, "%s %s= %s;", lhs.text, op, one)
+		return nil
+	}
+	cur, err := t.load(lhs, e.tok)
+	if err != nil {
+		return err
+	}
+	return t.store(lhs, derived("("+cur.text+" "+op+" "+one+")", ty, cur), e.tok)
+}
@@ -0,0 +1,757 @@
+// Copyright 2025 The GoGPU Authors
+// SPDX-License-Identifier: MIT
+
+// Package frontend translates GLSL compute shaders into WGSL, which the
+// WGSL frontend then lowers to IR.
+//
+// Only the subset of GLSL 4.30+ that compute kernels use is accepted:
+// buffer and uniform blocks, shared memory, structs, constants, helper
+// functions with in parameters, the usual control flow, barriers, atomics,
+// and the built-in functions with a WGSL counterpart. GLSL's implicit
+// conversions, mutable parameters, switch, do-while, and atomics on plain
+// integers are rewritten into their WGSL forms. Anything else is rejected
+// with an error located in the GLSL source.
+package frontend
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/gogpu/naga/internal/wgslnames"
+	"github.com/gogpu/naga/internal/wgslwriter"
+)
+
+// Result is a translated shader.
+type Result struct {
+	// WGSL is the translated source.
+	WGSL string
+
+	// Lines maps each WGSL line, indexed from 0, to the GLSL line it was
+	// translated from.
+	Lines []int
+}
+
+// Translate translates a GLSL compute shader to WGSL.
+func Translate(source string) (*Result, error) {
+	toks, err := lex(source)
+	if err != nil {
+		return nil, err
+	}
+	unit, err := parse(toks)
+	if err != nil {
+		return nil, err
+	}
+	t := newTranslator()
+	out, err := t.translate(unit)
+	if err != nil {
+		return nil, err
+	}
+	return &Result{WGSL: out.String(), Lines: out.Lines()}, nil
+}
+
+// symbolKind classifies what a name refers to.
+type symbolKind uint8
+
+const (
+	symVar symbolKind = iota
+	symConst
+	symParam
+)
+
+// symbol is a named variable, constant, parameter, or block member.
+type symbol struct {
+	kind symbolKind
+	name string // GLSL name
+	text string // WGSL expression naming it
+	ty   *glType
+
+	// space is the WGSL address space of a global: "private",
+	// "workgroup", "storage", "uniform", or "push_constant".
+	space    string
+	readOnly bool
+
+	// konst marks a WGSL const, usable in constant expressions; ival is
+	// its value when it is an integer constant.
+	konst bool
+	ival  *int64
+
+	// assigned records that a parameter is written to, so the function
+	// copies it into a local variable.
+	assigned bool
+}
+
+// funcInfo is a user function signature.
+type funcInfo struct {
+	decl   *funcDecl
+	name   string // WGSL name
+	ret    *glType
+	params []*glType
+
+	// calls are the user functions the body calls, for finding recursion.
+	calls []funcCall
+}
+
+type funcCall struct {
+	tok    token
+	callee *funcInfo
+}
+
+// builtinVar is a gl_ compute input.
+type builtinVar struct {
+	wgsl string
+	ty   *glType
+}
+
+var builtinVars = map[string]builtinVar{
+	"gl_GlobalInvocationID":   {"global_invocation_id", vectorType(scalarUint, 3)},
+	"gl_LocalInvocationID":    {"local_invocation_id", vectorType(scalarUint, 3)},
+	"gl_WorkGroupID":          {"workgroup_id", vectorType(scalarUint, 3)},
+	"gl_NumWorkGroups":        {"num_workgroups", vectorType(scalarUint, 3)},
+	"gl_LocalInvocationIndex": {"local_invocation_index", uintType},
+}
+
+type translator struct {
+	workgroup    [3]int64
+	hasWorkgroup bool
+
+	structs map[string]*structInfo
+	globals map[string]*symbol
+	funcs   map[string]*funcInfo
+	scopes  []map[string]*symbol
+
+	// fn is the function being translated; nil at module scope.
+	fn *funcInfo
+
+	// builtins records the gl_ inputs used, and whether a function other
+	// than main uses them.
+	builtins         map[string]bool
+	builtinsInHelper bool
+
+	decls *wgslwriter.Writer
+
+	// w receives the statements of the function being translated; temps
+	// numbers the temporaries it declares.
+	w     *wgslwriter.Writer
+	temps int
+
+	// marking is set during the first pass over function bodies, which
+	// marks the memory atomic functions use instead of requiring it to be
+	// atomic already.
+	marking bool
+}
+
+func newTranslator() *translator {
+	return &translator{
+		structs:  make(map[string]*structInfo),
+		globals:  make(map[string]*symbol),
+		funcs:    make(map[string]*funcInfo),
+		builtins: make(map[string]bool),
+		decls:    &wgslwriter.Writer{},
+	}
+}
+
+// translate runs the passes: declare every struct, global, and function
+// signature; mark the variables atomic functions use; then write
+// declarations and function bodies.
+func (t *translator) translate(unit *translationUnit) (*wgslwriter.Writer, error) {
+	// Declarations are resolved in source order, so a struct or constant
+	// is known before its uses; WGSL declarations are written once atomic
+	// uses have been found.
+	var hasLayoutIn bool
+	var funcs []*funcDecl
+	var pending []func() error
+	for _, d := range unit.decls {
+		switch d := d.(type) {
+		case *layoutInDecl:
+			if err := t.layoutIn(d); err != nil {
+				return nil, err
+			}
+			hasLayoutIn = true
+		case *funcDecl:
+			if err := t.declareFunc(d); err != nil {
+				return nil, err
+			}
+			if d.body != nil {
+				funcs = append(funcs, d)
+			}
+		case *structDecl:
+			st, err := t.declareStruct(d)
+			if err != nil {
+				return nil, err
+			}
+			pending = append(pending, func() error { t.writeStruct(d.tok.line, st); return nil })
+		case *blockDecl:
+			emit, err := t.declareBlock(d)
+			if err != nil {
+				return nil, err
+			}
+			pending = append(pending, emit)
+		case *varDecl:
+			emits, err := t.declareGlobal(d)
+			if err != nil {
+				return nil, err
+			}
+			pending = append(pending, emits...)
+		}
+	}
+	if !hasLayoutIn {
+		return nil, &Error{Line: 1, Column: 1, Message: "missing layout(local_size_x = ...) in; declaration"}
+	}
+	main, ok := t.funcs["main"]
+	if !ok || main.decl.body == nil {
+		return nil, &Error{Line: 1, Column: 1, Message: "missing void main() function"}
+	}
+	if main.ret.kind != kindVoid || len(main.params) > 0 {
+		return nil, errorAt(main.decl.tok, "main must be declared void main()")
+	}
+
+	if err := t.markAtomics(funcs); err != nil {
+		return nil, err
+	}
+	for _, emit := range pending {
+		if err := emit(); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, fn := range funcs {
+		t.funcs[fn.name].calls = nil
+	}
+	bodies := make([]*wgslwriter.Writer, len(funcs))
+	for i, fn := range funcs {
+		w, err := t.function(fn)
+		if err != nil {
+			return nil, err
+		}
+		bodies[i] = w
+	}
+
+	if err := t.checkRecursion(funcs); err != nil {
+		return nil, err
+	}
+
+	// Helpers read the gl_ inputs from private variables main fills in.
+	out := &wgslwriter.Writer{}
+	if t.builtinsInHelper {
+		for _, name := range t.usedBuiltins() {
+			out.Line(1, "var<private> %s: %s;", name, builtinVars[name].ty.wgsl())
+		}
+		out.Blank(1)
+	}
+	out.Append(t.decls)
+	for _, w := range bodies {
+		if n := len(out.Lines()); n > 0 && !strings.HasSuffix(out.String(), "\n\n") {
+			out.Blank(out.Lines()[n-1])
+		}
+		out.Append(w)
+	}
+	return out, nil
+}
+
+// checkRecursion rejects recursive calls, which GLSL and WGSL forbid.
+func (t *translator) checkRecursion(funcs []*funcDecl) error {
+	const (
+		unvisited = iota
+		visiting
+		done
+	)
+	state := make(map[*funcInfo]int)
+	var visit func(fi *funcInfo) error
+	visit = func(fi *funcInfo) error {
+		state[fi] = visiting
+		for _, c := range fi.calls {
+			switch state[c.callee] {
+			case visiting:
+				return errorAt(c.tok, "recursive call to %s", c.callee.decl.name)
+			case unvisited:
+				if err := visit(c.callee); err != nil {
+					return err
+				}
+			}
+		}
+		state[fi] = done
+		return nil
+	}
+	for _, fn := range funcs {
+		if fi := t.funcs[fn.name]; state[fi] == unvisited {
+			if err := visit(fi); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (t *translator) usedBuiltins() []string {
+	names := make([]string, 0, len(t.builtins))
+	for name := range t.builtins {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// layoutIn records the workgroup size.
+func (t *translator) layoutIn(d *layoutInDecl) error {
+	if t.hasWorkgroup {
+		return errorAt(d.quals.tok, "workgroup size declared twice")
+	}
+	t.workgroup = [3]int64{1, 1, 1}
+	for _, lq := range d.quals.layout {
+		axis := -1
+		switch lq.name {
+		case "local_size_x":
+			axis = 0
+		case "local_size_y":
+			axis = 1
+		case "local_size_z":
+			axis = 2
+		case "local_size_x_id", "local_size_y_id", "local_size_z_id":
+			return errorAt(lq.tok, "specialization constant workgroup sizes are not supported")
+		default:
+			return errorAt(lq.tok, "layout qualifier %s is not supported on inputs", lq.name)
+		}
+		if lq.value == nil {
+			return errorAt(lq.tok, "%s needs a value", lq.name)
+		}
+		v, err := t.constInt(lq.value)
+		if err != nil {
+			return err
+		}
+		if v < 1 {
+			return errorAt(lq.tok, "%s must be at least 1, got %d", lq.name, v)
+		}
+		t.workgroup[axis] = v
+	}
+	t.hasWorkgroup = true
+	return nil
+}
+
+// constInt evaluates an integer constant expression at module scope.
+func (t *translator) constInt(e expr) (int64, error) {
+	v, err := t.expr(e)
+	if err != nil {
+		return 0, err
+	}
+	if v.ival == nil || !v.ty.isInteger() || v.ty.kind != kindScalar {
+		return 0, errorAt(e.pos(), "expected a constant integer expression")
+	}
+	return *v.ival, nil
+}
+
+// resolveType resolves a written type plus declarator dimensions. Every
+// call returns fresh scalar types, so marking one atomic affects only the
+// declaration it belongs to.
+func (t *translator) resolveType(ts typeSpec, dims []expr) (*glType, error) {
+	var base *glType
+	if st, ok := t.structs[ts.name]; ok {
+		base = &glType{kind: kindStruct, st: st}
+	} else {
+		ty, ok, err := builtinType(ts.name)
+		if err != nil {
+			return nil, errorAt(ts.tok, "%v", err)
+		}
+		if !ok {
+			return nil, errorAt(ts.tok, "unknown type %s", ts.name)
+		}
+		c := *ty
+		base = &c
+	}
+	// int a[2][3] is an array of two arrays of three ints; the dimensions
+	// after the name come first, then those after the type.
+	all := append(append([]expr(nil), dims...), ts.dims...)
+	for i := len(all) - 1; i >= 0; i-- {
+		if base.kind == kindVoid {
+			return nil, errorAt(ts.tok, "array of void")
+		}
+		arr := &glType{kind: kindArray, elem: base}
+		if all[i] != nil {
+			n, err := t.constInt(all[i])
+			if err != nil {
+				return nil, err
+			}
+			if n < 1 {
+				return nil, errorAt(all[i].pos(), "array size must be positive, got %d", n)
+			}
+			arr.length = int(n)
+		}
+		base = arr
+	}
+	return base, nil
+}
+
+func (t *translator) declareStruct(d *structDecl) (*structInfo, error) {
+	if err := t.checkName(d.tok); err != nil {
+		return nil, err
+	}
+	st := &structInfo{name: d.name, wgsl: wgslnames.Mangle(d.name)}
+	members, err := t.members(d.members, false)
+	if err != nil {
+		return nil, err
+	}
+	for _, m := range members {
+		if m.ty.hasRuntimeArray() {
+			return nil, errorAt(d.tok, "struct %s: runtime-sized arrays are only allowed as the last member of a buffer block", d.name)
+		}
+	}
+	st.members = members
+	t.structs[d.name] = st
+	return st, nil
+}
+
+// members resolves struct or block members.
+func (t *translator) members(decls []varDecl, block bool) ([]structMember, error) {
+	var members []structMember
+	seen := make(map[string]bool)
+	for _, md := range decls {
+		for _, lq := range md.quals.layout {
+			switch lq.name {
+			case "std430", "std140", "row_major", "column_major":
+				if lq.name == "row_major" {
+					return nil, errorAt(lq.tok, "row_major matrices are not supported")
+				}
+			default:
+				return nil, errorAt(lq.tok, "member layout qualifier %s is not supported", lq.name)
+			}
+		}
+		if md.quals.storage != "" {
+			return nil, errorAt(md.quals.tok, "storage qualifier %s is not allowed on a member", md.quals.storage)
+		}
+		if len(md.quals.memory) > 0 && !block {
+			return nil, errorAt(md.quals.memory[0], "memory qualifiers are only allowed in buffer blocks")
+		}
+		for _, n := range md.names {
+			if n.init != nil {
+				return nil, errorAt(n.tok, "member %s cannot have an initializer", n.name)
+			}
+			if seen[n.name] {
+				return nil, errorAt(n.tok, "duplicate member %s", n.name)
+			}
+			seen[n.name] = true
+			ty, err := t.resolveType(md.ty, n.dims)
+			if err != nil {
+				return nil, err
+			}
+			if ty.kind == kindVoid {
+				return nil, errorAt(n.tok, "member %s has type void", n.name)
+			}
+			if ty.scalar == scalarBool && ty.kind != kindStruct && ty.kind != kindArray && block {
+				return nil, errorAt(n.tok, "bool member %s cannot be shared with the host; use uint", n.name)
+			}
+			members = append(members, structMember{name: n.name, wgsl: wgslnames.Mangle(n.name), ty: ty})
+		}
+	}
+	for i, m := range members {
+		if m.ty.hasRuntimeArray() && (i != len(members)-1 || !block) {
+			return nil, errorAt(decls[len(decls)-1].ty.tok, "only the last member of a buffer block may be a runtime-sized array")
+		}
+	}
+	return members, nil
+}
+
+func (t *translator) writeStruct(line int, st *structInfo) {
+	t.decls.Line(line, "struct %s {", st.wgsl)
+	t.decls.Indent++
+	for _, m := range st.members {
+		t.decls.Line(line, "%s: %s,", m.wgsl, m.ty.wgsl())
+	}
+	t.decls.Indent--
+	t.decls.Line(line, "}")
+	t.decls.Blank(line)
+}
+
+// declareBlock declares a buffer or uniform block and returns the function
+// that writes its WGSL declaration.
+func (t *translator) declareBlock(d *blockDecl) (func() error, error) {
+	space := "storage"
+	if d.quals.storage == "uniform" {
+		space = "uniform"
+	}
+	group, binding := int64(0), int64(-1)
+	for _, lq := range d.quals.layout {
+		switch lq.name {
+		case "std430":
+		case "std140":
+			if space == "storage" {
+				return nil, errorAt(lq.tok, "std140 buffer blocks are not supported; use std430")
+			}
+		case "push_constant":
+			space = "push_constant"
+		case "set", "binding":
+			if lq.value == nil {
+				return nil, errorAt(lq.tok, "%s needs a value", lq.name)
+			}
+			v, err := t.constInt(lq.value)
+			if err != nil {
+				return nil, err
+			}
+			if lq.name == "set" {
+				group = v
+			} else {
+				binding = v
+			}
+		default:
+			return nil, errorAt(lq.tok, "block layout qualifier %s is not supported", lq.name)
+		}
+	}
+	if binding < 0 && space != "push_constant" {
+		return nil, errorAt(d.tok, "block %s needs layout(binding = N)", d.name)
+	}
+	readOnly := space != "storage"
+	for _, m := range d.quals.memory {
+		switch m.text {
+		case "readonly":
+			readOnly = true
+		case "writeonly", "coherent", "restrict", "volatile":
+			// WGSL storage buffers are always readable, and coherent
+			// and volatile have no WGSL equivalent beyond barriers.
+		}
+	}
+	if err := t.checkName(d.tok); err != nil {
+		return nil, err
+	}
+	if _, dup := t.structs[d.name]; dup {
+		return nil, errorAt(d.tok, "%s is already declared", d.name)
+	}
+	members, err := t.members(d.members, true)
+	if err != nil {
+		return nil, err
+	}
+	st := &structInfo{name: d.name, wgsl: wgslnames.Mangle(d.name), members: members}
+	t.structs[d.name] = st
+	ty := &glType{kind: kindStruct, st: st}
+
+	varName := "_" + d.name
+	if d.instance != nil {
+		if len(d.instance.dims) > 0 {
+			return nil, errorAt(d.instance.tok, "arrays of blocks are not supported")
+		}
+		if err := t.declareGlobalName(d.instance.tok); err != nil {
+			return nil, err
+		}
+		varName = wgslnames.Mangle(d.instance.name)
+		t.globals[d.instance.name] = &symbol{kind: symVar, name: d.instance.name, text: varName, ty: ty, space: space, readOnly: readOnly}
+	} else {
+		for _, m := range members {
+			_, dupVar := t.globals[m.name]
+			_, dupFunc := t.funcs[m.name]
+			if dupVar || dupFunc {
+				return nil, errorAt(d.tok, "block member %s is already declared", m.name)
+			}
+			t.globals[m.name] = &symbol{kind: symVar, name: m.name, text: varName + "." + m.wgsl, ty: m.ty, space: space, readOnly: readOnly}
+		}
+	}
+
+	line := d.tok.line
+	return func() error {
+		t.writeStruct(line, st)
+		switch space {
+		case "storage":
+			access := "read_write"
+			if readOnly {
+				access = "read"
+				if st.hasAtomic() {
+					return errorAt(d.tok, "atomic functions need a writable buffer; remove readonly from %s", d.name)
+				}
+			}
+			t.decls.Line(line, "@group(%d) @binding(%d) var<storage, %s> %s: %s;", group, binding, access, varName, st.wgsl)
+		case "uniform":
+			t.decls.Line(line, "@group(%d) @binding(%d) var<uniform> %s: %s;", group, binding, varName, st.wgsl)
+		default:
+			t.decls.Line(line, "var<push_constant> %s: %s;", varName, st.wgsl)
+		}
+		t.decls.Blank(line)
+		return nil
+	}, nil
+}
+
+// hasAtomic reports whether any member of s is, or contains, an atomic.
+func (s *structInfo) hasAtomic() bool {
+	for _, m := range s.members {
+		if m.ty.hasAtomic() {
+			return true
+		}
+	}
+	return false
+}
+
+func (t *glType) hasAtomic() bool {
+	switch t.kind {
+	case kindScalar:
+		return t.atomic
+	case kindArray:
+		return t.elem.hasAtomic()
+	case kindStruct:
+		return t.st.hasAtomic()
+	}
+	return false
+}
+
+// checkName rejects names GLSL reserves.
+func (t *translator) checkName(tok token) error {
+	if strings.HasPrefix(tok.text, "gl_") || strings.Contains(tok.text, "__") {
+		return errorAt(tok, "identifier %s is reserved", tok.text)
+	}
+	return nil
+}
+
+func (t *translator) declareGlobalName(tok token) error {
+	if err := t.checkName(tok); err != nil {
+		return err
+	}
+	if _, dup := t.globals[tok.text]; dup {
+		return errorAt(tok, "%s is already declared", tok.text)
+	}
+	if _, dup := t.funcs[tok.text]; dup {
+		return errorAt(tok, "%s is already declared as a function", tok.text)
+	}
+	return nil
+}
+
+// declareGlobal declares module-scope variables and constants and returns
+// the functions that write them.
+func (t *translator) declareGlobal(d *varDecl) ([]func() error, error) {
+	var emits []func() error
+	if len(d.quals.layout) > 0 {
+		return nil, errorAt(d.quals.layout[0].tok, "layout qualifiers on variables are not supported; images and samplers are not available in this frontend")
+	}
+	switch d.quals.storage {
+	case "", "const", "shared":
+	case "uniform":
+		return nil, errorAt(d.quals.tok, "uniform variables outside a block are not supported; declare a uniform block")
+	default:
+		return nil, errorAt(d.quals.tok, "'%s' variables are not supported in compute shaders", d.quals.storage)
+	}
+	for _, n := range d.names {
+		n := n
+		if err := t.declareGlobalName(n.tok); err != nil {
+			return nil, err
+		}
+		ty, err := t.resolveType(d.ty, n.dims)
+		if err != nil {
+			return nil, err
+		}
+		if ty.kind == kindVoid {
+			return nil, errorAt(n.tok, "variable %s has type void", n.name)
+		}
+		if ty.hasRuntimeArray() {
+			return nil, errorAt(n.tok, "runtime-sized arrays are only allowed in buffer blocks")
+		}
+		sym := &symbol{kind: symVar, name: n.name, text: wgslnames.Mangle(n.name), ty: ty, space: "private"}
+		var init *value
+		switch d.quals.storage {
+		case "const":
+			if n.init == nil {
+				return nil, errorAt(n.tok, "constant %s needs an initializer", n.name)
+			}
+			v, err := t.initializer(n.init, ty)
+			if err != nil {
+				return nil, err
+			}
+			if !v.konst {
+				return nil, errorAt(n.init.pos(), "initializer of constant %s is not a constant expression", n.name)
+			}
+			sym.kind, sym.konst, sym.ival = symConst, true, v.ival
+			init = &v
+		case "shared":
+			if n.init != nil {
+				return nil, errorAt(n.tok, "shared variable %s cannot have an initializer", n.name)
+			}
+			sym.space = "workgroup"
+		default:
+			if n.init != nil {
+				v, err := t.initializer(n.init, ty)
+				if err != nil {
+					return nil, err
+				}
+				if !v.konst {
+					return nil, errorAt(n.init.pos(), "global initializer must be a constant expression")
+				}
+				init = &v
+			}
+		}
+		t.globals[n.name] = sym
+		line := n.tok.line
+		emits = append(emits, func() error {
+			switch {
+			case sym.kind == symConst:
+				t.decls.Line(line, "const %s: %s = %s;", sym.text, ty.wgsl(), init.text)
+			case sym.space == "workgroup":
+				t.decls.Line(line, "var<workgroup> %s: %s;", sym.text, ty.wgsl())
+			default:
+				if ty.hasAtomic() {
+					return errorAt(n.tok, "atomic functions need a shared variable or a buffer block member; %s is neither", n.name)
+				}
+				if init != nil {
+					t.decls.Line(line, "var<private> %s: %s = %s;", sym.text, ty.wgsl(), init.text)
+				} else {
+					t.decls.Line(line, "var<private> %s: %s;", sym.text, ty.wgsl())
+				}
+			}
+			return nil
+		})
+	}
+	return emits, nil
+}
+
+// initializer translates an initializer and converts it to ty.
+func (t *translator) initializer(e expr, ty *glType) (value, error) {
+	v, err := t.rvalue(e)
+	if err != nil {
+		return value{}, err
+	}
+	return t.convert(v, ty, e.pos())
+}
+
+func (t *translator) declareFunc(d *funcDecl) error {
+	ret, err := t.resolveType(d.ret, nil)
+	if err != nil {
+		return err
+	}
+	if ret.kind == kindArray {
+		return errorAt(d.ret.tok, "functions returning arrays are not supported")
+	}
+	var params []*glType
+	for _, p := range d.params {
+		switch p.qual {
+		case "", "in", "const":
+		default:
+			return errorAt(p.tok, "%s parameters are not supported; return a value instead", p.qual)
+		}
+		if p.ty.name == "void" && len(p.ty.dims) == 0 {
+			return errorAt(p.tok, "parameter of type void")
+		}
+		ty, err := t.resolveType(p.ty, nil)
+		if err != nil {
+			return err
+		}
+		if ty.hasRuntimeArray() {
+			return errorAt(p.tok, "runtime-sized array parameters are not supported")
+		}
+		params = append(params, ty)
+	}
+	if prev, ok := t.funcs[d.name]; ok {
+		same := prev.ret.equal(ret) && len(prev.params) == len(params)
+		for i := 0; same && i < len(params); i++ {
+			same = prev.params[i].equal(params[i])
+		}
+		if !same {
+			return errorAt(d.tok, "function %s is overloaded; overloading is not supported", d.name)
+		}
+		if prev.decl.body != nil && d.body != nil {
+			return errorAt(d.tok, "function %s is defined twice", d.name)
+		}
+		if d.body != nil {
+			prev.decl = d
+		}
+		return nil
+	}
+	if err := t.checkName(d.tok); err != nil {
+		return err
+	}
+	if _, ok := builtinFuncs[d.name]; ok {
+		return errorAt(d.tok, "%s is a built-in function and cannot be redeclared", d.name)
+	}
+	t.funcs[d.name] = &funcInfo{decl: d, name: wgslnames.Mangle(d.name), ret: ret, params: params}
+	return nil
+}
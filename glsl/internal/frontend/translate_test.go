@@ -0,0 +1,288 @@
+// Copyright 2025 The GoGPU Authors
+// SPDX-License-Identifier: MIT
+
+package frontend
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/gogpu/naga/ir"
+	"github.com/gogpu/naga/spirv"
+	"github.com/gogpu/naga/wgsl"
+)
+
+// compile translates source, lowers the WGSL, validates the IR, and
+// compiles it to SPIR-V.
+func compile(t *testing.T, source string) string {
+	t.Helper()
+	res, err := Translate(source)
+	if err != nil {
+		t.Fatalf("Translate: %v", err)
+	}
+	tokens, err := wgsl.NewLexer(res.WGSL).Tokenize()
+	if err != nil {
+		t.Fatalf("tokenize: %v\n%s", err, res.WGSL)
+	}
+	ast, err := wgsl.NewParser(tokens).Parse()
+	if err != nil {
+		t.Fatalf("parse: %v\n%s", err, res.WGSL)
+	}
+	module, err := wgsl.LowerWithSource(ast, res.WGSL)
+	if err != nil {
+		t.Fatalf("lower: %v\n%s", err, res.WGSL)
+	}
+	if _, err := ir.Validate(module); err != nil {
+		t.Fatalf("validate: %v\n%s", err, res.WGSL)
+	}
+	if _, err := spirv.NewBackend(spirv.DefaultOptions()).Compile(module); err != nil {
+		t.Fatalf("spirv: %v\n%s", err, res.WGSL)
+	}
+	if n := strings.Count(res.WGSL, "\n"); n != len(res.Lines) {
+		t.Errorf("line map has %d entries for %d lines", len(res.Lines), n)
+	}
+	return res.WGSL
+}
+
+const particleShader = `#version 450
+#define GROUP 64
+layout(local_size_x = GROUP) in;
+
+struct Particle { vec4 pos; vec4 vel; };
+
+layout(std430, set = 0, binding = 0) buffer Particles { Particle particles[]; };
+layout(std430, binding = 1) buffer Stats { uint count; uint hist[16]; } stats;
+layout(std140, binding = 2) uniform Params { float dt; int n; } params;
+
+shared float tile[GROUP];
+const float TWO_PI = 6.2831853;
+
+float energy(vec4 v) {
+    return 0.5 * dot(v.xyz, v.xyz);
+}
+
+int bucketOf(float x, int n) {
+    int i = int(x * 4.0);
+    if (i < 0) i = 0;
+    else if (i >= n) { i = n - 1; }
+    return i;
+}
+
+void main() {
+    uint gid = gl_GlobalInvocationID.x;
+    uint lid = gl_LocalInvocationID.x;
+    if (gid >= uint(params.n)) return;
+    Particle p = particles[gid];
+    p.pos.xyz += p.vel.xyz * params.dt;
+    p.vel.w = mod(p.vel.w + 1.0, TWO_PI);
+    tile[lid] = energy(p.vel);
+    memoryBarrierShared();
+    barrier();
+    for (int s = GROUP / 2; s > 0; s >>= 1) {
+        if (lid < uint(s)) tile[lid] += tile[lid + uint(s)];
+        barrier();
+    }
+    if (lid == 0u) atomicAdd(stats.count, 1);
+    atomicAdd(stats.hist[bucketOf(p.pos.x, 16)], 1u);
+    particles[gid] = p;
+}
+`
+
+func TestTranslateParticles(t *testing.T) {
+	out := compile(t, particleShader)
+	for _, want := range []string{
+		"@compute @workgroup_size(64, 1, 1)",
+		"@group(0) @binding(1) var<storage, read_write> stats: Stats;",
+		"count: atomic<u32>,",
+		"hist: array<atomic<u32>, 16>,",
+		"var<workgroup> tile: array<f32, 64>;",
+		"fn bucketOf(x: f32, n: i32) -> i32 {",
+		"let swizzle1 = (p.pos.xyz + (p.vel.xyz * params.dt));",
+		"for (var s: i32 = 32i; s > 0i; s = (s >> 1u)) {",
+		"_ = atomicAdd(&stats.count, 1u);",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output lacks %q:\n%s", want, out)
+		}
+	}
+	// memoryBarrierShared followed by barrier is a single barrier.
+	if n := strings.Count(out, "workgroupBarrier()"); n != 2 {
+		t.Errorf("got %d workgroup barriers, want 2:\n%s", n, out)
+	}
+}
+
+func TestTranslateControlFlow(t *testing.T) {
+	out := compile(t, `#version 430 core
+layout(local_size_x = 8, local_size_y = 8) in;
+layout(std430, binding = 0) readonly buffer In { uvec4 data[]; };
+layout(std430, binding = 1) writeonly buffer Out { int result[]; };
+
+uint index() { return gl_GlobalInvocationID.y * 8u + gl_GlobalInvocationID.x; }
+
+int clampAdd(int a, int b) {
+    a += b;
+    return clamp(a, 0, 100);
+}
+
+void main() {
+    uint i = index();
+    uvec4 d = data[i];
+    int acc = 0;
+    do { acc++; } while (acc < int(d.x));
+    switch (acc) {
+    case 0:
+    case 1:
+        acc = 10;
+        break;
+    case 2:
+        return;
+    default:
+        acc = clampAdd(acc, -1);
+    }
+    for (int j = 0, k = 2; j < 4; j++) acc += j * k;
+    result[i] = acc + int(all(equal(d.xy, d.zw))) + (d.x > 3u ? 1 : 0);
+}
+`)
+	for _, want := range []string{
+		"var<private> gl_GlobalInvocationID: vec3<u32>;",
+		"fn main(@builtin(global_invocation_id) gl_GlobalInvocationID_in: vec3<u32>) {",
+		"gl_GlobalInvocationID = gl_GlobalInvocationID_in;",
+		"fn clampAdd(a_in: i32, b: i32) -> i32 {",
+		"var a = a_in;",
+		"break if !(acc < i32(d.x));",
+		"case 0i, 1i: {",
+		"default: {",
+		"continuing {",
+		"select(0i, 1i, (d.x > 3u))",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output lacks %q:\n%s", want, out)
+		}
+	}
+}
+
+func TestTranslateExpressions(t *testing.T) {
+	out := compile(t, `#version 450
+layout(local_size_x = 1) in;
+layout(binding = 0) buffer B { uint u[]; } b;
+layout(binding = 1) buffer F { float f[]; };
+const uint N = gl_WorkGroupSize.x * 4u;
+shared uint scratch[N];
+
+void main() {
+    uint x = b.u[0];
+    uint neg = -x;
+    ivec2 v = ivec2(1, 2) << 1;
+    v = v | 3;
+    vec3 w = vec3(x);
+    mat2 m = mat2(2.0);
+    float a = inversesqrt(2.0) + atan(w.y, w.x) + float(bitCount(x)) + float(findLSB(x));
+    uint bits = floatBitsToUint(a) ^ packUnorm4x8(vec4(w.stp, 1.0));
+    v.yx = v;
+    scratch[0] = bits + uint(b.u.length());
+    f[0] = m[1].y + float(v.x) + float(neg);
+}
+`)
+	for _, want := range []string{
+		"var<workgroup> scratch: array<u32, 4>;",
+		"(0u - x)",
+		"(vec2<i32>(1i, 2i) << vec2<u32>(1u))",
+		"(v | vec2<i32>(3i))",
+		"mat2x2<f32>(vec2<f32>(2.0f, 0.0f), vec2<f32>(0.0f, 2.0f))",
+		"inverseSqrt(2.0f)",
+		"atan2(w.y, w.x)",
+		"i32(firstTrailingBit(x))",
+		"bitcast<u32>(a)",
+		"pack4x8unorm(vec4<f32>(w.xyz, 1.0f))",
+		"u32(i32(arrayLength(&b.u)))",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output lacks %q:\n%s", want, out)
+		}
+	}
+}
+
+func TestTranslateAtomics(t *testing.T) {
+	out := compile(t, `#version 450
+layout(local_size_x = 32) in;
+layout(binding = 0) buffer Counters { int total; uint slots[]; };
+shared uint hits;
+
+void main() {
+    if (gl_LocalInvocationIndex == 0u) hits = 0u;
+    barrier();
+    atomicAdd(hits, 1u);
+    barrier();
+    uint prev = atomicCompSwap(slots[gl_LocalInvocationIndex], 0u, hits);
+    total++;
+    atomicMax(total, int(prev));
+}
+`)
+	for _, want := range []string{
+		"var<workgroup> hits: atomic<u32>;",
+		"total: atomic<i32>,",
+		"atomicStore(&hits, 0u);",
+		"atomicCompareExchangeWeak(&_Counters.slots[gl_LocalInvocationIndex], 0u, atomicLoad(&hits)).old_value",
+		"atomicAdd(&_Counters.total, 1i);",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output lacks %q:\n%s", want, out)
+		}
+	}
+}
+
+func TestTranslateNames(t *testing.T) {
+	out := compile(t, `#version 450
+layout(local_size_x = 1) in;
+layout(binding = 0) buffer Data { float values[]; };
+
+float select(float loop) { return loop * 2.0; }
+
+void main() {
+    float var = select(values[0]);
+    values[0] = var;
+}
+`)
+	for _, want := range []string{"fn select_(loop_: f32) -> f32 {", "var var_: f32 = select_(_Data.values[0i]);"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output lacks %q:\n%s", want, out)
+		}
+	}
+}
+
+func TestTranslateErrors(t *testing.T) {
+	const header = "#version 450\nlayout(local_size_x = 1) in;\n"
+	tests := []struct {
+		name   string
+		source string
+		want   string // "line:column: message" prefix
+	}{
+		{"no version", "void main() {}", "1:1: missing #version"},
+		{"old version", "#version 330\nvoid main() {}", "1:1: #version 330"},
+		{"no workgroup", "#version 450\nvoid main() {}", "1:1: missing layout(local_size_x"},
+		{"no main", header + "void f() {}", "1:1: missing void main()"},
+		{"double", header + "void main() { double d = 1.0; }", "3:15: double precision type double is not supported"},
+		{"image", header + "layout(rgba8, binding = 0) uniform image2D img;\nvoid main() {}", "3:36: image2D: images and samplers are not supported"},
+		{"readonly", header + "layout(binding = 0) readonly buffer B { uint x[]; };\nvoid main() {\n    x[0] = 1u;\n}", "5:6: cannot assign to read-only x"},
+		{"conversion", header + "void main() {\n    float f = 1.5;\n    int i = f;\n}", "5:13: cannot implicitly convert float to int"},
+		{"fallthrough", header + "void main() {\n    int y = 1;\n    switch (y) { case 1: y = 2; case 2: break; }\n}", "5:18: falling through"},
+		{"private atomic", header + "uint c;\nvoid main() {\n    atomicAdd(c, 1u);\n}", "3:6: atomic functions need a shared variable or a buffer block member"},
+		{"recursion", header + "int f(int a);\nint g(int a) { return f(a); }\nint f(int a) { return g(a); }\nvoid main() { int y = f(1); }", "5:23: recursive call to g"},
+		{"out param", header + "void f(out int a) { a = 1; }\nvoid main() {}", "3:16: out parameters are not supported"},
+		{"undeclared", header + "void main() {\n    y = 1;\n}", "4:5: undeclared identifier y"},
+		{"macro function", "#version 450\n#define SQ(x) ((x) * (x))\n", "2:"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Translate(tt.source)
+			var fe *Error
+			if !errors.As(err, &fe) {
+				t.Fatalf("error = %v (%T), want *Error", err, err)
+			}
+			if !strings.HasPrefix(err.Error(), tt.want) {
+				t.Errorf("error = %q, want prefix %q", err, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,297 @@
+// Copyright 2025 The GoGPU Authors
+// SPDX-License-Identifier: MIT
+
+package frontend
+
+import (
+	"fmt"
+	"strings"
+)
+
+// scalarKind is the component type of a scalar, vector, or matrix.
+type scalarKind uint8
+
+const (
+	scalarBool scalarKind = iota
+	scalarInt
+	scalarUint
+	scalarFloat
+)
+
+// typeKind classifies a GLSL type.
+type typeKind uint8
+
+const (
+	kindVoid typeKind = iota
+	kindScalar
+	kindVector
+	kindMatrix
+	kindArray
+	kindStruct
+)
+
+// glType is a resolved GLSL type.
+type glType struct {
+	kind   typeKind
+	scalar scalarKind
+	size   int // vector components, or matrix rows
+	cols   int // matrix columns
+
+	elem   *glType // array element
+	length int     // array length; 0 for a runtime-sized array
+
+	st *structInfo
+
+	// atomic marks an int or uint scalar that atomic functions operate on;
+	// it is declared atomic<T> in WGSL and read with atomicLoad.
+	atomic bool
+}
+
+// structInfo is a declared struct or interface block type.
+type structInfo struct {
+	name    string // GLSL name
+	wgsl    string // WGSL name
+	members []structMember
+}
+
+type structMember struct {
+	name string
+	wgsl string
+	ty   *glType
+}
+
+func (s *structInfo) member(name string) (int, *structMember) {
+	for i := range s.members {
+		if s.members[i].name == name {
+			return i, &s.members[i]
+		}
+	}
+	return -1, nil
+}
+
+var (
+	voidType  = &glType{kind: kindVoid}
+	boolType  = scalarType(scalarBool)
+	intType   = scalarType(scalarInt)
+	uintType  = scalarType(scalarUint)
+	floatType = scalarType(scalarFloat)
+)
+
+func scalarType(s scalarKind) *glType { return &glType{kind: kindScalar, scalar: s} }
+
+func vectorType(s scalarKind, n int) *glType {
+	if n == 1 {
+		return scalarType(s)
+	}
+	return &glType{kind: kindVector, scalar: s, size: n}
+}
+
+func matrixType(cols, rows int) *glType {
+	return &glType{kind: kindMatrix, scalar: scalarFloat, cols: cols, size: rows}
+}
+
+// builtinTypeNames are the GLSL type keywords, including the double
+// precision types this frontend rejects with a clear error.
+var builtinTypeNames = map[string]bool{
+	"void": true, "bool": true, "int": true, "uint": true, "float": true, "double": true,
+}
+
+func init() {
+	for n := 2; n <= 4; n++ {
+		for _, prefix := range []string{"", "i", "u", "b", "d"} {
+			builtinTypeNames[fmt.Sprintf("%svec%d", prefix, n)] = true
+		}
+		for _, prefix := range []string{"", "d"} {
+			builtinTypeNames[fmt.Sprintf("%smat%d", prefix, n)] = true
+			for m := 2; m <= 4; m++ {
+				builtinTypeNames[fmt.Sprintf("%smat%dx%d", prefix, n, m)] = true
+			}
+		}
+	}
+}
+
+// builtinType resolves a built-in type name, reporting false for names
+// that are not built-in types.
+func builtinType(name string) (*glType, bool, error) {
+	switch name {
+	case "void":
+		return voidType, true, nil
+	case "bool":
+		return boolType, true, nil
+	case "int":
+		return intType, true, nil
+	case "uint":
+		return uintType, true, nil
+	case "float":
+		return floatType, true, nil
+	}
+	if !builtinTypeNames[name] {
+		return nil, false, nil
+	}
+	if strings.HasPrefix(name, "d") {
+		return nil, true, fmt.Errorf("double precision type %s is not supported", name)
+	}
+	var cols, rows int
+	if n, _ := fmt.Sscanf(name, "mat%dx%d", &cols, &rows); n == 2 {
+		return matrixType(cols, rows), true, nil
+	}
+	if n, _ := fmt.Sscanf(name, "mat%d", &cols); n == 1 {
+		return matrixType(cols, cols), true, nil
+	}
+	scalar := scalarFloat
+	switch name[0] {
+	case 'i':
+		scalar = scalarInt
+	case 'u':
+		scalar = scalarUint
+	case 'b':
+		scalar = scalarBool
+	}
+	return vectorType(scalar, int(name[len(name)-1]-'0')), true, nil
+}
+
+// isNumeric reports whether t is a scalar or vector of int, uint, or
+// float.
+func (t *glType) isNumeric() bool {
+	return (t.kind == kindScalar || t.kind == kindVector) && t.scalar != scalarBool
+}
+
+func (t *glType) isInteger() bool {
+	return (t.kind == kindScalar || t.kind == kindVector) && (t.scalar == scalarInt || t.scalar == scalarUint)
+}
+
+// withScalar returns a scalar or vector shaped like t with component s.
+func (t *glType) withScalar(s scalarKind) *glType {
+	if t.kind == kindVector {
+		return vectorType(s, t.size)
+	}
+	return scalarType(s)
+}
+
+// vectorSize is 1 for scalars and the component count for vectors.
+func (t *glType) vectorSize() int {
+	if t.kind == kindVector {
+		return t.size
+	}
+	return 1
+}
+
+// nonAtomic returns t with any atomic mark removed.
+func (t *glType) nonAtomic() *glType {
+	if !t.atomic {
+		return t
+	}
+	c := *t
+	c.atomic = false
+	return &c
+}
+
+func (t *glType) equal(o *glType) bool {
+	if t.kind != o.kind {
+		return false
+	}
+	switch t.kind {
+	case kindScalar, kindVector, kindMatrix:
+		return t.scalar == o.scalar && t.size == o.size && t.cols == o.cols
+	case kindArray:
+		return t.length == o.length && t.elem.equal(o.elem)
+	case kindStruct:
+		return t.st == o.st
+	}
+	return true
+}
+
+// String spells t in GLSL, for diagnostics.
+func (t *glType) String() string {
+	switch t.kind {
+	case kindVoid:
+		return "void"
+	case kindScalar:
+		return [...]string{"bool", "int", "uint", "float"}[t.scalar]
+	case kindVector:
+		return fmt.Sprintf("%svec%d", [...]string{"b", "i", "u", ""}[t.scalar], t.size)
+	case kindMatrix:
+		if t.cols == t.size {
+			return fmt.Sprintf("mat%d", t.cols)
+		}
+		return fmt.Sprintf("mat%dx%d", t.cols, t.size)
+	case kindArray:
+		if t.length == 0 {
+			return t.elem.String() + "[]"
+		}
+		return fmt.Sprintf("%s[%d]", t.elem, t.length)
+	case kindStruct:
+		return t.st.name
+	}
+	return "?"
+}
+
+// wgsl spells t in WGSL.
+func (t *glType) wgsl() string {
+	switch t.kind {
+	case kindScalar:
+		s := wgslScalar(t.scalar)
+		if t.atomic {
+			return "atomic<" + s + ">"
+		}
+		return s
+	case kindVector:
+		return fmt.Sprintf("vec%d<%s>", t.size, wgslScalar(t.scalar))
+	case kindMatrix:
+		return fmt.Sprintf("mat%dx%d<f32>", t.cols, t.size)
+	case kindArray:
+		if t.length == 0 {
+			return "array<" + t.elem.wgsl() + ">"
+		}
+		return fmt.Sprintf("array<%s, %d>", t.elem.wgsl(), t.length)
+	case kindStruct:
+		return t.st.wgsl
+	}
+	return "void"
+}
+
+func wgslScalar(s scalarKind) string {
+	return [...]string{"bool", "i32", "u32", "f32"}[s]
+}
+
+// hasRuntimeArray reports whether t is, or ends in, a runtime-sized array.
+func (t *glType) hasRuntimeArray() bool {
+	switch t.kind {
+	case kindArray:
+		return t.length == 0
+	case kindStruct:
+		n := len(t.st.members)
+		return n > 0 && t.st.members[n-1].ty.hasRuntimeArray()
+	}
+	return false
+}
+
+// implicitlyConverts reports whether GLSL converts a value of type from to
+// type to without a constructor: int to uint, and int or uint to float,
+// component-wise for vectors.
+func implicitlyConverts(from, to *glType) bool {
+	if from.kind != to.kind || (from.kind != kindScalar && from.kind != kindVector) || from.size != to.size {
+		return false
+	}
+	switch from.scalar {
+	case scalarInt:
+		return to.scalar == scalarUint || to.scalar == scalarFloat
+	case scalarUint:
+		return to.scalar == scalarFloat
+	}
+	return false
+}
+
+// rank orders scalar kinds by implicit conversion: int converts to uint,
+// and both convert to float.
+func rank(s scalarKind) int {
+	switch s {
+	case scalarInt:
+		return 1
+	case scalarUint:
+		return 2
+	case scalarFloat:
+		return 3
+	}
+	return 0
+}
@@ -2,7 +2,7 @@
 // SPDX-License-Identifier: MIT
 
 // Package hlsl provides HLSL (High-Level Shading Language) code generation
-// from the naga intermediate representation.
+// from the naga intermediate representation, and an HLSL frontend.
 //
 // HLSL is Microsoft's shader language for DirectX and is used extensively
 // on Windows platforms. This package generates HLSL source code compatible
@@ -11,10 +11,16 @@
 //
 // # Shader Model Support
 //
-// The package supports Shader Models from 5.0 to 6.7:
+// The package supports Shader Models from 5.0 to 6.8:
 //   - SM 5.0-5.1: Legacy FXC compiler, DXBC output
 //   - SM 6.0+: Modern DXC compiler, DXIL output
 //
+// Output uses legacy syntax, which FXC and every DXC language version
+// accept. Options.HLSL2021 targets DXC's -HV 2021 instead, writing and(),
+// or() and select() where HLSL 2021 no longer allows operators on bool
+// vectors, and vector<T, N> for declared vector types; on SM 5.x it falls back to legacy syntax unless
+// Options.StrictSyntax asks for an error.
+//
 // # Usage
 //
 //	module := parseWGSL(source) // or other frontend
@@ -36,4 +42,37 @@
 //	RWTexture: register(u#, space#) // UAVs
 //
 // The BindingMap in Options allows explicit control over register assignment.
+//
+// # Shader Frontend
+//
+// TranslateShader goes the other way: it reads an HLSL shader written for
+// Shader Model 5 and returns IR, so existing D3D11 shaders can be compiled
+// for Vulkan or Metal:
+//
+//	module, err := hlsl.TranslateShader(source, &hlsl.FrontendOptions{
+//	    EntryPoints: []hlsl.FrontendEntryPoint{
+//	        {Name: "VSMain", Stage: ir.StageVertex},
+//	        {Name: "PSMain", Stage: ir.StageFragment},
+//	    },
+//	})
+//
+// The shader is rewritten into WGSL, which ShaderToWGSL returns, and
+// lowered by the WGSL frontend. Compute entry points are found by their
+// [numthreads] attribute. Semantics map entry point inputs and outputs to
+// built-ins and locations, and registers map to bindings through
+// FrontendOptions.Bindings. cbuffers whose register packing differs from
+// the WGSL uniform layout are rejected with the offending member.
+//
+// # Vertex and Instance Indices
+//
+// WGSL's vertex_index and instance_index include the draw's first vertex
+// (or base vertex) and first instance, but SV_VertexID and SV_InstanceID
+// start at zero. Draws with a non-zero base, including indirect draws, need
+// one of two corrections:
+//   - SpecialConstantsBinding declares a NagaConstants constant buffer whose
+//     first_vertex and first_instance fields the host fills in per draw.
+//   - StartLocationSystemValues (SM 6.8) adds SV_StartVertexLocation and
+//     SV_StartInstanceLocation inputs, which the runtime fills in itself.
+//
+// Without either, the indices are those of SV_VertexID and SV_InstanceID.
 package hlsl
@@ -0,0 +1,151 @@
+// Copyright 2025 The GoGPU Authors
+// SPDX-License-Identifier: MIT
+
+package hlsl
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/gogpu/naga/hlsl/internal/frontend"
+	"github.com/gogpu/naga/ir"
+	"github.com/gogpu/naga/wgsl"
+)
+
+// FrontendError is an error in HLSL source given to TranslateShader or
+// ShaderToWGSL. Column is 0 when only the line is known.
+type FrontendError struct {
+	Line    int
+	Column  int
+	Message string
+}
+
+// Error implements the error interface.
+func (e *FrontendError) Error() string {
+	if e.Column == 0 {
+		return fmt.Sprintf("%d: %s", e.Line, e.Message)
+	}
+	return fmt.Sprintf("%d:%d: %s", e.Line, e.Column, e.Message)
+}
+
+// FrontendOptions configures TranslateShader.
+type FrontendOptions struct {
+	// EntryPoints names the vertex and fragment entry points. Functions
+	// with a [numthreads] attribute are compute entry points without
+	// being listed.
+	EntryPoints []FrontendEntryPoint
+
+	// Defines are object-like macros defined before the source. An empty
+	// value defines the macro as 1.
+	Defines map[string]string
+
+	// Bindings maps registers to the group and binding of the resource in
+	// the IR. Other resources bind to the group of their register space,
+	// taking the first free binding in declaration order.
+	Bindings map[FrontendRegister]ResourceBinding
+}
+
+// FrontendEntryPoint is a vertex or fragment entry point.
+type FrontendEntryPoint struct {
+	Name  string
+	Stage ir.ShaderStage
+}
+
+// FrontendRegister is an HLSL register, such as register(t3, space1).
+type FrontendRegister struct {
+	Type     RegisterType
+	Register uint32
+	Space    uint32
+}
+
+// TranslateShader translates an HLSL shader (a Shader Model 5 subset)
+// into IR. opts may be nil for compute shaders.
+//
+// The shader is rewritten into WGSL and lowered by the WGSL frontend, so
+// it must stay within what both languages express: cbuffers, structs,
+// Texture and SamplerState objects, structured buffers, groupshared
+// memory, helper functions with in, out, and inout parameters, the usual
+// control flow, Interlocked atomics, and the intrinsics with a WGSL
+// counterpart. Entry point inputs and outputs are matched by semantic:
+// SV_ semantics become built-ins, and other semantics become locations
+// shared by the vertex outputs and fragment inputs of the module. Storage
+// textures, doubles, and buffers whose HLSL layout differs from the WGSL
+// one are rejected. Errors are *FrontendError values located in the HLSL
+// source.
+func TranslateShader(source string, opts *FrontendOptions) (*ir.Module, error) {
+	res, err := translateShader(source, opts)
+	if err != nil {
+		return nil, err
+	}
+	tokens, err := wgsl.NewLexer(res.WGSL).Tokenize()
+	if err != nil {
+		return nil, shaderError(res, err)
+	}
+	ast, err := wgsl.NewParser(tokens).Parse()
+	if err != nil {
+		return nil, shaderError(res, err)
+	}
+	module, err := wgsl.LowerWithSource(ast, res.WGSL)
+	if err != nil {
+		return nil, shaderError(res, err)
+	}
+	return module, nil
+}
+
+// ShaderToWGSL translates an HLSL shader into the WGSL source
+// TranslateShader lowers. It is useful for inspecting the translation.
+func ShaderToWGSL(source string, opts *FrontendOptions) (string, error) {
+	res, err := translateShader(source, opts)
+	if err != nil {
+		return "", err
+	}
+	return res.WGSL, nil
+}
+
+func translateShader(source string, opts *FrontendOptions) (*frontend.Result, error) {
+	var fo frontend.Options
+	if opts != nil {
+		fo.Defines = opts.Defines
+		fo.EntryPoints = make(map[string]string, len(opts.EntryPoints))
+		for _, ep := range opts.EntryPoints {
+			switch ep.Stage {
+			case ir.StageVertex:
+				fo.EntryPoints[ep.Name] = "vertex"
+			case ir.StageFragment:
+				fo.EntryPoints[ep.Name] = "fragment"
+			default:
+				return nil, fmt.Errorf("hlsl: entry point %s: only vertex and fragment entry points are listed; compute shaders are marked with [numthreads]", ep.Name)
+			}
+		}
+		fo.Bindings = make(map[frontend.Register]frontend.Binding, len(opts.Bindings))
+		for r, b := range opts.Bindings {
+			reg := frontend.Register{Class: r.Type.String()[0], Index: r.Register, Space: r.Space}
+			fo.Bindings[reg] = frontend.Binding{Group: b.Group, Binding: b.Binding}
+		}
+	}
+	res, err := frontend.Translate(source, fo)
+	if err != nil {
+		var fe *frontend.Error
+		if errors.As(err, &fe) {
+			return nil, &FrontendError{Line: fe.Line, Column: fe.Column, Message: fe.Message}
+		}
+		return nil, err
+	}
+	return res, nil
+}
+
+// shaderError maps an error in the translated WGSL back to the HLSL line
+// it came from. The WGSL column says nothing about the HLSL one, so only
+// the line is reported.
+func shaderError(res *frontend.Result, err error) error {
+	located := wgsl.Errors(err)
+	if len(located) == 0 {
+		return fmt.Errorf("hlsl: translated shader failed to compile: %w", err)
+	}
+	e := located[0]
+	line := 0
+	if l := e.Span.Start.Line; l >= 1 && l <= len(res.Lines) {
+		line = res.Lines[l-1]
+	}
+	return &FrontendError{Line: line, Message: e.Message}
+}
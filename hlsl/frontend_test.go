@@ -0,0 +1,83 @@
+// Copyright 2025 The GoGPU Authors
+// SPDX-License-Identifier: MIT
+
+package hlsl_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/gogpu/naga/hlsl"
+	"github.com/gogpu/naga/ir"
+)
+
+const triangleShader = `cbuffer Frame : register(b0, space1) {
+    float4 tint;
+};
+
+struct VSOutput {
+    float4 position : SV_Position;
+    float3 color : COLOR;
+};
+
+VSOutput VSMain(float2 position : POSITION, float3 color : COLOR) {
+    VSOutput output;
+    output.position = float4(position, 0, 1);
+    output.color = color;
+    return output;
+}
+
+float4 PSMain(VSOutput input) : SV_Target {
+    return float4(input.color, 1) * tint;
+}
+`
+
+func TestTranslateShader(t *testing.T) {
+	opts := &hlsl.FrontendOptions{
+		EntryPoints: []hlsl.FrontendEntryPoint{
+			{Name: "VSMain", Stage: ir.StageVertex},
+			{Name: "PSMain", Stage: ir.StageFragment},
+		},
+		Bindings: map[hlsl.FrontendRegister]hlsl.ResourceBinding{
+			{Type: hlsl.RegisterTypeB, Register: 0, Space: 1}: {Group: 3, Binding: 2},
+		},
+	}
+	module, err := hlsl.TranslateShader(triangleShader, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stages := map[string]ir.ShaderStage{}
+	for _, ep := range module.EntryPoints {
+		stages[ep.Name] = ep.Stage
+	}
+	if stages["VSMain"] != ir.StageVertex || stages["PSMain"] != ir.StageFragment || len(stages) != 2 {
+		t.Errorf("entry points = %v, want VSMain and PSMain", stages)
+	}
+	found := false
+	for _, g := range module.GlobalVariables {
+		if g.Space == ir.SpaceUniform {
+			found = g.Binding != nil && *g.Binding == ir.ResourceBinding{Group: 3, Binding: 2}
+		}
+	}
+	if !found {
+		t.Error("cbuffer Frame is not bound to @group(3) @binding(2)")
+	}
+}
+
+func TestTranslateShaderError(t *testing.T) {
+	_, err := hlsl.TranslateShader("[numthreads(1, 1, 1)]\nvoid main() {\n    float x = undefined;\n}\n", nil)
+	var fe *hlsl.FrontendError
+	if !errors.As(err, &fe) {
+		t.Fatalf("error = %v (%T), want *FrontendError", err, err)
+	}
+	if fe.Line != 3 || fe.Column != 15 {
+		t.Errorf("error at %d:%d, want 3:15", fe.Line, fe.Column)
+	}
+
+	_, err = hlsl.TranslateShader(triangleShader, &hlsl.FrontendOptions{
+		EntryPoints: []hlsl.FrontendEntryPoint{{Name: "VSMain", Stage: ir.StageCompute}},
+	})
+	if err == nil {
+		t.Error("listing a compute entry point succeeded")
+	}
+}
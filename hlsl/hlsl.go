@@ -4,7 +4,9 @@
 package hlsl
 
 import (
+	"context"
 	"fmt"
+	"log/slog"
 
 	"github.com/gogpu/naga/hlsl/internal/codegen"
 	"github.com/gogpu/naga/internal/backend"
@@ -48,6 +50,9 @@ const (
 
 	// ShaderModel6_7 adds advanced mesh shaders and work graphs.
 	ShaderModel6_7
+
+	// ShaderModel6_8 adds SV_StartVertexLocation and SV_StartInstanceLocation.
+	ShaderModel6_8
 )
 
 // String returns a human-readable representation of the shader model.
@@ -133,6 +138,8 @@ func (sm ShaderModel) version() (major, minor uint8) {
 		return 6, 6
 	case ShaderModel6_7:
 		return 6, 7
+	case ShaderModel6_8:
+		return 6, 8
 	default:
 		return 5, 1
 	}
@@ -283,20 +290,92 @@ type Options struct {
 	// ForceLoopBounding adds maximum iteration limits to loops.
 	ForceLoopBounding bool
 
+	// FastMathSafeFloatChecks emits isnan/isinf as bit-pattern tests that
+	// fast-math compilation cannot fold away.
+	FastMathSafeFloatChecks bool
+
+	// FloatPrecision is the number of significant digits written for float
+	// literals. Zero writes the shortest form that reads back as the same
+	// value, such as 0.1 for the f32 nearest 0.1.
+	FloatPrecision int
+
+	// WriteMaskStores writes partial vector assignments, such as a GLSL
+	// v.zx = n or a store of a shuffle of the old and new vector, as
+	// assignments to the changed components rather than whole-vector
+	// stores. Stores into storage buffers become one Store per
+	// component.
+	WriteMaskStores bool
+
+	// HLSL2021 writes HLSL 2021 syntax, for DXC with -HV 2021: && and ||
+	// on bool vectors become and() and or(), selects with a vector
+	// condition become select(), and declared vector types are spelled
+	// vector<T, N>. Legacy syntax, the default, is what FXC
+	// and older DXC language versions accept. Below ShaderModel6_0, which
+	// only FXC compiles, legacy syntax is written anyway.
+	HLSL2021 bool
+
+	// StrictSyntax makes Compile fail when HLSL2021 is set for a shader
+	// model below 6.0, rather than falling back to legacy syntax.
+	StrictSyntax bool
+
+	// PreciseMath marks float locals and temporaries precise, and writes
+	// stored and returned float values to precise temporaries first, so
+	// FXC and DXC neither reassociate nor fuse the operations computing
+	// them. Physics and other compute shaders that need the same results
+	// on every GPU should set it.
+	PreciseMath bool
+
 	// DynamicStorageBufferOffsetsTargets maps group indices to their bind targets
 	// for dynamic storage buffer offset constant buffers.
 	DynamicStorageBufferOffsetsTargets map[uint32]OffsetsBindTarget
 
 	// SpecialConstantsBinding specifies the binding for the NagaConstants
-	// constant buffer.
+	// constant buffer. SV_VertexID and SV_InstanceID exclude the draw's base
+	// vertex and base instance; the buffer's first_vertex and first_instance
+	// fields, filled in by the host, restore WGSL's semantics.
 	SpecialConstantsBinding *BindTarget
 
+	// StartLocationSystemValues restores WGSL's vertex_index and
+	// instance_index semantics with the SM 6.8 SV_StartVertexLocation and
+	// SV_StartInstanceLocation inputs, so no constant buffer is needed.
+	// Requires ShaderModel6_8.
+	StartLocationSystemValues bool
+
+	// PipelineConstants provides values for pipeline-overridable constants,
+	// keyed by override ID or name. Compute entry points whose
+	// @workgroup_size names an override get [numthreads] from these values
+	// or the override defaults.
+	PipelineConstants ir.PipelineConstants
+
 	// EntryPoint specifies which entry point to compile.
 	EntryPoint string
 
 	// FragmentEntryPoint specifies a fragment entry point to consider when
 	// generating the output interface of vertex entry points.
 	FragmentEntryPoint *FragmentEntryPoint
+
+	// EntryPointNames renames entry points in the generated code
+	// (WGSL name → HLSL function name), e.g. "main" → "vs_main_quad".
+	// The new name is sanitized and escaped like any other identifier;
+	// TranslationInfo.EntryPointNames reports the final spelling.
+	// Renamed entry points ignore SymbolPrefix.
+	EntryPointNames map[string]string
+
+	// SymbolPrefix is prepended to every module-scope symbol: types,
+	// functions, globals, constants, and entry points not listed in
+	// EntryPointNames. Use it to avoid collisions when several generated
+	// shaders are concatenated into one HLSL file. Generated helper
+	// functions (naga_div, naga_mod, NagaBufferLength, ...) are prefixed
+	// too.
+	SymbolPrefix string
+
+	// SourceMap fills TranslationInfo.SourceMap.
+	SourceMap bool
+
+	// Logger, if set, receives debug traces of code generation phases and
+	// a warning for each polyfill emitted (e.g. "emulating
+	// textureSampleBaseClampToEdge"). nil disables logging.
+	Logger *slog.Logger
 }
 
 // FragmentEntryPoint describes a fragment entry point used to filter
@@ -514,6 +593,15 @@ type TranslationInfo struct {
 
 	// HelperFunctions lists any helper functions that were generated.
 	HelperFunctions []string
+
+	// SourceMap, with Options.SourceMap, maps the 1-based lines where
+	// statements start to the WGSL spans they were generated from.
+	SourceMap []ir.OutputSpan
+
+	// Padding lists the int _padN_i and _end_pad_i members, 4 bytes each,
+	// the output adds to the module's structs where the WGSL layout leaves
+	// gaps, in output order.
+	Padding []ir.StructPadding
 }
 
 // --- Keyword constants ---
@@ -549,8 +637,14 @@ const (
 // Compile generates HLSL source code from an IR module.
 // Returns the HLSL source, translation info, or an error.
 func Compile(module *ir.Module, options *Options) (string, *TranslationInfo, error) {
+	return CompileContext(context.Background(), module, options)
+}
+
+// CompileContext is like Compile but stops with an error wrapping ctx.Err()
+// once ctx is canceled. Cancellation is checked between functions.
+func CompileContext(ctx context.Context, module *ir.Module, options *Options) (string, *TranslationInfo, error) {
 	copts := toCodegenOptions(options)
-	src, cinfo, err := codegen.Compile(module, copts)
+	src, cinfo, err := codegen.CompileContext(ctx, module, copts)
 	if err != nil {
 		return "", nil, err
 	}
@@ -772,10 +866,22 @@ func toCodegenOptions(o *Options) *codegen.Options {
 		ZeroInitializeWorkgroupMemory:      o.ZeroInitializeWorkgroupMemory,
 		RestrictIndexing:                   o.RestrictIndexing,
 		ForceLoopBounding:                  o.ForceLoopBounding,
+		FastMathSafeFloatChecks:            o.FastMathSafeFloatChecks,
+		FloatPrecision:                     o.FloatPrecision,
+		WriteMaskStores:                    o.WriteMaskStores,
+		HLSL2021:                           o.HLSL2021,
+		StrictSyntax:                       o.StrictSyntax,
+		PreciseMath:                        o.PreciseMath,
+		StartLocationSystemValues:          o.StartLocationSystemValues,
 		DynamicStorageBufferOffsetsTargets: dynamicOffsets,
 		SpecialConstantsBinding:            specialBinding,
+		PipelineConstants:                  o.PipelineConstants,
 		EntryPoint:                         o.EntryPoint,
 		FragmentEntryPoint:                 fragEP,
+		EntryPointNames:                    o.EntryPointNames,
+		SymbolPrefix:                       o.SymbolPrefix,
+		SourceMap:                          o.SourceMap,
+		Logger:                             o.Logger,
 	}
 }
 
@@ -809,5 +915,7 @@ func fromCodegenTranslationInfo(ci *codegen.TranslationInfo) TranslationInfo {
 		RequiredShaderModel: ShaderModel(ci.RequiredShaderModel),
 		RegisterBindings:    ci.RegisterBindings,
 		HelperFunctions:     ci.HelperFunctions,
+		SourceMap:           ci.SourceMap,
+		Padding:             ci.Padding,
 	}
 }
@@ -4,6 +4,8 @@
 package hlsl_test
 
 import (
+	"bytes"
+	"log/slog"
 	"strings"
 	"testing"
 
@@ -1109,3 +1111,71 @@ fn fs_main(@location(0) uv: vec2<f32>) -> @location(0) vec4<f32> {
 		assertContains(t, code, "nagaSamplerHeap")
 	})
 }
+
+// TestE2E_PrivateGlobals verifies that private globals are emitted as
+// per-invocation statics, with array suffixes after the name and
+// initializers taken from module constants.
+func TestE2E_PrivateGlobals(t *testing.T) {
+	source := `
+const K = vec2<f32>(1.0, 2.0);
+var<private> table: array<f32, 3> = array<f32, 3>(1.0, 2.0, 3.0);
+var<private> zeros: array<u32, 4>;
+var<private> k = K;
+var<private> sw = K.yx;
+
+@compute @workgroup_size(1)
+fn main() {
+    k.x = table[1] + sw.y + f32(zeros[2]);
+}
+`
+	code := compileWGSLToHLSL(t, source)
+	assertContains(t, code, "static float table[3] = ")
+	assertContains(t, code, "static uint zeros[4] = (uint[4])0;")
+	assertContains(t, code, "static float2 k = K;")
+	assertContains(t, code, "static float2 sw = float2(2.0, 1.0);")
+	assertNotContains(t, code, "static float[3]")
+}
+
+// TestE2E_LoggerPolyfills verifies that Options.Logger receives phase
+// traces and one warning per emitted polyfill.
+func TestE2E_LoggerPolyfills(t *testing.T) {
+	source := `
+@group(0) @binding(0) var tex: texture_2d<f32>;
+@group(0) @binding(1) var samp: sampler;
+
+@fragment
+fn main(@location(0) uv: vec2<f32>, @location(1) @interpolate(flat) n: i32) -> @location(0) vec4<f32> {
+    let a = textureSampleBaseClampToEdge(tex, samp, uv);
+    let b = textureSampleBaseClampToEdge(tex, samp, uv.yx);
+    return a + b * f32(n % 3);
+}
+`
+	tokens, err := wgsl.NewLexer(source).Tokenize()
+	if err != nil {
+		t.Fatalf("Tokenize failed: %v", err)
+	}
+	ast, err := wgsl.NewParser(tokens).Parse()
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	module, err := wgsl.LowerWithSource(ast, source)
+	if err != nil {
+		t.Fatalf("Lower failed: %v", err)
+	}
+
+	var buf bytes.Buffer
+	opts := hlsl.DefaultOptions()
+	opts.Logger = slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	if _, _, err := hlsl.Compile(module, opts); err != nil {
+		t.Fatalf("HLSL Compile failed: %v", err)
+	}
+
+	log := buf.String()
+	assertContains(t, log, `msg="hlsl: writing module" backend=hlsl shader_model=`)
+	assertContains(t, log, `msg="hlsl: module written" backend=hlsl bytes=`)
+	assertContains(t, log, `level=WARN msg="emulating textureSampleBaseClampToEdge" backend=hlsl`)
+	assertContains(t, log, `level=WARN msg="emulating modulo" backend=hlsl`)
+	if n := strings.Count(log, "emulating textureSampleBaseClampToEdge"); n != 1 {
+		t.Errorf("clamp-to-edge polyfill logged %d times, want 1:\n%s", n, log)
+	}
+}
@@ -4,7 +4,9 @@
 package codegen
 
 import (
+	"context"
 	"fmt"
+	"log/slog"
 
 	"github.com/gogpu/naga/ir"
 )
@@ -53,6 +55,37 @@ type Options struct {
 	// Prevents infinite loops that could hang the GPU.
 	ForceLoopBounding bool
 
+	// FastMathSafeFloatChecks emits isnan/isinf as integer tests on the
+	// float's bit pattern. DXC fast-math assumes NaN and Inf never occur
+	// and may fold the intrinsics (and x != x) to false.
+	FastMathSafeFloatChecks bool
+
+	// FloatPrecision is the number of significant digits written for float
+	// literals; zero writes the shortest digits that round-trip.
+	FloatPrecision int
+
+	// WriteMaskStores writes a store that replaces only some components of
+	// a vector (see ir.StoreWriteMask) as an assignment to those
+	// components, such as v.zx = n.yx, instead of storing the whole vector.
+	WriteMaskStores bool
+
+	// HLSL2021 writes HLSL 2021 syntax for DXC's -HV 2021: && and || on
+	// bool vectors become and() and or(), and a select with a vector
+	// condition becomes select(), since HLSL 2021 only allows those
+	// operators on scalars. It needs ShaderModel6_0 or later; on older
+	// models, which FXC compiles, legacy syntax is written instead.
+	HLSL2021 bool
+
+	// StrictSyntax makes Compile fail when HLSL2021 is set for a shader
+	// model below 6.0, instead of falling back to legacy syntax.
+	StrictSyntax bool
+
+	// PreciseMath declares float locals and temporaries precise, baking
+	// float values that are stored or returned into precise temporaries,
+	// so the compiler neither reassociates nor contracts the operations
+	// computing them.
+	PreciseMath bool
+
 	// DynamicStorageBufferOffsetsTargets maps group indices to their bind targets
 	// for dynamic storage buffer offset constant buffers. When a storage buffer
 	// binding has DynamicStorageBufferOffsetsIndex set, the generated HLSL adds
@@ -68,6 +101,19 @@ type Options struct {
 	// Matches Rust naga's special_constants_binding option.
 	SpecialConstantsBinding *BindTarget
 
+	// StartLocationSystemValues offsets vertex and instance indices by the
+	// SM 6.8 SV_StartVertexLocation and SV_StartInstanceLocation inputs
+	// instead of the NagaConstants buffer. SV_VertexID and SV_InstanceID
+	// exclude the draw's base vertex and base instance, while WGSL's
+	// vertex_index and instance_index include them. Requires ShaderModel6_8.
+	StartLocationSystemValues bool
+
+	// PipelineConstants provides values for pipeline-overridable constants.
+	// When set, or when a compute entry point sizes its workgroup with an
+	// override, overrides are resolved (falling back to their defaults)
+	// before code generation, so [numthreads] gets concrete values.
+	PipelineConstants ir.PipelineConstants
+
 	// EntryPoint specifies which entry point to compile.
 	// If empty, the first entry point is used.
 	EntryPoint string
@@ -78,6 +124,21 @@ type Options struct {
 	// inputs will be stripped from the vertex output struct.
 	// Matches Rust naga's FragmentEntryPoint.
 	FragmentEntryPoint *FragmentEntryPoint
+
+	// EntryPointNames renames entry points (WGSL name → HLSL function name).
+	// Renamed entry points ignore SymbolPrefix.
+	EntryPointNames map[string]string
+
+	// SymbolPrefix is prepended to every module-scope symbol (types,
+	// functions, globals, constants, and entry points not in EntryPointNames)
+	// and to the generated helper functions.
+	SymbolPrefix string
+
+	// SourceMap fills TranslationInfo.SourceMap.
+	SourceMap bool
+
+	// Logger receives phase traces and polyfill warnings; nil disables logging.
+	Logger *slog.Logger
 }
 
 // FragmentEntryPoint describes a fragment entry point used to filter
@@ -108,6 +169,19 @@ func DefaultOptions() *Options {
 	}
 }
 
+// hasWorkgroupOverrides reports whether any entry point sizes a
+// workgroup axis with an override.
+func hasWorkgroupOverrides(module *ir.Module) bool {
+	for i := range module.EntryPoints {
+		for _, h := range module.EntryPoints[i].WorkgroupOverrides {
+			if h != nil {
+				return true
+			}
+		}
+	}
+	return false
+}
+
 // FeatureFlags indicates which HLSL features are used by the generated code.
 type FeatureFlags uint32
 
@@ -201,11 +275,25 @@ type TranslationInfo struct {
 
 	// HelperFunctions lists any helper functions that were generated.
 	HelperFunctions []string
+
+	// SourceMap maps output lines to the WGSL spans they were generated
+	// from, when Options.SourceMap is set.
+	SourceMap []ir.OutputSpan
+
+	// Padding lists the int members written into structs to keep the IR
+	// layout, in output order.
+	Padding []ir.StructPadding
 }
 
 // Compile generates HLSL source code from an IR module.
 // Returns the HLSL source, translation info, or an error.
 func Compile(module *ir.Module, options *Options) (string, *TranslationInfo, error) {
+	return CompileContext(context.Background(), module, options)
+}
+
+// CompileContext is like Compile but stops with an error wrapping
+// ctx.Err() once ctx is canceled. Cancellation is checked between functions.
+func CompileContext(ctx context.Context, module *ir.Module, options *Options) (string, *TranslationInfo, error) {
 	if module == nil {
 		return "", nil, &Error{
 			Kind:    ErrInternalError,
@@ -218,13 +306,41 @@ func Compile(module *ir.Module, options *Options) (string, *TranslationInfo, err
 		options = DefaultOptions()
 	}
 
+	// Process overrides if pipeline constants are provided or a workgroup
+	// size or array length depends on one. Deep-clone mutable parts to
+	// avoid mutating shared state.
+	if len(module.Overrides) > 0 && (len(options.PipelineConstants) > 0 || hasWorkgroupOverrides(module) || ir.HasOverrideSizedArrays(module)) {
+		module = ir.CloneModuleForOverrides(module)
+		if err := ir.ProcessOverrides(module, options.PipelineConstants); err != nil {
+			return "", nil, fmt.Errorf("hlsl: process overrides: %w", err)
+		}
+	}
+
+	if options.StartLocationSystemValues && options.ShaderModel < ShaderModel6_8 {
+		return "", nil, &Error{
+			Kind:    ErrUnsupportedFeature,
+			Message: fmt.Sprintf("start location system values require SM 6.8, target is %s", options.ShaderModel),
+		}
+	}
+
+	if options.HLSL2021 && options.StrictSyntax && options.ShaderModel < ShaderModel6_0 {
+		return "", nil, &Error{
+			Kind:    ErrUnsupportedFeature,
+			Message: fmt.Sprintf("HLSL 2021 syntax requires SM 6.0, target is %s", options.ShaderModel),
+		}
+	}
+
 	// Create writer
 	w := newWriter(module, options)
+	w.ctx = ctx
+	w.SourceMap = options.SourceMap
+	w.notes.Phase("hlsl: writing module", "shader_model", options.ShaderModel.String(), "entry_points", len(module.EntryPoints))
 
 	// Generate HLSL code
 	if err := w.writeModule(); err != nil {
 		return "", nil, fmt.Errorf("hlsl: %w", err)
 	}
+	w.notes.Phase("hlsl: module written", "bytes", w.Out.Len())
 
 	info := &TranslationInfo{
 		EntryPointNames:     w.entryPointNames,
@@ -232,7 +348,10 @@ func Compile(module *ir.Module, options *Options) (string, *TranslationInfo, err
 		RequiredShaderModel: w.requiredShaderModel,
 		RegisterBindings:    w.registerBindings,
 		HelperFunctions:     w.helperFunctions,
+		Padding:             w.padding,
 	}
 
-	return w.String(), info, nil
+	code, spans := w.StripMarks(w.String())
+	info.SourceMap = spans
+	return code, info, nil
 }
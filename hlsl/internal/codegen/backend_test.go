@@ -4,6 +4,7 @@
 package codegen
 
 import (
+	"context"
 	"errors"
 	"strings"
 	"testing"
@@ -270,3 +271,21 @@ func TestCompile_BindingMap(t *testing.T) {
 func containsSubstring(s, substr string) bool {
 	return strings.Contains(s, substr)
 }
+
+func TestCompile_ContextCanceled(t *testing.T) {
+	module := &ir.Module{
+		EntryPoints: []ir.EntryPoint{{
+			Name:      "main",
+			Stage:     ir.StageCompute,
+			Workgroup: [3]uint32{1, 1, 1},
+		}},
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, _, err := CompileContext(ctx, module, DefaultOptions()); !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context.Canceled, got %v", err)
+	}
+	if _, _, err := CompileContext(context.Background(), module, DefaultOptions()); err != nil {
+		t.Fatalf("CompileContext failed: %v", err)
+	}
+}
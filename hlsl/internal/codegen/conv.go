@@ -107,6 +107,9 @@ func BuiltInToSemantic(b ir.BuiltinValue) string {
 		return "SV_VertexID"
 	case ir.BuiltinInstanceIndex:
 		return "SV_InstanceID"
+	case ir.BuiltinPointSize:
+		// D3D10+ ignores it, but FXC and DXC accept PSIZE as an output.
+		return "PSIZE"
 	// Fragment shader
 	case ir.BuiltinFrontFacing:
 		return "SV_IsFrontFace"
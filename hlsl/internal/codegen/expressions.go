@@ -8,7 +8,6 @@ package codegen
 import (
 	"fmt"
 	"math"
-	"strconv"
 	"strings"
 
 	"github.com/gogpu/naga/ir"
@@ -42,19 +41,31 @@ func (w *Writer) writeExpression(handle ir.ExpressionHandle) error {
 
 	// Handle special constants (NagaConstants) for vertex_index, instance_index, num_workgroups.
 	// Matches Rust naga's ff_input check in write_expr.
+	// With StartLocationSystemValues the SM 6.8 start location inputs take
+	// the place of first_vertex and first_instance.
 	closingBracket := ""
-	if w.options.SpecialConstantsBinding != nil {
+	if w.options.SpecialConstantsBinding != nil || w.options.StartLocationSystemValues {
 		if bi := w.getFixedFunctionInput(handle); bi != nil {
 			switch *bi {
 			case ir.BuiltinVertexIndex:
-				w.Out.WriteString("(_NagaConstants.first_vertex + ")
+				if w.options.StartLocationSystemValues {
+					w.Out.WriteString("(" + startVertexParam + " + ")
+				} else {
+					w.Out.WriteString("(_NagaConstants.first_vertex + ")
+				}
 				closingBracket = ")"
 			case ir.BuiltinInstanceIndex:
-				w.Out.WriteString("(_NagaConstants.first_instance + ")
+				if w.options.StartLocationSystemValues {
+					w.Out.WriteString("(" + startInstanceParam + " + ")
+				} else {
+					w.Out.WriteString("(_NagaConstants.first_instance + ")
+				}
 				closingBracket = ")"
 			case ir.BuiltinNumWorkGroups:
-				w.Out.WriteString("uint3(_NagaConstants.first_vertex, _NagaConstants.first_instance, _NagaConstants.other)")
-				return nil
+				if w.options.SpecialConstantsBinding != nil {
+					w.Out.WriteString("uint3(_NagaConstants.first_vertex, _NagaConstants.first_instance, _NagaConstants.other)")
+					return nil
+				}
 			}
 		}
 	}
@@ -251,10 +262,10 @@ func (w *Writer) writeLiteralValue(v ir.LiteralValue) error {
 		fmt.Fprintf(&w.Out, "%duL", uint64(val))
 
 	case ir.LiteralF32:
-		w.Out.WriteString(formatFloat32(float32(val)))
+		w.Out.WriteString(formatFloat32(float32(val), w.options.FloatPrecision))
 
 	case ir.LiteralF64:
-		w.Out.WriteString(formatFloat64(float64(val)))
+		w.Out.WriteString(formatFloat64(float64(val), w.options.FloatPrecision))
 		w.Out.WriteByte('L')
 
 	case ir.LiteralAbstractInt:
@@ -269,16 +280,12 @@ func (w *Writer) writeLiteralValue(v ir.LiteralValue) error {
 		} else if math.IsNaN(float64(fval)) {
 			w.Out.WriteString("0.0/0.0")
 		} else {
-			s := strconv.FormatFloat(float64(fval), 'f', -1, 32)
-			if !strings.Contains(s, ".") {
-				s += ".0"
-			}
-			w.Out.WriteString(s)
+			w.Out.WriteString(formatFloat32(fval, w.options.FloatPrecision))
 			w.Out.WriteByte('h')
 		}
 
 	case ir.LiteralAbstractFloat:
-		w.Out.WriteString(formatFloat64(float64(val)))
+		w.Out.WriteString(formatFloat64(float64(val), w.options.FloatPrecision))
 
 	default:
 		return fmt.Errorf("unsupported literal type: %T", v)
@@ -574,7 +581,7 @@ func (w *Writer) writeAccessExpression(e ir.ExprAccess) error {
 	// use __get_col_of_matCx2(base, index) instead of base[index].
 	// Matches Rust naga: get_inner_matrix_of_struct_array_member || get_global_uniform_matrix.
 	{
-		m := w.getInnerMatrixOfStructArrayMember(e.Base)
+		m := w.getInnerMatrixOfStructArrayMember(e.Base, true)
 		if m == nil || !m.isMatCx2() {
 			m = w.getGlobalUniformMatrix(e.Base)
 		}
@@ -658,7 +665,7 @@ func (w *Writer) getAccessMaxIndex(base ir.ExpressionHandle) (uint32, bool) {
 	if baseType == nil {
 		// Last resort: use ir.ResolveExpressionType
 		if w.currentFunction != nil {
-			if res, err := ir.ResolveExpressionType(w.module, w.currentFunction, base); err == nil {
+			if res, err := ir.ExpressionType(w.module, w.currentFunction, base); err == nil {
 				inner := ir.TypeResInner(w.module, res)
 				if inner != nil {
 					baseType = &ir.Type{Inner: inner}
@@ -838,7 +845,7 @@ func (w *Writer) writeAccessIndexExpression(e ir.ExprAccessIndex) error {
 	if baseType == nil {
 		// Last resort: try resolving via ir.ResolveExpressionType
 		if w.currentFunction != nil {
-			if res, err := ir.ResolveExpressionType(w.module, w.currentFunction, e.Base); err == nil {
+			if res, err := ir.ExpressionType(w.module, w.currentFunction, e.Base); err == nil {
 				inner := ir.TypeResInner(w.module, res)
 				if inner != nil {
 					baseType = &ir.Type{Inner: inner}
@@ -941,7 +948,7 @@ func (w *Writer) writeAccessIndexExpression(e ir.ExprAccessIndex) error {
 		// use ._N notation matching the __matCx2 decomposed struct layout.
 		// Matches Rust: get_inner_matrix_of_struct_array_member || get_global_uniform_matrix.
 		if inner.Rows == 2 {
-			m := w.getInnerMatrixOfStructArrayMember(e.Base)
+			m := w.getInnerMatrixOfStructArrayMember(e.Base, true)
 			if m == nil || !m.isMatCx2() {
 				m = w.getGlobalUniformMatrix(e.Base)
 			}
@@ -1115,7 +1122,7 @@ func (w *Writer) writeLoadExpression(e ir.ExprLoad) error {
 	// Check if this load needs a matCx2-to-matrix cast.
 	// Applies to: global uniform matCx2 and struct member array-of-matCx2.
 	// Matches Rust naga: get_inner_matrix_of_struct_array_member || get_inner_matrix_of_global_uniform.
-	m := w.getInnerMatrixOfStructArrayMember(e.Pointer)
+	m := w.getInnerMatrixOfStructArrayMember(e.Pointer, false)
 	if m == nil || !m.isMatCx2() {
 		m = w.getInnerMatrixOfGlobalUniform(e.Pointer)
 	}
@@ -1282,7 +1289,7 @@ func (w *Writer) writeUnaryExpression(e ir.ExprUnary) error {
 	case ir.UnaryNegate:
 		// Check if the operand is I32 scalar or vector — use naga_neg
 		if w.isI32Negate(e.Expr) {
-			op = "naga_neg"
+			op = w.helperName("naga_neg")
 		} else {
 			op = "-"
 		}
@@ -1373,6 +1380,16 @@ func (w *Writer) writeBinaryExpression(e ir.ExprBinary) error {
 		return nil
 	}
 
+	// HLSL 2021 has no logical or bitwise operators on bool vectors.
+	if vec, ok := w.getExpressionTypeInner(e.Left).(ir.VectorType); ok && vec.Scalar.Kind == ir.ScalarBool && w.hlsl2021() {
+		switch e.Op {
+		case ir.BinaryLogicalAnd, ir.BinaryAnd:
+			return w.writeIntrinsicCall("and", e.Left, e.Right)
+		case ir.BinaryLogicalOr, ir.BinaryInclusiveOr:
+			return w.writeIntrinsicCall("or", e.Left, e.Right)
+		}
+	}
+
 	var op string
 	switch e.Op {
 	case ir.BinaryAdd:
@@ -1403,7 +1420,7 @@ func (w *Writer) writeBinaryExpression(e ir.ExprBinary) error {
 	case ir.BinaryDivide:
 		// Integer division uses naga_div for safety (matches Rust naga)
 		if w.isIntegerBinaryOp(e) {
-			fmt.Fprintf(&w.Out, "%s(", NagaDivFunction)
+			fmt.Fprintf(&w.Out, "%s(", w.helperName(NagaDivFunction))
 			if err := w.writeExpression(e.Left); err != nil {
 				return fmt.Errorf("binary left: %w", err)
 			}
@@ -1418,7 +1435,7 @@ func (w *Writer) writeBinaryExpression(e ir.ExprBinary) error {
 	case ir.BinaryModulo:
 		// Integer/float modulo uses naga_mod for safety (matches Rust naga)
 		if w.isIntOrFloatBinaryOp(e) {
-			fmt.Fprintf(&w.Out, "%s(", NagaModFunction)
+			fmt.Fprintf(&w.Out, "%s(", w.helperName(NagaModFunction))
 			if err := w.writeExpression(e.Left); err != nil {
 				return fmt.Errorf("binary left: %w", err)
 			}
@@ -1563,8 +1580,12 @@ func (w *Writer) isIntOrFloatBinaryOp(e ir.ExprBinary) bool {
 	return false
 }
 
-// writeSelectExpression writes a ternary select operation.
+// writeSelectExpression writes a ternary select operation, or a select()
+// call for a vector condition in HLSL 2021.
 func (w *Writer) writeSelectExpression(e ir.ExprSelect) error {
+	if _, ok := w.getExpressionTypeInner(e.Condition).(ir.VectorType); ok && w.hlsl2021() {
+		return w.writeIntrinsicCall("select", e.Condition, e.Accept, e.Reject)
+	}
 	w.Out.WriteByte('(')
 	if err := w.writeExpression(e.Condition); err != nil {
 		return fmt.Errorf("select condition: %w", err)
@@ -1581,6 +1602,28 @@ func (w *Writer) writeSelectExpression(e ir.ExprSelect) error {
 	return nil
 }
 
+// hlsl2021 reports whether HLSL 2021 syntax is written: Options.HLSL2021 on
+// a shader model DXC compiles.
+func (w *Writer) hlsl2021() bool {
+	return w.options != nil && w.options.HLSL2021 && w.options.ShaderModel >= ShaderModel6_0
+}
+
+// writeIntrinsicCall writes fn(args...).
+func (w *Writer) writeIntrinsicCall(fn string, args ...ir.ExpressionHandle) error {
+	w.Out.WriteString(fn)
+	w.Out.WriteByte('(')
+	for i, arg := range args {
+		if i > 0 {
+			w.Out.WriteString(", ")
+		}
+		if err := w.writeExpression(arg); err != nil {
+			return fmt.Errorf("%s argument %d: %w", fn, i, err)
+		}
+	}
+	w.Out.WriteByte(')')
+	return nil
+}
+
 // =============================================================================
 // Relational Expressions
 // =============================================================================
@@ -1600,6 +1643,11 @@ func (w *Writer) writeRelationalExpression(e ir.ExprRelational) error {
 	default:
 		return fmt.Errorf("unsupported relational function: %d", e.Fun)
 	}
+	if e.Fun != ir.RelationalAll && e.Fun != ir.RelationalAny && w.options.FastMathSafeFloatChecks {
+		if done, err := w.writeFloatBitsCheck(e); done || err != nil {
+			return err
+		}
+	}
 
 	w.Out.WriteString(funcName)
 	w.Out.WriteByte('(')
@@ -1610,6 +1658,45 @@ func (w *Writer) writeRelationalExpression(e ir.ExprRelational) error {
 	return nil
 }
 
+// writeFloatBitsCheck writes isnan/isinf as a test on the argument's bit
+// pattern: with the sign masked off, Inf is exactly the all-ones exponent and
+// NaN is anything above it. It reports false for f64, which keeps the
+// intrinsic.
+func (w *Writer) writeFloatBitsCheck(e ir.ExprRelational) (bool, error) {
+	var scalar ir.ScalarType
+	switch t := w.getExpressionTypeInner(e.Argument).(type) {
+	case ir.ScalarType:
+		scalar = t
+	case ir.VectorType:
+		scalar = t.Scalar
+	default:
+		return false, nil
+	}
+	if scalar.Kind != ir.ScalarFloat {
+		return false, nil
+	}
+	var cast, mask, inf string
+	switch scalar.Width {
+	case 2:
+		cast, mask, inf = "asuint16", "0x7fffu", "0x7c00u"
+	case 4:
+		cast, mask, inf = "asuint", "0x7fffffffu", "0x7f800000u"
+	default:
+		return false, nil
+	}
+	op := "=="
+	if e.Fun == ir.RelationalIsNan {
+		op = ">"
+	}
+
+	fmt.Fprintf(&w.Out, "((%s(", cast)
+	if err := w.writeExpression(e.Argument); err != nil {
+		return true, fmt.Errorf("relational argument: %w", err)
+	}
+	fmt.Fprintf(&w.Out, ") & %s) %s %s)", mask, op, inf)
+	return true, nil
+}
+
 // =============================================================================
 // Math Expressions
 // =============================================================================
@@ -1704,6 +1791,10 @@ func (w *Writer) writeMathExpression(e ir.ExprMath) error {
 	if err != nil {
 		return err
 	}
+	switch e.Fun {
+	case ir.MathModf, ir.MathFrexp, ir.MathExtractBits, ir.MathInsertBits:
+		funcName = w.helperName(funcName)
+	}
 
 	// Count arguments
 	args := []ir.ExpressionHandle{e.Arg}
@@ -2278,7 +2369,7 @@ func (w *Writer) writeAsExpression(e ir.ExprAs) error {
 					w.f2iCastFunctions = make(map[string]struct{})
 				}
 				w.f2iCastFunctions[funcName] = struct{}{}
-				w.Out.WriteString(funcName)
+				w.Out.WriteString(w.helperName(funcName))
 				w.Out.WriteByte('(')
 				if err := w.writeExpression(e.Expr); err != nil {
 					return fmt.Errorf("as conversion f2i: %w", err)
@@ -2435,7 +2526,7 @@ func (w *Writer) writeImageSampleExpression(e ir.ExprImageSample) error {
 		if _, isZero := e.Level.(ir.SampleLevelZero); isZero &&
 			e.DepthRef == nil && e.Gather == nil && e.ArrayIndex == nil && e.Offset == nil {
 			w.needsClampToEdgeHelper = true
-			w.Out.WriteString("nagaTextureSampleBaseClampToEdge(")
+			w.Out.WriteString(w.helperName("nagaTextureSampleBaseClampToEdge") + "(")
 			if err := w.writeExpression(e.Image); err != nil {
 				return fmt.Errorf("clamp to edge: image: %w", err)
 			}
@@ -2549,11 +2640,15 @@ func (w *Writer) writeImageSampleExpression(e ir.ExprImageSample) error {
 		}
 	}
 
-	// Offset — wrap in int2() to work around DXC bug
+	// Offset — wrap in int2() (int3() for 3D textures) to work around DXC bug
 	// https://github.com/microsoft/DirectXShaderCompiler/issues/5082#issuecomment-1540147807
 	// Use writeConstExpression to bypass named expression baking (matches Rust naga).
 	if e.Offset != nil {
-		w.Out.WriteString(", int2(")
+		offsetType := "int2"
+		if img := w.getImageTypeFromExpr(e.Image); img != nil && img.Dim == ir.Dim3D {
+			offsetType = "int3"
+		}
+		w.Out.WriteString(", " + offsetType + "(")
 		if err := w.writeConstExpression(*e.Offset); err != nil {
 			return fmt.Errorf("image sample: offset: %w", err)
 		}
@@ -2605,7 +2700,7 @@ func (w *Writer) writeImageLoadExpression(e ir.ExprImageLoad) error {
 	// Check if this is an external texture -- use nagaTextureLoadExternal helper
 	imgType := w.getImageTypeFromExpr(e.Image)
 	if imgType != nil && imgType.Class == ir.ImageClassExternal {
-		w.Out.WriteString("nagaTextureLoadExternal(")
+		w.Out.WriteString(w.helperName("nagaTextureLoadExternal") + "(")
 		if err := w.writeExpression(e.Image); err != nil {
 			return fmt.Errorf("image load external: image: %w", err)
 		}
@@ -2941,7 +3036,7 @@ func (w *Writer) writeImageQueryFunctionName(key wrappedImageQueryKey) {
 		arrayedStr = "Array"
 	}
 
-	fmt.Fprintf(&w.Out, "Naga%s%s%s%s", classStr, queryStr, dimStr, arrayedStr)
+	fmt.Fprintf(&w.Out, "%s%s%s%s%s", w.helperName("Naga"), classStr, queryStr, dimStr, arrayedStr)
 }
 
 // =============================================================================
@@ -3021,9 +3116,9 @@ func (w *Writer) writeArrayLengthExpression(e ir.ExprArrayLength) error {
 	w.nagaBufferLengthWritten[writable] = struct{}{}
 
 	// Write: ((NagaBufferLength[RW](var) - offset) / stride)
-	funcName := "NagaBufferLength"
+	funcName := w.helperName("NagaBufferLength")
 	if writable {
-		funcName = "NagaBufferLengthRW"
+		funcName += "RW"
 	}
 	fmt.Fprintf(&w.Out, "((%s(%s) - %d) / %d)", funcName, varName, offset, stride)
 	return nil
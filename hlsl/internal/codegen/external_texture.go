@@ -143,7 +143,7 @@ func (w *Writer) writeExternalTextureSampleHelper() {
 
 	paramsType := w.getExternalTextureParamsTypeName()
 
-	w.Out.WriteString("float4 nagaTextureSampleBaseClampToEdge(\n")
+	fmt.Fprintf(&w.Out, "float4 %s(\n", w.helperName("nagaTextureSampleBaseClampToEdge"))
 	w.Out.WriteString("    Texture2D<float4> plane0,\n")
 	w.Out.WriteString("    Texture2D<float4> plane1,\n")
 	w.Out.WriteString("    Texture2D<float4> plane2,\n")
@@ -196,7 +196,7 @@ func (w *Writer) writeExternalTextureLoadHelper() {
 
 	paramsType := w.getExternalTextureParamsTypeName()
 
-	w.Out.WriteString("float4 nagaTextureLoadExternal(\n")
+	fmt.Fprintf(&w.Out, "float4 %s(\n", w.helperName("nagaTextureLoadExternal"))
 	w.Out.WriteString("    Texture2D<float4> plane0,\n")
 	w.Out.WriteString("    Texture2D<float4> plane1,\n")
 	w.Out.WriteString("    Texture2D<float4> plane2,\n")
@@ -243,7 +243,7 @@ func (w *Writer) writeExternalTextureDimensionsHelper() {
 
 	paramsType := w.getExternalTextureParamsTypeName()
 
-	fmt.Fprintf(&w.Out, "uint2 NagaExternalDimensions2D(Texture2D<float4> plane0, Texture2D<float4> plane1, Texture2D<float4> plane2, %s params) {\n", paramsType)
+	fmt.Fprintf(&w.Out, "uint2 %s(Texture2D<float4> plane0, Texture2D<float4> plane1, Texture2D<float4> plane2, %s params) {\n", w.helperName("NagaExternalDimensions2D"), paramsType)
 	w.Out.WriteString("    if (any(params.size)) {\n")
 	w.Out.WriteString("        return params.size;\n")
 	w.Out.WriteString("    } else {\n")
@@ -104,6 +104,65 @@ func (w *Writer) hasSubgroupBuiltin(fn *ir.Function) bool {
 	return false
 }
 
+// hasBuiltinInput checks if any argument (or struct member) is bound to bi.
+func (w *Writer) hasBuiltinInput(fn *ir.Function, bi ir.BuiltinValue) bool {
+	isBuiltin := func(binding *ir.Binding) bool {
+		if binding == nil {
+			return false
+		}
+		bb, ok := (*binding).(ir.BuiltinBinding)
+		return ok && bb.Builtin == bi
+	}
+	for _, arg := range fn.Arguments {
+		if isBuiltin(arg.Binding) {
+			return true
+		}
+		if int(arg.Type) < len(w.module.Types) {
+			if st, ok := w.module.Types[arg.Type].Inner.(ir.StructType); ok {
+				for _, member := range st.Members {
+					if isBuiltin(member.Binding) {
+						return true
+					}
+				}
+			}
+		}
+	}
+	return false
+}
+
+// Entry point parameter names for the SM 6.8 start location inputs. The
+// leading "__" cannot collide with WGSL identifiers.
+const (
+	startVertexParam   = "__start_vertex_location"
+	startInstanceParam = "__start_instance_location"
+)
+
+// writeStartLocationParams appends the SM 6.8 start location inputs that
+// vertex_index and instance_index are offset by (see writeExpression).
+func (w *Writer) writeStartLocationParams(ep *ir.EntryPoint, hasParams bool) bool {
+	if !w.options.StartLocationSystemValues || ep.Stage != ir.StageVertex {
+		return hasParams
+	}
+	params := []struct {
+		builtin ir.BuiltinValue
+		decl    string
+	}{
+		{ir.BuiltinVertexIndex, "int " + startVertexParam + " : SV_StartVertexLocation"},
+		{ir.BuiltinInstanceIndex, "uint " + startInstanceParam + " : SV_StartInstanceLocation"},
+	}
+	for _, p := range params {
+		if !w.hasBuiltinInput(&ep.Function, p.builtin) {
+			continue
+		}
+		if hasParams {
+			w.Out.WriteString(", ")
+		}
+		w.Out.WriteString(p.decl)
+		hasParams = true
+	}
+	return hasParams
+}
+
 // =============================================================================
 // Entry Point Interface Struct Generation (matches Rust naga)
 // =============================================================================
@@ -471,6 +530,8 @@ func (w *Writer) writeEntryPointWithIO(epIdx int, ep *ir.EntryPoint) error {
 		}
 	}
 
+	hasParams = w.writeStartLocationParams(ep, hasParams)
+
 	// Add __local_invocation_id parameter for workgroup init
 	if needWgInit {
 		if hasParams {
@@ -524,7 +585,7 @@ func (w *Writer) writeEntryPointWithIO(epIdx int, ep *ir.EntryPoint) error {
 		if isRayQuery {
 			fmt.Fprintf(&w.Out, "%s %s%s;\n", localType, localName, arraySuffix)
 		} else {
-			fmt.Fprintf(&w.Out, "%s %s%s = ", localType, localName, arraySuffix)
+			fmt.Fprintf(&w.Out, "%s%s %s%s = ", w.precisePrefixForType(local.Type), localType, localName, arraySuffix)
 			if local.Init != nil {
 				if err := w.writeExpression(*local.Init); err != nil {
 					w.PopIndent()
@@ -681,7 +742,7 @@ func toLowerFirst(s string) string {
 // writeModHelper writes the safe modulo helper function.
 func (w *Writer) writeModHelper() {
 	w.WriteLine("// Safe modulo helper (truncated division semantics)")
-	w.WriteLine("int %s(int a, int b) {", NagaModFunction)
+	w.WriteLine("int %s(int a, int b) {", w.helperName(NagaModFunction))
 	w.PushIndent()
 	w.WriteLine("return a - b * (a / b);")
 	w.PopIndent()
@@ -689,7 +750,7 @@ func (w *Writer) writeModHelper() {
 	w.WriteLine("")
 
 	// Overload for uint
-	w.WriteLine("uint %s(uint a, uint b) {", NagaModFunction)
+	w.WriteLine("uint %s(uint a, uint b) {", w.helperName(NagaModFunction))
 	w.PushIndent()
 	w.WriteLine("return a - b * (a / b);")
 	w.PopIndent()
@@ -700,7 +761,7 @@ func (w *Writer) writeModHelper() {
 // writeDivHelper writes the safe division helper function.
 func (w *Writer) writeDivHelper() {
 	w.WriteLine("// Safe division helper (handles zero divisor)")
-	w.WriteLine("int %s(int a, int b) {", NagaDivFunction)
+	w.WriteLine("int %s(int a, int b) {", w.helperName(NagaDivFunction))
 	w.PushIndent()
 	w.WriteLine("return b != 0 ? a / b : 0;")
 	w.PopIndent()
@@ -708,7 +769,7 @@ func (w *Writer) writeDivHelper() {
 	w.WriteLine("")
 
 	// Overload for uint
-	w.WriteLine("uint %s(uint a, uint b) {", NagaDivFunction)
+	w.WriteLine("uint %s(uint a, uint b) {", w.helperName(NagaDivFunction))
 	w.PushIndent()
 	w.WriteLine("return b != 0u ? a / b : 0u;")
 	w.PopIndent()
@@ -719,7 +780,7 @@ func (w *Writer) writeDivHelper() {
 // writeAbsHelper writes the safe abs helper function.
 func (w *Writer) writeAbsHelper() {
 	w.WriteLine("// Safe abs helper (handles INT_MIN)")
-	w.WriteLine("int %s(int v) {", NagaAbsFunction)
+	w.WriteLine("int %s(int v) {", w.helperName(NagaAbsFunction))
 	w.PushIndent()
 	w.WriteLine("return v >= 0 ? v : (v == -2147483648 ? 2147483647 : -v);")
 	w.PopIndent()
@@ -730,7 +791,7 @@ func (w *Writer) writeAbsHelper() {
 // writeNegHelper writes the safe negation helper function.
 func (w *Writer) writeNegHelper() {
 	w.WriteLine("// Safe negation helper (handles INT_MIN)")
-	w.WriteLine("int %s(int v) {", NagaNegFunction)
+	w.WriteLine("int %s(int v) {", w.helperName(NagaNegFunction))
 	w.PushIndent()
 	w.WriteLine("return v == -2147483648 ? 2147483647 : -v;")
 	w.PopIndent()
@@ -749,7 +810,7 @@ func (w *Writer) writeModfHelper() {
 	w.WriteLine("};")
 	w.WriteLine("")
 
-	w.WriteLine("_naga_modf_result_f32 %s(float x) {", NagaModfFunction)
+	w.WriteLine("_naga_modf_result_f32 %s(float x) {", w.helperName(NagaModfFunction))
 	w.PushIndent()
 	w.WriteLine("_naga_modf_result_f32 result;")
 	w.WriteLine("result.fract = modf(x, result.whole);")
@@ -770,7 +831,7 @@ func (w *Writer) writeFrexpHelper() {
 	w.WriteLine("};")
 	w.WriteLine("")
 
-	w.WriteLine("_naga_frexp_result_f32 %s(float x) {", NagaFrexpFunction)
+	w.WriteLine("_naga_frexp_result_f32 %s(float x) {", w.helperName(NagaFrexpFunction))
 	w.PushIndent()
 	w.WriteLine("_naga_frexp_result_f32 result;")
 	w.WriteLine("result.fract = frexp(x, result.exp);")
@@ -783,7 +844,7 @@ func (w *Writer) writeFrexpHelper() {
 // writeExtractBitsOverload writes a single naga_extractBits overload for a type.
 // Matches Rust naga's write_wrapped_math_functions for ExtractBits.
 func (w *Writer) writeExtractBitsOverload(typeName string, scalarWidth uint8) {
-	fmt.Fprintf(&w.Out, "%s %s(\n", typeName, NagaExtractBitsFunction)
+	fmt.Fprintf(&w.Out, "%s %s(\n", typeName, w.helperName(NagaExtractBitsFunction))
 	fmt.Fprintf(&w.Out, "    %s e,\n", typeName)
 	fmt.Fprintf(&w.Out, "    uint offset,\n")
 	fmt.Fprintf(&w.Out, "    uint count\n")
@@ -812,7 +873,7 @@ func (w *Writer) writeInsertBitsOverload(typeName string, scalarWidth uint8) {
 	default:
 		scalarMax = 0xFFFFFFFF
 	}
-	fmt.Fprintf(&w.Out, "%s %s(\n", typeName, NagaInsertBitsFunction)
+	fmt.Fprintf(&w.Out, "%s %s(\n", typeName, w.helperName(NagaInsertBitsFunction))
 	fmt.Fprintf(&w.Out, "    %s e,\n", typeName)
 	fmt.Fprintf(&w.Out, "    %s newbits,\n", typeName)
 	fmt.Fprintf(&w.Out, "    uint offset,\n")
@@ -829,7 +890,7 @@ func (w *Writer) writeInsertBitsOverload(typeName string, scalarWidth uint8) {
 // writeF2I32Helper writes the float-to-i32 conversion helper with clamping.
 func (w *Writer) writeF2I32Helper() {
 	w.WriteLine("// Float to i32 conversion with clamping (handles NaN, inf)")
-	w.WriteLine("int %s(float v) {", NagaF2I32Function)
+	w.WriteLine("int %s(float v) {", w.helperName(NagaF2I32Function))
 	w.PushIndent()
 	w.WriteLine("return int(clamp(v, -2147483648.0, 2147483647.0));")
 	w.PopIndent()
@@ -840,7 +901,7 @@ func (w *Writer) writeF2I32Helper() {
 // writeF2U32Helper writes the float-to-u32 conversion helper with clamping.
 func (w *Writer) writeF2U32Helper() {
 	w.WriteLine("// Float to u32 conversion with clamping (handles NaN, inf)")
-	w.WriteLine("uint %s(float v) {", NagaF2U32Function)
+	w.WriteLine("uint %s(float v) {", w.helperName(NagaF2U32Function))
 	w.PushIndent()
 	w.WriteLine("return uint(clamp(v, 0.0, 4294967295.0));")
 	w.PopIndent()
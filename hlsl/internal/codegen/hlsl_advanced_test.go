@@ -602,6 +602,62 @@ fn vs_main(@builtin(vertex_index) vid: u32, @builtin(instance_index) iid: u32) -
 	})
 }
 
+func TestCompile_StartLocationSystemValues(t *testing.T) {
+	opts := DefaultOptions()
+	opts.FakeMissingBindings = true
+	opts.ShaderModel = ShaderModel6_8
+	opts.StartLocationSystemValues = true
+
+	src := `
+struct VsIn {
+    @builtin(vertex_index) vid: u32,
+    @location(0) pos: vec2<f32>,
+}
+
+@vertex
+fn vs_main(input: VsIn, @builtin(instance_index) iid: u32) -> @builtin(position) vec4<f32> {
+    return vec4<f32>(input.pos, f32(input.vid), f32(iid));
+}
+
+@vertex
+fn vs_plain(@location(0) pos: vec4<f32>) -> @builtin(position) vec4<f32> {
+    return pos;
+}
+`
+	code := compileWGSLToHLSL(t, src, opts)
+	mustContain(t, code, []string{
+		"int __start_vertex_location : SV_StartVertexLocation",
+		"uint __start_instance_location : SV_StartInstanceLocation",
+		"(__start_vertex_location + input.vid)",
+		"(__start_instance_location + iid)",
+		"float4 vs_plain(float4 pos : LOC0) : SV_Position",
+	})
+	mustNotContain(t, code, []string{"NagaConstants"})
+
+	// Below SM 6.8 the system values don't exist.
+	opts.ShaderModel = ShaderModel6_7
+	module := parseWGSL(t, src)
+	if _, _, err := Compile(module, opts); err == nil || !strings.Contains(err.Error(), "SM 6.8") {
+		t.Errorf("expected SM 6.8 error, got %v", err)
+	}
+}
+
+func TestCompile_PointSizeOutput(t *testing.T) {
+	src := `
+struct VsOut {
+    @builtin(position) pos: vec4<f32>,
+    @builtin(point_size) size: f32,
+}
+
+@vertex
+fn vs_main(@location(0) pos: vec4<f32>) -> VsOut {
+    return VsOut(pos, 4.0);
+}
+`
+	code := compileWGSLToHLSL(t, src, nil)
+	mustContain(t, code, []string{"float size : PSIZE;"})
+}
+
 // =============================================================================
 // Derivative Fine/Coarse Variants — covers all derivative axis+control combos
 // =============================================================================
@@ -726,6 +782,52 @@ fn fs_main(@builtin(position) pos: vec4<f32>) -> @location(0) f32 {
 	})
 }
 
+func TestCompile_TextureSampleOffsets(t *testing.T) {
+	src := `
+@group(0) @binding(0) var t3: texture_3d<f32>;
+@group(0) @binding(1) var d2: texture_depth_2d;
+@group(1) @binding(0) var s: sampler;
+@group(1) @binding(1) var sc: sampler_comparison;
+
+@fragment
+fn fs_main(@location(0) p: vec3<f32>) -> @location(0) vec4<f32> {
+    var c = textureSample(t3, s, p, vec3i(1, 2, 3));
+    c += textureSampleGrad(t3, s, p, p, p, vec3i(1, 2, 3));
+    c.x += textureSampleCompare(d2, sc, p.xy, 0.5, vec2i(1, 2));
+    c.x += textureSampleCompareLevel(d2, sc, p.xy, 0.5, vec2i(3, 4));
+    return c;
+}
+`
+	code := compileWGSLToHLSL(t, src, nil)
+	mustContain(t, code, []string{
+		".Sample(s, p, int3(int3(int(1), int(2), int(3))))",
+		".SampleGrad(s, p, p, p, int3(int3(int(1), int(2), int(3))))",
+		".SampleCmp(sc, p.xy, 0.5, int2(int2(int(1), int(2))))",
+		".SampleCmpLevelZero(sc, p.xy, 0.5, int2(int2(int(3), int(4))))",
+	})
+	mustNotContain(t, code, []string{"int2(int3("})
+}
+
+func TestCompile_FastMathSafeFloatChecks(t *testing.T) {
+	src := `
+@fragment
+fn fs_main(@location(0) x: f32, @location(1) v: vec3<f32>) -> @location(0) vec4<f32> {
+    return vec4<f32>(select(0.0, 1.0, isnan(x)), select(0.0, 1.0, isinf(v).y), 0.0, 0.0);
+}
+`
+	mustContain(t, compileWGSLToHLSL(t, src, nil), []string{"isnan(x)", "isinf(v)"})
+
+	opts := DefaultOptions()
+	opts.FakeMissingBindings = true
+	opts.FastMathSafeFloatChecks = true
+	code := compileWGSLToHLSL(t, src, opts)
+	mustContain(t, code, []string{
+		"((asuint(x) & 0x7fffffffu) > 0x7f800000u)",
+		"((asuint(v) & 0x7fffffffu) == 0x7f800000u)",
+	})
+	mustNotContain(t, code, []string{"isnan(", "isinf("})
+}
+
 // =============================================================================
 // Helpers from hlsl_compile_test.go reused via package scope
 // =============================================================================
@@ -764,3 +866,175 @@ func parseAdvanced(t *testing.T, src string) *ir.Module {
 	}
 	return module
 }
+
+const hlsl2021Shader = `
+@fragment
+fn fs(@location(0) a: vec4<f32>, @location(1) b: vec4<f32>) -> @location(0) vec4<f32> {
+    let lt = a < b;
+    let gt = a > b;
+    let both = lt & gt;
+    let either = lt | gt;
+    let s = a.x < b.x && a.y < b.y;
+    return select(select(a, b, both), a, either) + select(0.0, 1.0, s);
+}
+`
+
+func TestCompile_HLSL2021(t *testing.T) {
+	opts := DefaultOptions()
+	opts.FakeMissingBindings = true
+	opts.ShaderModel = ShaderModel6_0
+	opts.HLSL2021 = true
+	code := compileWGSLToHLSL(t, hlsl2021Shader, opts)
+	mustContain(t, code, []string{
+		"vector<float, 4> fs(",
+		"vector<bool, 4> both = and(lt, gt);",
+		"vector<bool, 4> either = or(lt, gt);",
+		"select(both, b, a)",
+		"select(either, a,",
+		"(s ? 1.0 : 0.0)", // scalar conditions keep the operators
+	})
+	mustNotContain(t, code, []string{"(lt & gt)", "(lt | gt)", "(both ?"})
+
+	// Legacy syntax, and HLSL 2021 on SM 5.1, which FXC compiles.
+	for _, sm := range []ShaderModel{ShaderModel6_0, ShaderModel5_1} {
+		opts.ShaderModel = sm
+		opts.HLSL2021 = sm == ShaderModel5_1
+		code = compileWGSLToHLSL(t, hlsl2021Shader, opts)
+		mustContain(t, code, []string{"(lt & gt)", "(lt | gt)", "(both ? b : a)", "float4 fs("})
+		mustNotContain(t, code, []string{"and(", "or(", "select(", "vector<"})
+	}
+
+	opts.StrictSyntax = true
+	_, _, err := Compile(parseWGSL(t, hlsl2021Shader), opts)
+	if err == nil || !strings.Contains(err.Error(), "HLSL 2021 syntax requires SM 6.0") {
+		t.Errorf("strict HLSL 2021 on SM 5.1: err = %v, want an unsupported feature error", err)
+	}
+}
+
+const vectorDivShader = `
+@group(0) @binding(0) var<storage, read_write> out: array<vec4<i32>>;
+@group(0) @binding(1) var<storage, read_write> outu: array<vec4<u32>>;
+
+@compute @workgroup_size(1)
+fn main() {
+    out[0] = out[1] / out[2];
+    out[3] = out[4] % out[5];
+    outu[0] = outu[1] / outu[2];
+    outu[3] = outu[4] % outu[5];
+}
+`
+
+// TestCompile_HLSL2021DivModHelpers checks that the naga_div and naga_mod
+// helpers for vectors avoid &, | and ?: on bool vectors in HLSL 2021.
+func TestCompile_HLSL2021DivModHelpers(t *testing.T) {
+	opts := DefaultOptions()
+	opts.ShaderModel = ShaderModel6_0
+	opts.HLSL2021 = true
+	code := compileWGSLToHLSL(t, vectorDivShader, opts)
+	mustContain(t, code, []string{
+		"return lhs / select(or(and(lhs == int(-2147483647 - 1), rhs == -1), rhs == 0), (vector<int, 4>)1, rhs);",
+		"vector<int, 4> divisor = select(or(and(lhs == int(-2147483647 - 1), rhs == -1), rhs == 0), (vector<int, 4>)1, rhs);",
+		"return lhs / select(rhs == 0u, (vector<uint, 4>)1u, rhs);",
+		"return lhs % select(rhs == 0u, (vector<uint, 4>)1u, rhs);",
+	})
+	mustNotContain(t, code, []string{"rhs == -1) |", "? 1 : rhs", "? 1u : rhs"})
+
+	opts.HLSL2021 = false
+	code = compileWGSLToHLSL(t, vectorDivShader, opts)
+	mustContain(t, code, []string{
+		"return lhs / (((lhs == int(-2147483647 - 1) & rhs == -1) | (rhs == 0)) ? 1 : rhs);",
+		"return lhs / (rhs == 0u ? 1u : rhs);",
+	})
+	mustNotContain(t, code, []string{"select("})
+}
+
+const preciseMathShader = `
+struct Body { pos: vec3<f32>, vel: vec3<f32> }
+@group(0) @binding(0) var<storage, read_write> bodies: array<Body>;
+
+fn drag(v: vec3<f32>, k: f32) -> vec3<f32> {
+    return v * (1.0 - k * length(v));
+}
+
+@compute @workgroup_size(64)
+fn step(@builtin(global_invocation_id) id: vec3<u32>) {
+    var b = bodies[id.x];
+    let dt = 0.016;
+    b.vel = drag(b.vel, 0.1) + vec3<f32>(0.0, -9.8, 0.0) * dt;
+    bodies[id.x].pos = b.pos + b.vel * dt;
+}
+`
+
+func TestCompile_PreciseMath(t *testing.T) {
+	opts := DefaultOptions()
+	opts.FakeMissingBindings = true
+	opts.PreciseMath = true
+	code := compileWGSLToHLSL(t, preciseMathShader, opts)
+	mustContain(t, code, []string{
+		"precise float3 _e6 = (v * (1.0 - (k * length(v))));", // returned
+		"precise float3 _e17 = (_e11 + (float3(0.0, -9.8, 0.0) * 0.016));",
+		"precise float3 _e27 = (_e23 + (_e25 * 0.016));", // stored
+		"bodies.Store3(0+id.x*32, asuint(_e27));",
+	})
+	mustNotContain(t, code, []string{"precise Body", "precise uint"})
+
+	opts.PreciseMath = false
+	code = compileWGSLToHLSL(t, preciseMathShader, opts)
+	mustNotContain(t, code, []string{"precise"})
+}
+
+const matCx2StoreShader = `
+struct S { m: mat3x2<f32>, am: array<mat2x2<f32>, 2> }
+var<workgroup> wg: S;
+@compute @workgroup_size(1)
+fn main(@builtin(local_invocation_index) i: u32) {
+    var t: S;
+    t.m[i][i] = 1.0;
+    t.am[i][i][1] = 2.0;
+    wg.m[i][i] = 3.0;
+    wg.am[i][i] = vec2(4.0);
+    wg.m[0].x = t.am[i][i][0];
+}
+`
+
+// TestCompile_MatCx2ElementStores checks stores through column then
+// component indices of decomposed matCx2 members.
+func TestCompile_MatCx2ElementStores(t *testing.T) {
+	code := compileWGSLToHLSL(t, matCx2StoreShader, nil)
+	mustContain(t, code, []string{
+		// The setters must write back to the caller's variable.
+		"void SetMatScalarmOnS(inout S obj, float scalar, uint mat_idx, uint vec_idx)",
+		"void __set_el_of_mat2x2(inout __mat2x2 mat, uint idx, uint vec_idx, float value)",
+		"SetMatScalarmOnS(t, 1.0, i, i);",
+		// The array is indexed, the matrix in it takes the column.
+		"__set_el_of_mat2x2(t.am[min(uint(i), 1u)], i, 1, 2.0);",
+		"__get_col_of_mat2x2(t.am[min(uint(i), 1u)], i)",
+		// Groupshared members are stored in place, not copied through a helper.
+		"case 2: { wg.m_2[i] = 3.0; break; }",
+		"case 1: { wg.am[min(uint(i), 1u)]._1 = (4.0).xx; break; }",
+	})
+	mustNotContain(t, code, []string{"SetMatScalarmOnS(wg", "__get_col_of_mat2x2(t.am,"})
+}
+
+// TestCompile_MatCx2ElementStoresThroughWorkgroupArgument checks that a
+// ptr<workgroup> argument gets the same in-place stores as the global.
+func TestCompile_MatCx2ElementStoresThroughWorkgroupArgument(t *testing.T) {
+	const shader = `
+struct S { m: mat3x2<f32>, am: array<mat2x2<f32>, 2> }
+var<workgroup> wg: S;
+fn put(p: ptr<workgroup, S>, i: u32) {
+    (*p).m[i][i] = 3.0;
+    (*p).am[i][i] = vec2(4.0);
+}
+@compute @workgroup_size(1)
+fn main(@builtin(local_invocation_index) i: u32) {
+    put(&wg, i);
+}
+`
+	code := compileWGSLToHLSL(t, shader, nil)
+	mustContain(t, code, []string{
+		"case 2: { p.m_2[i_1] = 3.0; break; }",
+		"case 1: { p.am[min(uint(i_1), 1u)]._1 = (4.0).xx; break; }",
+	})
+	mustNotContain(t, code, []string{"SetMatScalarmOnS(p", "__set_col_of_mat2x2(p."})
+}
@@ -121,6 +121,80 @@ fn cs_main(@builtin(global_invocation_id) gid: vec3<u32>) {
 	})
 }
 
+func TestCompile_ComputeBuiltinSemantics(t *testing.T) {
+	src := `
+@group(0) @binding(0) var<storage, read_write> buf: array<u32>;
+
+@compute @workgroup_size(8, 4, 2)
+fn first(
+    @builtin(global_invocation_id) gid: vec3<u32>,
+    @builtin(local_invocation_id) lid: vec3<u32>,
+    @builtin(local_invocation_index) li: u32,
+    @builtin(workgroup_id) wid: vec3<u32>,
+) {
+    buf[li] = gid.x + lid.y + wid.z;
+}
+
+const W: u32 = 16;
+
+@compute @workgroup_size(W)
+fn second(@builtin(global_invocation_id) gid: vec3<u32>) {
+    buf[gid.x] = gid.y;
+}
+`
+	code := compileWGSLToHLSL(t, src, nil)
+	mustContain(t, code, []string{
+		"[numthreads(8, 4, 2)]\nvoid first(uint3 gid : SV_DispatchThreadID, uint3 lid : SV_GroupThreadID, uint li : SV_GroupIndex, uint3 wid : SV_GroupID)",
+		"[numthreads(16, 1, 1)]\nvoid second(uint3 gid_1 : SV_DispatchThreadID)",
+	})
+}
+
+func TestCompile_ComputeWorkgroupSizeOverride(t *testing.T) {
+	src := `
+override BLOCK: u32 = 32;
+@id(7) override ROWS: u32 = 2;
+@group(0) @binding(0) var<storage, read_write> buf: array<u32>;
+
+@compute @workgroup_size(BLOCK, ROWS)
+fn cs_main(@builtin(global_invocation_id) gid: vec3<u32>) {
+    buf[gid.x] = BLOCK;
+}
+`
+	code := compileWGSLToHLSL(t, src, nil)
+	mustContain(t, code, []string{"[numthreads(32, 2, 1)]"})
+
+	opts := DefaultOptions()
+	opts.PipelineConstants = ir.PipelineConstants{"BLOCK": 64, "7": 4}
+	code = compileWGSLToHLSL(t, src, opts)
+	mustContain(t, code, []string{"[numthreads(64, 4, 1)]", "static const uint BLOCK = 64u;"})
+
+	opts.PipelineConstants = ir.PipelineConstants{"BLOCK": 0}
+	if _, _, err := Compile(parseWGSL(t, src), opts); err == nil || !strings.Contains(err.Error(), "must be a positive integer") {
+		t.Errorf("zero workgroup size: got %v", err)
+	}
+}
+
+func TestCompile_OverrideSizedWorkgroupArray(t *testing.T) {
+	src := `
+override N: u32 = 4u;
+@group(0) @binding(0) var<storage, read_write> out: array<f32>;
+var<workgroup> buf: array<f32, N * 2u>;
+@compute @workgroup_size(1)
+fn main(@builtin(local_invocation_index) i: u32) {
+    buf[i] = f32(i);
+    workgroupBarrier();
+    out[i] = buf[i];
+}
+`
+	code := compileWGSLToHLSL(t, src, DefaultOptions())
+	mustContain(t, code, []string{"groupshared float buf[8]"})
+
+	opts := DefaultOptions()
+	opts.PipelineConstants = ir.PipelineConstants{"N": 16}
+	code = compileWGSLToHLSL(t, src, opts)
+	mustContain(t, code, []string{"groupshared float buf[32]"})
+}
+
 // =============================================================================
 // Statement Tests — covers writeIfStatement, writeSwitchStatement,
 // writeLoopStatement, writeBreakStatement, writeContinueStatement,
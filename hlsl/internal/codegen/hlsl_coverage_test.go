@@ -278,6 +278,24 @@ fn f() -> u32 {
 	mustContain(t, code, []string{"GetDimensions("})
 }
 
+func TestCov_TextureNumLayers(t *testing.T) {
+	code := wgslToHLSL(t, `
+@group(0) @binding(0) var t2a: texture_2d_array<f32>;
+@group(0) @binding(1) var tsa: texture_storage_2d_array<r32uint, write>;
+@group(0) @binding(2) var t1: texture_1d<f32>;
+
+fn f() -> u32 {
+    return textureNumLayers(t2a) + textureNumLayers(tsa) + textureNumLevels(t1);
+}`)
+	// The element count follows the spatial dimensions; storage textures
+	// have no mip level argument or count.
+	mustContain(t, code, []string{
+		"tex.GetDimensions(0, ret.x, ret.y, ret.z, ret.w);\n    return ret.z;",
+		"tex.GetDimensions(ret.x, ret.y, ret.z);\n    return ret.z;",
+		"tex.GetDimensions(0, ret.x, ret.y);\n    return ret.y;",
+	})
+}
+
 // =============================================================================
 // Global variable types — covers writeGlobalVariableExpression
 // =============================================================================
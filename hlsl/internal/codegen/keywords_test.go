@@ -409,3 +409,31 @@ func BenchmarkEscape(b *testing.B) {
 		}
 	}
 }
+
+func TestReservedIdentifiersEscaped(t *testing.T) {
+	code := compileWGSLToHLSL(t, `
+struct half {
+    line: f32,
+    texture: f32,
+    point: f32,
+}
+
+@group(0) @binding(0) var<uniform> params: half;
+
+fn texture(line: f32, Texture2D: f32) -> f32 {
+    return line + Texture2D;
+}
+
+@fragment
+fn main() -> @location(0) vec4<f32> {
+    return vec4<f32>(texture(params.line, params.texture) + params.point);
+}
+`, nil)
+	mustContain(t, code, []string{
+		"struct half_ {",
+		"float line_;",
+		"float point_;",
+		"float texture_(float line_, float Texture2D_)",
+	})
+	mustNotContain(t, code, []string{"struct half {", "float line;"})
+}
@@ -41,6 +41,9 @@ const (
 
 	// ShaderModel6_7 adds advanced mesh shaders and work graphs.
 	ShaderModel6_7
+
+	// ShaderModel6_8 adds SV_StartVertexLocation and SV_StartInstanceLocation.
+	ShaderModel6_8
 )
 
 // String returns a human-readable representation of the shader model.
@@ -81,6 +84,8 @@ func (sm ShaderModel) version() (major, minor uint8) {
 		return 6, 6
 	case ShaderModel6_7:
 		return 6, 7
+	case ShaderModel6_8:
+		return 6, 8
 	default:
 		return 5, 1 // Default to 5.1 for unknown
 	}
@@ -28,8 +28,12 @@ func (w *Writer) updateExpressionsToBake(fn *ir.Function) {
 		refCounts[h]++
 	}
 
-	// Count references from expressions
+	// Count references from live expressions; dead ones are never written.
+	live := ir.LiveExpressions(fn)
 	for i := range fn.Expressions {
+		if !live[i] {
+			continue
+		}
 		switch e := fn.Expressions[i].Kind.(type) {
 		case ir.ExprAccess:
 			countExprRef(e.Base)
@@ -159,6 +163,64 @@ func (w *Writer) updateExpressionsToBake(fn *ir.Function) {
 			}
 		}
 	}
+
+	// PreciseMath: bake float arithmetic that is stored or returned, so a
+	// precise temporary covers the operations computing it.
+	if w.options.PreciseMath {
+		bakeFloat := func(h ir.ExpressionHandle) {
+			if int(h) >= len(fn.Expressions) || int(h) >= len(fn.ExpressionTypes) {
+				return
+			}
+			switch fn.Expressions[h].Kind.(type) {
+			case ir.ExprBinary, ir.ExprUnary, ir.ExprMath, ir.ExprSelect:
+				if w.precisePrefix(ir.TypeResInner(w.module, fn.ExpressionTypes[h])) != "" {
+					w.needBakeExpressions[h] = struct{}{}
+				}
+			}
+		}
+		ir.WalkStatements(fn.Body, func(stmt *ir.Statement) bool {
+			switch s := stmt.Kind.(type) {
+			case ir.StmtStore:
+				bakeFloat(s.Value)
+			case ir.StmtReturn:
+				if s.Value != nil {
+					bakeFloat(*s.Value)
+				}
+			}
+			return true
+		}, nil)
+	}
+}
+
+// precisePrefixForType is precisePrefix for the type at handle.
+func (w *Writer) precisePrefixForType(handle ir.TypeHandle) string {
+	if int(handle) >= len(w.module.Types) {
+		return ""
+	}
+	return w.precisePrefix(w.module.Types[handle].Inner)
+}
+
+// precisePrefix returns "precise " for a float scalar, vector or matrix
+// declared under Options.PreciseMath, and "" otherwise.
+func (w *Writer) precisePrefix(inner ir.TypeInner) string {
+	if !w.options.PreciseMath {
+		return ""
+	}
+	var kind ir.ScalarKind
+	switch t := inner.(type) {
+	case ir.ScalarType:
+		kind = t.Kind
+	case ir.VectorType:
+		kind = t.Scalar.Kind
+	case ir.MatrixType:
+		kind = t.Scalar.Kind
+	default:
+		return ""
+	}
+	if kind != ir.ScalarFloat {
+		return ""
+	}
+	return "precise "
 }
 
 // countStmtRefs counts expression references within statements.
@@ -257,6 +319,9 @@ func (w *Writer) writeBlock(block ir.Block) error {
 
 // writeStatement dispatches to the appropriate statement writer.
 func (w *Writer) writeStatement(kind ir.StatementKind) error {
+	if w.SourceMap {
+		w.MarkSpan(ir.StatementSpan(w.currentFunction, kind))
+	}
 	switch s := kind.(type) {
 	case ir.StmtEmit:
 		return w.writeEmitStatement(s)
@@ -383,7 +448,7 @@ func (w *Writer) writeEmittedExpression(handle ir.ExpressionHandle) error {
 		typeName, arraySuffix = w.typeToHLSLWithArraySuffix(exprType)
 	}
 	w.WriteIndent()
-	fmt.Fprintf(&w.Out, "%s %s%s = ", typeName, name, arraySuffix)
+	fmt.Fprintf(&w.Out, "%s%s %s%s = ", w.precisePrefix(exprType.Inner), typeName, name, arraySuffix)
 	if err := w.writeExpression(handle); err != nil {
 		return err
 	}
@@ -933,6 +998,11 @@ func (w *Writer) writeBarrierStatement(s ir.StmtBarrier) error {
 func (w *Writer) writeStoreStatement(s ir.StmtStore) error {
 	// Check if storing to a storage buffer pointer -> use ByteAddressBuffer Store
 	if w.isStoragePointer(s.Pointer) {
+		if w.options.WriteMaskStores {
+			if handled, err := w.writeMaskedStorageStore(s); handled || err != nil {
+				return err
+			}
+		}
 		varHandle, err := w.fillAccessChain(s.Pointer)
 		if err != nil {
 			return fmt.Errorf("storage store: %w", err)
@@ -952,6 +1022,12 @@ func (w *Writer) writeStoreStatement(s ir.StmtStore) error {
 		return err
 	}
 
+	if w.options.WriteMaskStores {
+		if handled, err := w.writeMaskedStore(s); handled || err != nil {
+			return err
+		}
+	}
+
 	w.WriteIndent()
 	if err := w.writeExpression(s.Pointer); err != nil {
 		return fmt.Errorf("store pointer: %w", err)
@@ -970,6 +1046,83 @@ func (w *Writer) writeStoreStatement(s ir.StmtStore) error {
 	return nil
 }
 
+// writeMaskedStore writes a store that replaces only some components of a
+// vector (see ir.StoreWriteMask): v.zx = n.yx when the new values are
+// components of one vector, or one assignment per component when no value
+// reads the stored variable. It reports false when the store must be
+// written whole.
+func (w *Writer) writeMaskedStore(s ir.StmtStore) (bool, error) {
+	mask, ok := ir.StoreWriteMask(w.module, w.currentFunction, s)
+	if !ok {
+		return false, nil
+	}
+	const components = "xyzw"
+	if source, read, ok := mask.Source(w.currentFunction); ok {
+		w.WriteIndent()
+		if err := w.writeExpression(s.Pointer); err != nil {
+			return true, fmt.Errorf("store pointer: %w", err)
+		}
+		w.Out.WriteByte('.')
+		for _, c := range mask.Targets {
+			w.Out.WriteByte(components[c])
+		}
+		w.Out.WriteString(" = ")
+		if err := w.writeExpression(source); err != nil {
+			return true, fmt.Errorf("store value: %w", err)
+		}
+		w.Out.WriteByte('.')
+		for _, c := range read {
+			w.Out.WriteByte(components[c])
+		}
+		w.Out.WriteString(";\n")
+		return true, nil
+	}
+	if mask.ReadsTarget(w.currentFunction, s) {
+		return false, nil
+	}
+	for i, c := range mask.Targets {
+		w.WriteIndent()
+		if err := w.writeExpression(s.Pointer); err != nil {
+			return true, fmt.Errorf("store pointer: %w", err)
+		}
+		fmt.Fprintf(&w.Out, ".%c = ", components[c])
+		if err := w.writeExpression(mask.Values[i]); err != nil {
+			return true, fmt.Errorf("store value: %w", err)
+		}
+		w.Out.WriteString(";\n")
+	}
+	return true, nil
+}
+
+// writeMaskedStorageStore writes a store into a storage buffer that
+// replaces only some components of a vector as one Store per component.
+// ByteAddressBuffer has no write mask. It reports false when the store
+// must be written whole.
+func (w *Writer) writeMaskedStorageStore(s ir.StmtStore) (bool, error) {
+	mask, ok := ir.StoreWriteMask(w.module, w.currentFunction, s)
+	if !ok || mask.ReadsTarget(w.currentFunction, s) {
+		return false, nil
+	}
+	vec, ok := w.getExpressionTypeInner(s.Value).(ir.VectorType)
+	if !ok {
+		return false, nil
+	}
+	for i, c := range mask.Targets {
+		varHandle, err := w.fillAccessChain(s.Pointer)
+		if err != nil {
+			return true, fmt.Errorf("storage store: %w", err)
+		}
+		// The chain runs leaf to root, so the component goes first.
+		component := subAccess{kind: subAccessOffset, offset: uint32(c) * uint32(vec.Scalar.Width)}
+		w.tempAccessChain = append([]subAccess{component}, w.tempAccessChain...)
+		sv := storeValue{kind: storeValueExpression, expr: mask.Values[i]}
+		if err := w.writeStorageStore(varHandle, sv, w.Indent, nil); err != nil {
+			return true, err
+		}
+	}
+	return true, nil
+}
+
 // writeMatCx2StoreCastIfNeeded checks if the store target is a struct member that
 // is matCx2 or array-of-matCx2, and if so writes a cast prefix.
 // Returns the closing string to write after the value (e.g. ")").
@@ -978,7 +1131,7 @@ func (w *Writer) writeMatCx2StoreCastIfNeeded(pointer ir.ExpressionHandle) strin
 	if w.currentFunction == nil {
 		return ""
 	}
-	m := w.getInnerMatrixOfStructArrayMember(pointer)
+	m := w.getInnerMatrixOfStructArrayMember(pointer, false)
 	if m == nil || !m.isMatCx2() {
 		return ""
 	}
@@ -1013,14 +1166,17 @@ func (w *Writer) writeMatCx2StoreCastIfNeeded(pointer ir.ExpressionHandle) strin
 }
 
 // getInnerMatrixOfStructArrayMember walks an access chain to find if the target
-// is a struct member that is matCx2 or array-of-matCx2.
+// is a struct member that is matCx2 or array-of-matCx2. With direct, handle
+// must reach the member through one of its matrices, as the base of a
+// column access does: the array member itself, or the struct, yields nil.
 // Matches Rust naga get_inner_matrix_of_struct_array_member.
-func (w *Writer) getInnerMatrixOfStructArrayMember(handle ir.ExpressionHandle) *matrixTypeInfo {
+func (w *Writer) getInnerMatrixOfStructArrayMember(handle ir.ExpressionHandle, direct bool) *matrixTypeInfo {
 	if w.currentFunction == nil || int(handle) >= len(w.currentFunction.Expressions) {
 		return nil
 	}
 
 	var arrayBase *ir.TypeHandle
+	var matData *matrixTypeInfo
 	current := handle
 	for {
 		if int(current) >= len(w.currentFunction.Expressions) {
@@ -1038,18 +1194,20 @@ func (w *Writer) getInnerMatrixOfStructArrayMember(handle ir.ExpressionHandle) *
 			}
 		}
 
-		switch resolved.(type) {
+		switch t := resolved.(type) {
 		case ir.MatrixType:
-			// Found matrix in chain
+			matData = &matrixTypeInfo{columns: t.Columns, rows: t.Rows, width: t.Scalar.Width}
 		case ir.ArrayType:
-			arr := resolved.(ir.ArrayType)
-			ab := arr.Base
+			ab := t.Base
 			arrayBase = &ab
 		case ir.StructType:
-			if arrayBase != nil {
-				return getInnerMatrixData(w.module, *arrayBase)
+			if arrayBase == nil {
+				return nil
 			}
-			return nil
+			if direct {
+				return matData
+			}
+			return getInnerMatrixData(w.module, *arrayBase)
 		default:
 			return nil
 		}
@@ -1100,7 +1258,7 @@ func (w *Writer) writeArrayMatCx2DynamicStoreIfNeeded(s ir.StmtStore) (bool, err
 		}
 		parentExpr := w.currentFunction.Expressions[parentBase].Kind
 		if acc, ok := parentExpr.(ir.ExprAccess); ok {
-			if m := w.getInnerMatrixOfStructArrayMember(acc.Base); m != nil && m.isMatCx2() {
+			if m := w.getInnerMatrixOfStructArrayMember(acc.Base, true); m != nil && m.isMatCx2() {
 				baseInner := w.getExpressionTypeInner(acc.Base)
 				if baseInner != nil {
 					if ptr, ok := baseInner.(ir.PointerType); ok {
@@ -1140,7 +1298,7 @@ func (w *Writer) writeArrayMatCx2DynamicStoreIfNeeded(s ir.StmtStore) (bool, err
 	}
 
 	// Check if this Access is on a matCx2 within array-of-matCx2
-	m := w.getInnerMatrixOfStructArrayMember(acc.Base)
+	m := w.getInnerMatrixOfStructArrayMember(acc.Base, true)
 	if m == nil || !m.isMatCx2() {
 		return false, nil
 	}
@@ -1159,6 +1317,20 @@ func (w *Writer) writeArrayMatCx2DynamicStoreIfNeeded(s ir.StmtStore) (bool, err
 	}
 
 	cols := uint8(m.columns)
+
+	if w.isGroupSharedPointer(acc.Base) {
+		base, err := w.writeExpressionToString(acc.Base)
+		if err != nil {
+			return true, err
+		}
+		column := func(i uint8) string { return fmt.Sprintf("%s._%d", base, i) }
+		var scalar *matCx2StoreIndex
+		if scalarInfo != nil {
+			scalar = &matCx2StoreIndex{isStatic: scalarInfo.isStatic, value: scalarInfo.dynamic, static_: scalarInfo.static_}
+		}
+		return true, w.writeMatCx2ColumnSwitch(cols, column, acc.Index, scalar, s.Value)
+	}
+
 	w.WriteIndent()
 
 	if scalarInfo != nil {
@@ -1204,6 +1376,79 @@ func (w *Writer) writeArrayMatCx2DynamicStoreIfNeeded(s ir.StmtStore) (bool, err
 	return true, nil
 }
 
+// isGroupSharedPointer reports whether pointer leads into a workgroup
+// global, directly or through a ptr<workgroup> argument. Stores there
+// cannot go through the inout setter helpers: the copy back writes the
+// whole struct, racing other invocations' stores.
+func (w *Writer) isGroupSharedPointer(pointer ir.ExpressionHandle) bool {
+	for int(pointer) < len(w.currentFunction.Expressions) {
+		switch e := w.currentFunction.Expressions[pointer].Kind.(type) {
+		case ir.ExprAccess:
+			pointer = e.Base
+		case ir.ExprAccessIndex:
+			pointer = e.Base
+		case ir.ExprGlobalVariable:
+			return int(e.Variable) < len(w.module.GlobalVariables) &&
+				w.module.GlobalVariables[e.Variable].Space == ir.SpaceWorkGroup
+		case ir.ExprFunctionArgument:
+			if int(e.Index) >= len(w.currentFunction.Arguments) {
+				return false
+			}
+			ptr, ok := w.module.Types[w.currentFunction.Arguments[e.Index].Type].Inner.(ir.PointerType)
+			return ok && ptr.Space == ir.SpaceWorkGroup
+		default:
+			return false
+		}
+	}
+	return false
+}
+
+// writeMatCx2ColumnStores stores the matrix value into the columns of a
+// decomposed matCx2, column(i) naming column i.
+func (w *Writer) writeMatCx2ColumnStores(columns uint8, column func(uint8) string, value ir.ExpressionHandle) error {
+	val, err := w.writeExpressionToString(value)
+	if err != nil {
+		return err
+	}
+	for i := uint8(0); i < columns; i++ {
+		w.WriteLine("%s = %s[%d];", column(i), val, i)
+	}
+	return nil
+}
+
+// writeMatCx2ColumnSwitch stores value into the column index of a
+// decomposed matCx2, or into its element scalar when scalar is set, as a
+// switch over the columns: the inline form of the setter helpers.
+func (w *Writer) writeMatCx2ColumnSwitch(columns uint8, column func(uint8) string, index ir.ExpressionHandle,
+	scalar *matCx2StoreIndex, value ir.ExpressionHandle) error {
+	idx, err := w.writeExpressionToString(index)
+	if err != nil {
+		return err
+	}
+	suffix := ""
+	if scalar != nil {
+		if scalar.isStatic {
+			suffix = fmt.Sprintf("[%d]", scalar.static_)
+		} else {
+			el, err := w.writeExpressionToString(scalar.value)
+			if err != nil {
+				return err
+			}
+			suffix = "[" + el + "]"
+		}
+	}
+	val, err := w.writeExpressionToString(value)
+	if err != nil {
+		return err
+	}
+	w.WriteLine("switch(%s) {", idx)
+	for i := uint8(0); i < columns; i++ {
+		w.WriteLine("case %d: { %s%s = %s; break; }", i, column(i), suffix, val)
+	}
+	w.WriteLine("}")
+	return nil
+}
+
 // matCx2StoreIndex represents an index access in the chain above a matCx2 member.
 type matCx2StoreIndex struct {
 	isStatic bool
@@ -1258,6 +1503,19 @@ func (w *Writer) writeMatCx2StoreIfNeeded(s ir.StmtStore) (bool, error) {
 	structName := w.typeNames[tyH]
 	fieldName := w.names[nameKey{kind: nameKeyStructMember, handle1: uint32(tyH), handle2: uint32(ai.Index)}]
 
+	if w.isGroupSharedPointer(ai.Base) && (vectorIdx == nil || !vectorIdx.isStatic) {
+		base, err := w.writeExpressionToString(ai.Base)
+		if err != nil {
+			return true, err
+		}
+		columns := uint8(w.module.Types[member.Type].Inner.(ir.MatrixType).Columns)
+		column := func(i uint8) string { return fmt.Sprintf("%s.%s_%d", base, fieldName, i) }
+		if vectorIdx == nil {
+			return true, w.writeMatCx2ColumnStores(columns, column, s.Value)
+		}
+		return true, w.writeMatCx2ColumnSwitch(columns, column, vectorIdx.value, scalarIdx, s.Value)
+	}
+
 	w.WriteIndent()
 
 	if vectorIdx == nil {
@@ -2044,7 +2302,7 @@ func (w *Writer) writeFunctionBody(fn *ir.Function) error {
 			// RayQuery<RAY_FLAG_NONE> rq; (no initialization)
 			fmt.Fprintf(&w.Out, "%s %s%s;\n", localType, localName, arraySuffix)
 		} else {
-			fmt.Fprintf(&w.Out, "%s %s%s = ", localType, localName, arraySuffix)
+			fmt.Fprintf(&w.Out, "%s%s %s%s = ", w.precisePrefixForType(local.Type), localType, localName, arraySuffix)
 			if local.Init != nil {
 				if err := w.writeExpression(*local.Init); err != nil {
 					return fmt.Errorf("local var init: %w", err)
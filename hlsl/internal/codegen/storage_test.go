@@ -1578,3 +1578,33 @@ func ptrVecSize(s ir.VectorSize) *ir.VectorSize {
 func ptrU32(v uint32) *uint32 {
 	return &v
 }
+
+func TestWriteMaskStores(t *testing.T) {
+	src := `
+@group(0) @binding(0) var<storage, read_write> colors: array<vec4<f32>>;
+
+@compute @workgroup_size(1)
+fn main() {
+    var v = colors[3];
+    let n = colors[1];
+    v = vec4<f32>(n.y, v.y, n.x, v.w);
+    colors[0] = vec4<f32>(colors[0].x, 2.0, colors[0].z, 3.0);
+    colors[2] = vec4<f32>(colors[2].y, colors[2].x, 1.0, colors[2].w);
+}
+`
+	plain := compileWGSLToHLSL(t, src, nil)
+	mustNotContain(t, plain, []string{"v.xz ="})
+
+	opts := DefaultOptions()
+	opts.FakeMissingBindings = true
+	opts.WriteMaskStores = true
+	code := compileWGSLToHLSL(t, src, opts)
+	mustContain(t, code, []string{
+		"v.xz = n.yx;",
+		// ByteAddressBuffer has no write mask: one Store per component.
+		"colors.Store(4+0, asuint(2.0));",
+		"colors.Store(12+0, asuint(3.0));",
+		// The swap reads what the first component store would change.
+		"colors.Store4(32, asuint(float4(_e32, _e36, 1.0, _e40)));",
+	})
+}
@@ -0,0 +1,101 @@
+package codegen
+
+import (
+	"testing"
+)
+
+const symbolsShader = `
+struct Light {
+    color: vec3<f32>,
+}
+
+const SCALE: f32 = 2.0;
+
+@group(0) @binding(0) var<uniform> light: Light;
+
+fn shade(c: vec3<f32>) -> vec3<f32> {
+    return c * SCALE;
+}
+
+@vertex
+fn main() -> @builtin(position) vec4<f32> {
+    return vec4<f32>(shade(light.color), 1.0);
+}
+
+@fragment
+fn fs_main() -> @location(0) vec4<f32> {
+    return vec4<f32>(shade(light.color), 1.0);
+}
+`
+
+func TestSymbolPrefixAndEntryPointNames(t *testing.T) {
+	module := parseWGSL(t, symbolsShader)
+	opts := DefaultOptions()
+	opts.FakeMissingBindings = true
+	opts.SymbolPrefix = "quad_"
+	opts.EntryPointNames = map[string]string{"main": "vs_main_quad"}
+
+	code, info, err := Compile(module, opts)
+	if err != nil {
+		t.Fatalf("Compile failed: %v", err)
+	}
+	mustContain(t, code, []string{
+		"struct quad_Light {",
+		"quad_shade(",
+		"quad_SCALE",
+		"cbuffer quad_light",
+		" vs_main_quad(",
+		" quad_fs_main(",
+	})
+	if got := info.EntryPointNames["main"]; got != "vs_main_quad" {
+		t.Errorf("EntryPointNames[main] = %q, want vs_main_quad", got)
+	}
+	if got := info.EntryPointNames["fs_main"]; got != "quad_fs_main" {
+		t.Errorf("EntryPointNames[fs_main] = %q, want quad_fs_main", got)
+	}
+}
+
+func TestEntryPointRenameReservedWord(t *testing.T) {
+	module := parseWGSL(t, symbolsShader)
+	opts := DefaultOptions()
+	opts.FakeMissingBindings = true
+	opts.EntryPointNames = map[string]string{"main": "cbuffer"}
+
+	_, info, err := Compile(module, opts)
+	if err != nil {
+		t.Fatalf("Compile failed: %v", err)
+	}
+	if got := info.EntryPointNames["main"]; got == "cbuffer" || got == "" {
+		t.Errorf("EntryPointNames[main] = %q, want escaped identifier", got)
+	}
+}
+
+func TestSymbolPrefixHelpers(t *testing.T) {
+	module := parseWGSL(t, `
+@group(0) @binding(0) var<storage, read_write> data: array<i32>;
+
+@compute @workgroup_size(1)
+fn main() {
+    let n = i32(arrayLength(&data));
+    data[0] = data[1] / n + data[2] % n;
+    data[3] = i32(modf(f32(n) * 0.5).whole);
+}
+`)
+	opts := DefaultOptions()
+	opts.FakeMissingBindings = true
+	opts.SymbolPrefix = "quad_"
+
+	code, _, err := Compile(module, opts)
+	if err != nil {
+		t.Fatalf("Compile failed: %v", err)
+	}
+	mustContain(t, code, []string{
+		" quad_naga_div(int lhs, int rhs)",
+		" quad_naga_mod(int lhs, int rhs)",
+		" quad_naga_modf(float arg)",
+		"uint quad_NagaBufferLengthRW(",
+		"quad_naga_div(",
+		"quad_naga_f2i32(",
+	})
+	mustNotContain(t, code, []string{" naga_div(", " naga_mod(", " naga_modf(", " NagaBufferLengthRW("})
+}
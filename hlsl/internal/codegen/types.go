@@ -9,6 +9,7 @@ import (
 	"strings"
 
 	"github.com/gogpu/naga/internal/backend"
+	"github.com/gogpu/naga/internal/textutil"
 	"github.com/gogpu/naga/ir"
 )
 
@@ -99,7 +100,7 @@ func (w *Writer) writeStructDefinition(handle ir.TypeHandle, _ string, st ir.Str
 		if member.Binding == nil && member.Offset > lastOffset {
 			padding := (member.Offset - lastOffset) / 4
 			for i := uint32(0); i < padding; i++ {
-				w.WriteLine("int _pad%d_%d;", memberIdx, i)
+				w.writePadding(handle, fmt.Sprintf("_pad%d_%d", memberIdx, i), memberIdx, lastOffset+4*i)
 			}
 		}
 
@@ -172,7 +173,7 @@ func (w *Writer) writeStructDefinition(handle ir.TypeHandle, _ string, st ir.Str
 	if len(st.Members) > 0 && st.Members[len(st.Members)-1].Binding == nil && st.Span > lastOffset {
 		padding := (st.Span - lastOffset) / 4
 		for i := uint32(0); i < padding; i++ {
-			w.WriteLine("int _end_pad_%d;", i)
+			w.writePadding(handle, fmt.Sprintf("_end_pad_%d", i), len(st.Members), lastOffset+4*i)
 		}
 	}
 
@@ -182,6 +183,15 @@ func (w *Writer) writeStructDefinition(handle ir.TypeHandle, _ string, st ir.Str
 	return nil
 }
 
+// writePadding writes a 4-byte int member named name at offset, before
+// member index before, and records it for TranslationInfo.Padding.
+func (w *Writer) writePadding(handle ir.TypeHandle, name string, before int, offset uint32) {
+	w.WriteLine("int %s;", name)
+	w.padding = append(w.padding, ir.StructPadding{
+		Struct: handle, Name: name, Before: before, Offset: offset, Size: 4,
+	})
+}
+
 // locationSemantic is the prefix for user-defined location semantics
 // (matches Rust naga). Sourced from internal/backend so HLSL and DXIL
 // share a single source of truth — see BUG-DXIL-028 for why drift here
@@ -336,6 +346,9 @@ func (w *Writer) typeToHLSLWithArraySuffix(typ *ir.Type) (typeName, arraySuffix
 		return scalarTypeToHLSL(inner), ""
 
 	case ir.VectorType:
+		if w.hlsl2021() {
+			return w.vectorTypeName(inner.Scalar, uint8(inner.Size)), ""
+		}
 		return vectorTypeToHLSL(inner), ""
 
 	case ir.MatrixType:
@@ -811,11 +824,11 @@ func (w *Writer) writeScalarValue(v ir.ScalarValue, typeHandle ir.TypeHandle) st
 
 		if width == 4 {
 			floatVal := float32FromBits(uint32(v.Bits))
-			return formatFloat32(floatVal)
+			return formatFloat32(floatVal, w.options.FloatPrecision)
 		}
 		// 64-bit double: add L suffix
 		floatVal := math.Float64frombits(v.Bits)
-		return formatFloat64(floatVal) + "L"
+		return formatFloat64(floatVal, w.options.FloatPrecision) + "L"
 
 	default:
 		return "0"
@@ -908,9 +921,12 @@ func (w *Writer) writeGlobalVariable(name string, global *ir.GlobalVariable) err
 		w.WriteLine("groupshared %s %s%s;", baseTypeName, name, arraySuffix)
 
 	case ir.SpacePrivate:
-		// Module-scope private variable (Rust naga always initializes)
+		// Module-scope private variable (Rust naga always initializes).
+		// HLSL statics are per-invocation, so each entry point gets its own
+		// copy initialized on entry. Arrays need the suffix after the name.
+		baseTypeName, arraySuffix := w.getTypeNameWithArraySuffix(global.Type)
 		w.WriteIndent()
-		fmt.Fprintf(&w.Out, "static %s %s = ", typeName, name)
+		fmt.Fprintf(&w.Out, "static %s %s%s = ", baseTypeName, name, arraySuffix)
 		if global.InitExpr != nil {
 			if err := w.writeGlobalConstExpression(*global.InitExpr); err != nil {
 				return err
@@ -1103,8 +1119,10 @@ func structsEqual(a, b ir.StructType) bool {
 	return true
 }
 
-// formatFloat32 formats a float32 for HLSL output.
-func formatFloat32(f float32) string {
+// formatFloat32 formats a float32 for HLSL output with precision
+// significant digits, or the shortest round-trip digits if precision is
+// zero. Finite values match Rust {:?} format (1e-7, 3.4028235e38).
+func formatFloat32(f float32, precision int) string {
 	if math.IsInf(float64(f), 1) {
 		return "1.#INF"
 	}
@@ -1114,19 +1132,11 @@ func formatFloat32(f float32) string {
 	if math.IsNaN(float64(f)) {
 		return "0.0/0.0"
 	}
-	// Use %g for compact representation, ensure decimal point for floats
-	s := fmt.Sprintf("%g", f)
-	if !strings.Contains(s, ".") && !strings.Contains(s, "e") && !strings.Contains(s, "E") {
-		s += ".0"
-	}
-	// Remove '+' from exponent to match Rust {:?} format (e.g., "e+38" -> "e38")
-	s = strings.Replace(s, "e+", "e", 1)
-	s = strings.Replace(s, "E+", "E", 1)
-	return s
+	return textutil.FormatFloat(float64(f), 32, precision)
 }
 
 // formatFloat64 formats a float64 for HLSL output.
-func formatFloat64(f float64) string {
+func formatFloat64(f float64, precision int) string {
 	if math.IsInf(f, 1) {
 		return "1.#INF"
 	}
@@ -1136,14 +1146,7 @@ func formatFloat64(f float64) string {
 	if math.IsNaN(f) {
 		return "0.0/0.0"
 	}
-	s := fmt.Sprintf("%g", f)
-	if !strings.Contains(s, ".") && !strings.Contains(s, "e") && !strings.Contains(s, "E") {
-		s += ".0"
-	}
-	// Remove '+' from exponent to match Rust {:?} format (e.g., "e+308" -> "e308")
-	s = strings.Replace(s, "e+", "e", 1)
-	s = strings.Replace(s, "E+", "E", 1)
-	return s
+	return textutil.FormatFloat(f, 64, precision)
 }
 
 // float32FromBits converts uint32 bits to float32.
@@ -1217,7 +1220,11 @@ func (w *Writer) writeArraySizes(handle ir.TypeHandle) {
 }
 
 // vectorTypeName returns the HLSL type name for a vector with the given scalar and size.
+// HLSL 2021 output spells it as the vector<T, N> template.
 func (w *Writer) vectorTypeName(scalar ir.ScalarType, size uint8) string {
+	if w.hlsl2021() {
+		return fmt.Sprintf("vector<%s, %d>", scalarTypeToHLSL(scalar), size)
+	}
 	return fmt.Sprintf("%s%d", scalarTypeHLSL(scalar), size)
 }
 
@@ -1416,7 +1423,8 @@ func (m *matrixTypeInfo) isMatCx2() bool {
 }
 
 // writeMatCx2TypedefAndFunctions writes the __matCx2 typedef and helper functions.
-// Matches Rust naga help.rs write_mat_cx2_typedef_and_functions.
+// Matches Rust naga help.rs write_mat_cx2_typedef_and_functions, except that
+// the setters take the matrix inout: passed by value, the store is lost.
 func (w *Writer) writeMatCx2TypedefAndFunctions(columns uint8) {
 	// typedef struct { float2 _0; float2 _1; ... } __matCx2;
 	w.Out.WriteString("typedef struct { ")
@@ -1436,7 +1444,7 @@ func (w *Writer) writeMatCx2TypedefAndFunctions(columns uint8) {
 	w.Out.WriteString("}\n")
 
 	// __set_col_of_matCx2
-	fmt.Fprintf(&w.Out, "void __set_col_of_mat%dx2(__mat%dx2 mat, uint idx, float2 value) {\n", columns, columns)
+	fmt.Fprintf(&w.Out, "void __set_col_of_mat%dx2(inout __mat%dx2 mat, uint idx, float2 value) {\n", columns, columns)
 	w.Out.WriteString("    switch(idx) {\n")
 	for i := uint8(0); i < columns; i++ {
 		fmt.Fprintf(&w.Out, "    case %d: { mat._%d = value; break; }\n", i, i)
@@ -1445,7 +1453,7 @@ func (w *Writer) writeMatCx2TypedefAndFunctions(columns uint8) {
 	w.Out.WriteString("}\n")
 
 	// __set_el_of_matCx2
-	fmt.Fprintf(&w.Out, "void __set_el_of_mat%dx2(__mat%dx2 mat, uint idx, uint vec_idx, float value) {\n", columns, columns)
+	fmt.Fprintf(&w.Out, "void __set_el_of_mat%dx2(inout __mat%dx2 mat, uint idx, uint vec_idx, float value) {\n", columns, columns)
 	w.Out.WriteString("    switch(idx) {\n")
 	for i := uint8(0); i < columns; i++ {
 		fmt.Fprintf(&w.Out, "    case %d: { mat._%d[vec_idx] = value; break; }\n", i, i)
@@ -1494,7 +1502,8 @@ func (w *Writer) writeAllMatCx2TypedefsAndFunctions() {
 
 // writeWrappedStructMatrixAccessFunctions writes GetMat/SetMat/SetMatVec/SetMatScalar
 // helper functions for a matCx2 member in a struct, if not already written.
-// Matches Rust naga help.rs write_wrapped_struct_matrix_* functions.
+// Matches Rust naga help.rs write_wrapped_struct_matrix_* functions, except
+// that the setters take the struct inout so their stores reach the caller.
 func (w *Writer) writeWrappedStructMatrixAccessFunctions(tyHandle ir.TypeHandle, memberIndex uint32) {
 	key := wrappedStructMatrixAccessKey{ty: tyHandle, index: memberIndex}
 	if _, done := w.wrappedStructMatrixAccess[key]; done {
@@ -1530,14 +1539,14 @@ func (w *Writer) writeWrappedStructMatrixAccessFunctions(tyHandle ir.TypeHandle,
 	w.Out.WriteString(");\n}\n\n")
 
 	// SetMat{field}On{struct}
-	fmt.Fprintf(&w.Out, "void SetMat%sOn%s(%s obj, %s mat) {\n", fieldName, structName, structName, matTypeName)
+	fmt.Fprintf(&w.Out, "void SetMat%sOn%s(inout %s obj, %s mat) {\n", fieldName, structName, structName, matTypeName)
 	for i := uint8(0); i < uint8(mat.Columns); i++ {
 		fmt.Fprintf(&w.Out, "    obj.%s_%d = mat[%d];\n", fieldName, i, i)
 	}
 	w.Out.WriteString("}\n\n")
 
 	// SetMatVec{field}On{struct}
-	fmt.Fprintf(&w.Out, "void SetMatVec%sOn%s(%s obj, %s vec, uint mat_idx) {\n", fieldName, structName, structName, vecTypeName)
+	fmt.Fprintf(&w.Out, "void SetMatVec%sOn%s(inout %s obj, %s vec, uint mat_idx) {\n", fieldName, structName, structName, vecTypeName)
 	w.Out.WriteString("    switch(mat_idx) {\n")
 	for i := uint8(0); i < uint8(mat.Columns); i++ {
 		fmt.Fprintf(&w.Out, "    case %d: { obj.%s_%d = vec; break; }\n", i, fieldName, i)
@@ -1545,7 +1554,7 @@ func (w *Writer) writeWrappedStructMatrixAccessFunctions(tyHandle ir.TypeHandle,
 	w.Out.WriteString("    }\n}\n\n")
 
 	// SetMatScalar{field}On{struct}
-	fmt.Fprintf(&w.Out, "void SetMatScalar%sOn%s(%s obj, %s scalar, uint mat_idx, uint vec_idx) {\n", fieldName, structName, structName, scalarTypeName)
+	fmt.Fprintf(&w.Out, "void SetMatScalar%sOn%s(inout %s obj, %s scalar, uint mat_idx, uint vec_idx) {\n", fieldName, structName, structName, scalarTypeName)
 	w.Out.WriteString("    switch(mat_idx) {\n")
 	for i := uint8(0); i < uint8(mat.Columns); i++ {
 		fmt.Fprintf(&w.Out, "    case %d: { obj.%s_%d[vec_idx] = scalar; break; }\n", i, fieldName, i)
@@ -4,6 +4,7 @@
 package codegen
 
 import (
+	"math"
 	"strings"
 	"testing"
 
@@ -277,23 +278,29 @@ func TestStructsEqual(t *testing.T) {
 // TestFormatFloat32 tests float32 formatting.
 func TestFormatFloat32(t *testing.T) {
 	tests := []struct {
-		name     string
-		value    float32
-		expected string
+		name      string
+		value     float32
+		precision int
+		expected  string
 	}{
-		{"zero", 0.0, "0.0"},
-		{"one", 1.0, "1.0"},
-		{"negative", -1.0, "-1.0"},
-		{"small", 0.5, "0.5"},
-		{"large", 1000000.0, "1e06"},
-		{"small_exp", 0.0001, "0.0001"},
+		{"zero", 0.0, 0, "0.0"},
+		{"one", 1.0, 0, "1.0"},
+		{"negative", -1.0, 0, "-1.0"},
+		{"small", 0.5, 0, "0.5"},
+		{"large", 1000000.0, 0, "1000000.0"},
+		{"small_exp", 0.0001, 0, "0.0001"},
+		{"tiny", 1e-7, 0, "1e-7"},
+		{"huge", 3e38, 0, "3e38"},
+		{"shortest", 0.1, 0, "0.1"},
+		{"precision", 0.1, 10, "0.1000000015"},
+		{"infinity", float32(math.Inf(1)), 3, "1.#INF"},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			got := formatFloat32(tt.value)
+			got := formatFloat32(tt.value, tt.precision)
 			if got != tt.expected {
-				t.Errorf("formatFloat32(%v) = %q, want %q", tt.value, got, tt.expected)
+				t.Errorf("formatFloat32(%v, %d) = %q, want %q", tt.value, tt.precision, got, tt.expected)
 			}
 		})
 	}
@@ -310,12 +317,13 @@ func TestFormatFloat64(t *testing.T) {
 		{"one", 1.0, "1.0"},
 		{"negative", -1.0, "-1.0"},
 		{"small", 0.5, "0.5"},
-		{"large", 1e15, "1e15"},
+		{"large", 1e15, "1000000000000000.0"},
+		{"exponent", 1e300, "1e300"},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			got := formatFloat64(tt.value)
+			got := formatFloat64(tt.value, 0)
 			if got != tt.expected {
 				t.Errorf("formatFloat64(%v) = %q, want %q", tt.value, got, tt.expected)
 			}
@@ -853,11 +861,11 @@ func TestWriteMatCx2TypedefAndFunctions(t *testing.T) {
 		t.Error("missing __get_col_of_mat3x2")
 	}
 	// Check column setter
-	if !strings.Contains(output, "void __set_col_of_mat3x2(__mat3x2 mat, uint idx, float2 value)") {
+	if !strings.Contains(output, "void __set_col_of_mat3x2(inout __mat3x2 mat, uint idx, float2 value)") {
 		t.Error("missing __set_col_of_mat3x2")
 	}
 	// Check element setter
-	if !strings.Contains(output, "void __set_el_of_mat3x2(__mat3x2 mat, uint idx, uint vec_idx, float value)") {
+	if !strings.Contains(output, "void __set_el_of_mat3x2(inout __mat3x2 mat, uint idx, uint vec_idx, float value)") {
 		t.Error("missing __set_el_of_mat3x2")
 	}
 }
@@ -887,13 +895,13 @@ func TestWriteWrappedStructMatrixAccessFunctions(t *testing.T) {
 	if !strings.Contains(out, "float3x2 GetMatmOnBaz(Baz obj)") {
 		t.Error("missing GetMatmOnBaz")
 	}
-	if !strings.Contains(out, "void SetMatmOnBaz(Baz obj, float3x2 mat)") {
+	if !strings.Contains(out, "void SetMatmOnBaz(inout Baz obj, float3x2 mat)") {
 		t.Error("missing SetMatmOnBaz")
 	}
-	if !strings.Contains(out, "void SetMatVecmOnBaz(Baz obj, float2 vec, uint mat_idx)") {
+	if !strings.Contains(out, "void SetMatVecmOnBaz(inout Baz obj, float2 vec, uint mat_idx)") {
 		t.Error("missing SetMatVecmOnBaz")
 	}
-	if !strings.Contains(out, "void SetMatScalarmOnBaz(Baz obj, float scalar, uint mat_idx, uint vec_idx)") {
+	if !strings.Contains(out, "void SetMatScalarmOnBaz(inout Baz obj, float scalar, uint mat_idx, uint vec_idx)") {
 		t.Error("missing SetMatScalarmOnBaz")
 	}
 
@@ -4,10 +4,12 @@
 package codegen
 
 import (
+	"context"
 	"fmt"
 	"sort"
 	"strings"
 
+	"github.com/gogpu/naga/internal/backend"
 	"github.com/gogpu/naga/internal/textutil"
 	"github.com/gogpu/naga/ir"
 )
@@ -76,8 +78,10 @@ type epStructMember struct {
 type Writer struct {
 	textutil.IndentWriter // provides Out, Indent, WriteLine, WriteIndent, PushIndent, PopIndent
 
+	ctx     context.Context // checked before each function is written
 	module  *ir.Module
 	options *Options
+	notes   *backend.Notes
 
 	// Name management
 	names map[nameKey]string
@@ -105,6 +109,7 @@ type Writer struct {
 	entryPointNames     map[string]string
 	registerBindings    map[string]string
 	helperFunctions     []string
+	padding             []ir.StructPadding
 	usedFeatures        FeatureFlags
 	requiredShaderModel ShaderModel
 
@@ -262,8 +267,10 @@ type epResultInfo struct {
 // newWriter creates a new HLSL writer.
 func newWriter(module *ir.Module, options *Options) *Writer {
 	return &Writer{
+		ctx:                         context.Background(),
 		module:                      module,
 		options:                     options,
+		notes:                       backend.NewNotes(options.Logger, "hlsl"),
 		names:                       make(map[nameKey]string),
 		namer:                       newNamer(),
 		typeNames:                   make(map[ir.TypeHandle]string),
@@ -395,8 +402,29 @@ func (w *Writer) writeHeader() {
 	// No header — matches Rust naga HLSL output
 }
 
+// helperName returns the name of a generated helper function, which
+// carries SymbolPrefix like the module's own functions.
+func (w *Writer) helperName(name string) string {
+	if w.options == nil {
+		return name
+	}
+	return w.options.SymbolPrefix + name
+}
+
 // registerNames assigns unique names to all IR entities.
 func (w *Writer) registerNames() error {
+	if w.options.SymbolPrefix != "" {
+		// A prefixed WGSL symbol must not take a prefixed helper's name.
+		for _, name := range []string{
+			NagaModfFunction, NagaFrexpFunction, NagaExtractBitsFunction, NagaInsertBitsFunction,
+			NagaAbsFunction, NagaDivFunction, NagaModFunction, "naga_neg",
+			"naga_f2i32", "naga_f2u32", "naga_f2i64", "naga_f2u64",
+			"NagaBufferLength", "NagaBufferLengthRW",
+			"nagaTextureSampleBaseClampToEdge", "nagaTextureLoadExternal",
+		} {
+			w.namer.reserve(w.helperName(name))
+		}
+	}
 	// Register type names
 	for handle := range w.module.Types {
 		typ := &w.module.Types[handle]
@@ -406,7 +434,7 @@ func (w *Writer) registerNames() error {
 		} else {
 			baseName = fmt.Sprintf("type_%d", handle)
 		}
-		name := w.namer.call(baseName)
+		name := w.namer.call(w.options.SymbolPrefix + baseName)
 		w.names[nameKey{kind: nameKeyType, handle1: uint32(handle)}] = name
 		w.typeNames[ir.TypeHandle(handle)] = name
 
@@ -444,7 +472,7 @@ func (w *Writer) registerNames() error {
 		if w.options.EntryPoint != "" && ep.Name != w.options.EntryPoint {
 			continue
 		}
-		name := w.namer.call(ep.Name)
+		name := w.namer.call(backend.EntryPointBase(w.options.EntryPointNames, w.options.SymbolPrefix, ep.Name))
 		w.names[nameKey{kind: nameKeyEntryPoint, handle1: uint32(epIdx)}] = name
 		w.entryPointNames[ep.Name] = name
 
@@ -468,7 +496,7 @@ func (w *Writer) registerNames() error {
 		} else {
 			baseName = fmt.Sprintf("function_%d", handle)
 		}
-		name := w.namer.call(baseName)
+		name := w.namer.call(w.options.SymbolPrefix + baseName)
 		w.names[nameKey{kind: nameKeyFunction, handle1: uint32(handle)}] = name
 
 		for argIdx, arg := range fn.Arguments {
@@ -490,7 +518,7 @@ func (w *Writer) registerNames() error {
 		} else {
 			baseName = fmt.Sprintf("global_%d", handle)
 		}
-		name := w.namer.call(baseName)
+		name := w.namer.call(w.options.SymbolPrefix + baseName)
 		w.names[nameKey{kind: nameKeyGlobalVariable, handle1: uint32(handle)}] = name
 	}
 
@@ -499,8 +527,9 @@ func (w *Writer) registerNames() error {
 		constant := &w.module.Constants[handle]
 		var baseName string
 		if constant.Name != "" {
-			baseName = constant.Name
+			baseName = w.options.SymbolPrefix + constant.Name
 		} else {
+			// The type name already carries SymbolPrefix.
 			typeName := w.typeNames[constant.Type]
 			baseName = fmt.Sprintf("const_%s", typeName)
 		}
@@ -817,35 +846,35 @@ func (w *Writer) writeDynamicBufferOffsets() {
 func (w *Writer) writeSpecialHelperFunctions() {
 	if w.needsModHelper {
 		w.WriteLine("// Safe modulo helper (truncated division semantics)")
-		w.WriteLine("int %s(int a, int b) {", NagaModFunction)
+		w.WriteLine("int %s(int a, int b) {", w.helperName(NagaModFunction))
 		w.PushIndent()
 		w.WriteLine("return a - b * (a / b);")
 		w.PopIndent()
 		w.WriteLine("}")
 		w.WriteLine("")
-		w.helperFunctions = append(w.helperFunctions, NagaModFunction)
+		w.helperFunctions = append(w.helperFunctions, w.helperName(NagaModFunction))
 	}
 
 	if w.needsDivHelper {
 		w.WriteLine("// Safe division helper (handles zero divisor)")
-		w.WriteLine("int %s(int a, int b) {", NagaDivFunction)
+		w.WriteLine("int %s(int a, int b) {", w.helperName(NagaDivFunction))
 		w.PushIndent()
 		w.WriteLine("return b != 0 ? a / b : 0;")
 		w.PopIndent()
 		w.WriteLine("}")
 		w.WriteLine("")
-		w.helperFunctions = append(w.helperFunctions, NagaDivFunction)
+		w.helperFunctions = append(w.helperFunctions, w.helperName(NagaDivFunction))
 	}
 
 	if w.needsAbsHelper {
 		w.WriteLine("// Safe abs helper (handles INT_MIN)")
-		w.WriteLine("int %s(int v) {", NagaAbsFunction)
+		w.WriteLine("int %s(int v) {", w.helperName(NagaAbsFunction))
 		w.PushIndent()
 		w.WriteLine("return v >= 0 ? v : (v == -2147483648 ? 2147483647 : -v);")
 		w.PopIndent()
 		w.WriteLine("}")
 		w.WriteLine("")
-		w.helperFunctions = append(w.helperFunctions, NagaAbsFunction)
+		w.helperFunctions = append(w.helperFunctions, w.helperName(NagaAbsFunction))
 	}
 }
 
@@ -991,7 +1020,7 @@ func (w *Writer) handleArrayLengthHelper(fn *ir.Function, alExpr ir.ExprArrayLen
 	if writable {
 		accessStr = "RW"
 	}
-	fmt.Fprintf(&w.Out, "uint NagaBufferLength%s(%sByteAddressBuffer buffer)\n", accessStr, accessStr)
+	fmt.Fprintf(&w.Out, "uint %s%s(%sByteAddressBuffer buffer)\n", w.helperName("NagaBufferLength"), accessStr, accessStr)
 	fmt.Fprintf(&w.Out, "{\n")
 	fmt.Fprintf(&w.Out, "    uint ret;\n")
 	fmt.Fprintf(&w.Out, "    buffer.GetDimensions(ret);\n")
@@ -1005,13 +1034,14 @@ func (w *Writer) handleClampToEdgeHelper(fn *ir.Function, is ir.ExprImageSample)
 		return
 	}
 	w.clampToEdgeHelperWritten = true
+	w.notes.Polyfill("textureSampleBaseClampToEdge")
 
 	// Check if the image is an external texture
 	imgType := w.resolveImageTypeFromFn(fn, is.Image)
 	if imgType != nil && imgType.Class == ir.ImageClassExternal {
 		w.writeExternalTextureSampleHelper()
 	} else {
-		w.Out.WriteString("float4 nagaTextureSampleBaseClampToEdge(Texture2D<float4> tex, SamplerState samp, float2 coords) {\n")
+		fmt.Fprintf(&w.Out, "float4 %s(Texture2D<float4> tex, SamplerState samp, float2 coords) {\n", w.helperName("nagaTextureSampleBaseClampToEdge"))
 		w.Out.WriteString("    float2 size;\n")
 		w.Out.WriteString("    tex.GetDimensions(size.x, size.y);\n")
 		w.Out.WriteString("    float2 half_texel = float2(0.5, 0.5) / size;\n")
@@ -1304,27 +1334,25 @@ func (w *Writer) writeWrappedImageQueryFunction(key wrappedImageQueryKey, imgTyp
 	var retSwizzle string
 	var numParams int
 
+	// GetDimensions writes the spatial dimensions, then the element count
+	// of an array, then the mip level count or sample count.
+	dimCoords := 2
+	switch key.dim {
+	case ir.Dim1D:
+		dimCoords = 1
+	case ir.Dim3D:
+		dimCoords = 3
+	}
+	numParams = dimCoords + arrayCoords + extraCoords
+
 	switch key.query {
 	case imageQuerySize, imageQuerySizeLevel:
-		switch key.dim {
-		case ir.Dim1D:
-			retSwizzle = "x"
-		case ir.Dim2D:
-			retSwizzle = "xy"
-		case ir.Dim3D:
-			retSwizzle = "xyz"
-		case ir.DimCube:
-			retSwizzle = "xy"
-		}
-		numParams = len(retSwizzle) + arrayCoords + extraCoords
-	case imageQueryNumLevels, imageQueryNumSamples, imageQueryNumLayers:
-		if key.arrayed || key.dim == ir.Dim3D {
-			retSwizzle = "w"
-			numParams = 4
-		} else {
-			retSwizzle = "z"
-			numParams = 3
-		}
+		retSwizzle = "xyz"[:dimCoords]
+	case imageQueryNumLayers:
+		retSwizzle = components[dimCoords]
+	case imageQueryNumLevels, imageQueryNumSamples:
+		numParams = dimCoords + arrayCoords + 1
+		retSwizzle = components[numParams-1]
 	}
 
 	// Write return type
@@ -1413,11 +1441,12 @@ func (w *Writer) writeClampToEdgeHelper(fn *ir.Function) {
 	}
 
 	w.clampToEdgeHelperWritten = true
+	w.notes.Polyfill("textureSampleBaseClampToEdge")
 
 	if isExternal {
 		w.writeExternalTextureSampleHelper()
 	} else {
-		w.Out.WriteString("float4 nagaTextureSampleBaseClampToEdge(Texture2D<float4> tex, SamplerState samp, float2 coords) {\n")
+		fmt.Fprintf(&w.Out, "float4 %s(Texture2D<float4> tex, SamplerState samp, float2 coords) {\n", w.helperName("nagaTextureSampleBaseClampToEdge"))
 		w.Out.WriteString("    float2 size;\n")
 		w.Out.WriteString("    tex.GetDimensions(size.x, size.y);\n")
 		w.Out.WriteString("    float2 half_texel = float2(0.5, 0.5) / size;\n")
@@ -1467,7 +1496,7 @@ func (w *Writer) writeWrappedUnaryOps(fn *ir.Function) {
 		w.wrappedNegOps[typeStr] = struct{}{}
 
 		// Write the naga_neg helper
-		fmt.Fprintf(&w.Out, "%s naga_neg(%s val) {\n", typeStr, typeStr)
+		fmt.Fprintf(&w.Out, "%s %s(%s val) {\n", typeStr, w.helperName("naga_neg"), typeStr)
 		fmt.Fprintf(&w.Out, "    return asint(-asuint(val));\n")
 		fmt.Fprintf(&w.Out, "}\n\n")
 	}
@@ -1525,7 +1554,7 @@ func (w *Writer) writeWrappedMathHelpers(fn *ir.Function) {
 		argTypeName := w.getTypeName(argTypeHandle)
 
 		if isModf {
-			fmt.Fprintf(&w.Out, "%s naga_modf(%s arg) {\n", resultStructName, argTypeName)
+			fmt.Fprintf(&w.Out, "%s %s(%s arg) {\n", resultStructName, w.helperName(NagaModfFunction), argTypeName)
 			fmt.Fprintf(&w.Out, "    %s other;\n", argTypeName)
 			fmt.Fprintf(&w.Out, "    %s result;\n", resultStructName)
 			fmt.Fprintf(&w.Out, "    result.fract = modf(arg, other);\n")
@@ -1534,7 +1563,7 @@ func (w *Writer) writeWrappedMathHelpers(fn *ir.Function) {
 			fmt.Fprintf(&w.Out, "}\n\n")
 		} else {
 			// frexp: result.fract = sign(arg) * frexp(arg, other)
-			fmt.Fprintf(&w.Out, "%s naga_frexp(%s arg) {\n", resultStructName, argTypeName)
+			fmt.Fprintf(&w.Out, "%s %s(%s arg) {\n", resultStructName, w.helperName(NagaFrexpFunction), argTypeName)
 			fmt.Fprintf(&w.Out, "    %s other;\n", argTypeName)
 			fmt.Fprintf(&w.Out, "    %s result;\n", resultStructName)
 			fmt.Fprintf(&w.Out, "    result.fract = sign(arg) * frexp(arg, other);\n")
@@ -1666,8 +1695,10 @@ func (w *Writer) writeWrappedBinaryOps(fn *ir.Function) {
 
 		switch binExpr.Op {
 		case ir.BinaryDivide:
+			w.notes.Polyfill("integer division")
 			w.writeNagaDivHelper(typeName, leftTypeName, rightTypeName, scalar)
 		case ir.BinaryModulo:
+			w.notes.Polyfill("modulo")
 			w.writeNagaModHelper(typeName, leftTypeName, rightTypeName, scalar)
 		}
 	}
@@ -1734,7 +1765,7 @@ func (w *Writer) writeWrappedCastFunctions(fn *ir.Function) {
 		// Get min/max clamp values based on source float type and destination int type
 		minVal, maxVal := f2iClampValues(srcScalar.Width, asExpr.Kind, dstWidth)
 
-		fmt.Fprintf(&w.Out, "%s %s(%s value) {\n", dstTypeStr, f2iCastFuncName(asExpr.Kind, dstWidth), srcTypeStr)
+		fmt.Fprintf(&w.Out, "%s %s(%s value) {\n", dstTypeStr, w.helperName(f2iCastFuncName(asExpr.Kind, dstWidth)), srcTypeStr)
 		fmt.Fprintf(&w.Out, "    return %s(clamp(value, %s, %s));\n", dstTypeStr, minVal, maxVal)
 		fmt.Fprintf(&w.Out, "}\n\n")
 	}
@@ -1813,13 +1844,10 @@ func f2iClampValues(srcWidth uint8, dstKind ir.ScalarKind, dstWidth uint8) (stri
 // Matches Rust naga's write_wrapped_binary_ops for BinaryOperator::Divide.
 func (w *Writer) writeNagaDivHelper(retType, leftType, rightType string, scalar *ir.ScalarType) {
 	w.WriteIndent()
-	fmt.Fprintf(&w.Out, "%s %s(%s lhs, %s rhs) {\n", retType, NagaDivFunction, leftType, rightType)
+	fmt.Fprintf(&w.Out, "%s %s(%s lhs, %s rhs) {\n", retType, w.helperName(NagaDivFunction), leftType, rightType)
 	switch scalar.Kind {
-	case ir.ScalarUint:
-		fmt.Fprintf(&w.Out, "    return lhs / (rhs == 0u ? 1u : rhs);\n")
-	case ir.ScalarSint:
-		minVal := i32MinLiteral(scalar.Width)
-		fmt.Fprintf(&w.Out, "    return lhs / (((lhs == %s & rhs == -1) | (rhs == 0)) ? 1 : rhs);\n", minVal)
+	case ir.ScalarUint, ir.ScalarSint:
+		fmt.Fprintf(&w.Out, "    return lhs / %s;\n", w.safeDivisorOperand(rightType, scalar))
 	}
 	w.Out.WriteString("}\n\n")
 }
@@ -1828,14 +1856,13 @@ func (w *Writer) writeNagaDivHelper(retType, leftType, rightType string, scalar
 // Matches Rust naga's write_wrapped_binary_ops for BinaryOperator::Modulo.
 func (w *Writer) writeNagaModHelper(retType, leftType, rightType string, scalar *ir.ScalarType) {
 	w.WriteIndent()
-	fmt.Fprintf(&w.Out, "%s %s(%s lhs, %s rhs) {\n", retType, NagaModFunction, leftType, rightType)
+	fmt.Fprintf(&w.Out, "%s %s(%s lhs, %s rhs) {\n", retType, w.helperName(NagaModFunction), leftType, rightType)
 	switch scalar.Kind {
 	case ir.ScalarUint:
-		fmt.Fprintf(&w.Out, "    return lhs %% (rhs == 0u ? 1u : rhs);\n")
+		fmt.Fprintf(&w.Out, "    return lhs %% %s;\n", w.safeDivisorOperand(rightType, scalar))
 	case ir.ScalarSint:
-		minVal := i32MinLiteral(scalar.Width)
 		// Use right_type for divisor (e.g., int4 for vector ops)
-		fmt.Fprintf(&w.Out, "    %s divisor = ((lhs == %s & rhs == -1) | (rhs == 0)) ? 1 : rhs;\n", rightType, minVal)
+		fmt.Fprintf(&w.Out, "    %s divisor = %s;\n", rightType, w.safeDivisor(rightType, scalar))
 		fmt.Fprintf(&w.Out, "    return lhs - (lhs / divisor) * divisor;\n")
 	case ir.ScalarFloat:
 		fmt.Fprintf(&w.Out, "    return lhs - rhs * trunc(lhs / rhs);\n")
@@ -1843,6 +1870,34 @@ func (w *Writer) writeNagaModHelper(retType, leftType, rightType string, scalar
 	w.Out.WriteString("}\n\n")
 }
 
+// safeDivisor returns the expression naga_div and naga_mod divide lhs by:
+// rhs, or 1 where rhs is zero or, for signed integers, where the quotient
+// of the minimum value by -1 would overflow. HLSL 2021 has no &, | or ?:
+// on bool vectors, so vector helpers use and(), or() and select() there.
+func (w *Writer) safeDivisor(rightType string, scalar *ir.ScalarType) string {
+	vector := rightType != scalarToHLSLStr(*scalar)
+	if scalar.Kind == ir.ScalarUint {
+		if vector && w.hlsl2021() {
+			return fmt.Sprintf("select(rhs == 0u, (%s)1u, rhs)", rightType)
+		}
+		return "rhs == 0u ? 1u : rhs"
+	}
+	minVal := i32MinLiteral(scalar.Width)
+	if vector && w.hlsl2021() {
+		return fmt.Sprintf("select(or(and(lhs == %s, rhs == -1), rhs == 0), (%s)1, rhs)", minVal, rightType)
+	}
+	return fmt.Sprintf("((lhs == %s & rhs == -1) | (rhs == 0)) ? 1 : rhs", minVal)
+}
+
+// safeDivisorOperand returns safeDivisor as the operand of a division.
+func (w *Writer) safeDivisorOperand(rightType string, scalar *ir.ScalarType) string {
+	divisor := w.safeDivisor(rightType, scalar)
+	if strings.Contains(divisor, "?") {
+		return "(" + divisor + ")"
+	}
+	return divisor
+}
+
 // i32MinLiteral returns the HLSL representation of the minimum signed integer value.
 // Uses int(-2147483647 - 1) to avoid compiler parsing issues with -2147483648.
 // Matches Rust naga's write_literal for Literal::I32(i32::MIN).
@@ -1874,6 +1929,9 @@ func (w *Writer) typeInnerToHLSLStr(inner ir.TypeInner) string {
 	case ir.ScalarType:
 		return scalarToHLSLStr(t)
 	case ir.VectorType:
+		if w.hlsl2021() {
+			return w.vectorTypeName(t.Scalar, uint8(t.Size))
+		}
 		return fmt.Sprintf("%s%d", scalarToHLSLStr(t.Scalar), t.Size)
 	default:
 		return "uint"
@@ -2099,7 +2157,7 @@ func (w *Writer) writeNagaBufferLengthHelpers(fn *ir.Function) {
 		if writable {
 			accessStr = "RW"
 		}
-		fmt.Fprintf(&w.Out, "uint NagaBufferLength%s(%sByteAddressBuffer buffer)\n", accessStr, accessStr)
+		fmt.Fprintf(&w.Out, "uint %s%s(%sByteAddressBuffer buffer)\n", w.helperName("NagaBufferLength"), accessStr, accessStr)
 		fmt.Fprintf(&w.Out, "{\n")
 		fmt.Fprintf(&w.Out, "    uint ret;\n")
 		fmt.Fprintf(&w.Out, "    buffer.GetDimensions(ret);\n")
@@ -2400,6 +2458,9 @@ func (w *Writer) writeFunctions() error {
 		if w.isEntryPointFunction(ir.FunctionHandle(handle)) {
 			continue
 		}
+		if err := w.ctx.Err(); err != nil {
+			return err
+		}
 		fn := &w.module.Functions[handle]
 		if err := w.writeFunction(ir.FunctionHandle(handle), fn); err != nil {
 			return err
@@ -2515,6 +2576,9 @@ func (w *Writer) writeEntryPoints() error {
 		if w.options.EntryPoint != "" && ep.Name != w.options.EntryPoint {
 			continue
 		}
+		if err := w.ctx.Err(); err != nil {
+			return err
+		}
 
 		if err := w.writeEntryPointWithIO(epIdx, ep); err != nil {
 			return err
@@ -125,6 +125,24 @@ func TestHLSL_StructPadding(t *testing.T) {
 	if !strings.Contains(output, "_end_pad_") {
 		t.Errorf("expected end padding, got:\n%s", output)
 	}
+
+	// Every padding member is reported with the bytes it covers.
+	want := []ir.StructPadding{
+		{Struct: 1, Name: "_pad1_0", Before: 1, Offset: 4, Size: 4},
+		{Struct: 1, Name: "_pad1_1", Before: 1, Offset: 8, Size: 4},
+		{Struct: 1, Name: "_pad1_2", Before: 1, Offset: 12, Size: 4},
+		{Struct: 1, Name: "_end_pad_0", Before: 2, Offset: 20, Size: 4},
+		{Struct: 1, Name: "_end_pad_1", Before: 2, Offset: 24, Size: 4},
+		{Struct: 1, Name: "_end_pad_2", Before: 2, Offset: 28, Size: 4},
+	}
+	if len(w.padding) != len(want) {
+		t.Fatalf("padding = %+v, want %+v", w.padding, want)
+	}
+	for i := range want {
+		if w.padding[i] != want[i] {
+			t.Errorf("padding[%d] = %+v, want %+v", i, w.padding[i], want[i])
+		}
+	}
 }
 
 // TestHLSL_PreciseModifier verifies `precise` on invariant SV_Position.
@@ -0,0 +1,269 @@
+// Copyright 2025 The GoGPU Authors
+// SPDX-License-Identifier: MIT
+
+package frontend
+
+// typeSpec is a type as written: a type name, any template arguments
+// (Texture2D<float4>, vector<float, 3>), and array dimensions written
+// after it. A template argument is a type or, when ty is nil, a constant.
+type typeSpec struct {
+	tok  token
+	name string
+	args []typeArg
+	dims []expr
+}
+
+type typeArg struct {
+	ty    *typeSpec
+	value expr
+}
+
+// semantic is the : NAME annotation of a parameter, member, or function.
+type semantic struct {
+	tok  token
+	name string
+}
+
+// register is the : register(t0, space1) annotation of a resource.
+type register struct {
+	tok   token
+	class byte // 'b', 't', 's', or 'u'
+	index uint32
+	space uint32
+}
+
+// declarator is one name in a declaration, with the array dimensions
+// written after it (x[4]), its annotations, and an optional initializer.
+type declarator struct {
+	tok      token
+	name     string
+	dims     []expr
+	semantic *semantic
+	register *register
+	init     expr
+}
+
+// qualifiers are the storage, interpolation, and matrix packing keywords
+// written before a declaration's type.
+type qualifiers struct {
+	tok   token
+	words []token
+}
+
+// has reports whether the qualifiers include word.
+func (q qualifiers) has(word string) bool {
+	_, ok := q.find(word)
+	return ok
+}
+
+func (q qualifiers) find(word string) (token, bool) {
+	for _, w := range q.words {
+		if w.text == word {
+			return w, true
+		}
+	}
+	return token{}, false
+}
+
+// attribute is a [name(args)] annotation of a function or statement.
+type attribute struct {
+	tok  token
+	name string
+	args []expr
+}
+
+// Top-level declarations.
+type (
+	// varDecl declares globals, locals, or members of one base type.
+	varDecl struct {
+		quals qualifiers
+		ty    typeSpec
+		names []declarator
+	}
+
+	// structDecl is a struct type declaration.
+	structDecl struct {
+		tok     token
+		name    string
+		members []varDecl
+	}
+
+	// cbufferDecl is a constant buffer.
+	cbufferDecl struct {
+		tok      token
+		name     string
+		register *register
+		members  []varDecl
+	}
+
+	// packMatrixDecl is #pragma pack_matrix.
+	packMatrixDecl struct {
+		tok      token
+		rowMajor bool
+	}
+
+	// funcDecl is a function definition, or a prototype when body is nil.
+	funcDecl struct {
+		attrs    []attribute
+		ret      typeSpec
+		tok      token
+		name     string
+		params   []param
+		semantic *semantic
+		body     *blockStmt
+	}
+)
+
+type param struct {
+	tok      token
+	quals    qualifiers
+	ty       typeSpec
+	name     string
+	semantic *semantic
+}
+
+// direction is in, out, or inout.
+func (p *param) direction() string {
+	switch {
+	case p.quals.has("inout"):
+		return "inout"
+	case p.quals.has("out"):
+		return "out"
+	}
+	return "in"
+}
+
+// Statements.
+type (
+	stmt interface{ pos() token }
+
+	blockStmt struct {
+		tok   token
+		stmts []stmt
+	}
+	declStmt struct{ decl *varDecl }
+	exprStmt struct {
+		tok token
+		x   expr
+	}
+	ifStmt struct {
+		tok  token
+		cond expr
+		then stmt
+		els  stmt
+	}
+	forStmt struct {
+		tok  token
+		init stmt
+		cond expr
+		post expr
+		body stmt
+	}
+	whileStmt struct {
+		tok  token
+		cond expr
+		body stmt
+	}
+	doWhileStmt struct {
+		tok  token
+		body stmt
+		cond expr
+	}
+	switchStmt struct {
+		tok      token
+		selector expr
+		body     []stmt
+	}
+	// caseLabel is case value: or default: inside a switch body.
+	caseLabel struct {
+		tok   token
+		value expr // nil for default
+	}
+	jumpStmt struct {
+		tok   token // break, continue, return, or discard
+		value expr
+	}
+	emptyStmt struct{ tok token }
+)
+
+func (s *blockStmt) pos() token   { return s.tok }
+func (s *declStmt) pos() token    { return s.decl.quals.tok }
+func (s *exprStmt) pos() token    { return s.tok }
+func (s *ifStmt) pos() token      { return s.tok }
+func (s *forStmt) pos() token     { return s.tok }
+func (s *whileStmt) pos() token   { return s.tok }
+func (s *doWhileStmt) pos() token { return s.tok }
+func (s *switchStmt) pos() token  { return s.tok }
+func (s *caseLabel) pos() token   { return s.tok }
+func (s *jumpStmt) pos() token    { return s.tok }
+func (s *emptyStmt) pos() token   { return s.tok }
+
+// Expressions.
+type (
+	expr interface{ pos() token }
+
+	identExpr   struct{ tok token }
+	literalExpr struct {
+		tok token // tokInt, tokUint, tokFloat, or an identifier true/false
+	}
+	unaryExpr struct {
+		tok     token
+		op      string
+		x       expr
+		postfix bool
+	}
+	binaryExpr struct {
+		tok  token
+		op   string
+		l, r expr
+	}
+	assignExpr struct {
+		tok  token
+		op   string // "=", "+=", ...
+		l, r expr
+	}
+	ternaryExpr struct {
+		tok             token
+		cond, then, els expr
+	}
+	// callExpr calls a function or constructor. A method call such as
+	// tex.Sample(s, uv) has recv set.
+	callExpr struct {
+		tok    token
+		callee typeSpec
+		recv   expr
+		args   []expr
+	}
+	// castExpr is a C-style cast, (float3)x.
+	castExpr struct {
+		tok token
+		ty  typeSpec
+		x   expr
+	}
+	// listExpr is an initializer list, {1, 2, 3}.
+	listExpr struct {
+		tok   token
+		elems []expr
+	}
+	indexExpr struct {
+		tok      token
+		x, index expr
+	}
+	memberExpr struct {
+		tok  token
+		x    expr
+		name string
+	}
+)
+
+func (e *identExpr) pos() token   { return e.tok }
+func (e *literalExpr) pos() token { return e.tok }
+func (e *unaryExpr) pos() token   { return e.tok }
+func (e *binaryExpr) pos() token  { return e.tok }
+func (e *assignExpr) pos() token  { return e.tok }
+func (e *ternaryExpr) pos() token { return e.tok }
+func (e *callExpr) pos() token    { return e.tok }
+func (e *castExpr) pos() token    { return e.tok }
+func (e *listExpr) pos() token    { return e.tok }
+func (e *indexExpr) pos() token   { return e.tok }
+func (e *memberExpr) pos() token  { return e.tok }
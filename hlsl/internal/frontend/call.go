@@ -0,0 +1,375 @@
+// Copyright 2025 The GoGPU Authors
+// SPDX-License-Identifier: MIT
+
+package frontend
+
+import (
+	"fmt"
+	"strings"
+)
+
+func (t *translator) call(e *callExpr) (value, error) {
+	if e.recv != nil {
+		return t.method(e)
+	}
+	name := e.callee.name
+	if isBuiltinTypeName(name) || t.structs[name] != nil {
+		return t.construct(e)
+	}
+	if fi, ok := t.funcs[name]; ok {
+		return t.callFunc(e, fi)
+	}
+	if b, ok := builtinFuncs[name]; ok {
+		if t.fn == nil {
+			return value{}, errorAt(e.tok, "%s cannot be called outside a function", name)
+		}
+		// The WGSL frontend does not evaluate built-in calls in constant
+		// expressions.
+		v, err := b(t, e)
+		v.konst = false
+		return v, err
+	}
+	return value{}, errorAt(e.tok, "undeclared function %s", name)
+}
+
+// args translates and loads call arguments, checking their count.
+func (t *translator) args(e *callExpr, counts ...int) ([]value, error) {
+	if err := argCount(e, counts...); err != nil {
+		return nil, err
+	}
+	vals := make([]value, len(e.args))
+	for i, a := range e.args {
+		v, err := t.rvalue(a)
+		if err != nil {
+			return nil, err
+		}
+		vals[i] = v
+	}
+	return vals, nil
+}
+
+func argCount(e *callExpr, counts ...int) error {
+	ok := len(counts) == 0
+	for _, n := range counts {
+		ok = ok || n == len(e.args)
+	}
+	if ok {
+		return nil
+	}
+	want := fmt.Sprint(counts[0])
+	if len(counts) > 1 {
+		want = fmt.Sprintf("%d or %d", counts[0], counts[1])
+	}
+	return errorAt(e.tok, "%s takes %s arguments, got %d", e.callee.name, want, len(e.args))
+}
+
+func joinText(vals []value) string {
+	parts := make([]string, len(vals))
+	for i, v := range vals {
+		parts[i] = v.text
+	}
+	return strings.Join(parts, ", ")
+}
+
+// callFunc translates a call of a user function. An out or inout argument
+// that is a local variable is passed by address; any other goes through a
+// temporary that is copied back after the statement.
+func (t *translator) callFunc(e *callExpr, fi *funcInfo) (value, error) {
+	if t.fn == nil {
+		return value{}, errorAt(e.tok, "function calls are not allowed in global initializers")
+	}
+	t.fn.calls = append(t.fn.calls, funcCall{tok: e.tok, callee: fi})
+	if fi.stage != "" && !fi.wrapped {
+		return value{}, errorAt(e.tok, "entry point %s cannot be called", fi.decl.name)
+	}
+	if err := argCount(e, len(fi.params)); err != nil {
+		return value{}, err
+	}
+	args := make([]string, len(e.args))
+	for i, a := range e.args {
+		pt := fi.params[i]
+		if fi.dirs[i] == "in" {
+			v, err := t.rvalue(a)
+			if err != nil {
+				return value{}, err
+			}
+			if v, err = t.convert(v, pt, a.pos()); err != nil {
+				return value{}, err
+			}
+			if pt.kind == kindTexture && v.ty.depth != pt.depth {
+				return value{}, errorAt(a.pos(), "passing a texture used with SampleCmp to a function is not supported")
+			}
+			args[i] = v.text
+			continue
+		}
+		arg, err := t.outArg(a, pt, fi.dirs[i] == "inout")
+		if err != nil {
+			return value{}, err
+		}
+		args[i] = arg
+	}
+	return value{text: fi.name + "(" + strings.Join(args, ", ") + ")", ty: fi.ret}, nil
+}
+
+// outArg translates an out or inout argument to the pointer passed for
+// it.
+func (t *translator) outArg(a expr, ty *hlType, inout bool) (string, error) {
+	if t.noTemps {
+		return "", errorAt(a.pos(), "out arguments are not supported in loop conditions and updates")
+	}
+	lhs, err := t.target(a)
+	if err != nil {
+		return "", err
+	}
+	if sym := lhs.root; sym != nil && lhs.swizzle == nil && lhs.ty.equal(ty) && lhs.ty.value() == lhs.ty {
+		switch {
+		case sym.local && lhs.text == sym.text:
+			return "&" + sym.text, nil
+		case sym.pointer && lhs.text == "(*"+sym.text+")":
+			return sym.text, nil
+		case sym.kind == symParam && lhs.text == sym.text:
+			// An assigned in parameter is copied into a variable.
+			return "&" + sym.text, nil
+		}
+	}
+	if !lhs.pure {
+		return "", errorAt(a.pos(), "out arguments that call functions are not supported; use a temporary")
+	}
+	tmp := t.temp("arg")
+	if inout {
+		cur, err := t.load(lhs, a.pos())
+		if err != nil {
+			return "", err
+		}
+		if cur, err = t.convert(cur, ty, a.pos()); err != nil {
+			return "", err
+		}
+		t.w.Line(a.pos().line, "var %s: %s = %s;", tmp, ty.wgsl(), cur.text)
+	} else {
+		t.w.Line(a.pos().line, "var %s: %s;", tmp, ty.wgsl())
+	}
+	at := a.pos()
+	t.post = append(t.post, func() error {
+		v, err := t.convert(value{text: tmp, ty: ty, pure: true}, lhs.ty.value(), at)
+		if err != nil {
+			return err
+		}
+		return t.store(lhs, v, at)
+	})
+	return "&" + tmp, nil
+}
+
+// construct translates a type constructor: a conversion, vector, or
+// matrix constructor.
+func (t *translator) construct(e *callExpr) (value, error) {
+	ty, err := t.resolveType(e.callee, nil)
+	if err != nil {
+		return value{}, err
+	}
+	switch ty.kind {
+	case kindStruct:
+		return value{}, errorAt(e.tok, "struct constructors are not supported; use an initializer list")
+	case kindScalar, kindVector, kindMatrix:
+	default:
+		return value{}, errorAt(e.tok, "cannot construct %s", ty)
+	}
+	vals, err := t.args(e)
+	if err != nil {
+		return value{}, err
+	}
+	if len(vals) == 0 {
+		return value{}, errorAt(e.tok, "constructor %s needs arguments", e.callee.name)
+	}
+	if len(vals) == 1 && (ty.kind == kindScalar || vals[0].ty.kind == kindScalar || vals[0].ty.kind == ty.kind) {
+		// One argument converts, widening a scalar and truncating a
+		// vector or matrix.
+		return t.convert(vals[0], ty, e.tok)
+	}
+	if ty.kind == kindVector {
+		return t.constructVector(e, ty, vals)
+	}
+	return t.constructMatrix(e, ty, vals)
+}
+
+func (t *translator) constructVector(e *callExpr, ty *hlType, vals []value) (value, error) {
+	n := 0
+	for i, v := range vals {
+		if !v.ty.isScalarOrVector() {
+			return value{}, errorAt(e.args[i].pos(), "%s constructor argument cannot be a %s", ty, v.ty)
+		}
+		vals[i] = castTo(v, v.ty.withScalar(ty.scalar))
+		n += v.ty.vectorSize()
+	}
+	if n != ty.size {
+		return value{}, errorAt(e.tok, "%s constructor needs %d components, got %d", ty, ty.size, n)
+	}
+	return derived(ty.wgsl()+"("+joinText(vals)+")", ty, vals...), nil
+}
+
+// constructMatrix translates a matrix constructor, whose components are
+// in row order; since a WGSL column is an HLSL row, they pass through.
+func (t *translator) constructMatrix(e *callExpr, ty *hlType, vals []value) (value, error) {
+	allRows := len(vals) == ty.size
+	n := 0
+	for i, v := range vals {
+		if !v.ty.isScalarOrVector() {
+			return value{}, errorAt(e.args[i].pos(), "%s constructor argument cannot be a %s", ty, v.ty)
+		}
+		allRows = allRows && v.ty.kind == kindVector && v.ty.size == ty.cols
+		n += v.ty.vectorSize()
+		vals[i] = castTo(v, v.ty.withScalar(scalarFloat))
+	}
+	if n != ty.size*ty.cols {
+		return value{}, errorAt(e.tok, "%s constructor needs %d components, got %d", ty, ty.size*ty.cols, n)
+	}
+	if allRows {
+		return derived(ty.wgsl()+"("+joinText(vals)+")", ty, vals...), nil
+	}
+	// WGSL takes either all scalars or all columns, so mixed arguments
+	// are split into components.
+	var comps []string
+	for i, v := range vals {
+		if v.ty.kind == kindScalar {
+			comps = append(comps, v.text)
+			continue
+		}
+		if !v.pure {
+			return value{}, errorAt(e.args[i].pos(), "vector arguments that call functions are not supported here; assign them to a variable first")
+		}
+		for c := 0; c < v.ty.size; c++ {
+			comps = append(comps, v.text+"."+"xyzw"[c:c+1])
+		}
+	}
+	return derived(ty.wgsl()+"("+strings.Join(comps, ", ")+")", ty, vals...), nil
+}
+
+// list translates an initializer list for a value of type ty. Lists
+// nest, but as in C their scalars may also fill an aggregate flat.
+func (t *translator) list(l *listExpr, ty *hlType) (value, error) {
+	var flat []value
+	if err := t.flatten(l, &flat); err != nil {
+		return value{}, err
+	}
+	if ty.kind == kindArray && ty.length == 0 {
+		n := components(ty.elem)
+		if n == 0 || len(flat)%n != 0 {
+			return value{}, errorAt(l.tok, "initializer does not fill a whole number of %s elements", ty.elem)
+		}
+		c := *ty
+		c.length = len(flat) / n
+		ty = &c
+	}
+	v, rest, err := t.build(l.tok, ty, flat)
+	if err != nil {
+		return value{}, err
+	}
+	if len(rest) > 0 {
+		return value{}, errorAt(l.tok, "too many initializers for %s", ty)
+	}
+	return v, nil
+}
+
+// flatten collects the scalar components of an initializer list.
+func (t *translator) flatten(e expr, out *[]value) error {
+	if l, ok := e.(*listExpr); ok {
+		for _, el := range l.elems {
+			if err := t.flatten(el, out); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	v, err := t.rvalue(e)
+	if err != nil {
+		return err
+	}
+	switch {
+	case v.ty.kind == kindScalar:
+		*out = append(*out, v)
+		return nil
+	case v.ty.kind == kindVector || v.ty.kind == kindMatrix:
+		if !v.pure {
+			return errorAt(e.pos(), "initializers that call functions and return vectors are not supported; assign them to a variable first")
+		}
+		if v.ty.kind == kindVector {
+			for c := 0; c < v.ty.size; c++ {
+				*out = append(*out, derived(v.text+"."+"xyzw"[c:c+1], scalarType(v.ty.scalar), v))
+			}
+			return nil
+		}
+		for r := 0; r < v.ty.size; r++ {
+			for c := 0; c < v.ty.cols; c++ {
+				*out = append(*out, derived(fmt.Sprintf("%s[%d][%d]", v.text, r, c), floatType, v))
+			}
+		}
+		return nil
+	}
+	return errorAt(e.pos(), "initializer lists of %s values are not supported", v.ty)
+}
+
+// components counts the scalars in a value of type ty.
+func components(ty *hlType) int {
+	switch ty.kind {
+	case kindScalar:
+		return 1
+	case kindVector:
+		return ty.size
+	case kindMatrix:
+		return ty.size * ty.cols
+	case kindArray:
+		return ty.length * components(ty.elem)
+	case kindStruct:
+		n := 0
+		for _, m := range ty.st.members {
+			n += components(m.ty)
+		}
+		return n
+	}
+	return 0
+}
+
+// build assembles a value of type ty from leading scalars.
+func (t *translator) build(at token, ty *hlType, flat []value) (value, []value, error) {
+	n := components(ty)
+	if n == 0 {
+		return value{}, nil, errorAt(at, "cannot initialize %s with a list", ty)
+	}
+	if len(flat) < n {
+		return value{}, nil, errorAt(at, "too few initializers for %s", ty)
+	}
+	switch ty.kind {
+	case kindScalar:
+		v, err := t.convert(flat[0], ty.value(), at)
+		return v, flat[1:], err
+	case kindVector, kindMatrix:
+		parts := make([]value, n)
+		for i := range parts {
+			parts[i] = castTo(flat[i], scalarType(ty.scalar))
+		}
+		text := ty.value().wgsl() + "(" + joinText(parts) + ")"
+		if ty.colMajor {
+			text = "transpose(" + text + ")"
+		}
+		return derived(text, ty, parts...), flat[n:], nil
+	}
+	var parts []value
+	var elems []*hlType
+	if ty.kind == kindArray {
+		for i := 0; i < ty.length; i++ {
+			elems = append(elems, ty.elem)
+		}
+	} else {
+		for _, m := range ty.st.members {
+			elems = append(elems, m.ty)
+		}
+	}
+	for _, et := range elems {
+		v, rest, err := t.build(at, et, flat)
+		if err != nil {
+			return value{}, nil, err
+		}
+		parts = append(parts, v)
+		flat = rest
+	}
+	return derived(ty.wgsl()+"("+joinText(parts)+")", ty, parts...), flat, nil
+}
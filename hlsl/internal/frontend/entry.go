@@ -0,0 +1,397 @@
+// Copyright 2025 The GoGPU Authors
+// SPDX-License-Identifier: MIT
+
+package frontend
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/gogpu/naga/internal/wgslnames"
+	"github.com/gogpu/naga/internal/wgslwriter"
+)
+
+// ioVar is one input or output of an entry point: a parameter, result,
+// or struct member with a semantic.
+type ioVar struct {
+	tok      token
+	semantic string // normalized: upper case, with an index unless SV_
+	path     string // WGSL access path below the parameter or result
+	ty       *hlType
+	wgslTy   *hlType // type of the WGSL input or output
+	attr     string  // @builtin or @location, with any @interpolate
+}
+
+// entryIO lists the inputs and outputs of an entry point by parameter;
+// the result's outputs come last.
+type entryIO struct {
+	inputs  [][]ioVar
+	outputs [][]ioVar
+}
+
+// systemValue is the WGSL built-in an SV_ semantic maps to, and the stages
+// reading it as an input and writing it as an output.
+type systemValue struct {
+	builtin string
+	ty      *hlType
+	in, out string
+}
+
+var systemValues = map[string]systemValue{
+	"SV_POSITION":         {"position", vectorType(scalarFloat, 4), "fragment", "vertex"},
+	"SV_VERTEXID":         {"vertex_index", uintType, "vertex", ""},
+	"SV_INSTANCEID":       {"instance_index", uintType, "vertex", ""},
+	"SV_ISFRONTFACE":      {"front_facing", boolType, "fragment", ""},
+	"SV_SAMPLEINDEX":      {"sample_index", uintType, "fragment", ""},
+	"SV_COVERAGE":         {"sample_mask", uintType, "fragment", "fragment"},
+	"SV_DEPTH":            {"frag_depth", floatType, "", "fragment"},
+	"SV_DISPATCHTHREADID": {"global_invocation_id", vectorType(scalarUint, 3), "compute", ""},
+	"SV_GROUPTHREADID":    {"local_invocation_id", vectorType(scalarUint, 3), "compute", ""},
+	"SV_GROUPID":          {"workgroup_id", vectorType(scalarUint, 3), "compute", ""},
+	"SV_GROUPINDEX":       {"local_invocation_index", uintType, "compute", ""},
+}
+
+// normalizeSemantic upper-cases a semantic and gives user semantics an
+// index, so TEXCOORD and texcoord0 match.
+func normalizeSemantic(name string) string {
+	s := strings.ToUpper(name)
+	if strings.HasPrefix(s, "SV_") {
+		if s == "SV_TARGET" {
+			return "SV_TARGET0"
+		}
+		if !strings.HasPrefix(s, "SV_TARGET") {
+			s = strings.TrimSuffix(s, "0")
+		}
+		return s
+	}
+	if !isDigit(s[len(s)-1]) {
+		s += "0"
+	}
+	return s
+}
+
+// checkEntryPoint matches the inputs and outputs of an entry point to WGSL
+// built-ins and locations. It reports whether the entry point needs a
+// wrapper: one is needed unless every parameter is an input whose type
+// is its WGSL type and the result, if any, is too.
+func (t *translator) checkEntryPoint(fi *funcInfo) (bool, error) {
+	d := fi.decl
+	io := &entryIO{inputs: make([][]ioVar, len(d.params)), outputs: make([][]ioVar, len(d.params)+1)}
+	fi.io = io
+	vertexInputs := 0
+	inputs := make(map[string]bool)
+	outputs := make(map[string]bool)
+	direct := true
+	for i := range d.params {
+		p := &d.params[i]
+		ty := fi.params[i]
+		if ty.isResource() {
+			return false, errorAt(p.tok, "entry point parameter %s cannot be a resource; declare it at global scope", p.name)
+		}
+		dir := fi.dirs[i]
+		if dir != "in" || ty.kind == kindStruct {
+			direct = false
+		}
+		if dir != "out" {
+			vars, err := t.ioVars(p.name, p.tok, ty, p.semantic, p.quals, "")
+			if err != nil {
+				return false, err
+			}
+			for j := range vars {
+				if err := t.classify(fi, &vars[j], true, &vertexInputs, inputs); err != nil {
+					return false, err
+				}
+				direct = direct && vars[j].ty.equal(vars[j].wgslTy)
+			}
+			io.inputs[i] = vars
+		}
+		if dir != "in" {
+			if fi.stage == "compute" {
+				return false, errorAt(p.tok, "compute shaders have no outputs; %s parameter %s is not allowed", dir, p.name)
+			}
+			vars, err := t.ioVars(p.name, p.tok, ty, p.semantic, p.quals, "")
+			if err != nil {
+				return false, err
+			}
+			for j := range vars {
+				if err := t.classify(fi, &vars[j], false, nil, outputs); err != nil {
+					return false, err
+				}
+			}
+			io.outputs[i] = vars
+		}
+	}
+	if fi.ret.kind != kindVoid {
+		if fi.stage == "compute" {
+			return false, errorAt(d.ret.tok, "compute shader %s must return void", d.name)
+		}
+		vars, err := t.ioVars(d.name, d.tok, fi.ret, d.semantic, qualifiers{}, "")
+		if err != nil {
+			return false, err
+		}
+		for j := range vars {
+			if err := t.classify(fi, &vars[j], false, nil, outputs); err != nil {
+				return false, err
+			}
+			direct = direct && fi.ret.kind != kindStruct && vars[j].ty.equal(vars[j].wgslTy)
+		}
+		io.outputs[len(d.params)] = vars
+	}
+	if fi.stage == "vertex" && !outputs["SV_POSITION"] {
+		return false, errorAt(d.tok, "vertex shader %s must output SV_Position", d.name)
+	}
+	return !direct, nil
+}
+
+// ioVars lists the inputs or outputs in a parameter or result of type ty,
+// flattening structs.
+func (t *translator) ioVars(name string, at token, ty *hlType, sem *semantic, quals qualifiers, path string) ([]ioVar, error) {
+	if ty.kind == kindStruct {
+		if sem != nil {
+			return nil, errorAt(sem.tok, "%s is a struct; give its members semantics instead", name)
+		}
+		var vars []ioVar
+		for _, m := range ty.st.members {
+			mv, err := t.ioVars(m.name, m.tok, m.ty, m.semantic, m.quals, path+"."+m.wgsl)
+			if err != nil {
+				return nil, err
+			}
+			vars = append(vars, mv...)
+		}
+		return vars, nil
+	}
+	if sem == nil {
+		return nil, errorAt(at, "entry point input or output %s needs a semantic", name)
+	}
+	if !ty.isScalarOrVector() {
+		return nil, errorAt(at, "entry point inputs and outputs of type %s are not supported", ty)
+	}
+	v := ioVar{tok: sem.tok, semantic: normalizeSemantic(sem.name), path: path, ty: ty.value()}
+	v.attr = interpolation(quals)
+	return []ioVar{v}, nil
+}
+
+// interpolation returns the @interpolate attribute the qualifiers ask
+// for, or "".
+func interpolation(quals qualifiers) string {
+	if quals.has("nointerpolation") {
+		return "flat"
+	}
+	kind := "perspective"
+	if quals.has("noperspective") {
+		kind = "linear"
+	}
+	switch {
+	case quals.has("centroid"):
+		return kind + ", centroid"
+	case quals.has("sample"):
+		return kind + ", sample"
+	case kind == "linear":
+		return kind
+	}
+	return ""
+}
+
+// classify gives an input or output its WGSL attribute and type. Vertex
+// inputs take locations in declaration order, varyings share locations by
+// semantic across the module, and fragment outputs take the index of
+// SV_Target.
+func (t *translator) classify(fi *funcInfo, v *ioVar, input bool, vertexInputs *int, seen map[string]bool) error {
+	if seen[v.semantic] {
+		return errorAt(v.tok, "duplicate semantic %s", v.semantic)
+	}
+	seen[v.semantic] = true
+	interp := v.attr
+	v.attr = ""
+	dir := "output"
+	if input {
+		dir = "input"
+	}
+
+	if sv, ok := systemValues[v.semantic]; ok {
+		stage := sv.out
+		if input {
+			stage = sv.in
+		}
+		if stage != fi.stage {
+			return errorAt(v.tok, "%s is not a %s shader %s", v.semantic, fi.stage, dir)
+		}
+		if sv.ty.scalar == scalarBool && v.ty.scalar != scalarBool {
+			return errorAt(v.tok, "%s must be a bool, got %s", v.semantic, v.ty)
+		}
+		v.attr = "@builtin(" + sv.builtin + ")"
+		v.wgslTy = sv.ty
+		return nil
+	}
+	if v.ty.scalar == scalarBool {
+		return errorAt(v.tok, "entry point inputs and outputs of type %s are not supported", v.ty)
+	}
+	v.wgslTy = v.ty
+	if index, ok := strings.CutPrefix(v.semantic, "SV_TARGET"); ok {
+		if fi.stage != "fragment" || input {
+			return errorAt(v.tok, "SV_Target is a fragment shader output")
+		}
+		n := 0
+		for _, c := range index {
+			if !isDigit(byte(c)) {
+				return errorAt(v.tok, "invalid semantic %s", v.semantic)
+			}
+			n = n*10 + int(c-'0')
+		}
+		if n > 7 {
+			return errorAt(v.tok, "SV_Target index %d is out of range", n)
+		}
+		v.attr = fmt.Sprintf("@location(%d)", n)
+		return nil
+	}
+	if strings.HasPrefix(v.semantic, "SV_") {
+		return errorAt(v.tok, "semantic %s is not supported", v.semantic)
+	}
+
+	switch {
+	case fi.stage == "compute":
+		return errorAt(v.tok, "compute shader inputs must have SV_ semantics, got %s", v.semantic)
+	case fi.stage == "vertex" && input:
+		v.attr = fmt.Sprintf("@location(%d)", *vertexInputs)
+		*vertexInputs++
+		return nil
+	case fi.stage == "fragment" && !input:
+		return errorAt(v.tok, "fragment shader outputs must have SV_Target or SV_Depth semantics, got %s", v.semantic)
+	}
+	// A vertex output or fragment input: the two stages find each other
+	// by semantic.
+	loc, ok := t.varyings[v.semantic]
+	if !ok {
+		loc = len(t.varyings)
+		t.varyings[v.semantic] = loc
+	}
+	v.attr = fmt.Sprintf("@location(%d)", loc)
+	if v.ty.isInteger() {
+		interp = "flat"
+	}
+	if interp != "" {
+		v.attr += " @interpolate(" + interp + ")"
+	}
+	return nil
+}
+
+// stageAttributes returns the WGSL attributes of an entry point.
+func stageAttributes(fi *funcInfo) string {
+	if fi.stage == "compute" {
+		w := fi.workgroup
+		return fmt.Sprintf("@compute @workgroup_size(%d, %d, %d)", w[0], w[1], w[2])
+	}
+	return "@" + fi.stage
+}
+
+// ioName is the name of a wrapper input or output member.
+func ioName(v ioVar) string {
+	return strings.ToLower(v.semantic)
+}
+
+// entryWrapper writes the WGSL entry point for an HLSL entry point whose
+// body was translated as an ordinary function. It gathers the inputs into
+// the HLSL parameters, calls the function, and returns its outputs.
+func (t *translator) entryWrapper(fi *funcInfo) (*wgslwriter.Writer, error) {
+	d := fi.decl
+	line := d.tok.line
+	w := &wgslwriter.Writer{}
+	name := wgslnames.Mangle(d.name)
+
+	var outputs []ioVar
+	var outRoots []string
+	for i, vars := range fi.io.outputs {
+		root := fmt.Sprintf("p%d", i)
+		if i == len(d.params) {
+			root = "result"
+		}
+		for _, v := range vars {
+			outputs = append(outputs, v)
+			outRoots = append(outRoots, root)
+		}
+	}
+	ret := ""
+	outStruct := ""
+	switch len(outputs) {
+	case 0:
+	case 1:
+		ret = fmt.Sprintf(" -> %s %s", outputs[0].attr, outputs[0].wgslTy.wgsl())
+	default:
+		outStruct = t.uniqueName(name + "Output")
+		w.Line(line, "struct %s {", outStruct)
+		w.Indent++
+		for _, v := range outputs {
+			w.Line(v.tok.line, "%s %s: %s,", v.attr, ioName(v), v.wgslTy.wgsl())
+		}
+		w.Indent--
+		w.Line(line, "}")
+		w.Blank(line)
+		ret = " -> " + outStruct
+	}
+
+	var params []string
+	for _, vars := range fi.io.inputs {
+		for _, v := range vars {
+			params = append(params, fmt.Sprintf("%s in_%s: %s", v.attr, ioName(v), v.wgslTy.wgsl()))
+		}
+	}
+	w.Line(line, "%s", stageAttributes(fi))
+	w.Line(line, "fn %s(%s)%s {", name, strings.Join(params, ", "), ret)
+	w.Indent++
+
+	args := make([]string, len(d.params))
+	for i := range d.params {
+		ty := fi.params[i]
+		vars := fi.io.inputs[i]
+		if fi.dirs[i] == "in" && ty.kind != kindStruct {
+			v, err := t.convert(value{text: "in_" + ioName(vars[0]), ty: vars[0].wgslTy, pure: true}, ty, vars[0].tok)
+			if err != nil {
+				return nil, err
+			}
+			args[i] = v.text
+			continue
+		}
+		local := fmt.Sprintf("p%d", i)
+		w.Line(line, "var %s: %s;", local, ty.wgsl())
+		for _, v := range vars {
+			in, err := t.convert(value{text: "in_" + ioName(v), ty: v.wgslTy, pure: true}, v.ty, v.tok)
+			if err != nil {
+				return nil, err
+			}
+			w.Line(v.tok.line, "%s%s = %s;", local, v.path, in.text)
+		}
+		args[i] = local
+		if fi.dirs[i] != "in" {
+			args[i] = "&" + local
+		}
+	}
+	call := fmt.Sprintf("%s(%s)", fi.name, strings.Join(args, ", "))
+	if fi.ret.kind == kindVoid {
+		w.Line(line, "%s;", call)
+	} else {
+		w.Line(line, "let result = %s;", call)
+	}
+
+	results := make([]string, len(outputs))
+	for i, v := range outputs {
+		out, err := t.convert(value{text: outRoots[i] + v.path, ty: v.ty, pure: true}, v.wgslTy, v.tok)
+		if err != nil {
+			return nil, err
+		}
+		results[i] = out.text
+	}
+	switch len(outputs) {
+	case 0:
+	case 1:
+		w.Line(line, "return %s;", results[0])
+	default:
+		w.Line(line, "var output: %s;", outStruct)
+		for i, v := range outputs {
+			w.Line(v.tok.line, "output.%s = %s;", ioName(v), results[i])
+		}
+		w.Line(line, "return output;")
+	}
+	w.Indent--
+	w.Line(d.body.tok.line, "}")
+	return w, nil
+}
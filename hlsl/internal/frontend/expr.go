@@ -0,0 +1,840 @@
+// Copyright 2025 The GoGPU Authors
+// SPDX-License-Identifier: MIT
+
+package frontend
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// value is a translated expression.
+type value struct {
+	text string
+	ty   *hlType
+
+	// ref marks an expression naming memory: a variable, or a member,
+	// element, or single component of one. mutable refs may be assigned.
+	// swizzle marks a multi-component swizzle of a ref, which WGSL cannot
+	// assign directly.
+	ref     bool
+	mutable bool
+	swizzle *swizzleRef
+
+	// konst marks a WGSL constant expression; ival and fval hold the
+	// value of a scalar constant when known.
+	konst bool
+	ival  *int64
+	fval  *float64
+
+	// pure is false when evaluating the expression calls a user function
+	// or an atomic, so it must not be evaluated twice or conditionally.
+	pure bool
+
+	// stmt marks the translation of an intrinsic that is a whole WGSL
+	// statement, such as clip or sincos; it has type void.
+	stmt bool
+
+	// root is the variable or parameter a ref is part of.
+	root *symbol
+}
+
+// swizzleRef is the base vector and components of a swizzle.
+type swizzleRef struct {
+	base       value
+	components string
+}
+
+// rvalue translates e and loads it.
+func (t *translator) rvalue(e expr) (value, error) {
+	v, err := t.expr(e)
+	if err != nil {
+		return value{}, err
+	}
+	return t.load(v, e.pos())
+}
+
+// load reads a value from memory, undoing the storage representation of
+// atomics, column-major matrices, and cbuffer bools.
+func (t *translator) load(v value, at token) (value, error) {
+	if v.ty.kind == kindVoid {
+		return value{}, errorAt(at, "void value used in an expression")
+	}
+	ty := v.ty
+	switch {
+	case ty.atomic:
+		return value{text: "atomicLoad(&" + v.text + ")", ty: ty.value(), pure: v.pure}, nil
+	case ty.hasAtomic():
+		return value{}, errorAt(at, "a %s containing atomics can only be accessed member by member", ty)
+	case ty.padded:
+		return value{}, errorAt(at, "reading a whole cbuffer array is not supported; read its elements")
+	case ty.colMajor:
+		return derived("transpose("+v.text+")", ty.value(), v), nil
+	case ty.hostBool:
+		u := ty.withScalar(scalarUint)
+		return derived("("+v.text+" != "+zero(u)+")", ty.value(), v), nil
+	}
+	v.ref, v.mutable, v.swizzle = false, false, nil
+	return v, nil
+}
+
+// expr translates e without loading it.
+func (t *translator) expr(e expr) (value, error) {
+	switch e := e.(type) {
+	case *literalExpr:
+		return literal(e.tok)
+	case *identExpr:
+		return t.ident(e.tok)
+	case *unaryExpr:
+		return t.unary(e)
+	case *binaryExpr:
+		return t.binary(e)
+	case *ternaryExpr:
+		return t.ternary(e)
+	case *indexExpr:
+		return t.index(e)
+	case *memberExpr:
+		return t.member(e)
+	case *callExpr:
+		return t.call(e)
+	case *castExpr:
+		return t.cast(e)
+	case *listExpr:
+		return value{}, errorAt(e.tok, "initializer lists are only allowed in declarations")
+	case *assignExpr:
+		return value{}, errorAt(e.tok, "assignment inside an expression is not supported; assign in a separate statement")
+	}
+	return value{}, errorAt(e.pos(), "unsupported expression")
+}
+
+// literal translates a numeric or boolean literal.
+func literal(tok token) (value, error) {
+	switch tok.kind {
+	case tokFloat:
+		f, err := strconv.ParseFloat(tok.text, 64)
+		if err != nil || math.IsInf(float64(float32(f)), 0) {
+			return value{}, errorAt(tok, "float literal %s is out of range", tok.text)
+		}
+		return constFloat(f), nil
+	case tokInt, tokUint:
+		text := tok.text
+		if len(text) > 1 && text[0] == '0' && isDigit(text[1]) {
+			// A leading zero makes a C literal octal.
+			text = "0o" + text[1:]
+		}
+		u, err := strconv.ParseUint(text, 0, 64)
+		if err != nil || u > math.MaxUint32 {
+			return value{}, errorAt(tok, "integer literal %s is out of range", tok.text)
+		}
+		if tok.kind == tokUint {
+			return constInt(int64(u), scalarUint), nil
+		}
+		// An int literal keeps its 32-bit pattern, so 0xFFFFFFFF is -1.
+		return constInt(int64(int32(uint32(u))), scalarInt), nil
+	}
+	return value{text: tok.text, ty: boolType, konst: true, pure: true}, nil
+}
+
+func constInt(v int64, kind scalarKind) value {
+	return value{text: formatInt(v, kind), ty: scalarType(kind), konst: true, ival: &v, pure: true}
+}
+
+func constFloat(f float64) value {
+	f = float64(float32(f))
+	return value{text: formatFloat(f), ty: floatType, konst: true, fval: &f, pure: true}
+}
+
+func formatInt(v int64, kind scalarKind) string {
+	if kind == scalarUint {
+		return strconv.FormatUint(uint64(uint32(v)), 10) + "u"
+	}
+	if v == math.MinInt32 {
+		return "(-2147483647i - 1i)"
+	}
+	if v < 0 {
+		return "(-" + strconv.FormatInt(-v, 10) + "i)"
+	}
+	return strconv.FormatInt(v, 10) + "i"
+}
+
+func formatFloat(f float64) string {
+	s := strconv.FormatFloat(f, 'g', -1, 32)
+	if !strings.ContainsAny(s, ".e") {
+		s += ".0"
+	}
+	if f < 0 {
+		return "(" + s + "f)"
+	}
+	return s + "f"
+}
+
+func (t *translator) ident(tok token) (value, error) {
+	name := tok.text
+	for i := len(t.scopes) - 1; i >= 0; i-- {
+		if sym, ok := t.scopes[i][name]; ok {
+			return symbolValue(sym), nil
+		}
+	}
+	if sym, ok := t.globals[name]; ok {
+		return symbolValue(sym), nil
+	}
+	if _, ok := t.funcs[name]; ok {
+		return value{}, errorAt(tok, "function %s used as a value", name)
+	}
+	if _, ok := t.structs[name]; ok {
+		return value{}, errorAt(tok, "%s names a cbuffer or struct, not a value", name)
+	}
+	return value{}, errorAt(tok, "undeclared identifier %s", name)
+}
+
+func symbolValue(sym *symbol) value {
+	v := value{text: sym.text, ty: sym.ty, pure: true, root: sym}
+	switch sym.kind {
+	case symConst:
+		v.konst, v.ival = true, sym.ival
+	case symParam:
+		v.ref, v.mutable = true, true
+		if sym.pointer {
+			v.text = "(*" + sym.text + ")"
+		}
+	default:
+		v.ref, v.mutable = true, !sym.readOnly
+	}
+	return v
+}
+
+func (t *translator) unary(e *unaryExpr) (value, error) {
+	if e.op == "++" || e.op == "--" {
+		return value{}, errorAt(e.tok, "%s inside an expression is not supported; use a separate statement", e.op)
+	}
+	x, err := t.rvalue(e.x)
+	if err != nil {
+		return value{}, err
+	}
+	numeric := x.ty.isNumeric() || x.ty.kind == kindMatrix
+	switch e.op {
+	case "+":
+		if !numeric {
+			return value{}, errorAt(e.tok, "unary + needs a numeric operand, got %s", x.ty)
+		}
+		return x, nil
+	case "-":
+		if x.ty.isScalarOrVector() && x.ty.scalar == scalarBool {
+			x = castTo(x, x.ty.withScalar(scalarInt))
+		} else if !numeric {
+			return value{}, errorAt(e.tok, "unary - needs a numeric operand, got %s", x.ty)
+		}
+		if x.ival != nil && x.ty.kind == kindScalar {
+			return constInt(-*x.ival, x.ty.scalar), nil
+		}
+		if x.fval != nil {
+			return constFloat(-*x.fval), nil
+		}
+		if x.ty.scalar == scalarUint && x.ty.kind != kindMatrix {
+			// WGSL has no unary minus for u32; wrapping subtraction is
+			// what HLSL computes.
+			return derived("("+zero(x.ty)+" - "+x.text+")", x.ty, x), nil
+		}
+		return derived("(-"+x.text+")", x.ty, x), nil
+	case "!":
+		if !x.ty.isScalarOrVector() {
+			return value{}, errorAt(e.tok, "! needs a scalar or vector operand, got %s", x.ty)
+		}
+		x = castTo(x, x.ty.withScalar(scalarBool))
+		return derived("(!"+x.text+")", x.ty, x), nil
+	case "~":
+		if !x.ty.isInteger() {
+			return value{}, errorAt(e.tok, "~ needs an integer operand, got %s", x.ty)
+		}
+		if x.ival != nil && x.ty.kind == kindScalar {
+			return constInt(^*x.ival, x.ty.scalar), nil
+		}
+		return derived("(~"+x.text+")", x.ty, x), nil
+	}
+	return value{}, errorAt(e.tok, "unsupported operator %s", e.op)
+}
+
+// derived builds the value of an operation on operands, which is
+// constant or pure only if all operands are.
+func derived(text string, ty *hlType, operands ...value) value {
+	v := value{text: text, ty: ty, konst: true, pure: true}
+	for _, o := range operands {
+		v.konst = v.konst && o.konst
+		v.pure = v.pure && o.pure
+	}
+	return v
+}
+
+// zero spells 0 of a scalar or vector type.
+func zero(ty *hlType) string {
+	s := literalOf(0, ty.scalar)
+	if ty.kind == kindVector {
+		return ty.wgsl() + "(" + s + ")"
+	}
+	return s
+}
+
+func literalOf(n int64, s scalarKind) string {
+	switch s {
+	case scalarFloat:
+		return formatFloat(float64(n))
+	case scalarBool:
+		return strconv.FormatBool(n != 0)
+	}
+	return formatInt(n, s)
+}
+
+// convert applies an HLSL implicit conversion of v to ty. HLSL converts
+// freely between numeric types, widens scalars, and truncates vectors
+// and matrices.
+func (t *translator) convert(v value, ty *hlType, at token) (value, error) {
+	if v.ty.equal(ty) {
+		return v, nil
+	}
+	switch {
+	case ty.isScalarOrVector() && v.ty.isScalarOrVector():
+		n, vs := ty.vectorSize(), v.ty.vectorSize()
+		if vs > 1 && n > vs {
+			return value{}, errorAt(at, "cannot convert %s to %s", v.ty, ty)
+		}
+		if vs > n {
+			v = truncate(v, n)
+		}
+		return splat(castTo(v, v.ty.withScalar(ty.scalar)), n), nil
+	case ty.kind == kindMatrix && v.ty.isScalarOrVector() && v.ty.kind == kindScalar:
+		s := castTo(v, floatType)
+		if !s.pure {
+			return value{}, errorAt(at, "converting a scalar that calls functions to %s is not supported; assign it to a variable first", ty)
+		}
+		rows := make([]string, ty.size)
+		for i := range rows {
+			rows[i] = fmt.Sprintf("vec%d<f32>(%s)", ty.cols, s.text)
+		}
+		return derived(ty.wgsl()+"("+strings.Join(rows, ", ")+")", ty, s), nil
+	case ty.kind == kindMatrix && v.ty.kind == kindMatrix && ty.size <= v.ty.size && ty.cols <= v.ty.cols:
+		if !v.pure {
+			return value{}, errorAt(at, "truncating a matrix that calls functions is not supported; assign it to a variable first")
+		}
+		rows := make([]string, ty.size)
+		for i := range rows {
+			rows[i] = truncate(derived(fmt.Sprintf("%s[%d]", v.text, i), vectorType(scalarFloat, v.ty.cols), v), ty.cols).text
+		}
+		return derived(ty.wgsl()+"("+strings.Join(rows, ", ")+")", ty, v), nil
+	}
+	return value{}, errorAt(at, "cannot convert %s to %s", v.ty, ty)
+}
+
+// truncate keeps the first n components of a vector.
+func truncate(v value, n int) value {
+	if v.ty.vectorSize() <= n {
+		return v
+	}
+	return derived(v.text+"."+"xyzw"[:n], vectorType(v.ty.scalar, n), v)
+}
+
+// castTo converts a scalar or vector to ty, of the same size, with a WGSL
+// value constructor, folding constants.
+func castTo(v value, ty *hlType) value {
+	if v.ty.equal(ty) {
+		return v
+	}
+	if ty.kind == kindScalar {
+		switch {
+		case v.ival != nil && ty.scalar == scalarFloat:
+			return constFloat(float64(*v.ival))
+		case v.ival != nil && ty.scalar != scalarBool:
+			return constInt(*v.ival, ty.scalar)
+		case v.fval != nil && ty.scalar != scalarFloat && ty.scalar != scalarBool && !math.IsNaN(*v.fval) && math.Abs(*v.fval) < 1<<31:
+			return constInt(int64(*v.fval), ty.scalar)
+		}
+	}
+	return derived(ty.wgsl()+"("+v.text+")", ty, v)
+}
+
+// splat widens a scalar to a vector of n components.
+func splat(v value, n int) value {
+	if n == 1 || v.ty.kind != kindScalar {
+		return v
+	}
+	return derived(fmt.Sprintf("vec%d<%s>(%s)", n, wgslScalar(v.ty.scalar), v.text), vectorType(v.ty.scalar, n), v)
+}
+
+// commonScalar converts operands to their common component type, the
+// highest in bool < int < uint < float, and to a common shape: scalars
+// are widened and longer vectors truncated, as HLSL does.
+func (t *translator) commonScalar(at token, vals ...value) ([]value, scalarKind, error) {
+	best := scalarInt
+	n := 0
+	for _, v := range vals {
+		if !v.ty.isScalarOrVector() {
+			return nil, 0, errorAt(at, "expected a scalar or vector operand, got %s", v.ty)
+		}
+		if rank(v.ty.scalar) > rank(best) {
+			best = v.ty.scalar
+		}
+		if s := v.ty.vectorSize(); s > 1 && (n == 0 || s < n) {
+			n = s
+		}
+	}
+	out := make([]value, len(vals))
+	for i, v := range vals {
+		if n > 0 {
+			v = truncate(v, n)
+		}
+		v = castTo(v, v.ty.withScalar(best))
+		if n > 0 {
+			v = splat(v, n)
+		}
+		out[i] = v
+	}
+	return out, best, nil
+}
+
+func (t *translator) binary(e *binaryExpr) (value, error) {
+	l, err := t.rvalue(e.l)
+	if err != nil {
+		return value{}, err
+	}
+	r, err := t.rvalue(e.r)
+	if err != nil {
+		return value{}, err
+	}
+	return t.binaryValues(e, l, r)
+}
+
+// binaryValues applies the operator of e to translated operands.
+func (t *translator) binaryValues(e *binaryExpr, l, r value) (value, error) {
+	if l.ty.kind == kindMatrix || r.ty.kind == kindMatrix {
+		return t.matrixOp(e, l, r)
+	}
+	if !l.ty.isScalarOrVector() || !r.ty.isScalarOrVector() {
+		return value{}, errorAt(e.tok, "unsupported operands for %s: %s and %s", e.op, l.ty, r.ty)
+	}
+	switch e.op {
+	case "&&", "||":
+		vals, _, err := t.commonScalar(e.tok, l, r)
+		if err != nil {
+			return value{}, err
+		}
+		l, r = castTo(vals[0], vals[0].ty.withScalar(scalarBool)), castTo(vals[1], vals[1].ty.withScalar(scalarBool))
+		op := e.op
+		if l.ty.kind == kindVector {
+			// HLSL applies logical operators componentwise.
+			op = op[:1]
+		}
+		return derived("("+l.text+" "+op+" "+r.text+")", l.ty, l, r), nil
+	case "==", "!=", "<", ">", "<=", ">=":
+		vals, _, err := t.commonScalar(e.tok, l, r)
+		if err != nil {
+			return value{}, err
+		}
+		l, r = vals[0], vals[1]
+		if l.ty.scalar == scalarBool && e.op != "==" && e.op != "!=" {
+			l, r = castTo(l, l.ty.withScalar(scalarInt)), castTo(r, r.ty.withScalar(scalarInt))
+		}
+		return derived("("+l.text+" "+e.op+" "+r.text+")", l.ty.withScalar(scalarBool), l, r), nil
+	case "<<", ">>":
+		return t.shift(e, l, r)
+	case "&", "|", "^":
+		if l.ty.scalar == scalarFloat || r.ty.scalar == scalarFloat {
+			return value{}, errorAt(e.tok, "%s needs integer operands, got %s and %s", e.op, l.ty, r.ty)
+		}
+		return t.componentwise(e, l, r, true)
+	}
+	return t.componentwise(e, l, r, false)
+}
+
+// componentwise translates an arithmetic or bitwise operator on scalars
+// and vectors. WGSL mixes a scalar with a vector for arithmetic but not
+// for bitwise operators, so those splat the scalar.
+func (t *translator) componentwise(e *binaryExpr, l, r value, splatScalar bool) (value, error) {
+	vals, s, err := t.commonScalar(e.tok, l, r)
+	if err != nil {
+		return value{}, err
+	}
+	if s == scalarBool {
+		// Arithmetic on bools computes in int.
+		for i, v := range vals {
+			vals[i] = castTo(v, v.ty.withScalar(scalarInt))
+		}
+	}
+	l, r = vals[0], vals[1]
+	ty := l.ty
+	if r.ty.vectorSize() > ty.vectorSize() {
+		ty = r.ty
+	}
+	if splatScalar {
+		l, r = splat(l, ty.vectorSize()), splat(r, ty.vectorSize())
+	}
+	if l.ival != nil && r.ival != nil && ty.kind == kindScalar {
+		if v, ok := foldInt(e.op, *l.ival, *r.ival, ty.scalar); ok {
+			return constInt(v, ty.scalar), nil
+		}
+	}
+	return derived("("+l.text+" "+e.op+" "+r.text+")", ty, l, r), nil
+}
+
+// foldInt evaluates an integer operator with 32-bit wrapping.
+func foldInt(op string, a, b int64, s scalarKind) (int64, bool) {
+	wrap := func(v int64) int64 {
+		if s == scalarUint {
+			return int64(uint32(v))
+		}
+		return int64(int32(v))
+	}
+	switch op {
+	case "+":
+		return wrap(a + b), true
+	case "-":
+		return wrap(a - b), true
+	case "*":
+		return wrap(a * b), true
+	case "/", "%":
+		if b == 0 || (s == scalarInt && a == math.MinInt32 && b == -1) {
+			return 0, false
+		}
+		if op == "/" {
+			return wrap(a / b), true
+		}
+		return wrap(a % b), true
+	case "&":
+		return wrap(a & b), true
+	case "|":
+		return wrap(a | b), true
+	case "^":
+		return wrap(a ^ b), true
+	case "<<":
+		if b < 0 || b > 31 {
+			return 0, false
+		}
+		return wrap(a << uint(b)), true
+	case ">>":
+		if b < 0 || b > 31 {
+			return 0, false
+		}
+		if s == scalarUint {
+			return int64(uint32(a) >> uint(b)), true
+		}
+		return wrap(a >> uint(b)), true
+	}
+	return 0, false
+}
+
+func (t *translator) shift(e *binaryExpr, l, r value) (value, error) {
+	if l.ty.scalar == scalarFloat || r.ty.scalar == scalarFloat {
+		return value{}, errorAt(e.tok, "%s needs integer operands, got %s and %s", e.op, l.ty, r.ty)
+	}
+	if l.ty.scalar == scalarBool {
+		l = castTo(l, l.ty.withScalar(scalarInt))
+	}
+	if l.ty.kind == kindScalar && r.ty.kind == kindVector {
+		l = splat(l, r.ty.size)
+	}
+	if l.ty.kind == kindVector && r.ty.kind == kindVector && l.ty.size != r.ty.size {
+		n := min(l.ty.size, r.ty.size)
+		l, r = truncate(l, n), truncate(r, n)
+	}
+	if l.ival != nil && r.ival != nil {
+		if v, ok := foldInt(e.op, *l.ival, *r.ival, l.ty.scalar); ok {
+			return constInt(v, l.ty.scalar), nil
+		}
+	}
+	// WGSL shift amounts are u32 and shaped like the shifted value.
+	r = splat(castTo(r, r.ty.withScalar(scalarUint)), l.ty.vectorSize())
+	return derived("("+l.text+" "+e.op+" "+r.text+")", l.ty, l, r), nil
+}
+
+// matrixOp translates operators with a matrix operand. HLSL applies every
+// operator componentwise, including * and /; matrix products use mul.
+func (t *translator) matrixOp(e *binaryExpr, l, r value) (value, error) {
+	toFloat := func(v value) value {
+		if v.ty.isScalarOrVector() {
+			return castTo(v, v.ty.withScalar(scalarFloat))
+		}
+		return v
+	}
+	l, r = toFloat(l), toFloat(r)
+	lm, rm := l.ty.kind == kindMatrix, r.ty.kind == kindMatrix
+	switch e.op {
+	case "+", "-", "*", "/":
+	default:
+		return value{}, errorAt(e.tok, "unsupported operands for %s: %s and %s", e.op, l.ty, r.ty)
+	}
+	switch {
+	case lm && rm:
+		if !l.ty.equal(r.ty) {
+			return value{}, errorAt(e.tok, "%s operands %s and %s have different sizes", e.op, l.ty, r.ty)
+		}
+		if e.op == "+" || e.op == "-" {
+			return derived("("+l.text+" "+e.op+" "+r.text+")", l.ty, l, r), nil
+		}
+		if !l.pure || !r.pure {
+			return value{}, errorAt(e.tok, "componentwise %s of matrices that call functions is not supported; assign them to variables first", e.op)
+		}
+		rows := make([]string, l.ty.size)
+		for i := range rows {
+			rows[i] = fmt.Sprintf("%s[%d] %s %s[%d]", l.text, i, e.op, r.text, i)
+		}
+		return derived(l.ty.wgsl()+"("+strings.Join(rows, ", ")+")", l.ty, l, r), nil
+	case l.ty.kind == kindScalar || r.ty.kind == kindScalar:
+		ty := l.ty
+		if rm {
+			ty = r.ty
+		}
+		if e.op == "*" || (e.op == "/" && lm) {
+			return derived("("+l.text+" "+e.op+" "+r.text+")", ty, l, r), nil
+		}
+		// WGSL has no matrix and scalar + or -, so the scalar is
+		// widened to a matrix.
+		var err error
+		if lm {
+			r, err = t.convert(r, ty, e.tok)
+		} else {
+			l, err = t.convert(l, ty, e.tok)
+		}
+		if err != nil {
+			return value{}, err
+		}
+		return t.matrixOp(e, l, r)
+	}
+	return value{}, errorAt(e.tok, "%s of %s and %s is not supported; use mul for matrix products", e.op, l.ty, r.ty)
+}
+
+func (t *translator) ternary(e *ternaryExpr) (value, error) {
+	cond, err := t.rvalue(e.cond)
+	if err != nil {
+		return value{}, err
+	}
+	if !cond.ty.isScalarOrVector() {
+		return value{}, errorAt(e.tok, "?: condition must be a scalar or vector, got %s", cond.ty)
+	}
+	cond = castTo(cond, cond.ty.withScalar(scalarBool))
+	a, err := t.rvalue(e.then)
+	if err != nil {
+		return value{}, err
+	}
+	b, err := t.rvalue(e.els)
+	if err != nil {
+		return value{}, err
+	}
+	if !a.pure || !b.pure {
+		return value{}, errorAt(e.tok, "?: operands that call functions are not supported, since both are evaluated; use if/else")
+	}
+	if a.ty.isScalarOrVector() && b.ty.isScalarOrVector() {
+		vals, _, err := t.commonScalar(e.tok, a, b)
+		if err != nil {
+			return value{}, err
+		}
+		a, b = vals[0], vals[1]
+		if n := cond.ty.vectorSize(); n > 1 {
+			if a.ty.vectorSize() > 1 && a.ty.vectorSize() != n {
+				return value{}, errorAt(e.tok, "?: condition %s and operands %s have different sizes", cond.ty, a.ty)
+			}
+			a, b = splat(a, n), splat(b, n)
+		}
+	}
+	if !a.ty.equal(b.ty) {
+		return value{}, errorAt(e.tok, "?: operands have different types %s and %s", a.ty, b.ty)
+	}
+	if a.ty.isResource() || a.ty.hasRuntimeArray() {
+		return value{}, errorAt(e.tok, "?: on %s values is not supported", a.ty)
+	}
+	if cond.ty.kind == kindVector && !a.ty.isScalarOrVector() {
+		return value{}, errorAt(e.tok, "?: with a vector condition needs vector operands")
+	}
+	return derived("select("+b.text+", "+a.text+", "+cond.text+")", a.ty, cond, a, b), nil
+}
+
+func (t *translator) index(e *indexExpr) (value, error) {
+	x, err := t.expr(e.x)
+	if err != nil {
+		return value{}, err
+	}
+	if x.swizzle != nil || x.ty.colMajor || x.ty.hostBool {
+		if x, err = t.load(x, e.tok); err != nil {
+			return value{}, err
+		}
+	}
+	i, err := t.rvalue(e.index)
+	if err != nil {
+		return value{}, err
+	}
+	if x.ty.kind == kindTexture {
+		return t.textureIndex(e, x, i)
+	}
+	if !i.ty.isInteger() || i.ty.kind != kindScalar {
+		if i.ty.kind == kindScalar && i.ty.isNumeric() {
+			i = castTo(i, intType)
+		} else {
+			return value{}, errorAt(e.index.pos(), "index must be an int or uint, got %s", i.ty)
+		}
+	}
+	var ty *hlType
+	text := x.text + "[" + i.text + "]"
+	switch x.ty.kind {
+	case kindArray:
+		ty = x.ty.elem
+		if x.ty.length > 0 && i.ival != nil && (*i.ival < 0 || *i.ival >= int64(x.ty.length)) {
+			return value{}, errorAt(e.index.pos(), "index %d is out of bounds for %s", *i.ival, x.ty)
+		}
+		if x.ty.padded && ty.vectorSize() < 4 {
+			// Each element of a cbuffer array fills a register, which
+			// WGSL declares as a vec4.
+			text += "." + "xyzw"[:ty.vectorSize()]
+		}
+	case kindBuffer:
+		ty = x.ty.elem
+	case kindVector:
+		ty = scalarType(x.ty.scalar)
+	case kindMatrix:
+		// A WGSL column is an HLSL row.
+		ty = vectorType(scalarFloat, x.ty.cols)
+	default:
+		return value{}, errorAt(e.tok, "cannot index a %s", x.ty)
+	}
+	return value{text: text, ty: ty, ref: x.ref, mutable: x.mutable, root: x.root, konst: x.konst && i.konst, pure: x.pure && i.pure}, nil
+}
+
+func (t *translator) member(e *memberExpr) (value, error) {
+	x, err := t.expr(e.x)
+	if err != nil {
+		return value{}, err
+	}
+	if x.swizzle != nil || x.ty.hostBool {
+		if x, err = t.load(x, e.tok); err != nil {
+			return value{}, err
+		}
+	}
+	switch x.ty.kind {
+	case kindStruct:
+		_, m := x.ty.st.member(e.name)
+		if m == nil {
+			return value{}, errorAt(e.tok, "%s has no member %s", x.ty, e.name)
+		}
+		return value{text: x.text + "." + m.wgsl, ty: m.ty, ref: x.ref, mutable: x.mutable, root: x.root, pure: x.pure}, nil
+	case kindScalar, kindVector:
+		return t.swizzle(e, x)
+	case kindMatrix:
+		return t.matrixSwizzle(e, x)
+	}
+	return value{}, errorAt(e.tok, "%s has no members", x.ty)
+}
+
+// swizzleSets are the HLSL component name sets.
+var swizzleSets = []string{"xyzw", "rgba"}
+
+func (t *translator) swizzle(e *memberExpr, x value) (value, error) {
+	if x.ty.atomic {
+		return value{}, errorAt(e.tok, "cannot swizzle an atomic")
+	}
+	n := x.ty.vectorSize()
+	var set string
+	for _, s := range swizzleSets {
+		if strings.ContainsRune(s, rune(e.name[0])) {
+			set = s
+		}
+	}
+	if set == "" || len(e.name) > 4 {
+		return value{}, errorAt(e.tok, "invalid swizzle .%s", e.name)
+	}
+	comps := make([]byte, len(e.name))
+	for i := 0; i < len(e.name); i++ {
+		idx := strings.IndexByte(set, e.name[i])
+		if idx < 0 || idx >= n {
+			return value{}, errorAt(e.tok, "invalid swizzle .%s of %s", e.name, x.ty)
+		}
+		comps[i] = "xyzw"[idx]
+	}
+	ty := vectorType(x.ty.scalar, len(comps))
+	if x.ty.kind == kindScalar {
+		// Swizzling a scalar repeats it.
+		v, err := t.load(x, e.tok)
+		if err != nil {
+			return value{}, err
+		}
+		return splat(v, len(comps)), nil
+	}
+	v := value{text: x.text + "." + string(comps), ty: ty, root: x.root, konst: x.konst, pure: x.pure}
+	if x.ref {
+		if len(comps) == 1 {
+			v.ref, v.mutable = true, x.mutable
+		} else {
+			v.swizzle = &swizzleRef{base: x, components: string(comps)}
+		}
+	}
+	return v, nil
+}
+
+// matrixSwizzle translates the _m01 (zero-based) and _12 (one-based)
+// element names of a matrix, which may be repeated to form a vector.
+func (t *translator) matrixSwizzle(e *memberExpr, x value) (value, error) {
+	var elems [][2]int
+	for rest := e.name; rest != ""; {
+		var row, col int
+		switch {
+		case len(rest) >= 4 && rest[:2] == "_m":
+			row, col = int(rest[2]-'0'), int(rest[3]-'0')
+			rest = rest[4:]
+		case len(rest) >= 3 && rest[0] == '_':
+			row, col = int(rest[1]-'1'), int(rest[2]-'1')
+			rest = rest[3:]
+		default:
+			return value{}, errorAt(e.tok, "invalid matrix member .%s", e.name)
+		}
+		if row < 0 || row >= x.ty.size || col < 0 || col >= x.ty.cols {
+			return value{}, errorAt(e.tok, "invalid matrix member .%s of %s", e.name, x.ty)
+		}
+		elems = append(elems, [2]int{row, col})
+	}
+	if len(elems) > 4 {
+		return value{}, errorAt(e.tok, "invalid matrix member .%s", e.name)
+	}
+	if x.ty.colMajor {
+		var err error
+		if x, err = t.load(x, e.tok); err != nil {
+			return value{}, err
+		}
+	}
+	if len(elems) == 1 {
+		v := value{text: fmt.Sprintf("%s[%d][%d]", x.text, elems[0][0], elems[0][1]), ty: floatType, root: x.root, konst: x.konst, pure: x.pure}
+		v.ref, v.mutable = x.ref, x.mutable
+		return v, nil
+	}
+	if !x.pure {
+		return value{}, errorAt(e.tok, "matrix member .%s of an expression that calls functions is not supported", e.name)
+	}
+	parts := make([]string, len(elems))
+	for i, el := range elems {
+		parts[i] = fmt.Sprintf("%s[%d][%d]", x.text, el[0], el[1])
+	}
+	ty := vectorType(scalarFloat, len(elems))
+	return derived(ty.wgsl()+"("+strings.Join(parts, ", ")+")", ty, x), nil
+}
+
+// cast translates a C-style cast, which allows the conversions implicit
+// ones do plus (S)0 for a zero struct.
+func (t *translator) cast(e *castExpr) (value, error) {
+	ty, err := t.resolveType(e.ty, nil)
+	if err != nil {
+		return value{}, err
+	}
+	x, err := t.rvalue(e.x)
+	if err != nil {
+		return value{}, err
+	}
+	if ty.kind == kindStruct || ty.kind == kindArray {
+		if x.ty.equal(ty) {
+			return x, nil
+		}
+		if x.ival != nil && *x.ival == 0 && !ty.hasRuntimeArray() {
+			return derived(ty.wgsl()+"()", ty, x), nil
+		}
+		return value{}, errorAt(e.tok, "cannot cast %s to %s", x.ty, ty)
+	}
+	if ty.kind == kindVoid || ty.isResource() {
+		return value{}, errorAt(e.tok, "cannot cast to %s", ty)
+	}
+	return t.convert(x, ty, e.tok)
+}
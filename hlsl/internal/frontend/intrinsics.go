@@ -0,0 +1,671 @@
+// Copyright 2025 The GoGPU Authors
+// SPDX-License-Identifier: MIT
+
+package frontend
+
+import (
+	"fmt"
+	"strings"
+)
+
+// builtinFunc translates a call to an HLSL intrinsic function.
+type builtinFunc func(t *translator, e *callExpr) (value, error)
+
+// builtinFuncs are the HLSL intrinsics with a WGSL counterpart. It is
+// filled in by init, since its functions refer back to it through the
+// expression translator.
+var builtinFuncs map[string]builtinFunc
+
+func init() {
+	builtinFuncs = map[string]builtinFunc{
+		"abs":   numericFunc("abs", 1),
+		"min":   numericFunc("min", 2),
+		"max":   numericFunc("max", 2),
+		"clamp": numericFunc("clamp", 3),
+		"sign":  signFunc,
+		"mad":   madFunc,
+		"mul":   mulFunc,
+
+		"saturate":   floatFunc("saturate", 1),
+		"lerp":       floatFunc("mix", 3),
+		"frac":       floatFunc("fract", 1),
+		"rsqrt":      floatFunc("inverseSqrt", 1),
+		"atan2":      floatFunc("atan2", 2),
+		"pow":        floatFunc("pow", 2),
+		"fmod":       fmodFunc,
+		"log10":      log10Func,
+		"rcp":        rcpFunc,
+		"ldexp":      ldexpFunc,
+		"length":     floatReduce("length", 1),
+		"distance":   floatReduce("distance", 2),
+		"dot":        dotFunc,
+		"cross":      floatFunc("cross", 2),
+		"normalize":  floatFunc("normalize", 1),
+		"reflect":    floatFunc("reflect", 2),
+		"refract":    refractFunc,
+		"step":       floatFunc("step", 2),
+		"smoothstep": floatFunc("smoothstep", 3),
+		"sincos":     sincosFunc,
+
+		"ddx":        floatFunc("dpdx", 1),
+		"ddy":        floatFunc("dpdy", 1),
+		"ddx_coarse": floatFunc("dpdxCoarse", 1),
+		"ddy_coarse": floatFunc("dpdyCoarse", 1),
+		"ddx_fine":   floatFunc("dpdxFine", 1),
+		"ddy_fine":   floatFunc("dpdyFine", 1),
+		"fwidth":     floatFunc("fwidth", 1),
+		"clip":       clipFunc,
+
+		"any":      reduceFunc("any"),
+		"all":      reduceFunc("all"),
+		"isnan":    floatTest("(%[1]s != %[1]s)"),
+		"isinf":    floatTest("(abs(%[1]s) > 3.40282347e38f)"),
+		"isfinite": floatTest("(abs(%[1]s) <= 3.40282347e38f)"),
+
+		"asuint":  bitcastFunc(scalarUint),
+		"asint":   bitcastFunc(scalarInt),
+		"asfloat": bitcastFunc(scalarFloat),
+
+		"countbits":    bitFunc("countOneBits"),
+		"firstbithigh": bitFunc("firstLeadingBit"),
+		"firstbitlow":  bitFunc("firstTrailingBit"),
+		"reversebits":  bitFunc("reverseBits"),
+		"f32tof16":     f32tof16Func,
+		"f16tof32":     f16tof32Func,
+
+		"transpose":   transposeFunc,
+		"determinant": determinantFunc,
+
+		"GroupMemoryBarrier":               barrierFunc("workgroupBarrier"),
+		"GroupMemoryBarrierWithGroupSync":  barrierFunc("workgroupBarrier"),
+		"DeviceMemoryBarrier":              barrierFunc("storageBarrier"),
+		"DeviceMemoryBarrierWithGroupSync": barrierFunc("storageBarrier"),
+		"AllMemoryBarrier":                 barrierFunc("storageBarrier", "workgroupBarrier"),
+		"AllMemoryBarrierWithGroupSync":    barrierFunc("storageBarrier", "workgroupBarrier"),
+
+		"InterlockedAdd":             interlockedFunc("atomicAdd"),
+		"InterlockedMin":             interlockedFunc("atomicMin"),
+		"InterlockedMax":             interlockedFunc("atomicMax"),
+		"InterlockedAnd":             interlockedFunc("atomicAnd"),
+		"InterlockedOr":              interlockedFunc("atomicOr"),
+		"InterlockedXor":             interlockedFunc("atomicXor"),
+		"InterlockedExchange":        interlockedFunc("atomicExchange"),
+		"InterlockedCompareExchange": compareExchangeFunc(true),
+		"InterlockedCompareStore":    compareExchangeFunc(false),
+	}
+	for hlsl, wgsl := range map[string]string{
+		"radians": "radians", "degrees": "degrees",
+		"sin": "sin", "cos": "cos", "tan": "tan", "asin": "asin", "acos": "acos", "atan": "atan",
+		"sinh": "sinh", "cosh": "cosh", "tanh": "tanh",
+		"exp": "exp", "log": "log", "exp2": "exp2", "log2": "log2", "sqrt": "sqrt",
+		"floor": "floor", "ceil": "ceil", "trunc": "trunc", "round": "round",
+	} {
+		builtinFuncs[hlsl] = floatFunc(wgsl, 1)
+	}
+}
+
+// statement writes the translation of an intrinsic that is a whole WGSL
+// statement and returns its void value.
+func (t *translator) statement(e *callExpr, format string, args ...any) value {
+	t.w.Line(e.tok.line, format, args...)
+	return value{ty: voidType, stmt: true}
+}
+
+// shape splats scalar arguments to the shortest vector among them, after
+// commonScalar truncated longer ones, since WGSL built-ins take matching
+// argument types.
+func shape(vals []value) []value {
+	n := 1
+	for _, v := range vals {
+		n = max(n, v.ty.vectorSize())
+	}
+	for i, v := range vals {
+		vals[i] = splat(v, n)
+	}
+	return vals
+}
+
+// floatArgs translates the arguments of a float function, converting
+// them to float and to a common shape.
+func (t *translator) floatArgs(e *callExpr, n int) ([]value, error) {
+	vals, err := t.args(e, n)
+	if err != nil {
+		return nil, err
+	}
+	for i, v := range vals {
+		if !v.ty.isScalarOrVector() {
+			return nil, errorAt(e.args[i].pos(), "%s takes scalar or vector arguments, got %s", e.callee.name, v.ty)
+		}
+		vals[i] = castTo(v, v.ty.withScalar(scalarFloat))
+	}
+	if vals, _, err = t.commonScalar(e.tok, vals...); err != nil {
+		return nil, err
+	}
+	return shape(vals), nil
+}
+
+func floatFunc(name string, n int) builtinFunc {
+	return func(t *translator, e *callExpr) (value, error) {
+		vals, err := t.floatArgs(e, n)
+		if err != nil {
+			return value{}, err
+		}
+		ty := vals[0].ty
+		if name == "cross" && ty.vectorSize() != 3 {
+			return value{}, errorAt(e.tok, "cross takes float3 arguments")
+		}
+		return derived(name+"("+joinText(vals)+")", ty, vals...), nil
+	}
+}
+
+// floatReduce is a float function returning a scalar.
+func floatReduce(name string, n int) builtinFunc {
+	return func(t *translator, e *callExpr) (value, error) {
+		vals, err := t.floatArgs(e, n)
+		if err != nil {
+			return value{}, err
+		}
+		if vals[0].ty.kind == kindScalar {
+			switch name {
+			case "length":
+				return derived("abs("+vals[0].text+")", floatType, vals...), nil
+			case "distance":
+				return derived("abs("+vals[0].text+" - "+vals[1].text+")", floatType, vals...), nil
+			}
+		}
+		return derived(name+"("+joinText(vals)+")", floatType, vals...), nil
+	}
+}
+
+func numericFunc(name string, n int) builtinFunc {
+	return func(t *translator, e *callExpr) (value, error) {
+		vals, err := t.args(e, n)
+		if err != nil {
+			return value{}, err
+		}
+		vals, s, err := t.commonScalar(e.tok, vals...)
+		if err != nil {
+			return value{}, err
+		}
+		if s == scalarBool {
+			for i, v := range vals {
+				vals[i] = castTo(v, v.ty.withScalar(scalarInt))
+			}
+		}
+		vals = shape(vals)
+		return derived(name+"("+joinText(vals)+")", vals[0].ty, vals...), nil
+	}
+}
+
+// signFunc translates sign, whose result is an int.
+func signFunc(t *translator, e *callExpr) (value, error) {
+	vals, err := t.args(e, 1)
+	if err != nil {
+		return value{}, err
+	}
+	v := vals[0]
+	if !v.ty.isNumeric() {
+		return value{}, errorAt(e.tok, "sign takes a numeric argument, got %s", v.ty)
+	}
+	if v.ty.scalar == scalarUint {
+		v = castTo(v, v.ty.withScalar(scalarInt))
+	}
+	return castTo(derived("sign("+v.text+")", v.ty, v), v.ty.withScalar(scalarInt)), nil
+}
+
+// madFunc translates mad, which is fma for floats.
+func madFunc(t *translator, e *callExpr) (value, error) {
+	vals, err := t.args(e, 3)
+	if err != nil {
+		return value{}, err
+	}
+	vals, s, err := t.commonScalar(e.tok, vals...)
+	if err != nil {
+		return value{}, err
+	}
+	vals = shape(vals)
+	if s == scalarFloat {
+		return derived("fma("+joinText(vals)+")", vals[0].ty, vals...), nil
+	}
+	return derived("("+vals[0].text+" * "+vals[1].text+" + "+vals[2].text+")", vals[0].ty, vals...), nil
+}
+
+// fmodFunc translates fmod, the remainder truncated toward zero, which is
+// what WGSL's % computes for floats.
+func fmodFunc(t *translator, e *callExpr) (value, error) {
+	vals, err := t.floatArgs(e, 2)
+	if err != nil {
+		return value{}, err
+	}
+	return derived("("+vals[0].text+" % "+vals[1].text+")", vals[0].ty, vals...), nil
+}
+
+func log10Func(t *translator, e *callExpr) (value, error) {
+	vals, err := t.floatArgs(e, 1)
+	if err != nil {
+		return value{}, err
+	}
+	return derived("(log2("+vals[0].text+") * 0.30103f)", vals[0].ty, vals...), nil
+}
+
+func rcpFunc(t *translator, e *callExpr) (value, error) {
+	vals, err := t.floatArgs(e, 1)
+	if err != nil {
+		return value{}, err
+	}
+	one := splat(constFloat(1), vals[0].ty.vectorSize())
+	return derived("("+one.text+" / "+vals[0].text+")", vals[0].ty, vals...), nil
+}
+
+func ldexpFunc(t *translator, e *callExpr) (value, error) {
+	vals, err := t.floatArgs(e, 2)
+	if err != nil {
+		return value{}, err
+	}
+	exp := castTo(vals[1], vals[1].ty.withScalar(scalarInt))
+	return derived("ldexp("+vals[0].text+", "+exp.text+")", vals[0].ty, vals[0], exp), nil
+}
+
+// dotFunc translates dot, which HLSL also defines on int vectors.
+func dotFunc(t *translator, e *callExpr) (value, error) {
+	vals, err := t.args(e, 2)
+	if err != nil {
+		return value{}, err
+	}
+	return t.dot(e, vals)
+}
+
+func (t *translator) dot(e *callExpr, vals []value) (value, error) {
+	vals, s, err := t.commonScalar(e.tok, vals...)
+	if err != nil {
+		return value{}, err
+	}
+	if s == scalarBool {
+		for i, v := range vals {
+			vals[i] = castTo(v, v.ty.withScalar(scalarInt))
+		}
+	}
+	vals = shape(vals)
+	ty := scalarType(vals[0].ty.scalar)
+	if vals[0].ty.kind == kindScalar {
+		return derived("("+vals[0].text+" * "+vals[1].text+")", ty, vals...), nil
+	}
+	return derived("dot("+joinText(vals)+")", ty, vals...), nil
+}
+
+func refractFunc(t *translator, e *callExpr) (value, error) {
+	vals, err := t.args(e, 3)
+	if err != nil {
+		return value{}, err
+	}
+	vecs, _, err := t.commonScalar(e.tok, vals[0], vals[1])
+	if err != nil {
+		return value{}, err
+	}
+	eta, err := t.convert(vals[2], floatType, e.args[2].pos())
+	if err != nil {
+		return value{}, err
+	}
+	i, n := castTo(vecs[0], vecs[0].ty.withScalar(scalarFloat)), castTo(vecs[1], vecs[1].ty.withScalar(scalarFloat))
+	if i.ty.kind != kindVector {
+		return value{}, errorAt(e.tok, "refract takes vectors, got %s", i.ty)
+	}
+	return derived("refract("+i.text+", "+n.text+", "+eta.text+")", i.ty, i, n, eta), nil
+}
+
+// mulFunc translates mul. A WGSL column is an HLSL row, so vector-matrix
+// products swap their operands.
+func mulFunc(t *translator, e *callExpr) (value, error) {
+	vals, err := t.args(e, 2)
+	if err != nil {
+		return value{}, err
+	}
+	if a, b := vals[0].ty, vals[1].ty; a.isScalarOrVector() && b.isScalarOrVector() && (a.kind == kindVector) == (b.kind == kindVector) {
+		return t.dot(e, vals)
+	}
+	for i, v := range vals {
+		if v.ty.isScalarOrVector() {
+			vals[i] = castTo(v, v.ty.withScalar(scalarFloat))
+		} else if v.ty.kind != kindMatrix {
+			return value{}, errorAt(e.args[i].pos(), "mul takes scalars, vectors, and matrices, got %s", v.ty)
+		}
+	}
+	a, b := vals[0], vals[1]
+	switch {
+	case a.ty.kind == kindScalar || b.ty.kind == kindScalar:
+		ty := a.ty
+		if a.ty.kind == kindScalar {
+			ty = b.ty
+		}
+		return derived("("+a.text+" * "+b.text+")", ty, a, b), nil
+	case a.ty.kind == kindVector && b.ty.kind == kindMatrix:
+		if a.ty.size != b.ty.size {
+			return value{}, errorAt(e.tok, "mul of %s and %s: sizes do not match", a.ty, b.ty)
+		}
+		return derived("("+b.text+" * "+a.text+")", vectorType(scalarFloat, b.ty.cols), a, b), nil
+	case a.ty.kind == kindMatrix && b.ty.kind == kindVector:
+		if a.ty.cols != b.ty.size {
+			return value{}, errorAt(e.tok, "mul of %s and %s: sizes do not match", a.ty, b.ty)
+		}
+		return derived("("+b.text+" * "+a.text+")", vectorType(scalarFloat, a.ty.size), a, b), nil
+	}
+	if a.ty.cols != b.ty.size {
+		return value{}, errorAt(e.tok, "mul of %s and %s: sizes do not match", a.ty, b.ty)
+	}
+	return derived("("+b.text+" * "+a.text+")", matrixType(a.ty.size, b.ty.cols), a, b), nil
+}
+
+// sincosFunc translates sincos(x, out s, out c).
+func sincosFunc(t *translator, e *callExpr) (value, error) {
+	if err := argCount(e, 3); err != nil {
+		return value{}, err
+	}
+	x, err := t.rvalue(e.args[0])
+	if err != nil {
+		return value{}, err
+	}
+	if !x.ty.isScalarOrVector() {
+		return value{}, errorAt(e.args[0].pos(), "sincos takes a float argument, got %s", x.ty)
+	}
+	x = castTo(x, x.ty.withScalar(scalarFloat))
+	if !x.pure {
+		tmp := t.temp("angle")
+		t.w.Line(e.tok.line, "let %s = %s;", tmp, x.text)
+		x = value{text: tmp, ty: x.ty, pure: true}
+	}
+	for i, fn := range []string{"sin", "cos"} {
+		lhs, err := t.target(e.args[i+1])
+		if err != nil {
+			return value{}, err
+		}
+		v, err := t.convert(derived(fn+"("+x.text+")", x.ty, x), lhs.ty.value(), e.args[i+1].pos())
+		if err != nil {
+			return value{}, err
+		}
+		if err := t.store(lhs, v, e.tok); err != nil {
+			return value{}, err
+		}
+	}
+	return value{ty: voidType, stmt: true}, nil
+}
+
+// clipFunc translates clip, which discards when any component is
+// negative.
+func clipFunc(t *translator, e *callExpr) (value, error) {
+	vals, err := t.floatArgs(e, 1)
+	if err != nil {
+		return value{}, err
+	}
+	v := vals[0]
+	if t.fn.stage != "" && t.fn.stage != "fragment" {
+		return value{}, errorAt(e.tok, "clip is only available in fragment shaders")
+	}
+	if v.ty.kind == kindVector {
+		return t.statement(e, "if any(%s < %s) { discard; }", v.text, zero(v.ty)), nil
+	}
+	return t.statement(e, "if %s < 0.0f { discard; }", v.text), nil
+}
+
+// reduceFunc translates any and all, which test numeric components
+// against zero.
+func reduceFunc(name string) builtinFunc {
+	return func(t *translator, e *callExpr) (value, error) {
+		vals, err := t.args(e, 1)
+		if err != nil {
+			return value{}, err
+		}
+		v := vals[0]
+		if !v.ty.isScalarOrVector() {
+			return value{}, errorAt(e.tok, "%s takes a scalar or vector, got %s", name, v.ty)
+		}
+		v = castTo(v, v.ty.withScalar(scalarBool))
+		if v.ty.kind == kindScalar {
+			return v, nil
+		}
+		return derived(name+"("+v.text+")", boolType, v), nil
+	}
+}
+
+// floatTest translates a per-component float classification; format
+// spells it with the argument, which must be pure.
+func floatTest(format string) builtinFunc {
+	return func(t *translator, e *callExpr) (value, error) {
+		vals, err := t.floatArgs(e, 1)
+		if err != nil {
+			return value{}, err
+		}
+		v := vals[0]
+		if !v.pure {
+			return value{}, errorAt(e.tok, "%s of an expression that calls functions is not supported; assign it to a variable first", e.callee.name)
+		}
+		return derived(fmt.Sprintf(format, v.text), v.ty.withScalar(scalarBool), v), nil
+	}
+}
+
+func bitcastFunc(to scalarKind) builtinFunc {
+	return func(t *translator, e *callExpr) (value, error) {
+		vals, err := t.args(e, 1)
+		if err != nil {
+			return value{}, err
+		}
+		v := vals[0]
+		if !v.ty.isNumeric() {
+			return value{}, errorAt(e.args[0].pos(), "%s takes a numeric argument, got %s", e.callee.name, v.ty)
+		}
+		ty := v.ty.withScalar(to)
+		if v.ty.scalar == to {
+			return v, nil
+		}
+		return derived("bitcast<"+ty.wgsl()+">("+v.text+")", ty, v), nil
+	}
+}
+
+// bitFunc translates a bit manipulation intrinsic, which takes and
+// returns uint.
+func bitFunc(name string) builtinFunc {
+	return func(t *translator, e *callExpr) (value, error) {
+		vals, err := t.args(e, 1)
+		if err != nil {
+			return value{}, err
+		}
+		v := vals[0]
+		if !v.ty.isInteger() {
+			return value{}, errorAt(e.args[0].pos(), "%s takes an integer argument, got %s", e.callee.name, v.ty)
+		}
+		if name != "firstLeadingBit" {
+			v = castTo(v, v.ty.withScalar(scalarUint))
+		}
+		res := derived(name+"("+v.text+")", v.ty, v)
+		return castTo(res, v.ty.withScalar(scalarUint)), nil
+	}
+}
+
+func f32tof16Func(t *translator, e *callExpr) (value, error) {
+	vals, err := t.args(e, 1)
+	if err != nil {
+		return value{}, err
+	}
+	v, err := t.convert(vals[0], floatType, e.args[0].pos())
+	if err != nil {
+		return value{}, err
+	}
+	return derived("pack2x16float(vec2<f32>("+v.text+", 0.0f))", uintType, v), nil
+}
+
+func f16tof32Func(t *translator, e *callExpr) (value, error) {
+	vals, err := t.args(e, 1)
+	if err != nil {
+		return value{}, err
+	}
+	v, err := t.convert(vals[0], uintType, e.args[0].pos())
+	if err != nil {
+		return value{}, err
+	}
+	return derived("unpack2x16float("+v.text+").x", floatType, v), nil
+}
+
+func transposeFunc(t *translator, e *callExpr) (value, error) {
+	vals, err := t.args(e, 1)
+	if err != nil {
+		return value{}, err
+	}
+	m := vals[0]
+	if m.ty.kind != kindMatrix {
+		return value{}, errorAt(e.tok, "transpose takes a matrix, got %s", m.ty)
+	}
+	return derived("transpose("+m.text+")", matrixType(m.ty.cols, m.ty.size), m), nil
+}
+
+func determinantFunc(t *translator, e *callExpr) (value, error) {
+	vals, err := t.args(e, 1)
+	if err != nil {
+		return value{}, err
+	}
+	m := vals[0]
+	if m.ty.kind != kindMatrix || m.ty.cols != m.ty.size {
+		return value{}, errorAt(e.tok, "determinant takes a square matrix, got %s", m.ty)
+	}
+	return derived("determinant("+m.text+")", floatType, m), nil
+}
+
+func barrierFunc(names ...string) builtinFunc {
+	return func(t *translator, e *callExpr) (value, error) {
+		if err := argCount(e, 0); err != nil {
+			return value{}, err
+		}
+		calls := make([]string, len(names))
+		for i, n := range names {
+			calls[i] = n + "();"
+		}
+		return t.statement(e, "%s", strings.Join(calls, " ")), nil
+	}
+}
+
+// atomicTarget translates the memory argument of an Interlocked function.
+// When marking, it marks the location atomic; otherwise it must already
+// be.
+func (t *translator) atomicTarget(e *callExpr) (value, error) {
+	mem, err := t.expr(e.args[0])
+	if err != nil {
+		return value{}, err
+	}
+	at := e.args[0].pos()
+	if !mem.ref || mem.swizzle != nil || mem.ty.kind != kindScalar || !mem.ty.isInteger() {
+		return value{}, errorAt(at, "%s needs an int or uint groupshared variable or RWStructuredBuffer element", e.callee.name)
+	}
+	if !mem.mutable || mem.root == nil || (mem.root.space != "workgroup" && mem.root.space != "storage") {
+		return value{}, errorAt(at, "%s needs groupshared memory or a RWStructuredBuffer", e.callee.name)
+	}
+	if t.marking {
+		mem.ty.atomic = true
+	} else if !mem.ty.atomic {
+		return value{}, errorAt(at, "%s needs groupshared memory or a RWStructuredBuffer", e.callee.name)
+	}
+	return mem, nil
+}
+
+// atomicOperands translates the operands of an Interlocked function to
+// the memory's type.
+func (t *translator) atomicOperands(e *callExpr, ty *hlType, args []expr) ([]string, error) {
+	ops := make([]string, len(args))
+	for i, a := range args {
+		v, err := t.rvalue(a)
+		if err != nil {
+			return nil, err
+		}
+		if v, err = t.convert(v, ty, a.pos()); err != nil {
+			return nil, err
+		}
+		ops[i] = v.text
+	}
+	return ops, nil
+}
+
+// interlockedFunc translates InterlockedOp(dest, value[, original]).
+func interlockedFunc(name string) builtinFunc {
+	return func(t *translator, e *callExpr) (value, error) {
+		counts := []int{2, 3}
+		if name == "atomicExchange" {
+			counts = []int{3}
+		}
+		if err := argCount(e, counts...); err != nil {
+			return value{}, err
+		}
+		mem, err := t.atomicTarget(e)
+		if err != nil {
+			return value{}, err
+		}
+		ty := mem.ty.value()
+		ops, err := t.atomicOperands(e, ty, e.args[1:2])
+		if err != nil {
+			return value{}, err
+		}
+		call := value{text: name + "(&" + mem.text + ", " + ops[0] + ")", ty: ty}
+		if len(e.args) == 2 {
+			return t.statement(e, "%s;", call.text), nil
+		}
+		return t.storeOriginal(e, call, e.args[2])
+	}
+}
+
+// storeOriginal stores the value an atomic returns in the original
+// argument of an Interlocked function.
+func (t *translator) storeOriginal(e *callExpr, call value, arg expr) (value, error) {
+	lhs, err := t.target(arg)
+	if err != nil {
+		return value{}, err
+	}
+	v, err := t.convert(call, lhs.ty.value(), arg.pos())
+	if err != nil {
+		return value{}, err
+	}
+	if err := t.store(lhs, v, e.tok); err != nil {
+		return value{}, err
+	}
+	return value{ty: voidType, stmt: true}, nil
+}
+
+// compareExchangeFunc translates InterlockedCompareExchange(dest,
+// compare, value, original) and InterlockedCompareStore(dest, compare,
+// value). WGSL's compare-exchange may fail spuriously, so it is retried
+// until it exchanges or finds a different value.
+func compareExchangeFunc(original bool) builtinFunc {
+	return func(t *translator, e *callExpr) (value, error) {
+		n := 3
+		if original {
+			n = 4
+		}
+		if err := argCount(e, n); err != nil {
+			return value{}, err
+		}
+		mem, err := t.atomicTarget(e)
+		if err != nil {
+			return value{}, err
+		}
+		ty := mem.ty.value()
+		ops, err := t.atomicOperands(e, ty, e.args[1:3])
+		if err != nil {
+			return value{}, err
+		}
+		line := e.tok.line
+		cmp, val, res := t.temp("compare"), t.temp("exchange"), t.temp("result")
+		t.w.Line(line, "let %s = %s;", cmp, ops[0])
+		t.w.Line(line, "let %s = %s;", val, ops[1])
+		t.w.Line(line, "loop {")
+		t.w.Indent++
+		t.w.Line(line, "let %s = atomicCompareExchangeWeak(&%s, %s, %s);", res, mem.text, cmp, val)
+		t.w.Line(line, "if %s.exchanged || %s.old_value != %s {", res, res, cmp)
+		t.w.Indent++
+		if original {
+			if _, err := t.storeOriginal(e, value{text: res + ".old_value", ty: ty, pure: true}, e.args[3]); err != nil {
+				return value{}, err
+			}
+		}
+		t.w.Line(line, "break;")
+		t.w.Indent--
+		t.w.Line(line, "}")
+		t.w.Indent--
+		t.w.Line(line, "}")
+		return value{ty: voidType, stmt: true}, nil
+	}
+}
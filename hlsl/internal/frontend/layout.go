@@ -0,0 +1,218 @@
+// Copyright 2025 The GoGPU Authors
+// SPDX-License-Identifier: MIT
+
+package frontend
+
+import "fmt"
+
+// A layout places struct members in memory by the rules of one language
+// and address space. HLSL and WGSL disagree on several of them, so
+// buffers whose HLSL and WGSL layouts differ are rejected rather than
+// silently reading the wrong bytes.
+type layout interface {
+	// offset returns where a member of type ty is placed when the
+	// previous member, prev (nil for the first), ends at off.
+	offset(off int, ty, prev *hlType) int
+	size(ty *hlType) int
+	stride(elem *hlType) int
+}
+
+func roundUp(n, align int) int { return (n + align - 1) / align * align }
+
+// hlslLayout is the cbuffer register packing when cbuffer is set, and the
+// tight packing of structured buffers otherwise.
+type hlslLayout struct{ cbuffer bool }
+
+func (l hlslLayout) offset(off int, ty, prev *hlType) int {
+	if !l.cbuffer {
+		return off
+	}
+	// A scalar or vector may not straddle a 16-byte register; anything
+	// else starts a register, as does the member after a struct.
+	if ty.isScalarOrVector() && (prev == nil || prev.kind != kindStruct) {
+		if off%16+l.size(ty) > 16 {
+			return roundUp(off, 16)
+		}
+		return off
+	}
+	return roundUp(off, 16)
+}
+
+func (l hlslLayout) size(ty *hlType) int {
+	switch ty.kind {
+	case kindScalar:
+		return 4
+	case kindVector:
+		return 4 * ty.size
+	case kindMatrix:
+		regs, comps := ty.size, ty.cols
+		if ty.colMajor {
+			regs, comps = comps, regs
+		}
+		if l.cbuffer {
+			return (regs-1)*16 + comps*4
+		}
+		return regs * comps * 4
+	case kindArray:
+		return (ty.length-1)*l.stride(ty.elem) + l.size(ty.elem)
+	case kindStruct:
+		off := 0
+		var prev *hlType
+		for _, m := range ty.st.members {
+			off = l.offset(off, m.ty, prev) + l.size(m.ty)
+			prev = m.ty
+		}
+		return off
+	}
+	return 0
+}
+
+func (l hlslLayout) stride(elem *hlType) int {
+	if l.cbuffer {
+		return roundUp(l.size(elem), 16)
+	}
+	return l.size(elem)
+}
+
+// wgslLayout is the WGSL layout of the uniform address space when uniform
+// is set, and of the storage address space otherwise.
+type wgslLayout struct{ uniform bool }
+
+func (l wgslLayout) offset(off int, ty, _ *hlType) int { return roundUp(off, l.align(ty)) }
+
+func (l wgslLayout) align(ty *hlType) int {
+	switch ty.kind {
+	case kindScalar:
+		return 4
+	case kindVector:
+		return vectorAlign(ty.size)
+	case kindMatrix:
+		_, rows := matrixShape(ty)
+		return vectorAlign(rows)
+	case kindArray:
+		a := l.align(l.element(ty))
+		if l.uniform {
+			a = roundUp(a, 16)
+		}
+		return a
+	case kindStruct:
+		a := 4
+		for _, m := range ty.st.members {
+			a = max(a, l.align(m.ty))
+		}
+		if l.uniform {
+			a = roundUp(a, 16)
+		}
+		return a
+	}
+	return 4
+}
+
+func (l wgslLayout) size(ty *hlType) int {
+	switch ty.kind {
+	case kindScalar:
+		return 4
+	case kindVector:
+		return 4 * ty.size
+	case kindMatrix:
+		cols, rows := matrixShape(ty)
+		return cols * roundUp(4*rows, vectorAlign(rows))
+	case kindArray:
+		return ty.length * l.stride(l.element(ty))
+	case kindStruct:
+		off := 0
+		for _, m := range ty.st.members {
+			off = l.offset(off, m.ty, nil) + l.size(m.ty)
+		}
+		return roundUp(off, l.align(ty))
+	}
+	return 0
+}
+
+func (l wgslLayout) stride(elem *hlType) int { return roundUp(l.size(elem), l.align(elem)) }
+
+// element returns the WGSL element type of an array, which is a
+// four-component vector for the padded arrays of a cbuffer.
+func (l wgslLayout) element(ty *hlType) *hlType {
+	if ty.padded {
+		return vectorType(ty.elem.scalar, 4)
+	}
+	return ty.elem
+}
+
+func vectorAlign(n int) int {
+	if n == 2 {
+		return 8
+	}
+	return 16
+}
+
+// matrixShape returns the columns and rows of the WGSL matrix declaring
+// ty.
+func matrixShape(ty *hlType) (cols, rows int) {
+	if ty.colMajor {
+		return ty.cols, ty.size
+	}
+	return ty.size, ty.cols
+}
+
+// checkCBufferLayout reports a cbuffer member whose offset in the HLSL
+// register packing differs from its offset in the WGSL uniform struct.
+func checkCBufferLayout(st *structInfo) error {
+	return compareLayout("cbuffer member", "", st, hlslLayout{cbuffer: true}, wgslLayout{uniform: true})
+}
+
+// structuredStride returns the stride of a structured buffer element,
+// reporting an element whose HLSL layout differs from its WGSL storage
+// layout.
+func structuredStride(elem *hlType, at token) (int, error) {
+	h, w := hlslLayout{}, wgslLayout{}
+	if elem.kind == kindStruct {
+		if err := compareLayout("structured buffer member", "", elem.st, h, w); err != nil {
+			return 0, err
+		}
+	} else if err := compareType("structured buffer element", elem.String(), at, elem, h, w); err != nil {
+		return 0, err
+	}
+	if hs, ws := h.stride(elem), w.stride(elem); hs != ws {
+		return 0, errorAt(at, "structured buffer element %s has a stride of %d bytes in HLSL but %d in WGSL; pad it to a multiple of 16 bytes", elem, hs, ws)
+	}
+	return h.stride(elem), nil
+}
+
+func compareLayout(what, prefix string, st *structInfo, h, w layout) error {
+	hoff, woff := 0, 0
+	var prev *hlType
+	for _, m := range st.members {
+		name := prefix + m.name
+		hoff, woff = h.offset(hoff, m.ty, prev), w.offset(woff, m.ty, prev)
+		if hoff != woff {
+			return errorAt(m.tok, "%s %s is at byte offset %d in HLSL but %d in WGSL; reorder members or add explicit padding", what, name, hoff, woff)
+		}
+		if err := compareType(what, name, m.tok, m.ty, h, w); err != nil {
+			return err
+		}
+		hoff += h.size(m.ty)
+		woff += w.size(m.ty)
+		prev = m.ty
+	}
+	return nil
+}
+
+// compareType compares the layouts inside a member of type ty.
+func compareType(what, name string, at token, ty *hlType, h, w layout) error {
+	switch ty.kind {
+	case kindStruct:
+		return compareLayout(what, name+".", ty.st, h, w)
+	case kindArray:
+		elem := ty.elem
+		if wl, ok := w.(wgslLayout); ok {
+			elem = wl.element(ty)
+		}
+		if hs, ws := h.stride(ty.elem), w.stride(elem); hs != ws {
+			return errorAt(at, "%s %s has an array stride of %d bytes in HLSL but %d in WGSL", what, name, hs, ws)
+		}
+		return compareType(what, fmt.Sprintf("%s[0]", name), at, ty.elem, h, w)
+	}
+	return nil
+}
@@ -0,0 +1,643 @@
+// Copyright 2025 The GoGPU Authors
+// SPDX-License-Identifier: MIT
+
+package frontend
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// tokenKind classifies an HLSL token.
+type tokenKind uint8
+
+const (
+	tokEOF tokenKind = iota
+	tokIdent
+	tokInt    // int literal; text holds the literal without suffix
+	tokUint   // uint literal (u/U suffix); text holds the literal without suffix
+	tokFloat  // float literal; text holds the literal without suffix
+	tokString // string literal, as in [shader("compute")]; text holds the contents
+	tokPunct
+
+	// tokPackMatrix is #pragma pack_matrix; text is row_major or
+	// column_major.
+	tokPackMatrix
+)
+
+// token is one lexed token. Line and Column are 1-based and, for tokens
+// produced by a macro expansion, locate the macro use.
+type token struct {
+	kind   tokenKind
+	text   string
+	line   int
+	column int
+}
+
+// Error is a frontend error located in the HLSL source.
+type Error struct {
+	Line    int
+	Column  int
+	Message string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("%d:%d: %s", e.Line, e.Column, e.Message)
+}
+
+func errorAt(tok token, format string, args ...any) *Error {
+	return &Error{Line: tok.line, Column: tok.column, Message: fmt.Sprintf(format, args...)}
+}
+
+// punctuators lists HLSL operators, longest first so that lexing is greedy.
+var punctuators = []string{
+	"<<=", ">>=",
+	"++", "--", "<<", ">>", "<=", ">=", "==", "!=", "&&", "||", "::",
+	"+=", "-=", "*=", "/=", "%=", "&=", "|=", "^=",
+	"(", ")", "[", "]", "{", "}", ".", ",", ";", ":", "?", "=",
+	"+", "-", "*", "/", "%", "<", ">", "!", "~", "&", "|", "^",
+}
+
+// conditional is an open #if, #ifdef, or #ifndef group.
+type conditional struct {
+	tok token
+	// active is whether the current branch is compiled; taken whether
+	// any branch so far was; outer whether the enclosing group is active.
+	active, taken, outer bool
+	sawElse              bool
+}
+
+// lexer splits HLSL source into tokens and runs the part of the
+// preprocessor this frontend supports: object-like #define macros,
+// conditional compilation, and #pragma pack_matrix.
+type lexer struct {
+	src    string
+	pos    int
+	line   int
+	column int
+
+	macros map[string][]token
+	conds  []conditional
+	tokens []token
+}
+
+// lex tokenizes src, returning the tokens terminated by tokEOF. defines
+// are object-like macros defined before the source; an empty value
+// defines the macro as 1.
+func lex(src string, defines map[string]string) ([]token, error) {
+	l := &lexer{src: src, line: 1, column: 1, macros: make(map[string][]token)}
+	names := make([]string, 0, len(defines))
+	for name := range defines {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		body := defines[name]
+		if body == "" {
+			body = "1"
+		}
+		if err := l.define(token{line: 1, column: 1}, name+" "+body); err != nil {
+			return nil, err
+		}
+	}
+	if err := l.run(); err != nil {
+		return nil, err
+	}
+	return l.tokens, nil
+}
+
+func (l *lexer) here() token { return token{line: l.line, column: l.column} }
+
+func (l *lexer) advance(n int) {
+	for i := 0; i < n && l.pos < len(l.src); i++ {
+		if l.src[l.pos] == '\n' {
+			l.line++
+			l.column = 1
+		} else {
+			l.column++
+		}
+		l.pos++
+	}
+}
+
+// active reports whether the current conditional group is compiled.
+func (l *lexer) active() bool {
+	return len(l.conds) == 0 || l.conds[len(l.conds)-1].active
+}
+
+func (l *lexer) run() error {
+	atLineStart := true
+	for l.pos < len(l.src) {
+		c := l.src[l.pos]
+		switch {
+		case c == '\n':
+			l.advance(1)
+			atLineStart = true
+			continue
+		case c == ' ' || c == '\t' || c == '\r' || c == '\f' || c == '\v':
+			l.advance(1)
+			continue
+		case strings.HasPrefix(l.src[l.pos:], "//"):
+			l.skipLine()
+			continue
+		case strings.HasPrefix(l.src[l.pos:], "/*"):
+			start := l.here()
+			end := strings.Index(l.src[l.pos+2:], "*/")
+			if end < 0 {
+				return errorAt(start, "unterminated block comment")
+			}
+			l.advance(end + 4)
+			continue
+		case c == '#':
+			if !atLineStart {
+				return errorAt(l.here(), "'#' must start a preprocessor line")
+			}
+			if err := l.directive(); err != nil {
+				return err
+			}
+			continue
+		}
+		if !l.active() {
+			l.skipLine()
+			continue
+		}
+		atLineStart = false
+
+		tok, err := l.next()
+		if err != nil {
+			return err
+		}
+		if tok.kind == tokIdent {
+			if body, ok := l.macros[tok.text]; ok {
+				l.tokens = l.expand(l.tokens, tok, body, map[string]bool{tok.text: true})
+				continue
+			}
+		}
+		l.tokens = append(l.tokens, tok)
+	}
+	if n := len(l.conds); n > 0 {
+		return errorAt(l.conds[n-1].tok, "unterminated conditional; missing #endif")
+	}
+	l.tokens = append(l.tokens, token{kind: tokEOF, line: l.line, column: l.column})
+	return nil
+}
+
+// skipLine moves to the end of the current line.
+func (l *lexer) skipLine() {
+	for l.pos < len(l.src) && l.src[l.pos] != '\n' {
+		l.advance(1)
+	}
+}
+
+// expand appends a macro body, located at use, expanding nested macros
+// other than those in active.
+func (l *lexer) expand(out []token, use token, body []token, active map[string]bool) []token {
+	for _, t := range body {
+		t.line, t.column = use.line, use.column
+		if t.kind == tokIdent && !active[t.text] {
+			if nested, ok := l.macros[t.text]; ok {
+				active[t.text] = true
+				out = l.expand(out, use, nested, active)
+				delete(active, t.text)
+				continue
+			}
+		}
+		out = append(out, t)
+	}
+	return out
+}
+
+// next lexes one token at the current position.
+func (l *lexer) next() (token, error) {
+	tok := l.here()
+	rest := l.src[l.pos:]
+	c := rest[0]
+	switch {
+	case isIdentStart(c):
+		n := 1
+		for n < len(rest) && isIdentPart(rest[n]) {
+			n++
+		}
+		tok.kind, tok.text = tokIdent, rest[:n]
+		l.advance(n)
+		return tok, nil
+	case isDigit(c) || (c == '.' && len(rest) > 1 && isDigit(rest[1])):
+		return l.number(tok)
+	case c == '"':
+		end := strings.IndexAny(rest[1:], "\"\n")
+		if end < 0 || rest[1+end] != '"' {
+			return tok, errorAt(tok, "unterminated string literal")
+		}
+		tok.kind, tok.text = tokString, rest[1:1+end]
+		l.advance(end + 2)
+		return tok, nil
+	}
+	for _, p := range punctuators {
+		if strings.HasPrefix(rest, p) {
+			tok.kind, tok.text = tokPunct, p
+			l.advance(len(p))
+			return tok, nil
+		}
+	}
+	return tok, errorAt(tok, "unexpected character %q", c)
+}
+
+// number lexes an integer or floating-point literal.
+func (l *lexer) number(tok token) (token, error) {
+	rest := l.src[l.pos:]
+	n := 0
+	isFloat := false
+	if strings.HasPrefix(rest, "0x") || strings.HasPrefix(rest, "0X") {
+		n = 2
+		for n < len(rest) && isHexDigit(rest[n]) {
+			n++
+		}
+	} else {
+		for n < len(rest) && isDigit(rest[n]) {
+			n++
+		}
+		if n < len(rest) && rest[n] == '.' {
+			isFloat = true
+			n++
+			for n < len(rest) && isDigit(rest[n]) {
+				n++
+			}
+		}
+		if n < len(rest) && (rest[n] == 'e' || rest[n] == 'E') {
+			m := n + 1
+			if m < len(rest) && (rest[m] == '+' || rest[m] == '-') {
+				m++
+			}
+			if m < len(rest) && isDigit(rest[m]) {
+				isFloat = true
+				n = m
+				for n < len(rest) && isDigit(rest[n]) {
+					n++
+				}
+			}
+		}
+	}
+	text := rest[:n]
+	suffix := ""
+	for n < len(rest) && isIdentPart(rest[n]) {
+		suffix += string(rest[n])
+		n++
+	}
+	l.advance(n)
+
+	lower := strings.ToLower(suffix)
+	switch {
+	case lower == "" && isFloat:
+		tok.kind = tokFloat
+	case (lower == "" || lower == "l") && !isFloat:
+		tok.kind = tokInt
+	case (lower == "u" || lower == "ul" || lower == "lu") && !isFloat:
+		tok.kind = tokUint
+	case lower == "f" || lower == "h":
+		// Half precision is computed as float.
+		tok.kind = tokFloat
+	case lower == "l" || lower == "lf":
+		return tok, errorAt(tok, "double precision literal %s%s is not supported", text, suffix)
+	default:
+		return tok, errorAt(tok, "invalid numeric literal %s%s", text, suffix)
+	}
+	tok.text = text
+	return tok, nil
+}
+
+// directive handles one preprocessor line.
+func (l *lexer) directive() error {
+	start := l.here()
+	end := strings.IndexByte(l.src[l.pos:], '\n')
+	if end < 0 {
+		end = len(l.src) - l.pos
+	}
+	line := l.src[l.pos+1 : l.pos+end]
+	if i := strings.Index(line, "//"); i >= 0 {
+		line = line[:i]
+	}
+	line = strings.TrimSpace(line)
+	name, args, _ := strings.Cut(line, " ")
+	args = strings.TrimSpace(args)
+	defer l.advance(end)
+
+	switch name {
+	case "if", "ifdef", "ifndef":
+		c := conditional{tok: start, outer: l.active()}
+		if c.outer {
+			ok, err := l.condition(start, name, args)
+			if err != nil {
+				return err
+			}
+			c.active, c.taken = ok, ok
+		}
+		l.conds = append(l.conds, c)
+		return nil
+	case "elif", "else", "endif":
+		n := len(l.conds)
+		if n == 0 {
+			return errorAt(start, "#%s without #if", name)
+		}
+		c := &l.conds[n-1]
+		switch {
+		case name == "endif":
+			l.conds = l.conds[:n-1]
+		case c.sawElse:
+			return errorAt(start, "#%s after #else", name)
+		case name == "else":
+			c.sawElse = true
+			c.active = c.outer && !c.taken
+			c.taken = c.taken || c.active
+		case c.outer && !c.taken:
+			ok, err := l.condition(start, "if", args)
+			if err != nil {
+				return err
+			}
+			c.active, c.taken = ok, ok
+		default:
+			c.active = false
+		}
+		return nil
+	}
+	if !l.active() {
+		return nil
+	}
+
+	switch name {
+	case "pragma":
+		if rest, ok := strings.CutPrefix(args, "pack_matrix"); ok {
+			mode := strings.Trim(strings.TrimSpace(rest), "()")
+			mode = strings.TrimSpace(mode)
+			if mode != "row_major" && mode != "column_major" {
+				return errorAt(start, "invalid #pragma pack_matrix(%s)", mode)
+			}
+			l.tokens = append(l.tokens, token{kind: tokPackMatrix, text: mode, line: start.line, column: start.column})
+		}
+		// Other pragmas, such as warning and message control, have no
+		// effect on the IR.
+	case "define":
+		return l.define(start, args)
+	case "undef":
+		delete(l.macros, args)
+	case "include":
+		return errorAt(start, "#include is not supported; inline the included file")
+	case "error":
+		return errorAt(start, "#error %s", args)
+	case "line":
+		return errorAt(start, "#line is not supported")
+	default:
+		return errorAt(start, "preprocessor directive #%s is not supported", name)
+	}
+	return nil
+}
+
+// define records an object-like macro. Its body is lexed on its own so
+// errors point into the #define line.
+func (l *lexer) define(start token, args string) error {
+	n := 0
+	for n < len(args) && isIdentPart(args[n]) {
+		n++
+	}
+	if n == 0 || !isIdentStart(args[0]) {
+		return errorAt(start, "#define needs a macro name")
+	}
+	name := args[:n]
+	if n < len(args) && args[n] == '(' {
+		return errorAt(start, "function-like macro %s is not supported", name)
+	}
+	if name == "defined" {
+		return errorAt(start, "macro name %s is reserved", name)
+	}
+	toks, err := l.lexLine(start, args[n:])
+	if err != nil {
+		return err
+	}
+	l.macros[name] = toks
+	return nil
+}
+
+// lexLine lexes the rest of a directive line.
+func (l *lexer) lexLine(start token, src string) ([]token, error) {
+	sub := &lexer{src: strings.TrimSpace(src), line: start.line, column: start.column, macros: l.macros}
+	for sub.pos < len(sub.src) {
+		if c := sub.src[sub.pos]; c == ' ' || c == '\t' || c == '\r' {
+			sub.advance(1)
+			continue
+		}
+		if strings.HasPrefix(sub.src[sub.pos:], "//") {
+			break
+		}
+		tok, err := sub.next()
+		if err != nil {
+			return nil, err
+		}
+		sub.tokens = append(sub.tokens, tok)
+	}
+	return sub.tokens, nil
+}
+
+// condition evaluates the condition of #if, #ifdef, or #ifndef.
+func (l *lexer) condition(start token, kind, args string) (bool, error) {
+	if kind != "if" {
+		if args == "" {
+			return false, errorAt(start, "#%s needs a macro name", kind)
+		}
+		_, defined := l.macros[args]
+		return defined == (kind == "ifdef"), nil
+	}
+	raw, err := l.lexLine(start, args)
+	if err != nil {
+		return false, err
+	}
+	// defined(X) is resolved before macro expansion; other identifiers
+	// expand, and any left over are 0.
+	var toks []token
+	for i := 0; i < len(raw); i++ {
+		t := raw[i]
+		if t.kind == tokIdent && t.text == "defined" {
+			j := i + 1
+			paren := j < len(raw) && raw[j].text == "("
+			if paren {
+				j++
+			}
+			if j >= len(raw) || raw[j].kind != tokIdent {
+				return false, errorAt(start, "defined needs a macro name")
+			}
+			_, ok := l.macros[raw[j].text]
+			if paren {
+				j++
+				if j >= len(raw) || raw[j].text != ")" {
+					return false, errorAt(start, "expected ')' after defined(%s", raw[j-1].text)
+				}
+			}
+			toks = append(toks, token{kind: tokInt, text: strconv.Itoa(boolInt(ok)), line: t.line, column: t.column})
+			i = j
+			continue
+		}
+		if t.kind == tokIdent {
+			if body, ok := l.macros[t.text]; ok {
+				toks = l.expand(toks, t, body, map[string]bool{t.text: true})
+				continue
+			}
+		}
+		toks = append(toks, t)
+	}
+	e := &condEval{toks: toks, start: start}
+	v, err := e.eval(0)
+	if err != nil {
+		return false, err
+	}
+	if e.pos != len(e.toks) {
+		return false, errorAt(start, "unexpected %s in #if", e.toks[e.pos].text)
+	}
+	return v != 0, nil
+}
+
+func boolInt(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+// condEval evaluates a preprocessor integer expression.
+type condEval struct {
+	toks  []token
+	pos   int
+	start token
+}
+
+// condLevels lists the binary operators of #if from lowest to highest
+// precedence.
+var condLevels = [][]string{
+	{"||"}, {"&&"}, {"|"}, {"^"}, {"&"}, {"==", "!="}, {"<", ">", "<=", ">="},
+	{"<<", ">>"}, {"+", "-"}, {"*", "/", "%"},
+}
+
+func (e *condEval) peek() string {
+	if e.pos < len(e.toks) && e.toks[e.pos].kind == tokPunct {
+		return e.toks[e.pos].text
+	}
+	return ""
+}
+
+func (e *condEval) eval(level int) (int64, error) {
+	if level == len(condLevels) {
+		return e.unary()
+	}
+	l, err := e.eval(level + 1)
+	if err != nil {
+		return 0, err
+	}
+	for {
+		op := e.peek()
+		found := false
+		for _, o := range condLevels[level] {
+			found = found || o == op
+		}
+		if !found {
+			return l, nil
+		}
+		e.pos++
+		r, err := e.eval(level + 1)
+		if err != nil {
+			return 0, err
+		}
+		switch op {
+		case "||":
+			l = int64(boolInt(l != 0 || r != 0))
+		case "&&":
+			l = int64(boolInt(l != 0 && r != 0))
+		case "|":
+			l |= r
+		case "^":
+			l ^= r
+		case "&":
+			l &= r
+		case "==":
+			l = int64(boolInt(l == r))
+		case "!=":
+			l = int64(boolInt(l != r))
+		case "<":
+			l = int64(boolInt(l < r))
+		case ">":
+			l = int64(boolInt(l > r))
+		case "<=":
+			l = int64(boolInt(l <= r))
+		case ">=":
+			l = int64(boolInt(l >= r))
+		case "<<":
+			l <<= uint64(r & 63)
+		case ">>":
+			l >>= uint64(r & 63)
+		case "+":
+			l += r
+		case "-":
+			l -= r
+		case "*":
+			l *= r
+		case "/", "%":
+			if r == 0 {
+				return 0, errorAt(e.start, "division by zero in #if")
+			}
+			if op == "/" {
+				l /= r
+			} else {
+				l %= r
+			}
+		}
+	}
+}
+
+func (e *condEval) unary() (int64, error) {
+	if e.pos >= len(e.toks) {
+		return 0, errorAt(e.start, "incomplete #if expression")
+	}
+	t := e.toks[e.pos]
+	e.pos++
+	switch {
+	case t.kind == tokPunct && t.text == "!":
+		v, err := e.unary()
+		return int64(boolInt(v == 0)), err
+	case t.kind == tokPunct && t.text == "-":
+		v, err := e.unary()
+		return -v, err
+	case t.kind == tokPunct && t.text == "~":
+		v, err := e.unary()
+		return ^v, err
+	case t.kind == tokPunct && t.text == "(":
+		v, err := e.eval(0)
+		if err != nil {
+			return 0, err
+		}
+		if e.peek() != ")" {
+			return 0, errorAt(e.start, "expected ')' in #if")
+		}
+		e.pos++
+		return v, nil
+	case t.kind == tokInt || t.kind == tokUint:
+		v, err := strconv.ParseInt(t.text, 0, 64)
+		if err != nil {
+			return 0, errorAt(e.start, "invalid integer %s in #if", t.text)
+		}
+		return v, nil
+	case t.kind == tokIdent:
+		// Undefined macros are 0.
+		return 0, nil
+	}
+	return 0, errorAt(e.start, "unexpected %s in #if", t.text)
+}
+
+func isIdentStart(c byte) bool {
+	return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+func isIdentPart(c byte) bool { return isIdentStart(c) || isDigit(c) }
+
+func isDigit(c byte) bool { return c >= '0' && c <= '9' }
+
+func isHexDigit(c byte) bool {
+	return isDigit(c) || (c >= 'a' && c <= 'f') || (c >= 'A' && c <= 'F')
+}
@@ -0,0 +1,1035 @@
+// Copyright 2025 The GoGPU Authors
+// SPDX-License-Identifier: MIT
+
+package frontend
+
+import (
+	"slices"
+	"strconv"
+)
+
+// translationUnit is a parsed shader: its top-level declarations in
+// source order.
+type translationUnit struct {
+	decls []any
+}
+
+// parser is a recursive-descent parser for the HLSL subset the frontend
+// accepts. Struct names are tracked so declarations and casts can be told
+// apart from expressions.
+type parser struct {
+	toks    []token
+	pos     int
+	structs map[string]bool
+}
+
+func parse(toks []token) (*translationUnit, error) {
+	p := &parser{toks: toks, structs: make(map[string]bool)}
+	unit := &translationUnit{}
+	for !p.at(tokEOF, "") {
+		decl, err := p.topLevel()
+		if err != nil {
+			return nil, err
+		}
+		if decl != nil {
+			unit.decls = append(unit.decls, decl)
+		}
+	}
+	return unit, nil
+}
+
+func (p *parser) peek() token { return p.toks[p.pos] }
+
+func (p *parser) peekAt(n int) token {
+	if p.pos+n < len(p.toks) {
+		return p.toks[p.pos+n]
+	}
+	return p.toks[len(p.toks)-1]
+}
+
+func (p *parser) next() token {
+	t := p.toks[p.pos]
+	if t.kind != tokEOF {
+		p.pos++
+	}
+	return t
+}
+
+// at reports whether the next token has kind and, if text is not empty,
+// that text.
+func (p *parser) at(kind tokenKind, text string) bool {
+	t := p.peek()
+	return t.kind == kind && (text == "" || t.text == text)
+}
+
+func (p *parser) atPunct(text string) bool { return p.at(tokPunct, text) }
+
+func (p *parser) atWord(text string) bool { return p.at(tokIdent, text) }
+
+func (p *parser) accept(text string) bool {
+	t := p.peek()
+	if (t.kind == tokPunct || t.kind == tokIdent) && t.text == text {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *parser) expect(text string) (token, error) {
+	t := p.peek()
+	if (t.kind == tokPunct || t.kind == tokIdent) && t.text == text {
+		p.pos++
+		return t, nil
+	}
+	return t, errorAt(t, "expected '%s', found %s", text, describe(t))
+}
+
+func (p *parser) ident() (token, error) {
+	t := p.peek()
+	if t.kind != tokIdent || keywords[t.text] {
+		return t, errorAt(t, "expected identifier, found %s", describe(t))
+	}
+	p.pos++
+	return t, nil
+}
+
+func describe(t token) string {
+	switch t.kind {
+	case tokEOF:
+		return "end of file"
+	case tokString:
+		return strconv.Quote(t.text)
+	case tokPackMatrix:
+		return "#pragma pack_matrix"
+	}
+	return "'" + t.text + "'"
+}
+
+// keywords are words that cannot name a variable or function.
+var keywords = map[string]bool{
+	"break": true, "case": true, "cbuffer": true, "class": true, "column_major": true,
+	"const": true, "continue": true, "default": true, "discard": true, "do": true, "else": true,
+	"extern": true, "false": true, "for": true, "groupshared": true, "if": true, "in": true,
+	"inline": true, "inout": true, "interface": true, "namespace": true,
+	"nointerpolation": true, "noperspective": true, "out": true, "packoffset": true, "precise": true,
+	"register": true, "return": true, "row_major": true, "shared": true, "static": true,
+	"struct": true, "switch": true, "tbuffer": true, "true": true, "typedef": true, "uniform": true,
+	"volatile": true, "while": true,
+}
+
+// qualifierWords are the keywords parsed as qualifiers.
+var qualifierWords = map[string]bool{
+	"static": true, "const": true, "groupshared": true, "uniform": true, "extern": true, "volatile": true,
+	"precise": true, "shared": true, "in": true, "out": true, "inout": true, "row_major": true,
+	"column_major": true, "nointerpolation": true, "linear": true, "noperspective": true,
+	"centroid": true, "sample": true, "inline": true, "snorm": true, "unorm": true,
+}
+
+// isTypeName reports whether name starts a type: a built-in type or a
+// declared struct.
+func (p *parser) isTypeName(name string) bool {
+	return isBuiltinTypeName(name) || p.structs[name]
+}
+
+func (p *parser) topLevel() (any, error) {
+	t := p.peek()
+	switch {
+	case p.accept(";"):
+		return nil, nil
+	case t.kind == tokPackMatrix:
+		p.next()
+		return &packMatrixDecl{tok: t, rowMajor: t.text == "row_major"}, nil
+	case p.atWord("struct"):
+		st, err := p.structDecl()
+		if err != nil {
+			return nil, err
+		}
+		if !p.atPunct(";") {
+			return nil, errorAt(p.peek(), "declaring variables with a struct definition is not supported; declare struct %s separately", st.name)
+		}
+		p.next()
+		return st, nil
+	case p.atWord("cbuffer"):
+		return p.cbufferDecl()
+	case p.atWord("tbuffer"):
+		return nil, errorAt(t, "tbuffer is not supported; use a cbuffer or StructuredBuffer")
+	case p.atWord("typedef"):
+		return nil, errorAt(t, "typedef is not supported")
+	case p.atWord("namespace") || p.atWord("class") || p.atWord("interface"):
+		return nil, errorAt(t, "%s is not supported", t.text)
+	}
+
+	attrs, err := p.attributes()
+	if err != nil {
+		return nil, err
+	}
+	quals := p.qualifiers()
+	ty, err := p.typeSpec()
+	if err != nil {
+		return nil, err
+	}
+	if p.at(tokIdent, "") && p.peekAt(1).kind == tokPunct && p.peekAt(1).text == "(" {
+		for _, w := range quals.words {
+			if w.text != "inline" && w.text != "precise" {
+				return nil, errorAt(w, "qualifier '%s' on a function declaration is not supported", w.text)
+			}
+		}
+		return p.funcDecl(attrs, ty)
+	}
+	if len(attrs) > 0 {
+		return nil, errorAt(attrs[0].tok, "attribute [%s] on a variable is not supported", attrs[0].name)
+	}
+	return p.varDeclRest(quals, ty)
+}
+
+// attributes parses any [name(args)] annotations.
+func (p *parser) attributes() ([]attribute, error) {
+	var attrs []attribute
+	for p.atPunct("[") {
+		p.next()
+		name, err := p.ident()
+		if err != nil {
+			return nil, err
+		}
+		a := attribute{tok: name, name: name.text}
+		if p.accept("(") {
+			for !p.accept(")") {
+				if len(a.args) > 0 {
+					if _, err := p.expect(","); err != nil {
+						return nil, err
+					}
+				}
+				if s := p.peek(); s.kind == tokString {
+					p.next()
+					a.args = append(a.args, &literalExpr{tok: s})
+					continue
+				}
+				v, err := p.conditional()
+				if err != nil {
+					return nil, err
+				}
+				a.args = append(a.args, v)
+			}
+		}
+		if _, err := p.expect("]"); err != nil {
+			return nil, err
+		}
+		attrs = append(attrs, a)
+	}
+	return attrs, nil
+}
+
+func (p *parser) qualifiers() qualifiers {
+	q := qualifiers{tok: p.peek()}
+	for p.isQualifier(0) {
+		q.words = append(q.words, p.next())
+	}
+	return q
+}
+
+// contextualQualifiers are qualifiers that are also common variable
+// names; they are qualifiers only when a type or qualifier follows.
+var contextualQualifiers = map[string]bool{"sample": true, "linear": true, "centroid": true}
+
+// isQualifier reports whether the token n ahead is a qualifier.
+func (p *parser) isQualifier(n int) bool {
+	t := p.peekAt(n)
+	if t.kind != tokIdent || !qualifierWords[t.text] {
+		return false
+	}
+	if contextualQualifiers[t.text] {
+		next := p.peekAt(n + 1)
+		return next.kind == tokIdent && (p.isTypeName(next.text) || p.isQualifier(n+1))
+	}
+	return true
+}
+
+func (p *parser) typeSpec() (typeSpec, error) {
+	t := p.peek()
+	if t.kind != tokIdent || !p.isTypeName(t.text) {
+		return typeSpec{}, errorAt(t, "expected a type, found %s", describe(t))
+	}
+	p.next()
+	ts := typeSpec{tok: t, name: t.text}
+	if p.atPunct("<") {
+		p.next()
+		for {
+			if n := p.peek(); n.kind == tokIdent && p.isTypeName(n.text) {
+				arg, err := p.typeSpec()
+				if err != nil {
+					return typeSpec{}, err
+				}
+				ts.args = append(ts.args, typeArg{ty: &arg})
+			} else {
+				// A constant argument is a literal or macro, so the
+				// closing > is not read as an operator.
+				v, err := p.unary()
+				if err != nil {
+					return typeSpec{}, err
+				}
+				ts.args = append(ts.args, typeArg{value: v})
+			}
+			if !p.accept(",") {
+				break
+			}
+		}
+		if p.atPunct(">>") {
+			return typeSpec{}, errorAt(p.peek(), "nested template arguments are not supported")
+		}
+		if _, err := p.expect(">"); err != nil {
+			return typeSpec{}, err
+		}
+	}
+	return ts, nil
+}
+
+func (p *parser) arrayDims() ([]expr, error) {
+	var dims []expr
+	for p.atPunct("[") {
+		p.next()
+		if p.accept("]") {
+			dims = append(dims, nil)
+			continue
+		}
+		size, err := p.conditional()
+		if err != nil {
+			return nil, err
+		}
+		if _, err := p.expect("]"); err != nil {
+			return nil, err
+		}
+		dims = append(dims, size)
+	}
+	return dims, nil
+}
+
+func (p *parser) structDecl() (*structDecl, error) {
+	p.next()
+	name, err := p.ident()
+	if err != nil {
+		return nil, err
+	}
+	members, err := p.memberList()
+	if err != nil {
+		return nil, err
+	}
+	p.structs[name.text] = true
+	return &structDecl{tok: name, name: name.text, members: members}, nil
+}
+
+// memberList parses { member declarations } of a struct or cbuffer.
+func (p *parser) memberList() ([]varDecl, error) {
+	if _, err := p.expect("{"); err != nil {
+		return nil, err
+	}
+	var members []varDecl
+	for !p.accept("}") {
+		if p.at(tokEOF, "") {
+			return nil, errorAt(p.peek(), "unterminated member list")
+		}
+		quals := p.qualifiers()
+		ty, err := p.typeSpec()
+		if err != nil {
+			return nil, err
+		}
+		if p.at(tokIdent, "") && p.peekAt(1).kind == tokPunct && p.peekAt(1).text == "(" {
+			return nil, errorAt(p.peek(), "member functions are not supported")
+		}
+		decl, err := p.varDeclRest(quals, ty)
+		if err != nil {
+			return nil, err
+		}
+		members = append(members, *decl)
+	}
+	if len(members) == 0 {
+		return nil, errorAt(p.toks[p.pos-1], "empty member list")
+	}
+	return members, nil
+}
+
+func (p *parser) cbufferDecl() (*cbufferDecl, error) {
+	p.next()
+	name, err := p.ident()
+	if err != nil {
+		return nil, err
+	}
+	cb := &cbufferDecl{tok: name, name: name.text}
+	if p.accept(":") {
+		if cb.register, err = p.register(); err != nil {
+			return nil, err
+		}
+	}
+	if cb.members, err = p.memberList(); err != nil {
+		return nil, err
+	}
+	p.accept(";")
+	return cb, nil
+}
+
+// register parses register(t0) or register(t0, space1) after the colon.
+func (p *parser) register() (*register, error) {
+	t, err := p.expect("register")
+	if err != nil {
+		return nil, err
+	}
+	if _, err := p.expect("("); err != nil {
+		return nil, err
+	}
+	r := &register{tok: t}
+	slot := p.next()
+	class, index, ok := splitRegister(slot.text)
+	if slot.kind != tokIdent || !ok || class == "space" {
+		return nil, errorAt(slot, "invalid register %s; expected b, t, s, or u and an index", slot.text)
+	}
+	r.class, r.index = class[0], index
+	if p.accept(",") {
+		sp := p.next()
+		class, index, ok := splitRegister(sp.text)
+		if sp.kind != tokIdent || !ok || class != "space" {
+			return nil, errorAt(sp, "invalid register space %s", sp.text)
+		}
+		r.space = index
+	}
+	if _, err := p.expect(")"); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// splitRegister splits t3 into t and 3, and space1 into space and 1.
+func splitRegister(s string) (string, uint32, bool) {
+	i := len(s)
+	for i > 0 && isDigit(s[i-1]) {
+		i--
+	}
+	if i == len(s) {
+		return "", 0, false
+	}
+	n, err := strconv.ParseUint(s[i:], 10, 32)
+	if err != nil {
+		return "", 0, false
+	}
+	class := s[:i]
+	switch class {
+	case "b", "t", "s", "u", "space":
+		return class, uint32(n), true
+	case "B", "T", "S", "U":
+		return string(class[0] + 'a' - 'A'), uint32(n), true
+	}
+	return "", 0, false
+}
+
+// annotations parses the : semantic, : register(...), and
+// : packoffset(...) annotations of a declarator.
+func (p *parser) annotations(d *declarator) error {
+	for p.atPunct(":") {
+		p.next()
+		t := p.peek()
+		switch {
+		case p.atWord("register"):
+			r, err := p.register()
+			if err != nil {
+				return err
+			}
+			d.register = r
+		case p.atWord("packoffset"):
+			return errorAt(t, "packoffset is not supported; members are laid out in declaration order")
+		case t.kind == tokIdent:
+			p.next()
+			d.semantic = &semantic{tok: t, name: t.text}
+		default:
+			return errorAt(t, "expected a semantic, register, or packoffset, found %s", describe(t))
+		}
+	}
+	return nil
+}
+
+// varDeclRest parses the declarators after a declaration's type.
+func (p *parser) varDeclRest(quals qualifiers, ty typeSpec) (*varDecl, error) {
+	decl := &varDecl{quals: quals, ty: ty}
+	for {
+		name, err := p.ident()
+		if err != nil {
+			return nil, err
+		}
+		d := declarator{tok: name, name: name.text}
+		if d.dims, err = p.arrayDims(); err != nil {
+			return nil, err
+		}
+		if err := p.annotations(&d); err != nil {
+			return nil, err
+		}
+		if p.accept("=") {
+			if d.init, err = p.initializer(); err != nil {
+				return nil, err
+			}
+		}
+		decl.names = append(decl.names, d)
+		if !p.accept(",") {
+			break
+		}
+	}
+	if _, err := p.expect(";"); err != nil {
+		return nil, err
+	}
+	return decl, nil
+}
+
+// initializer parses an expression or an initializer list.
+func (p *parser) initializer() (expr, error) {
+	if !p.atPunct("{") {
+		return p.assignment()
+	}
+	list := &listExpr{tok: p.next()}
+	for !p.accept("}") {
+		if len(list.elems) > 0 {
+			if _, err := p.expect(","); err != nil {
+				return nil, err
+			}
+			if p.accept("}") {
+				break
+			}
+		}
+		e, err := p.initializer()
+		if err != nil {
+			return nil, err
+		}
+		list.elems = append(list.elems, e)
+	}
+	return list, nil
+}
+
+func (p *parser) funcDecl(attrs []attribute, ret typeSpec) (*funcDecl, error) {
+	name := p.next()
+	fn := &funcDecl{attrs: attrs, ret: ret, tok: name, name: name.text}
+	p.next() // (
+	if p.atWord("void") && p.peekAt(1).kind == tokPunct && p.peekAt(1).text == ")" {
+		p.next()
+	}
+	for !p.atPunct(")") {
+		if len(fn.params) > 0 {
+			if _, err := p.expect(","); err != nil {
+				return nil, err
+			}
+		}
+		quals := p.qualifiers()
+		ty, err := p.typeSpec()
+		if err != nil {
+			return nil, err
+		}
+		n, err := p.ident()
+		if err != nil {
+			return nil, err
+		}
+		d := declarator{tok: n, name: n.text}
+		if d.dims, err = p.arrayDims(); err != nil {
+			return nil, err
+		}
+		if err := p.annotations(&d); err != nil {
+			return nil, err
+		}
+		if d.register != nil {
+			return nil, errorAt(d.register.tok, "register on a parameter is not supported")
+		}
+		if p.atPunct("=") {
+			return nil, errorAt(p.peek(), "default parameter values are not supported")
+		}
+		ty.dims = append(ty.dims, d.dims...)
+		fn.params = append(fn.params, param{tok: n, quals: quals, ty: ty, name: n.text, semantic: d.semantic})
+	}
+	p.next() // )
+	if p.accept(":") {
+		t, err := p.ident()
+		if err != nil {
+			return nil, err
+		}
+		fn.semantic = &semantic{tok: t, name: t.text}
+	}
+	if p.accept(";") {
+		return fn, nil
+	}
+	body, err := p.block()
+	if err != nil {
+		return nil, err
+	}
+	fn.body = body
+	return fn, nil
+}
+
+func (p *parser) block() (*blockStmt, error) {
+	open, err := p.expect("{")
+	if err != nil {
+		return nil, err
+	}
+	b := &blockStmt{tok: open}
+	for !p.accept("}") {
+		if p.at(tokEOF, "") {
+			return nil, errorAt(p.peek(), "unterminated block")
+		}
+		s, err := p.statement()
+		if err != nil {
+			return nil, err
+		}
+		b.stmts = append(b.stmts, s)
+	}
+	return b, nil
+}
+
+// startsDecl reports whether the next tokens begin a declaration rather
+// than an expression statement.
+func (p *parser) startsDecl() bool {
+	t := p.peek()
+	if t.kind != tokIdent {
+		return false
+	}
+	if p.isQualifier(0) {
+		return true
+	}
+	if !p.isTypeName(t.text) {
+		return false
+	}
+	// T name or T<...> name, but not a constructor call T(...).
+	n := p.peekAt(1)
+	return n.kind == tokIdent || (n.kind == tokPunct && n.text == "<")
+}
+
+// statementAttributes are the flow control hints HLSL allows before a
+// statement. They do not change the meaning of the code.
+var statementAttributes = map[string]bool{
+	"unroll": true, "loop": true, "fastopt": true, "allow_uav_condition": true,
+	"branch": true, "flatten": true, "forcecase": true, "call": true,
+}
+
+func (p *parser) statement() (stmt, error) {
+	if p.atPunct("[") {
+		attrs, err := p.attributes()
+		if err != nil {
+			return nil, err
+		}
+		for _, a := range attrs {
+			if !statementAttributes[a.name] {
+				return nil, errorAt(a.tok, "unknown statement attribute [%s]", a.name)
+			}
+		}
+	}
+	t := p.peek()
+	if t.kind == tokPunct {
+		switch t.text {
+		case "{":
+			return p.block()
+		case ";":
+			p.next()
+			return &emptyStmt{tok: t}, nil
+		}
+	}
+	if t.kind == tokIdent {
+		switch t.text {
+		case "if":
+			return p.ifStmt()
+		case "for":
+			return p.forStmt()
+		case "while":
+			p.next()
+			cond, err := p.parenExpr()
+			if err != nil {
+				return nil, err
+			}
+			body, err := p.statement()
+			if err != nil {
+				return nil, err
+			}
+			return &whileStmt{tok: t, cond: cond, body: body}, nil
+		case "do":
+			p.next()
+			body, err := p.statement()
+			if err != nil {
+				return nil, err
+			}
+			if _, err := p.expect("while"); err != nil {
+				return nil, err
+			}
+			cond, err := p.parenExpr()
+			if err != nil {
+				return nil, err
+			}
+			if _, err := p.expect(";"); err != nil {
+				return nil, err
+			}
+			return &doWhileStmt{tok: t, body: body, cond: cond}, nil
+		case "switch":
+			return p.switchStmt()
+		case "case", "default":
+			p.next()
+			label := &caseLabel{tok: t}
+			if t.text == "case" {
+				v, err := p.conditional()
+				if err != nil {
+					return nil, err
+				}
+				label.value = v
+			}
+			_, err := p.expect(":")
+			return label, err
+		case "break", "continue", "discard":
+			p.next()
+			_, err := p.expect(";")
+			return &jumpStmt{tok: t}, err
+		case "return":
+			p.next()
+			j := &jumpStmt{tok: t}
+			if !p.atPunct(";") {
+				v, err := p.expression()
+				if err != nil {
+					return nil, err
+				}
+				j.value = v
+			}
+			_, err := p.expect(";")
+			return j, err
+		case "struct":
+			return nil, errorAt(t, "struct declarations inside functions are not supported")
+		}
+	}
+	if p.startsDecl() {
+		quals := p.qualifiers()
+		ty, err := p.typeSpec()
+		if err != nil {
+			return nil, err
+		}
+		decl, err := p.varDeclRest(quals, ty)
+		if err != nil {
+			return nil, err
+		}
+		return &declStmt{decl: decl}, nil
+	}
+	x, err := p.expression()
+	if err != nil {
+		return nil, err
+	}
+	if _, err := p.expect(";"); err != nil {
+		return nil, err
+	}
+	return &exprStmt{tok: t, x: x}, nil
+}
+
+func (p *parser) parenExpr() (expr, error) {
+	if _, err := p.expect("("); err != nil {
+		return nil, err
+	}
+	x, err := p.expression()
+	if err != nil {
+		return nil, err
+	}
+	_, err = p.expect(")")
+	return x, err
+}
+
+func (p *parser) ifStmt() (stmt, error) {
+	t := p.next()
+	cond, err := p.parenExpr()
+	if err != nil {
+		return nil, err
+	}
+	then, err := p.statement()
+	if err != nil {
+		return nil, err
+	}
+	s := &ifStmt{tok: t, cond: cond, then: then}
+	if p.accept("else") {
+		if s.els, err = p.statement(); err != nil {
+			return nil, err
+		}
+	}
+	return s, nil
+}
+
+func (p *parser) forStmt() (stmt, error) {
+	t := p.next()
+	if _, err := p.expect("("); err != nil {
+		return nil, err
+	}
+	s := &forStmt{tok: t}
+	var err error
+	if !p.atPunct(";") {
+		if s.init, err = p.statement(); err != nil {
+			return nil, err
+		}
+	} else {
+		p.next()
+	}
+	if !p.atPunct(";") {
+		if s.cond, err = p.expression(); err != nil {
+			return nil, err
+		}
+	}
+	if _, err := p.expect(";"); err != nil {
+		return nil, err
+	}
+	if !p.atPunct(")") {
+		if s.post, err = p.expression(); err != nil {
+			return nil, err
+		}
+	}
+	if _, err := p.expect(")"); err != nil {
+		return nil, err
+	}
+	s.body, err = p.statement()
+	return s, err
+}
+
+func (p *parser) switchStmt() (stmt, error) {
+	t := p.next()
+	sel, err := p.parenExpr()
+	if err != nil {
+		return nil, err
+	}
+	body, err := p.block()
+	if err != nil {
+		return nil, err
+	}
+	return &switchStmt{tok: t, selector: sel, body: body.stmts}, nil
+}
+
+// expression parses a full expression. The comma operator is not
+// supported.
+func (p *parser) expression() (expr, error) {
+	x, err := p.assignment()
+	if err != nil {
+		return nil, err
+	}
+	if p.atPunct(",") {
+		return nil, errorAt(p.peek(), "the comma operator is not supported")
+	}
+	return x, nil
+}
+
+var assignOps = map[string]bool{
+	"=": true, "+=": true, "-=": true, "*=": true, "/=": true, "%=": true,
+	"<<=": true, ">>=": true, "&=": true, "|=": true, "^=": true,
+}
+
+func (p *parser) assignment() (expr, error) {
+	l, err := p.conditional()
+	if err != nil {
+		return nil, err
+	}
+	if t := p.peek(); t.kind == tokPunct && assignOps[t.text] {
+		p.next()
+		r, err := p.assignment()
+		if err != nil {
+			return nil, err
+		}
+		return &assignExpr{tok: t, op: t.text, l: l, r: r}, nil
+	}
+	return l, nil
+}
+
+func (p *parser) conditional() (expr, error) {
+	cond, err := p.binary(0)
+	if err != nil {
+		return nil, err
+	}
+	if !p.atPunct("?") {
+		return cond, nil
+	}
+	t := p.next()
+	then, err := p.assignment()
+	if err != nil {
+		return nil, err
+	}
+	if _, err := p.expect(":"); err != nil {
+		return nil, err
+	}
+	els, err := p.assignment()
+	if err != nil {
+		return nil, err
+	}
+	return &ternaryExpr{tok: t, cond: cond, then: then, els: els}, nil
+}
+
+// binaryLevels lists binary operators from lowest to highest precedence.
+var binaryLevels = [][]string{
+	{"||"}, {"&&"}, {"|"}, {"^"}, {"&"},
+	{"==", "!="}, {"<", ">", "<=", ">="}, {"<<", ">>"}, {"+", "-"}, {"*", "/", "%"},
+}
+
+func (p *parser) binary(level int) (expr, error) {
+	if level == len(binaryLevels) {
+		return p.unary()
+	}
+	l, err := p.binary(level + 1)
+	if err != nil {
+		return nil, err
+	}
+	for {
+		t := p.peek()
+		if t.kind != tokPunct || !slices.Contains(binaryLevels[level], t.text) {
+			return l, nil
+		}
+		p.next()
+		r, err := p.binary(level + 1)
+		if err != nil {
+			return nil, err
+		}
+		l = &binaryExpr{tok: t, op: t.text, l: l, r: r}
+	}
+}
+
+func (p *parser) unary() (expr, error) {
+	t := p.peek()
+	if t.kind == tokPunct {
+		switch t.text {
+		case "+", "-", "!", "~", "++", "--":
+			p.next()
+			x, err := p.unary()
+			if err != nil {
+				return nil, err
+			}
+			return &unaryExpr{tok: t, op: t.text, x: x}, nil
+		case "(":
+			if cast, ok, err := p.cast(); ok || err != nil {
+				return cast, err
+			}
+		}
+	}
+	return p.postfix()
+}
+
+// cast parses (type)x, reporting false when the parenthesis does not
+// start a cast.
+func (p *parser) cast() (expr, bool, error) {
+	n := p.peekAt(1)
+	if n.kind != tokIdent || !p.isTypeName(n.text) {
+		return nil, false, nil
+	}
+	start := p.pos
+	open := p.next()
+	ts, err := p.typeSpec()
+	if err != nil {
+		return nil, true, err
+	}
+	if ts.dims, err = p.arrayDims(); err != nil {
+		return nil, true, err
+	}
+	if !p.accept(")") {
+		// A parenthesized constructor, (float3(1, 2, 3)).x.
+		p.pos = start
+		return nil, false, nil
+	}
+	x, err := p.unary()
+	if err != nil {
+		return nil, true, err
+	}
+	return &castExpr{tok: open, ty: ts, x: x}, true, nil
+}
+
+func (p *parser) postfix() (expr, error) {
+	x, err := p.primary()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		t := p.peek()
+		if t.kind != tokPunct {
+			return x, nil
+		}
+		switch t.text {
+		case "[":
+			p.next()
+			idx, err := p.expression()
+			if err != nil {
+				return nil, err
+			}
+			if _, err := p.expect("]"); err != nil {
+				return nil, err
+			}
+			x = &indexExpr{tok: t, x: x, index: idx}
+		case ".":
+			p.next()
+			name, err := p.ident()
+			if err != nil {
+				return nil, err
+			}
+			if p.atPunct("(") {
+				args, err := p.arguments()
+				if err != nil {
+					return nil, err
+				}
+				x = &callExpr{tok: name, callee: typeSpec{tok: name, name: name.text}, recv: x, args: args}
+				continue
+			}
+			x = &memberExpr{tok: name, x: x, name: name.text}
+		case "++", "--":
+			p.next()
+			x = &unaryExpr{tok: t, op: t.text, x: x, postfix: true}
+		default:
+			return x, nil
+		}
+	}
+}
+
+func (p *parser) arguments() ([]expr, error) {
+	p.next() // (
+	var args []expr
+	for !p.accept(")") {
+		if len(args) > 0 {
+			if _, err := p.expect(","); err != nil {
+				return nil, err
+			}
+		}
+		a, err := p.assignment()
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, a)
+	}
+	return args, nil
+}
+
+func (p *parser) primary() (expr, error) {
+	t := p.peek()
+	switch t.kind {
+	case tokInt, tokUint, tokFloat:
+		p.next()
+		return &literalExpr{tok: t}, nil
+	case tokPunct:
+		if t.text == "(" {
+			return p.parenExpr()
+		}
+		if t.text == "{" {
+			return nil, errorAt(t, "initializer lists are only allowed in declarations")
+		}
+	case tokIdent:
+		if t.text == "true" || t.text == "false" {
+			p.next()
+			return &literalExpr{tok: t}, nil
+		}
+		if p.isTypeName(t.text) {
+			ts, err := p.typeSpec()
+			if err != nil {
+				return nil, err
+			}
+			if !p.atPunct("(") {
+				return nil, errorAt(p.peek(), "expected '(' after type %s", ts.name)
+			}
+			args, err := p.arguments()
+			if err != nil {
+				return nil, err
+			}
+			return &callExpr{tok: t, callee: ts, args: args}, nil
+		}
+		if keywords[t.text] {
+			break
+		}
+		p.next()
+		if p.atPunct("(") {
+			args, err := p.arguments()
+			if err != nil {
+				return nil, err
+			}
+			return &callExpr{tok: t, callee: typeSpec{tok: t, name: t.text}, args: args}, nil
+		}
+		return &identExpr{tok: t}, nil
+	}
+	return nil, errorAt(t, "expected an expression, found %s", describe(t))
+}
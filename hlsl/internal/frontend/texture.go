@@ -0,0 +1,412 @@
+// Copyright 2025 The GoGPU Authors
+// SPDX-License-Identifier: MIT
+
+package frontend
+
+import (
+	"fmt"
+	"strings"
+)
+
+// coordSizes are the coordinate components of each texture dimension,
+// including the array layer.
+var coordSizes = map[string]int{
+	"1d": 1, "2d": 2, "2d_array": 3, "3d": 3, "cube": 3, "cube_array": 4, "multisampled_2d": 2,
+}
+
+// gatherComponents are the components the Gather methods read.
+var gatherComponents = map[string]int{"Gather": 0, "GatherRed": 0, "GatherGreen": 1, "GatherBlue": 2, "GatherAlpha": 3}
+
+// isArray reports whether a texture dimension has layers.
+func isArray(dim string) bool { return strings.HasSuffix(dim, "_array") }
+
+// method translates a texture or structured buffer method call.
+func (t *translator) method(e *callExpr) (value, error) {
+	x, err := t.expr(e.recv)
+	if err != nil {
+		return value{}, err
+	}
+	if t.fn == nil {
+		return value{}, errorAt(e.tok, "%s cannot be called outside a function", e.callee.name)
+	}
+	switch x.ty.kind {
+	case kindTexture:
+		return t.textureMethod(e, x)
+	case kindBuffer:
+		return t.bufferMethod(e, x)
+	}
+	return value{}, errorAt(e.tok, "%s has no method %s", x.ty, e.callee.name)
+}
+
+func (t *translator) bufferMethod(e *callExpr, x value) (value, error) {
+	switch e.callee.name {
+	case "Load":
+		if err := argCount(e, 1); err != nil {
+			return value{}, err
+		}
+		return t.index(&indexExpr{tok: e.tok, x: e.recv, index: e.args[0]})
+	case "GetDimensions":
+		if err := argCount(e, 2); err != nil {
+			return value{}, err
+		}
+		stride, err := structuredStride(x.ty.elem, e.tok)
+		if err != nil {
+			return value{}, err
+		}
+		n := value{text: "arrayLength(&" + x.text + ")", ty: uintType}
+		if err := t.storeOut(e.args[0], n); err != nil {
+			return value{}, err
+		}
+		if err := t.storeOut(e.args[1], constInt(int64(stride), scalarUint)); err != nil {
+			return value{}, err
+		}
+		return value{ty: voidType, stmt: true}, nil
+	}
+	return value{}, errorAt(e.tok, "%s has no method %s", x.ty, e.callee.name)
+}
+
+// storeOut stores a result in an out argument of an intrinsic.
+func (t *translator) storeOut(arg expr, v value) error {
+	lhs, err := t.target(arg)
+	if err != nil {
+		return err
+	}
+	if v, err = t.convert(v, lhs.ty.value(), arg.pos()); err != nil {
+		return err
+	}
+	return t.store(lhs, v, arg.pos())
+}
+
+// texArgs holds the translated arguments of a texture method.
+type texArgs struct {
+	tex     value
+	sampler value
+	coords  []string // coordinates, then the array layer if any
+	extra   []string // level, bias, gradients, or depth reference
+	offset  string
+}
+
+// textureMethod translates the Sample, Load, Gather, and GetDimensions
+// methods of a texture.
+func (t *translator) textureMethod(e *callExpr, x value) (value, error) {
+	name := e.callee.name
+	dim := x.ty.texture
+	switch name {
+	case "Sample", "SampleLevel", "SampleBias", "SampleGrad", "SampleCmp", "SampleCmpLevelZero",
+		"Gather", "GatherRed", "GatherGreen", "GatherBlue", "GatherAlpha":
+	case "Load":
+		return t.textureLoad(e, x)
+	case "GetDimensions":
+		return t.textureDimensions(e, x)
+	default:
+		return value{}, errorAt(e.tok, "%s has no method %s", x.ty, name)
+	}
+	if dim == "multisampled_2d" {
+		return value{}, errorAt(e.tok, "multisampled textures cannot be sampled; use Load")
+	}
+	// Arguments after the sampler and coordinates, and whether an offset
+	// may follow them.
+	fixed, offset := 0, dim == "2d" || dim == "2d_array" || dim == "3d"
+	switch name {
+	case "SampleLevel", "SampleBias", "SampleCmp", "SampleCmpLevelZero":
+		// SampleCmpLevelZero takes the depth reference.
+		fixed = 1
+	case "SampleGrad":
+		fixed = 2
+	}
+	counts := []int{2 + fixed}
+	if offset {
+		counts = append(counts, 3+fixed)
+	}
+	if err := argCount(e, counts...); err != nil {
+		return value{}, err
+	}
+
+	compare := strings.HasPrefix(name, "SampleCmp")
+	if compare {
+		if x.root == nil || x.root.kind != symVar {
+			return value{}, errorAt(e.tok, "%s needs a global texture", name)
+		}
+		if t.marking {
+			x.root.ty.depth = true
+		}
+	}
+	depth := x.ty.depth
+	if x.ty.elem.scalar != scalarFloat && !depth {
+		return value{}, errorAt(e.tok, "%s cannot sample a texture of %s; use Load", name, x.ty.elem)
+	}
+
+	a := texArgs{tex: x}
+	s, err := t.rvalue(e.args[0])
+	if err != nil {
+		return value{}, err
+	}
+	if s.ty.kind != kindSampler {
+		return value{}, errorAt(e.args[0].pos(), "%s takes a sampler, got %s", name, s.ty)
+	}
+	if s.ty.comparison != compare {
+		if compare {
+			return value{}, errorAt(e.args[0].pos(), "%s needs a SamplerComparisonState", name)
+		}
+		return value{}, errorAt(e.args[0].pos(), "%s needs a SamplerState, not a SamplerComparisonState", name)
+	}
+	a.sampler = s
+	if a.coords, err = t.coords(e.args[1], dim, scalarFloat, false); err != nil {
+		return value{}, err
+	}
+	for i := 0; i < fixed; i++ {
+		arg := e.args[2+i]
+		v, err := t.rvalue(arg)
+		if err != nil {
+			return value{}, err
+		}
+		ty := floatType
+		if name == "SampleGrad" {
+			ty = vectorType(scalarFloat, coordSizes[dim]-boolInt(isArray(dim)))
+		}
+		if v, err = t.convert(v, ty, arg.pos()); err != nil {
+			return value{}, err
+		}
+		if name == "SampleLevel" && depth {
+			// Depth textures take an integer level.
+			v = castTo(v, intType)
+		}
+		a.extra = append(a.extra, v.text)
+	}
+	if len(e.args) > 2+fixed {
+		arg := e.args[2+fixed]
+		v, err := t.rvalue(arg)
+		if err != nil {
+			return value{}, err
+		}
+		n := 2
+		if dim == "3d" {
+			n = 3
+		}
+		if v, err = t.convert(v, vectorType(scalarInt, n), arg.pos()); err != nil {
+			return value{}, err
+		}
+		if !v.konst {
+			return value{}, errorAt(arg.pos(), "texture offsets must be constant")
+		}
+		a.offset = v.text
+	}
+
+	var call string
+	args := []string{a.tex.text, a.sampler.text}
+	args = append(args, a.coords...)
+	switch name {
+	case "Sample":
+		call = "textureSample"
+	case "SampleLevel":
+		call = "textureSampleLevel"
+	case "SampleBias":
+		call = "textureSampleBias"
+	case "SampleGrad":
+		call = "textureSampleGrad"
+	case "SampleCmp":
+		call = "textureSampleCompare"
+	case "SampleCmpLevelZero":
+		call = "textureSampleCompareLevel"
+	default:
+		call = "textureGather"
+		if !depth {
+			args = append([]string{fmt.Sprint(gatherComponents[name])}, args...)
+		} else if gatherComponents[name] != 0 {
+			return value{}, errorAt(e.tok, "%s is not supported on a texture also used with SampleCmp", name)
+		}
+	}
+	if depth && (name == "SampleBias" || name == "SampleGrad") {
+		return value{}, errorAt(e.tok, "%s is not supported on a texture also used with SampleCmp", name)
+	}
+	args = append(args, a.extra...)
+	if a.offset != "" {
+		args = append(args, a.offset)
+	}
+	text := call + "(" + strings.Join(args, ", ") + ")"
+	if compare {
+		return value{text: text, ty: floatType}, nil
+	}
+	if strings.HasPrefix(name, "Gather") {
+		// Gather returns four texels of one component, whatever the
+		// element type.
+		v := value{text: text, ty: vectorType(scalarFloat, 4)}
+		if !depth {
+			v.ty = vectorType(x.ty.elem.scalar, 4)
+		}
+		return v, nil
+	}
+	return t.texel(value{text: text, ty: vectorType(scalarFloat, 4)}, x.ty, depth), nil
+}
+
+// texel converts the vec4 (or f32, for a depth texture) a WGSL texture
+// function returns to the texture's element type.
+func (t *translator) texel(v value, tex *hlType, depth bool) value {
+	if depth {
+		v.ty = floatType
+	} else {
+		v.ty = vectorType(tex.elem.scalar, 4)
+	}
+	elem := tex.elem
+	if depth && elem.scalar != scalarFloat {
+		elem = elem.withScalar(scalarFloat)
+	}
+	if v.ty.kind == kindVector {
+		v = truncate(v, elem.vectorSize())
+	}
+	return splat(castTo(v, v.ty.withScalar(elem.scalar)), elem.vectorSize())
+}
+
+// coords translates texture coordinates to WGSL arguments, splitting off
+// the array layer and, when level is set, the trailing mip level.
+func (t *translator) coords(arg expr, dim string, s scalarKind, level bool) ([]string, error) {
+	n := coordSizes[dim]
+	total := n + boolInt(level)
+	v, err := t.rvalue(arg)
+	if err != nil {
+		return nil, err
+	}
+	if v, err = t.convert(v, vectorType(s, total), arg.pos()); err != nil {
+		return nil, err
+	}
+	layers := isArray(dim)
+	if !layers && !level {
+		return []string{v.text}, nil
+	}
+	if !v.pure {
+		tmp := t.temp("coords")
+		t.w.Line(arg.pos().line, "let %s = %s;", tmp, v.text)
+		v = value{text: tmp, ty: v.ty, pure: true}
+	}
+	m := n - boolInt(layers)
+	out := []string{truncate(v, m).text}
+	if m == 1 && total > 1 {
+		out[0] = v.text + ".x"
+	}
+	if layers {
+		layer := v.text + "." + "xyzw"[m:m+1]
+		if s == scalarFloat {
+			// The layer is rounded to the nearest integer.
+			layer = "i32(round(" + layer + "))"
+		}
+		out = append(out, layer)
+	}
+	if level {
+		out = append(out, v.text+"."+"xyzw"[n:n+1])
+	}
+	return out, nil
+}
+
+// textureLoad translates Load, whose integer coordinates end with the
+// mip level, or which takes a sample index for a multisampled texture.
+func (t *translator) textureLoad(e *callExpr, x value) (value, error) {
+	dim := x.ty.texture
+	if dim == "cube" || dim == "cube_array" {
+		return value{}, errorAt(e.tok, "Load is not available on cube textures")
+	}
+	ms := dim == "multisampled_2d"
+	if err := argCount(e, 1, 2); err != nil {
+		return value{}, err
+	}
+	if ms != (len(e.args) == 2) {
+		if ms {
+			return value{}, errorAt(e.tok, "Load on a multisampled texture takes coordinates and a sample index")
+		}
+		return value{}, errorAt(e.tok, "Load offsets are not supported")
+	}
+	args, err := t.coords(e.args[0], dim, scalarInt, !ms)
+	if err != nil {
+		return value{}, err
+	}
+	if ms {
+		v, err := t.rvalue(e.args[1])
+		if err != nil {
+			return value{}, err
+		}
+		if v, err = t.convert(v, intType, e.args[1].pos()); err != nil {
+			return value{}, err
+		}
+		args = append(args, v.text)
+	}
+	text := "textureLoad(" + x.text + ", " + strings.Join(args, ", ") + ")"
+	return t.texel(value{text: text}, x.ty, x.ty.depth), nil
+}
+
+// textureIndex translates tex[coords], which loads from mip level 0.
+func (t *translator) textureIndex(e *indexExpr, x value, i value) (value, error) {
+	dim := x.ty.texture
+	if dim == "cube" || dim == "cube_array" || dim == "multisampled_2d" {
+		return value{}, errorAt(e.tok, "indexing a %s is not supported", x.ty)
+	}
+	n := coordSizes[dim]
+	c, err := t.convert(i, vectorType(scalarUint, n), e.index.pos())
+	if err != nil {
+		return value{}, err
+	}
+	args := []string{c.text}
+	if isArray(dim) {
+		if !c.pure {
+			return value{}, errorAt(e.index.pos(), "texture coordinates that call functions are not supported here")
+		}
+		args = []string{truncate(c, n-1).text, c.text + "." + "xyzw"[n-1:n]}
+	}
+	text := "textureLoad(" + x.text + ", " + strings.Join(args, ", ") + ", 0)"
+	return t.texel(value{text: text}, x.ty, x.ty.depth), nil
+}
+
+// textureDimensions translates GetDimensions. With a leading mip level
+// argument it also returns the number of levels.
+func (t *translator) textureDimensions(e *callExpr, x value) (value, error) {
+	dim := x.ty.texture
+	sizes := map[string]int{"1d": 1, "2d": 2, "2d_array": 2, "3d": 3, "cube": 2, "cube_array": 2, "multisampled_2d": 2}[dim]
+	outs := sizes + boolInt(isArray(dim))
+	switch {
+	case dim == "multisampled_2d":
+		if err := argCount(e, 3); err != nil {
+			return value{}, err
+		}
+	default:
+		if err := argCount(e, outs, outs+2); err != nil {
+			return value{}, err
+		}
+	}
+	args := e.args
+	level := ""
+	withLevels := dim != "multisampled_2d" && len(args) == outs+2
+	if withLevels {
+		v, err := t.rvalue(args[0])
+		if err != nil {
+			return value{}, err
+		}
+		if v, err = t.convert(v, uintType, args[0].pos()); err != nil {
+			return value{}, err
+		}
+		level = ", " + v.text
+		args = args[1:]
+	}
+	dims := t.temp("dims")
+	t.w.Line(e.tok.line, "let %s = textureDimensions(%s%s);", dims, x.text, level)
+	results := make([]value, 0, len(args))
+	for i := 0; i < sizes; i++ {
+		text := dims
+		if sizes > 1 {
+			text += "." + "xyzw"[i:i+1]
+		}
+		results = append(results, value{text: text, ty: uintType, pure: true})
+	}
+	if isArray(dim) {
+		results = append(results, value{text: "textureNumLayers(" + x.text + ")", ty: uintType, pure: true})
+	}
+	switch {
+	case withLevels:
+		results = append(results, value{text: "textureNumLevels(" + x.text + ")", ty: uintType, pure: true})
+	case dim == "multisampled_2d":
+		results = append(results, value{text: "textureNumSamples(" + x.text + ")", ty: uintType, pure: true})
+	}
+	for i, r := range results {
+		if err := t.storeOut(args[i], r); err != nil {
+			return value{}, err
+		}
+	}
+	return value{ty: voidType, stmt: true}, nil
+}
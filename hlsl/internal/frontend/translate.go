@@ -0,0 +1,1098 @@
+// Copyright 2025 The GoGPU Authors
+// SPDX-License-Identifier: MIT
+
+// Package frontend translates HLSL (Shader Model 5) shaders into WGSL,
+// which the WGSL frontend then lowers to IR.
+//
+// The accepted subset is the one Direct3D 11 era shader libraries mostly
+// use: cbuffers, structs, Texture and SamplerState resources, structured
+// buffers, groupshared memory, static constants, helper functions with in,
+// out, and inout parameters, the usual control flow, Interlocked atomics,
+// and the intrinsics with a WGSL counterpart. Entry point parameters and
+// results are matched to WGSL built-ins and locations through their SV_
+// and user semantics. HLSL's implicit conversions, matrix conventions,
+// and switch are rewritten into their WGSL forms. Anything else is
+// rejected with an error located in the HLSL source.
+package frontend
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/gogpu/naga/internal/wgslnames"
+	"github.com/gogpu/naga/internal/wgslwriter"
+)
+
+// Options configures a translation.
+type Options struct {
+	// EntryPoints maps the names of vertex and fragment entry points to
+	// their stage, "vertex" or "fragment". Functions with a [numthreads]
+	// attribute are compute entry points without being listed.
+	EntryPoints map[string]string
+
+	// Defines are object-like macros defined before the source. An empty
+	// value defines the macro as 1.
+	Defines map[string]string
+
+	// Bindings maps registers to WGSL bindings. Other resources bind to
+	// the group of their register space, taking the first free binding in
+	// declaration order.
+	Bindings map[Register]Binding
+}
+
+// Register is an HLSL register such as register(t3, space1).
+type Register struct {
+	Class byte // 'b', 't', 's', or 'u'
+	Index uint32
+	Space uint32
+}
+
+func (r Register) String() string {
+	if r.Space == 0 {
+		return fmt.Sprintf("%c%d", r.Class, r.Index)
+	}
+	return fmt.Sprintf("%c%d, space%d", r.Class, r.Index, r.Space)
+}
+
+// Binding is a WGSL @group and @binding pair.
+type Binding struct {
+	Group   uint32
+	Binding uint32
+}
+
+// Result is a translated shader.
+type Result struct {
+	// WGSL is the translated source.
+	WGSL string
+
+	// Lines maps each WGSL line, indexed from 0, to the HLSL line it was
+	// translated from.
+	Lines []int
+}
+
+// Translate translates an HLSL shader to WGSL.
+func Translate(source string, opts Options) (*Result, error) {
+	toks, err := lex(source, opts.Defines)
+	if err != nil {
+		return nil, err
+	}
+	unit, err := parse(toks)
+	if err != nil {
+		return nil, err
+	}
+	t := newTranslator(opts)
+	out, err := t.translate(unit)
+	if err != nil {
+		return nil, err
+	}
+	return &Result{WGSL: out.String(), Lines: out.Lines()}, nil
+}
+
+// symbolKind classifies what a name refers to.
+type symbolKind uint8
+
+const (
+	symVar symbolKind = iota
+	symConst
+	symParam
+)
+
+// symbol is a named variable, constant, parameter, resource, or cbuffer
+// member.
+type symbol struct {
+	kind symbolKind
+	tok  token
+	name string // HLSL name
+	text string // WGSL expression naming it
+	ty   *hlType
+
+	// space is the WGSL address space of a global: "private",
+	// "workgroup", "storage", "uniform", or "handle" for textures and
+	// samplers.
+	space    string
+	readOnly bool
+
+	// konst marks a WGSL const, usable in constant expressions; ival is
+	// its value when it is an integer constant.
+	konst bool
+	ival  *int64
+
+	// local marks a function-scope variable, whose address can be passed
+	// for an out parameter.
+	local bool
+
+	// assigned records that an in parameter is written to, so the
+	// function copies it into a local variable. pointer marks an out or
+	// inout parameter, passed as a pointer.
+	assigned bool
+	pointer  bool
+
+	// register is the register of a resource or cbuffer, and binding the
+	// WGSL binding it was given.
+	register *Register
+	binding  Binding
+}
+
+// funcInfo is a user function signature.
+type funcInfo struct {
+	decl   *funcDecl
+	name   string // WGSL name
+	ret    *hlType
+	params []*hlType
+	dirs   []string // "in", "out", or "inout" for each parameter
+
+	// stage is "vertex", "fragment", or "compute" for an entry point;
+	// workgroup is a compute entry point's [numthreads] size. wrapped
+	// marks an entry point whose body is an ordinary function called by
+	// a generated entry point.
+	stage     string
+	workgroup [3]int64
+	wrapped   bool
+	io        *entryIO
+
+	// calls are the user functions the body calls, for finding recursion.
+	calls []funcCall
+}
+
+type funcCall struct {
+	tok    token
+	callee *funcInfo
+}
+
+type translator struct {
+	opts Options
+
+	// rowMajor is the matrix packing set by #pragma pack_matrix.
+	rowMajor bool
+
+	structs map[string]*structInfo
+	globals map[string]*symbol
+	funcs   map[string]*funcInfo
+	scopes  []map[string]*symbol
+
+	// resources are the cbuffers, textures, samplers, and buffers in
+	// declaration order.
+	resources []*symbol
+
+	// varyings maps the semantics of vertex outputs and fragment inputs
+	// to their locations.
+	varyings map[string]int
+
+	// fn is the function being translated; nil at module scope.
+	fn *funcInfo
+
+	decls *wgslwriter.Writer
+
+	// w receives the statements of the function being translated; temps
+	// numbers the temporaries it declares. post holds the stores that
+	// copy out arguments back after the statement being translated, and
+	// noTemps is set where no temporaries can be declared.
+	w       *wgslwriter.Writer
+	temps   int
+	post    []func() error
+	noTemps bool
+
+	// marking is set during the first pass over function bodies, which
+	// marks the memory Interlocked functions use as atomic and the
+	// textures comparison sampling uses as depth textures.
+	marking bool
+}
+
+func newTranslator(opts Options) *translator {
+	return &translator{
+		opts:     opts,
+		structs:  make(map[string]*structInfo),
+		globals:  make(map[string]*symbol),
+		funcs:    make(map[string]*funcInfo),
+		varyings: make(map[string]int),
+		decls:    &wgslwriter.Writer{},
+	}
+}
+
+// translate runs the passes: declare every struct, global, and function
+// signature; bind resources; mark the memory atomics use; then write
+// declarations, function bodies, and entry points.
+func (t *translator) translate(unit *translationUnit) (*wgslwriter.Writer, error) {
+	var funcs []*funcDecl
+	var pending []func() error
+	for _, d := range unit.decls {
+		switch d := d.(type) {
+		case *packMatrixDecl:
+			t.rowMajor = d.rowMajor
+		case *funcDecl:
+			if err := t.declareFunc(d); err != nil {
+				return nil, err
+			}
+			if d.body != nil {
+				funcs = append(funcs, d)
+			}
+		case *structDecl:
+			st, err := t.declareStruct(d)
+			if err != nil {
+				return nil, err
+			}
+			pending = append(pending, func() error { t.writeStruct(d.tok.line, st); return nil })
+		case *cbufferDecl:
+			emit, err := t.declareCBuffer(d)
+			if err != nil {
+				return nil, err
+			}
+			pending = append(pending, emit)
+		case *varDecl:
+			emits, err := t.declareGlobal(d)
+			if err != nil {
+				return nil, err
+			}
+			pending = append(pending, emits...)
+		}
+	}
+	if err := t.bindResources(); err != nil {
+		return nil, err
+	}
+	entries, err := t.entryPoints(funcs)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := t.markAtomics(funcs); err != nil {
+		return nil, err
+	}
+	for _, emit := range pending {
+		if err := emit(); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, fn := range funcs {
+		t.funcs[fn.name].calls = nil
+	}
+	var bodies []*wgslwriter.Writer
+	for _, fn := range funcs {
+		w, err := t.function(fn)
+		if err != nil {
+			return nil, err
+		}
+		bodies = append(bodies, w)
+		if fi := t.funcs[fn.name]; fi.stage != "" && entries[fi] {
+			ep, err := t.entryWrapper(fi)
+			if err != nil {
+				return nil, err
+			}
+			bodies = append(bodies, ep)
+		}
+	}
+
+	if err := t.checkRecursion(funcs); err != nil {
+		return nil, err
+	}
+
+	out := &wgslwriter.Writer{}
+	out.Append(t.decls)
+	for _, w := range bodies {
+		if n := len(out.Lines()); n > 0 && !strings.HasSuffix(out.String(), "\n\n") {
+			out.Blank(out.Lines()[n-1])
+		}
+		out.Append(w)
+	}
+	return out, nil
+}
+
+// entryPoints gives each entry point its stage and reports those that
+// need a wrapper function.
+func (t *translator) entryPoints(funcs []*funcDecl) (map[*funcInfo]bool, error) {
+	for _, fn := range funcs {
+		fi := t.funcs[fn.name]
+		for _, a := range fn.attrs {
+			switch a.name {
+			case "numthreads":
+				if len(a.args) != 3 {
+					return nil, errorAt(a.tok, "numthreads takes 3 arguments, got %d", len(a.args))
+				}
+				for i, arg := range a.args {
+					v, err := t.constInt(arg)
+					if err != nil {
+						return nil, err
+					}
+					if v < 1 {
+						return nil, errorAt(arg.pos(), "numthreads sizes must be at least 1, got %d", v)
+					}
+					fi.workgroup[i] = v
+				}
+				fi.stage = "compute"
+			case "shader":
+				// [shader("compute")] names the stage of a library
+				// function; numthreads and the options decide it here.
+			case "earlydepthstencil":
+				return nil, errorAt(a.tok, "[earlydepthstencil] is not supported")
+			default:
+				return nil, errorAt(a.tok, "function attribute [%s] is not supported", a.name)
+			}
+		}
+	}
+	names := make([]string, 0, len(t.opts.EntryPoints))
+	for name := range t.opts.EntryPoints {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		stage := t.opts.EntryPoints[name]
+		fi, ok := t.funcs[name]
+		if !ok || fi.decl.body == nil {
+			return nil, &Error{Line: 1, Column: 1, Message: fmt.Sprintf("entry point %s is not defined", name)}
+		}
+		switch stage {
+		case "vertex", "fragment":
+		default:
+			return nil, errorAt(fi.decl.tok, "entry point %s has unsupported stage %q", name, stage)
+		}
+		if fi.stage == "compute" {
+			return nil, errorAt(fi.decl.tok, "entry point %s has [numthreads], so it is a compute shader", name)
+		}
+		fi.stage = stage
+	}
+
+	wrapped := make(map[*funcInfo]bool)
+	found := false
+	for _, fn := range funcs {
+		fi := t.funcs[fn.name]
+		if fi.stage == "" {
+			continue
+		}
+		found = true
+		needs, err := t.checkEntryPoint(fi)
+		if err != nil {
+			return nil, err
+		}
+		if needs {
+			// The HLSL function keeps its body under another name; the
+			// entry point takes its name and calls it.
+			wrapped[fi] = true
+			fi.wrapped = true
+			fi.name = t.uniqueName(wgslnames.Mangle(fn.name) + "_impl")
+		}
+	}
+	if !found {
+		return nil, &Error{Line: 1, Column: 1, Message: "no entry points; give compute shaders [numthreads] and name vertex and fragment entry points in the options"}
+	}
+	return wrapped, nil
+}
+
+// uniqueName returns name, with underscores appended until it names no
+// declared function, global, or struct.
+func (t *translator) uniqueName(name string) string {
+	for {
+		clash := false
+		for _, fi := range t.funcs {
+			clash = clash || fi.name == name
+		}
+		for _, g := range t.globals {
+			clash = clash || g.text == name
+		}
+		for _, st := range t.structs {
+			clash = clash || st.wgsl == name
+		}
+		if !clash {
+			return name
+		}
+		name += "_"
+	}
+}
+
+// checkRecursion rejects recursive calls, which HLSL and WGSL forbid.
+func (t *translator) checkRecursion(funcs []*funcDecl) error {
+	const (
+		unvisited = iota
+		visiting
+		done
+	)
+	state := make(map[*funcInfo]int)
+	var visit func(fi *funcInfo) error
+	visit = func(fi *funcInfo) error {
+		state[fi] = visiting
+		for _, c := range fi.calls {
+			switch state[c.callee] {
+			case visiting:
+				return errorAt(c.tok, "recursive call to %s", c.callee.decl.name)
+			case unvisited:
+				if err := visit(c.callee); err != nil {
+					return err
+				}
+			}
+		}
+		state[fi] = done
+		return nil
+	}
+	for _, fn := range funcs {
+		if fi := t.funcs[fn.name]; state[fi] == unvisited {
+			if err := visit(fi); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// constInt evaluates an integer constant expression at module scope.
+func (t *translator) constInt(e expr) (int64, error) {
+	v, err := t.expr(e)
+	if err != nil {
+		return 0, err
+	}
+	if v.ival == nil || !v.ty.isInteger() || v.ty.kind != kindScalar {
+		return 0, errorAt(e.pos(), "expected a constant integer expression")
+	}
+	return *v.ival, nil
+}
+
+// resolveType resolves a written type plus declarator dimensions. Every
+// call returns fresh types, so marking one atomic affects only the
+// declaration it belongs to.
+func (t *translator) resolveType(ts typeSpec, dims []expr) (*hlType, error) {
+	base, err := t.baseType(ts)
+	if err != nil {
+		return nil, err
+	}
+	// float a[2][3] is an array of two arrays of three floats.
+	all := append(append([]expr(nil), dims...), ts.dims...)
+	for i := len(all) - 1; i >= 0; i-- {
+		if base.kind == kindVoid {
+			return nil, errorAt(ts.tok, "array of void")
+		}
+		if base.isResource() {
+			return nil, errorAt(ts.tok, "arrays of %s are not supported", base)
+		}
+		arr := &hlType{kind: kindArray, elem: base}
+		if all[i] != nil {
+			n, err := t.constInt(all[i])
+			if err != nil {
+				return nil, err
+			}
+			if n < 1 {
+				return nil, errorAt(all[i].pos(), "array size must be positive, got %d", n)
+			}
+			arr.length = int(n)
+		}
+		base = arr
+	}
+	return base, nil
+}
+
+func (t *translator) baseType(ts typeSpec) (*hlType, error) {
+	if st, ok := t.structs[ts.name]; ok {
+		if len(ts.args) > 0 {
+			return nil, errorAt(ts.tok, "struct %s takes no template arguments", ts.name)
+		}
+		return &hlType{kind: kindStruct, st: st}, nil
+	}
+	if msg, ok := unsupportedTypes[ts.name]; ok {
+		return nil, errorAt(ts.tok, "%s: %s", ts.name, msg)
+	}
+	if tex, ok := textureTypes[ts.name]; ok {
+		return t.textureType(ts, tex)
+	}
+	switch ts.name {
+	case "void":
+		return voidType, nil
+	case "vector", "matrix":
+		return t.templateNumeric(ts)
+	case "SamplerState", "sampler", "sampler_state", "SamplerComparisonState":
+		if len(ts.args) > 0 {
+			return nil, errorAt(ts.tok, "%s takes no template arguments", ts.name)
+		}
+		return &hlType{kind: kindSampler, comparison: ts.name == "SamplerComparisonState"}, nil
+	case "StructuredBuffer", "RWStructuredBuffer":
+		if len(ts.args) != 1 || ts.args[0].ty == nil {
+			return nil, errorAt(ts.tok, "%s needs an element type, as in %s<float4>", ts.name, ts.name)
+		}
+		elem, err := t.resolveType(*ts.args[0].ty, nil)
+		if err != nil {
+			return nil, err
+		}
+		if elem.kind == kindVoid || elem.hasResource() || elem.hasRuntimeArray() {
+			return nil, errorAt(ts.tok, "%s cannot hold %s", ts.name, elem)
+		}
+		return &hlType{kind: kindBuffer, elem: elem, writable: ts.name == "RWStructuredBuffer"}, nil
+	}
+	if len(ts.args) > 0 {
+		return nil, errorAt(ts.tok, "%s takes no template arguments", ts.name)
+	}
+	if s, ok := scalarNames[ts.name]; ok {
+		return scalarType(s), nil
+	}
+	base, rows, cols, ok := splitNumericType(ts.name)
+	if !ok {
+		return nil, errorAt(ts.tok, "unknown type %s", ts.name)
+	}
+	ty, err := numericType(base, rows, cols)
+	if err != nil {
+		return nil, errorAt(ts.tok, "%v", err)
+	}
+	return ty, nil
+}
+
+// templateNumeric resolves vector<T, N> and matrix<T, R, C>.
+func (t *translator) templateNumeric(ts typeSpec) (*hlType, error) {
+	want := 2
+	if ts.name == "matrix" {
+		want = 3
+	}
+	if len(ts.args) == 0 {
+		// vector and matrix alone are float4 and float4x4.
+		if want == 2 {
+			return vectorType(scalarFloat, 4), nil
+		}
+		return matrixType(4, 4), nil
+	}
+	if len(ts.args) != want || ts.args[0].ty == nil {
+		return nil, errorAt(ts.tok, "%s takes a component type and %d sizes", ts.name, want-1)
+	}
+	base := ts.args[0].ty.name
+	if _, ok := scalarNames[base]; !ok && base != "double" {
+		return nil, errorAt(ts.args[0].ty.tok, "%s component type must be a scalar, got %s", ts.name, base)
+	}
+	var sizes []int
+	for _, a := range ts.args[1:] {
+		if a.value == nil {
+			return nil, errorAt(ts.tok, "%s sizes must be constants", ts.name)
+		}
+		n, err := t.constInt(a.value)
+		if err != nil {
+			return nil, err
+		}
+		if n < 1 || n > 4 {
+			return nil, errorAt(a.value.pos(), "%s size must be 1 to 4, got %d", ts.name, n)
+		}
+		sizes = append(sizes, int(n))
+	}
+	cols := 0
+	if want == 3 {
+		cols = sizes[1]
+	}
+	ty, err := numericType(base, sizes[0], cols)
+	if err != nil {
+		return nil, errorAt(ts.tok, "%v", err)
+	}
+	return ty, nil
+}
+
+func (t *translator) textureType(ts typeSpec, dim string) (*hlType, error) {
+	elem := vectorType(scalarFloat, 4)
+	args := ts.args
+	if dim == "multisampled_2d" && len(args) == 2 {
+		// The sample count of Texture2DMS<float4, 4> is a property of
+		// the bound texture.
+		args = args[:1]
+	}
+	switch {
+	case len(args) == 1 && args[0].ty != nil:
+		var err error
+		if elem, err = t.resolveType(*args[0].ty, nil); err != nil {
+			return nil, err
+		}
+		if !elem.isNumeric() {
+			return nil, errorAt(args[0].ty.tok, "%s element type must be a numeric scalar or vector, got %s", ts.name, elem)
+		}
+	case len(args) > 0:
+		return nil, errorAt(ts.tok, "%s takes an element type, as in %s<float4>", ts.name, ts.name)
+	}
+	return &hlType{kind: kindTexture, texture: dim, elem: elem}, nil
+}
+
+func (t *translator) declareStruct(d *structDecl) (*structInfo, error) {
+	if err := t.checkName(d.tok); err != nil {
+		return nil, err
+	}
+	if _, dup := t.structs[d.name]; dup {
+		return nil, errorAt(d.tok, "struct %s is already declared", d.name)
+	}
+	st := &structInfo{name: d.name, wgsl: wgslnames.Mangle(d.name)}
+	members, err := t.members(d.members, false)
+	if err != nil {
+		return nil, err
+	}
+	for _, m := range members {
+		if m.ty.hasRuntimeArray() {
+			return nil, errorAt(m.tok, "member %s: arrays must have a size", m.name)
+		}
+		if m.ty.hasResource() {
+			return nil, errorAt(m.tok, "member %s: structs cannot hold resources", m.name)
+		}
+	}
+	st.members = members
+	t.structs[d.name] = st
+	return st, nil
+}
+
+// members resolves struct or cbuffer members.
+func (t *translator) members(decls []varDecl, cbuffer bool) ([]structMember, error) {
+	var members []structMember
+	seen := make(map[string]bool)
+	for _, md := range decls {
+		for _, w := range md.quals.words {
+			switch w.text {
+			case "row_major", "column_major", "nointerpolation", "linear", "noperspective", "centroid", "sample", "precise":
+			default:
+				return nil, errorAt(w, "qualifier '%s' is not allowed on a member", w.text)
+			}
+		}
+		for _, n := range md.names {
+			if n.init != nil {
+				return nil, errorAt(n.tok, "member %s cannot have an initializer", n.name)
+			}
+			if n.register != nil {
+				return nil, errorAt(n.register.tok, "register on member %s is not supported", n.name)
+			}
+			if seen[n.name] {
+				return nil, errorAt(n.tok, "duplicate member %s", n.name)
+			}
+			seen[n.name] = true
+			ty, err := t.resolveType(md.ty, n.dims)
+			if err != nil {
+				return nil, err
+			}
+			if ty.kind == kindVoid {
+				return nil, errorAt(n.tok, "member %s has type void", n.name)
+			}
+			if ty.hasRuntimeArray() {
+				return nil, errorAt(n.tok, "member %s: arrays must have a size", n.name)
+			}
+			t.packMatrices(ty, md.quals)
+			m := structMember{tok: n.tok, name: n.name, wgsl: wgslnames.Mangle(n.name), ty: ty, semantic: n.semantic, quals: md.quals}
+			if cbuffer {
+				if err := cbufferMember(&m); err != nil {
+					return nil, err
+				}
+			}
+			members = append(members, m)
+		}
+	}
+	return members, nil
+}
+
+// packMatrices marks the matrices of a member type column-major unless
+// the member or #pragma pack_matrix says they are row-major.
+func (t *translator) packMatrices(ty *hlType, quals qualifiers) {
+	row := t.rowMajor
+	if quals.has("row_major") {
+		row = true
+	} else if quals.has("column_major") {
+		row = false
+	}
+	for ty.kind == kindArray {
+		ty = ty.elem
+	}
+	if ty.kind == kindMatrix {
+		ty.colMajor = !row
+	}
+}
+
+// cbufferMember adapts the representation of a cbuffer member to its
+// register layout: bools are 32-bit integers, and the elements of scalar
+// and vector arrays each fill a register.
+func cbufferMember(m *structMember) error {
+	ty := m.ty
+	if ty.kind == kindArray && ty.elem.isScalarOrVector() {
+		ty.padded = true
+		ty = ty.elem
+	}
+	if ty.isScalarOrVector() && ty.scalar == scalarBool {
+		ty.hostBool = true
+		return nil
+	}
+	if ty.kind == kindStruct || ty.kind == kindArray {
+		if containsBool(ty) {
+			return errorAt(m.tok, "cbuffer member %s: bool inside a struct or nested array is not supported; use uint", m.name)
+		}
+	}
+	if ty.kind == kindArray && ty.elem.kind == kindArray {
+		return errorAt(m.tok, "cbuffer member %s: arrays of arrays are not supported", m.name)
+	}
+	return nil
+}
+
+func containsBool(ty *hlType) bool {
+	switch ty.kind {
+	case kindScalar, kindVector:
+		return ty.scalar == scalarBool
+	case kindArray:
+		return containsBool(ty.elem)
+	case kindStruct:
+		for _, m := range ty.st.members {
+			if containsBool(m.ty) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (t *translator) writeStruct(line int, st *structInfo) {
+	t.decls.Line(line, "struct %s {", st.wgsl)
+	t.decls.Indent++
+	for _, m := range st.members {
+		t.decls.Line(m.tok.line, "%s: %s,", m.wgsl, m.ty.wgsl())
+	}
+	t.decls.Indent--
+	t.decls.Line(line, "}")
+	t.decls.Blank(line)
+}
+
+// declareCBuffer declares a constant buffer, whose members are globals,
+// and returns the function that writes its WGSL declaration.
+func (t *translator) declareCBuffer(d *cbufferDecl) (func() error, error) {
+	if err := t.declareGlobalName(d.tok); err != nil {
+		return nil, err
+	}
+	if _, dup := t.structs[d.name]; dup {
+		return nil, errorAt(d.tok, "%s is already declared", d.name)
+	}
+	members, err := t.members(d.members, true)
+	if err != nil {
+		return nil, err
+	}
+	for _, m := range members {
+		if m.ty.hasResource() {
+			return nil, errorAt(m.tok, "resource %s cannot be a cbuffer member; declare it at global scope", m.name)
+		}
+		if m.semantic != nil {
+			return nil, errorAt(m.semantic.tok, "cbuffer member %s cannot have a semantic", m.name)
+		}
+	}
+	st := &structInfo{name: d.name, wgsl: wgslnames.Mangle(d.name), members: members}
+	if err := checkCBufferLayout(st); err != nil {
+		return nil, err
+	}
+	t.structs[d.name] = st
+
+	varName := "_" + d.name
+	for _, m := range members {
+		if err := t.declareGlobalName(m.tok); err != nil {
+			return nil, err
+		}
+		t.globals[m.name] = &symbol{kind: symVar, tok: m.tok, name: m.name, text: varName + "." + m.wgsl, ty: m.ty, space: "uniform", readOnly: true}
+	}
+	sym := &symbol{kind: symVar, tok: d.tok, name: d.name, text: varName, ty: &hlType{kind: kindStruct, st: st}, space: "uniform", readOnly: true}
+	if err := t.addResource(sym, d.register, 'b'); err != nil {
+		return nil, err
+	}
+
+	line := d.tok.line
+	return func() error {
+		t.writeStruct(line, st)
+		t.decls.Line(line, "@group(%d) @binding(%d) var<uniform> %s: %s;", sym.binding.Group, sym.binding.Binding, varName, st.wgsl)
+		t.decls.Blank(line)
+		return nil
+	}, nil
+}
+
+// addResource records a resource and its register, which must have the
+// expected class.
+func (t *translator) addResource(sym *symbol, r *register, class byte) error {
+	if r != nil {
+		if r.class != class {
+			return errorAt(r.tok, "%s needs a %c register, not %c", sym.name, class, r.class)
+		}
+		sym.register = &Register{Class: r.class, Index: r.index, Space: r.space}
+	}
+	t.resources = append(t.resources, sym)
+	return nil
+}
+
+// bindResources gives every resource its WGSL binding. Registers in the
+// options take the binding mapped to them; every other resource takes the
+// first free binding in the group of its space, in declaration order,
+// since HLSL registers of different classes share index numbers.
+func (t *translator) bindResources() error {
+	used := make(map[Binding]*symbol)
+	for _, sym := range t.resources {
+		if sym.register == nil {
+			continue
+		}
+		b, ok := t.opts.Bindings[*sym.register]
+		if !ok {
+			continue
+		}
+		if prev, dup := used[b]; dup {
+			return errorAt(sym.tok, "%s (register %s) and %s (register %s) both bind to @group(%d) @binding(%d)",
+				prev.name, prev.register, sym.name, sym.register, b.Group, b.Binding)
+		}
+		used[b] = sym
+		sym.binding = b
+	}
+	for _, sym := range t.resources {
+		b := Binding{}
+		if sym.register != nil {
+			if _, ok := t.opts.Bindings[*sym.register]; ok {
+				continue
+			}
+			b.Group = sym.register.Space
+		}
+		for used[b] != nil {
+			b.Binding++
+		}
+		sym.binding = b
+		used[b] = sym
+	}
+	return nil
+}
+
+// checkName rejects names HLSL or this translation reserves.
+func (t *translator) checkName(tok token) error {
+	if strings.Contains(tok.text, "__") {
+		return errorAt(tok, "identifier %s is reserved", tok.text)
+	}
+	return nil
+}
+
+func (t *translator) declareGlobalName(tok token) error {
+	if err := t.checkName(tok); err != nil {
+		return err
+	}
+	if _, dup := t.globals[tok.text]; dup {
+		return errorAt(tok, "%s is already declared", tok.text)
+	}
+	if _, dup := t.funcs[tok.text]; dup {
+		return errorAt(tok, "%s is already declared as a function", tok.text)
+	}
+	return nil
+}
+
+// declareGlobal declares module-scope resources, variables, and constants
+// and returns the functions that write them.
+func (t *translator) declareGlobal(d *varDecl) ([]func() error, error) {
+	var emits []func() error
+	static := d.quals.has("static")
+	isConst := d.quals.has("const")
+	shared := d.quals.has("groupshared")
+	for _, w := range d.quals.words {
+		switch w.text {
+		case "static", "const", "groupshared", "uniform", "extern", "precise", "row_major", "column_major":
+		default:
+			return nil, errorAt(w, "qualifier '%s' is not allowed on a global variable", w.text)
+		}
+	}
+	for _, n := range d.names {
+		n := n
+		if err := t.declareGlobalName(n.tok); err != nil {
+			return nil, err
+		}
+		ty, err := t.resolveType(d.ty, n.dims)
+		if err != nil {
+			return nil, err
+		}
+		if ty.kind == kindVoid {
+			return nil, errorAt(n.tok, "variable %s has type void", n.name)
+		}
+		if n.semantic != nil {
+			return nil, errorAt(n.semantic.tok, "semantic on global %s is not supported", n.name)
+		}
+		if ty.isResource() {
+			emit, err := t.declareResource(d, n, ty)
+			if err != nil {
+				return nil, err
+			}
+			emits = append(emits, emit)
+			continue
+		}
+		if n.register != nil {
+			return nil, errorAt(n.register.tok, "register on %s is not supported; declare it in a cbuffer", n.name)
+		}
+		if !static && !isConst && !shared {
+			return nil, errorAt(n.tok, "global %s is an implicit uniform, which is not supported; declare it in a cbuffer, or make it static", n.name)
+		}
+		if ty.hasResource() {
+			return nil, errorAt(n.tok, "variable %s cannot hold resources", n.name)
+		}
+		sym := &symbol{kind: symVar, tok: n.tok, name: n.name, text: wgslnames.Mangle(n.name), ty: ty, space: "private"}
+		var init *value
+		switch {
+		case shared:
+			if n.init != nil {
+				return nil, errorAt(n.tok, "groupshared variable %s cannot have an initializer", n.name)
+			}
+			sym.space = "workgroup"
+		case isConst:
+			if n.init == nil {
+				return nil, errorAt(n.tok, "constant %s needs an initializer", n.name)
+			}
+			v, err := t.initializer(n.init, ty)
+			if err != nil {
+				return nil, err
+			}
+			if !v.konst {
+				return nil, errorAt(n.init.pos(), "initializer of constant %s is not a constant expression", n.name)
+			}
+			ty = v.ty
+			sym.ty, sym.kind, sym.konst, sym.ival, sym.readOnly = ty, symConst, true, v.ival, true
+			init = &v
+		case n.init != nil:
+			v, err := t.initializer(n.init, ty)
+			if err != nil {
+				return nil, err
+			}
+			if !v.konst {
+				return nil, errorAt(n.init.pos(), "initializer of static %s must be a constant expression", n.name)
+			}
+			ty, sym.ty = v.ty, v.ty
+			init = &v
+		}
+		if ty.hasRuntimeArray() {
+			return nil, errorAt(n.tok, "array %s needs a size", n.name)
+		}
+		t.globals[n.name] = sym
+		line := n.tok.line
+		emits = append(emits, func() error {
+			switch {
+			case sym.kind == symConst:
+				t.decls.Line(line, "const %s: %s = %s;", sym.text, ty.wgsl(), init.text)
+			case sym.space == "workgroup":
+				t.decls.Line(line, "var<workgroup> %s: %s;", sym.text, ty.wgsl())
+			default:
+				if ty.hasAtomic() {
+					return errorAt(n.tok, "Interlocked functions need groupshared memory or a RWStructuredBuffer; %s is neither", n.name)
+				}
+				if init != nil {
+					t.decls.Line(line, "var<private> %s: %s = %s;", sym.text, ty.wgsl(), init.text)
+				} else {
+					t.decls.Line(line, "var<private> %s: %s;", sym.text, ty.wgsl())
+				}
+			}
+			return nil
+		})
+	}
+	return emits, nil
+}
+
+// declareResource declares a texture, sampler, or structured buffer.
+func (t *translator) declareResource(d *varDecl, n declarator, ty *hlType) (func() error, error) {
+	for _, w := range d.quals.words {
+		if w.text != "uniform" && w.text != "extern" {
+			return nil, errorAt(w, "qualifier '%s' is not allowed on resource %s", w.text, n.name)
+		}
+	}
+	if n.init != nil {
+		return nil, errorAt(n.tok, "resource %s cannot have an initializer", n.name)
+	}
+	sym := &symbol{kind: symVar, tok: n.tok, name: n.name, text: wgslnames.Mangle(n.name), ty: ty, space: "handle", readOnly: true}
+	class := byte('t')
+	switch {
+	case ty.kind == kindSampler:
+		class = 's'
+	case ty.kind == kindBuffer && ty.writable:
+		class, sym.readOnly = 'u', false
+	}
+	if ty.kind == kindBuffer {
+		sym.space = "storage"
+		if _, err := structuredStride(ty.elem, n.tok); err != nil {
+			return nil, err
+		}
+	}
+	if err := t.addResource(sym, n.register, class); err != nil {
+		return nil, err
+	}
+	t.globals[n.name] = sym
+	line := n.tok.line
+	return func() error {
+		b := sym.binding
+		switch ty.kind {
+		case kindBuffer:
+			access := "read"
+			if ty.writable {
+				access = "read_write"
+			} else if ty.elem.hasAtomic() {
+				return errorAt(n.tok, "Interlocked functions need a RWStructuredBuffer; %s is read-only", n.name)
+			}
+			t.decls.Line(line, "@group(%d) @binding(%d) var<storage, %s> %s: %s;", b.Group, b.Binding, access, sym.text, ty.wgsl())
+		default:
+			t.decls.Line(line, "@group(%d) @binding(%d) var %s: %s;", b.Group, b.Binding, sym.text, ty.wgsl())
+		}
+		t.decls.Blank(line)
+		return nil
+	}, nil
+}
+
+// initializer translates an initializer, which may be a list, and
+// converts it to ty. An unsized array takes its length from a list.
+func (t *translator) initializer(e expr, ty *hlType) (value, error) {
+	if list, ok := e.(*listExpr); ok {
+		return t.list(list, ty)
+	}
+	v, err := t.rvalue(e)
+	if err != nil {
+		return value{}, err
+	}
+	return t.convert(v, ty, e.pos())
+}
+
+func (t *translator) declareFunc(d *funcDecl) error {
+	ret, err := t.resolveType(d.ret, nil)
+	if err != nil {
+		return err
+	}
+	if ret.kind == kindArray {
+		return errorAt(d.ret.tok, "functions returning arrays are not supported")
+	}
+	if ret.isResource() {
+		return errorAt(d.ret.tok, "functions returning resources are not supported")
+	}
+	var params []*hlType
+	var dirs []string
+	for _, p := range d.params {
+		for _, w := range p.quals.words {
+			switch w.text {
+			case "in", "out", "inout", "const", "precise", "nointerpolation", "linear", "noperspective", "centroid", "sample":
+			case "uniform":
+				return errorAt(w, "uniform parameters are not supported; use a cbuffer")
+			default:
+				return errorAt(w, "qualifier '%s' is not allowed on a parameter", w.text)
+			}
+		}
+		ty, err := t.resolveType(p.ty, nil)
+		if err != nil {
+			return err
+		}
+		if ty.kind == kindVoid {
+			return errorAt(p.tok, "parameter of type void")
+		}
+		if ty.hasRuntimeArray() {
+			return errorAt(p.tok, "unsized array parameters are not supported")
+		}
+		dir := p.direction()
+		if ty.kind == kindBuffer {
+			return errorAt(p.tok, "buffer parameters are not supported; use the global buffer")
+		}
+		if ty.isResource() && dir != "in" {
+			return errorAt(p.tok, "%s parameters of type %s are not supported", dir, ty)
+		}
+		params = append(params, ty)
+		dirs = append(dirs, dir)
+	}
+	if prev, ok := t.funcs[d.name]; ok {
+		same := prev.ret.equal(ret) && len(prev.params) == len(params)
+		for i := 0; same && i < len(params); i++ {
+			same = prev.params[i].equal(params[i]) && prev.dirs[i] == dirs[i]
+		}
+		if !same {
+			return errorAt(d.tok, "function %s is overloaded; overloading is not supported", d.name)
+		}
+		if prev.decl.body != nil && d.body != nil {
+			return errorAt(d.tok, "function %s is defined twice", d.name)
+		}
+		if d.body != nil {
+			prev.decl = d
+		}
+		return nil
+	}
+	if err := t.checkName(d.tok); err != nil {
+		return err
+	}
+	if _, ok := builtinFuncs[d.name]; ok {
+		return errorAt(d.tok, "%s is an intrinsic function and cannot be redeclared", d.name)
+	}
+	if _, dup := t.globals[d.name]; dup {
+		return errorAt(d.tok, "%s is already declared", d.name)
+	}
+	t.funcs[d.name] = &funcInfo{decl: d, name: wgslnames.Mangle(d.name), ret: ret, params: params, dirs: dirs}
+	return nil
+}
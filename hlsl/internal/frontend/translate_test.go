@@ -0,0 +1,375 @@
+// Copyright 2025 The GoGPU Authors
+// SPDX-License-Identifier: MIT
+
+package frontend
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/gogpu/naga/ir"
+	"github.com/gogpu/naga/spirv"
+	"github.com/gogpu/naga/wgsl"
+)
+
+// compile translates source, lowers the WGSL, validates the IR, and
+// compiles it to SPIR-V.
+func compile(t *testing.T, source string, opts Options) string {
+	t.Helper()
+	res, err := Translate(source, opts)
+	if err != nil {
+		t.Fatalf("Translate: %v", err)
+	}
+	tokens, err := wgsl.NewLexer(res.WGSL).Tokenize()
+	if err != nil {
+		t.Fatalf("tokenize: %v\n%s", err, res.WGSL)
+	}
+	ast, err := wgsl.NewParser(tokens).Parse()
+	if err != nil {
+		t.Fatalf("parse: %v\n%s", err, res.WGSL)
+	}
+	module, err := wgsl.LowerWithSource(ast, res.WGSL)
+	if err != nil {
+		t.Fatalf("lower: %v\n%s", err, res.WGSL)
+	}
+	if _, err := ir.Validate(module); err != nil {
+		t.Fatalf("validate: %v\n%s", err, res.WGSL)
+	}
+	if _, err := spirv.NewBackend(spirv.DefaultOptions()).Compile(module); err != nil {
+		t.Fatalf("spirv: %v\n%s", err, res.WGSL)
+	}
+	if n := strings.Count(res.WGSL, "\n"); n != len(res.Lines) {
+		t.Errorf("line map has %d entries for %d lines", len(res.Lines), n)
+	}
+	return res.WGSL
+}
+
+func contains(t *testing.T, out string, wants ...string) {
+	t.Helper()
+	for _, want := range wants {
+		if !strings.Contains(out, want) {
+			t.Errorf("output lacks %q:\n%s", want, out)
+		}
+	}
+}
+
+var graphicsOptions = Options{EntryPoints: map[string]string{"VSMain": "vertex", "PSMain": "fragment"}}
+
+const meshShader = `#pragma pack_matrix(row_major)
+#define MAX_LIGHTS 2
+
+cbuffer Camera : register(b0) {
+    float4x4 viewProj;
+    float3 eye;
+    float time;
+};
+
+cbuffer Lights : register(b1) {
+    float4 lightDir[MAX_LIGHTS];
+    bool shadows;
+};
+
+Texture2D albedo : register(t0);
+SamplerState linearSampler : register(s0);
+
+struct VSInput {
+    float3 position : POSITION;
+    float3 normal : NORMAL;
+    float2 uv : TEXCOORD0;
+};
+
+struct PSInput {
+    float4 position : SV_Position;
+    float3 normal : NORMAL;
+    float2 uv : TEXCOORD;
+    nointerpolation uint instance : INSTANCE;
+};
+
+PSInput VSMain(VSInput input, uint id : SV_InstanceID) {
+    PSInput output;
+    output.position = mul(float4(input.position, 1.0), viewProj);
+    output.normal = input.normal;
+    output.uv = input.uv;
+    output.instance = id;
+    return output;
+}
+
+float lambert(float3 n, out float rim) {
+    float sum = 0;
+    [unroll] for (int i = 0; i < MAX_LIGHTS; i++) {
+        sum += saturate(dot(n, -lightDir[i].xyz));
+    }
+    rim = 1 - abs(n.z);
+    return shadows ? sum * 0.5 : sum;
+}
+
+float4 PSMain(PSInput input) : SV_Target {
+    float rim;
+    float4 color = albedo.Sample(linearSampler, input.uv);
+    color.rgb *= lambert(normalize(input.normal), rim) + rim;
+    if (color.a < 0.1)
+        discard;
+    return lerp(color, float4(1, 1, 1, 1), frac(time));
+}
+`
+
+func TestTranslateGraphics(t *testing.T) {
+	out := compile(t, meshShader, graphicsOptions)
+	contains(t, out,
+		"@group(0) @binding(0) var<uniform> _Camera: Camera;",
+		"lightDir: array<vec4<f32>, 2>,",
+		"shadows: u32,",
+		"@group(0) @binding(1) var<uniform> _Lights: Lights;",
+		"@group(0) @binding(2) var albedo: texture_2d<f32>;",
+		"@group(0) @binding(3) var linearSampler: sampler;",
+		"struct VSMainOutput {",
+		"@builtin(position) sv_position: vec4<f32>,",
+		"@location(2) @interpolate(flat) instance0: u32,",
+		"fn VSMain(@location(0) in_position0: vec3<f32>, @location(1) in_normal0: vec3<f32>, @location(2) in_texcoord0: vec2<f32>, @builtin(instance_index) in_sv_instanceid: u32) -> VSMainOutput {",
+		"(_Camera.viewProj * vec4<f32>(input.position, 1.0f))",
+		"fn lambert(n: vec3<f32>, rim: ptr<function, f32>) -> f32 {",
+		"(*rim) = (1.0f - abs(n.z));",
+		"(_Lights.shadows != 0u)",
+		"lambert(normalize(input.normal), &rim)",
+		"textureSample(albedo, linearSampler, input.uv)",
+		"discard;",
+		"@fragment",
+		"-> @location(0) vec4<f32> {",
+		"mix(color, vec4<f32>(1.0f, 1.0f, 1.0f, 1.0f), vec4<f32>(fract(_Camera.time)))",
+	)
+}
+
+const histogramShader = `#define GROUP 64
+
+struct Particle {
+    float3 position;
+    float mass;
+    float4 velocity;
+};
+
+cbuffer Params : register(b0) {
+    float dt;
+    uint count;
+};
+
+StructuredBuffer<Particle> input : register(t0);
+RWStructuredBuffer<Particle> output : register(u0);
+RWStructuredBuffer<uint> histogram : register(u1);
+
+groupshared uint bins[16];
+groupshared float tile[GROUP];
+static const float scale = 4.0;
+
+uint bucket(float x) {
+    return min((uint)(x * scale), 15u);
+}
+
+[numthreads(GROUP, 1, 1)]
+void CSMain(uint3 id : SV_DispatchThreadID, uint lid : SV_GroupIndex) {
+    if (lid < 16)
+        bins[lid] = 0;
+    GroupMemoryBarrierWithGroupSync();
+    uint n, stride;
+    input.GetDimensions(n, stride);
+    if (id.x >= min(n, count))
+        return;
+    Particle p = input[id.x];
+    p.position += p.velocity.xyz * dt;
+    tile[lid] = p.mass;
+    uint old;
+    InterlockedAdd(bins[bucket(p.position.y)], 1, old);
+    GroupMemoryBarrierWithGroupSync();
+    output[id.x] = p;
+    if (lid < 16) {
+        uint previous;
+        InterlockedCompareExchange(histogram[lid], 0, bins[lid], previous);
+        InterlockedMax(histogram[16 + lid], old);
+    }
+}
+`
+
+func TestTranslateCompute(t *testing.T) {
+	out := compile(t, histogramShader, Options{})
+	contains(t, out,
+		"@group(0) @binding(1) var<storage, read> input: array<Particle>;",
+		"@group(0) @binding(2) var<storage, read_write> output: array<Particle>;",
+		"@group(0) @binding(3) var<storage, read_write> histogram: array<atomic<u32>>;",
+		"var<workgroup> bins: array<atomic<u32>, 16>;",
+		"var<workgroup> tile: array<f32, 64>;",
+		"const scale: f32 = 4.0f;",
+		"@compute @workgroup_size(64, 1, 1)",
+		"fn CSMain(@builtin(global_invocation_id) id: vec3<u32>, @builtin(local_invocation_index) lid: u32) {",
+		"atomicStore(&bins[lid], 0u);",
+		"workgroupBarrier();",
+		"arrayLength(&input)",
+		"old = atomicAdd(&bins[bucket(p.position.y)], 1u);",
+		"atomicCompareExchangeWeak(&histogram[lid], compare",
+		"atomicMax(&histogram[(16u + lid)], old);",
+	)
+}
+
+func TestTranslateMatrices(t *testing.T) {
+	out := compile(t, `cbuffer Transforms {
+    float4x4 world;
+    row_major float3x4 bones;
+    float2x2 rotation;
+};
+
+RWStructuredBuffer<float4> result;
+
+[numthreads(1, 1, 1)]
+void main() {
+    float4 p = mul(world, float4(1, 2, 3, 1));
+    float3 b = mul(bones, p);
+    float2 r = mul(float2(1, 0), rotation);
+    float3x3 m = (float3x3)world;
+    m._m01 = 2;
+    result[0] = float4(mul(m, b) + float3(r, world[0][1]), determinant(rotation));
+}
+`, Options{})
+	contains(t, out,
+		"world: mat4x4<f32>,",
+		"bones: mat3x4<f32>,",
+		"rotation: mat2x2<f32>,",
+		"(vec4<f32>(1.0f, 2.0f, 3.0f, 1.0f) * transpose(_Transforms.world))",
+		"(p * _Transforms.bones)",
+		"(transpose(_Transforms.rotation) * vec2<f32>(1.0f, 0.0f))",
+		"m[0][1] = 2.0f;",
+	)
+}
+
+func TestTranslateOutParameters(t *testing.T) {
+	out := compile(t, `RWStructuredBuffer<int> data;
+groupshared int shared_[4];
+
+void split(int v, out int hi, inout int lo) {
+    hi = v >> 8;
+    lo += v & 255;
+}
+
+int twice(int x) {
+    x *= 2;
+    return x;
+}
+
+[numthreads(4, 1, 1)]
+void main(uint i : SV_GroupThreadID) {
+    int lo = 0;
+    split(data[i], shared_[i], lo);
+    int4 v;
+    split(twice(lo), v.y, v.z);
+    data[i] = v.y + v.z + shared_[i];
+}
+`, Options{})
+	contains(t, out,
+		"fn split(v: i32, hi: ptr<function, i32>, lo: ptr<function, i32>) {",
+		"(*lo) += (v & 255i);",
+		"fn twice(x_in: i32) -> i32 {",
+		"var x = x_in;",
+		"var arg1: i32;",
+		"split(data[i], &arg1, &lo);",
+		"shared_[i] = arg1;",
+		"fn main(@builtin(local_invocation_id) in_sv_groupthreadid: vec3<u32>) {",
+		"main_impl(in_sv_groupthreadid.x);",
+	)
+}
+
+func TestTranslateBindings(t *testing.T) {
+	const source = `Texture2D a : register(t0);
+Texture2D b : register(t0, space1);
+SamplerState s : register(s0);
+RWStructuredBuffer<float4> o : register(u2);
+
+[numthreads(1, 1, 1)]
+void main() {
+    o[0] = a.SampleLevel(s, float2(0, 0), 0) + b.Load(int3(0, 0, 0));
+}
+`
+	out := compile(t, source, Options{Bindings: map[Register]Binding{{Class: 's', Index: 0}: {Group: 2, Binding: 5}}})
+	contains(t, out,
+		"@group(0) @binding(0) var a: texture_2d<f32>;",
+		"@group(1) @binding(0) var b: texture_2d<f32>;",
+		"@group(2) @binding(5) var s: sampler;",
+		"@group(0) @binding(1) var<storage, read_write> o: array<vec4<f32>>;",
+	)
+
+	_, err := Translate(source, Options{Bindings: map[Register]Binding{
+		{Class: 't', Index: 0}: {Group: 0, Binding: 1},
+		{Class: 'u', Index: 2}: {Group: 0, Binding: 1},
+	}})
+	if err == nil || !strings.Contains(err.Error(), "a (register t0) and o (register u2) both bind to @group(0) @binding(1)") {
+		t.Errorf("error = %v, want a duplicate binding", err)
+	}
+}
+
+func TestTranslateErrors(t *testing.T) {
+	const compute = "[numthreads(1, 1, 1)]\n"
+	tests := []struct {
+		name   string
+		source string
+		want   string // "line:column: message" prefix
+	}{
+		{"no entry point", "float f(float x) { return x; }", "1:1: no entry points"},
+		{"layout", "cbuffer C {\n    float a;\n    float2 b;\n};\n" + compute + "void main() {}", "3:12: cbuffer member b is at byte offset 4 in HLSL but 8 in WGSL"},
+		{"structured stride", "StructuredBuffer<float3> b;\n" + compute + "void main() {}", "1:26: structured buffer element float3 has a stride of 12 bytes in HLSL but 16 in WGSL"},
+		{"fragment output", "float4 PSMain(float4 p : SV_Position) : COLOR { return p; }", "1:41: fragment shader outputs must have SV_Target or SV_Depth semantics, got COLOR0"},
+		{"compute input", compute + "void main(uint i : TEXCOORD) {}", "2:20: compute shader inputs must have SV_ semantics"},
+		{"missing semantic", compute + "void main(uint i) {}", "2:16: entry point input or output i needs a semantic"},
+		{"vector mul", compute + "void main() {\n    float2x2 m = 1;\n    float2 v = m * float2(1, 2);\n}", "4:18: * of float2x2 and float2 is not supported; use mul for matrix products"},
+		{"readonly", "StructuredBuffer<uint> b;\n" + compute + "void main() {\n    b[0] = 1;\n}", "4:6: cannot assign to read-only b"},
+		{"fallthrough", compute + "void main() {\n    int y = 1;\n    switch (y) { case 1: y = 2; case 2: break; }\n}", "4:18: falling through"},
+		{"private atomic", "static uint c;\n" + compute + "void main() {\n    InterlockedAdd(c, 1);\n}", "4:20: InterlockedAdd needs groupshared memory or a RWStructuredBuffer"},
+		{"recursion", "int f(int a);\nint g(int a) { return f(a); }\nint f(int a) { return g(a); }\n" + compute + "void main() { int y = f(1); }", "3:23: recursive call to g"},
+		{"unsupported type", compute + "void main() { double d = 1.0; }", "2:15: double: double precision is not supported"},
+		{"undeclared", compute + "void main() {\n    y = 1;\n}", "3:5: undeclared identifier y"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var opts Options
+			if strings.Contains(tt.source, "PSMain") {
+				opts.EntryPoints = map[string]string{"PSMain": "fragment"}
+			}
+			_, err := Translate(tt.source, opts)
+			var fe *Error
+			if !errors.As(err, &fe) {
+				t.Fatalf("error = %v (%T), want *Error", err, err)
+			}
+			if !strings.HasPrefix(err.Error(), tt.want) {
+				t.Errorf("error = %q, want prefix %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestTranslateTextures(t *testing.T) {
+	out := compile(t, `Texture2DArray<float4> layers : register(t0);
+Texture2D<float> shadowMap : register(t1);
+Texture3D<int2> volume : register(t2);
+SamplerState samp : register(s0);
+SamplerComparisonState cmp : register(s1);
+RWStructuredBuffer<float4> result : register(u0);
+
+[numthreads(8, 8, 1)]
+void main(uint3 id : SV_DispatchThreadID) {
+    float2 uv = id.xy / 64.0;
+    float4 c = layers.SampleLevel(samp, float3(uv, 2), 0);
+    float lit = shadowMap.SampleCmpLevelZero(cmp, uv, 0.5);
+    float4 red = shadowMap.GatherRed(samp, uv);
+    int2 v = volume.Load(int4(id, 0));
+    uint w, h, levels;
+    shadowMap.GetDimensions(0, w, h, levels);
+    result[id.y * 8 + id.x] = c * lit + red + float4(v, w, levels);
+}
+`, Options{})
+	contains(t, out,
+		"@group(0) @binding(1) var shadowMap: texture_depth_2d;",
+		"@group(0) @binding(2) var volume: texture_3d<i32>;",
+		"textureSampleLevel(layers, samp, vec3<f32>(uv, 2.0f).xy, i32(round(vec3<f32>(uv, 2.0f).z)), 0.0f)",
+		"textureSampleCompareLevel(shadowMap, cmp, uv, 0.5f)",
+		"textureGather(shadowMap, samp, uv)",
+		"textureLoad(volume, vec4<i32>(vec3<i32>(id), 0i).xyz, vec4<i32>(vec3<i32>(id), 0i).w).xy",
+		"let dims1 = textureDimensions(shadowMap, 0u);",
+		"levels = textureNumLevels(shadowMap);",
+	)
+}
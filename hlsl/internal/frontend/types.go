@@ -0,0 +1,465 @@
+// Copyright 2025 The GoGPU Authors
+// SPDX-License-Identifier: MIT
+
+package frontend
+
+import (
+	"fmt"
+	"strings"
+)
+
+// scalarKind is the component type of a scalar, vector, or matrix.
+type scalarKind uint8
+
+const (
+	scalarBool scalarKind = iota
+	scalarInt
+	scalarUint
+	scalarFloat
+)
+
+// typeKind classifies an HLSL type.
+type typeKind uint8
+
+const (
+	kindVoid typeKind = iota
+	kindScalar
+	kindVector
+	kindMatrix
+	kindArray
+	kindStruct
+	kindTexture
+	kindSampler
+	kindBuffer // StructuredBuffer or RWStructuredBuffer
+)
+
+// hlType is a resolved HLSL type.
+type hlType struct {
+	kind   typeKind
+	scalar scalarKind
+	size   int // vector components, or matrix rows
+	cols   int // matrix columns
+
+	elem   *hlType // array, buffer, or texture element
+	length int     // array length; 0 for a runtime-sized array
+
+	st *structInfo
+
+	// atomic marks an int or uint scalar that Interlocked functions
+	// operate on; it is declared atomic<T> in WGSL and read with
+	// atomicLoad.
+	atomic bool
+
+	// colMajor marks a matrix stored column by column, the HLSL default
+	// for struct and cbuffer members. A floatRxC value is a WGSL
+	// matRxC<f32>, whose columns are the HLSL rows, so a column-major
+	// matrix is declared matCxR<f32> and transposed when read or written.
+	colMajor bool
+
+	// hostBool marks a bool cbuffer member, which the host writes as a
+	// 32-bit integer. WGSL declares it u32 and reads compare with zero.
+	hostBool bool
+
+	// padded marks a cbuffer array of scalars or vectors, whose elements
+	// each take a 16-byte register. WGSL declares the elements as
+	// four-component vectors and reads their leading components.
+	padded bool
+
+	// texture is the dimension of a texture, as spelled in WGSL texture
+	// type names: "1d", "2d", "2d_array", "3d", "cube", "cube_array", or
+	// "multisampled_2d". depth marks a texture used for comparison
+	// sampling, which WGSL declares as a depth texture.
+	texture string
+	depth   bool
+
+	// comparison marks a SamplerComparisonState; writable marks a
+	// RWStructuredBuffer.
+	comparison bool
+	writable   bool
+}
+
+// structInfo is a declared struct or cbuffer type.
+type structInfo struct {
+	name    string // HLSL name
+	wgsl    string // WGSL name
+	members []structMember
+}
+
+type structMember struct {
+	tok      token
+	name     string
+	wgsl     string
+	ty       *hlType
+	semantic *semantic
+	quals    qualifiers
+}
+
+func (s *structInfo) member(name string) (int, *structMember) {
+	for i := range s.members {
+		if s.members[i].name == name {
+			return i, &s.members[i]
+		}
+	}
+	return -1, nil
+}
+
+var (
+	voidType  = &hlType{kind: kindVoid}
+	boolType  = scalarType(scalarBool)
+	intType   = scalarType(scalarInt)
+	uintType  = scalarType(scalarUint)
+	floatType = scalarType(scalarFloat)
+)
+
+func scalarType(s scalarKind) *hlType { return &hlType{kind: kindScalar, scalar: s} }
+
+func vectorType(s scalarKind, n int) *hlType {
+	if n == 1 {
+		return scalarType(s)
+	}
+	return &hlType{kind: kindVector, scalar: s, size: n}
+}
+
+// matrixType is the HLSL floatRxC type.
+func matrixType(rows, cols int) *hlType {
+	return &hlType{kind: kindMatrix, scalar: scalarFloat, size: rows, cols: cols}
+}
+
+// scalarNames maps the HLSL scalar type names to their component types.
+// Reduced and half precision types compute in float and int.
+var scalarNames = map[string]scalarKind{
+	"bool": scalarBool, "int": scalarInt, "uint": scalarUint, "dword": scalarUint, "float": scalarFloat,
+	"half": scalarFloat, "min16float": scalarFloat, "min10float": scalarFloat,
+	"min16int": scalarInt, "min12int": scalarInt, "min16uint": scalarUint,
+}
+
+// textureTypes maps the supported texture types to their WGSL dimension.
+var textureTypes = map[string]string{
+	"Texture1D": "1d", "Texture2D": "2d", "Texture2DArray": "2d_array", "Texture3D": "3d",
+	"TextureCube": "cube", "TextureCubeArray": "cube_array", "Texture2DMS": "multisampled_2d",
+}
+
+// unsupportedTypes are HLSL types the frontend recognizes only to reject
+// them with a clear error.
+var unsupportedTypes = map[string]string{
+	"double":                  "double precision is not supported",
+	"Buffer":                  "typed buffers are not supported; use StructuredBuffer",
+	"RWBuffer":                "typed buffers are not supported; use RWStructuredBuffer",
+	"ByteAddressBuffer":       "byte address buffers are not supported; use StructuredBuffer",
+	"RWByteAddressBuffer":     "byte address buffers are not supported; use RWStructuredBuffer",
+	"AppendStructuredBuffer":  "append buffers are not supported",
+	"ConsumeStructuredBuffer": "consume buffers are not supported",
+	"RWTexture1D":             "storage textures are not supported, since HLSL does not give their format",
+	"RWTexture2D":             "storage textures are not supported, since HLSL does not give their format",
+	"RWTexture2DArray":        "storage textures are not supported, since HLSL does not give their format",
+	"RWTexture3D":             "storage textures are not supported, since HLSL does not give their format",
+	"Texture1DArray":          "Texture1DArray has no WGSL equivalent",
+	"Texture2DMSArray":        "Texture2DMSArray has no WGSL equivalent",
+	"texture":                 "Direct3D 9 style textures are not supported; use Texture2D",
+	"sampler1D":               "Direct3D 9 style samplers are not supported; use SamplerState",
+	"sampler2D":               "Direct3D 9 style samplers are not supported; use SamplerState",
+	"sampler3D":               "Direct3D 9 style samplers are not supported; use SamplerState",
+	"samplerCUBE":             "Direct3D 9 style samplers are not supported; use SamplerState",
+}
+
+// isBuiltinTypeName reports whether name is an HLSL type keyword.
+func isBuiltinTypeName(name string) bool {
+	if _, ok := scalarNames[name]; ok {
+		return true
+	}
+	if _, ok := textureTypes[name]; ok {
+		return true
+	}
+	if _, ok := unsupportedTypes[name]; ok {
+		return true
+	}
+	switch name {
+	case "void", "vector", "matrix", "SamplerState", "SamplerComparisonState", "sampler", "sampler_state",
+		"StructuredBuffer", "RWStructuredBuffer":
+		return true
+	}
+	_, _, _, ok := splitNumericType(name)
+	return ok
+}
+
+// splitNumericType splits float4 into float, 4, 0 and float3x4 into
+// float, 3, 4.
+func splitNumericType(name string) (base string, rows, cols int, ok bool) {
+	i := len(name)
+	for i > 0 && (isDigit(name[i-1]) || name[i-1] == 'x') {
+		i--
+	}
+	if i == len(name) {
+		return "", 0, 0, false
+	}
+	base = name[:i]
+	if _, known := scalarNames[base]; !known && base != "double" {
+		return "", 0, 0, false
+	}
+	dims := name[i:]
+	switch {
+	case len(dims) == 1 && dims[0] >= '1' && dims[0] <= '4':
+		return base, int(dims[0] - '0'), 0, true
+	case len(dims) == 3 && dims[1] == 'x' && dims[0] >= '1' && dims[0] <= '4' && dims[2] >= '1' && dims[2] <= '4':
+		return base, int(dims[0] - '0'), int(dims[2] - '0'), true
+	}
+	return "", 0, 0, false
+}
+
+// numericType resolves a scalar, vector, or matrix of the named
+// component type, with cols 0 for a scalar or vector.
+func numericType(base string, rows, cols int) (*hlType, error) {
+	if base == "double" {
+		return nil, fmt.Errorf("double precision is not supported")
+	}
+	s := scalarNames[base]
+	if cols == 0 {
+		return vectorType(s, rows), nil
+	}
+	if s != scalarFloat {
+		return nil, fmt.Errorf("%s%dx%d: only float matrices are supported", base, rows, cols)
+	}
+	if rows < 2 || cols < 2 {
+		return nil, fmt.Errorf("%s%dx%d: matrices need at least two rows and columns", base, rows, cols)
+	}
+	return matrixType(rows, cols), nil
+}
+
+// isNumeric reports whether t is a scalar or vector of int, uint, or
+// float.
+func (t *hlType) isNumeric() bool {
+	return (t.kind == kindScalar || t.kind == kindVector) && t.scalar != scalarBool
+}
+
+// isScalarOrVector reports whether t is a scalar or vector of any
+// component type.
+func (t *hlType) isScalarOrVector() bool {
+	return t.kind == kindScalar || t.kind == kindVector
+}
+
+func (t *hlType) isInteger() bool {
+	return (t.kind == kindScalar || t.kind == kindVector) && (t.scalar == scalarInt || t.scalar == scalarUint)
+}
+
+// isResource reports whether t is a texture, sampler, or buffer.
+func (t *hlType) isResource() bool {
+	return t.kind == kindTexture || t.kind == kindSampler || t.kind == kindBuffer
+}
+
+// withScalar returns a scalar, vector, or matrix shaped like t with
+// component s.
+func (t *hlType) withScalar(s scalarKind) *hlType {
+	switch t.kind {
+	case kindVector:
+		return vectorType(s, t.size)
+	case kindMatrix:
+		return t
+	}
+	return scalarType(s)
+}
+
+// vectorSize is 1 for scalars and the component count for vectors.
+func (t *hlType) vectorSize() int {
+	if t.kind == kindVector {
+		return t.size
+	}
+	return 1
+}
+
+// value returns t as the type of a value read from memory: without the
+// atomic, column-major, host bool, or padding marks of its storage.
+func (t *hlType) value() *hlType {
+	if !t.atomic && !t.colMajor && !t.hostBool && !t.padded {
+		return t
+	}
+	c := *t
+	c.atomic, c.colMajor, c.hostBool, c.padded = false, false, false, false
+	return &c
+}
+
+func (t *hlType) equal(o *hlType) bool {
+	if t.kind != o.kind {
+		return false
+	}
+	switch t.kind {
+	case kindScalar, kindVector, kindMatrix:
+		return t.scalar == o.scalar && t.size == o.size && t.cols == o.cols
+	case kindArray:
+		return t.length == o.length && t.elem.equal(o.elem)
+	case kindStruct:
+		return t.st == o.st
+	case kindTexture:
+		return t.texture == o.texture && t.elem.equal(o.elem)
+	case kindSampler:
+		return t.comparison == o.comparison
+	case kindBuffer:
+		return t.writable == o.writable && t.elem.equal(o.elem)
+	}
+	return true
+}
+
+var scalarSpelling = [...]string{"bool", "int", "uint", "float"}
+
+// String spells t in HLSL, for diagnostics.
+func (t *hlType) String() string {
+	switch t.kind {
+	case kindVoid:
+		return "void"
+	case kindScalar:
+		return scalarSpelling[t.scalar]
+	case kindVector:
+		return fmt.Sprintf("%s%d", scalarSpelling[t.scalar], t.size)
+	case kindMatrix:
+		return fmt.Sprintf("float%dx%d", t.size, t.cols)
+	case kindArray:
+		if t.length == 0 {
+			return t.elem.String() + "[]"
+		}
+		return fmt.Sprintf("%s[%d]", t.elem, t.length)
+	case kindStruct:
+		return t.st.name
+	case kindTexture:
+		for name, dim := range textureTypes {
+			if dim == t.texture {
+				return name + "<" + t.elem.String() + ">"
+			}
+		}
+	case kindSampler:
+		if t.comparison {
+			return "SamplerComparisonState"
+		}
+		return "SamplerState"
+	case kindBuffer:
+		if t.writable {
+			return "RWStructuredBuffer<" + t.elem.String() + ">"
+		}
+		return "StructuredBuffer<" + t.elem.String() + ">"
+	}
+	return "?"
+}
+
+// wgsl spells t in WGSL.
+func (t *hlType) wgsl() string {
+	switch t.kind {
+	case kindScalar:
+		s := wgslScalar(t.scalar)
+		switch {
+		case t.atomic:
+			return "atomic<" + s + ">"
+		case t.hostBool:
+			return "u32"
+		}
+		return s
+	case kindVector:
+		if t.hostBool {
+			return fmt.Sprintf("vec%d<u32>", t.size)
+		}
+		return fmt.Sprintf("vec%d<%s>", t.size, wgslScalar(t.scalar))
+	case kindMatrix:
+		if t.colMajor {
+			return fmt.Sprintf("mat%dx%d<f32>", t.cols, t.size)
+		}
+		return fmt.Sprintf("mat%dx%d<f32>", t.size, t.cols)
+	case kindArray:
+		elem := t.elem.wgsl()
+		if t.padded {
+			elem = fmt.Sprintf("vec4<%s>", wgslScalar(t.elem.scalar))
+			if t.elem.hostBool {
+				elem = "vec4<u32>"
+			}
+		}
+		if t.length == 0 {
+			return "array<" + elem + ">"
+		}
+		return fmt.Sprintf("array<%s, %d>", elem, t.length)
+	case kindStruct:
+		return t.st.wgsl
+	case kindTexture:
+		sampled := wgslScalar(t.elem.scalar)
+		if t.depth {
+			return "texture_depth_" + t.texture
+		}
+		return "texture_" + t.texture + "<" + sampled + ">"
+	case kindSampler:
+		if t.comparison {
+			return "sampler_comparison"
+		}
+		return "sampler"
+	case kindBuffer:
+		return "array<" + t.elem.wgsl() + ">"
+	}
+	return "void"
+}
+
+func wgslScalar(s scalarKind) string {
+	return [...]string{"bool", "i32", "u32", "f32"}[s]
+}
+
+// hasRuntimeArray reports whether t is, or ends in, a runtime-sized array.
+func (t *hlType) hasRuntimeArray() bool {
+	switch t.kind {
+	case kindArray:
+		return t.length == 0
+	case kindStruct:
+		n := len(t.st.members)
+		return n > 0 && t.st.members[n-1].ty.hasRuntimeArray()
+	}
+	return false
+}
+
+// hasAtomic reports whether t is, or contains, an atomic.
+func (t *hlType) hasAtomic() bool {
+	switch t.kind {
+	case kindScalar:
+		return t.atomic
+	case kindArray:
+		return t.elem.hasAtomic()
+	case kindStruct:
+		for _, m := range t.st.members {
+			if m.ty.hasAtomic() {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// hasResource reports whether t is, or contains, a resource.
+func (t *hlType) hasResource() bool {
+	switch t.kind {
+	case kindTexture, kindSampler, kindBuffer:
+		return true
+	case kindArray:
+		return t.elem.hasResource()
+	case kindStruct:
+		for _, m := range t.st.members {
+			if m.ty.hasResource() {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// rank orders scalar kinds for the usual arithmetic conversions: bool
+// converts to int, int to uint, and all of them to float.
+func rank(s scalarKind) int {
+	switch s {
+	case scalarInt:
+		return 1
+	case scalarUint:
+		return 2
+	case scalarFloat:
+		return 3
+	}
+	return 0
+}
+
+// swizzleName spells component indexes in xyzw.
+func swizzleName(comps []int) string {
+	var b strings.Builder
+	for _, c := range comps {
+		b.WriteByte("xyzw"[c])
+	}
+	return b.String()
+}
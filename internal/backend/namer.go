@@ -0,0 +1,159 @@
+// Copyright 2025 The GoGPU Authors
+// SPDX-License-Identifier: MIT
+
+package backend
+
+import (
+	"fmt"
+	"strings"
+)
+
+// UnnamedIdentifier is the base used when a label sanitizes to nothing.
+const UnnamedIdentifier = "unnamed"
+
+// KeywordSet is a set of reserved identifiers for one target language.
+type KeywordSet map[string]struct{}
+
+// Contains reports whether name is in the set.
+func (s KeywordSet) Contains(name string) bool {
+	_, ok := s[name]
+	return ok
+}
+
+// Namer hands out unique, keyword-safe identifiers for the text backends.
+// It matches Rust naga's proc::Namer:
+//
+//   - labels are sanitized with SanitizeName;
+//   - the first use of a base is returned as is, with a trailing "_" when
+//     the base ends in a digit or is a keyword of the target language;
+//   - later uses of the same base get a "_N" suffix from a per-base counter.
+//
+// Renaming is deterministic: the same sequence of calls always yields the
+// same names. It is also collision-free: a suffixed result always ends in
+// "_" or "_N", and a label spelled like an earlier result sanitizes to a
+// base that is already taken (trailing underscores are trimmed), or ends
+// in a digit and so gets a trailing "_" of its own.
+type Namer struct {
+	// unique maps each sanitized base to its conflict count
+	// (0 = first use, 1 = one collision, ...).
+	unique map[string]uint32
+
+	isKeyword func(string) bool
+}
+
+// NewNamer returns a Namer that escapes the identifiers for which
+// isKeyword reports true. A nil isKeyword escapes nothing.
+func NewNamer(isKeyword func(string) bool) *Namer {
+	if isKeyword == nil {
+		isKeyword = func(string) bool { return false }
+	}
+	return &Namer{
+		unique:    make(map[string]uint32),
+		isKeyword: isKeyword,
+	}
+}
+
+// Call returns a fresh identifier derived from label.
+func (n *Namer) Call(label string) string {
+	base := SanitizeName(label)
+
+	if count, exists := n.unique[base]; exists {
+		count++
+		n.unique[base] = count
+		return fmt.Sprintf("%s_%d", base, count)
+	}
+
+	n.unique[base] = 0
+	if EndsWithDigit(base) || n.isKeyword(base) {
+		return base + "_"
+	}
+	return base
+}
+
+// Reserve marks the base of label as taken without returning a name, so
+// later calls with the same label get a suffixed identifier. Used for
+// fixed helper names emitted by the backend itself.
+func (n *Namer) Reserve(label string) {
+	base := SanitizeName(label)
+	if _, exists := n.unique[base]; !exists {
+		n.unique[base] = 0
+	}
+}
+
+// IsUsed reports whether the base of label has been handed out or reserved.
+func (n *Namer) IsUsed(label string) bool {
+	_, exists := n.unique[SanitizeName(label)]
+	return exists
+}
+
+// Namespace runs body in a fresh naming scope, restoring the outer scope
+// afterwards. Used for struct members, which only need to be unique among
+// themselves.
+func (n *Namer) Namespace(body func()) {
+	outer := n.unique
+	n.unique = make(map[string]uint32)
+	body()
+	n.unique = outer
+}
+
+// Reset forgets every name handed out so far.
+func (n *Namer) Reset() {
+	n.unique = make(map[string]uint32)
+}
+
+// Len returns the number of distinct bases in the current scope.
+func (n *Namer) Len() int {
+	return len(n.unique)
+}
+
+// SanitizeName turns an arbitrary label into an identifier base:
+//
+//   - leading digits are dropped;
+//   - C++-ish type separators (':', '<', '>', ',', ' ') become '_';
+//   - other characters outside [A-Za-z0-9_] become "u{04x}_";
+//   - runs of '_' are collapsed and trailing '_' trimmed.
+//
+// An empty result is replaced by UnnamedIdentifier.
+func SanitizeName(label string) string {
+	s := strings.TrimLeft(label, "0123456789")
+
+	if s != "" && !strings.Contains(s, "__") && isIdentifier(s) {
+		if s = strings.TrimRight(s, "_"); s != "" {
+			return s
+		}
+		return UnnamedIdentifier
+	}
+
+	buf := make([]byte, 0, len(s))
+	endsWithUnderscore := func() bool { return len(buf) > 0 && buf[len(buf)-1] == '_' }
+	for _, c := range s {
+		switch {
+		case IsASCIIAlphanumeric(c):
+			buf = append(buf, byte(c))
+		case c == '_', c == ':', c == '<', c == '>', c == ',', c == ' ':
+			if !endsWithUnderscore() {
+				buf = append(buf, '_')
+			}
+		default:
+			if len(buf) > 0 && !endsWithUnderscore() {
+				buf = append(buf, '_')
+			}
+			buf = fmt.Appendf(buf, "u%04x_", c)
+		}
+	}
+
+	result := strings.TrimRight(string(buf), "_")
+	if result == "" {
+		return UnnamedIdentifier
+	}
+	return result
+}
+
+func isIdentifier(s string) bool {
+	for _, c := range s {
+		if !IsASCIIAlphanumeric(c) && c != '_' {
+			return false
+		}
+	}
+	return true
+}
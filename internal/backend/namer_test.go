@@ -0,0 +1,105 @@
+package backend
+
+import "testing"
+
+func TestSanitizeName(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"foo", "foo"},
+		{"", "unnamed"},
+		{"___", "unnamed"},
+		{"123abc", "abc"},
+		{"v3____", "v3"},
+		{"__ab__", "_ab"},
+		{"vec<f32, 3>", "vec_f32_3"},
+		{"type::inner", "type_inner"},
+		{"θx", "u03b8_x"},
+		{"aθ", "a_u03b8"},
+	}
+	for _, tt := range tests {
+		if got := SanitizeName(tt.in); got != tt.want {
+			t.Errorf("SanitizeName(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestNamerEscapesKeywords(t *testing.T) {
+	keywords := KeywordSet{"half": {}, "texture": {}}
+	n := NewNamer(keywords.Contains)
+
+	for _, tt := range []struct{ in, want string }{
+		{"half", "half_"},
+		{"texture", "texture_"},
+		{"line", "line"},
+		{"v2", "v2_"},
+		{"half", "half_1"},
+	} {
+		if got := n.Call(tt.in); got != tt.want {
+			t.Errorf("Call(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestNamerCollisionFree(t *testing.T) {
+	keywords := KeywordSet{"half": {}}
+	n := NewNamer(keywords.Contains)
+
+	// Labels spelled like earlier results must not reproduce them.
+	labels := []string{
+		"a", "a", "a_1", "a_1", "a_2", "a_",
+		"half", "half_", "half_1",
+		"x1", "x1_", "x1_1",
+		"", "unnamed", "unnamed_1",
+		"1", "_1", "a__1",
+	}
+	seen := make(map[string]string)
+	for _, label := range labels {
+		got := n.Call(label)
+		if prev, dup := seen[got]; dup {
+			t.Errorf("Call(%q) = %q, already returned for %q", label, got, prev)
+		}
+		seen[got] = label
+	}
+}
+
+func TestNamerDeterministic(t *testing.T) {
+	labels := []string{"main", "main", "color", "v0", "color", "main_1"}
+	run := func() []string {
+		n := NewNamer(KeywordSet{"main": {}}.Contains)
+		out := make([]string, len(labels))
+		for i, l := range labels {
+			out[i] = n.Call(l)
+		}
+		return out
+	}
+	first, second := run(), run()
+	for i := range first {
+		if first[i] != second[i] {
+			t.Fatalf("run differs at %d: %q vs %q", i, first[i], second[i])
+		}
+	}
+}
+
+func TestNamerReserveAndNamespace(t *testing.T) {
+	n := NewNamer(nil)
+	n.Reserve("naga_div")
+	if got := n.Call("naga_div"); got != "naga_div_1" {
+		t.Errorf("reserved name: got %q, want naga_div_1", got)
+	}
+
+	n.Call("field")
+	n.Namespace(func() {
+		if got := n.Call("field"); got != "field" {
+			t.Errorf("namespaced name: got %q, want field", got)
+		}
+	})
+	if got := n.Call("field"); got != "field_1" {
+		t.Errorf("outer scope after namespace: got %q, want field_1", got)
+	}
+
+	n.Reset()
+	if n.Len() != 0 || n.IsUsed("field") {
+		t.Error("Reset should forget all names")
+	}
+}
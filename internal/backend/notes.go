@@ -0,0 +1,44 @@
+// Copyright 2025 The GoGPU Authors
+// SPDX-License-Identifier: MIT
+
+package backend
+
+import (
+	"log/slog"
+)
+
+// Notes reports code generation progress and polyfill choices to an
+// optional slog.Logger. A Notes with a nil Logger logs nothing.
+type Notes struct {
+	Logger  *slog.Logger
+	Backend string
+
+	seen map[string]bool
+}
+
+// NewNotes returns Notes that tag every record with backend.
+func NewNotes(logger *slog.Logger, backend string) *Notes {
+	return &Notes{Logger: logger, Backend: backend}
+}
+
+// Phase logs a debug-level trace of a code generation phase.
+func (n *Notes) Phase(msg string, args ...any) {
+	if n == nil || n.Logger == nil {
+		return
+	}
+	n.Logger.Debug(msg, append([]any{"backend", n.Backend}, args...)...)
+}
+
+// Polyfill logs, once per compilation, a warning that the backend emulates
+// what (e.g. "textureSampleBaseClampToEdge") because the target has no
+// direct equivalent.
+func (n *Notes) Polyfill(what string) {
+	if n == nil || n.Logger == nil || n.seen[what] {
+		return
+	}
+	if n.seen == nil {
+		n.seen = make(map[string]bool)
+	}
+	n.seen[what] = true
+	n.Logger.Warn("emulating "+what, "backend", n.Backend, "polyfill", what)
+}
@@ -0,0 +1,44 @@
+package backend
+
+import (
+	"bytes"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func TestNotes(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	n := NewNotes(logger, "msl")
+
+	n.Phase("writing module", "entry_points", 2)
+	n.Polyfill("textureSampleBaseClampToEdge")
+	n.Polyfill("textureSampleBaseClampToEdge")
+	n.Polyfill("modulo")
+
+	out := buf.String()
+	for _, want := range []string{
+		`level=DEBUG msg="writing module" backend=msl entry_points=2`,
+		`level=WARN msg="emulating textureSampleBaseClampToEdge" backend=msl polyfill=textureSampleBaseClampToEdge`,
+		`level=WARN msg="emulating modulo" backend=msl polyfill=modulo`,
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("log missing %q:\n%s", want, out)
+		}
+	}
+	if got := strings.Count(out, "emulating textureSampleBaseClampToEdge"); got != 1 {
+		t.Errorf("clamp-to-edge polyfill logged %d times, want 1", got)
+	}
+}
+
+func TestNotesDisabled(t *testing.T) {
+	// Neither a nil *Notes nor a nil Logger may panic.
+	var nilNotes *Notes
+	nilNotes.Phase("x")
+	nilNotes.Polyfill("x")
+
+	n := NewNotes(nil, "hlsl")
+	n.Phase("x")
+	n.Polyfill("x")
+}
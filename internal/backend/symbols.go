@@ -0,0 +1,16 @@
+package backend
+
+// EntryPointBase returns the namer base for an entry point under the text
+// backends' EntryPointNames/SymbolPrefix options. An explicit rename wins
+// verbatim (it is the full name the host will look up, so the prefix is not
+// applied on top); otherwise the prefix is prepended to the WGSL name. The
+// result still goes through the backend namer, so invalid or reserved names
+// are sanitized and escaped like any other identifier.
+//
+// Consumers: msl/internal/codegen, hlsl/internal/codegen (registerNames).
+func EntryPointBase(renames map[string]string, prefix, name string) string {
+	if renamed, ok := renames[name]; ok && renamed != "" {
+		return renamed
+	}
+	return prefix + name
+}
@@ -0,0 +1,24 @@
+package backend
+
+import "testing"
+
+func TestEntryPointBase(t *testing.T) {
+	renames := map[string]string{"main": "vs_main_quad", "empty": ""}
+	tests := []struct {
+		prefix, name, want string
+	}{
+		{"", "main", "vs_main_quad"},
+		{"quad_", "main", "vs_main_quad"}, // explicit rename ignores prefix
+		{"quad_", "fs_main", "quad_fs_main"},
+		{"", "fs_main", "fs_main"},
+		{"p_", "empty", "p_empty"}, // empty rename falls back
+	}
+	for _, tt := range tests {
+		if got := EntryPointBase(renames, tt.prefix, tt.name); got != tt.want {
+			t.Errorf("EntryPointBase(%q, %q) = %q, want %q", tt.prefix, tt.name, got, tt.want)
+		}
+	}
+	if got := EntryPointBase(nil, "", "main"); got != "main" {
+		t.Errorf("EntryPointBase with nil renames = %q, want main", got)
+	}
+}
@@ -0,0 +1,241 @@
+// Copyright 2025 The GoGPU Authors
+// SPDX-License-Identifier: MIT
+
+// Package facade compiles WGSL to any backend from a target name and JSON
+// options. It backs the bindings for other runtimes (js/wasm, C) that cannot
+// use the Go option structs directly.
+package facade
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/gogpu/naga"
+	"github.com/gogpu/naga/glsl"
+	"github.com/gogpu/naga/hlsl"
+	"github.com/gogpu/naga/ir"
+	"github.com/gogpu/naga/msl"
+	"github.com/gogpu/naga/spirv"
+)
+
+// Targets lists the accepted target names.
+var Targets = []string{"spirv", "msl", "hlsl", "glsl"}
+
+// Options is the JSON form of the compile options. Every field is optional;
+// versions are written as "1.3" (SPIR-V), "2.1" (MSL), "5.1" (HLSL shader
+// model) and "330" or "300es" (GLSL).
+type Options struct {
+	EntryPoint   string `json:"entry_point"`
+	Debug        bool   `json:"debug"`
+	Validate     *bool  `json:"validate"`
+	SPIRVVersion string `json:"spirv_version"`
+	MSLVersion   string `json:"msl_version"`
+	ShaderModel  string `json:"shader_model"`
+	GLSLVersion  string `json:"glsl_version"`
+}
+
+// Output is the result of a successful compilation. Code holds the SPIR-V
+// binary for the spirv target and UTF-8 source text otherwise.
+type Output struct {
+	Code     []byte
+	Warnings []string
+}
+
+// ParseOptions decodes JSON options. An empty string yields the defaults;
+// unknown fields are rejected so typos do not go unnoticed.
+func ParseOptions(data string) (Options, error) {
+	var opts Options
+	if strings.TrimSpace(data) == "" {
+		return opts, nil
+	}
+	dec := json.NewDecoder(strings.NewReader(data))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&opts); err != nil {
+		return opts, fmt.Errorf("invalid options: %w", err)
+	}
+	return opts, nil
+}
+
+// Compile compiles WGSL source for target ("spirv", "msl", "hlsl" or
+// "glsl") with JSON options.
+func Compile(source, target, optionsJSON string) (*Output, error) {
+	opts, err := ParseOptions(optionsJSON)
+	if err != nil {
+		return nil, err
+	}
+	return CompileOptions(source, target, opts)
+}
+
+// CompileOptions is Compile with decoded options.
+func CompileOptions(source, target string, opts Options) (*Output, error) {
+	return CompileContext(context.Background(), source, target, opts)
+}
+
+// CompileContext is CompileOptions with cancellation: ctx is checked
+// between passes, declarations, and the functions each backend writes; see
+// naga.CompileContext.
+func CompileContext(ctx context.Context, source, target string, opts Options) (*Output, error) {
+	compileOpts := naga.DefaultOptions()
+	compileOpts.Debug = opts.Debug
+	if opts.Validate != nil {
+		compileOpts.Validate = *opts.Validate
+	}
+	if opts.SPIRVVersion != "" {
+		major, minor, err := parseVersion(opts.SPIRVVersion)
+		if err != nil {
+			return nil, fmt.Errorf("spirv_version: %w", err)
+		}
+		compileOpts.SPIRVVersion = spirv.Version{Major: major, Minor: minor}
+	}
+
+	pm := naga.NewPassManager()
+	switch target {
+	case "spirv":
+	case "msl", "hlsl", "glsl":
+		if err := pm.Remove(naga.PassSPIRV); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("unknown target %q (want one of %s)", target, strings.Join(Targets, ", "))
+	}
+	state, _, err := pm.RunContext(ctx, source, compileOpts)
+	if err != nil {
+		return nil, err
+	}
+
+	out := &Output{}
+	for _, w := range state.Warnings {
+		out.Warnings = append(out.Warnings, fmt.Sprintf("%d:%d: %s", w.Span.Start.Line, w.Span.Start.Column, w.Message))
+	}
+	switch target {
+	case "spirv":
+		out.Code = state.SPIRV
+	case "msl":
+		code, err := compileMSL(ctx, state.Module, opts)
+		if err != nil {
+			return nil, err
+		}
+		out.Code = []byte(code)
+	case "hlsl":
+		code, err := compileHLSL(ctx, state.Module, opts)
+		if err != nil {
+			return nil, err
+		}
+		out.Code = []byte(code)
+	case "glsl":
+		code, err := compileGLSL(ctx, state.Module, opts)
+		if err != nil {
+			return nil, err
+		}
+		out.Code = []byte(code)
+	}
+	return out, nil
+}
+
+func compileMSL(ctx context.Context, module *ir.Module, opts Options) (string, error) {
+	mslOpts := msl.DefaultOptions()
+	if opts.MSLVersion != "" {
+		major, minor, err := parseVersion(opts.MSLVersion)
+		if err != nil {
+			return "", fmt.Errorf("msl_version: %w", err)
+		}
+		mslOpts.LangVersion = msl.Version{Major: major, Minor: minor}
+	}
+	var pipeline msl.PipelineOptions
+	if opts.EntryPoint != "" {
+		ep, err := findEntryPoint(module, opts.EntryPoint)
+		if err != nil {
+			return "", err
+		}
+		pipeline.EntryPoint = &msl.EntryPointSelector{Stage: ep.Stage, Name: ep.Name}
+	}
+	code, _, err := msl.CompileWithPipelineContext(ctx, module, mslOpts, pipeline)
+	return code, err
+}
+
+func compileHLSL(ctx context.Context, module *ir.Module, opts Options) (string, error) {
+	hlslOpts := hlsl.DefaultOptions()
+	hlslOpts.EntryPoint = opts.EntryPoint
+	if opts.ShaderModel != "" {
+		sm, err := parseShaderModel(opts.ShaderModel)
+		if err != nil {
+			return "", err
+		}
+		hlslOpts.ShaderModel = sm
+	}
+	code, _, err := hlsl.CompileContext(ctx, module, hlslOpts)
+	return code, err
+}
+
+func compileGLSL(ctx context.Context, module *ir.Module, opts Options) (string, error) {
+	glslOpts := glsl.DefaultOptions()
+	glslOpts.EntryPoint = opts.EntryPoint
+	if opts.GLSLVersion != "" {
+		v, err := parseGLSLVersion(opts.GLSLVersion)
+		if err != nil {
+			return "", err
+		}
+		glslOpts.LangVersion = v
+	}
+	code, _, err := glsl.CompileContext(ctx, module, glslOpts)
+	return code, err
+}
+
+func findEntryPoint(module *ir.Module, name string) (*ir.EntryPoint, error) {
+	for i := range module.EntryPoints {
+		if module.EntryPoints[i].Name == name {
+			return &module.EntryPoints[i], nil
+		}
+	}
+	return nil, fmt.Errorf("entry point %q not found", name)
+}
+
+// parseVersion parses "major.minor".
+func parseVersion(s string) (major, minor uint8, err error) {
+	majorStr, minorStr, ok := strings.Cut(s, ".")
+	if !ok {
+		return 0, 0, fmt.Errorf("invalid version %q (want major.minor)", s)
+	}
+	ma, err1 := strconv.ParseUint(majorStr, 10, 8)
+	mi, err2 := strconv.ParseUint(minorStr, 10, 8)
+	if err1 != nil || err2 != nil {
+		return 0, 0, fmt.Errorf("invalid version %q (want major.minor)", s)
+	}
+	return uint8(ma), uint8(mi), nil
+}
+
+var shaderModels = map[string]hlsl.ShaderModel{
+	"5.0": hlsl.ShaderModel5_0,
+	"5.1": hlsl.ShaderModel5_1,
+	"6.0": hlsl.ShaderModel6_0,
+	"6.1": hlsl.ShaderModel6_1,
+	"6.2": hlsl.ShaderModel6_2,
+	"6.3": hlsl.ShaderModel6_3,
+	"6.4": hlsl.ShaderModel6_4,
+	"6.5": hlsl.ShaderModel6_5,
+	"6.6": hlsl.ShaderModel6_6,
+	"6.7": hlsl.ShaderModel6_7,
+	"6.8": hlsl.ShaderModel6_8,
+}
+
+func parseShaderModel(s string) (hlsl.ShaderModel, error) {
+	sm, ok := shaderModels[s]
+	if !ok {
+		return 0, fmt.Errorf("shader_model: unsupported shader model %q", s)
+	}
+	return sm, nil
+}
+
+// parseGLSLVersion parses a #version number, with an "es" suffix for
+// OpenGL ES (e.g. "330", "450", "300es").
+func parseGLSLVersion(s string) (glsl.Version, error) {
+	num, es := strings.CutSuffix(s, "es")
+	n, err := strconv.ParseUint(strings.TrimSpace(num), 10, 16)
+	if err != nil || n < 100 {
+		return glsl.Version{}, fmt.Errorf("glsl_version: invalid version %q (want e.g. 330 or 300es)", s)
+	}
+	return glsl.Version{Major: uint8(n / 100), Minor: uint8(n % 100), ES: es}, nil
+}
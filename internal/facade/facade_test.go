@@ -0,0 +1,99 @@
+package facade
+
+import (
+	"encoding/binary"
+	"strings"
+	"testing"
+
+	"github.com/gogpu/naga/glsl"
+)
+
+const source = `
+@vertex
+fn vs_main(@builtin(vertex_index) idx: u32) -> @builtin(position) vec4<f32> {
+    return vec4<f32>(f32(idx), 0.0, 0.0, 1.0);
+}
+
+@fragment
+fn fs_main() -> @location(0) vec4<f32> {
+    var unused = 1.0;
+    return vec4<f32>(1.0);
+}
+`
+
+func TestCompileTargets(t *testing.T) {
+	tests := []struct {
+		target, options, want string
+	}{
+		{"msl", `{"msl_version": "2.1"}`, "// language: metal2.1"},
+		{"msl", `{"entry_point": "fs_main"}`, "fragment fs_mainOutput fs_main("},
+		{"hlsl", `{"shader_model": "6.0"}`, "float4 fs_main()"},
+		{"glsl", `{"glsl_version": "300es", "entry_point": "fs_main"}`, "#version 300 es"},
+		{"glsl", `{"entry_point": "vs_main"}`, "#version 330 core"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.target+" "+tt.options, func(t *testing.T) {
+			out, err := Compile(source, tt.target, tt.options)
+			if err != nil {
+				t.Fatalf("Compile: %v", err)
+			}
+			if !strings.Contains(string(out.Code), tt.want) {
+				t.Errorf("output does not contain %q:\n%s", tt.want, out.Code)
+			}
+			if len(out.Warnings) != 1 || !strings.Contains(out.Warnings[0], "unused") {
+				t.Errorf("warnings = %q, want one unused-variable warning", out.Warnings)
+			}
+		})
+	}
+}
+
+func TestCompileSPIRV(t *testing.T) {
+	out, err := Compile(source, "spirv", `{"spirv_version": "1.0"}`)
+	if err != nil {
+		t.Fatalf("Compile: %v", err)
+	}
+	if len(out.Code) < 20 || binary.LittleEndian.Uint32(out.Code) != 0x07230203 {
+		t.Fatalf("output is not SPIR-V")
+	}
+	if v := binary.LittleEndian.Uint32(out.Code[4:]); v != 0x00010000 {
+		t.Errorf("SPIR-V version word = %#x, want 1.0", v)
+	}
+}
+
+func TestCompileErrors(t *testing.T) {
+	tests := []struct {
+		name, source, target, options, want string
+	}{
+		{"unknown target", source, "dxbc", "", `unknown target "dxbc"`},
+		{"unknown option", source, "msl", `{"mls_version": "2.1"}`, "invalid options"},
+		{"bad json", source, "msl", `{`, "invalid options"},
+		{"bad version", source, "spirv", `{"spirv_version": "one"}`, "spirv_version"},
+		{"bad shader model", source, "hlsl", `{"shader_model": "4.0"}`, "unsupported shader model"},
+		{"bad glsl version", source, "glsl", `{"glsl_version": "es"}`, "glsl_version"},
+		{"missing entry point", source, "msl", `{"entry_point": "nope"}`, `entry point "nope" not found`},
+		{"parse error", "fn main( {", "msl", "", "parse error"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Compile(tt.source, tt.target, tt.options)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("error = %v, want it to mention %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseGLSLVersion(t *testing.T) {
+	tests := map[string]glsl.Version{
+		"330":   glsl.Version330,
+		"450":   glsl.Version450,
+		"300es": glsl.VersionES300,
+		"310es": glsl.VersionES310,
+	}
+	for in, want := range tests {
+		got, err := parseGLSLVersion(in)
+		if err != nil || got != want {
+			t.Errorf("parseGLSLVersion(%q) = %+v, %v; want %+v", in, got, err, want)
+		}
+	}
+}
@@ -147,7 +147,10 @@ func (r *TypeRegistry) appendTypeKey(inner ir.TypeInner) {
 		r.keyBuf = append(r.keyBuf, "array:"...)
 		r.keyBuf = strconv.AppendInt(r.keyBuf, int64(t.Base), 10)
 		r.keyBuf = append(r.keyBuf, ':')
-		if t.Size.Constant != nil {
+		if t.Size.Override != nil {
+			r.keyBuf = append(r.keyBuf, "override"...)
+			r.keyBuf = strconv.AppendUint(r.keyBuf, uint64(*t.Size.Override), 10)
+		} else if t.Size.Constant != nil {
 			r.keyBuf = strconv.AppendUint(r.keyBuf, uint64(*t.Size.Constant), 10)
 		} else {
 			r.keyBuf = append(r.keyBuf, "runtime"...)
@@ -0,0 +1,31 @@
+// Copyright 2025 The GoGPU Authors
+// SPDX-License-Identifier: MIT
+
+// Package testutil holds helpers shared by the tests of several packages.
+package testutil
+
+import (
+	"testing"
+
+	"github.com/gogpu/naga/ir"
+	"github.com/gogpu/naga/wgsl"
+)
+
+// LowerWGSL parses and lowers WGSL source to IR, failing the test on any
+// error.
+func LowerWGSL(t testing.TB, source string) *ir.Module {
+	t.Helper()
+	tokens, err := wgsl.NewLexer(source).Tokenize()
+	if err != nil {
+		t.Fatalf("tokenize: %v", err)
+	}
+	ast, err := wgsl.NewParser(tokens).Parse()
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	module, err := wgsl.LowerWithSource(ast, source)
+	if err != nil {
+		t.Fatalf("lower: %v", err)
+	}
+	return module
+}
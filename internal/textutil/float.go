@@ -0,0 +1,93 @@
+// Copyright 2025 The GoGPU Authors
+// SPDX-License-Identifier: MIT
+
+package textutil
+
+import (
+	"math"
+	"strconv"
+	"strings"
+)
+
+// FormatFloat formats f as a float literal the way Rust's {:?} prints it,
+// which the GLSL and HLSL backends follow: positional notation with ".0"
+// added to whole numbers, switching to an exponent without '+' or leading
+// zeros for magnitudes below 1e-4 or from 1e16 up (1e-7, 3.4028235e38).
+//
+// bitSize is 32 or 64, the precision f is rounded to. precision is the
+// number of significant digits to write; zero or less writes the fewest
+// digits that read back as the same value. The result does not depend on
+// the platform. Infinities and NaN come back as strconv spells them;
+// backends write their own forms for those.
+func FormatFloat(f float64, bitSize, precision int) string {
+	if math.IsInf(f, 0) || math.IsNaN(f) {
+		return strconv.FormatFloat(f, 'g', -1, 64)
+	}
+	neg, digits, exp := floatDigits(f, bitSize, precision)
+	abs := math.Abs(f)
+	lo, hi := 1e-4, 1e16
+	if bitSize == 32 {
+		lo, hi = float64(float32(lo)), float64(float32(hi))
+	}
+	var s string
+	if abs != 0 && abs < lo || abs >= hi {
+		s = digits[:1]
+		if len(digits) > 1 {
+			s += "." + digits[1:]
+		}
+		s += "e" + strconv.Itoa(exp)
+	} else {
+		s = positional(digits, exp)
+	}
+	if neg {
+		return "-" + s
+	}
+	return s
+}
+
+// FormatFloatDecimal is FormatFloat without exponents, the way Rust's {}
+// prints floats, which the MSL backend follows: 1e-7 is 0.0000001 and 1e20
+// is 100000000000000000000.0.
+func FormatFloatDecimal(f float64, bitSize, precision int) string {
+	if math.IsInf(f, 0) || math.IsNaN(f) {
+		return strconv.FormatFloat(f, 'g', -1, 64)
+	}
+	neg, digits, exp := floatDigits(f, bitSize, precision)
+	s := positional(digits, exp)
+	if neg {
+		return "-" + s
+	}
+	return s
+}
+
+// floatDigits returns the sign, the significant decimal digits without
+// trailing zeros, and the decimal exponent of the first digit of f.
+func floatDigits(f float64, bitSize, precision int) (neg bool, digits string, exp int) {
+	prec := -1
+	if precision > 0 {
+		prec = precision - 1
+	}
+	s := strconv.FormatFloat(f, 'e', prec, bitSize)
+	if s[0] == '-' {
+		neg, s = true, s[1:]
+	}
+	mantissa, e, _ := strings.Cut(s, "e")
+	exp, _ = strconv.Atoi(e)
+	digits = strings.TrimRight(strings.Replace(mantissa, ".", "", 1), "0")
+	if digits == "" {
+		return neg, "0", 0
+	}
+	return neg, digits, exp
+}
+
+// positional writes digits, whose first digit has decimal exponent exp,
+// with a decimal point and at least one digit after it.
+func positional(digits string, exp int) string {
+	switch {
+	case exp < 0:
+		return "0." + strings.Repeat("0", -exp-1) + digits
+	case exp+1 >= len(digits):
+		return digits + strings.Repeat("0", exp+1-len(digits)) + ".0"
+	}
+	return digits[:exp+1] + "." + digits[exp+1:]
+}
@@ -0,0 +1,65 @@
+// Copyright 2025 The GoGPU Authors
+// SPDX-License-Identifier: MIT
+
+package textutil
+
+import (
+	"math"
+	"strconv"
+	"testing"
+)
+
+func TestFormatFloat(t *testing.T) {
+	tests := []struct {
+		f         float64
+		bitSize   int
+		precision int
+		want      string
+		decimal   string
+	}{
+		{0, 32, 0, "0.0", "0.0"},
+		{math.Copysign(0, -1), 32, 0, "-0.0", "-0.0"},
+		{1, 32, 0, "1.0", "1.0"},
+		{-2.5, 32, 0, "-2.5", "-2.5"},
+		{float64(float32(0.1)), 32, 0, "0.1", "0.1"},
+		{float64(float32(0.1)), 64, 0, "0.10000000149011612", "0.10000000149011612"},
+		{0.1, 64, 0, "0.1", "0.1"},
+		{1e6, 32, 0, "1000000.0", "1000000.0"},
+		{0.0001, 32, 0, "0.0001", "0.0001"},
+		{float64(float32(1e-7)), 32, 0, "1e-7", "0.0000001"},
+		{float64(float32(1.5e-5)), 32, 0, "1.5e-5", "0.000015"},
+		{1e16, 64, 0, "1e16", "10000000000000000.0"},
+		{float64(float32(9.223372e18)), 32, 0, "9.223372e18", "9223372000000000000.0"},
+		{math.MaxFloat32, 32, 0, "3.4028235e38", "340282350000000000000000000000000000000.0"},
+		{math.MaxFloat64, 64, 0, "1.7976931348623157e308", ""},
+		{math.Pi, 64, 4, "3.142", "3.142"},
+		{float64(float32(0.1)), 32, 12, "0.10000000149", ""}, // trailing zeros dropped
+		{123456, 32, 2, "120000.0", "120000.0"},
+		{0.99996, 64, 4, "1.0", "1.0"},
+	}
+	for _, tt := range tests {
+		got := FormatFloat(tt.f, tt.bitSize, tt.precision)
+		if got != tt.want {
+			t.Errorf("FormatFloat(%v, %d, %d) = %q, want %q", tt.f, tt.bitSize, tt.precision, got, tt.want)
+		}
+		if tt.decimal == "" {
+			continue
+		}
+		if got := FormatFloatDecimal(tt.f, tt.bitSize, tt.precision); got != tt.decimal {
+			t.Errorf("FormatFloatDecimal(%v, %d, %d) = %q, want %q", tt.f, tt.bitSize, tt.precision, got, tt.decimal)
+		}
+	}
+}
+
+// TestFormatFloatRoundTrip checks that the shortest form reads back as
+// the same value.
+func TestFormatFloatRoundTrip(t *testing.T) {
+	for _, f := range []float32{0.1, 1.0 / 3, 1e-7, 6.1035156e-5, 16777217, math.SmallestNonzeroFloat32, math.MaxFloat32} {
+		for _, s := range []string{FormatFloat(float64(f), 32, 0), FormatFloatDecimal(float64(f), 32, 0)} {
+			back, err := strconv.ParseFloat(s, 32)
+			if err != nil || float32(back) != f {
+				t.Errorf("%v formats as %q, which reads back as %v (%v)", f, s, back, err)
+			}
+		}
+	}
+}
@@ -9,7 +9,10 @@ package textutil
 
 import (
 	"fmt"
+	"strconv"
 	"strings"
+
+	"github.com/gogpu/naga/ir"
 )
 
 // IndentWriter writes indented text to a strings.Builder.
@@ -20,6 +23,12 @@ type IndentWriter struct {
 
 	// Indent is the current indentation level (each level = 4 spaces).
 	Indent int
+
+	// SourceMap enables MarkSpan.
+	SourceMap bool
+
+	// marked holds the spans MarkSpan has written marks for.
+	marked []ir.Span
 }
 
 // WriteLine writes indented text followed by a newline.
@@ -54,3 +63,61 @@ func (w *IndentWriter) PopIndent() {
 		w.Indent--
 	}
 }
+
+// sourceMark delimits the marks MarkSpan writes. NUL never appears in
+// generated code.
+const sourceMark = '\x00'
+
+// MarkSpan records that the text written next was generated from span,
+// when SourceMap is set. The record is a mark embedded in Out rather than
+// an offset into it, so it survives backends writing sections to separate
+// buffers and splicing them together; StripMarks removes the marks from
+// the final text.
+func (w *IndentWriter) MarkSpan(span ir.Span) {
+	if !w.SourceMap || span.IsZero() {
+		return
+	}
+	w.Out.WriteByte(sourceMark)
+	w.Out.WriteString(strconv.Itoa(len(w.marked)))
+	w.Out.WriteByte(sourceMark)
+	w.marked = append(w.marked, span)
+}
+
+// StripMarks removes the marks MarkSpan wrote from code and returns the
+// code with the span each marked line was generated from. Lines are
+// 1-based; a line holding several marks maps to the last one, the most
+// specific.
+func (w *IndentWriter) StripMarks(code string) (string, []ir.OutputSpan) {
+	if strings.IndexByte(code, sourceMark) < 0 {
+		return code, nil
+	}
+	var out strings.Builder
+	out.Grow(len(code))
+	var spans []ir.OutputSpan
+	line := 1
+	for i := 0; i < len(code); i++ {
+		c := code[i]
+		if c == sourceMark {
+			end := strings.IndexByte(code[i+1:], sourceMark)
+			if end < 0 {
+				break
+			}
+			n, err := strconv.Atoi(code[i+1 : i+1+end])
+			i += end + 1
+			if err != nil || n >= len(w.marked) {
+				continue
+			}
+			if k := len(spans) - 1; k >= 0 && spans[k].Output == line {
+				spans[k].Span = w.marked[n]
+			} else {
+				spans = append(spans, ir.OutputSpan{Output: line, Span: w.marked[n]})
+			}
+			continue
+		}
+		if c == '\n' {
+			line++
+		}
+		out.WriteByte(c)
+	}
+	return out.String(), spans
+}
@@ -3,7 +3,12 @@
 
 package textutil
 
-import "testing"
+import (
+	"reflect"
+	"testing"
+
+	"github.com/gogpu/naga/ir"
+)
 
 func TestIndentWriter_WriteLine(t *testing.T) {
 	tests := []struct {
@@ -136,3 +141,28 @@ func TestIndentWriter_MultipleWrites(t *testing.T) {
 		t.Errorf("got:\n%s\nwant:\n%s", got, want)
 	}
 }
+
+func TestIndentWriter_StripMarks(t *testing.T) {
+	var w IndentWriter
+	w.MarkSpan(ir.Span{Start: 1, End: 2}) // SourceMap unset: no mark
+	w.WriteLine("header")
+	w.SourceMap = true
+	w.MarkSpan(ir.Span{Start: 3, End: 4})
+	w.WriteLine("a = 1;")
+	w.MarkSpan(ir.Span{}) // unknown span: no mark
+	w.MarkSpan(ir.Span{Start: 5, End: 6})
+	w.MarkSpan(ir.Span{Start: 7, End: 8})
+	w.WriteLine("b = 2;")
+
+	code, spans := w.StripMarks(w.Out.String())
+	if want := "header\na = 1;\nb = 2;\n"; code != want {
+		t.Errorf("code = %q, want %q", code, want)
+	}
+	want := []ir.OutputSpan{
+		{Output: 2, Span: ir.Span{Start: 3, End: 4}},
+		{Output: 3, Span: ir.Span{Start: 7, End: 8}},
+	}
+	if !reflect.DeepEqual(spans, want) {
+		t.Errorf("spans = %v, want %v", spans, want)
+	}
+}
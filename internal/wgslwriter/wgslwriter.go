@@ -0,0 +1,52 @@
+// Copyright 2025 The GoGPU Authors
+// SPDX-License-Identifier: MIT
+
+// Package wgslwriter builds the WGSL source of the frontends that translate
+// other shading languages into WGSL.
+package wgslwriter
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Writer accumulates WGSL lines, remembering for each the source line it
+// was translated from so errors found later in the WGSL can be reported
+// against the original source.
+type Writer struct {
+	// Indent is the nesting depth of the next line, four spaces per level.
+	Indent int
+
+	buf   strings.Builder
+	lines []int
+}
+
+// Line writes one indented line translated from source line src.
+func (w *Writer) Line(src int, format string, args ...any) {
+	w.buf.WriteString(strings.Repeat("    ", w.Indent))
+	fmt.Fprintf(&w.buf, format, args...)
+	w.buf.WriteByte('\n')
+	w.lines = append(w.lines, src)
+}
+
+// Blank writes an empty line attributed to src.
+func (w *Writer) Blank(src int) {
+	w.buf.WriteByte('\n')
+	w.lines = append(w.lines, src)
+}
+
+// Append copies the lines of o after those of w.
+func (w *Writer) Append(o *Writer) {
+	w.buf.WriteString(o.buf.String())
+	w.lines = append(w.lines, o.lines...)
+}
+
+// String returns the WGSL written so far.
+func (w *Writer) String() string {
+	return w.buf.String()
+}
+
+// Lines returns the source line of each WGSL line written so far.
+func (w *Writer) Lines() []int {
+	return w.lines
+}
@@ -193,6 +193,63 @@ func BenchmarkValidateModule(b *testing.B) {
 	}
 }
 
+// BenchmarkResolveExpressionType resolves every expression of a chain of
+// 64 vector additions in order, as a frontend does while adding them:
+// "walk" resolves each from scratch, "known" passes the types resolved so
+// far to ResolveExpressionTypeFrom, and "table" looks each up with
+// ExpressionType in the table a lowered function carries, as a backend
+// does.
+func BenchmarkResolveExpressionType(b *testing.B) {
+	vec4 := TypeHandle(0)
+	module := &Module{Types: []Type{{Inner: VectorType{Size: Vec4, Scalar: ScalarType{Kind: ScalarFloat, Width: 4}}}}}
+	fn := &Function{
+		Arguments:   []FunctionArgument{{Name: "v", Type: vec4}},
+		Expressions: []Expression{{Kind: ExprFunctionArgument{Index: 0}}},
+	}
+	for j := 1; j <= 64; j++ {
+		fn.Expressions = append(fn.Expressions, Expression{Kind: ExprBinary{
+			Op: BinaryAdd, Left: ExpressionHandle(j - 1), Right: 0,
+		}})
+	}
+
+	b.Run("walk", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			for h := range fn.Expressions {
+				if _, err := ResolveExpressionType(module, fn, ExpressionHandle(h)); err != nil {
+					b.Fatal(err)
+				}
+			}
+		}
+	})
+	b.Run("known", func(b *testing.B) {
+		b.ReportAllocs()
+		known := make([]TypeResolution, 0, len(fn.Expressions))
+		for i := 0; i < b.N; i++ {
+			known = known[:0]
+			for h := range fn.Expressions {
+				res, err := ResolveExpressionTypeFrom(module, fn, ExpressionHandle(h), known)
+				if err != nil {
+					b.Fatal(err)
+				}
+				known = append(known, res)
+			}
+		}
+	})
+	b.Run("table", func(b *testing.B) {
+		lowered := *fn
+		lowered.ExpressionTypes = ExpressionTypeTable(module, fn)
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			for h := range lowered.Expressions {
+				if _, err := ExpressionType(module, &lowered, ExpressionHandle(h)); err != nil {
+					b.Fatal(err)
+				}
+			}
+		}
+	})
+}
+
 // newExprHandle creates a pointer to an ExpressionHandle (helper for benchmarks).
 func newExprHandle(h ExpressionHandle) *ExpressionHandle {
 	return &h
@@ -0,0 +1,122 @@
+// Copyright 2025 The GoGPU Authors
+// SPDX-License-Identifier: MIT
+
+package ir
+
+import "fmt"
+
+// String returns the WGSL spelling of the builtin.
+func (b BuiltinValue) String() string {
+	switch b {
+	case BuiltinPosition:
+		return "position"
+	case BuiltinVertexIndex:
+		return "vertex_index"
+	case BuiltinInstanceIndex:
+		return "instance_index"
+	case BuiltinFrontFacing:
+		return "front_facing"
+	case BuiltinFragDepth:
+		return "frag_depth"
+	case BuiltinSampleIndex:
+		return "sample_index"
+	case BuiltinSampleMask:
+		return "sample_mask"
+	case BuiltinLocalInvocationID:
+		return "local_invocation_id"
+	case BuiltinLocalInvocationIndex:
+		return "local_invocation_index"
+	case BuiltinGlobalInvocationID:
+		return "global_invocation_id"
+	case BuiltinWorkGroupID:
+		return "workgroup_id"
+	case BuiltinNumWorkGroups:
+		return "num_workgroups"
+	case BuiltinNumSubgroups:
+		return "num_subgroups"
+	case BuiltinSubgroupID:
+		return "subgroup_id"
+	case BuiltinSubgroupSize:
+		return "subgroup_size"
+	case BuiltinSubgroupInvocationID:
+		return "subgroup_invocation_id"
+	case BuiltinBarycentric:
+		return "barycentric"
+	case BuiltinViewIndex:
+		return "view_index"
+	case BuiltinPrimitiveIndex:
+		return "primitive_index"
+	case BuiltinPointSize:
+		return "point_size"
+	case BuiltinMeshTaskSize:
+		return "mesh_task_size"
+	case BuiltinCullPrimitive:
+		return "cull_primitive"
+	case BuiltinPointIndex:
+		return "point_index"
+	case BuiltinLineIndices:
+		return "line_indices"
+	case BuiltinTriangleIndices:
+		return "triangle_indices"
+	case BuiltinVertexCount:
+		return "vertex_count"
+	case BuiltinVertices:
+		return "vertices"
+	case BuiltinPrimitiveCount:
+		return "primitive_count"
+	case BuiltinPrimitives:
+		return "primitives"
+	case BuiltinClipDistance:
+		return "clip_distances"
+	default:
+		return fmt.Sprintf("BuiltinValue(%d)", uint8(b))
+	}
+}
+
+// computeInputs are the builtins a compute, task, or mesh shader reads.
+var computeInputs = []BuiltinValue{
+	BuiltinLocalInvocationID, BuiltinLocalInvocationIndex, BuiltinGlobalInvocationID,
+	BuiltinWorkGroupID, BuiltinNumWorkGroups, BuiltinNumSubgroups, BuiltinSubgroupID,
+	BuiltinSubgroupSize, BuiltinSubgroupInvocationID,
+}
+
+// stageBuiltins lists, per stage, the builtins an entry point may take as
+// inputs and return as outputs. Task and mesh outputs are written through
+// the mesh output variable and task payload, not returned, and are not
+// listed here.
+var stageBuiltins = map[ShaderStage]struct{ inputs, outputs []BuiltinValue }{
+	StageVertex: {
+		inputs:  []BuiltinValue{BuiltinVertexIndex, BuiltinInstanceIndex, BuiltinViewIndex},
+		outputs: []BuiltinValue{BuiltinPosition, BuiltinPointSize, BuiltinClipDistance},
+	},
+	StageFragment: {
+		inputs: []BuiltinValue{
+			BuiltinPosition, BuiltinFrontFacing, BuiltinSampleIndex, BuiltinSampleMask,
+			BuiltinPrimitiveIndex, BuiltinBarycentric, BuiltinViewIndex,
+			BuiltinSubgroupSize, BuiltinSubgroupInvocationID,
+		},
+		outputs: []BuiltinValue{BuiltinFragDepth, BuiltinSampleMask},
+	},
+	StageCompute: {inputs: computeInputs},
+	StageTask:    {inputs: computeInputs, outputs: []BuiltinValue{BuiltinMeshTaskSize}},
+	StageMesh:    {inputs: computeInputs},
+}
+
+// CheckBuiltinUsage reports an error if an entry point of the given stage
+// cannot read builtin as an input (output false) or return it as an
+// output (output true), such as frag_depth outside fragment outputs or
+// vertex_index outside vertex inputs. Backends would otherwise emit
+// invalid shaders for it.
+func CheckBuiltinUsage(builtin BuiltinValue, stage ShaderStage, output bool) error {
+	allowed := stageBuiltins[stage]
+	list, direction := allowed.inputs, "an input"
+	if output {
+		list, direction = allowed.outputs, "an output"
+	}
+	for _, b := range list {
+		if b == builtin {
+			return nil
+		}
+	}
+	return fmt.Errorf("@builtin(%s) cannot be %s of a @%s entry point", builtin, direction, stageName(stage))
+}
@@ -0,0 +1,133 @@
+// Copyright 2025 The GoGPU Authors
+// SPDX-License-Identifier: MIT
+
+package ir
+
+// CallGraph records which functions each function of a module calls.
+// Entry points cannot be called, so they are roots rather than nodes.
+type CallGraph struct {
+	// Callees lists, for each function handle, the distinct functions
+	// it calls, in order of first call. Calls to handles outside the
+	// module are left out.
+	Callees [][]FunctionHandle
+}
+
+// BuildCallGraph returns the call graph of module's functions.
+func BuildCallGraph(module *Module) *CallGraph {
+	g := &CallGraph{Callees: make([][]FunctionHandle, len(module.Functions))}
+	for i := range module.Functions {
+		g.Callees[i] = blockCallees(module, module.Functions[i].Body)
+	}
+	return g
+}
+
+// Cycles returns the call cycles of the graph, each starting and ending
+// with the same function, such as [a b a] for a calling b calling a. WGSL
+// forbids recursion, so a valid module has none. Each cycle is reported
+// once, from the function of lowest handle found to enter it.
+func (g *CallGraph) Cycles() [][]FunctionHandle {
+	const (
+		unvisited = iota
+		onPath
+		done
+	)
+	state := make([]uint8, len(g.Callees))
+	var path []FunctionHandle
+	var cycles [][]FunctionHandle
+
+	var visit func(f FunctionHandle)
+	visit = func(f FunctionHandle) {
+		state[f] = onPath
+		path = append(path, f)
+		for _, callee := range g.Callees[f] {
+			switch state[callee] {
+			case unvisited:
+				visit(callee)
+			case onPath:
+				start := len(path) - 1
+				for path[start] != callee {
+					start--
+				}
+				cycle := append([]FunctionHandle(nil), path[start:]...)
+				cycles = append(cycles, append(cycle, callee))
+			}
+		}
+		path = path[:len(path)-1]
+		state[f] = done
+	}
+	for i := range g.Callees {
+		if state[i] == unvisited {
+			visit(FunctionHandle(i))
+		}
+	}
+	return cycles
+}
+
+// EntryPointStackEstimate returns an approximate size, in bytes, of the
+// stack the entry point at index ep needs: its local variables, plus the
+// deepest chain of calls it makes, each call counting the callee's
+// arguments, result, and local variables. Temporaries and spilled
+// registers are not counted and pointers count as nothing, so treat the
+// estimate as a floor on what a GPU that does not inline reserves. Cycles,
+// which are invalid, are cut where they close.
+func EntryPointStackEstimate(module *Module, ep int) uint32 {
+	graph := BuildCallGraph(module)
+	memo := make([]uint32, len(module.Functions))
+	state := make([]uint8, len(module.Functions)) // 0 unvisited, 1 in progress, 2 done
+
+	var deepest func(callees []FunctionHandle) uint32
+	var frame func(f FunctionHandle) uint32
+	frame = func(f FunctionHandle) uint32 {
+		switch state[f] {
+		case 1:
+			return 0
+		case 2:
+			return memo[f]
+		}
+		state[f] = 1
+		fn := &module.Functions[f]
+		size := localsSize(module, fn)
+		for _, arg := range fn.Arguments {
+			size += TypeSize(module, arg.Type)
+		}
+		if fn.Result != nil {
+			size += TypeSize(module, fn.Result.Type)
+		}
+		size += deepest(graph.Callees[f])
+		memo[f], state[f] = size, 2
+		return size
+	}
+	deepest = func(callees []FunctionHandle) uint32 {
+		var size uint32
+		for _, c := range callees {
+			size = max(size, frame(c))
+		}
+		return size
+	}
+
+	entry := &module.EntryPoints[ep].Function
+	return localsSize(module, entry) + deepest(blockCallees(module, entry.Body))
+}
+
+// blockCallees lists the distinct valid functions called in block, in
+// order of first call.
+func blockCallees(module *Module, block Block) []FunctionHandle {
+	var callees []FunctionHandle
+	seen := make(map[FunctionHandle]bool)
+	WalkStatements(block, func(stmt *Statement) bool {
+		if call, ok := stmt.Kind.(StmtCall); ok && int(call.Function) < len(module.Functions) && !seen[call.Function] {
+			seen[call.Function] = true
+			callees = append(callees, call.Function)
+		}
+		return true
+	}, nil)
+	return callees
+}
+
+func localsSize(module *Module, fn *Function) uint32 {
+	var size uint32
+	for _, lv := range fn.LocalVars {
+		size += TypeSize(module, lv.Type)
+	}
+	return size
+}
@@ -0,0 +1,68 @@
+// Copyright 2025 The GoGPU Authors
+// SPDX-License-Identifier: MIT
+
+package ir
+
+import (
+	"reflect"
+	"testing"
+)
+
+// caller returns a function calling each of callees in turn.
+func caller(name string, callees ...FunctionHandle) Function {
+	f := Function{Name: name}
+	for _, c := range callees {
+		f.Body = append(f.Body, Statement{Kind: StmtCall{Function: c}})
+	}
+	return f
+}
+
+func TestCallGraphCycles(t *testing.T) {
+	m := &Module{Functions: []Function{
+		caller("a", 1),
+		caller("b", 2),
+		caller("c", 1, 3),
+		caller("d", 3),
+		caller("leaf"),
+	}}
+	g := BuildCallGraph(m)
+	if want := []FunctionHandle{1, 3}; !reflect.DeepEqual(g.Callees[2], want) {
+		t.Errorf("callees of c = %v, want %v", g.Callees[2], want)
+	}
+	want := [][]FunctionHandle{{1, 2, 1}, {3, 3}}
+	if got := g.Cycles(); !reflect.DeepEqual(got, want) {
+		t.Errorf("cycles = %v, want %v", got, want)
+	}
+
+	expectErrors(t, m, "recursive call cycle: b -> c -> b", "recursive call cycle: d -> d")
+
+	acyclic := &Module{Functions: []Function{caller("a", 1), caller("b")}}
+	if got := BuildCallGraph(acyclic).Cycles(); got != nil {
+		t.Errorf("acyclic cycles = %v, want none", got)
+	}
+}
+
+func TestEntryPointStackEstimate(t *testing.T) {
+	f32 := ScalarType{Kind: ScalarFloat, Width: 4}
+	vec4 := VectorType{Size: Vec4, Scalar: f32}
+	// shallow: vec4 argument and result, no locals; 32 bytes.
+	shallow := caller("shallow")
+	shallow.Arguments = []FunctionArgument{{Name: "v", Type: 1}}
+	shallow.Result = &FunctionResult{Type: 1}
+	// deep: two f32 locals and calls leaf; 8 + 4.
+	deep := caller("deep", 2)
+	deep.LocalVars = []LocalVariable{{Name: "x", Type: 0}, {Name: "y", Type: 0}}
+	leaf := caller("leaf")
+	leaf.Result = &FunctionResult{Type: 0}
+	entry := caller("main", 0, 1)
+	entry.LocalVars = []LocalVariable{{Name: "color", Type: 1}}
+
+	m := &Module{
+		Types:       []Type{{Inner: f32}, {Inner: vec4}},
+		Functions:   []Function{shallow, deep, leaf},
+		EntryPoints: []EntryPoint{{Name: "main", Stage: StageFragment, Function: entry}},
+	}
+	if got := EntryPointStackEstimate(m, 0); got != 16+32 {
+		t.Errorf("estimate = %d, want 48", got)
+	}
+}
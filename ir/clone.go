@@ -0,0 +1,271 @@
+// Copyright 2025 The GoGPU Authors
+// SPDX-License-Identifier: MIT
+
+package ir
+
+import (
+	"maps"
+	"slices"
+)
+
+// CloneModule returns a deep copy of module: no slice, map, or pointer in
+// the copy is shared with module, so a pass may modify either one freely.
+// A plain struct copy shares every arena (Types, Expressions, statement
+// bodies, ...), and appending to or rewriting one then corrupts the other.
+//
+// CloneModuleForOverrides is cheaper when only the parts ProcessOverrides
+// rewrites need copying.
+func CloneModule(module *Module) *Module {
+	dst := *module
+	dst.Types = slices.Clone(module.Types)
+	for i := range dst.Types {
+		dst.Types[i].Inner = CloneTypeInner(dst.Types[i].Inner)
+	}
+	dst.Constants = slices.Clone(module.Constants)
+	for i := range dst.Constants {
+		dst.Constants[i].Value = cloneConstantValue(dst.Constants[i].Value)
+	}
+	dst.GlobalVariables = slices.Clone(module.GlobalVariables)
+	for i := range dst.GlobalVariables {
+		gv := &dst.GlobalVariables[i]
+		gv.Binding = clonePtr(gv.Binding)
+		gv.Init = clonePtr(gv.Init)
+		gv.InitExpr = clonePtr(gv.InitExpr)
+	}
+	dst.GlobalExpressions = cloneExpressions(module.GlobalExpressions)
+	dst.Functions = slices.Clone(module.Functions)
+	for i := range dst.Functions {
+		dst.Functions[i] = CloneFunction(&module.Functions[i])
+	}
+	dst.EntryPoints = slices.Clone(module.EntryPoints)
+	for i := range dst.EntryPoints {
+		ep := &dst.EntryPoints[i]
+		ep.Function = CloneFunction(&module.EntryPoints[i].Function)
+		ep.EarlyDepthTest = clonePtr(ep.EarlyDepthTest)
+		ep.TaskPayload = clonePtr(ep.TaskPayload)
+		if ep.MeshInfo != nil {
+			info := *ep.MeshInfo
+			info.MaxVerticesOverride = clonePtr(info.MaxVerticesOverride)
+			info.MaxPrimitivesOverride = clonePtr(info.MaxPrimitivesOverride)
+			ep.MeshInfo = &info
+		}
+		for axis := range ep.WorkgroupOverrides {
+			ep.WorkgroupOverrides[axis] = clonePtr(ep.WorkgroupOverrides[axis])
+		}
+	}
+	dst.Overrides = slices.Clone(module.Overrides)
+	for i := range dst.Overrides {
+		dst.Overrides[i].ID = clonePtr(dst.Overrides[i].ID)
+		dst.Overrides[i].Init = clonePtr(dst.Overrides[i].Init)
+	}
+	dst.SpecialTypes.ExternalTextureParams = clonePtr(module.SpecialTypes.ExternalTextureParams)
+	dst.SpecialTypes.ExternalTextureTransferFunction = clonePtr(module.SpecialTypes.ExternalTextureTransferFunction)
+	dst.SpecialTypes.RayIntersection = clonePtr(module.SpecialTypes.RayIntersection)
+	dst.TypeAliasNames = slices.Clone(module.TypeAliasNames)
+	dst.TypeUseOrder = slices.Clone(module.TypeUseOrder)
+	dst.DiagnosticFilters = slices.Clone(module.DiagnosticFilters)
+	for i := range dst.DiagnosticFilters {
+		dst.DiagnosticFilters[i].Parent = clonePtr(dst.DiagnosticFilters[i].Parent)
+	}
+	dst.DiagnosticFilterLeaf = clonePtr(module.DiagnosticFilterLeaf)
+	return &dst
+}
+
+// CloneFunction returns a deep copy of fn sharing no slice, map, or
+// pointer with it.
+func CloneFunction(fn *Function) Function {
+	dst := *fn
+	dst.Arguments = slices.Clone(fn.Arguments)
+	for i := range dst.Arguments {
+		dst.Arguments[i].Binding = cloneBinding(dst.Arguments[i].Binding)
+	}
+	if fn.Result != nil {
+		result := *fn.Result
+		result.Binding = cloneBinding(result.Binding)
+		dst.Result = &result
+	}
+	dst.LocalVars = slices.Clone(fn.LocalVars)
+	for i := range dst.LocalVars {
+		dst.LocalVars[i].Init = clonePtr(dst.LocalVars[i].Init)
+	}
+	dst.Expressions = cloneExpressions(fn.Expressions)
+	dst.ExpressionTypes = slices.Clone(fn.ExpressionTypes)
+	for i := range dst.ExpressionTypes {
+		tr := &dst.ExpressionTypes[i]
+		tr.Handle = clonePtr(tr.Handle)
+		tr.Value = CloneTypeInner(tr.Value)
+	}
+	dst.Body = CloneBlock(fn.Body)
+	dst.NamedExpressions = maps.Clone(fn.NamedExpressions)
+	dst.DiagnosticFilterLeaf = clonePtr(fn.DiagnosticFilterLeaf)
+	return dst
+}
+
+// CloneBlock returns a deep copy of block, including nested blocks and the
+// argument slices and optional handles of its statements.
+func CloneBlock(block Block) Block {
+	if block == nil {
+		return nil
+	}
+	out := make(Block, len(block))
+	for i, stmt := range block {
+		out[i] = Statement{Kind: cloneStatementKind(stmt.Kind)}
+	}
+	return out
+}
+
+func cloneStatementKind(kind StatementKind) StatementKind {
+	switch s := kind.(type) {
+	case StmtBlock:
+		s.Block = CloneBlock(s.Block)
+		return s
+	case StmtIf:
+		s.Accept = CloneBlock(s.Accept)
+		s.Reject = CloneBlock(s.Reject)
+		return s
+	case StmtSwitch:
+		cases := make([]SwitchCase, len(s.Cases))
+		for j, c := range s.Cases {
+			c.Body = CloneBlock(c.Body)
+			cases[j] = c
+		}
+		s.Cases = cases
+		return s
+	case StmtLoop:
+		s.Body = CloneBlock(s.Body)
+		s.Continuing = CloneBlock(s.Continuing)
+		s.BreakIf = clonePtr(s.BreakIf)
+		return s
+	case StmtReturn:
+		s.Value = clonePtr(s.Value)
+		return s
+	case StmtImageStore:
+		s.ArrayIndex = clonePtr(s.ArrayIndex)
+		return s
+	case StmtAtomic:
+		s.Fun = cloneAtomicFunction(s.Fun)
+		s.Result = clonePtr(s.Result)
+		return s
+	case StmtImageAtomic:
+		s.ArrayIndex = clonePtr(s.ArrayIndex)
+		s.Fun = cloneAtomicFunction(s.Fun)
+		return s
+	case StmtCall:
+		s.Arguments = slices.Clone(s.Arguments)
+		s.Result = clonePtr(s.Result)
+		return s
+	case StmtSubgroupBallot:
+		s.Predicate = clonePtr(s.Predicate)
+		return s
+	default:
+		return kind
+	}
+}
+
+func cloneAtomicFunction(fun AtomicFunction) AtomicFunction {
+	if exchange, ok := fun.(AtomicExchange); ok {
+		exchange.Compare = clonePtr(exchange.Compare)
+		return exchange
+	}
+	return fun
+}
+
+func cloneExpressions(exprs []Expression) []Expression {
+	out := slices.Clone(exprs)
+	for i := range out {
+		out[i].Kind = cloneExpressionKind(out[i].Kind)
+	}
+	return out
+}
+
+func cloneExpressionKind(kind ExpressionKind) ExpressionKind {
+	switch k := kind.(type) {
+	case ExprCompose:
+		k.Components = slices.Clone(k.Components)
+		return k
+	case ExprPhi:
+		k.Incoming = slices.Clone(k.Incoming)
+		return k
+	case ExprImageSample:
+		k.Gather = clonePtr(k.Gather)
+		k.ArrayIndex = clonePtr(k.ArrayIndex)
+		k.Offset = clonePtr(k.Offset)
+		k.DepthRef = clonePtr(k.DepthRef)
+		return k
+	case ExprImageLoad:
+		k.ArrayIndex = clonePtr(k.ArrayIndex)
+		k.Sample = clonePtr(k.Sample)
+		k.Level = clonePtr(k.Level)
+		return k
+	case ExprImageQuery:
+		if size, ok := k.Query.(ImageQuerySize); ok {
+			size.Level = clonePtr(size.Level)
+			k.Query = size
+		}
+		return k
+	case ExprMath:
+		k.Arg1 = clonePtr(k.Arg1)
+		k.Arg2 = clonePtr(k.Arg2)
+		k.Arg3 = clonePtr(k.Arg3)
+		return k
+	case ExprAs:
+		k.Convert = clonePtr(k.Convert)
+		return k
+	default:
+		return kind
+	}
+}
+
+// CloneTypeInner returns a deep copy of inner: struct members and their
+// bindings, and array, binding array, and value pointer sizes, are copied.
+func CloneTypeInner(inner TypeInner) TypeInner {
+	switch t := inner.(type) {
+	case StructType:
+		t.Members = slices.Clone(t.Members)
+		for i := range t.Members {
+			t.Members[i].Binding = cloneBinding(t.Members[i].Binding)
+		}
+		return t
+	case ArrayType:
+		t.Size.Constant = clonePtr(t.Size.Constant)
+		t.Size.Override = clonePtr(t.Size.Override)
+		return t
+	case BindingArrayType:
+		t.Size = clonePtr(t.Size)
+		return t
+	case ValuePointerType:
+		t.Size = clonePtr(t.Size)
+		return t
+	default:
+		return inner
+	}
+}
+
+func cloneConstantValue(value ConstantValue) ConstantValue {
+	if c, ok := value.(CompositeValue); ok {
+		c.Components = slices.Clone(c.Components)
+		return c
+	}
+	return value
+}
+
+func cloneBinding(binding *Binding) *Binding {
+	if binding == nil {
+		return nil
+	}
+	b := *binding
+	if loc, ok := b.(LocationBinding); ok {
+		loc.Interpolation = clonePtr(loc.Interpolation)
+		loc.BlendSrc = clonePtr(loc.BlendSrc)
+		b = loc
+	}
+	return &b
+}
+
+func clonePtr[T any](p *T) *T {
+	if p == nil {
+		return nil
+	}
+	v := *p
+	return &v
+}
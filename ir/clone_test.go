@@ -0,0 +1,182 @@
+// Copyright 2025 The GoGPU Authors
+// SPDX-License-Identifier: MIT
+
+package ir
+
+import (
+	"reflect"
+	"testing"
+)
+
+// cloneTestModule exercises every field CloneModule has to copy: pointers
+// in types, bindings, globals, expressions and statements, nested blocks,
+// and the module-level side tables.
+func cloneTestModule() *Module {
+	one, two := ExpressionHandle(1), ExpressionHandle(2)
+	n := uint32(4)
+	blend := uint32(0)
+	var loc Binding = LocationBinding{Location: 0, Interpolation: &Interpolation{Kind: InterpolationPerspective}, BlendSrc: &blend}
+	var pos Binding = BuiltinBinding{Builtin: BuiltinPosition}
+	f32 := ScalarType{Kind: ScalarFloat, Width: 4}
+	vec4 := TypeHandle(1)
+	width := uint8(4)
+	id := uint16(7)
+	leaf := DiagnosticFilterHandle(0)
+	size := Vec4
+	fn := Function{
+		Name:      "helper",
+		Arguments: []FunctionArgument{{Name: "c", Type: vec4, Binding: &loc}},
+		Result:    &FunctionResult{Type: vec4, Binding: &pos},
+		LocalVars: []LocalVariable{{Name: "v", Type: vec4, Init: &one}},
+		Expressions: []Expression{
+			{Kind: ExprFunctionArgument{Index: 0}},
+			{Kind: Literal{Value: LiteralF32(1)}},
+			{Kind: ExprCompose{Type: vec4, Components: []ExpressionHandle{1, 1, 1, 1}}},
+			{Kind: ExprMath{Fun: MathClamp, Arg: 2, Arg1: &one, Arg2: &two}},
+			{Kind: ExprAs{Expr: 1, Kind: ScalarSint, Convert: &width}},
+			{Kind: ExprImageQuery{Image: 0, Query: ImageQuerySize{Level: &one}}},
+		},
+		ExpressionTypes: []TypeResolution{{Handle: &vec4}, {Value: f32}, {Handle: &vec4}, {Handle: &vec4}, {Value: ValuePointerType{Size: &size, Scalar: f32}}, {Value: f32}},
+		Body: Block{
+			{Kind: StmtEmit{Range: Range{Start: 2, End: 5}}},
+			{Kind: StmtLoop{
+				Body:       Block{{Kind: StmtCall{Function: 0, Arguments: []ExpressionHandle{0, 2}, Result: &two}}},
+				Continuing: Block{{Kind: StmtAtomic{Pointer: 0, Fun: AtomicExchange{Compare: &one}, Value: 1, Result: &two}}},
+				BreakIf:    &one,
+			}},
+			{Kind: StmtSwitch{Selector: 1, Cases: []SwitchCase{{Value: SwitchValueDefault{}, Body: Block{{Kind: StmtReturn{Value: &two}}}}}}},
+		},
+		NamedExpressions:     map[ExpressionHandle]string{2: "color"},
+		DiagnosticFilterLeaf: &leaf,
+	}
+	return &Module{
+		Types: []Type{
+			{Name: "f32", Inner: f32},
+			{Inner: VectorType{Size: Vec4, Scalar: f32}},
+			{Name: "Out", Inner: StructType{Members: []StructMember{{Name: "c", Type: vec4, Binding: &loc}}}},
+			{Inner: ArrayType{Base: vec4, Size: ArraySize{Constant: &n}, Stride: 16}},
+		},
+		Constants:         []Constant{{Name: "K", Type: vec4, Value: CompositeValue{Components: []ConstantHandle{0, 0}}}},
+		GlobalVariables:   []GlobalVariable{{Name: "g", Type: vec4, Binding: &ResourceBinding{Group: 0, Binding: 1}, InitExpr: &one}},
+		GlobalExpressions: []Expression{{Kind: ExprCompose{Type: vec4, Components: []ExpressionHandle{0}}}},
+		Functions:         []Function{fn},
+		EntryPoints: []EntryPoint{{
+			Name: "main", Stage: StageFragment, Function: fn,
+			EarlyDepthTest:     &EarlyDepthTest{},
+			WorkgroupOverrides: [3]*OverrideHandle{new(OverrideHandle)},
+		}},
+		Overrides:            []Override{{Name: "o", ID: &id, Init: &one}},
+		SpecialTypes:         SpecialTypes{RayIntersection: &vec4},
+		TypeAliasNames:       []string{"alias"},
+		TypeUseOrder:         []TypeHandle{0, 1},
+		DiagnosticFilters:    []DiagnosticFilterNode{{Parent: &leaf}},
+		DiagnosticFilterLeaf: &leaf,
+	}
+}
+
+func TestCloneModuleDeepEqual(t *testing.T) {
+	src := cloneTestModule()
+	dst := CloneModule(src)
+	if !reflect.DeepEqual(src, dst) {
+		t.Fatal("clone differs from the source module")
+	}
+	assertNoAliasing(t, "Module", reflect.ValueOf(src).Elem(), reflect.ValueOf(dst).Elem())
+}
+
+// TestCloneModuleIndependent mutates a clone in ways passes do — appending
+// to arenas, rewriting types and statements in place — and checks the
+// source is unaffected.
+func TestCloneModuleIndependent(t *testing.T) {
+	src := cloneTestModule()
+	dst := CloneModule(src)
+
+	dst.Types = append(dst.Types[:1], Type{Name: "replaced", Inner: ScalarType{Kind: ScalarUint, Width: 4}})
+	dst.Types[0].Name = "renamed"
+	fn := &dst.EntryPoints[0].Function
+	fn.Expressions[2].Kind.(ExprCompose).Components[0] = 9
+	*fn.Expressions[3].Kind.(ExprMath).Arg1 = 9
+	*fn.Result.Binding = BuiltinBinding{Builtin: BuiltinFragDepth}
+	fn.Body[1].Kind.(StmtLoop).Body[0].Kind.(StmtCall).Arguments[0] = 9
+	fn.NamedExpressions[0] = "arg"
+	*dst.Overrides[0].ID = 1
+
+	if !reflect.DeepEqual(src, cloneTestModule()) {
+		t.Error("mutating the clone changed the source module")
+	}
+}
+
+func TestRemapBlockHandles(t *testing.T) {
+	src := cloneTestModule().Functions[0].Body
+	shift := func(h ExpressionHandle) ExpressionHandle { return h + 10 }
+	out := RemapBlockHandles(src, shift)
+
+	if !reflect.DeepEqual(src, cloneTestModule().Functions[0].Body) {
+		t.Fatal("RemapBlockHandles modified its input")
+	}
+	if emit := out[0].Kind.(StmtEmit); emit.Range != (Range{Start: 12, End: 15}) {
+		t.Errorf("emit range = %v, want [12, 15)", emit.Range)
+	}
+	loop := out[1].Kind.(StmtLoop)
+	call := loop.Body[0].Kind.(StmtCall)
+	if call.Arguments[0] != 10 || call.Arguments[1] != 12 || *call.Result != 12 {
+		t.Errorf("call = %+v, want arguments [10 12] and result 12", call)
+	}
+	if *loop.Continuing[0].Kind.(StmtAtomic).Fun.(AtomicExchange).Compare != 11 || *loop.BreakIf != 11 {
+		t.Error("continuing block or break-if not remapped")
+	}
+
+	compose := RemapExpressionHandles(ExprCompose{Components: []ExpressionHandle{1, 2}}, shift).(ExprCompose)
+	if compose.Components[0] != 11 || compose.Components[1] != 12 {
+		t.Errorf("compose components = %v, want [11 12]", compose.Components)
+	}
+
+	st := StructType{Members: []StructMember{{Type: 1}}}
+	remapped := RemapTypeHandles(st, func(h TypeHandle) TypeHandle { return h + 1 }).(StructType)
+	if remapped.Members[0].Type != 2 || st.Members[0].Type != 1 {
+		t.Errorf("RemapTypeHandles: got member type %d (source %d), want 2 (source 1)", remapped.Members[0].Type, st.Members[0].Type)
+	}
+}
+
+// assertNoAliasing walks a and b, two deep-equal values, and fails if
+// they share any non-empty slice, map, or pointer.
+func assertNoAliasing(t *testing.T, path string, a, b reflect.Value) {
+	t.Helper()
+	switch a.Kind() {
+	case reflect.Pointer:
+		if a.IsNil() {
+			return
+		}
+		if a.Pointer() == b.Pointer() {
+			t.Errorf("%s: pointer shared with the source", path)
+			return
+		}
+		assertNoAliasing(t, path, a.Elem(), b.Elem())
+	case reflect.Interface:
+		if !a.IsNil() {
+			assertNoAliasing(t, path, a.Elem(), b.Elem())
+		}
+	case reflect.Slice:
+		if a.Len() == 0 {
+			return
+		}
+		if a.Pointer() == b.Pointer() {
+			t.Errorf("%s: slice shared with the source", path)
+			return
+		}
+		for i := 0; i < a.Len(); i++ {
+			assertNoAliasing(t, path+"[]", a.Index(i), b.Index(i))
+		}
+	case reflect.Map:
+		if a.Len() > 0 && a.Pointer() == b.Pointer() {
+			t.Errorf("%s: map shared with the source", path)
+		}
+	case reflect.Struct:
+		for i := 0; i < a.NumField(); i++ {
+			assertNoAliasing(t, path+"."+a.Type().Field(i).Name, a.Field(i), b.Field(i))
+		}
+	case reflect.Array:
+		for i := 0; i < a.Len(); i++ {
+			assertNoAliasing(t, path+"[]", a.Index(i), b.Index(i))
+		}
+	}
+}
@@ -1,5 +1,11 @@
 package ir
 
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
 // CompactUnused removes globals and functions not reachable from any entry point.
 // Matches Rust naga's compact pass which traces from entry points and removes
 // unreachable global variables, functions, and their associated types.
@@ -123,6 +129,43 @@ func CompactUnused(module *Module) {
 	}
 }
 
+// SelectEntryPoints returns a copy of module with only the entry points
+// named in names, in module order, and the functions and global variables
+// they reach, pruned as CompactUnused prunes them, so each pipeline stage
+// can ship in its own artifact. A name selects every entry point it
+// names, whatever the stage. It fails, listing the entry points module
+// has, if a name selects none. module itself is not modified.
+func SelectEntryPoints(module *Module, names []string) (*Module, error) {
+	selected := make([]bool, len(module.EntryPoints))
+	for _, name := range names {
+		found := false
+		for i := range module.EntryPoints {
+			if module.EntryPoints[i].Name == name {
+				selected[i] = true
+				found = true
+			}
+		}
+		if !found {
+			have := make([]string, len(module.EntryPoints))
+			for i := range module.EntryPoints {
+				have[i] = module.EntryPoints[i].Name
+			}
+			return nil, fmt.Errorf("no entry point named %q (have %s)", name, strings.Join(have, ", "))
+		}
+	}
+
+	dst := CloneModule(module)
+	kept := dst.EntryPoints[:0]
+	for i, ep := range dst.EntryPoints {
+		if selected[i] {
+			kept = append(kept, ep)
+		}
+	}
+	dst.EntryPoints = kept
+	CompactUnused(dst)
+	return dst, nil
+}
+
 // traceStatementsForRefs traces statements for global variable and function call references.
 func traceStatementsForRefs(stmts []Statement, usedGlobals []bool, usedFunctions []bool, module *Module, traceFunc func(*Function)) {
 	for _, stmt := range stmts {
@@ -226,6 +269,13 @@ func CompactTypes(module *Module) {
 		markFunctionTypeRefs(&module.EntryPoints[i].Function, referenced)
 	}
 
+	// Global expressions built inline during constant lowering, e.g. the
+	// column Composes of a matrix constant, whose vector type is otherwise
+	// only embedded by value in the matrix type.
+	for _, expr := range module.GlobalExpressions {
+		markExprTypeRefs(expr.Kind, referenced)
+	}
+
 	// Step 2: Determine which types to keep.
 	// Keep referenced types AND all named types.
 	// Rust naga's compact keeps all named types (structs, aliases, etc.) regardless
@@ -653,32 +703,35 @@ func safeRemapFunctionTypes(f *Function, remap []TypeHandle) {
 
 // remapTypeInner updates type handles within a TypeInner.
 func remapTypeInner(inner TypeInner, remap []TypeHandle) TypeInner {
-	safe := func(h TypeHandle) TypeHandle {
+	return RemapTypeHandles(inner, func(h TypeHandle) TypeHandle {
 		if h == ^TypeHandle(0) || int(h) >= len(remap) {
 			return h
 		}
 		return remap[h]
-	}
-	switch t := inner.(type) {
+	})
+}
+
+// RemapTypeHandles returns inner with every type handle it refers to
+// replaced by rm(handle). The result shares no slices or pointers with
+// inner, so inner itself is never modified.
+func RemapTypeHandles(inner TypeInner, rm func(TypeHandle) TypeHandle) TypeInner {
+	switch t := CloneTypeInner(inner).(type) {
 	case ArrayType:
-		t.Base = safe(t.Base)
+		t.Base = rm(t.Base)
 		return t
 	case StructType:
-		members := make([]StructMember, len(t.Members))
-		copy(members, t.Members)
-		for i := range members {
-			members[i].Type = safe(members[i].Type)
+		for i := range t.Members {
+			t.Members[i].Type = rm(t.Members[i].Type)
 		}
-		t.Members = members
 		return t
 	case PointerType:
-		t.Base = safe(t.Base)
+		t.Base = rm(t.Base)
 		return t
 	case BindingArrayType:
-		t.Base = safe(t.Base)
+		t.Base = rm(t.Base)
 		return t
 	default:
-		return inner
+		return t
 	}
 }
 
@@ -850,17 +903,14 @@ func CompactExpressions(module *Module) {
 	}
 }
 
-func compactFunctionExpressions(f *Function) {
+// LiveExpressions reports which expressions of f are live: referenced by a
+// statement, a named expression, or a local variable initializer, directly or
+// through other live expressions. Emit ranges do not count as uses, matching
+// Rust naga's trace_block: "since evaluating expressions has no effect, we
+// don't need to assume that everything emitted is live." Backends use it to
+// skip expressions an abandoned lowering path left behind.
+func LiveExpressions(f *Function) []bool {
 	n := len(f.Expressions)
-	if n == 0 {
-		return
-	}
-
-	// Phase 1: Mark expressions directly referenced by statements, named
-	// expressions, and local variable initializers. Emit ranges do NOT mark
-	// expressions as used - matching Rust naga's trace_block which says:
-	// "since evaluating expressions has no effect, we don't need to assume
-	// that everything emitted is live."
 	used := make([]bool, n)
 
 	// Mark expressions referenced by named expressions (Rust: treat named as alive)
@@ -880,8 +930,8 @@ func compactFunctionExpressions(f *Function) {
 	// Mark expressions referenced by statements (Emit is skipped inside)
 	markStmtExprRefsForCompact(f.Body, used)
 
-	// Phase 2: Propagate usage from back to front through expressions.
-	// Since expressions can only refer to earlier expressions, a single
+	// Propagate usage from back to front through expressions. Since
+	// expressions can only refer to earlier expressions, a single
 	// back-to-front pass computes the full transitive closure.
 	for i := n - 1; i >= 0; i-- {
 		if !used[i] {
@@ -889,16 +939,21 @@ func compactFunctionExpressions(f *Function) {
 		}
 		markExprHandleRefs(f.Expressions[i].Kind, used)
 	}
+	return used
+}
 
-	// Phase 3: Check if anything would be removed.
-	allUsed := true
-	for _, u := range used {
-		if !u {
-			allUsed = false
-			break
-		}
+func compactFunctionExpressions(f *Function) {
+	n := len(f.Expressions)
+	if n == 0 {
+		return
 	}
-	if allUsed {
+
+	// Phases 1-2: mark live expressions.
+	used := LiveExpressions(f)
+
+	// Phase 3: Check if anything would be removed.
+	first := slices.Index(used, false)
+	if first < 0 {
 		return
 	}
 
@@ -915,9 +970,12 @@ func compactFunctionExpressions(f *Function) {
 	}
 	f.Expressions = newExprs
 
-	// Phase 5: Remap all expression handles within expressions.
-	for i := range f.Expressions {
-		f.Expressions[i].Kind = remapExprHandles(f.Expressions[i].Kind, remap)
+	// Phase 5: Remap all expression handles within expressions. Those
+	// before the first removed one only refer to handles that keep their
+	// number.
+	rm := remapBySlice(remap)
+	for i := first; i < len(f.Expressions); i++ {
+		f.Expressions[i].Kind = RemapExpressionHandles(f.Expressions[i].Kind, rm)
 	}
 
 	// Remap named expressions.
@@ -954,14 +1012,19 @@ func compactFunctionExpressions(f *Function) {
 
 // markExprHandleRefs marks expression handles referenced by an expression kind.
 func markExprHandleRefs(kind ExpressionKind, referenced []bool) {
-	mark := func(h ExpressionHandle) {
+	visitExprOperands(kind, func(h ExpressionHandle) {
 		if int(h) < len(referenced) {
 			referenced[h] = true
 		}
-	}
+	})
+}
+
+// visitExprOperands calls mark for every expression handle an expression
+// kind references directly.
+func visitExprOperands(kind ExpressionKind, mark func(ExpressionHandle)) {
 	markOpt := func(h *ExpressionHandle) {
-		if h != nil && int(*h) < len(referenced) {
-			referenced[*h] = true
+		if h != nil {
+			mark(*h)
 		}
 	}
 	switch k := kind.(type) {
@@ -1024,6 +1087,12 @@ func markExprHandleRefs(kind ExpressionKind, referenced []bool) {
 		mark(k.Query)
 	case ExprDerivative:
 		mark(k.Expr)
+	case ExprAlias:
+		mark(k.Source)
+	case ExprPhi:
+		for _, in := range k.Incoming {
+			mark(in.Value)
+		}
 		// These have no expression refs:
 		// Literal, ExprConstant, ExprZeroValue, ExprGlobalVariable,
 		// ExprLocalVariable, ExprFunctionArgument, ExprCallResult,
@@ -1448,12 +1517,24 @@ func remapGatherMode(mode GatherMode, rm func(ExpressionHandle) ExpressionHandle
 
 // remapExprHandles remaps expression handles within an expression kind.
 func remapExprHandles(kind ExpressionKind, remap []ExpressionHandle) ExpressionKind {
-	rm := func(h ExpressionHandle) ExpressionHandle {
+	return RemapExpressionHandles(kind, remapBySlice(remap))
+}
+
+// remapBySlice returns a remapping function backed by a handle table.
+// Handles past the end of the table are kept.
+func remapBySlice(remap []ExpressionHandle) func(ExpressionHandle) ExpressionHandle {
+	return func(h ExpressionHandle) ExpressionHandle {
 		if int(h) < len(remap) {
 			return remap[h]
 		}
 		return h
 	}
+}
+
+// RemapExpressionHandles returns kind with every expression handle it
+// refers to replaced by rm(handle). The result shares no slices or
+// pointers with kind, so kind itself is never modified.
+func RemapExpressionHandles(kind ExpressionKind, rm func(ExpressionHandle) ExpressionHandle) ExpressionKind {
 	rmOpt := func(h *ExpressionHandle) *ExpressionHandle {
 		if h == nil {
 			return nil
@@ -1469,6 +1550,17 @@ func remapExprHandles(kind ExpressionKind, remap []ExpressionHandle) ExpressionK
 		}
 		k.Components = comps
 		return k
+	case ExprAlias:
+		k.Source = rm(k.Source)
+		return k
+	case ExprPhi:
+		incoming := make([]PhiIncoming, len(k.Incoming))
+		for i, in := range k.Incoming {
+			in.Value = rm(in.Value)
+			incoming[i] = in
+		}
+		k.Incoming = incoming
+		return k
 	case ExprSplat:
 		k.Value = rm(k.Value)
 		return k
@@ -1570,12 +1662,21 @@ func remapSampleLevel(level SampleLevel, rm func(ExpressionHandle) ExpressionHan
 
 // remapStmtExprHandles remaps expression handles in all statements.
 func remapStmtExprHandles(stmts []Statement, remap []ExpressionHandle) {
-	rm := func(h ExpressionHandle) ExpressionHandle {
-		if int(h) < len(remap) {
-			return remap[h]
-		}
-		return h
-	}
+	remapStmtHandles(stmts, remapBySlice(remap))
+}
+
+// RemapBlockHandles returns a copy of block with every expression handle
+// its statements refer to, in nested blocks too, replaced by rm(handle).
+// block itself is never modified.
+func RemapBlockHandles(block Block, rm func(ExpressionHandle) ExpressionHandle) Block {
+	out := CloneBlock(block)
+	remapStmtHandles(out, rm)
+	return out
+}
+
+// remapStmtHandles remaps expression handles in stmts in place, including
+// argument slices shared with other blocks; clone first when they may be.
+func remapStmtHandles(stmts []Statement, rm func(ExpressionHandle) ExpressionHandle) {
 	rmOpt := func(h *ExpressionHandle) *ExpressionHandle {
 		if h == nil {
 			return nil
@@ -1586,22 +1687,22 @@ func remapStmtExprHandles(stmts []Statement, remap []ExpressionHandle) {
 	for i, stmt := range stmts {
 		switch s := stmt.Kind.(type) {
 		case StmtBlock:
-			remapStmtExprHandles(s.Block, remap)
+			remapStmtHandles(s.Block, rm)
 			stmts[i].Kind = s
 		case StmtIf:
 			s.Condition = rm(s.Condition)
-			remapStmtExprHandles(s.Accept, remap)
-			remapStmtExprHandles(s.Reject, remap)
+			remapStmtHandles(s.Accept, rm)
+			remapStmtHandles(s.Reject, rm)
 			stmts[i].Kind = s
 		case StmtSwitch:
 			s.Selector = rm(s.Selector)
 			for ci := range s.Cases {
-				remapStmtExprHandles(s.Cases[ci].Body, remap)
+				remapStmtHandles(s.Cases[ci].Body, rm)
 			}
 			stmts[i].Kind = s
 		case StmtLoop:
-			remapStmtExprHandles(s.Body, remap)
-			remapStmtExprHandles(s.Continuing, remap)
+			remapStmtHandles(s.Body, rm)
+			remapStmtHandles(s.Continuing, rm)
 			s.BreakIf = rmOpt(s.BreakIf)
 			stmts[i].Kind = s
 		case StmtReturn:
@@ -1708,7 +1809,10 @@ func deduplicateBlockEmits(stmts []Statement, exprs []Expression) []Statement {
 		return stmts
 	}
 	// First pass: remove empty, covered, and pre-emit-only Emits.
-	emitted := make(map[ExpressionHandle]bool)
+	// emitted[h-base] records handle h; it covers the handles from the
+	// first Emit of the block to the end of the last one.
+	var emitted []bool
+	var base ExpressionHandle
 	filtered := make([]Statement, 0, len(stmts))
 	for _, s := range stmts {
 		if emit, ok := s.Kind.(StmtEmit); ok {
@@ -1718,7 +1822,7 @@ func deduplicateBlockEmits(stmts []Statement, exprs []Expression) []Statement {
 			// Check if ALL handles in this range are already emitted
 			allCovered := true
 			for h := emit.Range.Start; h < emit.Range.End; h++ {
-				if !emitted[h] {
+				if h < base || int(h-base) >= len(emitted) || !emitted[h-base] {
 					allCovered = false
 					break
 				}
@@ -1739,8 +1843,14 @@ func deduplicateBlockEmits(stmts []Statement, exprs []Expression) []Statement {
 			if allPreEmit {
 				continue // Skip: all expressions are pre-emit, no Emit needed
 			}
+			if emitted == nil || emit.Range.Start < base {
+				emitted, base = rebase(emitted, base, emit.Range.Start), emit.Range.Start
+			}
+			if n := int(emit.Range.End - base); n > len(emitted) {
+				emitted = append(emitted, make([]bool, n-len(emitted))...)
+			}
 			for h := emit.Range.Start; h < emit.Range.End; h++ {
-				emitted[h] = true
+				emitted[h-base] = true
 			}
 		}
 		filtered = append(filtered, s)
@@ -1772,6 +1882,15 @@ func deduplicateBlockEmits(stmts []Statement, exprs []Expression) []Statement {
 	return result
 }
 
+// rebase returns the handle flags set, indexed from base, indexed from
+// the lower newBase instead.
+func rebase(set []bool, base, newBase ExpressionHandle) []bool {
+	if set == nil {
+		return []bool{}
+	}
+	return append(make([]bool, base-newBase, int(base-newBase)+len(set)), set...)
+}
+
 // isPreEmitExpression returns true for expression types that don't need Emit statements.
 // Matches Rust naga's Expression::needs_pre_emit().
 func isPreEmitExpression(kind ExpressionKind) bool {
@@ -248,6 +248,60 @@ func TestCompactUnused_MeshShaderTaskPayload(t *testing.T) {
 	}
 }
 
+func TestSelectEntryPoints(t *testing.T) {
+	module := &Module{
+		GlobalVariables: []GlobalVariable{
+			{Name: "mvp", Type: 0},
+			{Name: "tex", Type: 0},
+		},
+		Functions: []Function{
+			{Name: "shade", Expressions: []Expression{{Kind: ExprGlobalVariable{Variable: 1}}}},
+		},
+		EntryPoints: []EntryPoint{
+			{Name: "vs_main", Stage: StageVertex, Function: Function{
+				Expressions: []Expression{{Kind: ExprGlobalVariable{Variable: 0}}},
+			}},
+			{Name: "fs_main", Stage: StageFragment, Function: Function{
+				Body: []Statement{{Kind: StmtCall{Function: 0}}},
+			}},
+		},
+	}
+
+	fs, err := SelectEntryPoints(module, []string{"fs_main"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(fs.EntryPoints) != 1 || fs.EntryPoints[0].Name != "fs_main" {
+		t.Fatalf("entry points = %v, want fs_main", fs.EntryPoints)
+	}
+	if len(fs.GlobalVariables) != 1 || fs.GlobalVariables[0].Name != "tex" {
+		t.Errorf("globals = %v, want only tex", fs.GlobalVariables)
+	}
+	if got := fs.Functions[0].Expressions[0].Kind; got != (ExprGlobalVariable{Variable: 0}) {
+		t.Errorf("shade reads %v, want the remapped tex", got)
+	}
+	if len(fs.Functions) != 1 {
+		t.Errorf("%d functions, want shade", len(fs.Functions))
+	}
+
+	vs, err := SelectEntryPoints(module, []string{"vs_main"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(vs.GlobalVariables) != 1 || vs.GlobalVariables[0].Name != "mvp" || len(vs.Functions) != 0 {
+		t.Errorf("vs_main keeps globals %v and %d functions, want mvp alone", vs.GlobalVariables, len(vs.Functions))
+	}
+
+	if len(module.EntryPoints) != 2 || len(module.GlobalVariables) != 2 || module.Functions[0].Expressions[0].Kind != (ExprGlobalVariable{Variable: 1}) {
+		t.Error("the input module was modified")
+	}
+
+	_, err = SelectEntryPoints(module, []string{"vs_main", "cs_main"})
+	if err == nil || err.Error() != `no entry point named "cs_main" (have vs_main, fs_main)` {
+		t.Errorf("error = %v, want cs_main reported with the entry points available", err)
+	}
+}
+
 // --- CompactTypes tests ---
 
 func TestCompactTypes_EmptyModule(t *testing.T) {
@@ -2716,3 +2770,34 @@ func TestRemapFunctionTypes_AbstractTypeRemoved(t *testing.T) {
 		t.Errorf("expected nil handle for removed abstract type, got %v", f.ExpressionTypes[0].Handle)
 	}
 }
+
+// --- LiveExpressions tests ---
+
+func TestLiveExpressions(t *testing.T) {
+	ret := ExpressionHandle(3)
+	f := &Function{
+		LocalVars: []LocalVariable{{Name: "x", Type: 0}},
+		Expressions: []Expression{
+			{Kind: ExprLocalVariable{Variable: 0}},                    // [0] &x
+			{Kind: ExprLoad{Pointer: 0}},                              // [1] x, used by [3]
+			{Kind: ExprBinary{Op: BinaryMultiply, Left: 1, Right: 1}}, // [2] dead: only emitted
+			{Kind: ExprUnary{Op: UnaryNegate, Expr: 1}},               // [3] returned
+			{Kind: Literal{Value: LiteralF32(2)}},                     // [4] named
+			{Kind: ExprBinary{Op: BinaryAdd, Left: 2, Right: 2}},      // [5] dead, keeps [2] dead
+			{Kind: ExprAs{Expr: 4, Kind: ScalarSint, Convert: nil}},   // [6] dead
+		},
+		NamedExpressions: map[ExpressionHandle]string{4: "two"},
+		Body: []Statement{
+			{Kind: StmtEmit{Range: Range{Start: 1, End: 7}}},
+			{Kind: StmtReturn{Value: &ret}},
+		},
+	}
+
+	live := LiveExpressions(f)
+	want := []bool{true, true, false, true, true, false, false}
+	for i := range want {
+		if live[i] != want[i] {
+			t.Errorf("expression [%d] live = %v, want %v", i, live[i], want[i])
+		}
+	}
+}
@@ -0,0 +1,194 @@
+// Copyright 2025 The GoGPU Authors
+// SPDX-License-Identifier: MIT
+
+package ir
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ShareConstantArrays returns a copy of module in which constant arrays
+// built inside functions, such as lookup tables written out in several
+// functions, are replaced by module constants, one per distinct value.
+// An array is shared when all of its elements are literals, zero values
+// or other shared constants and it has at least minLen elements; smaller
+// arrays are cheaper to build in place. An array equal to an existing
+// module constant refers to that constant. New constants take the name
+// the array is bound to where it first appears (a let or var), or
+// "const_array" if it has none. module itself is not modified.
+//
+// Backends then write each table once, as a constant the functions read
+// from or copy into their local variables, instead of building it in
+// every function that uses it.
+func ShareConstantArrays(module *Module, minLen uint32) *Module {
+	dst := CloneModule(module)
+	s := &arraySharer{module: dst, minLen: minLen, byKey: make(map[string]ConstantHandle)}
+	s.globalKeys = make([]string, len(dst.GlobalExpressions))
+	for i := range dst.GlobalExpressions {
+		s.globalKeys[i] = s.globalKey(ExpressionHandle(i))
+	}
+	for i, c := range dst.Constants {
+		if c.Value != nil || c.IsAbstract || int(c.Init) >= len(s.globalKeys) {
+			continue
+		}
+		if key := s.globalKeys[c.Init]; key != "" && s.isArray(c.Type) {
+			if _, ok := s.byKey[key]; !ok {
+				s.byKey[key] = ConstantHandle(i)
+			}
+		}
+	}
+
+	changed := false
+	for i := range dst.Functions {
+		changed = s.shareFunction(&dst.Functions[i]) || changed
+	}
+	for i := range dst.EntryPoints {
+		changed = s.shareFunction(&dst.EntryPoints[i].Function) || changed
+	}
+	if changed {
+		CompactExpressions(dst)
+	}
+	return dst
+}
+
+// arraySharer holds the module constants shared so far, keyed by a
+// canonical spelling of their value.
+type arraySharer struct {
+	module     *Module
+	minLen     uint32
+	byKey      map[string]ConstantHandle
+	globalKeys []string // per global expression; "" if not a plain value
+}
+
+// isArray reports whether ty is a fixed-size array of at least minLen
+// elements.
+func (s *arraySharer) isArray(ty TypeHandle) bool {
+	if int(ty) >= len(s.module.Types) {
+		return false
+	}
+	arr, ok := s.module.Types[ty].Inner.(ArrayType)
+	return ok && arr.Size.Constant != nil && *arr.Size.Constant >= s.minLen
+}
+
+// valueKey spells a literal, zero value or composite given the keys of
+// its components, or returns "" if kind is none of those or a component
+// has no key.
+func (s *arraySharer) valueKey(kind ExpressionKind, component func(ExpressionHandle) string) string {
+	switch k := kind.(type) {
+	case Literal:
+		return fmt.Sprintf("%T(%v)", k.Value, k.Value)
+	case ExprZeroValue:
+		return fmt.Sprintf("zero(%d)", k.Type)
+	case ExprConstant:
+		if int(k.Constant) >= len(s.module.Constants) {
+			return ""
+		}
+		c := s.module.Constants[k.Constant]
+		if c.Value != nil || int(c.Init) >= len(s.globalKeys) {
+			return ""
+		}
+		return s.globalKeys[c.Init]
+	case ExprCompose:
+		var b strings.Builder
+		fmt.Fprintf(&b, "compose(%d", k.Type)
+		for _, h := range k.Components {
+			key := component(h)
+			if key == "" {
+				return ""
+			}
+			b.WriteString(",")
+			b.WriteString(key)
+		}
+		b.WriteString(")")
+		return b.String()
+	}
+	return ""
+}
+
+// globalKey returns the key of a global expression. Operands come before
+// the expressions using them, so their keys are already known.
+func (s *arraySharer) globalKey(h ExpressionHandle) string {
+	return s.valueKey(s.module.GlobalExpressions[h].Kind, func(op ExpressionHandle) string {
+		if op >= h {
+			return ""
+		}
+		return s.globalKeys[op]
+	})
+}
+
+// shareFunction replaces the shareable arrays of fn with module
+// constants and reports whether it changed anything. Arrays are visited
+// from the last expression down, so an array of arrays is shared whole
+// and the inner arrays it no longer needs are left to be compacted away
+// rather than becoming constants of their own.
+func (s *arraySharer) shareFunction(fn *Function) bool {
+	keys := make([]string, len(fn.Expressions))
+	for i := range fn.Expressions {
+		keys[i] = s.valueKey(fn.Expressions[i].Kind, func(op ExpressionHandle) string {
+			if int(op) >= i {
+				return ""
+			}
+			return keys[op]
+		})
+	}
+
+	changed := false
+	live := LiveExpressions(fn)
+	for i := len(fn.Expressions) - 1; i >= 0; i-- {
+		compose, ok := fn.Expressions[i].Kind.(ExprCompose)
+		if !ok || keys[i] == "" || !live[i] || !s.isArray(compose.Type) {
+			continue
+		}
+		c, ok := s.byKey[keys[i]]
+		if !ok {
+			c = ConstantHandle(len(s.module.Constants))
+			s.module.Constants = append(s.module.Constants, Constant{
+				Name: s.arrayName(fn, ExpressionHandle(i)),
+				Type: compose.Type,
+				Init: s.copyToGlobal(fn, ExpressionHandle(i)),
+			})
+			s.byKey[keys[i]] = c
+		}
+		fn.Expressions[i].Kind = ExprConstant{Constant: c}
+		live = LiveExpressions(fn)
+		changed = true
+	}
+	if changed {
+		fn.Body = filterEmitsInBlock(fn.Body, fn.Expressions)
+	}
+	return changed
+}
+
+// arrayName returns the name of the let or var h initializes in fn.
+func (s *arraySharer) arrayName(fn *Function, h ExpressionHandle) string {
+	if name, ok := fn.NamedExpressions[h]; ok && name != "" {
+		return name
+	}
+	for _, v := range fn.LocalVars {
+		if v.Init != nil && *v.Init == h && v.Name != "" {
+			return v.Name
+		}
+	}
+	return "const_array"
+}
+
+// copyToGlobal appends the value fn computes at h to the module's global
+// expressions and returns its handle there.
+func (s *arraySharer) copyToGlobal(fn *Function, h ExpressionHandle) ExpressionHandle {
+	kind := fn.Expressions[h].Kind
+	switch k := kind.(type) {
+	case ExprConstant:
+		return s.module.Constants[k.Constant].Init
+	case ExprCompose:
+		components := make([]ExpressionHandle, len(k.Components))
+		for i, op := range k.Components {
+			components[i] = s.copyToGlobal(fn, op)
+		}
+		kind = ExprCompose{Type: k.Type, Components: components}
+	}
+	g := ExpressionHandle(len(s.module.GlobalExpressions))
+	s.module.GlobalExpressions = append(s.module.GlobalExpressions, Expression{Kind: kind, Span: fn.Expressions[h].Span})
+	s.globalKeys = append(s.globalKeys, s.globalKey(g))
+	return g
+}
@@ -0,0 +1,68 @@
+package ir
+
+// DiagnosticFilterHandle is an index into Module.DiagnosticFilters.
+type DiagnosticFilterHandle uint32
+
+// Severity is the severity a diagnostic filter assigns to its rule.
+type Severity uint8
+
+const (
+	// SeverityOff silences the diagnostic.
+	SeverityOff Severity = iota
+	// SeverityInfo records the diagnostic without reporting it.
+	SeverityInfo
+	// SeverityWarning reports the diagnostic as a warning.
+	SeverityWarning
+	// SeverityError makes the diagnostic a compilation error.
+	SeverityError
+)
+
+// String returns the WGSL spelling of the severity.
+func (s Severity) String() string {
+	switch s {
+	case SeverityOff:
+		return "off"
+	case SeverityInfo:
+		return "info"
+	case SeverityWarning:
+		return "warning"
+	case SeverityError:
+		return "error"
+	default:
+		return "unknown"
+	}
+}
+
+// Standard diagnostic triggering rules.
+const (
+	RuleDerivativeUniformity = "derivative_uniformity"
+	RuleSubgroupUniformity   = "subgroup_uniformity"
+)
+
+// DiagnosticFilter sets the severity of one triggering rule. The rule is
+// a standard rule name (RuleDerivativeUniformity) or any other name the
+// source used, including dotted "vendor.rule" names.
+type DiagnosticFilter struct {
+	NewSeverity    Severity
+	TriggeringRule string
+}
+
+// DiagnosticFilterNode is one node of the diagnostic filter tree.
+type DiagnosticFilterNode struct {
+	Inner  DiagnosticFilter
+	Parent *DiagnosticFilterHandle
+}
+
+// DiagnosticSeverity returns the severity of rule at the scope whose
+// innermost filter is leaf, walking outwards through parent filters.
+// def is returned when no filter in the chain names the rule.
+func (m *Module) DiagnosticSeverity(leaf *DiagnosticFilterHandle, rule string, def Severity) Severity {
+	for h := leaf; h != nil && int(*h) < len(m.DiagnosticFilters); {
+		node := &m.DiagnosticFilters[*h]
+		if node.Inner.TriggeringRule == rule {
+			return node.Inner.NewSeverity
+		}
+		h = node.Parent
+	}
+	return def
+}
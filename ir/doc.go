@@ -14,6 +14,19 @@
 //   - Functions: All function definitions
 //   - EntryPoints: Shader entry points with stage information
 //
+// # Handles and Copies
+//
+// Types, expressions, functions, and statements refer to each other by
+// handle, an index into the owning arena. Copying a Module or Function
+// struct copies none of those arenas, so a pass that rewrites or appends
+// to a copy also changes the original. CloneModule, CloneFunction, and
+// CloneBlock make independent deep copies; RemapExpressionHandles,
+// RemapBlockHandles, and RemapTypeHandles rewrite handles without
+// modifying their input.
+//
+// WalkStatements and WalkExpressions traverse bodies and operand trees for
+// analysis and in-place rewrites.
+//
 // # Translation Pipeline
 //
 // The typical translation pipeline is:
@@ -4,6 +4,23 @@ package ir
 // Expressions follow Single Static Assignment (SSA) form similar to SPIR-V.
 type Expression struct {
 	Kind ExpressionKind
+
+	// Span is the source text the expression was lowered from, when the
+	// frontend records it. Expressions the frontend synthesizes, such as
+	// implicit loads, have the zero Span.
+	Span Span
+}
+
+// Span is a byte range [Start, End) of the source a module was lowered
+// from. The zero Span is unknown.
+type Span struct {
+	Start uint32
+	End   uint32
+}
+
+// IsZero reports whether the span is unknown.
+func (s Span) IsZero() bool {
+	return s == Span{}
 }
 
 // ExpressionKind represents the different kinds of expressions.
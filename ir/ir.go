@@ -53,6 +53,16 @@ type Module struct {
 	// during lowering. Used by ReorderTypes to reorder the type arena
 	// to match Rust naga's dependency-ordered type registration.
 	TypeUseOrder []TypeHandle
+
+	// DiagnosticFilters holds the filters from diagnostic directives and
+	// function @diagnostic attributes as a tree: each node points at the
+	// filter of the enclosing scope. Mirrors Rust naga's
+	// Module.diagnostic_filters arena.
+	DiagnosticFilters []DiagnosticFilterNode
+
+	// DiagnosticFilterLeaf is the innermost module-scope filter (the last
+	// diagnostic directive), or nil if there is none.
+	DiagnosticFilterLeaf *DiagnosticFilterHandle
 }
 
 // SpecialTypes holds handles to compiler-generated types used by backends.
@@ -145,6 +155,11 @@ type EntryPoint struct {
 	EarlyDepthTest *EarlyDepthTest       // For fragment shaders with early depth testing
 	MeshInfo       *MeshStageInfo        // For mesh shaders
 	TaskPayload    *GlobalVariableHandle // For mesh/task shaders referencing task payload variable
+
+	// WorkgroupOverrides names the override that sizes each workgroup
+	// axis, or nil where the size is a constant. Workgroup holds 1 for
+	// such axes until ProcessOverrides folds in the override's value.
+	WorkgroupOverrides [3]*OverrideHandle
 }
 
 // MeshOutputTopology specifies the primitive topology for mesh shader output.
@@ -278,6 +293,14 @@ func (ArrayType) typeInner() {}
 // ArraySize represents array size.
 type ArraySize struct {
 	Constant *uint32 // nil for runtime-sized arrays
+
+	// Override, for a workgroup array sized by an override-expression
+	// (`array<f32, N * 2u>`), is the override holding its length: N
+	// itself, or an unnamed override initialized to the expression.
+	// Constant then holds the length the overrides' default values give,
+	// or 1 if one has no default, until ProcessOverrides folds in the
+	// pipeline's values.
+	Override *OverrideHandle
 }
 
 // StructType represents struct types.
@@ -633,6 +656,11 @@ type Function struct {
 	// producing e.g. "float a = ..." instead of "float _e3 = ...".
 	// Matches Rust naga's Function::named_expressions.
 	NamedExpressions map[ExpressionHandle]string
+
+	// DiagnosticFilterLeaf is the innermost diagnostic filter in scope for
+	// the function body: its last @diagnostic attribute, or the module
+	// leaf when it has none.
+	DiagnosticFilterLeaf *DiagnosticFilterHandle
 }
 
 // FunctionArgument represents a function argument.
@@ -737,6 +765,11 @@ const (
 	SamplingCenter InterpolationSampling = iota
 	SamplingCentroid
 	SamplingSample
+	// SamplingFirst and SamplingEither only go with flat interpolation:
+	// the value of the primitive's first vertex, or of any one of its
+	// vertices. Flat interpolation with SamplingCenter means first.
+	SamplingFirst
+	SamplingEither
 )
 
 // TypeResolution represents the resolved type of an expression.
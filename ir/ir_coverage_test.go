@@ -1,6 +1,7 @@
 package ir
 
 import (
+	"strings"
 	"testing"
 )
 
@@ -274,6 +275,26 @@ func TestValidate_ErrorCases(t *testing.T) {
 		}
 	})
 
+	t.Run("bool_in_storage_buffer", func(t *testing.T) {
+		module := &Module{
+			Types: []Type{
+				{Name: "bool", Inner: ScalarType{Kind: ScalarBool, Width: 1}},
+				{Name: "S", Inner: StructType{Members: []StructMember{{Name: "flag", Type: 0}}, Span: 1}},
+			},
+			GlobalVariables: []GlobalVariable{
+				{Name: "s", Space: SpaceStorage, Binding: &ResourceBinding{}, Type: 1},
+				{Name: "p", Space: SpacePrivate, Type: 1},
+			},
+		}
+		errs, err := Validate(module)
+		if err != nil {
+			t.Fatalf("Validate returned error: %v", err)
+		}
+		if len(errs) != 1 || !strings.Contains(errs[0].Error(), "bool is not host-shareable (at s.flag)") {
+			t.Errorf("got %v, want one host-shareable error for s", errs)
+		}
+	})
+
 	t.Run("valid_minimal_module", func(t *testing.T) {
 		module := &Module{
 			Types: []Type{
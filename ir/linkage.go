@@ -0,0 +1,339 @@
+package ir
+
+import (
+	"fmt"
+	"sort"
+)
+
+// StageIO is an entry point input or output bound to a @location.
+type StageIO struct {
+	EntryPoint    string
+	Location      uint32
+	Type          TypeHandle
+	Interpolation Interpolation
+
+	// Argument is the entry point argument carrying the input; empty for
+	// outputs.
+	Argument string
+
+	// Struct and Member name the struct member carrying the binding. Both
+	// are empty when the binding is on the argument or result itself.
+	Struct string
+	Member string
+}
+
+// StageLinkError is a fragment input that does not match the vertex
+// output at the same location. Vertex is nil when no vertex output uses
+// the location.
+type StageLinkError struct {
+	Message  string
+	Vertex   *StageIO
+	Fragment StageIO
+}
+
+// Error implements the error interface.
+func (e StageLinkError) Error() string {
+	return fmt.Sprintf("%s -> %s: %s", e.vertexName(), e.Fragment.EntryPoint, e.Message)
+}
+
+func (e StageLinkError) vertexName() string {
+	if e.Vertex == nil {
+		return "vertex"
+	}
+	return e.Vertex.EntryPoint
+}
+
+// CheckStageLinkage checks that every @location input of the fragment
+// entry point has a vertex output of the vertex entry point at the same
+// location, with the same type and interpolation. Vertex outputs the
+// fragment stage does not read are allowed. Mismatches are returned in
+// location order; the error reports missing or misstaged entry points.
+func CheckStageLinkage(module *Module, vertex, fragment string) ([]StageLinkError, error) {
+	vs, err := findEntryPoint(module, vertex, StageVertex)
+	if err != nil {
+		return nil, err
+	}
+	fs, err := findEntryPoint(module, fragment, StageFragment)
+	if err != nil {
+		return nil, err
+	}
+
+	outputs := make(map[uint32]StageIO)
+	if r := vs.Function.Result; r != nil {
+		for _, io := range stageLocations(module, vs.Name, "", r.Type, r.Binding) {
+			outputs[io.Location] = io
+		}
+	}
+	var inputs []StageIO
+	for _, arg := range fs.Function.Arguments {
+		inputs = append(inputs, stageLocations(module, fs.Name, arg.Name, arg.Type, arg.Binding)...)
+	}
+	sort.SliceStable(inputs, func(i, j int) bool { return inputs[i].Location < inputs[j].Location })
+
+	var errs []StageLinkError
+	for _, in := range inputs {
+		out, ok := outputs[in.Location]
+		if !ok {
+			errs = append(errs, StageLinkError{
+				Message:  fmt.Sprintf("fragment input @location(%d) has no matching vertex output", in.Location),
+				Fragment: in,
+			})
+			continue
+		}
+		outType, inType := module.Types[out.Type].Inner, module.Types[in.Type].Inner
+		switch {
+		case !sameStageType(outType, inType):
+			errs = append(errs, StageLinkError{
+				Message: fmt.Sprintf("@location(%d) type mismatch: vertex output is %s, fragment input is %s",
+					in.Location, stageTypeName(outType), stageTypeName(inType)),
+				Vertex:   &out,
+				Fragment: in,
+			})
+		case out.Interpolation != in.Interpolation:
+			errs = append(errs, StageLinkError{
+				Message: fmt.Sprintf("@location(%d) interpolation mismatch: vertex output is %s, fragment input is %s",
+					in.Location, interpolationName(out.Interpolation), interpolationName(in.Interpolation)),
+				Vertex:   &out,
+				Fragment: in,
+			})
+		}
+	}
+	return errs, nil
+}
+
+// InterStageVariable is a @location variable an entry point passes to or
+// receives from another stage, and the shader components it takes.
+type InterStageVariable struct {
+	StageIO
+	Components uint32
+}
+
+// EntryPointInterStageComponents returns, in location order, the @location
+// outputs of the vertex entry point or inputs of the fragment entry point
+// at index ep, and the total components they take as WebGPU counts them
+// against maxInterStageShaderComponents: one per scalar or vector
+// component. The total of a fragment entry point also counts one component
+// for each front_facing, sample_index, sample_mask and primitive_index
+// input. Other stages pass nothing between stages.
+func EntryPointInterStageComponents(module *Module, ep int) ([]InterStageVariable, uint32) {
+	entry := &module.EntryPoints[ep]
+	var ios []StageIO
+	var total uint32
+	switch entry.Stage {
+	case StageVertex:
+		if r := entry.Function.Result; r != nil {
+			ios = stageLocations(module, entry.Name, "", r.Type, r.Binding)
+		}
+	case StageFragment:
+		for _, arg := range entry.Function.Arguments {
+			ios = append(ios, stageLocations(module, entry.Name, arg.Name, arg.Type, arg.Binding)...)
+			total += countedBuiltins(module, arg.Type, arg.Binding)
+		}
+	}
+	sort.SliceStable(ios, func(i, j int) bool { return ios[i].Location < ios[j].Location })
+
+	vars := make([]InterStageVariable, len(ios))
+	for i, io := range ios {
+		n := uint32(1)
+		if v, ok := module.Types[io.Type].Inner.(VectorType); ok {
+			n = uint32(v.Size)
+		}
+		vars[i] = InterStageVariable{StageIO: io, Components: n}
+		total += n
+	}
+	return vars, total
+}
+
+// countedBuiltins returns how many of the built-in fragment inputs of an
+// argument, or of its struct members, take an inter-stage component.
+func countedBuiltins(module *Module, ty TypeHandle, binding *Binding) uint32 {
+	counted := func(b *Binding) uint32 {
+		if b == nil {
+			return 0
+		}
+		if bb, ok := (*b).(BuiltinBinding); ok {
+			switch bb.Builtin {
+			case BuiltinFrontFacing, BuiltinSampleIndex, BuiltinSampleMask, BuiltinPrimitiveIndex:
+				return 1
+			}
+		}
+		return 0
+	}
+	if binding != nil {
+		return counted(binding)
+	}
+	st, ok := module.Types[ty].Inner.(StructType)
+	if !ok {
+		return 0
+	}
+	var n uint32
+	for _, m := range st.Members {
+		n += counted(m.Binding)
+	}
+	return n
+}
+
+// findEntryPoint returns the entry point with the given name and stage.
+func findEntryPoint(module *Module, name string, stage ShaderStage) (*EntryPoint, error) {
+	named := false
+	for i := range module.EntryPoints {
+		ep := &module.EntryPoints[i]
+		if ep.Name != name {
+			continue
+		}
+		if ep.Stage == stage {
+			return ep, nil
+		}
+		named = true
+	}
+	if named {
+		return nil, fmt.Errorf("entry point %q is not a %s shader", name, stageName(stage))
+	}
+	return nil, fmt.Errorf("entry point %q not found", name)
+}
+
+// stageLocations lists the @location bindings of an argument or result,
+// looking through a struct type to its members.
+func stageLocations(module *Module, entryPoint, argument string, ty TypeHandle, binding *Binding) []StageIO {
+	if binding != nil {
+		if loc, ok := (*binding).(LocationBinding); ok {
+			return []StageIO{{
+				EntryPoint:    entryPoint,
+				Location:      loc.Location,
+				Type:          ty,
+				Interpolation: effectiveInterpolation(module, ty, loc.Interpolation),
+				Argument:      argument,
+			}}
+		}
+		return nil
+	}
+	st, ok := module.Types[ty].Inner.(StructType)
+	if !ok {
+		return nil
+	}
+	var ios []StageIO
+	for _, m := range st.Members {
+		if m.Binding == nil {
+			continue
+		}
+		if loc, ok := (*m.Binding).(LocationBinding); ok {
+			ios = append(ios, StageIO{
+				EntryPoint:    entryPoint,
+				Location:      loc.Location,
+				Type:          m.Type,
+				Interpolation: effectiveInterpolation(module, m.Type, loc.Interpolation),
+				Argument:      argument,
+				Struct:        module.Types[ty].Name,
+				Member:        m.Name,
+			})
+		}
+	}
+	return ios
+}
+
+// effectiveInterpolation applies the WGSL defaults: integers are flat,
+// floats perspective-correct at the pixel center. Flat interpolation takes
+// the first vertex unless it says either.
+func effectiveInterpolation(module *Module, ty TypeHandle, interp *Interpolation) Interpolation {
+	if interp != nil {
+		if interp.Kind == InterpolationFlat {
+			if interp.Sampling == SamplingEither {
+				return *interp
+			}
+			return Interpolation{Kind: InterpolationFlat}
+		}
+		return *interp
+	}
+	var kind ScalarKind
+	switch t := module.Types[ty].Inner.(type) {
+	case ScalarType:
+		kind = t.Kind
+	case VectorType:
+		kind = t.Scalar.Kind
+	}
+	if kind == ScalarSint || kind == ScalarUint {
+		return Interpolation{Kind: InterpolationFlat}
+	}
+	return Interpolation{Kind: InterpolationPerspective, Sampling: SamplingCenter}
+}
+
+// sameStageType compares inter-stage types, which are scalars and vectors.
+func sameStageType(a, b TypeInner) bool {
+	switch a := a.(type) {
+	case ScalarType:
+		b, ok := b.(ScalarType)
+		return ok && a == b
+	case VectorType:
+		b, ok := b.(VectorType)
+		return ok && a == b
+	default:
+		return false
+	}
+}
+
+// stageTypeName returns the WGSL spelling of an inter-stage type.
+func stageTypeName(inner TypeInner) string {
+	scalar := func(s ScalarType) string {
+		switch s.Kind {
+		case ScalarSint:
+			return fmt.Sprintf("i%d", s.Width*8)
+		case ScalarUint:
+			return fmt.Sprintf("u%d", s.Width*8)
+		case ScalarFloat:
+			return fmt.Sprintf("f%d", s.Width*8)
+		default:
+			return "bool"
+		}
+	}
+	switch t := inner.(type) {
+	case ScalarType:
+		return scalar(t)
+	case VectorType:
+		return fmt.Sprintf("vec%d<%s>", t.Size, scalar(t.Scalar))
+	default:
+		return fmt.Sprintf("%T", inner)
+	}
+}
+
+// interpolationName returns the WGSL @interpolate arguments.
+func interpolationName(interp Interpolation) string {
+	switch interp.Kind {
+	case InterpolationFlat:
+		if interp.Sampling == SamplingEither {
+			return "flat, either"
+		}
+		return "flat"
+	case InterpolationLinear:
+		return "linear, " + samplingName(interp.Sampling)
+	default:
+		return "perspective, " + samplingName(interp.Sampling)
+	}
+}
+
+func samplingName(s InterpolationSampling) string {
+	switch s {
+	case SamplingCentroid:
+		return "centroid"
+	case SamplingSample:
+		return "sample"
+	default:
+		return "center"
+	}
+}
+
+func stageName(stage ShaderStage) string {
+	switch stage {
+	case StageVertex:
+		return "vertex"
+	case StageFragment:
+		return "fragment"
+	case StageCompute:
+		return "compute"
+	case StageTask:
+		return "task"
+	case StageMesh:
+		return "mesh"
+	default:
+		return fmt.Sprintf("stage %d", stage)
+	}
+}
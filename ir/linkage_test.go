@@ -0,0 +1,128 @@
+package ir
+
+import (
+	"strings"
+	"testing"
+)
+
+// linkageModule builds a module with a vertex entry point returning
+// struct VsOut { @location(0) color: vec4<f32>, @location(1) id: u32 }
+// and a fragment entry point taking the given arguments.
+func linkageModule(fsArgs ...FunctionArgument) *Module {
+	vec4f := VectorType{Size: Vec4, Scalar: ScalarType{Kind: ScalarFloat, Width: 4}}
+	loc := func(l uint32) *Binding {
+		var b Binding = LocationBinding{Location: l}
+		return &b
+	}
+	var pos Binding = BuiltinBinding{Builtin: BuiltinPosition}
+	m := &Module{
+		Types: []Type{
+			{Inner: vec4f},
+			{Inner: ScalarType{Kind: ScalarUint, Width: 4}},
+			{Inner: ScalarType{Kind: ScalarFloat, Width: 4}},
+			{Name: "VsOut", Inner: StructType{Members: []StructMember{
+				{Name: "pos", Type: 0, Binding: &pos},
+				{Name: "color", Type: 0, Binding: loc(0)},
+				{Name: "id", Type: 1, Binding: loc(1)},
+			}}},
+		},
+	}
+	m.EntryPoints = []EntryPoint{
+		{Name: "vs", Stage: StageVertex, Function: Function{Result: &FunctionResult{Type: 3}}},
+		{Name: "fs", Stage: StageFragment, Function: Function{Arguments: fsArgs}},
+	}
+	return m
+}
+
+func locationArg(name string, ty TypeHandle, location uint32, interp *Interpolation) FunctionArgument {
+	var b Binding = LocationBinding{Location: location, Interpolation: interp}
+	return FunctionArgument{Name: name, Type: ty, Binding: &b}
+}
+
+func TestCheckStageLinkage(t *testing.T) {
+	tests := []struct {
+		name string
+		args []FunctionArgument
+		want []string
+	}{
+		{"match", []FunctionArgument{locationArg("color", 0, 0, nil), locationArg("id", 1, 1, nil)}, nil},
+		{"subset", []FunctionArgument{locationArg("id", 1, 1, &Interpolation{Kind: InterpolationFlat, Sampling: SamplingCentroid})}, nil},
+		{"explicit default", []FunctionArgument{locationArg("color", 0, 0, &Interpolation{Kind: InterpolationPerspective})}, nil},
+		{"missing", []FunctionArgument{locationArg("uv", 0, 2, nil)}, []string{"@location(2) has no matching vertex output"}},
+		{"type", []FunctionArgument{locationArg("color", 2, 0, nil)}, []string{"vertex output is vec4<f32>, fragment input is f32"}},
+		{"interpolation", []FunctionArgument{locationArg("color", 0, 0, &Interpolation{Kind: InterpolationLinear})}, []string{"vertex output is perspective, center, fragment input is linear, center"}},
+		{"sorted", []FunctionArgument{locationArg("b", 0, 5, nil), locationArg("a", 0, 3, nil)}, []string{"@location(3)", "@location(5)"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			errs, err := CheckStageLinkage(linkageModule(tt.args...), "vs", "fs")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(errs) != len(tt.want) {
+				t.Fatalf("got %d errors %v, want %d", len(errs), errs, len(tt.want))
+			}
+			for i, want := range tt.want {
+				if !strings.Contains(errs[i].Error(), want) {
+					t.Errorf("error %d = %q, want it to mention %q", i, errs[i].Error(), want)
+				}
+			}
+		})
+	}
+}
+
+func TestCheckStageLinkageReportsBothSides(t *testing.T) {
+	errs, err := CheckStageLinkage(linkageModule(locationArg("id", 2, 1, nil)), "vs", "fs")
+	if err != nil || len(errs) != 1 {
+		t.Fatalf("got %v, %v", errs, err)
+	}
+	e := errs[0]
+	if e.Vertex == nil || e.Vertex.Struct != "VsOut" || e.Vertex.Member != "id" || e.Vertex.EntryPoint != "vs" {
+		t.Errorf("vertex side = %+v", e.Vertex)
+	}
+	if e.Fragment.Argument != "id" || e.Fragment.EntryPoint != "fs" || e.Fragment.Struct != "" {
+		t.Errorf("fragment side = %+v", e.Fragment)
+	}
+}
+
+func TestCheckStageLinkageEntryPoints(t *testing.T) {
+	m := linkageModule()
+	if _, err := CheckStageLinkage(m, "nope", "fs"); err == nil || !strings.Contains(err.Error(), "not found") {
+		t.Errorf("missing entry point: got %v", err)
+	}
+	if _, err := CheckStageLinkage(m, "fs", "fs"); err == nil || !strings.Contains(err.Error(), "is not a vertex shader") {
+		t.Errorf("wrong stage: got %v", err)
+	}
+	// Entry points are unique per stage: "fs" names a vertex shader too.
+	m.EntryPoints = append(m.EntryPoints, EntryPoint{Name: "fs", Stage: StageVertex, Function: m.EntryPoints[0].Function})
+	if _, err := CheckStageLinkage(m, "fs", "fs"); err != nil {
+		t.Errorf("same name in both stages: got %v", err)
+	}
+}
+
+func TestEntryPointInterStageComponents(t *testing.T) {
+	var facing Binding = BuiltinBinding{Builtin: BuiltinFrontFacing}
+	var fragPos Binding = BuiltinBinding{Builtin: BuiltinPosition}
+	m := linkageModule(
+		locationArg("id", 1, 1, nil),
+		FunctionArgument{Name: "facing", Type: 1, Binding: &facing},
+		FunctionArgument{Name: "pos", Type: 0, Binding: &fragPos},
+		locationArg("color", 0, 0, nil),
+	)
+	m.EntryPoints = append(m.EntryPoints, EntryPoint{Name: "cs", Stage: StageCompute})
+
+	vars, total := EntryPointInterStageComponents(m, 0)
+	if len(vars) != 2 || vars[0].Member != "color" || vars[0].Components != 4 || vars[1].Member != "id" || vars[1].Components != 1 || total != 5 {
+		t.Errorf("vertex outputs = %+v, %d; want color (4) and id (1), 5", vars, total)
+	}
+
+	// front_facing counts one component; position does not.
+	vars, total = EntryPointInterStageComponents(m, 1)
+	if len(vars) != 2 || vars[0].Argument != "color" || vars[1].Argument != "id" || total != 6 {
+		t.Errorf("fragment inputs = %+v, %d; want color and id in location order, 6", vars, total)
+	}
+
+	if vars, total := EntryPointInterStageComponents(m, 2); len(vars) != 0 || total != 0 {
+		t.Errorf("compute = %+v, %d; want none", vars, total)
+	}
+}
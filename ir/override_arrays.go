@@ -0,0 +1,97 @@
+// Copyright 2025 The GoGPU Authors
+// SPDX-License-Identifier: MIT
+
+package ir
+
+import (
+	"fmt"
+	"math"
+	"slices"
+)
+
+// HasOverrideSizedArrays reports whether module has an array type sized
+// by an override. Backends without specialization constants need the
+// overrides' values to write such arrays.
+func HasOverrideSizedArrays(module *Module) bool {
+	for i := range module.Types {
+		if arr, ok := module.Types[i].Inner.(ArrayType); ok && arr.Size.Override != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// ResolveOverrideArraySizes returns a copy of module whose override-sized
+// arrays are constant-sized, with the length their override takes given
+// constants, as in ProcessOverrides. Only the type arena is copied; module
+// itself is not modified.
+func ResolveOverrideArraySizes(module *Module, constants PipelineConstants) (*Module, error) {
+	resolved, err := resolveOverrideValues(module, constants)
+	if err != nil {
+		return nil, err
+	}
+	dst := *module
+	if err := foldOverrideArraySizes(&dst, resolved); err != nil {
+		return nil, err
+	}
+	return &dst, nil
+}
+
+// SetOverrideArrayDefaults sets the Constant length of each override-sized
+// array to the one the overrides' default values give, or 1 where an
+// override it depends on has no default. The arrays stay override-sized.
+func SetOverrideArrayDefaults(module *Module) {
+	if !HasOverrideSizedArrays(module) {
+		return
+	}
+	// An override without a default is NaN, as is everything computed
+	// from it.
+	resolved := make([]float64, len(module.Overrides))
+	for i := range module.Overrides {
+		val, err := resolveOverrideValue(module, i, nil, resolved)
+		if err != nil {
+			val = math.NaN()
+		}
+		resolved[i] = val
+	}
+	for i := range module.Types {
+		arr, ok := module.Types[i].Inner.(ArrayType)
+		if !ok || arr.Size.Override == nil {
+			continue
+		}
+		n := uint32(1)
+		if val := resolved[*arr.Size.Override]; val >= 1 && val <= math.MaxUint32 {
+			n = uint32(val)
+		}
+		arr.Size.Constant = &n
+		module.Types[i].Inner = arr
+	}
+}
+
+// foldOverrideArraySizes turns the override-sized arrays of module into
+// constant-sized ones with the resolved values of their overrides,
+// replacing module.Types by a copy.
+func foldOverrideArraySizes(module *Module, resolved []float64) error {
+	if !HasOverrideSizedArrays(module) {
+		return nil
+	}
+	module.Types = slices.Clone(module.Types)
+	for i := range module.Types {
+		arr, ok := module.Types[i].Inner.(ArrayType)
+		if !ok || arr.Size.Override == nil {
+			continue
+		}
+		h := *arr.Size.Override
+		val := resolved[h]
+		if !(val >= 1 && val <= math.MaxUint32) {
+			if name := module.Overrides[h].Name; name != "" {
+				return fmt.Errorf("array size override %q is %v, must be a positive integer", name, val)
+			}
+			return fmt.Errorf("override-sized array length is %v, must be a positive integer", val)
+		}
+		n := uint32(val)
+		arr.Size = ArraySize{Constant: &n}
+		module.Types[i].Inner = arr
+	}
+	return nil
+}
@@ -0,0 +1,128 @@
+// Copyright 2025 The GoGPU Authors
+// SPDX-License-Identifier: MIT
+
+package ir
+
+import "testing"
+
+// overrideArrayModule has `override N: u32 = 4u;` and a workgroup array of
+// N * 2u floats, sized by an unnamed override.
+func overrideArrayModule() *Module {
+	nInit, sizeInit := ExpressionHandle(0), ExpressionHandle(3)
+	size := OverrideHandle(1)
+	one := uint32(1)
+	return &Module{
+		Types: []Type{
+			{Inner: ScalarType{Kind: ScalarUint, Width: 4}},
+			{Inner: ScalarType{Kind: ScalarFloat, Width: 4}},
+			{Inner: ArrayType{Base: 1, Size: ArraySize{Constant: &one, Override: &size}, Stride: 4}},
+		},
+		GlobalExpressions: []Expression{
+			{Kind: Literal{Value: LiteralU32(4)}},
+			{Kind: ExprOverride{Override: 0}},
+			{Kind: Literal{Value: LiteralU32(2)}},
+			{Kind: ExprBinary{Op: BinaryMultiply, Left: 1, Right: 2}},
+		},
+		Overrides: []Override{
+			{Name: "N", Ty: 0, Init: &nInit},
+			{Ty: 0, Init: &sizeInit},
+		},
+		GlobalVariables: []GlobalVariable{{Name: "buf", Space: SpaceWorkGroup, Type: 2}},
+	}
+}
+
+func arrayLength(t *testing.T, module *Module) (uint32, bool) {
+	t.Helper()
+	arr := module.Types[2].Inner.(ArrayType)
+	if arr.Size.Constant == nil {
+		t.Fatal("array has no length")
+	}
+	return *arr.Size.Constant, arr.Size.Override != nil
+}
+
+func TestSetOverrideArrayDefaults(t *testing.T) {
+	module := overrideArrayModule()
+	SetOverrideArrayDefaults(module)
+	if n, pending := arrayLength(t, module); n != 8 || !pending {
+		t.Errorf("length %d, override-sized %v; want 8 and still override-sized", n, pending)
+	}
+
+	module = overrideArrayModule()
+	module.Overrides[0].Init = nil
+	SetOverrideArrayDefaults(module)
+	if n, _ := arrayLength(t, module); n != 1 {
+		t.Errorf("length %d without a default for N, want 1", n)
+	}
+}
+
+func TestProcessOverridesFoldsArraySizes(t *testing.T) {
+	module := overrideArrayModule()
+	types := module.Types
+	if err := ProcessOverrides(module, PipelineConstants{"N": 16}); err != nil {
+		t.Fatal(err)
+	}
+	if n, pending := arrayLength(t, module); n != 32 || pending {
+		t.Errorf("length %d, override-sized %v; want 32 and constant-sized", n, pending)
+	}
+	if types[2].Inner.(ArrayType).Size.Override == nil {
+		t.Error("ProcessOverrides modified the shared type arena")
+	}
+
+	if err := ProcessOverrides(overrideArrayModule(), PipelineConstants{"N": 0}); err == nil {
+		t.Error("expected an error for a zero array length")
+	}
+}
+
+func TestResolveOverrideArraySizes(t *testing.T) {
+	module := overrideArrayModule()
+	sized, err := ResolveOverrideArraySizes(module, PipelineConstants{"N": 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n, pending := arrayLength(t, sized); n != 6 || pending {
+		t.Errorf("length %d, override-sized %v; want 6 and constant-sized", n, pending)
+	}
+	if _, pending := arrayLength(t, module); !pending {
+		t.Error("input module modified")
+	}
+	if HasOverrideSizedArrays(sized) || !HasOverrideSizedArrays(module) {
+		t.Error("HasOverrideSizedArrays disagrees with the array types")
+	}
+}
+
+func TestSpecializeArraySizeOverride(t *testing.T) {
+	init := ExpressionHandle(0)
+	n := OverrideHandle(1)
+	one := uint32(1)
+	module := &Module{
+		Types: []Type{
+			{Inner: ScalarType{Kind: ScalarUint, Width: 4}},
+			{Inner: ScalarType{Kind: ScalarFloat, Width: 4}},
+			{Inner: ArrayType{Base: 1, Size: ArraySize{Constant: &one, Override: &n}, Stride: 4}},
+		},
+		GlobalExpressions: []Expression{{Kind: Literal{Value: LiteralU32(4)}}},
+		Overrides: []Override{
+			{Name: "other", Ty: 0},
+			{Name: "N", Ty: 0, Init: &init},
+		},
+	}
+
+	spec, err := Specialize(module, PipelineConstants{"other": 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ov := spec.Types[2].Inner.(ArrayType).Size.Override; ov == nil || spec.Overrides[*ov].Name != "N" {
+		t.Errorf("array size override = %v, want N after renumbering", ov)
+	}
+
+	spec, err = Specialize(module, PipelineConstants{"N": 5})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n, pending := arrayLength(t, spec); n != 5 || pending {
+		t.Errorf("length %d, override-sized %v; want 5 and constant-sized", n, pending)
+	}
+	if _, pending := arrayLength(t, module); !pending {
+		t.Error("input module modified")
+	}
+}
@@ -0,0 +1,282 @@
+// Copyright 2025 The GoGPU Authors
+// SPDX-License-Identifier: MIT
+
+package ir
+
+// PeepholeOptions configures Peephole.
+type PeepholeOptions struct {
+	// FiniteMath also rewrites the float patterns. WGSL lets an
+	// implementation assume no NaN reaches a shader at runtime, but the
+	// min, max, clamp and mix of the backends leave NaN operands undefined
+	// (GLSL.std.450 FMin) or return the other operand (MSL fmin), while
+	// the comparisons and selects they replace are well defined for NaN,
+	// so the float rewrites can change results for NaN inputs. Integer
+	// patterns are exact and always rewritten.
+	FiniteMath bool
+}
+
+// Peephole returns a copy of module in which comparison and select chains
+// are replaced by the built-in function they compute, so backends emit
+// one instruction a driver compiler recognizes instead of a comparison
+// and a select:
+//
+//	select(b, a, a < b)                → min(a, b)    (also <=, > and >=)
+//	select(b, a, a > b)                → max(a, b)
+//	min(max(x, lo), hi)                → clamp(x, lo, hi)
+//	max(min(x, hi), lo)                → clamp(x, lo, hi)
+//	mix(a, b, f32(c))                  → select(a, b, c)
+//	mix(a, b, select(0.0, 1.0, c))     → select(a, b, c)
+//
+// Clamp chains are rewritten only when lo and hi are constants with
+// lo <= hi, since clamp is undefined otherwise. Compared and selected
+// operands must be the same value: the same expression, equal literals,
+// or loads of the same memory with no statement between them. Float
+// patterns, mix included, are rewritten only with options.FiniteMath.
+// Expressions left unused are removed. module itself is not modified.
+func Peephole(module *Module, options PeepholeOptions) *Module {
+	dst := CloneModule(module)
+	changed := false
+	for i := range dst.Functions {
+		changed = peepholeFunction(dst, &dst.Functions[i], options) || changed
+	}
+	for i := range dst.EntryPoints {
+		changed = peepholeFunction(dst, &dst.EntryPoints[i].Function, options) || changed
+	}
+	if changed {
+		CompactExpressions(dst)
+	}
+	return dst
+}
+
+// peephole rewrites the expressions of one function.
+type peephole struct {
+	module  *Module
+	fn      *Function
+	options PeepholeOptions
+
+	// emit numbers the Emit statement evaluating each expression, from 1;
+	// 0 means none does.
+	emit []int
+}
+
+// peepholeFunction rewrites fn in place and reports whether it changed.
+// Expressions only refer to earlier ones, so a single forward pass sees
+// the operands of a clamp chain already rewritten to min and max.
+func peepholeFunction(module *Module, fn *Function, options PeepholeOptions) bool {
+	p := &peephole{module: module, fn: fn, options: options, emit: make([]int, len(fn.Expressions))}
+	n := 0
+	walkStatements(fn.Body, func(kind StatementKind) {
+		if emit, ok := kind.(StmtEmit); ok {
+			n++
+			for h := emit.Range.Start; h < emit.Range.End && int(h) < len(p.emit); h++ {
+				p.emit[h] = n
+			}
+		}
+	})
+
+	changed := false
+	for i := range fn.Expressions {
+		h := ExpressionHandle(i)
+		var kind ExpressionKind
+		var ok bool
+		switch e := fn.Expressions[i].Kind.(type) {
+		case ExprSelect:
+			if kind, ok = p.selectToMinMax(h, e); ok {
+				if clamp, ok := p.clampChain(h, kind.(ExprMath)); ok {
+					kind = clamp
+				}
+			}
+		case ExprMath:
+			switch e.Fun {
+			case MathMin, MathMax:
+				kind, ok = p.clampChain(h, e)
+			case MathMix:
+				kind, ok = p.mixToSelect(h, e)
+			}
+		}
+		if ok {
+			fn.Expressions[i].Kind = kind
+			changed = true
+		}
+	}
+	return changed
+}
+
+// selectToMinMax matches select(b, a, a < b) and its variants.
+func (p *peephole) selectToMinMax(h ExpressionHandle, sel ExprSelect) (ExpressionKind, bool) {
+	if !p.rewritable(h) {
+		return nil, false
+	}
+	cond, ok := p.fn.Expressions[sel.Condition].Kind.(ExprBinary)
+	if !ok {
+		return nil, false
+	}
+	left, right := cond.Left, cond.Right
+	switch cond.Op {
+	case BinaryLess, BinaryLessEqual:
+	case BinaryGreater, BinaryGreaterEqual:
+		left, right = right, left
+	default:
+		return nil, false
+	}
+	// The condition is now left < right (or <=); where both are equal the
+	// select and min or max return the same value.
+	accept, reject := sel.Accept, sel.Reject
+	switch {
+	case p.sameValue(left, accept) && p.sameValue(right, reject):
+		return ExprMath{Fun: MathMin, Arg: accept, Arg1: &reject}, true
+	case p.sameValue(left, reject) && p.sameValue(right, accept):
+		return ExprMath{Fun: MathMax, Arg: accept, Arg1: &reject}, true
+	}
+	return nil, false
+}
+
+// clampChain matches min(max(x, lo), hi) and max(min(x, hi), lo), with
+// the operands of either function in any order.
+func (p *peephole) clampChain(h ExpressionHandle, outer ExprMath) (ExpressionKind, bool) {
+	if outer.Arg1 == nil || !p.rewritable(h) {
+		return nil, false
+	}
+	innerFun := MathMax
+	if outer.Fun == MathMax {
+		innerFun = MathMin
+	}
+	for _, pair := range [][2]ExpressionHandle{{outer.Arg, *outer.Arg1}, {*outer.Arg1, outer.Arg}} {
+		inner, ok := p.fn.Expressions[pair[0]].Kind.(ExprMath)
+		if !ok || inner.Fun != innerFun || inner.Arg1 == nil {
+			continue
+		}
+		outerBound := pair[1]
+		for _, args := range [][2]ExpressionHandle{{inner.Arg, *inner.Arg1}, {*inner.Arg1, inner.Arg}} {
+			x, innerBound := args[0], args[1]
+			lo, hi := innerBound, outerBound
+			if outer.Fun == MathMax {
+				lo, hi = outerBound, innerBound
+			}
+			if p.ordered(lo, hi) {
+				return ExprMath{Fun: MathClamp, Arg: x, Arg1: &lo, Arg2: &hi}, true
+			}
+		}
+	}
+	return nil, false
+}
+
+// mixToSelect matches mix(a, b, t) where t is a boolean converted to
+// float. Mix computes a*(1-t) + b*t, which differs from the select for
+// infinite and NaN operands, so it is a float pattern.
+func (p *peephole) mixToSelect(h ExpressionHandle, mix ExprMath) (ExpressionKind, bool) {
+	if mix.Arg1 == nil || mix.Arg2 == nil || !p.rewritable(h) {
+		return nil, false
+	}
+	var cond ExpressionHandle
+	switch t := p.fn.Expressions[*mix.Arg2].Kind.(type) {
+	case ExprAs:
+		if t.Kind != ScalarFloat || t.Convert == nil || !p.isKind(t.Expr, ScalarBool) {
+			return nil, false
+		}
+		cond = t.Expr
+	case ExprSelect:
+		if !p.isFloat(t.Accept, 1) || !p.isFloat(t.Reject, 0) {
+			return nil, false
+		}
+		cond = t.Condition
+	default:
+		return nil, false
+	}
+	return ExprSelect{Condition: cond, Accept: *mix.Arg1, Reject: mix.Arg}, true
+}
+
+// rewritable reports whether the expression has a kind whose patterns
+// are rewritten: an integer, or a float with FiniteMath.
+func (p *peephole) rewritable(h ExpressionHandle) bool {
+	kind, ok := p.scalarKind(h)
+	if !ok {
+		return false
+	}
+	switch kind {
+	case ScalarSint, ScalarUint:
+		return true
+	case ScalarFloat:
+		return p.options.FiniteMath
+	}
+	return false
+}
+
+// scalarKind returns the scalar kind of a scalar or vector expression.
+func (p *peephole) scalarKind(h ExpressionHandle) (ScalarKind, bool) {
+	res, err := ExpressionType(p.module, p.fn, h)
+	if err != nil {
+		return 0, false
+	}
+	switch t := TypeResInner(p.module, res).(type) {
+	case ScalarType:
+		return t.Kind, true
+	case VectorType:
+		return t.Scalar.Kind, true
+	}
+	return 0, false
+}
+
+func (p *peephole) isKind(h ExpressionHandle, kind ScalarKind) bool {
+	k, ok := p.scalarKind(h)
+	return ok && k == kind
+}
+
+// sameValue reports whether a and b always evaluate to the same value.
+func (p *peephole) sameValue(a, b ExpressionHandle) bool {
+	if a == b {
+		return true
+	}
+	switch x := p.fn.Expressions[a].Kind.(type) {
+	case Literal:
+		y, ok := p.fn.Expressions[b].Kind.(Literal)
+		return ok && x.Value == y.Value
+	case ExprConstant:
+		y, ok := p.fn.Expressions[b].Kind.(ExprConstant)
+		return ok && x.Constant == y.Constant
+	case ExprLoad:
+		// Loads evaluated by the same Emit have no store between them.
+		y, ok := p.fn.Expressions[b].Kind.(ExprLoad)
+		return ok && p.emit[a] != 0 && p.emit[a] == p.emit[b] && samePointer(p.fn, x.Pointer, y.Pointer)
+	}
+	return false
+}
+
+// bound returns the value of a constant scalar, or of a splat of one.
+func (p *peephole) bound(h ExpressionHandle) (LiteralValue, bool) {
+	if splat, ok := p.fn.Expressions[h].Kind.(ExprSplat); ok {
+		h = splat.Value
+	}
+	return knownLiteral(p.module, p.fn, h)
+}
+
+// ordered reports whether lo and hi are constants with lo <= hi.
+func (p *peephole) ordered(lo, hi ExpressionHandle) bool {
+	l, ok := p.bound(lo)
+	if !ok {
+		return false
+	}
+	h, ok := p.bound(hi)
+	if !ok {
+		return false
+	}
+	le, ok := foldBinary(BinaryLessEqual, l, h)
+	return ok && le == LiteralBool(true)
+}
+
+// isFloat reports whether h is the float constant v, or a splat of it.
+func (p *peephole) isFloat(h ExpressionHandle, v float64) bool {
+	lit, ok := p.bound(h)
+	if !ok {
+		return false
+	}
+	switch f := lit.(type) {
+	case LiteralF32:
+		return float64(f) == v
+	case LiteralF64:
+		return float64(f) == v
+	case LiteralF16:
+		return float64(f) == v
+	}
+	return false
+}
@@ -0,0 +1,170 @@
+// Copyright 2025 The GoGPU Authors
+// SPDX-License-Identifier: MIT
+
+package ir
+
+// PositionFlow is the part of a vertex entry point that computes its
+// @builtin(position) output. Backends mark this arithmetic precise (SPIR-V
+// NoContraction, HLSL precise) so that pipelines compiled separately, such
+// as a depth prepass and a main pass, produce bit-identical positions.
+type PositionFlow struct {
+	// Expressions[h] is true when expression h of the entry point function
+	// contributes to the position output.
+	Expressions []bool
+
+	// Functions[h] is true when module function h is called on that path.
+	// The whole function counts as contributing.
+	Functions []bool
+}
+
+// PositionDataflow returns the expressions and functions feeding the
+// position output of the entry point at index ep. Values are followed
+// through operands, local variables (every store to a variable the
+// position path loads), and call results. It returns nil when the entry
+// point has no position output.
+func PositionDataflow(module *Module, ep int) *PositionFlow {
+	entry := &module.EntryPoints[ep]
+	fn := &entry.Function
+	if fn.Result == nil {
+		return nil
+	}
+
+	flow := &PositionFlow{
+		Expressions: make([]bool, len(fn.Expressions)),
+		Functions:   make([]bool, len(module.Functions)),
+	}
+	var work []ExpressionHandle
+	push := func(h ExpressionHandle) {
+		if int(h) < len(flow.Expressions) && !flow.Expressions[h] {
+			flow.Expressions[h] = true
+			work = append(work, h)
+		}
+	}
+
+	member, ok := positionMember(module, fn.Result)
+	if !ok {
+		return nil
+	}
+	walkStatements(fn.Body, func(s StatementKind) {
+		ret, ok := s.(StmtReturn)
+		if !ok || ret.Value == nil {
+			return
+		}
+		// Follow only the position component of a composed result.
+		if member >= 0 {
+			if c, ok := fn.Expressions[*ret.Value].Kind.(ExprCompose); ok && member < len(c.Components) {
+				push(c.Components[member])
+				return
+			}
+		}
+		push(*ret.Value)
+	})
+
+	stores := make(map[uint32][]ExpressionHandle)
+	calls := make(map[ExpressionHandle]StmtCall)
+	walkStatements(fn.Body, func(s StatementKind) {
+		switch st := s.(type) {
+		case StmtStore:
+			if v, ok := rootLocalVariable(fn, st.Pointer); ok {
+				stores[v] = append(stores[v], st.Value)
+			}
+		case StmtCall:
+			if st.Result != nil {
+				calls[*st.Result] = st
+			}
+		}
+	})
+
+	seenLocal := make(map[uint32]bool)
+	for len(work) > 0 {
+		h := work[len(work)-1]
+		work = work[:len(work)-1]
+		kind := fn.Expressions[h].Kind
+		visitExprOperands(kind, push)
+		switch k := kind.(type) {
+		case ExprLoad:
+			v, ok := rootLocalVariable(fn, k.Pointer)
+			if !ok || seenLocal[v] {
+				continue
+			}
+			seenLocal[v] = true
+			if init := fn.LocalVars[v].Init; init != nil {
+				push(*init)
+			}
+			for _, value := range stores[v] {
+				push(value)
+			}
+		case ExprCallResult:
+			if call, ok := calls[h]; ok {
+				markCalledFunction(module, call.Function, flow.Functions)
+				for _, arg := range call.Arguments {
+					push(arg)
+				}
+			}
+		}
+	}
+	return flow
+}
+
+// positionMember reports whether a function result is a position output.
+// member is the struct member holding the position, or -1 when the result
+// itself is the position.
+func positionMember(module *Module, result *FunctionResult) (member int, ok bool) {
+	if result.Binding != nil {
+		b, isBuiltin := (*result.Binding).(BuiltinBinding)
+		return -1, isBuiltin && b.Builtin == BuiltinPosition
+	}
+	st, isStruct := module.Types[result.Type].Inner.(StructType)
+	if !isStruct {
+		return 0, false
+	}
+	for i, m := range st.Members {
+		if m.Binding == nil {
+			continue
+		}
+		if b, isBuiltin := (*m.Binding).(BuiltinBinding); isBuiltin && b.Builtin == BuiltinPosition {
+			return i, true
+		}
+	}
+	return 0, false
+}
+
+// rootLocalVariable follows an access chain to the local variable it
+// indexes into.
+func rootLocalVariable(fn *Function, pointer ExpressionHandle) (uint32, bool) {
+	for int(pointer) < len(fn.Expressions) {
+		switch k := fn.Expressions[pointer].Kind.(type) {
+		case ExprLocalVariable:
+			return k.Variable, true
+		case ExprAccess:
+			pointer = k.Base
+		case ExprAccessIndex:
+			pointer = k.Base
+		default:
+			return 0, false
+		}
+	}
+	return 0, false
+}
+
+// markCalledFunction marks h and every function it calls.
+func markCalledFunction(module *Module, h FunctionHandle, marked []bool) {
+	if int(h) >= len(marked) || marked[h] {
+		return
+	}
+	marked[h] = true
+	walkStatements(module.Functions[h].Body, func(s StatementKind) {
+		if call, ok := s.(StmtCall); ok {
+			markCalledFunction(module, call.Function, marked)
+		}
+	})
+}
+
+// walkStatements calls visit for every statement in block, including
+// statements nested in control flow.
+func walkStatements(block Block, visit func(StatementKind)) {
+	WalkStatements(block, func(stmt *Statement) bool {
+		visit(stmt.Kind)
+		return true
+	}, nil)
+}
@@ -0,0 +1,123 @@
+// Copyright 2025 The GoGPU Authors
+// SPDX-License-Identifier: MIT
+
+package ir
+
+import "testing"
+
+// positionFlowModule builds a vertex entry point returning
+//
+//	VsOut(pos, uv * 2.0)
+//
+// where pos is stored into a local variable from helper(arg0) * 3.0.
+func positionFlowModule() *Module {
+	f32 := ScalarType{Kind: ScalarFloat, Width: 4}
+	var posBinding Binding = BuiltinBinding{Builtin: BuiltinPosition}
+	var uvBinding Binding = LocationBinding{Location: 0}
+	eh := func(h ExpressionHandle) *ExpressionHandle { return &h }
+
+	module := &Module{
+		Types: []Type{
+			{Inner: VectorType{Size: Vec4, Scalar: f32}},
+			{Inner: StructType{Members: []StructMember{
+				{Name: "pos", Type: 0, Binding: &posBinding},
+				{Name: "uv", Type: 0, Binding: &uvBinding, Offset: 16},
+			}, Span: 32}},
+		},
+		Functions: []Function{
+			{Name: "helper", Body: Block{}},
+			{Name: "unrelated", Body: Block{}},
+		},
+	}
+
+	fn := Function{
+		Name:   "vs",
+		Result: &FunctionResult{Type: 1},
+		LocalVars: []LocalVariable{
+			{Name: "p", Type: 0},
+		},
+		Expressions: []Expression{
+			{Kind: ExprFunctionArgument{Index: 0}},                             // 0
+			{Kind: ExprCallResult{Function: 0}},                                // 1
+			{Kind: Literal{Value: LiteralF32(3)}},                              // 2
+			{Kind: ExprBinary{Op: BinaryMultiply, Left: 1, Right: 2}},          // 3
+			{Kind: ExprLocalVariable{Variable: 0}},                             // 4
+			{Kind: ExprLoad{Pointer: 4}},                                       // 5
+			{Kind: ExprFunctionArgument{Index: 1}},                             // 6
+			{Kind: Literal{Value: LiteralF32(2)}},                              // 7
+			{Kind: ExprBinary{Op: BinaryMultiply, Left: 6, Right: 7}},          // 8
+			{Kind: ExprCompose{Type: 1, Components: []ExpressionHandle{5, 8}}}, // 9
+		},
+		Body: Block{
+			{Kind: StmtCall{Function: 0, Arguments: []ExpressionHandle{0}, Result: eh(1)}},
+			{Kind: StmtEmit{Range: Range{Start: 3, End: 4}}},
+			{Kind: StmtStore{Pointer: 4, Value: 3}},
+			{Kind: StmtEmit{Range: Range{Start: 5, End: 10}}},
+			{Kind: StmtReturn{Value: eh(9)}},
+		},
+	}
+	module.EntryPoints = []EntryPoint{{Name: "vs", Stage: StageVertex, Function: fn}}
+	return module
+}
+
+func TestPositionDataflow(t *testing.T) {
+	module := positionFlowModule()
+	flow := PositionDataflow(module, 0)
+	if flow == nil {
+		t.Fatal("PositionDataflow returned nil for a position output")
+	}
+
+	for h, want := range []bool{
+		true,  // arg0 passed to helper
+		true,  // helper result
+		true,  // 3.0
+		true,  // stored value
+		true,  // local variable
+		true,  // load of the local
+		false, // uv argument
+		false, // 2.0
+		false, // uv arithmetic
+		false, // compose itself is not followed, only the position component
+	} {
+		if flow.Expressions[h] != want {
+			t.Errorf("Expressions[%d] = %v, want %v", h, flow.Expressions[h], want)
+		}
+	}
+	if !flow.Functions[0] || flow.Functions[1] {
+		t.Errorf("Functions = %v, want [true false]", flow.Functions)
+	}
+}
+
+func TestPositionDataflowPlainResult(t *testing.T) {
+	module := positionFlowModule()
+	var pos Binding = BuiltinBinding{Builtin: BuiltinPosition}
+	fn := &module.EntryPoints[0].Function
+	fn.Result = &FunctionResult{Type: 0, Binding: &pos}
+	uv := ExpressionHandle(8)
+	fn.Body[len(fn.Body)-1] = Statement{Kind: StmtReturn{Value: &uv}}
+
+	flow := PositionDataflow(module, 0)
+	if flow == nil {
+		t.Fatal("PositionDataflow returned nil")
+	}
+	if !flow.Expressions[8] || !flow.Expressions[6] || flow.Expressions[3] {
+		t.Errorf("Expressions = %v", flow.Expressions)
+	}
+	if flow.Functions[0] {
+		t.Error("helper marked although it does not feed the position")
+	}
+}
+
+func TestPositionDataflowNoPosition(t *testing.T) {
+	module := positionFlowModule()
+	var loc Binding = LocationBinding{Location: 0}
+	module.EntryPoints[0].Function.Result = &FunctionResult{Type: 0, Binding: &loc}
+	if flow := PositionDataflow(module, 0); flow != nil {
+		t.Errorf("got %+v, want nil for a location-only result", flow)
+	}
+
+	module.EntryPoints[0].Function.Result = nil
+	if flow := PositionDataflow(module, 0); flow != nil {
+		t.Errorf("got %+v, want nil without a result", flow)
+	}
+}
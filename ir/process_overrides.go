@@ -5,6 +5,7 @@ package ir
 
 import (
 	"fmt"
+	"math"
 )
 
 // CloneModuleForOverrides creates a deep enough copy of a module for ProcessOverrides
@@ -116,6 +117,8 @@ type PipelineConstants map[string]float64
 // - ExprOverride in global expressions become ExprConstant
 // - ExprOverride in function expressions become Literal with resolved values
 // - Global variable initializers using overrides are evaluated
+// - Override-sized workgroup axes are folded into EntryPoint.Workgroup
+// - Override-sized arrays become constant-sized, in a copy of module.Types
 //
 // Matches Rust naga's back::pipeline_constants::process_overrides.
 func ProcessOverrides(module *Module, constants PipelineConstants) error {
@@ -124,13 +127,31 @@ func ProcessOverrides(module *Module, constants PipelineConstants) error {
 	}
 
 	// Phase 1: Resolve each override to a concrete value
-	resolvedValues := make([]float64, len(module.Overrides))
-	for i := range module.Overrides {
-		val, err := resolveOverrideValue(module, i, constants, resolvedValues)
-		if err != nil {
-			return fmt.Errorf("override %q: %w", module.Overrides[i].Name, err)
+	resolvedValues, err := resolveOverrideValues(module, constants)
+	if err != nil {
+		return err
+	}
+
+	// Phase 1b: Fold override-sized workgroup axes into Workgroup
+	for ei := range module.EntryPoints {
+		ep := &module.EntryPoints[ei]
+		for axis, h := range ep.WorkgroupOverrides {
+			if h == nil {
+				continue
+			}
+			val := resolvedValues[*h]
+			if !(val >= 1 && val <= math.MaxUint32) {
+				return fmt.Errorf("entry point %q: workgroup size override %q is %v, must be a positive integer",
+					ep.Name, module.Overrides[*h].Name, val)
+			}
+			ep.Workgroup[axis] = uint32(val)
+			ep.WorkgroupOverrides[axis] = nil
 		}
-		resolvedValues[i] = val
+	}
+
+	// Phase 1c: Fold override-sized arrays into constant-sized ones
+	if err := foldOverrideArraySizes(module, resolvedValues); err != nil {
+		return err
 	}
 
 	// Phase 2: Create constants for each override and replace ExprOverride
@@ -180,6 +201,19 @@ func ProcessOverrides(module *Module, constants PipelineConstants) error {
 	return nil
 }
 
+// resolveOverrideValues determines the concrete value of every override.
+func resolveOverrideValues(module *Module, constants PipelineConstants) ([]float64, error) {
+	resolved := make([]float64, len(module.Overrides))
+	for i := range module.Overrides {
+		val, err := resolveOverrideValue(module, i, constants, resolved)
+		if err != nil {
+			return nil, fmt.Errorf("override %q: %w", module.Overrides[i].Name, err)
+		}
+		resolved[i] = val
+	}
+	return resolved, nil
+}
+
 // resolveOverrideValue determines the concrete value for an override.
 func resolveOverrideValue(module *Module, idx int, constants PipelineConstants, resolved []float64) (float64, error) {
 	ov := &module.Overrides[idx]
@@ -385,13 +419,8 @@ func rebuildFunctionExpressions(fn *Function, module *Module, overrideToConstant
 	fn.Expressions = newExprs
 
 	// Rebuild ExpressionTypes for new arena
-	fn.ExpressionTypes = make([]TypeResolution, len(newExprs))
-	for i := range newExprs {
-		res, err := ResolveExpressionType(module, fn, ExpressionHandle(i))
-		if err == nil {
-			fn.ExpressionTypes[i] = res
-		}
-	}
+	fn.ExpressionTypes = nil
+	UpdateExpressionTypes(module, fn)
 
 	// Remap ALL handles in function body statements
 	remapBlockHandles(fn.Body, handleMap)
@@ -286,3 +286,43 @@ func TestProcessOverrides_ConstFoldInFunction(t *testing.T) {
 		// It's OK if init is still Binary — GLSL writer can const-eval at write time
 	}
 }
+
+func TestProcessOverrides_WorkgroupSize(t *testing.T) {
+	init0 := ExpressionHandle(0)
+	block := OverrideHandle(0)
+	module := &Module{
+		Types: []Type{
+			{Name: "u32", Inner: ScalarType{Kind: ScalarUint, Width: 4}},
+		},
+		Overrides: []Override{
+			{Name: "block", Ty: 0, Init: &init0},
+		},
+		GlobalExpressions: []Expression{
+			{Kind: Literal{Value: LiteralU32(64)}},
+		},
+		EntryPoints: []EntryPoint{{
+			Name:               "cs",
+			Stage:              StageCompute,
+			Workgroup:          [3]uint32{1, 4, 1},
+			WorkgroupOverrides: [3]*OverrideHandle{&block, nil, nil},
+		}},
+	}
+
+	if err := ProcessOverrides(CloneModuleForOverrides(module), PipelineConstants{"block": 128}); err != nil {
+		t.Fatalf("ProcessOverrides: %v", err)
+	}
+	if ep := module.EntryPoints[0]; ep.Workgroup != [3]uint32{1, 4, 1} || ep.WorkgroupOverrides[0] == nil {
+		t.Errorf("processing a clone modified the original entry point: %+v", ep)
+	}
+
+	if err := ProcessOverrides(module, nil); err != nil {
+		t.Fatalf("ProcessOverrides: %v", err)
+	}
+	ep := module.EntryPoints[0]
+	if ep.Workgroup != [3]uint32{64, 4, 1} {
+		t.Errorf("Workgroup = %v, want [64 4 1]", ep.Workgroup)
+	}
+	if ep.WorkgroupOverrides[0] != nil {
+		t.Error("WorkgroupOverrides not cleared after folding")
+	}
+}
@@ -5,6 +5,87 @@ import "fmt"
 // ResolveExpressionType resolves the type of an expression in a function.
 // Returns a TypeResolution that either references a module type or contains an inline type.
 func ResolveExpressionType(module *Module, fn *Function, handle ExpressionHandle) (TypeResolution, error) {
+	return ResolveExpressionTypeFrom(module, fn, handle, nil)
+}
+
+// ExpressionType returns the type of expression h of fn from the
+// fn.ExpressionTypes table, resolving it only when the table has no entry
+// for h; operands are then also taken from the table. It never modifies fn,
+// so backends may call it on a module they share.
+func ExpressionType(module *Module, fn *Function, h ExpressionHandle) (TypeResolution, error) {
+	if int(h) < len(fn.ExpressionTypes) {
+		if res := fn.ExpressionTypes[h]; res.Handle != nil || res.Value != nil {
+			return res, nil
+		}
+	}
+	return ResolveExpressionTypeFrom(module, fn, h, fn.ExpressionTypes)
+}
+
+// ExpressionTypeTable returns the type of every expression of fn, indexed
+// by handle: fn.ExpressionTypes itself when it has an entry for every
+// expression, as it does after lowering, or otherwise a copy with the
+// missing entries resolved. Entries that do not resolve stay zero. The
+// result may alias fn.ExpressionTypes and must not be modified.
+func ExpressionTypeTable(module *Module, fn *Function) []TypeResolution {
+	if expressionTypesComplete(fn) {
+		return fn.ExpressionTypes
+	}
+	types := make([]TypeResolution, len(fn.Expressions))
+	copy(types, fn.ExpressionTypes)
+	for i := range types {
+		if types[i].Handle != nil || types[i].Value != nil {
+			continue
+		}
+		if res, err := ResolveExpressionTypeFrom(module, fn, ExpressionHandle(i), types); err == nil {
+			types[i] = res
+		}
+	}
+	return types
+}
+
+// UpdateExpressionTypes fills fn.ExpressionTypes with the type of every
+// expression it has no entry for, such as the ones a transform appended.
+// A transform that changes what an expression computes, not only how,
+// must call InvalidateExpressionTypes first so the stale entries, and
+// those of the expressions using it, are resolved again.
+func UpdateExpressionTypes(module *Module, fn *Function) {
+	if !expressionTypesComplete(fn) {
+		fn.ExpressionTypes = ExpressionTypeTable(module, fn)
+	}
+}
+
+// InvalidateExpressionTypes drops the entries of fn.ExpressionTypes from
+// handle from on. Expressions only use expressions with smaller handles,
+// so this covers every expression whose type may depend on the one at
+// from. Later lookups resolve the dropped entries again.
+func InvalidateExpressionTypes(fn *Function, from ExpressionHandle) {
+	if int(from) < len(fn.ExpressionTypes) {
+		fn.ExpressionTypes = fn.ExpressionTypes[:from:from]
+	}
+}
+
+// expressionTypesComplete reports whether fn.ExpressionTypes has an entry
+// for every expression of fn.
+func expressionTypesComplete(fn *Function) bool {
+	if len(fn.ExpressionTypes) < len(fn.Expressions) {
+		return false
+	}
+	for _, res := range fn.ExpressionTypes[:len(fn.Expressions)] {
+		if res.Handle == nil && res.Value == nil {
+			return false
+		}
+	}
+	return true
+}
+
+// ResolveExpressionTypeFrom is like ResolveExpressionType, but takes the
+// type of each operand h with h < len(known) from known[h] instead of
+// resolving it again; a zero known[h] is resolved. ResolveExpressionType
+// walks the whole operand tree of the expression; a frontend that appends
+// the type of every expression to fn.ExpressionTypes as it adds it passes
+// those as known, so resolving each new expression only looks at its
+// direct operands.
+func ResolveExpressionTypeFrom(module *Module, fn *Function, handle ExpressionHandle, known []TypeResolution) (TypeResolution, error) {
 	if int(handle) >= len(fn.Expressions) {
 		return TypeResolution{}, fmt.Errorf("expression handle %d out of range (max %d)", handle, len(fn.Expressions))
 	}
@@ -25,13 +106,13 @@ func ResolveExpressionType(module *Module, fn *Function, handle ExpressionHandle
 		h := kind.Type
 		return TypeResolution{Handle: &h}, nil
 	case ExprAccess:
-		return resolveAccessType(module, fn, kind)
+		return resolveAccessType(module, fn, known, kind)
 	case ExprAccessIndex:
-		return resolveAccessIndexType(module, fn, kind)
+		return resolveAccessIndexType(module, fn, known, kind)
 	case ExprSplat:
-		return resolveSplatType(module, fn, kind)
+		return resolveSplatType(module, fn, known, kind)
 	case ExprSwizzle:
-		return resolveSwizzleType(module, fn, kind)
+		return resolveSwizzleType(module, fn, known, kind)
 	case ExprFunctionArgument:
 		if int(kind.Index) >= len(fn.Arguments) {
 			return TypeResolution{}, fmt.Errorf("function argument index %d out of range", kind.Index)
@@ -59,38 +140,38 @@ func ResolveExpressionType(module *Module, fn *Function, handle ExpressionHandle
 		// Matches Rust naga typifier: LocalVariable -> Pointer { base: var.ty, space: Function }
 		return TypeResolution{Value: PointerType{Base: lv.Type, Space: SpaceFunction}}, nil
 	case ExprLoad:
-		return resolveLoadType(module, fn, kind)
+		return resolveLoadType(module, fn, known, kind)
 	case ExprAlias:
 		// Alias is a transparent passthrough — defer to the source.
 		// Produced by the DXIL mem2reg pass; never seen by other backends.
-		return ResolveExpressionType(module, fn, kind.Source)
+		return resolveOperand(module, fn, known, kind.Source)
 	case ExprPhi:
 		// Phi result type matches every incoming (SSA invariant) — defer
 		// to the first one. Produced by the DXIL mem2reg pass.
 		if len(kind.Incoming) == 0 {
 			return TypeResolution{}, fmt.Errorf("ExprPhi with zero incomings")
 		}
-		return ResolveExpressionType(module, fn, kind.Incoming[0].Value)
+		return resolveOperand(module, fn, known, kind.Incoming[0].Value)
 	case ExprImageSample:
-		return resolveImageSampleType(module, fn, kind)
+		return resolveImageSampleType(module, fn, known, kind)
 	case ExprImageLoad:
-		return resolveImageLoadType(module, fn, kind)
+		return resolveImageLoadType(module, fn, known, kind)
 	case ExprImageQuery:
-		return resolveImageQueryType(module, fn, kind)
+		return resolveImageQueryType(module, fn, known, kind)
 	case ExprUnary:
-		return resolveUnaryType(module, fn, kind)
+		return resolveUnaryType(module, fn, known, kind)
 	case ExprBinary:
-		return resolveBinaryType(module, fn, kind)
+		return resolveBinaryType(module, fn, known, kind)
 	case ExprSelect:
-		return resolveSelectType(module, fn, kind)
+		return resolveSelectType(module, fn, known, kind)
 	case ExprDerivative:
-		return resolveDerivativeType(module, fn, kind)
+		return resolveDerivativeType(module, fn, known, kind)
 	case ExprRelational:
-		return resolveRelationalType(module, fn, kind)
+		return resolveRelationalType(module, fn, known, kind)
 	case ExprMath:
-		return resolveMathType(module, fn, kind)
+		return resolveMathType(module, fn, known, kind)
 	case ExprAs:
-		return resolveAsType(module, fn, kind)
+		return resolveAsType(module, fn, known, kind)
 	case ExprCallResult:
 		if int(kind.Function) >= len(module.Functions) {
 			return TypeResolution{}, fmt.Errorf("function %d out of range", kind.Function)
@@ -130,6 +211,15 @@ func ResolveExpressionType(module *Module, fn *Function, handle ExpressionHandle
 	}
 }
 
+// resolveOperand resolves the type of an operand of an expression being
+// resolved, taking it from known when it is there.
+func resolveOperand(module *Module, fn *Function, known []TypeResolution, handle ExpressionHandle) (TypeResolution, error) {
+	if int(handle) < len(known) && (known[handle].Handle != nil || known[handle].Value != nil) {
+		return known[handle], nil
+	}
+	return ResolveExpressionTypeFrom(module, fn, handle, known)
+}
+
 // ResolveLiteralType resolves the type of a literal expression.
 func ResolveLiteralType(lit Literal) (TypeResolution, error) {
 	return resolveLiteralType(lit)
@@ -180,8 +270,8 @@ func resolveOverrideType(module *Module, expr ExprOverride) (TypeResolution, err
 	return TypeResolution{Handle: &h}, nil
 }
 
-func resolveAccessType(module *Module, fn *Function, expr ExprAccess) (TypeResolution, error) {
-	baseType, err := ResolveExpressionType(module, fn, expr.Base)
+func resolveAccessType(module *Module, fn *Function, known []TypeResolution, expr ExprAccess) (TypeResolution, error) {
+	baseType, err := resolveOperand(module, fn, known, expr.Base)
 	if err != nil {
 		return TypeResolution{}, fmt.Errorf("access base: %w", err)
 	}
@@ -242,8 +332,8 @@ func resolveAccessType(module *Module, fn *Function, expr ExprAccess) (TypeResol
 	}
 }
 
-func resolveAccessIndexType(module *Module, fn *Function, expr ExprAccessIndex) (TypeResolution, error) {
-	baseType, err := ResolveExpressionType(module, fn, expr.Base)
+func resolveAccessIndexType(module *Module, fn *Function, known []TypeResolution, expr ExprAccessIndex) (TypeResolution, error) {
+	baseType, err := resolveOperand(module, fn, known, expr.Base)
 	if err != nil {
 		return TypeResolution{}, fmt.Errorf("access index base: %w", err)
 	}
@@ -316,8 +406,8 @@ func resolveAccessIndexType(module *Module, fn *Function, expr ExprAccessIndex)
 	}
 }
 
-func resolveSplatType(module *Module, fn *Function, expr ExprSplat) (TypeResolution, error) {
-	valueType, err := ResolveExpressionType(module, fn, expr.Value)
+func resolveSplatType(module *Module, fn *Function, known []TypeResolution, expr ExprSplat) (TypeResolution, error) {
+	valueType, err := resolveOperand(module, fn, known, expr.Value)
 	if err != nil {
 		return TypeResolution{}, fmt.Errorf("splat value: %w", err)
 	}
@@ -345,8 +435,8 @@ func resolveSplatType(module *Module, fn *Function, expr ExprSplat) (TypeResolut
 	return TypeResolution{Value: VectorType{Size: expr.Size, Scalar: scalar}}, nil
 }
 
-func resolveSwizzleType(module *Module, fn *Function, expr ExprSwizzle) (TypeResolution, error) {
-	vectorType, err := ResolveExpressionType(module, fn, expr.Vector)
+func resolveSwizzleType(module *Module, fn *Function, known []TypeResolution, expr ExprSwizzle) (TypeResolution, error) {
+	vectorType, err := resolveOperand(module, fn, known, expr.Vector)
 	if err != nil {
 		return TypeResolution{}, fmt.Errorf("swizzle vector: %w", err)
 	}
@@ -375,8 +465,8 @@ func resolveSwizzleType(module *Module, fn *Function, expr ExprSwizzle) (TypeRes
 	return TypeResolution{Value: VectorType{Size: expr.Size, Scalar: vec.Scalar}}, nil
 }
 
-func resolveLoadType(module *Module, fn *Function, expr ExprLoad) (TypeResolution, error) {
-	pointerType, err := ResolveExpressionType(module, fn, expr.Pointer)
+func resolveLoadType(module *Module, fn *Function, known []TypeResolution, expr ExprLoad) (TypeResolution, error) {
+	pointerType, err := resolveOperand(module, fn, known, expr.Pointer)
 	if err != nil {
 		return TypeResolution{}, fmt.Errorf("load pointer: %w", err)
 	}
@@ -429,19 +519,19 @@ func resolveLoadType(module *Module, fn *Function, expr ExprLoad) (TypeResolutio
 	return pointerType, nil
 }
 
-func resolveImageSampleType(module *Module, fn *Function, expr ExprImageSample) (TypeResolution, error) {
+func resolveImageSampleType(module *Module, fn *Function, known []TypeResolution, expr ExprImageSample) (TypeResolution, error) {
 	// Gather operations always return vec4, even for depth textures.
 	// This matches Rust naga typifier: ImageSample with gather: Some(_) -> Vec4.
 	if expr.Gather != nil {
-		return resolveImageGatherType(module, fn, expr.Image)
+		return resolveImageGatherType(module, fn, known, expr.Image)
 	}
-	return resolveImageResultType(module, fn, expr.Image, "image sample")
+	return resolveImageResultType(module, fn, known, expr.Image, "image sample")
 }
 
 // resolveImageGatherType resolves the return type for image gather operations.
 // Gather always returns vec4, with scalar kind matching the image's sampled kind.
-func resolveImageGatherType(module *Module, fn *Function, imageHandle ExpressionHandle) (TypeResolution, error) {
-	imageType, err := ResolveExpressionType(module, fn, imageHandle)
+func resolveImageGatherType(module *Module, fn *Function, known []TypeResolution, imageHandle ExpressionHandle) (TypeResolution, error) {
+	imageType, err := resolveOperand(module, fn, known, imageHandle)
 	if err != nil {
 		return TypeResolution{}, fmt.Errorf("image gather image: %w", err)
 	}
@@ -472,14 +562,14 @@ func resolveImageGatherType(module *Module, fn *Function, imageHandle Expression
 	}}, nil
 }
 
-func resolveImageLoadType(module *Module, fn *Function, expr ExprImageLoad) (TypeResolution, error) {
-	return resolveImageResultType(module, fn, expr.Image, "image load")
+func resolveImageLoadType(module *Module, fn *Function, known []TypeResolution, expr ExprImageLoad) (TypeResolution, error) {
+	return resolveImageResultType(module, fn, known, expr.Image, "image load")
 }
 
 // resolveImageResultType resolves the return type for image sample/load operations.
 // Depth images return scalar f32, sampled/storage images return vec4<f32>.
-func resolveImageResultType(module *Module, fn *Function, imageHandle ExpressionHandle, context string) (TypeResolution, error) {
-	imageType, err := ResolveExpressionType(module, fn, imageHandle)
+func resolveImageResultType(module *Module, fn *Function, known []TypeResolution, imageHandle ExpressionHandle, context string) (TypeResolution, error) {
+	imageType, err := resolveOperand(module, fn, known, imageHandle)
 	if err != nil {
 		return TypeResolution{}, fmt.Errorf("%s image: %w", context, err)
 	}
@@ -506,7 +596,6 @@ func resolveImageResultType(module *Module, fn *Function, imageHandle Expression
 	// Determine the scalar type based on image class.
 	// For storage images, use the format's scalar kind.
 	// For sampled images, use the SampledKind.
-	// Default to float for compatibility (many images don't have SampledKind set correctly).
 	scalarKind := ScalarFloat
 	width := uint8(4)
 	switch img.Class {
@@ -517,14 +606,7 @@ func resolveImageResultType(module *Module, fn *Function, imageHandle Expression
 			width = 8
 		}
 	case ImageClassSampled:
-		// Only use SampledKind if explicitly set (non-zero and non-Sint default).
-		// Many image types share the same type handle and don't have SampledKind set correctly.
-		if img.SampledKind == ScalarUint {
-			scalarKind = ScalarUint
-		} else if img.SampledKind == ScalarFloat {
-			scalarKind = ScalarFloat
-		}
-		// Default to Float for ScalarSint (which is the zero value / default)
+		scalarKind = img.SampledKind
 	}
 
 	return TypeResolution{Value: VectorType{
@@ -533,7 +615,7 @@ func resolveImageResultType(module *Module, fn *Function, imageHandle Expression
 	}}, nil
 }
 
-func resolveImageQueryType(module *Module, fn *Function, expr ExprImageQuery) (TypeResolution, error) {
+func resolveImageQueryType(module *Module, fn *Function, known []TypeResolution, expr ExprImageQuery) (TypeResolution, error) {
 	switch q := expr.Query.(type) {
 	case ImageQuerySize:
 		// Size returns the spatial dimensions only (no array layer count):
@@ -549,7 +631,7 @@ func resolveImageQueryType(module *Module, fn *Function, expr ExprImageQuery) (T
 
 		// Try to resolve the image's dimension from its type.
 		if fn != nil && int(expr.Image) < len(fn.Expressions) {
-			imgType, err := ResolveExpressionType(module, fn, expr.Image)
+			imgType, err := resolveOperand(module, fn, known, expr.Image)
 			if err == nil {
 				var inner TypeInner
 				if imgType.Handle != nil && int(*imgType.Handle) < len(module.Types) {
@@ -591,8 +673,8 @@ func resolveImageQueryType(module *Module, fn *Function, expr ExprImageQuery) (T
 	}
 }
 
-func resolveUnaryType(module *Module, fn *Function, expr ExprUnary) (TypeResolution, error) {
-	operandType, err := ResolveExpressionType(module, fn, expr.Expr)
+func resolveUnaryType(module *Module, fn *Function, known []TypeResolution, expr ExprUnary) (TypeResolution, error) {
+	operandType, err := resolveOperand(module, fn, known, expr.Expr)
 	if err != nil {
 		return TypeResolution{}, fmt.Errorf("unary operand: %w", err)
 	}
@@ -601,8 +683,8 @@ func resolveUnaryType(module *Module, fn *Function, expr ExprUnary) (TypeResolut
 	return operandType, nil
 }
 
-func resolveBinaryType(module *Module, fn *Function, expr ExprBinary) (TypeResolution, error) {
-	leftType, err := ResolveExpressionType(module, fn, expr.Left)
+func resolveBinaryType(module *Module, fn *Function, known []TypeResolution, expr ExprBinary) (TypeResolution, error) {
+	leftType, err := resolveOperand(module, fn, known, expr.Left)
 	if err != nil {
 		return TypeResolution{}, fmt.Errorf("binary left: %w", err)
 	}
@@ -642,7 +724,7 @@ func resolveBinaryType(module *Module, fn *Function, expr ExprBinary) (TypeResol
 		//   matrix * vector → vector(rows)
 		//   vector * matrix → vector(columns)
 		// For same-type multiplication, left type is correct.
-		rightType, rightErr := ResolveExpressionType(module, fn, expr.Right)
+		rightType, rightErr := resolveOperand(module, fn, known, expr.Right)
 		if rightErr != nil {
 			return TypeResolution{}, fmt.Errorf("binary right: %w", rightErr)
 		}
@@ -651,7 +733,7 @@ func resolveBinaryType(module *Module, fn *Function, expr ExprBinary) (TypeResol
 	default:
 		// Arithmetic and bitwise operators: if one side is scalar and the other is vector,
 		// the result is vector (WGSL broadcasts scalar to match vector size).
-		rightType, rightErr := ResolveExpressionType(module, fn, expr.Right)
+		rightType, rightErr := resolveOperand(module, fn, known, expr.Right)
 		if rightErr == nil {
 			leftInner := TypeResInner(module, leftType)
 			rightInner := TypeResInner(module, rightType)
@@ -734,26 +816,26 @@ func TypeResInner(module *Module, res TypeResolution) TypeInner {
 	return res.Value
 }
 
-func resolveSelectType(module *Module, fn *Function, expr ExprSelect) (TypeResolution, error) {
+func resolveSelectType(module *Module, fn *Function, known []TypeResolution, expr ExprSelect) (TypeResolution, error) {
 	// Select returns the type of accept/reject (they must match)
-	acceptType, err := ResolveExpressionType(module, fn, expr.Accept)
+	acceptType, err := resolveOperand(module, fn, known, expr.Accept)
 	if err != nil {
 		return TypeResolution{}, fmt.Errorf("select accept: %w", err)
 	}
 	return acceptType, nil
 }
 
-func resolveDerivativeType(module *Module, fn *Function, expr ExprDerivative) (TypeResolution, error) {
+func resolveDerivativeType(module *Module, fn *Function, known []TypeResolution, expr ExprDerivative) (TypeResolution, error) {
 	// Derivative preserves the expression type
-	exprType, err := ResolveExpressionType(module, fn, expr.Expr)
+	exprType, err := resolveOperand(module, fn, known, expr.Expr)
 	if err != nil {
 		return TypeResolution{}, fmt.Errorf("derivative expr: %w", err)
 	}
 	return exprType, nil
 }
 
-func resolveRelationalType(module *Module, fn *Function, expr ExprRelational) (TypeResolution, error) {
-	argType, err := ResolveExpressionType(module, fn, expr.Argument)
+func resolveRelationalType(module *Module, fn *Function, known []TypeResolution, expr ExprRelational) (TypeResolution, error) {
+	argType, err := resolveOperand(module, fn, known, expr.Argument)
 	if err != nil {
 		return TypeResolution{}, fmt.Errorf("relational argument: %w", err)
 	}
@@ -788,8 +870,8 @@ func resolveRelationalType(module *Module, fn *Function, expr ExprRelational) (T
 	return TypeResolution{Value: ScalarType{Kind: ScalarBool, Width: 1}}, nil
 }
 
-func resolveMathType(module *Module, fn *Function, expr ExprMath) (TypeResolution, error) {
-	argType, err := ResolveExpressionType(module, fn, expr.Arg)
+func resolveMathType(module *Module, fn *Function, known []TypeResolution, expr ExprMath) (TypeResolution, error) {
+	argType, err := resolveOperand(module, fn, known, expr.Arg)
 	if err != nil {
 		return TypeResolution{}, fmt.Errorf("math argument: %w", err)
 	}
@@ -826,7 +908,34 @@ func resolveMathType(module *Module, fn *Function, expr ExprMath) (TypeResolutio
 		return TypeResolution{Value: ScalarType{Kind: ScalarFloat, Width: 4}}, nil
 
 	case MathOuter:
-		// Outer product returns matrix - complex, skip for now
+		// outer(a, b) is a matrix with a column per component of a and a
+		// row per component of b.
+		a, aok := TypeResInner(module, argType).(VectorType)
+		if !aok || expr.Arg1 == nil {
+			return argType, nil
+		}
+		arg1Type, err := resolveOperand(module, fn, known, *expr.Arg1)
+		if err != nil {
+			return TypeResolution{}, fmt.Errorf("math argument 1: %w", err)
+		}
+		b, bok := TypeResInner(module, arg1Type).(VectorType)
+		if !bok {
+			return argType, nil
+		}
+		return TypeResolution{Value: MatrixType{Columns: a.Size, Rows: b.Size, Scalar: a.Scalar}}, nil
+
+	case MathDeterminant:
+		// Determinant returns a scalar of the matrix's component type
+		if m, ok := TypeResInner(module, argType).(MatrixType); ok {
+			return TypeResolution{Value: m.Scalar}, nil
+		}
+		return argType, nil
+
+	case MathTranspose:
+		// Transpose swaps the columns and rows
+		if m, ok := TypeResInner(module, argType).(MatrixType); ok && m.Columns != m.Rows {
+			return TypeResolution{Value: MatrixType{Columns: m.Rows, Rows: m.Columns, Scalar: m.Scalar}}, nil
+		}
 		return argType, nil
 
 	case MathUnpack4xI8:
@@ -967,8 +1076,8 @@ func findNamedType(module *Module, name string) int {
 	return -1
 }
 
-func resolveAsType(module *Module, fn *Function, expr ExprAs) (TypeResolution, error) {
-	exprType, err := ResolveExpressionType(module, fn, expr.Expr)
+func resolveAsType(module *Module, fn *Function, known []TypeResolution, expr ExprAs) (TypeResolution, error) {
+	exprType, err := resolveOperand(module, fn, known, expr.Expr)
 	if err != nil {
 		return TypeResolution{}, fmt.Errorf("as expr: %w", err)
 	}
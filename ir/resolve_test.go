@@ -288,6 +288,30 @@ func TestResolveExpressionType_MathFunctions(t *testing.T) {
 			},
 			wantType: ScalarType{Kind: ScalarFloat, Width: 4},
 		},
+		{
+			name:     "determinant returns scalar",
+			mathFunc: MathDeterminant,
+			argType: MatrixType{
+				Columns: Vec3,
+				Rows:    Vec3,
+				Scalar:  ScalarType{Kind: ScalarFloat, Width: 4},
+			},
+			wantType: ScalarType{Kind: ScalarFloat, Width: 4},
+		},
+		{
+			name:     "transpose swaps columns and rows",
+			mathFunc: MathTranspose,
+			argType: MatrixType{
+				Columns: Vec2,
+				Rows:    Vec4,
+				Scalar:  ScalarType{Kind: ScalarFloat, Width: 4},
+			},
+			wantType: MatrixType{
+				Columns: Vec4,
+				Rows:    Vec2,
+				Scalar:  ScalarType{Kind: ScalarFloat, Width: 4},
+			},
+		},
 	}
 
 	for _, tt := range tests {
@@ -304,7 +328,7 @@ func TestResolveExpressionType_MathFunctions(t *testing.T) {
 				},
 			}
 
-			got, err := ResolveExpressionType(module, fn, 1)
+			got, err := ResolveExpressionTypeFrom(module, fn, 1, fn.ExpressionTypes)
 			if err != nil {
 				t.Fatalf("ResolveExpressionType() error = %v", err)
 			}
@@ -364,6 +388,17 @@ func TestResolveExpressionType_ImageSample(t *testing.T) {
 	if vec.Scalar.Kind != ScalarFloat {
 		t.Errorf("expected ScalarFloat, got %d", vec.Scalar.Kind)
 	}
+
+	// An i32 texture yields i32 texels.
+	module.Types[0].Inner = ImageType{Dim: Dim2D, Class: ImageClassSampled, SampledKind: ScalarSint}
+	fn.ExpressionTypes[1] = TypeResolution{}
+	got, err = ResolveExpressionType(module, fn, 1)
+	if err != nil {
+		t.Fatalf("ResolveExpressionType() error = %v", err)
+	}
+	if vec, ok := got.Value.(VectorType); !ok || vec.Scalar.Kind != ScalarSint {
+		t.Errorf("i32 texture sample = %v, want vec4<i32>", got.Value)
+	}
 }
 
 // Helper function to compare TypeInner values
@@ -382,3 +417,141 @@ func typeInnerEqual(a, b TypeInner) bool {
 		return false
 	}
 }
+
+func TestResolveExpressionTypeFrom(t *testing.T) {
+	f32 := ScalarType{Kind: ScalarFloat, Width: 4}
+	vec2 := TypeHandle(0)
+	module := &Module{Types: []Type{{Inner: VectorType{Size: Vec2, Scalar: f32}}}}
+	fn := &Function{
+		Arguments: []FunctionArgument{{Name: "v", Type: vec2}},
+		Expressions: []Expression{
+			{Kind: ExprFunctionArgument{Index: 0}},
+			{Kind: ExprBinary{Op: BinaryMultiply, Left: 0, Right: 0}},
+			{Kind: ExprAccessIndex{Base: 1, Index: 0}},
+		},
+	}
+
+	walked, err := ResolveExpressionType(module, fn, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if walked.Value != f32 {
+		t.Fatalf("ResolveExpressionType = %+v, want f32", walked)
+	}
+
+	// Operands come from known, not from resolving them again: claiming
+	// the product is a vec4 makes the component a scalar of that vector.
+	u32 := ScalarType{Kind: ScalarUint, Width: 4}
+	known := []TypeResolution{{Handle: &vec2}, {Value: VectorType{Size: Vec4, Scalar: u32}}}
+	got, err := ResolveExpressionTypeFrom(module, fn, 2, known)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Value != u32 {
+		t.Errorf("ResolveExpressionTypeFrom = %+v, want the u32 component of the known type", got)
+	}
+
+	// A zero known entry is resolved.
+	got, err = ResolveExpressionTypeFrom(module, fn, 2, []TypeResolution{{}, {}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Value != f32 {
+		t.Errorf("ResolveExpressionTypeFrom with zero known = %+v, want f32", got)
+	}
+}
+
+// addChain returns a function computing v + v + ... over a vec4<f32>
+// argument, n additions long, with no ExpressionTypes table.
+func addChain(n int) (*Module, *Function) {
+	module := &Module{Types: []Type{{Inner: VectorType{Size: Vec4, Scalar: ScalarType{Kind: ScalarFloat, Width: 4}}}}}
+	fn := &Function{
+		Arguments:   []FunctionArgument{{Name: "v", Type: 0}},
+		Expressions: []Expression{{Kind: ExprFunctionArgument{Index: 0}}},
+	}
+	for j := 1; j <= n; j++ {
+		fn.Expressions = append(fn.Expressions, Expression{Kind: ExprBinary{
+			Op: BinaryAdd, Left: ExpressionHandle(j - 1), Right: 0,
+		}})
+	}
+	return module, fn
+}
+
+func TestExpressionTypeUsesTable(t *testing.T) {
+	module, fn := addChain(2)
+	u32 := ScalarType{Kind: ScalarUint, Width: 4}
+	fn.ExpressionTypes = []TypeResolution{{Value: u32}}
+
+	// An entry is returned as is, even one a resolution would disagree with.
+	res, err := ExpressionType(module, fn, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Value != u32 {
+		t.Errorf("ExpressionType(0) = %+v, want the table entry", res)
+	}
+
+	// A missing entry is resolved from the table entries of its operands.
+	res, err = ExpressionType(module, fn, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Value != u32 {
+		t.Errorf("ExpressionType(1) = %+v, want the operand's table type", res)
+	}
+	if len(fn.ExpressionTypes) != 1 {
+		t.Errorf("ExpressionType modified the table: len %d", len(fn.ExpressionTypes))
+	}
+}
+
+func TestExpressionTypeTable(t *testing.T) {
+	module, fn := addChain(3)
+
+	types := ExpressionTypeTable(module, fn)
+	if len(types) != len(fn.Expressions) {
+		t.Fatalf("len = %d, want %d", len(types), len(fn.Expressions))
+	}
+	for h, res := range types {
+		if res.Handle == nil || *res.Handle != 0 {
+			t.Errorf("types[%d] = %+v, want type 0", h, res)
+		}
+	}
+	if fn.ExpressionTypes != nil {
+		t.Error("ExpressionTypeTable filled fn.ExpressionTypes")
+	}
+
+	// A complete table is returned without copying.
+	fn.ExpressionTypes = types
+	if got := ExpressionTypeTable(module, fn); &got[0] != &types[0] {
+		t.Error("complete table was copied")
+	}
+}
+
+func TestUpdateAndInvalidateExpressionTypes(t *testing.T) {
+	module, fn := addChain(3)
+	UpdateExpressionTypes(module, fn)
+	if len(fn.ExpressionTypes) != 4 {
+		t.Fatalf("len = %d after update, want 4", len(fn.ExpressionTypes))
+	}
+
+	// Retype the argument: the entries from it on are stale until
+	// invalidated.
+	module.Types = append(module.Types, Type{Inner: VectorType{Size: Vec2, Scalar: ScalarType{Kind: ScalarFloat, Width: 4}}})
+	fn.Arguments[0].Type = 1
+	InvalidateExpressionTypes(fn, 0)
+	if len(fn.ExpressionTypes) != 0 {
+		t.Fatalf("len = %d after invalidation, want 0", len(fn.ExpressionTypes))
+	}
+	UpdateExpressionTypes(module, fn)
+	for h, res := range fn.ExpressionTypes {
+		if res.Handle == nil || *res.Handle != 1 {
+			t.Errorf("types[%d] = %+v, want type 1", h, res)
+		}
+	}
+
+	// Invalidating past the end keeps the table.
+	InvalidateExpressionTypes(fn, 10)
+	if len(fn.ExpressionTypes) != 4 {
+		t.Errorf("len = %d, want 4", len(fn.ExpressionTypes))
+	}
+}
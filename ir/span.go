@@ -0,0 +1,50 @@
+// Copyright 2025 The GoGPU Authors
+// SPDX-License-Identifier: MIT
+
+package ir
+
+// OutputSpan maps a position in generated code to the source span it was
+// generated from. Text backends number positions by 1-based output line,
+// the SPIR-V backend by 0-based instruction index after the header.
+type OutputSpan struct {
+	Output int
+	Span   Span
+}
+
+// StatementSpan returns the source span of a statement of fn. Statements
+// carry no span of their own, so it is taken from their expressions: the
+// range of source an Emit evaluates, or, for any other statement, the
+// operand that starts last in the source, which is the one written
+// closest to the statement. It returns the zero Span when no expression
+// involved has one.
+func StatementSpan(fn *Function, stmt StatementKind) Span {
+	if emit, ok := stmt.(StmtEmit); ok {
+		var span Span
+		for h := emit.Range.Start; h < emit.Range.End && int(h) < len(fn.Expressions); h++ {
+			span = span.union(fn.Expressions[h].Span)
+		}
+		return span
+	}
+	var span Span
+	StatementOperands(stmt, func(h ExpressionHandle) {
+		if int(h) >= len(fn.Expressions) {
+			return
+		}
+		if s := fn.Expressions[h].Span; !s.IsZero() && (span.IsZero() || s.Start > span.Start) {
+			span = s
+		}
+	})
+	return span
+}
+
+// union returns the smallest span covering s and o. The zero Span is
+// unknown, so it covers nothing.
+func (s Span) union(o Span) Span {
+	switch {
+	case o.IsZero():
+		return s
+	case s.IsZero():
+		return o
+	}
+	return Span{Start: min(s.Start, o.Start), End: max(s.End, o.End)}
+}
@@ -0,0 +1,34 @@
+// Copyright 2025 The GoGPU Authors
+// SPDX-License-Identifier: MIT
+
+package ir
+
+import "testing"
+
+func TestStatementSpan(t *testing.T) {
+	result := ExpressionHandle(2)
+	fn := &Function{
+		Expressions: []Expression{
+			{Kind: ExprFunctionArgument{Index: 0}, Span: Span{Start: 20, End: 21}},
+			{Kind: ExprBinary{Op: BinaryAdd, Left: 0, Right: 0}, Span: Span{Start: 10, End: 15}},
+			{Kind: ExprBinary{Op: BinaryMultiply, Left: 1, Right: 0}, Span: Span{Start: 8, End: 18}},
+			{Kind: Literal{Value: LiteralU32(1)}},
+		},
+	}
+	tests := []struct {
+		name string
+		stmt StatementKind
+		want Span
+	}{
+		{"emit covers its range", StmtEmit{Range: Range{Start: 1, End: 3}}, Span{Start: 8, End: 18}},
+		{"emit of unknown spans", StmtEmit{Range: Range{Start: 3, End: 4}}, Span{}},
+		{"return value", StmtReturn{Value: &result}, Span{Start: 8, End: 18}},
+		{"operand starting last", StmtStore{Pointer: 1, Value: 0}, Span{Start: 20, End: 21}},
+		{"no operands", StmtBreak{}, Span{}},
+	}
+	for _, tt := range tests {
+		if got := StatementSpan(fn, tt.stmt); got != tt.want {
+			t.Errorf("%s: StatementSpan = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
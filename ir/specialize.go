@@ -0,0 +1,644 @@
+// Copyright 2025 The GoGPU Authors
+// SPDX-License-Identifier: MIT
+
+package ir
+
+import (
+	"cmp"
+	"fmt"
+	"math"
+	"slices"
+	"strconv"
+)
+
+// Specialize returns a copy of module with selected overrides fixed to
+// values, for building shader variants (with and without shadows, say)
+// from one uber-shader. Keys name an override by name or @id as in
+// PipelineConstants; booleans use 1 and 0. Fixed overrides become
+// constants and leave module.Overrides.
+//
+// Scalar arithmetic, comparisons and selects on fixed values are folded,
+// if statements whose condition folds are replaced by the branch taken,
+// and expressions, functions and globals no entry point still reaches are
+// removed. module itself is not modified.
+//
+// Constants cannot be fixed here: lowering folds them into every
+// const-expression that reads them, in function bodies too, so a new
+// value has to be given when the module is lowered, with
+// wgsl.LowerOptions.Constants.
+func Specialize(module *Module, values PipelineConstants) (*Module, error) {
+	overrides, err := specializationTargets(module, values)
+	if err != nil {
+		return nil, err
+	}
+
+	dst := CloneModuleForOverrides(module)
+	for i := range dst.Functions {
+		dst.Functions[i].Body = CloneBlock(dst.Functions[i].Body)
+	}
+	for i := range dst.EntryPoints {
+		dst.EntryPoints[i].Function.Body = CloneBlock(dst.EntryPoints[i].Function.Body)
+	}
+
+	if err := fixOverrides(dst, overrides); err != nil {
+		return nil, err
+	}
+	for i := range dst.Functions {
+		specializeFunction(dst, &dst.Functions[i])
+	}
+	for i := range dst.EntryPoints {
+		specializeFunction(dst, &dst.EntryPoints[i].Function)
+	}
+	CompactExpressions(dst)
+	CompactUnused(dst)
+	return dst, nil
+}
+
+// specializationTargets matches the keys of values to overrides.
+func specializationTargets(module *Module, values PipelineConstants) (map[OverrideHandle]float64, error) {
+	overrides := make(map[OverrideHandle]float64)
+	for key, val := range values {
+		matched := false
+		for i, ov := range module.Overrides {
+			if ov.Name == key || (ov.ID != nil && strconv.Itoa(int(*ov.ID)) == key) {
+				overrides[OverrideHandle(i)] = val
+				matched = true
+			}
+		}
+		if matched {
+			continue
+		}
+		for _, c := range module.Constants {
+			if c.Name == key {
+				return nil, fmt.Errorf("specialize: %q is a constant, which lowering has already folded; set it with wgsl.LowerOptions.Constants", key)
+			}
+		}
+		return nil, fmt.Errorf("specialize: no override named %q", key)
+	}
+	return overrides, nil
+}
+
+// fixOverrides turns the overrides in fixed into constants and removes them
+// from module.Overrides, renumbering the rest. Workgroup sizes and array
+// lengths they set become constant.
+func fixOverrides(module *Module, fixed map[OverrideHandle]float64) error {
+	if len(fixed) == 0 {
+		return nil
+	}
+
+	toConstant := make(map[OverrideHandle]ConstantHandle, len(fixed))
+	for h, val := range fixed {
+		ov := module.Overrides[h]
+		init := ExpressionHandle(len(module.GlobalExpressions))
+		lit := makeOverrideLiteral(module, ov.Ty, val)
+		module.GlobalExpressions = append(module.GlobalExpressions, Expression{Kind: lit})
+		toConstant[h] = ConstantHandle(len(module.Constants))
+		module.Constants = append(module.Constants, Constant{
+			Name:  ov.Name,
+			Type:  ov.Ty,
+			Value: literalScalarValue(lit.Value),
+			Init:  init,
+		})
+	}
+
+	remap := make([]OverrideHandle, len(module.Overrides))
+	kept := make([]Override, 0, len(module.Overrides)-len(fixed))
+	for i, ov := range module.Overrides {
+		if _, ok := fixed[OverrideHandle(i)]; !ok {
+			remap[i] = OverrideHandle(len(kept))
+			kept = append(kept, ov)
+		}
+	}
+	module.Overrides = kept
+
+	replace := func(exprs []Expression) {
+		for i := range exprs {
+			eo, ok := exprs[i].Kind.(ExprOverride)
+			if !ok {
+				continue
+			}
+			if ch, ok := toConstant[eo.Override]; ok {
+				exprs[i].Kind = ExprConstant{Constant: ch}
+			} else {
+				exprs[i].Kind = ExprOverride{Override: remap[eo.Override]}
+			}
+		}
+	}
+	replace(module.GlobalExpressions)
+	for i := range module.Functions {
+		replace(module.Functions[i].Expressions)
+	}
+	for i := range module.EntryPoints {
+		ep := &module.EntryPoints[i]
+		replace(ep.Function.Expressions)
+		for axis, h := range ep.WorkgroupOverrides {
+			if h == nil {
+				continue
+			}
+			val, ok := fixed[*h]
+			if !ok {
+				nh := remap[*h]
+				ep.WorkgroupOverrides[axis] = &nh
+				continue
+			}
+			if !(val >= 1 && val <= math.MaxUint32) {
+				return fmt.Errorf("specialize: entry point %q: workgroup size %q is %v, must be a positive integer",
+					ep.Name, module.Constants[toConstant[*h]].Name, val)
+			}
+			ep.Workgroup[axis] = uint32(val)
+			ep.WorkgroupOverrides[axis] = nil
+		}
+	}
+
+	if !HasOverrideSizedArrays(module) {
+		return nil
+	}
+	module.Types = slices.Clone(module.Types)
+	for i := range module.Types {
+		arr, ok := module.Types[i].Inner.(ArrayType)
+		if !ok || arr.Size.Override == nil {
+			continue
+		}
+		val, ok := fixed[*arr.Size.Override]
+		if !ok {
+			nh := remap[*arr.Size.Override]
+			arr.Size.Override = &nh
+		} else {
+			if !(val >= 1 && val <= math.MaxUint32) {
+				return fmt.Errorf("specialize: array size %q is %v, must be a positive integer",
+					module.Constants[toConstant[*arr.Size.Override]].Name, val)
+			}
+			n := uint32(val)
+			arr.Size = ArraySize{Constant: &n}
+		}
+		module.Types[i].Inner = arr
+	}
+	return nil
+}
+
+// specializeFunction folds fn's expressions on fixed values and prunes the
+// if statements they decide.
+func specializeFunction(module *Module, fn *Function) {
+	for i := range fn.Expressions {
+		if lit, ok := foldExpression(module, fn, fn.Expressions[i].Kind); ok {
+			fn.Expressions[i].Kind = Literal{Value: lit}
+		}
+	}
+	fn.Body = pruneBlock(module, fn, fn.Body)
+	fn.Body = filterEmitsInBlock(fn.Body, fn.Expressions)
+}
+
+// foldExpression evaluates a scalar Binary, Unary or Select whose operands
+// are already known.
+func foldExpression(module *Module, fn *Function, kind ExpressionKind) (LiteralValue, bool) {
+	switch k := kind.(type) {
+	case ExprBinary:
+		left, ok := knownLiteral(module, fn, k.Left)
+		if !ok {
+			return nil, false
+		}
+		right, ok := knownLiteral(module, fn, k.Right)
+		if !ok {
+			return nil, false
+		}
+		return foldBinary(k.Op, left, right)
+	case ExprUnary:
+		val, ok := knownLiteral(module, fn, k.Expr)
+		if !ok {
+			return nil, false
+		}
+		return foldUnary(k.Op, val)
+	case ExprSelect:
+		cond, ok := knownLiteral(module, fn, k.Condition)
+		if !ok {
+			return nil, false
+		}
+		b, ok := cond.(LiteralBool)
+		if !ok {
+			return nil, false
+		}
+		if b {
+			return knownLiteral(module, fn, k.Accept)
+		}
+		return knownLiteral(module, fn, k.Reject)
+	}
+	return nil, false
+}
+
+// knownLiteral returns the value of a literal or scalar constant
+// expression.
+func knownLiteral(module *Module, fn *Function, h ExpressionHandle) (LiteralValue, bool) {
+	if int(h) >= len(fn.Expressions) {
+		return nil, false
+	}
+	switch k := fn.Expressions[h].Kind.(type) {
+	case Literal:
+		return k.Value, true
+	case ExprConstant:
+		return constantLiteral(module, k.Constant)
+	}
+	return nil, false
+}
+
+// constantLiteral returns the value of a scalar constant.
+func constantLiteral(module *Module, h ConstantHandle) (LiteralValue, bool) {
+	if int(h) >= len(module.Constants) {
+		return nil, false
+	}
+	init := module.Constants[h].Init
+	if int(init) < len(module.GlobalExpressions) {
+		if lit, ok := module.GlobalExpressions[init].Kind.(Literal); ok {
+			return lit.Value, true
+		}
+	}
+	return nil, false
+}
+
+// FoldBinary evaluates op on two scalar literals the way Specialize folds
+// it. It reports false when the operands differ in type, when op does not
+// apply to them, and for integer division by zero or overflow.
+func FoldBinary(op BinaryOperator, left, right LiteralValue) (LiteralValue, bool) {
+	return foldBinary(op, left, right)
+}
+
+// FoldUnary evaluates op on a scalar literal the way Specialize folds it.
+// It reports false when op does not apply to the literal.
+func FoldUnary(op UnaryOperator, val LiteralValue) (LiteralValue, bool) {
+	return foldUnary(op, val)
+}
+
+// FoldConvert converts a scalar literal to the scalar type of kind and
+// width, as WGSL value conversions do: floats convert to integers by
+// truncating toward zero, saturating at the bounds of the integer type.
+// It reports false for a kind and width that is not a scalar type.
+func FoldConvert(val LiteralValue, kind ScalarKind, width uint8) (LiteralValue, bool) {
+	return foldConvert(val, kind, width)
+}
+
+// FoldBitcast reinterprets the bits of a 32- or 64-bit scalar literal as
+// kind, keeping its width. It reports false for other literals.
+func FoldBitcast(val LiteralValue, kind ScalarKind) (LiteralValue, bool) {
+	return foldBitcast(val, kind)
+}
+
+func foldBinary(op BinaryOperator, left, right LiteralValue) (LiteralValue, bool) {
+	switch l := left.(type) {
+	case LiteralBool:
+		r, ok := right.(LiteralBool)
+		if !ok {
+			return nil, false
+		}
+		switch op {
+		case BinaryEqual:
+			return LiteralBool(l == r), true
+		case BinaryNotEqual, BinaryExclusiveOr:
+			return LiteralBool(l != r), true
+		case BinaryLogicalAnd, BinaryAnd:
+			return l && r, true
+		case BinaryLogicalOr, BinaryInclusiveOr:
+			return l || r, true
+		}
+	case LiteralI32:
+		if op == BinaryShiftLeft || op == BinaryShiftRight {
+			if r, ok := right.(LiteralU32); ok && r < 32 {
+				return foldShift(op, l, uint(r))
+			}
+			return nil, false
+		}
+		r, ok := right.(LiteralI32)
+		if !ok {
+			return nil, false
+		}
+		if (op == BinaryDivide || op == BinaryModulo) && (r == 0 || l == math.MinInt32 && r == -1) {
+			return nil, false
+		}
+		return foldInteger(op, l, r)
+	case LiteralU32:
+		if op == BinaryShiftLeft || op == BinaryShiftRight {
+			if r, ok := right.(LiteralU32); ok && r < 32 {
+				return foldShift(op, l, uint(r))
+			}
+			return nil, false
+		}
+		r, ok := right.(LiteralU32)
+		if !ok || (op == BinaryDivide || op == BinaryModulo) && r == 0 {
+			return nil, false
+		}
+		return foldInteger(op, l, r)
+	case LiteralF32:
+		if r, ok := right.(LiteralF32); ok {
+			return foldFloat(op, l, r)
+		}
+	case LiteralF64:
+		if r, ok := right.(LiteralF64); ok {
+			return foldFloat(op, l, r)
+		}
+	}
+	return nil, false
+}
+
+func foldInteger[T LiteralI32 | LiteralU32](op BinaryOperator, l, r T) (LiteralValue, bool) {
+	switch op {
+	case BinaryAdd:
+		return any(l + r).(LiteralValue), true
+	case BinarySubtract:
+		return any(l - r).(LiteralValue), true
+	case BinaryMultiply:
+		return any(l * r).(LiteralValue), true
+	case BinaryDivide:
+		return any(l / r).(LiteralValue), true
+	case BinaryModulo:
+		return any(l % r).(LiteralValue), true
+	case BinaryAnd:
+		return any(l & r).(LiteralValue), true
+	case BinaryInclusiveOr:
+		return any(l | r).(LiteralValue), true
+	case BinaryExclusiveOr:
+		return any(l ^ r).(LiteralValue), true
+	}
+	return foldComparison(op, l, r)
+}
+
+func foldShift[T LiteralI32 | LiteralU32](op BinaryOperator, l T, r uint) (LiteralValue, bool) {
+	if op == BinaryShiftLeft {
+		return any(l << r).(LiteralValue), true
+	}
+	return any(l >> r).(LiteralValue), true
+}
+
+func foldFloat[T LiteralF32 | LiteralF64](op BinaryOperator, l, r T) (LiteralValue, bool) {
+	switch op {
+	case BinaryAdd:
+		return any(l + r).(LiteralValue), true
+	case BinarySubtract:
+		return any(l - r).(LiteralValue), true
+	case BinaryMultiply:
+		return any(l * r).(LiteralValue), true
+	case BinaryDivide:
+		return any(l / r).(LiteralValue), true
+	case BinaryModulo:
+		return any(T(math.Mod(float64(l), float64(r)))).(LiteralValue), true
+	}
+	return foldComparison(op, l, r)
+}
+
+func foldComparison[T cmp.Ordered](op BinaryOperator, l, r T) (LiteralValue, bool) {
+	switch op {
+	case BinaryEqual:
+		return LiteralBool(l == r), true
+	case BinaryNotEqual:
+		return LiteralBool(l != r), true
+	case BinaryLess:
+		return LiteralBool(l < r), true
+	case BinaryLessEqual:
+		return LiteralBool(l <= r), true
+	case BinaryGreater:
+		return LiteralBool(l > r), true
+	case BinaryGreaterEqual:
+		return LiteralBool(l >= r), true
+	}
+	return nil, false
+}
+
+func foldUnary(op UnaryOperator, val LiteralValue) (LiteralValue, bool) {
+	switch v := val.(type) {
+	case LiteralBool:
+		if op == UnaryLogicalNot {
+			return !v, true
+		}
+	case LiteralI32:
+		switch op {
+		case UnaryNegate:
+			return -v, true
+		case UnaryBitwiseNot:
+			return ^v, true
+		}
+	case LiteralU32:
+		if op == UnaryBitwiseNot {
+			return ^v, true
+		}
+	case LiteralF32:
+		if op == UnaryNegate {
+			return -v, true
+		}
+	case LiteralF64:
+		if op == UnaryNegate {
+			return -v, true
+		}
+	}
+	return nil, false
+}
+
+func foldConvert(val LiteralValue, kind ScalarKind, width uint8) (LiteralValue, bool) {
+	var i int64
+	var f float64
+	isInt := true
+	switch v := val.(type) {
+	case LiteralBool:
+		if v {
+			i = 1
+		}
+		f = float64(i)
+	case LiteralU64:
+		if kind == ScalarUint && width == 8 {
+			return v, true
+		}
+		i, f = int64(min(v, math.MaxInt64)), float64(v)
+	case LiteralF16:
+		f, isInt = float64(v), false
+	case LiteralF32:
+		f, isInt = float64(v), false
+	case LiteralF64:
+		f, isInt = float64(v), false
+	case LiteralAbstractFloat:
+		f, isInt = float64(v), false
+	case LiteralI32:
+		i, f = int64(v), float64(v)
+	case LiteralU32:
+		i, f = int64(v), float64(v)
+	case LiteralI64:
+		i, f = int64(v), float64(v)
+	case LiteralAbstractInt:
+		i, f = int64(v), float64(v)
+	default:
+		return nil, false
+	}
+
+	switch {
+	case kind == ScalarBool:
+		return LiteralBool(f != 0), true
+	case kind == ScalarFloat && width == 2:
+		return LiteralF16(f), true
+	case kind == ScalarFloat && width == 4:
+		return LiteralF32(f), true
+	case kind == ScalarFloat && width == 8:
+		return LiteralF64(f), true
+	case kind == ScalarAbstractFloat:
+		return LiteralAbstractFloat(f), true
+	case kind == ScalarSint && width == 4:
+		if !isInt {
+			i = saturate(f, math.MinInt32, math.MaxInt32)
+		}
+		return LiteralI32(i), true
+	case kind == ScalarUint && width == 4:
+		if !isInt {
+			i = saturate(f, 0, math.MaxUint32)
+		}
+		return LiteralU32(i), true
+	case kind == ScalarSint && width == 8, kind == ScalarAbstractInt:
+		if !isInt {
+			i = saturate(f, math.MinInt64, math.MaxInt64)
+		}
+		if kind == ScalarAbstractInt {
+			return LiteralAbstractInt(i), true
+		}
+		return LiteralI64(i), true
+	case kind == ScalarUint && width == 8:
+		if !isInt {
+			if f >= math.MaxUint64 {
+				return LiteralU64(math.MaxUint64), true
+			}
+			i = saturate(f, 0, math.MaxInt64)
+		}
+		return LiteralU64(i), true
+	}
+	return nil, false
+}
+
+// saturate truncates f toward zero and clamps it to [lo, hi].
+func saturate(f float64, lo, hi int64) int64 {
+	switch {
+	case math.IsNaN(f):
+		return 0
+	case f <= float64(lo):
+		return lo
+	case f >= float64(hi):
+		return hi
+	}
+	return int64(f)
+}
+
+func foldBitcast(val LiteralValue, kind ScalarKind) (LiteralValue, bool) {
+	var bits uint64
+	var wide bool
+	switch v := val.(type) {
+	case LiteralI32:
+		bits = uint64(uint32(v))
+	case LiteralU32:
+		bits = uint64(v)
+	case LiteralF32:
+		bits = uint64(math.Float32bits(float32(v)))
+	case LiteralI64:
+		bits, wide = uint64(v), true
+	case LiteralU64:
+		bits, wide = uint64(v), true
+	case LiteralF64:
+		bits, wide = math.Float64bits(float64(v)), true
+	default:
+		return nil, false
+	}
+	switch {
+	case kind == ScalarSint && !wide:
+		return LiteralI32(int32(uint32(bits))), true
+	case kind == ScalarUint && !wide:
+		return LiteralU32(uint32(bits)), true
+	case kind == ScalarFloat && !wide:
+		return LiteralF32(math.Float32frombits(uint32(bits))), true
+	case kind == ScalarSint:
+		return LiteralI64(int64(bits)), true
+	case kind == ScalarUint:
+		return LiteralU64(bits), true
+	case kind == ScalarFloat:
+		return LiteralF64(math.Float64frombits(bits)), true
+	}
+	return nil, false
+}
+
+// pruneBlock replaces if statements with a known condition by the branch
+// taken, and drops statements after one that leaves the block.
+func pruneBlock(module *Module, fn *Function, block Block) Block {
+	out := make(Block, 0, len(block))
+	for _, stmt := range block {
+		switch s := stmt.Kind.(type) {
+		case StmtIf:
+			if cond, ok := knownLiteral(module, fn, s.Condition); ok {
+				if b, isBool := cond.(LiteralBool); isBool {
+					taken, dropped := s.Accept, s.Reject
+					if !b {
+						taken, dropped = dropped, taken
+					}
+					forgetNamedExpressions(fn, dropped)
+					out = append(out, pruneBlock(module, fn, taken)...)
+					if blockTerminates(out) {
+						return out
+					}
+					continue
+				}
+			}
+			s.Accept = pruneBlock(module, fn, s.Accept)
+			s.Reject = pruneBlock(module, fn, s.Reject)
+			stmt.Kind = s
+		case StmtBlock:
+			s.Block = pruneBlock(module, fn, s.Block)
+			stmt.Kind = s
+		case StmtSwitch:
+			for i := range s.Cases {
+				s.Cases[i].Body = pruneBlock(module, fn, s.Cases[i].Body)
+			}
+			stmt.Kind = s
+		case StmtLoop:
+			s.Body = pruneBlock(module, fn, s.Body)
+			s.Continuing = pruneBlock(module, fn, s.Continuing)
+			stmt.Kind = s
+		}
+		out = append(out, stmt)
+		if blockTerminates(out) {
+			return out
+		}
+	}
+	return out
+}
+
+// blockTerminates reports whether the last statement of block leaves it.
+func blockTerminates(block Block) bool {
+	if len(block) == 0 {
+		return false
+	}
+	switch block[len(block)-1].Kind.(type) {
+	case StmtReturn, StmtKill, StmtBreak, StmtContinue:
+		return true
+	}
+	return false
+}
+
+// forgetNamedExpressions drops the names of expressions evaluated in a
+// pruned block, so compaction can remove them.
+func forgetNamedExpressions(fn *Function, block Block) {
+	walkStatements(block, func(s StatementKind) {
+		if emit, ok := s.(StmtEmit); ok {
+			for h := emit.Range.Start; h < emit.Range.End; h++ {
+				delete(fn.NamedExpressions, h)
+			}
+		}
+	})
+}
+
+// literalScalarValue encodes a literal the way Constant.Value stores it.
+func literalScalarValue(v LiteralValue) ConstantValue {
+	switch l := v.(type) {
+	case LiteralBool:
+		if l {
+			return ScalarValue{Bits: 1, Kind: ScalarBool}
+		}
+		return ScalarValue{Kind: ScalarBool}
+	case LiteralI32:
+		return ScalarValue{Bits: uint64(uint32(l)), Kind: ScalarSint}
+	case LiteralU32:
+		return ScalarValue{Bits: uint64(l), Kind: ScalarUint}
+	case LiteralF32:
+		return ScalarValue{Bits: uint64(math.Float32bits(float32(l))), Kind: ScalarFloat}
+	case LiteralF64:
+		return ScalarValue{Bits: math.Float64bits(float64(l)), Kind: ScalarFloat}
+	}
+	return nil
+}
@@ -0,0 +1,147 @@
+// Copyright 2025 The GoGPU Authors
+// SPDX-License-Identifier: MIT
+
+package ir
+
+import (
+	"math"
+	"testing"
+)
+
+func TestFoldBinary(t *testing.T) {
+	tests := []struct {
+		name        string
+		op          BinaryOperator
+		left, right LiteralValue
+		want        LiteralValue // nil means not folded
+	}{
+		{"i32 add wraps", BinaryAdd, LiteralI32(math.MaxInt32), LiteralI32(1), LiteralI32(math.MinInt32)},
+		{"i32 modulo", BinaryModulo, LiteralI32(-7), LiteralI32(3), LiteralI32(-1)},
+		{"i32 divide by zero", BinaryDivide, LiteralI32(1), LiteralI32(0), nil},
+		{"i32 min / -1", BinaryDivide, LiteralI32(math.MinInt32), LiteralI32(-1), nil},
+		{"i32 shift", BinaryShiftLeft, LiteralI32(1), LiteralU32(4), LiteralI32(16)},
+		{"i32 shift too far", BinaryShiftLeft, LiteralI32(1), LiteralU32(32), nil},
+		{"u32 compare", BinaryGreater, LiteralU32(3), LiteralU32(2), LiteralBool(true)},
+		{"u32 modulo by zero", BinaryModulo, LiteralU32(3), LiteralU32(0), nil},
+		{"f32 modulo", BinaryModulo, LiteralF32(5.5), LiteralF32(2), LiteralF32(1.5)},
+		{"f64 less", BinaryLess, LiteralF64(1), LiteralF64(2), LiteralBool(true)},
+		{"bool and", BinaryLogicalAnd, LiteralBool(true), LiteralBool(false), LiteralBool(false)},
+		{"bool xor", BinaryExclusiveOr, LiteralBool(true), LiteralBool(false), LiteralBool(true)},
+		{"mixed types", BinaryAdd, LiteralI32(1), LiteralU32(1), nil},
+		{"f32 bitwise", BinaryAnd, LiteralF32(1), LiteralF32(1), nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := foldBinary(tt.op, tt.left, tt.right)
+			if tt.want == nil {
+				if ok {
+					t.Errorf("folded to %v, want not folded", got)
+				}
+				return
+			}
+			if !ok || got != tt.want {
+				t.Errorf("got %#v (%v), want %#v", got, ok, tt.want)
+			}
+		})
+	}
+}
+
+func TestFoldUnary(t *testing.T) {
+	if got, ok := foldUnary(UnaryLogicalNot, LiteralBool(false)); !ok || got != LiteralBool(true) {
+		t.Errorf("!false = %v", got)
+	}
+	if got, ok := foldUnary(UnaryBitwiseNot, LiteralU32(0)); !ok || got != LiteralU32(math.MaxUint32) {
+		t.Errorf("~0u = %v", got)
+	}
+	if _, ok := foldUnary(UnaryNegate, LiteralU32(1)); ok {
+		t.Error("-1u folded")
+	}
+}
+
+func TestFoldConvertAndBitcast(t *testing.T) {
+	tests := []struct {
+		name string
+		got  func() (LiteralValue, bool)
+		want LiteralValue // nil means not folded
+	}{
+		{"f32 to i32 truncates", func() (LiteralValue, bool) { return FoldConvert(LiteralF32(-2.75), ScalarSint, 4) }, LiteralI32(-2)},
+		{"f32 to u32 saturates", func() (LiteralValue, bool) { return FoldConvert(LiteralF32(-1), ScalarUint, 4) }, LiteralU32(0)},
+		{"f64 to i32 saturates", func() (LiteralValue, bool) { return FoldConvert(LiteralF64(1e10), ScalarSint, 4) }, LiteralI32(math.MaxInt32)},
+		{"bool to f32", func() (LiteralValue, bool) { return FoldConvert(LiteralBool(true), ScalarFloat, 4) }, LiteralF32(1)},
+		{"abstract int to u64", func() (LiteralValue, bool) { return FoldConvert(LiteralAbstractInt(7), ScalarUint, 8) }, LiteralU64(7)},
+		{"no f128", func() (LiteralValue, bool) { return FoldConvert(LiteralF32(1), ScalarFloat, 16) }, nil},
+		{"bitcast f32 to u32", func() (LiteralValue, bool) { return FoldBitcast(LiteralF32(1), ScalarUint) }, LiteralU32(0x3f800000)},
+		{"bitcast i64 to f64", func() (LiteralValue, bool) { return FoldBitcast(LiteralI64(0), ScalarFloat) }, LiteralF64(0)},
+		{"bitcast bool", func() (LiteralValue, bool) { return FoldBitcast(LiteralBool(true), ScalarUint) }, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := tt.got()
+			if tt.want == nil {
+				if ok {
+					t.Errorf("folded to %v, want not folded", got)
+				}
+				return
+			}
+			if !ok || got != tt.want {
+				t.Errorf("got %#v (%v), want %#v", got, ok, tt.want)
+			}
+		})
+	}
+}
+
+func TestPruneBlockStopsAfterReturn(t *testing.T) {
+	one := ExpressionHandle(1)
+	fn := &Function{
+		Expressions: []Expression{
+			{Kind: Literal{Value: LiteralBool(true)}},
+			{Kind: Literal{Value: LiteralF32(1)}},
+		},
+		Body: Block{
+			{Kind: StmtIf{
+				Condition: 0,
+				Accept:    Block{{Kind: StmtReturn{Value: &one}}},
+				Reject:    Block{{Kind: StmtKill{}}},
+			}},
+			{Kind: StmtKill{}},
+		},
+	}
+	got := pruneBlock(&Module{}, fn, fn.Body)
+	if len(got) != 1 {
+		t.Fatalf("got %d statements, want the return only: %#v", len(got), got)
+	}
+	if _, ok := got[0].Kind.(StmtReturn); !ok {
+		t.Errorf("got %#v, want StmtReturn", got[0].Kind)
+	}
+}
+
+func TestSpecializeWorkgroupOverride(t *testing.T) {
+	u32 := TypeHandle(0)
+	init := ExpressionHandle(0)
+	size := OverrideHandle(0)
+	module := &Module{
+		Types:             []Type{{Inner: ScalarType{Kind: ScalarUint, Width: 4}}},
+		GlobalExpressions: []Expression{{Kind: Literal{Value: LiteralU32(64)}}},
+		Overrides:         []Override{{Name: "block", Ty: u32, Init: &init}},
+		EntryPoints: []EntryPoint{{
+			Name:               "cs",
+			Stage:              StageCompute,
+			Workgroup:          [3]uint32{1, 1, 1},
+			WorkgroupOverrides: [3]*OverrideHandle{&size},
+		}},
+	}
+	spec, err := Specialize(module, PipelineConstants{"block": 128})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ep := spec.EntryPoints[0]; ep.Workgroup[0] != 128 || ep.WorkgroupOverrides[0] != nil {
+		t.Errorf("workgroup = %v, overrides %v", ep.Workgroup, ep.WorkgroupOverrides)
+	}
+	if module.EntryPoints[0].WorkgroupOverrides[0] == nil {
+		t.Error("input module modified")
+	}
+
+	if _, err := Specialize(module, PipelineConstants{"block": 0}); err == nil {
+		t.Error("expected an error for a zero workgroup size")
+	}
+}
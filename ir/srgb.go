@@ -0,0 +1,278 @@
+// Copyright 2025 The GoGPU Authors
+// SPDX-License-Identifier: MIT
+
+package ir
+
+import (
+	"fmt"
+	"slices"
+)
+
+// ColorConversion selects the transfer function ConvertFragmentColors
+// applies to fragment color outputs.
+type ColorConversion uint8
+
+const (
+	// ColorConversionNone leaves fragment outputs as they are.
+	ColorConversionNone ColorConversion = iota
+
+	// ColorConversionLinearToSRGB encodes linear colors with the sRGB
+	// transfer function, for presenting to a swapchain that has no sRGB
+	// format (common on GLES2 and WebGL1) so the hardware can't encode them.
+	ColorConversionLinearToSRGB
+
+	// ColorConversionSRGBToLinear decodes sRGB-encoded colors to linear.
+	ColorConversionSRGBToLinear
+)
+
+// String returns the conversion's name.
+func (c ColorConversion) String() string {
+	switch c {
+	case ColorConversionNone:
+		return "none"
+	case ColorConversionLinearToSRGB:
+		return "linear-to-srgb"
+	case ColorConversionSRGBToLinear:
+		return "srgb-to-linear"
+	default:
+		return fmt.Sprintf("ColorConversion(%d)", uint8(c))
+	}
+}
+
+// ConvertFragmentColors returns a copy of module in which the fragment
+// entry point named entryPoint passes its color outputs through conv
+// before returning them. Color outputs are the @location results, or
+// @location members of a struct result, of type vec3<f32> or vec4<f32>;
+// alpha and the second dual-source blend output, a blend factor, are left
+// alone. Linear-to-sRGB conversion clamps to [0, 1] first, as storing to a
+// unorm target would. module itself is not modified.
+func ConvertFragmentColors(module *Module, entryPoint string, conv ColorConversion) (*Module, error) {
+	if conv != ColorConversionLinearToSRGB && conv != ColorConversionSRGBToLinear {
+		return nil, fmt.Errorf("convert fragment colors: invalid conversion %s", conv)
+	}
+	idx := slices.IndexFunc(module.EntryPoints, func(ep EntryPoint) bool { return ep.Name == entryPoint })
+	if idx < 0 {
+		return nil, fmt.Errorf("convert fragment colors: no entry point %q", entryPoint)
+	}
+	if module.EntryPoints[idx].Stage != StageFragment {
+		return nil, fmt.Errorf("convert fragment colors: entry point %q is not a fragment shader", entryPoint)
+	}
+
+	dst := *module
+	dst.Types = slices.Clone(module.Types)
+	dst.EntryPoints = slices.Clone(module.EntryPoints)
+	fn := &dst.EntryPoints[idx].Function
+	if fn.Result == nil {
+		return &dst, nil
+	}
+	fn.Expressions = slices.Clone(fn.Expressions)
+	fn.ExpressionTypes = slices.Clone(fn.ExpressionTypes)
+
+	c := &colorConverter{module: &dst, fn: fn, conv: conv}
+	if !c.hasColorOutput() {
+		return &dst, nil
+	}
+	fn.Body = c.rewriteBlock(fn.Body)
+	return &dst, nil
+}
+
+// colorConverter rewrites the returns of one entry point function.
+type colorConverter struct {
+	module *Module
+	fn     *Function
+	conv   ColorConversion
+}
+
+// colorOutput reports whether a result or struct member with this binding
+// and type is a color to convert.
+func (c *colorConverter) colorOutput(binding *Binding, ty TypeHandle) bool {
+	if binding == nil {
+		return false
+	}
+	loc, ok := (*binding).(LocationBinding)
+	if !ok || (loc.BlendSrc != nil && *loc.BlendSrc == 1) {
+		return false
+	}
+	vec, ok := c.module.Types[ty].Inner.(VectorType)
+	return ok && vec.Size >= Vec3 && vec.Scalar == (ScalarType{Kind: ScalarFloat, Width: 4})
+}
+
+func (c *colorConverter) hasColorOutput() bool {
+	result := c.fn.Result
+	if c.colorOutput(result.Binding, result.Type) {
+		return true
+	}
+	if st, ok := c.module.Types[result.Type].Inner.(StructType); ok {
+		for _, m := range st.Members {
+			if c.colorOutput(m.Binding, m.Type) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// rewriteBlock returns block with every value return converted. Blocks
+// without returns are shared with the source module, never modified.
+func (c *colorConverter) rewriteBlock(block Block) Block {
+	var out Block
+	for i, stmt := range block {
+		var replaced []Statement
+		switch s := stmt.Kind.(type) {
+		case StmtReturn:
+			if s.Value != nil {
+				replaced = c.convertReturn(*s.Value)
+			}
+		case StmtBlock:
+			replaced = []Statement{{Kind: StmtBlock{Block: c.rewriteBlock(s.Block)}}}
+		case StmtIf:
+			s.Accept = c.rewriteBlock(s.Accept)
+			s.Reject = c.rewriteBlock(s.Reject)
+			replaced = []Statement{{Kind: s}}
+		case StmtSwitch:
+			cases := slices.Clone(s.Cases)
+			for j := range cases {
+				cases[j].Body = c.rewriteBlock(cases[j].Body)
+			}
+			s.Cases = cases
+			replaced = []Statement{{Kind: s}}
+		case StmtLoop:
+			s.Body = c.rewriteBlock(s.Body)
+			s.Continuing = c.rewriteBlock(s.Continuing)
+			replaced = []Statement{{Kind: s}}
+		}
+		if replaced == nil {
+			if out != nil {
+				out = append(out, stmt)
+			}
+			continue
+		}
+		if out == nil {
+			out = append(make(Block, 0, len(block)+1), block[:i]...)
+		}
+		out = append(out, replaced...)
+	}
+	if out == nil {
+		return block
+	}
+	return out
+}
+
+// convertReturn returns the statements replacing `return value`: an Emit
+// of the conversion and a return of the converted value.
+func (c *colorConverter) convertReturn(value ExpressionHandle) []Statement {
+	result := c.fn.Result
+
+	// Literals are not emitted, so they go first; everything appended after
+	// start is covered by one Emit.
+	lits := c.literals()
+	start := ExpressionHandle(len(c.fn.Expressions))
+
+	var converted ExpressionHandle
+	if c.colorOutput(result.Binding, result.Type) {
+		converted = c.convertColor(value, result.Type, lits)
+	} else {
+		st := c.module.Types[result.Type].Inner.(StructType)
+		components := make([]ExpressionHandle, len(st.Members))
+		for i, m := range st.Members {
+			components[i] = c.add(ExprAccessIndex{Base: value, Index: uint32(i)}, m.Type)
+			if c.colorOutput(m.Binding, m.Type) {
+				components[i] = c.convertColor(components[i], m.Type, lits)
+			}
+		}
+		converted = c.add(ExprCompose{Type: result.Type, Components: components}, result.Type)
+	}
+
+	end := ExpressionHandle(len(c.fn.Expressions))
+	return []Statement{
+		{Kind: StmtEmit{Range: Range{Start: start, End: end}}},
+		{Kind: StmtReturn{Value: &converted}},
+	}
+}
+
+// srgbLiterals holds the f32 literals of the sRGB transfer functions.
+type srgbLiterals struct {
+	zero, one, cutoff, scale, a, offset, exponent ExpressionHandle
+}
+
+func (c *colorConverter) literals() srgbLiterals {
+	f32 := c.typeHandle(ScalarType{Kind: ScalarFloat, Width: 4})
+	lit := func(v float32) ExpressionHandle { return c.add(Literal{Value: LiteralF32(v)}, f32) }
+	if c.conv == ColorConversionLinearToSRGB {
+		return srgbLiterals{
+			zero: lit(0), one: lit(1),
+			cutoff: lit(0.0031308), scale: lit(12.92),
+			a: lit(1.055), offset: lit(0.055), exponent: lit(1 / 2.4),
+		}
+	}
+	return srgbLiterals{
+		cutoff: lit(0.04045), scale: lit(12.92),
+		a: lit(1.055), offset: lit(0.055), exponent: lit(2.4),
+	}
+}
+
+// convertColor converts the rgb channels of a vec3 or vec4 color.
+//
+//	linear to sRGB: c <= 0.0031308 ? c * 12.92 : 1.055 * pow(c, 1/2.4) - 0.055
+//	sRGB to linear: c <= 0.04045 ? c / 12.92 : pow((c + 0.055) / 1.055, 2.4)
+func (c *colorConverter) convertColor(color ExpressionHandle, ty TypeHandle, lits srgbLiterals) ExpressionHandle {
+	size := c.module.Types[ty].Inner.(VectorType).Size
+	f32 := ScalarType{Kind: ScalarFloat, Width: 4}
+	vec3 := c.typeHandle(VectorType{Size: Vec3, Scalar: f32})
+	bvec3 := c.typeHandle(VectorType{Size: Vec3, Scalar: ScalarType{Kind: ScalarBool, Width: 1}})
+	splat := func(v ExpressionHandle) ExpressionHandle { return c.add(ExprSplat{Size: Vec3, Value: v}, vec3) }
+	binary := func(op BinaryOperator, l, r ExpressionHandle) ExpressionHandle {
+		return c.add(ExprBinary{Op: op, Left: l, Right: r}, vec3)
+	}
+	pow := func(x, y ExpressionHandle) ExpressionHandle {
+		return c.add(ExprMath{Fun: MathPow, Arg: x, Arg1: &y}, vec3)
+	}
+
+	rgb := color
+	if size == Vec4 {
+		rgb = c.add(ExprSwizzle{Size: Vec3, Vector: color, Pattern: [4]SwizzleComponent{SwizzleX, SwizzleY, SwizzleZ}}, vec3)
+	}
+	var cond, linear, curve ExpressionHandle
+	if c.conv == ColorConversionLinearToSRGB {
+		low, high := splat(lits.zero), splat(lits.one)
+		rgb = c.add(ExprMath{Fun: MathClamp, Arg: rgb, Arg1: &low, Arg2: &high}, vec3)
+		cond = c.add(ExprBinary{Op: BinaryLessEqual, Left: rgb, Right: splat(lits.cutoff)}, bvec3)
+		linear = binary(BinaryMultiply, rgb, splat(lits.scale))
+		curve = binary(BinarySubtract, binary(BinaryMultiply, splat(lits.a), pow(rgb, splat(lits.exponent))), splat(lits.offset))
+	} else {
+		cond = c.add(ExprBinary{Op: BinaryLessEqual, Left: rgb, Right: splat(lits.cutoff)}, bvec3)
+		linear = binary(BinaryDivide, rgb, splat(lits.scale))
+		curve = pow(binary(BinaryDivide, binary(BinaryAdd, rgb, splat(lits.offset)), splat(lits.a)), splat(lits.exponent))
+	}
+	out := c.add(ExprSelect{Condition: cond, Accept: linear, Reject: curve}, vec3)
+	if size == Vec4 {
+		f32h := c.typeHandle(f32)
+		alpha := c.add(ExprAccessIndex{Base: color, Index: 3}, f32h)
+		out = c.add(ExprCompose{Type: ty, Components: []ExpressionHandle{out, alpha}}, ty)
+	}
+	return out
+}
+
+// add appends an expression of type ty to the function.
+func (c *colorConverter) add(kind ExpressionKind, ty TypeHandle) ExpressionHandle {
+	h := ExpressionHandle(len(c.fn.Expressions))
+	c.fn.Expressions = append(c.fn.Expressions, Expression{Kind: kind})
+	// Expression types are dense; pad in case the source left them short.
+	for len(c.fn.ExpressionTypes) < int(h) {
+		c.fn.ExpressionTypes = append(c.fn.ExpressionTypes, TypeResolution{})
+	}
+	c.fn.ExpressionTypes = append(c.fn.ExpressionTypes, TypeResolution{Handle: &ty})
+	return h
+}
+
+// typeHandle returns the handle of a type with this inner type,
+// adding one if the module has none.
+func (c *colorConverter) typeHandle(inner TypeInner) TypeHandle {
+	for i, t := range c.module.Types {
+		if t.Inner == inner {
+			return TypeHandle(i)
+		}
+	}
+	c.module.Types = append(c.module.Types, Type{Inner: inner})
+	return TypeHandle(len(c.module.Types) - 1)
+}
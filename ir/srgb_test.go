@@ -0,0 +1,134 @@
+// Copyright 2025 The GoGPU Authors
+// SPDX-License-Identifier: MIT
+
+package ir
+
+import (
+	"strings"
+	"testing"
+)
+
+// srgbTestModule has a fragment entry point returning a vec4<f32> color
+// from two places and a vertex entry point.
+func srgbTestModule() *Module {
+	f32 := ScalarType{Kind: ScalarFloat, Width: 4}
+	var loc0 Binding = LocationBinding{Location: 0}
+	var pos Binding = BuiltinBinding{Builtin: BuiltinPosition}
+	arg, one := ExpressionHandle(0), ExpressionHandle(3)
+	vec4 := TypeHandle(1)
+	return &Module{
+		Types: []Type{
+			{Name: "f32", Inner: f32},
+			{Inner: VectorType{Size: Vec4, Scalar: f32}},
+			{Inner: ScalarType{Kind: ScalarBool, Width: 1}},
+		},
+		EntryPoints: []EntryPoint{
+			{
+				Name:  "fs",
+				Stage: StageFragment,
+				Function: Function{
+					Arguments: []FunctionArgument{{Name: "c", Type: vec4, Binding: &loc0}},
+					Result:    &FunctionResult{Type: vec4, Binding: &loc0},
+					Expressions: []Expression{
+						{Kind: ExprFunctionArgument{Index: 0}},     // [0] c
+						{Kind: Literal{Value: LiteralBool(true)}},  // [1]
+						{Kind: Literal{Value: LiteralF32(1)}},      // [2]
+						{Kind: ExprSplat{Size: Vec4, Value: 2}},    // [3] vec4(1.0)
+						{Kind: ExprAccessIndex{Base: 0, Index: 3}}, // [4] c.w
+					},
+					ExpressionTypes: []TypeResolution{
+						{Handle: &vec4}, {Value: ScalarType{Kind: ScalarBool, Width: 1}},
+						{Value: f32}, {Handle: &vec4}, {Value: f32},
+					},
+					Body: []Statement{
+						{Kind: StmtEmit{Range: Range{Start: 3, End: 4}}},
+						{Kind: StmtIf{Condition: 1, Accept: Block{{Kind: StmtReturn{Value: &one}}}}},
+						{Kind: StmtReturn{Value: &arg}},
+					},
+				},
+			},
+			{
+				Name:  "vs",
+				Stage: StageVertex,
+				Function: Function{
+					Result:      &FunctionResult{Type: vec4, Binding: &pos},
+					Expressions: []Expression{{Kind: ExprZeroValue{Type: vec4}}},
+					Body:        []Statement{{Kind: StmtReturn{Value: &arg}}},
+				},
+			},
+		},
+	}
+}
+
+func TestConvertFragmentColors(t *testing.T) {
+	for _, conv := range []ColorConversion{ColorConversionLinearToSRGB, ColorConversionSRGBToLinear} {
+		t.Run(conv.String(), func(t *testing.T) {
+			src := srgbTestModule()
+			srcExprs := len(src.EntryPoints[0].Function.Expressions)
+			srcTypes := len(src.Types)
+
+			dst, err := ConvertFragmentColors(src, "fs", conv)
+			if err != nil {
+				t.Fatalf("ConvertFragmentColors: %v", err)
+			}
+			if len(src.EntryPoints[0].Function.Expressions) != srcExprs || len(src.Types) != srcTypes {
+				t.Fatal("source module was modified")
+			}
+			if ret := src.EntryPoints[0].Function.Body[2].Kind.(StmtReturn); *ret.Value != 0 {
+				t.Fatal("source body was modified")
+			}
+
+			fn := &dst.EntryPoints[0].Function
+			if len(fn.ExpressionTypes) != len(fn.Expressions) {
+				t.Errorf("%d expression types for %d expressions", len(fn.ExpressionTypes), len(fn.Expressions))
+			}
+
+			// Both returns, including the nested one, return a recomposed vec4
+			// whose rgb went through the transfer function.
+			var returns []ExpressionHandle
+			walkStatements(fn.Body, func(s StatementKind) {
+				if ret, ok := s.(StmtReturn); ok {
+					returns = append(returns, *ret.Value)
+				}
+			})
+			if len(returns) != 2 {
+				t.Fatalf("got %d returns, want 2", len(returns))
+			}
+			for _, h := range returns {
+				compose, ok := fn.Expressions[h].Kind.(ExprCompose)
+				if !ok || len(compose.Components) != 2 {
+					t.Fatalf("return [%d] is %T, want a vec4 compose of rgb and alpha", h, fn.Expressions[h].Kind)
+				}
+				if _, ok := fn.Expressions[compose.Components[0]].Kind.(ExprSelect); !ok {
+					t.Errorf("rgb is %T, want ExprSelect", fn.Expressions[compose.Components[0]].Kind)
+				}
+				if alpha, ok := fn.Expressions[compose.Components[1]].Kind.(ExprAccessIndex); !ok || alpha.Index != 3 {
+					t.Errorf("alpha is %#v, want .w of the original color", fn.Expressions[compose.Components[1]].Kind)
+				}
+			}
+
+			// The vertex entry point is untouched.
+			if len(dst.EntryPoints[1].Function.Expressions) != 1 {
+				t.Error("vertex entry point was modified")
+			}
+		})
+	}
+}
+
+func TestConvertFragmentColorsErrors(t *testing.T) {
+	tests := []struct {
+		ep   string
+		conv ColorConversion
+		want string
+	}{
+		{"missing", ColorConversionLinearToSRGB, `no entry point "missing"`},
+		{"vs", ColorConversionLinearToSRGB, "not a fragment shader"},
+		{"fs", ColorConversionNone, "invalid conversion none"},
+	}
+	for _, tt := range tests {
+		_, err := ConvertFragmentColors(srgbTestModule(), tt.ep, tt.conv)
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("ConvertFragmentColors(%q, %s) = %v, want error containing %q", tt.ep, tt.conv, err, tt.want)
+		}
+	}
+}
@@ -55,3 +55,22 @@ func vectorAlignment(size VectorSize) uint32 {
 		return 4
 	}
 }
+
+// StructPadding describes a member a backend added to a struct so that
+// its output layout keeps the offsets and span of the IR layout. Engines
+// that serialize the struct on the host can mirror these members.
+type StructPadding struct {
+	// Struct is the struct the member was added to.
+	Struct TypeHandle
+
+	// Name is the padding member's name in the output.
+	Name string
+
+	// Before is the index of the member the padding precedes, or the
+	// number of members for padding at the end of the struct.
+	Before int
+
+	// Offset and Size are the bytes the padding member covers.
+	Offset uint32
+	Size   uint32
+}
@@ -0,0 +1,282 @@
+package ir
+
+import "sort"
+
+// NeedsDerivatives reports whether an expression computes screen-space
+// derivatives: dpdx/dpdy/fwidth and their variants, and texture samples
+// with an implicit or biased level of detail (gathers excluded).
+func NeedsDerivatives(kind ExpressionKind) bool {
+	switch k := kind.(type) {
+	case ExprDerivative:
+		return true
+	case ExprImageSample:
+		if k.Gather != nil {
+			return false
+		}
+		switch k.Level.(type) {
+		case nil, SampleLevelAuto, SampleLevelBias:
+			return true
+		}
+	}
+	return false
+}
+
+// NonUniformDerivatives returns, in ascending order, the handles of the
+// expressions in fn that need derivatives (see NeedsDerivatives) and are
+// evaluated where control flow may be non-uniform.
+//
+// fn is analyzed as a fragment entry point. Its arguments vary per
+// invocation, as do loads from private, workgroup, and writable storage
+// variables, atomic and subgroup results, and local variables assigned
+// such values or assigned under non-uniform control flow. Control flow
+// becomes non-uniform inside an if, switch, or break-if on a non-uniform
+// value, for the rest of a loop after a non-uniform break or continue,
+// and for the rest of the function after a non-uniform return. discard
+// demotes the invocation to a helper and leaves control flow uniform, as
+// in WGSL.
+//
+// Calls are not followed: derivatives inside called functions are not
+// reported, and a call result is non-uniform only when an argument is.
+func NonUniformDerivatives(module *Module, fn *Function) []ExpressionHandle {
+	a := &uniformityAnalysis{
+		module:   module,
+		fn:       fn,
+		callArgs: make(map[ExpressionHandle][]ExpressionHandle),
+		exprs:    make([]bool, len(fn.Expressions)),
+		locals:   make([]bool, len(fn.LocalVars)),
+	}
+	collectCallArgs(fn.Body, a.callArgs)
+
+	// Local variables only ever turn non-uniform, so this terminates.
+	for {
+		a.computeExpressions()
+		a.localsChanged = false
+		a.walkBlock(fn.Body, false, false)
+		if !a.localsChanged {
+			break
+		}
+	}
+
+	a.found = make(map[ExpressionHandle]struct{})
+	a.walkBlock(fn.Body, false, false)
+
+	handles := make([]ExpressionHandle, 0, len(a.found))
+	for h := range a.found {
+		handles = append(handles, h)
+	}
+	sort.Slice(handles, func(i, j int) bool { return handles[i] < handles[j] })
+	return handles
+}
+
+type uniformityAnalysis struct {
+	module   *Module
+	fn       *Function
+	callArgs map[ExpressionHandle][]ExpressionHandle
+
+	exprs         []bool // non-uniform value, per expression
+	locals        []bool // may hold a non-uniform value, per local variable
+	localsChanged bool
+
+	// found collects derivative expressions under non-uniform control
+	// flow; nil during the fixpoint passes.
+	found map[ExpressionHandle]struct{}
+}
+
+func collectCallArgs(block Block, out map[ExpressionHandle][]ExpressionHandle) {
+	for _, stmt := range block {
+		switch s := stmt.Kind.(type) {
+		case StmtCall:
+			if s.Result != nil {
+				out[*s.Result] = s.Arguments
+			}
+		case StmtBlock:
+			collectCallArgs(s.Block, out)
+		case StmtIf:
+			collectCallArgs(s.Accept, out)
+			collectCallArgs(s.Reject, out)
+		case StmtSwitch:
+			for _, c := range s.Cases {
+				collectCallArgs(c.Body, out)
+			}
+		case StmtLoop:
+			collectCallArgs(s.Body, out)
+			collectCallArgs(s.Continuing, out)
+		}
+	}
+}
+
+// computeExpressions recomputes expression uniformity until it is stable.
+func (a *uniformityAnalysis) computeExpressions() {
+	for changed := true; changed; {
+		changed = false
+		for h := range a.fn.Expressions {
+			if !a.exprs[h] && a.exprNonUniform(ExpressionHandle(h)) {
+				a.exprs[h] = true
+				changed = true
+			}
+		}
+	}
+}
+
+func (a *uniformityAnalysis) nonUniform(h ExpressionHandle) bool {
+	return int(h) < len(a.exprs) && a.exprs[h]
+}
+
+func (a *uniformityAnalysis) exprNonUniform(h ExpressionHandle) bool {
+	switch k := a.fn.Expressions[h].Kind.(type) {
+	case ExprFunctionArgument:
+		return true
+	case ExprLoad:
+		return a.nonUniform(k.Pointer) || a.rootNonUniform(k.Pointer)
+	case ExprCallResult:
+		for _, arg := range a.callArgs[h] {
+			if a.nonUniform(arg) {
+				return true
+			}
+		}
+		return false
+	case ExprAtomicResult, ExprSubgroupBallotResult, ExprSubgroupOperationResult, ExprRayQueryProceedResult:
+		return true
+	}
+	result := false
+	visitExprOperands(a.fn.Expressions[h].Kind, func(op ExpressionHandle) {
+		if a.nonUniform(op) {
+			result = true
+		}
+	})
+	return result
+}
+
+// rootNonUniform reports whether the variable a pointer expression points
+// into may hold different values in different invocations.
+func (a *uniformityAnalysis) rootNonUniform(ptr ExpressionHandle) bool {
+	if local, ok := a.rootLocal(ptr); ok {
+		return a.locals[local]
+	}
+	for int(ptr) < len(a.fn.Expressions) {
+		switch k := a.fn.Expressions[ptr].Kind.(type) {
+		case ExprAccess:
+			ptr = k.Base
+		case ExprAccessIndex:
+			ptr = k.Base
+		case ExprGlobalVariable:
+			if int(k.Variable) >= len(a.module.GlobalVariables) {
+				return false
+			}
+			g := &a.module.GlobalVariables[k.Variable]
+			switch g.Space {
+			case SpacePrivate, SpaceWorkGroup:
+				return true
+			case SpaceStorage:
+				return g.Access == StorageReadWrite
+			}
+			return false
+		default:
+			return true
+		}
+	}
+	return false
+}
+
+// rootLocal returns the local variable a pointer expression points into.
+func (a *uniformityAnalysis) rootLocal(ptr ExpressionHandle) (uint32, bool) {
+	for int(ptr) < len(a.fn.Expressions) {
+		switch k := a.fn.Expressions[ptr].Kind.(type) {
+		case ExprAccess:
+			ptr = k.Base
+		case ExprAccessIndex:
+			ptr = k.Base
+		case ExprLocalVariable:
+			return k.Variable, int(k.Variable) < len(a.locals)
+		default:
+			return 0, false
+		}
+	}
+	return 0, false
+}
+
+func (a *uniformityAnalysis) taintLocal(ptr ExpressionHandle) {
+	if local, ok := a.rootLocal(ptr); ok && !a.locals[local] {
+		a.locals[local] = true
+		a.localsChanged = true
+	}
+}
+
+// walkBlock visits block with control flow uniformity nu. inSwitch
+// reports whether a break leaves a switch rather than a loop. It returns
+// whether a non-uniform return or loop exit (break/continue) happened, in
+// which case control flow is non-uniform for the rest of the function or
+// loop respectively.
+func (a *uniformityAnalysis) walkBlock(block Block, nu, inSwitch bool) (returned, exitedLoop bool) {
+	for _, stmt := range block {
+		switch s := stmt.Kind.(type) {
+		case StmtEmit:
+			if nu && a.found != nil {
+				for h := s.Range.Start; h < s.Range.End && int(h) < len(a.fn.Expressions); h++ {
+					if NeedsDerivatives(a.fn.Expressions[h].Kind) {
+						a.found[h] = struct{}{}
+					}
+				}
+			}
+		case StmtStore:
+			if nu || a.nonUniform(s.Value) || a.nonUniform(s.Pointer) {
+				a.taintLocal(s.Pointer)
+			}
+		case StmtCall:
+			// A callee may write through pointer arguments.
+			for _, arg := range s.Arguments {
+				if _, ok := a.rootLocal(arg); ok && (nu || a.nonUniform(arg)) {
+					a.taintLocal(arg)
+				}
+			}
+		case StmtReturn:
+			if nu {
+				returned = true
+			}
+		case StmtBreak:
+			if nu && !inSwitch {
+				exitedLoop = true
+			}
+		case StmtContinue:
+			if nu {
+				exitedLoop = true
+			}
+		case StmtBlock:
+			r, e := a.walkBlock(s.Block, nu, inSwitch)
+			returned, exitedLoop = returned || r, exitedLoop || e
+		case StmtIf:
+			branch := nu || a.nonUniform(s.Condition)
+			r1, e1 := a.walkBlock(s.Accept, branch, inSwitch)
+			r2, e2 := a.walkBlock(s.Reject, branch, inSwitch)
+			returned, exitedLoop = returned || r1 || r2, exitedLoop || e1 || e2
+		case StmtSwitch:
+			branch := nu || a.nonUniform(s.Selector)
+			for _, c := range s.Cases {
+				r, e := a.walkBlock(c.Body, branch, true)
+				returned, exitedLoop = returned || r, exitedLoop || e
+			}
+		case StmtLoop:
+			if a.walkLoop(s, nu) {
+				returned = true
+			}
+		}
+		if returned || exitedLoop {
+			nu = true
+		}
+	}
+	return returned, exitedLoop
+}
+
+// walkLoop visits a loop and reports whether it contains a non-uniform
+// return. If any iteration can end non-uniformly, every iteration is
+// treated as non-uniform; invocations reconverge after the loop.
+func (a *uniformityAnalysis) walkLoop(s StmtLoop, nu bool) bool {
+	r1, e1 := a.walkBlock(s.Body, nu, false)
+	r2, e2 := a.walkBlock(s.Continuing, nu || e1, false)
+	diverged := r1 || e1 || r2 || e2 || (s.BreakIf != nil && a.nonUniform(*s.BreakIf))
+	if diverged && !nu {
+		r1, _ = a.walkBlock(s.Body, true, false)
+		r2, _ = a.walkBlock(s.Continuing, true, false)
+	}
+	return r1 || r2
+}
@@ -0,0 +1,134 @@
+package ir
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestNeedsDerivatives(t *testing.T) {
+	gather := SwizzleComponent(0)
+	tests := []struct {
+		name string
+		kind ExpressionKind
+		want bool
+	}{
+		{"dpdx", ExprDerivative{Axis: DerivativeX, Expr: 0}, true},
+		{"sample", ExprImageSample{}, true},
+		{"sample_bias", ExprImageSample{Level: SampleLevelBias{Bias: 0}}, true},
+		{"sample_level", ExprImageSample{Level: SampleLevelExact{Level: 0}}, false},
+		{"sample_zero", ExprImageSample{Level: SampleLevelZero{}}, false},
+		{"gather", ExprImageSample{Gather: &gather}, false},
+		{"literal", Literal{Value: LiteralF32(1)}, false},
+	}
+	for _, tt := range tests {
+		if got := NeedsDerivatives(tt.kind); got != tt.want {
+			t.Errorf("%s: got %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+// derivativeFunction builds
+//
+//	fn f(x: f32) {
+//	    var v = <init>;
+//	    <body>
+//	}
+//
+// over a fixed expression arena:
+//
+//	[0] x   [1] 0.0   [2] x > 0.0   [3] dpdx(0.0)   [4] &v   [5] *v   [6] *v > 0.0
+func derivativeFunction(body Block) *Function {
+	return &Function{
+		Arguments: []FunctionArgument{{Name: "x"}},
+		LocalVars: []LocalVariable{{Name: "v"}},
+		Expressions: []Expression{
+			{Kind: ExprFunctionArgument{Index: 0}},
+			{Kind: Literal{Value: LiteralF32(0)}},
+			{Kind: ExprBinary{Op: BinaryGreater, Left: 0, Right: 1}},
+			{Kind: ExprDerivative{Axis: DerivativeX, Expr: 1}},
+			{Kind: ExprLocalVariable{Variable: 0}},
+			{Kind: ExprLoad{Pointer: 4}},
+			{Kind: ExprBinary{Op: BinaryGreater, Left: 5, Right: 1}},
+		},
+		Body: body,
+	}
+}
+
+func TestNonUniformDerivatives(t *testing.T) {
+	emitDerivative := Statement{Kind: StmtEmit{Range: Range{Start: 3, End: 4}}}
+	nonUniformIf := func(accept ...Statement) Statement {
+		return Statement{Kind: StmtIf{Condition: 2, Accept: accept}}
+	}
+
+	tests := []struct {
+		name string
+		body Block
+		want []ExpressionHandle
+	}{
+		{
+			name: "uniform",
+			body: Block{emitDerivative},
+			want: nil,
+		},
+		{
+			name: "non_uniform_if",
+			body: Block{nonUniformIf(emitDerivative)},
+			want: []ExpressionHandle{3},
+		},
+		{
+			name: "after_non_uniform_return",
+			body: Block{nonUniformIf(Statement{Kind: StmtReturn{}}), emitDerivative},
+			want: []ExpressionHandle{3},
+		},
+		{
+			name: "after_discard",
+			body: Block{nonUniformIf(Statement{Kind: StmtKill{}}), emitDerivative},
+			want: nil,
+		},
+		{
+			name: "loop_after_non_uniform_break",
+			body: Block{{Kind: StmtLoop{Body: Block{
+				emitDerivative,
+				nonUniformIf(Statement{Kind: StmtBreak{}}),
+			}}}},
+			want: []ExpressionHandle{3},
+		},
+		{
+			name: "after_loop_reconverges",
+			body: Block{
+				{Kind: StmtLoop{Body: Block{nonUniformIf(Statement{Kind: StmtBreak{}})}}},
+				emitDerivative,
+			},
+			want: nil,
+		},
+		{
+			name: "tainted_local",
+			body: Block{
+				{Kind: StmtStore{Pointer: 4, Value: 0}},
+				{Kind: StmtEmit{Range: Range{Start: 5, End: 7}}},
+				{Kind: StmtIf{Condition: 6, Accept: Block{emitDerivative}}},
+			},
+			want: []ExpressionHandle{3},
+		},
+		{
+			name: "uniform_local",
+			body: Block{
+				{Kind: StmtStore{Pointer: 4, Value: 1}},
+				{Kind: StmtEmit{Range: Range{Start: 5, End: 7}}},
+				{Kind: StmtIf{Condition: 6, Accept: Block{emitDerivative}}},
+			},
+			want: nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := NonUniformDerivatives(&Module{}, derivativeFunction(tt.body))
+			if len(got) == 0 && len(tt.want) == 0 {
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,213 @@
+package ir
+
+// EntryPointGlobals returns, in handle order, the global variables the
+// entry point at index ep references, directly or through the functions it
+// calls.
+func EntryPointGlobals(module *Module, ep int) []GlobalVariableHandle {
+	usedGlobals := make([]bool, len(module.GlobalVariables))
+	usedFunctions := make([]bool, len(module.Functions))
+
+	var traceFunction func(f *Function)
+	traceFunction = func(f *Function) {
+		for _, expr := range f.Expressions {
+			if gv, ok := expr.Kind.(ExprGlobalVariable); ok && int(gv.Variable) < len(usedGlobals) {
+				usedGlobals[gv.Variable] = true
+			}
+		}
+		traceStatementsForRefs(f.Body, usedGlobals, usedFunctions, module, traceFunction)
+	}
+	entry := &module.EntryPoints[ep]
+	traceFunction(&entry.Function)
+	if entry.TaskPayload != nil && int(*entry.TaskPayload) < len(usedGlobals) {
+		usedGlobals[*entry.TaskPayload] = true
+	}
+
+	var globals []GlobalVariableHandle
+	for i, used := range usedGlobals {
+		if used {
+			globals = append(globals, GlobalVariableHandle(i))
+		}
+	}
+	return globals
+}
+
+// GlobalUse is the set of ways code accesses a global variable's memory.
+type GlobalUse uint8
+
+const (
+	// GlobalRead marks a load, an image sample or load, or the read half
+	// of an atomic operation.
+	GlobalRead GlobalUse = 1 << iota
+
+	// GlobalWrite marks a store, an image store, or the write half of an
+	// atomic operation.
+	GlobalWrite
+)
+
+// String returns "read", "write", "read-write", or "none".
+func (u GlobalUse) String() string {
+	switch u {
+	case GlobalRead:
+		return "read"
+	case GlobalWrite:
+		return "write"
+	case GlobalRead | GlobalWrite:
+		return "read-write"
+	}
+	return "none"
+}
+
+// GlobalUsage is a global variable an entry point references and how it
+// accesses it. Use is 0 for a global that is referenced without touching
+// its contents, such as a sampler, a buffer whose arrayLength is taken, or
+// an image whose dimensions are queried.
+type GlobalUsage struct {
+	Global GlobalVariableHandle
+	Use    GlobalUse
+}
+
+// EntryPointGlobalUses returns, in handle order, the globals
+// EntryPointGlobals reports for the entry point at index ep, with how it
+// accesses each. Accesses through pointers passed to called functions
+// count against the global the pointer was taken from.
+func EntryPointGlobalUses(module *Module, ep int) []GlobalUsage {
+	a := &useAnalysis{module: module, funcs: make([]*functionUses, len(module.Functions))}
+	uses := a.function(&module.EntryPoints[ep].Function)
+	globals := EntryPointGlobals(module, ep)
+	out := make([]GlobalUsage, len(globals))
+	for i, g := range globals {
+		out[i] = GlobalUsage{Global: g, Use: uses.globals[g]}
+	}
+	return out
+}
+
+// functionUses is how a function accesses globals, and the memory its
+// pointer arguments point to.
+type functionUses struct {
+	globals map[GlobalVariableHandle]GlobalUse
+	args    []GlobalUse
+}
+
+type useAnalysis struct {
+	module *Module
+	funcs  []*functionUses // memoized by handle
+}
+
+func (a *useAnalysis) function(f *Function) *functionUses {
+	uses := &functionUses{globals: make(map[GlobalVariableHandle]GlobalUse), args: make([]GlobalUse, len(f.Arguments))}
+	record := func(pointer ExpressionHandle, use GlobalUse) {
+		switch root := pointerRoot(f.Expressions, pointer).(type) {
+		case ExprGlobalVariable:
+			uses.globals[root.Variable] |= use
+		case ExprFunctionArgument:
+			if int(root.Index) < len(uses.args) {
+				uses.args[root.Index] |= use
+			}
+		}
+	}
+	for _, expr := range f.Expressions {
+		switch e := expr.Kind.(type) {
+		case ExprLoad:
+			record(e.Pointer, GlobalRead)
+		case ExprImageSample:
+			record(e.Image, GlobalRead)
+		case ExprImageLoad:
+			record(e.Image, GlobalRead)
+		}
+	}
+	WalkStatements(f.Body, func(stmt *Statement) bool {
+		switch s := stmt.Kind.(type) {
+		case StmtStore:
+			record(s.Pointer, GlobalWrite)
+		case StmtImageStore:
+			record(s.Image, GlobalWrite)
+		case StmtAtomic:
+			record(s.Pointer, GlobalRead|GlobalWrite)
+		case StmtImageAtomic:
+			record(s.Image, GlobalRead|GlobalWrite)
+		case StmtWorkGroupUniformLoad:
+			record(s.Pointer, GlobalRead)
+		case StmtRayQuery:
+			if init, ok := s.Fun.(RayQueryInitialize); ok {
+				record(init.AccelerationStructure, GlobalRead)
+			}
+		case StmtCall:
+			callee := a.callee(s.Function)
+			if callee == nil {
+				break
+			}
+			for g, use := range callee.globals {
+				uses.globals[g] |= use
+			}
+			for i, arg := range s.Arguments {
+				if i < len(callee.args) && callee.args[i] != 0 {
+					record(arg, callee.args[i])
+				}
+			}
+		}
+		return true
+	}, nil)
+	return uses
+}
+
+// callee returns the uses of the function h, analyzing it on first use.
+// Recursion is invalid, so a function being analyzed is treated as using
+// nothing rather than looping.
+func (a *useAnalysis) callee(h FunctionHandle) *functionUses {
+	if int(h) >= len(a.funcs) {
+		return nil
+	}
+	if a.funcs[h] == nil {
+		a.funcs[h] = &functionUses{}
+		a.funcs[h] = a.function(&a.module.Functions[h])
+	}
+	return a.funcs[h]
+}
+
+// pointerRoot follows accesses from pointer to the expression naming the
+// memory it points into: a global variable, a function argument, or
+// anything else for memory that is not shared, such as a local variable.
+func pointerRoot(exprs []Expression, pointer ExpressionHandle) ExpressionKind {
+	for range exprs {
+		if int(pointer) >= len(exprs) {
+			return nil
+		}
+		switch e := exprs[pointer].Kind.(type) {
+		case ExprAccess:
+			pointer = e.Base
+		case ExprAccessIndex:
+			pointer = e.Base
+		default:
+			return e
+		}
+	}
+	return nil
+}
+
+// WorkgroupAllocation is a workgroup variable and the bytes it takes of an
+// entry point's workgroup storage.
+type WorkgroupAllocation struct {
+	Global GlobalVariableHandle
+	Size   uint32
+}
+
+// EntryPointWorkgroupStorage returns, in handle order, the workgroup
+// variables the entry point at index ep references, and the total bytes
+// of workgroup storage they take. Sizes are counted as WebGPU counts them
+// against maxComputeWorkgroupStorageSize: the size of the variable's type
+// rounded up to a multiple of 16. Override-sized arrays count one element
+// until overrides are processed.
+func EntryPointWorkgroupStorage(module *Module, ep int) ([]WorkgroupAllocation, uint32) {
+	var allocs []WorkgroupAllocation
+	var total uint32
+	for _, g := range EntryPointGlobals(module, ep) {
+		gv := &module.GlobalVariables[g]
+		if gv.Space != SpaceWorkGroup {
+			continue
+		}
+		size := (TypeSize(module, gv.Type) + 15) &^ 15
+		allocs = append(allocs, WorkgroupAllocation{Global: g, Size: size})
+		total += size
+	}
+	return allocs, total
+}
@@ -0,0 +1,106 @@
+package ir
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestEntryPointGlobals(t *testing.T) {
+	u32 := ScalarType{Kind: ScalarUint, Width: 4}
+	global := func(h GlobalVariableHandle) Function {
+		return Function{Expressions: []Expression{{Kind: ExprGlobalVariable{Variable: h}}}}
+	}
+	helper := global(2)
+	entry := global(0)
+	entry.Expressions = append(entry.Expressions, Expression{Kind: ExprCallResult{Function: 0}})
+	entry.Body = []Statement{{Kind: StmtCall{Function: 0}}}
+
+	m := &Module{
+		Types:           []Type{{Inner: u32}},
+		GlobalVariables: []GlobalVariable{{Name: "a"}, {Name: "b"}, {Name: "c"}},
+		Functions:       []Function{helper},
+		EntryPoints: []EntryPoint{
+			{Name: "main", Stage: StageCompute, Function: entry},
+			{Name: "other", Stage: StageCompute, Function: global(1)},
+		},
+	}
+	if got, want := EntryPointGlobals(m, 0), []GlobalVariableHandle{0, 2}; !reflect.DeepEqual(got, want) {
+		t.Errorf("main globals = %v, want %v", got, want)
+	}
+	if got, want := EntryPointGlobals(m, 1), []GlobalVariableHandle{1}; !reflect.DeepEqual(got, want) {
+		t.Errorf("other globals = %v, want %v", got, want)
+	}
+}
+
+func TestEntryPointGlobalUses(t *testing.T) {
+	// store writes through its pointer argument.
+	store := Function{
+		Arguments:   []FunctionArgument{{Name: "p"}},
+		Expressions: []Expression{{Kind: ExprFunctionArgument{Index: 0}}},
+		Body:        []Statement{{Kind: StmtStore{Pointer: 0, Value: 0}}},
+	}
+	entry := Function{
+		Expressions: []Expression{
+			{Kind: ExprGlobalVariable{Variable: 0}},
+			{Kind: ExprLoad{Pointer: 0}},
+			{Kind: ExprGlobalVariable{Variable: 2}},
+			{Kind: ExprAccessIndex{Base: 2, Index: 1}},
+			{Kind: ExprGlobalVariable{Variable: 3}},
+			{Kind: ExprGlobalVariable{Variable: 1}},
+			{Kind: ExprArrayLength{Array: 5}},
+			{Kind: ExprLocalVariable{Variable: 0}},
+		},
+		Body: []Statement{
+			{Kind: StmtCall{Function: 0, Arguments: []ExpressionHandle{3}}},
+			{Kind: StmtIf{Condition: 1, Accept: []Statement{
+				{Kind: StmtAtomic{Pointer: 4, Fun: AtomicAdd{}, Value: 1}},
+				{Kind: StmtCall{Function: 0, Arguments: []ExpressionHandle{7}}},
+			}}},
+		},
+	}
+	m := &Module{
+		GlobalVariables: []GlobalVariable{{Name: "in"}, {Name: "sized"}, {Name: "out"}, {Name: "counter"}, {Name: "unused"}},
+		Functions:       []Function{store},
+		EntryPoints:     []EntryPoint{{Name: "main", Stage: StageCompute, Function: entry}},
+	}
+	want := []GlobalUsage{
+		{Global: 0, Use: GlobalRead},
+		{Global: 1, Use: 0},
+		{Global: 2, Use: GlobalWrite},
+		{Global: 3, Use: GlobalRead | GlobalWrite},
+	}
+	if got := EntryPointGlobalUses(m, 0); !reflect.DeepEqual(got, want) {
+		t.Errorf("uses = %v, want %v", got, want)
+	}
+	if got := (GlobalRead | GlobalWrite).String(); got != "read-write" {
+		t.Errorf("String = %q, want read-write", got)
+	}
+}
+
+func TestEntryPointWorkgroupStorage(t *testing.T) {
+	five := uint32(5)
+	entry := Function{Expressions: []Expression{
+		{Kind: ExprGlobalVariable{Variable: 0}},
+		{Kind: ExprGlobalVariable{Variable: 1}},
+		{Kind: ExprGlobalVariable{Variable: 3}},
+	}}
+	m := &Module{
+		Types: []Type{
+			{Inner: ScalarType{Kind: ScalarFloat, Width: 4}},
+			{Inner: ArrayType{Base: 0, Size: ArraySize{Constant: &five}, Stride: 4}},
+			{Inner: VectorType{Size: Vec3, Scalar: ScalarType{Kind: ScalarFloat, Width: 4}}},
+		},
+		GlobalVariables: []GlobalVariable{
+			{Name: "tile", Space: SpaceWorkGroup, Type: 1},
+			{Name: "scratch", Space: SpacePrivate, Type: 1},
+			{Name: "unused", Space: SpaceWorkGroup, Type: 1},
+			{Name: "sum", Space: SpaceWorkGroup, Type: 2},
+		},
+		EntryPoints: []EntryPoint{{Name: "main", Stage: StageCompute, Function: entry}},
+	}
+	allocs, total := EntryPointWorkgroupStorage(m, 0)
+	want := []WorkgroupAllocation{{Global: 0, Size: 32}, {Global: 3, Size: 16}}
+	if !reflect.DeepEqual(allocs, want) || total != 48 {
+		t.Errorf("storage = %v, %d; want %v, 48", allocs, total, want)
+	}
+}
@@ -2,6 +2,8 @@ package ir
 
 import (
 	"fmt"
+	"slices"
+	"strings"
 )
 
 // ValidationError represents a validation error.
@@ -11,20 +13,26 @@ type ValidationError struct {
 	Function   string
 	Expression *ExpressionHandle
 	Statement  int
+	// Spans are the source spans the error is about, the primary one
+	// first, when the frontend recorded them. They hold byte offsets, so
+	// Error leaves them out; the frontend that has the source reports
+	// them as lines and columns.
+	Spans []Span
 }
 
 // Error implements the error interface.
 func (e ValidationError) Error() string {
+	msg := e.Message
 	if e.Function != "" {
 		if e.Expression != nil {
-			return fmt.Sprintf("in function %s, expression %d: %s", e.Function, *e.Expression, e.Message)
+			return fmt.Sprintf("in function %s, expression %d: %s", e.Function, *e.Expression, msg)
 		}
 		if e.Statement >= 0 {
-			return fmt.Sprintf("in function %s, statement %d: %s", e.Function, e.Statement, e.Message)
+			return fmt.Sprintf("in function %s, statement %d: %s", e.Function, e.Statement, msg)
 		}
-		return fmt.Sprintf("in function %s: %s", e.Function, e.Message)
+		return fmt.Sprintf("in function %s: %s", e.Function, msg)
 	}
-	return e.Message
+	return msg
 }
 
 // Validator validates IR modules.
@@ -32,6 +40,12 @@ type Validator struct {
 	module  *Module
 	errors  []ValidationError
 	context validationContext
+
+	// writtenArgs memoizes writtenArguments by function.
+	writtenArgs map[FunctionHandle][]bool
+	// uses analyzes how functions access globals and their pointer
+	// arguments, for validateCallAliasing.
+	uses *useAnalysis
 }
 
 // validationContext holds current validation context.
@@ -77,6 +91,9 @@ func (v *Validator) ValidateModule() {
 	// Validate functions
 	v.validateFunctions()
 
+	// Reject recursion
+	v.validateCallGraph()
+
 	// Validate entry points
 	v.validateEntryPoints()
 }
@@ -134,6 +151,13 @@ func (v *Validator) validateType(handle TypeHandle, typ *Type) {
 		if inner.Base == handle {
 			v.addError(fmt.Sprintf("type %d: array has circular reference to itself", handle))
 		}
+		if ov := inner.Size.Override; ov != nil {
+			if int(*ov) >= len(v.module.Overrides) {
+				v.addError(fmt.Sprintf("type %d: array size override %d does not exist", handle, *ov))
+			} else if st, ok := v.module.Types[v.module.Overrides[*ov].Ty].Inner.(ScalarType); !ok || (st.Kind != ScalarSint && st.Kind != ScalarUint) {
+				v.addError(fmt.Sprintf("type %d: array size override %d must be an i32 or u32", handle, *ov))
+			}
+		}
 
 	case StructType:
 		// Validate struct members
@@ -210,7 +234,53 @@ func (v *Validator) validateGlobalVariables() {
 				v.addError(fmt.Sprintf("global variable %q: init constant %d does not exist", gv.Name, *gv.Init))
 			}
 		}
+
+		if isHostShared(gv.Space) && v.isValidTypeHandle(gv.Type) {
+			if path, found := FindBool(v.module, gv.Type); found {
+				v.addError(fmt.Sprintf("global variable %q: bool is not host-shareable (at %s%s); use u32 instead",
+					gv.Name, gv.Name, path))
+			}
+		}
+	}
+}
+
+// isHostShared reports whether variables in space are laid out in memory
+// the host reads or writes, so their types must be host-shareable.
+func isHostShared(space AddressSpace) bool {
+	switch space {
+	case SpaceUniform, SpaceStorage, SpacePushConstant, SpaceImmediate:
+		return true
+	}
+	return false
+}
+
+// FindBool reports whether the type ty is or holds a bool, scalar or
+// vector, and where: the path of struct members and array elements that
+// leads to it from a value of type ty, like ".flags[].enabled", empty
+// when ty itself is bool. Bool has no defined bit pattern in host memory,
+// so WGSL forbids it in uniform, storage and push constant buffers; the
+// IR gives it width 1 everywhere else.
+func FindBool(module *Module, ty TypeHandle) (path string, found bool) {
+	if int(ty) >= len(module.Types) {
+		return "", false
+	}
+	switch t := module.Types[ty].Inner.(type) {
+	case ScalarType:
+		return "", t.Kind == ScalarBool
+	case VectorType:
+		return "", t.Scalar.Kind == ScalarBool
+	case ArrayType:
+		if path, found := FindBool(module, t.Base); found {
+			return "[]" + path, true
+		}
+	case StructType:
+		for _, m := range t.Members {
+			if path, found := FindBool(module, m.Type); found {
+				return "." + m.Name + path, true
+			}
+		}
 	}
+	return "", false
 }
 
 // validateFunctions checks all functions.
@@ -238,6 +308,20 @@ func (v *Validator) validateFunctions() {
 	}
 }
 
+// validateCallGraph reports each call cycle, which WGSL forbids.
+func (v *Validator) validateCallGraph() {
+	for _, cycle := range BuildCallGraph(v.module).Cycles() {
+		names := make([]string, len(cycle))
+		for i, f := range cycle {
+			names[i] = v.module.Functions[f].Name
+			if names[i] == "" {
+				names[i] = fmt.Sprintf("function %d", f)
+			}
+		}
+		v.addError("recursive call cycle: " + strings.Join(names, " -> "))
+	}
+}
+
 // validateFunction validates a single function.
 func (v *Validator) validateFunction(fn *Function) {
 	// Validate arguments
@@ -385,6 +469,7 @@ func (v *Validator) validateExpression(handle ExpressionHandle, expr *Expression
 		if kind.DepthRef != nil && !v.isValidExpressionHandle(*kind.DepthRef) {
 			v.addErrorInExpression(handle, fmt.Sprintf("depth ref expression %d does not exist", *kind.DepthRef))
 		}
+		v.validateImageSample(handle, kind)
 
 	case ExprImageLoad:
 		if !v.isValidExpressionHandle(kind.Image) {
@@ -402,11 +487,22 @@ func (v *Validator) validateExpression(handle ExpressionHandle, expr *Expression
 		if kind.Level != nil && !v.isValidExpressionHandle(*kind.Level) {
 			v.addErrorInExpression(handle, fmt.Sprintf("level expression %d does not exist", *kind.Level))
 		}
+		if access, ok := v.storageImageAccess(kind.Image); ok && access == StorageAccessWrite {
+			v.addErrorInExpression(handle, fmt.Sprintf("textureLoad from write-only storage texture %s; declare it read or read_write",
+				v.resourceName(kind.Image)), expr.Span)
+		}
 
 	case ExprImageQuery:
 		if !v.isValidExpressionHandle(kind.Image) {
 			v.addErrorInExpression(handle, fmt.Sprintf("image expression %d does not exist", kind.Image))
 		}
+		if size, ok := kind.Query.(ImageQuerySize); ok && size.Level != nil {
+			if !v.isValidExpressionHandle(*size.Level) {
+				v.addErrorInExpression(handle, fmt.Sprintf("level expression %d does not exist", *size.Level))
+			} else {
+				v.validateImageQueryLevel(handle, kind.Image, *size.Level)
+			}
+		}
 
 	case ExprUnary:
 		if !v.isValidExpressionHandle(kind.Expr) {
@@ -489,6 +585,147 @@ func (v *Validator) validateExpression(handle ExpressionHandle, expr *Expression
 	}
 }
 
+// validateImageSample checks that a sample's image, sampler, and
+// coordinates are compatible: only sampled, depth, and external images
+// that are not multisampled can be sampled; depth comparisons need a
+// depth image and a comparison sampler, and a comparison sampler is only
+// usable for depth comparisons; the coordinate is a float vector sized by
+// the image dimension; and an array index is given exactly for arrayed
+// images. Operands with unresolvable types are left to other checks.
+func (v *Validator) validateImageSample(handle ExpressionHandle, sample ExprImageSample) {
+	image, ok := v.expressionInner(sample.Image).(ImageType)
+	if !ok {
+		if v.expressionInner(sample.Image) != nil {
+			v.addErrorInExpression(handle, fmt.Sprintf("image expression %d is not a texture", sample.Image))
+		}
+		return
+	}
+	sampler, ok := v.expressionInner(sample.Sampler).(SamplerType)
+	if !ok {
+		if v.expressionInner(sample.Sampler) != nil {
+			v.addErrorInExpression(handle, fmt.Sprintf("sampler expression %d is not a sampler", sample.Sampler))
+		}
+		return
+	}
+
+	switch {
+	case image.Class == ImageClassStorage:
+		v.addErrorInExpression(handle, "storage textures cannot be sampled")
+		return
+	case image.Multisampled:
+		v.addErrorInExpression(handle, "multisampled textures cannot be sampled")
+		return
+	}
+
+	if sample.DepthRef != nil {
+		if image.Class != ImageClassDepth {
+			v.addErrorInExpression(handle, "depth comparison requires a depth texture")
+		}
+		if !sampler.Comparison {
+			v.addErrorInExpression(handle, "depth comparison requires a sampler_comparison")
+		}
+	} else {
+		if sampler.Comparison {
+			v.addErrorInExpression(handle, "sampler_comparison can only be used for depth comparison")
+		}
+		if image.Class == ImageClassSampled && image.SampledKind != ScalarFloat && sample.Gather == nil {
+			v.addErrorInExpression(handle, "only float textures can be sampled (integer textures support gather and load)")
+		}
+	}
+
+	if image.Arrayed && sample.ArrayIndex == nil {
+		v.addErrorInExpression(handle, "arrayed texture requires an array index")
+	} else if !image.Arrayed && sample.ArrayIndex != nil {
+		v.addErrorInExpression(handle, "array index given for a non-arrayed texture")
+	}
+
+	want := imageCoordinateSize(image.Dim)
+	var (
+		size   int
+		scalar ScalarType
+	)
+	switch c := v.expressionInner(sample.Coordinate).(type) {
+	case nil:
+		return
+	case ScalarType:
+		size, scalar = 1, c
+	case VectorType:
+		size, scalar = int(c.Size), c.Scalar
+	}
+	if size != want || (scalar.Kind != ScalarFloat && scalar.Kind != ScalarAbstractFloat) {
+		v.addErrorInExpression(handle, fmt.Sprintf("%s texture needs a %s coordinate", imageDimName(image.Dim), floatCoordinateName(want)))
+	}
+}
+
+// validateImageQueryLevel checks the level of a size query: only images
+// with mip levels, which multisampled, storage and external images lack,
+// take one, and it is an i32 or u32. Operands with unresolvable types are
+// left to other checks.
+func (v *Validator) validateImageQueryLevel(handle, image, level ExpressionHandle) {
+	if img, ok := v.expressionInner(image).(ImageType); ok {
+		switch {
+		case img.Multisampled:
+			v.addErrorInExpression(handle, "multisampled textures have no level to query the size of")
+		case img.Class == ImageClassStorage:
+			v.addErrorInExpression(handle, "storage textures have no level to query the size of")
+		case img.Class == ImageClassExternal:
+			v.addErrorInExpression(handle, "external textures have no level to query the size of")
+		}
+	}
+	inner := v.expressionInner(level)
+	if t, ok := inner.(ScalarType); inner == nil || ok && t.Width == 4 && (t.Kind == ScalarSint || t.Kind == ScalarUint) {
+		return
+	}
+	v.addErrorInExpression(handle, "image size query level must be an i32 or u32")
+}
+
+// imageCoordinateSize returns the number of components of a sampling
+// coordinate for images of dimension dim.
+func imageCoordinateSize(dim ImageDimension) int {
+	switch dim {
+	case Dim1D:
+		return 1
+	case Dim3D, DimCube:
+		return 3
+	default:
+		return 2
+	}
+}
+
+func imageDimName(dim ImageDimension) string {
+	switch dim {
+	case Dim1D:
+		return "1D"
+	case Dim3D:
+		return "3D"
+	case DimCube:
+		return "cube"
+	default:
+		return "2D"
+	}
+}
+
+func floatCoordinateName(size int) string {
+	if size == 1 {
+		return "f32"
+	}
+	return fmt.Sprintf("vec%d<f32>", size)
+}
+
+// expressionInner returns the type of expression h in the function being
+// validated, or nil when it cannot be resolved.
+func (v *Validator) expressionInner(h ExpressionHandle) TypeInner {
+	fn := v.context.function
+	if fn == nil || !v.isValidExpressionHandle(h) {
+		return nil
+	}
+	res, err := ExpressionType(v.module, fn, h)
+	if err != nil {
+		return nil
+	}
+	return resolveInner(v.module, res)
+}
+
 // validateBlock validates a block of statements.
 func (v *Validator) validateBlock(block Block) {
 	for i, stmt := range block {
@@ -607,6 +844,7 @@ func (v *Validator) validateStatement(index int, stmt *Statement) {
 		if !v.isValidExpressionHandle(kind.Value) {
 			v.addErrorInStatement(index, fmt.Sprintf("value expression %d does not exist", kind.Value))
 		}
+		v.validateStorageWrite(index, stmt, kind.Pointer, "store to")
 
 	case StmtImageStore:
 		if !v.isValidExpressionHandle(kind.Image) {
@@ -621,6 +859,16 @@ func (v *Validator) validateStatement(index int, stmt *Statement) {
 		if !v.isValidExpressionHandle(kind.Value) {
 			v.addErrorInStatement(index, fmt.Sprintf("value expression %d does not exist", kind.Value))
 		}
+		if access, ok := v.storageImageAccess(kind.Image); ok && access == StorageAccessRead {
+			v.addErrorInStatement(index, fmt.Sprintf("textureStore to read-only storage texture %s; declare it write or read_write",
+				v.resourceName(kind.Image)), operandsSpan(v.context.function, kind))
+		}
+
+	case StmtImageAtomic:
+		if access, ok := v.storageImageAccess(kind.Image); ok && access != StorageAccessReadWrite && access != StorageAccessAtomic {
+			v.addErrorInStatement(index, fmt.Sprintf("atomic on storage texture %s, which is not read_write or atomic",
+				v.resourceName(kind.Image)), operandsSpan(v.context.function, kind))
+		}
 
 	case StmtAtomic:
 		if !v.isValidExpressionHandle(kind.Pointer) {
@@ -632,6 +880,7 @@ func (v *Validator) validateStatement(index int, stmt *Statement) {
 		if kind.Result != nil && !v.isValidExpressionHandle(*kind.Result) {
 			v.addErrorInStatement(index, fmt.Sprintf("result expression %d does not exist", *kind.Result))
 		}
+		v.validateStorageWrite(index, stmt, kind.Pointer, "atomic on")
 
 	case StmtWorkGroupUniformLoad:
 		if !v.isValidExpressionHandle(kind.Pointer) {
@@ -653,6 +902,15 @@ func (v *Validator) validateStatement(index int, stmt *Statement) {
 		if kind.Result != nil && !v.isValidExpressionHandle(*kind.Result) {
 			v.addErrorInStatement(index, fmt.Sprintf("result expression %d does not exist", *kind.Result))
 		}
+		if v.isValidFunctionHandle(kind.Function) {
+			v.validateCallAliasing(index, kind)
+			written := v.writtenArguments(kind.Function)
+			for i, arg := range kind.Arguments {
+				if i < len(written) && written[i] {
+					v.validateStorageWrite(index, stmt, arg, fmt.Sprintf("call to %s, which writes through argument %d, passing", v.functionName(kind.Function), i))
+				}
+			}
+		}
 
 	case StmtRayQuery:
 		if !v.isValidExpressionHandle(kind.Query) {
@@ -663,20 +921,35 @@ func (v *Validator) validateStatement(index int, stmt *Statement) {
 
 // validateEntryPoints checks all entry points.
 func (v *Validator) validateEntryPoints() {
-	names := make(map[string]bool)
+	// Entry points are selected by stage and name, so only two entry
+	// points of the same stage may not share a name.
+	type entryKey struct {
+		stage ShaderStage
+		name  string
+	}
+	names := make(map[entryKey]bool)
 
 	for i, ep := range v.module.EntryPoints {
 		if ep.Name == "" {
 			v.addError(fmt.Sprintf("entry point %d has empty name", i))
 		}
-		if names[ep.Name] {
-			v.addError(fmt.Sprintf("duplicate entry point name %q", ep.Name))
+		key := entryKey{ep.Stage, ep.Name}
+		if names[key] {
+			v.addError(fmt.Sprintf("duplicate entry point name %q for the %s stage", ep.Name, stageName(ep.Stage)))
 		}
-		names[ep.Name] = true
+		names[key] = true
 
 		// Entry point function is stored inline (not via handle).
 		fn := &v.module.EntryPoints[i].Function
 
+		v.context = validationContext{
+			function:       fn,
+			functionName:   ep.Name,
+			expressionUsed: make(map[ExpressionHandle]bool),
+		}
+		v.validateFunction(fn)
+		v.validateEntryPointBuiltins(&ep)
+
 		// Validate stage-specific requirements
 		switch ep.Stage {
 		case StageVertex:
@@ -703,6 +976,53 @@ func (v *Validator) validateEntryPoints() {
 	}
 }
 
+// validateEntryPointBuiltins checks that every builtin an entry point takes
+// or returns, directly or as a struct member, is legal for its stage and
+// direction.
+func (v *Validator) validateEntryPointBuiltins(ep *EntryPoint) {
+	check := func(binding *Binding, ty TypeHandle, output bool) {
+		for _, b := range v.builtinsOf(binding, ty) {
+			if err := CheckBuiltinUsage(b, ep.Stage, output); err != nil {
+				v.addError(fmt.Sprintf("entry point %q: %v", ep.Name, err))
+			}
+		}
+	}
+	for _, arg := range ep.Function.Arguments {
+		check(arg.Binding, arg.Type, false)
+	}
+	if result := ep.Function.Result; result != nil {
+		check(result.Binding, result.Type, true)
+	}
+}
+
+// builtinsOf returns the builtins bound by binding, or by the members of
+// ty if it is an unbound struct.
+func (v *Validator) builtinsOf(binding *Binding, ty TypeHandle) []BuiltinValue {
+	if binding != nil {
+		if b, ok := (*binding).(BuiltinBinding); ok {
+			return []BuiltinValue{b.Builtin}
+		}
+		return nil
+	}
+	if !v.isValidTypeHandle(ty) {
+		return nil
+	}
+	st, ok := v.module.Types[ty].Inner.(StructType)
+	if !ok {
+		return nil
+	}
+	var builtins []BuiltinValue
+	for _, m := range st.Members {
+		if m.Binding == nil {
+			continue
+		}
+		if b, ok := (*m.Binding).(BuiltinBinding); ok {
+			builtins = append(builtins, b.Builtin)
+		}
+	}
+	return builtins
+}
+
 // hasPositionBuiltin checks if the function result contains @builtin(position).
 // This can be either:
 // 1. Direct binding on result: fn() -> @builtin(position) vec4<f32>
@@ -780,19 +1100,289 @@ func (v *Validator) addErrorInFunction(msg string) {
 	})
 }
 
-func (v *Validator) addErrorInExpression(handle ExpressionHandle, msg string) {
+func (v *Validator) addErrorInExpression(handle ExpressionHandle, msg string, spans ...Span) {
 	v.errors = append(v.errors, ValidationError{
 		Message:    msg,
 		Function:   v.context.functionName,
 		Expression: &handle,
 		Statement:  -1,
+		Spans:      spans,
 	})
 }
 
-func (v *Validator) addErrorInStatement(index int, msg string) {
+func (v *Validator) addErrorInStatement(index int, msg string, spans ...Span) {
 	v.errors = append(v.errors, ValidationError{
 		Message:   msg,
 		Function:  v.context.functionName,
 		Statement: index,
+		Spans:     spans,
+	})
+}
+
+// validateCallAliasing rejects a call passing two pointers to overlapping
+// memory when the callee writes through either, which WGSL forbids:
+// backends assume pointer parameters do not alias, so such a call would
+// otherwise compile to code that reads stale values on some of them.
+// Pointers overlap when they have the same root variable and neither
+// access path diverges from the other at a constant index; a dynamic
+// index may select anything. This accepts disjoint elements of one
+// variable, such as swap(&a[0], &a[1]), which WGSL's coarser rule on root
+// identifiers rejects but every backend compiles correctly.
+//
+// A pointer into a global variable also must not be passed to a function
+// that accesses the global itself, directly or through the functions it
+// calls, when either access writes.
+func (v *Validator) validateCallAliasing(index int, call StmtCall) {
+	fn := v.context.function
+	written := v.writtenArguments(call.Function)
+	type pointerArg struct {
+		arg  int
+		root ExpressionKind
+		path []accessStep
+	}
+	var ptrs []pointerArg
+	for i, arg := range call.Arguments {
+		if root, path, ok := pointerAccessPath(fn, arg); ok {
+			ptrs = append(ptrs, pointerArg{i, root, path})
+		}
+	}
+	for i, a := range ptrs {
+		for _, b := range ptrs[i+1:] {
+			if a.root != b.root || !accessPathsOverlap(a.path, b.path) {
+				continue
+			}
+			if !(a.arg < len(written) && written[a.arg]) && !(b.arg < len(written) && written[b.arg]) {
+				continue
+			}
+			v.addErrorInStatement(index, fmt.Sprintf(
+				"call to %s: pointer arguments %d and %d may overlap in %s, and the callee writes through one of them",
+				v.functionName(call.Function), a.arg, b.arg, v.rootName(fn, a.root)),
+				fn.Expressions[call.Arguments[a.arg]].Span, fn.Expressions[call.Arguments[b.arg]].Span)
+		}
+	}
+
+	if v.uses == nil {
+		v.uses = &useAnalysis{module: v.module, funcs: make([]*functionUses, len(v.module.Functions))}
+	}
+	callee := v.uses.callee(call.Function)
+	if callee == nil {
+		return
+	}
+	for _, p := range ptrs {
+		global, ok := p.root.(ExprGlobalVariable)
+		if !ok || p.arg >= len(callee.args) {
+			continue
+		}
+		argUse, globalUse := callee.args[p.arg], callee.globals[global.Variable]
+		if argUse == 0 || globalUse == 0 || (argUse|globalUse)&GlobalWrite == 0 {
+			continue
+		}
+		v.addErrorInStatement(index, fmt.Sprintf(
+			"call to %s: pointer argument %d points into %s, which the callee also accesses directly, and one of the accesses writes",
+			v.functionName(call.Function), p.arg, v.rootName(fn, p.root)),
+			fn.Expressions[call.Arguments[p.arg]].Span)
+	}
+}
+
+// writtenArguments reports, for each argument of function h, whether the
+// function stores through it, directly or by passing it on to a function
+// that does.
+func (v *Validator) writtenArguments(h FunctionHandle) []bool {
+	if written, ok := v.writtenArgs[h]; ok {
+		return written
+	}
+	if v.writtenArgs == nil {
+		v.writtenArgs = make(map[FunctionHandle][]bool)
+	}
+	fn := &v.module.Functions[h]
+	written := make([]bool, len(fn.Arguments))
+	// Recursion is reported separately; the entry stops it from looping.
+	v.writtenArgs[h] = written
+	mark := func(pointer ExpressionHandle) {
+		if arg, ok := pointerRoot(fn.Expressions, pointer).(ExprFunctionArgument); ok && int(arg.Index) < len(written) {
+			written[arg.Index] = true
+		}
+	}
+	WalkStatements(fn.Body, func(stmt *Statement) bool {
+		switch s := stmt.Kind.(type) {
+		case StmtStore:
+			mark(s.Pointer)
+		case StmtAtomic:
+			mark(s.Pointer)
+		case StmtRayQuery:
+			mark(s.Query)
+		case StmtCall:
+			if !v.isValidFunctionHandle(s.Function) {
+				break
+			}
+			calleeWritten := v.writtenArguments(s.Function)
+			for i, arg := range s.Arguments {
+				if i < len(calleeWritten) && calleeWritten[i] {
+					mark(arg)
+				}
+			}
+		}
+		return true
+	}, nil)
+	return written
+}
+
+// accessStep is one index of an access path; a dynamic index is unknown.
+type accessStep struct {
+	index uint32
+	known bool
+}
+
+// pointerAccessPath returns the variable or argument pointer expression h
+// of fn points into, as pointerRoot does, and the indices taken from it,
+// outermost first. It fails for expressions that are not a pointer into a
+// variable or argument.
+func pointerAccessPath(fn *Function, h ExpressionHandle) (ExpressionKind, []accessStep, bool) {
+	var path []accessStep
+	for int(h) < len(fn.Expressions) {
+		switch e := fn.Expressions[h].Kind.(type) {
+		case ExprLocalVariable, ExprGlobalVariable, ExprFunctionArgument:
+			slices.Reverse(path)
+			return e, path, true
+		case ExprAccessIndex:
+			path = append(path, accessStep{index: e.Index, known: true})
+			h = e.Base
+		case ExprAccess:
+			step := accessStep{}
+			if int(e.Index) < len(fn.Expressions) {
+				if lit, ok := fn.Expressions[e.Index].Kind.(Literal); ok {
+					step.index, step.known = literalIndex(lit.Value)
+				}
+			}
+			path = append(path, step)
+			h = e.Base
+		default:
+			return nil, nil, false
+		}
+	}
+	return nil, nil, false
+}
+
+// literalIndex returns the value of an integer literal used as an index.
+func literalIndex(value LiteralValue) (uint32, bool) {
+	switch val := value.(type) {
+	case LiteralU32:
+		return uint32(val), true
+	case LiteralI32:
+		return uint32(val), val >= 0
+	case LiteralAbstractInt:
+		return uint32(val), val >= 0
+	}
+	return 0, false
+}
+
+// accessPathsOverlap reports whether two access paths from the same root
+// may reach overlapping memory: one is a prefix of the other, up to
+// dynamic indices.
+func accessPathsOverlap(a, b []accessStep) bool {
+	for i := 0; i < len(a) && i < len(b); i++ {
+		if a[i].known && b[i].known && a[i].index != b[i].index {
+			return false
+		}
+	}
+	return true
+}
+
+// rootName describes a pointer root for diagnostics.
+func (v *Validator) rootName(fn *Function, root ExpressionKind) string {
+	switch r := root.(type) {
+	case ExprLocalVariable:
+		if int(r.Variable) < len(fn.LocalVars) && fn.LocalVars[r.Variable].Name != "" {
+			return fmt.Sprintf("local variable %q", fn.LocalVars[r.Variable].Name)
+		}
+		return fmt.Sprintf("local variable %d", r.Variable)
+	case ExprGlobalVariable:
+		if int(r.Variable) < len(v.module.GlobalVariables) && v.module.GlobalVariables[r.Variable].Name != "" {
+			return fmt.Sprintf("global variable %q", v.module.GlobalVariables[r.Variable].Name)
+		}
+		return fmt.Sprintf("global variable %d", r.Variable)
+	case ExprFunctionArgument:
+		if int(r.Index) < len(fn.Arguments) && fn.Arguments[r.Index].Name != "" {
+			return fmt.Sprintf("argument %q", fn.Arguments[r.Index].Name)
+		}
+		return fmt.Sprintf("argument %d", r.Index)
+	}
+	return "the same memory"
+}
+
+// functionName names function h for diagnostics.
+func (v *Validator) functionName(h FunctionHandle) string {
+	if name := v.module.Functions[h].Name; name != "" {
+		return fmt.Sprintf("%q", name)
+	}
+	return fmt.Sprintf("function %d", h)
+}
+
+// validateStorageWrite reports a write through pointer, described by what,
+// into a storage buffer declared read-only, as var<storage> is by default.
+// Backends decorate such buffers NonWritable or declare them const, so the
+// write would otherwise compile to invalid code.
+func (v *Validator) validateStorageWrite(index int, stmt *Statement, pointer ExpressionHandle, what string) {
+	fn := v.context.function
+	root, ok := pointerRoot(fn.Expressions, pointer).(ExprGlobalVariable)
+	if !ok || !v.isValidGlobalVariableHandle(root.Variable) {
+		return
+	}
+	gv := &v.module.GlobalVariables[root.Variable]
+	if gv.Space != SpaceStorage || gv.Access != StorageRead {
+		return
+	}
+	v.addErrorInStatement(index, fmt.Sprintf("%s read-only storage buffer %s; declare it var<storage, read_write>",
+		what, v.resourceName(pointer)), operandsSpan(fn, stmt.Kind))
+}
+
+// storageImageAccess returns the access mode of image expression h when
+// it is a storage texture.
+func (v *Validator) storageImageAccess(h ExpressionHandle) (StorageAccess, bool) {
+	if !v.isValidExpressionHandle(h) {
+		return 0, false
+	}
+	img, ok := v.expressionInner(h).(ImageType)
+	if !ok || img.Class != ImageClassStorage {
+		return 0, false
+	}
+	return img.StorageAccess, true
+}
+
+// resourceName names the global variable or argument expression h
+// refers to, through any accesses, for diagnostics, with the binding of
+// a global so the declaration can be found.
+func (v *Validator) resourceName(h ExpressionHandle) string {
+	fn := v.context.function
+	switch root := pointerRoot(fn.Expressions, h).(type) {
+	case ExprGlobalVariable:
+		if !v.isValidGlobalVariableHandle(root.Variable) {
+			break
+		}
+		gv := &v.module.GlobalVariables[root.Variable]
+		name := fmt.Sprintf("%q", gv.Name)
+		if gv.Name == "" {
+			name = v.rootName(fn, root)
+		}
+		if gv.Binding != nil {
+			name += fmt.Sprintf(" (@group(%d) @binding(%d))", gv.Binding.Group, gv.Binding.Binding)
+		}
+		return name
+	case ExprFunctionArgument:
+		return v.rootName(fn, root)
+	}
+	return fmt.Sprintf("expression %d", h)
+}
+
+// operandsSpan returns the smallest span covering the operands of stmt
+// that have one, which for a store or a call is the statement's own
+// source less its punctuation.
+func operandsSpan(fn *Function, stmt StatementKind) Span {
+	var span Span
+	StatementOperands(stmt, func(h ExpressionHandle) {
+		if int(h) < len(fn.Expressions) {
+			span = span.union(fn.Expressions[h].Span)
+		}
 	})
+	return span
 }
@@ -310,10 +310,19 @@ func TestValidateNew_EntryPointEmptyName(t *testing.T) {
 func TestValidateNew_EntryPointDuplicateName(t *testing.T) {
 	m := newValidModule()
 	m.EntryPoints = []EntryPoint{
-		{Name: "main", Stage: StageVertex, Function: Function{Name: "main"}},
+		{Name: "main", Stage: StageCompute, Function: Function{Name: "main"}},
+		{Name: "main", Stage: StageCompute, Function: Function{Name: "main"}},
+	}
+	expectValidationErrors(t, m, `duplicate entry point name "main" for the compute stage`)
+}
+
+func TestValidateNew_EntryPointSameNameOtherStage(t *testing.T) {
+	m := newValidModule()
+	m.EntryPoints = []EntryPoint{
+		{Name: "main", Stage: StageCompute, Workgroup: [3]uint32{1, 1, 1}, Function: Function{Name: "main"}},
 		{Name: "main", Stage: StageFragment, Function: Function{Name: "main"}},
 	}
-	expectValidationErrors(t, m, "duplicate entry point name")
+	expectNoValidationErrors(t, m)
 }
 
 func TestValidateNew_VertexDirectPositionBinding(t *testing.T) {
@@ -318,6 +318,42 @@ func TestValidateSemantic_EntryPoints(t *testing.T) {
 			t.Errorf("expected no errors for vertex with struct position builtin, got: %v", errors)
 		}
 	})
+
+	t.Run("builtin in wrong stage or direction", func(t *testing.T) {
+		u32 := ScalarType{Kind: ScalarUint, Width: 4}
+		f32 := ScalarType{Kind: ScalarFloat, Width: 4}
+		vertexIndex := Binding(BuiltinBinding{Builtin: BuiltinVertexIndex})
+		fragDepth := Binding(BuiltinBinding{Builtin: BuiltinFragDepth})
+		module := &Module{
+			Types: []Type{
+				{Name: "u32", Inner: u32},
+				{Name: "f32", Inner: f32},
+				{Name: "Out", Inner: StructType{Members: []StructMember{
+					{Name: "depth", Type: TypeHandle(1), Binding: &fragDepth},
+				}}},
+			},
+			EntryPoints: []EntryPoint{
+				{Name: "fs", Stage: StageFragment, Function: Function{
+					Arguments: []FunctionArgument{{Name: "i", Type: TypeHandle(0), Binding: &vertexIndex}},
+					Result:    &FunctionResult{Type: TypeHandle(1), Binding: &fragDepth},
+				}},
+				{Name: "fs_in", Stage: StageFragment, Function: Function{
+					Arguments: []FunctionArgument{{Name: "d", Type: TypeHandle(1), Binding: &fragDepth}},
+				}},
+				{Name: "cs", Stage: StageCompute, Workgroup: [3]uint32{1, 1, 1}, Function: Function{
+					Result: &FunctionResult{Type: TypeHandle(2)},
+				}},
+			},
+		}
+		expectErrors(t, module,
+			`entry point "fs": @builtin(vertex_index) cannot be an input of a @fragment entry point`,
+			`entry point "fs_in": @builtin(frag_depth) cannot be an input of a @fragment entry point`,
+			`entry point "cs": @builtin(frag_depth) cannot be an output of a @compute entry point`)
+		errors, _ := Validate(module)
+		if containsError(errors, `"fs": @builtin(frag_depth)`) {
+			t.Errorf("frag_depth is a valid fragment output, got: %v", errors)
+		}
+	})
 }
 
 // --- Function validation tests ---
@@ -1039,6 +1075,203 @@ func TestValidateSemantic_StmtCallInvalid(t *testing.T) {
 	})
 }
 
+// aliasModule returns a module whose main passes &a[i] and &a[j] to a
+// callee taking two f32 pointers, which stores through the first one
+// when writes is set. A negative index is dynamic.
+func aliasModule(i, j int, writes bool) *Module {
+	f32 := TypeHandle(0)
+	ptr := TypeHandle(1)
+	arr := TypeHandle(2)
+	u32 := TypeHandle(3)
+	n := uint32(4)
+	callee := Function{
+		Name: "callee",
+		Arguments: []FunctionArgument{
+			{Name: "x", Type: ptr},
+			{Name: "y", Type: ptr},
+		},
+		Expressions: []Expression{
+			{Kind: ExprFunctionArgument{Index: 0}},
+			{Kind: Literal{Value: LiteralF32(1)}},
+		},
+	}
+	if writes {
+		callee.Body = Block{{Kind: StmtStore{Pointer: 0, Value: 1}}}
+	}
+	main := Function{
+		Name:      "main",
+		LocalVars: []LocalVariable{{Name: "a", Type: arr}, {Name: "i", Type: u32}},
+		Expressions: []Expression{
+			{Kind: ExprLocalVariable{Variable: 0}, Span: Span{Start: 1, End: 2}},
+			{Kind: ExprLocalVariable{Variable: 1}},
+			{Kind: ExprLoad{Pointer: 1}},
+		},
+	}
+	index := func(idx int) ExpressionHandle {
+		if idx < 0 {
+			main.Expressions = append(main.Expressions, Expression{Kind: ExprAccess{Base: 0, Index: 2}, Span: Span{Start: 10, End: 16}})
+		} else {
+			main.Expressions = append(main.Expressions, Expression{Kind: ExprAccessIndex{Base: 0, Index: uint32(idx)}, Span: Span{Start: 20, End: 26}})
+		}
+		return ExpressionHandle(len(main.Expressions) - 1)
+	}
+	args := []ExpressionHandle{index(i), index(j)}
+	main.Body = Block{{Kind: StmtCall{Function: 0, Arguments: args}}}
+	return &Module{
+		Types: []Type{
+			{Inner: ScalarType{Kind: ScalarFloat, Width: 4}},
+			{Inner: PointerType{Base: f32, Space: SpaceFunction}},
+			{Inner: ArrayType{Base: f32, Size: ArraySize{Constant: &n}, Stride: 4}},
+			{Inner: ScalarType{Kind: ScalarUint, Width: 4}},
+		},
+		Functions: []Function{callee, main},
+	}
+}
+
+func TestValidateSemantic_PointerArgumentAliasing(t *testing.T) {
+	t.Run("same element written", func(t *testing.T) {
+		errs, _ := Validate(aliasModule(1, 1, true))
+		if !containsError(errs, `pointer arguments 0 and 1 may overlap in local variable "a"`) {
+			t.Fatalf("expected an aliasing error, got %v", errs)
+		}
+		for _, e := range errs {
+			if strings.Contains(e.Message, "overlap") && len(e.Spans) != 2 {
+				t.Errorf("Spans = %v, want both arguments", e.Spans)
+			}
+		}
+	})
+	t.Run("dynamic index written", func(t *testing.T) {
+		expectErrors(t, aliasModule(-1, 2, true), "may overlap")
+	})
+	t.Run("distinct elements", func(t *testing.T) {
+		if errs, _ := Validate(aliasModule(0, 1, true)); containsError(errs, "overlap") {
+			t.Errorf("unexpected aliasing error: %v", errs)
+		}
+	})
+	t.Run("read only", func(t *testing.T) {
+		if errs, _ := Validate(aliasModule(1, 1, false)); containsError(errs, "overlap") {
+			t.Errorf("unexpected aliasing error: %v", errs)
+		}
+	})
+}
+
+// globalAliasModule returns a module whose main passes &g to a callee
+// that reads g directly and stores through its pointer argument when
+// writes is set, or else reads it.
+func globalAliasModule(writes bool) *Module {
+	f32 := TypeHandle(0)
+	ptr := TypeHandle(1)
+	callee := Function{
+		Name:      "callee",
+		Arguments: []FunctionArgument{{Name: "p", Type: ptr}},
+		Expressions: []Expression{
+			{Kind: ExprFunctionArgument{Index: 0}},
+			{Kind: Literal{Value: LiteralF32(1)}},
+			{Kind: ExprGlobalVariable{Variable: 0}},
+			{Kind: ExprLoad{Pointer: 2}},
+			{Kind: ExprLoad{Pointer: 0}},
+		},
+		Body: Block{{Kind: StmtEmit{Range: Range{Start: 3, End: 5}}}},
+	}
+	if writes {
+		callee.Body = append(callee.Body, Statement{Kind: StmtStore{Pointer: 0, Value: 1}})
+	}
+	main := Function{
+		Name:        "main",
+		Expressions: []Expression{{Kind: ExprGlobalVariable{Variable: 0}, Span: Span{Start: 5, End: 6}}},
+		Body:        Block{{Kind: StmtCall{Function: 0, Arguments: []ExpressionHandle{0}}}},
+	}
+	return &Module{
+		Types: []Type{
+			{Inner: ScalarType{Kind: ScalarFloat, Width: 4}},
+			{Inner: PointerType{Base: f32, Space: SpacePrivate}},
+		},
+		GlobalVariables: []GlobalVariable{{Name: "g", Space: SpacePrivate, Type: f32}},
+		Functions:       []Function{callee, main},
+	}
+}
+
+func TestValidateSemantic_GlobalPointerArgumentAliasing(t *testing.T) {
+	errs, _ := Validate(globalAliasModule(true))
+	if !containsError(errs, `pointer argument 0 points into global variable "g", which the callee also accesses directly`) {
+		t.Fatalf("expected an aliasing error, got %v", errs)
+	}
+	if errs, _ := Validate(globalAliasModule(false)); containsError(errs, "points into") {
+		t.Errorf("unexpected aliasing error for reads only: %v", errs)
+	}
+}
+
+func TestValidateSemantic_ReadOnlyStorageWrite(t *testing.T) {
+	module := func(access StorageAccessMode) *Module {
+		return &Module{
+			Types: []Type{{Inner: ScalarType{Kind: ScalarUint, Width: 4}}},
+			GlobalVariables: []GlobalVariable{{
+				Name: "buf", Space: SpaceStorage, Type: 0, Access: access,
+				Binding: &ResourceBinding{Group: 0, Binding: 2},
+			}},
+			Functions: []Function{{
+				Name: "main",
+				Expressions: []Expression{
+					{Kind: ExprGlobalVariable{Variable: 0}, Span: Span{Start: 4, End: 7}},
+					{Kind: Literal{Value: LiteralU32(1)}, Span: Span{Start: 10, End: 12}},
+				},
+				Body: Block{{Kind: StmtStore{Pointer: 0, Value: 1}}},
+			}},
+		}
+	}
+
+	errs, _ := Validate(module(StorageRead))
+	if !containsError(errs, `store to read-only storage buffer "buf" (@group(0) @binding(2))`) {
+		t.Fatalf("expected a read-only store error, got %v", errs)
+	}
+	if want := (Span{Start: 4, End: 12}); len(errs[0].Spans) != 1 || errs[0].Spans[0] != want {
+		t.Errorf("Spans = %v, want [%v]", errs[0].Spans, want)
+	}
+	if errs, _ := Validate(module(StorageReadWrite)); containsError(errs, "read-only") {
+		t.Errorf("unexpected error for read_write buffer: %v", errs)
+	}
+}
+
+func TestValidateSemantic_StorageTextureAccess(t *testing.T) {
+	module := func(access StorageAccess, load bool) *Module {
+		fn := Function{
+			Name: "main",
+			Expressions: []Expression{
+				{Kind: ExprGlobalVariable{Variable: 0}},
+				{Kind: Literal{Value: LiteralI32(0)}},
+				{Kind: ExprCompose{Type: 2, Components: []ExpressionHandle{1, 1}}},
+				{Kind: ExprZeroValue{Type: 3}},
+			},
+		}
+		if load {
+			fn.Expressions = append(fn.Expressions, Expression{Kind: ExprImageLoad{Image: 0, Coordinate: 2}})
+		} else {
+			fn.Body = Block{{Kind: StmtImageStore{Image: 0, Coordinate: 2, Value: 3}}}
+		}
+		return &Module{
+			Types: []Type{
+				{Inner: ImageType{Dim: Dim2D, Class: ImageClassStorage, StorageFormat: StorageFormatRgba8Unorm, StorageAccess: access}},
+				{Inner: ScalarType{Kind: ScalarSint, Width: 4}},
+				{Inner: VectorType{Size: Vec2, Scalar: ScalarType{Kind: ScalarSint, Width: 4}}},
+				{Inner: VectorType{Size: Vec4, Scalar: ScalarType{Kind: ScalarFloat, Width: 4}}},
+			},
+			GlobalVariables: []GlobalVariable{{
+				Name: "tex", Space: SpaceHandle, Type: 0,
+				Binding: &ResourceBinding{Group: 1, Binding: 0},
+			}},
+			Functions: []Function{fn},
+		}
+	}
+
+	expectErrors(t, module(StorageAccessWrite, true), `textureLoad from write-only storage texture "tex" (@group(1) @binding(0))`)
+	expectErrors(t, module(StorageAccessRead, false), `textureStore to read-only storage texture "tex"`)
+	for _, m := range []*Module{module(StorageAccessReadWrite, true), module(StorageAccessReadWrite, false)} {
+		if errs, _ := Validate(m); containsError(errs, "storage texture") {
+			t.Errorf("unexpected error for read_write texture: %v", errs)
+		}
+	}
+}
+
 func TestValidateSemantic_SwitchMultipleDefaults(t *testing.T) {
 	module := &Module{
 		Functions: []Function{
@@ -1281,6 +1514,63 @@ func TestValidateSemantic_ExprImageQueryInvalid(t *testing.T) {
 	expectErrors(t, module, "image expression 999 does not exist")
 }
 
+func TestValidateSemantic_ExprImageQueryLevel(t *testing.T) {
+	query := func(image, level ExpressionHandle) Expression {
+		return Expression{Kind: ExprImageQuery{Image: image, Query: ImageQuerySize{Level: &level}}}
+	}
+	module := &Module{
+		Types: []Type{
+			{Inner: ImageType{Dim: Dim2D, Class: ImageClassSampled, SampledKind: ScalarFloat}},
+			{Inner: ImageType{Dim: Dim2D, Class: ImageClassSampled, SampledKind: ScalarFloat, Multisampled: true}},
+			{Inner: ImageType{Dim: Dim2D, Class: ImageClassStorage, StorageFormat: StorageFormatRgba8Unorm, StorageAccess: StorageAccessWrite}},
+		},
+		GlobalVariables: []GlobalVariable{
+			{Name: "t", Space: SpaceHandle, Type: 0},
+			{Name: "tm", Space: SpaceHandle, Type: 1},
+			{Name: "ts", Space: SpaceHandle, Type: 2},
+		},
+		Functions: []Function{
+			{
+				Name: "fn",
+				Expressions: []Expression{
+					{Kind: ExprGlobalVariable{Variable: 0}},
+					{Kind: ExprGlobalVariable{Variable: 1}},
+					{Kind: ExprGlobalVariable{Variable: 2}},
+					{Kind: Literal{Value: LiteralU32(1)}},
+					{Kind: Literal{Value: LiteralF32(1)}},
+					query(0, 3),
+					query(0, 4),
+					query(1, 3),
+					query(2, 3),
+				},
+				Body: []Statement{{Kind: StmtEmit{Range: Range{Start: 5, End: 9}}}},
+			},
+		},
+	}
+	errs, err := Validate(module)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[ExpressionHandle]string{
+		6: "image size query level must be an i32 or u32",
+		7: "multisampled textures have no level to query the size of",
+		8: "storage textures have no level to query the size of",
+	}
+	for _, e := range errs {
+		if e.Expression == nil {
+			continue
+		}
+		if msg, ok := want[*e.Expression]; ok && strings.Contains(e.Error(), msg) {
+			delete(want, *e.Expression)
+		} else {
+			t.Errorf("unexpected error: %v", e)
+		}
+	}
+	for h, msg := range want {
+		t.Errorf("expression %d: missing error %q", h, msg)
+	}
+}
+
 // --- Positive tests for edge cases ---
 
 func TestValidateSemantic_ValidScalarWidths(t *testing.T) {
@@ -1396,3 +1686,101 @@ func TestValidateSemantic_ValidComputeWorkgroup(t *testing.T) {
 		t.Errorf("expected no errors for valid compute workgroup, got: %v", errors)
 	}
 }
+
+// sampleModule builds a fragment entry point that samples image with
+// sampler at a coordinate of type coord.
+func sampleModule(image ImageType, sampler SamplerType, coord TypeInner, depthRef, arrayIndex bool) *Module {
+	f32 := ScalarType{Kind: ScalarFloat, Width: 4}
+	module := &Module{
+		Types: []Type{
+			{Inner: image},
+			{Inner: sampler},
+			{Inner: coord},
+			{Inner: f32},
+		},
+		GlobalVariables: []GlobalVariable{
+			{Name: "t", Space: SpaceHandle, Binding: &ResourceBinding{Group: 0, Binding: 0}, Type: 0},
+			{Name: "s", Space: SpaceHandle, Binding: &ResourceBinding{Group: 0, Binding: 1}, Type: 1},
+		},
+	}
+	coordType, f32Type := TypeHandle(2), TypeHandle(3)
+	sample := ExprImageSample{Image: 0, Sampler: 1, Coordinate: 2, Level: SampleLevelAuto{}}
+	if depthRef {
+		ref := ExpressionHandle(3)
+		sample.DepthRef = &ref
+	}
+	if arrayIndex {
+		idx := ExpressionHandle(3)
+		sample.ArrayIndex = &idx
+	}
+	module.EntryPoints = []EntryPoint{{
+		Name:  "main",
+		Stage: StageFragment,
+		Function: Function{
+			Name: "main",
+			Expressions: []Expression{
+				{Kind: ExprGlobalVariable{Variable: 0}},
+				{Kind: ExprGlobalVariable{Variable: 1}},
+				{Kind: ExprZeroValue{Type: coordType}},
+				{Kind: ExprZeroValue{Type: f32Type}},
+				{Kind: sample},
+			},
+			Body: []Statement{{Kind: StmtEmit{Range: Range{Start: 4, End: 5}}}},
+		},
+	}}
+	return module
+}
+
+func TestValidateSemantic_ImageSampleCompatibility(t *testing.T) {
+	f32 := ScalarType{Kind: ScalarFloat, Width: 4}
+	vec2 := VectorType{Size: Vec2, Scalar: f32}
+	vec3 := VectorType{Size: Vec3, Scalar: f32}
+	tex2D := ImageType{Dim: Dim2D, Class: ImageClassSampled, SampledKind: ScalarFloat}
+	depth2D := ImageType{Dim: Dim2D, Class: ImageClassDepth}
+	plain, comparison := SamplerType{}, SamplerType{Comparison: true}
+
+	tests := []struct {
+		name       string
+		image      ImageType
+		sampler    SamplerType
+		coord      TypeInner
+		depthRef   bool
+		arrayIndex bool
+		want       string
+	}{
+		{name: "sampled", image: tex2D, sampler: plain, coord: vec2},
+		{name: "depth_plain_sample", image: depth2D, sampler: plain, coord: vec2},
+		{name: "depth_compare", image: depth2D, sampler: comparison, coord: vec2, depthRef: true},
+		{name: "cube", image: ImageType{Dim: DimCube, Class: ImageClassSampled, SampledKind: ScalarFloat}, sampler: plain, coord: vec3},
+		{name: "arrayed", image: ImageType{Dim: Dim2D, Arrayed: true, Class: ImageClassSampled, SampledKind: ScalarFloat}, sampler: plain, coord: vec2, arrayIndex: true},
+		{name: "compare_plain_sampler", image: depth2D, sampler: plain, coord: vec2, depthRef: true, want: "depth comparison requires a sampler_comparison"},
+		{name: "compare_color_texture", image: tex2D, sampler: comparison, coord: vec2, depthRef: true, want: "depth comparison requires a depth texture"},
+		{name: "comparison_sampler_without_ref", image: depth2D, sampler: comparison, coord: vec2, want: "sampler_comparison can only be used for depth comparison"},
+		{name: "multisampled", image: ImageType{Dim: Dim2D, Class: ImageClassSampled, Multisampled: true}, sampler: plain, coord: vec2, want: "multisampled textures cannot be sampled"},
+		{name: "storage", image: ImageType{Dim: Dim2D, Class: ImageClassStorage}, sampler: plain, coord: vec2, want: "storage textures cannot be sampled"},
+		{name: "integer", image: ImageType{Dim: Dim2D, Class: ImageClassSampled, SampledKind: ScalarSint}, sampler: plain, coord: vec2, want: "only float textures can be sampled"},
+		{name: "coordinate_size", image: tex2D, sampler: plain, coord: vec3, want: "2D texture needs a vec2<f32> coordinate"},
+		{name: "coordinate_kind", image: ImageType{Dim: Dim1D, Class: ImageClassSampled, SampledKind: ScalarFloat}, sampler: plain, coord: ScalarType{Kind: ScalarSint, Width: 4}, want: "1D texture needs a f32 coordinate"},
+		{name: "missing_array_index", image: ImageType{Dim: Dim2D, Arrayed: true, Class: ImageClassSampled, SampledKind: ScalarFloat}, sampler: plain, coord: vec2, want: "arrayed texture requires an array index"},
+		{name: "extra_array_index", image: tex2D, sampler: plain, coord: vec2, arrayIndex: true, want: "array index given for a non-arrayed texture"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			module := sampleModule(tt.image, tt.sampler, tt.coord, tt.depthRef, tt.arrayIndex)
+			if tt.want != "" {
+				expectErrors(t, module, "in function main, expression 4: "+tt.want)
+				return
+			}
+			if errs, _ := Validate(module); len(errs) != 0 {
+				t.Errorf("unexpected errors: %v", errs)
+			}
+		})
+	}
+}
+
+func TestValidateSemantic_ImageSampleNotATexture(t *testing.T) {
+	f32 := ScalarType{Kind: ScalarFloat, Width: 4}
+	module := sampleModule(ImageType{}, SamplerType{}, f32, false, false)
+	module.Types[0].Inner = f32
+	expectErrors(t, module, "image expression 0 is not a texture")
+}
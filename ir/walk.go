@@ -0,0 +1,135 @@
+// Copyright 2025 The GoGPU Authors
+// SPDX-License-Identifier: MIT
+
+package ir
+
+// WalkStatements walks block depth first, including the bodies of blocks,
+// ifs, switch cases, and loops. enter is called on each statement before
+// its nested blocks; returning false skips them and the statement's exit
+// call. exit, if not nil, is called after them.
+//
+// Statements are visited in place: a callback may replace a statement by
+// assigning through the pointer, and the walk then descends into the
+// replacement's nested blocks. Nested blocks share their backing arrays
+// with the statements holding them, so clone the function first (see
+// CloneFunction) when the original must stay intact.
+func WalkStatements(block Block, enter func(stmt *Statement) bool, exit func(stmt *Statement)) {
+	for i := range block {
+		stmt := &block[i]
+		if enter != nil && !enter(stmt) {
+			continue
+		}
+		switch s := stmt.Kind.(type) {
+		case StmtBlock:
+			WalkStatements(s.Block, enter, exit)
+		case StmtIf:
+			WalkStatements(s.Accept, enter, exit)
+			WalkStatements(s.Reject, enter, exit)
+		case StmtSwitch:
+			for _, c := range s.Cases {
+				WalkStatements(c.Body, enter, exit)
+			}
+		case StmtLoop:
+			WalkStatements(s.Body, enter, exit)
+			WalkStatements(s.Continuing, enter, exit)
+		}
+		if exit != nil {
+			exit(stmt)
+		}
+	}
+}
+
+// WalkExpressions walks the expressions root depends on, depth first
+// through their operands. enter is called on each expression before its
+// operands; returning false skips them and the expression's exit call.
+// exit, if not nil, is called after them. An expression reached along several paths is visited once.
+//
+// Expressions are visited in place, so a callback may replace one by
+// assigning through the pointer; its handle, and every reference to it,
+// stays the same. The walk descends into the replacement's operands.
+func WalkExpressions(exprs []Expression, root ExpressionHandle, enter func(h ExpressionHandle, expr *Expression) bool, exit func(h ExpressionHandle, expr *Expression)) {
+	visited := make([]bool, len(exprs))
+	var walk func(h ExpressionHandle)
+	walk = func(h ExpressionHandle) {
+		if int(h) >= len(exprs) || visited[h] {
+			return
+		}
+		visited[h] = true
+		expr := &exprs[h]
+		if enter != nil && !enter(h, expr) {
+			return
+		}
+		ExpressionOperands(expr.Kind, walk)
+		if exit != nil {
+			exit(h, expr)
+		}
+	}
+	walk(root)
+}
+
+// ExpressionOperands calls visit for each expression handle kind uses
+// directly as an operand, in field order.
+func ExpressionOperands(kind ExpressionKind, visit func(ExpressionHandle)) {
+	visitExprOperands(kind, visit)
+}
+
+// StatementOperands calls visit for each expression handle stmt refers to
+// directly: its operands and the result expressions it defines, but not
+// the statements of nested blocks or the range of an Emit.
+func StatementOperands(stmt StatementKind, visit func(ExpressionHandle)) {
+	visitOpt := func(h *ExpressionHandle) {
+		if h != nil {
+			visit(*h)
+		}
+	}
+	switch s := stmt.(type) {
+	case StmtIf:
+		visit(s.Condition)
+	case StmtSwitch:
+		visit(s.Selector)
+	case StmtLoop:
+		visitOpt(s.BreakIf)
+	case StmtReturn:
+		visitOpt(s.Value)
+	case StmtStore:
+		visit(s.Pointer)
+		visit(s.Value)
+	case StmtImageStore:
+		visit(s.Image)
+		visit(s.Coordinate)
+		visitOpt(s.ArrayIndex)
+		visit(s.Value)
+	case StmtCall:
+		for _, a := range s.Arguments {
+			visit(a)
+		}
+		visitOpt(s.Result)
+	case StmtAtomic:
+		visit(s.Pointer)
+		markAtomicFunctionRefs(s.Fun, visitOpt)
+		visit(s.Value)
+		visitOpt(s.Result)
+	case StmtWorkGroupUniformLoad:
+		visit(s.Pointer)
+		visit(s.Result)
+	case StmtRayQuery:
+		visit(s.Query)
+		markRayQueryFunctionRefs(s.Fun, visit)
+	case StmtSubgroupBallot:
+		visitOpt(s.Predicate)
+		visit(s.Result)
+	case StmtSubgroupGather:
+		markGatherModeRefs(s.Mode, visit)
+		visit(s.Argument)
+		visit(s.Result)
+	case StmtImageAtomic:
+		visit(s.Image)
+		visit(s.Coordinate)
+		visitOpt(s.ArrayIndex)
+		markAtomicFunctionRefs(s.Fun, visitOpt)
+		visit(s.Value)
+	case StmtSubgroupCollectiveOperation:
+		visit(s.Argument)
+		visit(s.Result)
+	}
+}
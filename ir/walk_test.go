@@ -0,0 +1,117 @@
+// Copyright 2025 The GoGPU Authors
+// SPDX-License-Identifier: MIT
+
+package ir
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestWalkStatements(t *testing.T) {
+	fn := CloneFunction(&cloneTestModule().Functions[0])
+
+	var entered, exited []string
+	name := func(s *Statement) string { return reflect.TypeOf(s.Kind).Name() }
+	WalkStatements(fn.Body, func(s *Statement) bool {
+		entered = append(entered, name(s))
+		return true
+	}, func(s *Statement) {
+		exited = append(exited, name(s))
+	})
+	wantEnter := []string{"StmtEmit", "StmtLoop", "StmtCall", "StmtAtomic", "StmtSwitch", "StmtReturn"}
+	wantExit := []string{"StmtEmit", "StmtCall", "StmtAtomic", "StmtLoop", "StmtReturn", "StmtSwitch"}
+	if !reflect.DeepEqual(entered, wantEnter) {
+		t.Errorf("enter order = %v, want %v", entered, wantEnter)
+	}
+	if !reflect.DeepEqual(exited, wantExit) {
+		t.Errorf("exit order = %v, want %v", exited, wantExit)
+	}
+
+	// Returning false from enter skips nested blocks.
+	entered, exited = nil, nil
+	WalkStatements(fn.Body, func(s *Statement) bool {
+		entered = append(entered, name(s))
+		return false
+	}, nil)
+	if len(entered) != 3 {
+		t.Errorf("skipping nested blocks entered %v, want the 3 top-level statements", entered)
+	}
+
+	// Replacing a nested statement writes through to the function body.
+	WalkStatements(fn.Body, func(s *Statement) bool {
+		if _, ok := s.Kind.(StmtReturn); ok {
+			s.Kind = StmtKill{}
+		}
+		return true
+	}, nil)
+	body := fn.Body[2].Kind.(StmtSwitch).Cases[0].Body
+	if _, ok := body[0].Kind.(StmtKill); !ok {
+		t.Errorf("replaced statement is %T, want StmtKill", body[0].Kind)
+	}
+}
+
+func TestWalkExpressions(t *testing.T) {
+	// [0] global texture, [1] global sampler, [2] coordinate literal,
+	// [3] splat of [2], [4] sample of [0] [1] [3], [5] [4] + [4]
+	exprs := []Expression{
+		{Kind: ExprGlobalVariable{Variable: 0}},
+		{Kind: ExprGlobalVariable{Variable: 1}},
+		{Kind: Literal{Value: LiteralF32(0.5)}},
+		{Kind: ExprSplat{Size: Vec2, Value: 2}},
+		{Kind: ExprImageSample{Image: 0, Sampler: 1, Coordinate: 3, Level: SampleLevelAuto{}}},
+		{Kind: ExprBinary{Op: BinaryAdd, Left: 4, Right: 4}},
+	}
+
+	var exits []ExpressionHandle
+	var textures []uint32
+	WalkExpressions(exprs, 5, func(h ExpressionHandle, e *Expression) bool {
+		if sample, ok := e.Kind.(ExprImageSample); ok {
+			if gv, ok := exprs[sample.Image].Kind.(ExprGlobalVariable); ok {
+				textures = append(textures, uint32(gv.Variable))
+			}
+		}
+		return true
+	}, func(h ExpressionHandle, e *Expression) {
+		exits = append(exits, h)
+	})
+	if want := []ExpressionHandle{0, 1, 2, 3, 4, 5}; !reflect.DeepEqual(exits, want) {
+		t.Errorf("post-order = %v, want %v (each expression once)", exits, want)
+	}
+	if !reflect.DeepEqual(textures, []uint32{0}) {
+		t.Errorf("sampled textures = %v, want [0]", textures)
+	}
+
+	// Rebind the texture: replace the global reference in place.
+	WalkExpressions(exprs, 5, func(h ExpressionHandle, e *Expression) bool {
+		if gv, ok := e.Kind.(ExprGlobalVariable); ok && gv.Variable == 0 {
+			e.Kind = ExprGlobalVariable{Variable: 7}
+		}
+		return true
+	}, nil)
+	if gv := exprs[0].Kind.(ExprGlobalVariable); gv.Variable != 7 {
+		t.Errorf("texture global = %d, want 7", gv.Variable)
+	}
+}
+
+func TestStatementOperands(t *testing.T) {
+	one, two := ExpressionHandle(1), ExpressionHandle(2)
+	tests := []struct {
+		stmt StatementKind
+		want []ExpressionHandle
+	}{
+		{StmtStore{Pointer: 3, Value: 4}, []ExpressionHandle{3, 4}},
+		{StmtCall{Arguments: []ExpressionHandle{5, 6}, Result: &two}, []ExpressionHandle{5, 6, 2}},
+		{StmtAtomic{Pointer: 3, Fun: AtomicExchange{Compare: &one}, Value: 4, Result: &two}, []ExpressionHandle{3, 1, 4, 2}},
+		{StmtImageStore{Image: 0, Coordinate: 1, ArrayIndex: &two, Value: 3}, []ExpressionHandle{0, 1, 2, 3}},
+		{StmtIf{Condition: 9, Accept: Block{{Kind: StmtReturn{Value: &one}}}}, []ExpressionHandle{9}},
+		{StmtEmit{Range: Range{Start: 0, End: 4}}, nil},
+	}
+	for _, tt := range tests {
+		var got []ExpressionHandle
+		StatementOperands(tt.stmt, func(h ExpressionHandle) { got = append(got, h) })
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("StatementOperands(%T) = %v, want %v", tt.stmt, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,179 @@
+// Copyright 2025 The GoGPU Authors
+// SPDX-License-Identifier: MIT
+
+package ir
+
+// WriteMask describes a store that replaces only some components of a
+// vector. WGSL cannot assign to a multi-component swizzle, so a partial
+// assignment such as GLSL's v.zx = n reaches the IR as a store of a
+// Compose whose untouched components reread the stored vector, the same
+// shape an OpVectorShuffle of the old and new values takes.
+type WriteMask struct {
+	// Targets are the components of the stored vector that change, in
+	// component order.
+	Targets []SwizzleComponent
+
+	// Values are the new scalar value of each target component.
+	Values []ExpressionHandle
+}
+
+// StoreWriteMask reports whether store keeps some components of the
+// vector it writes, returning the components it replaces. A component is
+// kept when the stored Compose takes it from a load of the same pointer,
+// either as a component of the loaded vector or as a load through a
+// pointer to the component, and that load still holds the current value:
+// it is emitted in the block of the store with only other Emits, and
+// stores to other variables, in between. Stores that replace every
+// component, or none, are not write masks.
+func StoreWriteMask(module *Module, fn *Function, store StmtStore) (WriteMask, bool) {
+	if int(store.Value) >= len(fn.Expressions) {
+		return WriteMask{}, false
+	}
+	compose, ok := fn.Expressions[store.Value].Kind.(ExprCompose)
+	if !ok || int(compose.Type) >= len(module.Types) {
+		return WriteMask{}, false
+	}
+	vec, ok := module.Types[compose.Type].Inner.(VectorType)
+	if !ok || len(compose.Components) != int(vec.Size) {
+		// A component is itself a vector.
+		return WriteMask{}, false
+	}
+
+	before, ok := statementsBefore(fn.Body, store)
+	if !ok {
+		return WriteMask{}, false
+	}
+	var mask WriteMask
+	for i, c := range compose.Components {
+		load, ok := rereadsComponent(fn, c, store.Pointer, uint32(i))
+		if !ok || !loadIsCurrent(fn, before, load, store.Pointer) {
+			mask.Targets = append(mask.Targets, SwizzleComponent(i))
+			mask.Values = append(mask.Values, c)
+		}
+	}
+	if len(mask.Targets) == 0 || len(mask.Targets) == len(compose.Components) {
+		return WriteMask{}, false
+	}
+	return mask, true
+}
+
+// Source reports whether every value is a constant-index component of one
+// vector expression, returning that vector and the components read, so
+// the store can be written as v.zx = n.yx.
+func (m WriteMask) Source(fn *Function) (ExpressionHandle, []SwizzleComponent, bool) {
+	var source ExpressionHandle
+	components := make([]SwizzleComponent, len(m.Values))
+	for i, v := range m.Values {
+		access, ok := fn.Expressions[v].Kind.(ExprAccessIndex)
+		if !ok || access.Index > uint32(SwizzleW) || (i > 0 && access.Base != source) {
+			return 0, nil, false
+		}
+		source, components[i] = access.Base, SwizzleComponent(access.Index)
+	}
+	return source, components, true
+}
+
+// ReadsTarget reports whether any value loads through a pointer into the
+// variable store writes. Such a store cannot be split into one store per
+// component, since an early store could change what a later value reads.
+func (m WriteMask) ReadsTarget(fn *Function, store StmtStore) bool {
+	root := pointerRoot(fn.Expressions, store.Pointer)
+	reads := false
+	for _, v := range m.Values {
+		WalkExpressions(fn.Expressions, v, func(_ ExpressionHandle, expr *Expression) bool {
+			if load, ok := expr.Kind.(ExprLoad); ok && (root == nil || pointerRoot(fn.Expressions, load.Pointer) == root) {
+				reads = true
+			}
+			return !reads
+		}, nil)
+	}
+	return reads
+}
+
+// rereadsComponent reports whether h is component index of the vector
+// pointer points to, read back unchanged, returning the load that reads
+// it.
+func rereadsComponent(fn *Function, h, pointer ExpressionHandle, index uint32) (ExpressionHandle, bool) {
+	switch e := fn.Expressions[h].Kind.(type) {
+	case ExprAccessIndex:
+		load, ok := fn.Expressions[e.Base].Kind.(ExprLoad)
+		return e.Base, ok && e.Index == index && samePointer(fn, load.Pointer, pointer)
+	case ExprLoad:
+		access, ok := fn.Expressions[e.Pointer].Kind.(ExprAccessIndex)
+		return h, ok && access.Index == index && samePointer(fn, access.Base, pointer)
+	}
+	return 0, false
+}
+
+// statementsBefore returns the statements that precede store in the
+// block holding it.
+func statementsBefore(block Block, store StmtStore) (Block, bool) {
+	for i, stmt := range block {
+		if s, ok := stmt.Kind.(StmtStore); ok && s == store {
+			return block[:i], true
+		}
+		for _, body := range stmtSubBlocks(stmt) {
+			if before, ok := statementsBefore(body, store); ok {
+				return before, true
+			}
+		}
+	}
+	return nil, false
+}
+
+// loadIsCurrent reports whether load, emitted in before, reads the value
+// pointer has at the end of before: nothing after its Emit may write
+// there. Only Emits and stores to other variables are known not to.
+func loadIsCurrent(fn *Function, before Block, load, pointer ExpressionHandle) bool {
+	root := pointerRoot(fn.Expressions, pointer)
+	for i := len(before) - 1; i >= 0; i-- {
+		switch s := before[i].Kind.(type) {
+		case StmtEmit:
+			if s.Range.Start <= load && load < s.Range.End {
+				return true
+			}
+		case StmtStore:
+			if !distinctVariables(root, pointerRoot(fn.Expressions, s.Pointer)) {
+				return false
+			}
+		default:
+			return false
+		}
+	}
+	return false
+}
+
+// distinctVariables reports whether the pointer roots a and b are two
+// different variables. A pointer argument may point into either.
+func distinctVariables(a, b ExpressionKind) bool {
+	switch a.(type) {
+	case ExprLocalVariable, ExprGlobalVariable:
+	default:
+		return false
+	}
+	switch b.(type) {
+	case ExprLocalVariable, ExprGlobalVariable:
+		return a != b
+	}
+	return false
+}
+
+// samePointer reports whether a and b are access chains that address the
+// same memory: the same variable, reached through the same constant
+// indices or the same index expressions.
+func samePointer(fn *Function, a, b ExpressionHandle) bool {
+	if a == b {
+		return true
+	}
+	switch x := fn.Expressions[a].Kind.(type) {
+	case ExprLocalVariable, ExprGlobalVariable, ExprFunctionArgument:
+		return fn.Expressions[b].Kind == x
+	case ExprAccessIndex:
+		y, ok := fn.Expressions[b].Kind.(ExprAccessIndex)
+		return ok && x.Index == y.Index && samePointer(fn, x.Base, y.Base)
+	case ExprAccess:
+		y, ok := fn.Expressions[b].Kind.(ExprAccess)
+		return ok && x.Index == y.Index && samePointer(fn, x.Base, y.Base)
+	}
+	return false
+}
@@ -0,0 +1,102 @@
+// Copyright 2025 The GoGPU Authors
+// SPDX-License-Identifier: MIT
+
+package ir
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestStoreWriteMask(t *testing.T) {
+	f32 := ScalarType{Kind: ScalarFloat, Width: 4}
+	m := &Module{Types: []Type{{Inner: VectorType{Size: Vec4, Scalar: f32}}}}
+	fn := &Function{
+		LocalVars: []LocalVariable{{Name: "v", Type: 0}, {Name: "n", Type: 0}},
+		Expressions: []Expression{
+			{Kind: ExprLocalVariable{Variable: 0}},     // 0: &v
+			{Kind: ExprLoad{Pointer: 0}},               // 1: v
+			{Kind: ExprLocalVariable{Variable: 1}},     // 2: &n
+			{Kind: ExprLoad{Pointer: 2}},               // 3: n
+			{Kind: ExprAccessIndex{Base: 3, Index: 1}}, // 4: n.y
+			{Kind: ExprAccessIndex{Base: 1, Index: 1}}, // 5: v.y
+			{Kind: ExprAccessIndex{Base: 3, Index: 0}}, // 6: n.x
+			{Kind: ExprLocalVariable{Variable: 0}},     // 7: &v again
+			{Kind: ExprAccessIndex{Base: 7, Index: 3}}, // 8: &v.w
+			{Kind: ExprLoad{Pointer: 8}},               // 9: v.w
+			{Kind: ExprCompose{Type: 0, Components: []ExpressionHandle{4, 5, 6, 9}}},
+			{Kind: ExprCompose{Type: 0, Components: []ExpressionHandle{5, 4, 6, 9}}},
+			{Kind: ExprCompose{Type: 0, Components: []ExpressionHandle{4, 4, 6, 6}}},
+		},
+	}
+
+	// body makes the statements the given stores, after the Emit of every
+	// expression.
+	body := func(stores ...StmtStore) {
+		fn.Body = Block{{Kind: StmtEmit{Range: Range{Start: 1, End: 13}}}}
+		for _, s := range stores {
+			fn.Body = append(fn.Body, Statement{Kind: s})
+		}
+	}
+
+	// v = vec4(n.y, v.y, n.x, v.w) is v.xz = n.yx.
+	body(StmtStore{Pointer: 0, Value: 10})
+	mask, ok := StoreWriteMask(m, fn, StmtStore{Pointer: 0, Value: 10})
+	if !ok {
+		t.Fatal("no write mask")
+	}
+	if want := []SwizzleComponent{SwizzleX, SwizzleZ}; !reflect.DeepEqual(mask.Targets, want) {
+		t.Errorf("targets = %v, want %v", mask.Targets, want)
+	}
+	source, read, ok := mask.Source(fn)
+	if want := []SwizzleComponent{SwizzleY, SwizzleX}; !ok || source != 3 || !reflect.DeepEqual(read, want) {
+		t.Errorf("source = %d %v %v, want 3 %v", source, read, ok, want)
+	}
+	if mask.ReadsTarget(fn, StmtStore{Pointer: 0, Value: 10}) {
+		t.Error("v.xz = n.yx reads v")
+	}
+
+	// v = vec4(v.y, n.y, n.x, v.w) moves v.y into x before replacing it.
+	store := StmtStore{Pointer: 0, Value: 11}
+	body(store)
+	if mask, ok = StoreWriteMask(m, fn, store); !ok {
+		t.Fatal("no write mask for the swap")
+	}
+	if !mask.ReadsTarget(fn, store) {
+		t.Error("a value taken from v does not read v")
+	}
+
+	// Replacing every component, or storing to another variable, is a
+	// whole store.
+	body(StmtStore{Pointer: 0, Value: 12}, StmtStore{Pointer: 2, Value: 10})
+	if _, ok := StoreWriteMask(m, fn, StmtStore{Pointer: 0, Value: 12}); ok {
+		t.Error("write mask for a store of every component")
+	}
+	if _, ok := StoreWriteMask(m, fn, StmtStore{Pointer: 2, Value: 10}); ok {
+		t.Error("write mask for a store to another variable")
+	}
+
+	// A store to n between the loads and the store to v leaves the loads
+	// of v current.
+	body(StmtStore{Pointer: 2, Value: 12}, StmtStore{Pointer: 0, Value: 10})
+	if _, ok := StoreWriteMask(m, fn, StmtStore{Pointer: 0, Value: 10}); !ok {
+		t.Error("no write mask after a store to another variable")
+	}
+
+	// A store to v after the loads makes them stale: the components they
+	// read are old values to write, not components to keep.
+	body(StmtStore{Pointer: 8, Value: 6}, StmtStore{Pointer: 0, Value: 10})
+	if mask, ok := StoreWriteMask(m, fn, StmtStore{Pointer: 0, Value: 10}); ok {
+		t.Errorf("write mask %v over a stale load", mask.Targets)
+	}
+
+	// A load emitted outside the block of the store is not known to be
+	// current either.
+	fn.Body = Block{
+		{Kind: StmtEmit{Range: Range{Start: 1, End: 13}}},
+		{Kind: StmtBlock{Block: Block{{Kind: StmtStore{Pointer: 0, Value: 10}}}}},
+	}
+	if mask, ok := StoreWriteMask(m, fn, StmtStore{Pointer: 0, Value: 10}); ok {
+		t.Errorf("write mask %v over a load from an enclosing block", mask.Targets)
+	}
+}
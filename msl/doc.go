@@ -24,12 +24,28 @@
 //
 // # MSL Language Versions
 //
-// The backend supports MSL 1.2 through 3.0. Features used depend on the target version:
-//   - MSL 1.2: Basic shaders, most texture operations
-//   - MSL 2.0: Tessellation, indirect command buffers
-//   - MSL 2.1: Improved array handling
-//   - MSL 2.3: Ray tracing, 64-bit atomics
-//   - MSL 3.0: Mesh shaders, extended features
+// The backend targets MSL 1.0 through 3.2 and rejects any other
+// LangVersion. Some constructs need a newer version than the one asked for:
+//   - MSL 1.2: dual-source blending
+//   - MSL 2.1: invariant outputs
+//   - MSL 2.3: barycentric coordinates, view_index
+//   - MSL 2.4: ray queries
+//   - MSL 3.0: f32 atomics, mesh and object functions
+//   - MSL 3.1: 64-bit atomic textures
+//
+// The output is written for the newest version the module needs, which
+// TranslationInfo.LangVersion reports; with Options.StrictVersion, Compile
+// fails instead. bfloat and residency sets are not generated yet.
+//
+// # Mesh and Object Functions
+//
+// A task entry point becomes an [[object]] function and a mesh entry point
+// a [[mesh]] function. The task payload is their object_data [[payload]]
+// argument. One thread of the object function hands the returned grid size
+// to metal::mesh_grid_properties. The mesh function writes its output
+// variable in threadgroup memory as WGSL does, and copies it into the
+// metal::mesh argument before returning. Workgroup sizes and output limits
+// set by overrides are rejected.
 //
 // # Type Mapping
 //
@@ -67,6 +83,11 @@
 //   - fragment: Fragment shaders with [[position]], [[color(N)]], etc.
 //   - kernel: Compute shaders with [[thread_position_in_grid]], etc.
 //
+// Metal's [[vertex_id]] and [[instance_id]] already include the draw's base
+// vertex and base instance, for direct and indirect draws alike, so WGSL's
+// vertex_index and instance_index map to them without the correction the
+// HLSL backend needs.
+//
 // # Helper Functions
 //
 // Some WGSL operations require polyfill functions in MSL:
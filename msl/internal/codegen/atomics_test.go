@@ -0,0 +1,59 @@
+package codegen
+
+import (
+	"strings"
+	"testing"
+)
+
+// atomicsShader exercises atomics in struct members, in runtime-sized arrays
+// of structs and in workgroup memory. The runtime-sized global is the second
+// binding so its buffer size slot is not the first one.
+const atomicsShader = `
+struct Bar { value: f32, atom: atomic<i32> }
+struct Cell { value: f32, count: atomic<u32> }
+@group(0) @binding(0) var<storage, read_write> bar: Bar;
+@group(0) @binding(1) var<storage, read_write> cells: array<Cell>;
+var<workgroup> wg_count: atomic<u32>;
+var<workgroup> wg_arr: array<atomic<i32>, 4>;
+
+@compute @workgroup_size(4)
+fn main(@builtin(local_invocation_index) li: u32) {
+    atomicAdd(&bar.atom, 1);
+    atomicAdd(&cells[li].count, 1u);
+    atomicMax(&wg_arr[li], 2);
+    let r = atomicCompareExchangeWeak(&cells[li].count, 0u, 1u);
+    if r.exchanged { atomicAdd(&wg_count, 1u); }
+}
+`
+
+func TestAtomicsInStructsAndWorkgroup(t *testing.T) {
+	code := compileWGSL(t, atomicsShader)
+
+	for _, want := range []string{
+		"metal::atomic_int atom;",
+		"metal::atomic_uint count;",
+		"metal::atomic_store_explicit(&wg_count, 0, metal::memory_order_relaxed);",
+		"metal::atomic_fetch_add_explicit(&bar.atom, 1, metal::memory_order_relaxed);",
+		"metal::atomic_fetch_max_explicit(&wg_arr.inner[li], 2, metal::memory_order_relaxed)",
+		"naga_atomic_compare_exchange_weak_explicit(&uint(li) < 1 + (_buffer_sizes.size1 - 0 - 8) / 8 ? cells[li].count : DefaultConstructible()",
+	} {
+		if !strings.Contains(code, want) {
+			t.Errorf("missing %q in:\n%s", want, code)
+		}
+	}
+	if strings.Contains(code, "_buffer_sizes.size0") {
+		t.Errorf("runtime-sized binding 1 must use size1:\n%s", code)
+	}
+	if strings.Contains(code, "&uint(li) < 1 + (_buffer_sizes.size1 - 0 - 8) / 8 ? cells[li].count : oob") {
+		t.Errorf("atomic pointer must not fall back to oob:\n%s", code)
+	}
+}
+
+func TestAtomicOnRuntimeArrayIsBoundsChecked(t *testing.T) {
+	code := compileWGSL(t, atomicsShader)
+	// Statement-level atomics on a runtime-sized array skip the operation when
+	// the index is out of bounds instead of taking the address of a temporary.
+	if !strings.Contains(code, "(_buffer_sizes.size1 - 0 - 8) / 8 ? metal::atomic_fetch_add_explicit(&cells[li].count") {
+		t.Errorf("atomicAdd on cells[li].count is not bounds checked:\n%s", code)
+	}
+}
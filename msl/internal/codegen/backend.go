@@ -1,7 +1,9 @@
 package codegen
 
 import (
+	"context"
 	"fmt"
+	"log/slog"
 
 	"github.com/gogpu/naga/ir"
 )
@@ -22,8 +24,26 @@ var (
 	Version2_4 = Version{Major: 2, Minor: 4}
 	Version3_0 = Version{Major: 3, Minor: 0}
 	Version3_1 = Version{Major: 3, Minor: 1}
+	Version3_2 = Version{Major: 3, Minor: 2}
 )
 
+// knownVersions lists the MSL versions Metal defines, oldest first. The
+// last is the newest this backend targets.
+var knownVersions = []Version{
+	{1, 0}, {1, 1}, {1, 2}, {2, 0}, {2, 1}, {2, 2}, {2, 3}, {2, 4}, {3, 0}, {3, 1}, {3, 2},
+}
+
+// checkVersion reports an error if v is not an MSL version this backend
+// targets.
+func checkVersion(v Version) error {
+	for _, known := range knownVersions {
+		if v == known {
+			return nil
+		}
+	}
+	return fmt.Errorf("msl: unsupported language version %s (newest supported is %s)", v, knownVersions[len(knownVersions)-1])
+}
+
 // String returns the version as "major.minor".
 func (v Version) String() string {
 	return fmt.Sprintf("%d.%d", v.Major, v.Minor)
@@ -243,6 +263,37 @@ type Options struct {
 	// Required for point primitive topologies.
 	AllowAndForcePointSize bool
 
+	// InvariantPosition marks every vertex position output
+	// [[position, invariant]], as if it were declared @invariant, so a
+	// depth prepass and the main pass compute bit-identical positions.
+	InvariantPosition bool
+
+	// DisableFMAContraction emits "#pragma clang fp contract(off)" so the
+	// Metal compiler does not fuse multiplies and adds into FMAs, which
+	// round differently. Fast math can still reassociate; pair this with
+	// MTLCompileOptions.mathMode = MTLMathModeSafe.
+	DisableFMAContraction bool
+
+	// FastMathSafeFloatChecks emits isnan/isinf as integer tests on the
+	// float's bit pattern. Metal compiles with fast math by default, which
+	// assumes NaN and Inf never occur and folds metal::isnan to false.
+	FastMathSafeFloatChecks bool
+
+	// PreciseMath calls the metal::precise variants of f32 transcendental
+	// functions instead of the ones fast math selects, and implies
+	// DisableFMAContraction and FastMathSafeFloatChecks, so results do not
+	// depend on the GPU's approximations.
+	PreciseMath bool
+
+	// FloatPrecision is the number of significant digits written for float
+	// literals; zero writes the shortest digits that round-trip.
+	FloatPrecision int
+
+	// WriteMaskStores writes a store that replaces only some components of
+	// a vector (see ir.StoreWriteMask) as an assignment to those
+	// components, such as v.zx = n.yx, instead of storing the whole vector.
+	WriteMaskStores bool
+
 	// VertexPullingTransform enables vertex pulling transformation.
 	// When true, vertex shaders receive raw buffer data instead of assembled
 	// vertex attributes. The shader reads bytes from vertex buffers and
@@ -252,6 +303,26 @@ type Options struct {
 	// VertexBufferMappings describes the vertex buffer layout for vertex pulling.
 	// Each entry describes one vertex buffer with its stride, step mode, and attributes.
 	VertexBufferMappings []VertexBufferMapping
+
+	// EntryPointNames renames entry points (WGSL name → MSL function name).
+	// Renamed entry points ignore SymbolPrefix.
+	EntryPointNames map[string]string
+
+	// SymbolPrefix is prepended to every module-scope symbol (types,
+	// functions, globals, constants, overrides, and entry points not in
+	// EntryPointNames), and to the generated helper functions, so several
+	// generated shaders can share one Metal library.
+	SymbolPrefix string
+
+	// SourceMap fills TranslationInfo.SourceMap.
+	SourceMap bool
+
+	// StrictVersion makes Compile fail when the module needs a newer MSL
+	// version than LangVersion, instead of raising the header's version.
+	StrictVersion bool
+
+	// Logger receives phase traces and polyfill warnings; nil disables logging.
+	Logger *slog.Logger
 }
 
 // VertexFormat describes the format of a vertex attribute.
@@ -355,12 +426,29 @@ type EntryPointSelector struct {
 
 // TranslationInfo contains information about the compiled MSL output.
 type TranslationInfo struct {
+	// LangVersion is the version the output is written for: the
+	// requested LangVersion, or a newer one if the module needs it.
+	LangVersion Version
+
 	// EntryPointNames maps original entry point names to generated MSL names.
 	EntryPointNames map[string]string
 
 	// RequiresSizesBuffer indicates if a sizes buffer is needed for
 	// runtime-sized arrays.
 	RequiresSizesBuffer bool
+
+	// RequiresPreserveInvariance is set when an output is [[invariant]].
+	// Metal honours the attribute only when the library is compiled with
+	// MTLCompileOptions.preserveInvariance enabled.
+	RequiresPreserveInvariance bool
+
+	// SourceMap maps output lines to the WGSL spans they were generated
+	// from, when Options.SourceMap is set.
+	SourceMap []ir.OutputSpan
+
+	// Padding lists the char array members written into structs to keep
+	// the IR layout, in output order.
+	Padding []ir.StructPadding
 }
 
 // Compile generates MSL source code from an IR module.
@@ -371,10 +459,34 @@ func Compile(module *ir.Module, options Options) (string, TranslationInfo, error
 
 // CompileWithPipeline generates MSL source code with pipeline-specific options.
 func CompileWithPipeline(module *ir.Module, options Options, pipeline PipelineOptions) (string, TranslationInfo, error) {
+	return CompileWithPipelineContext(context.Background(), module, options, pipeline)
+}
+
+// CompileWithPipelineContext is like CompileWithPipeline but stops with an
+// error wrapping ctx.Err() once ctx is canceled. Cancellation is checked
+// between functions.
+func CompileWithPipelineContext(ctx context.Context, module *ir.Module, options Options, pipeline PipelineOptions) (string, TranslationInfo, error) {
 	// Apply defaults for zero values
 	if options.LangVersion.Major == 0 {
 		options.LangVersion = Version2_1
 	}
+	if err := checkVersion(options.LangVersion); err != nil {
+		return "", TranslationInfo{}, err
+	}
+	if options.PreciseMath {
+		options.DisableFMAContraction = true
+		options.FastMathSafeFloatChecks = true
+	}
+
+	// Override-sized workgroup arrays take the length the pipeline
+	// constants, or else the overrides' defaults, give.
+	if ir.HasOverrideSizedArrays(module) {
+		sized, err := ir.ResolveOverrideArraySizes(module, options.PipelineConstants)
+		if err != nil {
+			return "", TranslationInfo{}, fmt.Errorf("msl: %w", err)
+		}
+		module = sized
+	}
 
 	// Apply pipeline constants to override values if any are specified.
 	if len(options.PipelineConstants) > 0 && len(module.Overrides) > 0 {
@@ -383,16 +495,29 @@ func CompileWithPipeline(module *ir.Module, options Options, pipeline PipelineOp
 
 	// Create writer
 	w := newWriter(module, &options, &pipeline)
+	w.ctx = ctx
+	w.SourceMap = options.SourceMap
+	w.notes.Phase("msl: writing module", "version", options.LangVersion.String(), "entry_points", len(module.EntryPoints))
 
 	// Generate MSL code
 	if err := w.writeModule(); err != nil {
 		return "", TranslationInfo{}, fmt.Errorf("msl: %w", err)
 	}
+	w.notes.Phase("msl: module written", "bytes", w.Out.Len())
+	if options.StrictVersion && options.LangVersion.Less(w.minRequiredVersion) {
+		return "", TranslationInfo{}, fmt.Errorf("msl: module uses %s, which needs MSL %s, but LangVersion is %s",
+			w.versionFeature, w.minRequiredVersion, options.LangVersion)
+	}
 
 	info := TranslationInfo{
-		EntryPointNames:     w.entryPointNames,
-		RequiresSizesBuffer: w.needsSizesBuffer,
+		LangVersion:                w.effectiveVersion(),
+		EntryPointNames:            w.entryPointNames,
+		RequiresSizesBuffer:        w.needsSizesBuffer,
+		RequiresPreserveInvariance: w.usesInvariance,
+		Padding:                    w.padding,
 	}
 
-	return w.String(), info, nil
+	code, spans := w.StripMarks(w.String())
+	info.SourceMap = spans
+	return code, info, nil
 }
@@ -1,6 +1,8 @@
 package codegen
 
 import (
+	"context"
+	"errors"
 	"runtime"
 	"strings"
 	"testing"
@@ -927,3 +929,21 @@ func ptrExpr(h ir.ExpressionHandle) *ir.ExpressionHandle {
 func bindingPtr(b ir.Binding) *ir.Binding {
 	return &b
 }
+
+func TestMSL_CompileContextCanceled(t *testing.T) {
+	module := &ir.Module{
+		EntryPoints: []ir.EntryPoint{{
+			Name:      "main",
+			Stage:     ir.StageCompute,
+			Workgroup: [3]uint32{1, 1, 1},
+		}},
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, _, err := CompileWithPipelineContext(ctx, module, DefaultOptions(), PipelineOptions{}); !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context.Canceled, got %v", err)
+	}
+	if _, _, err := CompileWithPipelineContext(context.Background(), module, DefaultOptions(), PipelineOptions{}); err != nil {
+		t.Fatalf("CompileWithPipelineContext failed: %v", err)
+	}
+}
@@ -3,7 +3,6 @@ package codegen
 import (
 	"fmt"
 	"math"
-	"strconv"
 	"strings"
 
 	"github.com/gogpu/naga/ir"
@@ -353,14 +352,7 @@ func (w *Writer) writeLiteral(lit ir.Literal) error {
 			w.write("NAN")
 		} else {
 			// Format f16 with 'h' suffix, matching Rust naga.
-			s := strconv.FormatFloat(float64(val), 'f', -1, 32)
-			// If integer, add ".0h" suffix; otherwise just "h".
-			if !strings.Contains(s, ".") {
-				s += ".0h"
-			} else {
-				s += "h"
-			}
-			w.write("%s", s)
+			w.write("%sh", w.formatFloat(float64(val), 32))
 		}
 
 	case ir.LiteralF32:
@@ -374,12 +366,8 @@ func (w *Writer) writeLiteral(lit ir.Literal) error {
 		} else if math.IsNaN(float64(val)) {
 			w.write("NAN")
 		} else {
-			// Use strconv with 'f' format (no exponent) matching Rust's Display for f32.
-			s := strconv.FormatFloat(float64(val), 'f', -1, 32)
-			if !strings.Contains(s, ".") {
-				s += ".0"
-			}
-			w.write("%s", s)
+			// No exponent, matching Rust's Display for f32.
+			w.write("%s", w.formatFloat(float64(val), 32))
 		}
 
 	case ir.LiteralF64:
@@ -393,11 +381,7 @@ func (w *Writer) writeLiteral(lit ir.Literal) error {
 		} else if math.IsNaN(val) {
 			w.write("NAN")
 		} else {
-			s := strconv.FormatFloat(val, 'f', -1, 64)
-			if !strings.Contains(s, ".") {
-				s += ".0"
-			}
-			w.write("%s", s)
+			w.write("%s", w.formatFloat(val, 64))
 		}
 
 	case ir.LiteralAbstractInt:
@@ -416,11 +400,7 @@ func (w *Writer) writeLiteral(lit ir.Literal) error {
 		} else if math.IsNaN(float64(val)) {
 			w.write("NAN")
 		} else {
-			s := strconv.FormatFloat(float64(val), 'f', -1, 32)
-			if !strings.Contains(s, ".") {
-				s += ".0"
-			}
-			w.write("%s", s)
+			w.write("%s", w.formatFloat(float64(val), 32))
 		}
 
 	default:
@@ -1451,7 +1431,7 @@ func (w *Writer) writeUnary(unary ir.ExprUnary) error {
 				}
 			}
 			w.registerNegHelper(*argScalar, vecSize)
-			w.write("naga_neg(")
+			w.write("%s(", w.helperName("naga_neg"))
 			if err := w.writeExpression(unary.Expr); err != nil {
 				return err
 			}
@@ -1484,7 +1464,7 @@ func (w *Writer) writeBinary(binary ir.ExprBinary, _ ir.ExpressionHandle) error
 		// Matches Rust naga: per-type naga_div using metal::select.
 		if o, ok := w.getIntegerOverload(binary.Left); ok {
 			w.addDivOverload(o)
-			w.write("naga_div(")
+			w.write("%s(", w.helperName("naga_div"))
 			if err := w.writeExpression(binary.Left); err != nil {
 				return err
 			}
@@ -1501,7 +1481,7 @@ func (w *Writer) writeBinary(binary ir.ExprBinary, _ ir.ExpressionHandle) error
 		// Matches Rust naga: per-type naga_mod using metal::select.
 		if o, ok := w.getIntegerOverload(binary.Left); ok {
 			w.addModOverload(o)
-			w.write("naga_mod(")
+			w.write("%s(", w.helperName("naga_mod"))
 			if err := w.writeExpression(binary.Left); err != nil {
 				return err
 			}
@@ -1725,7 +1705,7 @@ func (w *Writer) writeMath(mathExpr ir.ExprMath) error {
 			}
 			if scalar.Kind == ir.ScalarSint {
 				w.registerAbsHelper(scalar, vecSize)
-				w.write("naga_abs(")
+				w.write("%s(", w.helperName("naga_abs"))
 				if err := w.writeExpression(mathExpr.Arg); err != nil {
 					return err
 				}
@@ -1990,7 +1970,13 @@ func (w *Writer) writeMath(mathExpr ir.ExprMath) error {
 	}
 
 	// Standard function call
-	w.write("%s%s(", Namespace, funcName)
+	ns := Namespace
+	if w.options.PreciseMath && preciseMathFunctions[funcName] {
+		if s := w.getExpressionScalarType(mathExpr.Arg); s != nil && s.Kind == ir.ScalarFloat && s.Width == 4 {
+			ns += "precise::"
+		}
+	}
+	w.write("%s%s(", ns, funcName)
 	if err := w.writeExpression(mathExpr.Arg); err != nil {
 		return err
 	}
@@ -2016,6 +2002,14 @@ func (w *Writer) writeMath(mathExpr ir.ExprMath) error {
 	return nil
 }
 
+// preciseMathFunctions are the math functions metal::precise provides f32
+// overloads of, which Options.PreciseMath calls.
+var preciseMathFunctions = map[string]bool{
+	"acos": true, "acosh": true, "asin": true, "asinh": true, "atan": true, "atan2": true, "atanh": true,
+	"cos": true, "cosh": true, "sin": true, "sinh": true, "tan": true, "tanh": true,
+	"exp": true, "exp2": true, "log": true, "log2": true, "pow": true, "sqrt": true, "rsqrt": true,
+}
+
 // mathFunctionName returns the MSL name for a math function.
 func mathFunctionName(fun ir.MathFunction) string {
 	switch fun {
@@ -2295,7 +2289,7 @@ func (w *Writer) writeModf(mathExpr ir.ExprMath) error {
 		scalar = &ir.ScalarType{Kind: ir.ScalarFloat, Width: 4}
 	}
 	w.registerModfResult(*scalar, vectorSize)
-	w.write("naga_modf(")
+	w.write("%s(", w.helperName("naga_modf"))
 	if err := w.writeExpression(mathExpr.Arg); err != nil {
 		return err
 	}
@@ -2311,7 +2305,7 @@ func (w *Writer) writeFrexp(mathExpr ir.ExprMath) error {
 		scalar = &ir.ScalarType{Kind: ir.ScalarFloat, Width: 4}
 	}
 	w.registerFrexpResult(*scalar, vectorSize)
-	w.write("naga_frexp(")
+	w.write("%s(", w.helperName("naga_frexp"))
 	if err := w.writeExpression(mathExpr.Arg); err != nil {
 		return err
 	}
@@ -2504,7 +2498,7 @@ func (w *Writer) writeAs(as ir.ExprAs) error {
 		} else if isFloatToInt {
 			dstScalar := ir.ScalarType{Kind: as.Kind, Width: *as.Convert}
 			w.registerF2IHelper(*srcScalar, srcVecSize, dstScalar)
-			w.write("%s(", f2iFunctionName(dstScalar))
+			w.write("%s(", w.helperName(f2iFunctionName(dstScalar)))
 			if err := w.writeExpression(as.Expr); err != nil {
 				return err
 			}
@@ -2618,7 +2612,7 @@ func (w *Writer) writeImageSample(sample ir.ExprImageSample) error {
 	// Matches Rust naga's nagaTextureSampleBaseClampToEdge wrapper.
 	if sample.ClampToEdge {
 		w.needsTextureSampleBaseClampToEdge = true
-		w.write("nagaTextureSampleBaseClampToEdge(")
+		w.write("%s(", w.helperName("nagaTextureSampleBaseClampToEdge"))
 		if err := w.writeExpression(sample.Image); err != nil {
 			return err
 		}
@@ -2701,7 +2695,7 @@ func (w *Writer) writeImageSample(sample ir.ExprImageSample) error {
 		}
 		w.write(")")
 	case ir.SampleLevelGradient:
-		w.write(", %sgradient2d(", Namespace)
+		w.write(", %s%s(", Namespace, w.sampleGradientFunction(sample.Image))
 		if err := w.writeExpression(level.X); err != nil {
 			return err
 		}
@@ -2735,6 +2729,21 @@ func (w *Writer) writeImageSample(sample ir.ExprImageSample) error {
 	return nil
 }
 
+// sampleGradientFunction returns the Metal gradient options type matching
+// the dimension of the sampled image: gradient3d and gradientcube take 3D
+// derivatives, everything else gradient2d.
+func (w *Writer) sampleGradientFunction(imageHandle ir.ExpressionHandle) string {
+	if imgType := w.getImageType(imageHandle); imgType != nil {
+		switch imgType.Dim {
+		case ir.Dim3D:
+			return "gradient3d"
+		case ir.DimCube:
+			return "gradientcube"
+		}
+	}
+	return "gradient2d"
+}
+
 // isImageCubeMap checks if the image expression is a cube map texture.
 func (w *Writer) isImageCubeMap(imageHandle ir.ExpressionHandle) bool {
 	imgType := w.getImageType(imageHandle)
@@ -2751,7 +2760,7 @@ func (w *Writer) writeImageLoad(load ir.ExprImageLoad, handle ir.ExpressionHandl
 	imgType := w.getImageType(load.Image)
 	if imgType != nil && imgType.Class == ir.ImageClassExternal {
 		w.needsExternalTextureLoad = true
-		w.write("nagaTextureLoadExternal(")
+		w.write("%s(", w.helperName("nagaTextureLoadExternal"))
 		if err := w.writeExpression(load.Image); err != nil {
 			return err
 		}
@@ -3241,7 +3250,7 @@ func (w *Writer) writeImageQuerySize(image ir.ExpressionHandle, level *ir.Expres
 	// External textures: call nagaTextureDimensionsExternal helper.
 	if imgType := w.resolveImageType(image); imgType != nil && imgType.Class == ir.ImageClassExternal {
 		w.needsExternalTextureDimensions = true
-		w.write("nagaTextureDimensionsExternal(")
+		w.write("%s(", w.helperName("nagaTextureDimensionsExternal"))
 		if err := w.writeExpression(image); err != nil {
 			return err
 		}
@@ -3349,7 +3358,7 @@ func (w *Writer) writeImageQuerySize(image ir.ExpressionHandle, level *ir.Expres
 // resolveImageType resolves the type of an image expression and returns the ImageType,
 // or nil if the type cannot be resolved.
 func (w *Writer) resolveImageType(image ir.ExpressionHandle) *ir.ImageType {
-	exprType, err := ir.ResolveExpressionType(w.module, w.currentFunction, image)
+	exprType, err := ir.ExpressionType(w.module, w.currentFunction, image)
 	if err != nil {
 		return nil
 	}
@@ -3396,6 +3405,17 @@ func (w *Writer) writeRelational(rel ir.ExprRelational) error {
 	case ir.RelationalIsInf:
 		funcName = "isinf"
 	}
+	if rel.Fun == ir.RelationalAll || rel.Fun == ir.RelationalAny {
+		// metal::all/any take vectors; on a scalar bool they are the identity.
+		if s, ok := w.getExpressionType(rel.Argument).(ir.ScalarType); ok && s.Kind == ir.ScalarBool {
+			return w.writeExpression(rel.Argument)
+		}
+	}
+	if (rel.Fun == ir.RelationalIsNan || rel.Fun == ir.RelationalIsInf) && w.options.FastMathSafeFloatChecks {
+		if done, err := w.writeFloatBitsCheck(rel); done || err != nil {
+			return err
+		}
+	}
 
 	w.write("%s%s(", Namespace, funcName)
 	if err := w.writeExpression(rel.Argument); err != nil {
@@ -3405,6 +3425,48 @@ func (w *Writer) writeRelational(rel ir.ExprRelational) error {
 	return nil
 }
 
+// writeFloatBitsCheck writes isnan/isinf as a test on the argument's bit
+// pattern, which fast math cannot fold away: with the sign masked off, Inf is
+// exactly the all-ones exponent and NaN is anything above it.
+func (w *Writer) writeFloatBitsCheck(rel ir.ExprRelational) (bool, error) {
+	var scalar ir.ScalarType
+	var size ir.VectorSize
+	switch t := w.getExpressionType(rel.Argument).(type) {
+	case ir.ScalarType:
+		scalar = t
+	case ir.VectorType:
+		scalar, size = t.Scalar, t.Size
+	default:
+		return false, nil
+	}
+	if scalar.Kind != ir.ScalarFloat {
+		return false, nil
+	}
+	var bitsType, mask, inf string
+	switch scalar.Width {
+	case 2:
+		bitsType, mask, inf = "ushort", "0x7fff", "0x7c00"
+	case 4:
+		bitsType, mask, inf = "uint", "0x7fffffffu", "0x7f800000u"
+	default:
+		return false, nil
+	}
+	if size != 0 {
+		bitsType = fmt.Sprintf("%s%s%d", Namespace, bitsType, size)
+	}
+	op := "=="
+	if rel.Fun == ir.RelationalIsNan {
+		op = ">"
+	}
+
+	w.write("((%sas_type<%s>(", Namespace, bitsType)
+	if err := w.writeExpression(rel.Argument); err != nil {
+		return true, err
+	}
+	w.write(") & %s) %s %s)", mask, op, inf)
+	return true, nil
+}
+
 // writeArrayLength writes a runtime array length query.
 // Emits "1 + (_buffer_sizes.sizeN - offset - elementSize) / stride".
 // The Array expression refers to a GlobalVariable or AccessIndex into a struct
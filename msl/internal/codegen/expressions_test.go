@@ -1422,3 +1422,35 @@ func TestMSL_IfElseStatement(t *testing.T) {
 	mustContainMSL(t, result, "if (")
 	mustContainMSL(t, result, "} else {")
 }
+
+// TestMSL_DeadUserDoesNotBakeLoad verifies that a load used only by a dead
+// expression is not baked into an unused temporary.
+func TestMSL_DeadUserDoesNotBakeLoad(t *testing.T) {
+	ret := ir.ExpressionHandle(4)
+	module := &ir.Module{
+		Types: []ir.Type{
+			{Name: "f32", Inner: ir.ScalarType{Kind: ir.ScalarFloat, Width: 4}},
+		},
+		Functions: []ir.Function{
+			{
+				Name:      "f",
+				Result:    &ir.FunctionResult{Type: 0},
+				LocalVars: []ir.LocalVariable{{Name: "x", Type: 0}},
+				Expressions: []ir.Expression{
+					{Kind: ir.ExprLocalVariable{Variable: 0}},                       // [0] &x
+					{Kind: ir.ExprLoad{Pointer: 0}},                                 // [1] x, only used by [2]
+					{Kind: ir.ExprBinary{Op: ir.BinaryMultiply, Left: 1, Right: 1}}, // [2] dead
+					{Kind: ir.Literal{Value: ir.LiteralF32(2.0)}},                   // [3]
+					{Kind: ir.ExprUnary{Op: ir.UnaryNegate, Expr: 3}},               // [4] returned
+				},
+				Body: []ir.Statement{
+					{Kind: ir.StmtEmit{Range: ir.Range{Start: 1, End: 5}}},
+					{Kind: ir.StmtReturn{Value: &ret}},
+				},
+			},
+		},
+	}
+	result := compileModule(t, module)
+	mustNotContainMSL(t, result, "_e1")
+	mustContainMSL(t, result, "return -(2.0);")
+}
@@ -39,6 +39,7 @@ const (
 	attrPosition            = "[[position]]"
 	spaceConstant           = "constant"
 	spaceDevice             = "device"
+	spaceObjectData         = "object_data"
 	interpCenterPerspective = "center_perspective"
 )
 
@@ -172,7 +173,8 @@ func (w *Writer) writeLocalVars(fn *ir.Function) error {
 // for resources — they must be passed through from entry points.
 func needsPassThrough(space ir.AddressSpace) bool {
 	switch space {
-	case ir.SpaceUniform, ir.SpaceStorage, ir.SpaceHandle, ir.SpacePrivate, ir.SpaceWorkGroup, ir.SpaceImmediate:
+	case ir.SpaceUniform, ir.SpaceStorage, ir.SpaceHandle, ir.SpacePrivate, ir.SpaceWorkGroup, ir.SpaceImmediate,
+		ir.SpaceTaskPayload:
 		return true
 	default:
 		return false
@@ -494,6 +496,7 @@ func (w *Writer) writeEntryPoint(epIdx int, ep *ir.EntryPoint) error {
 		w.entryPointInputStructArg = -1
 		w.flattenedMemberNames = nil
 		w.hasVaryings = false
+		w.meshEntry = nil
 	}()
 
 	// Determine if this entry point should do vertex pulling.
@@ -539,6 +542,13 @@ func (w *Writer) writeEntryPoint(epIdx int, ep *ir.EntryPoint) error {
 		stageKeyword = "fragment"
 	case ir.StageCompute:
 		stageKeyword = "kernel"
+	case ir.StageTask, ir.StageMesh:
+		me, err := w.beginMeshEntry(epIdx, ep)
+		if err != nil {
+			return err
+		}
+		w.meshEntry = me
+		stageKeyword = "[[" + meshStageName(ep.Stage) + "]]"
 	default:
 		return fmt.Errorf("msl: unsupported shader stage %d for entry point %q", ep.Stage, ep.Name)
 	}
@@ -565,6 +575,10 @@ func (w *Writer) writeEntryPoint(epIdx int, ep *ir.EntryPoint) error {
 		return returnType, w.writeBindingAttribute(*fn.Result.Binding)
 	}
 	returnType, returnAttr := resolveReturnSignature()
+	if w.meshEntry != nil {
+		// The mesh grid size of a task shader goes to __mesh_grid.
+		returnType, returnAttr = "void", ""
+	}
 
 	// Function signature — Rust naga format:
 	// First param: "\n  param", subsequent: "\n, param"
@@ -581,6 +595,14 @@ func (w *Writer) writeEntryPoint(epIdx int, ep *ir.EntryPoint) error {
 			epUsedGlobals[h] = struct{}{}
 		}
 	}
+	// The payload is an argument, and the mesh output is copied to
+	// __mesh, even if the body never uses them.
+	if ep.TaskPayload != nil && w.meshEntry != nil {
+		epUsedGlobals[uint32(*ep.TaskPayload)] = struct{}{}
+	}
+	if ep.Stage == ir.StageMesh {
+		epUsedGlobals[uint32(ep.MeshInfo.OutputVariable)] = struct{}{}
+	}
 
 	// Check if we need workgroup zero-initialization for this entry point.
 	// This requires: compute shader + ZeroInitializeWorkgroupMemory + workgroup vars
@@ -588,7 +610,7 @@ func (w *Writer) writeEntryPoint(epIdx int, ep *ir.EntryPoint) error {
 	// !fun_info[handle].is_empty()).
 	needWorkgroupInit := false
 	localInvocationIDName := ""
-	if w.options.ZeroInitializeWorkgroupMemory && ep.Stage == ir.StageCompute {
+	if w.options.ZeroInitializeWorkgroupMemory && (ep.Stage == ir.StageCompute || w.meshEntry != nil) {
 		for i, global := range w.module.GlobalVariables {
 			if _, used := epUsedGlobals[uint32(i)]; !used {
 				continue
@@ -599,6 +621,15 @@ func (w *Writer) writeEntryPoint(epIdx int, ep *ir.EntryPoint) error {
 			}
 		}
 	}
+	switch ep.Stage {
+	case ir.StageTask:
+		w.writeEntryPointParam(paramCount, fmt.Sprintf("%smesh_grid_properties %s", Namespace, meshGridArgName))
+		paramCount++
+	case ir.StageMesh:
+		w.writeEntryPointParam(paramCount, fmt.Sprintf("%s %s", w.meshParamType(w.meshEntry), meshArgName))
+		paramCount++
+	}
+
 	// Stage input struct — only emit if there are actual varyings (location-bound members).
 	// Rust naga uses has_varyings to decide whether to emit the stage_in parameter.
 	if w.hasVaryings {
@@ -611,6 +642,7 @@ func (w *Writer) writeEntryPoint(epIdx int, ep *ir.EntryPoint) error {
 	// Track if we find a local_invocation_id builtin to reuse for workgroup init.
 	// For VPT: also track existing vertex_id and instance_id.
 	existingLocalInvocationID := ""
+	existingLocalInvocationIndex := ""
 	vptExistingVertexID := ""
 	vptExistingInstanceID := ""
 	for i, arg := range fn.Arguments {
@@ -628,6 +660,9 @@ func (w *Writer) writeEntryPoint(epIdx int, ep *ir.EntryPoint) error {
 					if builtin.Builtin == ir.BuiltinLocalInvocationID {
 						existingLocalInvocationID = argName
 					}
+					if builtin.Builtin == ir.BuiltinLocalInvocationIndex {
+						existingLocalInvocationIndex = argName
+					}
 					if builtin.Builtin == ir.BuiltinVertexIndex {
 						vptExistingVertexID = argName
 					}
@@ -668,6 +703,9 @@ func (w *Writer) writeEntryPoint(epIdx int, ep *ir.EntryPoint) error {
 			if builtin.Builtin == ir.BuiltinLocalInvocationID {
 				existingLocalInvocationID = memberName
 			}
+			if builtin.Builtin == ir.BuiltinLocalInvocationIndex {
+				existingLocalInvocationIndex = memberName
+			}
 			if builtin.Builtin == ir.BuiltinVertexIndex {
 				vptExistingVertexID = memberName
 			}
@@ -677,6 +715,17 @@ func (w *Writer) writeEntryPoint(epIdx int, ep *ir.EntryPoint) error {
 		}
 	}
 
+	// Object and mesh functions pick one thread to set the mesh grid or
+	// the primitive count, by its index in the threadgroup.
+	if w.meshEntry != nil {
+		w.meshEntry.localIndex = existingLocalInvocationIndex
+		if w.meshEntry.localIndex == "" {
+			w.meshEntry.localIndex = meshLocalIndexArg
+			w.writeEntryPointParam(paramCount, fmt.Sprintf("uint %s [[thread_index_in_threadgroup]]", meshLocalIndexArg))
+			paramCount++
+		}
+	}
+
 	// Workgroup zero-init parameter: __local_invocation_id.
 	// Emitted AFTER builtin parameters but BEFORE resource bindings.
 	// Matches Rust naga: only emit if no existing local_invocation_id argument.
@@ -736,6 +785,9 @@ func (w *Writer) writeEntryPoint(epIdx int, ep *ir.EntryPoint) error {
 			attr := w.resolveImmediatesBufferBinding(ep.Name)
 			w.writeEntryPointParam(paramCount, fmt.Sprintf("constant %s& %s %s", typeName, name, attr))
 			paramCount++
+		} else if global.Space == ir.SpaceTaskPayload {
+			w.writeEntryPointParam(paramCount, w.taskPayloadParam(uint32(i), ep.Stage))
+			paramCount++
 		}
 	}
 
@@ -909,6 +961,9 @@ func (w *Writer) writeEntryPoint(epIdx int, ep *ir.EntryPoint) error {
 	if err := w.writeBlock(fn.Body); err != nil {
 		return err
 	}
+	if ep.Stage == ir.StageMesh && !blockEndsWithReturn(fn.Body) {
+		w.writeMeshOutput()
+	}
 
 	w.PopIndent()
 	w.WriteLine("}")
@@ -1499,7 +1554,7 @@ func (w *Writer) writePassThroughParam(handle uint32) {
 		space := addressSpaceName(global.Space)
 		typeName := w.writeTypeName(global.Type, StorageAccess(0))
 
-		if space == spaceConstant || space == spaceDevice {
+		if space == spaceConstant || space == spaceDevice || space == spaceObjectData {
 			constQual := ""
 			if w.isStorageGlobalReadOnly(handle) {
 				constQual = " const"
@@ -1680,7 +1735,7 @@ func (w *Writer) writeInlineSamplers(epName string, epUsedGlobals map[uint32]str
 func (w *Writer) writeBindingAttribute(binding ir.Binding) string {
 	switch b := binding.(type) {
 	case ir.BuiltinBinding:
-		return builtinOutputAttribute(b)
+		return builtinOutputAttribute(w.outputBuiltin(b))
 	case ir.LocationBinding:
 		return fmt.Sprintf("[[color(%d)]]", b.Location)
 	}
@@ -1692,8 +1747,9 @@ func (w *Writer) writeBindingAttribute(binding ir.Binding) string {
 func (w *Writer) outputMemberAttribute(binding ir.Binding, stage ir.ShaderStage) string {
 	switch b := binding.(type) {
 	case ir.BuiltinBinding:
+		b = w.outputBuiltin(b)
 		if b.Invariant {
-			w.requireVersion(Version2_1)
+			w.requireVersion(Version2_1, "invariant outputs")
 		}
 		return builtinOutputAttribute(b)
 	case ir.LocationBinding:
@@ -1708,7 +1764,7 @@ func (w *Writer) outputMemberAttribute(binding ir.Binding, stage ir.ShaderStage)
 		case ir.StageFragment:
 			// Fragment outputs use color() with optional dual-source index()
 			if b.BlendSrc != nil {
-				w.requireVersion(Version1_2) // dual source blending requires Metal 1.2
+				w.requireVersion(Version1_2, "dual-source blending")
 				return fmt.Sprintf("[[color(%d) index(%d)]]", b.Location, *b.BlendSrc)
 			}
 			return fmt.Sprintf("[[color(%d)]]", b.Location)
@@ -1724,9 +1780,9 @@ func (w *Writer) outputMemberAttribute(binding ir.Binding, stage ir.ShaderStage)
 func (w *Writer) requireBuiltinVersion(builtin ir.BuiltinValue) {
 	switch builtin {
 	case ir.BuiltinBarycentric:
-		w.requireVersion(Version2_3)
+		w.requireVersion(Version2_3, "barycentric coordinates")
 	case ir.BuiltinViewIndex:
-		w.requireVersion(Version2_3)
+		w.requireVersion(Version2_3, "view_index")
 	}
 }
 
@@ -1776,6 +1832,18 @@ func builtinInputAttribute(builtin ir.BuiltinValue, stage ir.ShaderStage) string
 	return ""
 }
 
+// outputBuiltin applies Options.InvariantPosition to a built-in output and
+// records invariant outputs for TranslationInfo.
+func (w *Writer) outputBuiltin(b ir.BuiltinBinding) ir.BuiltinBinding {
+	if b.Builtin == ir.BuiltinPosition && w.options != nil && w.options.InvariantPosition {
+		b.Invariant = true
+	}
+	if b.Invariant {
+		w.usesInvariance = true
+	}
+	return b
+}
+
 // builtinOutputAttribute returns the MSL attribute for a built-in output.
 func builtinOutputAttribute(binding ir.BuiltinBinding) string {
 	switch binding.Builtin {
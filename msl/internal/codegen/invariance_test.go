@@ -0,0 +1,99 @@
+package codegen
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/gogpu/naga/ir"
+)
+
+const invarianceShader = `
+struct VsOut {
+    @builtin(position) pos: vec4<f32>,
+    @location(0) uv: vec2<f32>,
+}
+
+@group(0) @binding(0) var<uniform> mvp: mat4x4<f32>;
+
+@vertex
+fn vs_struct(@location(0) p: vec3<f32>) -> VsOut {
+    return VsOut(mvp * vec4<f32>(p, 1.0), p.xy);
+}
+
+@vertex
+fn vs_plain(@location(0) p: vec3<f32>) -> @builtin(position) vec4<f32> {
+    return mvp * vec4<f32>(p, 1.0);
+}
+
+@fragment
+fn fs(@location(0) uv: vec2<f32>) -> @location(0) vec4<f32> {
+    return vec4<f32>(uv, 0.0, 1.0);
+}
+`
+
+func TestInvariantPositionOption(t *testing.T) {
+	opts := DefaultOptions()
+	opts.FakeMissingBindings = true
+
+	code, info := compileWGSLWithInfo(t, invarianceShader, opts)
+	if strings.Contains(code, "invariant") || info.RequiresPreserveInvariance {
+		t.Errorf("invariant emitted without the option (info %+v):\n%s", info, code)
+	}
+
+	opts.InvariantPosition = true
+	code, info = compileWGSLWithInfo(t, invarianceShader, opts)
+	if got := strings.Count(code, "[[position, invariant]]"); got != 2 {
+		t.Errorf("got %d invariant positions, want 2:\n%s", got, code)
+	}
+	if !info.RequiresPreserveInvariance {
+		t.Error("RequiresPreserveInvariance not set")
+	}
+	if strings.Contains(code, "[[user(loc0), invariant]]") {
+		t.Errorf("invariant applied to a non-position output:\n%s", code)
+	}
+}
+
+func TestInvariantAttributeReported(t *testing.T) {
+	src := `
+struct VsOut {
+    @builtin(position) @invariant pos: vec4<f32>,
+}
+
+@vertex
+fn vs() -> VsOut {
+    return VsOut(vec4<f32>(1.0));
+}
+`
+	opts := DefaultOptions()
+	opts.LangVersion = Version2_0
+	code, info := compileWGSLWithInfo(t, src, opts)
+	if !strings.Contains(code, "[[position, invariant]]") || !strings.HasPrefix(code, "// language: metal2.1") {
+		t.Errorf("@invariant output member should need Metal 2.1:\n%s", code)
+	}
+	if !info.RequiresPreserveInvariance {
+		t.Error("RequiresPreserveInvariance not set for @invariant")
+	}
+}
+
+func TestDisableFMAContraction(t *testing.T) {
+	opts := DefaultOptions()
+	opts.FakeMissingBindings = true
+	code, _ := compileWGSLWithInfo(t, invarianceShader, opts)
+	if strings.Contains(code, "fp contract") {
+		t.Errorf("contraction pragma emitted by default:\n%s", code)
+	}
+
+	opts.DisableFMAContraction = true
+	code, _ = compileWGSLWithInfo(t, invarianceShader, opts)
+	if !strings.Contains(code, "#include <simd/simd.h>\n#pragma clang fp contract(off)\n") {
+		t.Errorf("missing contraction pragma after includes:\n%s", code)
+	}
+}
+
+func TestOutputBuiltinLeavesOtherBuiltins(t *testing.T) {
+	w := &Writer{options: &Options{InvariantPosition: true}}
+	b := w.outputBuiltin(ir.BuiltinBinding{Builtin: ir.BuiltinFragDepth})
+	if b.Invariant || w.usesInvariance {
+		t.Errorf("frag_depth marked invariant: %+v", b)
+	}
+}
@@ -0,0 +1,53 @@
+package codegen
+
+import "testing"
+
+const reservedIdentifiersWGSL = `
+struct half {
+    line: f32,
+    texture: f32,
+    point: f32,
+}
+
+@group(0) @binding(0) var<uniform> params: half;
+
+fn texture(line: f32, Texture2D: f32) -> f32 {
+    let kernel = line + Texture2D;
+    return kernel;
+}
+
+@fragment
+fn main() -> @location(0) vec4<f32> {
+    return vec4<f32>(texture(params.line, params.texture) + params.point);
+}
+`
+
+func TestReservedIdentifiersEscaped(t *testing.T) {
+	out := compileWGSL(t, reservedIdentifiersWGSL)
+	for _, want := range []string{
+		"struct half_ {",
+		"float texture;",
+		"float texture(",
+		"float kernel_ =",
+		"fragment main_Output main_(",
+		"constant half_& params",
+	} {
+		mustContainMSL(t, out, want)
+	}
+	// Metal handle types are always spelled metal::texture2d etc., so a
+	// plain "texture" is a valid MSL identifier and stays as is.
+	mustNotContainMSL(t, out, "struct half {")
+}
+
+func TestEscapeNameMetalWords(t *testing.T) {
+	for _, tt := range []struct{ in, want string }{
+		{"half", "half_"},
+		{"texture", "texture"},
+		{"device", "device_"},
+		{"line", "line"},
+	} {
+		if got := escapeName(tt.in); got != tt.want {
+			t.Errorf("escapeName(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
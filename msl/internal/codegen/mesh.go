@@ -0,0 +1,286 @@
+package codegen
+
+import (
+	"fmt"
+
+	"github.com/gogpu/naga/ir"
+)
+
+// Object and mesh functions (MSL 3.0).
+//
+// A WGSL task shader becomes an [[object]] function: the task payload is
+// its object_data [[payload]] argument, and the mesh grid size it returns
+// is handed to metal::mesh_grid_properties instead. A WGSL mesh shader
+// becomes a [[mesh]] function: its output variable stays a threadgroup
+// variable the body writes as usual, and is copied into the metal::mesh
+// argument at every return.
+
+// Synthetic names of the object and mesh function arguments. WGSL
+// identifiers cannot start with two underscores.
+const (
+	meshGridArgName   = "__mesh_grid"
+	meshArgName       = "__mesh"
+	meshLocalIndexArg = "__local_invocation_index"
+)
+
+// meshEntry is the object or mesh function being written.
+type meshEntry struct {
+	stage ir.ShaderStage
+	// localIndex is the name of the thread_index_in_threadgroup argument.
+	localIndex string
+	// threads is the number of threads in the workgroup.
+	threads uint32
+
+	// Mesh functions only.
+	info          *ir.MeshStageInfo
+	vertexStruct  string
+	primStruct    string // "" when no member is per-primitive data
+	indicesMember int    // member of the primitive type holding the indices
+}
+
+// meshTopology returns the metal::topology and the number of indices per
+// primitive of a mesh output topology.
+func meshTopology(t ir.MeshOutputTopology) (string, int) {
+	switch t {
+	case ir.MeshTopologyPoints:
+		return "point", 1
+	case ir.MeshTopologyLines:
+		return "line", 2
+	default:
+		return "triangle", 3
+	}
+}
+
+// beginMeshEntry checks an object or mesh entry point and, for a mesh
+// function, writes the vertex and primitive structs of its metal::mesh.
+func (w *Writer) beginMeshEntry(epIdx int, ep *ir.EntryPoint) (*meshEntry, error) {
+	w.requireVersion(Version3_0, "mesh and object functions")
+	for _, o := range ep.WorkgroupOverrides {
+		if o != nil {
+			return nil, fmt.Errorf("entry point %q: workgroup size set by an override is not supported for %s functions",
+				ep.Name, meshStageName(ep.Stage))
+		}
+	}
+	me := &meshEntry{stage: ep.Stage, threads: ep.Workgroup[0] * ep.Workgroup[1] * ep.Workgroup[2]}
+	if ep.Stage == ir.StageTask {
+		return me, nil
+	}
+	if ep.MeshInfo == nil {
+		return nil, fmt.Errorf("mesh entry point %q has no mesh output", ep.Name)
+	}
+	if ep.MeshInfo.MaxVerticesOverride != nil || ep.MeshInfo.MaxPrimitivesOverride != nil {
+		return nil, fmt.Errorf("entry point %q: mesh output limits set by overrides are not supported", ep.Name)
+	}
+	me.info = ep.MeshInfo
+	me.indicesMember = -1
+
+	epName := w.getName(nameKey{kind: nameKeyEntryPoint, handle1: uint32(epIdx)})
+	vertexType, ok := w.module.Types[me.info.VertexOutputType].Inner.(ir.StructType)
+	if !ok {
+		return nil, fmt.Errorf("mesh entry point %q: vertex output is not a struct", ep.Name)
+	}
+	me.vertexStruct = w.namer.call(epName + "Vertex")
+	w.WriteLine("struct %s {", me.vertexStruct)
+	w.PushIndent()
+	for i, member := range vertexType.Members {
+		if attr := w.meshVertexAttribute(member.Binding); attr != "" {
+			name := w.getName(nameKey{kind: nameKeyStructMember, handle1: uint32(me.info.VertexOutputType), handle2: uint32(i)})
+			w.WriteLine("%s %s %s;", w.writeTypeName(member.Type, StorageAccess(0)), name, attr)
+		}
+	}
+	w.PopIndent()
+	w.WriteLine("};")
+
+	primType, ok := w.module.Types[me.info.PrimitiveOutputType].Inner.(ir.StructType)
+	if !ok {
+		return nil, fmt.Errorf("mesh entry point %q: primitive output is not a struct", ep.Name)
+	}
+	var primMembers []string
+	for i, member := range primType.Members {
+		if member.Binding == nil {
+			continue
+		}
+		if b, ok := (*member.Binding).(ir.BuiltinBinding); ok {
+			switch b.Builtin {
+			case ir.BuiltinPointIndex, ir.BuiltinLineIndices, ir.BuiltinTriangleIndices:
+				me.indicesMember = i
+				continue
+			}
+		}
+		if attr := meshPrimitiveAttribute(*member.Binding); attr != "" {
+			name := w.getName(nameKey{kind: nameKeyStructMember, handle1: uint32(me.info.PrimitiveOutputType), handle2: uint32(i)})
+			primMembers = append(primMembers, fmt.Sprintf("%s %s %s;", w.writeTypeName(member.Type, StorageAccess(0)), name, attr))
+		}
+	}
+	if me.indicesMember < 0 {
+		return nil, fmt.Errorf("mesh entry point %q: primitive output has no indices", ep.Name)
+	}
+	if len(primMembers) > 0 {
+		me.primStruct = w.namer.call(epName + "Primitive")
+		w.WriteLine("struct %s {", me.primStruct)
+		w.PushIndent()
+		for _, m := range primMembers {
+			w.WriteLine("%s", m)
+		}
+		w.PopIndent()
+		w.WriteLine("};")
+	}
+	return me, nil
+}
+
+func meshStageName(stage ir.ShaderStage) string {
+	if stage == ir.StageTask {
+		return "object"
+	}
+	return "mesh"
+}
+
+// meshVertexAttribute returns the attribute of a vertex output member of
+// a mesh function, or "" when it is not passed on.
+func (w *Writer) meshVertexAttribute(binding *ir.Binding) string {
+	if binding == nil {
+		return ""
+	}
+	return w.outputMemberAttribute(*binding, ir.StageVertex)
+}
+
+// meshPrimitiveAttribute returns the attribute of a per-primitive output
+// member of a mesh function, or "" when it is not passed on.
+func meshPrimitiveAttribute(binding ir.Binding) string {
+	switch b := binding.(type) {
+	case ir.BuiltinBinding:
+		switch b.Builtin {
+		case ir.BuiltinCullPrimitive:
+			return "[[primitive_culled]]"
+		case ir.BuiltinPrimitiveIndex:
+			return "[[primitive_id]]"
+		}
+	case ir.LocationBinding:
+		return fmt.Sprintf("[[user(loc%d)]]", b.Location)
+	}
+	return ""
+}
+
+// meshParamType returns the metal::mesh type of a mesh function's output
+// argument.
+func (w *Writer) meshParamType(me *meshEntry) string {
+	topology, _ := meshTopology(me.info.Topology)
+	prim := me.primStruct
+	if prim == "" {
+		prim = "void"
+	}
+	return fmt.Sprintf("%smesh<%s, %s, %d, %d, %stopology::%s>",
+		Namespace, me.vertexStruct, prim, me.info.MaxVertices, me.info.MaxPrimitives, Namespace, topology)
+}
+
+// taskPayloadParam returns the [[payload]] argument of an object or mesh
+// function. A mesh function only reads the payload.
+func (w *Writer) taskPayloadParam(handle uint32, stage ir.ShaderStage) string {
+	global := &w.module.GlobalVariables[handle]
+	name := w.getName(nameKey{kind: nameKeyGlobalVariable, handle1: handle})
+	typeName := w.writeTypeName(global.Type, StorageAccess(0))
+	if stage == ir.StageMesh {
+		return fmt.Sprintf("object_data %s const& %s [[payload]]", typeName, name)
+	}
+	return fmt.Sprintf("object_data %s& %s [[payload]]", typeName, name)
+}
+
+// writeTaskReturn writes the return of an object function: one thread
+// sets the mesh grid size the task shader returned.
+func (w *Writer) writeTaskReturn(value ir.ExpressionHandle) error {
+	w.WriteLine("if (%s == 0u) {", w.meshEntry.localIndex)
+	w.PushIndent()
+	w.WriteIndent()
+	w.write("%s.set_threadgroups_per_grid(", meshGridArgName)
+	if err := w.writeExpression(value); err != nil {
+		return err
+	}
+	w.write(");\n")
+	w.PopIndent()
+	w.WriteLine("}")
+	w.WriteLine("return;")
+	return nil
+}
+
+// writeMeshOutput copies the mesh output variable into the metal::mesh
+// argument, once the whole threadgroup has written it. The counts are
+// clamped to the declared maximums.
+func (w *Writer) writeMeshOutput() {
+	me := w.meshEntry
+	info := me.info
+	out := w.getName(nameKey{kind: nameKeyGlobalVariable, handle1: uint32(info.OutputVariable)})
+	outType := w.module.GlobalVariables[info.OutputVariable].Type
+	st, _ := w.module.Types[outType].Inner.(ir.StructType)
+	var vertices, primitives, vertexCount, primitiveCount string
+	for i, member := range st.Members {
+		if member.Binding == nil {
+			continue
+		}
+		b, ok := (*member.Binding).(ir.BuiltinBinding)
+		if !ok {
+			continue
+		}
+		path := out + "." + w.getName(nameKey{kind: nameKeyStructMember, handle1: uint32(outType), handle2: uint32(i)})
+		switch b.Builtin {
+		case ir.BuiltinVertices:
+			vertices = path + ".inner[__i]"
+		case ir.BuiltinPrimitives:
+			primitives = path + ".inner[__i]"
+		case ir.BuiltinVertexCount:
+			vertexCount = path
+		case ir.BuiltinPrimitiveCount:
+			primitiveCount = path
+		}
+	}
+
+	w.WriteLine("%sthreadgroup_barrier(%smem_flags::mem_threadgroup);", Namespace, Namespace)
+	w.WriteLine("if (%s == 0u) {", me.localIndex)
+	w.PushIndent()
+	w.WriteLine("%s.set_primitive_count(%smin(%s, %du));", meshArgName, Namespace, primitiveCount, info.MaxPrimitives)
+	w.PopIndent()
+	w.WriteLine("}")
+
+	w.WriteLine("for (uint __i = %s; __i < %smin(%s, %du); __i += %du) {",
+		me.localIndex, Namespace, vertexCount, info.MaxVertices, me.threads)
+	w.PushIndent()
+	w.WriteLine("%s.set_vertex(__i, %s { %s });", meshArgName, me.vertexStruct,
+		w.meshMemberList(vertices, info.VertexOutputType, func(b ir.Binding) bool { return w.meshVertexAttribute(&b) != "" }))
+	w.PopIndent()
+	w.WriteLine("}")
+
+	w.WriteLine("for (uint __i = %s; __i < %smin(%s, %du); __i += %du) {",
+		me.localIndex, Namespace, primitiveCount, info.MaxPrimitives, me.threads)
+	w.PushIndent()
+	_, perPrim := meshTopology(info.Topology)
+	indices := primitives + "." + w.getName(nameKey{kind: nameKeyStructMember, handle1: uint32(info.PrimitiveOutputType), handle2: uint32(me.indicesMember)})
+	for k, c := range []string{"x", "y", "z"}[:perPrim] {
+		index := indices
+		if perPrim > 1 {
+			index += "." + c
+		}
+		w.WriteLine("%s.set_index(__i * %du + %du, %s);", meshArgName, perPrim, k, index)
+	}
+	if me.primStruct != "" {
+		w.WriteLine("%s.set_primitive(__i, %s { %s });", meshArgName, me.primStruct,
+			w.meshMemberList(primitives, info.PrimitiveOutputType, func(b ir.Binding) bool { return meshPrimitiveAttribute(b) != "" }))
+	}
+	w.PopIndent()
+	w.WriteLine("}")
+}
+
+// meshMemberList lists the members of the struct at base that keep is
+// true for, as aggregate initializers.
+func (w *Writer) meshMemberList(base string, ty ir.TypeHandle, keep func(ir.Binding) bool) string {
+	st, _ := w.module.Types[ty].Inner.(ir.StructType)
+	list := ""
+	for i, member := range st.Members {
+		if member.Binding == nil || !keep(*member.Binding) {
+			continue
+		}
+		if list != "" {
+			list += ", "
+		}
+		list += base + "." + w.getName(nameKey{kind: nameKeyStructMember, handle1: uint32(ty), handle2: uint32(i)})
+	}
+	return list
+}
@@ -0,0 +1,93 @@
+// Copyright 2025 The GoGPU Authors
+// SPDX-License-Identifier: MIT
+
+package codegen
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/gogpu/naga/internal/testutil"
+)
+
+const meshShader = `
+enable wgpu_mesh_shader;
+
+struct TaskPayload {
+    colorMask: vec4<f32>,
+}
+struct VertexOutput {
+    @builtin(position) position: vec4<f32>,
+    @location(0) color: vec4<f32>,
+}
+struct PrimitiveOutput {
+    @builtin(line_indices) indices: vec2<u32>,
+    @builtin(cull_primitive) cull: bool,
+}
+struct MeshOutput {
+    @builtin(vertices) vertices: array<VertexOutput, 2>,
+    @builtin(primitives) primitives: array<PrimitiveOutput, 1>,
+    @builtin(vertex_count) vertex_count: u32,
+    @builtin(primitive_count) primitive_count: u32,
+}
+
+var<task_payload> payload: TaskPayload;
+var<workgroup> mesh_output: MeshOutput;
+
+fn mask() -> vec4<f32> {
+    return payload.colorMask;
+}
+
+@task @payload(payload) @workgroup_size(4)
+fn ts_main() -> @builtin(mesh_task_size) vec3<u32> {
+    payload.colorMask = vec4(1.0);
+    return vec3(2u, 1u, 1u);
+}
+
+@mesh(mesh_output) @payload(payload) @workgroup_size(2)
+fn ms_main(@builtin(local_invocation_index) index: u32) {
+    mesh_output.vertex_count = 2;
+    mesh_output.primitive_count = 1;
+    mesh_output.vertices[index].position = vec4(f32(index));
+    mesh_output.vertices[index].color = mask();
+    mesh_output.primitives[0].indices = vec2(0u, 1u);
+}
+`
+
+func TestMeshAndObjectFunctions(t *testing.T) {
+	code, info, err := Compile(testutil.LowerWGSL(t, meshShader), DefaultOptions())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.LangVersion != Version3_0 {
+		t.Errorf("written for MSL %s, want 3.0", info.LangVersion)
+	}
+	for _, want := range []string{
+		"[[object]] void ts_main(\n  metal::mesh_grid_properties __mesh_grid\n, uint __local_invocation_index [[thread_index_in_threadgroup]]\n, object_data TaskPayload& payload [[payload]]\n)",
+		"    if (__local_invocation_index == 0u) {\n        __mesh_grid.set_threadgroups_per_grid(metal::uint3(2u, 1u, 1u));\n    }\n    return;\n",
+		"struct ms_mainVertex {\n    metal::float4 position [[position]];\n    metal::float4 color [[user(loc0)",
+		"struct ms_mainPrimitive {\n    bool cull [[primitive_culled]];\n};",
+		"[[mesh]] void ms_main(\n  metal::mesh<ms_mainVertex, ms_mainPrimitive, 2, 1, metal::topology::line> __mesh\n, uint index [[thread_index_in_threadgroup]]\n, metal::uint3 __local_invocation_id [[thread_position_in_threadgroup]]\n, object_data TaskPayload const& payload [[payload]]\n)",
+		"metal::float4 mask(\n    object_data TaskPayload const& payload\n)",
+		"    threadgroup MeshOutput mesh_output;\n",
+		"__mesh.set_primitive_count(metal::min(mesh_output.primitive_count, 1u));",
+		"for (uint __i = index; __i < metal::min(mesh_output.vertex_count, 2u); __i += 2u) {",
+		"__mesh.set_vertex(__i, ms_mainVertex { mesh_output.vertices.inner[__i].position, mesh_output.vertices.inner[__i].color });",
+		"__mesh.set_index(__i * 2u + 1u, mesh_output.primitives.inner[__i].indices.y);",
+		"__mesh.set_primitive(__i, ms_mainPrimitive { mesh_output.primitives.inner[__i].cull });",
+	} {
+		if !strings.Contains(code, want) {
+			t.Errorf("missing %q in:\n%s", want, code)
+		}
+	}
+}
+
+func TestMeshFunctionsStrictVersion(t *testing.T) {
+	opts := DefaultOptions()
+	opts.LangVersion = Version2_4
+	opts.StrictVersion = true
+	_, _, err := Compile(testutil.LowerWGSL(t, meshShader), opts)
+	if err == nil || !strings.Contains(err.Error(), "mesh and object functions") || !strings.Contains(err.Error(), "3.0") {
+		t.Errorf("err = %v, want mesh and object functions needing MSL 3.0", err)
+	}
+}
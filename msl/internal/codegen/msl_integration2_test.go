@@ -473,7 +473,7 @@ func TestIntegration_StorageFormats(t *testing.T) {
 			src := `
 @group(0) @binding(0) var img: texture_storage_2d<` + tt.format + `, write>;
 @compute @workgroup_size(1) fn main() {
-    textureStore(img, vec2(0i, 0i), vec4(0i));
+    textureStore(img, vec2(0i, 0i), vec4(0.0));
 }
 `
 			// Adjust for int/uint types.
@@ -1,6 +1,9 @@
 package codegen
 
 import (
+	"bytes"
+	"log/slog"
+	"strings"
 	"testing"
 
 	"github.com/gogpu/naga/ir"
@@ -30,6 +33,27 @@ fn main() {
 	mustContainMSL(t, code, "100.0")
 }
 
+func TestIntegration8_OverrideSizedWorkgroupArray(t *testing.T) {
+	src := `
+override N: u32 = 4u;
+@group(0) @binding(0) var<storage, read_write> out: array<f32>;
+var<workgroup> buf: array<f32, N * 2u>;
+@compute @workgroup_size(1)
+fn main(@builtin(local_invocation_index) i: u32) {
+    buf[i] = f32(i);
+    workgroupBarrier();
+    out[i] = buf[i];
+}
+`
+	code := compileWGSLWithOpts(t, src, DefaultOptions())
+	mustContainMSL(t, code, "float inner[8]")
+
+	opts := DefaultOptions()
+	opts.PipelineConstants = map[string]float64{"N": 16}
+	code = compileWGSLWithOpts(t, src, opts)
+	mustContainMSL(t, code, "float inner[32]")
+}
+
 func TestIntegration8_PipelineConstantsWithIf(t *testing.T) {
 	// Exercises adjustBlockHandles for StmtIf
 	src := `
@@ -577,3 +601,166 @@ fn main() {
 	mustContainMSL(t, code, "float add(")
 	mustContainMSL(t, code, "float mul(")
 }
+
+// =============================================================================
+// Test: Private globals become per-entry-point locals
+// (covers writePrivateVarLocals and pass-through of thread references)
+// =============================================================================
+
+func TestIntegration8_PrivateGlobalsPerEntryPoint(t *testing.T) {
+	src := `
+const K = vec2<f32>(1.0, 2.0);
+var<private> table: array<f32, 3> = array<f32, 3>(1.0, 2.0, 3.0);
+var<private> k = K;
+var<private> sw = K.yx;
+fn lookup(i: i32) -> f32 { return table[i] + k.y + sw.x; }
+@compute @workgroup_size(1)
+fn cs() { k.x = lookup(1); }
+@fragment
+fn fs() -> @location(0) vec4<f32> { return vec4<f32>(k, sw); }
+`
+	code := compileWGSL(t, src)
+	mustContainMSL(t, code, "thread type_")
+	mustContainMSL(t, code, "metal::float2 k = K;")
+	mustContainMSL(t, code, "metal::float2 sw = metal::float2(2.0, 1.0);")
+	if n := strings.Count(code, "metal::float2 k = K;"); n != 2 {
+		t.Errorf("expected k to be initialized in both entry points, found %d\n%s", n, code)
+	}
+	if strings.Contains(code, "\nmetal::float2 k") {
+		t.Errorf("private global emitted at module scope:\n%s", code)
+	}
+}
+
+// =============================================================================
+// Test: Logger receives phase traces and polyfill warnings
+// =============================================================================
+
+func TestIntegration8_LoggerPolyfills(t *testing.T) {
+	src := `
+@group(0) @binding(0) var tex: texture_2d<f32>;
+@group(0) @binding(1) var samp: sampler;
+
+@fragment
+fn main(@location(0) uv: vec2<f32>) -> @location(0) vec4<f32> {
+    return textureSampleBaseClampToEdge(tex, samp, uv);
+}
+`
+	var buf bytes.Buffer
+	opts := DefaultOptions()
+	opts.Logger = slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	code := compileWGSLWithOpts(t, src, opts)
+	mustContainMSL(t, code, "nagaTextureSampleBaseClampToEdge")
+
+	log := buf.String()
+	for _, want := range []string{
+		`level=DEBUG msg="msl: writing module" backend=msl version=`,
+		`level=DEBUG msg="msl: module written" backend=msl bytes=`,
+		`level=WARN msg="emulating textureSampleBaseClampToEdge" backend=msl polyfill=textureSampleBaseClampToEdge`,
+	} {
+		if !strings.Contains(log, want) {
+			t.Errorf("log missing %q:\n%s", want, log)
+		}
+	}
+
+	// Without a logger nothing is written and output is unchanged.
+	if plain := compileWGSLWithOpts(t, src, DefaultOptions()); plain != code {
+		t.Error("output differs when a logger is set")
+	}
+}
+
+func TestMSL_TextureSampleVariants(t *testing.T) {
+	code := compileWGSL(t, `
+@group(0) @binding(0) var t3: texture_3d<f32>;
+@group(0) @binding(1) var tc: texture_cube<f32>;
+@group(0) @binding(2) var d2: texture_depth_2d;
+@group(1) @binding(0) var s: sampler;
+@group(1) @binding(1) var sc: sampler_comparison;
+
+@fragment
+fn main(@location(0) p: vec3<f32>) -> @location(0) vec4<f32> {
+    var c = textureSampleGrad(t3, s, p, p, p, vec3i(1, 2, 3));
+    c += textureSampleGrad(tc, s, p, p, p);
+    c.x += textureSampleCompare(d2, sc, p.xy, 0.5, vec2i(1, 2));
+    c.x += textureSampleCompareLevel(d2, sc, p.xy, 0.5, vec2i(3, 4));
+    return c;
+}
+`)
+	mustContainMSL(t, code, "metal::gradient3d(p, p), metal::int3(1, 2, 3))")
+	mustContainMSL(t, code, "tc.sample(s, p, metal::gradientcube(p, p))")
+	mustContainMSL(t, code, "d2_.sample_compare(sc, p.xy, 0.5, metal::int2(1, 2))")
+	mustContainMSL(t, code, "d2_.sample_compare(sc, p.xy, 0.5, metal::int2(3, 4))")
+	mustNotContainMSL(t, code, "gradient2d")
+}
+
+func TestMSL_FastMathSafeFloatChecks(t *testing.T) {
+	src := `
+enable f16;
+
+@fragment
+fn main(@location(0) x: f32, @location(1) v: vec3<f32>, @location(2) h: f16) -> @location(0) vec4<f32> {
+    return vec4<f32>(select(0.0, 1.0, isnan(x)), select(0.0, 1.0, isinf(v).y),
+                     select(0.0, 1.0, isnan(h)), 0.0);
+}
+`
+	plain := compileWGSL(t, src)
+	mustContainMSL(t, plain, "metal::isnan(x)")
+	mustContainMSL(t, plain, "metal::isinf(v)")
+
+	opts := DefaultOptions()
+	opts.FastMathSafeFloatChecks = true
+	code := compileWGSLWithOpts(t, src, opts)
+	mustContainMSL(t, code, "((metal::as_type<uint>(x) & 0x7fffffffu) > 0x7f800000u)")
+	mustContainMSL(t, code, "((metal::as_type<metal::uint3>(v) & 0x7fffffffu) == 0x7f800000u)")
+	mustContainMSL(t, code, "((metal::as_type<ushort>(h) & 0x7fff) > 0x7c00)")
+	mustNotContainMSL(t, code, "metal::isnan")
+	mustNotContainMSL(t, code, "metal::isinf")
+}
+
+func TestMSL_PreciseMath(t *testing.T) {
+	src := `
+enable f16;
+
+@compute @workgroup_size(1)
+fn main() {
+    var x = 0.5;
+    var v = vec2<f32>(0.25);
+    var h = 0.5h;
+    x = sin(x) + pow(x, 2.0) * sqrt(x) + abs(x);
+    v = exp(v) + atan2(v, v.yx);
+    h = cos(h);
+    _ = isnan(x);
+}
+`
+	plain := compileWGSL(t, src)
+	mustNotContainMSL(t, plain, "precise")
+
+	opts := DefaultOptions()
+	opts.PreciseMath = true
+	code := compileWGSLWithOpts(t, src, opts)
+	mustContainMSL(t, code, "#pragma clang fp contract(off)")
+	mustContainMSL(t, code, "metal::precise::sin(")
+	mustContainMSL(t, code, "metal::precise::pow(")
+	mustContainMSL(t, code, "metal::precise::sqrt(")
+	mustContainMSL(t, code, "metal::precise::exp(")
+	mustContainMSL(t, code, "metal::precise::atan2(")
+	mustContainMSL(t, code, "metal::abs(")     // no precise variant
+	mustContainMSL(t, code, "metal::cos(")     // f16 keeps the plain overload
+	mustNotContainMSL(t, code, "metal::isnan") // implies FastMathSafeFloatChecks
+}
+
+// TestMSL_VertexInstanceIndexIncludeBase verifies vertex_index and
+// instance_index read [[vertex_id]] and [[instance_id]] unadjusted: Metal
+// already includes the base vertex and base instance in both.
+func TestMSL_VertexInstanceIndexIncludeBase(t *testing.T) {
+	code := compileWGSL(t, `
+@vertex
+fn vs(@builtin(vertex_index) vid: u32, @builtin(instance_index) iid: u32) -> @builtin(position) vec4<f32> {
+    return vec4<f32>(f32(vid), f32(iid), 0.0, 1.0);
+}
+`)
+	mustContainMSL(t, code, "uint vid [[vertex_id]]")
+	mustContainMSL(t, code, "uint iid [[instance_id]]")
+	mustContainMSL(t, code, "static_cast<float>(vid), static_cast<float>(iid)")
+	mustNotContainMSL(t, code, "base_vertex")
+	mustNotContainMSL(t, code, "base_instance")
+}
@@ -19,6 +19,14 @@ func compileWGSL(t *testing.T, src string) string {
 
 // compileWGSLWithOpts compiles WGSL source to MSL with custom options.
 func compileWGSLWithOpts(t *testing.T, src string, opts Options) string {
+	t.Helper()
+	code, _ := compileWGSLWithInfo(t, src, opts)
+	return code
+}
+
+// compileWGSLWithInfo compiles WGSL source to MSL with custom options and
+// also returns the translation info.
+func compileWGSLWithInfo(t *testing.T, src string, opts Options) (string, TranslationInfo) {
 	t.Helper()
 	lexer := wgsl.NewLexer(src)
 	tokens, lexErr := lexer.Tokenize()
@@ -34,11 +42,11 @@ func compileWGSLWithOpts(t *testing.T, src string, opts Options) string {
 	if err != nil {
 		t.Fatalf("Lower error: %v", err)
 	}
-	code, _, compileErr := Compile(module, opts)
+	code, info, compileErr := Compile(module, opts)
 	if compileErr != nil {
 		t.Fatalf("MSL compile error: %v", compileErr)
 	}
-	return code
+	return code, info
 }
 
 // computeWrap wraps a WGSL expression in a compute shader with storage output
@@ -668,15 +668,8 @@ func processFunctionOverrides(m *ir.Module, fn *ir.Function, overrideValues map[
 			}
 		}
 		// Fill in types for new expressions that don't have a mapping from old.
-		for i := range newExprs {
-			if newTypes[i].Handle == nil && newTypes[i].Value == nil {
-				// Try to resolve from the expression itself.
-				if res, err := ir.ResolveExpressionType(m, fn, ir.ExpressionHandle(i)); err == nil {
-					newTypes[i] = res
-				}
-			}
-		}
 		fn.ExpressionTypes = newTypes
+		ir.UpdateExpressionTypes(m, fn)
 	}
 
 	// Update local variable init handles.
@@ -2,6 +2,7 @@ package codegen
 
 import (
 	"fmt"
+	"slices"
 
 	"github.com/gogpu/naga/ir"
 )
@@ -44,6 +45,9 @@ func (w *Writer) writeBlock(block ir.Block) error {
 
 // writeStatement writes a single statement.
 func (w *Writer) writeStatement(stmt ir.Statement) error {
+	if w.SourceMap {
+		w.MarkSpan(ir.StatementSpan(w.currentFunction, stmt.Kind))
+	}
 	return w.writeStatementKind(stmt.Kind)
 }
 
@@ -438,8 +442,13 @@ func (w *Writer) getIRNamedExpression(handle ir.ExpressionHandle) (string, bool)
 //   - Dot4 packed: both arguments baked (used 4x in expansion)
 func (w *Writer) collectNeedBakeExpressions(fn *ir.Function) {
 	// Count references: how many times each expression handle is used by others
+	// References from dead expressions don't count: those are never written.
 	refCounts := make([]int, len(fn.Expressions))
+	live := ir.LiveExpressions(fn)
 	for i := range fn.Expressions {
+		if !live[i] {
+			continue
+		}
 		expr := &fn.Expressions[i]
 		for _, ref := range exprRefs(expr.Kind) {
 			if int(ref) < len(refCounts) {
@@ -815,7 +824,7 @@ func (w *Writer) bakeExpressionImpl(handle ir.ExpressionHandle, name string, use
 		// Try re-resolving type from expression (handles cases where
 		// CompactTypes dropped abstract type handles).
 		if w.currentFunction != nil && int(handle) < len(w.currentFunction.Expressions) {
-			if reResolved, err := ir.ResolveExpressionType(w.module, w.currentFunction, handle); err == nil {
+			if reResolved, err := ir.ExpressionType(w.module, w.currentFunction, handle); err == nil {
 				if reResolved.Handle != nil {
 					typeName = w.writeTypeName(*reResolved.Handle, StorageAccess(0))
 				} else if reResolved.Value != nil {
@@ -1089,6 +1098,15 @@ func (w *Writer) writeEntryPointOutputReturn(value ir.ExpressionHandle) (bool, e
 
 // writeReturn writes a return statement.
 func (w *Writer) writeReturn(ret ir.StmtReturn) error {
+	if w.meshEntry != nil {
+		if w.meshEntry.stage == ir.StageMesh {
+			w.writeMeshOutput()
+		} else if ret.Value != nil {
+			return w.writeTaskReturn(*ret.Value)
+		}
+		w.WriteLine("return;")
+		return nil
+	}
 	if ret.Value == nil {
 		w.WriteLine("return;")
 		return nil
@@ -1183,6 +1201,14 @@ func (w *Writer) writeUncheckedStore(store ir.StmtStore) error {
 		return nil
 	}
 
+	if w.options.WriteMaskStores && w.currentFunction != nil {
+		if mask, ok := ir.StoreWriteMask(w.module, w.currentFunction, store); ok {
+			if handled, err := w.writeMaskedStore(store, mask); handled || err != nil {
+				return err
+			}
+		}
+	}
+
 	w.WriteIndent()
 	if w.shouldDerefPointer(store.Pointer) {
 		w.write("*")
@@ -1198,6 +1224,63 @@ func (w *Writer) writeUncheckedStore(store ir.StmtStore) error {
 	return nil
 }
 
+// writeMaskedStore writes a store that replaces only the components in
+// mask: v.zx = n.yx when the new values are components of one vector, or
+// one assignment per component when no value reads the stored variable.
+// Packed vec3 members take no multi-component swizzle, so they are always
+// written per component, with bracket indices. It reports false when the
+// store must be written whole.
+func (w *Writer) writeMaskedStore(store ir.StmtStore, mask ir.WriteMask) (bool, error) {
+	const components = "xyzw"
+	packed := w.isPackedVec3Access(store.Pointer)
+	writeTarget := func() error {
+		w.WriteIndent()
+		if w.shouldDerefPointer(store.Pointer) {
+			w.write("(*")
+			defer w.write(")")
+		}
+		return w.writeExpression(store.Pointer)
+	}
+
+	if source, read, ok := mask.Source(w.currentFunction); ok && !packed && !w.isPackedVec3Access(source) {
+		if err := writeTarget(); err != nil {
+			return true, err
+		}
+		w.write(".")
+		for _, c := range mask.Targets {
+			w.write("%c", components[c])
+		}
+		w.write(" = ")
+		if err := w.writeExpression(source); err != nil {
+			return true, err
+		}
+		w.write(".")
+		for _, c := range read {
+			w.write("%c", components[c])
+		}
+		w.write(";\n")
+		return true, nil
+	}
+	if mask.ReadsTarget(w.currentFunction, store) {
+		return false, nil
+	}
+	for i, c := range mask.Targets {
+		if err := writeTarget(); err != nil {
+			return true, err
+		}
+		if packed {
+			w.write("[%d] = ", c)
+		} else {
+			w.write(".%c = ", components[c])
+		}
+		if err := w.writeExpression(mask.Values[i]); err != nil {
+			return true, err
+		}
+		w.write(";\n")
+	}
+	return true, nil
+}
+
 // isAtomicPointer checks if an expression is a pointer to an atomic type.
 // Matches Rust naga's TypeInner::is_atomic_pointer.
 // Note: our type resolution strips the pointer wrapper for access chains,
@@ -1353,70 +1436,57 @@ func (w *Writer) computeAccessLength(baseHandle ir.ExpressionHandle) boundsCheck
 }
 
 // computeDynamicArrayLength computes the runtime length of a dynamic array.
-// The array is the last member of a struct that is a storage buffer global variable.
+// The array is either a storage buffer global variable itself or the last
+// member of the struct such a global holds.
 func (w *Writer) computeDynamicArrayLength(baseHandle ir.ExpressionHandle, stride uint32) boundsCheckLength {
-	// Walk back up the access chain to find the global variable.
-	if w.currentFunction == nil {
+	if w.currentFunction == nil || int(baseHandle) >= len(w.currentFunction.Expressions) {
 		return boundsCheckLength{kind: boundsLengthNone}
 	}
 
-	// Find the global variable and struct layout.
-	// The base is an AccessIndex into a struct, where the struct is the global variable.
-	if int(baseHandle) >= len(w.currentFunction.Expressions) {
-		return boundsCheckLength{kind: boundsLengthNone}
-	}
-	expr := &w.currentFunction.Expressions[baseHandle]
-	accessIdx, ok := expr.Kind.(ir.ExprAccessIndex)
-	if !ok {
-		return boundsCheckLength{kind: boundsLengthNone}
+	// Find the global variable: the base itself, or the struct an
+	// AccessIndex selects the array from.
+	gvHandle := baseHandle
+	viaMember := false
+	if accessIdx, ok := w.currentFunction.Expressions[baseHandle].Kind.(ir.ExprAccessIndex); ok {
+		gvHandle = accessIdx.Base
+		viaMember = true
 	}
-
-	// The base of the AccessIndex should be a GlobalVariable.
-	if int(accessIdx.Base) >= len(w.currentFunction.Expressions) {
+	if int(gvHandle) >= len(w.currentFunction.Expressions) {
 		return boundsCheckLength{kind: boundsLengthNone}
 	}
-	gvExpr := &w.currentFunction.Expressions[accessIdx.Base]
-	gv, ok := gvExpr.Kind.(ir.ExprGlobalVariable)
-	if !ok {
+	gv, ok := w.currentFunction.Expressions[gvHandle].Kind.(ir.ExprGlobalVariable)
+	if !ok || int(gv.Variable) >= len(w.module.GlobalVariables) {
 		return boundsCheckLength{kind: boundsLengthNone}
 	}
 
-	// Look up the buffer size index for this global variable.
-	bufSizeIdx := -1
-	for i, h := range w.bufferSizeGlobals {
-		if h == uint32(gv.Variable) {
-			bufSizeIdx = i
-			break
-		}
-	}
-	if bufSizeIdx < 0 {
+	// Only globals with a _mslBufferSizes member have a known length. The
+	// member is named after the global's handle, not its position in the list.
+	if !slices.Contains(w.bufferSizeGlobals, uint32(gv.Variable)) {
 		return boundsCheckLength{kind: boundsLengthNone}
 	}
 
-	// Get the struct type to compute the fixed offset.
-	if int(gv.Variable) >= len(w.module.GlobalVariables) {
-		return boundsCheckLength{kind: boundsLengthNone}
-	}
 	globalVar := &w.module.GlobalVariables[gv.Variable]
 	if int(globalVar.Type) >= len(w.module.Types) {
 		return boundsCheckLength{kind: boundsLengthNone}
 	}
-	st, ok := w.module.Types[globalVar.Type].Inner.(ir.StructType)
-	if !ok {
-		return boundsCheckLength{kind: boundsLengthNone}
-	}
-
-	// The dynamic array is the last member.
-	if len(st.Members) == 0 {
-		return boundsCheckLength{kind: boundsLengthNone}
+	arrayType := globalVar.Type
+	var memberOffset uint32
+	if viaMember {
+		// The dynamic array is the last member of the struct.
+		st, ok := w.module.Types[globalVar.Type].Inner.(ir.StructType)
+		if !ok || len(st.Members) == 0 {
+			return boundsCheckLength{kind: boundsLengthNone}
+		}
+		lastMember := &st.Members[len(st.Members)-1]
+		arrayType = lastMember.Type
+		memberOffset = lastMember.Offset
 	}
-	lastMember := &st.Members[len(st.Members)-1]
 
 	// Get the array element type to compute element size.
 	// Matches Rust naga: size = module.types[base].inner.size(ctx)
 	var elementSize uint32
-	if int(lastMember.Type) < len(w.module.Types) {
-		if arrType, ok := w.module.Types[lastMember.Type].Inner.(ir.ArrayType); ok {
+	if int(arrayType) < len(w.module.Types) {
+		if arrType, ok := w.module.Types[arrayType].Inner.(ir.ArrayType); ok {
 			elementSize = w.typeSize(arrType.Base)
 		}
 	}
@@ -1426,8 +1496,8 @@ func (w *Writer) computeDynamicArrayLength(baseHandle ir.ExpressionHandle, strid
 
 	return boundsCheckLength{
 		kind:          boundsLengthDynamic,
-		dynamicGlobal: uint32(bufSizeIdx),
-		memberOffset:  lastMember.Offset,
+		dynamicGlobal: uint32(gv.Variable),
+		memberOffset:  memberOffset,
 		elementSize:   elementSize,
 		stride:        stride,
 	}
@@ -1690,7 +1760,7 @@ func (w *Writer) writeAtomicCompareExchange(atomic ir.StmtAtomic, exchange ir.At
 		w.namedExpressions[*atomic.Result] = tempName
 		w.write("%s %s = ", structName, tempName)
 	}
-	w.write("naga_atomic_compare_exchange_weak_explicit(&")
+	w.write("%s(&", w.helperName("naga_atomic_compare_exchange_weak_explicit"))
 	if err := w.writeExpression(atomic.Pointer); err != nil {
 		return err
 	}
@@ -1764,6 +1834,23 @@ func (w *Writer) writeAtomicStore(atomic ir.StmtAtomic) error {
 
 // writeCall writes a function call statement.
 func (w *Writer) writeCall(call ir.StmtCall) error {
+	// A reference cannot bind to a vector component, so those arguments
+	// are copied into a temporary and back around the call.
+	components := make(map[int]string)
+	for i, arg := range call.Arguments {
+		ptr, ok := w.getExpressionType(arg).(ir.ValuePointerType)
+		if !ok || ptr.Size != nil {
+			continue
+		}
+		components[i] = w.namer.call("component")
+		w.WriteIndent()
+		w.write("%s %s = ", scalarTypeName(ptr.Scalar), components[i])
+		if err := w.writeExpression(arg); err != nil {
+			return err
+		}
+		w.write(";\n")
+	}
+
 	w.WriteIndent()
 
 	// Assign result if needed
@@ -1786,6 +1873,10 @@ func (w *Writer) writeCall(call ir.StmtCall) error {
 		if i > 0 {
 			w.write(", ")
 		}
+		if name, ok := components[i]; ok {
+			w.write("%s", name)
+			continue
+		}
 		if err := w.writeExpression(arg); err != nil {
 			return err
 		}
@@ -1813,6 +1904,17 @@ func (w *Writer) writeCall(call ir.StmtCall) error {
 	}
 
 	w.write(");\n")
+	for i, arg := range call.Arguments {
+		name, ok := components[i]
+		if !ok {
+			continue
+		}
+		w.WriteIndent()
+		if err := w.writeExpression(arg); err != nil {
+			return err
+		}
+		w.write(" = %s;\n", name)
+	}
 	return nil
 }
 
@@ -810,3 +810,100 @@ func TestMSL_RayQueryTerminate(t *testing.T) {
 	result := compileModule(t, module)
 	mustContainMSL(t, result, ".ready = false;")
 }
+
+func TestMSL_WriteMaskStores(t *testing.T) {
+	src := `
+struct Particle {
+    pos: vec3<f32>,
+    mass: f32,
+}
+
+@group(0) @binding(0) var<storage, read_write> particles: array<Particle>;
+@group(0) @binding(1) var<storage, read_write> colors: array<vec4<f32>>;
+
+@compute @workgroup_size(1)
+fn main() {
+    var v = colors[3];
+    let n = colors[1];
+    v = vec4<f32>(n.y, v.y, n.x, v.w);
+    colors[0] = vec4<f32>(colors[0].x, 2.0, colors[0].z, 3.0);
+    colors[2] = v;
+    particles[0].pos = vec3<f32>(particles[0].pos.x, n.z, n.w);
+    particles[1].pos = vec3<f32>(particles[1].pos.y, particles[1].pos.x, 1.0);
+}
+`
+	plain := compileWGSL(t, src)
+	mustNotContainMSL(t, plain, ".xz =")
+
+	opts := DefaultOptions()
+	opts.WriteMaskStores = true
+	code := compileWGSLWithOpts(t, src, opts)
+	mustContainMSL(t, code, "v.xz = n.yx;")
+	mustContainMSL(t, code, "colors[0].y = 2.0;")
+	mustContainMSL(t, code, "colors[0].w = 3.0;")
+	// Packed vec3 members take no multi-component swizzle.
+	mustContainMSL(t, code, "particles[0].pos[1] = n.z;")
+	mustContainMSL(t, code, "particles[0].pos[2] = n.w;")
+	mustContainMSL(t, code, "particles[1].pos = metal::float3(_e48, _e53, 1.0);")
+
+	// old is loaded before v.y changes: its components are values to
+	// store, not components of v to keep.
+	stale := compileWGSLWithOpts(t, `
+@group(0) @binding(0) var<storage, read_write> buf: array<vec4<f32>>;
+
+@compute @workgroup_size(1)
+fn main() {
+    var v = buf[0];
+    let old = v;
+    v.y = 5.0;
+    v = vec4<f32>(9.0, old.y, old.z, old.w);
+    buf[1] = v;
+}
+`, opts)
+	mustContainMSL(t, stale, "v = metal::float4(9.0, old.y, old.z, old.w);")
+	mustNotContainMSL(t, stale, "v.x = 9.0;")
+}
+
+func TestMSL_VectorComponentPointerArgument(t *testing.T) {
+	f32 := ir.ScalarType{Kind: ir.ScalarFloat, Width: 4}
+	module := &ir.Module{
+		Types: []ir.Type{
+			{Inner: f32},
+			{Inner: ir.VectorType{Size: ir.Vec4, Scalar: f32}},
+			{Inner: ir.PointerType{Base: 0, Space: ir.SpaceFunction}},
+		},
+		Functions: []ir.Function{{
+			Name:      "set",
+			Arguments: []ir.FunctionArgument{{Name: "p", Type: 2}},
+			Expressions: []ir.Expression{
+				{Kind: ir.ExprFunctionArgument{Index: 0}},
+				{Kind: ir.Literal{Value: ir.LiteralF32(2.0)}},
+			},
+			Body: ir.Block{{Kind: ir.StmtStore{Pointer: 0, Value: 1}}},
+		}},
+		EntryPoints: []ir.EntryPoint{{
+			Name:      "main",
+			Stage:     ir.StageCompute,
+			Workgroup: [3]uint32{1, 1, 1},
+			Function: ir.Function{
+				Name:      "main",
+				LocalVars: []ir.LocalVariable{{Name: "v", Type: 1}},
+				Expressions: []ir.Expression{
+					{Kind: ir.ExprLocalVariable{Variable: 0}},
+					{Kind: ir.ExprAccessIndex{Base: 0, Index: 1}},
+				},
+				Body: ir.Block{
+					{Kind: ir.StmtEmit{Range: ir.Range{Start: 1, End: 2}}},
+					{Kind: ir.StmtCall{Function: 0, Arguments: []ir.ExpressionHandle{1}}},
+				},
+			},
+		}},
+	}
+	for _, fn := range []*ir.Function{&module.Functions[0], &module.EntryPoints[0].Function} {
+		ir.UpdateExpressionTypes(module, fn)
+	}
+	code := compileModule(t, module)
+	// A thread float& cannot bind to v.y; the component goes through a
+	// temporary.
+	mustContainMSL(t, code, "float component = v.y;\n    set(component);\n    v.y = component;")
+}
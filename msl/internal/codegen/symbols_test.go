@@ -0,0 +1,116 @@
+package codegen
+
+import (
+	"strings"
+	"testing"
+)
+
+const symbolsShader = `
+struct Light {
+    color: vec3<f32>,
+}
+
+const SCALE: f32 = 2.0;
+
+@group(0) @binding(0) var<uniform> light: Light;
+
+fn shade(c: vec3<f32>) -> vec3<f32> {
+    return c * SCALE;
+}
+
+@vertex
+fn main() -> @builtin(position) vec4<f32> {
+    return vec4<f32>(shade(light.color), 1.0);
+}
+
+@fragment
+fn fs_main() -> @location(0) vec4<f32> {
+    return vec4<f32>(shade(light.color), 1.0);
+}
+`
+
+func TestSymbolPrefixAndEntryPointNames(t *testing.T) {
+	opts := DefaultOptions()
+	opts.FakeMissingBindings = true
+	opts.SymbolPrefix = "quad_"
+	opts.EntryPointNames = map[string]string{"main": "vs_main_quad"}
+	code, info := compileWGSLWithInfo(t, symbolsShader, opts)
+
+	for _, want := range []string{
+		"struct quad_Light {",
+		"quad_shade(",
+		"quad_SCALE",
+		"vertex vs_main_quadOutput vs_main_quad(",
+		"fragment quad_fs_mainOutput quad_fs_main(",
+	} {
+		if !strings.Contains(code, want) {
+			t.Errorf("expected output to contain %q\n%s", want, code)
+		}
+	}
+	if got := info.EntryPointNames["main"]; got != "vs_main_quad" {
+		t.Errorf("EntryPointNames[main] = %q, want vs_main_quad", got)
+	}
+	if got := info.EntryPointNames["fs_main"]; got != "quad_fs_main" {
+		t.Errorf("EntryPointNames[fs_main] = %q, want quad_fs_main", got)
+	}
+}
+
+func TestEntryPointRenameIsSanitized(t *testing.T) {
+	opts := DefaultOptions()
+	opts.FakeMissingBindings = true
+	// "kernel" is reserved in MSL and "1" would start with a digit.
+	opts.EntryPointNames = map[string]string{"main": "kernel", "fs_main": "1fs-main"}
+	code, info := compileWGSLWithInfo(t, symbolsShader, opts)
+
+	if got := info.EntryPointNames["main"]; got != "kernel_" {
+		t.Errorf("EntryPointNames[main] = %q, want kernel_", got)
+	}
+	if got := info.EntryPointNames["fs_main"]; strings.ContainsAny(got, "-1") || got == "" {
+		t.Errorf("EntryPointNames[fs_main] = %q, want sanitized identifier", got)
+	}
+	if !strings.Contains(code, " kernel_(") {
+		t.Errorf("expected escaped entry point name in output\n%s", code)
+	}
+}
+
+func TestNoSymbolPrefixByDefault(t *testing.T) {
+	opts := DefaultOptions()
+	opts.FakeMissingBindings = true
+	code, info := compileWGSLWithInfo(t, symbolsShader, opts)
+	if !strings.Contains(code, "struct Light {") || info.EntryPointNames["main"] != "main_" {
+		t.Errorf("default naming changed: main → %q\n%s", info.EntryPointNames["main"], code)
+	}
+}
+
+func TestSymbolPrefixHelpers(t *testing.T) {
+	opts := DefaultOptions()
+	opts.FakeMissingBindings = true
+	opts.SymbolPrefix = "quad_"
+	code := compileWGSLWithOpts(t, `
+@group(0) @binding(0) var<storage, read_write> data: array<i32>;
+
+@compute @workgroup_size(1)
+fn main() {
+    data[0] = data[1] / data[2] + data[3] % data[4];
+    data[5] = i32(modf(f32(data[6]) * 0.5).whole);
+}
+`, opts)
+
+	for _, want := range []string{
+		"int quad_naga_div(int lhs, int rhs) {",
+		"int quad_naga_mod(int lhs, int rhs) {",
+		" quad_naga_modf(float arg) {",
+		"int quad_naga_f2i32(float value) {",
+		"quad_naga_div(",
+		"quad_naga_modf(",
+	} {
+		if !strings.Contains(code, want) {
+			t.Errorf("expected output to contain %q\n%s", want, code)
+		}
+	}
+	for _, bad := range []string{" naga_div(", " naga_mod(", " naga_modf(", " naga_f2i32("} {
+		if strings.Contains(code, bad) {
+			t.Errorf("expected every helper to be prefixed, found %q\n%s", bad, code)
+		}
+	}
+}
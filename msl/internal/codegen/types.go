@@ -3,9 +3,9 @@ package codegen
 import (
 	"fmt"
 	"math"
-	"strconv"
 	"strings"
 
+	"github.com/gogpu/naga/internal/textutil"
 	"github.com/gogpu/naga/ir"
 )
 
@@ -114,7 +114,7 @@ func (w *Writer) writeStructDefinition(handle ir.TypeHandle, _ string, st ir.Str
 		// and this member's offset. Matches Rust naga: writer.rs ~line 4519.
 		if member.Offset > lastOffset {
 			pad := member.Offset - lastOffset
-			w.WriteLine("char _pad%d[%d];", memberIdx, pad)
+			w.writePadding(handle, memberIdx, lastOffset, pad)
 			// Track that this member has padding before it, for aggregate init.
 			// Matches Rust naga's struct_member_pads set.
 			w.structPads[nameKey{kind: nameKeyStructMember, handle1: uint32(handle), handle2: uint32(memberIdx)}] = struct{}{}
@@ -150,7 +150,7 @@ func (w *Writer) writeStructDefinition(handle ir.TypeHandle, _ string, st ir.Str
 	// and storage buffer types. Matches Rust naga: writer.rs ~line 4568.
 	if lastOffset < st.Span {
 		pad := st.Span - lastOffset
-		w.WriteLine("char _pad%d[%d];", len(st.Members), pad)
+		w.writePadding(handle, len(st.Members), lastOffset, pad)
 	}
 
 	w.PopIndent()
@@ -158,6 +158,16 @@ func (w *Writer) writeStructDefinition(handle ir.TypeHandle, _ string, st ir.Str
 	return nil
 }
 
+// writePadding writes a char array member covering size bytes at offset,
+// before member index before, and records it for TranslationInfo.Padding.
+func (w *Writer) writePadding(handle ir.TypeHandle, before int, offset, size uint32) {
+	name := fmt.Sprintf("_pad%d", before)
+	w.WriteLine("char %s[%d];", name, size)
+	w.padding = append(w.padding, ir.StructPadding{
+		Struct: handle, Name: name, Before: before, Offset: offset, Size: size,
+	})
+}
+
 // writeArrayWrapper writes a wrapper struct for array types.
 // For fixed-size arrays, emits a wrapper struct with an inner array member.
 // For runtime-sized (dynamic) arrays, emits a typedef with size [1] so that
@@ -346,6 +356,8 @@ func addressSpaceName(space ir.AddressSpace) string {
 		return "" // Handles don't have address space qualifiers
 	case ir.SpacePushConstant, ir.SpaceImmediate:
 		return "constant"
+	case ir.SpaceTaskPayload:
+		return spaceObjectData
 	default:
 		return ""
 	}
@@ -416,7 +428,7 @@ func (w *Writer) imageTypeName(img ir.ImageType, _ StorageAccess) string {
 		sampleType = storageFormatToMSLType(img.StorageFormat)
 		// R64 formats require Metal 3.1 for int64 atomic textures
 		if img.StorageFormat == ir.StorageFormatR64Uint || img.StorageFormat == ir.StorageFormatR64Sint {
-			w.requireVersion(Version3_1)
+			w.requireVersion(Version3_1, "64-bit atomic textures")
 		}
 	default:
 		sampleType = typeFloat
@@ -498,8 +510,8 @@ func (w *Writer) atomicTypeName(a ir.AtomicType) string {
 		// Matches Rust naga output.
 		return Namespace + "atomic_ulong"
 	case ir.ScalarFloat:
-		// MSL 3.1+ supports atomic_float for f32 atomics.
-		// Matches Rust naga output.
+		// atomic_float is available from MSL 3.0. Matches Rust naga output.
+		w.requireVersion(Version3_0, "f32 atomics")
 		return Namespace + "atomic_float"
 	default:
 		return Namespace + "atomic_uint"
@@ -651,8 +663,13 @@ func (w *Writer) writeConstants() error {
 	// Write overrides as constant declarations.
 	// In Rust naga, process_overrides converts Override expressions to Constants
 	// before the MSL writer runs. We emit them directly as constants here.
+	// Unnamed overrides only hold the length of override-sized arrays,
+	// which are written with their resolved length.
 	for handle := range w.module.Overrides {
 		ov := &w.module.Overrides[handle]
+		if ov.Name == "" {
+			continue
+		}
 		if err := w.writeOverrideAsConstant(ir.OverrideHandle(handle), ov); err != nil {
 			return err
 		}
@@ -788,11 +805,7 @@ func (w *Writer) writeScalarValue(v ir.ScalarValue, typeHandle ir.TypeHandle) er
 			} else if math.IsNaN(floatVal) {
 				w.write("NAN")
 			} else {
-				s := strconv.FormatFloat(floatVal, 'f', -1, 32)
-				if !strings.Contains(s, ".") {
-					s += ".0"
-				}
-				w.write("%sh", s)
+				w.write("%sh", w.formatFloat(floatVal, 32))
 			}
 		} else if width == 4 {
 			floatVal := math.Float32frombits(uint32(v.Bits))
@@ -805,11 +818,7 @@ func (w *Writer) writeScalarValue(v ir.ScalarValue, typeHandle ir.TypeHandle) er
 			} else if math.IsNaN(float64(floatVal)) {
 				w.write("NAN")
 			} else {
-				s := strconv.FormatFloat(float64(floatVal), 'f', -1, 32)
-				if !strings.Contains(s, ".") {
-					s += ".0"
-				}
-				w.write("%s", s)
+				w.write("%s", w.formatFloat(float64(floatVal), 32))
 			}
 		} else {
 			floatVal := math.Float64frombits(v.Bits)
@@ -822,11 +831,7 @@ func (w *Writer) writeScalarValue(v ir.ScalarValue, typeHandle ir.TypeHandle) er
 			} else if math.IsNaN(floatVal) {
 				w.write("NAN")
 			} else {
-				s := strconv.FormatFloat(floatVal, 'f', -1, 64)
-				if !strings.Contains(s, ".") {
-					s += ".0"
-				}
-				w.write("%s", s)
+				w.write("%s", w.formatFloat(floatVal, 64))
 			}
 		}
 
@@ -901,3 +906,10 @@ func halfToFloat32(bits uint16) float32 {
 		return math.Float32frombits(sign<<31 | 0xff<<23 | frac<<13)
 	}
 }
+
+// formatFloat formats a finite float of bitSize 32 or 64 for MSL output with
+// the configured significant digits, or the shortest round-trip digits.
+// Like Rust's Display it never uses an exponent; whole numbers get ".0".
+func (w *Writer) formatFloat(f float64, bitSize int) string {
+	return textutil.FormatFloatDecimal(f, bitSize, w.options.FloatPrecision)
+}
@@ -34,6 +34,42 @@ func TestMSL_StructEmission(t *testing.T) {
 	mustContainMSL(t, result, "float z")
 }
 
+// TestMSL_StructPaddingInfo checks that the padding members written into a
+// struct are reported in TranslationInfo.Padding.
+func TestMSL_StructPaddingInfo(t *testing.T) {
+	module := &ir.Module{
+		Types: []ir.Type{
+			{Inner: ir.ScalarType{Kind: ir.ScalarFloat, Width: 4}},
+			{Name: "Padded", Inner: ir.StructType{
+				Members: []ir.StructMember{
+					{Name: "a", Type: 0, Offset: 0},
+					{Name: "b", Type: 0, Offset: 16},
+				},
+				Span: 32,
+			}},
+		},
+	}
+	result, info, err := Compile(module, DefaultOptions())
+	if err != nil {
+		t.Fatalf("Compile failed: %v", err)
+	}
+	mustContainMSL(t, result, "char _pad1[12];")
+	mustContainMSL(t, result, "char _pad2[12];")
+
+	want := []ir.StructPadding{
+		{Struct: 1, Name: "_pad1", Before: 1, Offset: 4, Size: 12},
+		{Struct: 1, Name: "_pad2", Before: 2, Offset: 20, Size: 12},
+	}
+	if len(info.Padding) != len(want) {
+		t.Fatalf("Padding = %+v, want %+v", info.Padding, want)
+	}
+	for i := range want {
+		if info.Padding[i] != want[i] {
+			t.Errorf("Padding[%d] = %+v, want %+v", i, info.Padding[i], want[i])
+		}
+	}
+}
+
 // =============================================================================
 // Test: Array type (with wrapper struct for MSL)
 // =============================================================================
@@ -0,0 +1,64 @@
+// Copyright 2025 The GoGPU Authors
+// SPDX-License-Identifier: MIT
+
+package codegen
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/gogpu/naga/internal/testutil"
+)
+
+const floatAtomicShader = `
+@group(0) @binding(0) var<storage, read_write> total: atomic<f32>;
+
+@compute @workgroup_size(1)
+fn main() {
+    atomicAdd(&total, 1.0);
+}
+`
+
+func TestLangVersionRange(t *testing.T) {
+	for _, v := range []Version{Version1_0, {Major: 2, Minor: 2}, Version3_1, Version3_2} {
+		opts := DefaultOptions()
+		opts.LangVersion = v
+		code, info, err := Compile(testutil.LowerWGSL(t, invarianceShader), opts)
+		if err != nil {
+			t.Errorf("MSL %s: %v", v, err)
+			continue
+		}
+		if info.LangVersion != v || !strings.HasPrefix(code, "// language: metal"+v.String()+"\n") {
+			t.Errorf("MSL %s: written for %s, header %q", v, info.LangVersion, strings.SplitN(code, "\n", 2)[0])
+		}
+	}
+	for _, v := range []Version{{2, 5}, {3, 3}, {4, 0}} {
+		opts := DefaultOptions()
+		opts.LangVersion = v
+		if _, _, err := Compile(testutil.LowerWGSL(t, invarianceShader), opts); err == nil || !strings.Contains(err.Error(), "unsupported language version "+v.String()) {
+			t.Errorf("MSL %s: err = %v, want unsupported language version", v, err)
+		}
+	}
+}
+
+func TestRequiredVersion(t *testing.T) {
+	opts := DefaultOptions()
+	code, info, err := Compile(testutil.LowerWGSL(t, floatAtomicShader), opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.LangVersion != Version3_0 || !strings.HasPrefix(code, "// language: metal3.0\n") {
+		t.Errorf("f32 atomics written for %s, want 3.0:\n%s", info.LangVersion, code)
+	}
+
+	opts.StrictVersion = true
+	_, _, err = Compile(testutil.LowerWGSL(t, floatAtomicShader), opts)
+	if err == nil || !strings.Contains(err.Error(), "f32 atomics, which needs MSL 3.0, but LangVersion is 2.1") {
+		t.Errorf("strict 2.1: err = %v, want the f32 atomics version error", err)
+	}
+
+	opts.LangVersion = Version3_2
+	if _, info, err = Compile(testutil.LowerWGSL(t, floatAtomicShader), opts); err != nil || info.LangVersion != Version3_2 {
+		t.Errorf("strict 3.2: version %s, err %v; want 3.2 and no error", info.LangVersion, err)
+	}
+}
@@ -1,9 +1,11 @@
 package codegen
 
 import (
+	"context"
 	"fmt"
 	"strings"
 
+	"github.com/gogpu/naga/internal/backend"
 	"github.com/gogpu/naga/internal/textutil"
 	"github.com/gogpu/naga/ir"
 )
@@ -153,14 +155,17 @@ func epFuncHandle(epIdx int) ir.FunctionHandle {
 type Writer struct {
 	textutil.IndentWriter // provides Out, Indent, WriteLine, WriteIndent, PushIndent, PopIndent
 
+	ctx      context.Context // checked before each function is written
 	module   *ir.Module
 	options  *Options
 	pipeline *PipelineOptions
+	notes    *backend.Notes
 
 	// Name management
 	names      map[nameKey]string
 	namer      *namer
 	structPads map[nameKey]struct{} // Tracks struct members that need padding
+	padding    []ir.StructPadding   // Padding members written, for TranslationInfo
 
 	// Type tracking
 	typeNames     map[ir.TypeHandle]string
@@ -217,6 +222,9 @@ type Writer struct {
 	// triggering emission of the _RayQuery struct and _map_intersection_type helper.
 	needsRayQuery bool
 
+	// usesInvariance is set when an output is written [[invariant]].
+	usesInvariance bool
+
 	// modfResultTypes tracks the modf result struct variants needed (by scalar/vector type).
 	// Each entry describes a _modf_result_* struct to emit.
 	modfResultTypes []wrappedMathResult
@@ -244,6 +252,7 @@ type Writer struct {
 	entryPointOutputStructName string // MSL struct name for entry point output
 	entryPointInputStructArg   int
 	entryPointStage            ir.ShaderStage // current entry point's stage
+	meshEntry                  *meshEntry     // current object or mesh function, or nil
 
 	// flattenedMemberNames maps (struct type, member index) to the MSL name
 	// used for that member when it appears as a flattened entry point parameter.
@@ -286,6 +295,9 @@ type Writer struct {
 	// are encountered. The final header version is max(options.LangVersion, minRequiredVersion).
 	minRequiredVersion Version
 
+	// versionFeature names the feature that set minRequiredVersion.
+	versionFeature string
+
 	// unnamedCount tracks the number of unnamed variables allocated for subgroup operations
 	// and other unnamed results. Used by allocateUnnamedVar().
 	unnamedCount int
@@ -329,136 +341,29 @@ type vptAttributeResolved struct {
 	name      string
 }
 
-// namer generates unique identifiers.
-// It matches Rust naga's Namer behavior: per-base counters, trailing underscore
-// for names ending in a digit or matching a keyword.
+// namer generates unique identifiers: the shared backend.Namer with the
+// MSL reserved-word table.
 type namer struct {
-	usedNames map[string]struct{}
-	// perBase tracks the last numeric suffix used for each sanitized base name.
-	// Zero means "no suffix has been appended yet".
-	perBase map[string]uint32
+	*backend.Namer
 }
 
 func newNamer() *namer {
-	return &namer{
-		usedNames: make(map[string]struct{}),
-		perBase:   make(map[string]uint32),
-	}
+	return &namer{Namer: backend.NewNamer(isReserved)}
 }
 
-// fallbackName is used when sanitization produces an empty string.
-const fallbackName = "unnamed"
-
 // call generates a unique name based on the given base.
-// Matches Rust naga Namer::call behavior:
-//   - sanitize base name
-//   - if first use: return base (with trailing _ if ends with digit or is keyword)
-//   - if already used: append _{N} suffix with per-base counter
 func (n *namer) call(base string) string {
-	sanitized := sanitizeName(base)
-	if sanitized == "" {
-		sanitized = fallbackName
-	}
-
-	count, exists := n.perBase[sanitized]
-	if exists {
-		// Already used this base — append suffix with incremented counter
-		count++
-		n.perBase[sanitized] = count
-		candidate := fmt.Sprintf("%s_%d", sanitized, count)
-		n.usedNames[candidate] = struct{}{}
-		return candidate
-	}
-
-	// First use of this base
-	n.perBase[sanitized] = 0
-
-	result := sanitized
-	// Add trailing underscore if name ends with digit or is a reserved keyword
-	if result != "" && result[len(result)-1] >= '0' && result[len(result)-1] <= '9' {
-		result += "_"
-	} else if isReserved(result) {
-		result += "_"
-	}
-
-	n.usedNames[result] = struct{}{}
-	return result
-}
-
-// sanitizeName cleans a raw label for use as an identifier base.
-// Matches Rust naga Namer::sanitize behavior:
-//   - drops leading digits
-//   - retains only ASCII alphanumeric and '_'
-//   - converts non-ASCII characters to u{04hex}_ format
-//   - converts C++-ish type separators (:, <, >, ,) to underscores
-//   - collapses consecutive underscores
-//   - trims trailing underscores
-func sanitizeName(s string) string {
-	if s == "" {
-		return fallbackName
-	}
-
-	// Fast path: if already valid (no leading digits, no special chars, no __)
-	valid := true
-	for i, c := range s {
-		if i == 0 && c >= '0' && c <= '9' {
-			valid = false
-			break
-		}
-		if !isAlphanumericOrUnderscore(c) {
-			valid = false
-			break
-		}
-	}
-	if valid && !strings.Contains(s, "__") {
-		return strings.TrimRight(s, "_")
-	}
-
-	// Slow path: filter character by character, matching Rust naga Namer::sanitize
-	var buf strings.Builder
-	for _, c := range s {
-		// Convert C++-ish type separators to underscores
-		switch c {
-		case ':', '<', '>', ',':
-			c = '_'
-		}
-
-		hadUnderscoreAtEnd := buf.Len() > 0 && buf.String()[buf.Len()-1] == '_'
-		if hadUnderscoreAtEnd && c == '_' {
-			continue // collapse consecutive underscores
-		}
-
-		if isAlphanumericOrUnderscore(c) {
-			if buf.Len() == 0 && c >= '0' && c <= '9' {
-				continue // drop leading digits
-			}
-			buf.WriteRune(c)
-		} else {
-			// Non-ASCII or special character: convert to u{04hex}_ format
-			if buf.Len() > 0 && !hadUnderscoreAtEnd {
-				buf.WriteByte('_')
-			}
-			fmt.Fprintf(&buf, "u%04x_", c)
-		}
-	}
-
-	result := strings.TrimRight(buf.String(), "_")
-	if result == "" {
-		return fallbackName
-	}
-	return result
-}
-
-func isAlphanumericOrUnderscore(c rune) bool {
-	return (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9') || c == '_'
+	return n.Call(base)
 }
 
 // newWriter creates a new MSL writer.
 func newWriter(module *ir.Module, options *Options, pipeline *PipelineOptions) *Writer {
 	return &Writer{
+		ctx:                      context.Background(),
 		module:                   module,
 		options:                  options,
 		pipeline:                 pipeline,
+		notes:                    backend.NewNotes(options.Logger, "msl"),
 		names:                    make(map[nameKey]string),
 		namer:                    newNamer(),
 		structPads:               make(map[nameKey]struct{}),
@@ -482,10 +387,12 @@ func (w *Writer) String() string {
 // requireVersion bumps the minimum required Metal version if the given
 // version is higher than the current minimum. This is called when features
 // requiring specific Metal versions are encountered (e.g., barycentric_coord
-// requires Metal 2.3).
-func (w *Writer) requireVersion(v Version) {
+// requires Metal 2.3). feature names the construct for Options.StrictVersion
+// errors.
+func (w *Writer) requireVersion(v Version, feature string) {
 	if w.minRequiredVersion.Less(v) {
 		w.minRequiredVersion = v
+		w.versionFeature = feature
 	}
 }
 
@@ -579,6 +486,9 @@ func (w *Writer) writeModule() error {
 		if w.isEntryPointFunction(ir.FunctionHandle(handle)) {
 			continue
 		}
+		if err := w.ctx.Err(); err != nil {
+			return err
+		}
 		w.Out = strings.Builder{}
 		if err := w.writeFunction(ir.FunctionHandle(handle), fn); err != nil {
 			return err
@@ -602,6 +512,9 @@ func (w *Writer) writeModule() error {
 				continue
 			}
 		}
+		if err := w.ctx.Err(); err != nil {
+			return err
+		}
 		w.Out = strings.Builder{}
 		if err := w.writeEntryPoint(epIdx, &ep); err != nil {
 			return err
@@ -803,7 +716,7 @@ func (w *Writer) scanRayQueryTypes() {
 	for _, typ := range w.module.Types {
 		if _, ok := typ.Inner.(ir.RayQueryType); ok {
 			w.needsRayQuery = true
-			w.requireVersion(Version2_4)
+			w.requireVersion(Version2_4, "ray queries")
 			return
 		}
 	}
@@ -870,7 +783,7 @@ func (w *Writer) writeModfFrexpFunctions() {
 		if i > 0 {
 			w.WriteLine("")
 		}
-		w.WriteLine("%s naga_modf(%s arg) {", structName, argType)
+		w.WriteLine("%s %s(%s arg) {", structName, w.helperName("naga_modf"), argType)
 		w.PushIndent()
 		w.WriteLine("%s other;", argType)
 		w.WriteLine("%s fract = %smodf(arg, other);", argType, Namespace)
@@ -893,7 +806,7 @@ func (w *Writer) writeModfFrexpFunctions() {
 		if i > 0 || len(w.modfResultTypes) > 0 {
 			w.WriteLine("")
 		}
-		w.WriteLine("%s naga_frexp(%s arg) {", structName, argType)
+		w.WriteLine("%s %s(%s arg) {", structName, w.helperName("naga_frexp"), argType)
 		w.PushIndent()
 		w.WriteLine("%s other;", expLocalType)
 		w.WriteLine("%s fract = %sfrexp(arg, other);", argType, Namespace)
@@ -940,7 +853,7 @@ func (w *Writer) writeAtomicCompareExchangeFunctions() {
 
 		// Device overload
 		w.WriteLine("template <typename A>")
-		w.WriteLine("%s naga_atomic_compare_exchange_weak_explicit(", structName)
+		w.WriteLine("%s %s(", structName, w.helperName("naga_atomic_compare_exchange_weak_explicit"))
 		w.PushIndent()
 		w.WriteLine("device A *atomic_ptr,")
 		w.WriteLine("%s cmp,", scalarName)
@@ -960,7 +873,7 @@ func (w *Writer) writeAtomicCompareExchangeFunctions() {
 
 		// Threadgroup overload
 		w.WriteLine("template <typename A>")
-		w.WriteLine("%s naga_atomic_compare_exchange_weak_explicit(", structName)
+		w.WriteLine("%s %s(", structName, w.helperName("naga_atomic_compare_exchange_weak_explicit"))
 		w.PushIndent()
 		w.WriteLine("threadgroup A *atomic_ptr,")
 		w.WriteLine("%s cmp,", scalarName)
@@ -1059,7 +972,8 @@ func (w *Writer) writeF2IHelpers() {
 // writeF2IHelper emits a single float-to-int helper function overload.
 // Rust naga emits per-(src, vector, dst) overloads with type-specific clamp bounds.
 func (w *Writer) writeF2IHelper(ovl f2iOverload) {
-	funName := f2iFunctionName(ovl.dstScalar)
+	w.notes.Polyfill("saturating float-to-int conversion")
+	funName := w.helperName(f2iFunctionName(ovl.dstScalar))
 
 	// Build source type name
 	var srcTypeName string
@@ -1357,6 +1271,9 @@ func (w *Writer) writeHeader() {
 	w.WriteLine("// language: metal%d.%d", v.Major, v.Minor)
 	w.WriteLine("#include <metal_stdlib>")
 	w.WriteLine("#include <simd/simd.h>")
+	if w.options.DisableFMAContraction {
+		w.WriteLine("#pragma clang fp contract(off)")
+	}
 	w.WriteLine("")
 	w.WriteLine("using metal::uint;")
 	// Trailing blank line is omitted when DefaultConstructible or _RayQuery follows
@@ -1465,7 +1382,8 @@ func (w *Writer) writeTextureSampleBaseClampToEdge() {
 	if !w.needsTextureSampleBaseClampToEdge {
 		return
 	}
-	w.WriteLine("metal::float4 nagaTextureSampleBaseClampToEdge(metal::texture2d<float, metal::access::sample> tex, metal::sampler samp, metal::float2 coords) {")
+	w.notes.Polyfill("textureSampleBaseClampToEdge")
+	w.WriteLine("metal::float4 %s(metal::texture2d<float, metal::access::sample> tex, metal::sampler samp, metal::float2 coords) {", w.helperName("nagaTextureSampleBaseClampToEdge"))
 	w.PushIndent()
 	w.WriteLine("metal::float2 half_texel = 0.5 / metal::float2(tex.get_width(0u), tex.get_height(0u));")
 	w.WriteLine("return tex.sample(samp, metal::clamp(coords, half_texel, 1.0 - half_texel), metal::level(0.0));")
@@ -1477,7 +1395,8 @@ func (w *Writer) writeTextureSampleBaseClampToEdge() {
 // writeExternalTextureSampleBaseClampToEdge emits the external texture version of
 // nagaTextureSampleBaseClampToEdge. Handles multi-plane YUV sampling with transfer functions.
 func (w *Writer) writeExternalTextureSampleBaseClampToEdge() {
-	w.write("float4 nagaTextureSampleBaseClampToEdge(NagaExternalTextureWrapper tex, %ssampler samp, float2 coords) {\n", Namespace)
+	w.notes.Polyfill("textureSampleBaseClampToEdge")
+	w.write("float4 %s(NagaExternalTextureWrapper tex, %ssampler samp, float2 coords) {\n", w.helperName("nagaTextureSampleBaseClampToEdge"), Namespace)
 	l1, l2, l3 := "    ", "        ", "            "
 	w.write("%suint2 plane0_size = uint2(tex.plane0.get_width(), tex.plane0.get_height());\n", l1)
 	w.write("%scoords = tex.params.sample_transform * float3(coords, 1.0);\n", l1)
@@ -1510,7 +1429,7 @@ func (w *Writer) writeExternalTextureSampleBaseClampToEdge() {
 
 // writeExternalTextureLoadHelper emits the nagaTextureLoadExternal helper function.
 func (w *Writer) writeExternalTextureLoadHelper() {
-	w.write("float4 nagaTextureLoadExternal(NagaExternalTextureWrapper tex, uint2 coords) {\n")
+	w.write("float4 %s(NagaExternalTextureWrapper tex, uint2 coords) {\n", w.helperName("nagaTextureLoadExternal"))
 	l1, l2, l3 := "    ", "        ", "            "
 	w.write("%suint2 plane0_size = uint2(tex.plane0.get_width(), tex.plane0.get_height());\n", l1)
 	w.write("%suint2 cropped_size = %sany(tex.params.size != 0) ? tex.params.size : plane0_size;\n", l1, Namespace)
@@ -1537,7 +1456,7 @@ func (w *Writer) writeExternalTextureLoadHelper() {
 
 // writeExternalTextureDimensionsHelper emits the nagaTextureDimensionsExternal helper.
 func (w *Writer) writeExternalTextureDimensionsHelper() {
-	w.write("uint2 nagaTextureDimensionsExternal(NagaExternalTextureWrapper tex) {\n")
+	w.write("uint2 %s(NagaExternalTextureWrapper tex) {\n", w.helperName("nagaTextureDimensionsExternal"))
 	l1, l2 := "    ", "        "
 	w.write("%sif (%sany(tex.params.size != uint2(0u))) {\n", l1, Namespace)
 	w.write("%sreturn tex.params.size;\n", l2)
@@ -1583,6 +1502,18 @@ func (w *Writer) registerNames() error {
 	// Entry point functions are stored inline in EntryPoints, not in Functions[].
 	// No need for an entryPointFuncNames map to skip them.
 
+	if w.options.SymbolPrefix != "" {
+		// A prefixed WGSL symbol must not take a prefixed helper's name.
+		for _, name := range []string{
+			"naga_div", "naga_mod", "naga_abs", "naga_neg", "naga_modf", "naga_frexp",
+			"naga_f2i32", "naga_f2u32", "naga_f2i64", "naga_f2u64",
+			"naga_atomic_compare_exchange_weak_explicit",
+			"nagaTextureSampleBaseClampToEdge", "nagaTextureLoadExternal", "nagaTextureDimensionsExternal",
+		} {
+			w.namer.Reserve(w.helperName(name))
+		}
+	}
+
 	// 1. Register type names.
 	// Types with built-in MSL names (scalars, vectors, matrices) use "type" as
 	// their base name, matching Rust naga where these types are unnamed in the
@@ -1594,7 +1525,7 @@ func (w *Writer) registerNames() error {
 		} else {
 			baseName = "type"
 		}
-		name := w.namer.call(baseName)
+		name := w.namer.call(w.options.SymbolPrefix + baseName)
 		w.names[nameKey{kind: nameKeyType, handle1: uint32(handle)}] = name
 		w.typeNames[ir.TypeHandle(handle)] = name
 
@@ -1624,7 +1555,7 @@ func (w *Writer) registerNames() error {
 	// 2. Register entry point names, arguments, and locals.
 	// Rust naga registers entry points BEFORE regular functions.
 	for epIdx, ep := range w.module.EntryPoints {
-		epName := w.namer.call(ep.Name)
+		epName := w.namer.call(backend.EntryPointBase(w.options.EntryPointNames, w.options.SymbolPrefix, ep.Name))
 		w.names[nameKey{kind: nameKeyEntryPoint, handle1: uint32(epIdx)}] = epName
 		w.entryPointNames[ep.Name] = epName
 
@@ -1670,7 +1601,7 @@ func (w *Writer) registerNames() error {
 		if baseName == "" {
 			baseName = "function"
 		}
-		funcName := w.namer.call(baseName)
+		funcName := w.namer.call(w.options.SymbolPrefix + baseName)
 		w.names[nameKey{kind: nameKeyFunction, handle1: uint32(handle)}] = funcName
 
 		// Register argument names via namer.call to match Rust.
@@ -1702,7 +1633,7 @@ func (w *Writer) registerNames() error {
 		} else {
 			baseName = "global"
 		}
-		name := w.namer.call(baseName)
+		name := w.namer.call(w.options.SymbolPrefix + baseName)
 		w.names[nameKey{kind: nameKeyGlobalVariable, handle1: uint32(handle)}] = name
 
 		// For external texture globals, register plane and params names.
@@ -1733,8 +1664,9 @@ func (w *Writer) registerNames() error {
 	for handle, constant := range w.module.Constants {
 		var baseName string
 		if constant.Name != "" {
-			baseName = constant.Name
+			baseName = w.options.SymbolPrefix + constant.Name
 		} else {
+			// The type name already carries SymbolPrefix.
 			typeName := w.names[nameKey{kind: nameKeyType, handle1: uint32(constant.Type)}]
 			baseName = fmt.Sprintf("const_%s", typeName)
 		}
@@ -1749,7 +1681,7 @@ func (w *Writer) registerNames() error {
 		if baseName == "" {
 			baseName = fmt.Sprintf("override_%d", handle)
 		}
-		name := w.namer.call(baseName)
+		name := w.namer.call(w.options.SymbolPrefix + baseName)
 		w.names[nameKey{kind: nameKeyOverride, handle1: uint32(handle)}] = name
 	}
 
@@ -1782,7 +1714,7 @@ func (w *Writer) writeHelperFunctions() {
 	for _, o := range w.helperOverloads {
 		typeName := o.mslTypeName()
 		if o.isDiv {
-			w.WriteLine("%s naga_div(%s lhs, %s rhs) {", typeName, typeName, typeName)
+			w.WriteLine("%s %s(%s lhs, %s rhs) {", typeName, w.helperName("naga_div"), typeName, typeName)
 			w.PushIndent()
 			switch o.kind {
 			case ir.ScalarSint:
@@ -1793,7 +1725,7 @@ func (w *Writer) writeHelperFunctions() {
 			}
 			w.PopIndent()
 		} else {
-			w.WriteLine("%s naga_mod(%s lhs, %s rhs) {", typeName, typeName, typeName)
+			w.WriteLine("%s %s(%s lhs, %s rhs) {", typeName, w.helperName("naga_mod"), typeName, typeName)
 			w.PushIndent()
 			switch o.kind {
 			case ir.ScalarSint:
@@ -1820,7 +1752,7 @@ func (w *Writer) writeHelperFunctions() {
 			typeName = fmt.Sprintf("%s%s%d", Namespace, typeName, a.vecSize)
 			unsignedName = fmt.Sprintf("%s%s%d", Namespace, unsignedName, a.vecSize)
 		}
-		w.WriteLine("%s naga_abs(%s val) {", typeName, typeName)
+		w.WriteLine("%s %s(%s val) {", typeName, w.helperName("naga_abs"), typeName)
 		w.PushIndent()
 		w.WriteLine("return %sselect(as_type<%s>(-as_type<%s>(val)), val, val >= 0);", Namespace, typeName, unsignedName)
 		w.PopIndent()
@@ -1854,7 +1786,7 @@ func (w *Writer) writeHelperSubset(overloads []divModOverload, dots []dotWrapper
 	for _, o := range overloads {
 		typeName := o.mslTypeName()
 		if o.isDiv {
-			w.WriteLine("%s naga_div(%s lhs, %s rhs) {", typeName, typeName, typeName)
+			w.WriteLine("%s %s(%s lhs, %s rhs) {", typeName, w.helperName("naga_div"), typeName, typeName)
 			w.PushIndent()
 			switch o.kind {
 			case ir.ScalarSint:
@@ -1865,7 +1797,7 @@ func (w *Writer) writeHelperSubset(overloads []divModOverload, dots []dotWrapper
 			}
 			w.PopIndent()
 		} else {
-			w.WriteLine("%s naga_mod(%s lhs, %s rhs) {", typeName, typeName, typeName)
+			w.WriteLine("%s %s(%s lhs, %s rhs) {", typeName, w.helperName("naga_mod"), typeName, typeName)
 			w.PushIndent()
 			switch o.kind {
 			case ir.ScalarSint:
@@ -1910,7 +1842,12 @@ func (w *Writer) writeHelperSubsetDivMod(overloads []divModOverload) {
 	for _, o := range overloads {
 		typeName := o.mslTypeName()
 		if o.isDiv {
-			w.WriteLine("%s naga_div(%s lhs, %s rhs) {", typeName, typeName, typeName)
+			w.notes.Polyfill("integer division")
+		} else {
+			w.notes.Polyfill("modulo")
+		}
+		if o.isDiv {
+			w.WriteLine("%s %s(%s lhs, %s rhs) {", typeName, w.helperName("naga_div"), typeName, typeName)
 			w.PushIndent()
 			switch o.kind {
 			case ir.ScalarSint:
@@ -1921,7 +1858,7 @@ func (w *Writer) writeHelperSubsetDivMod(overloads []divModOverload) {
 			}
 			w.PopIndent()
 		} else {
-			w.WriteLine("%s naga_mod(%s lhs, %s rhs) {", typeName, typeName, typeName)
+			w.WriteLine("%s %s(%s lhs, %s rhs) {", typeName, w.helperName("naga_mod"), typeName, typeName)
 			w.PushIndent()
 			switch o.kind {
 			case ir.ScalarSint:
@@ -1947,7 +1884,7 @@ func (w *Writer) writeHelperSubsetAbs(helpers []absHelper) {
 			typeName = fmt.Sprintf("%s%s%d", Namespace, typeName, a.vecSize)
 			unsignedName = fmt.Sprintf("%s%s%d", Namespace, unsignedName, a.vecSize)
 		}
-		w.WriteLine("%s naga_abs(%s val) {", typeName, typeName)
+		w.WriteLine("%s %s(%s val) {", typeName, w.helperName("naga_abs"), typeName)
 		w.PushIndent()
 		w.WriteLine("return metal::select(as_type<%s>(-as_type<%s>(val)), val, val >= 0);", typeName, unsignedName)
 		w.PopIndent()
@@ -1959,6 +1896,7 @@ func (w *Writer) writeHelperSubsetAbs(helpers []absHelper) {
 func (w *Writer) writeHelperSubsetDot(dots []dotWrapper) {
 	components := [4]string{"x", "y", "z", "w"}
 	for _, d := range dots {
+		w.notes.Polyfill("integer dot product")
 		retType := scalarTypeName(d.scalar)
 		vecType := fmt.Sprintf("%s%s%d", Namespace, retType, d.size)
 		w.WriteLine("%s %s(%s a, %s b) {", retType, d.name, vecType, vecType)
@@ -2010,11 +1948,20 @@ func (w *Writer) addModOverload(o divModOverload) {
 	w.helperOverloads = append(w.helperOverloads, o)
 }
 
+// helperName returns the name of a generated helper function, which
+// carries SymbolPrefix like the module's own functions.
+func (w *Writer) helperName(name string) string {
+	if w.options == nil {
+		return name
+	}
+	return w.options.SymbolPrefix + name
+}
+
 // registerDotWrapper registers an integer dot product wrapper function and returns its name.
 // Format: naga_dot_{type}{size} (e.g., naga_dot_int2, naga_dot_uint3).
 func (w *Writer) registerDotWrapper(scalar ir.ScalarType, size ir.VectorSize) string {
 	typeName := scalarTypeName(scalar)
-	name := fmt.Sprintf("naga_dot_%s%d", typeName, size)
+	name := w.helperName(fmt.Sprintf("naga_dot_%s%d", typeName, size))
 	for _, existing := range w.dotWrappers {
 		if existing.name == name {
 			return name
@@ -2049,7 +1996,7 @@ func (w *Writer) writeNegHelper(h absHelper) {
 		typeName = fmt.Sprintf("%s%s%d", Namespace, typeName, h.vecSize)
 		unsignedName = fmt.Sprintf("%s%s%d", Namespace, unsignedName, h.vecSize)
 	}
-	w.WriteLine("%s naga_neg(%s val) {", typeName, typeName)
+	w.WriteLine("%s %s(%s val) {", typeName, w.helperName("naga_neg"), typeName)
 	w.PushIndent()
 	w.WriteLine("return as_type<%s>(-as_type<%s>(val));", typeName, unsignedName)
 	w.PopIndent()
@@ -2208,13 +2155,13 @@ func (w *Writer) markGlobalWritePerFunc(handle ir.ExpressionHandle, expressions
 	}
 }
 
-// isStorageGlobalReadOnly returns true if a storage global variable is not
-// written to by the current function. Matches Rust naga's per-function
+// isStorageGlobalReadOnly returns true if a storage or task payload global
+// variable is not written to by the current function. Matches Rust naga's per-function
 // GlobalUse::WRITE analysis: each function parameter gets const based on
 // whether THAT function writes to the global, not module-wide.
 func (w *Writer) isStorageGlobalReadOnly(handle uint32) bool {
 	global := &w.module.GlobalVariables[handle]
-	if global.Space != ir.SpaceStorage {
+	if global.Space != ir.SpaceStorage && global.Space != ir.SpaceTaskPayload {
 		return false
 	}
 	// Check per-function write usage (matches Rust naga's per-function analysis).
@@ -4,7 +4,9 @@
 package msl
 
 import (
+	"context"
 	"fmt"
+	"log/slog"
 
 	"github.com/gogpu/naga/ir"
 	"github.com/gogpu/naga/msl/internal/codegen"
@@ -39,6 +41,7 @@ var (
 	Version2_4 = Version{Major: 2, Minor: 4}
 	Version3_0 = Version{Major: 3, Minor: 0}
 	Version3_1 = Version{Major: 3, Minor: 1}
+	Version3_2 = Version{Major: 3, Minor: 2}
 )
 
 // BoundsCheckPolicy controls how out-of-bounds accesses are handled.
@@ -194,10 +197,17 @@ type EntryPointResources struct {
 
 // Options configures MSL code generation.
 type Options struct {
-	// LangVersion is the target MSL version.
-	// Defaults to Version2_1 if zero.
+	// LangVersion is the target MSL version, from 1.0 through 3.2.
+	// Defaults to Version2_1 if zero. A module using something a newer
+	// version adds, such as f32 atomics (3.0), is written for that newer
+	// version unless StrictVersion is set.
 	LangVersion Version
 
+	// StrictVersion makes Compile fail, naming the feature, when the
+	// module needs a newer MSL version than LangVersion, rather than
+	// raising the version the output is written for.
+	StrictVersion bool
+
 	// PerEntryPointMap maps entry point names to their resource bindings.
 	// If nil, bindings are auto-generated.
 	PerEntryPointMap map[string]EntryPointResources
@@ -226,11 +236,68 @@ type Options struct {
 	// AllowAndForcePointSize forces point size output for vertex shaders.
 	AllowAndForcePointSize bool
 
+	// InvariantPosition marks every vertex position output invariant, as if
+	// it were declared @invariant, so separately compiled passes (e.g. a
+	// depth prepass and the main pass) produce identical positions.
+	InvariantPosition bool
+
+	// DisableFMAContraction stops the Metal compiler from fusing multiplies
+	// and adds into FMAs. Combine it with MTLMathModeSafe when positions
+	// must match exactly across pipelines.
+	DisableFMAContraction bool
+
+	// FastMathSafeFloatChecks emits isnan/isinf as bit-pattern tests that
+	// survive Metal's default fast-math mode.
+	FastMathSafeFloatChecks bool
+
+	// PreciseMath calls the metal::precise variants of f32 transcendental
+	// functions (sin, exp, pow, sqrt, ...) rather than the fast ones
+	// Metal's default fast math selects, and implies DisableFMAContraction
+	// and FastMathSafeFloatChecks. Physics and other compute shaders that
+	// need the same results on every GPU should set it and compile with
+	// MTLCompileOptions.mathMode = MTLMathModeSafe.
+	PreciseMath bool
+
+	// FloatPrecision is the number of significant digits written for float
+	// literals. Zero writes the shortest form that reads back as the same
+	// value, such as 0.1 for the f32 nearest 0.1.
+	FloatPrecision int
+
+	// WriteMaskStores writes partial vector assignments, such as a GLSL
+	// v.zx = n or a store of a shuffle of the old and new vector, as
+	// assignments to the changed components rather than whole-vector
+	// stores. Stores into packed vec3 members, which cannot take
+	// a multi-component swizzle, become one store per component.
+	WriteMaskStores bool
+
 	// VertexPullingTransform enables vertex pulling transformation.
 	VertexPullingTransform bool
 
 	// VertexBufferMappings describes the vertex buffer layout for vertex pulling.
 	VertexBufferMappings []VertexBufferMapping
+
+	// EntryPointNames renames entry points in the generated code
+	// (WGSL name → MSL function name), e.g. "main" → "vs_main_quad".
+	// The new name is sanitized and escaped like any other identifier;
+	// TranslationInfo.EntryPointNames reports the final spelling.
+	// Renamed entry points ignore SymbolPrefix.
+	EntryPointNames map[string]string
+
+	// SymbolPrefix is prepended to every module-scope symbol: types,
+	// functions, globals, constants, overrides, and entry points not
+	// listed in EntryPointNames. Use it to avoid collisions when several
+	// generated shaders are concatenated into one Metal library.
+	// Generated helper functions (naga_div, naga_mod, ...) are prefixed
+	// too.
+	SymbolPrefix string
+
+	// SourceMap fills TranslationInfo.SourceMap.
+	SourceMap bool
+
+	// Logger, if set, receives debug traces of code generation phases and
+	// a warning for each polyfill emitted (e.g. "emulating
+	// textureSampleBaseClampToEdge"). nil disables logging.
+	Logger *slog.Logger
 }
 
 // VertexFormat describes the format of a vertex attribute.
@@ -322,12 +389,31 @@ type EntryPointSelector struct {
 
 // TranslationInfo contains information about the compiled MSL output.
 type TranslationInfo struct {
+	// LangVersion is the MSL version the output is written for, to pass
+	// as MTLCompileOptions.languageVersion: Options.LangVersion, or a
+	// newer one if the module needs it.
+	LangVersion Version
+
 	// EntryPointNames maps original entry point names to generated MSL names.
 	EntryPointNames map[string]string
 
 	// RequiresSizesBuffer indicates if a sizes buffer is needed for
 	// runtime-sized arrays.
 	RequiresSizesBuffer bool
+
+	// RequiresPreserveInvariance reports that an output is invariant; the
+	// library must be compiled with MTLCompileOptions.preserveInvariance.
+	RequiresPreserveInvariance bool
+
+	// SourceMap, with Options.SourceMap, maps the 1-based lines where
+	// statements start to the WGSL spans they were generated from.
+	SourceMap []ir.OutputSpan
+
+	// Padding lists the char _padN[size] members the output adds to the
+	// module's structs, in output order. MSL's natural layout packs
+	// members tighter than WGSL, so these keep the offsets a host
+	// serializer computed from the WGSL layout.
+	Padding []ir.StructPadding
 }
 
 // DefaultBoundsCheckPolicies returns conservative bounds check policies.
@@ -356,11 +442,23 @@ func Compile(module *ir.Module, options Options) (string, TranslationInfo, error
 	return CompileWithPipeline(module, options, PipelineOptions{})
 }
 
+// CompileContext is like Compile but stops with an error wrapping ctx.Err()
+// once ctx is canceled. Cancellation is checked between functions.
+func CompileContext(ctx context.Context, module *ir.Module, options Options) (string, TranslationInfo, error) {
+	return CompileWithPipelineContext(ctx, module, options, PipelineOptions{})
+}
+
 // CompileWithPipeline generates MSL source code with pipeline-specific options.
 func CompileWithPipeline(module *ir.Module, options Options, pipeline PipelineOptions) (string, TranslationInfo, error) {
+	return CompileWithPipelineContext(context.Background(), module, options, pipeline)
+}
+
+// CompileWithPipelineContext is CompileWithPipeline with the cancellation
+// of CompileContext.
+func CompileWithPipelineContext(ctx context.Context, module *ir.Module, options Options, pipeline PipelineOptions) (string, TranslationInfo, error) {
 	copts := toCodegenOptions(options)
 	cpipe := toCodegenPipelineOptions(pipeline)
-	src, cinfo, err := codegen.CompileWithPipeline(module, copts, cpipe)
+	src, cinfo, err := codegen.CompileWithPipelineContext(ctx, module, copts, cpipe)
 	if err != nil {
 		return "", TranslationInfo{}, err
 	}
@@ -424,8 +522,19 @@ func toCodegenOptions(o Options) codegen.Options {
 		FakeMissingBindings:           o.FakeMissingBindings,
 		PipelineConstants:             o.PipelineConstants,
 		AllowAndForcePointSize:        o.AllowAndForcePointSize,
+		InvariantPosition:             o.InvariantPosition,
+		DisableFMAContraction:         o.DisableFMAContraction,
+		FastMathSafeFloatChecks:       o.FastMathSafeFloatChecks,
+		PreciseMath:                   o.PreciseMath,
+		FloatPrecision:                o.FloatPrecision,
+		WriteMaskStores:               o.WriteMaskStores,
 		VertexPullingTransform:        o.VertexPullingTransform,
 		VertexBufferMappings:          vbMappings,
+		EntryPointNames:               o.EntryPointNames,
+		SymbolPrefix:                  o.SymbolPrefix,
+		SourceMap:                     o.SourceMap,
+		StrictVersion:                 o.StrictVersion,
+		Logger:                        o.Logger,
 	}
 }
 
@@ -507,7 +616,11 @@ func toCodegenPipelineOptions(p PipelineOptions) codegen.PipelineOptions {
 // fromCodegenTranslationInfo converts internal codegen TranslationInfo to public type.
 func fromCodegenTranslationInfo(ci codegen.TranslationInfo) TranslationInfo {
 	return TranslationInfo{
-		EntryPointNames:     ci.EntryPointNames,
-		RequiresSizesBuffer: ci.RequiresSizesBuffer,
+		LangVersion:                Version{Major: ci.LangVersion.Major, Minor: ci.LangVersion.Minor},
+		EntryPointNames:            ci.EntryPointNames,
+		RequiresSizesBuffer:        ci.RequiresSizesBuffer,
+		RequiresPreserveInvariance: ci.RequiresPreserveInvariance,
+		SourceMap:                  ci.SourceMap,
+		Padding:                    ci.Padding,
 	}
 }
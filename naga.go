@@ -31,10 +31,30 @@
 //
 //	module, _ := naga.Lower(ast)
 //	glslCode, info, err := glsl.Compile(module, glsl.DefaultOptions())
+//
+// ParseFile and LowerFile are Parse and Lower for tools that report
+// diagnostics: errors and warnings carry the file name and source span.
+// Both stages are also available as a Frontend, the interface other
+// source languages implement:
+//
+//	result, err := naga.WGSLFrontend{}.Translate(ctx, "shader.wgsl", source)
+//	if err != nil {
+//	    log.Fatal(err) // shader.wgsl:7:12: function main body: unresolved identifier: x
+//	}
+//	for _, w := range result.Warnings {
+//	    fmt.Println(w) // shader.wgsl:3:9: unused variable 'x' in function 'main'
+//	}
+//
+// GLSLComputeFrontend is a Frontend for GLSL compute shaders; see
+// glsl.TranslateCompute.
 package naga
 
 import (
+	"context"
 	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
 
 	"github.com/gogpu/naga/ir"
 	"github.com/gogpu/naga/spirv"
@@ -49,8 +69,358 @@ type CompileOptions struct {
 	// Debug enables debug info in output (OpName, OpLine, etc.)
 	Debug bool
 
+	// DebugExpressionNames, with Debug, also names each intermediate
+	// SPIR-V result after its WGSL expression text. See
+	// spirv.Options.DebugExpressionNames.
+	DebugExpressionNames bool
+
+	// SourceMap makes the spirv pass fill PassState.SourceMap. See
+	// spirv.Options.SourceMap.
+	SourceMap bool
+
 	// Validate enables IR validation before code generation
 	Validate bool
+
+	// WarningsAsErrors fails compilation with a *WarningsError when
+	// lowering reports any warning (unused variables, parameters, private
+	// globals, unreachable code).
+	WarningsAsErrors bool
+
+	// Strict fails lowering on source the WGSL spec does not allow but
+	// which is otherwise lowered to a default with a warning. See
+	// wgsl.LowerOptions.Strict.
+	Strict bool
+
+	// Constants gives module constants other values before they are
+	// folded. See wgsl.LowerOptions.Constants.
+	Constants ir.PipelineConstants
+
+	// LanguageFeatures lists the WGSL language features shaders may name in
+	// requires directives. A shader requiring any other feature fails with
+	// a *LanguageFeatureError. nil allows every feature this compiler
+	// implements (wgsl.ImplementedLanguageFeatures); an empty, non-nil
+	// slice rejects every requires directive.
+	LanguageFeatures []string
+
+	// Features are the compile-time feature flags @if attributes test;
+	// see ParseWithFeatures. nil sets no feature.
+	Features map[string]bool
+
+	// LinkStages, when set, checks that the named vertex entry point's
+	// @location outputs match the named fragment entry point's inputs in
+	// type and interpolation. Mismatches fail with a *LinkError.
+	LinkStages *StageLink
+
+	// Limits, when set, checks each compute entry point's workgroup
+	// storage against Limits.MaxComputeWorkgroupStorageSize, and the
+	// components each vertex and fragment entry point passes between
+	// stages against Limits.MaxInterStageShaderComponents. Entry points
+	// over a limit fail with a *WorkgroupStorageError or an
+	// *InterStageComponentsError.
+	Limits *Limits
+
+	// ShareConstantArrays, when nonzero, moves constant arrays of at
+	// least this many elements built inside functions into module
+	// constants, one per distinct value, so a lookup table pasted into
+	// several functions is written once. See ir.ShareConstantArrays.
+	ShareConstantArrays uint32
+
+	// EntryPoints, when not empty, keeps only the entry points with these
+	// names, and the functions and globals they use, in the output, so
+	// each stage can be shipped as its own SPIR-V module. Naming an entry
+	// point the shader does not have is an error. See
+	// ir.SelectEntryPoints.
+	EntryPoints []string
+
+	// Peephole, when set, replaces comparison and select chains with the
+	// min, max, clamp or select they compute before code generation. See
+	// ir.Peephole.
+	Peephole *ir.PeepholeOptions
+
+	// Logger, if set, receives a debug record for each compile pass and
+	// SPIR-V generation phase, and a warning for each polyfill the SPIR-V
+	// backend emits. nil disables logging.
+	Logger *slog.Logger
+}
+
+// StageLink names the vertex and fragment entry points of one pipeline.
+type StageLink struct {
+	Vertex   string
+	Fragment string
+}
+
+// StageMismatch is a fragment input that does not match the vertex
+// output at the same location. VertexSpan is the zero Span when the
+// vertex stage has no output at that location.
+type StageMismatch struct {
+	Message      string
+	VertexSpan   wgsl.Span
+	FragmentSpan wgsl.Span
+}
+
+// LinkError is returned by CompileWithOptions when CompileOptions.LinkStages
+// is set and the stages do not match.
+type LinkError struct {
+	Mismatches []StageMismatch
+}
+
+// Error implements the error interface, reporting the first mismatch.
+func (e *LinkError) Error() string {
+	if len(e.Mismatches) == 0 {
+		return "stage linkage failed"
+	}
+	first := e.Mismatches[0]
+	msg := first.Message
+	if first.FragmentSpan.Start.Line > 0 {
+		msg = fmt.Sprintf("%d:%d: %s", first.FragmentSpan.Start.Line, first.FragmentSpan.Start.Column, msg)
+	}
+	if first.VertexSpan.Start.Line > 0 {
+		msg += fmt.Sprintf(" (vertex output declared at %d:%d)", first.VertexSpan.Start.Line, first.VertexSpan.Start.Column)
+	}
+	if n := len(e.Mismatches) - 1; n > 0 {
+		msg += fmt.Sprintf(" (and %d more)", n)
+	}
+	return msg
+}
+
+// CheckStageLinkage compares the @location outputs of a vertex entry point
+// with the inputs of a fragment entry point, returning every fragment
+// input that has no vertex output at its location or whose type or
+// interpolation differs. Spans point into the WGSL source of ast; they
+// are zero when ast is nil.
+func CheckStageLinkage(ast *wgsl.Module, module *ir.Module, vertex, fragment string) ([]StageMismatch, error) {
+	errs, err := ir.CheckStageLinkage(module, vertex, fragment)
+	if err != nil {
+		return nil, err
+	}
+	mismatches := make([]StageMismatch, len(errs))
+	for i, e := range errs {
+		mismatches[i].Message = e.Message
+		mismatches[i].FragmentSpan, _ = ast.StageIOSpan(e.Fragment)
+		if e.Vertex != nil {
+			mismatches[i].VertexSpan, _ = ast.StageIOSpan(*e.Vertex)
+		}
+	}
+	return mismatches, nil
+}
+
+// Limits are the device limits CompileOptions.Limits checks shaders
+// against. A zero field is not checked.
+type Limits struct {
+	// MaxComputeWorkgroupStorageSize is the bytes of var<workgroup>
+	// memory one compute entry point may use.
+	MaxComputeWorkgroupStorageSize uint32
+
+	// MaxInterStageShaderComponents is the components of @location
+	// variables a vertex entry point may output or a fragment entry point
+	// may take as input, as ir.EntryPointInterStageComponents counts them.
+	// Some drivers, mostly mobile GLES ones, drop varyings past their
+	// budget instead of failing.
+	MaxInterStageShaderComponents uint32
+}
+
+// DefaultLimits returns the WebGPU default limits.
+func DefaultLimits() Limits {
+	return Limits{MaxComputeWorkgroupStorageSize: 16384, MaxInterStageShaderComponents: 60}
+}
+
+// WorkgroupVariable is a var<workgroup> counted against an entry point's
+// workgroup storage. Size is rounded up to 16 bytes as WebGPU counts it.
+type WorkgroupVariable struct {
+	Name string
+	Size uint32
+	Span wgsl.Span
+}
+
+// WorkgroupStorageError is returned by CompileWithOptions when a compute
+// entry point uses more workgroup storage than CompileOptions.Limits
+// allows. Variables lists the workgroup variables the entry point uses, in
+// global variable handle order.
+type WorkgroupStorageError struct {
+	EntryPoint string
+	Size       uint32
+	Limit      uint32
+	Variables  []WorkgroupVariable
+}
+
+// Error implements the error interface, pointing at the first variable.
+func (e *WorkgroupStorageError) Error() string {
+	msg := fmt.Sprintf("entry point '%s' uses %d bytes of workgroup storage, more than the limit of %d",
+		e.EntryPoint, e.Size, e.Limit)
+	if len(e.Variables) > 0 && e.Variables[0].Span.Start.Line > 0 {
+		first := e.Variables[0].Span.Start
+		msg = fmt.Sprintf("%d:%d: %s", first.Line, first.Column, msg)
+	}
+	return msg
+}
+
+// checkWorkgroupStorage reports the first compute entry point of module
+// whose workgroup storage exceeds limits. Spans point into the WGSL
+// source of ast; they are zero when ast is nil.
+func checkWorkgroupStorage(ast *wgsl.Module, module *ir.Module, limits Limits) error {
+	if limits.MaxComputeWorkgroupStorageSize == 0 {
+		return nil
+	}
+	for i, ep := range module.EntryPoints {
+		if ep.Stage != ir.StageCompute {
+			continue
+		}
+		allocs, total := ir.EntryPointWorkgroupStorage(module, i)
+		if total <= limits.MaxComputeWorkgroupStorageSize {
+			continue
+		}
+		vars := make([]WorkgroupVariable, len(allocs))
+		for j, a := range allocs {
+			name := module.GlobalVariables[a.Global].Name
+			vars[j] = WorkgroupVariable{Name: name, Size: a.Size}
+			vars[j].Span, _ = ast.GlobalSpan(name)
+		}
+		return &WorkgroupStorageError{
+			EntryPoint: ep.Name,
+			Size:       total,
+			Limit:      limits.MaxComputeWorkgroupStorageSize,
+			Variables:  vars,
+		}
+	}
+	return nil
+}
+
+// InterStageVariable is a @location variable counted against an entry
+// point's inter-stage components. Name is the struct member or argument
+// carrying it, empty for a vertex result bound directly to a location.
+type InterStageVariable struct {
+	Name       string
+	Location   uint32
+	Components uint32
+	Span       wgsl.Span
+}
+
+// InterStageComponentsError is returned by CompileWithOptions when a
+// vertex entry point outputs, or a fragment entry point takes as input,
+// more inter-stage components than CompileOptions.Limits allows.
+// Variables lists the ones over the budget: counting built-in inputs
+// first and then the variables in location order, each variable that no
+// longer fits.
+type InterStageComponentsError struct {
+	EntryPoint string
+	Stage      ir.ShaderStage
+	Components uint32
+	Limit      uint32
+	Variables  []InterStageVariable
+}
+
+// Error implements the error interface, pointing at the first variable
+// over the budget.
+func (e *InterStageComponentsError) Error() string {
+	direction := "outputs"
+	if e.Stage == ir.StageFragment {
+		direction = "inputs"
+	}
+	msg := fmt.Sprintf("entry point '%s' %s %d inter-stage components, more than the limit of %d",
+		e.EntryPoint, direction, e.Components, e.Limit)
+	if len(e.Variables) == 0 {
+		return msg
+	}
+	over := make([]string, len(e.Variables))
+	for i, v := range e.Variables {
+		over[i] = fmt.Sprintf("@location(%d)", v.Location)
+		if v.Name != "" {
+			over[i] = fmt.Sprintf("'%s' %s", v.Name, over[i])
+		}
+	}
+	msg += "; over the limit: " + strings.Join(over, ", ")
+	if first := e.Variables[0].Span.Start; first.Line > 0 {
+		msg = fmt.Sprintf("%d:%d: %s", first.Line, first.Column, msg)
+	}
+	return msg
+}
+
+// checkInterStageComponents reports the first vertex or fragment entry
+// point of module whose inter-stage components exceed limits. Spans point
+// into the WGSL source of ast; they are zero when ast is nil.
+func checkInterStageComponents(ast *wgsl.Module, module *ir.Module, limits Limits) error {
+	limit := limits.MaxInterStageShaderComponents
+	if limit == 0 {
+		return nil
+	}
+	for i, ep := range module.EntryPoints {
+		vars, total := ir.EntryPointInterStageComponents(module, i)
+		if total <= limit {
+			continue
+		}
+		used := total
+		for _, v := range vars {
+			used -= v.Components
+		}
+		var over []InterStageVariable
+		for _, v := range vars {
+			used += v.Components
+			if used <= limit {
+				continue
+			}
+			name := v.Argument
+			if v.Struct != "" {
+				name = v.Member
+			}
+			span, _ := ast.StageIOSpan(v.StageIO)
+			over = append(over, InterStageVariable{Name: name, Location: v.Location, Components: v.Components, Span: span})
+		}
+		return &InterStageComponentsError{
+			EntryPoint: ep.Name,
+			Stage:      ep.Stage,
+			Components: total,
+			Limit:      limit,
+			Variables:  over,
+		}
+	}
+	return nil
+}
+
+// LanguageFeatureError is returned by CompileWithOptions when the shader
+// requires a language feature missing from CompileOptions.LanguageFeatures.
+type LanguageFeatureError struct {
+	Feature string
+	Line    int
+	Column  int
+}
+
+// Error implements the error interface.
+func (e *LanguageFeatureError) Error() string {
+	return fmt.Sprintf("%d:%d: shader requires language feature '%s', which is not enabled", e.Line, e.Column, e.Feature)
+}
+
+// checkLanguageFeatures reports the first feature required by ast that
+// allowed does not contain. A nil allowed list accepts everything.
+func checkLanguageFeatures(ast *wgsl.Module, allowed []string) error {
+	if allowed == nil {
+		return nil
+	}
+	for _, f := range ast.Requires() {
+		if !slices.Contains(allowed, f.Name) {
+			return &LanguageFeatureError{Feature: f.Name, Line: f.Span.Start.Line, Column: f.Span.Start.Column}
+		}
+	}
+	return nil
+}
+
+// WarningsError is returned by CompileWithOptions when
+// CompileOptions.WarningsAsErrors is set and lowering produced warnings.
+type WarningsError struct {
+	Warnings []wgsl.Warning
+}
+
+// Error implements the error interface, reporting the first warning.
+func (e *WarningsError) Error() string {
+	if len(e.Warnings) == 0 {
+		return "warnings treated as errors"
+	}
+	first := e.Warnings[0]
+	msg := fmt.Sprintf("warnings treated as errors: %d:%d: %s",
+		first.Span.Start.Line, first.Span.Start.Column, first.Message)
+	if n := len(e.Warnings) - 1; n > 0 {
+		msg += fmt.Sprintf(" (and %d more)", n)
+	}
+	return msg
 }
 
 // DefaultOptions returns sensible default options.
@@ -73,45 +443,41 @@ func Compile(source string) ([]byte, error) {
 // CompileWithOptions compiles WGSL source code to SPIR-V binary with custom options.
 //
 // The compilation pipeline is:
-//  1. Parse WGSL source to AST
-//  2. Lower AST to IR (intermediate representation)
-//  3. Validate IR (if enabled)
+//  1. Parse WGSL source to AST, checking requires directives against
+//     LanguageFeatures
+//  2. Lower AST to IR (intermediate representation), failing on warnings
+//     if WarningsAsErrors is set
+//  3. Validate IR (if enabled), check workgroup storage against Limits
+//     if set, and check vertex/fragment linkage if LinkStages is set
 //  4. Generate SPIR-V binary
+//
+// Each step is a pass of the standard PassManager; use NewPassManager to
+// insert custom passes or CompileWithStats to time them.
 func CompileWithOptions(source string, opts CompileOptions) ([]byte, error) {
-	// Parse WGSL to AST
-	ast, err := Parse(source)
-	if err != nil {
-		return nil, fmt.Errorf("parse error: %w", err)
-	}
+	return CompileContext(context.Background(), source, opts)
+}
 
-	// Lower AST to IR (pass source for error messages)
-	module, err := LowerWithSource(ast, source)
+// CompileContext is CompileWithOptions with cancellation: once ctx is
+// canceled, compilation stops at the next pass boundary, declaration, or
+// function and returns an error wrapping ctx.Err(). Editors and servers
+// use it to abandon stale compilations of large shaders.
+func CompileContext(ctx context.Context, source string, opts CompileOptions) ([]byte, error) {
+	state, _, err := NewPassManager().RunContext(ctx, source, opts)
 	if err != nil {
-		return nil, fmt.Errorf("lowering error: %w", err)
-	}
-
-	// Validate IR if requested
-	if opts.Validate {
-		validationErrors, err := Validate(module)
-		if err != nil {
-			return nil, fmt.Errorf("validation error: %w", err)
-		}
-		if len(validationErrors) > 0 {
-			return nil, fmt.Errorf("validation failed: %w", &validationErrors[0])
-		}
+		return nil, err
 	}
+	return state.SPIRV, nil
+}
 
-	// Generate SPIR-V
-	spirvOpts := spirv.Options{
-		Version: opts.SPIRVVersion,
-		Debug:   opts.Debug,
-	}
-	spirvBytes, err := GenerateSPIRV(module, spirvOpts)
+// CompileWithStats is CompileWithOptions that also reports per-pass
+// timing and module/binary counters. The stats are returned even when
+// compilation fails.
+func CompileWithStats(source string, opts CompileOptions) ([]byte, *Stats, error) {
+	state, stats, err := NewPassManager().Run(source, opts)
 	if err != nil {
-		return nil, fmt.Errorf("SPIR-V generation error: %w", err)
+		return nil, stats, err
 	}
-
-	return spirvBytes, nil
+	return state.SPIRV, stats, nil
 }
 
 // Parse parses WGSL source code to AST (Abstract Syntax Tree).
@@ -119,6 +485,20 @@ func CompileWithOptions(source string, opts CompileOptions) ([]byte, error) {
 // This is the first stage of compilation. The AST represents the syntactic
 // structure of the shader but does not include semantic information like types.
 func Parse(source string) (*wgsl.Module, error) {
+	return ParseWithFeatures(source, nil)
+}
+
+// ParseWithFeatures is Parse with compile-time feature flags. A
+// declaration, struct member, or statement marked @if(condition) is kept
+// only when its condition holds for features, so one source can build
+// several shader variants:
+//
+//	@if(FOG)
+//	fn apply_fog(color: vec3<f32>, depth: f32) -> vec3<f32> { ... }
+//
+// Conditions combine feature names with true, false, !, &&, and ||. A
+// feature missing from the map is false.
+func ParseWithFeatures(source string, features map[string]bool) (*wgsl.Module, error) {
 	// Tokenize
 	lexer := wgsl.NewLexer(source)
 	tokens, err := lexer.Tokenize()
@@ -127,7 +507,7 @@ func Parse(source string) (*wgsl.Module, error) {
 	}
 
 	// Parse to AST
-	parser := wgsl.NewParser(tokens)
+	parser := wgsl.NewParserWithFeatures(tokens, features)
 	module, err := parser.Parse()
 	if err != nil {
 		return nil, fmt.Errorf("parse error: %w", err)
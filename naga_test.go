@@ -1,8 +1,13 @@
 package naga
 
 import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
 	"testing"
 
+	"github.com/gogpu/naga/ir"
 	"github.com/gogpu/naga/spirv"
 )
 
@@ -545,3 +550,456 @@ fn main(@builtin(vertex_index) idx: u32) -> @builtin(position) vec4<f32> {
 
 	t.Logf("Generated %d bytes of SPIR-V for local const", len(spirvBytes))
 }
+
+func TestCompileWarningsAsErrors(t *testing.T) {
+	source := `
+var<private> unused: f32;
+
+@fragment
+fn main() -> @location(0) vec4<f32> {
+    return vec4<f32>(1.0);
+}
+`
+	if _, err := CompileWithOptions(source, DefaultOptions()); err != nil {
+		t.Fatalf("warnings must not fail compilation by default: %v", err)
+	}
+
+	opts := DefaultOptions()
+	opts.WarningsAsErrors = true
+	_, err := CompileWithOptions(source, opts)
+	var warnErr *WarningsError
+	if !errors.As(err, &warnErr) {
+		t.Fatalf("expected *WarningsError, got %v", err)
+	}
+	if len(warnErr.Warnings) != 1 || warnErr.Warnings[0].Message != "unused private variable 'unused'" {
+		t.Errorf("unexpected warnings: %+v", warnErr.Warnings)
+	}
+}
+
+func TestCompileStrict(t *testing.T) {
+	source := `
+override scale;
+
+@compute @workgroup_size(1)
+fn main() {
+    _ = scale;
+}
+`
+	if _, err := CompileWithOptions(source, DefaultOptions()); err != nil {
+		t.Fatalf("permissive mode must lower the untyped override: %v", err)
+	}
+
+	opts := DefaultOptions()
+	opts.Strict = true
+	_, err := CompileWithOptions(source, opts)
+	if err == nil || !strings.Contains(err.Error(), "override 'scale' has neither a type nor an initializer") {
+		t.Errorf("strict mode: got %v", err)
+	}
+}
+
+func TestCompileLanguageFeatures(t *testing.T) {
+	source := `
+requires readonly_and_readwrite_storage_textures, pointer_composite_access,;
+
+@compute @workgroup_size(1)
+fn main() {}
+`
+	if _, err := CompileWithOptions(source, DefaultOptions()); err != nil {
+		t.Fatalf("implemented features must be allowed by default: %v", err)
+	}
+
+	opts := DefaultOptions()
+	opts.LanguageFeatures = []string{"readonly_and_readwrite_storage_textures"}
+	_, err := CompileWithOptions(source, opts)
+	var featErr *LanguageFeatureError
+	if !errors.As(err, &featErr) {
+		t.Fatalf("expected *LanguageFeatureError, got %v", err)
+	}
+	if featErr.Feature != "pointer_composite_access" || featErr.Line != 2 {
+		t.Errorf("unexpected error: %+v", featErr)
+	}
+
+	opts.LanguageFeatures = []string{}
+	if _, err := CompileWithOptions("@compute @workgroup_size(1) fn main() {}", opts); err != nil {
+		t.Errorf("shader without requires must compile with no features enabled: %v", err)
+	}
+}
+
+func TestCompileUnknownLanguageFeature(t *testing.T) {
+	for source, want := range map[string]string{
+		"requires no_such_feature;":         "unknown language feature 'no_such_feature'",
+		"requires subgroup_uniformity;":     "language feature 'subgroup_uniformity' is not yet implemented",
+		"requires pointer_composite_access": "expected ;",
+		"requires;":                         "expected language feature name",
+	} {
+		_, err := Compile(source)
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("%q: got %v, want error containing %q", source, err, want)
+		}
+	}
+}
+
+func TestCompileFeatures(t *testing.T) {
+	source := `
+@if(SHADOWS) @group(0) @binding(0) var shadow_map: texture_depth_2d;
+@if(SHADOWS) @group(0) @binding(1) var shadow_samp: sampler_comparison;
+
+@fragment
+fn fs(@location(0) uv: vec2<f32>) -> @location(0) vec4<f32> {
+    var light = 1.0;
+    @if(SHADOWS) {
+        light = textureSampleCompare(shadow_map, shadow_samp, uv, 0.5);
+    }
+    @if(FOG && !SHADOWS) light = light * 0.5;
+    return vec4<f32>(light);
+}
+`
+	for _, tt := range []struct {
+		features map[string]bool
+		globals  int
+	}{
+		{nil, 0},
+		{map[string]bool{"FOG": true}, 0},
+		{map[string]bool{"SHADOWS": true, "FOG": true}, 2},
+	} {
+		opts := DefaultOptions()
+		opts.Features = tt.features
+		if _, err := CompileWithOptions(source, opts); err != nil {
+			t.Fatalf("features %v: %v", tt.features, err)
+		}
+
+		ast, err := ParseWithFeatures(source, tt.features)
+		if err != nil {
+			t.Fatal(err)
+		}
+		module, err := Lower(ast)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := len(module.GlobalVariables); got != tt.globals {
+			t.Errorf("features %v: got %d globals, want %d", tt.features, got, tt.globals)
+		}
+	}
+
+	if _, err := Compile(source); err != nil {
+		t.Errorf("source without features must compile: %v", err)
+	}
+}
+
+func TestCompileRejectsIncompatibleSampler(t *testing.T) {
+	source := `
+@group(0) @binding(0) var t: texture_depth_2d;
+@group(0) @binding(1) var s: sampler;
+
+@fragment
+fn main() -> @location(0) vec4<f32> {
+    return vec4<f32>(textureSampleCompare(t, s, vec2<f32>(0.5), 0.5));
+}
+`
+	_, err := Compile(source)
+	if err == nil || !strings.Contains(err.Error(), "depth comparison requires a sampler_comparison") {
+		t.Errorf("expected sampler compatibility error, got %v", err)
+	}
+}
+
+const linkSource = `
+struct VsOut {
+    @builtin(position) pos: vec4<f32>,
+    @location(0) color: vec4<f32>,
+    @location(1) @interpolate(flat) id: u32,
+}
+
+@vertex
+fn vs() -> VsOut {
+    return VsOut(vec4<f32>(0.0), vec4<f32>(1.0), 0u);
+}
+
+@fragment
+fn fs_ok(@location(0) color: vec4<f32>) -> @location(0) vec4<f32> {
+    return color;
+}
+
+@fragment
+fn fs_bad(@location(0) color: vec3<f32>, @location(2) uv: vec2<f32>) -> @location(0) vec4<f32> {
+    return vec4<f32>(color, uv.x);
+}
+`
+
+func TestCompileLinkStages(t *testing.T) {
+	opts := DefaultOptions()
+	opts.LinkStages = &StageLink{Vertex: "vs", Fragment: "fs_ok"}
+	if _, err := CompileWithOptions(linkSource, opts); err != nil {
+		t.Fatalf("matching stages: %v", err)
+	}
+
+	opts.LinkStages = &StageLink{Vertex: "vs", Fragment: "fs_bad"}
+	_, err := CompileWithOptions(linkSource, opts)
+	var linkErr *LinkError
+	if !errors.As(err, &linkErr) {
+		t.Fatalf("expected *LinkError, got %v", err)
+	}
+	if len(linkErr.Mismatches) != 2 {
+		t.Fatalf("expected 2 mismatches, got %+v", linkErr.Mismatches)
+	}
+	typeErr := linkErr.Mismatches[0]
+	if !strings.Contains(typeErr.Message, "vertex output is vec4<f32>, fragment input is vec3<f32>") {
+		t.Errorf("type mismatch message = %q", typeErr.Message)
+	}
+	if typeErr.VertexSpan.Start.Line != 4 || typeErr.FragmentSpan.Start.Line != 19 {
+		t.Errorf("spans = vertex %d, fragment %d; want 4 and 19",
+			typeErr.VertexSpan.Start.Line, typeErr.FragmentSpan.Start.Line)
+	}
+	missing := linkErr.Mismatches[1]
+	if !strings.Contains(missing.Message, "@location(2) has no matching vertex output") || missing.VertexSpan.Start.Line != 0 {
+		t.Errorf("missing output mismatch = %+v", missing)
+	}
+	if !strings.Contains(err.Error(), "19:") || !strings.Contains(err.Error(), "vertex output declared at 4:") {
+		t.Errorf("error %q should carry both locations", err)
+	}
+
+	opts.LinkStages = &StageLink{Vertex: "vs", Fragment: "missing"}
+	if _, err := CompileWithOptions(linkSource, opts); err == nil || !strings.Contains(err.Error(), "not found") {
+		t.Errorf("unknown entry point: got %v", err)
+	}
+}
+
+func TestCompileWorkgroupStorageLimit(t *testing.T) {
+	const source = `
+var<workgroup> tile: array<vec4<f32>, 1024>;
+var<workgroup> count: atomic<u32>;
+
+@compute @workgroup_size(64)
+fn main(@builtin(local_invocation_index) i: u32) {
+    tile[i] = vec4<f32>(f32(atomicAdd(&count, 1u)));
+}
+`
+	opts := DefaultOptions()
+	limits := DefaultLimits()
+	opts.Limits = &limits
+	_, err := CompileWithOptions(source, opts)
+	var storageErr *WorkgroupStorageError
+	if !errors.As(err, &storageErr) {
+		t.Fatalf("expected *WorkgroupStorageError, got %v", err)
+	}
+	if storageErr.EntryPoint != "main" || storageErr.Size != 16400 || storageErr.Limit != 16384 {
+		t.Errorf("error = %+v", storageErr)
+	}
+	want := []string{"tile@2:16384", "count@3:16"}
+	var got []string
+	for _, v := range storageErr.Variables {
+		got = append(got, fmt.Sprintf("%s@%d:%d", v.Name, v.Span.Start.Line, v.Size))
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("variables = %v, want %v", got, want)
+	}
+	if !strings.Contains(err.Error(), "2:1: entry point 'main' uses 16400 bytes") {
+		t.Errorf("error %q should point at tile", err)
+	}
+
+	limits.MaxComputeWorkgroupStorageSize = 32768
+	if _, err := CompileWithOptions(source, opts); err != nil {
+		t.Errorf("raised limit: %v", err)
+	}
+}
+
+func TestCompileInterStageComponentLimit(t *testing.T) {
+	const source = `
+struct VsOut {
+    @builtin(position) pos: vec4<f32>,
+    @location(0) color: vec4<f32>,
+    @location(2) uv: vec2<f32>,
+    @location(1) normal: vec3<f32>,
+}
+
+@vertex
+fn vs() -> VsOut {
+    return VsOut(vec4<f32>(0.0), vec4<f32>(1.0), vec2<f32>(0.5), vec3<f32>(0.0, 0.0, 1.0));
+}
+
+@fragment
+fn fs(in: VsOut, @builtin(front_facing) facing: bool) -> @location(0) vec4<f32> {
+    return select(in.color, vec4<f32>(in.uv, 0.0, 1.0), facing);
+}
+`
+	opts := DefaultOptions()
+	limits := DefaultLimits()
+	opts.Limits = &limits
+	if _, err := CompileWithOptions(source, opts); err != nil {
+		t.Fatalf("default limits: %v", err)
+	}
+
+	// The vertex stage outputs 4+3+2 = 9 components; with a limit of 8
+	// the last in location order, uv, is over the budget.
+	limits.MaxInterStageShaderComponents = 8
+	_, err := CompileWithOptions(source, opts)
+	var ioErr *InterStageComponentsError
+	if !errors.As(err, &ioErr) {
+		t.Fatalf("expected *InterStageComponentsError, got %v", err)
+	}
+	if ioErr.EntryPoint != "vs" || ioErr.Stage != ir.StageVertex || ioErr.Components != 9 || ioErr.Limit != 8 {
+		t.Errorf("error = %+v", ioErr)
+	}
+	want := []string{"uv@5:2"}
+	var got []string
+	for _, v := range ioErr.Variables {
+		got = append(got, fmt.Sprintf("%s@%d:%d", v.Name, v.Span.Start.Line, v.Components))
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("variables = %v, want %v", got, want)
+	}
+	if !strings.Contains(err.Error(), "5:18: entry point 'vs' outputs 9 inter-stage components, more than the limit of 8; over the limit: 'uv' @location(2)") {
+		t.Errorf("error %q should point at uv", err)
+	}
+
+	// The fragment stage also counts front_facing: 10 components.
+	limits.MaxInterStageShaderComponents = 9
+	_, err = CompileWithOptions(source, opts)
+	if !errors.As(err, &ioErr) || ioErr.EntryPoint != "fs" || ioErr.Components != 10 || len(ioErr.Variables) != 1 {
+		t.Errorf("fragment stage: got %v", err)
+	}
+}
+
+func TestCompileRejectsRecursion(t *testing.T) {
+	const source = `
+fn a() { b(); }
+fn b() { a(); }
+
+@compute @workgroup_size(1)
+fn main() { a(); }
+`
+	_, err := Compile(source)
+	if err == nil || !strings.Contains(err.Error(), "recursive call cycle: b -> a -> b") {
+		t.Errorf("error = %v, want a recursion cycle report", err)
+	}
+}
+
+func TestCompileRejectsAliasingPointerArguments(t *testing.T) {
+	const source = `
+fn swap(x: ptr<function, f32>, y: ptr<function, f32>) {
+    let t = *x;
+    *x = *y;
+    *y = t;
+}
+
+fn forward(x: ptr<function, f32>, y: ptr<function, f32>) { swap(x, y); }
+
+@compute @workgroup_size(1)
+fn main() {
+    var a: array<f32, 4>;
+    swap(&a[0], &a[1]);
+    forward(&a[2], &a[2]);
+}
+`
+	_, err := Compile(source)
+	want := `call to "forward": pointer arguments 0 and 1 may overlap in local variable "a"`
+	if err == nil || !strings.Contains(err.Error(), want) {
+		t.Fatalf("error = %v, want %q", err, want)
+	}
+	if spans := "(at 14:14, 14:21)"; !strings.Contains(err.Error(), spans) {
+		t.Errorf("error = %v, want both argument locations %s", err, spans)
+	}
+}
+
+func TestCompileRejectsGlobalAliasingPointerArgument(t *testing.T) {
+	const source = `
+var<private> g: f32;
+
+fn f(p: ptr<private, f32>) -> f32 {
+    *p = 1.0;
+    return g;
+}
+
+fn g_plus(p: ptr<private, f32>) -> f32 { return *p + g; }
+
+@compute @workgroup_size(1)
+fn main() {
+    _ = g_plus(&g);
+    _ = f(&g);
+}
+`
+	_, err := Compile(source)
+	want := `call to "f": pointer argument 0 points into global variable "g", which the callee also accesses directly`
+	if err == nil || !strings.Contains(err.Error(), want) || !strings.Contains(err.Error(), "(at 14:12)") {
+		t.Fatalf("error = %v, want %q at 14:12", err, want)
+	}
+}
+
+func TestCompileRejectsStorageAccessViolations(t *testing.T) {
+	tests := []struct {
+		name, source, want string
+	}{
+		{
+			name: "store to read-only buffer",
+			source: `
+@group(0) @binding(0) var<storage> buf: array<u32>;
+@compute @workgroup_size(1)
+fn main() { buf[0] = 1u; }
+`,
+			want: `store to read-only storage buffer "buf" (@group(0) @binding(0))`,
+		},
+		{
+			name: "write through pointer parameter",
+			source: `
+@group(0) @binding(0) var<storage, read> buf: array<u32>;
+fn put(p: ptr<storage, u32, read_write>) { *p = 2u; }
+@compute @workgroup_size(1)
+fn main() { put(&buf[1]); }
+`,
+			want: `call to "put", which writes through argument 0, passing read-only storage buffer "buf"`,
+		},
+		{
+			name: "load from write-only texture",
+			source: `
+@group(0) @binding(1) var tex: texture_storage_2d<rgba8unorm, write>;
+@compute @workgroup_size(1)
+fn main() { _ = textureLoad(tex, vec2<i32>(0, 0)); }
+`,
+			want: `textureLoad from write-only storage texture "tex" (@group(0) @binding(1))`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Compile(tt.source)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("error = %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestCompileEntryPoints(t *testing.T) {
+	const source = `
+@group(0) @binding(0) var<uniform> mvp: mat4x4<f32>;
+@group(0) @binding(1) var tex: texture_2d<f32>;
+@group(0) @binding(2) var samp: sampler;
+
+fn shade(uv: vec2<f32>) -> vec4<f32> { return textureSample(tex, samp, uv); }
+
+@vertex
+fn vs_main(@location(0) p: vec3<f32>) -> @builtin(position) vec4<f32> { return mvp * vec4<f32>(p, 1.0); }
+
+@fragment
+fn fs_main(@builtin(position) p: vec4<f32>) -> @location(0) vec4<f32> { return shade(p.xy); }
+`
+	opts := DefaultOptions()
+	all, err := CompileWithOptions(source, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	opts.EntryPoints = []string{"vs_main"}
+	vs, err := CompileWithOptions(source, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(vs), "vs_main") || strings.Contains(string(vs), "fs_main") {
+		t.Error("vertex-only module does not have exactly the vs_main entry point")
+	}
+	if len(vs) >= len(all) {
+		t.Errorf("vertex-only module is %d bytes, want less than %d", len(vs), len(all))
+	}
+
+	opts.EntryPoints = []string{"vs_main", "main"}
+	if _, err := CompileWithOptions(source, opts); err == nil || !strings.Contains(err.Error(), `no entry point named "main" (have vs_main, fs_main)`) {
+		t.Errorf("error = %v, want the missing entry point reported", err)
+	}
+}
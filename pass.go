@@ -0,0 +1,391 @@
+package naga
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/gogpu/naga/ir"
+	"github.com/gogpu/naga/spirv"
+	"github.com/gogpu/naga/wgsl"
+)
+
+// Names of the standard passes run by a PassManager, in order.
+const (
+	PassParse       = "parse"
+	PassLower       = "lower"
+	PassValidate    = "validate"
+	PassLink        = "link"
+	PassEntryPoints = "entry-points"
+	PassShareArrays = "share-arrays"
+	PassPeephole    = "peephole"
+	PassSPIRV       = "spirv"
+)
+
+// Pass is one step of the compile pipeline. Run reads and updates the
+// shared PassState; returning an error stops the pipeline.
+type Pass struct {
+	Name string
+	Run  func(*PassState) error
+}
+
+// PassState is the data passed from pass to pass. Each standard pass fills
+// in its output: parse sets AST, lower sets Module and Warnings, spirv
+// sets SPIRV. Custom passes inserted between them may inspect or replace
+// any field, e.g. transform Module before code generation.
+type PassState struct {
+	// Context is the context the pipeline runs under; passes doing long
+	// work should give up once it is canceled. It is never nil.
+	Context context.Context
+
+	Source  string
+	Options CompileOptions
+
+	AST      *wgsl.Module
+	Module   *ir.Module
+	Warnings []wgsl.Warning
+	SPIRV    []byte
+
+	// SourceMap maps SPIR-V instructions to WGSL spans when
+	// Options.SourceMap is set.
+	SourceMap []ir.OutputSpan
+}
+
+// PassTiming is the wall-clock time one pass took.
+type PassTiming struct {
+	Name     string
+	Duration time.Duration
+}
+
+// Stats describes one run of a PassManager.
+type Stats struct {
+	// Passes lists every pass that ran, in order, including the one that
+	// failed, if any.
+	Passes []PassTiming
+
+	// Total is the time spent in all passes.
+	Total time.Duration
+
+	// Functions, EntryPoints, Types, Constants and GlobalVariables count
+	// the lowered module's arenas.
+	Functions       int
+	EntryPoints     int
+	Types           int
+	Constants       int
+	GlobalVariables int
+
+	// Expressions counts expressions in all functions and entry points.
+	// GlobalExpressions counts the module's constant expression arena.
+	Expressions       int
+	GlobalExpressions int
+
+	// SPIRVInstructions and SPIRVWords size the generated binary,
+	// excluding the five-word header.
+	SPIRVInstructions int
+	SPIRVWords        int
+}
+
+// PassManager runs the compile pipeline as a list of named passes, timing
+// each one. NewPassManager installs the standard passes; custom passes
+// can be inserted around them.
+type PassManager struct {
+	passes []Pass
+}
+
+// NewPassManager returns a pass manager with the standard pipeline:
+// parse, lower, validate (a no-op unless CompileOptions.Validate or
+// CompileOptions.Limits is set), link
+// (a no-op unless CompileOptions.LinkStages is set), entry-points (a
+// no-op unless CompileOptions.EntryPoints is set), share-arrays (a
+// no-op unless CompileOptions.ShareConstantArrays is set), peephole (a
+// no-op unless CompileOptions.Peephole is set, and validating its output
+// again with CompileOptions.Validate), and spirv. Running it is
+// equivalent to CompileWithOptions.
+func NewPassManager() *PassManager {
+	return &PassManager{passes: []Pass{
+		{Name: PassParse, Run: parsePass},
+		{Name: PassLower, Run: lowerPass},
+		{Name: PassValidate, Run: validatePass},
+		{Name: PassLink, Run: linkPass},
+		{Name: PassEntryPoints, Run: entryPointsPass},
+		{Name: PassShareArrays, Run: shareArraysPass},
+		{Name: PassPeephole, Run: peepholePass},
+		{Name: PassSPIRV, Run: spirvPass},
+	}}
+}
+
+// Passes returns the names of the registered passes, in run order.
+func (pm *PassManager) Passes() []string {
+	names := make([]string, len(pm.passes))
+	for i, p := range pm.passes {
+		names[i] = p.Name
+	}
+	return names
+}
+
+// Add appends a pass to the end of the pipeline.
+func (pm *PassManager) Add(p Pass) {
+	pm.passes = append(pm.passes, p)
+}
+
+// InsertBefore inserts p before the pass named before.
+func (pm *PassManager) InsertBefore(before string, p Pass) error {
+	i := pm.index(before)
+	if i < 0 {
+		return fmt.Errorf("no pass named %q", before)
+	}
+	pm.passes = append(pm.passes[:i], append([]Pass{p}, pm.passes[i:]...)...)
+	return nil
+}
+
+// InsertAfter inserts p after the pass named after.
+func (pm *PassManager) InsertAfter(after string, p Pass) error {
+	i := pm.index(after)
+	if i < 0 {
+		return fmt.Errorf("no pass named %q", after)
+	}
+	pm.passes = append(pm.passes[:i+1], append([]Pass{p}, pm.passes[i+1:]...)...)
+	return nil
+}
+
+// Remove deletes the pass with the given name.
+func (pm *PassManager) Remove(name string) error {
+	i := pm.index(name)
+	if i < 0 {
+		return fmt.Errorf("no pass named %q", name)
+	}
+	pm.passes = append(pm.passes[:i], pm.passes[i+1:]...)
+	return nil
+}
+
+func (pm *PassManager) index(name string) int {
+	for i, p := range pm.passes {
+		if p.Name == name {
+			return i
+		}
+	}
+	return -1
+}
+
+// Run compiles source with opts through every pass in order. It returns
+// the final state and statistics; on error, both describe the pipeline
+// up to and including the failing pass.
+func (pm *PassManager) Run(source string, opts CompileOptions) (*PassState, *Stats, error) {
+	return pm.RunContext(context.Background(), source, opts)
+}
+
+// RunContext is Run with cancellation. ctx is checked before each pass
+// and, by the standard lower and spirv passes, between declarations and
+// functions; a canceled run returns an error wrapping ctx.Err().
+func (pm *PassManager) RunContext(ctx context.Context, source string, opts CompileOptions) (*PassState, *Stats, error) {
+	state := &PassState{Context: ctx, Source: source, Options: opts}
+	stats := &Stats{}
+	logger := opts.Logger
+	for _, p := range pm.passes {
+		if err := ctx.Err(); err != nil {
+			stats.count(state)
+			return state, stats, fmt.Errorf("compilation canceled before %s pass: %w", p.Name, err)
+		}
+		start := time.Now()
+		err := p.Run(state)
+		elapsed := time.Since(start)
+		stats.Passes = append(stats.Passes, PassTiming{Name: p.Name, Duration: elapsed})
+		stats.Total += elapsed
+		if err != nil {
+			if logger != nil {
+				logger.Debug("naga: pass failed", "pass", p.Name, "duration", elapsed, "error", err)
+			}
+			stats.count(state)
+			return state, stats, err
+		}
+		if logger != nil {
+			logger.Debug("naga: pass done", "pass", p.Name, "duration", elapsed)
+		}
+	}
+	stats.count(state)
+	return state, stats, nil
+}
+
+// count fills in the module and binary counters from state.
+func (s *Stats) count(state *PassState) {
+	if m := state.Module; m != nil {
+		s.Functions = len(m.Functions)
+		s.EntryPoints = len(m.EntryPoints)
+		s.Types = len(m.Types)
+		s.Constants = len(m.Constants)
+		s.GlobalVariables = len(m.GlobalVariables)
+		s.GlobalExpressions = len(m.GlobalExpressions)
+		s.Expressions = 0
+		for i := range m.Functions {
+			s.Expressions += len(m.Functions[i].Expressions)
+		}
+		for i := range m.EntryPoints {
+			s.Expressions += len(m.EntryPoints[i].Function.Expressions)
+		}
+	}
+	s.SPIRVInstructions, s.SPIRVWords = countSPIRV(state.SPIRV)
+}
+
+// countSPIRV counts the instructions and words after the SPIR-V header.
+func countSPIRV(code []byte) (instructions, words int) {
+	const headerBytes = 5 * 4
+	if len(code) < headerBytes {
+		return 0, 0
+	}
+	for off := headerBytes; off+4 <= len(code); {
+		wordCount := int(binary.LittleEndian.Uint32(code[off:]) >> 16)
+		if wordCount == 0 {
+			break
+		}
+		instructions++
+		words += wordCount
+		off += wordCount * 4
+	}
+	return instructions, words
+}
+
+func parsePass(s *PassState) error {
+	ast, err := ParseWithFeatures(s.Source, s.Options.Features)
+	if err != nil {
+		return fmt.Errorf("parse error: %w", err)
+	}
+	if err := checkLanguageFeatures(ast, s.Options.LanguageFeatures); err != nil {
+		return err
+	}
+	s.AST = ast
+	return nil
+}
+
+func lowerPass(s *PassState) error {
+	lowered, err := wgsl.LowerWithOptions(s.Context, s.AST, s.Source, wgsl.LowerOptions{
+		Strict:    s.Options.Strict,
+		Constants: s.Options.Constants,
+	})
+	if err != nil {
+		return fmt.Errorf("lowering error: %w", err)
+	}
+	if s.Options.WarningsAsErrors && len(lowered.Warnings) > 0 {
+		return fmt.Errorf("lowering error: %w", &WarningsError{Warnings: lowered.Warnings})
+	}
+	s.Module = lowered.Module
+	s.Warnings = lowered.Warnings
+	return nil
+}
+
+func validatePass(s *PassState) error {
+	if s.Options.Validate {
+		validationErrors, err := Validate(s.Module)
+		if err != nil {
+			return fmt.Errorf("validation error: %w", err)
+		}
+		if len(validationErrors) > 0 {
+			return fmt.Errorf("validation failed: %w%s", &validationErrors[0], sourceLocations(s.Source, validationErrors[0].Spans))
+		}
+	}
+	if s.Options.Limits != nil {
+		if err := checkWorkgroupStorage(s.AST, s.Module, *s.Options.Limits); err != nil {
+			return fmt.Errorf("validation failed: %w", err)
+		}
+		if err := checkInterStageComponents(s.AST, s.Module, *s.Options.Limits); err != nil {
+			return fmt.Errorf("validation failed: %w", err)
+		}
+	}
+	return nil
+}
+
+// sourceLocations formats spans of source as " (at line:column, ...)",
+// or "" when there are none.
+func sourceLocations(source string, spans []ir.Span) string {
+	var locs []string
+	for _, span := range spans {
+		if pos := wgsl.SourceSpan(source, span).Start; pos.Line > 0 {
+			locs = append(locs, fmt.Sprintf("%d:%d", pos.Line, pos.Column))
+		}
+	}
+	if len(locs) == 0 {
+		return ""
+	}
+	return " (at " + strings.Join(locs, ", ") + ")"
+}
+
+func linkPass(s *PassState) error {
+	link := s.Options.LinkStages
+	if link == nil {
+		return nil
+	}
+	mismatches, err := CheckStageLinkage(s.AST, s.Module, link.Vertex, link.Fragment)
+	if err != nil {
+		return fmt.Errorf("link error: %w", err)
+	}
+	if len(mismatches) > 0 {
+		return fmt.Errorf("link error: %w", &LinkError{Mismatches: mismatches})
+	}
+	return nil
+}
+
+func entryPointsPass(s *PassState) error {
+	if len(s.Options.EntryPoints) == 0 {
+		return nil
+	}
+	module, err := ir.SelectEntryPoints(s.Module, s.Options.EntryPoints)
+	if err != nil {
+		return fmt.Errorf("entry point selection failed: %w", err)
+	}
+	s.Module = module
+	return nil
+}
+
+// shareArraysPass and peepholePass rewrite the module after the validate pass
+// has run, so with CompileOptions.Validate the rewritten module is validated
+// again.
+func shareArraysPass(s *PassState) error {
+	if s.Options.ShareConstantArrays == 0 {
+		return nil
+	}
+	s.Module = ir.ShareConstantArrays(s.Module, s.Options.ShareConstantArrays)
+	return revalidate(s, "constant array sharing")
+}
+
+func peepholePass(s *PassState) error {
+	if s.Options.Peephole == nil {
+		return nil
+	}
+	s.Module = ir.Peephole(s.Module, *s.Options.Peephole)
+	return revalidate(s, "peephole")
+}
+
+// revalidate validates a module rewritten after the validate pass when
+// CompileOptions.Validate is set. after names the rewrite in the error.
+func revalidate(s *PassState, after string) error {
+	if !s.Options.Validate {
+		return nil
+	}
+	validationErrors, err := Validate(s.Module)
+	if err != nil {
+		return fmt.Errorf("validation error after %s: %w", after, err)
+	}
+	if len(validationErrors) > 0 {
+		return fmt.Errorf("validation failed after %s: %w", after, &validationErrors[0])
+	}
+	return nil
+}
+
+func spirvPass(s *PassState) error {
+	backend := spirv.NewBackend(spirv.Options{
+		Version:              s.Options.SPIRVVersion,
+		Debug:                s.Options.Debug,
+		DebugExpressionNames: s.Options.DebugExpressionNames,
+		DebugSource:          s.Source,
+		SourceMap:            s.Options.SourceMap,
+		Logger:               s.Options.Logger,
+	})
+	spirvBytes, err := backend.CompileContext(s.Context, s.Module)
+	if err != nil {
+		return fmt.Errorf("SPIR-V generation error: %w", err)
+	}
+	s.SPIRV = spirvBytes
+	s.SourceMap = backend.SourceMap()
+	return nil
+}
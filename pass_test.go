@@ -0,0 +1,216 @@
+package naga
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log/slog"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/gogpu/naga/ir"
+	"github.com/gogpu/naga/spirv"
+)
+
+const passSource = `
+fn helper(x: f32) -> f32 {
+    return x * 2.0;
+}
+
+@fragment
+fn main() -> @location(0) vec4<f32> {
+    return vec4<f32>(helper(0.5));
+}
+`
+
+func TestPassManagerMatchesCompile(t *testing.T) {
+	want, err := Compile(passSource)
+	if err != nil {
+		t.Fatal(err)
+	}
+	state, stats, err := NewPassManager().Run(passSource, DefaultOptions())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(state.SPIRV, want) {
+		t.Error("pass manager output differs from Compile")
+	}
+
+	var names []string
+	for _, p := range stats.Passes {
+		names = append(names, p.Name)
+	}
+	wantNames := []string{PassParse, PassLower, PassValidate, PassLink, PassEntryPoints, PassShareArrays, PassPeephole, PassSPIRV}
+	if !reflect.DeepEqual(names, wantNames) {
+		t.Errorf("passes = %v, want %v", names, wantNames)
+	}
+	if stats.Total <= 0 {
+		t.Error("total time not recorded")
+	}
+	if stats.Functions != 1 || stats.EntryPoints != 1 || stats.Expressions == 0 {
+		t.Errorf("module counters = %+v", stats)
+	}
+	if stats.SPIRVWords != len(want)/4-5 || stats.SPIRVInstructions == 0 {
+		t.Errorf("SPIR-V counters = %d instructions, %d words for %d bytes",
+			stats.SPIRVInstructions, stats.SPIRVWords, len(want))
+	}
+}
+
+func TestPassManagerCustomPasses(t *testing.T) {
+	pm := NewPassManager()
+	var sawModule bool
+	if err := pm.InsertAfter(PassLower, Pass{Name: "inspect", Run: func(s *PassState) error {
+		sawModule = s.Module != nil
+		return nil
+	}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := pm.InsertBefore(PassParse, Pass{Name: "first", Run: func(*PassState) error { return nil }}); err != nil {
+		t.Fatal(err)
+	}
+	if err := pm.Remove(PassLink); err != nil {
+		t.Fatal(err)
+	}
+	pm.Add(Pass{Name: "last", Run: func(s *PassState) error {
+		if len(s.SPIRV) == 0 {
+			return errors.New("no SPIR-V")
+		}
+		return nil
+	}})
+
+	want := []string{"first", PassParse, PassLower, "inspect", PassValidate, PassEntryPoints, PassShareArrays, PassPeephole, PassSPIRV, "last"}
+	if got := pm.Passes(); !reflect.DeepEqual(got, want) {
+		t.Fatalf("passes = %v, want %v", got, want)
+	}
+	if _, _, err := pm.Run(passSource, DefaultOptions()); err != nil {
+		t.Fatal(err)
+	}
+	if !sawModule {
+		t.Error("custom pass after lower did not see the module")
+	}
+	if err := pm.InsertAfter("missing", Pass{Name: "x"}); err == nil {
+		t.Error("expected error inserting after an unknown pass")
+	}
+}
+
+func TestPassManagerTransformAndFailure(t *testing.T) {
+	pm := NewPassManager()
+	errStop := errors.New("stop")
+	if err := pm.InsertBefore(PassSPIRV, Pass{Name: "reject", Run: func(s *PassState) error {
+		if len(s.Module.Functions) > 0 {
+			return errStop
+		}
+		return nil
+	}}); err != nil {
+		t.Fatal(err)
+	}
+	_, stats, err := pm.Run(passSource, DefaultOptions())
+	if !errors.Is(err, errStop) {
+		t.Fatalf("expected custom pass error, got %v", err)
+	}
+	if last := stats.Passes[len(stats.Passes)-1].Name; last != "reject" {
+		t.Errorf("last timed pass = %q, want reject", last)
+	}
+	if stats.Functions != 1 || stats.SPIRVWords != 0 {
+		t.Errorf("counters after failure = %+v", stats)
+	}
+
+	// A transform pass can rewrite the module before code generation.
+	pm = NewPassManager()
+	if err := pm.InsertAfter(PassValidate, Pass{Name: "rename", Run: func(s *PassState) error {
+		s.Module.EntryPoints[0].Name = "renamed"
+		return nil
+	}}); err != nil {
+		t.Fatal(err)
+	}
+	opts := DefaultOptions()
+	opts.Debug = true
+	state, _, err := pm.Run(passSource, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Contains(state.SPIRV, []byte("renamed")) {
+		t.Error("SPIR-V does not reflect the transformed module")
+	}
+}
+
+func TestCompileWithStatsReportsFailingPass(t *testing.T) {
+	_, stats, err := CompileWithStats("fn main( {", DefaultOptions())
+	if err == nil || !strings.Contains(err.Error(), "parse error") {
+		t.Fatalf("expected parse error, got %v", err)
+	}
+	if len(stats.Passes) != 1 || stats.Passes[0].Name != PassParse {
+		t.Errorf("passes = %+v, want only parse", stats.Passes)
+	}
+}
+
+func TestCountSPIRV(t *testing.T) {
+	module := &ir.Module{}
+	code, err := GenerateSPIRV(module, spirv.Options{Version: spirv.Version1_3})
+	if err != nil {
+		t.Fatal(err)
+	}
+	instructions, words := countSPIRV(code)
+	if words != len(code)/4-5 || instructions == 0 {
+		t.Errorf("countSPIRV = %d, %d for %d bytes", instructions, words, len(code))
+	}
+	if i, w := countSPIRV(code[:8]); i != 0 || w != 0 {
+		t.Errorf("short input = %d, %d", i, w)
+	}
+}
+
+func TestCompileLogger(t *testing.T) {
+	var buf bytes.Buffer
+	opts := DefaultOptions()
+	opts.Logger = slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	if _, err := CompileWithOptions(passSource, opts); err != nil {
+		t.Fatalf("CompileWithOptions: %v", err)
+	}
+	log := buf.String()
+	for _, name := range NewPassManager().Passes() {
+		if !strings.Contains(log, `msg="naga: pass done" pass=`+name+" ") {
+			t.Errorf("log has no trace for pass %s:\n%s", name, log)
+		}
+	}
+	if !strings.Contains(log, `msg="spirv: writing module" backend=spirv`) {
+		t.Errorf("log has no SPIR-V backend trace:\n%s", log)
+	}
+
+	buf.Reset()
+	if _, err := CompileWithOptions("fn main( {", opts); err == nil {
+		t.Fatal("expected parse error")
+	}
+	if !strings.Contains(buf.String(), `msg="naga: pass failed" pass=parse`) {
+		t.Errorf("log has no failure trace:\n%s", buf.String())
+	}
+}
+
+func TestCompileContextCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := CompileContext(ctx, passSource, DefaultOptions()); !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context.Canceled, got %v", err)
+	}
+
+	// Canceling from inside a pass stops the pipeline at the next boundary.
+	ctx, cancel = context.WithCancel(context.Background())
+	defer cancel()
+	pm := NewPassManager()
+	if err := pm.InsertAfter(PassLower, Pass{Name: "cancel", Run: func(*PassState) error {
+		cancel()
+		return nil
+	}}); err != nil {
+		t.Fatal(err)
+	}
+	state, stats, err := pm.RunContext(ctx, passSource, DefaultOptions())
+	if !errors.Is(err, context.Canceled) || !strings.Contains(err.Error(), "before validate pass") {
+		t.Fatalf("expected cancellation before validate, got %v", err)
+	}
+	if state.Module == nil || state.SPIRV != nil {
+		t.Errorf("state after cancellation: module=%v, %d SPIR-V bytes", state.Module != nil, len(state.SPIRV))
+	}
+	if last := stats.Passes[len(stats.Passes)-1].Name; last != "cancel" {
+		t.Errorf("last timed pass = %q, want cancel", last)
+	}
+}
@@ -0,0 +1,305 @@
+package naga
+
+import (
+	"fmt"
+	"math"
+	"testing"
+
+	"github.com/gogpu/naga/ir"
+	"github.com/gogpu/naga/spirv"
+)
+
+const peepholeShader = `
+fn min_lt(a: i32, b: i32) -> i32 { return select(b, a, a < b); }
+fn min_ge(a: i32, b: i32) -> i32 { return select(b, a, b >= a); }
+fn max_lt(a: i32, b: i32) -> i32 { return select(a, b, a < b); }
+fn max_le(a: i32, b: i32) -> i32 { return select(a, b, a <= b); }
+fn max_gt(a: i32, b: i32) -> i32 { return select(b, a, a > b); }
+fn min_var(a: i32, b: i32) -> i32 { var x = a; return select(b, x, x < b); }
+fn clamp_chain(x: i32) -> i32 { return min(max(x, -3), 5); }
+fn clamp_swapped(x: i32) -> i32 { return max(-3, min(5, x)); }
+fn clamp_select(x: i32) -> i32 { let lo = select(-3, x, x > -3); return select(5, lo, lo < 5); }
+
+fn no_eq(a: i32, b: i32) -> i32 { return select(b, a, a == b); }
+fn no_other(a: i32, b: i32, c: i32) -> i32 { return select(b, a, a < c); }
+fn no_empty_clamp(x: i32) -> i32 { return min(max(x, 5), -3); }
+fn no_store(a: i32, b: i32) -> i32 { var x = a; let y = x; x = b; return select(b, x, y < b); }
+
+fn fmin_lt(a: f32, b: f32) -> f32 { return select(b, a, a < b); }
+fn fmax_gt(a: f32, b: f32) -> f32 { return select(b, a, a > b); }
+fn fclamp(x: f32) -> f32 { return min(max(x, 0.0), 1.0); }
+fn fmix(a: f32, b: f32, c: bool) -> f32 { return mix(a, b, f32(c)); }
+fn fmix_select(a: f32, b: f32, c: bool) -> f32 { return mix(a, b, select(0.0, 1.0, c)); }
+
+@compute @workgroup_size(1)
+fn main() {}
+`
+
+// peepholeFunction returns the function of module named name.
+func peepholeFunction(t *testing.T, module *ir.Module, name string) *ir.Function {
+	t.Helper()
+	for i := range module.Functions {
+		if module.Functions[i].Name == name {
+			return &module.Functions[i]
+		}
+	}
+	t.Fatalf("no function %q", name)
+	return nil
+}
+
+// returned returns the expression fn returns.
+func returned(t *testing.T, fn *ir.Function) ir.ExpressionKind {
+	t.Helper()
+	for _, stmt := range fn.Body {
+		if ret, ok := stmt.Kind.(ir.StmtReturn); ok && ret.Value != nil {
+			return fn.Expressions[*ret.Value].Kind
+		}
+	}
+	t.Fatalf("%s returns nothing", fn.Name)
+	return nil
+}
+
+// shape names the kind of an expression, and the function of a math
+// expression.
+func shape(kind ir.ExpressionKind) string {
+	if m, ok := kind.(ir.ExprMath); ok {
+		return fmt.Sprintf("math %d", m.Fun)
+	}
+	return fmt.Sprintf("%T", kind)
+}
+
+// evalReturn evaluates what fn returns for args, following WGSL: ordered
+// comparisons with NaN are false, and min, max and clamp return the
+// non-NaN operand.
+func evalReturn(t *testing.T, fn *ir.Function, args ...float64) float64 {
+	t.Helper()
+	var locals []float64
+	for range fn.LocalVars {
+		locals = append(locals, 0)
+	}
+	var eval func(h ir.ExpressionHandle) float64
+	eval = func(h ir.ExpressionHandle) float64 {
+		switch e := fn.Expressions[h].Kind.(type) {
+		case ir.ExprFunctionArgument:
+			return args[e.Index]
+		case ir.Literal:
+			switch v := e.Value.(type) {
+			case ir.LiteralI32:
+				return float64(v)
+			case ir.LiteralF32:
+				return float64(v)
+			case ir.LiteralBool:
+				if v {
+					return 1
+				}
+				return 0
+			}
+		case ir.ExprLoad:
+			return locals[fn.Expressions[e.Pointer].Kind.(ir.ExprLocalVariable).Variable]
+		case ir.ExprAs:
+			return eval(e.Expr)
+		case ir.ExprBinary:
+			l, r := eval(e.Left), eval(e.Right)
+			var b bool
+			switch e.Op {
+			case ir.BinaryLess:
+				b = l < r
+			case ir.BinaryLessEqual:
+				b = l <= r
+			case ir.BinaryGreater:
+				b = l > r
+			case ir.BinaryGreaterEqual:
+				b = l >= r
+			case ir.BinaryEqual:
+				b = l == r
+			default:
+				t.Fatalf("eval: binary op %d", e.Op)
+			}
+			if b {
+				return 1
+			}
+			return 0
+		case ir.ExprSelect:
+			if eval(e.Condition) != 0 {
+				return eval(e.Accept)
+			}
+			return eval(e.Reject)
+		case ir.ExprMath:
+			x := eval(e.Arg)
+			switch e.Fun {
+			case ir.MathMin:
+				return minNum(x, eval(*e.Arg1))
+			case ir.MathMax:
+				return -minNum(-x, -eval(*e.Arg1))
+			case ir.MathClamp:
+				return minNum(-minNum(-x, -eval(*e.Arg1)), eval(*e.Arg2))
+			case ir.MathMix:
+				s := eval(*e.Arg2)
+				return x*(1-s) + eval(*e.Arg1)*s
+			}
+		}
+		t.Fatalf("eval: %T", fn.Expressions[h].Kind)
+		return 0
+	}
+	var run func(block ir.Block) (float64, bool)
+	run = func(block ir.Block) (float64, bool) {
+		for _, stmt := range block {
+			switch s := stmt.Kind.(type) {
+			case ir.StmtStore:
+				locals[fn.Expressions[s.Pointer].Kind.(ir.ExprLocalVariable).Variable] = eval(s.Value)
+			case ir.StmtReturn:
+				return eval(*s.Value), true
+			}
+		}
+		return 0, false
+	}
+	for i, lv := range fn.LocalVars {
+		if lv.Init != nil {
+			locals[i] = eval(*lv.Init)
+		}
+	}
+	v, _ := run(fn.Body)
+	return v
+}
+
+// minNum is IEEE 754 minNum: the smaller operand, or the other when one
+// is NaN.
+func minNum(a, b float64) float64 {
+	switch {
+	case math.IsNaN(a):
+		return b
+	case math.IsNaN(b):
+		return a
+	}
+	return math.Min(a, b)
+}
+
+func lowerPeephole(t *testing.T) *ir.Module {
+	t.Helper()
+	ast, err := Parse(peepholeShader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	module, err := Lower(ast)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return module
+}
+
+func TestPeepholeIntegers(t *testing.T) {
+	module := lowerPeephole(t)
+	opt := ir.Peephole(module, ir.PeepholeOptions{})
+
+	values := []float64{math.MinInt32, -7, -3, -1, 0, 1, 4, 5, 6, math.MaxInt32}
+	want := map[string]ir.MathFunction{
+		"min_lt": ir.MathMin, "min_ge": ir.MathMin, "max_lt": ir.MathMax, "max_le": ir.MathMax,
+		"max_gt": ir.MathMax, "min_var": ir.MathMin,
+		"clamp_chain": ir.MathClamp, "clamp_swapped": ir.MathClamp, "clamp_select": ir.MathClamp,
+	}
+	for name, fun := range want {
+		before, after := peepholeFunction(t, module, name), peepholeFunction(t, opt, name)
+		if m, ok := returned(t, after).(ir.ExprMath); !ok || m.Fun != fun {
+			t.Errorf("%s returns %#v, want math function %d", name, returned(t, after), fun)
+		}
+		if len(after.Expressions) >= len(before.Expressions) {
+			t.Errorf("%s: %d expressions, want fewer than %d", name, len(after.Expressions), len(before.Expressions))
+		}
+		for _, a := range values {
+			for _, b := range values {
+				if got, want := evalReturn(t, after, a, b), evalReturn(t, before, a, b); got != want {
+					t.Errorf("%s(%v, %v) = %v after peephole, %v before", name, a, b, got, want)
+				}
+			}
+		}
+	}
+
+	for _, name := range []string{"no_eq", "no_other", "no_empty_clamp", "no_store"} {
+		before, after := peepholeFunction(t, module, name), peepholeFunction(t, opt, name)
+		if shape(returned(t, before)) != shape(returned(t, after)) {
+			t.Errorf("%s rewritten to %#v", name, returned(t, after))
+		}
+	}
+}
+
+func TestPeepholeFloatNaN(t *testing.T) {
+	module := lowerPeephole(t)
+	floats := []string{"fmin_lt", "fmax_gt", "fclamp", "fmix", "fmix_select"}
+	nan := math.NaN()
+
+	// Without FiniteMath floats keep the select, whose result for NaN is
+	// defined, unlike the backends' min and max.
+	opt := ir.Peephole(module, ir.PeepholeOptions{})
+	for _, name := range floats {
+		before, after := peepholeFunction(t, module, name), peepholeFunction(t, opt, name)
+		if shape(returned(t, before)) != shape(returned(t, after)) {
+			t.Errorf("%s rewritten without FiniteMath to %#v", name, returned(t, after))
+		}
+	}
+	fminLt := peepholeFunction(t, opt, "fmin_lt")
+	if got := evalReturn(t, fminLt, 1, nan); !math.IsNaN(got) {
+		t.Errorf("fmin_lt(1, NaN) = %v, want NaN as the select returns", got)
+	}
+	if got := evalReturn(t, fminLt, nan, 1); got != 1 {
+		t.Errorf("fmin_lt(NaN, 1) = %v, want 1", got)
+	}
+
+	fast := ir.Peephole(module, ir.PeepholeOptions{FiniteMath: true})
+	want := map[string]string{
+		"fmin_lt": "min", "fmax_gt": "max", "fclamp": "clamp", "fmix": "select", "fmix_select": "select",
+	}
+	values := []float64{-2.5, -0, 0, 0.25, 1, 3}
+	for _, name := range floats {
+		before, after := peepholeFunction(t, module, name), peepholeFunction(t, fast, name)
+		got := "unchanged"
+		switch e := returned(t, after).(type) {
+		case ir.ExprSelect:
+			got = "select"
+		case ir.ExprMath:
+			got = map[ir.MathFunction]string{ir.MathMin: "min", ir.MathMax: "max", ir.MathClamp: "clamp"}[e.Fun]
+		}
+		if got != want[name] {
+			t.Errorf("%s with FiniteMath: %s, want %s", name, got, want[name])
+		}
+		for _, a := range values {
+			for _, b := range values {
+				for _, c := range []float64{0, 1} {
+					if got, want := evalReturn(t, after, a, b, c), evalReturn(t, before, a, b, c); got != want {
+						t.Errorf("%s(%v, %v, %v) = %v after peephole, %v before", name, a, b, c, got, want)
+					}
+				}
+			}
+		}
+	}
+}
+
+func TestPeepholeOutputValidates(t *testing.T) {
+	module := lowerPeephole(t)
+	for _, opts := range []ir.PeepholeOptions{{}, {FiniteMath: true}} {
+		errs, err := Validate(ir.Peephole(module, opts))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(errs) > 0 {
+			t.Errorf("FiniteMath=%v: peephole output fails validation: %v", opts.FiniteMath, errs[0])
+		}
+	}
+}
+
+func TestCompilePeephole(t *testing.T) {
+	plain, err := CompileWithOptions(peepholeShader, CompileOptions{SPIRVVersion: spirv.Version1_3, Validate: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	opt, err := CompileWithOptions(peepholeShader, CompileOptions{
+		SPIRVVersion: spirv.Version1_3,
+		Validate:     true,
+		Peephole:     &ir.PeepholeOptions{FiniteMath: true},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(opt) >= len(plain) {
+		t.Errorf("peephole binary is %d bytes, want fewer than %d", len(opt), len(plain))
+	}
+}
@@ -0,0 +1,319 @@
+package naga
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/gogpu/naga/glsl"
+	"github.com/gogpu/naga/hlsl"
+	"github.com/gogpu/naga/ir"
+	"github.com/gogpu/naga/msl"
+	"github.com/gogpu/naga/spirv"
+	"github.com/gogpu/naga/wgsl"
+)
+
+// Target selects the output language of a Program.
+type Target uint8
+
+// Program targets.
+const (
+	TargetSPIRV Target = iota
+	TargetMSL
+	TargetHLSL
+	TargetGLSL
+)
+
+// String returns the target's lower-case name.
+func (t Target) String() string {
+	switch t {
+	case TargetSPIRV:
+		return "spirv"
+	case TargetMSL:
+		return "msl"
+	case TargetHLSL:
+		return "hlsl"
+	case TargetGLSL:
+		return "glsl"
+	default:
+		return fmt.Sprintf("target(%d)", t)
+	}
+}
+
+// Program is one module plus the entry points of one pipeline: a vertex
+// stage with an optional fragment stage, or a compute stage. Compile
+// builds every stage from the same module with the same binding remap, so
+// the stages agree on resource slots.
+type Program struct {
+	Module *ir.Module
+
+	// AST, if set, is the module's source AST; link errors then carry
+	// source spans.
+	AST *wgsl.Module
+
+	Vertex   string
+	Fragment string
+	Compute  string
+
+	// BindingRemap moves resources to new slots in every stage. Bindings
+	// not in the map keep their slot.
+	BindingRemap map[ir.ResourceBinding]ir.ResourceBinding
+}
+
+// ProgramOptions holds per-target backend options. nil uses the backend's
+// DefaultOptions. Entry point selection is set per stage by Compile.
+type ProgramOptions struct {
+	SPIRV *spirv.Options
+	MSL   *msl.Options
+	HLSL  *hlsl.Options
+	GLSL  *glsl.Options
+}
+
+// StageArtifact is the compiled code of one stage. Code is a SPIR-V binary
+// or source text. Bindings lists the (remapped) resource slots the stage
+// uses, ordered by group and binding.
+type StageArtifact struct {
+	EntryPoint string
+	Stage      ir.ShaderStage
+	Code       []byte
+	Bindings   []ir.ResourceBinding
+}
+
+// ProgramBinding is one resource slot of the program, listed once even
+// when several stages use it.
+type ProgramBinding struct {
+	Binding ir.ResourceBinding
+	Name    string
+	Stages  []ir.ShaderStage
+}
+
+// ProgramOutput is the result of Program.Compile. Stages are in pipeline
+// order (vertex, fragment) or hold the single compute stage; Bindings is
+// the union of their resources, ordered by group and binding.
+type ProgramOutput struct {
+	Stages   []StageArtifact
+	Bindings []ProgramBinding
+}
+
+// NewProgram parses and lowers WGSL source into a Program with no stages
+// selected; set Vertex/Fragment or Compute before calling Compile.
+func NewProgram(source string) (*Program, error) {
+	ast, err := Parse(source)
+	if err != nil {
+		return nil, fmt.Errorf("parse error: %w", err)
+	}
+	module, err := wgsl.LowerWithSource(ast, source)
+	if err != nil {
+		return nil, fmt.Errorf("lowering error: %w", err)
+	}
+	return &Program{Module: module, AST: ast}, nil
+}
+
+// programStage is a selected entry point and the globals it uses.
+type programStage struct {
+	index   int
+	globals []ir.GlobalVariableHandle
+}
+
+// Compile validates the program and compiles every selected stage for
+// target. It fails if a stage is missing or has the wrong shader stage,
+// if remapping puts two resources in one slot, or, with both vertex and
+// fragment stages, if their interfaces do not match (a *LinkError).
+func (p *Program) Compile(target Target, opts ProgramOptions) (*ProgramOutput, error) {
+	if p.Module == nil {
+		return nil, fmt.Errorf("program has no module")
+	}
+	names, err := p.stageNames()
+	if err != nil {
+		return nil, err
+	}
+
+	var stages []programStage
+	for _, sel := range names {
+		idx, err := entryPointIndex(p.Module, sel.name, sel.stage)
+		if err != nil {
+			return nil, err
+		}
+		stages = append(stages, programStage{index: idx, globals: ir.EntryPointGlobals(p.Module, idx)})
+	}
+
+	if p.Vertex != "" && p.Fragment != "" {
+		mismatches, err := CheckStageLinkage(p.AST, p.Module, p.Vertex, p.Fragment)
+		if err != nil {
+			return nil, err
+		}
+		if len(mismatches) > 0 {
+			return nil, &LinkError{Mismatches: mismatches}
+		}
+	}
+
+	module, bindings, err := p.remappedModule(stages)
+	if err != nil {
+		return nil, err
+	}
+
+	out := &ProgramOutput{Bindings: bindings}
+	for _, st := range stages {
+		ep := module.EntryPoints[st.index]
+		code, err := compileStage(module, st.index, target, opts)
+		if err != nil {
+			return nil, fmt.Errorf("%s stage %q: %w", programStageName(ep.Stage), ep.Name, err)
+		}
+		artifact := StageArtifact{EntryPoint: ep.Name, Stage: ep.Stage, Code: code}
+		for _, h := range st.globals {
+			if b := module.GlobalVariables[h].Binding; b != nil {
+				artifact.Bindings = append(artifact.Bindings, *b)
+			}
+		}
+		sortBindings(artifact.Bindings, func(i int) ir.ResourceBinding { return artifact.Bindings[i] })
+		out.Stages = append(out.Stages, artifact)
+	}
+	return out, nil
+}
+
+type stageSelection struct {
+	name  string
+	stage ir.ShaderStage
+}
+
+// stageNames returns the selected entry points in pipeline order.
+func (p *Program) stageNames() ([]stageSelection, error) {
+	switch {
+	case p.Compute != "" && (p.Vertex != "" || p.Fragment != ""):
+		return nil, fmt.Errorf("program mixes a compute stage with render stages")
+	case p.Compute != "":
+		return []stageSelection{{p.Compute, ir.StageCompute}}, nil
+	case p.Vertex == "":
+		return nil, fmt.Errorf("program has no vertex or compute stage")
+	case p.Fragment == "":
+		return []stageSelection{{p.Vertex, ir.StageVertex}}, nil
+	default:
+		return []stageSelection{{p.Vertex, ir.StageVertex}, {p.Fragment, ir.StageFragment}}, nil
+	}
+}
+
+func entryPointIndex(module *ir.Module, name string, stage ir.ShaderStage) (int, error) {
+	for i := range module.EntryPoints {
+		ep := &module.EntryPoints[i]
+		if ep.Name != name {
+			continue
+		}
+		if ep.Stage != stage {
+			return 0, fmt.Errorf("entry point %q is not a %s shader", name, programStageName(stage))
+		}
+		return i, nil
+	}
+	return 0, fmt.Errorf("entry point %q not found", name)
+}
+
+// remappedModule returns a shallow copy of the module with BindingRemap
+// applied to its globals, and the program's deduplicated binding table.
+// The copy shares everything but GlobalVariables with p.Module.
+func (p *Program) remappedModule(stages []programStage) (*ir.Module, []ProgramBinding, error) {
+	module := *p.Module
+	module.GlobalVariables = make([]ir.GlobalVariable, len(p.Module.GlobalVariables))
+	copy(module.GlobalVariables, p.Module.GlobalVariables)
+	for i := range module.GlobalVariables {
+		gv := &module.GlobalVariables[i]
+		if gv.Binding == nil {
+			continue
+		}
+		if to, ok := p.BindingRemap[*gv.Binding]; ok {
+			gv.Binding = &to
+		}
+	}
+
+	var bindings []ProgramBinding
+	slot := make(map[ir.ResourceBinding]int)
+	owner := make(map[ir.ResourceBinding]ir.GlobalVariableHandle)
+	for _, st := range stages {
+		stage := module.EntryPoints[st.index].Stage
+		for _, h := range st.globals {
+			gv := &module.GlobalVariables[h]
+			if gv.Binding == nil {
+				continue
+			}
+			b := *gv.Binding
+			if prev, ok := owner[b]; ok && prev != h {
+				return nil, nil, fmt.Errorf("globals %q and %q both use @group(%d) @binding(%d)",
+					module.GlobalVariables[prev].Name, gv.Name, b.Group, b.Binding)
+			}
+			owner[b] = h
+			if i, ok := slot[b]; ok {
+				if last := bindings[i].Stages; last[len(last)-1] != stage {
+					bindings[i].Stages = append(bindings[i].Stages, stage)
+				}
+				continue
+			}
+			slot[b] = len(bindings)
+			bindings = append(bindings, ProgramBinding{Binding: b, Name: gv.Name, Stages: []ir.ShaderStage{stage}})
+		}
+	}
+	sortBindings(bindings, func(i int) ir.ResourceBinding { return bindings[i].Binding })
+	return &module, bindings, nil
+}
+
+func sortBindings[T any](s []T, key func(int) ir.ResourceBinding) {
+	sort.SliceStable(s, func(i, j int) bool {
+		a, b := key(i), key(j)
+		if a.Group != b.Group {
+			return a.Group < b.Group
+		}
+		return a.Binding < b.Binding
+	})
+}
+
+// compileStage compiles the entry point at index ep of module. SPIR-V gets
+// a copy of the module holding only that entry point; the text backends
+// select it through their options.
+func compileStage(module *ir.Module, ep int, target Target, opts ProgramOptions) ([]byte, error) {
+	entry := module.EntryPoints[ep]
+	switch target {
+	case TargetSPIRV:
+		o := spirv.DefaultOptions()
+		if opts.SPIRV != nil {
+			o = *opts.SPIRV
+		}
+		single := *module
+		single.EntryPoints = []ir.EntryPoint{entry}
+		return spirv.NewBackend(o).Compile(&single)
+	case TargetMSL:
+		o := msl.DefaultOptions()
+		if opts.MSL != nil {
+			o = *opts.MSL
+		}
+		code, _, err := msl.CompileWithPipeline(module, o, msl.PipelineOptions{
+			EntryPoint: &msl.EntryPointSelector{Stage: entry.Stage, Name: entry.Name},
+		})
+		return []byte(code), err
+	case TargetHLSL:
+		o := hlsl.DefaultOptions()
+		if opts.HLSL != nil {
+			c := *opts.HLSL
+			o = &c
+		}
+		o.EntryPoint = entry.Name
+		code, _, err := hlsl.Compile(module, o)
+		return []byte(code), err
+	case TargetGLSL:
+		o := glsl.DefaultOptions()
+		if opts.GLSL != nil {
+			o = *opts.GLSL
+		}
+		o.EntryPoint = entry.Name
+		code, _, err := glsl.Compile(module, o)
+		return []byte(code), err
+	default:
+		return nil, fmt.Errorf("unknown target %v", target)
+	}
+}
+
+func programStageName(stage ir.ShaderStage) string {
+	switch stage {
+	case ir.StageVertex:
+		return "vertex"
+	case ir.StageFragment:
+		return "fragment"
+	default:
+		return "compute"
+	}
+}
@@ -0,0 +1,169 @@
+package naga
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/gogpu/naga/ir"
+)
+
+const programSource = `
+struct Camera { view_proj: mat4x4<f32> }
+struct Material { tint: vec4<f32> }
+
+@group(0) @binding(0) var<uniform> camera: Camera;
+@group(1) @binding(0) var<uniform> material: Material;
+@group(1) @binding(1) var tex: texture_2d<f32>;
+@group(1) @binding(2) var samp: sampler;
+@group(2) @binding(0) var<storage, read_write> counts: array<u32>;
+
+struct VsOut {
+    @builtin(position) pos: vec4<f32>,
+    @location(0) uv: vec2<f32>,
+}
+
+fn tinted(c: vec4<f32>) -> vec4<f32> {
+    return c * material.tint;
+}
+
+@vertex
+fn vs(@location(0) p: vec3<f32>) -> VsOut {
+    let t = tinted(vec4<f32>(1.0));
+    return VsOut(camera.view_proj * vec4<f32>(p, t.w), p.xy);
+}
+
+@fragment
+fn fs(@location(0) uv: vec2<f32>) -> @location(0) vec4<f32> {
+    return tinted(textureSample(tex, samp, uv));
+}
+
+@fragment
+fn fs_bad(@location(0) uv: vec3<f32>) -> @location(0) vec4<f32> {
+    return vec4<f32>(uv, 1.0);
+}
+
+@compute @workgroup_size(1)
+fn cs() {
+    counts[0] = 1u;
+}
+`
+
+func TestProgramCompile(t *testing.T) {
+	p, err := NewProgram(programSource)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p.Vertex, p.Fragment = "vs", "fs"
+
+	for _, target := range []Target{TargetSPIRV, TargetMSL, TargetHLSL, TargetGLSL} {
+		t.Run(target.String(), func(t *testing.T) {
+			out, err := p.Compile(target, ProgramOptions{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(out.Stages) != 2 || out.Stages[0].Stage != ir.StageVertex || out.Stages[1].Stage != ir.StageFragment {
+				t.Fatalf("stages = %+v", out.Stages)
+			}
+			for _, st := range out.Stages {
+				if len(st.Code) == 0 {
+					t.Errorf("%s: empty code", st.EntryPoint)
+				}
+			}
+			// The fragment stage must not see the vertex-only binding.
+			if got := out.Stages[1].Bindings; len(got) != 3 || got[0] != (ir.ResourceBinding{Group: 1, Binding: 0}) {
+				t.Errorf("fragment bindings = %v", got)
+			}
+			var names []string
+			for _, b := range out.Bindings {
+				names = append(names, b.Name)
+			}
+			if got := strings.Join(names, ","); got != "camera,material,tex,samp" {
+				t.Errorf("program bindings = %s", got)
+			}
+			material := out.Bindings[1]
+			if len(material.Stages) != 2 {
+				t.Errorf("material stages = %v, want vertex and fragment", material.Stages)
+			}
+		})
+	}
+}
+
+func TestProgramBindingRemap(t *testing.T) {
+	p, err := NewProgram(programSource)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p.Vertex, p.Fragment = "vs", "fs"
+	p.BindingRemap = map[ir.ResourceBinding]ir.ResourceBinding{
+		{Group: 1, Binding: 2}: {Group: 3, Binding: 7},
+	}
+	out, err := p.Compile(TargetGLSL, ProgramOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	last := out.Bindings[len(out.Bindings)-1]
+	if last.Name != "samp" || last.Binding != (ir.ResourceBinding{Group: 3, Binding: 7}) {
+		t.Errorf("remapped binding = %+v", last)
+	}
+	if b := p.Module.GlobalVariables[3].Binding; b.Group != 1 || b.Binding != 2 {
+		t.Errorf("Compile modified the program's module: %+v", b)
+	}
+
+	p.BindingRemap = map[ir.ResourceBinding]ir.ResourceBinding{
+		{Group: 1, Binding: 2}: {Group: 1, Binding: 1},
+	}
+	if _, err := p.Compile(TargetGLSL, ProgramOptions{}); err == nil || !strings.Contains(err.Error(), `"tex" and "samp"`) {
+		t.Errorf("colliding remap: got %v", err)
+	}
+}
+
+func TestProgramCompute(t *testing.T) {
+	p, err := NewProgram(programSource)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p.Compute = "cs"
+	out, err := p.Compile(TargetMSL, ProgramOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(out.Stages) != 1 || len(out.Bindings) != 1 || out.Bindings[0].Name != "counts" {
+		t.Errorf("output = %+v", out)
+	}
+}
+
+func TestProgramErrors(t *testing.T) {
+	p, err := NewProgram(programSource)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name                      string
+		vertex, fragment, compute string
+		want                      string
+	}{
+		{"empty", "", "", "", "no vertex or compute stage"},
+		{"mixed", "vs", "", "cs", "mixes a compute stage"},
+		{"missing", "vs", "nope", "", `"nope" not found`},
+		{"wrong stage", "fs", "", "", `"fs" is not a vertex shader`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p.Vertex, p.Fragment, p.Compute = tt.vertex, tt.fragment, tt.compute
+			if _, err := p.Compile(TargetSPIRV, ProgramOptions{}); err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("got %v, want error containing %q", err, tt.want)
+			}
+		})
+	}
+
+	p.Vertex, p.Fragment, p.Compute = "vs", "fs_bad", ""
+	_, err = p.Compile(TargetSPIRV, ProgramOptions{})
+	var linkErr *LinkError
+	if !errors.As(err, &linkErr) {
+		t.Fatalf("expected *LinkError, got %v", err)
+	}
+	if linkErr.Mismatches[0].FragmentSpan.Start.Line == 0 {
+		t.Error("link error has no source span")
+	}
+}
@@ -0,0 +1,223 @@
+// Copyright 2025 The GoGPU Authors
+// SPDX-License-Identifier: MIT
+
+package reflection
+
+import (
+	"sort"
+
+	"github.com/gogpu/naga/ir"
+)
+
+// CompatRestriction is something WebGPU compatibility mode, the profile
+// for OpenGL ES 3.1 and Direct3D 11 class devices, does not allow.
+type CompatRestriction string
+
+// Restrictions reported by CompatibilityIssues.
+const (
+	// CompatSampleMask is the sample_mask built-in.
+	CompatSampleMask CompatRestriction = "sample-mask"
+	// CompatSampleIndex is the sample_index built-in.
+	CompatSampleIndex CompatRestriction = "sample-index"
+	// CompatFlatInterpolation is flat interpolation of an inter-stage
+	// variable other than @interpolate(flat, either): @interpolate(flat),
+	// @interpolate(flat, first), or an integer with no @interpolate, which
+	// defaults to flat.
+	CompatFlatInterpolation CompatRestriction = "flat-interpolation"
+	// CompatLinearInterpolation is @interpolate(linear).
+	CompatLinearInterpolation CompatRestriction = "linear-interpolation"
+	// CompatSampleInterpolation is per-sample interpolation,
+	// @interpolate(perspective, sample) and the like.
+	CompatSampleInterpolation CompatRestriction = "sample-interpolation"
+	// CompatCubeArray is a texture_cube_array or texture_depth_cube_array.
+	CompatCubeArray CompatRestriction = "cube-array"
+	// CompatDepthTextureLoad is textureLoad on a depth texture.
+	CompatDepthTextureLoad CompatRestriction = "depth-texture-load"
+	// CompatStorageFormat is a storage texture format compatibility mode
+	// leaves out (rg32uint, rg32sint and rg32float); Detail names it.
+	CompatStorageFormat CompatRestriction = "storage-format"
+)
+
+// CompatIssue is one restriction of compatibility mode a module breaks.
+type CompatIssue struct {
+	Restriction CompatRestriction `json:"restriction"`
+
+	// Detail narrows the restriction, such as the WGSL name of a storage
+	// texture format.
+	Detail string `json:"detail,omitempty"`
+
+	// EntryPoints names, in declaration order, the entry points that
+	// break it directly or through the functions they call.
+	EntryPoints []string `json:"entryPoints"`
+}
+
+// CompatibilityIssues lists what module's entry points use that WebGPU
+// compatibility mode does not allow, sorted by restriction and detail. An
+// empty result means the module is valid in compatibility mode as far as
+// the shader itself goes; per-device limits, such as storage buffers in
+// vertex shaders, are not checked. Code no entry point reaches is not
+// counted.
+func CompatibilityIssues(module *ir.Module) []CompatIssue {
+	found := make(map[compatKey]*CompatIssue)
+	for i := range module.EntryPoints {
+		c := &compatCollector{module: module, used: make(map[compatKey]bool), seenFns: make([]bool, len(module.Functions))}
+		c.entryPoint(i)
+		for k := range c.used {
+			issue := found[k]
+			if issue == nil {
+				issue = &CompatIssue{Restriction: k.restriction, Detail: k.detail, EntryPoints: []string{}}
+				found[k] = issue
+			}
+			issue.EntryPoints = append(issue.EntryPoints, module.EntryPoints[i].Name)
+		}
+	}
+
+	issues := make([]CompatIssue, 0, len(found))
+	for _, issue := range found {
+		issues = append(issues, *issue)
+	}
+	sort.Slice(issues, func(i, j int) bool {
+		if issues[i].Restriction != issues[j].Restriction {
+			return issues[i].Restriction < issues[j].Restriction
+		}
+		return issues[i].Detail < issues[j].Detail
+	})
+	return issues
+}
+
+type compatKey struct {
+	restriction CompatRestriction
+	detail      string
+}
+
+// compatCollector gathers the restrictions one entry point breaks.
+type compatCollector struct {
+	module  *ir.Module
+	used    map[compatKey]bool
+	seenFns []bool
+}
+
+func (c *compatCollector) add(r CompatRestriction, detail string) {
+	c.used[compatKey{r, detail}] = true
+}
+
+func (c *compatCollector) entryPoint(index int) {
+	ep := &c.module.EntryPoints[index]
+	for _, h := range ir.EntryPointGlobals(c.module, index) {
+		c.typeHandle(c.module.GlobalVariables[h].Type)
+	}
+	for _, arg := range ep.Function.Arguments {
+		c.entryInterface(arg.Binding, arg.Type, ep.Stage == ir.StageFragment)
+	}
+	if ep.Function.Result != nil {
+		c.entryInterface(ep.Function.Result.Binding, ep.Function.Result.Type, ep.Stage == ir.StageVertex)
+	}
+	c.function(&ep.Function)
+}
+
+// entryInterface checks an entry point argument or result, or the members
+// of one that is a struct. interStage is set for vertex outputs and
+// fragment inputs.
+func (c *compatCollector) entryInterface(binding *ir.Binding, ty ir.TypeHandle, interStage bool) {
+	if binding != nil {
+		c.binding(*binding, interStage)
+		return
+	}
+	if int(ty) >= len(c.module.Types) {
+		return
+	}
+	if st, ok := c.module.Types[ty].Inner.(ir.StructType); ok {
+		for _, m := range st.Members {
+			if m.Binding != nil {
+				c.binding(*m.Binding, interStage)
+			}
+		}
+	}
+}
+
+func (c *compatCollector) binding(b ir.Binding, interStage bool) {
+	switch b := b.(type) {
+	case ir.BuiltinBinding:
+		switch b.Builtin {
+		case ir.BuiltinSampleMask:
+			c.add(CompatSampleMask, "")
+		case ir.BuiltinSampleIndex:
+			c.add(CompatSampleIndex, "")
+		}
+	case ir.LocationBinding:
+		if b.Interpolation == nil {
+			return
+		}
+		if interStage && b.Interpolation.Kind == ir.InterpolationFlat && b.Interpolation.Sampling != ir.SamplingEither {
+			c.add(CompatFlatInterpolation, "")
+		}
+		if b.Interpolation.Kind == ir.InterpolationLinear {
+			c.add(CompatLinearInterpolation, "")
+		}
+		if b.Interpolation.Sampling == ir.SamplingSample {
+			c.add(CompatSampleInterpolation, "")
+		}
+	}
+}
+
+func (c *compatCollector) typeHandle(h ir.TypeHandle) {
+	for int(h) < len(c.module.Types) {
+		switch t := c.module.Types[h].Inner.(type) {
+		case ir.BindingArrayType:
+			h = t.Base
+			continue
+		case ir.ImageType:
+			if t.Dim == ir.DimCube && t.Arrayed {
+				c.add(CompatCubeArray, "")
+			}
+			if t.Class == ir.ImageClassStorage {
+				switch t.StorageFormat {
+				case ir.StorageFormatRg32Uint, ir.StorageFormatRg32Sint, ir.StorageFormatRg32Float:
+					c.add(CompatStorageFormat, storageFormatNames[t.StorageFormat])
+				}
+			}
+		}
+		return
+	}
+}
+
+func (c *compatCollector) function(fn *ir.Function) {
+	for _, expr := range fn.Expressions {
+		load, ok := expr.Kind.(ir.ExprImageLoad)
+		if !ok {
+			continue
+		}
+		res, err := expressionType(c.module, fn, load.Image)
+		if err != nil {
+			continue
+		}
+		if img, ok := ir.TypeResInner(c.module, res).(ir.ImageType); ok && img.Class == ir.ImageClassDepth {
+			c.add(CompatDepthTextureLoad, "")
+		}
+	}
+	c.block(fn.Body)
+}
+
+func (c *compatCollector) block(block ir.Block) {
+	for _, stmt := range block {
+		switch s := stmt.Kind.(type) {
+		case ir.StmtBlock:
+			c.block(s.Block)
+		case ir.StmtIf:
+			c.block(s.Accept)
+			c.block(s.Reject)
+		case ir.StmtSwitch:
+			for _, cs := range s.Cases {
+				c.block(cs.Body)
+			}
+		case ir.StmtLoop:
+			c.block(s.Body)
+			c.block(s.Continuing)
+		case ir.StmtCall:
+			if int(s.Function) < len(c.seenFns) && !c.seenFns[s.Function] {
+				c.seenFns[s.Function] = true
+				c.function(&c.module.Functions[s.Function])
+			}
+		}
+	}
+}
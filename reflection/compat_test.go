@@ -0,0 +1,109 @@
+package reflection
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/gogpu/naga/internal/testutil"
+)
+
+const compatShader = `
+@group(0) @binding(0) var cubes: texture_cube_array<f32>;
+@group(0) @binding(1) var samp: sampler;
+@group(0) @binding(2) var depth: texture_depth_2d;
+@group(0) @binding(3) var out: texture_storage_2d<rg32float, write>;
+@group(0) @binding(4) var plain: texture_2d<f32>;
+
+struct VertexOutput {
+    @builtin(position) position: vec4<f32>,
+    @location(0) @interpolate(linear) uv: vec2<f32>,
+    @location(1) @interpolate(perspective, sample) normal: vec3<f32>,
+    @location(2) @interpolate(flat) id: u32,
+}
+
+@vertex
+fn vs() -> VertexOutput {
+    return VertexOutput(vec4<f32>(0.0), vec2<f32>(0.0), vec3<f32>(0.0), 0u);
+}
+
+fn load_depth(p: vec2<i32>) -> f32 {
+    return textureLoad(depth, p, 0);
+}
+
+@fragment
+fn fs(in: VertexOutput, @builtin(sample_index) s: u32) -> @builtin(sample_mask) u32 {
+    let c = textureSample(cubes, samp, in.normal, 0);
+    return u32(c.x + load_depth(vec2<i32>(in.uv)));
+}
+
+@compute @workgroup_size(1)
+fn cs() {
+    textureStore(out, vec2<i32>(0), vec4<f32>(1.0));
+}
+
+@fragment
+fn core_only() -> @location(0) vec4<f32> {
+    return textureLoad(plain, vec2<i32>(0), 0);
+}
+`
+
+func TestCompatibilityIssues(t *testing.T) {
+	got := CompatibilityIssues(testutil.LowerWGSL(t, compatShader))
+	want := []CompatIssue{
+		{Restriction: CompatCubeArray, EntryPoints: []string{"fs"}},
+		{Restriction: CompatDepthTextureLoad, EntryPoints: []string{"fs"}},
+		{Restriction: CompatFlatInterpolation, EntryPoints: []string{"vs", "fs"}},
+		{Restriction: CompatLinearInterpolation, EntryPoints: []string{"vs", "fs"}},
+		{Restriction: CompatSampleIndex, EntryPoints: []string{"fs"}},
+		{Restriction: CompatSampleInterpolation, EntryPoints: []string{"vs", "fs"}},
+		{Restriction: CompatSampleMask, EntryPoints: []string{"fs"}},
+		{Restriction: CompatStorageFormat, Detail: "rg32float", EntryPoints: []string{"cs"}},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("CompatibilityIssues =\n%+v\nwant\n%+v", got, want)
+	}
+
+	if issues := CompatibilityIssues(testutil.LowerWGSL(t, resourceShader)); len(issues) != 0 {
+		t.Errorf("got %+v, want no issues", issues)
+	}
+}
+
+func TestCompatibilityIssuesFlatInterpolation(t *testing.T) {
+	for _, tc := range []struct {
+		attr string
+		want bool
+	}{
+		{"@interpolate(flat)", true},
+		{"@interpolate(flat, first)", true},
+		{"", true}, // integers default to flat
+		{"@interpolate(flat, either)", false},
+	} {
+		src := `
+struct VertexOutput {
+    @builtin(position) position: vec4<f32>,
+    @location(0) ` + tc.attr + ` id: u32,
+}
+
+@vertex
+fn vs() -> VertexOutput {
+    return VertexOutput(vec4<f32>(0.0), 0u);
+}
+
+@fragment
+fn fs(in: VertexOutput) -> @location(0) u32 {
+    return in.id;
+}
+`
+		var want []CompatIssue
+		if tc.want {
+			want = []CompatIssue{{Restriction: CompatFlatInterpolation, EntryPoints: []string{"vs", "fs"}}}
+		}
+		got := CompatibilityIssues(testutil.LowerWGSL(t, src))
+		if len(got) == 0 && len(want) == 0 {
+			continue
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("%q: CompatibilityIssues = %+v, want %+v", tc.attr, got, want)
+		}
+	}
+}
@@ -0,0 +1,490 @@
+// Copyright 2025 The GoGPU Authors
+// SPDX-License-Identifier: MIT
+
+package reflection
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ChangeKind says whether a Change adds, removes, or modifies something.
+type ChangeKind string
+
+// Change kinds reported by Diff.
+const (
+	ChangeAdded    ChangeKind = "added"
+	ChangeRemoved  ChangeKind = "removed"
+	ChangeModified ChangeKind = "modified"
+)
+
+// Change is one difference between two Documents.
+type Change struct {
+	Kind ChangeKind `json:"kind"`
+
+	// Subject names what changed, such as `binding @group(0) @binding(1)`,
+	// `entry point "main"` or `struct "Scene"`.
+	Subject string `json:"subject"`
+
+	// Detail describes a modification, such as "type texture ->
+	// storage-texture", or names the stage of an added or removed entry
+	// point. It is empty for other additions and removals.
+	Detail string `json:"detail,omitempty"`
+
+	// Rebuild is set when the change breaks something the host built
+	// from the old reflection: bind group and pipeline layouts, vertex
+	// buffer layouts, buffer contents, dispatch sizes, or the set of
+	// entry points. Renames and resource access changes that leave the
+	// layout alone do not.
+	Rebuild bool `json:"rebuild"`
+}
+
+// DocumentDiff lists the changes between two versions of a module's
+// reflection, as Diff reports them.
+type DocumentDiff struct {
+	Changes []Change `json:"changes"`
+}
+
+// Empty reports whether the two Documents describe the same interface.
+func (d *DocumentDiff) Empty() bool {
+	return len(d.Changes) == 0
+}
+
+// NeedsPipelineRebuild reports whether any change needs more than swapping
+// the shader module: without one, a hot reload can recreate pipelines from
+// the new module with the layouts, bind groups and vertex buffers the host
+// already has.
+func (d *DocumentDiff) NeedsPipelineRebuild() bool {
+	for _, c := range d.Changes {
+		if c.Rebuild {
+			return true
+		}
+	}
+	return false
+}
+
+// String writes one line per change: "+" for additions, "-" for removals
+// and "~" for modifications, with "[rebuild]" after changes that need a
+// pipeline rebuild.
+func (d *DocumentDiff) String() string {
+	var b strings.Builder
+	for _, c := range d.Changes {
+		switch c.Kind {
+		case ChangeAdded:
+			b.WriteString("+ ")
+		case ChangeRemoved:
+			b.WriteString("- ")
+		default:
+			b.WriteString("~ ")
+		}
+		b.WriteString(c.Subject)
+		if c.Detail != "" {
+			b.WriteString(": ")
+			b.WriteString(c.Detail)
+		}
+		if c.Rebuild {
+			b.WriteString(" [rebuild]")
+		}
+		b.WriteString("\n")
+	}
+	return b.String()
+}
+
+// Diff compares the reflection of two versions of a module, so an engine
+// reloading a shader can tell whether the new module fits the pipelines
+// and resources built for the old one. Bindings are matched by group and
+// binding, entry points and vertex inputs by entry point name, fragment
+// outputs by location, and structs by name. Stack estimates and SchemaVersion are not compared.
+//
+// Changes are listed bindings first, by group then binding; then entry
+// points, vertex inputs and structs, each by name.
+func Diff(old, new *Document) *DocumentDiff {
+	d := &DocumentDiff{Changes: []Change{}}
+	d.bindings(old.Bindings, new.Bindings)
+	d.entryPoints(old.EntryPoints, new.EntryPoints, new.Bindings)
+	d.vertexInputs(old.VertexInputs, new.VertexInputs)
+	d.structs(old.Structs, new.Structs)
+	return d
+}
+
+func (d *DocumentDiff) add(kind ChangeKind, subject, detail string, rebuild bool) {
+	d.Changes = append(d.Changes, Change{Kind: kind, Subject: subject, Detail: detail, Rebuild: rebuild})
+}
+
+type slot struct{ group, binding uint32 }
+
+func (s slot) String() string {
+	return fmt.Sprintf("@group(%d) @binding(%d)", s.group, s.binding)
+}
+
+// bindingSlots indexes bindings by slot. Where globals share a slot, the
+// first in Reflect order stands for it.
+func bindingSlots(bindings []BindingInfo) (map[slot]BindingInfo, []slot) {
+	m := make(map[slot]BindingInfo, len(bindings))
+	var order []slot
+	for _, b := range bindings {
+		s := slot{b.Group, b.Binding}
+		if _, ok := m[s]; !ok {
+			m[s] = b
+			order = append(order, s)
+		}
+	}
+	return m, order
+}
+
+func (d *DocumentDiff) bindings(old, new []BindingInfo) {
+	oldSlots, oldOrder := bindingSlots(old)
+	newSlots, newOrder := bindingSlots(new)
+	all := mergeSlots(oldOrder, newOrder)
+	for _, s := range all {
+		o, inOld := oldSlots[s]
+		n, inNew := newSlots[s]
+		subject := "binding " + s.String()
+		switch {
+		case !inOld:
+			d.add(ChangeAdded, fmt.Sprintf("%s %q", subject, n.Name), "", true)
+		case !inNew:
+			d.add(ChangeRemoved, fmt.Sprintf("%s %q", subject, o.Name), "", true)
+		default:
+			if o.Type != n.Type {
+				d.add(ChangeModified, subject, fmt.Sprintf("type %s -> %s", o.Type, n.Type), true)
+			}
+			if oc, nc := countString(o.Count), countString(n.Count); oc != nc {
+				d.add(ChangeModified, subject, fmt.Sprintf("count %s -> %s", oc, nc), true)
+			}
+			d.bindingLayout(subject, o, n)
+			if o.Name != n.Name {
+				d.add(ChangeModified, subject, fmt.Sprintf("name %q -> %q", o.Name, n.Name), false)
+			}
+		}
+	}
+}
+
+// bindingLayout compares the details of a binding its bind group layout
+// entry records. A type change already implies them, so they are compared
+// between bindings of the same type only.
+func (d *DocumentDiff) bindingLayout(subject string, old, new BindingInfo) {
+	if old.Type != new.Type {
+		return
+	}
+	details := []struct{ what, old, new string }{
+		{"view dimension", old.ViewDimension, new.ViewDimension},
+		{"sample type", old.SampleType, new.SampleType},
+		{"storage format", old.StorageFormat, new.StorageFormat},
+		{"multisampled", fmt.Sprint(old.Multisampled), fmt.Sprint(new.Multisampled)},
+		{"min binding size", fmt.Sprint(old.MinBindingSize), fmt.Sprint(new.MinBindingSize)},
+	}
+	for _, detail := range details {
+		if detail.old != detail.new {
+			d.add(ChangeModified, subject, fmt.Sprintf("%s %s -> %s", detail.what, detail.old, detail.new), true)
+		}
+	}
+}
+
+// mergeSlots returns the slots of a and b, each once, by group then
+// binding.
+func mergeSlots(a, b []slot) []slot {
+	seen := make(map[slot]bool, len(a)+len(b))
+	var all []slot
+	for _, s := range append(append([]slot{}, a...), b...) {
+		if !seen[s] {
+			seen[s] = true
+			all = append(all, s)
+		}
+	}
+	sort.Slice(all, func(i, j int) bool {
+		if all[i].group != all[j].group {
+			return all[i].group < all[j].group
+		}
+		return all[i].binding < all[j].binding
+	})
+	return all
+}
+
+func countString(count *uint32) string {
+	if count == nil {
+		return "none"
+	}
+	return fmt.Sprint(*count)
+}
+
+// sortedNames returns the names of a and b, each once, sorted.
+func sortedNames(a, b []string) []string {
+	seen := make(map[string]bool, len(a)+len(b))
+	var all []string
+	for _, name := range append(append([]string{}, a...), b...) {
+		if !seen[name] {
+			seen[name] = true
+			all = append(all, name)
+		}
+	}
+	sort.Strings(all)
+	return all
+}
+
+func (d *DocumentDiff) entryPoints(old, new []EntryPointInfo, newBindings []BindingInfo) {
+	oldByName := make(map[string]EntryPointInfo, len(old))
+	newByName := make(map[string]EntryPointInfo, len(new))
+	var oldNames, newNames []string
+	for _, ep := range old {
+		oldByName[ep.Name] = ep
+		oldNames = append(oldNames, ep.Name)
+	}
+	for _, ep := range new {
+		newByName[ep.Name] = ep
+		newNames = append(newNames, ep.Name)
+	}
+	types, _ := bindingSlots(newBindings)
+
+	for _, name := range sortedNames(oldNames, newNames) {
+		o, inOld := oldByName[name]
+		n, inNew := newByName[name]
+		subject := fmt.Sprintf("entry point %q", name)
+		switch {
+		case !inOld:
+			d.add(ChangeAdded, subject, n.Stage, true)
+			continue
+		case !inNew:
+			d.add(ChangeRemoved, subject, o.Stage, true)
+			continue
+		}
+		if o.Stage != n.Stage {
+			d.add(ChangeModified, subject, fmt.Sprintf("stage %s -> %s", o.Stage, n.Stage), true)
+		}
+		if ow, nw := workgroupString(o), workgroupString(n); ow != nw {
+			d.add(ChangeModified, subject, fmt.Sprintf("workgroup size %s -> %s", ow, nw), true)
+		}
+		if ost, nst := storageString(o.WorkgroupStorageSize), storageString(n.WorkgroupStorageSize); ost != nst {
+			d.add(ChangeModified, subject, fmt.Sprintf("workgroup storage %s -> %s", ost, nst), false)
+		}
+		d.bindingUses(subject, o.Bindings, n.Bindings, types)
+		d.outputs(subject, o.Outputs, n.Outputs)
+	}
+}
+
+// outputs compares the @location results of a fragment entry point, which
+// the color targets of its render pipelines follow. They are matched by
+// location and blend source, and listed in that order.
+func (d *DocumentDiff) outputs(subject string, old, new []FragmentOutput) {
+	type slot struct{ location, blendSrc uint32 }
+	slotOf := func(o FragmentOutput) slot {
+		if o.BlendSrc != nil {
+			return slot{o.Location, *o.BlendSrc}
+		}
+		return slot{o.Location, 0}
+	}
+	oldOutputs := make(map[slot]FragmentOutput, len(old))
+	newOutputs := make(map[slot]FragmentOutput, len(new))
+	var all []slot
+	for _, o := range old {
+		oldOutputs[slotOf(o)] = o
+		all = append(all, slotOf(o))
+	}
+	for _, o := range new {
+		newOutputs[slotOf(o)] = o
+		if _, ok := oldOutputs[slotOf(o)]; !ok {
+			all = append(all, slotOf(o))
+		}
+	}
+	sort.Slice(all, func(i, j int) bool {
+		if all[i].location != all[j].location {
+			return all[i].location < all[j].location
+		}
+		return all[i].blendSrc < all[j].blendSrc
+	})
+	for _, s := range all {
+		o, inOld := oldOutputs[s]
+		n, inNew := newOutputs[s]
+		switch {
+		case !inOld:
+			d.add(ChangeModified, subject, fmt.Sprintf("added output %s %s", outputString(n), n.Type), true)
+		case !inNew:
+			d.add(ChangeModified, subject, fmt.Sprintf("removed output %s %s", outputString(o), o.Type), true)
+		case o.Type != n.Type:
+			d.add(ChangeModified, subject, fmt.Sprintf("output %s type %s -> %s", outputString(n), o.Type, n.Type), true)
+		}
+	}
+}
+
+func outputString(o FragmentOutput) string {
+	if o.BlendSrc != nil {
+		return fmt.Sprintf("@location(%d) @blend_src(%d)", o.Location, *o.BlendSrc)
+	}
+	return fmt.Sprintf("@location(%d)", o.Location)
+}
+
+// bindingUses compares the bindings one entry point references. Starting
+// or stopping to use a binding changes the entry point's visibility in a
+// derived bind group layout. An access change only matters to storage
+// textures, whose layout entry records it.
+func (d *DocumentDiff) bindingUses(subject string, old, new []BindingUse, types map[slot]BindingInfo) {
+	oldUses := make(map[slot]BindingUse, len(old))
+	newUses := make(map[slot]BindingUse, len(new))
+	var oldOrder, newOrder []slot
+	for _, u := range old {
+		s := slot{u.Group, u.Binding}
+		if _, ok := oldUses[s]; !ok {
+			oldUses[s] = u
+			oldOrder = append(oldOrder, s)
+		}
+	}
+	for _, u := range new {
+		s := slot{u.Group, u.Binding}
+		if _, ok := newUses[s]; !ok {
+			newUses[s] = u
+			newOrder = append(newOrder, s)
+		}
+	}
+	for _, s := range mergeSlots(oldOrder, newOrder) {
+		o, inOld := oldUses[s]
+		n, inNew := newUses[s]
+		switch {
+		case !inOld:
+			d.add(ChangeModified, subject, fmt.Sprintf("now uses %s", s), true)
+		case !inNew:
+			d.add(ChangeModified, subject, fmt.Sprintf("no longer uses %s", s), true)
+		case o.Access != n.Access:
+			d.add(ChangeModified, subject, fmt.Sprintf("access to %s %s -> %s", s, o.Access, n.Access),
+				types[s].Type == BindingStorageTexture)
+		}
+	}
+}
+
+// workgroupString spells the workgroup size of an entry point, naming the
+// override that sizes an axis in place of its size.
+func workgroupString(e EntryPointInfo) string {
+	if e.WorkgroupSize == nil {
+		return "none"
+	}
+	axes := make([]string, 3)
+	for i, size := range e.WorkgroupSize {
+		axes[i] = strconv.FormatUint(uint64(size), 10)
+		if i < len(e.WorkgroupSizeOverrides) && e.WorkgroupSizeOverrides[i] != "" {
+			axes[i] = e.WorkgroupSizeOverrides[i]
+		}
+	}
+	return "(" + strings.Join(axes, ", ") + ")"
+}
+
+func storageString(size *uint32) string {
+	if size == nil {
+		return "none"
+	}
+	return fmt.Sprintf("%d bytes", *size)
+}
+
+func (d *DocumentDiff) vertexInputs(old, new []VertexInputLayout) {
+	oldByName := make(map[string]VertexInputLayout, len(old))
+	newByName := make(map[string]VertexInputLayout, len(new))
+	var oldNames, newNames []string
+	for _, v := range old {
+		oldByName[v.EntryPoint] = v
+		oldNames = append(oldNames, v.EntryPoint)
+	}
+	for _, v := range new {
+		newByName[v.EntryPoint] = v
+		newNames = append(newNames, v.EntryPoint)
+	}
+	// Vertex inputs come and go with their entry points, which are
+	// reported already.
+	for _, name := range sortedNames(oldNames, newNames) {
+		o, inOld := oldByName[name]
+		n, inNew := newByName[name]
+		if !inOld || !inNew {
+			continue
+		}
+		subject := fmt.Sprintf("vertex input %q", name)
+		if len(o.Buffers) != len(n.Buffers) {
+			d.add(ChangeModified, subject, fmt.Sprintf("buffers %d -> %d", len(o.Buffers), len(n.Buffers)), true)
+		}
+		for i := 0; i < len(o.Buffers) && i < len(n.Buffers); i++ {
+			d.vertexBuffer(fmt.Sprintf("%s buffer %d", subject, i), o.Buffers[i], n.Buffers[i])
+		}
+	}
+}
+
+func (d *DocumentDiff) vertexBuffer(subject string, old, new VertexBufferLayout) {
+	if old.ArrayStride != new.ArrayStride {
+		d.add(ChangeModified, subject, fmt.Sprintf("stride %d -> %d", old.ArrayStride, new.ArrayStride), true)
+	}
+	if old.StepMode != new.StepMode {
+		d.add(ChangeModified, subject, fmt.Sprintf("step mode %s -> %s", old.StepMode, new.StepMode), true)
+	}
+	oldAttrs := make(map[uint32]VertexAttribute, len(old.Attributes))
+	newAttrs := make(map[uint32]VertexAttribute, len(new.Attributes))
+	var locations []uint32
+	for _, a := range old.Attributes {
+		oldAttrs[a.ShaderLocation] = a
+		locations = append(locations, a.ShaderLocation)
+	}
+	for _, a := range new.Attributes {
+		newAttrs[a.ShaderLocation] = a
+		if _, ok := oldAttrs[a.ShaderLocation]; !ok {
+			locations = append(locations, a.ShaderLocation)
+		}
+	}
+	sort.Slice(locations, func(i, j int) bool { return locations[i] < locations[j] })
+	for _, loc := range locations {
+		o, inOld := oldAttrs[loc]
+		n, inNew := newAttrs[loc]
+		switch {
+		case !inOld:
+			d.add(ChangeModified, subject, fmt.Sprintf("added @location(%d) %s", loc, n.Format), true)
+		case !inNew:
+			d.add(ChangeModified, subject, fmt.Sprintf("removed @location(%d) %s", loc, o.Format), true)
+		default:
+			if o.Format != n.Format {
+				d.add(ChangeModified, subject, fmt.Sprintf("@location(%d) format %s -> %s", loc, o.Format, n.Format), true)
+			}
+			if o.Offset != n.Offset {
+				d.add(ChangeModified, subject, fmt.Sprintf("@location(%d) offset %d -> %d", loc, o.Offset, n.Offset), true)
+			}
+		}
+	}
+}
+
+func (d *DocumentDiff) structs(old, new []StructLayout) {
+	oldByName := make(map[string]StructLayout, len(old))
+	newByName := make(map[string]StructLayout, len(new))
+	var oldNames, newNames []string
+	for _, s := range old {
+		oldByName[s.Name] = s
+		oldNames = append(oldNames, s.Name)
+	}
+	for _, s := range new {
+		newByName[s.Name] = s
+		newNames = append(newNames, s.Name)
+	}
+	for _, name := range sortedNames(oldNames, newNames) {
+		o, inOld := oldByName[name]
+		n, inNew := newByName[name]
+		subject := fmt.Sprintf("struct %q", name)
+		switch {
+		case !inOld:
+			d.add(ChangeAdded, subject, "", true)
+			continue
+		case !inNew:
+			d.add(ChangeRemoved, subject, "", true)
+			continue
+		}
+		if o.Size != n.Size {
+			d.add(ChangeModified, subject, fmt.Sprintf("size %d -> %d", o.Size, n.Size), true)
+		}
+		if len(o.Members) != len(n.Members) {
+			d.add(ChangeModified, subject, fmt.Sprintf("members %d -> %d", len(o.Members), len(n.Members)), true)
+		}
+		// Members are compared by position, since the host writes them by
+		// offset; a rename alone leaves the layout as it was.
+		for i := 0; i < len(o.Members) && i < len(n.Members); i++ {
+			om, nm := o.Members[i], n.Members[i]
+			if om.Type != nm.Type || om.Offset != nm.Offset || om.Size != nm.Size {
+				d.add(ChangeModified, subject, fmt.Sprintf("member %d %s %s at %d -> %s %s at %d",
+					i, om.Name, om.Type, om.Offset, nm.Name, nm.Type, nm.Offset), true)
+			} else if om.Name != nm.Name {
+				d.add(ChangeModified, subject, fmt.Sprintf("member %d name %q -> %q", i, om.Name, nm.Name), false)
+			}
+		}
+	}
+}
@@ -0,0 +1,137 @@
+// Copyright 2025 The GoGPU Authors
+// SPDX-License-Identifier: MIT
+
+package reflection
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/gogpu/naga/internal/testutil"
+)
+
+func describeWGSL(t *testing.T, src string) *Document {
+	t.Helper()
+	doc, err := Describe(testutil.LowerWGSL(t, src))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return doc
+}
+
+func TestDiffIdentical(t *testing.T) {
+	d := Diff(describeWGSL(t, documentShader), describeWGSL(t, documentShader))
+	if !d.Empty() || d.NeedsPipelineRebuild() || d.String() != "" {
+		t.Errorf("diff of a module with itself = %q, want none", d.String())
+	}
+}
+
+// TestDiffSwapOnly checks that a body edit with renames leaves the
+// layouts alone, so swapping the shader module is enough.
+func TestDiffSwapOnly(t *testing.T) {
+	renamed := strings.NewReplacer(
+		"var<uniform> scene", "var<uniform> frame",
+		"scene.", "frame.",
+		"intensity", "power",
+		"lights[0].color, 1.0", "lights[1].color, 0.5",
+	).Replace(documentShader)
+	d := Diff(describeWGSL(t, documentShader), describeWGSL(t, renamed))
+	want := `~ binding @group(0) @binding(0): name "scene" -> "frame"
+~ struct "Light": member 1 name "intensity" -> "power"
+`
+	if got := d.String(); got != want {
+		t.Errorf("diff:\n%s\nwant:\n%s", got, want)
+	}
+	if d.NeedsPipelineRebuild() {
+		t.Error("renames need a pipeline rebuild, want a module swap")
+	}
+}
+
+func TestDiffRebuild(t *testing.T) {
+	changed := strings.NewReplacer(
+		"count: u32 }", "count: u32, exposure: vec4<f32> }",
+		"@location(1) uv: vec2<f32>", "@location(2) uv: vec3<f32>",
+		"vec3<f32>(uv, 0.0)", "uv",
+		"var<storage, read> particles", "var<storage, read_write> particles",
+		"@fragment", "@group(1) @binding(0) var tex: texture_2d<f32>;\n\n@compute @workgroup_size(8)\nfn cs() { _ = textureLoad(tex, vec2<i32>(0), 0); }\n\n@fragment",
+	).Replace(documentShader)
+	d := Diff(describeWGSL(t, documentShader), describeWGSL(t, changed))
+	want := `~ binding @group(0) @binding(0): min binding size 112 -> 128 [rebuild]
+~ binding @group(0) @binding(1): type read-only-storage-buffer -> storage-buffer [rebuild]
++ binding @group(1) @binding(0) "tex" [rebuild]
++ entry point "cs": compute [rebuild]
+~ vertex input "vs_main" buffer 0: stride 20 -> 24 [rebuild]
+~ vertex input "vs_main" buffer 0: removed @location(1) float32x2 [rebuild]
+~ vertex input "vs_main" buffer 0: added @location(2) float32x3 [rebuild]
+~ struct "Scene": size 112 -> 128 [rebuild]
+~ struct "Scene": members 3 -> 4 [rebuild]
+`
+	if got := d.String(); got != want {
+		t.Errorf("diff:\n%s\nwant:\n%s", got, want)
+	}
+	if !d.NeedsPipelineRebuild() {
+		t.Error("layout changes do not need a pipeline rebuild")
+	}
+}
+
+func TestDiffEntryPointBindings(t *testing.T) {
+	const before = `
+@group(0) @binding(0) var img: texture_storage_2d<rgba8unorm, write>;
+@group(0) @binding(1) var<storage, read_write> buf: array<u32>;
+
+@compute @workgroup_size(8, 8)
+fn main() {
+    textureStore(img, vec2<i32>(0), vec4<f32>(1.0));
+    buf[0] = 1u;
+}
+`
+	after := strings.NewReplacer(
+		"@workgroup_size(8, 8)", "@workgroup_size(16)",
+		"buf[0] = 1u;", "_ = buf[0];",
+	).Replace(before)
+	d := Diff(describeWGSL(t, before), describeWGSL(t, after))
+	want := []Change{
+		{Kind: ChangeModified, Subject: `entry point "main"`, Detail: "workgroup size (8, 8, 1) -> (16, 1, 1)", Rebuild: true},
+		{Kind: ChangeModified, Subject: `entry point "main"`, Detail: "access to @group(0) @binding(1) write -> read", Rebuild: false},
+	}
+	if len(d.Changes) != len(want) {
+		t.Fatalf("changes = %+v, want %+v", d.Changes, want)
+	}
+	for i := range want {
+		if d.Changes[i] != want[i] {
+			t.Errorf("change %d = %+v, want %+v", i, d.Changes[i], want[i])
+		}
+	}
+}
+
+func TestDiffBindingLayoutAndOutputs(t *testing.T) {
+	const before = `
+@group(0) @binding(0) var tex: texture_2d<f32>;
+@group(0) @binding(1) var ms: texture_multisampled_2d<f32>;
+@group(0) @binding(2) var img: texture_storage_2d<rgba8unorm, write>;
+
+@fragment
+fn fs() -> @location(0) vec4<f32> {
+    textureStore(img, vec2<i32>(0), vec4<f32>(1.0));
+    return textureLoad(tex, vec2<i32>(0), 0) + textureLoad(ms, vec2<i32>(0), 0);
+}
+`
+	after := strings.NewReplacer(
+		"texture_2d<f32>", "texture_2d_array<u32>",
+		"textureLoad(tex, vec2<i32>(0), 0)", "vec4<f32>(textureLoad(tex, vec2<i32>(0), 0, 0))",
+		"texture_multisampled_2d<f32>", "texture_2d<f32>",
+		"rgba8unorm", "rgba16float",
+		"-> @location(0) vec4<f32>", "-> @location(1) vec4<f32>",
+	).Replace(before)
+	d := Diff(describeWGSL(t, before), describeWGSL(t, after))
+	want := `~ binding @group(0) @binding(0): view dimension 2d -> 2d-array [rebuild]
+~ binding @group(0) @binding(0): sample type float -> uint [rebuild]
+~ binding @group(0) @binding(1): multisampled true -> false [rebuild]
+~ binding @group(0) @binding(2): storage format rgba8unorm -> rgba16float [rebuild]
+~ entry point "fs": removed output @location(0) vec4<f32> [rebuild]
+~ entry point "fs": added output @location(1) vec4<f32> [rebuild]
+`
+	if got := d.String(); got != want {
+		t.Errorf("diff:\n%s\nwant:\n%s", got, want)
+	}
+}
@@ -0,0 +1,80 @@
+// Copyright 2025 The GoGPU Authors
+// SPDX-License-Identifier: MIT
+
+// Package reflection extracts machine-readable pipeline metadata from a
+// naga IR module.
+//
+// Backends answer "what code do I emit"; this package answers "what does
+// the host need to know to drive the shader". Results are plain structs
+// with JSON tags that follow WebGPU descriptor field names, so they can be
+// fed directly into gogpu pipeline descriptors or serialized by nagac.
+//
+// # Binding Usage
+//
+// Reflect also lists, for each entry point, the bindings it references and
+// whether it reads, writes, or both reads and writes each one, so a
+// renderer can plan barriers between passes without parsing the shader.
+// The analysis is static: a write in a branch that never runs still
+// counts.
+//
+// # Vertex Layouts
+//
+// VertexLayout derives vertex buffer layouts from a vertex entry point's
+// @location inputs:
+//
+//	layout, err := reflection.VertexLayout(module, "vs_main", reflection.VertexLayoutOptions{
+//	    Packing: reflection.PackingInterleaved,
+//	})
+//
+// # Required Features
+//
+// RequiredFeatures lists the optional GPU features (f16, subgroups,
+// extended storage formats, ray queries, multiview, ...) a module's entry
+// points use, with what a given backend target needs for each, so adapter
+// support can be checked before pipelines are created:
+//
+//	reqs, err := reflection.RequiredFeatures(module, reflection.TargetSPIRV)
+//
+// # Compatibility Mode
+//
+// CompatibilityIssues lists what a module uses that WebGPU compatibility
+// mode forbids (sample_mask, linear interpolation, cube array textures,
+// textureLoad on depth textures, ...), so an engine can tell whether one
+// module serves both core and compatibility devices or needs a variant.
+//
+// # Documents
+//
+// Describe gathers entry points, bindings, the vertex layout of every
+// vertex entry point, and the memory layout of buffer structs into one
+// Document. Its JSON form carries a SchemaVersion so tools outside Go can
+// check what they are reading; nagac -reflect writes it.
+//
+// # Hot Reload
+//
+// Diff compares the Documents of two versions of a module and lists the
+// bindings, entry points, vertex inputs and buffer structs that changed.
+// NeedsPipelineRebuild tells an engine reloading a shader whether the new
+// module fits the layouts and resources it already built, so swapping the
+// shader module is enough; nagac -diff prints the result.
+//
+// # Ordering
+//
+// Every slice in a result has a documented, deterministic order that
+// depends only on the module, never on map iteration or on how the
+// compiler numbers handles internally, so JSON output and code generated
+// from it diff cleanly across compiler versions:
+//
+//   - Reflect: entry points in declaration order; bindings, including each
+//     entry point's, by group, then binding, then name.
+//   - VertexLayout: attributes by shader location; buffers by slot.
+//   - RequiredFeatures: by feature, then detail; each requirement's entry
+//     points in declaration order.
+//   - CompatibilityIssues: by restriction, then detail; each issue's entry
+//     points in declaration order.
+//   - Diff: bindings by group, then binding; then entry points, vertex
+//     inputs and structs, each by name.
+//   - Describe: as Reflect; vertex inputs in entry point declaration
+//     order; structs by name, members in declaration order.
+//
+// Changing any of these orders is treated as a breaking change.
+package reflection
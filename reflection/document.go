@@ -0,0 +1,139 @@
+// Copyright 2025 The GoGPU Authors
+// SPDX-License-Identifier: MIT
+
+package reflection
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/gogpu/naga/ir"
+)
+
+// SchemaVersion is the version of the Document JSON schema. It is bumped
+// whenever a field is removed, renamed, or changes meaning; new fields may
+// be added without a bump, so consumers should ignore unknown fields.
+const SchemaVersion = 1
+
+// Document is everything this package reports about a module in one
+// value, as written by nagac -reflect.
+type Document struct {
+	SchemaVersion int              `json:"schemaVersion"`
+	EntryPoints   []EntryPointInfo `json:"entryPoints"`
+	Bindings      []BindingInfo    `json:"bindings"`
+
+	// VertexInputs holds the interleaved vertex layout of each vertex
+	// entry point, in declaration order.
+	VertexInputs []VertexInputLayout `json:"vertexInputs"`
+
+	// Structs holds the memory layout of every struct a uniform, storage,
+	// or push constant global contains, sorted by name.
+	Structs []StructLayout `json:"structs"`
+}
+
+// StructLayout is the host-visible memory layout of a struct.
+type StructLayout struct {
+	Name    string         `json:"name"`
+	Size    uint32         `json:"size"`
+	Members []MemberLayout `json:"members"`
+}
+
+// MemberLayout is one struct member. Type is its WGSL spelling. Size is 0
+// for a runtime-sized array, whose length the buffer binding decides.
+type MemberLayout struct {
+	Name   string `json:"name"`
+	Type   string `json:"type"`
+	Offset uint32 `json:"offset"`
+	Size   uint32 `json:"size"`
+}
+
+// Describe builds the complete reflection Document of module.
+func Describe(module *ir.Module) (*Document, error) {
+	info := Reflect(module)
+	doc := &Document{
+		SchemaVersion: SchemaVersion,
+		EntryPoints:   info.EntryPoints,
+		Bindings:      info.Bindings,
+		VertexInputs:  []VertexInputLayout{},
+		Structs:       []StructLayout{},
+	}
+	for _, ep := range module.EntryPoints {
+		if ep.Stage != ir.StageVertex {
+			continue
+		}
+		layout, err := VertexLayout(module, ep.Name, VertexLayoutOptions{Packing: PackingInterleaved})
+		if err != nil {
+			return nil, err
+		}
+		doc.VertexInputs = append(doc.VertexInputs, *layout)
+	}
+
+	seen := make([]bool, len(module.Types))
+	for _, gv := range module.GlobalVariables {
+		switch gv.Space {
+		case ir.SpaceUniform, ir.SpaceStorage, ir.SpacePushConstant:
+			doc.Structs = appendStructLayouts(doc.Structs, module, gv.Type, seen)
+		}
+	}
+	sort.SliceStable(doc.Structs, func(i, j int) bool { return doc.Structs[i].Name < doc.Structs[j].Name })
+	return doc, nil
+}
+
+// appendStructLayouts appends the layout of h, if it is a struct, and of
+// every struct nested in it that seen does not already mark.
+func appendStructLayouts(out []StructLayout, module *ir.Module, h ir.TypeHandle, seen []bool) []StructLayout {
+	if int(h) >= len(module.Types) || seen[h] {
+		return out
+	}
+	seen[h] = true
+	switch t := module.Types[h].Inner.(type) {
+	case ir.ArrayType:
+		return appendStructLayouts(out, module, t.Base, seen)
+	case ir.StructType:
+		layout := StructLayout{Name: module.Types[h].Name, Size: t.Span, Members: make([]MemberLayout, len(t.Members))}
+		for i, m := range t.Members {
+			size := ir.TypeSize(module, m.Type)
+			if arr, ok := module.Types[m.Type].Inner.(ir.ArrayType); ok && arr.Size.Constant == nil {
+				size = 0
+			}
+			layout.Members[i] = MemberLayout{Name: m.Name, Type: wgslTypeName(module, m.Type), Offset: m.Offset, Size: size}
+			out = appendStructLayouts(out, module, m.Type, seen)
+		}
+		out = append(out, layout)
+	}
+	return out
+}
+
+// wgslTypeName spells a host-shareable type in WGSL syntax.
+func wgslTypeName(module *ir.Module, h ir.TypeHandle) string {
+	ty := module.Types[h]
+	switch t := ty.Inner.(type) {
+	case ir.ScalarType:
+		return wgslScalarName(t)
+	case ir.AtomicType:
+		return "atomic<" + wgslScalarName(t.Scalar) + ">"
+	case ir.VectorType:
+		return fmt.Sprintf("vec%d<%s>", t.Size, wgslScalarName(t.Scalar))
+	case ir.MatrixType:
+		return fmt.Sprintf("mat%dx%d<%s>", t.Columns, t.Rows, wgslScalarName(t.Scalar))
+	case ir.ArrayType:
+		if t.Size.Constant == nil {
+			return "array<" + wgslTypeName(module, t.Base) + ">"
+		}
+		return fmt.Sprintf("array<%s, %d>", wgslTypeName(module, t.Base), *t.Size.Constant)
+	}
+	return ty.Name
+}
+
+func wgslScalarName(s ir.ScalarType) string {
+	switch s.Kind {
+	case ir.ScalarSint:
+		return fmt.Sprintf("i%d", s.Width*8)
+	case ir.ScalarUint:
+		return fmt.Sprintf("u%d", s.Width*8)
+	case ir.ScalarFloat:
+		return fmt.Sprintf("f%d", s.Width*8)
+	default:
+		return "bool"
+	}
+}
@@ -0,0 +1,96 @@
+// Copyright 2025 The GoGPU Authors
+// SPDX-License-Identifier: MIT
+
+package reflection
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/gogpu/naga/internal/testutil"
+)
+
+const documentShader = `
+struct Light { color: vec3<f32>, intensity: f32 }
+struct Scene { view: mat4x4<f32>, lights: array<Light, 2>, count: u32 }
+struct Particles { items: array<vec4<f32>> }
+struct VsOut { @builtin(position) pos: vec4<f32> }
+
+@group(0) @binding(0) var<uniform> scene: Scene;
+@group(0) @binding(1) var<storage, read> particles: Particles;
+
+@vertex
+fn vs_main(@location(0) pos: vec3<f32>, @location(1) uv: vec2<f32>) -> VsOut {
+    return VsOut(scene.view * vec4<f32>(pos + vec3<f32>(uv, 0.0), 1.0) + particles.items[0]);
+}
+
+@fragment
+fn fs_main() -> @location(0) vec4<f32> {
+    return vec4<f32>(scene.lights[0].color, 1.0);
+}
+`
+
+func TestDescribe(t *testing.T) {
+	doc, err := Describe(testutil.LowerWGSL(t, documentShader))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if doc.SchemaVersion != SchemaVersion {
+		t.Errorf("schema version = %d, want %d", doc.SchemaVersion, SchemaVersion)
+	}
+	if len(doc.EntryPoints) != 2 || len(doc.Bindings) != 2 {
+		t.Errorf("got %d entry points and %d bindings, want 2 and 2", len(doc.EntryPoints), len(doc.Bindings))
+	}
+	if len(doc.VertexInputs) != 1 || doc.VertexInputs[0].EntryPoint != "vs_main" || len(doc.VertexInputs[0].Buffers[0].Attributes) != 2 {
+		t.Errorf("vertex inputs = %+v, want the two vs_main attributes", doc.VertexInputs)
+	}
+
+	want := []StructLayout{
+		{Name: "Light", Size: 16, Members: []MemberLayout{
+			{Name: "color", Type: "vec3<f32>", Offset: 0, Size: 12},
+			{Name: "intensity", Type: "f32", Offset: 12, Size: 4},
+		}},
+		{Name: "Particles", Size: 16, Members: []MemberLayout{
+			{Name: "items", Type: "array<vec4<f32>>", Offset: 0, Size: 0},
+		}},
+		{Name: "Scene", Size: 112, Members: []MemberLayout{
+			{Name: "view", Type: "mat4x4<f32>", Offset: 0, Size: 64},
+			{Name: "lights", Type: "array<Light, 2>", Offset: 64, Size: 32},
+			{Name: "count", Type: "u32", Offset: 96, Size: 4},
+		}},
+	}
+	if !reflect.DeepEqual(doc.Structs, want) {
+		t.Errorf("structs =\n%+v\nwant\n%+v", doc.Structs, want)
+	}
+
+	data, err := json.Marshal(doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, key := range []string{`"schemaVersion":1`, `"vertexInputs":`, `"structs":`} {
+		if !strings.Contains(string(data), key) {
+			t.Errorf("JSON is missing %s: %s", key, data)
+		}
+	}
+}
+
+func TestDescribeVertexWithoutInputs(t *testing.T) {
+	doc, err := Describe(testutil.LowerWGSL(t, `
+@vertex
+fn vs_main(@builtin(vertex_index) i: u32) -> @builtin(position) vec4<f32> {
+    return vec4<f32>(f32(i), 0.0, 0.0, 1.0);
+}
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := json.Marshal(doc.VertexInputs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `[{"entryPoint":"vs_main","buffers":[]}]`; string(data) != want {
+		t.Errorf("vertex inputs = %s, want %s", data, want)
+	}
+}
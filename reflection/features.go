@@ -0,0 +1,384 @@
+// Copyright 2025 The GoGPU Authors
+// SPDX-License-Identifier: MIT
+
+package reflection
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/gogpu/naga/ir"
+)
+
+// Feature is an optional GPU capability a shader depends on. Names follow
+// WebGPU GPUFeatureName where one exists.
+type Feature string
+
+// Features reported by RequiredFeatures.
+const (
+	FeatureShaderF16          Feature = "shader-f16"
+	FeatureShaderF64          Feature = "shader-f64"
+	FeatureShaderInt64        Feature = "shader-int64"
+	FeatureSubgroups          Feature = "subgroups"
+	FeatureStorageImageFormat Feature = "storage-image-format"
+	FeatureRayQuery           Feature = "ray-query"
+	FeatureMultiview          Feature = "multiview"
+)
+
+// Target names the backend a feature report is for. The values match
+// naga.Target.String.
+type Target string
+
+// Report targets.
+const (
+	TargetSPIRV Target = "spirv"
+	TargetMSL   Target = "msl"
+	TargetHLSL  Target = "hlsl"
+	TargetGLSL  Target = "glsl"
+)
+
+// FeatureRequirement is one feature a module needs.
+type FeatureRequirement struct {
+	Feature Feature `json:"feature"`
+
+	// Detail narrows the feature, such as the WGSL name of a storage
+	// texture format.
+	Detail string `json:"detail,omitempty"`
+
+	// Requirement is what the target needs for it: a SPIR-V capability,
+	// Metal language version, HLSL shader model, or GLSL extension.
+	Requirement string `json:"requirement"`
+
+	// EntryPoints names, in declaration order, the entry points that use
+	// the feature directly or through the functions they call.
+	EntryPoints []string `json:"entryPoints"`
+}
+
+// RequiredFeatures lists the features module's entry points need on
+// target, sorted by feature and detail. Code no entry point reaches is not
+// counted. Storage texture formats are reported only when they are outside
+// the set every Vulkan and D3D12 device supports.
+func RequiredFeatures(module *ir.Module, target Target) ([]FeatureRequirement, error) {
+	switch target {
+	case TargetSPIRV, TargetMSL, TargetHLSL, TargetGLSL:
+	default:
+		return nil, fmt.Errorf("reflection: unknown target %q", target)
+	}
+
+	found := make(map[featureKey]*FeatureRequirement)
+	for i := range module.EntryPoints {
+		c := &featureCollector{module: module, used: make(map[featureKey]bool), seenFns: make([]bool, len(module.Functions))}
+		c.entryPoint(i)
+		for k := range c.used {
+			r := found[k]
+			if r == nil {
+				r = &FeatureRequirement{
+					Feature:     k.feature,
+					Detail:      k.detail,
+					Requirement: featureRequirement(k.feature, k.detail, target),
+					EntryPoints: []string{},
+				}
+				found[k] = r
+			}
+			r.EntryPoints = append(r.EntryPoints, module.EntryPoints[i].Name)
+		}
+	}
+
+	reqs := make([]FeatureRequirement, 0, len(found))
+	for _, r := range found {
+		reqs = append(reqs, *r)
+	}
+	sort.Slice(reqs, func(i, j int) bool {
+		if reqs[i].Feature != reqs[j].Feature {
+			return reqs[i].Feature < reqs[j].Feature
+		}
+		return reqs[i].Detail < reqs[j].Detail
+	})
+	return reqs, nil
+}
+
+type featureKey struct {
+	feature Feature
+	detail  string
+}
+
+// featureCollector gathers the features one entry point reaches.
+type featureCollector struct {
+	module  *ir.Module
+	used    map[featureKey]bool
+	seenFns []bool
+}
+
+func (c *featureCollector) add(f Feature, detail string) {
+	c.used[featureKey{f, detail}] = true
+}
+
+func (c *featureCollector) entryPoint(index int) {
+	ep := &c.module.EntryPoints[index]
+	for _, h := range ir.EntryPointGlobals(c.module, index) {
+		c.typeHandle(c.module.GlobalVariables[h].Type, nil)
+	}
+	c.function(&ep.Function)
+}
+
+func (c *featureCollector) function(fn *ir.Function) {
+	for _, arg := range fn.Arguments {
+		c.binding(arg.Binding)
+		c.typeHandle(arg.Type, nil)
+	}
+	if fn.Result != nil {
+		c.binding(fn.Result.Binding)
+		c.typeHandle(fn.Result.Type, nil)
+	}
+	for _, lv := range fn.LocalVars {
+		c.typeHandle(lv.Type, nil)
+	}
+	for h := range fn.Expressions {
+		res, err := expressionType(c.module, fn, ir.ExpressionHandle(h))
+		if err != nil {
+			continue
+		}
+		if res.Handle != nil {
+			c.typeHandle(*res.Handle, nil)
+		} else if res.Value != nil {
+			c.typeInner(res.Value, nil)
+		}
+	}
+	c.block(fn.Body)
+}
+
+func (c *featureCollector) block(block ir.Block) {
+	for _, stmt := range block {
+		switch s := stmt.Kind.(type) {
+		case ir.StmtBlock:
+			c.block(s.Block)
+		case ir.StmtIf:
+			c.block(s.Accept)
+			c.block(s.Reject)
+		case ir.StmtSwitch:
+			for _, cs := range s.Cases {
+				c.block(cs.Body)
+			}
+		case ir.StmtLoop:
+			c.block(s.Body)
+			c.block(s.Continuing)
+		case ir.StmtCall:
+			if int(s.Function) < len(c.seenFns) && !c.seenFns[s.Function] {
+				c.seenFns[s.Function] = true
+				c.function(&c.module.Functions[s.Function])
+			}
+		case ir.StmtSubgroupBallot, ir.StmtSubgroupCollectiveOperation, ir.StmtSubgroupGather:
+			c.add(FeatureSubgroups, "")
+		case ir.StmtBarrier:
+			if s.Flags&ir.BarrierSubGroup != 0 {
+				c.add(FeatureSubgroups, "")
+			}
+		case ir.StmtRayQuery:
+			c.add(FeatureRayQuery, "")
+		}
+	}
+}
+
+func (c *featureCollector) binding(b *ir.Binding) {
+	if b == nil {
+		return
+	}
+	builtin, ok := (*b).(ir.BuiltinBinding)
+	if !ok {
+		return
+	}
+	switch builtin.Builtin {
+	case ir.BuiltinViewIndex:
+		c.add(FeatureMultiview, "")
+	case ir.BuiltinNumSubgroups, ir.BuiltinSubgroupID, ir.BuiltinSubgroupSize, ir.BuiltinSubgroupInvocationID:
+		c.add(FeatureSubgroups, "")
+	}
+}
+
+// typeHandle records the features of a type. seen guards recursion
+// through struct members and arrays.
+func (c *featureCollector) typeHandle(h ir.TypeHandle, seen []bool) {
+	if int(h) >= len(c.module.Types) {
+		return
+	}
+	if seen == nil {
+		seen = make([]bool, len(c.module.Types))
+	}
+	if seen[h] {
+		return
+	}
+	seen[h] = true
+	c.typeInner(c.module.Types[h].Inner, seen)
+}
+
+func (c *featureCollector) typeInner(inner ir.TypeInner, seen []bool) {
+	switch t := inner.(type) {
+	case ir.ScalarType:
+		c.scalar(t)
+	case ir.VectorType:
+		c.scalar(t.Scalar)
+	case ir.MatrixType:
+		c.scalar(t.Scalar)
+	case ir.AtomicType:
+		c.scalar(t.Scalar)
+	case ir.PointerType:
+		c.typeHandle(t.Base, seen)
+	case ir.ValuePointerType:
+		c.scalar(t.Scalar)
+	case ir.ArrayType:
+		c.typeHandle(t.Base, seen)
+	case ir.BindingArrayType:
+		c.typeHandle(t.Base, seen)
+	case ir.StructType:
+		for _, m := range t.Members {
+			c.binding(m.Binding)
+			c.typeHandle(m.Type, seen)
+		}
+	case ir.ImageType:
+		if t.Class == ir.ImageClassStorage && !basicStorageFormat(t.StorageFormat) {
+			c.add(FeatureStorageImageFormat, storageFormatNames[t.StorageFormat])
+		}
+	case ir.RayQueryType, ir.AccelerationStructureType:
+		c.add(FeatureRayQuery, "")
+	}
+}
+
+func (c *featureCollector) scalar(s ir.ScalarType) {
+	switch {
+	case s.Kind == ir.ScalarFloat && s.Width == 2:
+		c.add(FeatureShaderF16, "")
+	case s.Kind == ir.ScalarFloat && s.Width == 8:
+		c.add(FeatureShaderF64, "")
+	case (s.Kind == ir.ScalarSint || s.Kind == ir.ScalarUint) && s.Width == 8:
+		c.add(FeatureShaderInt64, "")
+	}
+}
+
+// expressionType returns the lowered type of an expression, resolving it
+// when the module's type table has no entry for it.
+func expressionType(module *ir.Module, fn *ir.Function, h ir.ExpressionHandle) (ir.TypeResolution, error) {
+	return ir.ExpressionType(module, fn, h)
+}
+
+// basicStorageFormat reports whether a storage format is usable without
+// SPIR-V StorageImageExtendedFormats (the Vulkan and D3D12 baseline).
+func basicStorageFormat(f ir.StorageFormat) bool {
+	switch f {
+	case ir.StorageFormatRgba32Float, ir.StorageFormatRgba16Float, ir.StorageFormatR32Float,
+		ir.StorageFormatRgba8Unorm, ir.StorageFormatRgba8Snorm,
+		ir.StorageFormatRgba32Sint, ir.StorageFormatRgba16Sint, ir.StorageFormatRgba8Sint, ir.StorageFormatR32Sint,
+		ir.StorageFormatRgba32Uint, ir.StorageFormatRgba16Uint, ir.StorageFormatRgba8Uint, ir.StorageFormatR32Uint:
+		return true
+	}
+	return false
+}
+
+// featureRequirement describes what target needs to support a feature.
+func featureRequirement(f Feature, detail string, target Target) string {
+	switch f {
+	case FeatureShaderF16:
+		return map[Target]string{
+			TargetSPIRV: "capability Float16, StorageBuffer16BitAccess",
+			TargetMSL:   "half (all Metal versions)",
+			TargetHLSL:  "shader model 6.2 with -enable-16bit-types",
+			TargetGLSL:  "GL_EXT_shader_explicit_arithmetic_types_float16",
+		}[target]
+	case FeatureShaderF64:
+		return map[Target]string{
+			TargetSPIRV: "capability Float64",
+			TargetMSL:   "unsupported",
+			TargetHLSL:  "shader model 5.0 double precision",
+			TargetGLSL:  "GLSL 4.00 or GL_ARB_gpu_shader_fp64",
+		}[target]
+	case FeatureShaderInt64:
+		return map[Target]string{
+			TargetSPIRV: "capability Int64",
+			TargetMSL:   "Metal 2.3",
+			TargetHLSL:  "shader model 6.0",
+			TargetGLSL:  "GL_ARB_gpu_shader_int64",
+		}[target]
+	case FeatureSubgroups:
+		return map[Target]string{
+			TargetSPIRV: "SPIR-V 1.3, capability GroupNonUniform",
+			TargetMSL:   "Metal 2.1 SIMD-group functions",
+			TargetHLSL:  "shader model 6.0 wave intrinsics",
+			TargetGLSL:  "GL_KHR_shader_subgroup_basic",
+		}[target]
+	case FeatureStorageImageFormat:
+		if detail == "r64uint" || detail == "r64sint" {
+			return map[Target]string{
+				TargetSPIRV: "capability Int64ImageEXT (SPV_EXT_shader_image_int64)",
+				TargetMSL:   "Metal 3.1",
+				TargetHLSL:  "shader model 6.6 64-bit typed resources",
+				TargetGLSL:  "GL_EXT_shader_image_int64",
+			}[target]
+		}
+		return map[Target]string{
+			TargetSPIRV: "capability StorageImageExtendedFormats",
+			TargetMSL:   "read-write texture tier 2",
+			TargetHLSL:  "typed UAV load additional formats",
+			TargetGLSL:  "image format layout qualifier " + detail,
+		}[target]
+	case FeatureRayQuery:
+		return map[Target]string{
+			TargetSPIRV: "capability RayQueryKHR (SPV_KHR_ray_query)",
+			TargetMSL:   "Metal 2.3 intersection queries",
+			TargetHLSL:  "shader model 6.5 inline ray tracing",
+			TargetGLSL:  "GL_EXT_ray_query",
+		}[target]
+	case FeatureMultiview:
+		return map[Target]string{
+			TargetSPIRV: "capability MultiView",
+			TargetMSL:   "amplification with [[amplification_id]]",
+			TargetHLSL:  "shader model 6.1 SV_ViewID",
+			TargetGLSL:  "GL_OVR_multiview2",
+		}[target]
+	}
+	return ""
+}
+
+// storageFormatNames maps storage formats to their WGSL names.
+var storageFormatNames = map[ir.StorageFormat]string{
+	ir.StorageFormatR8Unorm:       "r8unorm",
+	ir.StorageFormatR8Snorm:       "r8snorm",
+	ir.StorageFormatR8Uint:        "r8uint",
+	ir.StorageFormatR8Sint:        "r8sint",
+	ir.StorageFormatR16Uint:       "r16uint",
+	ir.StorageFormatR16Sint:       "r16sint",
+	ir.StorageFormatR16Float:      "r16float",
+	ir.StorageFormatRg8Unorm:      "rg8unorm",
+	ir.StorageFormatRg8Snorm:      "rg8snorm",
+	ir.StorageFormatRg8Uint:       "rg8uint",
+	ir.StorageFormatRg8Sint:       "rg8sint",
+	ir.StorageFormatR32Uint:       "r32uint",
+	ir.StorageFormatR32Sint:       "r32sint",
+	ir.StorageFormatR32Float:      "r32float",
+	ir.StorageFormatRg16Uint:      "rg16uint",
+	ir.StorageFormatRg16Sint:      "rg16sint",
+	ir.StorageFormatRg16Float:     "rg16float",
+	ir.StorageFormatRgba8Unorm:    "rgba8unorm",
+	ir.StorageFormatRgba8Snorm:    "rgba8snorm",
+	ir.StorageFormatRgba8Uint:     "rgba8uint",
+	ir.StorageFormatRgba8Sint:     "rgba8sint",
+	ir.StorageFormatBgra8Unorm:    "bgra8unorm",
+	ir.StorageFormatRgb10a2Uint:   "rgb10a2uint",
+	ir.StorageFormatRgb10a2Unorm:  "rgb10a2unorm",
+	ir.StorageFormatRg11b10Ufloat: "rg11b10ufloat",
+	ir.StorageFormatRg32Uint:      "rg32uint",
+	ir.StorageFormatRg32Sint:      "rg32sint",
+	ir.StorageFormatRg32Float:     "rg32float",
+	ir.StorageFormatRgba16Uint:    "rgba16uint",
+	ir.StorageFormatRgba16Sint:    "rgba16sint",
+	ir.StorageFormatRgba16Float:   "rgba16float",
+	ir.StorageFormatRgba32Uint:    "rgba32uint",
+	ir.StorageFormatRgba32Sint:    "rgba32sint",
+	ir.StorageFormatRgba32Float:   "rgba32float",
+	ir.StorageFormatR16Unorm:      "r16unorm",
+	ir.StorageFormatR16Snorm:      "r16snorm",
+	ir.StorageFormatRg16Unorm:     "rg16unorm",
+	ir.StorageFormatRg16Snorm:     "rg16snorm",
+	ir.StorageFormatRgba16Unorm:   "rgba16unorm",
+	ir.StorageFormatRgba16Snorm:   "rgba16snorm",
+	ir.StorageFormatR64Uint:       "r64uint",
+	ir.StorageFormatR64Sint:       "r64sint",
+}
@@ -0,0 +1,119 @@
+package reflection
+
+import (
+	"os"
+	"reflect"
+	"testing"
+
+	"github.com/gogpu/naga/internal/testutil"
+)
+
+const featureShader = `
+enable f16;
+enable subgroups;
+
+@group(0) @binding(0) var<storage, read_write> data: array<f32>;
+@group(0) @binding(1) var img: texture_storage_2d<rg32float, write>;
+@group(0) @binding(2) var basic: texture_storage_2d<rgba8unorm, write>;
+
+fn half_scale(x: f32) -> f32 {
+    let h: f16 = f16(x);
+    return f32(h * 2.0h);
+}
+
+@compute @workgroup_size(64)
+fn reduce(@builtin(global_invocation_id) id: vec3<u32>) {
+    data[id.x] = subgroupAdd(half_scale(data[id.x]));
+}
+
+@compute @workgroup_size(8, 8)
+fn write_img(@builtin(global_invocation_id) id: vec3<u32>) {
+    textureStore(img, vec2<i32>(id.xy), vec4<f32>(1.0));
+    textureStore(basic, vec2<i32>(id.xy), vec4<f32>(1.0));
+}
+
+@compute @workgroup_size(1)
+fn plain() {
+    data[0] = 1.0;
+}
+
+fn unused_half() -> f16 {
+    return 1.0h;
+}
+`
+
+func TestRequiredFeatures(t *testing.T) {
+	module := testutil.LowerWGSL(t, featureShader)
+	got, err := RequiredFeatures(module, TargetSPIRV)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []FeatureRequirement{
+		{Feature: FeatureShaderF16, Requirement: "capability Float16, StorageBuffer16BitAccess", EntryPoints: []string{"reduce"}},
+		{Feature: FeatureStorageImageFormat, Detail: "rg32float", Requirement: "capability StorageImageExtendedFormats", EntryPoints: []string{"write_img"}},
+		{Feature: FeatureSubgroups, Requirement: "SPIR-V 1.3, capability GroupNonUniform", EntryPoints: []string{"reduce"}},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("RequiredFeatures =\n%+v\nwant\n%+v", got, want)
+	}
+}
+
+func TestRequiredFeaturesTargets(t *testing.T) {
+	module := testutil.LowerWGSL(t, featureShader)
+	for target, want := range map[Target]string{
+		TargetMSL:  "Metal 2.1 SIMD-group functions",
+		TargetHLSL: "shader model 6.0 wave intrinsics",
+		TargetGLSL: "GL_KHR_shader_subgroup_basic",
+	} {
+		reqs, err := RequiredFeatures(module, target)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(reqs) != 3 || reqs[2].Requirement != want {
+			t.Errorf("%s: %+v", target, reqs)
+		}
+	}
+
+	if _, err := RequiredFeatures(module, "dxil"); err == nil {
+		t.Error("expected an error for an unknown target")
+	}
+}
+
+func TestRequiredFeaturesSnapshots(t *testing.T) {
+	tests := []struct {
+		file string
+		want Feature
+	}{
+		{"multiview.wgsl", FeatureMultiview},
+		{"ray-query.wgsl", FeatureRayQuery},
+		{"subgroup-operations.wgsl", FeatureSubgroups},
+	}
+	for _, tt := range tests {
+		t.Run(tt.file, func(t *testing.T) {
+			src, err := os.ReadFile("../snapshot/testdata/in/" + tt.file)
+			if err != nil {
+				t.Fatal(err)
+			}
+			reqs, err := RequiredFeatures(testutil.LowerWGSL(t, string(src)), TargetSPIRV)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, r := range reqs {
+				if r.Feature == tt.want {
+					return
+				}
+			}
+			t.Errorf("%s not reported: %+v", tt.want, reqs)
+		})
+	}
+}
+
+func TestRequiredFeaturesNone(t *testing.T) {
+	reqs, err := RequiredFeatures(testutil.LowerWGSL(t, resourceShader), TargetSPIRV)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(reqs) != 0 {
+		t.Errorf("got %+v, want no features", reqs)
+	}
+}
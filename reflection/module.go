@@ -0,0 +1,351 @@
+// Copyright 2025 The GoGPU Authors
+// SPDX-License-Identifier: MIT
+
+package reflection
+
+import (
+	"sort"
+
+	"github.com/gogpu/naga/ir"
+)
+
+// BindingType classifies a resource binding the way WebGPU bind group
+// layout entries do.
+type BindingType string
+
+// Binding types reported by Reflect.
+const (
+	BindingUniformBuffer         BindingType = "uniform-buffer"
+	BindingStorageBuffer         BindingType = "storage-buffer"
+	BindingReadOnlyStorageBuffer BindingType = "read-only-storage-buffer"
+	BindingSampler               BindingType = "sampler"
+	BindingComparisonSampler     BindingType = "comparison-sampler"
+	BindingTexture               BindingType = "texture"
+	BindingDepthTexture          BindingType = "depth-texture"
+	BindingStorageTexture        BindingType = "storage-texture"
+	BindingExternalTexture       BindingType = "external-texture"
+	BindingAccelerationStructure BindingType = "acceleration-structure"
+)
+
+// EntryPointInfo describes one entry point.
+type EntryPointInfo struct {
+	Name  string `json:"name"`
+	Stage string `json:"stage"`
+
+	// WorkgroupSize is set for compute entry points. An axis sized by an
+	// override is 0: its size is only known once the pipeline sets the
+	// override named in WorkgroupSizeOverrides.
+	WorkgroupSize *[3]uint32 `json:"workgroupSize,omitempty"`
+
+	// WorkgroupSizeOverrides is set when overrides size the workgroup: the
+	// name of the override sizing each axis, or "" for an axis of constant
+	// size.
+	WorkgroupSizeOverrides []string `json:"workgroupSizeOverrides,omitempty"`
+
+	// WorkgroupStorageSize is set for compute entry points: the bytes of
+	// var<workgroup> memory they use, each variable rounded up to 16
+	// bytes as WebGPU counts it against maxComputeWorkgroupStorageSize.
+	WorkgroupStorageSize *uint32 `json:"workgroupStorageSize,omitempty"`
+
+	// StackEstimate approximates, in bytes, the stack the entry point
+	// needs for its local variables and the deepest chain of calls it
+	// makes (see ir.EntryPointStackEstimate). It matters on targets with
+	// small GPU stacks that do not inline every call.
+	StackEstimate uint32 `json:"stackEstimate"`
+
+	// Bindings are the resource bindings the entry point references,
+	// directly or through the functions it calls, in the order of
+	// ModuleInfo.Bindings.
+	Bindings []BindingUse `json:"bindings"`
+
+	// Outputs are the @location results of a fragment entry point, by
+	// location; the render pipeline's color targets must match them.
+	Outputs []FragmentOutput `json:"outputs,omitempty"`
+}
+
+// FragmentOutput is one @location result of a fragment entry point. Type
+// is its WGSL spelling.
+type FragmentOutput struct {
+	Location uint32  `json:"location"`
+	BlendSrc *uint32 `json:"blendSrc,omitempty"`
+	Type     string  `json:"type"`
+}
+
+// BindingAccess is how an entry point accesses a binding's contents.
+type BindingAccess string
+
+// Binding accesses reported by Reflect. A binding is written by stores,
+// texture stores, and atomics, and read by loads, texture samples and
+// loads, and atomics. AccessNone marks a binding referenced without
+// reading or writing its contents, such as a sampler or a buffer whose
+// length alone is queried.
+const (
+	AccessNone      BindingAccess = "none"
+	AccessRead      BindingAccess = "read"
+	AccessWrite     BindingAccess = "write"
+	AccessReadWrite BindingAccess = "read-write"
+)
+
+// BindingUse is one binding an entry point references. Tools planning
+// barriers between passes can tell from Access which resources a pass
+// only reads.
+type BindingUse struct {
+	Group   uint32        `json:"group"`
+	Binding uint32        `json:"binding"`
+	Name    string        `json:"name"`
+	Access  BindingAccess `json:"access"`
+}
+
+// BindingInfo describes one @group/@binding resource. Count is set for
+// binding arrays with a fixed size.
+type BindingInfo struct {
+	Group   uint32      `json:"group"`
+	Binding uint32      `json:"binding"`
+	Name    string      `json:"name"`
+	Type    BindingType `json:"type"`
+	Count   *uint32     `json:"count,omitempty"`
+
+	// ViewDimension is the texture view dimension of a texture or storage
+	// texture binding: "1d", "2d", "2d-array", "cube", "cube-array" or "3d".
+	ViewDimension string `json:"viewDimension,omitempty"`
+
+	// SampleType is the sample type of a texture binding: "float", "sint",
+	// "uint" or "depth".
+	SampleType string `json:"sampleType,omitempty"`
+
+	// StorageFormat is the WGSL texel format of a storage texture.
+	StorageFormat string `json:"storageFormat,omitempty"`
+
+	// Multisampled is set for multisampled textures.
+	Multisampled bool `json:"multisampled,omitempty"`
+
+	// MinBindingSize is the smallest buffer a uniform or storage binding
+	// accepts, counting one element of a runtime-sized array.
+	MinBindingSize uint32 `json:"minBindingSize,omitempty"`
+}
+
+// ModuleInfo is the host-facing summary of a module. Its slices are in
+// the stable order documented on the package: entry points in declaration
+// order, bindings by group, binding, and name.
+type ModuleInfo struct {
+	EntryPoints []EntryPointInfo `json:"entryPoints"`
+	Bindings    []BindingInfo    `json:"bindings"`
+}
+
+// Reflect summarizes module's entry points, in declaration order, and
+// resource bindings, ordered by group, then binding, then name. Globals
+// that share a slot (typically used by different entry points) are thus
+// listed in a fixed order regardless of where they are declared.
+func Reflect(module *ir.Module) *ModuleInfo {
+	info := &ModuleInfo{
+		EntryPoints: []EntryPointInfo{},
+		Bindings:    []BindingInfo{},
+	}
+	for i, ep := range module.EntryPoints {
+		e := EntryPointInfo{
+			Name:          ep.Name,
+			Stage:         stageName(ep.Stage),
+			StackEstimate: ir.EntryPointStackEstimate(module, i),
+			Bindings:      bindingUses(module, i),
+		}
+		if ep.Stage == ir.StageCompute {
+			size := ep.Workgroup
+			for axis, o := range ep.WorkgroupOverrides {
+				if o == nil {
+					continue
+				}
+				if e.WorkgroupSizeOverrides == nil {
+					e.WorkgroupSizeOverrides = make([]string, 3)
+				}
+				e.WorkgroupSizeOverrides[axis] = module.Overrides[*o].Name
+				size[axis] = 0
+			}
+			e.WorkgroupSize = &size
+			_, storage := ir.EntryPointWorkgroupStorage(module, i)
+			e.WorkgroupStorageSize = &storage
+		}
+		if ep.Stage == ir.StageFragment {
+			e.Outputs = fragmentOutputs(module, ep.Function.Result)
+		}
+		info.EntryPoints = append(info.EntryPoints, e)
+	}
+	for _, gv := range module.GlobalVariables {
+		if gv.Binding == nil {
+			continue
+		}
+		b := BindingInfo{Group: gv.Binding.Group, Binding: gv.Binding.Binding, Name: gv.Name}
+		inner := module.Types[gv.Type].Inner
+		if arr, ok := inner.(ir.BindingArrayType); ok {
+			b.Count = arr.Size
+			inner = module.Types[arr.Base].Inner
+		}
+		b.Type = bindingType(gv, inner)
+		describeBinding(&b, module, gv, inner)
+		info.Bindings = append(info.Bindings, b)
+	}
+	sort.SliceStable(info.Bindings, func(i, j int) bool {
+		a, b := info.Bindings[i], info.Bindings[j]
+		return bindingLess(a.Group, a.Binding, a.Name, b.Group, b.Binding, b.Name)
+	})
+	return info
+}
+
+// bindingUses lists the bound globals entry point ep references.
+func bindingUses(module *ir.Module, ep int) []BindingUse {
+	uses := []BindingUse{}
+	for _, u := range ir.EntryPointGlobalUses(module, ep) {
+		gv := module.GlobalVariables[u.Global]
+		if gv.Binding == nil {
+			continue
+		}
+		uses = append(uses, BindingUse{
+			Group:   gv.Binding.Group,
+			Binding: gv.Binding.Binding,
+			Name:    gv.Name,
+			Access:  BindingAccess(u.Use.String()),
+		})
+	}
+	sort.SliceStable(uses, func(i, j int) bool {
+		a, b := uses[i], uses[j]
+		return bindingLess(a.Group, a.Binding, a.Name, b.Group, b.Binding, b.Name)
+	})
+	return uses
+}
+
+// bindingLess orders bindings by group, then binding, then name.
+func bindingLess(ag, ab uint32, an string, bg, bb uint32, bn string) bool {
+	if ag != bg {
+		return ag < bg
+	}
+	if ab != bb {
+		return ab < bb
+	}
+	return an < bn
+}
+
+func bindingType(gv ir.GlobalVariable, inner ir.TypeInner) BindingType {
+	switch gv.Space {
+	case ir.SpaceUniform:
+		return BindingUniformBuffer
+	case ir.SpaceStorage:
+		if gv.Access == ir.StorageRead {
+			return BindingReadOnlyStorageBuffer
+		}
+		return BindingStorageBuffer
+	}
+	switch t := inner.(type) {
+	case ir.SamplerType:
+		if t.Comparison {
+			return BindingComparisonSampler
+		}
+		return BindingSampler
+	case ir.ImageType:
+		switch t.Class {
+		case ir.ImageClassDepth:
+			return BindingDepthTexture
+		case ir.ImageClassStorage:
+			return BindingStorageTexture
+		case ir.ImageClassExternal:
+			return BindingExternalTexture
+		default:
+			return BindingTexture
+		}
+	case ir.AccelerationStructureType:
+		return BindingAccelerationStructure
+	}
+	return BindingUniformBuffer
+}
+
+// describeBinding fills in the layout details of a binding beyond its
+// type: the view of a texture, or the size of a buffer.
+func describeBinding(b *BindingInfo, module *ir.Module, gv ir.GlobalVariable, inner ir.TypeInner) {
+	switch gv.Space {
+	case ir.SpaceUniform, ir.SpaceStorage:
+		b.MinBindingSize = ir.TypeSize(module, gv.Type)
+		return
+	}
+	img, ok := inner.(ir.ImageType)
+	if !ok || img.Class == ir.ImageClassExternal {
+		return
+	}
+	b.ViewDimension = viewDimension(img)
+	b.Multisampled = img.Multisampled
+	switch img.Class {
+	case ir.ImageClassDepth:
+		b.SampleType = "depth"
+	case ir.ImageClassStorage:
+		b.StorageFormat = storageFormatNames[img.StorageFormat]
+	default:
+		switch img.SampledKind {
+		case ir.ScalarSint:
+			b.SampleType = "sint"
+		case ir.ScalarUint:
+			b.SampleType = "uint"
+		default:
+			b.SampleType = "float"
+		}
+	}
+}
+
+// viewDimension names the texture view dimension of an image type the way
+// WebGPU does.
+func viewDimension(img ir.ImageType) string {
+	var dim string
+	switch img.Dim {
+	case ir.Dim1D:
+		return "1d"
+	case ir.Dim3D:
+		return "3d"
+	case ir.DimCube:
+		dim = "cube"
+	default:
+		dim = "2d"
+	}
+	if img.Arrayed {
+		return dim + "-array"
+	}
+	return dim
+}
+
+// fragmentOutputs lists the @location results of a fragment entry point,
+// by location.
+func fragmentOutputs(module *ir.Module, result *ir.FunctionResult) []FragmentOutput {
+	if result == nil {
+		return nil
+	}
+	var outputs []FragmentOutput
+	add := func(binding *ir.Binding, ty ir.TypeHandle) {
+		if binding == nil {
+			return
+		}
+		if loc, ok := (*binding).(ir.LocationBinding); ok {
+			outputs = append(outputs, FragmentOutput{Location: loc.Location, BlendSrc: loc.BlendSrc, Type: wgslTypeName(module, ty)})
+		}
+	}
+	if st, ok := module.Types[result.Type].Inner.(ir.StructType); ok {
+		for _, m := range st.Members {
+			add(m.Binding, m.Type)
+		}
+	} else {
+		add(result.Binding, result.Type)
+	}
+	sort.SliceStable(outputs, func(i, j int) bool { return outputs[i].Location < outputs[j].Location })
+	return outputs
+}
+
+func stageName(stage ir.ShaderStage) string {
+	switch stage {
+	case ir.StageVertex:
+		return "vertex"
+	case ir.StageFragment:
+		return "fragment"
+	case ir.StageCompute:
+		return "compute"
+	case ir.StageTask:
+		return "task"
+	case ir.StageMesh:
+		return "mesh"
+	default:
+		return "unknown"
+	}
+}
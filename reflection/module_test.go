@@ -0,0 +1,177 @@
+package reflection
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/gogpu/naga/internal/testutil"
+)
+
+const resourceShader = `
+struct Params { scale: f32 }
+@group(1) @binding(0) var<uniform> params: Params;
+@group(0) @binding(2) var<storage, read> input: array<f32>;
+@group(0) @binding(3) var<storage, read_write> output: array<f32>;
+@group(0) @binding(0) var tex: texture_2d<f32>;
+@group(0) @binding(1) var samp: sampler;
+@group(2) @binding(0) var shadow: texture_depth_2d;
+@group(2) @binding(1) var shadow_samp: sampler_comparison;
+@group(2) @binding(2) var img: texture_storage_2d<rgba8unorm, write>;
+
+@compute @workgroup_size(8, 4, 1)
+fn cs_main(@builtin(global_invocation_id) id: vec3<u32>) {
+    output[id.x] = input[id.x] * params.scale;
+    textureStore(img, vec2<i32>(id.xy), vec4<f32>(1.0));
+}
+
+@fragment
+fn fs_main(@location(0) uv: vec2<f32>) -> @location(0) vec4<f32> {
+    let d = textureSampleCompare(shadow, shadow_samp, uv, 0.5);
+    return textureSample(tex, samp, uv) * d;
+}
+`
+
+func TestReflect(t *testing.T) {
+	info := Reflect(testutil.LowerWGSL(t, resourceShader))
+
+	noStorage := uint32(0)
+	wantEPs := []EntryPointInfo{
+		{Name: "cs_main", Stage: "compute", WorkgroupSize: &[3]uint32{8, 4, 1}, WorkgroupStorageSize: &noStorage, Bindings: []BindingUse{
+			{Group: 0, Binding: 2, Name: "input", Access: AccessRead},
+			{Group: 0, Binding: 3, Name: "output", Access: AccessWrite},
+			{Group: 1, Binding: 0, Name: "params", Access: AccessRead},
+			{Group: 2, Binding: 2, Name: "img", Access: AccessWrite},
+		}},
+		{Name: "fs_main", Stage: "fragment", Bindings: []BindingUse{
+			{Group: 0, Binding: 0, Name: "tex", Access: AccessRead},
+			{Group: 0, Binding: 1, Name: "samp", Access: AccessNone},
+			{Group: 2, Binding: 0, Name: "shadow", Access: AccessRead},
+			{Group: 2, Binding: 1, Name: "shadow_samp", Access: AccessNone},
+		}, Outputs: []FragmentOutput{{Location: 0, Type: "vec4<f32>"}}},
+	}
+	if !reflect.DeepEqual(info.EntryPoints, wantEPs) {
+		t.Errorf("entry points = %+v, want %+v", info.EntryPoints, wantEPs)
+	}
+
+	wantBindings := []BindingInfo{
+		{Group: 0, Binding: 0, Name: "tex", Type: BindingTexture, ViewDimension: "2d", SampleType: "float"},
+		{Group: 0, Binding: 1, Name: "samp", Type: BindingSampler},
+		{Group: 0, Binding: 2, Name: "input", Type: BindingReadOnlyStorageBuffer, MinBindingSize: 4},
+		{Group: 0, Binding: 3, Name: "output", Type: BindingStorageBuffer, MinBindingSize: 4},
+		{Group: 1, Binding: 0, Name: "params", Type: BindingUniformBuffer, MinBindingSize: 4},
+		{Group: 2, Binding: 0, Name: "shadow", Type: BindingDepthTexture, ViewDimension: "2d", SampleType: "depth"},
+		{Group: 2, Binding: 1, Name: "shadow_samp", Type: BindingComparisonSampler},
+		{Group: 2, Binding: 2, Name: "img", Type: BindingStorageTexture, ViewDimension: "2d", StorageFormat: "rgba8unorm"},
+	}
+	if !reflect.DeepEqual(info.Bindings, wantBindings) {
+		t.Errorf("bindings =\n%+v\nwant\n%+v", info.Bindings, wantBindings)
+	}
+}
+
+func TestReflectBindingOrder(t *testing.T) {
+	// Two entry points alias the same slot; the order must not depend on
+	// which global is declared first.
+	const src = `
+@group(0) @binding(0) var<storage, read_write> zeta: array<u32>;
+@group(0) @binding(0) var<storage, read_write> alpha: array<f32>;
+@group(1) @binding(0) var<uniform> late: vec4<f32>;
+@group(0) @binding(1) var<uniform> early: vec4<f32>;
+
+@compute @workgroup_size(1)
+fn a() { zeta[0] = u32(early.x); }
+
+@compute @workgroup_size(1)
+fn b() { alpha[0] = late.x; }
+`
+	info := Reflect(testutil.LowerWGSL(t, src))
+	var got []string
+	for _, b := range info.Bindings {
+		got = append(got, b.Name)
+	}
+	want := []string{"alpha", "zeta", "early", "late"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("binding order = %v, want %v", got, want)
+	}
+}
+
+func TestReflectBindingAccess(t *testing.T) {
+	info := Reflect(testutil.LowerWGSL(t, `
+@group(0) @binding(0) var<storage, read_write> counters: array<atomic<u32>>;
+@group(0) @binding(1) var<storage, read_write> data: array<f32>;
+@group(0) @binding(2) var<storage, read_write> sizes: array<u32>;
+
+fn bump(p: ptr<storage, f32, read_write>) {
+    *p = *p + 1.0;
+}
+
+@compute @workgroup_size(1)
+fn main(@builtin(global_invocation_id) id: vec3<u32>) {
+    atomicAdd(&counters[0], 1u);
+    bump(&data[id.x]);
+    _ = arrayLength(&sizes);
+}
+`))
+	want := []BindingUse{
+		{Group: 0, Binding: 0, Name: "counters", Access: AccessReadWrite},
+		{Group: 0, Binding: 1, Name: "data", Access: AccessReadWrite},
+		{Group: 0, Binding: 2, Name: "sizes", Access: AccessNone},
+	}
+	if got := info.EntryPoints[0].Bindings; !reflect.DeepEqual(got, want) {
+		t.Errorf("bindings =\n%+v\nwant\n%+v", got, want)
+	}
+}
+
+func TestReflectWorkgroupStorage(t *testing.T) {
+	info := Reflect(testutil.LowerWGSL(t, `
+var<workgroup> tile: array<f32, 65>;
+var<workgroup> total: atomic<u32>;
+var<workgroup> unused: array<vec4<f32>, 64>;
+
+@compute @workgroup_size(64)
+fn main(@builtin(local_invocation_index) i: u32) {
+    tile[i] = f32(i);
+    atomicAdd(&total, 1u);
+}
+`))
+	// 260 bytes round up to 272, the atomic to 16; unused is not counted.
+	if got := info.EntryPoints[0].WorkgroupStorageSize; got == nil || *got != 288 {
+		t.Errorf("workgroup storage = %v, want 288", got)
+	}
+}
+
+func TestReflectWorkgroupSizeOverrides(t *testing.T) {
+	info := Reflect(testutil.LowerWGSL(t, `
+override block_x: u32 = 16;
+
+@compute @workgroup_size(block_x, 4)
+fn main() {}
+`))
+	ep := info.EntryPoints[0]
+	if ep.WorkgroupSize == nil || *ep.WorkgroupSize != [3]uint32{0, 4, 1} {
+		t.Errorf("workgroup size = %v, want [0 4 1]", ep.WorkgroupSize)
+	}
+	if want := []string{"block_x", "", ""}; !reflect.DeepEqual(ep.WorkgroupSizeOverrides, want) {
+		t.Errorf("workgroup size overrides = %q, want %q", ep.WorkgroupSizeOverrides, want)
+	}
+}
+
+func TestReflectStackEstimate(t *testing.T) {
+	info := Reflect(testutil.LowerWGSL(t, `
+fn blend(a: vec4<f32>, b: vec4<f32>) -> vec4<f32> {
+    var t = a;
+    t = t * b;
+    return t;
+}
+
+@fragment
+fn main() -> @location(0) vec4<f32> {
+    var weights: array<f32, 8>;
+    weights[0] = 1.0;
+    return blend(vec4<f32>(weights[0]), vec4<f32>(1.0));
+}
+`))
+	// 32 bytes of weights, plus blend's two arguments, result, and t.
+	if got := info.EntryPoints[0].StackEstimate; got != 32+64 {
+		t.Errorf("stack estimate = %d, want 96", got)
+	}
+}
@@ -0,0 +1,258 @@
+// Copyright 2025 The GoGPU Authors
+// SPDX-License-Identifier: MIT
+
+package reflection
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/gogpu/naga/ir"
+)
+
+// VertexFormat is a WebGPU GPUVertexFormat name (e.g. "float32x3").
+type VertexFormat string
+
+// Vertex formats produced by VertexLayout. Each shader input type maps to
+// its full-width format; narrower formats (unorm8x4 etc.) are a host-side
+// choice the shader cannot express.
+const (
+	VertexFormatFloat16   VertexFormat = "float16"
+	VertexFormatFloat16x2 VertexFormat = "float16x2"
+	VertexFormatFloat16x4 VertexFormat = "float16x4"
+	VertexFormatFloat32   VertexFormat = "float32"
+	VertexFormatFloat32x2 VertexFormat = "float32x2"
+	VertexFormatFloat32x3 VertexFormat = "float32x3"
+	VertexFormatFloat32x4 VertexFormat = "float32x4"
+	VertexFormatUint32    VertexFormat = "uint32"
+	VertexFormatUint32x2  VertexFormat = "uint32x2"
+	VertexFormatUint32x3  VertexFormat = "uint32x3"
+	VertexFormatUint32x4  VertexFormat = "uint32x4"
+	VertexFormatSint32    VertexFormat = "sint32"
+	VertexFormatSint32x2  VertexFormat = "sint32x2"
+	VertexFormatSint32x3  VertexFormat = "sint32x3"
+	VertexFormatSint32x4  VertexFormat = "sint32x4"
+)
+
+// Size returns the byte size of one element in this format.
+func (f VertexFormat) Size() uint64 {
+	switch f {
+	case VertexFormatFloat16:
+		return 2
+	case VertexFormatFloat16x2, VertexFormatFloat32, VertexFormatUint32, VertexFormatSint32:
+		return 4
+	case VertexFormatFloat16x4, VertexFormatFloat32x2, VertexFormatUint32x2, VertexFormatSint32x2:
+		return 8
+	case VertexFormatFloat32x3, VertexFormatUint32x3, VertexFormatSint32x3:
+		return 12
+	case VertexFormatFloat32x4, VertexFormatUint32x4, VertexFormatSint32x4:
+		return 16
+	default:
+		return 0
+	}
+}
+
+// VertexStepMode is a WebGPU GPUVertexStepMode name.
+type VertexStepMode string
+
+const (
+	// StepVertex advances the buffer once per vertex.
+	StepVertex VertexStepMode = "vertex"
+	// StepInstance advances the buffer once per instance.
+	StepInstance VertexStepMode = "instance"
+)
+
+// Packing selects how vertex attributes are distributed over buffers.
+type Packing uint8
+
+const (
+	// PackingInterleaved places all per-vertex attributes in one buffer
+	// (and all per-instance attributes in a second one), in location order.
+	PackingInterleaved Packing = iota
+
+	// PackingSeparate places every attribute in its own buffer at offset 0.
+	PackingSeparate
+)
+
+// VertexLayoutOptions configures VertexLayout.
+type VertexLayoutOptions struct {
+	// Packing selects interleaved or one-buffer-per-attribute layout.
+	Packing Packing
+
+	// InstanceLocations lists @location values whose attributes step per
+	// instance rather than per vertex.
+	InstanceLocations []uint32
+}
+
+// VertexAttribute mirrors GPUVertexAttribute, plus the WGSL input name.
+type VertexAttribute struct {
+	Name           string       `json:"name"`
+	Format         VertexFormat `json:"format"`
+	Offset         uint64       `json:"offset"`
+	ShaderLocation uint32       `json:"shaderLocation"`
+}
+
+// VertexBufferLayout mirrors GPUVertexBufferLayout.
+type VertexBufferLayout struct {
+	ArrayStride uint64            `json:"arrayStride"`
+	StepMode    VertexStepMode    `json:"stepMode"`
+	Attributes  []VertexAttribute `json:"attributes"`
+}
+
+// VertexInputLayout is the complete vertex input description of one
+// vertex entry point. Buffers are in slot order.
+type VertexInputLayout struct {
+	EntryPoint string               `json:"entryPoint"`
+	Buffers    []VertexBufferLayout `json:"buffers"`
+}
+
+// VertexLayout derives vertex buffer layouts from the @location inputs of
+// the named vertex entry point. Inputs declared directly as arguments and
+// inputs declared as members of struct arguments are both collected;
+// builtins are ignored.
+//
+// Offsets honor the WebGPU rule that an attribute offset is a multiple of
+// min(4, format size), and every stride is rounded up to a multiple of 4.
+func VertexLayout(module *ir.Module, entryPoint string, opts VertexLayoutOptions) (*VertexInputLayout, error) {
+	var ep *ir.EntryPoint
+	for i := range module.EntryPoints {
+		if module.EntryPoints[i].Name == entryPoint {
+			ep = &module.EntryPoints[i]
+			break
+		}
+	}
+	if ep == nil {
+		return nil, fmt.Errorf("reflection: entry point %q not found", entryPoint)
+	}
+	if ep.Stage != ir.StageVertex {
+		return nil, fmt.Errorf("reflection: entry point %q is not a vertex shader", entryPoint)
+	}
+
+	attrs, err := vertexAttributes(module, &ep.Function)
+	if err != nil {
+		return nil, fmt.Errorf("reflection: entry point %q: %w", entryPoint, err)
+	}
+
+	instanced := make(map[uint32]bool, len(opts.InstanceLocations))
+	for _, loc := range opts.InstanceLocations {
+		instanced[loc] = true
+	}
+	stepOf := func(a VertexAttribute) VertexStepMode {
+		if instanced[a.ShaderLocation] {
+			return StepInstance
+		}
+		return StepVertex
+	}
+
+	layout := &VertexInputLayout{EntryPoint: entryPoint, Buffers: []VertexBufferLayout{}}
+	switch opts.Packing {
+	case PackingSeparate:
+		for _, a := range attrs {
+			layout.Buffers = append(layout.Buffers, VertexBufferLayout{
+				ArrayStride: alignUp(a.Format.Size(), 4),
+				StepMode:    stepOf(a),
+				Attributes:  []VertexAttribute{a},
+			})
+		}
+	case PackingInterleaved:
+		for _, mode := range []VertexStepMode{StepVertex, StepInstance} {
+			var buf VertexBufferLayout
+			buf.StepMode = mode
+			for _, a := range attrs {
+				if stepOf(a) != mode {
+					continue
+				}
+				size := a.Format.Size()
+				a.Offset = alignUp(buf.ArrayStride, min(size, 4))
+				buf.ArrayStride = a.Offset + size
+				buf.Attributes = append(buf.Attributes, a)
+			}
+			if len(buf.Attributes) == 0 {
+				continue
+			}
+			buf.ArrayStride = alignUp(buf.ArrayStride, 4)
+			layout.Buffers = append(layout.Buffers, buf)
+		}
+	default:
+		return nil, fmt.Errorf("reflection: unknown packing %d", opts.Packing)
+	}
+	return layout, nil
+}
+
+// vertexAttributes collects the location-bound inputs of fn sorted by
+// location, with formats resolved and offsets left at zero.
+func vertexAttributes(module *ir.Module, fn *ir.Function) ([]VertexAttribute, error) {
+	var attrs []VertexAttribute
+	add := func(name string, binding ir.Binding, ty ir.TypeHandle) error {
+		loc, ok := binding.(ir.LocationBinding)
+		if !ok {
+			return nil
+		}
+		format, err := vertexFormat(module, ty)
+		if err != nil {
+			return fmt.Errorf("input %q at location %d: %w", name, loc.Location, err)
+		}
+		attrs = append(attrs, VertexAttribute{Name: name, Format: format, ShaderLocation: loc.Location})
+		return nil
+	}
+
+	for _, arg := range fn.Arguments {
+		if arg.Binding != nil {
+			if err := add(arg.Name, *arg.Binding, arg.Type); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		st, ok := module.Types[arg.Type].Inner.(ir.StructType)
+		if !ok {
+			continue
+		}
+		for _, m := range st.Members {
+			if m.Binding == nil {
+				continue
+			}
+			if err := add(m.Name, *m.Binding, m.Type); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	sort.SliceStable(attrs, func(i, j int) bool { return attrs[i].ShaderLocation < attrs[j].ShaderLocation })
+	return attrs, nil
+}
+
+// vertexFormat maps a shader input type to its natural vertex format.
+func vertexFormat(module *ir.Module, h ir.TypeHandle) (VertexFormat, error) {
+	var (
+		scalar ir.ScalarType
+		size   = 1
+	)
+	switch t := module.Types[h].Inner.(type) {
+	case ir.ScalarType:
+		scalar = t
+	case ir.VectorType:
+		scalar = t.Scalar
+		size = int(t.Size)
+	default:
+		return "", fmt.Errorf("type is not a scalar or vector")
+	}
+
+	switch {
+	case scalar.Kind == ir.ScalarFloat && scalar.Width == 4:
+		return [...]VertexFormat{VertexFormatFloat32, VertexFormatFloat32x2, VertexFormatFloat32x3, VertexFormatFloat32x4}[size-1], nil
+	case scalar.Kind == ir.ScalarUint && scalar.Width == 4:
+		return [...]VertexFormat{VertexFormatUint32, VertexFormatUint32x2, VertexFormatUint32x3, VertexFormatUint32x4}[size-1], nil
+	case scalar.Kind == ir.ScalarSint && scalar.Width == 4:
+		return [...]VertexFormat{VertexFormatSint32, VertexFormatSint32x2, VertexFormatSint32x3, VertexFormatSint32x4}[size-1], nil
+	case scalar.Kind == ir.ScalarFloat && scalar.Width == 2:
+		// WebGPU has no float16x3; a vec3<f16> input reads the first three
+		// components of a float16x4 attribute.
+		return [...]VertexFormat{VertexFormatFloat16, VertexFormatFloat16x2, VertexFormatFloat16x4, VertexFormatFloat16x4}[size-1], nil
+	default:
+		return "", fmt.Errorf("no vertex format for scalar kind %d width %d", scalar.Kind, scalar.Width)
+	}
+}
+
+func alignUp(v, align uint64) uint64 {
+	return (v + align - 1) / align * align
+}
@@ -0,0 +1,139 @@
+package reflection
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/gogpu/naga/internal/testutil"
+)
+
+const vertexShader = `
+struct VertexInput {
+    @location(0) position: vec3<f32>,
+    @location(2) uv: vec2<f32>,
+    @builtin(vertex_index) index: u32,
+}
+
+@vertex
+fn vs_main(
+    input: VertexInput,
+    @location(1) normal: vec3<f32>,
+    @location(3) color: u32,
+    @location(4) offset: vec4<f32>,
+) -> @builtin(position) vec4<f32> {
+    return vec4<f32>(input.position + normal, f32(color)) + offset + vec4<f32>(input.uv, 0.0, 0.0);
+}
+
+@fragment
+fn fs_main() -> @location(0) vec4<f32> {
+    return vec4<f32>(1.0);
+}
+`
+
+func TestVertexLayoutInterleaved(t *testing.T) {
+	module := testutil.LowerWGSL(t, vertexShader)
+	layout, err := VertexLayout(module, "vs_main", VertexLayoutOptions{
+		Packing:           PackingInterleaved,
+		InstanceLocations: []uint32{4},
+	})
+	if err != nil {
+		t.Fatalf("VertexLayout: %v", err)
+	}
+	if len(layout.Buffers) != 2 {
+		t.Fatalf("buffers: got %d, want 2", len(layout.Buffers))
+	}
+
+	perVertex := layout.Buffers[0]
+	if perVertex.StepMode != StepVertex || perVertex.ArrayStride != 36 {
+		t.Errorf("vertex buffer: got stride %d step %s, want 36 vertex", perVertex.ArrayStride, perVertex.StepMode)
+	}
+	want := []VertexAttribute{
+		{Name: "position", Format: VertexFormatFloat32x3, Offset: 0, ShaderLocation: 0},
+		{Name: "normal", Format: VertexFormatFloat32x3, Offset: 12, ShaderLocation: 1},
+		{Name: "uv", Format: VertexFormatFloat32x2, Offset: 24, ShaderLocation: 2},
+		{Name: "color", Format: VertexFormatUint32, Offset: 32, ShaderLocation: 3},
+	}
+	if len(perVertex.Attributes) != len(want) {
+		t.Fatalf("attributes: got %+v, want %+v", perVertex.Attributes, want)
+	}
+	for i := range want {
+		if perVertex.Attributes[i] != want[i] {
+			t.Errorf("attribute %d: got %+v, want %+v", i, perVertex.Attributes[i], want[i])
+		}
+	}
+
+	perInstance := layout.Buffers[1]
+	if perInstance.StepMode != StepInstance || perInstance.ArrayStride != 16 || len(perInstance.Attributes) != 1 {
+		t.Errorf("instance buffer: got %+v", perInstance)
+	}
+}
+
+func TestVertexLayoutSeparate(t *testing.T) {
+	module := testutil.LowerWGSL(t, vertexShader)
+	layout, err := VertexLayout(module, "vs_main", VertexLayoutOptions{Packing: PackingSeparate})
+	if err != nil {
+		t.Fatalf("VertexLayout: %v", err)
+	}
+	if len(layout.Buffers) != 5 {
+		t.Fatalf("buffers: got %d, want 5", len(layout.Buffers))
+	}
+	for i, buf := range layout.Buffers {
+		if len(buf.Attributes) != 1 || buf.Attributes[0].Offset != 0 {
+			t.Errorf("buffer %d: got %+v", i, buf)
+		}
+		if buf.ArrayStride != buf.Attributes[0].Format.Size() {
+			t.Errorf("buffer %d: stride %d, want format size %d", i, buf.ArrayStride, buf.Attributes[0].Format.Size())
+		}
+	}
+}
+
+func TestVertexLayoutJSON(t *testing.T) {
+	module := testutil.LowerWGSL(t, vertexShader)
+	layout, err := VertexLayout(module, "vs_main", VertexLayoutOptions{})
+	if err != nil {
+		t.Fatalf("VertexLayout: %v", err)
+	}
+	data, err := json.Marshal(layout)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{`"arrayStride":52`, `"stepMode":"vertex"`, `"shaderLocation":4`, `"format":"float32x4"`} {
+		if !strings.Contains(string(data), want) {
+			t.Errorf("JSON missing %s: %s", want, data)
+		}
+	}
+}
+
+func TestVertexLayoutErrors(t *testing.T) {
+	module := testutil.LowerWGSL(t, vertexShader)
+	if _, err := VertexLayout(module, "missing", VertexLayoutOptions{}); err == nil {
+		t.Error("expected error for missing entry point")
+	}
+	if _, err := VertexLayout(module, "fs_main", VertexLayoutOptions{}); err == nil {
+		t.Error("expected error for non-vertex entry point")
+	}
+}
+
+func TestVertexLayoutF16Alignment(t *testing.T) {
+	module := testutil.LowerWGSL(t, `
+enable f16;
+
+@vertex
+fn main(@location(0) a: f16, @location(1) b: vec2<f16>) -> @builtin(position) vec4<f32> {
+    return vec4<f32>(f32(a) + f32(b.x));
+}
+`)
+	layout, err := VertexLayout(module, "main", VertexLayoutOptions{})
+	if err != nil {
+		t.Fatalf("VertexLayout: %v", err)
+	}
+	attrs := layout.Buffers[0].Attributes
+	if attrs[0].Format != VertexFormatFloat16 || attrs[1].Format != VertexFormatFloat16x2 {
+		t.Fatalf("formats: got %s, %s", attrs[0].Format, attrs[1].Format)
+	}
+	// float16x2 needs 4-byte alignment, so it skips past the 2-byte float16.
+	if attrs[1].Offset != 4 || layout.Buffers[0].ArrayStride != 8 {
+		t.Errorf("got offset %d stride %d, want 4 and 8", attrs[1].Offset, layout.Buffers[0].ArrayStride)
+	}
+}
@@ -0,0 +1,57 @@
+#!/usr/bin/env bash
+# Compare compiler benchmarks between two git revisions.
+#
+# Runs the root package benchmarks (tokenize, per-stage, batch) on both
+# revisions in temporary worktrees and prints a benchstat comparison when
+# benchstat is installed, or both raw results otherwise.
+#
+# Usage:
+#   bash scripts/bench-compare.sh <old-rev> [new-rev]      # new-rev defaults to HEAD
+#
+# Environment:
+#   BENCH   benchmark regexp (default: .)
+#   COUNT   runs per benchmark, for benchstat statistics (default: 6)
+#
+# Install benchstat with:
+#   go install golang.org/x/perf/cmd/benchstat@latest
+
+set -euo pipefail
+
+if [[ $# -lt 1 ]]; then
+    echo "usage: $0 <old-rev> [new-rev]" >&2
+    exit 2
+fi
+
+OLD_REV="$1"
+NEW_REV="${2:-HEAD}"
+BENCH="${BENCH:-.}"
+COUNT="${COUNT:-6}"
+
+ROOT="$(git rev-parse --show-toplevel)"
+WORK="$(mktemp -d)"
+cleanup() {
+    git -C "$ROOT" worktree remove --force "$WORK/old" >/dev/null 2>&1 || true
+    git -C "$ROOT" worktree remove --force "$WORK/new" >/dev/null 2>&1 || true
+    rm -rf "$WORK"
+}
+trap cleanup EXIT
+
+run_bench() {
+    local rev="$1" dir="$2" out="$3"
+    git -C "$ROOT" worktree add --detach --quiet "$dir" "$rev"
+    echo "Benchmarking $rev ($(git -C "$dir" rev-parse --short HEAD))..." >&2
+    (cd "$dir" && go test -run '^$' -bench "$BENCH" -benchmem -count "$COUNT" .) > "$out"
+}
+
+run_bench "$OLD_REV" "$WORK/old" "$WORK/old.txt"
+run_bench "$NEW_REV" "$WORK/new" "$WORK/new.txt"
+
+if command -v benchstat >/dev/null 2>&1; then
+    benchstat "$WORK/old.txt" "$WORK/new.txt"
+else
+    echo "benchstat not found; raw results follow." >&2
+    echo "== $OLD_REV"
+    cat "$WORK/old.txt"
+    echo "== $NEW_REV"
+    cat "$WORK/new.txt"
+fi
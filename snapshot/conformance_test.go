@@ -0,0 +1,219 @@
+package snapshot_test
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/gogpu/naga/glsl"
+	"github.com/gogpu/naga/hlsl"
+	"github.com/gogpu/naga/ir"
+	"github.com/gogpu/naga/msl"
+	"github.com/gogpu/naga/spirv"
+	"github.com/gogpu/naga/wgsl"
+)
+
+// ---------------------------------------------------------------------------
+// Conformance Matrix
+// ---------------------------------------------------------------------------
+
+// conformanceDir holds a vendored subset of WebGPU CTS shader cases. Each
+// case is a standalone WGSL module reduced from the CTS test group named in
+// its header comment.
+//
+// NAGA_CTS_DIR points the matrix at another corpus, for example shaders
+// extracted from a full CTS checkout:
+//
+//	NAGA_CTS_DIR=/path/to/corpus go test ./snapshot -run TestConformanceMatrix -v
+const conformanceDir = "testdata/cts"
+
+// knownFailuresFile lists expected failures as "<case> <column> <reason>",
+// one per line, with # comments. It lives next to the corpus.
+const knownFailuresFile = "known_failures.txt"
+
+// conformanceColumns are the matrix columns, in pipeline order.
+var conformanceColumns = []string{"lower", "validate", "spv", "msl", "hlsl", "glsl"}
+
+// errBlocked marks a column that could not run because lowering failed.
+var errBlocked = errors.New("blocked: lowering failed")
+
+// TestConformanceMatrix compiles every corpus case through the WGSL
+// frontend, IR validation and all four backends with default options.
+//
+// A failure listed in known_failures.txt is logged; an unlisted failure, or a
+// listed failure that now passes, fails the test so the list only ever
+// shrinks. The per-column pass counts are logged as a summary, which makes
+// conformance progress measurable between releases.
+func TestConformanceMatrix(t *testing.T) {
+	dir := conformanceDir
+	if env := os.Getenv("NAGA_CTS_DIR"); env != "" {
+		dir = env
+	}
+	cases := loadInputShaders(t, dir)
+	if len(cases) == 0 {
+		t.Fatalf("no conformance cases found in %s", dir)
+	}
+	known := loadKnownFailures(t, filepath.Join(dir, knownFailuresFile))
+
+	passed := make(map[string]int, len(conformanceColumns))
+	for i := range cases {
+		c := &cases[i]
+		results := runConformanceCase(c.source)
+		t.Run(c.name, func(t *testing.T) {
+			for _, col := range conformanceColumns {
+				err := results[col]
+				reason, isKnown := known[c.name+" "+col]
+				delete(known, c.name+" "+col)
+				switch {
+				case errors.Is(err, errBlocked):
+					// Reported once, by the lower column.
+				case err == nil && isKnown:
+					t.Errorf("%s: passes but is listed in %s (%s); remove the entry", col, knownFailuresFile, reason)
+				case err == nil:
+					passed[col]++
+				case isKnown:
+					t.Logf("%s: known failure (%s): %v", col, reason, err)
+				default:
+					t.Errorf("%s: %v", col, err)
+				}
+			}
+		})
+	}
+	for key := range known {
+		t.Errorf("%s lists %q, which is not a corpus case", knownFailuresFile, key)
+	}
+
+	var summary strings.Builder
+	fmt.Fprintf(&summary, "conformance matrix (%d cases):", len(cases))
+	for _, col := range conformanceColumns {
+		fmt.Fprintf(&summary, " %s %d/%d", col, passed[col], len(cases))
+	}
+	t.Log(summary.String())
+}
+
+// runConformanceCase compiles source through every matrix column and
+// returns the error of each; nil means the column passed. Panics are
+// reported as errors so one crashing backend does not hide the rest.
+func runConformanceCase(source string) map[string]error {
+	results := make(map[string]error, len(conformanceColumns))
+	module, err := guardConformance(func() (*ir.Module, error) {
+		tokens, err := wgsl.NewLexer(source).Tokenize()
+		if err != nil {
+			return nil, err
+		}
+		ast, err := wgsl.NewParser(tokens).Parse()
+		if err != nil {
+			return nil, err
+		}
+		return wgsl.LowerWithSource(ast, source)
+	})
+	results["lower"] = err
+	if err != nil {
+		for _, col := range conformanceColumns[1:] {
+			results[col] = errBlocked
+		}
+		return results
+	}
+
+	_, results["validate"] = guardConformance(func() (struct{}, error) {
+		errs, err := ir.Validate(module)
+		if err == nil && len(errs) > 0 {
+			err = fmt.Errorf("%d validation errors, first: %v", len(errs), errs[0])
+		}
+		return struct{}{}, err
+	})
+	// Backends see the module as a pipeline would: overrides resolved to
+	// their defaults.
+	if len(module.Overrides) > 0 {
+		module = ir.CloneModuleForOverrides(module)
+		if err := ir.ProcessOverrides(module, nil); err != nil {
+			for _, col := range conformanceColumns[2:] {
+				results[col] = fmt.Errorf("process overrides: %w", err)
+			}
+			return results
+		}
+	}
+	_, results["spv"] = guardConformance(func() ([]byte, error) {
+		return spirv.NewBackend(spirv.DefaultOptions()).Compile(module)
+	})
+	_, results["msl"] = guardConformance(func() (string, error) {
+		code, _, err := msl.Compile(module, msl.DefaultOptions())
+		return code, err
+	})
+	_, results["hlsl"] = guardConformance(func() (string, error) {
+		code, _, err := hlsl.Compile(module, hlsl.DefaultOptions())
+		return code, err
+	})
+	_, results["glsl"] = guardConformance(func() (struct{}, error) {
+		return struct{}{}, compileConformanceGLSL(module)
+	})
+	return results
+}
+
+// compileConformanceGLSL compiles each entry point separately, using
+// GLSL 4.30 for compute and the default version otherwise.
+func compileConformanceGLSL(module *ir.Module) error {
+	for i := range module.EntryPoints {
+		ep := &module.EntryPoints[i]
+		opts := glsl.DefaultOptions()
+		opts.EntryPoint = ep.Name
+		if ep.Stage == ir.StageCompute {
+			opts.LangVersion = glsl.Version430
+		}
+		if _, _, err := glsl.Compile(module, opts); err != nil {
+			return fmt.Errorf("entry point %s: %w", ep.Name, err)
+		}
+	}
+	return nil
+}
+
+// guardConformance runs fn and converts a panic into an error.
+func guardConformance[T any](fn func() (T, error)) (result T, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic: %v", r)
+		}
+	}()
+	return fn()
+}
+
+// loadKnownFailures reads a known failures file. A missing file means no
+// known failures.
+func loadKnownFailures(t *testing.T, path string) map[string]string {
+	t.Helper()
+
+	known := make(map[string]string)
+	f, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return known
+	}
+	if err != nil {
+		t.Fatalf("open %s: %v", path, err)
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		fields := strings.Fields(text)
+		if len(fields) < 3 {
+			t.Fatalf("%s:%d: want \"<case> <column> <reason>\", got %q", path, line, text)
+		}
+		if !slices.Contains(conformanceColumns, fields[1]) {
+			t.Fatalf("%s:%d: unknown column %q", path, line, fields[1])
+		}
+		known[fields[0]+" "+fields[1]] = strings.Join(fields[2:], " ")
+	}
+	if err := scanner.Err(); err != nil {
+		t.Fatalf("read %s: %v", path, err)
+	}
+	return known
+}
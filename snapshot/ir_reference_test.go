@@ -98,6 +98,10 @@ func TestIRReference(t *testing.T) {
 
 			// Global expression count: different const evaluation depth
 			"abstract-types-const": true, // 217 vs 207 GE — different const folding granularity
+
+			// Input differs: quadBroadcast from lane 3, where Rust's input uses
+			// the out-of-range lane 4.
+			"subgroup-operations": true,
 		}
 
 		t.Run(shaderName, func(t *testing.T) {
@@ -192,6 +196,7 @@ func TestIRReferenceCompact(t *testing.T) {
 			"ray-query":              true,
 			"math-functions":         true,
 			"shadow":                 true,
+			"subgroup-operations":    true,
 		}
 
 		t.Run(shaderName, func(t *testing.T) {
@@ -0,0 +1,12 @@
+// CTS: webgpu:shader,execution,expression,binary,bitwise_shift:*
+@group(0) @binding(0) var<storage, read> inputs: array<vec2u>;
+@group(0) @binding(1) var<storage, read_write> outputs: array<vec4u>;
+
+@compute @workgroup_size(1)
+fn main(@builtin(global_invocation_id) id: vec3u) {
+    let i = id.x;
+    let e = inputs[i].x;
+    let s = inputs[i].y % 32u;
+    let si = bitcast<i32>(e);
+    outputs[i] = vec4u(e << s, e >> s, bitcast<u32>(si >> s), (e & 0xffu) | (e ^ s));
+}
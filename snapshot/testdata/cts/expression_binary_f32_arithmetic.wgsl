@@ -0,0 +1,11 @@
+// CTS: webgpu:shader,execution,expression,binary,f32_arithmetic:*
+@group(0) @binding(0) var<storage, read> inputs: array<vec2f>;
+@group(0) @binding(1) var<storage, read_write> outputs: array<vec4f>;
+
+@compute @workgroup_size(1)
+fn main(@builtin(global_invocation_id) id: vec3u) {
+    let i = id.x;
+    let a = inputs[i].x;
+    let b = inputs[i].y;
+    outputs[i] = vec4f(a + b, a - b, a * b, a / b);
+}
@@ -0,0 +1,15 @@
+// CTS: webgpu:shader,execution,expression,call,builtin,{countOneBits,reverseBits,extractBits,insertBits,firstLeadingBit}:*
+@group(0) @binding(0) var<storage, read> inputs: array<u32>;
+@group(0) @binding(1) var<storage, read_write> outputs: array<array<u32, 5>>;
+
+@compute @workgroup_size(1)
+fn main(@builtin(global_invocation_id) id: vec3u) {
+    let e = inputs[id.x];
+    outputs[id.x] = array<u32, 5>(
+        countOneBits(e),
+        reverseBits(e),
+        extractBits(e, 4u, 8u),
+        insertBits(e, 0xau, 4u, 4u),
+        firstLeadingBit(e),
+    );
+}
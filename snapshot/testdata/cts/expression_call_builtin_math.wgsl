@@ -0,0 +1,14 @@
+// CTS: webgpu:shader,execution,expression,call,builtin,{clamp,mix,smoothstep,fma}:*
+@group(0) @binding(0) var<storage, read> inputs: array<vec4f>;
+@group(0) @binding(1) var<storage, read_write> outputs: array<vec4f>;
+
+@compute @workgroup_size(1)
+fn main(@builtin(global_invocation_id) id: vec3u) {
+    let v = inputs[id.x];
+    outputs[id.x] = vec4f(
+        clamp(v.x, v.y, v.z),
+        mix(v.x, v.y, v.w),
+        smoothstep(v.x, v.y, v.z),
+        fma(v.x, v.y, v.z),
+    );
+}
@@ -0,0 +1,15 @@
+// CTS: webgpu:shader,execution,expression,call,builtin,{pack4x8unorm,unpack2x16float,pack4xI8}:*
+@group(0) @binding(0) var<storage, read> inputs: array<vec4f>;
+@group(0) @binding(1) var<storage, read_write> outputs: array<vec4u>;
+
+@compute @workgroup_size(1)
+fn main(@builtin(global_invocation_id) id: vec3u) {
+    let v = inputs[id.x];
+    let h = unpack2x16float(pack2x16float(v.xy));
+    outputs[id.x] = vec4u(
+        pack4x8unorm(v),
+        pack4x8snorm(v),
+        bitcast<u32>(h.x),
+        pack4xI8(vec4i(v)),
+    );
+}
@@ -0,0 +1,18 @@
+// CTS: webgpu:shader,execution,expression,{constant,override}:*
+const SIZE = 4u;
+const SCALE = vec2f(2.0, 0.5);
+const TABLE = array<i32, SIZE>(1, 2, 3, 4);
+
+override threshold: f32 = 0.5;
+@id(7) override enabled: bool = true;
+
+@group(0) @binding(0) var<storage, read_write> outputs: array<f32, SIZE>;
+
+@compute @workgroup_size(SIZE)
+fn main(@builtin(local_invocation_index) li: u32) {
+    var v = f32(TABLE[li]) * SCALE.x * SCALE.y;
+    if enabled && v > threshold {
+        v = -v;
+    }
+    outputs[li] = v;
+}
@@ -0,0 +1,11 @@
+// CTS: webgpu:shader,execution,{expression,call,builtin,derivatives,statement,discard}:*
+@fragment
+fn main(@location(0) v: vec2f) -> @location(0) vec4f {
+    let dx = dpdx(v.x);
+    let dy = dpdyFine(v.y);
+    let w = fwidthCoarse(v);
+    if v.x < 0.0 {
+        discard;
+    }
+    return vec4f(dx, dy, w);
+}
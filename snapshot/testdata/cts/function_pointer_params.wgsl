@@ -0,0 +1,32 @@
+// CTS: webgpu:shader,execution,function,{call,pointer_params}:*
+struct Pair { a: f32, b: f32 }
+
+var<private> global_pair: Pair;
+var<workgroup> shared_value: u32;
+
+fn swap(p: ptr<function, Pair>) {
+    let t = (*p).a;
+    (*p).a = (*p).b;
+    (*p).b = t;
+}
+
+fn bump(p: ptr<private, f32>, by: f32) -> f32 {
+    *p += by;
+    return *p;
+}
+
+fn store_shared(p: ptr<workgroup, u32>, v: u32) {
+    *p = v;
+}
+
+@group(0) @binding(0) var<storage, read_write> outputs: vec4f;
+
+@compute @workgroup_size(1)
+fn main() {
+    var pair = Pair(1.0, 2.0);
+    swap(&pair);
+    let r = bump(&global_pair.b, pair.a);
+    store_shared(&shared_value, 3u);
+    workgroupBarrier();
+    outputs = vec4f(pair.a, pair.b, r, f32(shared_value));
+}
@@ -0,0 +1,24 @@
+// CTS: webgpu:shader,execution,memory_layout:*
+struct Inner {
+    a: vec3f,
+    b: f32,
+}
+
+struct Uniforms {
+    m: mat3x3f,
+    v: vec3f,
+    inner: array<Inner, 2>,
+    @align(16) s: u32,
+    @size(32) t: vec2f,
+}
+
+@group(0) @binding(0) var<uniform> u: Uniforms;
+@group(0) @binding(1) var<storage, read_write> outputs: array<vec4f, 4>;
+
+@compute @workgroup_size(1)
+fn main() {
+    outputs[0] = vec4f(u.m * u.v, u.inner[1].b);
+    outputs[1] = vec4f(u.inner[0].a, f32(u.s));
+    outputs[2] = vec4f(u.t, u.m[2].xy);
+    outputs[3] = vec4f(u.m[1], 0.0);
+}
@@ -0,0 +1,21 @@
+// CTS: webgpu:shader,execution,memory_model,atomicity:*
+struct Counters {
+    total: atomic<u32>,
+    max: atomic<i32>,
+    slots: array<atomic<u32>, 8>,
+}
+
+@group(0) @binding(0) var<storage, read_write> counters: Counters;
+var<workgroup> local_total: atomic<u32>;
+
+@compute @workgroup_size(64)
+fn main(@builtin(local_invocation_index) li: u32) {
+    atomicAdd(&local_total, 1u);
+    atomicMax(&counters.max, i32(li));
+    atomicOr(&counters.slots[li % 8u], 1u << (li / 8u));
+    let swap = atomicCompareExchangeWeak(&counters.slots[0], 0u, li);
+    workgroupBarrier();
+    if li == 0u && !swap.exchanged {
+        atomicAdd(&counters.total, atomicLoad(&local_total));
+    }
+}
@@ -0,0 +1,13 @@
+// CTS: webgpu:shader,execution,robust_access:*
+@group(0) @binding(0) var<storage, read_write> data: array<f32>;
+@group(0) @binding(1) var<uniform> index: vec4i;
+
+@compute @workgroup_size(1)
+fn main() {
+    var local: array<f32, 4>;
+    let n = arrayLength(&data);
+    local[index.x] = data[index.y];
+    data[u32(index.z)] = local[index.w] + f32(n);
+    let m = mat4x4f();
+    data[0] = m[index.x][index.y];
+}
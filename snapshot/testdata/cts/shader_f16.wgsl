@@ -0,0 +1,12 @@
+// CTS: webgpu:shader,execution,expression,binary,f16_arithmetic:*
+enable f16;
+
+@group(0) @binding(0) var<storage, read> inputs: array<vec2h>;
+@group(0) @binding(1) var<storage, read_write> outputs: array<vec4h>;
+
+@compute @workgroup_size(1)
+fn main(@builtin(global_invocation_id) id: vec3u) {
+    let a = inputs[id.x].x;
+    let b = inputs[id.x].y;
+    outputs[id.x] = vec4h(a + b, a - b, a * b, a / b);
+}
@@ -0,0 +1,22 @@
+// CTS: webgpu:shader,execution,shader_io,compute_builtins:*
+struct Out {
+    local_id: vec3u,
+    local_index: u32,
+    global_id: vec3u,
+    group_id: vec3u,
+    num_groups: vec3u,
+}
+
+@group(0) @binding(0) var<storage, read_write> outputs: array<Out>;
+
+@compute @workgroup_size(2, 2, 1)
+fn main(
+    @builtin(local_invocation_id) local_id: vec3u,
+    @builtin(local_invocation_index) local_index: u32,
+    @builtin(global_invocation_id) global_id: vec3u,
+    @builtin(workgroup_id) group_id: vec3u,
+    @builtin(num_workgroups) num_groups: vec3u,
+) {
+    let i = global_id.x + global_id.y * 4u;
+    outputs[i] = Out(local_id, local_index, global_id, group_id, num_groups);
+}
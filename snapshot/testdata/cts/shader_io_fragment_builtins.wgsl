@@ -0,0 +1,15 @@
+// CTS: webgpu:shader,execution,shader_io,fragment_builtins:*
+struct Out {
+    @location(0) color: vec4f,
+    @builtin(sample_mask) mask: u32,
+}
+
+@fragment
+fn main(
+    @builtin(position) pos: vec4f,
+    @builtin(front_facing) front: bool,
+    @builtin(sample_index) sample: u32,
+) -> Out {
+    let side = select(0.0, 1.0, front);
+    return Out(vec4f(pos.xy, side, f32(sample)), 1u << sample);
+}
@@ -0,0 +1,16 @@
+// CTS: webgpu:shader,execution,shader_io,shared_structs:*
+struct Interface {
+    @builtin(position) pos: vec4f,
+    @location(0) value: f32,
+}
+
+@vertex
+fn vs_main(@builtin(vertex_index) vi: u32) -> Interface {
+    let x = f32(vi & 1u) * 2.0 - 1.0;
+    return Interface(vec4f(x, 0.0, 0.0, 1.0), x);
+}
+
+@fragment
+fn fs_main(in: Interface) -> @location(0) vec4f {
+    return vec4f(in.value, in.pos.xy, 1.0);
+}
@@ -0,0 +1,24 @@
+// CTS: webgpu:shader,execution,shader_io,user_io:*
+struct VertexOut {
+    @builtin(position) pos: vec4f,
+    @location(0) color: vec4f,
+    @location(1) @interpolate(flat) id: u32,
+    @location(2) @interpolate(linear, centroid) uv: vec2f,
+    @location(3) @interpolate(perspective, sample) w: f32,
+}
+
+@vertex
+fn vs_main(@builtin(vertex_index) vi: u32, @location(0) position: vec2f) -> VertexOut {
+    var out: VertexOut;
+    out.pos = vec4f(position, 0.0, 1.0);
+    out.color = vec4f(f32(vi));
+    out.id = vi;
+    out.uv = position * 0.5 + 0.5;
+    out.w = 1.0;
+    return out;
+}
+
+@fragment
+fn fs_main(in: VertexOut) -> @location(0) vec4f {
+    return in.color * vec4f(in.uv, in.w, f32(in.id));
+}
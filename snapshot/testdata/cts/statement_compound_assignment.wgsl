@@ -0,0 +1,20 @@
+// CTS: webgpu:shader,execution,statement,compound_assignment:*
+struct Data {
+    v: vec4f,
+    m: mat2x2f,
+    a: array<i32, 4>,
+}
+
+@group(0) @binding(0) var<storage, read_write> data: Data;
+
+fn index() -> u32 { return 1u; }
+
+@compute @workgroup_size(1)
+fn main() {
+    data.v *= 2.0;
+    data.v.y += 1.0;
+    data.m *= mat2x2f(1.0, 0.0, 0.0, 1.0);
+    data.a[index()] <<= 2u;
+    data.a[2] |= 1;
+    data.a[3]--;
+}
@@ -0,0 +1,28 @@
+// CTS: webgpu:shader,execution,statement,{loop,for,while}:*
+@group(0) @binding(0) var<storage, read_write> outputs: array<u32, 4>;
+
+@compute @workgroup_size(1)
+fn main() {
+    var i = 0u;
+    loop {
+        if i == 2u { i++; continue; }
+        outputs[0] += i;
+        continuing {
+            i++;
+            break if i >= 6u;
+        }
+    }
+    for (var j = 0u; j < 4u; j++) {
+        if j == 3u { break; }
+        outputs[1] += j;
+    }
+    var k = 8u;
+    while k > 0u {
+        k /= 2u;
+        outputs[2] += 1u;
+    }
+    for (;;) {
+        outputs[3] = 7u;
+        break;
+    }
+}
@@ -0,0 +1,19 @@
+// CTS: webgpu:shader,execution,statement,switch:*
+const FOUR = 4;
+
+@group(0) @binding(0) var<storage, read_write> outputs: array<i32>;
+
+@compute @workgroup_size(1)
+fn main(@builtin(global_invocation_id) id: vec3u) {
+    var r = -1;
+    switch i32(id.x) {
+        case 0: { r = 10; }
+        case 1, 2: { r = 20; }
+        case FOUR - 1, default: { r = 30; }
+        case FOUR: {
+            if id.y == 0u { break; }
+            r = 40;
+        }
+    }
+    outputs[id.x] = r;
+}
@@ -0,0 +1,16 @@
+// CTS: webgpu:shader,execution,expression,call,builtin,{subgroupAdd,subgroupBallot,subgroupBroadcast}:*
+enable subgroups;
+
+@group(0) @binding(0) var<storage, read_write> outputs: array<vec4u>;
+
+@compute @workgroup_size(32)
+fn main(
+    @builtin(global_invocation_id) id: vec3u,
+    @builtin(subgroup_invocation_id) sid: u32,
+    @builtin(subgroup_size) size: u32,
+) {
+    let sum = subgroupAdd(sid);
+    let ballot = subgroupBallot(sid % 2u == 0u);
+    let first = subgroupBroadcast(sid, 0u);
+    outputs[id.x] = vec4u(sum, ballot.x, first, size);
+}
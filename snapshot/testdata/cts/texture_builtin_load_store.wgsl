@@ -0,0 +1,14 @@
+// CTS: webgpu:shader,execution,expression,call,builtin,{textureLoad,textureStore,textureDimensions}:*
+@group(0) @binding(0) var src: texture_2d<f32>;
+@group(0) @binding(1) var dst: texture_storage_2d<rgba8unorm, write>;
+@group(0) @binding(2) var ms: texture_multisampled_2d<f32>;
+
+@compute @workgroup_size(8, 8)
+fn main(@builtin(global_invocation_id) id: vec3u) {
+    let size = textureDimensions(src, 0);
+    if any(id.xy >= size) {
+        return;
+    }
+    let c = textureLoad(src, id.xy, 0) + textureLoad(ms, id.xy, 0);
+    textureStore(dst, id.xy, c * 0.5);
+}
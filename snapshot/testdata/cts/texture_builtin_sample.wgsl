@@ -0,0 +1,15 @@
+// CTS: webgpu:shader,execution,expression,call,builtin,{textureSample,textureSampleLevel,textureSampleCompare}:*
+@group(0) @binding(0) var t: texture_2d<f32>;
+@group(0) @binding(1) var t_array: texture_2d_array<f32>;
+@group(0) @binding(2) var t_depth: texture_depth_2d;
+@group(0) @binding(3) var s: sampler;
+@group(0) @binding(4) var s_cmp: sampler_comparison;
+
+@fragment
+fn main(@location(0) uv: vec2f) -> @location(0) vec4f {
+    let a = textureSample(t, s, uv);
+    let b = textureSampleLevel(t_array, s, uv, 1, 0.0);
+    let c = textureSampleCompare(t_depth, s_cmp, uv, 0.5);
+    let d = textureSampleBias(t, s, uv, 1.0, vec2i(1, -1));
+    return a + b + d + vec4f(c);
+}
@@ -0,0 +1,22 @@
+// CTS: webgpu:shader,execution,zero_init:*
+struct S {
+    a: array<vec3u, 3>,
+    b: atomic<u32>,
+}
+
+var<private> p: array<f32, 4>;
+var<workgroup> w: S;
+var<workgroup> w_arr: array<u32, 16>;
+
+@group(0) @binding(0) var<storage, read_write> outputs: array<u32, 4>;
+
+@compute @workgroup_size(16)
+fn main(@builtin(local_invocation_index) li: u32) {
+    var f: vec4f;
+    workgroupBarrier();
+    if li == 0u {
+        outputs[0] = u32(p[1] + f.w);
+        outputs[1] = w.a[2].z + atomicLoad(&w.b);
+        outputs[2] = w_arr[15];
+    }
+}
@@ -1,7 +1,7 @@
 #version 330 core
 uniform sampler2D _group_0_binding_0_fs;
 
-uniform sampler2DShadow _group_0_binding_2_fs;
+uniform sampler2D _group_0_binding_2_fs;
 
 uniform sampler2DShadow _group_0_binding_2_fs__group_0_binding_3_fs;
 
@@ -19,7 +19,7 @@ void color() {
 }
 
 void depth() {
-    float phony_6 = textureLod(_group_0_binding_2_fs, vec2(vec2(1.0, 2.0)), 1);
+    float phony_6 = textureLod(_group_0_binding_2_fs, vec2(vec2(1.0, 2.0)), 1).x;
     float phony_7 = texture(_group_0_binding_2_fs__group_0_binding_3_fs, vec3(vec2(1.0, 2.0), 0.0));
     vec4 phony_8 = textureGather(_group_0_binding_2_fs__group_0_binding_3_fs, vec2(vec2(1.0, 2.0)), 0.0);
     return;
@@ -6,8 +6,8 @@ const float16_t MIN_F16_ = 3.18727e-319LF;
 const float16_t MAX_F16_ = 1.5683e-319LF;
 const float MIN_F32_ = -3.4028235e38;
 const float MAX_F32_ = 3.4028235e38;
-const double MIN_F64_ = -1.7976931348623157e+308LF;
-const double MAX_F64_ = 1.7976931348623157e+308LF;
+const double MIN_F64_ = -1.7976931348623157e308LF;
+const double MAX_F64_ = 1.7976931348623157e308LF;
 
 
 void test_const_eval() {
@@ -0,0 +1,51 @@
+#version 430 core
+#extension GL_ARB_compute_shader : require
+#extension GL_ARB_shader_storage_buffer_object : require
+layout(local_size_x = 4, local_size_y = 1, local_size_z = 1) in;
+
+struct Bar {
+    mat4x3 _matrix;
+    mat3x2 m;
+    mat2x2 am[2];
+};
+layout(std430) buffer Bar_block_0Compute { Bar _group_0_binding_0_cs; };
+
+shared Bar wg;
+
+Bar pv = Bar(mat4x3(0.0), mat3x2(0.0), mat2x2[2](mat2x2(0.0), mat2x2(0.0)));
+
+
+void main() {
+    if (gl_LocalInvocationID == uvec3(0u)) {
+        wg = Bar(mat4x3(0.0), mat3x2(0.0), mat2x2[2](mat2x2(0.0), mat2x2(0.0)));
+    }
+    memoryBarrierShared();
+    barrier();
+    uvec3 id = gl_LocalInvocationID;
+    Bar t_1 = Bar(mat4x3(0.0), mat3x2(0.0), mat2x2[2](mat2x2(0.0), mat2x2(0.0)));
+    uint i = (id.x % 2u);
+    uint j = (id.y % 2u);
+    _group_0_binding_0_cs._matrix[1][2] = 1.0;
+    _group_0_binding_0_cs._matrix[i][j] = 2.0;
+    _group_0_binding_0_cs.m[i][j] = 3.0;
+    _group_0_binding_0_cs.am[i][j][1] = 4.0;
+    wg.m = mat3x2(vec2(1.0), vec2(2.0), vec2(3.0));
+    wg.m[i] = vec2(5.0);
+    wg.m[i][j] = 6.0;
+    wg.am[i][j] = vec2(7.0);
+    wg.am[1][i][j] = 8.0;
+    pv.m[i][j] = 9.0;
+    pv.am[i][j][0] = 10.0;
+    t_1.m[i][1] = 11.0;
+    t_1.m[i][j] = 12.0;
+    t_1.am[i][j] = vec2(13.0);
+    t_1.am[i][j][i] = 14.0;
+    memoryBarrierShared();
+    barrier();
+    float _e96 = t_1.m[j][i];
+    float _e102 = wg.am[i][j][i];
+    float _e108 = pv.m[i][0];
+    _group_0_binding_0_cs._matrix[0][i] = ((_e96 + _e102) + _e108);
+    return;
+}
+
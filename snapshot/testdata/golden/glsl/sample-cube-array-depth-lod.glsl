@@ -1,5 +1,6 @@
 #version 330 core
 #extension GL_ARB_texture_cube_map_array : require
+#extension GL_EXT_texture_shadow_lod : require
 uniform samplerCubeArrayShadow _group_0_binding_0_fs;
 
 layout(location = 0) out float _fs2p_location0;
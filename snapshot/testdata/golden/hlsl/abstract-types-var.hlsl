@@ -52,16 +52,16 @@ static int2 xvis_ai_1 = (int(1)).xx;
 static uint2 xvus_ai_1 = (1u).xx;
 static float2 xvfs_ai_1 = (1.0).xx;
 static float2 xvfs_af_1 = (1.0).xx;
-static float[2] xafafaf_1 = Constructarray2_float_(1.0, 2.0);
-static float[2] xafaiai_1 = Constructarray2_float_(1.0, 2.0);
-static int[2] xaipaiai_1 = Constructarray2_int_(int(1), int(2));
-static uint[2] xaupaiai = Constructarray2_uint_(1u, 2u);
-static float[2] xafpaiaf_1 = Constructarray2_float_(1.0, 2.0);
-static float[2] xafpafai_1 = Constructarray2_float_(1.0, 2.0);
-static float[2] xafpafaf_1 = Constructarray2_float_(1.0, 2.0);
-static int3[1] xavipai_1 = Constructarray1_int3_((int(1)).xxx);
-static float3[1] xavfpai_1 = Constructarray1_float3_((1.0).xxx);
-static float3[1] xavfpaf_1 = Constructarray1_float3_((1.0).xxx);
+static float xafafaf_1[2] = Constructarray2_float_(1.0, 2.0);
+static float xafaiai_1[2] = Constructarray2_float_(1.0, 2.0);
+static int xaipaiai_1[2] = Constructarray2_int_(int(1), int(2));
+static uint xaupaiai[2] = Constructarray2_uint_(1u, 2u);
+static float xafpaiaf_1[2] = Constructarray2_float_(1.0, 2.0);
+static float xafpafai_1[2] = Constructarray2_float_(1.0, 2.0);
+static float xafpafaf_1[2] = Constructarray2_float_(1.0, 2.0);
+static int3 xavipai_1[1] = Constructarray1_int3_((int(1)).xxx);
+static float3 xavfpai_1[1] = Constructarray1_float3_((1.0).xxx);
+static float3 xavfpaf_1[1] = Constructarray1_float3_((1.0).xxx);
 static int2 xvisai_1 = (int(1)).xx;
 static uint2 xvusai_1 = (1u).xx;
 static float2 xvfsai_1 = (1.0).xx;
@@ -72,15 +72,15 @@ static int2 ivis_ai = (int(1)).xx;
 static uint2 ivus_ai = (1u).xx;
 static float2 ivfs_ai = (1.0).xx;
 static float2 ivfs_af = (1.0).xx;
-static float[2] iafafaf = Constructarray2_float_(1.0, 2.0);
-static float[2] iafaiai = Constructarray2_float_(1.0, 2.0);
-static int[2] iaipaiai_1 = Constructarray2_int_(int(1), int(2));
-static float[2] iafpafaf_1 = Constructarray2_float_(1.0, 2.0);
-static float[2] iafpaiaf_1 = Constructarray2_float_(1.0, 2.0);
-static float[2] iafpafai_1 = Constructarray2_float_(1.0, 2.0);
-static int3[1] iavipai = Constructarray1_int3_((int(1)).xxx);
-static int3[1] iavfpai = Constructarray1_int3_((int(1)).xxx);
-static float3[1] iavfpaf = Constructarray1_float3_((1.0).xxx);
+static float iafafaf[2] = Constructarray2_float_(1.0, 2.0);
+static float iafaiai[2] = Constructarray2_float_(1.0, 2.0);
+static int iaipaiai_1[2] = Constructarray2_int_(int(1), int(2));
+static float iafpafaf_1[2] = Constructarray2_float_(1.0, 2.0);
+static float iafpaiaf_1[2] = Constructarray2_float_(1.0, 2.0);
+static float iafpafai_1[2] = Constructarray2_float_(1.0, 2.0);
+static int3 iavipai[1] = Constructarray1_int3_((int(1)).xxx);
+static int3 iavfpai[1] = Constructarray1_int3_((int(1)).xxx);
+static float3 iavfpaf[1] = Constructarray1_float3_((1.0).xxx);
 
 void globals()
 {
@@ -6,13 +6,13 @@ float2 __get_col_of_mat2x2(__mat2x2 mat, uint idx) {
     default: { return (float2)0; }
     }
 }
-void __set_col_of_mat2x2(__mat2x2 mat, uint idx, float2 value) {
+void __set_col_of_mat2x2(inout __mat2x2 mat, uint idx, float2 value) {
     switch(idx) {
     case 0: { mat._0 = value; break; }
     case 1: { mat._1 = value; break; }
     }
 }
-void __set_el_of_mat2x2(__mat2x2 mat, uint idx, uint vec_idx, float value) {
+void __set_el_of_mat2x2(inout __mat2x2 mat, uint idx, uint vec_idx, float value) {
     switch(idx) {
     case 0: { mat._0[vec_idx] = value; break; }
     case 1: { mat._1[vec_idx] = value; break; }
@@ -29,7 +29,7 @@ float2 __get_col_of_mat4x2(__mat4x2 mat, uint idx) {
     default: { return (float2)0; }
     }
 }
-void __set_col_of_mat4x2(__mat4x2 mat, uint idx, float2 value) {
+void __set_col_of_mat4x2(inout __mat4x2 mat, uint idx, float2 value) {
     switch(idx) {
     case 0: { mat._0 = value; break; }
     case 1: { mat._1 = value; break; }
@@ -37,7 +37,7 @@ void __set_col_of_mat4x2(__mat4x2 mat, uint idx, float2 value) {
     case 3: { mat._3 = value; break; }
     }
 }
-void __set_el_of_mat4x2(__mat4x2 mat, uint idx, uint vec_idx, float value) {
+void __set_el_of_mat4x2(inout __mat4x2 mat, uint idx, uint vec_idx, float value) {
     switch(idx) {
     case 0: { mat._0[vec_idx] = value; break; }
     case 1: { mat._1[vec_idx] = value; break; }
@@ -111,13 +111,13 @@ float3x2 GetMatmOnBaz(Baz obj) {
     return float3x2(obj.m_0, obj.m_1, obj.m_2);
 }
 
-void SetMatmOnBaz(Baz obj, float3x2 mat) {
+void SetMatmOnBaz(inout Baz obj, float3x2 mat) {
     obj.m_0 = mat[0];
     obj.m_1 = mat[1];
     obj.m_2 = mat[2];
 }
 
-void SetMatVecmOnBaz(Baz obj, float2 vec, uint mat_idx) {
+void SetMatVecmOnBaz(inout Baz obj, float2 vec, uint mat_idx) {
     switch(mat_idx) {
     case 0: { obj.m_0 = vec; break; }
     case 1: { obj.m_1 = vec; break; }
@@ -125,7 +125,7 @@ void SetMatVecmOnBaz(Baz obj, float2 vec, uint mat_idx) {
     }
 }
 
-void SetMatScalarmOnBaz(Baz obj, float scalar, uint mat_idx, uint vec_idx) {
+void SetMatScalarmOnBaz(inout Baz obj, float scalar, uint mat_idx, uint vec_idx) {
     switch(mat_idx) {
     case 0: { obj.m_0[vec_idx] = scalar; break; }
     case 1: { obj.m_1[vec_idx] = scalar; break; }
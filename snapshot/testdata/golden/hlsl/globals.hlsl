@@ -7,14 +7,14 @@ float2 __get_col_of_mat3x2(__mat3x2 mat, uint idx) {
     default: { return (float2)0; }
     }
 }
-void __set_col_of_mat3x2(__mat3x2 mat, uint idx, float2 value) {
+void __set_col_of_mat3x2(inout __mat3x2 mat, uint idx, float2 value) {
     switch(idx) {
     case 0: { mat._0 = value; break; }
     case 1: { mat._1 = value; break; }
     case 2: { mat._2 = value; break; }
     }
 }
-void __set_el_of_mat3x2(__mat3x2 mat, uint idx, uint vec_idx, float value) {
+void __set_el_of_mat3x2(inout __mat3x2 mat, uint idx, uint vec_idx, float value) {
     switch(idx) {
     case 0: { mat._0[vec_idx] = value; break; }
     case 1: { mat._1[vec_idx] = value; break; }
@@ -32,7 +32,7 @@ float2 __get_col_of_mat4x2(__mat4x2 mat, uint idx) {
     default: { return (float2)0; }
     }
 }
-void __set_col_of_mat4x2(__mat4x2 mat, uint idx, float2 value) {
+void __set_col_of_mat4x2(inout __mat4x2 mat, uint idx, float2 value) {
     switch(idx) {
     case 0: { mat._0 = value; break; }
     case 1: { mat._1 = value; break; }
@@ -40,7 +40,7 @@ void __set_col_of_mat4x2(__mat4x2 mat, uint idx, float2 value) {
     case 3: { mat._3 = value; break; }
     }
 }
-void __set_el_of_mat4x2(__mat4x2 mat, uint idx, uint vec_idx, float value) {
+void __set_el_of_mat4x2(inout __mat4x2 mat, uint idx, uint vec_idx, float value) {
     switch(idx) {
     case 0: { mat._0[vec_idx] = value; break; }
     case 1: { mat._1[vec_idx] = value; break; }
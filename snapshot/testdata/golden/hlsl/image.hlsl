@@ -198,7 +198,7 @@ uint NagaNumLayers2DArray(Texture2DArray<float4> tex)
 {
     uint4 ret;
     tex.GetDimensions(0, ret.x, ret.y, ret.z, ret.w);
-    return ret.w;
+    return ret.z;
 }
 
 uint NagaNumLevels2DArray(Texture2DArray<float4> tex)
@@ -226,7 +226,7 @@ uint NagaNumLayersCubeArray(TextureCubeArray<float4> tex)
 {
     uint4 ret;
     tex.GetDimensions(0, ret.x, ret.y, ret.z, ret.w);
-    return ret.w;
+    return ret.z;
 }
 
 uint NagaNumLevels3D(Texture3D<float4> tex)
@@ -0,0 +1,113 @@
+typedef struct { float2 _0; float2 _1; } __mat2x2;
+float2 __get_col_of_mat2x2(__mat2x2 mat, uint idx) {
+    switch(idx) {
+    case 0: { return mat._0; }
+    case 1: { return mat._1; }
+    default: { return (float2)0; }
+    }
+}
+void __set_col_of_mat2x2(inout __mat2x2 mat, uint idx, float2 value) {
+    switch(idx) {
+    case 0: { mat._0 = value; break; }
+    case 1: { mat._1 = value; break; }
+    }
+}
+void __set_el_of_mat2x2(inout __mat2x2 mat, uint idx, uint vec_idx, float value) {
+    switch(idx) {
+    case 0: { mat._0[vec_idx] = value; break; }
+    case 1: { mat._1[vec_idx] = value; break; }
+    }
+}
+
+struct Bar {
+    row_major float4x3 _matrix;
+    int _pad1_0;
+    float2 m_0; float2 m_1; float2 m_2;
+    __mat2x2 am[2];
+    int _end_pad_0;
+    int _end_pad_1;
+};
+
+RWByteAddressBuffer bar : register(u0);
+groupshared Bar wg;
+static Bar pv = (Bar)0;
+
+uint naga_mod(uint lhs, uint rhs) {
+    return lhs % (rhs == 0u ? 1u : rhs);
+}
+
+float3x2 GetMatmOnBar(Bar obj) {
+    return float3x2(obj.m_0, obj.m_1, obj.m_2);
+}
+
+void SetMatmOnBar(inout Bar obj, float3x2 mat) {
+    obj.m_0 = mat[0];
+    obj.m_1 = mat[1];
+    obj.m_2 = mat[2];
+}
+
+void SetMatVecmOnBar(inout Bar obj, float2 vec, uint mat_idx) {
+    switch(mat_idx) {
+    case 0: { obj.m_0 = vec; break; }
+    case 1: { obj.m_1 = vec; break; }
+    case 2: { obj.m_2 = vec; break; }
+    }
+}
+
+void SetMatScalarmOnBar(inout Bar obj, float scalar, uint mat_idx, uint vec_idx) {
+    switch(mat_idx) {
+    case 0: { obj.m_0[vec_idx] = scalar; break; }
+    case 1: { obj.m_1[vec_idx] = scalar; break; }
+    case 2: { obj.m_2[vec_idx] = scalar; break; }
+    }
+}
+
+[numthreads(4, 1, 1)]
+void main(uint3 id : SV_GroupThreadID, uint3 __local_invocation_id : SV_GroupThreadID)
+{
+    if (all(__local_invocation_id == uint3(0u, 0u, 0u))) {
+        wg = (Bar)0;
+    }
+    GroupMemoryBarrierWithGroupSync();
+    Bar t = (Bar)0;
+
+    uint i = naga_mod(id.x, 2u);
+    uint j = naga_mod(id.y, 2u);
+    bar.Store(8+16+0, asuint(1.0));
+    bar.Store(j*4+i*16+0, asuint(2.0));
+    bar.Store(j*4+i*8+64, asuint(3.0));
+    bar.Store(4+j*8+i*16+88, asuint(4.0));
+    wg.m_0 = float3x2((1.0).xx, (2.0).xx, (3.0).xx)[0];
+    wg.m_1 = float3x2((1.0).xx, (2.0).xx, (3.0).xx)[1];
+    wg.m_2 = float3x2((1.0).xx, (2.0).xx, (3.0).xx)[2];
+    switch(i) {
+    case 0: { wg.m_0 = (5.0).xx; break; }
+    case 1: { wg.m_1 = (5.0).xx; break; }
+    case 2: { wg.m_2 = (5.0).xx; break; }
+    }
+    switch(i) {
+    case 0: { wg.m_0[j] = 6.0; break; }
+    case 1: { wg.m_1[j] = 6.0; break; }
+    case 2: { wg.m_2[j] = 6.0; break; }
+    }
+    switch(j) {
+    case 0: { wg.am[min(uint(i), 1u)]._0 = (7.0).xx; break; }
+    case 1: { wg.am[min(uint(i), 1u)]._1 = (7.0).xx; break; }
+    }
+    switch(i) {
+    case 0: { wg.am[1]._0[j] = 8.0; break; }
+    case 1: { wg.am[1]._1[j] = 8.0; break; }
+    }
+    SetMatScalarmOnBar(pv, 9.0, i, j);
+    __set_el_of_mat2x2(pv.am[min(uint(i), 1u)], j, 0, 10.0);
+    SetMatScalarmOnBar(t, 11.0, i, 1);
+    SetMatScalarmOnBar(t, 12.0, i, j);
+    __set_col_of_mat2x2(t.am[min(uint(i), 1u)], j, (13.0).xx);
+    __set_el_of_mat2x2(t.am[min(uint(i), 1u)], j, i, 14.0);
+    GroupMemoryBarrierWithGroupSync();
+    float _e96 = GetMatmOnBar(t)[min(uint(j), 2u)][min(uint(i), 1u)];
+    float _e102 = __get_col_of_mat2x2(wg.am[min(uint(i), 1u)], j)[min(uint(i), 1u)];
+    float _e108 = GetMatmOnBar(pv)[min(uint(i), 2u)].x;
+    bar.Store(i*4+0+0, asuint(((_e96 + _e102) + _e108)));
+    return;
+}
@@ -51,18 +51,18 @@ void ms_main(uint index : SV_GroupIndex, uint3 id : SV_DispatchThreadID)
     mesh_output.vertex_count = 3u;
     mesh_output.primitive_count = 1u;
     workgroupData = 2.0;
-    mesh_output.vertices_[0].position = 0.0;
-    float4 _e22 = taskPayload.colorMask;
-    mesh_output.vertices_[0].color = (0.0 * _e22);
-    mesh_output.vertices_[1].position = 1.0;
-    float4 _e36 = taskPayload.colorMask;
-    mesh_output.vertices_[1].color = (1.0 * _e36);
-    mesh_output.vertices_[2].position = 0.0;
-    float4 _e50 = taskPayload.colorMask;
-    mesh_output.vertices_[2].color = (0.0 * _e50);
+    mesh_output.vertices_[0].position = float4(0.0, 1.0, 0.0, 1.0);
+    float4 _e30 = taskPayload.colorMask;
+    mesh_output.vertices_[0].color = (float4(0.0, 1.0, 0.0, 1.0) * _e30);
+    mesh_output.vertices_[1].position = float4(-1.0, -1.0, 0.0, 1.0);
+    float4 _e52 = taskPayload.colorMask;
+    mesh_output.vertices_[1].color = (float4(0.0, 0.0, 1.0, 1.0) * _e52);
+    mesh_output.vertices_[2].position = float4(1.0, -1.0, 0.0, 1.0);
+    float4 _e74 = taskPayload.colorMask;
+    mesh_output.vertices_[2].color = (float4(1.0, 0.0, 0.0, 1.0) * _e74);
     mesh_output.primitives_[0].indices_ = uint3(0u, 1u, 2u);
-    bool _e66 = taskPayload.visible;
-    mesh_output.primitives_[0].cull = !(_e66);
+    bool _e90 = taskPayload.visible;
+    mesh_output.primitives_[0].cull = !(_e90);
     mesh_output.primitives_[0].colorMask = float4(1.0, 0.0, 1.0, 1.0);
     return;
 }
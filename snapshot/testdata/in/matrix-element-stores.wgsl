@@ -0,0 +1,43 @@
+// Stores through column then component indices of matrices, static and
+// dynamic, in every address space a matrix can be written in.
+
+struct Bar {
+    _matrix: mat4x3<f32>,
+    m: mat3x2<f32>,
+    am: array<mat2x2<f32>, 2>,
+}
+
+@group(0) @binding(0)
+var<storage, read_write> bar: Bar;
+
+var<workgroup> wg: Bar;
+var<private> pv: Bar;
+
+@compute @workgroup_size(4)
+fn main(@builtin(local_invocation_id) id: vec3<u32>) {
+    let i = id.x % 2u;
+    let j = id.y % 2u;
+    var t: Bar;
+
+    bar._matrix[1].z = 1.0;
+    bar._matrix[i][j] = 2.0;
+    bar.m[i][j] = 3.0;
+    bar.am[i][j][1] = 4.0;
+
+    wg.m = mat3x2<f32>(vec2(1.0), vec2(2.0), vec2(3.0));
+    wg.m[i] = vec2(5.0);
+    wg.m[i][j] = 6.0;
+    wg.am[i][j] = vec2(7.0);
+    wg.am[1][i][j] = 8.0;
+
+    pv.m[i][j] = 9.0;
+    pv.am[i][j][0] = 10.0;
+
+    t.m[i].y = 11.0;
+    t.m[i][j] = 12.0;
+    t.am[i][j] = vec2(13.0);
+    t.am[i][j][i] = 14.0;
+
+    workgroupBarrier();
+    bar._matrix[0][i] = t.m[j][i] + wg.am[i][j][i] + pv.m[i].x;
+}
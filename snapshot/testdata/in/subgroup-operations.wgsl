@@ -35,7 +35,7 @@ fn main(
     subgroupShuffleUp(subgroup_invocation_id, 1u);
     subgroupShuffleXor(subgroup_invocation_id, sizes.subgroup_size - 1u);
 
-    quadBroadcast(subgroup_invocation_id, 4u);
+    quadBroadcast(subgroup_invocation_id, 3u);
     quadSwapX(subgroup_invocation_id);
     quadSwapY(subgroup_invocation_id);
     quadSwapDiagonal(subgroup_invocation_id);
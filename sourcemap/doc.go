@@ -0,0 +1,22 @@
+// Copyright 2025 The GoGPU Authors
+// SPDX-License-Identifier: MIT
+
+// Package sourcemap maps generated shader code back to the WGSL it was
+// compiled from, for graphics debuggers and crash-report symbolication.
+//
+// Backends record the spans with an option and return them with their
+// output: glsl, msl and hlsl in TranslationInfo.SourceMap, keyed by
+// output line, and spirv from Backend.SourceMap, keyed by instruction
+// index. New turns them into a Map, which serializes as JSON and is
+// written next to the generated file:
+//
+//	opts := msl.DefaultOptions()
+//	opts.SourceMap = true
+//	code, info, err := msl.Compile(module, opts)
+//	m := sourcemap.New("msl", source, info.SourceMap)
+//	m.File, m.Source = "shader.metal", "shader.wgsl"
+//	data, err := json.Marshal(m)
+//
+// Only the places a statement or expression starts are mapped; Find
+// attributes any other output position to the nearest mapping before it.
+package sourcemap
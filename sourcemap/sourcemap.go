@@ -0,0 +1,97 @@
+// Copyright 2025 The GoGPU Authors
+// SPDX-License-Identifier: MIT
+
+package sourcemap
+
+import (
+	"sort"
+	"unicode/utf8"
+
+	"github.com/gogpu/naga/ir"
+)
+
+// Version is the version of the Map JSON schema. It is bumped whenever a
+// field is removed, renamed, or changes meaning.
+const Version = 1
+
+// Units of Mapping.Output.
+const (
+	// UnitLine numbers the lines of text output from 1.
+	UnitLine = "line"
+	// UnitInstruction numbers SPIR-V instructions from 0, counting from
+	// the first after the module header.
+	UnitInstruction = "instruction"
+)
+
+// Map is a source map of one generated file.
+type Map struct {
+	Version int `json:"version"`
+	// Target is the language of the generated file: spirv, msl, hlsl
+	// or glsl.
+	Target string `json:"target"`
+	// File and Source name the generated file and the WGSL file, when
+	// known.
+	File   string `json:"file,omitempty"`
+	Source string `json:"source,omitempty"`
+	// Unit is what Mapping.Output counts.
+	Unit string `json:"unit"`
+	// Mappings are sorted by Output.
+	Mappings []Mapping `json:"mappings"`
+}
+
+// Mapping maps one output position to a WGSL span. Start and End are
+// byte offsets into the source; Line and Column, and EndLine and
+// EndColumn for End, are 1-based, with columns counted in runes like
+// WGSL diagnostics.
+type Mapping struct {
+	Output    int `json:"output"`
+	Start     int `json:"start"`
+	End       int `json:"end"`
+	Line      int `json:"line"`
+	Column    int `json:"column"`
+	EndLine   int `json:"endLine"`
+	EndColumn int `json:"endColumn"`
+}
+
+// New builds the map for code generated for target from source, with the
+// spans a backend returned. Spans past the end of source are dropped.
+func New(target, source string, spans []ir.OutputSpan) *Map {
+	unit := UnitLine
+	if target == "spirv" {
+		unit = UnitInstruction
+	}
+	m := &Map{Version: Version, Target: target, Unit: unit, Mappings: []Mapping{}}
+
+	lineStarts := []int{0}
+	for i := 0; i < len(source); i++ {
+		if source[i] == '\n' {
+			lineStarts = append(lineStarts, i+1)
+		}
+	}
+	position := func(offset int) (line, column int) {
+		line = sort.Search(len(lineStarts), func(i int) bool { return lineStarts[i] > offset })
+		return line, utf8.RuneCountInString(source[lineStarts[line-1]:offset]) + 1
+	}
+
+	for _, s := range spans {
+		start, end := int(s.Span.Start), int(s.Span.End)
+		if end > len(source) || start > end {
+			continue
+		}
+		mapping := Mapping{Output: s.Output, Start: start, End: end}
+		mapping.Line, mapping.Column = position(start)
+		mapping.EndLine, mapping.EndColumn = position(end)
+		m.Mappings = append(m.Mappings, mapping)
+	}
+	sort.SliceStable(m.Mappings, func(i, j int) bool { return m.Mappings[i].Output < m.Mappings[j].Output })
+	return m
+}
+
+// Find returns the mapping covering output: the last one at or before it.
+func (m *Map) Find(output int) (Mapping, bool) {
+	i := sort.Search(len(m.Mappings), func(i int) bool { return m.Mappings[i].Output > output })
+	if i == 0 {
+		return Mapping{}, false
+	}
+	return m.Mappings[i-1], true
+}
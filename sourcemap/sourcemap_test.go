@@ -0,0 +1,177 @@
+// Copyright 2025 The GoGPU Authors
+// SPDX-License-Identifier: MIT
+
+package sourcemap_test
+
+import (
+	"bytes"
+	"encoding/binary"
+	"strings"
+	"testing"
+
+	"github.com/gogpu/naga/glsl"
+	"github.com/gogpu/naga/hlsl"
+	"github.com/gogpu/naga/internal/testutil"
+	"github.com/gogpu/naga/ir"
+	"github.com/gogpu/naga/msl"
+	"github.com/gogpu/naga/sourcemap"
+	"github.com/gogpu/naga/spirv"
+)
+
+const shader = `@group(0) @binding(0) var<storage, read_write> data: array<f32>;
+
+fn scale(x: f32) -> f32 {
+    return x * 2.0;
+}
+
+@compute @workgroup_size(64)
+fn main(@builtin(global_invocation_id) id: vec3<u32>) {
+    let v = data[id.x];
+    if v > 1.0 {
+        data[id.x] = scale(v);
+    }
+}
+`
+
+func TestNew(t *testing.T) {
+	source := "fn f() {\n    let é = 1 + 2;\n}\n"
+	start := strings.Index(source, "1 + 2")
+	m := sourcemap.New("glsl", source, []ir.OutputSpan{
+		{Output: 7, Span: ir.Span{Start: uint32(start), End: uint32(start + 5)}},
+		{Output: 3, Span: ir.Span{Start: 0, End: 8}},
+		{Output: 9, Span: ir.Span{Start: 0, End: 100}}, // past the source
+	})
+	if m.Unit != sourcemap.UnitLine || m.Version != sourcemap.Version {
+		t.Errorf("unit %q version %d", m.Unit, m.Version)
+	}
+	want := []sourcemap.Mapping{
+		{Output: 3, Start: 0, End: 8, Line: 1, Column: 1, EndLine: 1, EndColumn: 9},
+		{Output: 7, Start: start, End: start + 5, Line: 2, Column: 13, EndLine: 2, EndColumn: 18},
+	}
+	if len(m.Mappings) != len(want) {
+		t.Fatalf("mappings = %+v, want %+v", m.Mappings, want)
+	}
+	for i := range want {
+		if m.Mappings[i] != want[i] {
+			t.Errorf("mapping %d = %+v, want %+v", i, m.Mappings[i], want[i])
+		}
+	}
+
+	if _, ok := m.Find(2); ok {
+		t.Error("Find(2) found a mapping before the first")
+	}
+	if got, ok := m.Find(5); !ok || got.Output != 3 {
+		t.Errorf("Find(5) = %+v, %v, want the mapping of output 3", got, ok)
+	}
+	if got, ok := m.Find(7); !ok || got.Output != 7 {
+		t.Errorf("Find(7) = %+v, %v, want the mapping of output 7", got, ok)
+	}
+
+	if u := sourcemap.New("spirv", source, nil).Unit; u != sourcemap.UnitInstruction {
+		t.Errorf("spirv unit = %q", u)
+	}
+}
+
+// checkLines checks that text compiled with source maps on matches plain
+// and that each WGSL snippet maps to a line holding the code it became.
+func checkLines(t *testing.T, target, plain, mapped string, spans []ir.OutputSpan, want map[string]string) {
+	t.Helper()
+	if plain != mapped {
+		t.Errorf("%s: source maps changed the output", target)
+	}
+	lines := strings.Split(mapped, "\n")
+	m := sourcemap.New(target, shader, spans)
+	for snippet, code := range want {
+		found := false
+		for _, mapping := range m.Mappings {
+			if shader[mapping.Start:mapping.End] == snippet && strings.Contains(lines[mapping.Output-1], code) {
+				found = true
+			}
+		}
+		if !found {
+			t.Errorf("%s: %q not mapped to a line with %q:\n%+v", target, snippet, code, m.Mappings)
+		}
+	}
+}
+
+func TestTextBackends(t *testing.T) {
+	module := testutil.LowerWGSL(t, shader)
+	want := map[string]string{"x * 2.0": "x * 2.0", "v > 1.0": "if ", "scale(v)": "scale(v)"}
+
+	mo := msl.DefaultOptions()
+	plainMSL, _, err := msl.Compile(module, mo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mo.SourceMap = true
+	mappedMSL, info, err := msl.Compile(module, mo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkLines(t, "msl", plainMSL, mappedMSL, info.SourceMap, want)
+
+	ho := hlsl.DefaultOptions()
+	plainHLSL, _, err := hlsl.Compile(module, ho)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ho.SourceMap = true
+	mappedHLSL, hinfo, err := hlsl.Compile(module, ho)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkLines(t, "hlsl", plainHLSL, mappedHLSL, hinfo.SourceMap, want)
+
+	gopts := glsl.DefaultOptions()
+	gopts.EntryPoint = "main"
+	plainGLSL, _, err := glsl.Compile(module, gopts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	gopts.SourceMap = true
+	mappedGLSL, ginfo, err := glsl.Compile(module, gopts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkLines(t, "glsl", plainGLSL, mappedGLSL, ginfo.SourceMap, want)
+}
+
+func TestSPIRV(t *testing.T) {
+	module := testutil.LowerWGSL(t, shader)
+	plain, err := spirv.NewBackend(spirv.DefaultOptions()).Compile(module)
+	if err != nil {
+		t.Fatal(err)
+	}
+	opts := spirv.DefaultOptions()
+	opts.SourceMap = true
+	backend := spirv.NewBackend(opts)
+	code, err := backend.Compile(module)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(plain, code) {
+		t.Error("source maps changed the binary")
+	}
+
+	// Opcodes of the instructions after the header, by index.
+	var opcodes []uint32
+	for i := 5 * 4; i < len(code); {
+		word := binary.LittleEndian.Uint32(code[i:])
+		opcodes = append(opcodes, word&0xffff)
+		i += int(word>>16) * 4
+	}
+	const opFMul, opFOrdGreaterThan, opFunctionCall = 133, 186, 57
+	want := map[string]uint32{"x * 2.0": opFMul, "v > 1.0": opFOrdGreaterThan, "scale(v)": opFunctionCall}
+	m := sourcemap.New("spirv", shader, backend.SourceMap())
+	for snippet, opcode := range want {
+		found := false
+		for _, mapping := range m.Mappings {
+			if shader[mapping.Start:mapping.End] == snippet && opcodes[mapping.Output] == opcode {
+				found = true
+			}
+		}
+		if !found {
+			t.Errorf("%q not mapped to opcode %d: %+v", snippet, opcode, m.Mappings)
+		}
+	}
+}
@@ -0,0 +1,219 @@
+package naga
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/gogpu/naga/ir"
+	"github.com/gogpu/naga/msl"
+	"github.com/gogpu/naga/wgsl"
+)
+
+const uberShader = `
+const SHADOWS: bool = true;
+override quality: u32 = 1u;
+@id(7) override exposure: f32 = 1.0;
+
+@group(0) @binding(0) var shadow_map: texture_depth_2d;
+@group(0) @binding(1) var shadow_samp: sampler_comparison;
+
+fn shadow(uv: vec2<f32>) -> f32 {
+    return textureSampleCompare(shadow_map, shadow_samp, uv, 0.5);
+}
+
+@fragment
+fn fs(@location(0) uv: vec2<f32>) -> @location(0) vec4<f32> {
+    var light = 1.0;
+    if SHADOWS {
+        light = shadow(uv);
+    }
+    if quality > 2u {
+        light = light * 0.5;
+    }
+    return vec4<f32>(light * exposure);
+}
+`
+
+func lowerUber(t *testing.T) *ir.Module {
+	t.Helper()
+	ast, err := Parse(uberShader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	module, err := Lower(ast)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return module
+}
+
+func specializedMSL(t *testing.T, module *ir.Module) string {
+	t.Helper()
+	code, _, err := msl.Compile(module, msl.DefaultOptions())
+	if err != nil {
+		t.Fatalf("MSL: %v", err)
+	}
+	return code
+}
+
+func TestSpecializeOverride(t *testing.T) {
+	module := lowerUber(t)
+	before := specializedMSL(t, module)
+
+	spec, err := ir.Specialize(module, ir.PipelineConstants{"quality": 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(spec.Overrides) != 1 || spec.Overrides[0].Name != "exposure" {
+		t.Errorf("Overrides = %+v, want only exposure", spec.Overrides)
+	}
+	code := specializedMSL(t, spec)
+	if strings.Contains(code, "quality >") {
+		t.Errorf("specialized shader still tests quality:\n%s", code)
+	}
+	if !strings.Contains(code, "0.5") {
+		t.Errorf("quality > 2 branch should be kept unconditionally:\n%s", code)
+	}
+
+	// The source module is untouched.
+	if after := specializedMSL(t, module); after != before {
+		t.Errorf("Specialize modified its input:\nbefore\n%s\nafter\n%s", before, after)
+	}
+	if errs, err := Validate(spec); err != nil || len(errs) != 0 {
+		t.Errorf("specialized module does not validate: %v %v", errs, err)
+	}
+}
+
+func TestSpecializeRejectsConstant(t *testing.T) {
+	_, err := ir.Specialize(lowerUber(t), ir.PipelineConstants{"SHADOWS": 0})
+	if err == nil || !strings.Contains(err.Error(), "LowerOptions.Constants") {
+		t.Errorf("got %v, want an error pointing to LowerOptions.Constants", err)
+	}
+}
+
+func TestLowerConstantValues(t *testing.T) {
+	ast, err := Parse(uberShader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lowered, err := wgsl.LowerWithOptions(context.Background(), ast, uberShader,
+		wgsl.LowerOptions{Constants: ir.PipelineConstants{"SHADOWS": 0}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	noShadows, err := ir.Specialize(lowered.Module, ir.PipelineConstants{"quality": 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(noShadows.Functions) != 0 || len(noShadows.GlobalVariables) != 0 {
+		t.Errorf("shadow function and resources should be removed: %d functions, %d globals",
+			len(noShadows.Functions), len(noShadows.GlobalVariables))
+	}
+	code := specializedMSL(t, noShadows)
+	for _, bad := range []string{"shadow", "if ("} {
+		if strings.Contains(code, bad) {
+			t.Errorf("specialized shader still contains %q:\n%s", bad, code)
+		}
+	}
+}
+
+func TestLowerConstantValuesFoldedInFunctions(t *testing.T) {
+	const source = `
+const SHADOWS: bool = true;
+const NO_SHADOWS = !SHADOWS;
+const RADIUS: i32 = 3;
+const DIAM: i32 = RADIUS * 2 + 1;
+
+@fragment
+fn fs() -> @location(0) vec4<f32> {
+    var light = 1.0;
+    if RADIUS * 2 > 5 {
+        light = 0.25;
+    }
+    if NO_SHADOWS {
+        light = light * 0.5;
+    }
+    let d = RADIUS + 1;
+    return vec4<f32>(light, f32(d), f32(RADIUS), f32(DIAM));
+}
+`
+	ast, err := Parse(source)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lowered, err := wgsl.LowerWithOptions(context.Background(), ast, source,
+		wgsl.LowerOptions{Constants: ir.PipelineConstants{"SHADOWS": 0, "RADIUS": 0}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if errs, err := Validate(lowered.Module); err != nil || len(errs) != 0 {
+		t.Fatalf("module does not validate: %v %v", errs, err)
+	}
+	code := specializedMSL(t, lowered.Module)
+	for _, want := range []string{"NO_SHADOWS = true", "RADIUS = 0", "DIAM = 1", "if (false)", "static_cast<float>(1)"} {
+		if !strings.Contains(code, want) {
+			t.Errorf("shader lacks %q:\n%s", want, code)
+		}
+	}
+	for _, bad := range []string{"if (true)", "static_cast<float>(4)", "3.0"} {
+		if strings.Contains(code, bad) {
+			t.Errorf("shader still contains %q from the declared RADIUS:\n%s", bad, code)
+		}
+	}
+}
+
+func TestLowerConstantValuesErrors(t *testing.T) {
+	const source = "const A: u32 = 1u;\nconst V = vec2<f32>(1.0, 2.0);\n"
+	ast, err := Parse(source)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		values ir.PipelineConstants
+		want   string
+	}{
+		{ir.PipelineConstants{"MISSING": 1}, `no module constant named "MISSING"`},
+		{ir.PipelineConstants{"A": -1}, "does not fit in u32"},
+		{ir.PipelineConstants{"A": 1.5}, "does not fit in u32"},
+		{ir.PipelineConstants{"V": 1}, "not a scalar"},
+	}
+	for _, tt := range tests {
+		_, err := wgsl.LowerWithOptions(context.Background(), ast, source, wgsl.LowerOptions{Constants: tt.values})
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%v: got %v, want an error containing %q", tt.values, err, tt.want)
+		}
+	}
+}
+
+func TestLowerConstantReadingOverride(t *testing.T) {
+	ast, err := Parse("override SHADOWS: bool = true;\nconst NO_SHADOWS = !SHADOWS;\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Lower(ast); err == nil || !strings.Contains(err.Error(), "reads override 'SHADOWS'") {
+		t.Errorf("got %v, want an error for a constant reading an override", err)
+	}
+}
+
+func TestSpecializeOverrideByID(t *testing.T) {
+	module := lowerUber(t)
+	spec, err := ir.Specialize(module, ir.PipelineConstants{"7": 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(spec.Overrides) != 1 || spec.Overrides[0].Name != "quality" {
+		t.Errorf("Overrides = %+v, want only quality", spec.Overrides)
+	}
+	code := specializedMSL(t, spec)
+	if !strings.Contains(code, "shadow_map") || !strings.Contains(code, "exposure") {
+		t.Errorf("unexpected output:\n%s", code)
+	}
+}
+
+func TestSpecializeUnknownKey(t *testing.T) {
+	_, err := ir.Specialize(lowerUber(t), ir.PipelineConstants{"MISSING": 1})
+	if err == nil || !strings.Contains(err.Error(), `"MISSING"`) {
+		t.Errorf("got %v, want an unknown key error", err)
+	}
+}
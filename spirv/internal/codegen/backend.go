@@ -1,9 +1,14 @@
 package codegen
 
 import (
+	"context"
 	"fmt"
 	"math"
+	"slices"
+	"strings"
+	"unicode/utf8"
 
+	"github.com/gogpu/naga/internal/backend"
 	"github.com/gogpu/naga/ir"
 )
 
@@ -42,6 +47,7 @@ type Backend struct {
 	module  *ir.Module
 	builder *ModuleBuilder
 	options Options
+	notes   *backend.Notes
 
 	// Type cache (IR TypeHandle → SPIR-V ID)
 	typeIDs map[ir.TypeHandle]uint32
@@ -49,6 +55,11 @@ type Backend struct {
 	// Constant cache (IR ConstantHandle → SPIR-V ID)
 	constantIDs map[ir.ConstantHandle]uint32
 
+	// Specialization constants: one per override, and one per global
+	// expression an override initializer computes from other overrides.
+	overrideIDs map[ir.OverrideHandle]uint32
+	specExprIDs map[ir.ExpressionHandle]uint32
+
 	// Global variable cache
 	globalIDs map[ir.GlobalVariableHandle]uint32
 
@@ -97,6 +108,14 @@ type Backend struct {
 	// Track used extensions (to avoid duplicates)
 	usedExtensions map[string]bool
 
+	// IDs already named by nameExpression
+	namedExprIDs map[uint32]bool
+
+	// Source span of each result ID computing an expression, and the
+	// instruction spans built from it, when Options.SourceMap is set
+	idSpans   map[uint32]ir.Span
+	sourceMap []ir.OutputSpan
+
 	// Cached void type ID (only one void type allowed in SPIR-V)
 	voidTypeID uint32
 
@@ -121,6 +140,12 @@ type Backend struct {
 	// point is a vertex shader that doesn't already have a PointSize output.
 	forcePointSizeVars map[int]uint32
 
+	// positionFlows holds the position dataflow of each vertex entry point
+	// when Options.NoContraction is NoContractionPosition; preciseFunctions
+	// marks the functions called on those paths.
+	positionFlows    map[int]*ir.PositionFlow
+	preciseFunctions []bool
+
 	// Workgroup init polyfill: LocalInvocationId Input variable IDs per entry point.
 	// Created when the entry point has workgroup variables that need zero-initialization.
 	workgroupInitVars map[int]uint32
@@ -139,6 +164,13 @@ type Backend struct {
 	// other emit functions between Reset() and Build().
 	ib InstructionBuilder
 
+	// Builders handed out by newIB. They are taken in order from ibSlab,
+	// whose word buffers are carved from ibWords, and reused by the next
+	// compilation after Reset.
+	ibSlab  []InstructionBuilder
+	ibWords []uint32
+	ibNext  int
+
 	// Wrapped helper functions for integer div/mod safety.
 	// Key: wrappedBinaryOp (op + left/right SPIR-V type IDs).
 	// Value: SPIR-V function ID of the wrapper.
@@ -170,6 +202,8 @@ func NewBackend(options Options) *Backend {
 		options:             options,
 		typeIDs:             make(map[ir.TypeHandle]uint32, 16),
 		constantIDs:         make(map[ir.ConstantHandle]uint32, 16),
+		overrideIDs:         make(map[ir.OverrideHandle]uint32),
+		specExprIDs:         make(map[ir.ExpressionHandle]uint32),
 		globalIDs:           make(map[ir.GlobalVariableHandle]uint32, 4),
 		functionIDs:         make(map[ir.FunctionHandle]uint32, 4),
 		entryInputVars:      make(map[int][]*entryPointInput, 2),
@@ -183,6 +217,8 @@ func NewBackend(options Options) *Backend {
 		matrixTypeIDs:       make(map[uint32]uint32, 4),
 		usedCapabilities:    make(map[Capability]bool, 4),
 		usedExtensions:      make(map[string]bool, 2),
+		namedExprIDs:        make(map[uint32]bool),
+		idSpans:             make(map[uint32]ir.Span),
 		funcTypeIDs:         make(map[string]uint32, 4),
 		wrappedStorageVars:  make(map[ir.GlobalVariableHandle]bool, 2),
 		blockDecoratedTypes: make(map[uint32]bool, 4),
@@ -210,6 +246,8 @@ func (b *Backend) Reset() {
 	// Clear maps — Go 1.21+ clear() keeps capacity, removes all entries
 	clear(b.typeIDs)
 	clear(b.constantIDs)
+	clear(b.overrideIDs)
+	clear(b.specExprIDs)
 	clear(b.globalIDs)
 	clear(b.functionIDs)
 	clear(b.entryInputVars)
@@ -223,6 +261,9 @@ func (b *Backend) Reset() {
 	clear(b.matrixTypeIDs)
 	clear(b.usedCapabilities)
 	clear(b.usedExtensions)
+	clear(b.namedExprIDs)
+	clear(b.idSpans)
+	b.sourceMap = nil
 	clear(b.funcTypeIDs)
 	clear(b.wrappedStorageVars)
 	clear(b.blockDecoratedTypes)
@@ -242,6 +283,7 @@ func (b *Backend) Reset() {
 
 	// Reset instruction builder scratch space
 	b.ib.words = b.ib.words[:0]
+	b.ibNext = 0
 }
 
 // needsF16Polyfill checks if a type is f16-related and needs polyfill
@@ -265,6 +307,7 @@ func (b *Backend) needsF16Polyfill(typeHandle ir.TypeHandle) bool {
 // getF16PolyfillTypeID returns the f32 equivalent type ID for an f16 type.
 // For f16 -> f32, for vec2<f16> -> vec2<f32>, etc.
 func (b *Backend) getF16PolyfillTypeID(typeHandle ir.TypeHandle) (uint32, error) {
+	b.notes.Polyfill("f16 shader I/O")
 	inner := b.module.Types[typeHandle].Inner
 	switch t := inner.(type) {
 	case ir.ScalarType:
@@ -283,24 +326,49 @@ func (b *Backend) getF16PolyfillTypeID(typeHandle ir.TypeHandle) (uint32, error)
 	}
 }
 
-// newIB creates a new InstructionBuilder that allocates from the module's arena.
-// Use this for cases where the builder must survive across calls to other emit
-// methods (e.g. when emitExpression is called between AddWord and Build).
-// For simple cases (build immediately after adding words), use b.ib instead.
+// ibWordsPerBuilder is the word capacity newIB gives each builder; longer
+// instructions grow their builder's buffer.
+const ibWordsPerBuilder = 8
+
+// newIB returns an empty InstructionBuilder that allocates from the module's
+// arena. Use this for cases where the builder must survive across calls to
+// other emit methods (e.g. when emitExpression is called between AddWord and
+// Build). For simple cases (build immediately after adding words), use b.ib
+// instead.
+//
+// Builders come from a slab that is refilled when exhausted and rewound by
+// Reset, so a reused Backend allocates none in steady state. A builder must
+// not be used after the compilation that obtained it.
 func (b *Backend) newIB() *InstructionBuilder {
-	return &InstructionBuilder{
-		words: make([]uint32, 0, 8),
-		arena: &b.builder.arena,
+	if b.ibNext == len(b.ibSlab) {
+		n := max(16, 2*len(b.ibSlab))
+		b.ibSlab = make([]InstructionBuilder, n)
+		b.ibWords = make([]uint32, n*ibWordsPerBuilder)
+		b.ibNext = 0
 	}
+	off := b.ibNext * ibWordsPerBuilder
+	ib := &b.ibSlab[b.ibNext]
+	b.ibNext++
+	ib.words = b.ibWords[off : off : off+ibWordsPerBuilder]
+	ib.arena = &b.builder.arena
+	return ib
 }
 
 // Compile translates an IR module to SPIR-V binary.
 // The Backend is automatically reset before each compilation, so
 // a single Backend instance can be reused across multiple Compile calls.
 func (b *Backend) Compile(module *ir.Module) ([]byte, error) {
+	return b.CompileContext(context.Background(), module)
+}
+
+// CompileContext is like Compile but stops with ctx.Err() once ctx is
+// canceled. Cancellation is checked between functions.
+func (b *Backend) CompileContext(ctx context.Context, module *ir.Module) ([]byte, error) {
 	// Reset all per-compilation state (maps cleared, slices truncated).
 	b.Reset()
 	b.module = module
+	b.notes = backend.NewNotes(b.options.Logger, "spirv")
+	b.notes.Phase("spirv: writing module", "version", fmt.Sprintf("%d.%d", b.options.Version.Major, b.options.Version.Minor), "entry_points", len(module.EntryPoints))
 
 	// Reuse or create the ModuleBuilder.
 	if b.builder != nil {
@@ -362,7 +430,7 @@ func (b *Backend) Compile(module *ir.Module) ([]byte, error) {
 	}
 
 	// 11. Functions
-	if err := b.emitFunctions(); err != nil {
+	if err := b.emitFunctions(ctx); err != nil {
 		return nil, err
 	}
 
@@ -383,7 +451,26 @@ func (b *Backend) Compile(module *ir.Module) ([]byte, error) {
 		b.addCapability(CapabilityLinkage)
 	}
 
-	return b.builder.Build(), nil
+	// 14. Drop declarations nothing ended up needing
+	if b.options.TrimUnusedCapabilities {
+		keep := make(map[Capability]bool, len(b.options.Capabilities))
+		for _, c := range b.options.Capabilities {
+			keep[c] = true
+		}
+		b.builder.trimUnused(keep)
+	}
+
+	// 15. Caller-requested extensions and decorations
+	if err := b.emitExtraDeclarations(); err != nil {
+		return nil, err
+	}
+
+	code := b.builder.Build()
+	if b.options.SourceMap {
+		b.sourceMap = b.builder.instructionSpans(b.idSpans)
+	}
+	b.notes.Phase("spirv: module written", "bytes", len(code))
+	return code, nil
 }
 
 // emitCapabilities adds required SPIR-V capabilities.
@@ -449,6 +536,66 @@ func (b *Backend) addExtension(name string) {
 	}
 }
 
+// emitExtraDeclarations adds Options.Extensions and
+// Options.BindingDecorations once the module is otherwise complete, so
+// trimming cannot drop them. Every binding a decoration names must be a
+// global variable of the module.
+func (b *Backend) emitExtraDeclarations() error {
+	for _, name := range b.options.Extensions {
+		if name == "" {
+			return fmt.Errorf("spirv: empty extension name")
+		}
+		b.addExtension(name)
+	}
+	for i, d := range b.options.BindingDecorations {
+		switch d.Decoration {
+		case DecorationBinding, DecorationDescriptorSet:
+			return fmt.Errorf("spirv: binding decoration %d: @group and @binding are written by the backend", i)
+		}
+		if len(d.IDOperands) > 0 && len(d.Operands) > 0 {
+			return fmt.Errorf("spirv: binding decoration %d: both literal and ID operands", i)
+		}
+		if len(d.IDOperands) > 0 && b.langVersion() < 0x10200 {
+			return fmt.Errorf("spirv: binding decoration %d: OpDecorateId needs SPIR-V 1.2", i)
+		}
+		targets := b.bindingVariableIDs(d.Binding)
+		if len(targets) == 0 {
+			return fmt.Errorf("spirv: binding decoration %d: no variable at @group(%d) @binding(%d)", i, d.Binding.Group, d.Binding.Binding)
+		}
+		ids := make([]uint32, 0, len(d.IDOperands))
+		for _, operand := range d.IDOperands {
+			vars := b.bindingVariableIDs(operand)
+			if len(vars) != 1 {
+				return fmt.Errorf("spirv: binding decoration %d: operand @group(%d) @binding(%d) names %d variables, want 1", i, operand.Group, operand.Binding, len(vars))
+			}
+			ids = append(ids, vars[0])
+		}
+		for _, id := range targets {
+			if len(d.IDOperands) > 0 {
+				b.builder.AddDecorateID(id, d.Decoration, ids...)
+			} else {
+				b.builder.AddDecorate(id, d.Decoration, d.Operands...)
+			}
+		}
+	}
+	return nil
+}
+
+// bindingVariableIDs returns the IDs of the global variables bound at
+// binding, in handle order.
+func (b *Backend) bindingVariableIDs(binding ir.ResourceBinding) []uint32 {
+	var ids []uint32
+	for handle, global := range b.module.GlobalVariables {
+		if global.Binding == nil || *global.Binding != binding {
+			continue
+		}
+		if id, ok := b.globalIDs[ir.GlobalVariableHandle(handle)]; ok {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
 // decorateNonUniformBindingArrayAccess adds the ShaderNonUniform capability,
 // the SPV_EXT_descriptor_indexing extension, and a NonUniform decoration to id.
 // Matches Rust naga's decorate_non_uniform_binding_array_access.
@@ -618,11 +765,10 @@ func (b *Backend) emitType(handle ir.TypeHandle) (uint32, error) {
 
 		if inner.Size.Constant != nil {
 			// Fixed-size array
-			u32TypeID, err := b.emitScalarType(ir.ScalarType{Kind: ir.ScalarUint, Width: 4})
+			sizeID, err := b.emitArrayLength(inner.Size)
 			if err != nil {
 				return 0, err
 			}
-			sizeID := b.builder.AddConstant(u32TypeID, *inner.Size.Constant)
 			id = b.builder.AddTypeArray(baseID, sizeID)
 		} else {
 			// Runtime-sized array (storage buffers)
@@ -813,11 +959,10 @@ func (b *Backend) emitTypeWithoutLayout(handle ir.TypeHandle) (uint32, error) {
 
 		if inner.Size.Constant != nil {
 			// Fixed-size array without ArrayStride
-			u32TypeID, err := b.emitScalarType(ir.ScalarType{Kind: ir.ScalarUint, Width: 4})
+			sizeID, err := b.emitArrayLength(inner.Size)
 			if err != nil {
 				return 0, err
 			}
-			sizeID := b.builder.AddConstant(u32TypeID, *inner.Size.Constant)
 			id = b.builder.AddTypeArray(baseID, sizeID)
 		} else {
 			// Runtime-sized array (no ArrayStride needed)
@@ -2062,6 +2207,8 @@ func builtinToSPIRV(builtin ir.BuiltinValue, storageClass StorageClass) BuiltIn
 		return BuiltInVertexIndex
 	case ir.BuiltinInstanceIndex:
 		return BuiltInInstanceIndex
+	case ir.BuiltinPointSize:
+		return BuiltInPointSize
 	case ir.BuiltinFrontFacing:
 		return BuiltInFrontFacing
 	case ir.BuiltinFragDepth:
@@ -2215,6 +2362,9 @@ func (b *Backend) emitEntryPoints() error {
 				entryPoint.Workgroup[0],
 				entryPoint.Workgroup[1],
 				entryPoint.Workgroup[2])
+			if err := b.emitWorkgroupSizeOverrides(entryPoint); err != nil {
+				return err
+			}
 		}
 	}
 	return nil
@@ -2320,6 +2470,7 @@ func (b *Backend) emitWorkgroupInitPolyfill(epIdx int, fn *ir.Function, emitter
 	if len(workgroupVars) == 0 {
 		return nil
 	}
+	b.notes.Polyfill("workgroup memory zero-initialization")
 
 	// Check if the entry point already has a LocalInvocationId argument
 	var localInvocID uint32 // the loaded vec3<u32> value
@@ -2487,11 +2638,11 @@ func (b *Backend) emitWrappedFunctions(fn *ir.Function) error {
 			continue
 		}
 		// Resolve left operand type to get scalar kind
-		leftType, err := ir.ResolveExpressionType(b.module, fn, binary.Left)
+		leftType, err := ir.ExpressionType(b.module, fn, binary.Left)
 		if err != nil {
 			continue
 		}
-		rightType, err := ir.ResolveExpressionType(b.module, fn, binary.Right)
+		rightType, err := ir.ExpressionType(b.module, fn, binary.Right)
 		if err != nil {
 			continue
 		}
@@ -2733,13 +2884,13 @@ func (b *Backend) emitWrappedBinaryOp(op ir.BinaryOperator, returnTypeInner ir.T
 	})
 
 	// Serialize to the module's function definitions
-	b.builder.functions = append(b.builder.functions, fb.ToInstructions()...)
+	b.builder.functions = fb.AppendInstructions(b.builder.functions)
 
 	return nil
 }
 
 // emitFunctions emits all functions (both regular and entry point).
-func (b *Backend) emitFunctions() error {
+func (b *Backend) emitFunctions(ctx context.Context) error {
 	// First, scan all functions and entry points for integer div/mod,
 	// and emit wrapper helper functions. This must happen before emitting
 	// any regular functions, matching Rust naga's write_wrapped_functions
@@ -2761,8 +2912,13 @@ func (b *Backend) emitFunctions() error {
 		}
 	}
 
+	b.collectPositionFlows()
+
 	// Emit regular functions
 	for handle := range b.module.Functions {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
 		fn := &b.module.Functions[handle]
 		if err := b.emitRegularFunction(ir.FunctionHandle(handle), fn); err != nil {
 			return err
@@ -2774,6 +2930,9 @@ func (b *Backend) emitFunctions() error {
 		if b.module.EntryPoints[epIdx].Stage == ir.StageTask || b.module.EntryPoints[epIdx].Stage == ir.StageMesh {
 			continue
 		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
 		fn := &b.module.EntryPoints[epIdx].Function
 		if err := b.emitEntryPointFunction(epIdx, fn); err != nil {
 			return err
@@ -2782,6 +2941,31 @@ func (b *Backend) emitFunctions() error {
 	return nil
 }
 
+// collectPositionFlows computes the arithmetic feeding vertex position
+// outputs for NoContractionPosition.
+func (b *Backend) collectPositionFlows() {
+	b.positionFlows = nil
+	b.preciseFunctions = nil
+	if b.options.NoContraction != NoContractionPosition {
+		return
+	}
+	b.positionFlows = make(map[int]*ir.PositionFlow)
+	b.preciseFunctions = make([]bool, len(b.module.Functions))
+	for epIdx := range b.module.EntryPoints {
+		if b.module.EntryPoints[epIdx].Stage != ir.StageVertex {
+			continue
+		}
+		flow := ir.PositionDataflow(b.module, epIdx)
+		if flow == nil {
+			continue
+		}
+		b.positionFlows[epIdx] = flow
+		for h, precise := range flow.Functions {
+			b.preciseFunctions[h] = b.preciseFunctions[h] || precise
+		}
+	}
+}
+
 // emitRegularFunction emits a regular (non-entry-point) function.
 func (b *Backend) emitRegularFunction(handle ir.FunctionHandle, fn *ir.Function) error {
 	return b.emitFunctionImpl(fn, false, handle, -1)
@@ -2937,13 +3121,18 @@ func (b *Backend) emitFunctionImpl(fn *ir.Function, isEntryPoint bool, handle ir
 	}
 
 	// Create the entry block. The label was allocated earlier to match ID ordering.
+	// Most expressions become one instruction, and straight-line functions
+	// keep them all in the entry block.
 	entryBlock := NewBlock(entryBlockLabel)
+	entryBlock.Body = make([]Instruction, 0, len(fn.Expressions))
 
 	// 3. Create expression emitter for this function
 	emitter := &ExpressionEmitter{
 		backend:               b,
 		function:              fn,
 		exprIDs:               make(map[ir.ExpressionHandle]uint32, len(fn.Expressions)),
+		live:                  ir.LiveExpressions(fn),
+		types:                 ir.ExpressionTypeTable(b.module, fn),
 		paramIDs:              paramIDs,
 		isEntryPoint:          isEntryPoint,
 		epIdx:                 epIdx,
@@ -2958,6 +3147,16 @@ func (b *Backend) emitFunctionImpl(fn *ir.Function, isEntryPoint bool, handle ir
 		rayQueryTrackers:      make(map[ir.ExpressionHandle]rayQueryTrackerIDs),
 	}
 	emitter.localVarIDs = localVarIDs
+	switch {
+	case b.options.NoContraction == NoContractionAll:
+		emitter.preciseAll = true
+	case isEntryPoint:
+		if flow := b.positionFlows[epIdx]; flow != nil {
+			emitter.precise = flow.Expressions
+		}
+	case int(handle) < len(b.preciseFunctions):
+		emitter.preciseAll = b.preciseFunctions[handle]
+	}
 
 	// Associate ray query tracker variables with expression handles.
 	// When an expression is ExprLocalVariable referencing a ray_query local var,
@@ -3293,7 +3492,7 @@ func (b *Backend) emitFunctionImpl(fn *ir.Function, isEntryPoint bool, handle ir
 	b.builder.funcSink = nil
 
 	// Serialize all blocks into the flat instruction list
-	b.builder.functions = append(b.builder.functions, fb.ToInstructions()...)
+	b.builder.functions = fb.AppendInstructions(b.builder.functions)
 
 	return nil
 }
@@ -3303,8 +3502,15 @@ type ExpressionEmitter struct {
 	backend     *Backend
 	function    *ir.Function // Renamed from fn for consistency
 	exprIDs     map[ir.ExpressionHandle]uint32
-	paramIDs    []uint32 // Function parameter IDs (or loaded input values for entry points)
-	localVarIDs []uint32 // Local variable IDs
+	live        []bool              // Expressions reachable from statements; Emit skips the rest
+	types       []ir.TypeResolution // Resolved type of each expression; zero where resolution failed
+	paramIDs    []uint32            // Function parameter IDs (or loaded input values for entry points)
+	localVarIDs []uint32            // Local variable IDs
+
+	// NoContraction: preciseAll decorates all float arithmetic of the
+	// function; otherwise precise marks the expressions to decorate.
+	preciseAll bool
+	precise    []bool
 
 	// Entry point context
 	isEntryPoint bool              // True if this is an entry point function
@@ -3321,6 +3527,11 @@ type ExpressionEmitter struct {
 	// Passed by value to ensure nested loops get isolated copies.
 	loopCtx LoopContext
 
+	// Labels a break statement has branched to. A switch whose cases all
+	// end in a terminator still has a reachable merge block if one of them
+	// breaks out of it.
+	breakTargets map[uint32]bool
+
 	// Cached call result IDs (set by emitCall, read by ExprCallResult)
 	callResultIDs map[ir.ExpressionHandle]uint32
 
@@ -3454,6 +3665,17 @@ func (e *ExpressionEmitter) isNonUniformExpression(handle ir.ExpressionHandle) b
 	}
 }
 
+// resolveType returns the type of an expression of the function, resolved
+// when the emitter was created.
+func (e *ExpressionEmitter) resolveType(handle ir.ExpressionHandle) (ir.TypeResolution, error) {
+	if int(handle) < len(e.types) {
+		if res := e.types[handle]; res.Handle != nil || res.Value != nil {
+			return res, nil
+		}
+	}
+	return ir.ExpressionType(e.backend.module, e.function, handle)
+}
+
 // setCurrentBlock switches the instruction emission sink to a new block.
 // All subsequent Add* calls on the ModuleBuilder will append to this block's body.
 func (e *ExpressionEmitter) setCurrentBlock(block *Block) {
@@ -3596,11 +3818,20 @@ func (e *ExpressionEmitter) emitExpression(handle ir.ExpressionHandle) (uint32,
 	var id uint32
 	var err error
 
+	if e.preciseAll || e.precise != nil {
+		builder := e.backend.builder
+		prev := builder.noContraction
+		builder.noContraction = e.preciseAll || (int(handle) < len(e.precise) && e.precise[handle])
+		defer func() { builder.noContraction = prev }()
+	}
+
 	switch kind := expr.Kind.(type) {
 	case ir.Literal:
 		id, err = e.emitLiteral(kind.Value)
 	case ir.ExprConstant:
 		return e.emitConstantRef(kind)
+	case ir.ExprOverride:
+		return e.backend.emitOverride(kind.Override)
 	case ir.ExprZeroValue:
 		// OpConstantNull — zero value for any type (matches Rust naga)
 		typeID, err := e.backend.emitType(kind.Type)
@@ -3637,6 +3868,8 @@ func (e *ExpressionEmitter) emitExpression(handle ir.ExpressionHandle) (uint32,
 		id, err = e.emitMath(kind)
 	case ir.ExprDerivative:
 		id, err = e.emitDerivative(kind)
+	case ir.ExprRelational:
+		id, err = e.emitRelational(handle, kind)
 	case ir.ExprImageSample:
 		id, err = e.emitImageSample(kind)
 	case ir.ExprImageLoad:
@@ -3677,9 +3910,43 @@ func (e *ExpressionEmitter) emitExpression(handle ir.ExpressionHandle) (uint32,
 
 	// Cache the result
 	e.exprIDs[handle] = id
+	if e.backend.options.Debug && e.backend.options.DebugExpressionNames {
+		e.backend.nameExpression(id, expr)
+	}
+	if e.backend.options.SourceMap {
+		e.backend.recordSpan(id, expr)
+	}
 	return id, nil
 }
 
+// maxExpressionNameLen bounds the OpName nameExpression emits, in bytes.
+const maxExpressionNameLen = 48
+
+// nameExpression names id after the source text expr was lowered from,
+// with whitespace runs collapsed and long text truncated. Literals and
+// zero values are constants the builder shares, and an ID several
+// expressions map to keeps its first name.
+func (b *Backend) nameExpression(id uint32, expr *ir.Expression) {
+	switch expr.Kind.(type) {
+	case ir.Literal, ir.ExprZeroValue:
+		return
+	}
+	source := b.options.DebugSource
+	if b.namedExprIDs[id] || expr.Span.IsZero() || int(expr.Span.End) > len(source) || expr.Span.Start >= expr.Span.End {
+		return
+	}
+	name := strings.Join(strings.Fields(source[expr.Span.Start:expr.Span.End]), " ")
+	if len(name) > maxExpressionNameLen {
+		cut := maxExpressionNameLen - len("...")
+		for cut > 0 && !utf8.RuneStart(name[cut]) {
+			cut--
+		}
+		name = name[:cut] + "..."
+	}
+	b.namedExprIDs[id] = true
+	b.builder.AddName(id, name)
+}
+
 // emitConstExpression emits an expression as a SPIR-V constant (in the declarations section).
 // This is required for SPIR-V operands that must be constant, such as ConstOffset for image sampling.
 // WGSL guarantees texture offsets are const-expressions, so this handles Literal, Compose of constants,
@@ -3723,7 +3990,7 @@ func (e *ExpressionEmitter) emitConstExpression(handle ir.ExpressionHandle) (uin
 		id = e.backend.builder.AddConstantComposite(typeID, componentIDs...)
 	case ir.ExprSplat:
 		// Splat as constant composite
-		valueType, rErr := ir.ResolveExpressionType(e.backend.module, e.function, kind.Value)
+		valueType, rErr := e.resolveType(kind.Value)
 		if rErr != nil {
 			return 0, fmt.Errorf("splat value type: %w", rErr)
 		}
@@ -3763,96 +4030,101 @@ func (e *ExpressionEmitter) emitConstExpression(handle ir.ExpressionHandle) (uin
 
 // emitLiteral emits a literal value.
 func (e *ExpressionEmitter) emitLiteral(value ir.LiteralValue) (uint32, error) {
+	return e.backend.emitLiteral(value)
+}
+
+// emitLiteral emits a literal value as a constant.
+func (b *Backend) emitLiteral(value ir.LiteralValue) (uint32, error) {
 	switch v := value.(type) {
 	case ir.LiteralF32:
-		typeID, err := e.backend.emitScalarType(ir.ScalarType{Kind: ir.ScalarFloat, Width: 4})
+		typeID, err := b.emitScalarType(ir.ScalarType{Kind: ir.ScalarFloat, Width: 4})
 		if err != nil {
 			return 0, err
 		}
-		return e.backend.builder.AddConstantFloat32(typeID, float32(v)), nil
+		return b.builder.AddConstantFloat32(typeID, float32(v)), nil
 
 	case ir.LiteralF64:
-		typeID, err := e.backend.emitScalarType(ir.ScalarType{Kind: ir.ScalarFloat, Width: 8})
+		typeID, err := b.emitScalarType(ir.ScalarType{Kind: ir.ScalarFloat, Width: 8})
 		if err != nil {
 			return 0, err
 		}
-		return e.backend.builder.AddConstantFloat64(typeID, float64(v)), nil
+		return b.builder.AddConstantFloat64(typeID, float64(v)), nil
 
 	case ir.LiteralU32:
-		typeID, err := e.backend.emitScalarType(ir.ScalarType{Kind: ir.ScalarUint, Width: 4})
+		typeID, err := b.emitScalarType(ir.ScalarType{Kind: ir.ScalarUint, Width: 4})
 		if err != nil {
 			return 0, err
 		}
-		return e.backend.builder.AddConstant(typeID, uint32(v)), nil
+		return b.builder.AddConstant(typeID, uint32(v)), nil
 
 	case ir.LiteralI32:
-		typeID, err := e.backend.emitScalarType(ir.ScalarType{Kind: ir.ScalarSint, Width: 4})
+		typeID, err := b.emitScalarType(ir.ScalarType{Kind: ir.ScalarSint, Width: 4})
 		if err != nil {
 			return 0, err
 		}
-		return e.backend.builder.AddConstant(typeID, uint32(v)), nil
+		return b.builder.AddConstant(typeID, uint32(v)), nil
 
 	case ir.LiteralAbstractInt:
 		// Abstract integers default to i32 in SPIR-V
-		typeID, err := e.backend.emitScalarType(ir.ScalarType{Kind: ir.ScalarSint, Width: 4})
+		typeID, err := b.emitScalarType(ir.ScalarType{Kind: ir.ScalarSint, Width: 4})
 		if err != nil {
 			return 0, err
 		}
-		return e.backend.builder.AddConstant(typeID, uint32(int32(v))), nil
+		return b.builder.AddConstant(typeID, uint32(int32(v))), nil
 
 	case ir.LiteralAbstractFloat:
 		// Abstract floats default to f32 in SPIR-V
-		typeID, err := e.backend.emitScalarType(ir.ScalarType{Kind: ir.ScalarFloat, Width: 4})
+		typeID, err := b.emitScalarType(ir.ScalarType{Kind: ir.ScalarFloat, Width: 4})
 		if err != nil {
 			return 0, err
 		}
-		return e.backend.builder.AddConstantFloat32(typeID, float32(v)), nil
+		return b.builder.AddConstantFloat32(typeID, float32(v)), nil
 
 	case ir.LiteralF16:
-		typeID, err := e.backend.emitScalarType(ir.ScalarType{Kind: ir.ScalarFloat, Width: 2})
+		typeID, err := b.emitScalarType(ir.ScalarType{Kind: ir.ScalarFloat, Width: 2})
 		if err != nil {
 			return 0, err
 		}
 		// F16 is stored as a 32-bit word with the 16-bit float in the low bits.
 		// Convert float32 value to float16 bit representation (IEEE 754 half-precision).
-		return e.backend.builder.AddConstant(typeID, float32ToF16Bits(float32(v))), nil
+		return b.builder.AddConstant(typeID, float32ToF16Bits(float32(v))), nil
 
 	case ir.LiteralI64:
-		typeID, err := e.backend.emitScalarType(ir.ScalarType{Kind: ir.ScalarSint, Width: 8})
+		typeID, err := b.emitScalarType(ir.ScalarType{Kind: ir.ScalarSint, Width: 8})
 		if err != nil {
 			return 0, err
 		}
 		bits := uint64(v)
-		return e.backend.builder.AddConstant(typeID, uint32(bits&0xFFFFFFFF), uint32(bits>>32)), nil
+		return b.builder.AddConstant(typeID, uint32(bits&0xFFFFFFFF), uint32(bits>>32)), nil
 
 	case ir.LiteralU64:
-		typeID, err := e.backend.emitScalarType(ir.ScalarType{Kind: ir.ScalarUint, Width: 8})
+		typeID, err := b.emitScalarType(ir.ScalarType{Kind: ir.ScalarUint, Width: 8})
 		if err != nil {
 			return 0, err
 		}
 		bits := uint64(v)
-		return e.backend.builder.AddConstant(typeID, uint32(bits&0xFFFFFFFF), uint32(bits>>32)), nil
+		return b.builder.AddConstant(typeID, uint32(bits&0xFFFFFFFF), uint32(bits>>32)), nil
 
 	case ir.LiteralBool:
-		typeID, err := e.backend.emitScalarType(ir.ScalarType{Kind: ir.ScalarBool, Width: 1})
+		typeID, err := b.emitScalarType(ir.ScalarType{Kind: ir.ScalarBool, Width: 1})
 		if err != nil {
 			return 0, err
 		}
 		if v {
 			// OpConstantTrue
-			resultID := e.backend.builder.AllocID()
-			builder := e.newIB()
+			resultID := b.builder.AllocID()
+			builder := b.newIB()
 			builder.AddWord(typeID)
 			builder.AddWord(resultID)
-			e.backend.builder.types = append(e.backend.builder.types, builder.Build(OpConstantTrue))
+			b.builder.types = append(b.builder.types, builder.Build(OpConstantTrue))
 			return resultID, nil
 		}
 		// OpConstantFalse
-		resultID := e.backend.builder.AllocID()
-		builder := e.newIB()
+		resultID := b.builder.AllocID()
+		builder := b.newIB()
 		builder.AddWord(typeID)
 		builder.AddWord(resultID)
-		e.backend.builder.types = append(e.backend.builder.types, builder.Build(OpConstantFalse))
+		b.builder.types = append(b.builder.types, builder.Build(OpConstantFalse))
 		return resultID, nil
 
 	default:
@@ -4098,7 +4370,7 @@ func (e *ExpressionEmitter) emitCompose(compose ir.ExprCompose) (uint32, error)
 // In SPIR-V, this is OpCompositeConstruct with the same scalar ID repeated.
 func (e *ExpressionEmitter) emitSplat(splat ir.ExprSplat) (uint32, error) {
 	// Resolve the scalar type from the splat value
-	valueType, err := ir.ResolveExpressionType(e.backend.module, e.function, splat.Value)
+	valueType, err := e.resolveType(splat.Value)
 	if err != nil {
 		return 0, fmt.Errorf("splat value type: %w", err)
 	}
@@ -4186,7 +4458,7 @@ func (e *ExpressionEmitter) maybeCopyLogicalForStore(pointerExpr, valueExpr ir.E
 	}
 
 	// Resolve the value expression's IR type to check if it's a composite needing layout.
-	valueTypeRes, err := ir.ResolveExpressionType(e.backend.module, e.function, valueExpr)
+	valueTypeRes, err := e.resolveType(valueExpr)
 	if err != nil {
 		return valueID, err
 	}
@@ -4343,7 +4615,7 @@ func (e *ExpressionEmitter) resolveAccessElementType(baseType ir.TypeResolution,
 // Returns the VALUE at the indexed location (not a pointer).
 func (e *ExpressionEmitter) emitAccess(exprHandle ir.ExpressionHandle, access ir.ExprAccess) (uint32, error) {
 	// Get result type from type inference
-	baseType, err := ir.ResolveExpressionType(e.backend.module, e.function, access.Base)
+	baseType, err := e.resolveType(access.Base)
 	if err != nil {
 		return 0, fmt.Errorf("access base type: %w", err)
 	}
@@ -4374,8 +4646,9 @@ func (e *ExpressionEmitter) emitAccess(exprHandle ir.ExpressionHandle, access ir
 		// Matches Rust naga's is_nonuniform_binding_array_access + decorate pattern.
 		isNonUniformBA := e.isNonUniformBindingArrayAccess(access.Base, access.Index)
 
-		// Base is a pointer - use emitPointerExpression to get SPIR-V pointer, then OpAccessChain
-		baseID, err := e.emitPointerExpression(access.Base)
+		// Base is a pointer - emit the access chain to the element, then auto-load.
+		// emitAccessChain decorates a non-uniform binding array access.
+		ptrID, err := e.emitAccessChain(exprHandle)
 		if err != nil {
 			return 0, err
 		}
@@ -4390,18 +4663,6 @@ func (e *ExpressionEmitter) emitAccess(exprHandle ir.ExpressionHandle, access ir
 			return 0, err
 		}
 
-		// Create pointer type for OpAccessChain result
-		ptrType := e.backend.emitPointerType(storageClass, accessElementTypeID)
-
-		// OpAccessChain returns a pointer, then we auto-load
-		ptrID := e.backend.builder.AddAccessChain(ptrType, baseID, indexID)
-
-		// For non-uniform binding array access, decorate the pointer (AccessChain result)
-		// with NonUniform. See VUID-RuntimeSpirv-NonUniform-06274.
-		if isNonUniformBA {
-			e.backend.decorateNonUniformBindingArrayAccess(ptrID)
-		}
-
 		// SPIR-V forbids OpLoad on runtime-sized arrays and types containing them.
 		// Return the pointer directly; downstream Access/AccessIndex will use
 		// OpAccessChain to reach individual elements.
@@ -4466,6 +4727,13 @@ func (e *ExpressionEmitter) emitAccess(exprHandle ir.ExpressionHandle, access ir
 	}
 }
 
+// isLive reports whether an expression is reachable from a statement, a named
+// expression, or a local variable initializer. Handles past the liveness table
+// (expressions appended after it was computed) count as live.
+func (e *ExpressionEmitter) isLive(handle ir.ExpressionHandle) bool {
+	return int(handle) >= len(e.live) || e.live[handle]
+}
+
 // isVariableReference returns true if the expression is a variable reference
 // (GlobalVariable or LocalVariable). These are pointer expressions in the WGSL
 // Load Rule model and should not be emitted directly in the Emit loop.
@@ -4559,10 +4827,8 @@ func (e *ExpressionEmitter) emitPointerExpression(handle ir.ExpressionHandle) (u
 		return varID, nil
 	case ir.ExprFunctionArgument:
 		return e.emitFunctionArgRef(k)
-	case ir.ExprAccessIndex:
-		return e.emitAccessIndexAsPointer(k)
-	case ir.ExprAccess:
-		return e.emitAccessAsPointer(k)
+	case ir.ExprAccessIndex, ir.ExprAccess:
+		return e.emitAccessChain(handle)
 	case ir.ExprLoad:
 		// ExprLoad dereferences a pointer. For pointer chain contexts (store targets,
 		// access chains), skip the load and pass through to the underlying pointer.
@@ -4573,27 +4839,30 @@ func (e *ExpressionEmitter) emitPointerExpression(handle ir.ExpressionHandle) (u
 	}
 }
 
-// emitAccessIndexAsPointer emits an access index as a SPIR-V pointer without loading.
-func (e *ExpressionEmitter) emitAccessIndexAsPointer(access ir.ExprAccessIndex) (uint32, error) {
-	baseID, err := e.emitPointerExpression(access.Base)
-	if err != nil {
-		return 0, err
-	}
-
-	// Get result type from type inference
-	baseType, err := ir.ResolveExpressionType(e.backend.module, e.function, access.Base)
-	if err != nil {
-		return 0, fmt.Errorf("access index base type: %w", err)
+// emitAccessChain emits the pointer an Access or AccessIndex on a pointer
+// yields as a single OpAccessChain. The indices of the contiguous accesses
+// down to the variable or pointer argument they start from become its
+// operands, so a.b[i].c is one instruction instead of one per level, each
+// needing its own pointer type. Loads the chain passes through are skipped,
+// as in emitPointerExpression.
+func (e *ExpressionEmitter) emitAccessChain(handle ir.ExpressionHandle) (uint32, error) {
+	var base ir.ExpressionHandle
+	var elementType ir.TypeResolution
+	var err error
+	switch k := e.function.Expressions[handle].Kind.(type) {
+	case ir.ExprAccessIndex:
+		base = k.Base
+		elementType, err = e.resolveAccessBaseElement(k.Base, int(k.Index))
+	case ir.ExprAccess:
+		base = k.Base
+		elementType, err = e.resolveAccessBaseElement(k.Base, -1)
+	default:
+		return 0, fmt.Errorf("expression %T is not an access", k)
 	}
-
-	// Determine the element type.
-	// Determine the element type, unwrapping pointer types
-	elementType, err := e.resolveAccessElementType(baseType, int(access.Index))
 	if err != nil {
-		return 0, fmt.Errorf("access index as pointer element type: %w", err)
+		return 0, err
 	}
-
-	storageClass, err := e.getExpressionStorageClass(access.Base)
+	storageClass, err := e.getExpressionStorageClass(base)
 	if err != nil {
 		return 0, err
 	}
@@ -4607,55 +4876,94 @@ func (e *ExpressionEmitter) emitAccessIndexAsPointer(access ir.ExprAccessIndex)
 	if err != nil {
 		return 0, err
 	}
-	indexID := e.backend.builder.AddConstant(u32Type, access.Index)
 
-	ptrType := e.backend.emitPointerType(storageClass, elementTypeID)
-	return e.backend.builder.AddAccessChain(ptrType, baseID, indexID), nil
-}
+	// Collect the indices from the outermost access in, then reverse them.
+	var indices []uint32
+	nonUniform := false
+	root := handle
+walk:
+	for {
+		switch k := e.function.Expressions[root].Kind.(type) {
+		case ir.ExprAccessIndex:
+			indices = append(indices, e.backend.builder.AddConstant(u32Type, k.Index))
+			root = k.Base
+		case ir.ExprAccess:
+			indexID, err := e.emitExpression(k.Index)
+			if err != nil {
+				return 0, err
+			}
+			indices = append(indices, indexID)
+			// A non-uniform binding array index makes the resulting
+			// pointer non-uniform. See VUID-RuntimeSpirv-NonUniform-06274.
+			nonUniform = nonUniform || e.isNonUniformBindingArrayAccess(k.Base, k.Index)
+			root = k.Base
+		case ir.ExprLoad:
+			root = k.Pointer
+		default:
+			break walk
+		}
+	}
 
-// emitAccessAsPointer emits a dynamic access as a SPIR-V pointer without loading.
-func (e *ExpressionEmitter) emitAccessAsPointer(access ir.ExprAccess) (uint32, error) {
-	baseID, err := e.emitPointerExpression(access.Base)
-	if err != nil {
+	var baseID uint32
+	if gv, ok := e.function.Expressions[root].Kind.(ir.ExprGlobalVariable); ok && e.backend.wrappedStorageVars[gv.Variable] {
+		// Index through the wrapper struct as part of the same chain.
+		if baseID, err = e.emitGlobalVarRef(gv); err != nil {
+			return 0, err
+		}
+		indices = append(indices, e.backend.builder.AddConstant(u32Type, 0))
+	} else if baseID, err = e.emitPointerExpression(root); err != nil {
 		return 0, err
 	}
+	slices.Reverse(indices)
 
-	indexID, err := e.emitExpression(access.Index)
-	if err != nil {
-		return 0, err
+	ptrType := e.backend.emitPointerType(storageClass, elementTypeID)
+	ptrID := e.backend.builder.AddAccessChain(ptrType, baseID, indices...)
+	if nonUniform {
+		e.backend.decorateNonUniformBindingArrayAccess(ptrID)
 	}
+	return ptrID, nil
+}
 
-	// Get result type from type inference
-	baseType, err := ir.ResolveExpressionType(e.backend.module, e.function, access.Base)
+// resolveAccessBaseElement returns the type of element index of the
+// expression base, unwrapping a pointer; index -1 is a dynamic index.
+func (e *ExpressionEmitter) resolveAccessBaseElement(base ir.ExpressionHandle, index int) (ir.TypeResolution, error) {
+	baseType, err := e.resolveType(base)
 	if err != nil {
-		return 0, fmt.Errorf("access base type: %w", err)
+		return ir.TypeResolution{}, fmt.Errorf("access base type: %w", err)
 	}
-
-	// Determine the element type, unwrapping pointer types
-	elementType, err := e.resolveAccessElementType(baseType, -1)
+	elementType, err := e.resolveAccessElementType(baseType, index)
 	if err != nil {
-		return 0, fmt.Errorf("access as pointer element type: %w", err)
+		return ir.TypeResolution{}, fmt.Errorf("access element type: %w", err)
 	}
+	return elementType, nil
+}
 
-	storageClass, err := e.getExpressionStorageClass(access.Base)
-	if err != nil {
-		return 0, err
+// isPointerAccess reports whether handle is an Access or AccessIndex that
+// yields a pointer. Its OpAccessChain is emitted where the pointer is used,
+// as one chain from the variable, rather than level by level in its Emit
+// range.
+func (e *ExpressionEmitter) isPointerAccess(handle ir.ExpressionHandle) bool {
+	switch e.function.Expressions[handle].Kind.(type) {
+	case ir.ExprAccess, ir.ExprAccessIndex:
+	default:
+		return false
 	}
-	// Use layout-free element type for Workgroup (VUID-StandaloneSpirv-None-10684).
-	elementTypeID, err := e.backend.resolveTypeForStorageClass(elementType, storageClass)
+	res, err := e.resolveType(handle)
 	if err != nil {
-		return 0, err
+		return false
 	}
-
-	ptrType := e.backend.emitPointerType(storageClass, elementTypeID)
-	return e.backend.builder.AddAccessChain(ptrType, baseID, indexID), nil
+	switch e.resolveTypeInner(res).(type) {
+	case ir.PointerType, ir.ValuePointerType:
+		return true
+	}
+	return false
 }
 
 // emitAccessIndex emits a static index access operation.
 // Returns a VALUE (auto-loads from pointers). For pointer destinations, use emitAccessIndexAsPointer.
 func (e *ExpressionEmitter) emitAccessIndex(exprHandle ir.ExpressionHandle, access ir.ExprAccessIndex) (uint32, error) {
 	// Get result type from type inference
-	baseType, err := ir.ResolveExpressionType(e.backend.module, e.function, access.Base)
+	baseType, err := e.resolveType(access.Base)
 	if err != nil {
 		return 0, fmt.Errorf("access index base type: %w", err)
 	}
@@ -4677,18 +4985,12 @@ func (e *ExpressionEmitter) emitAccessIndex(exprHandle ir.ExpressionHandle, acce
 	}
 
 	if isPointerBase {
-		// Base is a pointer - use emitPointerExpression to get SPIR-V pointer,
-		// then OpAccessChain, then auto-load to return a VALUE.
-		baseID, err := e.emitPointerExpression(access.Base)
-		if err != nil {
-			return 0, err
-		}
-
-		u32Type, err := e.backend.emitScalarType(ir.ScalarType{Kind: ir.ScalarUint, Width: 4})
+		// Base is a pointer - emit the access chain to the element, then
+		// auto-load to return a VALUE.
+		ptrID, err := e.emitAccessChain(exprHandle)
 		if err != nil {
 			return 0, err
 		}
-		indexID := e.backend.builder.AddConstant(u32Type, access.Index)
 
 		storageClass, err := e.getExpressionStorageClass(access.Base)
 		if err != nil {
@@ -4700,9 +5002,6 @@ func (e *ExpressionEmitter) emitAccessIndex(exprHandle ir.ExpressionHandle, acce
 			return 0, err
 		}
 
-		ptrType := e.backend.emitPointerType(storageClass, accessElementTypeID)
-		ptrID := e.backend.builder.AddAccessChain(ptrType, baseID, indexID)
-
 		// SPIR-V forbids OpLoad on runtime-sized arrays and types containing them.
 		// Return the pointer directly; downstream Access/AccessIndex will use
 		// OpAccessChain to reach individual elements.
@@ -4751,7 +5050,7 @@ func (e *ExpressionEmitter) resolveTypeInner(res ir.TypeResolution) ir.TypeInner
 func (e *ExpressionEmitter) spillToInternalVariable(base ir.ExpressionHandle) error {
 	if _, alreadySpilled := e.spilledComposites[base]; !alreadySpilled {
 		// Create new Function-space variable for the base type.
-		baseType, _ := ir.ResolveExpressionType(e.backend.module, e.function, base)
+		baseType, _ := e.resolveType(base)
 		baseTypeID, err := e.backend.resolveTypeResolution(baseType)
 		if err != nil {
 			return err
@@ -4901,7 +5200,7 @@ func (e *ExpressionEmitter) emitSwizzle(swizzle ir.ExprSwizzle) (uint32, error)
 
 // extractVectorScalar extracts the scalar type from a vector expression.
 func (e *ExpressionEmitter) extractVectorScalar(handle ir.ExpressionHandle) (ir.ScalarType, error) {
-	vectorType, err := ir.ResolveExpressionType(e.backend.module, e.function, handle)
+	vectorType, err := e.resolveType(handle)
 	if err != nil {
 		return ir.ScalarType{}, fmt.Errorf("swizzle vector type: %w", err)
 	}
@@ -4928,7 +5227,7 @@ func (e *ExpressionEmitter) emitAs(as ir.ExprAs) (uint32, error) {
 	}
 
 	// Resolve source expression type to get source scalar kind
-	srcType, err := ir.ResolveExpressionType(e.backend.module, e.function, as.Expr)
+	srcType, err := e.resolveType(as.Expr)
 	if err != nil {
 		return 0, fmt.Errorf("as source type: %w", err)
 	}
@@ -5192,7 +5491,7 @@ func (e *ExpressionEmitter) emitLoad(load ir.ExprLoad) (uint32, error) {
 	// Get the pointer expression's type and dereference it to find the loaded value type.
 	// Pointer expressions (ExprLocalVariable, ExprGlobalVariable, etc.) resolve to
 	// PointerType/ValuePointerType. OpLoad needs the pointed-TO type, not the pointer type.
-	pointerType, err := ir.ResolveExpressionType(e.backend.module, e.function, load.Pointer)
+	pointerType, err := e.resolveType(load.Pointer)
 	if err != nil {
 		return 0, fmt.Errorf("load pointer type: %w", err)
 	}
@@ -5298,7 +5597,7 @@ func (e *ExpressionEmitter) emitUnary(unary ir.ExprUnary) (uint32, error) {
 	}
 
 	// Get operand type to determine correct opcode
-	operandType, err := ir.ResolveExpressionType(e.backend.module, e.function, unary.Expr)
+	operandType, err := e.resolveType(unary.Expr)
 	if err != nil {
 		return 0, fmt.Errorf("unary operand type: %w", err)
 	}
@@ -5366,7 +5665,7 @@ func (e *ExpressionEmitter) emitBinary(binary ir.ExprBinary) (uint32, error) {
 	}
 
 	// Get left operand type to determine correct opcode
-	leftType, err := ir.ResolveExpressionType(e.backend.module, e.function, binary.Left)
+	leftType, err := e.resolveType(binary.Left)
 	if err != nil {
 		return 0, fmt.Errorf("binary left type: %w", err)
 	}
@@ -5471,7 +5770,7 @@ func (e *ExpressionEmitter) emitBinary(binary ir.ExprBinary) (uint32, error) {
 			}
 			opcode = OpFAdd
 			// vec + scalar or scalar + vec: splat scalar to matching vector
-			rightType, rErr := ir.ResolveExpressionType(e.backend.module, e.function, binary.Right)
+			rightType, rErr := e.resolveType(binary.Right)
 			if rErr == nil {
 				var promErr error
 				leftID, rightID, resultType, promErr = e.promoteScalarToVector(leftType, rightType, leftID, rightID, resultType)
@@ -5491,7 +5790,7 @@ func (e *ExpressionEmitter) emitBinary(binary ir.ExprBinary) (uint32, error) {
 			}
 			opcode = OpFSub
 			// vec - scalar or scalar - vec: splat scalar to matching vector
-			rightType, rErr := ir.ResolveExpressionType(e.backend.module, e.function, binary.Right)
+			rightType, rErr := e.resolveType(binary.Right)
 			if rErr == nil {
 				var promErr error
 				leftID, rightID, resultType, promErr = e.promoteScalarToVector(leftType, rightType, leftID, rightID, resultType)
@@ -5506,7 +5805,7 @@ func (e *ExpressionEmitter) emitBinary(binary ir.ExprBinary) (uint32, error) {
 		if scalarKind == ir.ScalarFloat {
 			// Check for special multiplication cases (vector-scalar, matrix-vector, etc.)
 			// that require dedicated SPIR-V opcodes.
-			rightType, rightErr := ir.ResolveExpressionType(e.backend.module, e.function, binary.Right)
+			rightType, rightErr := e.resolveType(binary.Right)
 			if rightErr != nil {
 				return 0, fmt.Errorf("binary right type: %w", rightErr)
 			}
@@ -5576,7 +5875,7 @@ func (e *ExpressionEmitter) emitBinary(binary ir.ExprBinary) (uint32, error) {
 			// Integer multiplication: OpIMul requires matching types.
 			// For vector*scalar or scalar*vector, splat the scalar to match.
 			// Matches Rust naga's write_vector_scalar_mult (block.rs:2548).
-			rightType, _ := ir.ResolveExpressionType(e.backend.module, e.function, binary.Right)
+			rightType, _ := e.resolveType(binary.Right)
 			leftInner := typeResolutionInner(e.backend.module, leftType)
 			rightInner := typeResolutionInner(e.backend.module, rightType)
 			leftVec, leftIsVec := leftInner.(ir.VectorType)
@@ -5616,7 +5915,7 @@ func (e *ExpressionEmitter) emitBinary(binary ir.ExprBinary) (uint32, error) {
 			opcode = OpFDiv
 			// Check for vec / scalar — SPIR-V has no OpVectorDivideScalar.
 			// Splat the scalar to a matching vector.
-			rightType, rErr := ir.ResolveExpressionType(e.backend.module, e.function, binary.Right)
+			rightType, rErr := e.resolveType(binary.Right)
 			if rErr == nil {
 				var promErr error
 				leftID, rightID, resultType, promErr = e.promoteScalarToVector(leftType, rightType, leftID, rightID, resultType)
@@ -5626,7 +5925,7 @@ func (e *ExpressionEmitter) emitBinary(binary ir.ExprBinary) (uint32, error) {
 			}
 		} else {
 			// Integer divide: use wrapped function for safety
-			rightType, _ := ir.ResolveExpressionType(e.backend.module, e.function, binary.Right)
+			rightType, _ := e.resolveType(binary.Right)
 			leftTypeID, err := e.backend.resolveTypeResolution(leftType)
 			if err != nil {
 				return 0, err
@@ -5649,7 +5948,7 @@ func (e *ExpressionEmitter) emitBinary(binary ir.ExprBinary) (uint32, error) {
 	case ir.BinaryModulo:
 		if scalarKind == ir.ScalarFloat {
 			opcode = OpFMod
-			rightType, rErr := ir.ResolveExpressionType(e.backend.module, e.function, binary.Right)
+			rightType, rErr := e.resolveType(binary.Right)
 			if rErr == nil {
 				var promErr error
 				leftID, rightID, resultType, promErr = e.promoteScalarToVector(leftType, rightType, leftID, rightID, resultType)
@@ -5659,7 +5958,7 @@ func (e *ExpressionEmitter) emitBinary(binary ir.ExprBinary) (uint32, error) {
 			}
 		} else {
 			// Integer modulo: use wrapped function for safety
-			rightType, _ := ir.ResolveExpressionType(e.backend.module, e.function, binary.Right)
+			rightType, _ := e.resolveType(binary.Right)
 			leftTypeID, err := e.backend.resolveTypeResolution(leftType)
 			if err != nil {
 				return 0, err
@@ -5858,7 +6157,7 @@ func (e *ExpressionEmitter) emitSelect(sel ir.ExprSelect) (uint32, error) {
 	}
 
 	// Result type is same as accept/reject branches
-	acceptType, err := ir.ResolveExpressionType(e.backend.module, e.function, sel.Accept)
+	acceptType, err := e.resolveType(sel.Accept)
 	if err != nil {
 		return 0, fmt.Errorf("select accept type: %w", err)
 	}
@@ -5870,7 +6169,7 @@ func (e *ExpressionEmitter) emitSelect(sel ir.ExprSelect) (uint32, error) {
 	// SPIR-V OpSelect requires the condition to be the same size as the result.
 	// WGSL allows scalar bool condition with vector operands (broadcast).
 	// When condition is scalar bool but result is vector, splat the condition.
-	condType, err := ir.ResolveExpressionType(e.backend.module, e.function, sel.Condition)
+	condType, err := e.resolveType(sel.Condition)
 	if err != nil {
 		return 0, fmt.Errorf("select condition type: %w", err)
 	}
@@ -5968,12 +6267,14 @@ func (e *ExpressionEmitter) emitStatement(stmt ir.Statement) error {
 
 	switch kind := stmt.Kind.(type) {
 	case ir.StmtEmit:
-		// Emit all expressions in range.
+		// Emit all live expressions in range. Dead ones have no uses, so
+		// emitting them would only add instructions.
 		// Skip variable reference expressions (GlobalVariable, LocalVariable) —
 		// they are pointer expressions in the WGSL Load Rule model and should
 		// not be auto-loaded here. The ExprLoad wrapping them handles the load.
+		// Pointer accesses are likewise emitted where they are used.
 		for handle := kind.Range.Start; handle < kind.Range.End; handle++ {
-			if e.isVariableReference(handle) {
+			if !e.isLive(handle) || e.isVariableReference(handle) || e.isPointerAccess(handle) {
 				continue
 			}
 			_, err := e.emitExpression(handle)
@@ -6002,6 +6303,10 @@ func (e *ExpressionEmitter) emitStatement(stmt ir.Statement) error {
 		if e.loopCtx.BreakID == 0 {
 			return fmt.Errorf("break statement outside of loop or switch")
 		}
+		if e.breakTargets == nil {
+			e.breakTargets = make(map[uint32]bool)
+		}
+		e.breakTargets[e.loopCtx.BreakID] = true
 		e.consumeBlock(makeBranchInstruction(e.loopCtx.BreakID))
 		return nil
 
@@ -6632,8 +6937,11 @@ func (e *ExpressionEmitter) emitSwitch(stmt ir.StmtSwitch) error {
 	mergeBlock := NewBlock(mergeLabel)
 	e.setCurrentBlock(&mergeBlock)
 
-	// If all cases terminated, merge block is unreachable
-	if allCasesTerminated {
+	// If all cases terminated without breaking out of the switch, the
+	// merge block is unreachable. A case ending in `break` (including a
+	// degenerate default-only switch) branches to the merge block, so the
+	// statements after the switch must still be emitted.
+	if allCasesTerminated && !e.breakTargets[mergeLabel] {
 		e.consumeBlock(Instruction{Opcode: OpUnreachable})
 	}
 
@@ -6649,7 +6957,7 @@ func (e *ExpressionEmitter) emitMath(mathExpr ir.ExprMath) (uint32, error) {
 	}
 
 	// Get argument type to determine result type and correct opcodes
-	argType, err := ir.ResolveExpressionType(e.backend.module, e.function, mathExpr.Arg)
+	argType, err := e.resolveType(mathExpr.Arg)
 	if err != nil {
 		return 0, fmt.Errorf("math argument type: %w", err)
 	}
@@ -7263,7 +7571,7 @@ func (e *ExpressionEmitter) emitMath(mathExpr ir.ExprMath) (uint32, error) {
 	// FMix: if selector (arg2) is scalar but result is vector, splat the selector.
 	// SPIR-V FMix requires all operands to match Result Type.
 	if needsMixSplat && len(operands) >= 3 && mathExpr.Arg2 != nil {
-		selectorType, _ := ir.ResolveExpressionType(e.backend.module, e.function, *mathExpr.Arg2)
+		selectorType, _ := e.resolveType(*mathExpr.Arg2)
 		selectorInner := ir.TypeResInner(e.backend.module, selectorType)
 		argInner2 := ir.TypeResInner(e.backend.module, argType)
 		if _, isScalar := selectorInner.(ir.ScalarType); isScalar {
@@ -7595,7 +7903,7 @@ func (e *ExpressionEmitter) emitDerivative(deriv ir.ExprDerivative) (uint32, err
 	}
 
 	// Get result type from expression (derivative preserves type)
-	exprType, err := ir.ResolveExpressionType(e.backend.module, e.function, deriv.Expr)
+	exprType, err := e.resolveType(deriv.Expr)
 	if err != nil {
 		return 0, fmt.Errorf("derivative expression type: %w", err)
 	}
@@ -7646,6 +7954,53 @@ func (e *ExpressionEmitter) emitDerivative(deriv ir.ExprDerivative) (uint32, err
 	return e.backend.builder.AddUnaryOp(opcode, resultType, exprID), nil
 }
 
+// emitRelational emits a relational test. all and any reduce a bool vector
+// with OpAll/OpAny and pass a scalar bool through unchanged. isnan and isinf
+// map to OpIsNan and OpIsInf, which are exact: unlike text backends, SPIR-V
+// only drops NaN/Inf handling when the module opts in with FPFastMathMode
+// decorations.
+func (e *ExpressionEmitter) emitRelational(handle ir.ExpressionHandle, rel ir.ExprRelational) (uint32, error) {
+	argID, err := e.emitExpression(rel.Argument)
+	if err != nil {
+		return 0, err
+	}
+
+	if rel.Fun == ir.RelationalAll || rel.Fun == ir.RelationalAny {
+		argType, err := e.resolveType(rel.Argument)
+		if err != nil {
+			return 0, fmt.Errorf("relational argument type: %w", err)
+		}
+		if s, ok := ir.TypeResInner(e.backend.module, argType).(ir.ScalarType); ok && s.Kind == ir.ScalarBool {
+			return argID, nil
+		}
+	}
+
+	resType, err := e.resolveType(handle)
+	if err != nil {
+		return 0, fmt.Errorf("relational result type: %w", err)
+	}
+	resultType, err := e.backend.resolveTypeResolution(resType)
+	if err != nil {
+		return 0, err
+	}
+
+	var opcode OpCode
+	switch rel.Fun {
+	case ir.RelationalAll:
+		opcode = OpAll
+	case ir.RelationalAny:
+		opcode = OpAny
+	case ir.RelationalIsNan:
+		opcode = OpIsNan
+	case ir.RelationalIsInf:
+		opcode = OpIsInf
+	default:
+		return 0, fmt.Errorf("unsupported relational function: %v", rel.Fun)
+	}
+
+	return e.backend.builder.AddUnaryOp(opcode, resultType, argID), nil
+}
+
 // OpDot represents OpDot opcode (dot product).
 const OpDot OpCode = 148
 
@@ -7712,7 +8067,7 @@ func (e *ExpressionEmitter) emitImageSample(sample ir.ExprImageSample) (uint32,
 	// For non-Dref depth sampling (without gather), SPIR-V returns vec4
 	// but we need scalar, so we CompositeExtract the first component.
 	isDepthImage := false
-	exprType, resolveErr := ir.ResolveExpressionType(e.backend.module, e.function, sample.Image)
+	exprType, resolveErr := e.resolveType(sample.Image)
 	if resolveErr == nil {
 		inner := typeResolutionInner(e.backend.module, exprType)
 		if imgType, ok := inner.(ir.ImageType); ok {
@@ -7878,7 +8233,7 @@ func (e *ExpressionEmitter) emitImageSample(sample ir.ExprImageSample) (uint32,
 		// For depth images, the WGSL Lod is integer (i32/u32), so we must convert.
 		// Matches Rust naga image.rs line 1010-1044.
 		if isDepthImage {
-			lodType, lodErr := ir.ResolveExpressionType(e.backend.module, e.function, level.Level)
+			lodType, lodErr := e.resolveType(level.Level)
 			if lodErr == nil {
 				lodInner := ir.TypeResInner(e.backend.module, lodType)
 				if sc, ok := lodInner.(ir.ScalarType); ok && (sc.Kind == ir.ScalarSint || sc.Kind == ir.ScalarUint) {
@@ -8013,7 +8368,7 @@ func (e *ExpressionEmitter) emitImageCoordinates(
 		return imageCoordinates{}, err
 	}
 
-	coordType, err := ir.ResolveExpressionType(e.backend.module, e.function, coordExpr)
+	coordType, err := e.resolveType(coordExpr)
 	if err != nil {
 		return imageCoordinates{}, err
 	}
@@ -8051,7 +8406,7 @@ func (e *ExpressionEmitter) emitImageCoordinates(
 	}
 
 	// Resolve array index type and bitcast if needed
-	arrayIndexType, _ := ir.ResolveExpressionType(e.backend.module, e.function, *arrayIndex)
+	arrayIndexType, _ := e.resolveType(*arrayIndex)
 	arrayIndexInner := typeResolutionInner(e.backend.module, arrayIndexType)
 	if scalar, ok := arrayIndexInner.(ir.ScalarType); ok {
 		if scalar.Kind != componentScalar.Kind {
@@ -8124,7 +8479,7 @@ func (e *ExpressionEmitter) emitImageLoad(load ir.ExprImageLoad) (uint32, error)
 	}
 
 	// Determine image class from type
-	imageType, err := ir.ResolveExpressionType(e.backend.module, e.function, load.Image)
+	imageType, err := e.resolveType(load.Image)
 	if err != nil {
 		return 0, err
 	}
@@ -8558,7 +8913,7 @@ func (e *ExpressionEmitter) emitImageQuery(query ir.ExprImageQuery) (uint32, err
 	builder := e.newIB()
 
 	// Resolve image type for dimension-dependent queries.
-	imageType, err := ir.ResolveExpressionType(e.backend.module, e.function, query.Image)
+	imageType, err := e.resolveType(query.Image)
 	if err != nil {
 		return 0, fmt.Errorf("emitImageQuery: resolve image type: %w", err)
 	}
@@ -8611,25 +8966,7 @@ func (e *ExpressionEmitter) emitImageQuery(query ir.ExprImageQuery) (uint32, err
 		builder.AddWord(extendedID)
 		builder.AddWord(imageID)
 
-		// Matching Rust naga: multisampled or storage images use OpImageQuerySize (no level).
-		// Sampled/depth non-multisampled images use OpImageQuerySizeLod (with explicit or default level 0).
-		// SPIR-V spec: OpImageQuerySize requires MS=1 or Sampled!=1.
-		useQuerySize := false
-		switch imgType.Class {
-		case ir.ImageClassSampled:
-			useQuerySize = imgType.Multisampled
-		case ir.ImageClassDepth:
-			useQuerySize = imgType.Multisampled
-		case ir.ImageClassExternal:
-			// External textures have Sampled=1, need OpImageQuerySizeLod like sampled
-			useQuerySize = false
-		case ir.ImageClassStorage:
-			useQuerySize = true
-		default:
-			useQuerySize = true
-		}
-
-		if useQuerySize {
+		if querySizeWithoutLod(imgType) {
 			e.backend.builder.funcAppend(builder.Build(OpImageQuerySize))
 		} else {
 			// Sampled/depth non-multisampled: use OpImageQuerySizeLod
@@ -8681,9 +9018,9 @@ func (e *ExpressionEmitter) emitImageQuery(query ir.ExprImageQuery) (uint32, err
 		e.backend.builder.funcAppend(builder.Build(OpImageQueryLevels))
 
 	case ir.ImageQueryNumLayers:
-		// NumLayers uses OpImageQuerySizeLod to get the extended size vector,
-		// then extracts the last component (the layer count).
-		// Matches Rust naga: vec_size based on dim, then CompositeExtract last element.
+		// NumLayers queries the extended size vector, whose last component
+		// is the layer count, and extracts that component. Like a size
+		// query, storage and multisampled images take no level.
 		var vecSize uint32
 		switch imgType.Dim {
 		case ir.Dim1D:
@@ -8706,10 +9043,13 @@ func (e *ExpressionEmitter) emitImageQuery(query ir.ExprImageQuery) (uint32, err
 		builder.AddWord(extendedTypeID)
 		builder.AddWord(extendedID)
 		builder.AddWord(imageID)
-		// Always use level 0 for NumLayers query
-		zeroID := e.backend.builder.AddConstant(scalarID, 0)
-		builder.AddWord(zeroID)
-		e.backend.builder.funcAppend(builder.Build(OpImageQuerySizeLod))
+		if querySizeWithoutLod(imgType) {
+			e.backend.builder.funcAppend(builder.Build(OpImageQuerySize))
+		} else {
+			zeroID := e.backend.builder.AddConstant(scalarID, 0)
+			builder.AddWord(zeroID)
+			e.backend.builder.funcAppend(builder.Build(OpImageQuerySizeLod))
+		}
 
 		// Extract the last component (layer count)
 		resultType := scalarID
@@ -8733,6 +9073,21 @@ func (e *ExpressionEmitter) emitImageQuery(query ir.ExprImageQuery) (uint32, err
 	return resultID, nil
 }
 
+// querySizeWithoutLod reports whether the size of img is queried with
+// OpImageQuerySize rather than OpImageQuerySizeLod. SPIR-V allows the
+// former only for multisampled images or those with Sampled != 1, and
+// the latter only for the rest (matching Rust naga).
+func querySizeWithoutLod(img ir.ImageType) bool {
+	switch img.Class {
+	case ir.ImageClassSampled, ir.ImageClassDepth:
+		return img.Multisampled
+	case ir.ImageClassExternal:
+		// External textures have Sampled=1, need OpImageQuerySizeLod like sampled
+		return false
+	}
+	return true
+}
+
 // getSampledImageType returns the type ID for a sampled image.
 // Uses caching to ensure the same type is reused for identical image configurations.
 func (b *Backend) getSampledImageType(fn *ir.Function, imageExpr ir.ExpressionHandle) (uint32, error) {
@@ -8741,7 +9096,7 @@ func (b *Backend) getSampledImageType(fn *ir.Function, imageExpr ir.ExpressionHa
 		Dim:   ir.Dim2D,
 		Class: ir.ImageClassSampled,
 	}
-	exprType, err := ir.ResolveExpressionType(b.module, fn, imageExpr)
+	exprType, err := ir.ExpressionType(b.module, fn, imageExpr)
 	if err == nil {
 		inner := typeResolutionInner(b.module, exprType)
 		if imgType, ok := inner.(ir.ImageType); ok {
@@ -8864,7 +9219,7 @@ func (e *ExpressionEmitter) emitWorkGroupUniformLoad(stmt ir.StmtWorkGroupUnifor
 	_ = e.emitBarrier(ir.StmtBarrier{Flags: ir.BarrierWorkGroup})
 
 	// Resolve the result type from the result expression
-	resultType, err := ir.ResolveExpressionType(e.backend.module, e.function, stmt.Result)
+	resultType, err := e.resolveType(stmt.Result)
 	if err != nil {
 		return fmt.Errorf("workgroup uniform load: cannot resolve result type: %w", err)
 	}
@@ -8894,7 +9249,7 @@ func (e *ExpressionEmitter) emitWorkGroupUniformLoad(stmt ir.StmtWorkGroupUnifor
 func (e *ExpressionEmitter) resolveAtomicScalar(pointer ir.ExpressionHandle) ir.ScalarType {
 	defaultScalar := ir.ScalarType{Kind: ir.ScalarUint, Width: 4}
 
-	pointerType, err := ir.ResolveExpressionType(e.backend.module, e.function, pointer)
+	pointerType, err := e.resolveType(pointer)
 	if err != nil {
 		return defaultScalar
 	}
@@ -9173,7 +9528,7 @@ func (e *ExpressionEmitter) appendArrayIndex(coordID uint32, arrayIndexExpr, coo
 		return 0, err
 	}
 	// Convert array index to float if it's integer
-	arrayIndexType, _ := ir.ResolveExpressionType(e.backend.module, e.function, arrayIndexExpr)
+	arrayIndexType, _ := e.resolveType(arrayIndexExpr)
 	indexInner := typeResolutionInner(e.backend.module, arrayIndexType)
 	if scalar, ok := indexInner.(ir.ScalarType); ok && scalar.Kind != ir.ScalarFloat {
 		floatTypeID, err := e.backend.emitScalarType(ir.ScalarType{Kind: ir.ScalarFloat, Width: 4})
@@ -9193,7 +9548,7 @@ func (e *ExpressionEmitter) appendArrayIndex(coordID uint32, arrayIndexExpr, coo
 		arrayIndexID = convertedID
 	}
 	// Extend coordinate vector: e.g. vec2(x,y) + arrayIndex → vec3(x,y,arrayIndex)
-	coordType, _ := ir.ResolveExpressionType(e.backend.module, e.function, coordExpr)
+	coordType, _ := e.resolveType(coordExpr)
 	coordInner := typeResolutionInner(e.backend.module, coordType)
 	if coordVec, ok := coordInner.(ir.VectorType); ok {
 		floatScalarID, err := e.backend.emitScalarType(ir.ScalarType{Kind: ir.ScalarFloat, Width: 4})
@@ -9526,6 +9881,9 @@ func (e *ExpressionEmitter) emitCall(call ir.StmtCall) error {
 	// Cache the result ID for ExprCallResult and handle deferred stores.
 	if call.Result != nil {
 		e.callResultIDs[*call.Result] = resultID
+		if e.backend.options.SourceMap {
+			e.backend.recordSpan(resultID, &e.function.Expressions[*call.Result])
+		}
 		if err := e.processDeferredStores(*call.Result, resultID); err != nil {
 			return err
 		}
@@ -9973,7 +10331,7 @@ func (e *ExpressionEmitter) emitSubgroupGather(stmt ir.StmtSubgroupGather) error
 
 // resolveSubgroupTypeID resolves the SPIR-V type ID for a subgroup result expression.
 func (e *ExpressionEmitter) resolveSubgroupTypeID(handle ir.ExpressionHandle) (uint32, error) {
-	typeRes, err := ir.ResolveExpressionType(e.backend.module, e.function, handle)
+	typeRes, err := e.resolveType(handle)
 	if err != nil {
 		return 0, fmt.Errorf("cannot resolve subgroup result type: %w", err)
 	}
@@ -9999,7 +10357,7 @@ func (e *ExpressionEmitter) resolveSubgroupTypeID(handle ir.ExpressionHandle) (u
 
 // resolveSubgroupScalarKind extracts the scalar kind from a subgroup argument expression.
 func (e *ExpressionEmitter) resolveSubgroupScalarKind(handle ir.ExpressionHandle) ir.ScalarKind {
-	typeRes, err := ir.ResolveExpressionType(e.backend.module, e.function, handle)
+	typeRes, err := e.resolveType(handle)
 	if err != nil {
 		return ir.ScalarUint
 	}
@@ -1,6 +1,9 @@
 package codegen
 
 import (
+	"context"
+	"errors"
+	"slices"
 	"strings"
 	"testing"
 
@@ -845,6 +848,7 @@ func TestOpLoadResultType(t *testing.T) {
 						{Kind: ir.ExprLocalVariable{Variable: 0}}, // expr 0: &x
 						{Kind: ir.ExprLoad{Pointer: 0}},           // expr 1: *(&x)
 					},
+					NamedExpressions: map[ir.ExpressionHandle]string{1: "v"},
 					Body: []ir.Statement{
 						{Kind: ir.StmtEmit{Range: ir.Range{Start: 0, End: 2}}},
 					},
@@ -912,6 +916,7 @@ func TestSaturateEmitsFClampWith3Operands(t *testing.T) {
 						{Kind: ir.Literal{Value: ir.LiteralF32(0.7)}},
 						{Kind: ir.ExprMath{Fun: ir.MathSaturate, Arg: 0}},
 					},
+					NamedExpressions: map[ir.ExpressionHandle]string{1: "s"},
 					Body: []ir.Statement{
 						{Kind: ir.StmtEmit{Range: ir.Range{Start: 0, End: 2}}},
 					},
@@ -968,6 +973,7 @@ func TestModfStructReturnType(t *testing.T) {
 						{Kind: ir.Literal{Value: ir.LiteralF32(1.5)}}, // expr 0: 1.5f
 						{Kind: ir.ExprMath{Fun: ir.MathModf, Arg: 0}}, // expr 1: modf(1.5)
 					},
+					NamedExpressions: map[ir.ExpressionHandle]string{1: "m"},
 					Body: []ir.Statement{
 						{Kind: ir.StmtEmit{Range: ir.Range{Start: 0, End: 2}}},
 					},
@@ -1274,6 +1280,7 @@ func TestIndexByValueSpillsToFunction(t *testing.T) {
 						{Kind: ir.Literal{Value: ir.LiteralI32(2)}}, // [2] index 2
 						{Kind: ir.ExprAccess{Base: 1, Index: 2}},    // [3] arr[2] — by-value access
 					},
+					NamedExpressions: map[ir.ExpressionHandle]string{3: "elem"},
 					Body: []ir.Statement{
 						{Kind: ir.StmtEmit{Range: ir.Range{Start: 0, End: 4}}},
 					},
@@ -1650,3 +1657,94 @@ func TestEmitInlineType_UnsupportedType(t *testing.T) {
 		t.Errorf("unexpected error message: %v", err)
 	}
 }
+
+func TestBackendCompileContextCanceled(t *testing.T) {
+	module := &ir.Module{
+		EntryPoints: []ir.EntryPoint{{
+			Name:      "main",
+			Stage:     ir.StageCompute,
+			Workgroup: [3]uint32{1, 1, 1},
+		}},
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := NewBackend(DefaultOptions()).CompileContext(ctx, module); !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context.Canceled, got %v", err)
+	}
+	if _, err := NewBackend(DefaultOptions()).CompileContext(context.Background(), module); err != nil {
+		t.Fatalf("CompileContext failed: %v", err)
+	}
+}
+
+// TestDeadExpressionsNotEmitted verifies that expressions covered by an Emit
+// range but never used by a statement produce no instructions.
+func TestDeadExpressionsNotEmitted(t *testing.T) {
+	ret := ir.ExpressionHandle(3)
+	module := &ir.Module{
+		Types: []ir.Type{
+			{Inner: ir.ScalarType{Kind: ir.ScalarFloat, Width: 4}},
+		},
+		Functions: []ir.Function{
+			{
+				Name:      "f",
+				Result:    &ir.FunctionResult{Type: 0},
+				LocalVars: []ir.LocalVariable{{Name: "x", Type: 0}},
+				Expressions: []ir.Expression{
+					{Kind: ir.ExprLocalVariable{Variable: 0}},                       // [0] &x
+					{Kind: ir.ExprLoad{Pointer: 0}},                                 // [1] x
+					{Kind: ir.ExprBinary{Op: ir.BinaryMultiply, Left: 1, Right: 1}}, // [2] dead
+					{Kind: ir.ExprUnary{Op: ir.UnaryNegate, Expr: 1}},               // [3] returned
+				},
+				Body: []ir.Statement{
+					{Kind: ir.StmtEmit{Range: ir.Range{Start: 1, End: 4}}},
+					{Kind: ir.StmtReturn{Value: &ret}},
+				},
+			},
+		},
+	}
+
+	spvBytes, err := NewBackend(DefaultOptions()).Compile(module)
+	if err != nil {
+		t.Fatalf("compile failed: %v", err)
+	}
+	counts := countOpcodes(spvBytes, OpLoad, OpFMul, OpFNegate)
+	if counts[OpFMul] != 0 {
+		t.Errorf("dead multiply emitted %d OpFMul, want 0", counts[OpFMul])
+	}
+	if counts[OpLoad] != 1 || counts[OpFNegate] != 1 {
+		t.Errorf("live expressions: got %d OpLoad, %d OpFNegate, want 1 each", counts[OpLoad], counts[OpFNegate])
+	}
+}
+
+// TestAccessChainsCollapsed checks that nested accesses through a pointer
+// emit one OpAccessChain with an index per level, not a chain per level,
+// and no loads of the aggregates in between.
+func TestAccessChainsCollapsed(t *testing.T) {
+	source := `
+struct Inner { v: array<vec4<f32>, 4>, s: f32 }
+struct Outer { inner: array<Inner, 8>, n: u32 }
+@group(0) @binding(0) var<storage, read_write> buf: Outer;
+
+@compute @workgroup_size(1)
+fn main(@builtin(local_invocation_index) i: u32) {
+    buf.inner[i].v[2].y = buf.inner[3].s;
+}
+`
+	spv := compileWGSL(t, source)
+	assertValidSPIRV(t, spv)
+
+	var indices []int
+	for _, inst := range decodeSPIRVInstructions(spv) {
+		if inst.opcode == OpAccessChain {
+			// Words: opcode, result type, result, base, indices...
+			indices = append(indices, len(inst.words)-4)
+		}
+	}
+	// The storage buffer is wrapped in a struct, which adds a leading 0.
+	if !slices.Equal(indices, []int{4, 6}) {
+		t.Errorf("OpAccessChain index counts = %v, want [4 6]", indices)
+	}
+	if n := countOpcodeInInstrs(decodeSPIRVInstructions(spv), OpLoad); n != 2 {
+		t.Errorf("got %d OpLoad, want 2 (the invocation index and buf.inner[3].s)", n)
+	}
+}
@@ -325,3 +325,59 @@ func BenchmarkModuleBuilderTypes(b *testing.B) {
 		runtime.KeepAlive(u32)
 	}
 }
+
+// BenchmarkSPIRVEmitAllocsPerExpression reports the heap allocations of
+// compiling each shader with a reused Backend, per IR expression.
+func BenchmarkSPIRVEmitAllocsPerExpression(b *testing.B) {
+	for _, bc := range spirvBenchShaders {
+		b.Run(bc.name, func(b *testing.B) {
+			module := parseToIR(b, bc.source)
+			exprs := 0
+			for i := range module.Functions {
+				exprs += len(module.Functions[i].Expressions)
+			}
+			for i := range module.EntryPoints {
+				exprs += len(module.EntryPoints[i].Function.Expressions)
+			}
+			backend := NewBackend(Options{Version: Version1_3})
+
+			var before, after runtime.MemStats
+			b.ReportAllocs()
+			b.ResetTimer()
+			runtime.ReadMemStats(&before)
+			for i := 0; i < b.N; i++ {
+				result, err := backend.Compile(module)
+				if err != nil {
+					b.Fatalf("spirv emit failed: %v", err)
+				}
+				runtime.KeepAlive(result)
+			}
+			runtime.ReadMemStats(&after)
+			b.ReportMetric(float64(after.Mallocs-before.Mallocs)/float64(b.N)/float64(exprs), "allocs/expr")
+		})
+	}
+}
+
+// BenchmarkNewIB benchmarks taking an instruction builder from a Backend
+// and building an instruction with it, which allocates nothing once the
+// builder slab and word arena have grown.
+func BenchmarkNewIB(b *testing.B) {
+	backend := NewBackend(Options{Version: Version1_3})
+	backend.builder = NewModuleBuilder(Version1_3)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if i%1024 == 0 {
+			backend.Reset()
+			backend.builder.Reset(Version1_3)
+		}
+		ib := backend.newIB()
+		ib.AddWord(1)
+		ib.AddWord(2)
+		ib.AddWord(3)
+		ib.AddWord(4)
+		inst := ib.Build(OpFAdd)
+		runtime.KeepAlive(inst)
+	}
+}
@@ -1,5 +1,7 @@
 package codegen
 
+import "slices"
+
 // Block represents a SPIR-V basic block under construction.
 // Instructions are appended via Push; the block is not yet terminated.
 type Block struct {
@@ -56,13 +58,19 @@ func (f *FunctionBuilder) Consume(block Block, terminator Instruction) {
 //	...
 //	OpFunctionEnd
 func (f *FunctionBuilder) ToInstructions() []Instruction {
+	return f.AppendInstructions(nil)
+}
+
+// AppendInstructions appends the serialized function to dst, as
+// ToInstructions does, growing dst at most once.
+func (f *FunctionBuilder) AppendInstructions(dst []Instruction) []Instruction {
 	// Pre-calculate capacity: signature + params + per-block(label+body) + variables + functionEnd.
 	capacity := 1 + len(f.Parameters) + len(f.Variables) + 1 // signature + params + vars + funcEnd
 	for _, block := range f.Blocks {
 		capacity += 1 + len(block.Body) // label + body
 	}
 
-	result := make([]Instruction, 0, capacity)
+	result := slices.Grow(dst, capacity)
 	result = append(result, f.Signature)
 	result = append(result, f.Parameters...)
 
@@ -286,3 +286,27 @@ func TestFunctionBuilder_EmptyFunction(t *testing.T) {
 		t.Errorf("result[1].Opcode = %d, want OpFunctionEnd", result[1].Opcode)
 	}
 }
+
+func TestFunctionBuilder_AppendInstructions(t *testing.T) {
+	var fb FunctionBuilder
+	fb.Signature = Instruction{Opcode: OpFunction, Words: []uint32{1, 0, 2}}
+	block := NewBlock(100)
+	block.Push(Instruction{Opcode: OpLoad, Words: []uint32{3, 4, 5}})
+	fb.Consume(block, Instruction{Opcode: OpReturn})
+
+	prefix := Instruction{Opcode: OpFunctionEnd}
+	result := fb.AppendInstructions([]Instruction{prefix})
+
+	want := fb.ToInstructions()
+	if len(result) != 1+len(want) {
+		t.Fatalf("result length = %d, want %d", len(result), 1+len(want))
+	}
+	if result[0].Opcode != prefix.Opcode {
+		t.Errorf("result[0].Opcode = %d, want the prefix %d", result[0].Opcode, prefix.Opcode)
+	}
+	for i, inst := range want {
+		if got := result[1+i]; got.Opcode != inst.Opcode || len(got.Words) != len(inst.Words) {
+			t.Errorf("result[%d] = %v, want %v", 1+i, got, inst)
+		}
+	}
+}
@@ -2,6 +2,8 @@ package codegen
 
 import (
 	"encoding/binary"
+	"reflect"
+	"strings"
 	"testing"
 
 	"github.com/gogpu/naga/ir"
@@ -638,3 +640,87 @@ func TestCapability_MultipleNonStandardTypes(t *testing.T) {
 	assertNoCapability(t, caps, CapabilityFloat16)
 	assertNoCapability(t, caps, CapabilityImageQuery)
 }
+
+// extractDecorations returns the operand words (target, decoration, then
+// parameters) of every instruction with the given opcode.
+func extractDecorations(spvBytes []byte, op OpCode) [][]uint32 {
+	var decorations [][]uint32
+	for offset := 20; offset+4 <= len(spvBytes); {
+		word := binary.LittleEndian.Uint32(spvBytes[offset:])
+		wordCount := int(word >> 16)
+		if wordCount == 0 || offset+wordCount*4 > len(spvBytes) {
+			break
+		}
+		if word&0xFFFF == uint32(op) {
+			operands := make([]uint32, wordCount-1)
+			for i := range operands {
+				operands[i] = binary.LittleEndian.Uint32(spvBytes[offset+4+i*4:])
+			}
+			decorations = append(decorations, operands)
+		}
+		offset += wordCount * 4
+	}
+	return decorations
+}
+
+// TestExtraExtensionsAndBindingDecorations checks that Options.Extensions
+// and Options.BindingDecorations are written after the backend's own
+// declarations, and that decorations naming unknown bindings fail.
+func TestExtraExtensionsAndBindingDecorations(t *testing.T) {
+	source := `@group(0) @binding(0) var<storage, read_write> data: array<u32>;
+@group(0) @binding(1) var<storage, read_write> counter: array<u32>;
+@compute @workgroup_size(1)
+fn main() {
+    data[0] = counter[0];
+}`
+	opts := DefaultOptions()
+	opts.Version = Version1_4
+	opts.Extensions = []string{"SPV_GOOGLE_hlsl_functionality1", "SPV_GOOGLE_hlsl_functionality1"}
+	opts.BindingDecorations = []BindingDecoration{
+		{Binding: ir.ResourceBinding{Group: 0, Binding: 0}, Decoration: DecorationCoherent},
+		{Binding: ir.ResourceBinding{Group: 0, Binding: 0}, Decoration: 5634, IDOperands: []ir.ResourceBinding{{Group: 0, Binding: 1}}},
+	}
+	spvBytes := compileWGSLForCapabilityTestWithOpts(t, source, opts)
+
+	if exts := extractExtensions(spvBytes); !reflect.DeepEqual(exts, []string{"SPV_GOOGLE_hlsl_functionality1"}) {
+		t.Errorf("extensions = %q, want the requested one once", exts)
+	}
+	bound := make(map[uint32]uint32) // binding -> variable ID
+	var coherent []uint32
+	for _, d := range extractDecorations(spvBytes, OpDecorate) {
+		switch Decoration(d[1]) {
+		case DecorationBinding:
+			bound[d[2]] = d[0]
+		case DecorationCoherent:
+			coherent = append(coherent, d[0])
+		}
+	}
+	if !reflect.DeepEqual(coherent, []uint32{bound[0]}) {
+		t.Errorf("Coherent decorates %v, want the variable at binding 0 (%d)", coherent, bound[0])
+	}
+	byID := extractDecorations(spvBytes, OpDecorateID)
+	if want := [][]uint32{{bound[0], 5634, bound[1]}}; !reflect.DeepEqual(byID, want) {
+		t.Errorf("OpDecorateId operands = %v, want %v", byID, want)
+	}
+
+	module := compileWGSLModule(t, source)
+	for _, tt := range []struct {
+		name       string
+		version    Version
+		decoration BindingDecoration
+		want       string
+	}{
+		{"unknown binding", Version1_4, BindingDecoration{Binding: ir.ResourceBinding{Group: 1}, Decoration: DecorationCoherent}, "no variable at @group(1) @binding(0)"},
+		{"unknown ID operand", Version1_4, BindingDecoration{Decoration: 5634, IDOperands: []ir.ResourceBinding{{Binding: 7}}}, "operand @group(0) @binding(7) names 0 variables"},
+		{"binding override", Version1_4, BindingDecoration{Decoration: DecorationBinding, Operands: []uint32{3}}, "written by the backend"},
+		{"OpDecorateId before 1.2", Version1_1, BindingDecoration{Decoration: 5634, IDOperands: []ir.ResourceBinding{{Binding: 1}}}, "needs SPIR-V 1.2"},
+	} {
+		opts := DefaultOptions()
+		opts.Version = tt.version
+		opts.BindingDecorations = []BindingDecoration{tt.decoration}
+		_, err := NewBackend(opts).Compile(module)
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%s: err = %v, want %q", tt.name, err, tt.want)
+		}
+	}
+}
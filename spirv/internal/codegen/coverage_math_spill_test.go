@@ -331,6 +331,7 @@ func TestSpillCompositeFieldAccessWithIndexChain(t *testing.T) {
 						// expr 4: access index .b (field 1)
 						{Kind: ir.ExprAccessIndex{Base: 2, Index: 1}},
 					},
+					NamedExpressions: map[ir.ExpressionHandle]string{3: "a", 4: "b"},
 					Body: []ir.Statement{
 						{Kind: ir.StmtEmit{Range: ir.Range{Start: 0, End: 2}}},
 						{Kind: ir.StmtCall{
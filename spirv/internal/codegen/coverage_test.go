@@ -2491,6 +2491,7 @@ func TestCompileModuleWithLocalVarValue(t *testing.T) {
 						{Kind: ir.Literal{Value: ir.LiteralU32(42)}}, // expr 1: 42u
 						{Kind: ir.ExprLoad{Pointer: 0}},              // expr 2: *(&x) = load x
 					},
+					NamedExpressions: map[ir.ExpressionHandle]string{2: "v"},
 					Body: []ir.Statement{
 						{Kind: ir.StmtEmit{Range: ir.Range{Start: 1, End: 2}}},
 						{Kind: ir.StmtStore{Pointer: 0, Value: 1}},
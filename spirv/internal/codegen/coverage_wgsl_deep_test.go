@@ -1,6 +1,7 @@
 package codegen
 
 import (
+	"strings"
 	"testing"
 )
 
@@ -529,12 +530,19 @@ func TestStorageTextureMultipleFormats(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
+			value := "vec4<f32>(1.0, 0.0, 0.0, 1.0)"
+			switch {
+			case strings.HasSuffix(tt.format, "uint"):
+				value = "vec4<u32>(1u, 0u, 0u, 1u)"
+			case strings.HasSuffix(tt.format, "sint"):
+				value = "vec4<i32>(1i, 0i, 0i, 1i)"
+			}
 			source := `
 @group(0) @binding(0) var output: texture_storage_2d<` + tt.format + `, write>;
 
 @compute @workgroup_size(1)
 fn main(@builtin(global_invocation_id) id: vec3<u32>) {
-    textureStore(output, vec2<i32>(vec2<u32>(id.x, id.y)), vec4<f32>(1.0, 0.0, 0.0, 1.0));
+    textureStore(output, vec2<i32>(vec2<u32>(id.x, id.y)), ` + value + `);
 }
 `
 			spv := compileWGSL(t, source)
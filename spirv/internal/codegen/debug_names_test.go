@@ -0,0 +1,53 @@
+package codegen
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/gogpu/naga/wgsl"
+)
+
+func TestDebugExpressionNames(t *testing.T) {
+	const source = `@group(0) @binding(0) var<storage, read_write> data: array<f32>;
+
+@compute @workgroup_size(1)
+fn main() {
+    let scale = data[1] * 2.0;
+    data[0] = sqrt(scale + data[2]) + data[3] * data[4] * data[5] * data[6] * data[7];
+}
+`
+	tokens, err := wgsl.NewLexer(source).Tokenize()
+	if err != nil {
+		t.Fatal(err)
+	}
+	ast, err := wgsl.NewParser(tokens).Parse()
+	if err != nil {
+		t.Fatal(err)
+	}
+	module, err := wgsl.Lower(ast)
+	if err != nil {
+		t.Fatal(err)
+	}
+	compile := func(names bool) []byte {
+		opts := DefaultOptions()
+		opts.Debug = true
+		opts.DebugExpressionNames = names
+		opts.DebugSource = source
+		code, err := NewBackend(opts).Compile(module)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return code
+	}
+
+	plain := compile(false)
+	named := compile(true)
+	for _, name := range []string{"data[1] * 2.0", "scale + data[2]", "sqrt(scale + data[2])", "sqrt(scale + data[2]) + data[3] * data[4] * d...\x00"} {
+		if !bytes.Contains(named, []byte(name)) {
+			t.Errorf("no OpName %q", name)
+		}
+		if bytes.Contains(plain, []byte(name)) {
+			t.Errorf("OpName %q without DebugExpressionNames", name)
+		}
+	}
+}
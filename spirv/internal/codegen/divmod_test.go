@@ -113,6 +113,7 @@ func TestWrappedDivU32(t *testing.T) {
 						// [2] binary divide
 						{Kind: ir.ExprBinary{Op: ir.BinaryDivide, Left: 0, Right: 1}},
 					},
+					NamedExpressions: map[ir.ExpressionHandle]string{2: "q"},
 					Body: ir.Block{
 						ir.Statement{Kind: ir.StmtEmit{Range: ir.Range{Start: 0, End: 3}}},
 					},
@@ -266,6 +267,7 @@ func TestFloatDivNoWrapper(t *testing.T) {
 						{Kind: ir.Literal{Value: ir.LiteralF32(3.0)}},
 						{Kind: ir.ExprBinary{Op: ir.BinaryDivide, Left: 0, Right: 1}},
 					},
+					NamedExpressions: map[ir.ExpressionHandle]string{2: "q"},
 					Body: ir.Block{
 						ir.Statement{Kind: ir.StmtEmit{Range: ir.Range{Start: 0, End: 3}}},
 					},
@@ -320,6 +322,7 @@ func TestWrappedDivDeduplicated(t *testing.T) {
 						{Kind: ir.Literal{Value: ir.LiteralU32(7)}},
 						{Kind: ir.ExprBinary{Op: ir.BinaryDivide, Left: 3, Right: 4}},
 					},
+					NamedExpressions: map[ir.ExpressionHandle]string{2: "q0", 5: "q1"},
 					Body: ir.Block{
 						ir.Statement{Kind: ir.StmtEmit{Range: ir.Range{Start: 0, End: 6}}},
 					},
@@ -375,6 +378,7 @@ func TestWrappedDivVec2I32(t *testing.T) {
 						{Kind: ir.ExprCompose{Type: 1, Components: []ir.ExpressionHandle{3, 4}}},
 						{Kind: ir.ExprBinary{Op: ir.BinaryDivide, Left: 2, Right: 5}},
 					},
+					NamedExpressions: map[ir.ExpressionHandle]string{6: "q"},
 					Body: ir.Block{
 						ir.Statement{Kind: ir.StmtEmit{Range: ir.Range{Start: 0, End: 7}}},
 					},
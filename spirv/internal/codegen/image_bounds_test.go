@@ -33,6 +33,7 @@ func makeImageLoadModule() *ir.Module {
 						{Kind: ir.Literal{Value: ir.LiteralI32(0)}},                                // [4] level 0
 						{Kind: ir.ExprImageLoad{Image: 0, Coordinate: 3, Level: ptrExprHandle(4)}}, // [5] textureLoad
 					},
+					NamedExpressions: map[ir.ExpressionHandle]string{5: "texel"},
 					Body: []ir.Statement{
 						{Kind: ir.StmtEmit{Range: ir.Range{Start: 0, End: 6}}},
 					},
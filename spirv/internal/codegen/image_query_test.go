@@ -0,0 +1,59 @@
+package codegen
+
+import "testing"
+
+const numLayersShader = `
+@group(0) @binding(0) var t2a: texture_2d_array<f32>;
+@group(0) @binding(1) var tca: texture_cube_array<f32>;
+@group(0) @binding(2) var tda: texture_depth_2d_array;
+@group(0) @binding(3) var tsa: texture_storage_2d_array<rgba8unorm, write>;
+@group(0) @binding(4) var<storage, read_write> out: array<u32>;
+
+@compute @workgroup_size(1)
+fn main() {
+    out[0] = textureNumLayers(t2a);
+    out[1] = textureNumLayers(tca);
+    out[2] = textureNumLayers(tda);
+    out[3] = textureNumLayers(tsa);
+}
+`
+
+// TestImageQueryNumLayers checks that textureNumLayers queries the size
+// vector of each arrayed image with the instruction its class allows and
+// extracts the layer count after the spatial dimensions.
+func TestImageQueryNumLayers(t *testing.T) {
+	instrs := decodeSPIRVInstructions(compileSPIRV(t, numLayersShader))
+
+	var lod, noLod int
+	sizes := map[uint32]bool{}
+	for _, inst := range instrs {
+		switch inst.opcode {
+		case OpImageQuerySizeLod:
+			lod++
+			sizes[inst.words[2]] = true
+		case OpImageQuerySize:
+			// Storage images take no level operand.
+			if len(inst.words) != 4 {
+				t.Errorf("OpImageQuerySize has %d words, want 4", len(inst.words))
+			}
+			noLod++
+			sizes[inst.words[2]] = true
+		}
+	}
+	if lod != 3 || noLod != 1 {
+		t.Errorf("got %d OpImageQuerySizeLod and %d OpImageQuerySize, want 3 and 1", lod, noLod)
+	}
+
+	extracts := 0
+	for _, inst := range instrs {
+		if inst.opcode == OpCompositeExtract && sizes[inst.words[3]] {
+			extracts++
+			if got := inst.words[4]; got != 2 {
+				t.Errorf("layer count extracted from component %d, want 2", got)
+			}
+		}
+	}
+	if extracts != 4 {
+		t.Errorf("got %d extracts of a queried size, want 4", extracts)
+	}
+}
@@ -0,0 +1,73 @@
+// Copyright 2025 The GoGPU Authors
+// SPDX-License-Identifier: MIT
+
+package codegen
+
+import "testing"
+
+const noContractionShader = `
+struct VsOut {
+    @builtin(position) pos: vec4<f32>,
+    @location(0) uv: vec2<f32>,
+}
+
+@group(0) @binding(0) var<uniform> mvp: mat4x4<f32>;
+
+fn scale(p: vec3<f32>) -> vec3<f32> {
+    return p * 2.0 + vec3<f32>(0.5);
+}
+
+@vertex
+fn vs(@location(0) p: vec3<f32>, @location(1) uv: vec2<f32>) -> VsOut {
+    var world = scale(p);
+    world = world - vec3<f32>(1.0);
+    return VsOut(mvp * vec4<f32>(world, 1.0), uv * 3.0 + vec2<f32>(0.25));
+}
+`
+
+// countNoContraction counts OpDecorate <id> NoContraction instructions.
+func countNoContraction(data []byte) int {
+	n := 0
+	for _, inst := range decodeSPIRVInstructions(data) {
+		if inst.opcode == OpDecorate && inst.wordCount == 3 && Decoration(inst.words[2]) == DecorationNoContraction {
+			n++
+		}
+	}
+	return n
+}
+
+func TestNoContractionModes(t *testing.T) {
+	opts := DefaultOptions()
+	if got := countNoContraction(compileWGSLForCapabilityTestWithOpts(t, noContractionShader, opts)); got != 0 {
+		t.Errorf("NoContractionNone: %d decorations, want 0", got)
+	}
+
+	opts.NoContraction = NoContractionPosition
+	position := countNoContraction(compileWGSLForCapabilityTestWithOpts(t, noContractionShader, opts))
+
+	opts.NoContraction = NoContractionAll
+	all := countNoContraction(compileWGSLForCapabilityTestWithOpts(t, noContractionShader, opts))
+
+	// Position path: p*2, +0.5 in scale, world-1, mvp*vec4. The uv math
+	// (uv*3, +0.25) is only decorated in All mode.
+	if position < 4 {
+		t.Errorf("NoContractionPosition: %d decorations, want at least 4", position)
+	}
+	if all-position != 2 {
+		t.Errorf("NoContractionAll decorated %d, Position %d; want the 2 uv operations extra", all, position)
+	}
+}
+
+func TestNoContractionFragmentUnaffected(t *testing.T) {
+	src := `
+@fragment
+fn fs(@location(0) uv: vec2<f32>) -> @location(0) vec4<f32> {
+    return vec4<f32>(uv * 2.0, 0.0, 1.0);
+}
+`
+	opts := DefaultOptions()
+	opts.NoContraction = NoContractionPosition
+	if got := countNoContraction(compileWGSLForCapabilityTestWithOpts(t, src, opts)); got != 0 {
+		t.Errorf("fragment shader got %d NoContraction decorations in Position mode", got)
+	}
+}
@@ -0,0 +1,491 @@
+package codegen
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/gogpu/naga/ir"
+)
+
+// emitOverride returns the specialization constant of an override,
+// emitting it on first use by an expression or a workgroup size. One
+// SPIR-V module serves every pipeline configuration: the pipeline sets
+// overrides with an @id through VkSpecializationInfo, and initializers
+// computed from other overrides follow as OpSpecConstantOp chains.
+// Overrides without an @id keep their default value. Unused overrides are
+// not emitted, so one whose initializer has no SPIR-V form (float
+// arithmetic, say) is only an error when the shader reads it.
+func (b *Backend) emitOverride(handle ir.OverrideHandle) (uint32, error) {
+	if id, ok := b.overrideIDs[handle]; ok {
+		return id, nil
+	}
+	if int(handle) >= len(b.module.Overrides) {
+		return 0, fmt.Errorf("override not found: %v", handle)
+	}
+	override := &b.module.Overrides[handle]
+
+	scalar, ok := b.module.Types[override.Ty].Inner.(ir.ScalarType)
+	if !ok {
+		return 0, fmt.Errorf("override %q: type must be a scalar", override.Name)
+	}
+	typeID, err := b.emitType(override.Ty)
+	if err != nil {
+		return 0, err
+	}
+
+	var id uint32
+	var value ir.LiteralValue = ir.LiteralU32(0)
+	derived := false
+	if override.Init != nil {
+		if lit, ok := b.module.GlobalExpressions[*override.Init].Kind.(ir.Literal); ok {
+			value = lit.Value
+		} else {
+			derived = true
+		}
+	}
+
+	if derived {
+		// OpSpecConstantOp results cannot carry a SpecId, so an override
+		// the pipeline can both set and compute has no SPIR-V form.
+		if override.ID != nil {
+			return 0, fmt.Errorf("override %q: @id(%d) override initialized from other overrides cannot be a specialization constant", override.Name, *override.ID)
+		}
+		id, err = b.emitSpecExpression(*override.Init, scalar)
+		if err != nil {
+			return 0, fmt.Errorf("override %q: %w", override.Name, err)
+		}
+	} else {
+		opcode, words, err := specConstantDefault(scalar, value)
+		if err != nil {
+			return 0, fmt.Errorf("override %q: %w", override.Name, err)
+		}
+		id = b.builder.AddSpecConstant(opcode, typeID, words...)
+		if override.ID != nil {
+			b.builder.AddDecorate(id, DecorationSpecID, uint32(*override.ID))
+		}
+	}
+
+	if b.options.Debug && override.Name != "" {
+		b.builder.AddName(id, override.Name)
+	}
+	b.overrideIDs[handle] = id
+	return id, nil
+}
+
+// specConstantDefault returns the opcode and value words of a
+// specialization constant of type scalar defaulting to value.
+func specConstantDefault(scalar ir.ScalarType, value ir.LiteralValue) (OpCode, []uint32, error) {
+	if scalar.Kind == ir.ScalarBool {
+		if b, ok := value.(ir.LiteralBool); ok && bool(b) {
+			return OpSpecConstantTrue, nil, nil
+		}
+		return OpSpecConstantFalse, nil, nil
+	}
+
+	v := ir.LiteralToFloat(value)
+	var bits uint64
+	switch scalar.Kind {
+	case ir.ScalarFloat:
+		switch scalar.Width {
+		case 2:
+			return OpSpecConstant, []uint32{float32ToF16Bits(float32(v))}, nil
+		case 4:
+			return OpSpecConstant, []uint32{math.Float32bits(float32(v))}, nil
+		}
+		bits = math.Float64bits(v)
+	case ir.ScalarSint:
+		bits = uint64(int64(v))
+	case ir.ScalarUint:
+		bits = uint64(v)
+	default:
+		return 0, nil, fmt.Errorf("unsupported override scalar kind %v", scalar.Kind)
+	}
+	if scalar.Width == 8 {
+		return OpSpecConstant, []uint32{uint32(bits), uint32(bits >> 32)}, nil
+	}
+	return OpSpecConstant, []uint32{uint32(bits)}, nil
+}
+
+// emitSpecExpression emits a global expression an override initializer
+// computes from other overrides. Literals and constants become ordinary
+// constants, operations become OpSpecConstantOp. The Shader capability
+// allows only integer and boolean operations there, so float arithmetic
+// on overrides is an error; ir.ProcessOverrides can fold it instead.
+//
+// Unsuffixed literals in initializers carry no type of their own, so
+// want, the scalar type the surrounding operation expects, types them.
+func (b *Backend) emitSpecExpression(handle ir.ExpressionHandle, want ir.ScalarType) (uint32, error) {
+	if scalar, ok := b.specScalar(handle); ok {
+		want = scalar
+	}
+	if id, ok := b.specExprIDs[handle]; ok {
+		return id, nil
+	}
+	typeID, err := b.emitScalarType(want)
+	if err != nil {
+		return 0, err
+	}
+
+	var id uint32
+	switch kind := b.module.GlobalExpressions[handle].Kind.(type) {
+	case ir.Literal:
+		// A literal is typed by its context, so it is not cached.
+		return b.emitLiteral(literalAs(kind.Value, want))
+	case ir.ExprConstant:
+		id, err = b.emitConstant(kind.Constant)
+	case ir.ExprOverride:
+		id, err = b.emitOverride(kind.Override)
+	case ir.ExprUnary:
+		id, err = b.emitSpecUnary(typeID, want, kind)
+	case ir.ExprBinary:
+		id, err = b.emitSpecBinary(typeID, want, kind)
+	case ir.ExprSelect:
+		if want.Kind == ir.ScalarFloat {
+			return 0, fmt.Errorf("select on float values cannot be a specialization constant")
+		}
+		var ids [3]uint32
+		for i, h := range []ir.ExpressionHandle{kind.Condition, kind.Accept, kind.Reject} {
+			operand := want
+			if i == 0 {
+				operand = ir.ScalarType{Kind: ir.ScalarBool, Width: 1}
+			}
+			if ids[i], err = b.emitSpecExpression(h, operand); err != nil {
+				return 0, err
+			}
+		}
+		id = b.builder.AddSpecConstantOp(typeID, OpSelect, ids[0], ids[1], ids[2])
+	case ir.ExprAs:
+		id, err = b.emitSpecConversion(typeID, want, kind)
+	default:
+		return 0, fmt.Errorf("%T cannot be a specialization constant", kind)
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	b.specExprIDs[handle] = id
+	return id, nil
+}
+
+// specScalar returns the scalar type of a global expression in an
+// override initializer, or false when only a bare literal decides it.
+func (b *Backend) specScalar(handle ir.ExpressionHandle) (ir.ScalarType, bool) {
+	boolean := ir.ScalarType{Kind: ir.ScalarBool, Width: 1}
+	switch kind := b.module.GlobalExpressions[handle].Kind.(type) {
+	case ir.ExprOverride:
+		scalar, ok := b.module.Types[b.module.Overrides[kind.Override].Ty].Inner.(ir.ScalarType)
+		return scalar, ok
+	case ir.ExprConstant:
+		scalar, ok := b.module.Types[b.module.Constants[kind.Constant].Type].Inner.(ir.ScalarType)
+		return scalar, ok
+	case ir.ExprUnary:
+		if kind.Op == ir.UnaryLogicalNot {
+			return boolean, true
+		}
+		return b.specScalar(kind.Expr)
+	case ir.ExprBinary:
+		if isComparison(kind.Op) || kind.Op == ir.BinaryLogicalAnd || kind.Op == ir.BinaryLogicalOr {
+			return boolean, true
+		}
+		if scalar, ok := b.specScalar(kind.Left); ok {
+			return scalar, true
+		}
+		if kind.Op == ir.BinaryShiftLeft || kind.Op == ir.BinaryShiftRight {
+			return ir.ScalarType{}, false
+		}
+		return b.specScalar(kind.Right)
+	case ir.ExprSelect:
+		if scalar, ok := b.specScalar(kind.Accept); ok {
+			return scalar, true
+		}
+		return b.specScalar(kind.Reject)
+	case ir.ExprAs:
+		src, ok := b.specScalar(kind.Expr)
+		if kind.Convert != nil {
+			return ir.ScalarType{Kind: kind.Kind, Width: *kind.Convert}, true
+		}
+		return ir.ScalarType{Kind: kind.Kind, Width: src.Width}, ok
+	}
+	return ir.ScalarType{}, false
+}
+
+// isComparison reports whether op compares its operands.
+func isComparison(op ir.BinaryOperator) bool {
+	switch op {
+	case ir.BinaryEqual, ir.BinaryNotEqual, ir.BinaryLess, ir.BinaryLessEqual,
+		ir.BinaryGreater, ir.BinaryGreaterEqual:
+		return true
+	}
+	return false
+}
+
+// literalAs converts a literal to the scalar type of its context.
+func literalAs(value ir.LiteralValue, scalar ir.ScalarType) ir.LiteralValue {
+	if v, ok := value.(ir.LiteralBool); ok && scalar.Kind == ir.ScalarBool {
+		return v
+	}
+	v := ir.LiteralToFloat(value)
+	switch scalar.Kind {
+	case ir.ScalarBool:
+		return ir.LiteralBool(v != 0)
+	case ir.ScalarSint:
+		if scalar.Width == 8 {
+			return ir.LiteralI64(int64(v))
+		}
+		return ir.LiteralI32(int32(v))
+	case ir.ScalarUint:
+		if scalar.Width == 8 {
+			return ir.LiteralU64(uint64(v))
+		}
+		return ir.LiteralU32(uint32(v))
+	case ir.ScalarFloat:
+		switch scalar.Width {
+		case 2:
+			return ir.LiteralF16(float32(v))
+		case 8:
+			return ir.LiteralF64(v)
+		}
+		return ir.LiteralF32(float32(v))
+	}
+	return value
+}
+
+// emitSpecUnary emits a unary operation on a specialization constant.
+func (b *Backend) emitSpecUnary(typeID uint32, scalar ir.ScalarType, unary ir.ExprUnary) (uint32, error) {
+	if scalar.Kind == ir.ScalarFloat {
+		return 0, fmt.Errorf("float negation cannot be a specialization constant")
+	}
+	operand, err := b.emitSpecExpression(unary.Expr, scalar)
+	if err != nil {
+		return 0, err
+	}
+	var opcode OpCode
+	switch unary.Op {
+	case ir.UnaryNegate:
+		opcode = OpSNegate
+	case ir.UnaryLogicalNot:
+		opcode = OpLogicalNot
+	case ir.UnaryBitwiseNot:
+		opcode = OpNot
+	default:
+		return 0, fmt.Errorf("unsupported unary operator: %v", unary.Op)
+	}
+	return b.builder.AddSpecConstantOp(typeID, opcode, operand), nil
+}
+
+// emitSpecBinary emits a binary operation on specialization constants.
+// Comparisons take their operand type from whichever side has one; shift
+// amounts keep their own type, defaulting to u32.
+func (b *Backend) emitSpecBinary(typeID uint32, scalar ir.ScalarType, binary ir.ExprBinary) (uint32, error) {
+	leftType, rightType := scalar, scalar
+	switch {
+	case isComparison(binary.Op):
+		leftType = ir.ScalarType{Kind: ir.ScalarSint, Width: 4}
+		if s, ok := b.specScalar(binary.Left); ok {
+			leftType = s
+		} else if s, ok := b.specScalar(binary.Right); ok {
+			leftType = s
+		}
+		rightType = leftType
+	case binary.Op == ir.BinaryShiftLeft || binary.Op == ir.BinaryShiftRight:
+		rightType = ir.ScalarType{Kind: ir.ScalarUint, Width: 4}
+	}
+
+	left, err := b.emitSpecExpression(binary.Left, leftType)
+	if err != nil {
+		return 0, err
+	}
+	right, err := b.emitSpecExpression(binary.Right, rightType)
+	if err != nil {
+		return 0, err
+	}
+	opcode, err := specBinaryOp(binary.Op, leftType.Kind)
+	if err != nil {
+		return 0, err
+	}
+	return b.builder.AddSpecConstantOp(typeID, opcode, left, right), nil
+}
+
+// specBinaryOp returns the OpSpecConstantOp opcode of op on operands of
+// the given kind.
+func specBinaryOp(op ir.BinaryOperator, kind ir.ScalarKind) (OpCode, error) {
+	signed := kind == ir.ScalarSint
+	switch kind {
+	case ir.ScalarFloat:
+		return 0, fmt.Errorf("float arithmetic cannot be a specialization constant")
+	case ir.ScalarBool:
+		switch op {
+		case ir.BinaryEqual:
+			return OpLogicalEqual, nil
+		case ir.BinaryNotEqual, ir.BinaryExclusiveOr:
+			return OpLogicalNotEqual, nil
+		case ir.BinaryAnd, ir.BinaryLogicalAnd:
+			return OpLogicalAnd, nil
+		case ir.BinaryInclusiveOr, ir.BinaryLogicalOr:
+			return OpLogicalOr, nil
+		}
+		return 0, fmt.Errorf("unsupported boolean operator: %v", op)
+	}
+
+	pick := func(s, u OpCode) OpCode {
+		if signed {
+			return s
+		}
+		return u
+	}
+	switch op {
+	case ir.BinaryAdd:
+		return OpIAdd, nil
+	case ir.BinarySubtract:
+		return OpISub, nil
+	case ir.BinaryMultiply:
+		return OpIMul, nil
+	case ir.BinaryDivide:
+		return pick(OpSDiv, OpUDiv), nil
+	case ir.BinaryModulo:
+		return pick(OpSRem, OpUMod), nil
+	case ir.BinaryEqual:
+		return OpIEqual, nil
+	case ir.BinaryNotEqual:
+		return OpINotEqual, nil
+	case ir.BinaryLess:
+		return pick(OpSLessThan, OpULessThan), nil
+	case ir.BinaryLessEqual:
+		return pick(OpSLessThanEqual, OpULessThanEqual), nil
+	case ir.BinaryGreater:
+		return pick(OpSGreaterThan, OpUGreaterThan), nil
+	case ir.BinaryGreaterEqual:
+		return pick(OpSGreaterThanEqual, OpUGreaterThanEqual), nil
+	case ir.BinaryAnd:
+		return OpBitwiseAnd, nil
+	case ir.BinaryExclusiveOr:
+		return OpBitwiseXor, nil
+	case ir.BinaryInclusiveOr:
+		return OpBitwiseOr, nil
+	case ir.BinaryShiftLeft:
+		return OpShiftLeftLogical, nil
+	case ir.BinaryShiftRight:
+		return pick(OpShiftRightArithmetic, OpShiftRightLogical), nil
+	}
+	return 0, fmt.Errorf("unsupported integer operator: %v", op)
+}
+
+// emitSpecConversion emits a conversion between integer and boolean
+// specialization constants. OpSpecConstantOp has no same-width integer
+// conversion, so i32 and u32 convert by adding zero of the result type,
+// as glslang does.
+func (b *Backend) emitSpecConversion(typeID uint32, scalar ir.ScalarType, as ir.ExprAs) (uint32, error) {
+	src, ok := b.specScalar(as.Expr)
+	if !ok {
+		src = scalar
+	}
+	operand, err := b.emitSpecExpression(as.Expr, src)
+	if err != nil {
+		return 0, err
+	}
+	if src == scalar {
+		return operand, nil
+	}
+	if src.Kind == ir.ScalarFloat || scalar.Kind == ir.ScalarFloat {
+		return 0, fmt.Errorf("float conversion cannot be a specialization constant")
+	}
+
+	zero := func(s ir.ScalarType) (uint32, error) {
+		id, err := b.emitScalarType(s)
+		if err != nil {
+			return 0, err
+		}
+		if s.Width == 8 {
+			return b.builder.AddConstant(id, 0, 0), nil
+		}
+		return b.builder.AddConstant(id, 0), nil
+	}
+
+	switch {
+	case scalar.Kind == ir.ScalarBool:
+		z, err := zero(src)
+		if err != nil {
+			return 0, err
+		}
+		return b.builder.AddSpecConstantOp(typeID, OpINotEqual, operand, z), nil
+	case src.Kind == ir.ScalarBool:
+		z, err := zero(scalar)
+		if err != nil {
+			return 0, err
+		}
+		one := b.builder.AddConstant(typeID, 1)
+		if scalar.Width == 8 {
+			one = b.builder.AddConstant(typeID, 1, 0)
+		}
+		return b.builder.AddSpecConstantOp(typeID, OpSelect, operand, one, z), nil
+	case src.Width != scalar.Width:
+		if src.Kind == ir.ScalarSint {
+			return b.builder.AddSpecConstantOp(typeID, OpSConvert, operand), nil
+		}
+		return b.builder.AddSpecConstantOp(typeID, OpUConvert, operand), nil
+	default:
+		z, err := zero(scalar)
+		if err != nil {
+			return 0, err
+		}
+		return b.builder.AddSpecConstantOp(typeID, OpIAdd, operand, z), nil
+	}
+}
+
+// emitArrayLength returns the length operand of OpTypeArray: a u32
+// constant, or the specialization constant of the override sizing a
+// workgroup array, so the pipeline sets the length with the override.
+func (b *Backend) emitArrayLength(size ir.ArraySize) (uint32, error) {
+	if size.Override != nil {
+		id, err := b.emitOverride(*size.Override)
+		if err != nil {
+			return 0, fmt.Errorf("array length: %w", err)
+		}
+		return id, nil
+	}
+	u32TypeID, err := b.emitScalarType(ir.ScalarType{Kind: ir.ScalarUint, Width: 4})
+	if err != nil {
+		return 0, err
+	}
+	return b.builder.AddConstant(u32TypeID, *size.Constant), nil
+}
+
+// emitWorkgroupSizeOverrides decorates an OpSpecConstantComposite as the
+// WorkgroupSize built-in when overrides size the workgroup of ep. The
+// built-in replaces LocalSize for every entry point of the module, so it
+// needs ep to be the only compute entry point.
+func (b *Backend) emitWorkgroupSizeOverrides(ep ir.EntryPoint) error {
+	if ep.WorkgroupOverrides == [3]*ir.OverrideHandle{} {
+		return nil
+	}
+	for _, other := range b.module.EntryPoints {
+		if other.Stage == ir.StageCompute && other.Name != ep.Name {
+			return fmt.Errorf("entry point %q: workgroup size overrides need it to be the only compute entry point in the module", ep.Name)
+		}
+	}
+
+	u32 := ir.ScalarType{Kind: ir.ScalarUint, Width: 4}
+	u32ID, err := b.emitScalarType(u32)
+	if err != nil {
+		return err
+	}
+	var axes [3]uint32
+	for axis, h := range ep.WorkgroupOverrides {
+		if h == nil {
+			axes[axis] = b.builder.AddConstant(u32ID, ep.Workgroup[axis])
+			continue
+		}
+		id, err := b.emitOverride(*h)
+		if err != nil {
+			return err
+		}
+		if scalar, ok := b.module.Types[b.module.Overrides[*h].Ty].Inner.(ir.ScalarType); ok && scalar != u32 {
+			id = b.builder.AddSpecConstantOp(u32ID, OpIAdd, id, b.builder.AddConstant(u32ID, 0))
+		}
+		axes[axis] = id
+	}
+	vecID := b.emitVectorType(u32ID, 3)
+	id := b.builder.AddSpecConstantComposite(vecID, axes[:]...)
+	b.builder.AddDecorate(id, DecorationBuiltIn, uint32(BuiltInWorkgroupSize))
+	return nil
+}
@@ -0,0 +1,169 @@
+// Copyright 2025 The GoGPU Authors
+// SPDX-License-Identifier: MIT
+
+package codegen
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestOverridesSpecConstants(t *testing.T) {
+	data := compileWGSLForCapabilityTest(t, `
+override scale: f32 = 2.0;
+@id(3) override count: u32 = 4u;
+@id(4) override enabled: bool = true;
+@id(5) override wg: i32 = 64;
+override total = count * 2u + 1u;
+override half = wg / 2;
+@group(0) @binding(0) var<storage, read_write> out: array<f32>;
+@compute @workgroup_size(wg, 1)
+fn main(@builtin(local_invocation_index) i: u32) {
+    if enabled {
+        out[i] = scale + f32(total) + f32(half);
+    }
+}
+`)
+	instrs := decodeSPIRVInstructions(data)
+
+	counts := countOpcodes(data, OpSpecConstant, OpSpecConstantTrue, OpSpecConstantOp, OpSpecConstantComposite)
+	if counts[OpSpecConstant] != 3 || counts[OpSpecConstantTrue] != 1 {
+		t.Errorf("expected 3 OpSpecConstant and 1 OpSpecConstantTrue, got %v", counts)
+	}
+	if counts[OpSpecConstantComposite] != 1 {
+		t.Errorf("expected the workgroup size as 1 OpSpecConstantComposite, got %d", counts[OpSpecConstantComposite])
+	}
+
+	specIDs := map[uint32]bool{}
+	workgroupSize := false
+	ops := map[OpCode]bool{}
+	for _, inst := range instrs {
+		switch inst.opcode {
+		case OpDecorate:
+			if len(inst.words) >= 4 && Decoration(inst.words[2]) == DecorationSpecID {
+				specIDs[inst.words[3]] = true
+			}
+			if len(inst.words) >= 4 && Decoration(inst.words[2]) == DecorationBuiltIn &&
+				BuiltIn(inst.words[3]) == BuiltInWorkgroupSize {
+				workgroupSize = true
+			}
+		case OpSpecConstantOp:
+			if len(inst.words) >= 4 {
+				ops[OpCode(inst.words[3])] = true
+			}
+		}
+	}
+	for _, id := range []uint32{3, 4, 5} {
+		if !specIDs[id] {
+			t.Errorf("missing SpecId %d decoration", id)
+		}
+	}
+	if !workgroupSize {
+		t.Error("missing WorkgroupSize built-in decoration")
+	}
+	for _, op := range []OpCode{OpIMul, OpIAdd, OpSDiv} {
+		if !ops[op] {
+			t.Errorf("missing OpSpecConstantOp with opcode %d", op)
+		}
+	}
+}
+
+func TestOverridesSpecConstantErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		source  string
+		wantErr string
+	}{
+		{
+			name: "float arithmetic",
+			source: `
+override scale: f32 = 2.0;
+override doubled = scale * 2.0;
+@group(0) @binding(0) var<storage, read_write> out: array<f32>;
+@compute @workgroup_size(1)
+fn main() { out[0] = doubled; }
+`,
+			wantErr: "float arithmetic cannot be a specialization constant",
+		},
+		{
+			name: "id on derived override",
+			source: `
+@id(0) override count: u32 = 4u;
+@id(1) override total = count + 1u;
+@group(0) @binding(0) var<storage, read_write> out: array<u32>;
+@compute @workgroup_size(1)
+fn main() { out[0] = total; }
+`,
+			wantErr: "initialized from other overrides cannot be a specialization constant",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			module := compileWGSLModule(t, tt.source)
+			_, err := NewBackend(DefaultOptions()).Compile(module)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("expected error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+// Overrides are emitted when read, so an unused one whose initializer has
+// no SPIR-V form does not fail the module.
+func TestOverridesUnusedNotEmitted(t *testing.T) {
+	data := compileWGSLForCapabilityTest(t, `
+override scale: f32 = 2.0;
+override half_scale = scale * 0.5;
+@id(1) override count: u32 = 4u;
+@group(0) @binding(0) var<storage, read_write> out: array<u32>;
+@compute @workgroup_size(1)
+fn main() { out[0] = count; }
+`)
+	counts := countOpcodes(data, OpSpecConstant, OpSpecConstantOp)
+	if counts[OpSpecConstant] != 1 || counts[OpSpecConstantOp] != 0 {
+		t.Errorf("expected only count as a specialization constant, got %v", counts)
+	}
+}
+
+// Workgroup arrays sized by overrides take their length from the
+// specialization constant, computed with OpSpecConstantOp for an
+// expression.
+func TestOverridesSizeWorkgroupArrays(t *testing.T) {
+	data := compileWGSLForCapabilityTest(t, `
+@id(0) override N: u32 = 4u;
+var<workgroup> buf: array<f32, N * 2u>;
+var<workgroup> raw: array<u32, N>;
+@compute @workgroup_size(64)
+fn main(@builtin(local_invocation_index) i: u32) {
+    buf[i % (N * 2u)] = f32(i);
+    raw[0] = u32(buf[1]);
+}
+`)
+	specOps := map[uint32]OpCode{}
+	specConstants := map[uint32]bool{}
+	var lengths []uint32
+	for _, inst := range decodeSPIRVInstructions(data) {
+		switch inst.opcode {
+		case OpSpecConstant:
+			specConstants[inst.words[2]] = true
+		case OpSpecConstantOp:
+			specOps[inst.words[2]] = OpCode(inst.words[3])
+		case OpTypeArray:
+			lengths = append(lengths, inst.words[3])
+		}
+	}
+	var byN, byProduct, other int
+	for _, id := range lengths {
+		switch {
+		case specConstants[id]:
+			byN++
+		case specOps[id] == OpIMul:
+			byProduct++
+		default:
+			other++
+		}
+	}
+	if byN == 0 || byProduct == 0 || other != 0 {
+		t.Errorf("want arrays sized by N and by N * 2u only, got %d, %d and %d others", byN, byProduct, other)
+	}
+}
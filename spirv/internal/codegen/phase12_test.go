@@ -738,6 +738,38 @@ func TestForcePointSize_VertexShader(t *testing.T) {
 	}
 }
 
+// TestForcePointSize_ShaderOutput verifies that a @builtin(point_size) output
+// is decorated BuiltIn PointSize and that ForcePointSize doesn't add a second
+// PointSize variable next to it.
+func TestForcePointSize_ShaderOutput(t *testing.T) {
+	src := `
+struct VsOut {
+    @builtin(position) pos: vec4<f32>,
+    @builtin(point_size) size: f32,
+};
+
+@vertex
+fn main(@location(0) pos: vec4<f32>) -> VsOut {
+    return VsOut(pos, 4.0);
+}
+`
+	for _, force := range []bool{false, true} {
+		opts := DefaultOptions()
+		opts.ForcePointSize = force
+		spvBytes := compileWGSLForCapabilityTestWithOpts(t, src, opts)
+
+		pointSizeVars := 0
+		for _, builtinVal := range findBuiltInDecorations(spvBytes) {
+			if BuiltIn(builtinVal) == BuiltInPointSize {
+				pointSizeVars++
+			}
+		}
+		if pointSizeVars != 1 {
+			t.Errorf("ForcePointSize=%v: got %d PointSize variables, want 1", force, pointSizeVars)
+		}
+	}
+}
+
 // TestForcePointSize_FragmentShader verifies that fragment shaders do NOT
 // get a PointSize variable even when ForcePointSize=true.
 func TestForcePointSize_FragmentShader(t *testing.T) {
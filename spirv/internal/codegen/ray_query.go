@@ -277,7 +277,7 @@ func (b *Backend) writeRayQueryInitialize() uint32 {
 
 	fb.Consume(mergeBlock, Instruction{Opcode: OpReturn})
 
-	b.builder.functions = append(b.builder.functions, fb.ToInstructions()...)
+	b.builder.functions = fb.AppendInstructions(b.builder.functions)
 	b.rayQueryFuncIDs[rqFuncInitialize] = funcID
 	return funcID
 }
@@ -365,7 +365,7 @@ func (b *Backend) writeRayQueryProceed() uint32 {
 	mergeBlock.Push(Instruction{Opcode: OpLoad, Words: []uint32{boolTypeID, loadedProceededID, proceededID}})
 	fb.Consume(mergeBlock, Instruction{Opcode: OpReturnValue, Words: []uint32{loadedProceededID}})
 
-	b.builder.functions = append(b.builder.functions, fb.ToInstructions()...)
+	b.builder.functions = fb.AppendInstructions(b.builder.functions)
 	b.rayQueryFuncIDs[rqFuncProceed] = funcID
 	return funcID
 }
@@ -602,7 +602,7 @@ func (b *Backend) writeRayQueryGetIntersection(committed bool) uint32 {
 	finalBlock.Push(Instruction{Opcode: OpLoad, Words: []uint32{riTypeID, loadedIntersectionID, blankIntersectionID}})
 	fb.Consume(finalBlock, Instruction{Opcode: OpReturnValue, Words: []uint32{loadedIntersectionID}})
 
-	b.builder.functions = append(b.builder.functions, fb.ToInstructions()...)
+	b.builder.functions = fb.AppendInstructions(b.builder.functions)
 	b.rayQueryFuncIDs[kind] = funcID
 
 	_ = boolPtrTypeID
@@ -748,7 +748,7 @@ func (b *Backend) writeRayQueryGenerateIntersection() uint32 {
 
 	fb.Consume(finalBlock, Instruction{Opcode: OpReturn})
 
-	b.builder.functions = append(b.builder.functions, fb.ToInstructions()...)
+	b.builder.functions = fb.AppendInstructions(b.builder.functions)
 	b.rayQueryFuncIDs[rqFuncGenerateIntersection] = funcID
 	return funcID
 }
@@ -826,7 +826,7 @@ func (b *Backend) writeRayQueryConfirmIntersection() uint32 {
 
 	fb.Consume(finalBlock, Instruction{Opcode: OpReturn})
 
-	b.builder.functions = append(b.builder.functions, fb.ToInstructions()...)
+	b.builder.functions = fb.AppendInstructions(b.builder.functions)
 	b.rayQueryFuncIDs[rqFuncConfirmIntersection] = funcID
 	return funcID
 }
@@ -0,0 +1,65 @@
+// Copyright 2025 The GoGPU Authors
+// SPDX-License-Identifier: MIT
+
+package codegen
+
+import "testing"
+
+// countOpcodes counts the instructions with each of the given opcodes.
+func countOpcodes(data []byte, opcodes ...OpCode) map[OpCode]int {
+	counts := make(map[OpCode]int, len(opcodes))
+	for _, inst := range decodeSPIRVInstructions(data) {
+		for _, op := range opcodes {
+			if inst.opcode == op {
+				counts[op]++
+			}
+		}
+	}
+	return counts
+}
+
+func TestRelationalIsNanIsInf(t *testing.T) {
+	data := compileWGSLForCapabilityTest(t, `
+fn classify(x: f32, v: vec3<f32>) -> u32 {
+    var r = 0u;
+    if isnan(x) { r |= 1u; }
+    if isinf(x) { r |= 2u; }
+    if isnan(v).y { r |= 4u; }
+    if isinf(v).z { r |= 8u; }
+    return r;
+}
+
+@fragment
+fn main(@location(0) x: f32, @location(1) v: vec3<f32>) -> @location(0) @interpolate(flat) u32 {
+    return classify(x, v);
+}
+`)
+	assertValidSPIRV(t, data)
+	counts := countOpcodes(data, OpIsNan, OpIsInf)
+	if counts[OpIsNan] != 2 || counts[OpIsInf] != 2 {
+		t.Errorf("got %d OpIsNan and %d OpIsInf, want 2 of each", counts[OpIsNan], counts[OpIsInf])
+	}
+}
+
+func TestRelationalAnyAll(t *testing.T) {
+	data := compileWGSLForCapabilityTest(t, `
+@fragment
+fn main(@location(0) v: vec4f, @location(1) w: vec4f) -> @location(0) vec4f {
+    let b = v.x > w.x;
+    let m = v < w;
+    var r = select(v, w, m);
+    if any(b) && all(!b) { r.x = 1.0; }
+    if all(m) || any(v.xy == w.xy) { r.y = 2.0; }
+    return select(r, vec4f(0.0), any(m));
+}
+`)
+	assertValidSPIRV(t, data)
+	// any(b) and all(!b) on a scalar bool pass the operand through.
+	counts := countOpcodes(data, OpAny, OpAll, OpSelect)
+	if counts[OpAny] != 2 || counts[OpAll] != 1 {
+		t.Errorf("got %d OpAny and %d OpAll, want 2 and 1", counts[OpAny], counts[OpAll])
+	}
+	if counts[OpSelect] != 2 {
+		t.Errorf("got %d OpSelect, want 2", counts[OpSelect])
+	}
+}
@@ -0,0 +1,71 @@
+package codegen
+
+import "github.com/gogpu/naga/ir"
+
+// SourceMap returns, after a compilation with Options.SourceMap, the
+// source span of each instruction computing an expression, keyed by its
+// 0-based index among the instructions after the module header.
+// Instructions in between, such as stores and branches, belong to the
+// nearest mapped instruction before them.
+func (b *Backend) SourceMap() []ir.OutputSpan {
+	return b.sourceMap
+}
+
+// recordSpan records the span of the expression id computes. Literals,
+// constants and handles to variables and arguments name IDs declared
+// outside the code computing the expression, so they are not recorded,
+// and an ID several expressions map to keeps the first span.
+func (b *Backend) recordSpan(id uint32, expr *ir.Expression) {
+	switch expr.Kind.(type) {
+	case ir.Literal, ir.ExprZeroValue, ir.ExprConstant, ir.ExprOverride,
+		ir.ExprGlobalVariable, ir.ExprLocalVariable, ir.ExprFunctionArgument:
+		return
+	}
+	if id == 0 || expr.Span.IsZero() {
+		return
+	}
+	if _, ok := b.idSpans[id]; !ok {
+		b.idSpans[id] = expr.Span
+	}
+}
+
+// instructionSpans maps the function instructions defining the IDs in
+// spans to their spans, numbering instructions as Build writes them.
+func (b *ModuleBuilder) instructionSpans(spans map[uint32]ir.Span) []ir.OutputSpan {
+	index := len(b.capabilities) + len(b.extensions) + len(b.extInstImports) +
+		len(b.entryPoints) + len(b.executionModes) + len(b.debugStrings) +
+		len(b.debugNames) + len(b.annotations) + len(b.types) + len(b.globalVars)
+	if b.memoryModel != nil {
+		index++
+	}
+
+	var out []ir.OutputSpan
+	defined := make(map[uint32]bool, len(spans))
+	for i, inst := range b.functions {
+		// The second word is the result ID of instructions with a result
+		// type; skip those without a result that carry an operand there.
+		if len(inst.Words) < 2 || !definesSecondWord(inst.Opcode) {
+			continue
+		}
+		id := inst.Words[1]
+		span, ok := spans[id]
+		if !ok || defined[id] {
+			continue
+		}
+		defined[id] = true
+		out = append(out, ir.OutputSpan{Output: index + i, Span: span})
+	}
+	return out
+}
+
+// definesSecondWord reports whether the second word of a function
+// instruction with opcode op, if any, is a result ID.
+func definesSecondWord(op OpCode) bool {
+	switch op {
+	case OpStore, OpImageWrite, OpAtomicStore, OpBranchConditional, OpSwitch,
+		OpSelectionMerge, OpLoopMerge, OpControlBarrier, OpMemoryBarrier,
+		OpRayQueryInitializeKHR, OpRayQueryGenerateIntersectionKHR:
+		return false
+	}
+	return true
+}
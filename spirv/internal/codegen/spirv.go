@@ -1,7 +1,11 @@
 // Package codegen implements SPIR-V code generation from naga IR.
 package codegen
 
-import "github.com/gogpu/naga/ir"
+import (
+	"log/slog"
+
+	"github.com/gogpu/naga/ir"
+)
 
 // Version represents a SPIR-V version.
 type Version struct {
@@ -31,6 +35,18 @@ type Options struct {
 	// Debug includes debug information
 	Debug bool
 
+	// DebugExpressionNames, with Debug, names each intermediate result
+	// after the WGSL text of the expression it computes, taken from the
+	// expression spans and DebugSource.
+	DebugExpressionNames bool
+
+	// DebugSource is the source text expression spans index into.
+	DebugSource string
+
+	// SourceMap records the span of each instruction computing an
+	// expression, returned by Backend.SourceMap.
+	SourceMap bool
+
 	// Validation enables output validation
 	Validation bool
 
@@ -68,8 +84,55 @@ type Options struct {
 	// When false, validation checks are skipped and helper functions branch
 	// unconditionally. Matches Rust naga's ray_query_initialization_tracking.
 	RayQueryInitTracking bool
+
+	// NoContraction selects which float arithmetic is decorated
+	// NoContraction, forbidding the driver from fusing it (e.g. into FMAs).
+	NoContraction NoContractionMode
+
+	// TrimUnusedCapabilities removes, once the module is written, the
+	// capabilities, extensions, and extended instruction set imports no
+	// instruction needs. Capabilities listed in Capabilities are kept.
+	// Off by default, matching Rust naga's output.
+	TrimUnusedCapabilities bool
+
+	// Extensions are additional extensions to declare, after the ones the
+	// generated code needs. Duplicates of those are dropped.
+	Extensions []string
+
+	// BindingDecorations are decorations added to resource variables after
+	// the backend's own, for vendor tooling or driver workarounds.
+	BindingDecorations []BindingDecoration
+
+	// Logger receives phase traces and polyfill warnings; nil disables logging.
+	Logger *slog.Logger
+}
+
+// BindingDecoration decorates the global variables bound at Binding.
+// With IDOperands set it is written as OpDecorateId, whose operands are
+// the variables at those bindings; otherwise as OpDecorate with Operands.
+type BindingDecoration struct {
+	Binding    ir.ResourceBinding
+	Decoration Decoration
+	Operands   []uint32
+	IDOperands []ir.ResourceBinding
 }
 
+// NoContractionMode selects the float arithmetic decorated NoContraction.
+type NoContractionMode uint8
+
+const (
+	// NoContractionNone lets the driver contract all arithmetic.
+	NoContractionNone NoContractionMode = iota
+
+	// NoContractionPosition decorates the arithmetic that computes vertex
+	// position outputs (see ir.PositionDataflow), so separately compiled
+	// pipelines agree on positions and depth without slowing other math.
+	NoContractionPosition
+
+	// NoContractionAll decorates all float arithmetic.
+	NoContractionAll
+)
+
 // BoundsCheckPolicy controls how out-of-bounds resource accesses are handled.
 type BoundsCheckPolicy uint8
 
@@ -180,46 +243,52 @@ type OpCode uint16
 
 // Common opcodes
 const (
-	OpNop               OpCode = 0
-	OpSource            OpCode = 3
-	OpString            OpCode = 7
-	OpName              OpCode = 5
-	OpMemberName        OpCode = 6
-	OpExtInstImport     OpCode = 11
-	OpMemoryModel       OpCode = 14
-	OpEntryPoint        OpCode = 15
-	OpExecutionMode     OpCode = 16
-	OpCapability        OpCode = 17
-	OpTypeVoid          OpCode = 19
-	OpTypeBool          OpCode = 20
-	OpTypeInt           OpCode = 21
-	OpTypeFloat         OpCode = 22
-	OpTypeVector        OpCode = 23
-	OpTypeMatrix        OpCode = 24
-	OpTypeArray         OpCode = 28
-	OpTypeRuntimeArray  OpCode = 29
-	OpTypeStruct        OpCode = 30
-	OpTypePointer       OpCode = 32
-	OpTypeFunction      OpCode = 33
-	OpConstant          OpCode = 43
-	OpConstantComposite OpCode = 44
-	OpConstantNull      OpCode = 46
-	OpFunction          OpCode = 54
-	OpFunctionParameter OpCode = 55
-	OpFunctionEnd       OpCode = 56
-	OpFunctionCall      OpCode = 57
-	OpVariable          OpCode = 59
-	OpLoad              OpCode = 61
-	OpStore             OpCode = 62
-	OpAccessChain       OpCode = 65
-	OpDecorate          OpCode = 71
-	OpMemberDecorate    OpCode = 72
-	OpLabel             OpCode = 248
-	OpBranch            OpCode = 249
-	OpPhi               OpCode = 245
-	OpReturn            OpCode = 253
-	OpReturnValue       OpCode = 254
-	OpUnreachable       OpCode = 255
+	OpNop                   OpCode = 0
+	OpSource                OpCode = 3
+	OpString                OpCode = 7
+	OpName                  OpCode = 5
+	OpMemberName            OpCode = 6
+	OpExtInstImport         OpCode = 11
+	OpMemoryModel           OpCode = 14
+	OpEntryPoint            OpCode = 15
+	OpExecutionMode         OpCode = 16
+	OpCapability            OpCode = 17
+	OpTypeVoid              OpCode = 19
+	OpTypeBool              OpCode = 20
+	OpTypeInt               OpCode = 21
+	OpTypeFloat             OpCode = 22
+	OpTypeVector            OpCode = 23
+	OpTypeMatrix            OpCode = 24
+	OpTypeArray             OpCode = 28
+	OpTypeRuntimeArray      OpCode = 29
+	OpTypeStruct            OpCode = 30
+	OpTypePointer           OpCode = 32
+	OpTypeFunction          OpCode = 33
+	OpConstant              OpCode = 43
+	OpConstantComposite     OpCode = 44
+	OpConstantNull          OpCode = 46
+	OpSpecConstantTrue      OpCode = 48
+	OpSpecConstantFalse     OpCode = 49
+	OpSpecConstant          OpCode = 50
+	OpSpecConstantComposite OpCode = 51
+	OpSpecConstantOp        OpCode = 52
+	OpFunction              OpCode = 54
+	OpFunctionParameter     OpCode = 55
+	OpFunctionEnd           OpCode = 56
+	OpFunctionCall          OpCode = 57
+	OpVariable              OpCode = 59
+	OpLoad                  OpCode = 61
+	OpStore                 OpCode = 62
+	OpAccessChain           OpCode = 65
+	OpDecorate              OpCode = 71
+	OpMemberDecorate        OpCode = 72
+	OpDecorateID            OpCode = 332
+	OpLabel                 OpCode = 248
+	OpBranch                OpCode = 249
+	OpPhi                   OpCode = 245
+	OpReturn                OpCode = 253
+	OpReturnValue           OpCode = 254
+	OpUnreachable           OpCode = 255
 )
 
 // Decoration represents a SPIR-V decoration.
@@ -227,6 +296,7 @@ type Decoration uint32
 
 // Common decorations
 const (
+	DecorationSpecID        Decoration = 1
 	DecorationBlock         Decoration = 2
 	DecorationColMajor      Decoration = 5
 	DecorationRowMajor      Decoration = 4
@@ -237,8 +307,13 @@ const (
 	DecorationNoPerspective Decoration = 13
 	DecorationCentroid      Decoration = 16
 	DecorationSample        Decoration = 17
+	DecorationRestrict      Decoration = 19
+	DecorationAliased       Decoration = 20
+	DecorationVolatile      Decoration = 21
+	DecorationCoherent      Decoration = 23
 	DecorationNonWritable   Decoration = 24
 	DecorationNonReadable   Decoration = 25
+	DecorationNoContraction Decoration = 42
 	DecorationLocation      Decoration = 30
 	DecorationIndex         Decoration = 32 // For dual-source blending
 	DecorationBinding       Decoration = 33
@@ -288,6 +363,7 @@ const (
 	BuiltInSubgroupID           BuiltIn = 40
 	BuiltInViewIndex            BuiltIn = 4440
 	BuiltInBaryCoordKHR         BuiltIn = 5286
+	BuiltInBaryCoordNoPerspKHR  BuiltIn = 5287
 )
 
 // ExecutionModel represents a SPIR-V execution model.
@@ -0,0 +1,197 @@
+package codegen
+
+import (
+	"fmt"
+	"testing"
+)
+
+// checkReachableTerminators walks the control flow graph of every function
+// and fails if a block reachable from the function entry ends in
+// OpUnreachable, or if a branch targets a label that is never defined.
+// A reachable OpUnreachable means the emitter dropped the statements that
+// follow a construct it wrongly believed could not fall through.
+func checkReachableTerminators(t *testing.T, instrs []spirvInstruction, names map[uint32]string) {
+	t.Helper()
+
+	type block struct {
+		term    OpCode
+		targets []uint32
+	}
+	var blocks map[uint32]*block
+	var entry, current uint32
+	var function string
+
+	check := func() {
+		seen := map[uint32]bool{entry: true}
+		stack := []uint32{entry}
+		for len(stack) > 0 {
+			label := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			b := blocks[label]
+			if b == nil {
+				t.Errorf("%s: branch to undefined label %%%d", function, label)
+				continue
+			}
+			if b.term == OpUnreachable {
+				t.Errorf("%s: reachable block %%%d ends in OpUnreachable", function, label)
+			}
+			for _, target := range b.targets {
+				if !seen[target] {
+					seen[target] = true
+					stack = append(stack, target)
+				}
+			}
+		}
+	}
+
+	for _, inst := range instrs {
+		switch inst.opcode {
+		case OpFunction:
+			blocks = make(map[uint32]*block)
+			entry = 0
+			function = names[inst.words[2]]
+			if function == "" {
+				function = fmt.Sprintf("function %%%d", inst.words[2])
+			}
+		case OpLabel:
+			current = inst.words[1]
+			blocks[current] = &block{}
+			if entry == 0 {
+				entry = current
+			}
+		case OpBranch:
+			blocks[current].term = inst.opcode
+			blocks[current].targets = []uint32{inst.words[1]}
+		case OpBranchConditional:
+			blocks[current].term = inst.opcode
+			blocks[current].targets = []uint32{inst.words[2], inst.words[3]}
+		case OpSwitch:
+			b := blocks[current]
+			b.term = inst.opcode
+			b.targets = []uint32{inst.words[2]}
+			for i := 4; i < len(inst.words); i += 2 {
+				b.targets = append(b.targets, inst.words[i])
+			}
+		case OpReturn, OpReturnValue, OpKill, OpUnreachable:
+			blocks[current].term = inst.opcode
+		case OpFunctionEnd:
+			check()
+		}
+	}
+}
+
+// switchShader covers continue and break inside switches nested in loops:
+// continue from a case (directly, under an if, and from a nested switch),
+// loops with continuing blocks and for-loop updates, and degenerate
+// default-only switches whose single case continues or breaks.
+const switchShader = `
+@group(0) @binding(0) var<storage, read_write> out: array<i32>;
+
+fn with_continuing(x: i32) -> i32 {
+    var i = 0;
+    var acc = 0;
+    loop {
+        switch x + i {
+            case 1: { continue; }
+            case 2, 3: {
+                if acc > 5 { continue; }
+                acc += 2;
+            }
+            default: { acc += 1; }
+        }
+        acc += 10;
+        continuing {
+            i += 1;
+            break if i >= 4;
+        }
+    }
+    return acc;
+}
+
+fn degenerate() -> i32 {
+    var acc = 0;
+    for (var i = 0; i < 4; i++) {
+        switch i {
+            default: { continue; }
+        }
+    }
+    for (var j = 0; j < 4; j++) {
+        switch j {
+            default: { break; }
+        }
+        acc += j;
+    }
+    return acc;
+}
+
+fn nested(x: i32, y: i32) -> i32 {
+    var acc = 0;
+    var n = 0;
+    while n < 3 {
+        n += 1;
+        switch x {
+            case 0: {
+                switch y {
+                    case 0: { continue; }
+                    default: { acc += 1; }
+                }
+                acc += 100;
+            }
+            default: {}
+        }
+        acc += 1000;
+    }
+    return acc;
+}
+
+fn break_under_if(x: i32) -> i32 {
+    var acc = 0;
+    loop {
+        switch x {
+            case 0: {
+                if acc == 0 { break; }
+                return acc;
+            }
+            default: { return -1; }
+        }
+        acc += 1;
+        if acc > 2 { break; }
+    }
+    return acc;
+}
+
+@compute @workgroup_size(1)
+fn main() {
+    out[0] = with_continuing(out[1]);
+    out[2] = degenerate();
+    out[3] = nested(out[4], out[5]);
+    out[6] = break_under_if(out[7]);
+}
+`
+
+func TestSwitchContinueAndBreakInLoops(t *testing.T) {
+	spirvBytes := compileWGSLToSPIRV(t, "SwitchInLoops", switchShader)
+	instrs := decodeSPIRVInstructions(spirvBytes)
+	checkReachableTerminators(t, instrs, collectNames(instrs))
+	validateWithVulkanSDK(t, spirvBytes)
+}
+
+func TestDegenerateSwitchBreakKeepsFollowingCode(t *testing.T) {
+	spirvBytes := compileWGSLToSPIRV(t, "DegenerateSwitchBreak", `
+@group(0) @binding(0) var<storage, read_write> out: array<u32>;
+
+@compute @workgroup_size(1)
+fn main() {
+    switch out[0] {
+        default: { break; }
+    }
+    out[1] = 7u;
+}
+`)
+	instrs := decodeSPIRVInstructions(spirvBytes)
+	checkReachableTerminators(t, instrs, collectNames(instrs))
+	// The store after the switch must survive.
+	if countOpcode(instrs, OpStore) == 0 {
+		t.Error("store after the degenerate switch was dropped")
+	}
+}
@@ -0,0 +1,232 @@
+package codegen
+
+// trimmableCapabilities are the capabilities trimUnused can tell are
+// needed from the instructions of a module. Other capabilities, such as
+// Shader or the subgroup ones, are declared only where the code needs
+// them and are always kept.
+var trimmableCapabilities = map[Capability]bool{
+	CapabilityFloat16:                            true,
+	CapabilityFloat64:                            true,
+	CapabilityInt8:                               true,
+	CapabilityInt16:                              true,
+	CapabilityInt64:                              true,
+	CapabilityStorageBuffer16BitAccess:           true,
+	CapabilityUniformAndStorageBuffer16BitAccess: true,
+	CapabilityStorageInputOutput16:               true,
+	CapabilitySampled1D:                          true,
+	CapabilityImage1D:                            true,
+	CapabilitySampledCubeArray:                   true,
+	CapabilityImageCubeArray:                     true,
+	CapabilityStorageImageExtendedFormats:        true,
+	CapabilityImageQuery:                         true,
+	CapabilityDerivativeControl:                  true,
+	CapabilitySampleRateShading:                  true,
+	CapabilityMultiView:                          true,
+	CapabilityFragmentBarycentricKHR:             true,
+	CapabilityClipDistance:                       true,
+	CapabilityGeometry:                           true,
+	CapabilityShaderNonUniform:                   true,
+}
+
+// extensionCapabilities lists, for the extensions trimUnused may remove,
+// the capabilities that need them. An extension is removed once none of
+// its capabilities are declared.
+var extensionCapabilities = map[string][]Capability{
+	"SPV_KHR_16bit_storage": {
+		CapabilityStorageBuffer16BitAccess,
+		CapabilityUniformAndStorageBuffer16BitAccess,
+		CapabilityStorageInputOutput16,
+	},
+	"SPV_EXT_descriptor_indexing":         {CapabilityShaderNonUniform},
+	"SPV_KHR_multiview":                   {CapabilityMultiView},
+	"SPV_KHR_fragment_shader_barycentric": {CapabilityFragmentBarycentricKHR},
+}
+
+// basicImageFormats are the storage image formats the Shader capability
+// covers. Unknown and the 64-bit formats have capabilities of their own.
+var basicImageFormats = map[ImageFormat]bool{
+	ImageFormatUnknown:    true,
+	ImageFormatRgba32f:    true,
+	ImageFormatRgba16f:    true,
+	ImageFormatR32f:       true,
+	ImageFormatRgba8:      true,
+	ImageFormatRgba8Snorm: true,
+	ImageFormatRgba32i:    true,
+	ImageFormatRgba16i:    true,
+	ImageFormatRgba8i:     true,
+	ImageFormatR32i:       true,
+	ImageFormatRgba32ui:   true,
+	ImageFormatRgba16ui:   true,
+	ImageFormatRgba8ui:    true,
+	ImageFormatR32ui:      true,
+	ImageFormatR64ui:      true,
+	ImageFormatR64i:       true,
+}
+
+// trimUnused removes the capabilities, extensions, and extended
+// instruction set imports that no instruction of the finished module
+// needs. Capabilities in keep stay declared regardless. Types are
+// emitted for every IR type and capabilities are registered with them,
+// so a 16-bit float used only in function-local math would otherwise
+// still declare the 16-bit storage capabilities.
+func (b *ModuleBuilder) trimUnused(keep map[Capability]bool) {
+	needed := make(map[Capability]bool)
+	usedSets := make(map[uint32]bool)
+	has16Bit := make(map[uint32]bool) // type IDs holding 16-bit scalars
+
+	builtin := func(v BuiltIn) {
+		switch v {
+		case BuiltInSampleID, BuiltInSamplePosition:
+			needed[CapabilitySampleRateShading] = true
+		case BuiltInViewIndex:
+			needed[CapabilityMultiView] = true
+		case BuiltInBaryCoordKHR, BuiltInBaryCoordNoPerspKHR:
+			needed[CapabilityFragmentBarycentricKHR] = true
+		case BuiltInClipDistance:
+			needed[CapabilityClipDistance] = true
+		case BuiltInPrimitiveID:
+			needed[CapabilityGeometry] = true
+		}
+	}
+	decoration := func(d Decoration, operands []uint32) {
+		switch d {
+		case DecorationBuiltIn:
+			if len(operands) > 0 {
+				builtin(BuiltIn(operands[0]))
+			}
+		case DecorationSample:
+			needed[CapabilitySampleRateShading] = true
+		case DecorationNonUniform:
+			needed[CapabilityShaderNonUniform] = true
+		}
+	}
+
+	sections := [][]Instruction{
+		b.entryPoints, b.executionModes, b.debugStrings, b.debugNames,
+		b.annotations, b.types, b.globalVars, b.functions,
+	}
+	for _, section := range sections {
+		for _, inst := range section {
+			w := inst.Words
+			switch inst.Opcode {
+			case OpExtInst:
+				usedSets[w[2]] = true
+			case OpTypeFloat:
+				switch w[1] {
+				case 16:
+					needed[CapabilityFloat16] = true
+					has16Bit[w[0]] = true
+				case 64:
+					needed[CapabilityFloat64] = true
+				}
+			case OpTypeInt:
+				switch w[1] {
+				case 8:
+					needed[CapabilityInt8] = true
+				case 16:
+					needed[CapabilityInt16] = true
+					has16Bit[w[0]] = true
+				case 64:
+					needed[CapabilityInt64] = true
+				}
+			case OpTypeVector, OpTypeMatrix, OpTypeArray, OpTypeRuntimeArray:
+				has16Bit[w[0]] = has16Bit[w[1]]
+			case OpTypeStruct:
+				for _, member := range w[1:] {
+					if has16Bit[member] {
+						has16Bit[w[0]] = true
+					}
+				}
+			case OpTypePointer:
+				if !has16Bit[w[2]] {
+					break
+				}
+				switch StorageClass(w[1]) {
+				case StorageClassStorageBuffer:
+					needed[CapabilityStorageBuffer16BitAccess] = true
+				case StorageClassUniform:
+					needed[CapabilityUniformAndStorageBuffer16BitAccess] = true
+				case StorageClassInput, StorageClassOutput:
+					needed[CapabilityStorageInputOutput16] = true
+				}
+			case OpTypeImage:
+				// id, sampled type, dim, depth, arrayed, ms, sampled, format
+				dim, arrayed, storage := w[2], w[4] == 1, w[6] == 2
+				switch {
+				case dim == 0 && storage:
+					needed[CapabilityImage1D] = true
+				case dim == 0:
+					needed[CapabilitySampled1D] = true
+				case dim == 3 && arrayed && storage:
+					needed[CapabilityImageCubeArray] = true
+				case dim == 3 && arrayed:
+					needed[CapabilitySampledCubeArray] = true
+				}
+				if !basicImageFormats[ImageFormat(w[7])] {
+					needed[CapabilityStorageImageExtendedFormats] = true
+				}
+			case OpImageQuerySizeLod, OpImageQuerySize, OpImageQueryLod, OpImageQueryLevels, OpImageQuerySamples:
+				needed[CapabilityImageQuery] = true
+			case OpDPdxFine, OpDPdyFine, OpFwidthFine, OpDPdxCoarse, OpDPdyCoarse, OpFwidthCoarse:
+				needed[CapabilityDerivativeControl] = true
+			case OpDecorate:
+				decoration(Decoration(w[1]), w[2:])
+			case OpMemberDecorate:
+				decoration(Decoration(w[2]), w[3:])
+			}
+		}
+	}
+
+	declared := make(map[Capability]bool)
+	capabilities := b.capabilities[:0]
+	for _, inst := range b.capabilities {
+		c := Capability(inst.Words[0])
+		if trimmableCapabilities[c] && !needed[c] && !keep[c] {
+			continue
+		}
+		declared[c] = true
+		capabilities = append(capabilities, inst)
+	}
+	b.capabilities = capabilities
+
+	extensions := b.extensions[:0]
+	for _, inst := range b.extensions {
+		if caps, ok := extensionCapabilities[literalString(inst.Words)]; ok && !anyDeclared(declared, caps) {
+			continue
+		}
+		extensions = append(extensions, inst)
+	}
+	b.extensions = extensions
+
+	imports := b.extInstImports[:0]
+	for _, inst := range b.extInstImports {
+		if usedSets[inst.Words[0]] {
+			imports = append(imports, inst)
+		}
+	}
+	b.extInstImports = imports
+}
+
+func anyDeclared(declared map[Capability]bool, caps []Capability) bool {
+	for _, c := range caps {
+		if declared[c] {
+			return true
+		}
+	}
+	return false
+}
+
+// literalString decodes a nul-terminated SPIR-V literal string.
+func literalString(words []uint32) string {
+	var s []byte
+	for _, w := range words {
+		for i := 0; i < 4; i++ {
+			c := byte(w >> (8 * i))
+			if c == 0 {
+				return string(s)
+			}
+			s = append(s, c)
+		}
+	}
+	return string(s)
+}
@@ -0,0 +1,107 @@
+package codegen
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/gogpu/naga/wgsl"
+)
+
+// compileTrimmed compiles source with TrimUnusedCapabilities and extra
+// declared capabilities.
+func compileTrimmed(t *testing.T, source string, extra ...Capability) []byte {
+	t.Helper()
+	tokens, err := wgsl.NewLexer(source).Tokenize()
+	if err != nil {
+		t.Fatalf("Tokenize: %v", err)
+	}
+	ast, err := wgsl.NewParser(tokens).Parse()
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	module, err := wgsl.Lower(ast)
+	if err != nil {
+		t.Fatalf("Lower: %v", err)
+	}
+	opts := DefaultOptions()
+	opts.TrimUnusedCapabilities = true
+	opts.Capabilities = extra
+	code, err := NewBackend(opts).Compile(module)
+	if err != nil {
+		t.Fatalf("Compile: %v", err)
+	}
+	return code
+}
+
+func TestTrimUnusedCapabilities(t *testing.T) {
+	tests := []struct {
+		name    string
+		source  string
+		extra   []Capability
+		want    []Capability
+		notWant []Capability
+		strings map[string]bool // extension or import name -> present
+	}{
+		{
+			name: "f16 in function-local math",
+			source: `enable f16;
+@group(0) @binding(0) var<storage, read_write> out: array<f32>;
+@compute @workgroup_size(1)
+fn main() {
+    let h = f16(out[0]) * 2.0h;
+    out[0] = f32(h);
+}`,
+			want:    []Capability{CapabilityFloat16},
+			notWant: []Capability{CapabilityStorageBuffer16BitAccess, CapabilityUniformAndStorageBuffer16BitAccess, CapabilityStorageInputOutput16},
+			strings: map[string]bool{"SPV_KHR_16bit_storage": false, "GLSL.std.450": false},
+		},
+		{
+			name: "f16 in a storage buffer",
+			source: `enable f16;
+@group(0) @binding(0) var<storage, read_write> out: array<f16>;
+@compute @workgroup_size(1)
+fn main() {
+    out[0] = sqrt(out[0]);
+}`,
+			want:    []Capability{CapabilityFloat16, CapabilityStorageBuffer16BitAccess},
+			notWant: []Capability{CapabilityUniformAndStorageBuffer16BitAccess, CapabilityStorageInputOutput16},
+			strings: map[string]bool{"SPV_KHR_16bit_storage": true, "GLSL.std.450": true},
+		},
+		{
+			name: "sample mask without per-sample shading",
+			source: `@fragment
+fn main(@builtin(sample_mask) mask: u32) -> @location(0) vec4<f32> {
+    return vec4<f32>(f32(mask));
+}`,
+			notWant: []Capability{CapabilitySampleRateShading},
+		},
+		{
+			name: "requested capabilities stay",
+			source: `@compute @workgroup_size(1)
+fn main() {}`,
+			extra: []Capability{CapabilityFloat64},
+			want:  []Capability{CapabilityShader, CapabilityFloat64},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code := compileTrimmed(t, tt.source, tt.extra...)
+			caps := extractCapabilities(code)
+			for _, c := range tt.want {
+				if !caps[uint32(c)] {
+					t.Errorf("capability %s was trimmed", capabilityNameForTest(uint32(c)))
+				}
+			}
+			for _, c := range tt.notWant {
+				if caps[uint32(c)] {
+					t.Errorf("capability %s was kept", capabilityNameForTest(uint32(c)))
+				}
+			}
+			for name, present := range tt.strings {
+				if got := bytes.Contains(code, []byte(name)); got != present {
+					t.Errorf("%s present = %v, want %v", name, got, present)
+				}
+			}
+		})
+	}
+}
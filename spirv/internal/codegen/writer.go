@@ -165,6 +165,10 @@ type ModuleBuilder struct {
 	// ID allocation
 	nextID uint32
 
+	// noContraction decorates float arithmetic emitted by AddBinaryOp and
+	// AddUnaryOp with NoContraction while set.
+	noContraction bool
+
 	// Shared instruction builder to avoid per-instruction allocation.
 	ib InstructionBuilder
 
@@ -213,6 +217,7 @@ func NewModuleBuilder(version Version) *ModuleBuilder {
 // repeated allocation of instruction slices and the word arena.
 func (b *ModuleBuilder) Reset(version Version) {
 	b.version = version
+	b.noContraction = false
 	b.generator = GeneratorID
 	b.bound = 0
 	b.schema = 0
@@ -368,6 +373,17 @@ func (b *ModuleBuilder) AddDecorate(id uint32, decoration Decoration, params ...
 	b.annotations = append(b.annotations, b.ib.Build(OpDecorate))
 }
 
+// AddDecorateID adds a decoration whose operands are IDs (OpDecorateId).
+func (b *ModuleBuilder) AddDecorateID(id uint32, decoration Decoration, ids ...uint32) {
+	b.ib.Reset()
+	b.ib.AddWord(id)
+	b.ib.AddWord(uint32(decoration))
+	for _, param := range ids {
+		b.ib.AddWord(param)
+	}
+	b.annotations = append(b.annotations, b.ib.Build(OpDecorateID))
+}
+
 // AddMemberDecorate adds a member decoration.
 func (b *ModuleBuilder) AddMemberDecorate(structID, member uint32, decoration Decoration, params ...uint32) {
 	b.ib.Reset()
@@ -586,6 +602,50 @@ func (b *ModuleBuilder) AddConstantComposite(typeID uint32, constituents ...uint
 	return id
 }
 
+// AddSpecConstant adds OpSpecConstantTrue, OpSpecConstantFalse, or
+// OpSpecConstant with the default value in values. Specialization
+// constants are never deduplicated: each one is set on its own.
+func (b *ModuleBuilder) AddSpecConstant(opcode OpCode, typeID uint32, values ...uint32) uint32 {
+	id := b.AllocID()
+	b.ib.Reset()
+	b.ib.AddWord(typeID)
+	b.ib.AddWord(id)
+	for _, value := range values {
+		b.ib.AddWord(value)
+	}
+	b.types = append(b.types, b.ib.Build(opcode))
+	return id
+}
+
+// AddSpecConstantComposite adds OpSpecConstantComposite.
+func (b *ModuleBuilder) AddSpecConstantComposite(typeID uint32, constituents ...uint32) uint32 {
+	id := b.AllocID()
+	b.ib.Reset()
+	b.ib.AddWord(typeID)
+	b.ib.AddWord(id)
+	for _, constituent := range constituents {
+		b.ib.AddWord(constituent)
+	}
+	b.types = append(b.types, b.ib.Build(OpSpecConstantComposite))
+	return id
+}
+
+// AddSpecConstantOp adds OpSpecConstantOp, which computes opcode on
+// constant or specialization constant operands when the module is
+// specialized.
+func (b *ModuleBuilder) AddSpecConstantOp(typeID uint32, opcode OpCode, operands ...uint32) uint32 {
+	id := b.AllocID()
+	b.ib.Reset()
+	b.ib.AddWord(typeID)
+	b.ib.AddWord(id)
+	b.ib.AddWord(uint32(opcode))
+	for _, operand := range operands {
+		b.ib.AddWord(operand)
+	}
+	b.types = append(b.types, b.ib.Build(OpSpecConstantOp))
+	return id
+}
+
 // AddVariable adds OpVariable.
 func (b *ModuleBuilder) AddVariable(pointerType uint32, storageClass StorageClass) uint32 {
 	id := b.AllocID()
@@ -681,6 +741,7 @@ func (b *ModuleBuilder) AddBinaryOp(opcode OpCode, resultType uint32, left uint3
 	b.ib.AddWord(left)
 	b.ib.AddWord(right)
 	b.funcAppend(b.ib.Build(opcode))
+	b.decorateNoContraction(opcode, resultID)
 	return resultID
 }
 
@@ -692,9 +753,24 @@ func (b *ModuleBuilder) AddUnaryOp(opcode OpCode, resultType uint32, operand uin
 	b.ib.AddWord(resultID)
 	b.ib.AddWord(operand)
 	b.funcAppend(b.ib.Build(opcode))
+	b.decorateNoContraction(opcode, resultID)
 	return resultID
 }
 
+// decorateNoContraction decorates the result of a float arithmetic
+// instruction NoContraction while noContraction is set.
+func (b *ModuleBuilder) decorateNoContraction(opcode OpCode, resultID uint32) {
+	if !b.noContraction {
+		return
+	}
+	switch opcode {
+	case OpFNegate, OpFAdd, OpFSub, OpFMul, OpFDiv, OpFRem, OpFMod,
+		OpVectorTimesScalar, OpMatrixTimesScalar, OpVectorTimesMatrix,
+		OpMatrixTimesVector, OpMatrixTimesMatrix, OpDot:
+		b.AddDecorate(resultID, DecorationNoContraction)
+	}
+}
+
 // AddLoad adds OpLoad.
 func (b *ModuleBuilder) AddLoad(resultType uint32, pointer uint32) uint32 {
 	resultID := b.AllocID()
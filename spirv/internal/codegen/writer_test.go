@@ -183,3 +183,37 @@ func TestModuleBuilder_IDAllocation(t *testing.T) {
 
 	t.Logf("Allocated IDs: %d, %d, %d", id1, id2, id3)
 }
+
+func TestBackendNewIBReuse(t *testing.T) {
+	backend := NewBackend(Options{Version: Version1_3})
+	backend.builder = NewModuleBuilder(Version1_3)
+
+	first := backend.newIB()
+	first.AddWord(7)
+	for range 100 {
+		backend.newIB().AddWord(1)
+	}
+	if inst := first.Build(OpNop); len(inst.Words) != 1 || inst.Words[0] != 7 {
+		t.Errorf("first builder words = %v, want [7]", inst.Words)
+	}
+
+	// After Reset the same builders are handed out again, emptied.
+	backend.Reset()
+	backend.builder.Reset(Version1_3)
+	allocs := testing.AllocsPerRun(10, func() {
+		backend.Reset()
+		for range 100 {
+			ib := backend.newIB()
+			if len(ib.words) != 0 {
+				t.Fatalf("newIB returned %d words, want none", len(ib.words))
+			}
+			ib.AddWord(1)
+			ib.AddWord(2)
+			_ = ib.Build(OpNop)
+		}
+		backend.builder.Reset(Version1_3)
+	})
+	if allocs != 0 {
+		t.Errorf("newIB after Reset: %v allocs per run, want 0", allocs)
+	}
+}
@@ -5,6 +5,8 @@
 package spirv
 
 import (
+	"log/slog"
+
 	"github.com/gogpu/naga/ir"
 	"github.com/gogpu/naga/spirv/internal/codegen"
 )
@@ -51,6 +53,22 @@ type BoundsCheckPolicies struct {
 	Index BoundsCheckPolicy
 }
 
+// NoContractionMode selects which float arithmetic is decorated
+// NoContraction.
+type NoContractionMode uint8
+
+// NoContractionMode values.
+const (
+	// NoContractionNone lets the driver contract all arithmetic (default).
+	NoContractionNone NoContractionMode = iota
+	// NoContractionPosition decorates only the arithmetic computing vertex
+	// position outputs, fixing z-fighting between separately compiled
+	// passes without slowing the rest of the shader.
+	NoContractionPosition
+	// NoContractionAll decorates all float arithmetic.
+	NoContractionAll
+)
+
 // Capability represents a SPIR-V capability.
 // This is an alias because Capability values are used directly with
 // implementation types (ModuleBuilder, Backend) that remain aliases.
@@ -109,6 +127,23 @@ type Options struct {
 	// Debug includes debug information.
 	Debug bool
 
+	// DebugExpressionNames, with Debug, names every intermediate result
+	// after the WGSL text of the expression it computes (truncated), so
+	// SPIR-V dumps in RenderDoc or Nsight read like the source. Names
+	// come from expression spans, so DebugSource must be the source the
+	// module was lowered from. Off by default: the names make binaries
+	// much larger.
+	DebugExpressionNames bool
+
+	// DebugSource is the WGSL source the module was lowered from, used by
+	// DebugExpressionNames.
+	DebugSource string
+
+	// SourceMap makes Backend.SourceMap map the instructions computing
+	// expressions, by index after the module header, to the WGSL spans
+	// of the expressions. The binary is unchanged.
+	SourceMap bool
+
 	// Validation enables output validation.
 	Validation bool
 
@@ -132,6 +167,52 @@ type Options struct {
 
 	// RayQueryInitTracking enables initialization tracking for ray queries.
 	RayQueryInitTracking bool
+
+	// NoContraction decorates float arithmetic NoContraction so drivers do
+	// not fuse it into FMAs, which round differently between pipelines.
+	NoContraction NoContractionMode
+
+	// TrimUnusedCapabilities drops capabilities, extensions, and extended
+	// instruction set imports that nothing in the generated module needs,
+	// such as the 16-bit storage capabilities of a shader that uses f16
+	// only in function-local math. Drivers may reject modules declaring
+	// capabilities they lack even when unused. Capabilities listed in
+	// Capabilities are kept. Off by default so output matches Rust naga.
+	TrimUnusedCapabilities bool
+
+	// Extensions are additional OpExtension declarations, written after
+	// the ones the generated code needs, for vendor extensions the
+	// backend does not know about.
+	Extensions []string
+
+	// BindingDecorations are extra decorations for resource variables,
+	// written after the backend's own (e.g. Coherent on a storage buffer
+	// a driver mis-caches). Compile fails if a decoration names a binding
+	// no global variable has, or tries to set @group or @binding.
+	BindingDecorations []BindingDecoration
+
+	// Logger, if set, receives debug traces of code generation phases and
+	// a warning for each polyfill emitted (e.g. "emulating f16 shader I/O").
+	// nil disables logging.
+	Logger *slog.Logger
+}
+
+// BindingDecoration decorates the global variables bound at Binding.
+type BindingDecoration struct {
+	// Binding selects the variables to decorate.
+	Binding ir.ResourceBinding
+
+	// Decoration is the decoration to add.
+	Decoration Decoration
+
+	// Operands are the literal operands of the decoration.
+	Operands []uint32
+
+	// IDOperands, if set, writes the decoration as OpDecorateId (SPIR-V
+	// 1.2) with the IDs of the variables at these bindings as operands,
+	// for decorations such as HlslCounterBufferGOOGLE. Each binding must
+	// name exactly one variable.
+	IDOperands []ir.ResourceBinding
 }
 
 // DefaultOptions returns sensible default options.
@@ -306,6 +387,7 @@ const (
 	OpStore             = codegen.OpStore
 	OpAccessChain       = codegen.OpAccessChain
 	OpDecorate          = codegen.OpDecorate
+	OpDecorateID        = codegen.OpDecorateID
 	OpMemberDecorate    = codegen.OpMemberDecorate
 	OpLabel             = codegen.OpLabel
 	OpBranch            = codegen.OpBranch
@@ -551,6 +633,10 @@ const (
 	DecorationNoPerspective = codegen.DecorationNoPerspective
 	DecorationCentroid      = codegen.DecorationCentroid
 	DecorationSample        = codegen.DecorationSample
+	DecorationRestrict      = codegen.DecorationRestrict
+	DecorationAliased       = codegen.DecorationAliased
+	DecorationVolatile      = codegen.DecorationVolatile
+	DecorationCoherent      = codegen.DecorationCoherent
 	DecorationNonWritable   = codegen.DecorationNonWritable
 	DecorationNonReadable   = codegen.DecorationNonReadable
 	DecorationLocation      = codegen.DecorationLocation
@@ -881,6 +967,9 @@ func toCodegenOptions(o Options) codegen.Options {
 		},
 		Capabilities:            o.Capabilities,
 		Debug:                   o.Debug,
+		DebugExpressionNames:    o.DebugExpressionNames,
+		DebugSource:             o.DebugSource,
+		SourceMap:               o.SourceMap,
 		Validation:              o.Validation,
 		UseStorageInputOutput16: o.UseStorageInputOutput16,
 		ForcePointSize:          o.ForcePointSize,
@@ -891,7 +980,23 @@ func toCodegenOptions(o Options) codegen.Options {
 			ImageStore: codegen.BoundsCheckPolicy(o.BoundsCheckPolicies.ImageStore),
 			Index:      codegen.BoundsCheckPolicy(o.BoundsCheckPolicies.Index),
 		},
-		CapabilitiesAvailable: o.CapabilitiesAvailable,
-		RayQueryInitTracking:  o.RayQueryInitTracking,
+		CapabilitiesAvailable:  o.CapabilitiesAvailable,
+		RayQueryInitTracking:   o.RayQueryInitTracking,
+		NoContraction:          codegen.NoContractionMode(o.NoContraction),
+		TrimUnusedCapabilities: o.TrimUnusedCapabilities,
+		Extensions:             o.Extensions,
+		BindingDecorations:     toCodegenDecorations(o.BindingDecorations),
+		Logger:                 o.Logger,
+	}
+}
+
+func toCodegenDecorations(decorations []BindingDecoration) []codegen.BindingDecoration {
+	if len(decorations) == 0 {
+		return nil
+	}
+	out := make([]codegen.BindingDecoration, len(decorations))
+	for i, d := range decorations {
+		out[i] = codegen.BindingDecoration(d)
 	}
+	return out
 }
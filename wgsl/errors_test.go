@@ -3,6 +3,8 @@ package wgsl
 import (
 	"strings"
 	"testing"
+
+	"github.com/gogpu/naga/ir"
 )
 
 func TestLowerWithSource_ErrorPosition(t *testing.T) {
@@ -65,3 +67,20 @@ fn main() -> @builtin(position) vec4<f32> {
 		t.Fatal("expected error for unknown type")
 	}
 }
+
+func TestSourceSpan(t *testing.T) {
+	const source = "a\n// é\nxy"
+	y := uint32(strings.Index(source, "y"))
+	span := SourceSpan(source, ir.Span{Start: y, End: y + 1})
+	if span.Start.Line != 3 || span.Start.Column != 2 || span.End.Column != 3 {
+		t.Errorf("y at %+v, want 3:2-3:3", span)
+	}
+	// Columns count runes, not bytes.
+	end := uint32(len("a\n// é"))
+	if span := SourceSpan(source, ir.Span{Start: 2, End: end}); span.End.Line != 2 || span.End.Column != 5 {
+		t.Errorf("end of line 2 at %d:%d, want 2:5", span.End.Line, span.End.Column)
+	}
+	if span := SourceSpan(source, ir.Span{Start: 0, End: 100}); span != (Span{}) {
+		t.Errorf("span past the source = %+v, want zero", span)
+	}
+}
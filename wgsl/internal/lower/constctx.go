@@ -0,0 +1,198 @@
+package lower
+
+import (
+	"fmt"
+	"math/bits"
+
+	"github.com/gogpu/naga/ir"
+	"github.com/gogpu/naga/wgsl/internal/parser"
+)
+
+// Const-expression contexts.
+//
+// WGSL requires a const-expression of integer type in several places:
+// array sizes, switch case selectors, and the arguments of @group,
+// @binding, @location, @blend_src, @id, @align, @size and @workgroup_size.
+// The helpers below evaluate those expressions with the constant evaluator
+// and report the spec's type and range restrictions as lowering errors, so
+// `@workgroup_size(WG_X)` or `@location(BASE + 1u)` work with module
+// constants and `@binding(1.5)` is rejected instead of silently dropped.
+
+// evalConstInteger evaluates expr as a const-expression of type i32, u32 or
+// abstract integer. The returned kind is ScalarAbstractInt for values built
+// only from unsuffixed literals and abstract constants.
+func (l *Lowerer) evalConstInteger(expr parser.Expr) (ir.ScalarKind, int64, error) {
+	v, err := l.evalConstValue(expr)
+	if err != nil {
+		// Function-scope constants are only visible to the integer
+		// evaluator; it cannot tell abstract values from i32.
+		kind, val, intErr := l.evalConstantIntExpr(expr)
+		if intErr != nil || (kind != ir.ScalarSint && kind != ir.ScalarUint) {
+			return 0, 0, err
+		}
+		return kind, val, nil
+	}
+	if !v.isScalar() {
+		return 0, 0, fmt.Errorf("expected an integer, got a composite value")
+	}
+	switch v.value.Kind {
+	case ir.ScalarSint, ir.ScalarAbstractInt, ir.ScalarUint:
+		return v.value.Kind, v.intValue(), nil
+	case ir.ScalarAbstractFloat:
+		return 0, 0, fmt.Errorf("expected an integer, got an abstract float")
+	default:
+		return 0, 0, fmt.Errorf("expected an integer, got %s", scalarKindName(v.value.Kind))
+	}
+}
+
+// evalConstIndex evaluates expr as a non-negative integer const-expression
+// no smaller than minimum. what names the context in error messages.
+func (l *Lowerer) evalConstIndex(what string, expr parser.Expr, minimum uint32) (uint32, error) {
+	_, val, err := l.evalConstInteger(expr)
+	if err != nil {
+		return 0, fmt.Errorf("%s must be an integer const-expression: %w", what, err)
+	}
+	if val < int64(minimum) || val > int64(^uint32(0)) {
+		if minimum > 0 {
+			return 0, fmt.Errorf("%s must be greater than %d, got %d", what, minimum-1, val)
+		}
+		return 0, fmt.Errorf("%s must not be negative, got %d", what, val)
+	}
+	return uint32(val), nil
+}
+
+// isOverrideSized reports whether the size of arr reads an override.
+func (l *Lowerer) isOverrideSized(arr *parser.ArrayType) bool {
+	return arr.Size != nil && l.overrideReadBy(arr.Size) != ""
+}
+
+// resolveOverrideSizedArray resolves the type of a workgroup variable
+// sized by an override-expression, the one place WGSL allows them. Its
+// length is held by an override: the override itself for
+// `array<f32, N>`, or else a new unnamed override initialized to the size
+// expression, so each such type is distinct, as the spec requires.
+func (l *Lowerer) resolveOverrideSizedArray(arr *parser.ArrayType) (ir.TypeHandle, error) {
+	base, err := l.resolveType(arr.Element)
+	if err != nil {
+		return 0, err
+	}
+
+	h, ok := ir.OverrideHandle(0), false
+	if ident, isIdent := arr.Size.(*parser.Ident); isIdent {
+		h, ok = l.moduleOverrides[ident.Name]
+	}
+	if !ok {
+		init := l.buildOverrideInitExpr(arr.Size)
+		if init == nil {
+			return 0, fmt.Errorf("array size must be an override-expression of overrides and literals")
+		}
+		h = ir.OverrideHandle(len(l.module.Overrides))
+		l.module.Overrides = append(l.module.Overrides, ir.Override{Ty: l.inferOverrideType(arr.Size)})
+		if l.overrideInitExprs == nil {
+			l.overrideInitExprs = make(map[ir.OverrideHandle]ir.OverrideInitExpr)
+		}
+		l.overrideInitExprs[h] = init
+	}
+	st, ok := l.module.Types[l.module.Overrides[h].Ty].Inner.(ir.ScalarType)
+	if !ok || (st.Kind != ir.ScalarSint && st.Kind != ir.ScalarUint) {
+		return 0, fmt.Errorf("array size must be an integer, got %s", typeName(l.module.Types[l.module.Overrides[h].Ty].Inner))
+	}
+
+	one := uint32(1) // until SetOverrideArrayDefaults
+	return l.registerArrayType(base, ir.ArraySize{Constant: &one, Override: &h}), nil
+}
+
+// evalAttributeArg evaluates the single argument of a numeric attribute
+// such as @binding(N) or @location(N).
+func (l *Lowerer) evalAttributeArg(attr *parser.Attribute, minimum uint32) (uint32, error) {
+	if len(attr.Args) != 1 {
+		return 0, fmt.Errorf("@%s expects 1 argument, got %d", attr.Name, len(attr.Args))
+	}
+	return l.evalConstIndex("@"+attr.Name, attr.Args[0], minimum)
+}
+
+// memberLayoutAttributes returns the explicit @align and @size values of a
+// struct member, or 0 when the attribute is absent. natural is the size of
+// the member type, which @size may not go below.
+func (l *Lowerer) memberLayoutAttributes(attrs []parser.Attribute, natural uint32) (align, size uint32, err error) {
+	for i := range attrs {
+		attr := &attrs[i]
+		switch attr.Name {
+		case "align":
+			if align, err = l.evalAttributeArg(attr, 1); err != nil {
+				return 0, 0, err
+			}
+			if bits.OnesCount32(align) != 1 {
+				return 0, 0, fmt.Errorf("@align must be a power of two, got %d", align)
+			}
+		case "size":
+			if size, err = l.evalAttributeArg(attr, 1); err != nil {
+				return 0, 0, err
+			}
+			if size < natural {
+				return 0, 0, fmt.Errorf("@size(%d) is smaller than the member type size %d", size, natural)
+			}
+		}
+	}
+	return align, size, nil
+}
+
+// checkSwitchSelectors validates the case selectors of a switch statement
+// against its selector expression: every selector must be an integer
+// const-expression, concrete i32 and u32 selectors may not be mixed, an
+// abstract selector must be representable in the consensus type, and no
+// value may appear twice. It returns the consensus scalar kind of the
+// selector and case values: ScalarUint, ScalarSint, or ScalarAbstractInt
+// when all of them are abstract.
+func (l *Lowerer) checkSwitchSelectors(stmt *parser.SwitchStmt, selector ir.ExpressionHandle) (ir.ScalarKind, error) {
+	selectorKind := ir.ScalarAbstractInt
+	if inner := l.resolveExprTypeInner(selector); inner != nil {
+		sc, ok := inner.(ir.ScalarType)
+		if !ok || (sc.Kind != ir.ScalarSint && sc.Kind != ir.ScalarUint && sc.Kind != ir.ScalarAbstractInt) {
+			return 0, fmt.Errorf("switch selector must be i32 or u32, got %s", typeName(inner))
+		}
+		selectorKind = sc.Kind
+	}
+	// A selector built from unsuffixed literals is already typed i32 in
+	// the IR but still converts to the type of the case selectors.
+	if kind, _, err := l.evalConstInteger(stmt.Selector); err == nil && kind == ir.ScalarAbstractInt {
+		selectorKind = ir.ScalarAbstractInt
+	}
+
+	type caseValue struct {
+		kind ir.ScalarKind
+		val  int64
+	}
+	var values []caseValue
+	consensus := selectorKind
+	for _, clause := range stmt.Cases {
+		for _, sel := range clause.Selectors {
+			kind, val, err := l.evalConstInteger(sel)
+			if err != nil {
+				return 0, fmt.Errorf("switch case selector must be an integer const-expression: %w", err)
+			}
+			if kind != ir.ScalarAbstractInt {
+				if consensus != ir.ScalarAbstractInt && consensus != kind {
+					return 0, fmt.Errorf("switch case selector of type %s does not match %s", scalarKindName(kind), scalarKindName(consensus))
+				}
+				consensus = kind
+			}
+			values = append(values, caseValue{kind: kind, val: val})
+		}
+	}
+
+	seen := make(map[uint32]bool, len(values))
+	for _, v := range values {
+		if consensus == ir.ScalarUint && v.val < 0 {
+			return 0, fmt.Errorf("switch case selector %d is not representable as u32", v.val)
+		}
+		if seen[uint32(v.val)] {
+			if consensus == ir.ScalarUint {
+				return 0, fmt.Errorf("duplicate switch case selector %du", uint32(v.val))
+			}
+			return 0, fmt.Errorf("duplicate switch case selector %d", int32(v.val))
+		}
+		seen[uint32(v.val)] = true
+	}
+	return consensus, nil
+}
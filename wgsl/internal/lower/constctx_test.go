@@ -0,0 +1,228 @@
+package lower
+
+import (
+	"testing"
+
+	"github.com/gogpu/naga/ir"
+)
+
+func TestConstExprAttributes(t *testing.T) {
+	module := mustCompile(t, `
+const BASE = 1u;
+const WG_X = 8u;
+const WG_Y: i32 = 2;
+@group(BASE - 1u) @binding(BASE + 1u) var<storage, read_write> out: array<u32>;
+
+struct VsOut {
+    @builtin(position) pos: vec4f,
+    @location(BASE + 1u) color: vec4f,
+}
+
+@id(ID) override scale: f32 = 1.0;
+const ID = 3;
+
+@vertex
+fn vs() -> VsOut {
+    return VsOut(vec4f(scale), vec4f());
+}
+
+@compute @workgroup_size(WG_X, WG_Y * 2, 1)
+fn main() {
+    out[0] = 1u;
+}
+`)
+	if rb := module.GlobalVariables[0].Binding; rb == nil || rb.Group != 0 || rb.Binding != 2 {
+		t.Errorf("binding = %+v, want group 0 binding 2", rb)
+	}
+	if id := module.Overrides[0].ID; id == nil || *id != 3 {
+		t.Errorf("override id = %v, want 3", id)
+	}
+	for _, ep := range module.EntryPoints {
+		if ep.Stage == ir.StageCompute && ep.Workgroup != [3]uint32{8, 4, 1} {
+			t.Errorf("workgroup = %v, want [8 4 1]", ep.Workgroup)
+		}
+	}
+	for _, typ := range module.Types {
+		st, ok := typ.Inner.(ir.StructType)
+		if !ok || typ.Name != "VsOut" {
+			continue
+		}
+		loc, ok := (*st.Members[1].Binding).(ir.LocationBinding)
+		if !ok || loc.Location != 2 {
+			t.Errorf("color binding = %+v, want location 2", *st.Members[1].Binding)
+		}
+	}
+}
+
+func TestConstExprMemberLayout(t *testing.T) {
+	module := mustCompile(t, `
+struct S {
+    a: f32,
+    @align(ALIGN) b: f32,
+    @size(ALIGN * 2) c: f32,
+}
+const ALIGN = 16;
+@group(0) @binding(0) var<storage> s: S;
+@compute @workgroup_size(1)
+fn main() { _ = s.b; }
+`)
+	for _, typ := range module.Types {
+		if st, ok := typ.Inner.(ir.StructType); ok && typ.Name == "S" {
+			if st.Members[1].Offset != 16 || st.Members[2].Offset != 20 || st.Span != 64 {
+				t.Errorf("offsets %d %d span %d, want 16 20 64", st.Members[1].Offset, st.Members[2].Offset, st.Span)
+			}
+		}
+	}
+}
+
+func TestConstExprContextErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		want string
+	}{
+		{"float binding", `@group(0) @binding(1.5) var<storage> a: array<u32>;`, "@binding must be an integer const-expression"},
+		{"negative group", `@group(-1) @binding(0) var<storage> a: array<u32>;`, "@group must not be negative"},
+		{"var binding", `var<private> g: u32; @group(0) @binding(g) var<storage> a: array<u32>;`, "'g' is not a constant"},
+		{"float location", `@fragment fn main() -> @location(0.0) vec4f { return vec4f(); }`, "@location must be an integer const-expression"},
+		{"id out of range", `@id(70000) override o: u32 = 1;`, "@id must be less than 65536"},
+		{"align not power of two", `struct S { @align(3) x: f32 }`, "@align must be a power of two"},
+		{"size too small", `struct S { @size(2) x: f32 }`, "@size(2) is smaller than the member type size 4"},
+		{"workgroup zero", `@compute @workgroup_size(0) fn main() {}`, "@workgroup_size must be greater than 0"},
+		{"workgroup float", `@compute @workgroup_size(1.0) fn main() {}`, "got an abstract float"},
+		{"workgroup runtime", `var<private> g: u32; @compute @workgroup_size(g) fn main() {}`, "'g' is not a constant"},
+		{"array size float", `const X = 2.5; var<private> a: array<f32, X>;`, "array size must be an integer const-expression"},
+		{"array size override", `override X = 2; var<private> a: array<f32, X>;`, "only the type of a workgroup variable can be sized by an override"},
+		{"array size float override", `override X = 2.0; var<workgroup> a: array<f32, X>;`, "array size must be an integer, got f32"},
+		{"case type mismatch", `fn f(x: u32) { switch x { case 1i: {} default: {} } }`, "switch case selector of type i32 does not match u32"},
+		{"case mixed types", `fn f() { switch 1 { case 1u, 2i: {} default: {} } }`, "does not match"},
+		{"case negative u32", `fn f(x: u32) { switch x { case -1: {} default: {} } }`, "-1 is not representable as u32"},
+		{"case duplicate", `fn f(x: i32) { switch x { case 1, 2: {} case 1 + 0: {} default: {} } }`, "duplicate switch case selector 1"},
+		{"case runtime", `fn f(x: i32) { var n = 3; switch x { case n: {} default: {} } }`, "switch case selector must be an integer const-expression"},
+		{"float selector", `fn f(x: f32) { switch x { case 1: {} default: {} } }`, "switch selector must be i32 or u32, got f32"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			expectError(t, tt.src, tt.want)
+		})
+	}
+}
+
+func TestOverrideSizedWorkgroupArrays(t *testing.T) {
+	module := mustCompile(t, `
+override N: u32 = 4u;
+override M: u32;
+var<workgroup> buf: array<f32, N * 2u>;
+var<workgroup> raw: array<u32, N>;
+var<workgroup> later: array<u32, M>;
+
+@compute @workgroup_size(1)
+fn main() {
+    buf[0] = 1.0;
+    raw[0] = 2u;
+    later[0] = 3u;
+}
+`)
+	want := map[string]struct {
+		override string
+		length   uint32
+	}{
+		"buf":   {"", 8},
+		"raw":   {"N", 4},
+		"later": {"M", 1},
+	}
+	for _, gv := range module.GlobalVariables {
+		arr, ok := module.Types[gv.Type].Inner.(ir.ArrayType)
+		if !ok || arr.Size.Override == nil || arr.Size.Constant == nil {
+			t.Errorf("%s: type %+v, want an override-sized array", gv.Name, module.Types[gv.Type].Inner)
+			continue
+		}
+		w := want[gv.Name]
+		if name := module.Overrides[*arr.Size.Override].Name; name != w.override || *arr.Size.Constant != w.length {
+			t.Errorf("%s: sized by override %q with default length %d, want %q and %d",
+				gv.Name, name, *arr.Size.Constant, w.override, w.length)
+		}
+	}
+}
+
+func TestConstExprSwitchSelectors(t *testing.T) {
+	module := mustCompile(t, `
+const ONE = 1;
+fn f(x: u32) {
+    const TWO = 2u;
+    switch x {
+        case 0: {}
+        case ONE + 1, TWO + 1u: {}
+        default: {}
+    }
+}`)
+	var sw *ir.StmtSwitch
+	for _, st := range module.Functions[0].Body {
+		if s, ok := st.Kind.(ir.StmtSwitch); ok {
+			sw = &s
+		}
+	}
+	if sw == nil {
+		t.Fatal("switch statement not found")
+	}
+	want := []ir.SwitchValue{ir.SwitchValueU32(0), ir.SwitchValueU32(2), ir.SwitchValueU32(3), ir.SwitchValueDefault{}}
+	if len(sw.Cases) != len(want) {
+		t.Fatalf("got %d cases, want %d", len(sw.Cases), len(want))
+	}
+	for i, c := range sw.Cases {
+		if c.Value != want[i] {
+			t.Errorf("case %d = %v, want %v", i, c.Value, want[i])
+		}
+	}
+}
+
+func TestConstExprSwitchSelectorExpressions(t *testing.T) {
+	tests := []struct {
+		name     string
+		selector string
+		cases    string
+		want     []ir.SwitchValue
+	}{
+		{"arithmetic", "x", "case 1 + 2: {}", []ir.SwitchValue{ir.SwitchValueI32(3)}},
+		{"zero value", "x", "case i32(): {}", []ir.SwitchValue{ir.SwitchValueI32(0)}},
+		{"swizzle", "x", "case vec4(4).x: {}", []ir.SwitchValue{ir.SwitchValueI32(4)}},
+		{"index", "x", "case vec2<i32>(8, 9)[1]: {}", []ir.SwitchValue{ir.SwitchValueI32(9)}},
+		{"conversion", "x", "case i32(12u), -(13): {}", []ir.SwitchValue{ir.SwitchValueI32(12), ir.SwitchValueI32(-13)}},
+		{"builtins", "x", "case max(10, 11), min(3, 2), abs(-20): {}",
+			[]ir.SwitchValue{ir.SwitchValueI32(11), ir.SwitchValueI32(2), ir.SwitchValueI32(20)}},
+		{"clamp and select", "x", "case clamp(100, 0, 50), select(1, 2, 3 < 2): {}",
+			[]ir.SwitchValue{ir.SwitchValueI32(50), ir.SwitchValueI32(1)}},
+		{"vector builtins", "x", "case abs(vec2(-4, 9)).x + max(vec2(-4, 9), vec2(0)).y: {}",
+			[]ir.SwitchValue{ir.SwitchValueI32(13)}},
+		{"converted to u32", "u32(x)", "case max(1u, 5), 2 * 3: {}", []ir.SwitchValue{ir.SwitchValueU32(5), ir.SwitchValueU32(6)}},
+		{"abstract selector", "4", "case 1u << 2u: {}", []ir.SwitchValue{ir.SwitchValueU32(4)}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			module := mustCompile(t, "fn f(x: i32) { switch "+tt.selector+" { "+tt.cases+" default: {} } }")
+			var sw *ir.StmtSwitch
+			for _, st := range module.Functions[0].Body {
+				if s, ok := st.Kind.(ir.StmtSwitch); ok {
+					sw = &s
+				}
+			}
+			if sw == nil {
+				t.Fatal("switch statement not found")
+			}
+			var got []ir.SwitchValue
+			for _, c := range sw.Cases {
+				if _, ok := c.Value.(ir.SwitchValueDefault); !ok {
+					got = append(got, c.Value)
+				}
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("case values = %v, want %v", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Errorf("case %d = %#v, want %#v", i, got[i], tt.want[i])
+				}
+			}
+		})
+	}
+}
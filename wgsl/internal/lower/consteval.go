@@ -0,0 +1,1281 @@
+package lower
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/gogpu/naga/ir"
+	"github.com/gogpu/naga/wgsl/internal/parser"
+)
+
+// constValue is a module-scope constant expression evaluated at compile
+// time. It is either a scalar (value) or a composite: vector components,
+// matrix columns, array elements, or struct members.
+//
+// Concrete numbers are 32-bit. Unsuffixed literals (and values computed
+// only from them) are abstract: they are held at 64 bits, an int64 or a
+// float64, take on the scalar type of the other operand, and become
+// i32/f32 when materialized.
+type constValue struct {
+	value    ir.ScalarValue
+	abstract bool
+
+	// inner is the composite type (VectorType, MatrixType, ArrayType, or
+	// StructType); nil for scalars.
+	inner      ir.TypeInner
+	components []constValue
+
+	// structType is the type handle of a struct value, which unlike the
+	// other composites cannot be re-registered from inner alone.
+	structType ir.TypeHandle
+}
+
+func (v constValue) isScalar() bool { return v.inner == nil }
+
+// leaf returns the first scalar of v, which carries v's scalar kind.
+func (v constValue) leaf() constValue {
+	for !v.isScalar() {
+		if len(v.components) == 0 {
+			return constValue{}
+		}
+		v = v.components[0]
+	}
+	return v
+}
+
+// intValue returns the value of an integer or bool scalar.
+func (v constValue) intValue() int64 {
+	switch v.value.Kind {
+	case ir.ScalarSint:
+		return int64(int32(v.value.Bits))
+	case ir.ScalarUint:
+		return int64(uint32(v.value.Bits))
+	case ir.ScalarBool:
+		return int64(v.value.Bits & 1)
+	default:
+		return int64(v.value.Bits)
+	}
+}
+
+// floatValue returns the value of a float scalar.
+func (v constValue) floatValue() float64 {
+	if v.value.Kind == ir.ScalarAbstractFloat {
+		return math.Float64frombits(v.value.Bits)
+	}
+	return float64(math.Float32frombits(uint32(v.value.Bits)))
+}
+
+// concrete returns the 32-bit value of a scalar, concretizing an abstract
+// one to i32/f32.
+func (v constValue) concrete() (ir.ScalarValue, error) {
+	return convertConstScalar(v.value, v.scalarType().Kind)
+}
+
+// constOverflowError reports a const-expression whose value does not fit
+// its type, or that shifts by at least its bit width. Unlike the evaluator's other errors, which only mean it does
+// not handle an expression, it is an error in the shader.
+type constOverflowError struct{ msg string }
+
+func (e *constOverflowError) Error() string { return e.msg }
+
+func overflowf(format string, args ...any) error {
+	return &constOverflowError{msg: fmt.Sprintf(format, args...)}
+}
+
+// scalarType returns the concrete scalar type of a scalar value.
+func (v constValue) scalarType() ir.ScalarType {
+	switch v.value.Kind {
+	case ir.ScalarBool:
+		return ir.ScalarType{Kind: ir.ScalarBool, Width: 1}
+	case ir.ScalarAbstractInt:
+		return ir.ScalarType{Kind: ir.ScalarSint, Width: 4}
+	case ir.ScalarAbstractFloat:
+		return ir.ScalarType{Kind: ir.ScalarFloat, Width: 4}
+	default:
+		return ir.ScalarType{Kind: v.value.Kind, Width: 4}
+	}
+}
+
+// lowerEvaluatedConstant lowers a module constant whose initializer the
+// specialized lowering paths reject, by evaluating it with evalConstValue:
+// arithmetic on composite constants (v * 2.0), swizzles and member access
+// (v.xy), indexing (arr[1]), and negation of composites. Abstract scalar
+// results stay abstract constants; everything else becomes a concrete
+// constant whose init is a tree of Literal/Compose global expressions.
+func (l *Lowerer) lowerEvaluatedConstant(c *parser.ConstDecl) error {
+	v, err := l.evalConstValue(c.Init)
+	if err != nil {
+		return fmt.Errorf("module constant '%s': %w", c.Name, err)
+	}
+
+	if c.Type != nil {
+		typeHandle, err := l.resolveType(c.Type)
+		if err != nil {
+			return fmt.Errorf("constant %s: %w", c.Name, err)
+		}
+		if v, err = l.convertConstValue(v, l.module.Types[typeHandle].Inner, false); err != nil {
+			return fmt.Errorf("module constant '%s': %w", c.Name, err)
+		}
+	} else if v.isScalar() && v.abstract {
+		// Abstract constants are kept as sign-extended 64-bit integers and
+		// f32 values.
+		sv := ir.ScalarValue{Bits: v.value.Bits, Kind: ir.ScalarSint}
+		if v.value.Kind == ir.ScalarAbstractFloat {
+			if sv, err = v.concrete(); err != nil {
+				return fmt.Errorf("module constant '%s': %w", c.Name, err)
+			}
+		}
+		l.abstractConstants[c.Name] = &abstractConstInfo{scalarValue: &sv}
+		return nil
+	}
+
+	init, typeHandle, err := l.emitConstValue(v)
+	if err != nil {
+		return fmt.Errorf("module constant '%s': %w", c.Name, err)
+	}
+	constant := ir.Constant{Name: c.Name, Type: typeHandle, Init: init}
+	if v.isScalar() {
+		if constant.Value, err = v.concrete(); err != nil {
+			return fmt.Errorf("module constant '%s': %w", c.Name, err)
+		}
+	}
+	handle := ir.ConstantHandle(len(l.module.Constants))
+	l.module.Constants = append(l.module.Constants, constant)
+	l.moduleConstants[c.Name] = handle
+	l.markConstInlineInit(handle)
+	return nil
+}
+
+// evalConstValue evaluates a module-scope constant expression.
+func (l *Lowerer) evalConstValue(expr parser.Expr) (constValue, error) {
+	switch e := expr.(type) {
+	case *parser.Literal:
+		return l.evalConstLiteral(e)
+	case *parser.Ident:
+		if l.isLocalName(e.Name) {
+			// Function-scope constants are left to the integer evaluator.
+			return constValue{}, fmt.Errorf("'%s' is not a module-scope constant", e.Name)
+		}
+		if h, ok := l.moduleConstants[e.Name]; ok {
+			return l.constValueOfConstant(h)
+		}
+		if info, ok := l.abstractConstants[e.Name]; ok {
+			if info.scalarValue != nil {
+				sv := *info.scalarValue
+				switch sv.Kind {
+				case ir.ScalarSint:
+					sv.Kind = ir.ScalarAbstractInt
+				case ir.ScalarFloat:
+					sv = ir.ScalarValue{Bits: math.Float64bits(float64(math.Float32frombits(uint32(sv.Bits)))), Kind: ir.ScalarAbstractFloat}
+				}
+				return constValue{value: sv, abstract: sv.Kind != ir.ScalarBool}, nil
+			}
+			if info.compositeAST != nil {
+				return l.evalConstValue(info.compositeAST)
+			}
+		}
+		return constValue{}, fmt.Errorf("'%s' is not a constant", e.Name)
+	case *parser.ConstructExpr:
+		return l.evalConstConstruct(e.Type, e.Args)
+	case *parser.CallExpr:
+		if v, ok, err := l.evalConstBuiltin(e.Func.Name, e.Args); ok {
+			return v, err
+		}
+		return l.evalConstConstruct(&parser.NamedType{Name: e.Func.Name}, e.Args)
+	case *parser.UnaryExpr:
+		operand, err := l.evalConstValue(e.Operand)
+		if err != nil {
+			return constValue{}, err
+		}
+		return mapConstValue(operand, func(s constValue) (constValue, error) {
+			return evalConstUnary(e.Op, s)
+		})
+	case *parser.BinaryExpr:
+		left, err := l.evalConstValue(e.Left)
+		if err != nil {
+			return constValue{}, err
+		}
+		right, err := l.evalConstValue(e.Right)
+		if err != nil {
+			return constValue{}, err
+		}
+		return evalConstBinary(e.Op, left, right)
+	case *parser.MemberExpr:
+		base, err := l.evalConstValue(e.Expr)
+		if err != nil {
+			return constValue{}, err
+		}
+		if st, ok := base.inner.(ir.StructType); ok {
+			for i, m := range st.Members {
+				if m.Name == e.Member {
+					return base.components[i], nil
+				}
+			}
+			return constValue{}, fmt.Errorf("struct has no member '%s'", e.Member)
+		}
+		return evalConstSwizzle(base, e.Member)
+	case *parser.IndexExpr:
+		base, err := l.evalConstValue(e.Expr)
+		if err != nil {
+			return constValue{}, err
+		}
+		index, err := l.evalConstValue(e.Index)
+		if err != nil {
+			return constValue{}, err
+		}
+		if !index.isScalar() || index.value.Kind == ir.ScalarBool || index.value.Kind == ir.ScalarFloat || index.value.Kind == ir.ScalarAbstractFloat {
+			return constValue{}, fmt.Errorf("index must be an integer")
+		}
+		i := index.intValue()
+		if base.isScalar() || i < 0 || i >= int64(len(base.components)) {
+			return constValue{}, fmt.Errorf("index %d out of bounds", i)
+		}
+		return base.components[i], nil
+	default:
+		return constValue{}, fmt.Errorf("unsupported constant expression %T", expr)
+	}
+}
+
+// evalConstLiteral evaluates a bool, i32, u32, f32 or abstract literal.
+// A literal out of the range of its type is an error.
+func (l *Lowerer) evalConstLiteral(lit *parser.Literal) (constValue, error) {
+	text := lit.Value
+	switch lit.Kind {
+	case parser.TokenIntLiteral:
+		digits, suffix := text, ""
+		if strings.HasSuffix(text, "li") || strings.HasSuffix(text, "lu") {
+			return constValue{}, fmt.Errorf("only 32-bit constants can be evaluated, got %s", text)
+		}
+		if strings.HasSuffix(text, "i") || strings.HasSuffix(text, "u") {
+			digits, suffix = text[:len(text)-1], text[len(text)-1:]
+		}
+		n, err := strconv.ParseUint(digits, 0, 64)
+		if err != nil && !errors.Is(err, strconv.ErrRange) {
+			return constValue{}, fmt.Errorf("invalid integer literal %s", text)
+		}
+		switch {
+		case suffix == "i" && (err != nil || n > math.MaxInt32):
+			return constValue{}, overflowf("literal %s does not fit in i32", text)
+		case suffix == "u" && (err != nil || n > math.MaxUint32):
+			return constValue{}, overflowf("literal %s does not fit in u32", text)
+		case suffix == "" && (err != nil || n > math.MaxInt64):
+			return constValue{}, overflowf("literal %s does not fit in an abstract integer", text)
+		case suffix == "i":
+			return constValue{value: ir.ScalarValue{Bits: n, Kind: ir.ScalarSint}}, nil
+		case suffix == "u":
+			return constValue{value: ir.ScalarValue{Bits: n, Kind: ir.ScalarUint}}, nil
+		}
+		return constValue{value: ir.ScalarValue{Bits: n, Kind: ir.ScalarAbstractInt}, abstract: true}, nil
+	case parser.TokenFloatLiteral:
+		digits, suffix := splitFloatSuffix(text)
+		if suffix == "h" || strings.HasSuffix(text, "lf") {
+			return constValue{}, fmt.Errorf("only 32-bit constants can be evaluated, got %s", text)
+		}
+		if (strings.HasPrefix(digits, "0x") || strings.HasPrefix(digits, "0X")) && !strings.ContainsAny(digits, "pP") {
+			digits += "p0" // strconv requires the exponent of a hex float
+		}
+		bitSize, name := 64, "an abstract float"
+		if suffix == "f" {
+			bitSize, name = 32, "f32"
+		}
+		f, err := strconv.ParseFloat(digits, bitSize)
+		if errors.Is(err, strconv.ErrRange) {
+			return constValue{}, overflowf("literal %s does not fit in %s", text, name)
+		} else if err != nil {
+			return constValue{}, fmt.Errorf("invalid float literal %s", text)
+		}
+		if suffix == "f" {
+			return constValue{value: ir.ScalarValue{Bits: uint64(math.Float32bits(float32(f))), Kind: ir.ScalarFloat}}, nil
+		}
+		return constValue{value: ir.ScalarValue{Bits: math.Float64bits(f), Kind: ir.ScalarAbstractFloat}, abstract: true}, nil
+	}
+	kind, bits, err := l.evalLiteral(lit)
+	if err != nil {
+		return constValue{}, err
+	}
+	return constValue{value: ir.ScalarValue{Bits: bits, Kind: kind}}, nil
+}
+
+// evalConstConstruct evaluates a type constructor: scalar conversion,
+// vector, matrix, array, or struct construction, with the type taken from typ or
+// inferred from the arguments for bare vecN/matCxR/array constructors.
+func (l *Lowerer) evalConstConstruct(typ parser.Type, args []parser.Expr) (constValue, error) {
+	values := make([]constValue, len(args))
+	for i, arg := range args {
+		v, err := l.evalConstValue(arg)
+		if err != nil {
+			return constValue{}, err
+		}
+		values[i] = v
+	}
+
+	if typeHandle, err := l.resolveType(typ); err == nil {
+		inner := l.module.Types[typeHandle].Inner
+		if _, ok := inner.(ir.StructType); ok {
+			if len(values) == 0 {
+				return l.zeroStructConstValue(typeHandle)
+			}
+			v, err := l.buildConstValue(typeHandle, values)
+			if err != nil {
+				return constValue{}, err
+			}
+			return l.convertConstValue(v, inner, false)
+		}
+		if len(values) == 0 {
+			return l.zeroConstValue(inner)
+		}
+		if _, ok := inner.(ir.ScalarType); ok {
+			if len(values) != 1 || !values[0].isScalar() {
+				return constValue{}, fmt.Errorf("scalar constructor takes one scalar argument")
+			}
+			return l.convertConstValue(values[0], inner, true)
+		}
+		v, err := buildConstComposite(inner, values)
+		if err != nil {
+			return constValue{}, err
+		}
+		return l.convertConstValue(v, inner, true)
+	}
+
+	// Bare constructor: infer the shape from the name and the scalar type
+	// from the arguments.
+	named, ok := typ.(*parser.NamedType)
+	if !ok || len(values) == 0 {
+		return constValue{}, fmt.Errorf("cannot infer constructor type")
+	}
+	leaf := values[0].leaf()
+	for _, v := range values[1:] {
+		if s := v.leaf(); leaf.abstract && (!s.abstract || s.value.Kind == ir.ScalarAbstractFloat) {
+			leaf = s
+		}
+	}
+	scalar := leaf.scalarType()
+	var shape ir.TypeInner
+	switch name := named.Name; {
+	case len(name) == 4 && strings.HasPrefix(name, "vec") && name[3] >= '2' && name[3] <= '4':
+		shape = ir.VectorType{Size: ir.VectorSize(name[3] - '0'), Scalar: scalar}
+	case len(name) == 6 && strings.HasPrefix(name, "mat") && name[4] == 'x':
+		shape = ir.MatrixType{Columns: ir.VectorSize(name[3] - '0'), Rows: ir.VectorSize(name[5] - '0'), Scalar: scalar}
+	case name == "array":
+		n := uint32(len(values))
+		base := values[0]
+		baseInner := base.inner
+		if base.isScalar() {
+			baseInner = scalar
+		}
+		var baseHandle ir.TypeHandle
+		if _, ok := baseInner.(ir.StructType); ok {
+			baseHandle = base.structType
+		} else {
+			baseHandle = l.registerType("", baseInner)
+		}
+		shape = ir.ArrayType{Base: baseHandle, Size: ir.ArraySize{Constant: &n}, Stride: l.typeStride(baseHandle)}
+	default:
+		return constValue{}, fmt.Errorf("cannot infer type of %s constructor", named.Name)
+	}
+	v, err := buildConstComposite(shape, values)
+	if err != nil {
+		return constValue{}, err
+	}
+	if leaf.abstract {
+		// All arguments abstract: the result stays abstract.
+		return v, nil
+	}
+	return l.convertConstValue(v, shape, false)
+}
+
+// buildConstComposite arranges constructor arguments into a composite of
+// type inner: vectors take scalars and vectors (or splat one scalar),
+// matrices take columns or all scalars in column-major order, and arrays
+// take one argument per element.
+func buildConstComposite(inner ir.TypeInner, args []constValue) (constValue, error) {
+	switch t := inner.(type) {
+	case ir.VectorType:
+		var comps []constValue
+		for _, a := range args {
+			if a.isScalar() {
+				comps = append(comps, a)
+			} else {
+				comps = append(comps, a.components...)
+			}
+		}
+		if len(comps) == 1 {
+			for len(comps) < int(t.Size) {
+				comps = append(comps, comps[0])
+			}
+		}
+		if len(comps) != int(t.Size) {
+			return constValue{}, fmt.Errorf("vector of %d components built from %d values", t.Size, len(comps))
+		}
+		return constValue{inner: t, components: comps}, nil
+	case ir.MatrixType:
+		column := ir.VectorType{Size: t.Rows, Scalar: t.Scalar}
+		if len(args) == int(t.Columns) {
+			cols := make([]constValue, len(args))
+			for i, a := range args {
+				if _, ok := a.inner.(ir.VectorType); !ok || len(a.components) != int(t.Rows) {
+					return constValue{}, fmt.Errorf("matrix column %d is not a %d-component vector", i, t.Rows)
+				}
+				cols[i] = constValue{inner: column, components: a.components}
+			}
+			return constValue{inner: t, components: cols}, nil
+		}
+		if len(args) != int(t.Columns)*int(t.Rows) {
+			return constValue{}, fmt.Errorf("matrix constructor takes %d columns or %d scalars, got %d arguments", t.Columns, int(t.Columns)*int(t.Rows), len(args))
+		}
+		cols := make([]constValue, t.Columns)
+		for c := range cols {
+			cols[c] = constValue{inner: column, components: args[c*int(t.Rows) : (c+1)*int(t.Rows)]}
+		}
+		return constValue{inner: t, components: cols}, nil
+	case ir.ArrayType:
+		if t.Size.Constant == nil || int(*t.Size.Constant) != len(args) {
+			return constValue{}, fmt.Errorf("array constructor element count mismatch")
+		}
+		return constValue{inner: t, components: args}, nil
+	default:
+		return constValue{}, fmt.Errorf("unsupported constant type %T", inner)
+	}
+}
+
+// buildConstValue arranges constructor arguments into a composite of the
+// type at handle, taking one argument per member for structs.
+func (l *Lowerer) buildConstValue(handle ir.TypeHandle, args []constValue) (constValue, error) {
+	st, ok := l.module.Types[handle].Inner.(ir.StructType)
+	if !ok {
+		return buildConstComposite(l.module.Types[handle].Inner, args)
+	}
+	if len(args) != len(st.Members) {
+		return constValue{}, fmt.Errorf("struct constructor takes %d members, got %d arguments", len(st.Members), len(args))
+	}
+	return constValue{inner: st, components: args, structType: handle}, nil
+}
+
+// convertConstValue converts every scalar of v to the scalar type of
+// target, checking that v has target's shape. Unless explicit (a
+// conversion constructor), only abstract values convert, and abstract
+// floats never convert to integers.
+func (l *Lowerer) convertConstValue(v constValue, target ir.TypeInner, explicit bool) (constValue, error) {
+	switch t := target.(type) {
+	case ir.ScalarType:
+		if !v.isScalar() {
+			return constValue{}, fmt.Errorf("cannot convert composite to scalar")
+		}
+		if t.Width != 4 && t.Kind != ir.ScalarBool {
+			return constValue{}, fmt.Errorf("only 32-bit constants can be evaluated")
+		}
+		if !explicit && v.scalarType().Kind != t.Kind &&
+			(!v.abstract || v.value.Kind == ir.ScalarAbstractFloat) {
+			return constValue{}, fmt.Errorf("cannot convert %s constant to %s", scalarKindName(v.scalarType().Kind), scalarKindName(t.Kind))
+		}
+		sv, err := convertConstScalar(v.value, t.Kind)
+		if err != nil {
+			return constValue{}, err
+		}
+		return constValue{value: sv}, nil
+	case ir.VectorType, ir.MatrixType, ir.ArrayType:
+		var element ir.TypeInner
+		var n int
+		switch t := t.(type) {
+		case ir.VectorType:
+			element, n = t.Scalar, int(t.Size)
+		case ir.MatrixType:
+			element, n = ir.VectorType{Size: t.Rows, Scalar: t.Scalar}, int(t.Columns)
+		case ir.ArrayType:
+			if t.Size.Constant == nil {
+				return constValue{}, fmt.Errorf("runtime-sized array constant")
+			}
+			element, n = l.module.Types[t.Base].Inner, int(*t.Size.Constant)
+		}
+		if v.isScalar() || len(v.components) != n {
+			return constValue{}, fmt.Errorf("constant value does not match declared type")
+		}
+		comps := make([]constValue, n)
+		for i, c := range v.components {
+			conv, err := l.convertConstValue(c, element, explicit)
+			if err != nil {
+				return constValue{}, err
+			}
+			comps[i] = conv
+		}
+		return constValue{inner: target, components: comps}, nil
+	case ir.StructType:
+		if _, ok := v.inner.(ir.StructType); !ok || len(v.components) != len(t.Members) {
+			return constValue{}, fmt.Errorf("constant value does not match declared type")
+		}
+		comps := make([]constValue, len(t.Members))
+		for i, m := range t.Members {
+			conv, err := l.convertConstValue(v.components[i], l.module.Types[m.Type].Inner, explicit)
+			if err != nil {
+				return constValue{}, err
+			}
+			comps[i] = conv
+		}
+		return constValue{inner: t, components: comps, structType: v.structType}, nil
+	default:
+		return constValue{}, fmt.Errorf("unsupported constant type %T", target)
+	}
+}
+
+// convertConstScalar converts a scalar value to kind. An abstract or
+// float value out of the range of an integer kind is an error; a
+// conversion between i32 and u32 keeps the bits.
+func convertConstScalar(sv ir.ScalarValue, kind ir.ScalarKind) (ir.ScalarValue, error) {
+	if sv.Kind == kind {
+		return sv, nil
+	}
+	v := constValue{value: sv}
+	isFloat := sv.Kind == ir.ScalarFloat || sv.Kind == ir.ScalarAbstractFloat
+	switch kind {
+	case ir.ScalarFloat, ir.ScalarAbstractFloat:
+		f := float64(v.intValue())
+		if isFloat {
+			f = v.floatValue()
+		}
+		return floatConstScalar(kind, f, "conversion")
+	case ir.ScalarSint, ir.ScalarUint:
+		if sv.Kind == ir.ScalarSint || sv.Kind == ir.ScalarUint {
+			return ir.ScalarValue{Bits: uint64(uint32(sv.Bits)), Kind: kind}, nil
+		}
+		if isFloat {
+			// Float to integer conversion rounds toward zero and saturates.
+			lo, hi := float64(math.MinInt32), float64(math.MaxInt32)
+			if kind == ir.ScalarUint {
+				lo, hi = 0, math.MaxUint32
+			}
+			f := math.Trunc(math.Max(lo, math.Min(hi, v.floatValue())))
+			return intConstScalar(kind, int64(f), "conversion")
+		}
+		return intConstScalar(kind, v.intValue(), "conversion")
+	case ir.ScalarBool:
+		nonzero := v.intValue() != 0
+		if isFloat {
+			nonzero = v.floatValue() != 0
+		}
+		if nonzero {
+			return ir.ScalarValue{Bits: 1, Kind: kind}, nil
+		}
+		return ir.ScalarValue{Kind: kind}, nil
+	default:
+		return sv, nil
+	}
+}
+
+// intConstScalar returns i as a scalar of the integer kind, or an error
+// naming what computed it when i is out of range.
+func intConstScalar(kind ir.ScalarKind, i int64, what string) (ir.ScalarValue, error) {
+	switch {
+	case kind == ir.ScalarSint && (i < math.MinInt32 || i > math.MaxInt32),
+		kind == ir.ScalarUint && (i < 0 || i > math.MaxUint32):
+		return ir.ScalarValue{}, overflowf("constant %s overflows %s: %d", what, scalarKindName(kind), i)
+	case kind == ir.ScalarAbstractInt:
+		return ir.ScalarValue{Bits: uint64(i), Kind: kind}, nil
+	}
+	return ir.ScalarValue{Bits: uint64(uint32(i)), Kind: kind}, nil
+}
+
+// floatConstScalar returns f as a scalar of the float kind, or an error
+// naming what computed it when f is not finite in that kind.
+func floatConstScalar(kind ir.ScalarKind, f float64, what string) (ir.ScalarValue, error) {
+	if kind == ir.ScalarAbstractFloat {
+		if math.IsInf(f, 0) || math.IsNaN(f) {
+			return ir.ScalarValue{}, overflowf("constant %s overflows %s", what, scalarKindName(kind))
+		}
+		return ir.ScalarValue{Bits: math.Float64bits(f), Kind: kind}, nil
+	}
+	f32 := float32(f)
+	if math.IsInf(float64(f32), 0) || math.IsNaN(float64(f32)) {
+		return ir.ScalarValue{}, overflowf("constant %s overflows %s", what, scalarKindName(kind))
+	}
+	return ir.ScalarValue{Bits: uint64(math.Float32bits(f32)), Kind: kind}, nil
+}
+
+// mapConstValue applies fn to every scalar of v.
+func mapConstValue(v constValue, fn func(constValue) (constValue, error)) (constValue, error) {
+	if v.isScalar() {
+		return fn(v)
+	}
+	comps := make([]constValue, len(v.components))
+	for i, c := range v.components {
+		r, err := mapConstValue(c, fn)
+		if err != nil {
+			return constValue{}, err
+		}
+		comps[i] = r
+	}
+	return constValue{inner: v.inner, components: comps}, nil
+}
+
+// evalConstUnary applies a unary operator to a scalar.
+func evalConstUnary(op parser.TokenKind, s constValue) (constValue, error) {
+	var err error
+	switch kind := s.value.Kind; {
+	case op == parser.TokenMinus && kind == ir.ScalarFloat:
+		s.value.Bits = uint64(math.Float32bits(-math.Float32frombits(uint32(s.value.Bits))))
+	case op == parser.TokenMinus && kind == ir.ScalarAbstractFloat:
+		s.value.Bits = math.Float64bits(-s.floatValue())
+	case op == parser.TokenMinus && kind == ir.ScalarAbstractInt:
+		if s.intValue() == math.MinInt64 {
+			return constValue{}, overflowf("constant negation overflows %s", scalarKindName(kind))
+		}
+		s.value.Bits = uint64(-s.intValue())
+	case op == parser.TokenMinus && kind == ir.ScalarSint:
+		s.value, err = intConstScalar(kind, -s.intValue(), "negation")
+	case op == parser.TokenBang && kind == ir.ScalarBool:
+		s.value.Bits ^= 1
+	case op == parser.TokenTilde && kind == ir.ScalarAbstractInt:
+		s.value.Bits = ^s.value.Bits
+	case op == parser.TokenTilde && (kind == ir.ScalarSint || kind == ir.ScalarUint):
+		s.value.Bits = uint64(^uint32(s.value.Bits))
+	default:
+		return constValue{}, fmt.Errorf("invalid operand for unary %s", op)
+	}
+	return s, err
+}
+
+// evalConstBinary applies a binary operator componentwise, broadcasting
+// a scalar operand over a composite one.
+func evalConstBinary(op parser.TokenKind, left, right constValue) (constValue, error) {
+	switch {
+	case left.isScalar() && right.isScalar():
+		return evalConstScalarBinary(op, left, right)
+	case left.isScalar():
+		return mapConstValue(right, func(r constValue) (constValue, error) { return evalConstScalarBinary(op, left, r) })
+	case right.isScalar():
+		return mapConstValue(left, func(l constValue) (constValue, error) { return evalConstScalarBinary(op, l, right) })
+	}
+	if _, ok := left.inner.(ir.MatrixType); ok && op != parser.TokenPlus && op != parser.TokenMinus {
+		return constValue{}, fmt.Errorf("matrix %s is not supported in constant expressions", op)
+	}
+	if _, ok := left.inner.(ir.ArrayType); ok {
+		return constValue{}, fmt.Errorf("arrays do not support %s", op)
+	}
+	if len(left.components) != len(right.components) {
+		return constValue{}, fmt.Errorf("operand sizes differ: %d and %d", len(left.components), len(right.components))
+	}
+	comps := make([]constValue, len(left.components))
+	for i := range comps {
+		c, err := evalConstBinary(op, left.components[i], right.components[i])
+		if err != nil {
+			return constValue{}, err
+		}
+		comps[i] = c
+	}
+	inner := left.inner
+	if vec, ok := inner.(ir.VectorType); ok && len(comps) > 0 {
+		vec.Scalar = comps[0].scalarType()
+		inner = vec
+	}
+	return constValue{inner: inner, components: comps}, nil
+}
+
+// unifyConstScalars converts an abstract operand to the other operand's
+// type, or an abstract int to abstract float when paired with one.
+func unifyConstScalars(left, right constValue) (constValue, constValue, error) {
+	var err error
+	switch {
+	case left.abstract && !right.abstract:
+		left.value, err = convertConstScalar(left.value, right.value.Kind)
+		left.abstract = false
+	case right.abstract && !left.abstract:
+		right.value, err = convertConstScalar(right.value, left.value.Kind)
+		right.abstract = false
+	case left.value.Kind == ir.ScalarAbstractInt && right.value.Kind == ir.ScalarAbstractFloat:
+		left.value, err = convertConstScalar(left.value, ir.ScalarAbstractFloat)
+	case right.value.Kind == ir.ScalarAbstractInt && left.value.Kind == ir.ScalarAbstractFloat:
+		right.value, err = convertConstScalar(right.value, ir.ScalarAbstractFloat)
+	}
+	return left, right, err
+}
+
+// evalConstScalarBinary applies a binary operator to two scalars. An
+// abstract operand takes on the other operand's type, except that a shift
+// has the type of its left operand. Concrete arithmetic is exact: a result
+// out of the range of its type is an error rather than wrapping.
+func evalConstScalarBinary(op parser.TokenKind, left, right constValue) (constValue, error) {
+	shift := op == parser.TokenLessLess || op == parser.TokenGreaterGreater
+	if !shift {
+		var err error
+		if left, right, err = unifyConstScalars(left, right); err != nil {
+			return constValue{}, err
+		}
+	}
+	kind := left.value.Kind
+	if kind != right.value.Kind && !shift {
+		return constValue{}, fmt.Errorf("mismatched operand types for %s", op)
+	}
+	result := constValue{value: ir.ScalarValue{Kind: kind}, abstract: left.abstract && (right.abstract || shift)}
+	boolResult := func(b bool) (constValue, error) {
+		r := constValue{value: ir.ScalarValue{Kind: ir.ScalarBool}}
+		if b {
+			r.value.Bits = 1
+		}
+		return r, nil
+	}
+
+	var err error
+	switch kind {
+	case ir.ScalarBool:
+		a, b := left.value.Bits, right.value.Bits
+		switch op {
+		case parser.TokenEqualEqual:
+			return boolResult(a == b)
+		case parser.TokenBangEqual:
+			return boolResult(a != b)
+		case parser.TokenAmpAmp, parser.TokenAmpersand:
+			return boolResult(a&b != 0)
+		case parser.TokenPipePipe, parser.TokenPipe:
+			return boolResult(a|b != 0)
+		}
+	case ir.ScalarFloat, ir.ScalarAbstractFloat:
+		x, y := left.floatValue(), right.floatValue()
+		var r float64
+		switch op {
+		case parser.TokenPlus:
+			r = x + y
+		case parser.TokenMinus:
+			r = x - y
+		case parser.TokenStar:
+			r = x * y
+		case parser.TokenSlash:
+			r = x / y
+		case parser.TokenPercent:
+			r = math.Mod(x, y)
+		case parser.TokenEqualEqual:
+			return boolResult(x == y)
+		case parser.TokenBangEqual:
+			return boolResult(x != y)
+		case parser.TokenLess:
+			return boolResult(x < y)
+		case parser.TokenLessEqual:
+			return boolResult(x <= y)
+		case parser.TokenGreater:
+			return boolResult(x > y)
+		case parser.TokenGreaterEqual:
+			return boolResult(x >= y)
+		default:
+			return constValue{}, fmt.Errorf("invalid float operator %s", op)
+		}
+		if kind == ir.ScalarFloat {
+			// f32 operations round their result to f32.
+			r = float64(float32(r))
+		}
+		result.value, err = floatConstScalar(kind, r, op.String())
+		return result, err
+	default:
+		x, y := left.intValue(), right.intValue()
+		var r int64
+		ok := true
+		switch op {
+		case parser.TokenPlus:
+			r = x + y
+			ok = (r > x) == (y > 0)
+		case parser.TokenMinus:
+			r = x - y
+			ok = (r < x) == (y > 0)
+		case parser.TokenStar:
+			r = x * y
+			ok = x == 0 || (r/x == y && !(x == -1 && y == math.MinInt64))
+		case parser.TokenSlash, parser.TokenPercent:
+			if y == 0 {
+				return constValue{}, fmt.Errorf("division by zero in constant expression")
+			}
+			if x == math.MinInt64 && y == -1 {
+				return constValue{}, overflowf("constant %s overflows %s", op, scalarKindName(kind))
+			}
+			if op == parser.TokenSlash {
+				r = x / y
+			} else {
+				r = x % y
+			}
+		case parser.TokenAmpersand:
+			r = x & y
+		case parser.TokenPipe:
+			r = x | y
+		case parser.TokenCaret:
+			r = x ^ y
+		case parser.TokenLessLess, parser.TokenGreaterGreater:
+			width := int64(32)
+			if kind == ir.ScalarAbstractInt {
+				width = 64
+			}
+			if y < 0 || y >= width {
+				return constValue{}, overflowf("constant shift by %d is out of range for %s", y, scalarKindName(kind))
+			}
+			if op == parser.TokenGreaterGreater {
+				r = x >> y
+				break
+			}
+			// Concrete values are 32-bit, so their shifted value fits in
+			// an int64 and is range checked below.
+			r = x << y
+			ok = r>>y == x
+		case parser.TokenEqualEqual:
+			return boolResult(x == y)
+		case parser.TokenBangEqual:
+			return boolResult(x != y)
+		case parser.TokenLess:
+			return boolResult(x < y)
+		case parser.TokenLessEqual:
+			return boolResult(x <= y)
+		case parser.TokenGreater:
+			return boolResult(x > y)
+		case parser.TokenGreaterEqual:
+			return boolResult(x >= y)
+		default:
+			return constValue{}, fmt.Errorf("invalid integer operator %s", op)
+		}
+		if !ok {
+			return constValue{}, overflowf("constant %s overflows %s", op, scalarKindName(kind))
+		}
+		result.value, err = intConstScalar(kind, r, op.String())
+		return result, err
+	}
+	return constValue{}, fmt.Errorf("invalid bool operator %s", op)
+}
+
+// evalConstBuiltin evaluates the built-in functions that commonly appear
+// in integer const-expressions such as switch case selectors and array
+// sizes: abs, min, max, clamp and select, componentwise on vectors. ok is
+// false when name is not one of them or is shadowed by a user function.
+func (l *Lowerer) evalConstBuiltin(name string, args []parser.Expr) (v constValue, ok bool, err error) {
+	arity := map[string]int{"abs": 1, "min": 2, "max": 2, "clamp": 3, "select": 3}[name]
+	if arity == 0 {
+		return constValue{}, false, nil
+	}
+	if _, shadowed := l.functions[name]; shadowed {
+		return constValue{}, false, nil
+	}
+	if len(args) != arity {
+		return constValue{}, true, fmt.Errorf("%s expects %d arguments, got %d", name, arity, len(args))
+	}
+	values := make([]constValue, len(args))
+	for i, arg := range args {
+		if values[i], err = l.evalConstValue(arg); err != nil {
+			return constValue{}, true, err
+		}
+	}
+
+	switch name {
+	case "abs":
+		v, err = mapConstValue(values[0], evalConstAbs)
+	case "min":
+		v, err = zipConstValues(values[0], values[1], func(a, b constValue) (constValue, error) {
+			return evalConstMinMax(a, b, false)
+		})
+	case "max":
+		v, err = zipConstValues(values[0], values[1], func(a, b constValue) (constValue, error) {
+			return evalConstMinMax(a, b, true)
+		})
+	case "clamp":
+		v, err = zipConstValues(values[0], values[1], func(a, b constValue) (constValue, error) {
+			return evalConstMinMax(a, b, true)
+		})
+		if err == nil {
+			v, err = zipConstValues(v, values[2], func(a, b constValue) (constValue, error) {
+				return evalConstMinMax(a, b, false)
+			})
+		}
+	case "select":
+		v, err = evalConstSelect(values[0], values[1], values[2])
+	}
+	return v, true, err
+}
+
+// zipConstValues applies fn to corresponding scalars of two values of the
+// same shape.
+func zipConstValues(a, b constValue, fn func(a, b constValue) (constValue, error)) (constValue, error) {
+	if a.isScalar() != b.isScalar() || len(a.components) != len(b.components) {
+		return constValue{}, fmt.Errorf("arguments have different shapes")
+	}
+	if a.isScalar() {
+		return fn(a, b)
+	}
+	comps := make([]constValue, len(a.components))
+	for i := range comps {
+		c, err := zipConstValues(a.components[i], b.components[i], fn)
+		if err != nil {
+			return constValue{}, err
+		}
+		comps[i] = c
+	}
+	inner := a.inner
+	if vec, ok := inner.(ir.VectorType); ok && len(comps) > 0 {
+		vec.Scalar = comps[0].scalarType()
+		inner = vec
+	}
+	return constValue{inner: inner, components: comps}, nil
+}
+
+// evalConstAbs evaluates abs on a numeric scalar.
+func evalConstAbs(s constValue) (constValue, error) {
+	switch s.value.Kind {
+	case ir.ScalarSint:
+		if int32(s.value.Bits) < 0 {
+			s.value.Bits = uint64(uint32(-int32(s.value.Bits)))
+		}
+	case ir.ScalarAbstractInt:
+		if int64(s.value.Bits) < 0 {
+			s.value.Bits = uint64(-int64(s.value.Bits))
+		}
+	case ir.ScalarFloat:
+		s.value.Bits &^= 1 << 31
+	case ir.ScalarAbstractFloat:
+		s.value.Bits &^= 1 << 63
+	case ir.ScalarUint:
+	default:
+		return constValue{}, fmt.Errorf("abs of %s", scalarKindName(s.value.Kind))
+	}
+	return s, nil
+}
+
+// evalConstMinMax returns the smaller (or, if larger, the larger) of two
+// numeric scalars, after converting abstract operands.
+func evalConstMinMax(a, b constValue, larger bool) (constValue, error) {
+	if a.value.Kind == ir.ScalarBool || b.value.Kind == ir.ScalarBool {
+		return constValue{}, fmt.Errorf("min/max of bool")
+	}
+	a, b, err := unifyConstScalars(a, b)
+	if err != nil {
+		return constValue{}, err
+	}
+	less, err := evalConstScalarBinary(parser.TokenLess, a, b)
+	if err != nil {
+		return constValue{}, err
+	}
+	if (less.value.Bits != 0) != larger {
+		return a, nil
+	}
+	return b, nil
+}
+
+// evalConstSelect evaluates select(f, t, cond) with a scalar or
+// componentwise vector condition.
+func evalConstSelect(f, t, cond constValue) (constValue, error) {
+	if cond.isScalar() {
+		if cond.value.Kind != ir.ScalarBool {
+			return constValue{}, fmt.Errorf("select condition must be bool")
+		}
+		if f.isScalar() && t.isScalar() {
+			var err error
+			if f, t, err = unifyConstScalars(f, t); err != nil {
+				return constValue{}, err
+			}
+		}
+		if cond.value.Bits != 0 {
+			return t, nil
+		}
+		return f, nil
+	}
+	if len(f.components) != len(cond.components) || len(t.components) != len(cond.components) {
+		return constValue{}, fmt.Errorf("select condition has %d components, values have %d", len(cond.components), len(f.components))
+	}
+	comps := make([]constValue, len(cond.components))
+	for i := range comps {
+		c, err := evalConstSelect(f.components[i], t.components[i], cond.components[i])
+		if err != nil {
+			return constValue{}, err
+		}
+		comps[i] = c
+	}
+	return constValue{inner: f.inner, components: comps}, nil
+}
+
+// evalConstSwizzle evaluates a vector swizzle or single-component access.
+func evalConstSwizzle(base constValue, member string) (constValue, error) {
+	vec, ok := base.inner.(ir.VectorType)
+	if !ok || len(member) == 0 || len(member) > 4 {
+		return constValue{}, fmt.Errorf("invalid member access .%s on constant", member)
+	}
+	comps := make([]constValue, len(member))
+	for i, ch := range member {
+		idx := strings.IndexRune("xyzw", ch)
+		if idx < 0 {
+			idx = strings.IndexRune("rgba", ch)
+		}
+		if idx < 0 || idx >= len(base.components) {
+			return constValue{}, fmt.Errorf("invalid swizzle component '%c'", ch)
+		}
+		comps[i] = base.components[idx]
+	}
+	if len(comps) == 1 {
+		return comps[0], nil
+	}
+	vec.Size = ir.VectorSize(len(comps))
+	return constValue{inner: vec, components: comps}, nil
+}
+
+// zeroConstValue returns the zero value of a 32-bit scalar, vector,
+// matrix, or fixed-size array type.
+func (l *Lowerer) zeroConstValue(inner ir.TypeInner) (constValue, error) {
+	switch t := inner.(type) {
+	case ir.ScalarType:
+		return constValue{value: ir.ScalarValue{Kind: t.Kind}}, nil
+	case ir.VectorType:
+		comps := make([]constValue, t.Size)
+		for i := range comps {
+			comps[i] = constValue{value: ir.ScalarValue{Kind: t.Scalar.Kind}}
+		}
+		return constValue{inner: t, components: comps}, nil
+	case ir.MatrixType:
+		col, _ := l.zeroConstValue(ir.VectorType{Size: t.Rows, Scalar: t.Scalar})
+		comps := make([]constValue, t.Columns)
+		for i := range comps {
+			comps[i] = col
+		}
+		return constValue{inner: t, components: comps}, nil
+	case ir.ArrayType:
+		if t.Size.Constant == nil {
+			return constValue{}, fmt.Errorf("runtime-sized array constant")
+		}
+		var elem constValue
+		var err error
+		if _, ok := l.module.Types[t.Base].Inner.(ir.StructType); ok {
+			elem, err = l.zeroStructConstValue(t.Base)
+		} else {
+			elem, err = l.zeroConstValue(l.module.Types[t.Base].Inner)
+		}
+		if err != nil {
+			return constValue{}, err
+		}
+		comps := make([]constValue, *t.Size.Constant)
+		for i := range comps {
+			comps[i] = elem
+		}
+		return constValue{inner: t, components: comps}, nil
+	default:
+		return constValue{}, fmt.Errorf("unsupported constant type %T", inner)
+	}
+}
+
+// zeroStructConstValue returns the zero value of the struct type at
+// handle, whose members may themselves be structs.
+func (l *Lowerer) zeroStructConstValue(handle ir.TypeHandle) (constValue, error) {
+	st := l.module.Types[handle].Inner.(ir.StructType)
+	comps := make([]constValue, len(st.Members))
+	for i, m := range st.Members {
+		var err error
+		if _, ok := l.module.Types[m.Type].Inner.(ir.StructType); ok {
+			comps[i], err = l.zeroStructConstValue(m.Type)
+		} else {
+			comps[i], err = l.zeroConstValue(l.module.Types[m.Type].Inner)
+		}
+		if err != nil {
+			return constValue{}, err
+		}
+	}
+	return constValue{inner: st, components: comps, structType: handle}, nil
+}
+
+// constValueOfConstant reads back a lowered module constant.
+func (l *Lowerer) constValueOfConstant(h ir.ConstantHandle) (constValue, error) {
+	c := &l.module.Constants[h]
+	inner := l.module.Types[c.Type].Inner
+	switch v := c.Value.(type) {
+	case ir.ScalarValue:
+		if st, ok := inner.(ir.ScalarType); ok && st.Width != 4 && st.Kind != ir.ScalarBool {
+			return constValue{}, fmt.Errorf("only 32-bit constants can be evaluated")
+		}
+		return constValue{value: v}, nil
+	case ir.CompositeValue:
+		comps := make([]constValue, len(v.Components))
+		for i, ch := range v.Components {
+			comp, err := l.constValueOfConstant(ch)
+			if err != nil {
+				return constValue{}, err
+			}
+			comps[i] = comp
+		}
+		return l.buildConstValue(c.Type, comps)
+	case ir.ZeroConstantValue:
+		if _, ok := inner.(ir.StructType); ok {
+			return l.zeroStructConstValue(c.Type)
+		}
+		return l.zeroConstValue(inner)
+	}
+	if !l.constsWithInlineInit[h] {
+		return constValue{}, fmt.Errorf("constant '%s' has no value", c.Name)
+	}
+	return l.constValueOfGlobalExpr(c.Init)
+}
+
+// constValueOfGlobalExpr reads back a constant init expression tree.
+func (l *Lowerer) constValueOfGlobalExpr(h ir.ExpressionHandle) (constValue, error) {
+	switch e := l.module.GlobalExpressions[h].Kind.(type) {
+	case ir.Literal:
+		switch v := e.Value.(type) {
+		case ir.LiteralF32:
+			return constValue{value: ir.ScalarValue{Bits: uint64(math.Float32bits(float32(v))), Kind: ir.ScalarFloat}}, nil
+		case ir.LiteralI32:
+			return constValue{value: ir.ScalarValue{Bits: uint64(uint32(v)), Kind: ir.ScalarSint}}, nil
+		case ir.LiteralU32:
+			return constValue{value: ir.ScalarValue{Bits: uint64(v), Kind: ir.ScalarUint}}, nil
+		case ir.LiteralBool:
+			if v {
+				return constValue{value: ir.ScalarValue{Bits: 1, Kind: ir.ScalarBool}}, nil
+			}
+			return constValue{value: ir.ScalarValue{Kind: ir.ScalarBool}}, nil
+		}
+		return constValue{}, fmt.Errorf("only 32-bit constants can be evaluated")
+	case ir.ExprCompose:
+		comps := make([]constValue, len(e.Components))
+		for i, ch := range e.Components {
+			comp, err := l.constValueOfGlobalExpr(ch)
+			if err != nil {
+				return constValue{}, err
+			}
+			comps[i] = comp
+		}
+		return l.buildConstValue(e.Type, comps)
+	case ir.ExprSplat:
+		comp, err := l.constValueOfGlobalExpr(e.Value)
+		if err != nil {
+			return constValue{}, err
+		}
+		return buildConstComposite(ir.VectorType{Size: e.Size, Scalar: comp.scalarType()}, []constValue{comp})
+	case ir.ExprZeroValue:
+		if _, ok := l.module.Types[e.Type].Inner.(ir.StructType); ok {
+			return l.zeroStructConstValue(e.Type)
+		}
+		return l.zeroConstValue(l.module.Types[e.Type].Inner)
+	case ir.ExprConstant:
+		return l.constValueOfConstant(e.Constant)
+	default:
+		return constValue{}, fmt.Errorf("unsupported constant init %T", e)
+	}
+}
+
+// emitConstValue materializes v as Literal/Compose global expressions,
+// concretizing abstract scalars to i32/f32, and returns the init
+// expression and its type.
+func (l *Lowerer) emitConstValue(v constValue) (ir.ExpressionHandle, ir.TypeHandle, error) {
+	if v.isScalar() {
+		scalar := v.scalarType()
+		sv, err := v.concrete()
+		if err != nil {
+			return 0, 0, err
+		}
+		lit := scalarValueToLiteral(sv)
+		if lit == nil {
+			return 0, 0, fmt.Errorf("cannot represent constant of kind %d", v.value.Kind)
+		}
+		return l.addGlobalExpr(ir.Literal{Value: lit}), l.registerType("", scalar), nil
+	}
+
+	comps := make([]ir.ExpressionHandle, len(v.components))
+	var compType ir.TypeHandle
+	for i, c := range v.components {
+		h, ty, err := l.emitConstValue(c)
+		if err != nil {
+			return 0, 0, err
+		}
+		comps[i], compType = h, ty
+	}
+
+	var inner ir.TypeInner
+	switch t := v.inner.(type) {
+	case ir.VectorType:
+		t.Scalar = v.leaf().scalarType()
+		inner = t
+	case ir.MatrixType:
+		t.Scalar = v.leaf().scalarType()
+		inner = t
+	case ir.ArrayType:
+		t.Base = compType
+		t.Stride = l.typeStride(compType)
+		inner = t
+	case ir.StructType:
+		return l.addGlobalExpr(ir.ExprCompose{Type: v.structType, Components: comps}), v.structType, nil
+	default:
+		return 0, 0, fmt.Errorf("unsupported constant type %T", v.inner)
+	}
+	typeHandle := l.registerType("", inner)
+	return l.addGlobalExpr(ir.ExprCompose{Type: typeHandle, Components: comps}), typeHandle, nil
+}
+
+// typeStride returns the array element stride of a type: its size
+// rounded up to its alignment.
+func (l *Lowerer) typeStride(handle ir.TypeHandle) uint32 {
+	align, size := l.typeAlignmentAndSize(handle)
+	return (size + align - 1) &^ (align - 1)
+}
+
+// scalarKindName returns the WGSL name of a 32-bit scalar kind.
+func scalarKindName(kind ir.ScalarKind) string {
+	switch kind {
+	case ir.ScalarSint:
+		return "i32"
+	case ir.ScalarUint:
+		return "u32"
+	case ir.ScalarFloat:
+		return "f32"
+	case ir.ScalarBool:
+		return "bool"
+	case ir.ScalarAbstractInt:
+		return "AbstractInt"
+	case ir.ScalarAbstractFloat:
+		return "AbstractFloat"
+	default:
+		return "abstract"
+	}
+}
+
+// lowerEvaluatedGlobalInit evaluates a global variable initializer that
+// is a constant expression and materializes it as the variable's init
+// expression, converted to the variable's type.
+func (l *Lowerer) lowerEvaluatedGlobalInit(typeHandle ir.TypeHandle, init parser.Expr) (ir.ExpressionHandle, error) {
+	// A constant of the variable's type is referenced directly, which also
+	// covers struct constants the evaluator does not represent.
+	if id, ok := init.(*parser.Ident); ok {
+		if h, ok := l.moduleConstants[id.Name]; ok && l.module.Constants[h].Type == typeHandle {
+			return l.addGlobalExpr(ir.ExprConstant{Constant: h}), nil
+		}
+	}
+	v, err := l.evalConstValue(init)
+	if err != nil {
+		return 0, err
+	}
+	if v, err = l.convertConstValue(v, l.module.Types[typeHandle].Inner, false); err != nil {
+		return 0, err
+	}
+	h, _, err := l.emitConstValue(v)
+	return h, err
+}
+
+// inferEvaluatedConstType returns the concrete type of a constant
+// expression, with abstract scalars concretized to i32/f32.
+func (l *Lowerer) inferEvaluatedConstType(expr parser.Expr) (ir.TypeHandle, error) {
+	v, err := l.evalConstValue(expr)
+	if err != nil {
+		return 0, err
+	}
+	if v.isScalar() {
+		return l.registerType("", v.scalarType()), nil
+	}
+	inner := v.inner
+	switch t := inner.(type) {
+	case ir.VectorType:
+		t.Scalar = v.leaf().scalarType()
+		inner = t
+	case ir.MatrixType:
+		t.Scalar = v.leaf().scalarType()
+		inner = t
+	}
+	return l.registerType("", inner), nil
+}
+
+// splitFloatSuffix splits the f or h suffix off a float literal. In a
+// hexadecimal literal they are digits unless they follow the exponent.
+func splitFloatSuffix(text string) (string, string) {
+	last := len(text) - 1
+	if last < 0 || (text[last] != 'f' && text[last] != 'h') {
+		return text, ""
+	}
+	if hex := strings.HasPrefix(text, "0x") || strings.HasPrefix(text, "0X"); hex && !strings.ContainsAny(text, "pP") {
+		return text, ""
+	}
+	return text[:last], text[last:]
+}
@@ -0,0 +1,263 @@
+package lower
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/gogpu/naga/ir"
+)
+
+// constLeaves flattens a constant's init expression into its literals.
+func constLeaves(t *testing.T, m *ir.Module, name string) []ir.LiteralValue {
+	t.Helper()
+	for _, c := range m.Constants {
+		if c.Name != name {
+			continue
+		}
+		var leaves []ir.LiteralValue
+		var walk func(h ir.ExpressionHandle)
+		walk = func(h ir.ExpressionHandle) {
+			switch e := m.GlobalExpressions[h].Kind.(type) {
+			case ir.Literal:
+				leaves = append(leaves, e.Value)
+			case ir.ExprCompose:
+				for _, c := range e.Components {
+					walk(c)
+				}
+			default:
+				t.Fatalf("constant %s: unexpected init expression %T", name, e)
+			}
+		}
+		walk(c.Init)
+		return leaves
+	}
+	t.Fatalf("constant %s not found", name)
+	return nil
+}
+
+func TestEvaluatedCompositeConstants(t *testing.T) {
+	src := `
+const v_f32_one = vec4<f32>(1.0, 1.0, 1.0, 1.0);
+const twice = v_f32_one * 2.0;
+const sum = v_f32_one + vec4<f32>(0.5);
+const sw = v_f32_one.zy;
+const comp = twice.w;
+const arr = array<i32, 3>(1, 2, 3);
+const elem = arr[1] << 2u;
+const neg = -vec2<f32>(1.0, 2.0);
+const m = mat2x2<f32>(1.0, 2.0, 3.0, 4.0);
+const col = m[1];
+const mixed: vec2<u32> = vec2(3, 4) % vec2(2, 3);
+const cmp = vec2<i32>(1, 5) < vec2<i32>(3, 3);
+const precise = vec2(16777217.0, 0.5) - vec2(16777216.0, 0.0);
+const big: vec2<u32> = vec2(3000000000, 1) + vec2(1, 1);
+const wide = vec2(1 << 40, 8) >> vec2(38, 2);
+
+@compute @workgroup_size(1)
+fn main() {
+    var a = twice + sum + vec4<f32>(sw, comp, f32(elem));
+    var b = neg + col + vec2<f32>(mixed) + select(vec2<f32>(0.0), vec2<f32>(1.0), cmp);
+}
+`
+	result, err := lowerDiagnosticSource(t, src)
+	if err != nil {
+		t.Fatalf("lower failed: %v", err)
+	}
+	m := result.Module
+
+	tests := []struct {
+		name string
+		want []ir.LiteralValue
+	}{
+		{"twice", []ir.LiteralValue{ir.LiteralF32(2), ir.LiteralF32(2), ir.LiteralF32(2), ir.LiteralF32(2)}},
+		{"sum", []ir.LiteralValue{ir.LiteralF32(1.5), ir.LiteralF32(1.5), ir.LiteralF32(1.5), ir.LiteralF32(1.5)}},
+		{"sw", []ir.LiteralValue{ir.LiteralF32(1), ir.LiteralF32(1)}},
+		{"comp", []ir.LiteralValue{ir.LiteralF32(2)}},
+		{"elem", []ir.LiteralValue{ir.LiteralI32(8)}},
+		{"neg", []ir.LiteralValue{ir.LiteralF32(-1), ir.LiteralF32(-2)}},
+		{"col", []ir.LiteralValue{ir.LiteralF32(3), ir.LiteralF32(4)}},
+		{"mixed", []ir.LiteralValue{ir.LiteralU32(1), ir.LiteralU32(1)}},
+		{"cmp", []ir.LiteralValue{ir.LiteralBool(true), ir.LiteralBool(false)}},
+		{"precise", []ir.LiteralValue{ir.LiteralF32(1), ir.LiteralF32(0.5)}},
+		{"big", []ir.LiteralValue{ir.LiteralU32(3000000001), ir.LiteralU32(2)}},
+		{"wide", []ir.LiteralValue{ir.LiteralI32(4), ir.LiteralI32(2)}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := constLeaves(t, m, tt.name)
+			if len(got) != len(tt.want) {
+				t.Fatalf("got %d components %v, want %v", len(got), got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Errorf("component %d = %#v, want %#v", i, got[i], tt.want[i])
+				}
+			}
+		})
+	}
+
+	for _, c := range m.Constants {
+		if c.Name == "twice" {
+			if vec, ok := m.Types[c.Type].Inner.(ir.VectorType); !ok || vec.Size != ir.Vec4 || vec.Scalar.Kind != ir.ScalarFloat {
+				t.Errorf("twice has type %#v, want vec4<f32>", m.Types[c.Type].Inner)
+			}
+		}
+	}
+}
+
+func TestEvaluatedConstantErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		want string
+	}{
+		{"index out of bounds", "const a = array<i32, 2>(1, 2);\nconst b = a[2];", "out of bounds"},
+		{"bad swizzle", "const v = vec2<f32>(1.0, 2.0);\nconst s = v.z;", "swizzle"},
+		{"size mismatch", "const v = vec2<f32>(1.0, 2.0) + vec3<f32>(1.0);", "sizes differ"},
+		{"division by zero", "const v = vec2<i32>(1, 2) / vec2<i32>(1, 0);", "division by zero"},
+		{"float to int", "const v: vec2<i32> = vec2<f32>(1.0, 2.0) * 2.0;", "cannot convert f32"},
+		{"i32 overflow", "const v = 0x7fffffffi + 1i;", "+ overflows i32"},
+		{"u32 overflow", "const v = vec2(0xffffffffu, 1u) * 2u;", "* overflows u32"},
+		{"i32 negation", "const v = -(-0x7fffffffi - 1i);", "negation overflows i32"},
+		{"literal out of range", "const v: i32 = 3000000000;", "overflows i32"},
+		{"shift out of range", "const v = 1u << 32u;", "out of range for u32"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := lowerDiagnosticSource(t, tt.src)
+			if err == nil {
+				t.Fatal("expected error")
+			}
+			if !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("error %q does not mention %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestStructConstantsIndexedAtRuntime(t *testing.T) {
+	src := `
+struct Tri { p: array<vec2f, 2>, scale: f32 }
+const TRI = Tri(array(vec2f(0.0, 1.0), vec2f(1.0, 0.0)), 2);
+const TRIS = array(Tri(TRI.p, 3.0), Tri());
+@vertex
+fn vs(@builtin(vertex_index) i: u32) -> @builtin(position) vec4f {
+    return vec4f(TRI.p[i] * TRIS[i].scale + TRIS[0].p[1], 0.0, 1.0);
+}
+`
+	result, err := lowerDiagnosticSource(t, src)
+	if err != nil {
+		t.Fatalf("lower failed: %v", err)
+	}
+	m := result.Module
+
+	want := map[string][]ir.LiteralValue{
+		"TRI": {ir.LiteralF32(0), ir.LiteralF32(1), ir.LiteralF32(1), ir.LiteralF32(0), ir.LiteralF32(2)},
+		"TRIS": {
+			ir.LiteralF32(0), ir.LiteralF32(1), ir.LiteralF32(1), ir.LiteralF32(0), ir.LiteralF32(3),
+			ir.LiteralF32(0), ir.LiteralF32(0), ir.LiteralF32(0), ir.LiteralF32(0), ir.LiteralF32(0),
+		},
+	}
+	for name, leaves := range want {
+		got := constLeaves(t, m, name)
+		if len(got) != len(leaves) {
+			t.Fatalf("%s: got %v, want %v", name, got, leaves)
+		}
+		for i := range got {
+			if got[i] != leaves[i] {
+				t.Errorf("%s component %d = %#v, want %#v", name, i, got[i], leaves[i])
+			}
+		}
+	}
+
+	// The runtime indices stay accesses; the constant index TRIS[0].p[1]
+	// folds to the element vector, not to one of its scalars.
+	fn := &m.EntryPoints[0].Function
+	accesses := 0
+	for _, e := range fn.Expressions {
+		switch k := e.Kind.(type) {
+		case ir.ExprAccess:
+			accesses++
+		case ir.ExprBinary:
+			if k.Op != ir.BinaryAdd {
+				continue
+			}
+			compose, ok := fn.Expressions[k.Right].Kind.(ir.ExprCompose)
+			if !ok || len(compose.Components) != 2 {
+				t.Fatalf("TRIS[0].p[1] lowered to %#v, want a vec2 Compose", fn.Expressions[k.Right].Kind)
+			}
+			if x := fn.Expressions[compose.Components[0]].Kind; x != (ir.Literal{Value: ir.LiteralF32(1)}) {
+				t.Errorf("TRIS[0].p[1].x = %#v, want 1.0", x)
+			}
+		}
+	}
+	if accesses != 2 {
+		t.Errorf("got %d runtime accesses, want 2", accesses)
+	}
+}
+
+func TestMatrixProductNotFoldedComponentwise(t *testing.T) {
+	src := `
+const m = mat2x2<f32>(1.0, 2.0, 3.0, 4.0);
+@compute @workgroup_size(1)
+fn main() {
+    var b = m * m;
+}
+`
+	result, err := lowerDiagnosticSource(t, src)
+	if err != nil {
+		t.Fatalf("lower failed: %v", err)
+	}
+	fn := &result.Module.EntryPoints[0].Function
+	found := false
+	for _, e := range fn.Expressions {
+		if bin, ok := e.Kind.(ir.ExprBinary); ok && bin.Op == ir.BinaryMultiply {
+			found = true
+		}
+	}
+	if !found {
+		t.Error("matrix product was folded; expected a Binary multiply expression")
+	}
+}
+
+func TestPrivateGlobalConstantInit(t *testing.T) {
+	src := `
+struct Config { a: f32, b: f32 }
+const CFG = Config(1.0, 2.0);
+const K = vec2<f32>(1.0, 2.0);
+var<private> cfg: Config = CFG;
+var<private> k = K;
+var<private> sw = K.yx;
+var<private> n: u32 = 4;
+
+@compute @workgroup_size(1)
+fn main() {
+    k = sw * f32(n) + vec2<f32>(cfg.a);
+}
+`
+	result, err := lowerDiagnosticSource(t, src)
+	if err != nil {
+		t.Fatalf("lower failed: %v", err)
+	}
+	m := result.Module
+	inits := map[string]ir.Expression{}
+	for _, gv := range m.GlobalVariables {
+		if gv.InitExpr == nil {
+			t.Errorf("global %s has no init expression", gv.Name)
+			continue
+		}
+		inits[gv.Name] = m.GlobalExpressions[*gv.InitExpr]
+	}
+	if _, ok := inits["cfg"].Kind.(ir.ExprConstant); !ok {
+		t.Errorf("cfg init = %T, want a constant reference", inits["cfg"].Kind)
+	}
+	if _, ok := inits["k"].Kind.(ir.ExprConstant); !ok {
+		t.Errorf("k init = %T, want a constant reference", inits["k"].Kind)
+	}
+	if c, ok := inits["sw"].Kind.(ir.ExprCompose); !ok || len(c.Components) != 2 {
+		t.Errorf("sw init = %#v, want a 2-component compose", inits["sw"].Kind)
+	}
+	if lit, ok := inits["n"].Kind.(ir.Literal); !ok || lit.Value != ir.LiteralU32(4) {
+		t.Errorf("n init = %#v, want 4u", inits["n"].Kind)
+	}
+}
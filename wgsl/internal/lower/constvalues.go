@@ -0,0 +1,195 @@
+package lower
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/gogpu/naga/ir"
+	"github.com/gogpu/naga/wgsl/internal/parser"
+)
+
+// Constant values.
+//
+// Options.Constants fixes module constants to other values. Constants are
+// folded into every const-expression that reads them, at module scope and
+// in function bodies, so the value is substituted for the initializer in
+// the AST before the constant is lowered: everything lowered after it
+// sees the new value.
+
+// checkConstantValues reports a key of values that names no module
+// constant of ast.
+func checkConstantValues(ast *parser.Module, values ir.PipelineConstants) error {
+	for name := range values {
+		found := false
+		for _, c := range ast.Constants {
+			if c.Name == name {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return fmt.Errorf("no module constant named %q", name)
+		}
+	}
+	return nil
+}
+
+// substituteConstantValue returns c with its initializer replaced by the
+// value Options.Constants gives it, or c itself if none does. The value
+// takes the type of c: its declared type, or else the type of its
+// initializer. Booleans use 1 and 0.
+func (l *Lowerer) substituteConstantValue(c *parser.ConstDecl) (*parser.ConstDecl, error) {
+	val, ok := l.constantValues[c.Name]
+	if !ok {
+		return c, nil
+	}
+
+	var kind ir.ScalarKind
+	var width uint8 = 4
+	if c.Type != nil {
+		th, err := l.resolveType(c.Type)
+		if err != nil {
+			return nil, err
+		}
+		st, ok := l.module.Types[th].Inner.(ir.ScalarType)
+		if !ok {
+			return nil, fmt.Errorf("constant '%s' is not a scalar and cannot be given a value", c.Name)
+		}
+		kind, width = st.Kind, st.Width
+	} else {
+		v, err := l.evalConstValue(c.Init)
+		if err != nil {
+			return nil, fmt.Errorf("constant '%s' cannot be given a value: %w", c.Name, err)
+		}
+		if !v.isScalar() {
+			return nil, fmt.Errorf("constant '%s' is not a scalar and cannot be given a value", c.Name)
+		}
+		kind = v.value.Kind
+	}
+
+	init, err := constantValueExpr(kind, width, val, c.Init.Pos())
+	if err != nil {
+		return nil, fmt.Errorf("constant '%s': %w", c.Name, err)
+	}
+	sub := *c
+	sub.Init = init
+	return &sub, nil
+}
+
+// constantValueExpr returns a literal of the given scalar type with value
+// val, negated if val is negative.
+func constantValueExpr(kind ir.ScalarKind, width uint8, val float64, span parser.Span) (parser.Expr, error) {
+	if kind == ir.ScalarBool {
+		if val != 0 && val != 1 {
+			return nil, fmt.Errorf("value %v is not a boolean, use 1 or 0", val)
+		}
+		return &parser.Literal{Kind: parser.TokenBoolLiteral, Value: strconv.FormatBool(val == 1), Span: span}, nil
+	}
+	if math.IsNaN(val) || math.IsInf(val, 0) {
+		return nil, fmt.Errorf("value %v is not finite", val)
+	}
+
+	var text string
+	var tok parser.TokenKind
+	switch {
+	case kind == ir.ScalarFloat || kind == ir.ScalarAbstractFloat:
+		bits, suffix := 64, ""
+		if kind == ir.ScalarFloat {
+			bits, suffix = 32, "f"
+			if width == 2 {
+				suffix = "h"
+			}
+			if math.Abs(val) > math.MaxFloat32 {
+				return nil, fmt.Errorf("value %v does not fit in f32", val)
+			}
+		}
+		text = strconv.FormatFloat(math.Abs(val), 'g', -1, bits)
+		if !strings.ContainsAny(text, ".e") {
+			text += ".0"
+		}
+		text, tok = text+suffix, parser.TokenFloatLiteral
+	case kind == ir.ScalarSint && width == 4, kind == ir.ScalarUint && width == 4, kind == ir.ScalarAbstractInt:
+		lo, hi, suffix := float64(math.MinInt64), float64(math.MaxInt64), ""
+		switch kind {
+		case ir.ScalarSint:
+			lo, hi, suffix = math.MinInt32, math.MaxInt32, "i"
+		case ir.ScalarUint:
+			lo, hi, suffix = 0, math.MaxUint32, "u"
+		}
+		if val != math.Trunc(val) || val < lo || val > hi {
+			return nil, fmt.Errorf("value %v does not fit in %s", val, scalarKindName(kind))
+		}
+		if kind == ir.ScalarSint && val == lo {
+			// 2147483648i does not fit, so the minimum is -2147483647i - 1i.
+			return &parser.BinaryExpr{
+				Left: &parser.UnaryExpr{Op: parser.TokenMinus, Operand: &parser.Literal{
+					Kind: parser.TokenIntLiteral, Value: strconv.Itoa(math.MaxInt32) + suffix, Span: span,
+				}, Span: span},
+				Op:    parser.TokenMinus,
+				Right: &parser.Literal{Kind: parser.TokenIntLiteral, Value: "1" + suffix, Span: span},
+				Span:  span,
+			}, nil
+		}
+		text = strconv.FormatUint(uint64(math.Abs(val)), 10)
+		text, tok = text+suffix, parser.TokenIntLiteral
+	default:
+		return nil, fmt.Errorf("only 32-bit and abstract scalars can be given a value")
+	}
+
+	lit := &parser.Literal{Kind: tok, Value: text, Span: span}
+	if math.Signbit(val) {
+		return &parser.UnaryExpr{Op: parser.TokenMinus, Operand: lit, Span: span}, nil
+	}
+	return lit, nil
+}
+
+// overrideReadBy returns the name of an override the const-expression expr
+// reads, or "". A constant cannot depend on an override: its value must be
+// known when the shader is created.
+func (l *Lowerer) overrideReadBy(expr parser.Expr) string {
+	switch e := expr.(type) {
+	case *parser.Ident:
+		if _, ok := l.moduleOverrides[e.Name]; ok {
+			return e.Name
+		}
+	case *parser.BinaryExpr:
+		if name := l.overrideReadBy(e.Left); name != "" {
+			return name
+		}
+		return l.overrideReadBy(e.Right)
+	case *parser.UnaryExpr:
+		return l.overrideReadBy(e.Operand)
+	case *parser.CallExpr:
+		return l.overrideReadByAll(e.Args)
+	case *parser.ConstructExpr:
+		return l.overrideReadByAll(e.Args)
+	case *parser.IndexExpr:
+		if name := l.overrideReadBy(e.Expr); name != "" {
+			return name
+		}
+		return l.overrideReadBy(e.Index)
+	case *parser.MemberExpr:
+		return l.overrideReadBy(e.Expr)
+	case *parser.BitcastExpr:
+		return l.overrideReadBy(e.Expr)
+	}
+	return ""
+}
+
+func (l *Lowerer) overrideReadByAll(exprs []parser.Expr) string {
+	for _, e := range exprs {
+		if name := l.overrideReadBy(e); name != "" {
+			return name
+		}
+	}
+	return ""
+}
+
+// constReadsOverrideError reports a const declaration whose initializer
+// reads an override.
+func constReadsOverrideError(decl *parser.ConstDecl, override string) error {
+	return fmt.Errorf("constant '%s' reads override '%s', which is not a const-expression; declare '%s' as an override",
+		decl.Name, override, decl.Name)
+}
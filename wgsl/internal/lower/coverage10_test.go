@@ -1,6 +1,7 @@
 package lower
 
 import (
+	"strings"
 	"testing"
 
 	"github.com/gogpu/naga/ir"
@@ -44,6 +45,68 @@ func TestLowerBuiltinConstructorFromVec(t *testing.T) {
 // Texture gather operations (56.1% and 54.5%)
 // ---------------------------------------------------------------------------
 
+func TestLowerMatrixConstructorColumns(t *testing.T) {
+	src := `
+@vertex
+fn vs(@builtin(vertex_index) i: u32) -> @builtin(position) vec4f {
+    let f = f32(i);
+    let v0 = vec3f(f, 0.0, 0.0);
+    let m = mat3x3<f32>(v0, vec3f(0.0, f, 0.0), vec3f(0.0, 0.0, 1.0));
+    let n = mat2x2<f32>(0.0, f, 2.0, 3.0);
+    return vec4f(m[0] + vec3f(n[1], 0.0), 1.0);
+}
+`
+	result, err := lowerDiagnosticSource(t, src)
+	if err != nil {
+		t.Fatalf("lower failed: %v", err)
+	}
+	m := result.Module
+	fn := &m.EntryPoints[0].Function
+	matrices := 0
+	for _, e := range fn.Expressions {
+		compose, ok := e.Kind.(ir.ExprCompose)
+		if !ok {
+			continue
+		}
+		mat, ok := m.Types[compose.Type].Inner.(ir.MatrixType)
+		if !ok {
+			continue
+		}
+		matrices++
+		if len(compose.Components) != int(mat.Columns) {
+			t.Fatalf("mat%dx%d Compose has %d components, want one per column", mat.Columns, mat.Rows, len(compose.Components))
+		}
+		for _, c := range compose.Components {
+			res, err := ir.ResolveExpressionType(m, fn, c)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if vec, ok := ir.TypeResInner(m, res).(ir.VectorType); !ok || vec.Size != mat.Rows {
+				t.Errorf("mat%dx%d column has type %#v", mat.Columns, mat.Rows, ir.TypeResInner(m, res))
+			}
+		}
+	}
+	if matrices != 2 {
+		t.Errorf("got %d matrix constructions, want 2", matrices)
+	}
+
+	errs := []struct {
+		body string
+		want string
+	}{
+		{"let a = mat2x2<f32>(1.0, 2.0, 3.0);", "takes 2 column vectors or 4 scalars, got 3"},
+		{"let a = mat2x2f(vec2f(1.0), vec3f(1.0));", "column 1 must be a vec2"},
+		{"let a = mat2x2(vec2f(1.0), 2.0, 3.0);", "got 3 arguments"},
+		{"let a = mat2x2<f32>(vec2f(1.0), vec2f(1.0), 1.0, 2.0);", "argument 0 must be a scalar"},
+	}
+	for _, tt := range errs {
+		_, err := lowerDiagnosticSource(t, "fn f() {\n    "+tt.body+"\n}\n")
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%s: error %v, want %q", tt.body, err, tt.want)
+		}
+	}
+}
+
 func TestLowerTextureGatherWithCoords(t *testing.T) {
 	src := `@group(0) @binding(0) var tex: texture_2d<f32>;
 @group(0) @binding(1) var samp: sampler;
@@ -54,6 +117,72 @@ fn main(@location(0) uv: vec2<f32>) -> @location(0) vec4<f32> {
 	mustCompile(t, src)
 }
 
+func TestLowerTextureOverloadByParameterType(t *testing.T) {
+	// The optional textureLoad/textureStore arguments are routed by the
+	// image type of the texture, also when it is a function parameter or a
+	// binding array element rather than a global.
+	src := `@group(0) @binding(0) var arr: binding_array<texture_2d_array<f32>, 2>;
+fn load(t: texture_2d_array<f32>, c: vec2i) -> vec4f { return textureLoad(t, c, 1, 0); }
+fn loadms(t: texture_multisampled_2d<f32>, c: vec2i) -> vec4f { return textureLoad(t, c, 3); }
+fn store(t: texture_storage_2d_array<rgba8unorm, write>, c: vec2i, v: vec4f) { textureStore(t, c, 2, v); }
+fn element(c: vec2i) -> vec4f { return textureLoad(arr[1], c, 1, 0); }
+`
+	m := mustCompile(t, src)
+	loads := func(fn *ir.Function) ir.ExprImageLoad {
+		for _, e := range fn.Expressions {
+			if load, ok := e.Kind.(ir.ExprImageLoad); ok {
+				return load
+			}
+		}
+		t.Fatalf("%s: no ImageLoad", fn.Name)
+		return ir.ExprImageLoad{}
+	}
+	for _, i := range []int{0, 3} {
+		load := loads(&m.Functions[i])
+		if load.ArrayIndex == nil || load.Level == nil || load.Sample != nil {
+			t.Errorf("%s: ImageLoad %+v, want array index and level", m.Functions[i].Name, load)
+		}
+	}
+	if load := loads(&m.Functions[1]); load.Sample == nil || load.ArrayIndex != nil || load.Level != nil {
+		t.Errorf("loadms: ImageLoad %+v, want sample index only", load)
+	}
+
+	store := &m.Functions[2]
+	for _, st := range store.Body {
+		if s, ok := st.Kind.(ir.StmtImageStore); ok {
+			if s.ArrayIndex == nil {
+				t.Error("store: ImageStore has no array index")
+			}
+			if _, ok := store.Expressions[s.Value].Kind.(ir.ExprFunctionArgument); !ok {
+				t.Errorf("store: ImageStore value is %T, want the v parameter", store.Expressions[s.Value].Kind)
+			}
+		}
+	}
+}
+
+func TestLowerTextureAtomicChecks(t *testing.T) {
+	call := func(decl, stmt string) string {
+		return "@group(0) @binding(0) var t: " + decl + ";\n@compute @workgroup_size(1)\nfn main() { " + stmt + "; }"
+	}
+	expectError(t, call("texture_2d<u32>", "textureAtomicMax(t, vec2i(0), 1u)"), "requires a storage texture with atomic access")
+	expectError(t, call("texture_storage_2d<rgba8uint, read_write>", "textureAtomicMax(t, vec2i(0), 1u)"), "rgba8uint format does not support atomics")
+	expectError(t, call("texture_storage_2d<r32uint, atomic>", "textureAtomicMin(t, vec2i(0), 1i)"), "i32 value does not match the r32uint format")
+
+	// An abstract value takes the texel type of the format.
+	m := mustCompile(t, call("texture_storage_2d_array<r32sint, atomic>", "textureAtomicOr(t, vec2i(0), 2, 1)"))
+	fn := &m.EntryPoints[0].Function
+	for _, st := range fn.Body {
+		if a, ok := st.Kind.(ir.StmtImageAtomic); ok {
+			if a.ArrayIndex == nil {
+				t.Error("ImageAtomic has no array index")
+			}
+			if v := fn.Expressions[a.Value].Kind; v != (ir.Literal{Value: ir.LiteralI32(1)}) {
+				t.Errorf("ImageAtomic value = %#v, want i32 1", v)
+			}
+		}
+	}
+}
+
 func TestLowerTextureGatherCompareDepth(t *testing.T) {
 	src := `@group(0) @binding(0) var tex: texture_depth_2d;
 @group(0) @binding(1) var samp: sampler_comparison;
@@ -65,7 +194,7 @@ fn main(@location(0) uv: vec2<f32>) -> @location(0) vec4<f32> {
 }
 
 // ---------------------------------------------------------------------------
-// typeShapeMatches — type matching (56.2%)
+// checkArgumentType — type matching
 // ---------------------------------------------------------------------------
 
 func TestLowerTypeShapeMatchesVecConvert(t *testing.T) {
@@ -540,3 +669,164 @@ fn test() -> i32 {
 }`
 	mustCompile(t, src)
 }
+
+// ---------------------------------------------------------------------------
+// Quad operation checks
+// ---------------------------------------------------------------------------
+
+func TestLowerQuadOperationChecks(t *testing.T) {
+	wrap := func(body string) string {
+		return `enable subgroups;
+@fragment
+fn main(@builtin(subgroup_invocation_id) i: u32, @location(0) f: f32) -> @location(0) vec4<f32> {
+    ` + body + `
+    return vec4<f32>(0.0);
+}`
+	}
+
+	mustCompile(t, wrap(`let a = quadBroadcast(f, 3u);
+    let b = quadBroadcast(vec3<i32>(1), 2);
+    let c = quadSwapDiagonal(vec2<f32>(f));`))
+
+	expectError(t, wrap(`let a = quadBroadcast(f, i);`), "quadBroadcast: the lane id must be a const-expression")
+	expectError(t, wrap(`let a = quadBroadcast(f, 4u);`), "quadBroadcast: the lane id must be in [0, 4), got 4")
+	expectError(t, wrap(`let a = quadBroadcast(f, -1);`), "quadBroadcast: the lane id must be in [0, 4), got -1")
+	expectError(t, wrap(`let a = quadSwapY(true);`), "quadSwapY: the value must be a numeric scalar or vector")
+	expectError(t, wrap(`let a = quadBroadcast(vec2<bool>(true), 0u);`), "quadBroadcast: the value must be a numeric scalar or vector")
+}
+
+func TestLowerOverrideDerivedTypes(t *testing.T) {
+	src := `@id(0) override count: u32 = 4u;
+override wg: i32 = 64;
+override scale = 1.5;
+override total = count * 2u + 1u;
+override half = wg / 2;
+override twice = 2 * wg;
+override big = total > 8u;
+override third = scale / 3;
+@compute @workgroup_size(1)
+fn main() {
+    _ = total;
+    _ = half;
+    _ = twice;
+    _ = big;
+    _ = third;
+}`
+	module := mustCompile(t, src)
+	want := map[string]ir.ScalarKind{
+		"total": ir.ScalarUint,
+		"half":  ir.ScalarSint,
+		"twice": ir.ScalarSint,
+		"big":   ir.ScalarBool,
+		"third": ir.ScalarFloat,
+	}
+	for _, o := range module.Overrides {
+		kind, ok := want[o.Name]
+		if !ok {
+			continue
+		}
+		scalar, isScalar := module.Types[o.Ty].Inner.(ir.ScalarType)
+		if !isScalar || scalar.Kind != kind {
+			t.Errorf("override %s: expected scalar kind %v, got %v", o.Name, kind, module.Types[o.Ty].Inner)
+		}
+	}
+}
+
+// ---------------------------------------------------------------------------
+// textureDimensions level argument
+// ---------------------------------------------------------------------------
+
+// TestLowerTextureDimensionsLevel follows the queries entry point of
+// image.wgsl: the level is converted to a concrete i32 or u32, and textures
+// without mip levels reject it.
+func TestLowerTextureDimensionsLevel(t *testing.T) {
+	src := `@group(0) @binding(0) var image_1d: texture_1d<f32>;
+@group(0) @binding(1) var image_2d: texture_2d<f32>;
+@group(0) @binding(2) var image_cube_array: texture_cube_array<f32>;
+@group(0) @binding(3) var image_aa: texture_multisampled_2d<f32>;
+@vertex
+fn queries() -> @builtin(position) vec4<f32> {
+    let dim_1d = textureDimensions(image_1d);
+    let dim_1d_lod = textureDimensions(image_1d, i32(dim_1d));
+    let dim_2d_lod = textureDimensions(image_2d, 1);
+    let dim_2d_u32 = textureDimensions(image_2d, dim_1d);
+    let dim_cube_array_lod = textureDimensions(image_cube_array, 1 + 1);
+    let dim_2s_ms = textureDimensions(image_aa);
+    let sum = dim_1d + dim_1d_lod + dim_2d_lod.y + dim_2d_u32.y + dim_cube_array_lod.y + dim_2s_ms.y;
+    return vec4<f32>(f32(sum));
+}`
+	module := mustCompile(t, src)
+	fn := module.EntryPoints[0].Function
+	var levels []ir.ScalarKind
+	for _, expr := range fn.Expressions {
+		query, ok := expr.Kind.(ir.ExprImageQuery)
+		if !ok {
+			continue
+		}
+		size, ok := query.Query.(ir.ImageQuerySize)
+		if !ok || size.Level == nil {
+			continue
+		}
+		scalar, ok := ir.TypeResInner(module, fn.ExpressionTypes[*size.Level]).(ir.ScalarType)
+		if !ok || scalar.Width != 4 {
+			t.Fatalf("level has type %v, want a 32-bit scalar", ir.TypeResInner(module, fn.ExpressionTypes[*size.Level]))
+		}
+		levels = append(levels, scalar.Kind)
+	}
+	want := []ir.ScalarKind{ir.ScalarSint, ir.ScalarSint, ir.ScalarUint, ir.ScalarSint}
+	if len(levels) != len(want) {
+		t.Fatalf("got %d size queries with a level, want %d", len(levels), len(want))
+	}
+	for i := range want {
+		if levels[i] != want[i] {
+			t.Errorf("level %d has kind %v, want %v", i, levels[i], want[i])
+		}
+	}
+
+	errs := []struct {
+		decl, call, want string
+	}{
+		{"texture_multisampled_2d<f32>", "textureDimensions(t, 0)", "multisampled textures have no level argument"},
+		{"texture_depth_multisampled_2d", "textureDimensions(t, 0u)", "multisampled textures have no level argument"},
+		{"texture_storage_2d<rgba8unorm, write>", "textureDimensions(t, 0)", "storage textures have no level argument"},
+		{"texture_2d<f32>", "textureDimensions(t, 1.0)", "level must be i32 or u32, got f32"},
+		{"texture_2d<f32>", "textureDimensions(t, 0, 1)", "takes at most 2 argument(s), got 3"},
+		{"texture_2d<f32>", "textureNumLevels(t, 0)", "takes at most 1 argument(s), got 2"},
+	}
+	for _, tt := range errs {
+		t.Run(tt.call, func(t *testing.T) {
+			expectError(t, `@group(0) @binding(0) var t: `+tt.decl+`;
+@compute @workgroup_size(1)
+fn main() {
+    _ = `+tt.call+`;
+}`, tt.want)
+		})
+	}
+}
+
+func TestLowerBoolNotHostShareable(t *testing.T) {
+	errs := []struct {
+		decl, want string
+	}{
+		{"@group(0) @binding(0) var<storage, read_write> s: S;", "var<storage> 's' holds a bool at s.flag"},
+		{"@group(0) @binding(0) var<uniform> u: array<vec4<bool>, 2>;", "var<uniform> 'u' holds a bool at u[]"},
+		{"@group(0) @binding(0) var<storage> b: bool;", "x != 0u when reading and select(0u, 1u, b) when writing"},
+	}
+	for _, tt := range errs {
+		t.Run(tt.decl, func(t *testing.T) {
+			expectError(t, "struct S { a: f32, flag: bool }\n"+tt.decl+"\n@compute @workgroup_size(1)\nfn main() {}", tt.want)
+		})
+	}
+
+	// Private and workgroup variables never reach the host, so bool is fine
+	// there and keeps the IR's 1-byte layout.
+	m := mustCompile(t, `struct S { a: f32, flag: bool, b: f32 }
+var<workgroup> w: S;
+var<private> p: array<bool, 3>;
+@compute @workgroup_size(1)
+fn main() { w.flag = p[1]; }`)
+	st := m.Types[m.GlobalVariables[0].Type].Inner.(ir.StructType)
+	if st.Members[2].Offset != 8 || st.Span != 12 {
+		t.Errorf("S has b at %d and span %d, want 8 and 12", st.Members[2].Offset, st.Span)
+	}
+}
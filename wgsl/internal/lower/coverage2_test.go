@@ -1,6 +1,7 @@
 package lower
 
 import (
+	"strings"
 	"testing"
 
 	"github.com/gogpu/naga/ir"
@@ -612,6 +613,50 @@ func TestLowerShortAliasConstructors(t *testing.T) {
 	mustCompile(t, src)
 }
 
+func TestLowerShortAliasTable(t *testing.T) {
+	// Each predeclared alias names the same type as its long form, in type
+	// positions, constructors, bitcast targets, and inferred global types.
+	var aliases []string
+	for _, n := range []string{"2", "3", "4"} {
+		for _, s := range []string{"i", "u", "f", "h"} {
+			aliases = append(aliases, "vec"+n+s)
+		}
+		for _, m := range []string{"2", "3", "4"} {
+			aliases = append(aliases, "mat"+n+"x"+m+"f", "mat"+n+"x"+m+"h")
+		}
+	}
+	for _, alias := range aliases {
+		long, ok := lookupShortTypeAlias(alias)
+		if !ok {
+			t.Errorf("%s is not a short type alias", alias)
+			continue
+		}
+		t.Run(alias, func(t *testing.T) {
+			src := "enable f16;\nvar<private> g = " + alias + "();\nfn f(x: " + alias + ") -> " +
+				long.baseName + "<" + long.scalarName + "> { return " + alias + "(x); }"
+			m := mustCompile(t, src)
+			want := m.Functions[0].Arguments[0].Type
+			if got := *m.Functions[0].Result; got.Type != want {
+				t.Errorf("%s resolves to %v, %s<%s> to %v", alias, want, long.baseName, long.scalarName, got.Type)
+			}
+			if got := m.GlobalVariables[0].Type; got != want {
+				t.Errorf("var<private> g = %s() has type %v, want %v", alias, got, want)
+			}
+		})
+	}
+
+	for _, name := range []string{"vec5f", "vec3", "mat2x2i", "mat4x4u", "vec2b", "f"} {
+		if _, ok := lookupShortTypeAlias(name); ok {
+			t.Errorf("%s is not a predeclared alias", name)
+		}
+	}
+
+	mustCompile(t, `fn test() {
+    let a = bitcast<vec3u>(vec3f(1.0));
+    let b = bitcast<vec2f>(vec2i(1));
+}`)
+}
+
 // -----------------------------------------------------------------------
 // Length and distance
 // -----------------------------------------------------------------------
@@ -860,10 +905,17 @@ func TestLowerStorageFormats(t *testing.T) {
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
+			value := "vec4<f32>(1.0)"
+			switch {
+			case strings.HasSuffix(tt.format, "uint"):
+				value = "vec4<u32>(1u)"
+			case strings.HasSuffix(tt.format, "sint"):
+				value = "vec4<i32>(1i)"
+			}
 			src := `@group(0) @binding(0) var t: texture_storage_2d<` + tt.format + `, write>;
 @compute @workgroup_size(1)
 fn main(@builtin(global_invocation_id) id: vec3<u32>) {
-    textureStore(t, vec2<i32>(0, 0), vec4<f32>(1.0));
+    textureStore(t, vec2<i32>(0, 0), ` + value + `);
 }`
 			mustCompile(t, src)
 		})
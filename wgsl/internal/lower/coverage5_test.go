@@ -13,10 +13,8 @@ import (
 func TestLowerScalarKindConversions(t *testing.T) {
 	src := `fn test() {
     var fi: f32 = 42;
-    var fu: f32 = 42u;
-    var iu: i32 = 10u;
     var ui: u32 = 10;
-    _ = fi; _ = fu; _ = iu; _ = ui;
+    _ = fi; _ = ui;
 }`
 	mustCompile(t, src)
 }
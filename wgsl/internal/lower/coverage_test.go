@@ -944,9 +944,8 @@ func TestLowerErrorUndefinedFunction(t *testing.T) {
 }
 
 func TestLowerDuplicateFunction(t *testing.T) {
-	// The lowerer silently overwrites duplicate functions (matching Rust naga).
 	src := `fn foo() {} fn foo() -> i32 { return 42; }`
-	mustCompile(t, src)
+	expectError(t, src, "1:13: redefinition of 'foo' (previously declared at 1:1)")
 }
 
 func TestLowerErrorMathArgCount(t *testing.T) {
@@ -0,0 +1,189 @@
+package lower
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/gogpu/naga/ir"
+	"github.com/gogpu/naga/wgsl/internal/parser"
+)
+
+func lowerDiagnosticSource(t *testing.T, src string) (*LowerResult, error) {
+	t.Helper()
+	tokens, err := parser.NewLexer(src).Tokenize()
+	if err != nil {
+		t.Fatal(err)
+	}
+	ast, err := parser.NewParser(tokens).Parse()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return LowerWithWarnings(ast, src)
+}
+
+const derivativeShader = `
+@group(0) @binding(0) var t: texture_2d<f32>;
+@group(0) @binding(1) var s: sampler;
+
+@fragment
+fn main(@location(0) uv: vec2<f32>) -> @location(0) vec4<f32> {
+    var color = vec4<f32>(0.0);
+    %s if uv.x > 0.5 {
+        color = textureSample(t, s, uv);
+    }
+    return color + vec4<f32>(dpdx(1.0));
+}
+`
+
+func derivativeWarnings(warnings []Warning) int {
+	n := 0
+	for _, w := range warnings {
+		if strings.Contains(w.Message, "non-uniform control flow") {
+			n++
+		}
+	}
+	return n
+}
+
+func TestDerivativeUniformityDefaultWarns(t *testing.T) {
+	src := strings.Replace(derivativeShader, "%s", "", 1)
+	result, err := lowerDiagnosticSource(t, src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := derivativeWarnings(result.Warnings); got != 1 {
+		t.Errorf("derivative warnings: got %d, want 1: %v", got, warningMessages(result.Warnings))
+	}
+}
+
+func TestDerivativeUniformitySeverity(t *testing.T) {
+	tests := []struct {
+		name      string
+		directive string
+		attr      string
+		stmt      string
+		warnings  int
+		wantErr   bool
+	}{
+		{name: "directive_off", directive: "diagnostic(off, derivative_uniformity);"},
+		{name: "directive_info", directive: "diagnostic(info, derivative_uniformity);"},
+		{name: "directive_error", directive: "diagnostic(error, derivative_uniformity);", wantErr: true},
+		{name: "function_off", attr: "@diagnostic(off, derivative_uniformity)"},
+		{name: "function_overrides_directive", directive: "diagnostic(error, derivative_uniformity);", attr: "@diagnostic(warning, derivative_uniformity)", warnings: 1},
+		{name: "statement_off", directive: "diagnostic(error, derivative_uniformity);", stmt: "@diagnostic(off, derivative_uniformity)"},
+		{name: "statement_error", stmt: "@diagnostic(error, derivative_uniformity)", wantErr: true},
+		{name: "other_rule", stmt: "@diagnostic(off, subgroup_uniformity)", warnings: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			src := tt.directive + strings.Replace(derivativeShader, "%s", tt.stmt, 1)
+			if tt.attr != "" {
+				src = strings.Replace(src, "@fragment", "@fragment "+tt.attr, 1)
+			}
+			result, err := lowerDiagnosticSource(t, src)
+			if tt.wantErr {
+				if err == nil || !strings.Contains(err.Error(), "derivative_uniformity") {
+					t.Fatalf("expected derivative_uniformity error, got %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := derivativeWarnings(result.Warnings); got != tt.warnings {
+				t.Errorf("derivative warnings: got %d, want %d: %v", got, tt.warnings, warningMessages(result.Warnings))
+			}
+		})
+	}
+}
+
+func TestDerivativeUniformityUniformFlow(t *testing.T) {
+	result, err := lowerDiagnosticSource(t, `
+@group(0) @binding(0) var t: texture_2d<f32>;
+@group(0) @binding(1) var s: sampler;
+@group(0) @binding(2) var<uniform> threshold: f32;
+
+@fragment
+fn main(@location(0) uv: vec2<f32>) -> @location(0) vec4<f32> {
+    if uv.x < 0.0 {
+        discard;
+    }
+    var color = vec4<f32>(0.0);
+    if threshold > 0.5 {
+        color = textureSample(t, s, uv);
+    }
+    if uv.y > 0.5 {
+        color += textureSampleLevel(t, s, uv, 0.0);
+    }
+    return color;
+}
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := derivativeWarnings(result.Warnings); got != 0 {
+		t.Errorf("derivative warnings: got %d, want 0: %v", got, warningMessages(result.Warnings))
+	}
+}
+
+func TestDiagnosticFilterTree(t *testing.T) {
+	result, err := lowerDiagnosticSource(t, `
+diagnostic(off, derivative_uniformity);
+
+fn thing() {}
+
+@diagnostic(warning, derivative_uniformity)
+fn with_diagnostic() {}
+
+@compute @workgroup_size(1)
+fn main() {
+    thing();
+    with_diagnostic();
+}
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := result.Module
+	if len(m.DiagnosticFilters) != 2 {
+		t.Fatalf("filters: got %+v, want 2", m.DiagnosticFilters)
+	}
+	if m.DiagnosticFilterLeaf == nil || *m.DiagnosticFilterLeaf != 0 {
+		t.Errorf("module leaf: got %v, want 0", m.DiagnosticFilterLeaf)
+	}
+	fnFilter := m.DiagnosticFilters[1]
+	if fnFilter.Inner.NewSeverity != ir.SeverityWarning || fnFilter.Parent == nil || *fnFilter.Parent != 0 {
+		t.Errorf("function filter: got %+v", fnFilter)
+	}
+	wantLeaf := map[string]ir.DiagnosticFilterHandle{"thing": 0, "with_diagnostic": 1}
+	for _, f := range m.Functions {
+		if f.DiagnosticFilterLeaf == nil || *f.DiagnosticFilterLeaf != wantLeaf[f.Name] {
+			t.Errorf("function %s leaf: got %v, want %d", f.Name, f.DiagnosticFilterLeaf, wantLeaf[f.Name])
+		}
+	}
+}
+
+func TestDiagnosticConflictsAndUnknownRules(t *testing.T) {
+	if _, err := lowerDiagnosticSource(t, `
+diagnostic(off, derivative_uniformity);
+diagnostic(error, derivative_uniformity);
+fn main() {}
+`); err == nil || !strings.Contains(err.Error(), "conflicting diagnostic filters") {
+		t.Errorf("expected conflict error, got %v", err)
+	}
+
+	result, err := lowerDiagnosticSource(t, `
+diagnostic(off, derivative_uniformity);
+diagnostic(off, derivative_uniformity);
+diagnostic(warning, no_such_rule);
+diagnostic(warning, vendor.rule);
+fn main() {}
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	msgs := warningMessages(result.Warnings)
+	if len(msgs) != 1 || msgs[0] != "unrecognized diagnostic rule 'no_such_rule'" {
+		t.Errorf("warnings: got %v", msgs)
+	}
+}
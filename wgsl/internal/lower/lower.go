@@ -1,9 +1,13 @@
 package lower
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"math"
 	"math/bits"
+	"slices"
+	"sort"
 	"strconv"
 	"strings"
 
@@ -40,6 +44,7 @@ type Lowerer struct {
 	functions       map[string]ir.FunctionHandle // Named function lookup (non-entry-point only)
 	entryPointFuncs map[string]bool              // Names of entry point functions
 	funcMustUse     map[string]bool              // Functions with @must_use attribute
+	calledFuncs     map[string]bool              // Functions some function or entry point calls
 
 	// Variable usage tracking for unused variable warnings
 	localDecls        map[string]parser.Span // Where each local variable was declared
@@ -48,6 +53,20 @@ type Lowerer struct {
 	localIsVar        map[string]bool        // Which locals are var declarations (not let/const)
 	localIsPtr        map[string]bool        // Which locals are pointer let-bindings (let p = &v[i])
 	localAbstractASTs map[string]parser.Expr // Abstract local const init ASTs (deferred to use site)
+	unusedDecls       []scopedDecl           // Unused variables whose scope has ended
+	expiredLocals     map[string]parser.Span // Declarations of names whose scope has ended
+
+	// Module-scope usage tracking for unused private global warnings
+	privateGlobals []*parser.VarDecl                    // var<private> declarations, in lowering order
+	structDecls    map[ir.TypeHandle]*parser.StructDecl // Struct declarations, for member attribute spans
+	usedGlobals    map[string]bool                      // Which module-scope variables have been referenced
+
+	// Diagnostic filtering for the derivative uniformity analysis.
+	// Statement-level @diagnostic filters are not stored in the IR; they
+	// are resolved here while the statement is lowered.
+	stmtDiagnostics []parser.Diagnostic                   // @diagnostic filters of enclosing statements, outermost first
+	stmtSpan        parser.Span                           // span of the statement being lowered
+	derivatives     map[ir.ExpressionHandle]derivativeUse // reportable derivative expressions of the current function
 
 	// Scope stack for lexical scoping of local variables.
 	// Each entry saves the previous binding for names shadowed in a block scope.
@@ -57,8 +76,9 @@ type Lowerer struct {
 	currentFunc    *ir.Function
 	currentFuncIdx ir.FunctionHandle
 	currentExprIdx ir.ExpressionHandle
-	isInsideLoop   bool // true when lowering statements inside a loop body
-	isStatement    bool // true when lowering an expression as a statement (ExprStmt)
+	exprSpan       ir.Span // span of the AST expression being lowered
+	isInsideLoop   bool    // true when lowering statements inside a loop body
+	isStatement    bool    // true when lowering an expression as a statement (ExprStmt)
 
 	// nonConstExprs tracks expression handles that are forced non-const.
 	// WGSL spec: "let" binding initializers are not const expressions.
@@ -102,6 +122,14 @@ type Lowerer struct {
 	// constsWithInlineInit tracks constants whose Init was set inline during lowering.
 	constsWithInlineInit map[ir.ConstantHandle]bool
 
+	// strict rejects the WGSL the lowerer otherwise accepts with a
+	// warning; see Options.Strict.
+	strict bool
+
+	// constantValues replaces the initializers of module constants; see
+	// Options.Constants.
+	constantValues ir.PipelineConstants
+
 	// Errors and warnings
 	errors   parser.SourceErrors
 	warnings []Warning
@@ -138,6 +166,39 @@ func LowerWithSource(ast *parser.Module, source string) (*ir.Module, error) {
 
 // LowerWithWarnings converts a WGSL AST module to Naga IR, returning warnings.
 func LowerWithWarnings(ast *parser.Module, source string) (*LowerResult, error) {
+	return LowerWithWarningsContext(context.Background(), ast, source)
+}
+
+// LowerWithWarningsContext is like LowerWithWarnings but stops with
+// ctx.Err() once ctx is canceled. Cancellation is checked between
+// declarations.
+func LowerWithWarningsContext(ctx context.Context, ast *parser.Module, source string) (*LowerResult, error) {
+	return LowerWithOptions(ctx, ast, source, Options{})
+}
+
+// Options configures lowering.
+type Options struct {
+	// Strict rejects source the WGSL spec does not allow but the lowerer
+	// otherwise accepts with a warning: unknown builtins (lowered as
+	// position), module-scope variables without an address space and
+	// unknown address spaces (lowered as function), and overrides with
+	// neither a type nor an initializer (lowered as f32).
+	Strict bool
+
+	// Constants replaces the initializers of the named module constants,
+	// which must be scalars, by the given values. Booleans use 1 and 0.
+	// Every const-expression reading a replaced constant is folded with
+	// its new value. Naming a constant the module does not declare is an
+	// error.
+	Constants ir.PipelineConstants
+}
+
+// LowerWithOptions is like LowerWithWarningsContext, configured by options.
+func LowerWithOptions(ctx context.Context, ast *parser.Module, source string, options Options) (*LowerResult, error) {
+	if err := checkConstantValues(ast, options.Constants); err != nil {
+		return nil, err
+	}
+
 	// Pre-size module-level slices based on AST declaration counts.
 	// This avoids repeated slice growth during lowering.
 	nFuncs := len(ast.Functions)
@@ -183,22 +244,31 @@ func LowerWithWarnings(ast *parser.Module, source string) (*LowerResult, error)
 		functions:         make(map[string]ir.FunctionHandle, nFuncs),
 		entryPointFuncs:   make(map[string]bool, 4),
 		funcMustUse:       make(map[string]bool, 4),
+		calledFuncs:       make(map[string]bool, nFuncs),
 		localDecls:        make(map[string]parser.Span, 16),
 		usedLocals:        make(map[string]bool, 16),
 		localConsts:       make(map[string]bool, 4),
 		localIsVar:        make(map[string]bool, 16),
 		localIsPtr:        make(map[string]bool, 4),
 		localAbstractASTs: make(map[string]parser.Expr, 4),
+		expiredLocals:     make(map[string]parser.Span, 4),
+		usedGlobals:       make(map[string]bool, max(nGlobals, 8)),
+		structDecls:       make(map[ir.TypeHandle]*parser.StructDecl, 8),
+		strict:            options.Strict,
+		constantValues:    options.Constants,
 	}
 
 	// Register built-in types
 	l.registerBuiltinTypes()
 
+	l.lowerDiagnosticDirectives(ast.Diagnostics)
+
 	// Dependency-ordered single-pass processing matching Rust naga's visit_ordered().
 	// Declarations are topologically sorted by their dependencies, then processed
 	// in a single pass. This ensures every declaration is lowered AFTER all
 	// declarations it references, producing identical type registration order.
-	sortedDecls := parser.DependencyOrder(ast.Declarations)
+	sortedDecls, cycles := parser.DependencyOrderCycles(l.dropRedefinitions(ast.Declarations))
+	cyclic := l.reportCycles(cycles)
 
 	// Pre-register function names to support forward references.
 	// Entry point functions are NOT added to Module.Functions[] — they are
@@ -233,37 +303,43 @@ func LowerWithWarnings(ast *parser.Module, source string) (*LowerResult, error)
 	processedFunctions := make(map[string]bool)
 
 	for _, decl := range sortedDecls {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		if cyclic[decl] {
+			continue
+		}
 		switch d := decl.(type) {
 		case *parser.AliasDecl:
 			if err := l.lowerAlias(d); err != nil {
-				l.addError(err.Error(), d.Span)
+				l.addDeclError(err, d.Span)
 			}
 		case *parser.StructDecl:
 			if err := l.lowerStruct(d); err != nil {
-				l.addError(err.Error(), d.Span)
+				l.addDeclError(err, d.Span)
 			}
 		case *parser.VarDecl:
 			if err := l.lowerGlobalVar(d); err != nil {
-				l.addError(err.Error(), d.Span)
+				l.addDeclError(err, d.Span)
 			}
 		case *parser.OverrideDecl:
 			if err := l.lowerOverride(d); err != nil {
-				l.addError(err.Error(), d.Span)
+				l.addDeclError(err, d.Span)
 			}
 		case *parser.ConstDecl:
 			if err := l.lowerConstant(d); err != nil {
-				l.addError(err.Error(), d.Span)
+				l.addDeclError(err, d.Span)
 			}
 		case *parser.FunctionDecl:
 			if err := l.lowerFunction(d); err != nil {
-				l.addError(err.Error(), d.Span)
+				l.addDeclError(err, d.Span)
 			}
 			processedFunctions[d.Name] = true
 		case *parser.ConstAssertDecl:
 			// Module-scope const_assert — evaluate and error if false.
 			// Matches Rust naga: ConstAssertFailed / NotBool.
 			if err := l.evalConstAssert(d.Condition); err != nil {
-				l.addError(err.Error(), d.Span)
+				l.addDeclError(err, d.Span)
 			}
 		}
 	}
@@ -272,8 +348,11 @@ func LowerWithWarnings(ast *parser.Module, source string) (*LowerResult, error)
 	// that build AST manually without populating Declarations).
 	for _, f := range ast.Functions {
 		if !processedFunctions[f.Name] {
+			if err := ctx.Err(); err != nil {
+				return nil, err
+			}
 			if err := l.lowerFunction(f); err != nil {
-				l.addError(err.Error(), f.Span)
+				l.addDeclError(err, f.Span)
 			}
 		}
 	}
@@ -282,6 +361,9 @@ func LowerWithWarnings(ast *parser.Module, source string) (*LowerResult, error)
 		return nil, &l.errors
 	}
 
+	l.checkUnusedGlobals()
+	l.checkUnusedFunctions(ast.Functions)
+
 	// Copy deduplicated types from registry to module
 	l.module.Types = l.registry.GetTypes()
 
@@ -323,17 +405,112 @@ func LowerWithWarnings(ast *parser.Module, source string) (*LowerResult, error)
 	// for all module-scope entities.
 	l.buildGlobalExpressions()
 
+	// Override-sized arrays take the length of the overrides' defaults
+	// until the pipeline's values are known.
+	ir.SetOverrideArrayDefaults(l.module)
+
 	return &LowerResult{
 		Module:   l.module,
 		Warnings: l.warnings,
 	}, nil
 }
 
+// dropRedefinitions reports each module-scope declaration whose name an
+// earlier declaration already has, and returns decls without them, so that
+// references resolve to the first declaration rather than the last.
+func (l *Lowerer) dropRedefinitions(decls []parser.Decl) []parser.Decl {
+	seen := make(map[string]parser.Span, len(decls))
+	kept := make([]parser.Decl, 0, len(decls))
+	for _, d := range decls {
+		name := parser.DeclName(d)
+		if prev, dup := seen[name]; dup {
+			l.addError(fmt.Sprintf("redefinition of '%s' (previously declared at %d:%d)",
+				name, prev.Start.Line, prev.Start.Column), d.Pos())
+			continue
+		}
+		if name != "" {
+			seen[name] = d.Pos()
+		}
+		kept = append(kept, d)
+	}
+	return kept
+}
+
+// reportCycles reports each cycle of module-scope declarations, which
+// WGSL forbids, at the declaration it starts from, and returns the
+// declarations on them other than functions: lowering one would only fail
+// again on the reference the cycle leaves unresolved, while functions
+// keep the handles assigned to them in dependency order. Cycles of
+// functions alone lower fine and are left to the validator, which
+// reports recursion.
+func (l *Lowerer) reportCycles(cycles [][]parser.Decl) map[parser.Decl]bool {
+	var cyclic map[parser.Decl]bool
+	for _, cycle := range cycles {
+		if !slices.ContainsFunc(cycle, func(d parser.Decl) bool {
+			_, isFunc := d.(*parser.FunctionDecl)
+			return !isFunc
+		}) {
+			continue
+		}
+		first := parser.DeclName(cycle[0])
+		if len(cycle) == 1 {
+			l.addError(fmt.Sprintf("declaration of '%s' is recursive", first), cycle[0].Pos())
+		} else {
+			names := make([]string, 0, len(cycle)+1)
+			for _, d := range cycle {
+				names = append(names, parser.DeclName(d))
+			}
+			names = append(names, first)
+			l.addError(fmt.Sprintf("declaration of '%s' is cyclic: %s", first, strings.Join(names, " -> ")), cycle[0].Pos())
+		}
+		if cyclic == nil {
+			cyclic = make(map[parser.Decl]bool)
+		}
+		for _, d := range cycle {
+			if _, isFunc := d.(*parser.FunctionDecl); !isFunc {
+				cyclic[d] = true
+			}
+		}
+	}
+	return cyclic
+}
+
 // addError adds an error with source location.
 func (l *Lowerer) addError(message string, span parser.Span) {
 	l.errors.Add(parser.NewSourceError(message, span, l.source))
 }
 
+// spannedError is an error about a specific piece of source, such as an
+// identifier or a declaration, rather than the whole module declaration
+// being lowered when it occurred.
+type spannedError struct {
+	span parser.Span
+	msg  string
+}
+
+func (e *spannedError) Error() string { return e.msg }
+
+// addDeclError adds an error raised while lowering the declaration at
+// span. It is reported at the span of a wrapped spannedError, if any.
+func (l *Lowerer) addDeclError(err error, span parser.Span) {
+	var se *spannedError
+	if errors.As(err, &se) && se.span.Start.Line > 0 {
+		span = se.span
+	}
+	l.addError(err.Error(), span)
+}
+
+// nonSpec reports source outside the WGSL spec that the lowerer accepts
+// as a convenience: in strict mode it returns an error at span, otherwise
+// it adds a warning and returns nil.
+func (l *Lowerer) nonSpec(message string, span parser.Span) error {
+	if l.strict {
+		return &spannedError{span: span, msg: message}
+	}
+	l.warnings = append(l.warnings, Warning{Message: message, Span: span})
+	return nil
+}
+
 // addGlobalExpr adds an expression to Module.GlobalExpressions and returns its handle.
 func (l *Lowerer) addGlobalExpr(kind ir.ExpressionKind) ir.ExpressionHandle {
 	h := ir.ExpressionHandle(len(l.module.GlobalExpressions))
@@ -706,13 +883,15 @@ func (l *Lowerer) lowerStruct(s *parser.StructDecl) error {
 		// Calculate proper alignment and size for WGSL uniform buffer layout
 		align, size := l.typeAlignmentAndSize(typeHandle)
 
-		// Check for explicit @align(N) attribute on the member
-		if explicitAlign := getAlignAttribute(m.Attributes); explicitAlign > 0 {
+		// Check for explicit @align(N) and @size(N) attributes on the member
+		explicitAlign, explicitSize, err := l.memberLayoutAttributes(m.Attributes, size)
+		if err != nil {
+			return fmt.Errorf("struct %s member %s: %w", s.Name, m.Name, err)
+		}
+		if explicitAlign > 0 {
 			align = explicitAlign
 		}
-
-		// Check for explicit @size(N) attribute on the member
-		if explicitSize := getSizeAttribute(m.Attributes); explicitSize > 0 {
+		if explicitSize > 0 {
 			size = explicitSize
 		}
 
@@ -733,40 +912,11 @@ func (l *Lowerer) lowerStruct(s *parser.StructDecl) error {
 	}
 	// Round struct size up to alignment of largest member
 	structSize := (offset + maxAlign - 1) &^ (maxAlign - 1)
-	l.registerNamedType(s.Name, ir.StructType{Members: members, Span: structSize})
+	handle := l.registerNamedType(s.Name, ir.StructType{Members: members, Span: structSize})
+	l.structDecls[handle] = s
 	return nil
 }
 
-// getAlignAttribute extracts the value from an @align(N) attribute, returns 0 if not found.
-func getAlignAttribute(attrs []parser.Attribute) uint32 {
-	for _, attr := range attrs {
-		if attr.Name == "align" && len(attr.Args) == 1 {
-			if lit, ok := attr.Args[0].(*parser.Literal); ok {
-				var val uint32
-				if _, err := fmt.Sscanf(lit.Value, "%d", &val); err == nil {
-					return val
-				}
-			}
-		}
-	}
-	return 0
-}
-
-// getSizeAttribute extracts the value from a @size(N) attribute, returns 0 if not found.
-func getSizeAttribute(attrs []parser.Attribute) uint32 {
-	for _, attr := range attrs {
-		if attr.Name == "size" && len(attr.Args) == 1 {
-			if lit, ok := attr.Args[0].(*parser.Literal); ok {
-				var val uint32
-				if _, err := fmt.Sscanf(lit.Value, "%d", &val); err == nil {
-					return val
-				}
-			}
-		}
-	}
-	return 0
-}
-
 // typeAlignmentAndSize returns the alignment and size of a type for uniform buffer layout.
 // Follows WGSL/WebGPU alignment rules (similar to std140 but with some differences).
 func (l *Lowerer) typeAlignmentAndSize(handle ir.TypeHandle) (align, size uint32) {
@@ -855,7 +1005,12 @@ func (l *Lowerer) typeAlignmentAndSize(handle ir.TypeHandle) (align, size uint32
 func (l *Lowerer) lowerGlobalVar(v *parser.VarDecl) error {
 	var typeHandle ir.TypeHandle
 	var err error
-	if v.Type != nil {
+	if arr, ok := v.Type.(*parser.ArrayType); ok && v.AddressSpace == "workgroup" && l.isOverrideSized(arr) {
+		typeHandle, err = l.resolveOverrideSizedArray(arr)
+		if err != nil {
+			return fmt.Errorf("global var %s: %w", v.Name, err)
+		}
+	} else if v.Type != nil {
 		typeHandle, err = l.resolveType(v.Type)
 		if err != nil {
 			return fmt.Errorf("global var %s: %w", v.Name, err)
@@ -870,7 +1025,7 @@ func (l *Lowerer) lowerGlobalVar(v *parser.VarDecl) error {
 		return fmt.Errorf("global var %s: type annotation required without initializer", v.Name)
 	}
 
-	space := l.addressSpace(v.AddressSpace)
+	space, known := l.addressSpace(v.AddressSpace)
 
 	// Samplers and textures must use SpaceHandle (maps to UniformConstant in SPIR-V)
 	// This is required by Vulkan: "Variables identified with the UniformConstant
@@ -878,6 +1033,28 @@ func (l *Lowerer) lowerGlobalVar(v *parser.VarDecl) error {
 	// Such variables must be typed as OpTypeImage, OpTypeSampler, OpTypeSampledImage"
 	if l.isOpaqueResourceType(typeHandle) {
 		space = ir.SpaceHandle
+	} else if !known {
+		message := fmt.Sprintf("unknown address space '%s' for global variable '%s'; treated as function", v.AddressSpace, v.Name)
+		if v.AddressSpace == "" {
+			message = fmt.Sprintf("global variable '%s' has no address space; treated as function", v.Name)
+		}
+		if err := l.nonSpec(message, v.Span); err != nil {
+			return err
+		}
+	}
+
+	// Bool has no host representation, and the backends disagree on its
+	// size in memory (HLSL stores 4 bytes, MSL and the IR layout 1), so a
+	// buffer holding one would not match the host's view of it. The
+	// variable is still declared so its uses do not report errors too.
+	switch space {
+	case ir.SpaceUniform, ir.SpaceStorage, ir.SpacePushConstant, ir.SpaceImmediate:
+		if path, found := ir.FindBool(l.module, typeHandle); found {
+			l.addError(fmt.Sprintf(
+				"var<%s> '%s' holds a bool at %s%s, which is not host-shareable; "+
+					"declare it u32 and convert with x != 0u when reading and select(0u, 1u, b) when writing",
+				v.AddressSpace, v.Name, v.Name, path), v.Span)
+		}
 	}
 
 	var binding *ir.ResourceBinding
@@ -885,26 +1062,24 @@ func (l *Lowerer) lowerGlobalVar(v *parser.VarDecl) error {
 	// Parse @group and @binding attributes
 	hasGroup := false
 	hasBinding := false
-	for _, attr := range v.Attributes {
-		if attr.Name == "group" && len(attr.Args) > 0 {
-			if lit, ok := attr.Args[0].(*parser.Literal); ok {
-				group, _ := strconv.ParseUint(lit.Value, 10, 32)
-				if binding == nil {
-					binding = &ir.ResourceBinding{}
-				}
-				binding.Group = uint32(group)
-				hasGroup = true
-			}
+	for i := range v.Attributes {
+		attr := &v.Attributes[i]
+		if attr.Name != "group" && attr.Name != "binding" {
+			continue
 		}
-		if attr.Name == "binding" && len(attr.Args) > 0 {
-			if lit, ok := attr.Args[0].(*parser.Literal); ok {
-				bind, _ := strconv.ParseUint(lit.Value, 10, 32)
-				if binding == nil {
-					binding = &ir.ResourceBinding{}
-				}
-				binding.Binding = uint32(bind)
-				hasBinding = true
-			}
+		val, err := l.evalAttributeArg(attr, 0)
+		if err != nil {
+			return fmt.Errorf("global var '%s': %w", v.Name, err)
+		}
+		if binding == nil {
+			binding = &ir.ResourceBinding{}
+		}
+		if attr.Name == "group" {
+			binding.Group = val
+			hasGroup = true
+		} else {
+			binding.Binding = val
+			hasBinding = true
 		}
 	}
 
@@ -977,6 +1152,14 @@ func (l *Lowerer) lowerGlobalVar(v *parser.VarDecl) error {
 							l.globalVarInitASTs = make(map[ir.GlobalVariableHandle]parser.Expr)
 						}
 						l.globalVarInitASTs[gvHandle] = v.Init
+					default:
+						// Constant references and other constant expressions
+						// (var<private> k = K; var<private> x = v.xy;).
+						h, evalErr := l.lowerEvaluatedGlobalInit(typeHandle, v.Init)
+						if evalErr != nil {
+							return fmt.Errorf("global var '%s': %w", v.Name, evalErr)
+						}
+						initExpr = &h
 					}
 				}
 			}
@@ -995,6 +1178,9 @@ func (l *Lowerer) lowerGlobalVar(v *parser.VarDecl) error {
 		Access:   accessMode,
 	})
 	l.globals[v.Name] = handle
+	if space == ir.SpacePrivate {
+		l.privateGlobals = append(l.privateGlobals, v)
+	}
 	return nil
 }
 
@@ -1063,6 +1249,10 @@ func (l *Lowerer) inferGlobalVarType(init parser.Expr) (ir.TypeHandle, error) {
 			if h, ok := l.types[e.Func.Name]; ok {
 				return h, nil
 			}
+			// Predeclared short alias constructor (vec4u(1u), mat2x2f(...))
+			if _, ok := lookupShortTypeAlias(e.Func.Name); ok {
+				return l.resolveType(&parser.NamedType{Name: e.Func.Name})
+			}
 			return 0, fmt.Errorf("unsupported call type: %s", e.Func.Name)
 		}
 	case *parser.Literal:
@@ -1070,14 +1260,22 @@ func (l *Lowerer) inferGlobalVarType(init parser.Expr) (ir.TypeHandle, error) {
 		if err != nil {
 			return 0, err
 		}
+		if kind == ir.ScalarBool {
+			return l.registerType("", ir.ScalarType{Kind: kind, Width: 1}), nil
+		}
 		return l.registerType("", ir.ScalarType{Kind: kind, Width: 4}), nil
 	case *parser.UnaryExpr:
 		if e.Op == parser.TokenMinus {
 			return l.inferGlobalVarType(e.Operand)
 		}
 		return 0, fmt.Errorf("unsupported unary op for type inference")
+	case *parser.Ident:
+		if h, ok := l.moduleConstants[e.Name]; ok {
+			return l.module.Constants[h].Type, nil
+		}
+		return l.inferEvaluatedConstType(init)
 	default:
-		return 0, fmt.Errorf("unsupported type: %v", init)
+		return l.inferEvaluatedConstType(init)
 	}
 }
 
@@ -1100,20 +1298,28 @@ func (l *Lowerer) lowerOverride(o *parser.OverrideDecl) error {
 		typeHandle = l.inferOverrideType(o.Init)
 	} else {
 		// No type, no init — this is an error in WGSL, but we default to f32.
+		if err := l.nonSpec(fmt.Sprintf("override '%s' has neither a type nor an initializer; treated as f32", o.Name), o.Span); err != nil {
+			return err
+		}
 		typeHandle = l.registerType("f32", ir.ScalarType{Kind: ir.ScalarFloat, Width: 4})
 	}
 
 	// Parse @id attribute.
 	var id *uint16
-	for _, attr := range o.Attributes {
-		if attr.Name == "id" && len(attr.Args) > 0 {
-			if lit, ok := attr.Args[0].(*parser.Literal); ok {
-				if idVal, parseErr := strconv.ParseUint(lit.Value, 10, 16); parseErr == nil {
-					id16 := uint16(idVal)
-					id = &id16
-				}
-			}
+	for i := range o.Attributes {
+		attr := &o.Attributes[i]
+		if attr.Name != "id" {
+			continue
+		}
+		idVal, err := l.evalAttributeArg(attr, 0)
+		if err != nil {
+			return fmt.Errorf("override '%s': %w", o.Name, err)
+		}
+		if idVal > 0xFFFF {
+			return fmt.Errorf("override '%s': @id must be less than 65536, got %d", o.Name, idVal)
 		}
+		id16 := uint16(idVal)
+		id = &id16
 	}
 
 	// Create the Override (init will be set later in buildGlobalExpressions).
@@ -1145,54 +1351,97 @@ func (l *Lowerer) lowerOverride(o *parser.OverrideDecl) error {
 // inferOverrideType infers the concrete type for an override from its init expression.
 // Overrides are always concrete (never abstract).
 func (l *Lowerer) inferOverrideType(init parser.Expr) ir.TypeHandle {
-	switch e := init.(type) {
+	handle, _ := l.overrideOperandType(init)
+	return handle
+}
+
+// overrideOperandType returns the type of an override initializer
+// expression and whether it is concrete. An abstract expression, such as
+// an unsuffixed literal, gets the type it concretizes to, so in
+// `count * 2` the concrete count decides the type of the product.
+func (l *Lowerer) overrideOperandType(expr parser.Expr) (ir.TypeHandle, bool) {
+	f32 := func() ir.TypeHandle { return l.registerType("f32", ir.ScalarType{Kind: ir.ScalarFloat, Width: 4}) }
+	i32 := func() ir.TypeHandle { return l.registerType("i32", ir.ScalarType{Kind: ir.ScalarSint, Width: 4}) }
+	u32 := func() ir.TypeHandle { return l.registerType("u32", ir.ScalarType{Kind: ir.ScalarUint, Width: 4}) }
+	boolType := func() ir.TypeHandle { return l.registerType("bool", ir.ScalarType{Kind: ir.ScalarBool, Width: 1}) }
+
+	switch e := expr.(type) {
 	case *parser.Literal:
 		switch e.Kind {
 		case parser.TokenFloatLiteral:
-			return l.registerType("f32", ir.ScalarType{Kind: ir.ScalarFloat, Width: 4})
+			if strings.HasSuffix(e.Value, "h") {
+				return l.registerType("f16", ir.ScalarType{Kind: ir.ScalarFloat, Width: 2}), true
+			}
+			return f32(), strings.HasSuffix(e.Value, "f")
 		case parser.TokenIntLiteral:
 			// Check for suffix
 			if len(e.Value) > 0 {
 				last := e.Value[len(e.Value)-1]
 				switch last {
 				case 'u':
-					return l.registerType("u32", ir.ScalarType{Kind: ir.ScalarUint, Width: 4})
+					return u32(), true
 				case 'i':
-					return l.registerType("i32", ir.ScalarType{Kind: ir.ScalarSint, Width: 4})
+					return i32(), true
 				}
 			}
 			// Unsuffixed integer literal in override context => i32
-			return l.registerType("i32", ir.ScalarType{Kind: ir.ScalarSint, Width: 4})
-		case parser.TokenTrue, parser.TokenFalse:
-			return l.registerType("bool", ir.ScalarType{Kind: ir.ScalarBool, Width: 1})
+			return i32(), false
+		case parser.TokenTrue, parser.TokenFalse, parser.TokenBoolLiteral:
+			return boolType(), true
 		}
 	case *parser.Ident:
 		// Reference to another override — inherit its type.
 		if oh, ok := l.moduleOverrides[e.Name]; ok {
 			if int(oh) < len(l.module.Overrides) {
-				return l.module.Overrides[oh].Ty
+				return l.module.Overrides[oh].Ty, true
 			}
 		}
 		// Reference to an abstract constant — infer concrete type.
 		if info, ok := l.abstractConstants[e.Name]; ok && info.scalarValue != nil {
 			switch info.scalarValue.Kind {
 			case ir.ScalarSint:
-				return l.registerType("", ir.ScalarType{Kind: ir.ScalarSint, Width: 4})
+				return l.registerType("", ir.ScalarType{Kind: ir.ScalarSint, Width: 4}), false
 			case ir.ScalarUint:
-				return l.registerType("", ir.ScalarType{Kind: ir.ScalarUint, Width: 4})
+				return l.registerType("", ir.ScalarType{Kind: ir.ScalarUint, Width: 4}), false
 			case ir.ScalarFloat:
-				return l.registerType("", ir.ScalarType{Kind: ir.ScalarFloat, Width: 4})
+				return l.registerType("", ir.ScalarType{Kind: ir.ScalarFloat, Width: 4}), false
 			}
 		}
 		// Reference to a constant — inherit its type.
 		if ch, ok := l.moduleConstants[e.Name]; ok {
 			if int(ch) < len(l.module.Constants) {
-				return l.module.Constants[ch].Type
+				return l.module.Constants[ch].Type, true
+			}
+		}
+	case *parser.UnaryExpr:
+		if e.Op == parser.TokenBang {
+			return boolType(), true
+		}
+		return l.overrideOperandType(e.Operand)
+	case *parser.BinaryExpr:
+		switch e.Op {
+		case parser.TokenEqualEqual, parser.TokenBangEqual, parser.TokenLess, parser.TokenLessEqual,
+			parser.TokenGreater, parser.TokenGreaterEqual, parser.TokenAmpAmp, parser.TokenPipePipe:
+			return boolType(), true
+		}
+		left, leftConcrete := l.overrideOperandType(e.Left)
+		if leftConcrete {
+			return left, true
+		}
+		right, rightConcrete := l.overrideOperandType(e.Right)
+		if rightConcrete {
+			return right, true
+		}
+		// Both sides abstract: a float operand makes the result float.
+		if t, ok := l.registry.Lookup(right); ok {
+			if scalar, ok := t.Inner.(ir.ScalarType); ok && scalar.Kind == ir.ScalarFloat {
+				return right, false
 			}
 		}
+		return left, false
 	}
 	// Default to f32 for override expressions.
-	return l.registerType("f32", ir.ScalarType{Kind: ir.ScalarFloat, Width: 4})
+	return f32(), false
 }
 
 // buildOverrideInitExpr builds a simplified AST for override init re-evaluation.
@@ -1240,7 +1489,7 @@ func (l *Lowerer) buildOverrideInitExpr(expr parser.Expr) ir.OverrideInitExpr {
 		if left == nil || right == nil {
 			return nil
 		}
-		op, ok := binaryOpTable[e.Op]
+		op, ok := binaryOperator(e.Op)
 		if !ok {
 			return nil
 		}
@@ -1380,10 +1629,77 @@ func convertScalarBits(srcKind ir.ScalarKind, bits uint64, target ir.ScalarType)
 }
 
 // lowerConstant converts a constant declaration to IR.
+//
+// Initializers the specialized paths below reject (composite arithmetic,
+// swizzles, indexing) are retried with the general constant evaluator;
+// if that fails too, the original error is reported, unless the evaluator
+// found the value overflows.
 func (l *Lowerer) lowerConstant(c *parser.ConstDecl) error {
 	if c.Init == nil {
 		return fmt.Errorf("module constant '%s' must have initializer", c.Name)
 	}
+	if name := l.overrideReadBy(c.Init); name != "" {
+		return constReadsOverrideError(c, name)
+	}
+	c, err := l.substituteConstantValue(c)
+	if err != nil {
+		return err
+	}
+	constsBefore, exprsBefore := len(l.module.Constants), len(l.module.GlobalExpressions)
+	if err := l.lowerConstantInit(c); err != nil {
+		l.discardConstantsFrom(constsBefore, exprsBefore)
+		if evalErr := l.lowerEvaluatedConstant(c); evalErr != nil {
+			l.discardConstantsFrom(constsBefore, exprsBefore)
+			var overflow *constOverflowError
+			if errors.As(evalErr, &overflow) {
+				return evalErr
+			}
+			return err
+		}
+	} else if err := l.checkConstOverflow(c); err != nil {
+		l.discardConstantsFrom(constsBefore, exprsBefore)
+		return err
+	}
+	return nil
+}
+
+// checkConstOverflow reports a constant whose initializer overflows its
+// type. The specialized paths compute in wrapping or 64-bit arithmetic,
+// so their result is checked against the general evaluator, which runs
+// after them so as not to register types out of source order.
+func (l *Lowerer) checkConstOverflow(c *parser.ConstDecl) error {
+	v, err := l.evalConstValue(c.Init)
+	if err == nil && c.Type != nil {
+		if th, typeErr := l.resolveType(c.Type); typeErr == nil {
+			_, err = l.convertConstValue(v, l.module.Types[th].Inner, false)
+		}
+	}
+	var overflow *constOverflowError
+	if errors.As(err, &overflow) {
+		return fmt.Errorf("module constant '%s': %w", c.Name, err)
+	}
+	return nil
+}
+
+// discardConstantsFrom drops constants and global expressions added by a
+// failed attempt to lower a constant.
+func (l *Lowerer) discardConstantsFrom(constsBefore, exprsBefore int) {
+	for name, h := range l.moduleConstants {
+		if int(h) >= constsBefore {
+			delete(l.moduleConstants, name)
+		}
+	}
+	for h := range l.constsWithInlineInit {
+		if int(h) >= constsBefore {
+			delete(l.constsWithInlineInit, h)
+		}
+	}
+	l.module.Constants = l.module.Constants[:constsBefore]
+	l.module.GlobalExpressions = l.module.GlobalExpressions[:exprsBefore]
+}
+
+// lowerConstantInit lowers a constant through the initializer-specific paths.
+func (l *Lowerer) lowerConstantInit(c *parser.ConstDecl) error {
 
 	// Track whether this constant has abstract type in Rust naga.
 	// In Rust naga, constants whose type is abstract (e.g., `const ONE = 1;`)
@@ -1419,7 +1735,8 @@ func (l *Lowerer) lowerConstant(c *parser.ConstDecl) error {
 	case *parser.UnaryExpr:
 		err = l.lowerConstantUnaryExpr(c.Name, c.Type, init)
 	default:
-		return fmt.Errorf("module constant '%s': unsupported initializer %T", c.Name, c.Init)
+		// Swizzles, member access, indexing
+		return l.lowerEvaluatedConstant(c)
 	}
 	if err != nil {
 		return err
@@ -1488,7 +1805,7 @@ func (l *Lowerer) lowerAbstractConstant(c *parser.ConstDecl) error {
 			return fmt.Errorf("abstract constant '%s': unknown reference '%s'", c.Name, init.Name)
 		}
 	default:
-		return fmt.Errorf("abstract constant '%s': unsupported initializer %T", c.Name, c.Init)
+		return l.lowerEvaluatedConstant(c)
 	}
 	return nil
 }
@@ -1845,234 +2162,8 @@ func (l *Lowerer) lowerConstantBinaryExpr(name string, typ parser.Type, expr *pa
 		return nil
 	}
 
-	// Try vector binary operation: vec2(1.0) + vec2(3.0, 4.0)
-	if vecErr := l.lowerConstantVectorBinaryExpr(name, typ, expr); vecErr == nil {
-		return nil
-	}
-
-	return fmt.Errorf("module constant '%s': %w", name, intErr)
-}
-
-// lowerConstantVectorBinaryExpr handles binary operations on vector constants at module scope.
-// Evaluates: vec2(1.0) + vec2(3.0, 4.0), vec2(3.0) == vec2(3.0, 4.0), etc.
-func (l *Lowerer) lowerConstantVectorBinaryExpr(name string, typ parser.Type, expr *parser.BinaryExpr) error {
-	// Evaluate left and right as anonymous vector constants (no name = won't be emitted)
-	leftHandle, leftErr := l.evalAsVectorConstantHandle(expr.Left)
-	if leftErr != nil {
-		return leftErr
-	}
-	leftConst := l.module.Constants[leftHandle]
-
-	rightHandle, rightErr := l.evalAsVectorConstantHandle(expr.Right)
-	if rightErr != nil {
-		return rightErr
-	}
-	rightConst := l.module.Constants[rightHandle]
-	_ = rightHandle
-
-	// Both must be composites of the same vector type
-	leftComps, leftOK := leftConst.Value.(ir.CompositeValue)
-	rightComps, rightOK := rightConst.Value.(ir.CompositeValue)
-	if !leftOK || !rightOK {
-		return fmt.Errorf("vector binary operands must be composite constants")
-	}
-
-	leftInner := l.module.Types[leftConst.Type].Inner
-	leftVec, leftIsVec := leftInner.(ir.VectorType)
-	if !leftIsVec {
-		return fmt.Errorf("left operand is not a vector type")
-	}
-
-	numComponents := int(leftVec.Size)
-
-	// Handle splat: vec2(1.0) creates a composite with 1 component for a vec2.
-	// Expand to numComponents by replicating the single component.
-	leftComps = l.expandSplatComponents(leftComps, numComponents)
-	rightComps = l.expandSplatComponents(rightComps, numComponents)
-
-	if len(leftComps.Components) != numComponents || len(rightComps.Components) != numComponents {
-		return fmt.Errorf("vector component count mismatch: left=%d, right=%d, expected=%d",
-			len(leftComps.Components), len(rightComps.Components), numComponents)
-	}
-
-	// Determine if this is a comparison (returns vec<bool>) or arithmetic (returns vec<T>)
-	isComparison := expr.Op == parser.TokenEqualEqual || expr.Op == parser.TokenBangEqual ||
-		expr.Op == parser.TokenLess || expr.Op == parser.TokenLessEqual ||
-		expr.Op == parser.TokenGreater || expr.Op == parser.TokenGreaterEqual
-
-	// Evaluate component-wise and create GE directly (matching Rust naga).
-	geComponents := make([]ir.ExpressionHandle, numComponents)
-	for i := 0; i < numComponents; i++ {
-		leftSV, lOK := l.module.Constants[leftComps.Components[i]].Value.(ir.ScalarValue)
-		rightSV, rOK := l.module.Constants[rightComps.Components[i]].Value.(ir.ScalarValue)
-		if !lOK || !rOK {
-			return fmt.Errorf("vector component %d is not scalar", i)
-		}
-
-		var resultBits uint64
-		var resultKind ir.ScalarKind
-
-		if isComparison {
-			resultKind = ir.ScalarBool
-			cmp := l.evalScalarComparison(expr.Op, leftSV, rightSV)
-			if cmp {
-				resultBits = 1
-			}
-		} else {
-			if leftSV.Kind == ir.ScalarFloat || rightSV.Kind == ir.ScalarFloat {
-				resultKind = ir.ScalarFloat
-			} else {
-				resultKind = leftSV.Kind
-			}
-			resultBits = l.evalScalarArithmetic(expr.Op, leftSV, rightSV)
-		}
-
-		lit := scalarValueToLiteral(ir.ScalarValue{Bits: resultBits, Kind: resultKind})
-		if lit == nil {
-			return fmt.Errorf("cannot create literal for component %d", i)
-		}
-		geComponents[i] = l.addGlobalExpr(ir.Literal{Value: lit})
-	}
-
-	// Create result vector type
-	var resultTypeHandle ir.TypeHandle
-	if isComparison {
-		resultTypeHandle = l.registerType("", ir.VectorType{
-			Size:   leftVec.Size,
-			Scalar: ir.ScalarType{Kind: ir.ScalarBool, Width: 1},
-		})
-	} else if typ != nil {
-		var err error
-		resultTypeHandle, err = l.resolveType(typ)
-		if err != nil {
-			return err
-		}
-	} else {
-		resultTypeHandle = leftConst.Type
-	}
-
-	initHandle := l.addGlobalExpr(ir.ExprCompose{Type: resultTypeHandle, Components: geComponents})
-	constHandle := ir.ConstantHandle(len(l.module.Constants))
-	l.module.Constants = append(l.module.Constants, ir.Constant{
-		Name: name,
-		Type: resultTypeHandle,
-		Init: initHandle,
-	})
-	l.moduleConstants[name] = constHandle
-	l.markConstInlineInit(constHandle)
-	return nil
-}
-
-// expandSplatComponents expands a single-component composite to fill the expected size.
-// This handles vec2(1.0) where the composite has 1 component but the vector needs 2.
-func (l *Lowerer) expandSplatComponents(cv ir.CompositeValue, expectedSize int) ir.CompositeValue {
-	if len(cv.Components) == 1 && expectedSize > 1 {
-		expanded := make([]ir.ConstantHandle, expectedSize)
-		for i := range expanded {
-			expanded[i] = cv.Components[0]
-		}
-		return ir.CompositeValue{Components: expanded}
-	}
-	return cv
-}
-
-// evalAsVectorConstantHandle evaluates an expression as an anonymous vector constant
-// and returns its handle. The constant is unnamed (won't be emitted by the backend).
-func (l *Lowerer) evalAsVectorConstantHandle(expr parser.Expr) (ir.ConstantHandle, error) {
-	switch e := expr.(type) {
-	case *parser.ConstructExpr:
-		if err := l.lowerCompositeConstant("", nil, e, false); err != nil {
-			return 0, err
-		}
-		return ir.ConstantHandle(len(l.module.Constants) - 1), nil
-	case *parser.CallExpr:
-		if err := l.lowerCallConstant("", nil, e); err != nil {
-			return 0, err
-		}
-		return ir.ConstantHandle(len(l.module.Constants) - 1), nil
-	default:
-		return 0, fmt.Errorf("unsupported vector constant operand: %T", expr)
-	}
-}
-
-// evalScalarComparison evaluates a comparison operation on two scalar values.
-func (l *Lowerer) evalScalarComparison(op parser.TokenKind, left, right ir.ScalarValue) bool {
-	if left.Kind == ir.ScalarFloat {
-		lf := math.Float32frombits(uint32(left.Bits))
-		rf := math.Float32frombits(uint32(right.Bits))
-		switch op {
-		case parser.TokenEqualEqual:
-			return lf == rf
-		case parser.TokenBangEqual:
-			return lf != rf
-		case parser.TokenLess:
-			return lf < rf
-		case parser.TokenLessEqual:
-			return lf <= rf
-		case parser.TokenGreater:
-			return lf > rf
-		case parser.TokenGreaterEqual:
-			return lf >= rf
-		}
-	}
-	// Integer comparison
-	switch op {
-	case parser.TokenEqualEqual:
-		return left.Bits == right.Bits
-	case parser.TokenBangEqual:
-		return left.Bits != right.Bits
-	default:
-		return false
-	}
-}
-
-// evalScalarArithmetic evaluates an arithmetic operation on two scalar values.
-func (l *Lowerer) evalScalarArithmetic(op parser.TokenKind, left, right ir.ScalarValue) uint64 {
-	// Mixed int+float: promote integer to float before arithmetic.
-	// This handles AbstractInt + AbstractFloat (e.g., vec2(1,1) + vec2(1.0,1.0)).
-	if left.Kind != right.Kind {
-		if left.Kind == ir.ScalarFloat && (right.Kind == ir.ScalarSint || right.Kind == ir.ScalarUint) {
-			right = ir.ScalarValue{Bits: uint64(math.Float32bits(float32(int64(right.Bits)))), Kind: ir.ScalarFloat}
-		} else if right.Kind == ir.ScalarFloat && (left.Kind == ir.ScalarSint || left.Kind == ir.ScalarUint) {
-			left = ir.ScalarValue{Bits: uint64(math.Float32bits(float32(int64(left.Bits)))), Kind: ir.ScalarFloat}
-		}
-	}
-	if left.Kind == ir.ScalarFloat {
-		lf := float64(math.Float32frombits(uint32(left.Bits)))
-		rf := float64(math.Float32frombits(uint32(right.Bits)))
-		var result float64
-		switch op {
-		case parser.TokenPlus:
-			result = lf + rf
-		case parser.TokenMinus:
-			result = lf - rf
-		case parser.TokenStar:
-			result = lf * rf
-		case parser.TokenSlash:
-			if rf != 0 {
-				result = lf / rf
-			}
-		default:
-			result = lf
-		}
-		return uint64(math.Float32bits(float32(result)))
-	}
-	// Integer arithmetic
-	switch op {
-	case parser.TokenPlus:
-		return left.Bits + right.Bits
-	case parser.TokenMinus:
-		return left.Bits - right.Bits
-	case parser.TokenStar:
-		return left.Bits * right.Bits
-	case parser.TokenSlash:
-		if right.Bits != 0 {
-			return left.Bits / right.Bits
-		}
-		return 0
-	default:
-		return left.Bits
-	}
+	// Composite operands: vec2(1.0) + vec2(3.0, 4.0), v * 2.0, a == b
+	return l.lowerEvaluatedConstant(&parser.ConstDecl{Name: name, Type: typ, Init: expr})
 }
 
 // float32ToHalf converts a float32 value to IEEE 754 half-precision (16-bit) bits
@@ -3426,6 +3517,9 @@ func (l *Lowerer) evalLiteral(lit *parser.Literal) (ir.ScalarKind, uint64, error
 			isUnsigned = true
 		} else if len(text) > 0 && text[len(text)-1] == 'i' {
 			text = text[:len(text)-1]
+		} else {
+			// Abstract integers are 64-bit; materializing one checks its range.
+			is64bit = true
 		}
 		if isUnsigned {
 			bitSize := 32
@@ -3643,7 +3737,11 @@ func (l *Lowerer) lowerFunction(f *parser.FunctionDecl) error {
 	for k := range l.localAbstractASTs {
 		delete(l.localAbstractASTs, k)
 	}
+	clear(l.expiredLocals)
+	l.unusedDecls = l.unusedDecls[:0]
 	l.scopeStack = l.scopeStack[:0]
+	l.stmtDiagnostics = l.stmtDiagnostics[:0]
+	clear(l.derivatives)
 	// Reset per-function GlobalVariable expression cache.
 	// Each function gets its own expression arena, so cached handles from
 	// previous functions are invalid.
@@ -3679,6 +3777,11 @@ func (l *Lowerer) lowerFunction(f *parser.FunctionDecl) error {
 		NamedExpressions: make(map[ir.ExpressionHandle]string, nParams+4),
 	}
 	l.currentFunc = fn
+	leaf, err := l.lowerFunctionDiagnostics(f.Attributes)
+	if err != nil {
+		return fmt.Errorf("function %s: %w", f.Name, err)
+	}
+	fn.DiagnosticFilterLeaf = leaf
 	// Reuse nonConstExprs map — clear instead of reallocating.
 	if l.nonConstExprs == nil {
 		l.nonConstExprs = make(map[ir.ExpressionHandle]bool, 8)
@@ -3688,6 +3791,17 @@ func (l *Lowerer) lowerFunction(f *parser.FunctionDecl) error {
 		}
 	}
 
+	// Parameters are declared in the function scope and the body opens a
+	// scope of its own, so body declarations may shadow parameters, as in
+	// Rust naga. Every scope is unwound on return, leaving no function-scope
+	// bindings behind for later module-scope declarations.
+	l.pushScope()
+	defer func() {
+		for len(l.scopeStack) > 0 {
+			l.popScope()
+		}
+	}()
+
 	// Lower parameters
 	for i, p := range f.Params {
 		typeHandle, err := l.resolveType(p.Type)
@@ -3709,6 +3823,9 @@ func (l *Lowerer) lowerFunction(f *parser.FunctionDecl) error {
 		exprHandle := l.addExpression(ir.Expression{
 			Kind: ir.ExprFunctionArgument{Index: uint32(i)},
 		})
+		if err := l.scopeSet(p.Name, p.Span); err != nil {
+			return fmt.Errorf("function %s: %w", f.Name, err)
+		}
 		l.locals[p.Name] = exprHandle
 		// Rust naga adds function arguments to named_expressions
 		fn.NamedExpressions[exprHandle] = p.Name
@@ -3731,6 +3848,7 @@ func (l *Lowerer) lowerFunction(f *parser.FunctionDecl) error {
 	}
 
 	// Lower function body
+	l.pushScope()
 	if f.Body != nil {
 		if err := l.lowerBlock(f.Body, &fn.Body); err != nil {
 			return fmt.Errorf("function %s body: %w", f.Name, err)
@@ -3741,16 +3859,23 @@ func (l *Lowerer) lowerFunction(f *parser.FunctionDecl) error {
 	// This ensures every control flow path ends with a Return statement.
 	ensureBlockReturns(&fn.Body)
 
-	// Check for unused local variables
-	l.checkUnusedVariables(f.Name)
-
 	// Register unused let bindings in NamedExpressions so backends emit them.
 	// Used let bindings are already emitted through the normal baking mechanism.
 	l.registerUnusedLetBindings()
 
-	// Check if this is an entry point
-	stage := l.entryPointStage(f.Attributes)
+	// End the body scope, then check for unused local variables and
+	// parameters: a parameter is only seen again once the body's
+	// declarations shadowing it are gone.
+	l.popScope()
+	l.checkUnusedVariables(f.Name)
+	l.checkUnusedParameters(f)
+
+	// Check if this is an entry point
+	stage := l.entryPointStage(f.Attributes)
 	if stage != nil {
+		if err := l.checkEntryPointBuiltins(f, fn, *stage); err != nil {
+			return fmt.Errorf("entry point '%s': %w", f.Name, err)
+		}
 		// Entry point functions are stored inline in EntryPoint.Function,
 		// NOT in Module.Functions[] (matching Rust naga).
 		ep := ir.EntryPoint{
@@ -3772,11 +3897,16 @@ func (l *Lowerer) lowerFunction(f *parser.FunctionDecl) error {
 			if !hasWGSize {
 				return fmt.Errorf("@compute entry point '%s' is missing @workgroup_size attribute", f.Name)
 			}
-			ep.Workgroup = l.extractWorkgroupSize(f.Attributes)
+			var err error
+			ep.Workgroup, ep.WorkgroupOverrides, err = l.extractWorkgroupSize(f.Attributes)
+			if err != nil {
+				return fmt.Errorf("entry point '%s': %w", f.Name, err)
+			}
 		}
 		// Extract early_depth_test for fragment shaders
 		if *stage == ir.StageFragment {
 			ep.EarlyDepthTest = l.extractEarlyDepthTest(f.Attributes)
+			l.checkDerivativeUniformity(fn)
 		}
 		// Extract task_payload from @payload(varName) attribute
 		ep.TaskPayload = l.extractTaskPayload(f.Attributes)
@@ -3795,14 +3925,20 @@ func (l *Lowerer) lowerFunction(f *parser.FunctionDecl) error {
 	return nil
 }
 
-// scopeEntry records the previous binding for a name that was shadowed.
+// scopeEntry records the binding a name had before it was declared in a
+// scope, so popScope can restore it.
 type scopeEntry struct {
-	name     string
-	hadLocal bool                // was there a previous binding in l.locals?
-	prevExpr ir.ExpressionHandle // previous l.locals[name] (if hadLocal)
-	hadConst bool                // was there a previous l.localConsts[name]?
-	hadVar   bool                // was there a previous l.localIsVar[name]?
-	hadPtr   bool                // was there a previous l.localIsPtr[name]?
+	name         string
+	span         parser.Span         // declaration in this scope
+	hadLocal     bool                // was there a previous binding in l.locals?
+	prevExpr     ir.ExpressionHandle // previous l.locals[name] (if hadLocal)
+	prevConst    bool                // previous l.localConsts[name]
+	prevVar      bool                // previous l.localIsVar[name]
+	prevPtr      bool                // previous l.localIsPtr[name]
+	prevAbstract parser.Expr         // previous l.localAbstractASTs[name]
+	hadDecl      bool                // was there a previous l.localDecls[name]?
+	prevDecl     parser.Span         // previous l.localDecls[name] (if hadDecl)
+	prevUsed     bool                // previous l.usedLocals[name]
 }
 
 // scopeFrame represents one lexical scope level.
@@ -3810,12 +3946,20 @@ type scopeFrame struct {
 	entries []scopeEntry
 }
 
+// scopedDecl is a variable declaration that went out of scope.
+type scopedDecl struct {
+	name string
+	span parser.Span
+}
+
 // pushScope starts a new lexical scope. Call popScope when leaving.
 func (l *Lowerer) pushScope() {
 	l.scopeStack = append(l.scopeStack, scopeFrame{})
 }
 
-// popScope restores variable bindings to their state before pushScope.
+// popScope ends the innermost scope: variables declared in it that were
+// never used are queued for unused variable warnings, and every name it
+// declared gets back the binding it had before.
 func (l *Lowerer) popScope() {
 	if len(l.scopeStack) == 0 {
 		return
@@ -3824,65 +3968,149 @@ func (l *Lowerer) popScope() {
 	l.scopeStack = l.scopeStack[:len(l.scopeStack)-1]
 
 	for _, e := range frame.entries {
+		if span, ok := l.localDecls[e.name]; ok && !l.usedLocals[e.name] {
+			l.unusedDecls = append(l.unusedDecls, scopedDecl{name: e.name, span: span})
+		}
+		l.clearLocal(e.name)
 		if e.hadLocal {
 			l.locals[e.name] = e.prevExpr
 		} else {
-			delete(l.locals, e.name)
+			l.expiredLocals[e.name] = e.span
 		}
-		if !e.hadConst {
-			delete(l.localConsts, e.name)
+		if e.prevConst {
+			l.localConsts[e.name] = true
 		}
-		if !e.hadVar {
-			delete(l.localIsVar, e.name)
+		if e.prevVar {
+			l.localIsVar[e.name] = true
 		}
-		if !e.hadPtr {
-			delete(l.localIsPtr, e.name)
+		if e.prevPtr {
+			l.localIsPtr[e.name] = true
+		}
+		if e.prevAbstract != nil {
+			l.localAbstractASTs[e.name] = e.prevAbstract
+		}
+		if e.hadDecl {
+			l.localDecls[e.name] = e.prevDecl
+		}
+		if e.prevUsed {
+			l.usedLocals[e.name] = true
 		}
 	}
 }
 
-// scopeSet records that a name is being bound in the current scope, saving
-// any previous binding for restoration by popScope.
-func (l *Lowerer) scopeSet(name string) {
+// scopeSet declares name, spanning span, in the current scope. It saves
+// any binding the name has from an enclosing scope for restoration by
+// popScope and clears it, so the new declaration starts from nothing: a
+// 'let' shadowing a 'var' or an abstract 'const' does not inherit their
+// bookkeeping. Declaring a name twice in the same scope is an error.
+func (l *Lowerer) scopeSet(name string, span parser.Span) error {
 	if len(l.scopeStack) == 0 {
-		return
+		return nil
 	}
 	frame := &l.scopeStack[len(l.scopeStack)-1]
 
-	// Only save the first time this name is shadowed in this scope.
 	for _, e := range frame.entries {
 		if e.name == name {
-			return // already saved
+			return &spannedError{
+				span: span,
+				msg: fmt.Sprintf("redefinition of '%s' (previously declared at %d:%d)",
+					name, e.span.Start.Line, e.span.Start.Column),
+			}
 		}
 	}
 
 	prevExpr, hadLocal := l.locals[name]
-	_, hadConst := l.localConsts[name]
-	_, hadVar := l.localIsVar[name]
-	_, hadPtr := l.localIsPtr[name]
-
+	prevDecl, hadDecl := l.localDecls[name]
 	frame.entries = append(frame.entries, scopeEntry{
-		name:     name,
-		hadLocal: hadLocal,
-		prevExpr: prevExpr,
-		hadConst: hadConst,
-		hadVar:   hadVar,
-		hadPtr:   hadPtr,
+		name:         name,
+		span:         span,
+		hadLocal:     hadLocal,
+		prevExpr:     prevExpr,
+		prevConst:    l.localConsts[name],
+		prevVar:      l.localIsVar[name],
+		prevPtr:      l.localIsPtr[name],
+		prevAbstract: l.localAbstractASTs[name],
+		hadDecl:      hadDecl,
+		prevDecl:     prevDecl,
+		prevUsed:     l.usedLocals[name],
 	})
+	l.clearLocal(name)
+	return nil
+}
+
+// clearLocal removes every function-scope binding of name.
+func (l *Lowerer) clearLocal(name string) {
+	delete(l.locals, name)
+	delete(l.localConsts, name)
+	delete(l.localIsVar, name)
+	delete(l.localIsPtr, name)
+	delete(l.localAbstractASTs, name)
+	delete(l.localDecls, name)
+	delete(l.usedLocals, name)
+}
+
+// isLocalName reports whether name is bound in function scope, where it
+// shadows module-scope constants, overrides and globals of the same name.
+func (l *Lowerer) isLocalName(name string) bool {
+	_, ok := l.locals[name]
+	return ok
 }
 
 // lowerBlock converts a block statement to IR statements.
 func (l *Lowerer) lowerBlock(block *parser.BlockStmt, target *[]ir.Statement) error {
-	for _, stmt := range block.Statements {
+	for i, stmt := range block.Statements {
 		if err := l.lowerStatement(stmt, target); err != nil {
 			return err
 		}
+		l.checkUnreachable(stmt, block.Statements[i+1:])
 	}
 	return nil
 }
 
-// lowerStatement converts a statement to IR.
+// checkUnreachable warns once per block when statements follow a return
+// or discard. The statements are still lowered; only the first one is
+// reported.
+func (l *Lowerer) checkUnreachable(stmt parser.Stmt, rest []parser.Stmt) {
+	if len(rest) == 0 {
+		return
+	}
+	var after string
+	switch stmt.(type) {
+	case *parser.ReturnStmt:
+		after = "return"
+	case *parser.DiscardStmt:
+		after = "discard"
+	default:
+		return
+	}
+	l.warnings = append(l.warnings, Warning{
+		Message: fmt.Sprintf("unreachable code after %s", after),
+		Span:    rest[0].Pos(),
+	})
+}
+
+// lowerStatement converts a statement to IR, with its @diagnostic
+// filters in scope.
 func (l *Lowerer) lowerStatement(stmt parser.Stmt, target *[]ir.Statement) error {
+	depth := len(l.stmtDiagnostics)
+	if diags := statementDiagnostics(stmt); len(diags) > 0 {
+		if err := l.checkDiagnostics(diags); err != nil {
+			return err
+		}
+		l.stmtDiagnostics = append(l.stmtDiagnostics, diags...)
+	}
+	outerSpan := l.stmtSpan
+	l.stmtSpan = stmt.Pos()
+
+	err := l.lowerStatementKind(stmt, target)
+
+	l.stmtSpan = outerSpan
+	l.stmtDiagnostics = l.stmtDiagnostics[:depth]
+	return err
+}
+
+// lowerStatementKind dispatches on the statement type.
+func (l *Lowerer) lowerStatementKind(stmt parser.Stmt, target *[]ir.Statement) error {
 	switch s := stmt.(type) {
 	case *parser.ReturnStmt:
 		return l.lowerReturn(s, target)
@@ -3921,6 +4149,11 @@ func (l *Lowerer) lowerStatement(stmt parser.Stmt, target *[]ir.Statement) error
 		*target = append(*target, ir.Statement{Kind: ir.StmtKill{}})
 		return nil
 	case *parser.ExprStmt:
+		// Only a call is a valid expression statement. Anything else keeps
+		// just its side-effecting calls; the value is discarded.
+		if _, ok := s.Expr.(*parser.CallExpr); !ok {
+			return l.lowerDiscardedExpression(s, target)
+		}
 		// Evaluate expression for side effects.
 		// Set isStatement flag so atomic calls know their result is discarded.
 		l.isStatement = true
@@ -4020,6 +4253,11 @@ func (l *Lowerer) lowerLocalVar(v *parser.VarDecl, target *[]ir.Statement) error
 	// For explicit type: var x: u32 = 42 → concretize AbstractInt(42) to LiteralU32(42).
 	// For inferred type: var idx = 1 → concretize AbstractInt(1) to LiteralI32(1).
 	// Rust naga always concretizes abstract literals at var declaration sites.
+	if initHandle != nil && hasExplicitType {
+		if err := l.checkInitType(v.Name, typeHandle, *initHandle, v.Init, v.Span); err != nil {
+			return err
+		}
+	}
 	if initHandle != nil {
 		l.concretizeExpressionToType(*initHandle, typeHandle)
 	}
@@ -4067,7 +4305,9 @@ func (l *Lowerer) lowerLocalVar(v *parser.VarDecl, target *[]ir.Statement) error
 		}
 		l.emitStateStart = nil
 	}
-	l.scopeSet(v.Name)
+	if err := l.scopeSet(v.Name, v.Span); err != nil {
+		return err
+	}
 	l.locals[v.Name] = exprHandle
 
 	// Emit Store for runtime initial values (or const values inside loops).
@@ -4160,6 +4400,89 @@ func (l *Lowerer) constEvalExprToU32(handle ir.ExpressionHandle) (uint32, bool)
 	return 0, false
 }
 
+// lowerDiscardedExpression lowers an expression statement that is not a
+// function call, such as `x + 1.0;`. A pure expression is still checked but
+// dropped with a warning; one that contains calls with side effects is kept
+// whole so the calls run in evaluation order, its value unused.
+func (l *Lowerer) lowerDiscardedExpression(s *parser.ExprStmt, target *[]ir.Statement) error {
+	if l.hasEffectfulCall(s.Expr) {
+		l.warnings = append(l.warnings, Warning{
+			Message: "value of expression statement is discarded",
+			Span:    s.Span,
+		})
+		emitStart := l.emitStartWithTarget(target)
+		if _, err := l.lowerExpression(s.Expr, target); err != nil {
+			return err
+		}
+		l.emitFinish(emitStart, target)
+		return nil
+	}
+
+	l.warnings = append(l.warnings, Warning{
+		Message: "expression statement has no effect",
+		Span:    s.Span,
+	})
+	// Lower into a scratch block so type errors are still reported; the
+	// expressions are never emitted and are removed by CompactExpressions.
+	var scratch []ir.Statement
+	emitStart := l.emitStartWithTarget(&scratch)
+	if _, err := l.lowerExpression(s.Expr, &scratch); err != nil {
+		return err
+	}
+	l.emitFinish(emitStart, &scratch)
+	return nil
+}
+
+// hasEffectfulCall reports whether expr contains a call that may have side
+// effects: a user function or a builtin other than math functions,
+// constructors and texture reads.
+func (l *Lowerer) hasEffectfulCall(expr parser.Expr) bool {
+	switch e := expr.(type) {
+	case *parser.CallExpr:
+		if !l.isPureCall(e.Func.Name) {
+			return true
+		}
+		return slices.ContainsFunc(e.Args, l.hasEffectfulCall)
+	case *parser.BinaryExpr:
+		return l.hasEffectfulCall(e.Left) || l.hasEffectfulCall(e.Right)
+	case *parser.UnaryExpr:
+		return l.hasEffectfulCall(e.Operand)
+	case *parser.IndexExpr:
+		return l.hasEffectfulCall(e.Expr) || l.hasEffectfulCall(e.Index)
+	case *parser.MemberExpr:
+		return l.hasEffectfulCall(e.Expr)
+	case *parser.ConstructExpr:
+		return slices.ContainsFunc(e.Args, l.hasEffectfulCall)
+	case *parser.BitcastExpr:
+		return l.hasEffectfulCall(e.Expr)
+	}
+	return false
+}
+
+// isPureCall reports whether calling name has no side effects.
+func (l *Lowerer) isPureCall(name string) bool {
+	if _, ok := l.functions[name]; ok {
+		return false
+	}
+	if _, ok := mathFunction(name); ok {
+		return true
+	}
+	if _, ok := l.types[name]; ok || l.isBuiltinConstructor(name) {
+		return true
+	}
+	switch name {
+	case "textureStore", "textureAtomicMin", "textureAtomicMax", "textureAtomicAdd",
+		"textureAtomicAnd", "textureAtomicOr", "textureAtomicXor":
+		return false
+	case "select", "all", "any", "arrayLength", "bitcast",
+		"f32", "f16", "i32", "u32", "bool", "i64", "u64",
+		"dpdx", "dpdy", "fwidth", "dpdxCoarse", "dpdyCoarse", "fwidthCoarse",
+		"dpdxFine", "dpdyFine", "fwidthFine":
+		return true
+	}
+	return l.isTextureFunction(name)
+}
+
 // lowerAssign converts an assignment statement to IR.
 func (l *Lowerer) lowerAssign(assign *parser.AssignStmt, target *[]ir.Statement) error {
 	// WGSL discard pattern: _ = expr; evaluates RHS for side effects, discards result.
@@ -4203,9 +4526,11 @@ func (l *Lowerer) lowerAssign(assign *parser.AssignStmt, target *[]ir.Statement)
 	// Special case: *ptr = value extracts the inner pointer.
 	var pointer ir.ExpressionHandle
 	var err error
+	deref := false
 	if unary, ok := assign.Left.(*parser.UnaryExpr); ok && unary.Op == parser.TokenStar {
 		// *ptr dereference: the operand itself is the pointer
 		pointer, err = l.lowerExpressionForRef(unary.Operand, target)
+		deref = true
 	} else {
 		pointer, err = l.lowerExpressionForRef(assign.Left, target)
 	}
@@ -4233,6 +4558,13 @@ func (l *Lowerer) lowerAssign(assign *parser.AssignStmt, target *[]ir.Statement)
 		// Must happen BEFORE Splat to match Rust expression ordering:
 		// concretize → Load → Splat → Binary
 		loaded := l.applyLoadRule(pointer)
+		if deref && loaded == pointer {
+			// A pointer value, such as a ptr parameter, is not a reference
+			// the load rule applies to, but *ptr always reads through it.
+			loaded = l.addExpression(ir.Expression{
+				Kind: ir.ExprLoad{Pointer: pointer},
+			})
+		}
 		// Splat scalar RHS to match vector LHS (e.g., a += 1.0 where a: vec2<f32>).
 		value = l.splatScalarToMatchPointer(pointer, value)
 		value = l.addExpression(ir.Expression{
@@ -4243,6 +4575,11 @@ func (l *Lowerer) lowerAssign(assign *parser.AssignStmt, target *[]ir.Statement)
 			},
 		})
 	}
+	if assign.Op == parser.TokenEqual {
+		if err := l.checkStoreType(pointer, value, assign.Right, assign.Span); err != nil {
+			return err
+		}
+	}
 	// Concretize abstract RHS to match the store target's type.
 	// E.g., c2[vi + 1u] = 42; where c2 is vec2<u32> → concretize AbstractInt(42) to U32(42).
 	l.concretizeStoreValue(pointer, value)
@@ -4455,6 +4792,9 @@ func (l *Lowerer) lowerLoop(loopStmt *parser.LoopStmt, target *[]ir.Statement) e
 			}
 		}
 
+		// The continuing block is nested in the body's scope: it sees the
+		// body's declarations and may shadow them.
+		l.pushScope()
 		for _, stmt := range nonBreakIfStmts {
 			if err := l.lowerStatement(stmt, &continuing); err != nil {
 				return err
@@ -4470,6 +4810,7 @@ func (l *Lowerer) lowerLoop(loopStmt *parser.LoopStmt, target *[]ir.Statement) e
 			l.emitFinish(emitStart, &continuing)
 			breakIfHandle = &handle
 		}
+		l.popScope()
 	}
 
 	l.popScope() // end scope shared by body and continuing
@@ -4495,28 +4836,11 @@ func (l *Lowerer) lowerSwitch(switchStmt *parser.SwitchStmt, target *[]ir.Statem
 	// Determine consensus type for selector + all case values.
 	// Matches Rust naga: automatic_conversion_consensus across selector and cases,
 	// defaulting to I32 if all are abstract.
-	consensusUnsigned := false
-	// Check if selector is already U32.
-	if inner := l.resolveExprTypeInner(selector); inner != nil {
-		if sc, ok := inner.(ir.ScalarType); ok && sc.Kind == ir.ScalarUint {
-			consensusUnsigned = true
-		}
-	}
-	// Check if any case value is U32 — this overrides consensus.
-	if !consensusUnsigned {
-		for _, clause := range switchStmt.Cases {
-			for _, sel := range clause.Selectors {
-				kind, _, err := l.evalConstantIntExpr(sel)
-				if err == nil && kind == ir.ScalarUint {
-					consensusUnsigned = true
-					break
-				}
-			}
-			if consensusUnsigned {
-				break
-			}
-		}
+	consensus, err := l.checkSwitchSelectors(switchStmt, selector)
+	if err != nil {
+		return err
 	}
+	consensusUnsigned := consensus == ir.ScalarUint
 
 	// Concretize abstract selector to consensus type.
 	if consensusUnsigned {
@@ -4559,21 +4883,10 @@ func (l *Lowerer) lowerSwitch(switchStmt *parser.SwitchStmt, target *[]ir.Statem
 				})
 			}
 			for j, sel := range clause.Selectors {
-				value, err := l.lowerSwitchCaseValue(sel)
+				value, err := l.lowerSwitchCaseValue(sel, consensusUnsigned)
 				if err != nil {
 					return fmt.Errorf("switch case %d selector: %w", i, err)
 				}
-				// Coerce case value to consensus type.
-				// Matches Rust naga: all case values match the consensus scalar type.
-				if consensusUnsigned {
-					if v, ok := value.(ir.SwitchValueI32); ok {
-						value = ir.SwitchValueU32(uint32(v))
-					}
-				} else {
-					if v, ok := value.(ir.SwitchValueU32); ok {
-						value = ir.SwitchValueI32(int32(v))
-					}
-				}
 				isLast := j == len(clause.Selectors)-1
 				if clause.IsDefault && !clause.DefaultFirst && isLast {
 					// Default appears after selectors: last selector is fallthrough,
@@ -4616,22 +4929,20 @@ func (l *Lowerer) lowerSwitch(switchStmt *parser.SwitchStmt, target *[]ir.Statem
 	return nil
 }
 
-// lowerSwitchCaseValue converts a switch case selector to IR.
-// Handles literal integers, named constants, constant binary expressions,
-// and type constructor zero values (e.g., i32()).
-func (l *Lowerer) lowerSwitchCaseValue(expr parser.Expr) (ir.SwitchValue, error) {
-	kind, val, err := l.evalConstantIntExpr(expr)
+// lowerSwitchCaseValue evaluates a switch case selector, which may be any
+// integer const-expression (1 + 2, i32(), vec4(4).x, max(A, B)), and
+// converts it to the consensus type of the switch. Matches Rust naga: all
+// case values have the consensus scalar type. Range and type checks were
+// already done by checkSwitchSelectors.
+func (l *Lowerer) lowerSwitchCaseValue(expr parser.Expr, unsigned bool) (ir.SwitchValue, error) {
+	_, val, err := l.evalConstInteger(expr)
 	if err != nil {
 		return nil, fmt.Errorf("switch case selector: %w", err)
 	}
-	switch kind {
-	case ir.ScalarUint:
+	if unsigned {
 		return ir.SwitchValueU32(uint32(val)), nil
-	case ir.ScalarSint:
-		return ir.SwitchValueI32(int32(val)), nil
-	default:
-		return nil, fmt.Errorf("switch case selector must be integer, got %v", kind)
 	}
+	return ir.SwitchValueI32(int32(val)), nil
 }
 
 // evalConstAssert evaluates a const_assert condition expression.
@@ -4670,6 +4981,9 @@ func (l *Lowerer) tryEvalConstantBool(expr parser.Expr) (bool, bool) {
 		}
 		return false, false
 	case *parser.Ident:
+		if l.isLocalName(e.Name) {
+			return false, false
+		}
 		// Check abstract constants for bool values
 		if info, ok := l.abstractConstants[e.Name]; ok && info.scalarValue != nil {
 			if info.scalarValue.Kind == ir.ScalarBool {
@@ -4857,6 +5171,17 @@ func (l *Lowerer) evalLiteralAsInt(lit *parser.Literal) (ir.ScalarKind, int64, e
 // evalConstantIdent resolves a named constant to its integer value.
 // Checks abstract constants, module-level constants, and local constants.
 func (l *Lowerer) evalConstantIdent(name string) (ir.ScalarKind, int64, error) {
+	// A function-scope declaration shadows module-scope constants.
+	if l.isLocalName(name) {
+		if ast, ok := l.localAbstractASTs[name]; ok {
+			return l.evalConstantIntExpr(ast)
+		}
+		if l.localConsts[name] {
+			return l.evalExpressionAsConstantInt(l.locals[name])
+		}
+		return 0, 0, fmt.Errorf("'%s' is not a constant", name)
+	}
+
 	// Check abstract constants first (not in module.Constants)
 	if info, ok := l.abstractConstants[name]; ok && info.scalarValue != nil {
 		sv := info.scalarValue
@@ -4901,11 +5226,6 @@ func (l *Lowerer) evalConstantIdent(name string) (ir.ScalarKind, int64, error) {
 		}
 	}
 
-	// Check local constants (const declarations inside functions)
-	if exprHandle, ok := l.locals[name]; ok {
-		return l.evalExpressionAsConstantInt(exprHandle)
-	}
-
 	return 0, 0, fmt.Errorf("'%s' is not a known constant", name)
 }
 
@@ -5082,10 +5402,6 @@ func (l *Lowerer) lowerLocalConst(decl *parser.ConstDecl, target *[]ir.Statement
 	// where abstract const expressions are created during declaration but removed
 	// by compact. The concrete expressions are created fresh when referenced.
 	if decl.IsConst && !hasExplicitType && !l.initHasConcreteType(decl.Init) {
-		l.scopeSet(decl.Name)
-		l.localAbstractASTs[decl.Name] = decl.Init
-		l.localConsts[decl.Name] = true
-
 		// Still create the abstract expression to match Rust naga's pattern:
 		// Rust creates the expression during const declaration but it becomes dead
 		// after concretization at the use site. CompactExpressions removes it.
@@ -5097,6 +5413,12 @@ func (l *Lowerer) lowerLocalConst(decl *parser.ConstDecl, target *[]ir.Statement
 		l.emitFinish(emitStart, target)
 		// Store the handle but DON'T concretize — it stays abstract.
 		// The handle is NOT used for var init; a fresh handle is created at use site.
+		// The name comes into scope only after its initializer.
+		if err := l.scopeSet(decl.Name, decl.Span); err != nil {
+			return err
+		}
+		l.localAbstractASTs[decl.Name] = decl.Init
+		l.localConsts[decl.Name] = true
 		l.locals[decl.Name] = initHandle
 		return nil
 	}
@@ -5111,6 +5433,9 @@ func (l *Lowerer) lowerLocalConst(decl *parser.ConstDecl, target *[]ir.Statement
 
 	// Concretize abstract literals to match explicit type annotation or default type.
 	if hasExplicitType {
+		if err := l.checkInitType(decl.Name, explicitType, initHandle, decl.Init, decl.Span); err != nil {
+			return err
+		}
 		l.concretizeExpressionToType(initHandle, explicitType)
 	} else if !decl.IsConst {
 		// No explicit type on let/var: concretize abstract to default.
@@ -5123,7 +5448,9 @@ func (l *Lowerer) lowerLocalConst(decl *parser.ConstDecl, target *[]ir.Statement
 		return nil
 	}
 
-	l.scopeSet(decl.Name)
+	if err := l.scopeSet(decl.Name, decl.Span); err != nil {
+		return err
+	}
 	l.locals[decl.Name] = initHandle
 
 	// Track pointer let-bindings: let p = &v[i]
@@ -5148,13 +5475,20 @@ func (l *Lowerer) lowerLocalConst(decl *parser.ConstDecl, target *[]ir.Statement
 	return nil
 }
 
-// lowerExpression converts an expression to IR.
+// lowerExpression converts an expression to IR. Expressions appended
+// while lowering it carry its span, unless a subexpression's is closer.
 func (l *Lowerer) lowerExpression(expr parser.Expr, target *[]ir.Statement) (ir.ExpressionHandle, error) {
+	outer := l.exprSpan
+	if pos := expr.Pos(); pos.End.Offset > pos.Start.Offset {
+		l.exprSpan = ir.Span{Start: uint32(pos.Start.Offset), End: uint32(pos.End.Offset)}
+	}
+	defer func() { l.exprSpan = outer }()
+
 	switch e := expr.(type) {
 	case *parser.Literal:
 		return l.lowerLiteral(e)
 	case *parser.Ident:
-		handle, err := l.resolveIdentifier(e.Name)
+		handle, err := l.resolveIdentifier(e)
 		if err != nil {
 			return 0, err
 		}
@@ -5493,7 +5827,12 @@ func (l *Lowerer) tryFoldASTBinary(bin *parser.BinaryExpr) (ir.ExpressionHandle,
 	// AbstractFloat + F32 → F32
 	// AbstractInt + I32 → I32
 	// etc.
-	leftVal, rightVal = concretizeLiteralPair(leftVal, rightVal)
+	// A shift has the type of its left operand; its operands need no
+	// consensus.
+	op := l.tokenToBinaryOp(bin.Op)
+	if op != ir.BinaryShiftLeft && op != ir.BinaryShiftRight {
+		leftVal, rightVal = concretizeLiteralPair(leftVal, rightVal)
+	}
 
 	// Skip 64-bit folding — Rust naga's constant evaluator doesn't implement
 	// I64/U64/F64 binary arithmetic, keeping them as separate expressions.
@@ -5502,7 +5841,7 @@ func (l *Lowerer) tryFoldASTBinary(bin *parser.BinaryExpr) (ir.ExpressionHandle,
 	}
 
 	// Compute result purely in Go values
-	result, ok := foldBinaryLiterals(l.tokenToBinaryOp(bin.Op), leftVal, rightVal)
+	result, ok := foldBinaryLiterals(op, leftVal, rightVal)
 	if !ok {
 		return 0, false
 	}
@@ -7004,58 +7343,16 @@ func (l *Lowerer) getTypeScalar(typeHandle ir.TypeHandle) (ir.ScalarType, bool)
 // This implements WGSL's automatic type conversion at declaration sites:
 //
 // let x: vec2<u32> = vec2(44, 45) → concretize 44,45 from AbstractInt to u32.
-// checkArgumentType verifies that a call argument's resolved type matches the
-// expected parameter type. Abstract types are allowed (concretization handles them).
-func (l *Lowerer) checkArgumentType(argHandle ir.ExpressionHandle, paramType ir.TypeHandle, funcName string, argIndex int) error {
-	if l.currentFunc == nil || int(argHandle) >= len(l.currentFunc.ExpressionTypes) {
-		return nil
-	}
-	argInner := ir.TypeResInner(l.module, l.currentFunc.ExpressionTypes[argHandle])
-	if argInner == nil {
-		return nil
-	}
-	// Skip abstract types — concretization will handle or report them.
-	if s, ok := argInner.(ir.ScalarType); ok && (s.Kind == ir.ScalarAbstractInt || s.Kind == ir.ScalarAbstractFloat) {
-		return nil
-	}
-	if int(paramType) >= len(l.module.Types) {
+// checkArgumentType verifies that a call argument's type is the parameter
+// type, or an abstract type that converts to it. It runs before the argument
+// is concretized.
+func (l *Lowerer) checkArgumentType(argHandle ir.ExpressionHandle, arg parser.Expr, paramType ir.TypeHandle, funcName string, argIndex int) error {
+	argInner, ok := l.valueConvertsTo(argHandle, arg, paramType)
+	if ok {
 		return nil
 	}
 	paramInner := l.module.Types[paramType].Inner
-	if !typeShapeMatches(argInner, paramInner) {
-		return fmt.Errorf("function '%s' argument %d: type mismatch (expected %s, got %s)", funcName, argIndex, typeName(paramInner), typeName(argInner))
-	}
-	return nil
-}
-
-// typeShapeMatches checks if two TypeInner values have compatible shapes.
-// Scalar↔Scalar, Vector↔Vector (same size), Matrix↔Matrix (same dims), etc.
-func typeShapeMatches(arg, param ir.TypeInner) bool {
-	switch p := param.(type) {
-	case ir.ScalarType:
-		a, ok := arg.(ir.ScalarType)
-		return ok && (a.Kind == p.Kind || a.Kind == ir.ScalarAbstractInt || a.Kind == ir.ScalarAbstractFloat)
-	case ir.VectorType:
-		a, ok := arg.(ir.VectorType)
-		return ok && a.Size == p.Size
-	case ir.MatrixType:
-		a, ok := arg.(ir.MatrixType)
-		return ok && a.Columns == p.Columns && a.Rows == p.Rows
-	case ir.ArrayType:
-		_, ok := arg.(ir.ArrayType)
-		return ok
-	case ir.StructType:
-		_, ok := arg.(ir.StructType)
-		return ok
-	case ir.PointerType:
-		_, ok := arg.(ir.PointerType)
-		return ok
-	case ir.AtomicType:
-		_, ok := arg.(ir.AtomicType)
-		return ok
-	default:
-		return true // opaque types — trust downstream validation
-	}
+	return fmt.Errorf("function '%s' argument %d: type mismatch (expected %s, got %s)", funcName, argIndex, typeName(paramInner), typeName(argInner))
 }
 
 func typeName(inner ir.TypeInner) string {
@@ -7073,6 +7370,10 @@ func typeName(inner ir.TypeInner) string {
 			return "u32"
 		case ir.ScalarBool:
 			return "bool"
+		case ir.ScalarAbstractInt:
+			return "AbstractInt"
+		case ir.ScalarAbstractFloat:
+			return "AbstractFloat"
 		}
 	case ir.VectorType:
 		return fmt.Sprintf("vec%d<%s>", t.Size, typeName(t.Scalar))
@@ -7367,7 +7668,7 @@ func (l *Lowerer) lowerCall(call *parser.CallExpr, target *[]ir.Statement) (ir.E
 	}
 
 	// Check if this is a short type alias constructor (e.g., vec3f(1.0, 2.0, 3.0))
-	if expanded, ok := shortTypeAliases[funcName]; ok {
+	if expanded, ok := lookupShortTypeAlias(funcName); ok {
 		return l.lowerShortAliasConstructor(expanded, call.Args, target)
 	}
 
@@ -7479,6 +7780,7 @@ func (l *Lowerer) lowerCall(call *parser.CallExpr, target *[]ir.Statement) (ir.E
 	if !ok {
 		return 0, fmt.Errorf("unknown function: %s", funcName)
 	}
+	l.calledFuncs[funcName] = true
 
 	// Enforce @must_use: if the function is marked @must_use and its result
 	// is discarded as a statement, emit an error.
@@ -7503,10 +7805,10 @@ func (l *Lowerer) lowerCall(call *parser.CallExpr, target *[]ir.Statement) (ir.E
 			return 0, fmt.Errorf("function '%s' expects %d argument(s), got %d", funcName, len(fn.Arguments), len(args))
 		}
 		for i, argHandle := range args {
-			l.concretizeExpressionToType(argHandle, fn.Arguments[i].Type)
-			if err := l.checkArgumentType(argHandle, fn.Arguments[i].Type, funcName, i); err != nil {
+			if err := l.checkArgumentType(argHandle, call.Args[i], fn.Arguments[i].Type, funcName, i); err != nil {
 				return 0, err
 			}
+			l.concretizeExpressionToType(argHandle, fn.Arguments[i].Type)
 		}
 	}
 
@@ -7823,7 +8125,10 @@ func (l *Lowerer) lowerConstruct(cons *parser.ConstructExpr, target *[]ir.Statem
 	// final Compose (not the named alias type). This is because the column grouping creates
 	// new intermediate expressions, and the final Compose type should be anonymous.
 	origLen := len(components)
-	grouped := l.groupMatrixColumns(typeHandle, components)
+	grouped, err := l.groupMatrixColumns(typeHandle, components)
+	if err != nil {
+		return 0, err
+	}
 	composeType := typeHandle
 	if origLen > 0 && len(grouped) != origLen {
 		// Grouping happened — use anonymous type for the final Compose.
@@ -7842,9 +8147,6 @@ func (l *Lowerer) lowerConstruct(cons *parser.ConstructExpr, target *[]ir.Statem
 	}), nil
 }
 
-// groupMatrixColumns groups scalar matrix components into column vectors.
-// If the type is a matrix and components are scalars (count = cols * rows),
-// creates intermediate Compose expressions for each column vector.
 // isMatrixScalarConstruct checks if a ConstructExpr is a matrix constructor
 // with all scalar literal args (e.g., mat2x2(1, 2, 3, 4)).
 func (l *Lowerer) isMatrixScalarConstruct(cons *parser.ConstructExpr) bool {
@@ -7952,21 +8254,49 @@ func (l *Lowerer) lowerMatrixScalarConstruct(cons *parser.ConstructExpr, target
 	}), nil
 }
 
-func (l *Lowerer) groupMatrixColumns(typeHandle ir.TypeHandle, components []ir.ExpressionHandle) []ir.ExpressionHandle {
+// groupMatrixColumns groups scalar matrix components into column vectors.
+// A matrix is constructed from one column vector per column or from
+// cols*rows scalars in column-major order; for the latter it creates an
+// intermediate Compose for each column. Any other argument list is an
+// error. Non-matrix types pass through unchanged.
+func (l *Lowerer) groupMatrixColumns(typeHandle ir.TypeHandle, components []ir.ExpressionHandle) ([]ir.ExpressionHandle, error) {
 	if int(typeHandle) >= len(l.module.Types) {
-		return components
+		return components, nil
 	}
 	mat, ok := l.module.Types[typeHandle].Inner.(ir.MatrixType)
-	if !ok {
-		return components
+	if !ok || len(components) <= 1 {
+		// Zero-value and matrix conversion constructors.
+		return components, nil
 	}
 
 	cols := int(mat.Columns)
 	rows := int(mat.Rows)
+	name := fmt.Sprintf("mat%dx%d", cols, rows)
 
-	// Only group if we have exactly cols*rows scalar components
-	if len(components) != cols*rows {
-		return components
+	switch len(components) {
+	case cols:
+		for i, comp := range components {
+			res, err := ir.ResolveExpressionType(l.module, l.currentFunc, comp)
+			if err != nil {
+				continue
+			}
+			if vec, ok := ir.TypeResInner(l.module, res).(ir.VectorType); !ok || int(vec.Size) != rows {
+				return nil, fmt.Errorf("%s constructor: column %d must be a vec%d", name, i, rows)
+			}
+		}
+		return components, nil
+	case cols * rows:
+		for i, comp := range components {
+			res, err := ir.ResolveExpressionType(l.module, l.currentFunc, comp)
+			if err != nil {
+				continue
+			}
+			if _, ok := ir.TypeResInner(l.module, res).(ir.ScalarType); !ok {
+				return nil, fmt.Errorf("%s constructor: argument %d must be a scalar", name, i)
+			}
+		}
+	default:
+		return nil, fmt.Errorf("%s constructor takes %d column vectors or %d scalars, got %d arguments", name, cols, cols*rows, len(components))
 	}
 
 	// Create column vector type
@@ -7980,7 +8310,7 @@ func (l *Lowerer) groupMatrixColumns(typeHandle ir.TypeHandle, components []ir.E
 			Kind: ir.ExprCompose{Type: colTypeHandle, Components: colArgs},
 		})
 	}
-	return colComponents
+	return colComponents, nil
 }
 
 // zeroLiteral returns the zero literal value for a scalar type.
@@ -8406,6 +8736,15 @@ func (l *Lowerer) resolveTargetScalarKind(t parser.Type) (ir.ScalarKind, error)
 			}
 			return ir.ScalarFloat, nil // default to float
 		default:
+			// Predeclared short aliases (vec3u, vec4f) and user aliases.
+			if handle, err := l.resolveType(ty); err == nil {
+				switch inner := l.module.Types[handle].Inner.(type) {
+				case ir.ScalarType:
+					return inner.Kind, nil
+				case ir.VectorType:
+					return inner.Scalar.Kind, nil
+				}
+			}
 			return 0, fmt.Errorf("unsupported bitcast target type '%s'", ty.Name)
 		}
 	default:
@@ -8449,6 +8788,9 @@ func (l *Lowerer) tryConstantArrayIndex(idx *parser.IndexExpr, target *[]ir.Stat
 	if !ok {
 		return 0, false
 	}
+	if l.isLocalName(ident.Name) {
+		return 0, false
+	}
 	constHandle, ok := l.moduleConstants[ident.Name]
 	if !ok {
 		return 0, false
@@ -9004,6 +9346,13 @@ func (l *Lowerer) constFoldAccessIndex(ai ir.ExprAccessIndex) (ir.ExpressionHand
 		return 0, false
 	}
 
+	// Flattening yields scalars, so it only finds the element of vectors
+	// and arrays of scalars; other arrays and structs take the element
+	// from the top-level Compose.
+	if !l.hasScalarElements(ai.Base) {
+		return l.constCompositeElement(ai.Base, ai.Index)
+	}
+
 	// Flatten nested Compose to get scalar component handles
 	flat, ok := l.flattenConstCompose(ai.Base)
 	if !ok {
@@ -9018,6 +9367,40 @@ func (l *Lowerer) constFoldAccessIndex(ai ir.ExprAccessIndex) (ir.ExpressionHand
 	return flat[idx], true
 }
 
+// constCompositeElement returns element index of a constant array or
+// struct expression, copying a referenced constant into the function.
+func (l *Lowerer) constCompositeElement(handle ir.ExpressionHandle, index uint32) (ir.ExpressionHandle, bool) {
+	if c, ok := l.currentFunc.Expressions[handle].Kind.(ir.ExprConstant); ok {
+		copied, ok := l.deepCopyConstantValue(c.Constant)
+		if !ok {
+			return 0, false
+		}
+		handle = copied
+	}
+	compose, ok := l.currentFunc.Expressions[handle].Kind.(ir.ExprCompose)
+	if !ok || int(index) >= len(compose.Components) {
+		return 0, false
+	}
+	return compose.Components[index], true
+}
+
+// hasScalarElements reports whether the expression is a vector or an
+// array of scalars.
+func (l *Lowerer) hasScalarElements(handle ir.ExpressionHandle) bool {
+	res, err := ir.ResolveExpressionType(l.module, l.currentFunc, handle)
+	if err != nil {
+		return false
+	}
+	switch t := ir.TypeResInner(l.module, res).(type) {
+	case ir.VectorType:
+		return true
+	case ir.ArrayType:
+		_, ok := l.module.Types[t.Base].Inner.(ir.ScalarType)
+		return ok
+	}
+	return false
+}
+
 // constFoldRelational evaluates any/all on a const bool vector.
 // any(vec4<bool>(false,false,false,false)) → false
 // all(vec4<bool>(true,true,true,true)) → true
@@ -9382,14 +9765,22 @@ func (l *Lowerer) literalScalarType(lit ir.LiteralValue) ir.ScalarType {
 func (l *Lowerer) addExpressionRaw(expr ir.Expression) ir.ExpressionHandle {
 	handle := l.currentExprIdx
 	l.currentExprIdx++
+	if expr.Span.IsZero() {
+		expr.Span = l.exprSpan
+	}
 	l.currentFunc.Expressions = append(l.currentFunc.Expressions, expr)
 
-	exprType, err := ir.ResolveExpressionType(l.module, l.currentFunc, handle)
+	// Operand types were resolved as the operands were added.
+	exprType, err := ir.ResolveExpressionTypeFrom(l.module, l.currentFunc, handle, l.currentFunc.ExpressionTypes)
 	if err != nil {
 		exprType = ir.TypeResolution{}
 	}
 	l.currentFunc.ExpressionTypes = append(l.currentFunc.ExpressionTypes, exprType)
 
+	if ir.NeedsDerivatives(expr.Kind) {
+		l.recordDerivative(handle)
+	}
+
 	return handle
 }
 
@@ -9518,9 +9909,15 @@ func (l *Lowerer) isUnsizedType(handle ir.ExpressionHandle) bool {
 // may be a reference (pointer) to a variable. Used for Store targets (assignment LHS),
 // address-of (&) operator, and atomic pointer arguments.
 func (l *Lowerer) lowerExpressionForRef(expr parser.Expr, target *[]ir.Statement) (ir.ExpressionHandle, error) {
+	outer := l.exprSpan
+	if pos := expr.Pos(); pos.End.Offset > pos.Start.Offset {
+		l.exprSpan = ir.Span{Start: uint32(pos.Start.Offset), End: uint32(pos.End.Offset)}
+	}
+	defer func() { l.exprSpan = outer }()
+
 	switch e := expr.(type) {
 	case *parser.Ident:
-		return l.resolveIdentifier(e.Name)
+		return l.resolveIdentifier(e)
 	case *parser.MemberExpr:
 		return l.lowerMemberForRef(e, target)
 	case *parser.IndexExpr:
@@ -9996,7 +10393,8 @@ func (l *Lowerer) scalarValueToLiteralWithType(sv ir.ScalarValue, typeHandle ir.
 	return scalarValueToLiteral(sv)
 }
 
-func (l *Lowerer) resolveIdentifier(name string) (ir.ExpressionHandle, error) {
+func (l *Lowerer) resolveIdentifier(ident *parser.Ident) (ir.ExpressionHandle, error) {
+	name := ident.Name
 	// Check abstract local consts first — re-lower the AST fresh at use site.
 	// In Rust naga, the original abstract expression becomes dead and compact
 	// removes it. A fresh concretized expression is created at the reference site.
@@ -10083,13 +10481,21 @@ func (l *Lowerer) resolveIdentifier(name string) (ir.ExpressionHandle, error) {
 	// Rust naga creates separate GlobalVariable expressions for each reference,
 	// each via interrupt_emitter so they fall outside emit ranges.
 	if handle, ok := l.globals[name]; ok {
+		l.usedGlobals[name] = true
 		exprHandle := l.interruptEmitter(ir.Expression{
 			Kind: ir.ExprGlobalVariable{Variable: handle},
 		})
 		return exprHandle, nil
 	}
 
-	return 0, fmt.Errorf("unresolved identifier: %s", name)
+	if decl, ok := l.expiredLocals[name]; ok {
+		return 0, &spannedError{
+			span: ident.Span,
+			msg: fmt.Sprintf("'%s' is used outside of its scope (declared at %d:%d)",
+				name, decl.Start.Line, decl.Start.Column),
+		}
+	}
+	return 0, &spannedError{span: ident.Span, msg: "unresolved identifier: " + name}
 }
 
 // resolveType converts a WGSL type to an IR type handle.
@@ -10105,26 +10511,27 @@ func (l *Lowerer) resolveType(typ parser.Type) (ir.TypeHandle, error) {
 		// Parse size expression if present
 		var size ir.ArraySize
 		if t.Size != nil {
-			if n, ok := l.tryEvalConstantUint(t.Size); ok {
-				if n == 0 {
-					return 0, fmt.Errorf("array size must be greater than 0")
+			n, err := l.evalConstIndex("array size", t.Size, 1)
+			if err != nil {
+				if name := l.overrideReadBy(t.Size); name != "" {
+					return 0, fmt.Errorf("array size reads override '%s'; only the type of a workgroup variable can be sized by an override", name)
 				}
-				constSize := uint32(n)
-				size.Constant = &constSize
+				return 0, err
 			}
+			size.Constant = &n
 		}
-		// Compute element stride for SPIR-V ArrayStride decoration.
-		// Runtime arrays are always in storage buffers (std430 layout),
-		// so stride = roundUp(elemAlign, elemSize).
-		elemAlign, elemSize := l.typeAlignmentAndSize(base)
-		stride := (elemSize + elemAlign - 1) &^ (elemAlign - 1)
-		return l.registerType("", ir.ArrayType{Base: base, Size: size, Stride: stride}), nil
+		return l.registerArrayType(base, size), nil
 	case *parser.PtrType:
 		pointee, err := l.resolveType(t.PointeeType)
 		if err != nil {
 			return 0, err
 		}
-		space := l.addressSpace(t.AddressSpace)
+		space, known := l.addressSpace(t.AddressSpace)
+		if !known {
+			if err := l.nonSpec(fmt.Sprintf("unknown address space '%s' in pointer type; treated as function", t.AddressSpace), t.Span); err != nil {
+				return 0, err
+			}
+		}
 		return l.registerType("", ir.PointerType{Base: pointee, Space: space}), nil
 	case *parser.BindingArrayType:
 		base, err := l.resolveType(t.Element)
@@ -10145,6 +10552,15 @@ func (l *Lowerer) resolveType(typ parser.Type) (ir.TypeHandle, error) {
 	}
 }
 
+// registerArrayType registers an array of base. Its stride, for the SPIR-V
+// ArrayStride decoration, is that of storage buffers (std430): the element
+// size rounded up to its alignment, as runtime arrays only live there.
+func (l *Lowerer) registerArrayType(base ir.TypeHandle, size ir.ArraySize) ir.TypeHandle {
+	elemAlign, elemSize := l.typeAlignmentAndSize(base)
+	stride := (elemSize + elemAlign - 1) &^ (elemAlign - 1)
+	return l.registerType("", ir.ArrayType{Base: base, Size: size, Stride: stride})
+}
+
 // inferConstructorType infers the concrete type for a type constructor without template args
 // (e.g., vec2(a, b) where the scalar type is inferred from arguments).
 // Implements WGSL consensus type rules: concrete types win over abstract, float wins over int.
@@ -10351,7 +10767,9 @@ func (l *Lowerer) resolveNamedType(t *parser.NamedType) (ir.TypeHandle, error) {
 	}
 
 	// Texture types without type parameters (e.g., texture_depth_2d, texture_depth_2d_array)
-	if len(t.Name) >= 7 && t.Name[:7] == "texture" {
+	// Match "texture_" (with underscore) so user identifiers such as a
+	// function named `texture` are not parsed as a texture type.
+	if strings.HasPrefix(t.Name, "texture_") {
 		imgType := l.parseTextureType(t)
 		// When encountering texture_external, generate the special param/transfer types
 		// that backends need for lowering external textures to ordinary textures.
@@ -10363,7 +10781,7 @@ func (l *Lowerer) resolveNamedType(t *parser.NamedType) (ir.TypeHandle, error) {
 	}
 
 	// Check for WGSL predeclared short type aliases (e.g., vec3f -> vec3<f32>)
-	if expanded, ok := shortTypeAliases[t.Name]; ok {
+	if expanded, ok := lookupShortTypeAlias(t.Name); ok {
 		return l.resolveNamedType(&parser.NamedType{
 			Name:       expanded.baseName,
 			TypeParams: []parser.Type{&parser.NamedType{Name: expanded.scalarName}},
@@ -10420,7 +10838,7 @@ func (l *Lowerer) resolveParameterizedType(t *parser.NamedType) (ir.TypeHandle,
 	}
 
 	// Texture types: texture_2d<f32>, texture_storage_2d<rgba8unorm, write>, etc.
-	if len(t.Name) >= 7 && t.Name[:7] == "texture" {
+	if strings.HasPrefix(t.Name, "texture_") {
 		imgType := l.parseTextureType(t)
 		if imgType.Class == ir.ImageClassExternal {
 			l.generateExternalTextureTypes()
@@ -10601,7 +11019,10 @@ func (l *Lowerer) lowerBuiltinConstructor(name string, args []parser.Expr, targe
 	}
 
 	// Matrix with scalar args: group into column vectors (matches Rust naga).
-	components = l.groupMatrixColumns(typeHandle, components)
+	components, err := l.groupMatrixColumns(typeHandle, components)
+	if err != nil {
+		return 0, err
+	}
 
 	return l.addExpression(ir.Expression{
 		Kind: ir.ExprCompose{Type: typeHandle, Components: components},
@@ -10723,7 +11144,10 @@ func (l *Lowerer) lowerShortAliasConstructor(alias shortTypeAlias, args []parser
 	}
 
 	// Matrix with scalar args: group into column vectors (matches Rust naga).
-	components = l.groupMatrixColumns(typeHandle, components)
+	components, err = l.groupMatrixColumns(typeHandle, components)
+	if err != nil {
+		return 0, err
+	}
 
 	return l.addExpression(ir.Expression{
 		Kind: ir.ExprCompose{Type: typeHandle, Components: components},
@@ -10736,140 +11160,221 @@ type shortTypeAlias struct {
 	scalarName string // e.g., "f32", "i32", "u32", "f16"
 }
 
-// shortTypeAliases maps WGSL predeclared short type names to their expanded forms.
-// Per WGSL spec, these are built-in type aliases, not keywords.
-var shortTypeAliases = map[string]shortTypeAlias{
-	// Vector aliases
-	"vec2i": {"vec2", "i32"}, "vec3i": {"vec3", "i32"}, "vec4i": {"vec4", "i32"},
-	"vec2u": {"vec2", "u32"}, "vec3u": {"vec3", "u32"}, "vec4u": {"vec4", "u32"},
-	"vec2f": {"vec2", "f32"}, "vec3f": {"vec3", "f32"}, "vec4f": {"vec4", "f32"},
-	"vec2h": {"vec2", "f16"}, "vec3h": {"vec3", "f16"}, "vec4h": {"vec4", "f16"},
-	// Matrix aliases - f32
-	"mat2x2f": {"mat2x2", "f32"}, "mat2x3f": {"mat2x3", "f32"}, "mat2x4f": {"mat2x4", "f32"},
-	"mat3x2f": {"mat3x2", "f32"}, "mat3x3f": {"mat3x3", "f32"}, "mat3x4f": {"mat3x4", "f32"},
-	"mat4x2f": {"mat4x2", "f32"}, "mat4x3f": {"mat4x3", "f32"}, "mat4x4f": {"mat4x4", "f32"},
-	// Matrix aliases - f16
-	"mat2x2h": {"mat2x2", "f16"}, "mat2x3h": {"mat2x3", "f16"}, "mat2x4h": {"mat2x4", "f16"},
-	"mat3x2h": {"mat3x2", "f16"}, "mat3x3h": {"mat3x3", "f16"}, "mat3x4h": {"mat3x4", "f16"},
-	"mat4x2h": {"mat4x2", "f16"}, "mat4x3h": {"mat4x3", "f16"}, "mat4x4h": {"mat4x4", "f16"},
-}
-
-// mathFuncTable is a package-level lookup table for WGSL math function names to IR math functions.
-// Declared at package scope to avoid reallocating the map on every call to getMathFunction.
-var mathFuncTable = map[string]ir.MathFunction{
-	// Comparison functions
-	"abs":      ir.MathAbs,
-	"min":      ir.MathMin,
-	"max":      ir.MathMax,
-	"clamp":    ir.MathClamp,
-	"saturate": ir.MathSaturate,
+// lookupShortTypeAlias expands a WGSL predeclared short type alias, such as
+// vec3f or mat4x4h. Per WGSL spec, these are built-in type aliases, not
+// keywords.
+func lookupShortTypeAlias(name string) (shortTypeAlias, bool) {
+	if len(name) < 5 {
+		return shortTypeAlias{}, false
+	}
+	base := name[:len(name)-1]
+	var scalar string
+	switch name[len(name)-1] {
+	case 'i':
+		scalar = "i32"
+	case 'u':
+		scalar = "u32"
+	case 'f':
+		scalar = "f32"
+	case 'h':
+		scalar = "f16"
+	default:
+		return shortTypeAlias{}, false
+	}
+	switch base {
+	case "vec2", "vec3", "vec4":
+		return shortTypeAlias{base, scalar}, true
+	case "mat2x2", "mat2x3", "mat2x4", "mat3x2", "mat3x3", "mat3x4", "mat4x2", "mat4x3", "mat4x4":
+		// Matrices are float only.
+		if scalar == "f32" || scalar == "f16" {
+			return shortTypeAlias{base, scalar}, true
+		}
+	}
+	return shortTypeAlias{}, false
+}
 
+// mathFunction maps a WGSL built-in function name to its IR math function.
+func mathFunction(name string) (ir.MathFunction, bool) {
+	switch name {
+	// Comparison functions
+	case "abs":
+		return ir.MathAbs, true
+	case "min":
+		return ir.MathMin, true
+	case "max":
+		return ir.MathMax, true
+	case "clamp":
+		return ir.MathClamp, true
+	case "saturate":
+		return ir.MathSaturate, true
 	// Trigonometric functions
-	"cos":   ir.MathCos,
-	"cosh":  ir.MathCosh,
-	"sin":   ir.MathSin,
-	"sinh":  ir.MathSinh,
-	"tan":   ir.MathTan,
-	"tanh":  ir.MathTanh,
-	"acos":  ir.MathAcos,
-	"asin":  ir.MathAsin,
-	"atan":  ir.MathAtan,
-	"atan2": ir.MathAtan2,
-	"asinh": ir.MathAsinh,
-	"acosh": ir.MathAcosh,
-	"atanh": ir.MathAtanh,
-
+	case "cos":
+		return ir.MathCos, true
+	case "cosh":
+		return ir.MathCosh, true
+	case "sin":
+		return ir.MathSin, true
+	case "sinh":
+		return ir.MathSinh, true
+	case "tan":
+		return ir.MathTan, true
+	case "tanh":
+		return ir.MathTanh, true
+	case "acos":
+		return ir.MathAcos, true
+	case "asin":
+		return ir.MathAsin, true
+	case "atan":
+		return ir.MathAtan, true
+	case "atan2":
+		return ir.MathAtan2, true
+	case "asinh":
+		return ir.MathAsinh, true
+	case "acosh":
+		return ir.MathAcosh, true
+	case "atanh":
+		return ir.MathAtanh, true
 	// Angle conversion
-	"radians": ir.MathRadians,
-	"degrees": ir.MathDegrees,
-
+	case "radians":
+		return ir.MathRadians, true
+	case "degrees":
+		return ir.MathDegrees, true
 	// Decomposition functions
-	"ceil":  ir.MathCeil,
-	"floor": ir.MathFloor,
-	"round": ir.MathRound,
-	"fract": ir.MathFract,
-	"trunc": ir.MathTrunc,
-
+	case "ceil":
+		return ir.MathCeil, true
+	case "floor":
+		return ir.MathFloor, true
+	case "round":
+		return ir.MathRound, true
+	case "fract":
+		return ir.MathFract, true
+	case "trunc":
+		return ir.MathTrunc, true
 	// Exponential functions
-	"exp":  ir.MathExp,
-	"exp2": ir.MathExp2,
-	"log":  ir.MathLog,
-	"log2": ir.MathLog2,
-	"pow":  ir.MathPow,
-
+	case "exp":
+		return ir.MathExp, true
+	case "exp2":
+		return ir.MathExp2, true
+	case "log":
+		return ir.MathLog, true
+	case "log2":
+		return ir.MathLog2, true
+	case "pow":
+		return ir.MathPow, true
 	// Geometric functions
-	"dot":          ir.MathDot,
-	"dot4I8Packed": ir.MathDot4I8Packed,
-	"dot4U8Packed": ir.MathDot4U8Packed,
-	"cross":        ir.MathCross,
-	"distance":     ir.MathDistance,
-	"length":       ir.MathLength,
-	"normalize":    ir.MathNormalize,
-	"faceForward":  ir.MathFaceForward,
-	"reflect":      ir.MathReflect,
-	"refract":      ir.MathRefract,
-
+	case "dot":
+		return ir.MathDot, true
+	case "dot4I8Packed":
+		return ir.MathDot4I8Packed, true
+	case "dot4U8Packed":
+		return ir.MathDot4U8Packed, true
+	case "cross":
+		return ir.MathCross, true
+	case "distance":
+		return ir.MathDistance, true
+	case "length":
+		return ir.MathLength, true
+	case "normalize":
+		return ir.MathNormalize, true
+	case "faceForward":
+		return ir.MathFaceForward, true
+	case "reflect":
+		return ir.MathReflect, true
+	case "refract":
+		return ir.MathRefract, true
 	// Computational functions
-	"sign":        ir.MathSign,
-	"fma":         ir.MathFma,
-	"mix":         ir.MathMix,
-	"step":        ir.MathStep,
-	"smoothstep":  ir.MathSmoothStep,
-	"sqrt":        ir.MathSqrt,
-	"inverseSqrt": ir.MathInverseSqrt,
-
+	case "sign":
+		return ir.MathSign, true
+	case "fma":
+		return ir.MathFma, true
+	case "mix":
+		return ir.MathMix, true
+	case "step":
+		return ir.MathStep, true
+	case "smoothstep":
+		return ir.MathSmoothStep, true
+	case "sqrt":
+		return ir.MathSqrt, true
+	case "inverseSqrt":
+		return ir.MathInverseSqrt, true
 	// Matrix functions
-	"transpose":   ir.MathTranspose,
-	"determinant": ir.MathDeterminant,
-
+	case "transpose":
+		return ir.MathTranspose, true
+	case "determinant":
+		return ir.MathDeterminant, true
 	// Bit manipulation functions
-	"countTrailingZeros": ir.MathCountTrailingZeros,
-	"countLeadingZeros":  ir.MathCountLeadingZeros,
-	"countOneBits":       ir.MathCountOneBits,
-	"reverseBits":        ir.MathReverseBits,
-	"extractBits":        ir.MathExtractBits,
-	"insertBits":         ir.MathInsertBits,
-	"firstTrailingBit":   ir.MathFirstTrailingBit,
-	"firstLeadingBit":    ir.MathFirstLeadingBit,
-
+	case "countTrailingZeros":
+		return ir.MathCountTrailingZeros, true
+	case "countLeadingZeros":
+		return ir.MathCountLeadingZeros, true
+	case "countOneBits":
+		return ir.MathCountOneBits, true
+	case "reverseBits":
+		return ir.MathReverseBits, true
+	case "extractBits":
+		return ir.MathExtractBits, true
+	case "insertBits":
+		return ir.MathInsertBits, true
+	case "firstTrailingBit":
+		return ir.MathFirstTrailingBit, true
+	case "firstLeadingBit":
+		return ir.MathFirstLeadingBit, true
 	// Data packing functions
-	"pack4x8snorm":  ir.MathPack4x8snorm,
-	"pack4x8unorm":  ir.MathPack4x8unorm,
-	"pack2x16snorm": ir.MathPack2x16snorm,
-	"pack2x16unorm": ir.MathPack2x16unorm,
-	"pack2x16float": ir.MathPack2x16float,
-
+	case "pack4x8snorm":
+		return ir.MathPack4x8snorm, true
+	case "pack4x8unorm":
+		return ir.MathPack4x8unorm, true
+	case "pack2x16snorm":
+		return ir.MathPack2x16snorm, true
+	case "pack2x16unorm":
+		return ir.MathPack2x16unorm, true
+	case "pack2x16float":
+		return ir.MathPack2x16float, true
 	// Data unpacking functions
-	"unpack4x8snorm":  ir.MathUnpack4x8snorm,
-	"unpack4x8unorm":  ir.MathUnpack4x8unorm,
-	"unpack2x16snorm": ir.MathUnpack2x16snorm,
-	"unpack2x16unorm": ir.MathUnpack2x16unorm,
-	"unpack2x16float": ir.MathUnpack2x16float,
-	"unpack4xI8":      ir.MathUnpack4xI8,
-	"unpack4xU8":      ir.MathUnpack4xU8,
-	"pack4xI8":        ir.MathPack4xI8,
-	"pack4xU8":        ir.MathPack4xU8,
-	"pack4xI8Clamp":   ir.MathPack4xI8Clamp,
-	"pack4xU8Clamp":   ir.MathPack4xU8Clamp,
-
+	case "unpack4x8snorm":
+		return ir.MathUnpack4x8snorm, true
+	case "unpack4x8unorm":
+		return ir.MathUnpack4x8unorm, true
+	case "unpack2x16snorm":
+		return ir.MathUnpack2x16snorm, true
+	case "unpack2x16unorm":
+		return ir.MathUnpack2x16unorm, true
+	case "unpack2x16float":
+		return ir.MathUnpack2x16float, true
+	case "unpack4xI8":
+		return ir.MathUnpack4xI8, true
+	case "unpack4xU8":
+		return ir.MathUnpack4xU8, true
+	case "pack4xI8":
+		return ir.MathPack4xI8, true
+	case "pack4xU8":
+		return ir.MathPack4xU8, true
+	case "pack4xI8Clamp":
+		return ir.MathPack4xI8Clamp, true
+	case "pack4xU8Clamp":
+		return ir.MathPack4xU8Clamp, true
 	// Decomposition functions (struct return)
-	"modf":  ir.MathModf,
-	"frexp": ir.MathFrexp,
-	"ldexp": ir.MathLdexp,
-
-	// Matrix functions
-	"inverse": ir.MathInverse,
-
+	case "modf":
+		return ir.MathModf, true
+	case "frexp":
+		return ir.MathFrexp, true
+	case "ldexp":
+		return ir.MathLdexp, true
+	// Matrix functions
+	case "inverse":
+		return ir.MathInverse, true
 	// Precision
-	"quantizeToF16": ir.MathQuantizeF16,
-
+	case "quantizeToF16":
+		return ir.MathQuantizeF16, true
 	// Vector/matrix operations
-	"outerProduct": ir.MathOuter,
+	case "outerProduct":
+		return ir.MathOuter, true
+	}
+	return 0, false
 }
 
 func (l *Lowerer) getMathFunction(name string) (ir.MathFunction, bool) {
-	fn, ok := mathFuncTable[name]
-	return fn, ok
+	return mathFunction(name)
 }
 
 // isFloatOnlyMathFunc returns true for math functions that only accept float arguments.
@@ -12176,6 +12681,15 @@ func literalScalar(lit ir.LiteralValue) ir.ScalarType {
 // - Compose op Literal (broadcast scalar to each component)
 // - Literal op Compose (broadcast scalar to each component)
 func (l *Lowerer) tryFoldVectorBinaryOp(op ir.BinaryOperator, left, right ir.ExpressionHandle) (ir.ExpressionHandle, bool) {
+	// Matrix products are not componentwise; leave them to the backends.
+	if op == ir.BinaryMultiply {
+		if _, ok := l.resolveExprTypeInner(left).(ir.MatrixType); ok {
+			return 0, false
+		}
+		if _, ok := l.resolveExprTypeInner(right).(ir.MatrixType); ok {
+			return 0, false
+		}
+	}
 	litsL, okL := l.extractConstVectorLiterals(left)
 	litsR, okR := l.extractConstVectorLiterals(right)
 	litScalarL, okScalarL := l.extractConstLiteral(left)
@@ -12766,6 +13280,19 @@ func (l *Lowerer) lowerSelectCall(args []parser.Expr, target *[]ir.Statement) (i
 	l.concretizeAbstractToDefault(falseVal)
 	l.concretizeAbstractToDefault(trueVal)
 
+	// A vector condition selects per component, so the values must be
+	// vectors of the same width: select(vecN<T>, vecN<T>, vecN<bool>).
+	if cond, ok := l.resolveExprTypeInner(condition).(ir.VectorType); ok {
+		switch val := l.resolveExprTypeInner(trueVal).(type) {
+		case ir.ScalarType:
+			return 0, fmt.Errorf("select() with a vec%d<bool> condition requires vector values, got a scalar", cond.Size)
+		case ir.VectorType:
+			if val.Size != cond.Size {
+				return 0, fmt.Errorf("select() condition has %d components but the values have %d", cond.Size, val.Size)
+			}
+		}
+	}
+
 	return l.addExpression(ir.Expression{
 		Kind: ir.ExprSelect{
 			Condition: condition,
@@ -12841,13 +13368,11 @@ func (l *Lowerer) lowerRelationalCall(fun ir.RelationalFunction, args []parser.E
 		return 0, err
 	}
 
-	// Constant fold: any(scalar_bool_literal) and all(scalar_bool_literal) are identity.
-	// When the argument is a single scalar bool (not a vector), any/all just return it.
+	// any(b) and all(b) are the identity on a scalar bool, so only vector
+	// reductions reach the IR. Matches Rust naga's no-op check.
 	if fun == ir.RelationalAll || fun == ir.RelationalAny {
-		if lit, ok := l.currentFunc.Expressions[arg].Kind.(ir.Literal); ok {
-			if _, isBool := lit.Value.(ir.LiteralBool); isBool {
-				return arg, nil
-			}
+		if s, ok := l.resolveExprTypeInner(arg).(ir.ScalarType); ok && s.Kind == ir.ScalarBool {
+			return arg, nil
 		}
 	}
 
@@ -12869,6 +13394,55 @@ func (l *Lowerer) lowerArrayLengthCall(args []parser.Expr, target *[]ir.Statemen
 	}), nil
 }
 
+// checkEntryPointBuiltins rejects builtins that an entry point of this
+// stage cannot take as a parameter or return, reporting the offending
+// @builtin attribute, on the parameter, the return type, or a member of
+// the struct passed or returned.
+func (l *Lowerer) checkEntryPointBuiltins(f *parser.FunctionDecl, fn *ir.Function, stage ir.ShaderStage) error {
+	for i, p := range f.Params {
+		if err := l.checkBuiltinAttrs(p.Attributes, fn.Arguments[i].Type, stage, false); err != nil {
+			return err
+		}
+	}
+	if fn.Result != nil {
+		return l.checkBuiltinAttrs(f.ReturnAttrs, fn.Result.Type, stage, true)
+	}
+	return nil
+}
+
+func (l *Lowerer) checkBuiltinAttrs(attrs []parser.Attribute, ty ir.TypeHandle, stage ir.ShaderStage, output bool) error {
+	if err := checkBuiltinAttr(attrs, stage, output); err != nil {
+		return err
+	}
+	if decl := l.structDecls[ty]; decl != nil {
+		for _, m := range decl.Members {
+			if err := checkBuiltinAttr(m.Attributes, stage, output); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// checkBuiltinAttr checks the @builtin attribute among attrs, if any.
+func checkBuiltinAttr(attrs []parser.Attribute, stage ir.ShaderStage, output bool) error {
+	for _, attr := range attrs {
+		if attr.Name != "builtin" || len(attr.Args) == 0 {
+			continue
+		}
+		id, ok := attr.Args[0].(*parser.Ident)
+		if !ok {
+			continue
+		}
+		if b, ok := builtinTable[id.Name]; ok {
+			if err := ir.CheckBuiltinUsage(b, stage, output); err != nil {
+				return &spannedError{span: attr.Span, msg: err.Error()}
+			}
+		}
+	}
+	return nil
+}
+
 // Attribute parsing
 
 func (l *Lowerer) paramBinding(attrs []parser.Attribute) *ir.Binding {
@@ -12899,31 +13473,38 @@ func (l *Lowerer) collectBinding(attrs []parser.Attribute) *ir.Binding {
 		case "builtin":
 			if len(attr.Args) > 0 {
 				if id, ok := attr.Args[0].(*parser.Ident); ok {
-					var b ir.Binding = ir.BuiltinBinding{Builtin: l.builtin(id.Name)}
+					builtin, ok := builtinTable[id.Name]
+					if !ok {
+						builtin = ir.BuiltinPosition
+						if err := l.nonSpec(fmt.Sprintf("unknown builtin '%s'; treated as position", id.Name), attr.Span); err != nil {
+							l.addError(err.Error(), attr.Span)
+							continue
+						}
+					}
+					var b ir.Binding = ir.BuiltinBinding{Builtin: builtin}
 					builtinBinding = &b
 				}
 			}
 		case "location":
-			if len(attr.Args) > 0 {
-				if lit, ok := attr.Args[0].(*parser.Literal); ok {
-					loc, _ := strconv.ParseUint(lit.Value, 10, 32)
-					if locBinding == nil {
-						locBinding = &ir.LocationBinding{}
-					}
-					locBinding.Location = uint32(loc)
-				}
+			loc, err := l.evalAttributeArg(attr, 0)
+			if err != nil {
+				l.addError(err.Error(), attr.Span)
+				continue
+			}
+			if locBinding == nil {
+				locBinding = &ir.LocationBinding{}
 			}
+			locBinding.Location = loc
 		case "blend_src":
-			if len(attr.Args) > 0 {
-				if lit, ok := attr.Args[0].(*parser.Literal); ok {
-					idx, _ := strconv.ParseUint(lit.Value, 10, 32)
-					if locBinding == nil {
-						locBinding = &ir.LocationBinding{}
-					}
-					v := uint32(idx)
-					locBinding.BlendSrc = &v
-				}
+			idx, err := l.evalAttributeArg(attr, 0)
+			if err != nil {
+				l.addError(err.Error(), attr.Span)
+				continue
+			}
+			if locBinding == nil {
+				locBinding = &ir.LocationBinding{}
 			}
+			locBinding.BlendSrc = &idx
 		case "interpolate":
 			interp := l.parseInterpolateAttr(attr)
 			if interp != nil {
@@ -12990,6 +13571,10 @@ func (l *Lowerer) parseInterpolateAttr(attr *parser.Attribute) *ir.Interpolation
 				sampling = ir.SamplingCentroid
 			case "sample":
 				sampling = ir.SamplingSample
+			case "first":
+				sampling = ir.SamplingFirst
+			case "either":
+				sampling = ir.SamplingEither
 			}
 		}
 	}
@@ -13092,10 +13677,6 @@ func (l *Lowerer) entryPointStage(attrs []parser.Attribute) *ir.ShaderStage {
 	return nil
 }
 
-// extractWorkgroupSize extracts workgroup_size from attributes.
-// Returns [x, y, z] where defaults are 1.
-// Handles literal values, constant references (TWO, THREE), and simple
-// constant expressions (TWO - 1u). Matches Rust naga's const_u32 evaluation.
 // extractEarlyDepthTest checks for @early_depth_test attribute and returns the configuration.
 // Matches Rust: @early_depth_test(force) → Force, @early_depth_test(less_equal) → Allow(LessEqual).
 func (l *Lowerer) extractEarlyDepthTest(attrs []parser.Attribute) *ir.EarlyDepthTest {
@@ -13123,70 +13704,38 @@ func (l *Lowerer) extractEarlyDepthTest(attrs []parser.Attribute) *ir.EarlyDepth
 	return nil
 }
 
-func (l *Lowerer) extractWorkgroupSize(attrs []parser.Attribute) [3]uint32 {
+// extractWorkgroupSize extracts workgroup_size from attributes.
+// Returns [x, y, z] where defaults are 1, and the override handle of each
+// axis given by an override name. Other arguments must be positive integer
+// const-expressions (literals, constants such as TWO, or TWO - 1u).
+func (l *Lowerer) extractWorkgroupSize(attrs []parser.Attribute) ([3]uint32, [3]*ir.OverrideHandle, error) {
 	result := [3]uint32{1, 1, 1}
+	var overrides [3]*ir.OverrideHandle
 	for _, attr := range attrs {
 		if attr.Name != "workgroup_size" {
 			continue
 		}
-		for i, arg := range attr.Args {
-			if i >= 3 {
-				break
-			}
-			if val, ok := l.evalConstU32Expr(arg); ok {
-				result[i] = val
-			}
-		}
-		break
-	}
-	return result
-}
-
-// evalConstU32Expr evaluates an expression as a compile-time u32 constant.
-// Handles literals, constant identifier references, and simple binary expressions.
-func (l *Lowerer) evalConstU32Expr(expr parser.Expr) (uint32, bool) {
-	switch e := expr.(type) {
-	case *parser.Literal:
-		if val, err := strconv.ParseUint(e.Value, 10, 32); err == nil {
-			return uint32(val), true
-		}
-		// Try parsing as signed
-		if val, err := strconv.ParseInt(e.Value, 10, 32); err == nil && val >= 0 {
-			return uint32(val), true
+		if len(attr.Args) == 0 || len(attr.Args) > 3 {
+			return result, overrides, fmt.Errorf("@workgroup_size expects 1 to 3 arguments, got %d", len(attr.Args))
 		}
-	case *parser.Ident:
-		// Look up named constant
-		for _, c := range l.module.Constants {
-			if c.Name == e.Name {
-				if sv, ok := c.Value.(ir.ScalarValue); ok {
-					return uint32(sv.Bits), true
+		for i, arg := range attr.Args {
+			// A bare override name sizes the axis at pipeline creation;
+			// backends resolve it through ProcessOverrides.
+			if ident, ok := arg.(*parser.Ident); ok {
+				if h, ok := l.moduleOverrides[ident.Name]; ok {
+					overrides[i] = &h
+					continue
 				}
 			}
-		}
-	case *parser.BinaryExpr:
-		left, okL := l.evalConstU32Expr(e.Left)
-		right, okR := l.evalConstU32Expr(e.Right)
-		if okL && okR {
-			switch e.Op {
-			case parser.TokenPlus:
-				return left + right, true
-			case parser.TokenMinus:
-				return left - right, true
-			case parser.TokenStar:
-				return left * right, true
-			case parser.TokenSlash:
-				if right != 0 {
-					return left / right, true
-				}
+			val, err := l.evalConstIndex("@workgroup_size", arg, 1)
+			if err != nil {
+				return result, overrides, err
 			}
+			result[i] = val
 		}
-	case *parser.CallExpr:
-		// Handle type constructors like u32(expr)
-		if len(e.Args) == 1 {
-			return l.evalConstU32Expr(e.Args[0])
-		}
+		break
 	}
-	return 0, false
+	return result, overrides, nil
 }
 
 // extractTaskPayload extracts the task payload global variable from @payload(varName) attribute.
@@ -13362,13 +13911,8 @@ var builtinTable = map[string]ir.BuiltinValue{
 	"primitive_count":        ir.BuiltinPrimitiveCount,
 	"primitives":             ir.BuiltinPrimitives,
 	"clip_distances":         ir.BuiltinClipDistance,
-}
-
-func (l *Lowerer) builtin(name string) ir.BuiltinValue {
-	if b, ok := builtinTable[name]; ok {
-		return b
-	}
-	return ir.BuiltinPosition // Default
+	// Not in WGSL: a naga extension for GLES/Metal point sprites.
+	"point_size": ir.BuiltinPointSize,
 }
 
 // addressSpaceTable maps WGSL address space names to IR address spaces.
@@ -13384,11 +13928,13 @@ var addressSpaceTable = map[string]ir.AddressSpace{
 	"immediate":     ir.SpaceImmediate,
 }
 
-func (l *Lowerer) addressSpace(space string) ir.AddressSpace {
+// addressSpace returns the IR address space named space. For an empty or
+// unknown name it returns SpaceFunction and false.
+func (l *Lowerer) addressSpace(space string) (ir.AddressSpace, bool) {
 	if s, ok := addressSpaceTable[space]; ok {
-		return s
+		return s, true
 	}
-	return ir.SpaceFunction // Default
+	return ir.SpaceFunction, false
 }
 
 // isOpaqueResourceType checks if a type is an opaque resource (sampler or image/texture).
@@ -13515,6 +14061,16 @@ func (l *Lowerer) parseSampledScalarKind(param parser.Type) ir.ScalarKind {
 	}
 }
 
+// storageFormatName returns the WGSL spelling of a storage format.
+func storageFormatName(format ir.StorageFormat) string {
+	for name, f := range storageFormatTable {
+		if f == format {
+			return name
+		}
+	}
+	return fmt.Sprintf("format %d", format)
+}
+
 // parseStorageFormat parses a storage texture format from a type parameter.
 // storageFormatTable maps WGSL storage format names to IR storage formats.
 var storageFormatTable = map[string]ir.StorageFormat{
@@ -13610,62 +14166,308 @@ func (l *Lowerer) parseStorageAccess(param parser.Type) ir.StorageAccess {
 	}
 }
 
-// binaryOpTable maps token kinds to binary operators.
-var binaryOpTable = map[parser.TokenKind]ir.BinaryOperator{
-	parser.TokenPlus:           ir.BinaryAdd,
-	parser.TokenMinus:          ir.BinarySubtract,
-	parser.TokenStar:           ir.BinaryMultiply,
-	parser.TokenSlash:          ir.BinaryDivide,
-	parser.TokenPercent:        ir.BinaryModulo,
-	parser.TokenEqualEqual:     ir.BinaryEqual,
-	parser.TokenBangEqual:      ir.BinaryNotEqual,
-	parser.TokenLess:           ir.BinaryLess,
-	parser.TokenLessEqual:      ir.BinaryLessEqual,
-	parser.TokenGreater:        ir.BinaryGreater,
-	parser.TokenGreaterEqual:   ir.BinaryGreaterEqual,
-	parser.TokenAmpAmp:         ir.BinaryLogicalAnd,
-	parser.TokenPipePipe:       ir.BinaryLogicalOr,
-	parser.TokenAmpersand:      ir.BinaryAnd,
-	parser.TokenPipe:           ir.BinaryInclusiveOr,
-	parser.TokenCaret:          ir.BinaryExclusiveOr,
-	parser.TokenLessLess:       ir.BinaryShiftLeft,
-	parser.TokenGreaterGreater: ir.BinaryShiftRight,
-}
-
-// unaryOpTable maps token kinds to unary operators.
-var unaryOpTable = map[parser.TokenKind]ir.UnaryOperator{
-	parser.TokenMinus: ir.UnaryNegate,
-	parser.TokenBang:  ir.UnaryLogicalNot,
-	parser.TokenTilde: ir.UnaryBitwiseNot,
+// binaryOperator maps a binary operator token to its IR operator.
+func binaryOperator(tok parser.TokenKind) (ir.BinaryOperator, bool) {
+	switch tok {
+	case parser.TokenPlus:
+		return ir.BinaryAdd, true
+	case parser.TokenMinus:
+		return ir.BinarySubtract, true
+	case parser.TokenStar:
+		return ir.BinaryMultiply, true
+	case parser.TokenSlash:
+		return ir.BinaryDivide, true
+	case parser.TokenPercent:
+		return ir.BinaryModulo, true
+	case parser.TokenEqualEqual:
+		return ir.BinaryEqual, true
+	case parser.TokenBangEqual:
+		return ir.BinaryNotEqual, true
+	case parser.TokenLess:
+		return ir.BinaryLess, true
+	case parser.TokenLessEqual:
+		return ir.BinaryLessEqual, true
+	case parser.TokenGreater:
+		return ir.BinaryGreater, true
+	case parser.TokenGreaterEqual:
+		return ir.BinaryGreaterEqual, true
+	case parser.TokenAmpAmp:
+		return ir.BinaryLogicalAnd, true
+	case parser.TokenPipePipe:
+		return ir.BinaryLogicalOr, true
+	case parser.TokenAmpersand:
+		return ir.BinaryAnd, true
+	case parser.TokenPipe:
+		return ir.BinaryInclusiveOr, true
+	case parser.TokenCaret:
+		return ir.BinaryExclusiveOr, true
+	case parser.TokenLessLess:
+		return ir.BinaryShiftLeft, true
+	case parser.TokenGreaterGreater:
+		return ir.BinaryShiftRight, true
+	}
+	return 0, false
+}
+
+// unaryOperator maps a unary operator token to its IR operator.
+func unaryOperator(tok parser.TokenKind) (ir.UnaryOperator, bool) {
+	switch tok {
+	case parser.TokenMinus:
+		return ir.UnaryNegate, true
+	case parser.TokenBang:
+		return ir.UnaryLogicalNot, true
+	case parser.TokenTilde:
+		return ir.UnaryBitwiseNot, true
+	}
+	return 0, false
 }
 
 func (l *Lowerer) tokenToBinaryOp(tok parser.TokenKind) ir.BinaryOperator {
-	if op, ok := binaryOpTable[tok]; ok {
+	if op, ok := binaryOperator(tok); ok {
 		return op
 	}
 	return ir.BinaryAdd // Default
 }
 
 func (l *Lowerer) tokenToUnaryOp(tok parser.TokenKind) ir.UnaryOperator {
-	if op, ok := unaryOpTable[tok]; ok {
+	if op, ok := unaryOperator(tok); ok {
 		return op
 	}
 	return ir.UnaryNegate // Default
 }
 
 // checkUnusedVariables reports warnings for local variables that are declared but never used.
+// Warnings are reported in declaration order.
 func (l *Lowerer) checkUnusedVariables(funcName string) {
-	for name, span := range l.localDecls {
-		if !l.usedLocals[name] {
-			// Variables starting with _ are intentionally unused
-			if len(name) > 0 && name[0] == '_' {
-				continue
+	start := len(l.warnings)
+	for _, d := range l.unusedDecls {
+		// Variables starting with _ are intentionally unused
+		if len(d.name) > 0 && d.name[0] == '_' {
+			continue
+		}
+		l.warnings = append(l.warnings, Warning{
+			Message: fmt.Sprintf("unused variable '%s' in function '%s'", d.name, funcName),
+			Span:    d.span,
+		})
+	}
+	added := l.warnings[start:]
+	sort.Slice(added, func(i, j int) bool {
+		a, b := added[i].Span.Start, added[j].Span.Start
+		if a.Line != b.Line {
+			return a.Line < b.Line
+		}
+		return a.Column < b.Column
+	})
+}
+
+// checkUnusedParameters reports warnings for parameters of f that are never
+// read. Entry point parameters are exempt: they declare the pipeline
+// interface whether or not the body reads them.
+func (l *Lowerer) checkUnusedParameters(f *parser.FunctionDecl) {
+	if l.entryPointFuncs[f.Name] {
+		return
+	}
+	for _, p := range f.Params {
+		if l.usedLocals[p.Name] || strings.HasPrefix(p.Name, "_") {
+			continue
+		}
+		l.warnings = append(l.warnings, Warning{
+			Message: fmt.Sprintf("unused parameter '%s' in function '%s'", p.Name, f.Name),
+			Span:    p.Span,
+		})
+	}
+}
+
+// checkUnusedGlobals reports warnings for var<private> globals that no
+// function references.
+func (l *Lowerer) checkUnusedGlobals() {
+	for _, v := range l.privateGlobals {
+		if l.usedGlobals[v.Name] || strings.HasPrefix(v.Name, "_") {
+			continue
+		}
+		l.warnings = append(l.warnings, Warning{
+			Message: fmt.Sprintf("unused private variable '%s'", v.Name),
+			Span:    v.Span,
+		})
+	}
+}
+
+// checkUnusedFunctions reports warnings for functions that no function or
+// entry point calls. A module without entry points is a library whose
+// functions are all its interface, so it gets none.
+func (l *Lowerer) checkUnusedFunctions(funcs []*parser.FunctionDecl) {
+	if len(l.entryPointFuncs) == 0 {
+		return
+	}
+	for _, f := range funcs {
+		if l.entryPointFuncs[f.Name] || l.calledFuncs[f.Name] || strings.HasPrefix(f.Name, "_") {
+			continue
+		}
+		l.warnings = append(l.warnings, Warning{
+			Message: fmt.Sprintf("unused function '%s'", f.Name),
+			Span:    f.Span,
+		})
+	}
+}
+
+// derivativeUse is a derivative expression whose uniformity is checked,
+// with the severity in effect where it appears.
+type derivativeUse struct {
+	severity ir.Severity
+	span     parser.Span
+}
+
+// diagnosticSeverity converts a parser severity name.
+func diagnosticSeverity(name string) ir.Severity {
+	switch name {
+	case parser.SeverityOff:
+		return ir.SeverityOff
+	case parser.SeverityInfo:
+		return ir.SeverityInfo
+	case parser.SeverityError:
+		return ir.SeverityError
+	default:
+		return ir.SeverityWarning
+	}
+}
+
+// statementDiagnostics returns the @diagnostic filters attached to stmt.
+func statementDiagnostics(stmt parser.Stmt) []parser.Diagnostic {
+	switch s := stmt.(type) {
+	case *parser.BlockStmt:
+		return s.Diagnostics
+	case *parser.IfStmt:
+		return s.Diagnostics
+	case *parser.ForStmt:
+		return s.Diagnostics
+	case *parser.WhileStmt:
+		return s.Diagnostics
+	case *parser.LoopStmt:
+		return s.Diagnostics
+	case *parser.SwitchStmt:
+		return s.Diagnostics
+	}
+	return nil
+}
+
+// checkDiagnostics validates the filters of one scope. Two filters for the
+// same rule with different severities conflict (an error); a single-token
+// rule this implementation does not know draws a warning, while dotted
+// rules are accepted silently as the WGSL spec requires.
+func (l *Lowerer) checkDiagnostics(diags []parser.Diagnostic) error {
+	for i, d := range diags {
+		for _, prev := range diags[:i] {
+			if prev.Rule == d.Rule && prev.Severity != d.Severity {
+				return fmt.Errorf("conflicting diagnostic filters for '%s': %s and %s", d.Rule, prev.Severity, d.Severity)
 			}
-			l.warnings = append(l.warnings, Warning{
-				Message: fmt.Sprintf("unused variable '%s' in function '%s'", name, funcName),
-				Span:    span,
-			})
 		}
+		if strings.Contains(d.Rule, ".") || d.Rule == ir.RuleDerivativeUniformity || d.Rule == ir.RuleSubgroupUniformity {
+			continue
+		}
+		l.warnings = append(l.warnings, Warning{
+			Message: fmt.Sprintf("unrecognized diagnostic rule '%s'", d.Rule),
+			Span:    d.Span,
+		})
+	}
+	return nil
+}
+
+// addDiagnosticFilter appends a filter node under parent and returns its handle.
+func (l *Lowerer) addDiagnosticFilter(d parser.Diagnostic, parent *ir.DiagnosticFilterHandle) *ir.DiagnosticFilterHandle {
+	h := ir.DiagnosticFilterHandle(len(l.module.DiagnosticFilters))
+	l.module.DiagnosticFilters = append(l.module.DiagnosticFilters, ir.DiagnosticFilterNode{
+		Inner: ir.DiagnosticFilter{
+			NewSeverity:    diagnosticSeverity(d.Severity),
+			TriggeringRule: d.Rule,
+		},
+		Parent: parent,
+	})
+	return &h
+}
+
+// lowerDiagnosticDirectives records the module's diagnostic directives as a
+// chain of filters, each directive's parent being the one before it.
+// Matches Rust naga's Module.diagnostic_filter_leaf.
+func (l *Lowerer) lowerDiagnosticDirectives(diags []parser.Diagnostic) {
+	if err := l.checkDiagnostics(diags); err != nil {
+		l.addError(err.Error(), diags[len(diags)-1].Span)
+		return
+	}
+	for _, d := range diags {
+		l.module.DiagnosticFilterLeaf = l.addDiagnosticFilter(d, l.module.DiagnosticFilterLeaf)
+	}
+}
+
+// lowerFunctionDiagnostics chains a function's @diagnostic attributes
+// under the module filters and returns the function's filter leaf.
+func (l *Lowerer) lowerFunctionDiagnostics(attrs []parser.Attribute) (*ir.DiagnosticFilterHandle, error) {
+	var diags []parser.Diagnostic
+	for _, attr := range attrs {
+		if attr.Name != "diagnostic" {
+			continue
+		}
+		d, err := attr.Diagnostic()
+		if err != nil {
+			return nil, err
+		}
+		diags = append(diags, d)
+	}
+	if err := l.checkDiagnostics(diags); err != nil {
+		return nil, err
+	}
+	leaf := l.module.DiagnosticFilterLeaf
+	for _, d := range diags {
+		leaf = l.addDiagnosticFilter(d, leaf)
+	}
+	return leaf, nil
+}
+
+// recordDerivative remembers a derivative expression of the current
+// function for checkDerivativeUniformity, unless derivative_uniformity
+// is off or info where it appears. Without any filter the rule is a
+// warning: the WGSL spec makes it an error, but Rust naga does not
+// enforce it and existing shaders rely on that.
+func (l *Lowerer) recordDerivative(h ir.ExpressionHandle) {
+	severity := ir.SeverityWarning
+	found := false
+	for i := len(l.stmtDiagnostics) - 1; i >= 0; i-- {
+		if d := l.stmtDiagnostics[i]; d.Rule == ir.RuleDerivativeUniformity {
+			severity, found = diagnosticSeverity(d.Severity), true
+			break
+		}
+	}
+	if !found {
+		severity = l.module.DiagnosticSeverity(l.currentFunc.DiagnosticFilterLeaf, ir.RuleDerivativeUniformity, ir.SeverityWarning)
+	}
+	if severity < ir.SeverityWarning {
+		return
+	}
+	if l.derivatives == nil {
+		l.derivatives = make(map[ir.ExpressionHandle]derivativeUse, 4)
+	}
+	l.derivatives[h] = derivativeUse{severity: severity, span: l.stmtSpan}
+}
+
+// checkDerivativeUniformity reports the recorded derivatives of a fragment
+// entry point that are evaluated in non-uniform control flow, once per
+// statement.
+func (l *Lowerer) checkDerivativeUniformity(fn *ir.Function) {
+	if len(l.derivatives) == 0 {
+		return
+	}
+	reported := make(map[parser.Span]bool)
+	for _, h := range ir.NonUniformDerivatives(l.module, fn) {
+		use, ok := l.derivatives[h]
+		if !ok || reported[use.span] {
+			continue
+		}
+		reported[use.span] = true
+		message := fmt.Sprintf("derivative in non-uniform control flow (%s)", ir.RuleDerivativeUniformity)
+		if use.severity == ir.SeverityError {
+			l.addError(message, use.span)
+			continue
+		}
+		l.warnings = append(l.warnings, Warning{Message: message, Span: use.span})
 	}
 }
 
@@ -13715,22 +14517,35 @@ func (l *Lowerer) registerUnusedLetBindings() {
 	}
 }
 
-// assignOpTable maps compound assignment token kinds to binary operators.
-var assignOpTable = map[parser.TokenKind]ir.BinaryOperator{
-	parser.TokenPlusEqual:           ir.BinaryAdd,
-	parser.TokenMinusEqual:          ir.BinarySubtract,
-	parser.TokenStarEqual:           ir.BinaryMultiply,
-	parser.TokenSlashEqual:          ir.BinaryDivide,
-	parser.TokenPercentEqual:        ir.BinaryModulo,
-	parser.TokenAmpEqual:            ir.BinaryAnd,
-	parser.TokenPipeEqual:           ir.BinaryInclusiveOr,
-	parser.TokenCaretEqual:          ir.BinaryExclusiveOr,
-	parser.TokenLessLessEqual:       ir.BinaryShiftLeft,
-	parser.TokenGreaterGreaterEqual: ir.BinaryShiftRight,
+// assignOperator maps a compound assignment token to its binary operator.
+func assignOperator(tok parser.TokenKind) (ir.BinaryOperator, bool) {
+	switch tok {
+	case parser.TokenPlusEqual:
+		return ir.BinaryAdd, true
+	case parser.TokenMinusEqual:
+		return ir.BinarySubtract, true
+	case parser.TokenStarEqual:
+		return ir.BinaryMultiply, true
+	case parser.TokenSlashEqual:
+		return ir.BinaryDivide, true
+	case parser.TokenPercentEqual:
+		return ir.BinaryModulo, true
+	case parser.TokenAmpEqual:
+		return ir.BinaryAnd, true
+	case parser.TokenPipeEqual:
+		return ir.BinaryInclusiveOr, true
+	case parser.TokenCaretEqual:
+		return ir.BinaryExclusiveOr, true
+	case parser.TokenLessLessEqual:
+		return ir.BinaryShiftLeft, true
+	case parser.TokenGreaterGreaterEqual:
+		return ir.BinaryShiftRight, true
+	}
+	return 0, false
 }
 
 func (l *Lowerer) assignOpToBinary(tok parser.TokenKind) ir.BinaryOperator {
-	if op, ok := assignOpTable[tok]; ok {
+	if op, ok := assignOperator(tok); ok {
 		return op
 	}
 	return ir.BinaryAdd // Default
@@ -14060,19 +14875,19 @@ func (l *Lowerer) lowerTextureCall(name string, args []parser.Expr, target *[]ir
 
 	case "textureDimensions":
 		// textureDimensions(t) or textureDimensions(t, level)
-		return l.lowerTextureQuery(args, target, ir.ImageQuerySize{})
+		return l.lowerTextureQuery(name, args, target, ir.ImageQuerySize{})
 
 	case "textureNumLevels":
 		// textureNumLevels(t)
-		return l.lowerTextureQuery(args, target, ir.ImageQueryNumLevels{})
+		return l.lowerTextureQuery(name, args, target, ir.ImageQueryNumLevels{})
 
 	case "textureNumLayers":
 		// textureNumLayers(t)
-		return l.lowerTextureQuery(args, target, ir.ImageQueryNumLayers{})
+		return l.lowerTextureQuery(name, args, target, ir.ImageQueryNumLayers{})
 
 	case "textureNumSamples":
 		// textureNumSamples(t)
-		return l.lowerTextureQuery(args, target, ir.ImageQueryNumSamples{})
+		return l.lowerTextureQuery(name, args, target, ir.ImageQueryNumSamples{})
 
 	default:
 		return 0, fmt.Errorf("unknown texture function: %s", name)
@@ -14223,6 +15038,9 @@ func (l *Lowerer) lowerTextureSample(args []parser.Expr, target *[]ir.Statement,
 	if err != nil {
 		return 0, err
 	}
+	if err := l.checkFilterableTexture(args[0], image); err != nil {
+		return 0, err
+	}
 
 	sampler, err := l.lowerExpression(args[1], target)
 	if err != nil {
@@ -14276,7 +15094,7 @@ func (l *Lowerer) lowerTextureSample(args []parser.Expr, target *[]ir.Statement,
 }
 
 // lowerTextureSampleCompare converts a depth texture comparison sampling call to IR.
-// textureSampleCompare(t, s, coord, depth_ref) or (t, s, coord, array_index, depth_ref)
+// textureSampleCompare(t, s, coord [, array_index], depth_ref [, offset])
 func (l *Lowerer) lowerTextureSampleCompare(args []parser.Expr, target *[]ir.Statement, level ir.SampleLevel) (ir.ExpressionHandle, error) {
 	if len(args) < 4 {
 		return 0, fmt.Errorf("textureSampleCompare requires at least 4 arguments")
@@ -14317,12 +15135,24 @@ func (l *Lowerer) lowerTextureSampleCompare(args []parser.Expr, target *[]ir.Sta
 	}
 	l.convertExpressionToFloat(depthRef) // depth_ref must be float
 
+	// Optional offset after depth_ref (2D and 2D array only).
+	var offset *ir.ExpressionHandle
+	if depthRefIdx+1 < len(args) {
+		off, offErr := l.lowerExpression(args[depthRefIdx+1], target)
+		if offErr != nil {
+			return 0, offErr
+		}
+		l.concretizeExpressionToScalar(off, ir.ScalarType{Kind: ir.ScalarSint, Width: 4})
+		offset = &off
+	}
+
 	return l.addExpression(ir.Expression{
 		Kind: ir.ExprImageSample{
 			Image:      image,
 			Sampler:    sampler,
 			Coordinate: coord,
 			ArrayIndex: arrayIndex,
+			Offset:     offset,
 			Level:      level,
 			DepthRef:   &depthRef,
 		},
@@ -14340,6 +15170,9 @@ func (l *Lowerer) lowerTextureSampleClampToEdge(args []parser.Expr, target *[]ir
 	if err != nil {
 		return 0, err
 	}
+	if err := l.checkFilterableTexture(args[0], image); err != nil {
+		return 0, err
+	}
 
 	sampler, err := l.lowerExpression(args[1], target)
 	if err != nil {
@@ -14532,78 +15365,322 @@ func (l *Lowerer) evalGatherComponent(expr parser.Expr) (ir.SwizzleComponent, er
 
 // isTextureArrayed checks if a texture expression refers to an arrayed image type.
 func (l *Lowerer) isTextureArrayed(expr parser.Expr) bool {
-	ident, ok := expr.(*parser.Ident)
-	if !ok {
-		return false
-	}
-	for _, gv := range l.module.GlobalVariables {
-		if gv.Name == ident.Name {
-			if int(gv.Type) < len(l.module.Types) {
-				if img, ok := l.module.Types[gv.Type].Inner.(ir.ImageType); ok {
-					return img.Arrayed
+	img, ok := l.getTextureImageType(expr)
+	return ok && img.Arrayed
+}
+
+// isTextureDepth checks if a texture expression refers to a depth image type.
+func (l *Lowerer) isTextureDepth(expr parser.Expr) bool {
+	img, ok := l.getTextureImageType(expr)
+	return ok && img.Class == ir.ImageClassDepth
+}
+
+// isTextureMultisampled checks if a texture expression refers to a multisampled image type.
+func (l *Lowerer) isTextureMultisampled(expr parser.Expr) bool {
+	img, ok := l.getTextureImageType(expr)
+	return ok && img.Multisampled
+}
+
+// getTextureImageType retrieves the ImageType for a texture expression, if available.
+// The texture builtins pick their overload from it: whether an argument after
+// the coordinate is an array index, a sample index, or a level depends on the
+// image type, which a texture reaches through a global, a function parameter,
+// or an element of a binding array.
+func (l *Lowerer) getTextureImageType(expr parser.Expr) (ir.ImageType, bool) {
+	img, ok := l.textureArgType(expr).(ir.ImageType)
+	return img, ok
+}
+
+// textureArgType returns the type of a texture argument expression without
+// lowering it: a global or local (function parameter) name, or an index into
+// a binding array.
+func (l *Lowerer) textureArgType(expr parser.Expr) ir.TypeInner {
+	switch e := expr.(type) {
+	case *parser.Ident:
+		if h, ok := l.locals[e.Name]; ok {
+			return l.resolveExprTypeInner(h)
+		}
+		for _, gv := range l.module.GlobalVariables {
+			if gv.Name == e.Name {
+				if int(gv.Type) < len(l.module.Types) {
+					return l.module.Types[gv.Type].Inner
 				}
+				return nil
 			}
-			return false
+		}
+	case *parser.IndexExpr:
+		if arr, ok := l.textureArgType(e.Expr).(ir.BindingArrayType); ok && int(arr.Base) < len(l.module.Types) {
+			return l.module.Types[arr.Base].Inner
 		}
 	}
-	return false
+	return nil
 }
 
-// isTextureDepth checks if a texture expression refers to a depth image type.
-func (l *Lowerer) isTextureDepth(expr parser.Expr) bool {
-	ident, ok := expr.(*parser.Ident)
+// checkFilterableTexture rejects sampling a texture with integer texels:
+// only float textures can go through a sampler, integer ones must use
+// textureLoad or textureGather. Catching it here reports the texture
+// argument instead of a bare expression handle from IR validation.
+func (l *Lowerer) checkFilterableTexture(arg parser.Expr, image ir.ExpressionHandle) error {
+	img, ok := l.resolveExprTypeInner(image).(ir.ImageType)
+	if !ok || img.Class != ir.ImageClassSampled || img.SampledKind == ir.ScalarFloat {
+		return nil
+	}
+	return &spannedError{
+		span: arg.Pos(),
+		msg: fmt.Sprintf("cannot sample a texture of %s texels; use textureLoad or textureGather",
+			typeName(ir.ScalarType{Kind: img.SampledKind, Width: 4})),
+	}
+}
+
+// checkInitType rejects a declaration whose explicit type is not the type
+// of its initializer, such as `let x: i32 = 1.5;` or an integer texture
+// loaded into a float variable. Abstract values may convert to the
+// declared type; WGSL has no other implicit conversions. It must run
+// before the initializer is concretized to the declared type.
+func (l *Lowerer) checkInitType(name string, declared ir.TypeHandle, init ir.ExpressionHandle, expr parser.Expr, span parser.Span) error {
+	have, ok := l.valueConvertsTo(init, expr, declared)
+	if ok {
+		return nil
+	}
+	want := l.module.Types[declared].Inner
+	switch l.currentFunc.Expressions[init].Kind.(type) {
+	case ir.ExprImageSample, ir.ExprImageLoad:
+		return &spannedError{
+			span: span,
+			msg: fmt.Sprintf("cannot initialize '%s' of type %s with a %s texel; convert it explicitly with %s(...)",
+				name, typeName(want), typeName(have), typeName(want)),
+		}
+	}
+	return &spannedError{
+		span: span,
+		msg:  fmt.Sprintf("cannot initialize '%s' of type %s with a value of type %s", name, typeName(want), typeName(have)),
+	}
+}
+
+// checkStoreType rejects an assignment of a value whose type is not the
+// type the pointer refers to. Like checkInitType it runs before the value
+// is concretized.
+func (l *Lowerer) checkStoreType(pointer, value ir.ExpressionHandle, expr parser.Expr, span parser.Span) error {
+	want := l.resolveExprTypeInner(pointer) // the pointee type
+	have := l.resolveExprTypeInner(value)
+	if want == nil || have == nil || l.convertibleTo(have, want, nil) {
+		return nil
+	}
+	leaf, ok := l.abstractLeaf(value, expr, have)
+	if ok && l.convertibleTo(have, want, &leaf) {
+		return nil
+	}
+	if ok {
+		have = withScalar(have, leaf)
+	}
+	return &spannedError{
+		span: span,
+		msg:  fmt.Sprintf("cannot assign a value of type %s to a reference of type %s", typeName(have), typeName(want)),
+	}
+}
+
+// valueConvertsTo reports whether value, lowered from expr, can be used
+// where a value of type want is expected, and returns the type to name in
+// an error. Unresolved types pass.
+func (l *Lowerer) valueConvertsTo(value ir.ExpressionHandle, expr parser.Expr, want ir.TypeHandle) (ir.TypeInner, bool) {
+	if l.currentFunc == nil || int(value) >= len(l.currentFunc.ExpressionTypes) || int(want) >= len(l.module.Types) {
+		return nil, true
+	}
+	res := l.currentFunc.ExpressionTypes[value]
+	if res.Handle != nil && *res.Handle == want {
+		return nil, true
+	}
+	have := ir.TypeResInner(l.module, res)
+	if have == nil {
+		return nil, true
+	}
+	if _, ok := have.(ir.StructType); ok && res.Handle != nil {
+		return have, false
+	}
+	wantInner := l.module.Types[want].Inner
+	if l.convertibleTo(have, wantInner, nil) {
+		return nil, true
+	}
+	leaf, ok := l.abstractLeaf(value, expr, have)
 	if !ok {
-		return false
+		return have, false
 	}
-	for _, gv := range l.module.GlobalVariables {
-		if gv.Name == ident.Name {
-			if int(gv.Type) < len(l.module.Types) {
-				if img, ok := l.module.Types[gv.Type].Inner.(ir.ImageType); ok {
-					return img.Class == ir.ImageClassDepth
-				}
+	return withScalar(have, leaf), l.convertibleTo(have, wantInner, &leaf)
+}
+
+// abstractLeaf returns the abstract scalar type of value, lowered from
+// expr, when it is an abstract value of type have. The expression types
+// record abstract values with the concrete types they default to, and
+// folding can leave the lowered value concrete, as for `1 << 3u` or an
+// element of an abstract array constant; the const-expression expr still
+// tells.
+func (l *Lowerer) abstractLeaf(value ir.ExpressionHandle, expr parser.Expr, have ir.TypeInner) (ir.ScalarType, bool) {
+	if l.isAbstractValue(value) {
+		if s, ok := l.leafScalar(have); ok {
+			return abstractScalar(s), true
+		}
+	}
+	switch e := expr.(type) {
+	case nil:
+		return ir.ScalarType{}, false
+	case *parser.ConstructExpr:
+		// vec2() and mat2x2() are zero values of abstract type.
+		if named, ok := e.Type.(*parser.NamedType); ok && len(e.Args) == 0 && len(named.TypeParams) == 0 {
+			switch {
+			case strings.HasPrefix(named.Name, "vec"):
+				return ir.ScalarType{Kind: ir.ScalarAbstractInt, Width: 8}, true
+			case strings.HasPrefix(named.Name, "mat"):
+				return ir.ScalarType{Kind: ir.ScalarAbstractFloat, Width: 8}, true
 			}
-			return false
 		}
 	}
-	return false
+	v, err := l.evalConstValue(expr)
+	if err != nil || !v.leaf().abstract {
+		return ir.ScalarType{}, false
+	}
+	return ir.ScalarType{Kind: v.leaf().value.Kind, Width: 8}, true
 }
 
-// isTextureMultisampled checks if a texture expression refers to a multisampled image type.
-func (l *Lowerer) isTextureMultisampled(expr parser.Expr) bool {
-	ident, ok := expr.(*parser.Ident)
-	if !ok {
+// isAbstractValue reports whether value is an abstract literal, or is
+// built from abstract literals alone. Constructors with an explicit type
+// concretize their arguments, so their components are never abstract.
+func (l *Lowerer) isAbstractValue(value ir.ExpressionHandle) bool {
+	if l.currentFunc == nil || int(value) >= len(l.currentFunc.Expressions) {
 		return false
 	}
-	for _, gv := range l.module.GlobalVariables {
-		if gv.Name == ident.Name {
-			if int(gv.Type) < len(l.module.Types) {
-				if img, ok := l.module.Types[gv.Type].Inner.(ir.ImageType); ok {
-					return img.Multisampled
-				}
+	switch e := l.currentFunc.Expressions[value].Kind.(type) {
+	case ir.Literal:
+		switch e.Value.(type) {
+		case ir.LiteralAbstractInt, ir.LiteralAbstractFloat:
+			return true
+		}
+	case ir.ExprSplat:
+		return l.isAbstractValue(e.Value)
+	case ir.ExprUnary:
+		return l.isAbstractValue(e.Expr)
+	case ir.ExprBinary:
+		if e.Op == ir.BinaryShiftLeft || e.Op == ir.BinaryShiftRight {
+			return l.isAbstractValue(e.Left)
+		}
+		return l.isAbstractValue(e.Left) && l.isAbstractValue(e.Right)
+	case ir.ExprAccess:
+		return l.isAbstractValue(e.Base)
+	case ir.ExprAccessIndex:
+		return l.isAbstractValue(e.Base)
+	case ir.ExprCompose:
+		for _, c := range e.Components {
+			if !l.isAbstractValue(c) {
+				return false
 			}
-			return false
 		}
+		return len(e.Components) > 0
 	}
 	return false
 }
 
-// getTextureImageType retrieves the ImageType for a texture expression, if available.
-func (l *Lowerer) getTextureImageType(expr parser.Expr) (ir.ImageType, bool) {
-	ident, ok := expr.(*parser.Ident)
-	if !ok {
-		return ir.ImageType{}, false
+// leafScalar returns the scalar type of a scalar, vector or matrix, or of
+// the elements of an array of them.
+func (l *Lowerer) leafScalar(inner ir.TypeInner) (ir.ScalarType, bool) {
+	switch t := inner.(type) {
+	case ir.ScalarType:
+		return t, true
+	case ir.VectorType:
+		return t.Scalar, true
+	case ir.MatrixType:
+		return t.Scalar, true
+	case ir.ArrayType:
+		if int(t.Base) < len(l.module.Types) {
+			return l.leafScalar(l.module.Types[t.Base].Inner)
+		}
 	}
-	for _, gv := range l.module.GlobalVariables {
-		if gv.Name == ident.Name {
-			if int(gv.Type) < len(l.module.Types) {
-				if img, ok := l.module.Types[gv.Type].Inner.(ir.ImageType); ok {
-					return img, true
-				}
-			}
-			return ir.ImageType{}, false
+	return ir.ScalarType{}, false
+}
+
+// withScalar returns inner, a scalar, vector or matrix type, with its
+// scalar replaced.
+func withScalar(inner ir.TypeInner, scalar ir.ScalarType) ir.TypeInner {
+	switch t := inner.(type) {
+	case ir.ScalarType:
+		return scalar
+	case ir.VectorType:
+		return ir.VectorType{Size: t.Size, Scalar: scalar}
+	case ir.MatrixType:
+		return ir.MatrixType{Columns: t.Columns, Rows: t.Rows, Scalar: scalar}
+	}
+	return inner
+}
+
+// abstractScalar returns the abstract type whose values default to the
+// concrete scalar s.
+func abstractScalar(s ir.ScalarType) ir.ScalarType {
+	switch s.Kind {
+	case ir.ScalarFloat:
+		return ir.ScalarType{Kind: ir.ScalarAbstractFloat, Width: 8}
+	case ir.ScalarSint:
+		return ir.ScalarType{Kind: ir.ScalarAbstractInt, Width: 8}
+	}
+	return s
+}
+
+// convertibleTo reports whether a value of type have can be used where a
+// want is expected: the types are equal, or the value is abstract and its
+// scalars, leaf when it is not nil, convert to want's automatically.
+// Struct handles are compared by the caller; pointee types and opaque types
+// are left to IR validation.
+func (l *Lowerer) convertibleTo(have, want ir.TypeInner, leaf *ir.ScalarType) bool {
+	switch w := want.(type) {
+	case ir.ScalarType:
+		h, ok := have.(ir.ScalarType)
+		return ok && scalarConvertibleTo(h, w, leaf)
+	case ir.VectorType:
+		h, ok := have.(ir.VectorType)
+		return ok && h.Size == w.Size && scalarConvertibleTo(h.Scalar, w.Scalar, leaf)
+	case ir.MatrixType:
+		h, ok := have.(ir.MatrixType)
+		return ok && h.Columns == w.Columns && h.Rows == w.Rows && scalarConvertibleTo(h.Scalar, w.Scalar, leaf)
+	case ir.ArrayType:
+		h, ok := have.(ir.ArrayType)
+		if !ok {
+			return false
+		}
+		if h.Base == w.Base || int(h.Base) >= len(l.module.Types) || int(w.Base) >= len(l.module.Types) {
+			return true
 		}
+		return l.convertibleTo(l.module.Types[h.Base].Inner, l.module.Types[w.Base].Inner, leaf)
+	case ir.StructType:
+		_, ok := have.(ir.StructType)
+		return ok
+	case ir.PointerType:
+		switch have.(type) {
+		case ir.PointerType, ir.ValuePointerType:
+			return true
+		}
+		return false
+	case ir.AtomicType:
+		_, ok := have.(ir.AtomicType)
+		return ok
 	}
-	return ir.ImageType{}, false
+	return true
+}
+
+// scalarConvertibleTo reports whether a scalar of type have, or leaf when
+// it is not nil, converts to want. An abstract integer converts to any
+// integer or float type, an abstract float to any float type.
+func scalarConvertibleTo(have, want ir.ScalarType, leaf *ir.ScalarType) bool {
+	if leaf != nil {
+		have = *leaf
+	}
+	switch have.Kind {
+	case ir.ScalarAbstractInt:
+		switch want.Kind {
+		case ir.ScalarSint, ir.ScalarUint, ir.ScalarFloat, ir.ScalarAbstractInt, ir.ScalarAbstractFloat:
+			return true
+		}
+		return false
+	case ir.ScalarAbstractFloat:
+		return want.Kind == ir.ScalarFloat || want.Kind == ir.ScalarAbstractFloat
+	}
+	return have == want
 }
 
 // lowerTextureLoad converts a texture load call to IR.
@@ -14713,6 +15790,16 @@ func (l *Lowerer) lowerTextureStore(args []parser.Expr, target *[]ir.Statement)
 	// This matches Rust naga's expression_with_leaf_scalar for textureStore.
 	if imgType, ok := l.getTextureImageType(args[0]); ok && imgType.Class == ir.ImageClassStorage {
 		scalarKind := imgType.StorageFormat.ScalarKind()
+		// Only abstract values convert; a concrete value of the wrong kind
+		// would otherwise be silently rewritten.
+		if got, ok := l.resolveExprScalar(value); ok && got.Kind != scalarKind && l.initHasConcreteType(args[nextArg]) {
+			return 0, &spannedError{
+				span: args[nextArg].Pos(),
+				msg: fmt.Sprintf("textureStore: %s value does not match the %s format, whose texels are %s",
+					typeName(l.resolveExprTypeInner(value)), storageFormatName(imgType.StorageFormat),
+					typeName(ir.VectorType{Size: ir.Vec4, Scalar: ir.ScalarType{Kind: scalarKind, Width: 4}})),
+			}
+		}
 		switch scalarKind {
 		case ir.ScalarFloat:
 			l.convertExpressionToFloat(value)
@@ -14754,6 +15841,27 @@ func (l *Lowerer) lowerTextureAtomic(name string, args []parser.Expr, target *[]
 		return 0, fmt.Errorf("%s requires at least 3 arguments", name)
 	}
 
+	// Texture atomics operate on single-channel integer storage textures
+	// declared with atomic access, which lowers to read-write access.
+	imgType, ok := l.getTextureImageType(args[0])
+	if ok && (imgType.Class != ir.ImageClassStorage ||
+		(imgType.StorageAccess != ir.StorageAccessReadWrite && imgType.StorageAccess != ir.StorageAccessAtomic)) {
+		return 0, &spannedError{
+			span: args[0].Pos(),
+			msg:  fmt.Sprintf("%s requires a storage texture with atomic access, such as texture_storage_2d<r32uint, atomic>", name),
+		}
+	}
+	if ok {
+		switch imgType.StorageFormat {
+		case ir.StorageFormatR32Uint, ir.StorageFormatR32Sint, ir.StorageFormatR64Uint:
+		default:
+			return 0, &spannedError{
+				span: args[0].Pos(),
+				msg:  fmt.Sprintf("%s: the %s format does not support atomics; use r32uint, r32sint, or r64uint", name, storageFormatName(imgType.StorageFormat)),
+			}
+		}
+	}
+
 	image, err := l.lowerExpression(args[0], target)
 	if err != nil {
 		return 0, err
@@ -14781,6 +15889,19 @@ func (l *Lowerer) lowerTextureAtomic(name string, args []parser.Expr, target *[]
 	if err != nil {
 		return 0, err
 	}
+	if ok {
+		// The value has the texel type of the format; abstract integers
+		// take it, other values must already have it.
+		scalar := imgType.StorageFormat.Scalar()
+		if got, gotOK := l.resolveExprScalar(value); gotOK && got != scalar && l.initHasConcreteType(args[nextArg]) {
+			return 0, &spannedError{
+				span: args[nextArg].Pos(),
+				msg: fmt.Sprintf("%s: %s value does not match the %s format, whose texels are %s",
+					name, typeName(l.resolveExprTypeInner(value)), storageFormatName(imgType.StorageFormat), typeName(scalar)),
+			}
+		}
+		l.concretizeExpressionToScalar(value, scalar)
+	}
 
 	var fun ir.AtomicFunction
 	switch name {
@@ -14812,32 +15933,50 @@ func (l *Lowerer) lowerTextureAtomic(name string, args []parser.Expr, target *[]
 	return 0, nil
 }
 
-// lowerTextureQuery converts a texture query call to IR.
-func (l *Lowerer) lowerTextureQuery(args []parser.Expr, target *[]ir.Statement, query ir.ImageQuery) (ir.ExpressionHandle, error) {
+// lowerTextureQuery converts a texture query call to IR. Only
+// textureDimensions takes a second argument, the mip level, which must be
+// an i32 or u32 (an abstract integer becomes i32, as in Rust naga) and is
+// only allowed for textures with mip levels: not multisampled, storage or
+// external ones.
+func (l *Lowerer) lowerTextureQuery(name string, args []parser.Expr, target *[]ir.Statement, query ir.ImageQuery) (ir.ExpressionHandle, error) {
+	sizeQuery, isSize := query.(ir.ImageQuerySize)
+	maxArgs := 1
+	if isSize {
+		maxArgs = 2
+	}
+	if len(args) > maxArgs {
+		return 0, fmt.Errorf("%s takes at most %d argument(s), got %d", name, maxArgs, len(args))
+	}
+
 	image, err := l.lowerExpression(args[0], target)
 	if err != nil {
 		return 0, err
 	}
 
-	// For textureDimensions with level argument — concretize abstract int to i32.
-	// Rust naga concretizes the level argument (e.g., literal 1 → I32(1)).
-	if len(args) > 1 {
-		if sizeQuery, ok := query.(ir.ImageQuerySize); ok {
-			level, err := l.lowerExpression(args[1], target)
-			if err != nil {
-				return 0, err
+	if isSize && len(args) > 1 {
+		if img, ok := l.getTextureImageType(args[0]); ok {
+			switch {
+			case img.Multisampled:
+				return 0, fmt.Errorf("textureDimensions: multisampled textures have no level argument")
+			case img.Class == ir.ImageClassStorage:
+				return 0, fmt.Errorf("textureDimensions: storage textures have no level argument")
+			case img.Class == ir.ImageClassExternal:
+				return 0, fmt.Errorf("textureDimensions: external textures have no level argument")
 			}
-			// Concretize AbstractInt → I32 for image query level
-			if int(level) < len(l.currentFunc.Expressions) {
-				if lit, ok := l.currentFunc.Expressions[level].Kind.(ir.Literal); ok {
-					if ai, ok := lit.Value.(ir.LiteralAbstractInt); ok {
-						l.currentFunc.Expressions[level].Kind = ir.Literal{Value: ir.LiteralI32(int32(ai))}
-					}
-				}
+		}
+		level, err := l.lowerExpression(args[1], target)
+		if err != nil {
+			return 0, err
+		}
+		l.concretizeAbstractToDefault(level)
+		if inner := l.resolveExprTypeInner(level); inner != nil {
+			sc, ok := inner.(ir.ScalarType)
+			if !ok || sc.Width != 4 || sc.Kind != ir.ScalarSint && sc.Kind != ir.ScalarUint {
+				return 0, fmt.Errorf("textureDimensions: level must be i32 or u32, got %s", typeName(inner))
 			}
-			sizeQuery.Level = &level
-			query = sizeQuery
 		}
+		sizeQuery.Level = &level
+		query = sizeQuery
 	}
 
 	return l.addExpression(ir.Expression{
@@ -15139,7 +16278,10 @@ func (l *Lowerer) lowerTypeConstructorCall(typeHandle ir.TypeHandle, args []pars
 	// final Compose (not the named alias type).
 	{
 		origLen := len(components)
-		components = l.groupMatrixColumns(typeHandle, components)
+		components, err := l.groupMatrixColumns(typeHandle, components)
+		if err != nil {
+			return 0, err
+		}
 		composeType := typeHandle
 		if origLen > 0 && len(components) != origLen {
 			if int(typeHandle) < len(l.module.Types) {
@@ -15370,6 +16512,24 @@ func (l *Lowerer) lowerSubgroupGather(gatherKind string, args []parser.Expr, tar
 		if len(args) < 2 {
 			return 0, fmt.Errorf("quadBroadcast requires 2 arguments")
 		}
+		if err := l.checkQuadArgument(gatherKind, args[0], argument); err != nil {
+			return 0, err
+		}
+		// The lane is a const-expression, so every backend can pass it
+		// where a constant operand is required (SPIR-V before 1.5).
+		_, lane, err := l.evalConstantIntExpr(args[1])
+		if err != nil {
+			return 0, &spannedError{
+				span: args[1].Pos(),
+				msg:  "quadBroadcast: the lane id must be a const-expression",
+			}
+		}
+		if lane < 0 || lane >= 4 {
+			return 0, &spannedError{
+				span: args[1].Pos(),
+				msg:  fmt.Sprintf("quadBroadcast: the lane id must be in [0, 4), got %d", lane),
+			}
+		}
 		index, err := l.lowerExpression(args[1], target)
 		if err != nil {
 			return 0, err
@@ -15414,6 +16574,10 @@ func (l *Lowerer) lowerQuadSwap(funcName string, args []parser.Expr, target *[]i
 		return 0, err
 	}
 
+	if err := l.checkQuadArgument(funcName, args[0], argument); err != nil {
+		return 0, err
+	}
+
 	var dir ir.QuadDirection
 	switch funcName {
 	case "quadSwapX":
@@ -15448,6 +16612,24 @@ func (l *Lowerer) lowerQuadSwap(funcName string, args []parser.Expr, target *[]i
 	return resultHandle, nil
 }
 
+// checkQuadArgument rejects quad operation values that are not numeric
+// scalars or vectors.
+func (l *Lowerer) checkQuadArgument(funcName string, arg parser.Expr, argument ir.ExpressionHandle) error {
+	scalar, ok := l.resolveExprScalar(argument)
+	switch l.resolveExprTypeInner(argument).(type) {
+	case ir.ScalarType, ir.VectorType:
+		if !ok || scalar.Kind != ir.ScalarBool {
+			return nil
+		}
+	case nil:
+		return nil
+	}
+	return &spannedError{
+		span: arg.Pos(),
+		msg:  fmt.Sprintf("%s: the value must be a numeric scalar or vector, got %s", funcName, typeName(l.resolveExprTypeInner(argument))),
+	}
+}
+
 // ensureTypeHandle returns a TypeHandle for the given TypeResolution.
 // If the resolution already has a handle, it is returned directly.
 // Otherwise, the type is registered through the type registry for proper deduplication.
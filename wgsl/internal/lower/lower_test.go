@@ -1,6 +1,8 @@
 package lower
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"testing"
 
@@ -1064,6 +1066,39 @@ fn main() {
 	}
 }
 
+// TestLowerCompoundAssignThroughPointer verifies that *p += v and (*p)++
+// load the current value through a pointer parameter before storing.
+func TestLowerCompoundAssignThroughPointer(t *testing.T) {
+	src := `fn bump(p: ptr<function, i32>) {
+    *p += 2i;
+    (*p)++;
+}
+@compute @workgroup_size(1)
+fn main() {
+    var x = 0i;
+    bump(&x);
+}`
+	module, err := compileWGSL(t, src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fn := &module.Functions[0]
+	loads := 0
+	for _, e := range fn.Expressions {
+		switch k := e.Kind.(type) {
+		case ir.ExprLoad:
+			loads++
+		case ir.ExprBinary:
+			if _, ok := fn.Expressions[k.Left].Kind.(ir.ExprFunctionArgument); ok {
+				t.Errorf("binary operand is the pointer argument itself")
+			}
+		}
+	}
+	if loads != 2 {
+		t.Errorf("got %d loads, want 2", loads)
+	}
+}
+
 // TestLowerNestedMatrixColumnGrouping verifies that matrix constructors nested
 // inside array constructors produce column-grouped Compose GEs.
 // array<mat2x2<f32>, 1>(mat2x2<f32>(0, 1, 2, 3)) should create:
@@ -1529,7 +1564,7 @@ fn main() {
 fn main() {
     takes_vec(1.0);
 }`,
-			wantErr: "type mismatch (expected vec2<f32>, got f32)",
+			wantErr: "type mismatch (expected vec2<f32>, got AbstractFloat)",
 		},
 		{
 			name: "vec3 passed as vec4",
@@ -1630,3 +1665,215 @@ func searchSubstring(s, substr string) bool {
 	}
 	return false
 }
+
+// TestLowerFunctionNamedTexture checks that a user identifier spelled
+// "texture" is not mistaken for a texture_* type name.
+func TestLowerFunctionNamedTexture(t *testing.T) {
+	module := mustCompile(t, `
+fn texture(x: f32) -> f32 {
+    return x * 2.0;
+}
+
+@fragment
+fn main() -> @location(0) vec4<f32> {
+    return vec4<f32>(texture(1.0));
+}
+`)
+	if len(module.Functions) != 1 || module.Functions[0].Name != "texture" {
+		t.Fatalf("expected one function named texture, got %+v", module.Functions)
+	}
+}
+
+func TestLowerWithWarningsContextCanceled(t *testing.T) {
+	src := `
+fn helper() -> f32 { return 1.0; }
+@compute @workgroup_size(1)
+fn main() { _ = helper(); }
+`
+	tokens, err := parser.NewLexer(src).Tokenize()
+	if err != nil {
+		t.Fatal(err)
+	}
+	ast, err := parser.NewParser(tokens).Parse()
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := LowerWithWarningsContext(ctx, ast, src); !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context.Canceled, got %v", err)
+	}
+	if _, err := LowerWithWarningsContext(context.Background(), ast, src); err != nil {
+		t.Fatalf("lowering with a live context failed: %v", err)
+	}
+}
+
+func TestRelationalScalarBoolIsIdentity(t *testing.T) {
+	module := mustCompile(t, `
+fn f(b: bool, v: vec2<bool>) -> bool {
+    return any(b) && all(!b) && any(v);
+}
+`)
+	var funs []ir.RelationalFunction
+	for _, expr := range module.Functions[0].Expressions {
+		if rel, ok := expr.Kind.(ir.ExprRelational); ok {
+			funs = append(funs, rel.Fun)
+		}
+	}
+	if len(funs) != 1 || funs[0] != ir.RelationalAny {
+		t.Errorf("relational expressions = %v, want only the vector any", funs)
+	}
+}
+
+func TestSelectVectorCondition(t *testing.T) {
+	module := mustCompile(t, `
+fn f(a: vec4<f32>, b: vec4<f32>) -> vec4<f32> {
+    return select(a, b, a < b);
+}
+`)
+	fn := &module.Functions[0]
+	for _, expr := range fn.Expressions {
+		sel, ok := expr.Kind.(ir.ExprSelect)
+		if !ok {
+			continue
+		}
+		cond, ok := ir.TypeResInner(module, fn.ExpressionTypes[sel.Condition]).(ir.VectorType)
+		if !ok || cond.Size != ir.Vec4 || cond.Scalar.Kind != ir.ScalarBool {
+			t.Errorf("select condition type = %+v, want vec4<bool>", fn.ExpressionTypes[sel.Condition])
+		}
+		return
+	}
+	t.Fatal("no select expression")
+}
+
+func TestSelectVectorConditionMismatch(t *testing.T) {
+	expectError(t, `fn f(m: vec4<bool>) -> f32 { return select(0.0, 1.0, m); }`,
+		"requires vector values")
+	expectError(t, `fn f(m: vec2<bool>) -> vec4<f32> { return select(vec4(0.0), vec4(1.0), m); }`,
+		"condition has 2 components but the values have 4")
+}
+
+// TestPointSizeBuiltin verifies the @builtin(point_size) extension lowers to
+// BuiltinPointSize rather than falling back to position.
+func TestPointSizeBuiltin(t *testing.T) {
+	module := mustCompile(t, `
+struct VsOut {
+    @builtin(position) pos: vec4<f32>,
+    @builtin(point_size) size: f32,
+}
+
+@vertex
+fn vs(@location(0) pos: vec4<f32>) -> VsOut {
+    return VsOut(pos, 4.0);
+}
+`)
+	result := module.EntryPoints[0].Function.Result
+	st, ok := module.Types[result.Type].Inner.(ir.StructType)
+	if !ok || len(st.Members) != 2 {
+		t.Fatalf("expected a two-member struct result, got %T", module.Types[result.Type].Inner)
+	}
+	bb, ok := (*st.Members[1].Binding).(ir.BuiltinBinding)
+	if !ok || bb.Builtin != ir.BuiltinPointSize {
+		t.Errorf("size binding = %#v, want BuiltinPointSize", *st.Members[1].Binding)
+	}
+}
+
+// TestTexelTypeMismatch verifies texel kind mismatches are reported at the
+// offending argument or declaration instead of reaching the backends.
+func TestTexelTypeMismatch(t *testing.T) {
+	expectError(t, `@group(0) @binding(0) var t: texture_2d<i32>;
+@group(0) @binding(1) var s: sampler;
+fn f() -> vec4<i32> { return textureSample(t, s, vec2(0.5)); }`,
+		"3:44: function f body: cannot sample a texture of i32 texels")
+	expectError(t, `@group(0) @binding(0) var t: texture_2d<u32>;
+fn f() { let c: vec4<f32> = textureLoad(t, vec2(0), 0); }`,
+		"cannot initialize 'c' of type vec4<f32> with a vec4<u32> texel")
+	expectError(t, `enable f16;
+@group(0) @binding(0) var t: texture_2d<f32>;
+@group(0) @binding(1) var s: sampler;
+fn f() { var c: vec4<f16> = textureSampleLevel(t, s, vec2(0.5), 0.0); }`,
+		"cannot initialize 'c' of type vec4<f16> with a vec4<f32> texel")
+	expectError(t, `@group(0) @binding(0) var t: texture_storage_2d<rgba8unorm, write>;
+fn f() { textureStore(t, vec2(0), vec4<i32>(1)); }`,
+		"2:35: function f body: textureStore: vec4<i32> value does not match the rgba8unorm format")
+
+	// Abstract values still convert to the format's texel type.
+	mustCompile(t, `@group(0) @binding(0) var t: texture_storage_2d<r32uint, write>;
+@group(0) @binding(1) var u: texture_storage_2d<rgba8unorm, write>;
+fn f() {
+    textureStore(t, vec2(0), vec4(1));
+    textureStore(u, vec2(0), vec4(1, 0, 0, 1));
+}`)
+}
+
+// TestValueTypeMismatch verifies initializers, assignments and call
+// arguments of the wrong type are rejected instead of being converted.
+func TestValueTypeMismatch(t *testing.T) {
+	expectError(t, `fn f() { let x: i32 = 1.5; }`,
+		"cannot initialize 'x' of type i32 with a value of type AbstractFloat")
+	expectError(t, `fn f() { let c: vec4<f32> = vec4<u32>(1u); }`,
+		"cannot initialize 'c' of type vec4<f32> with a value of type vec4<u32>")
+	expectError(t, `fn f() { var x: f32 = 42u; }`,
+		"cannot initialize 'x' of type f32 with a value of type u32")
+	expectError(t, `fn f() {
+    var x: i32;
+    x = 2u;
+}`, "3:5: function f body: cannot assign a value of type u32 to a reference of type i32")
+	expectError(t, `fn f() {
+    var v: vec2<f32>;
+    v.x = 1i;
+}`, "cannot assign a value of type i32 to a reference of type f32")
+	expectError(t, `fn g(v: vec2<f32>) {}
+fn f() { g(vec2<i32>(1i)); }`,
+		"function 'g' argument 0: type mismatch (expected vec2<f32>, got vec2<i32>)")
+
+	// Abstract values convert to the declared type.
+	mustCompile(t, `const A = array(1, 2);
+@group(0) @binding(0) var t: texture_2d<i32>;
+fn g(a: array<f32, 2>, v: vec2<u32>) {}
+fn f() {
+    let x: f32 = 1;
+    let s = 1 << 3u;
+    let y: i32 = s;
+    var z: u32 = 1 << 3u;
+    var m: mat2x2<f32> = mat2x2();
+    var w: f32 = A[0];
+    z = 7;
+    let c: vec2<i32> = textureLoad(t, vec2(0), 0).xy;
+    g(A, vec2());
+}`)
+}
+
+// TestEntryPointBuiltinStage verifies builtins used in the wrong stage or
+// direction are reported at their @builtin attribute.
+func TestEntryPointBuiltinStage(t *testing.T) {
+	expectError(t, `@fragment
+fn fs(@builtin(vertex_index) i: u32) -> @location(0) vec4<f32> { return vec4<f32>(0.0); }`,
+		"2:7: entry point 'fs': @builtin(vertex_index) cannot be an input of a @fragment entry point")
+	expectError(t, `@compute @workgroup_size(1)
+fn cs(@builtin(frag_depth) d: f32) {}`,
+		"2:7: entry point 'cs': @builtin(frag_depth) cannot be an input of a @compute entry point")
+	expectError(t, `struct Out {
+    @builtin(position) pos: vec4<f32>,
+    @builtin(frag_depth) depth: f32,
+}
+@vertex
+fn vs() -> Out { return Out(vec4<f32>(0.0), 0.5); }`,
+		"3:5: entry point 'vs': @builtin(frag_depth) cannot be an output of a @vertex entry point")
+	expectError(t, `struct In {
+    @builtin(global_invocation_id) id: vec3<u32>,
+}
+@fragment
+fn fs(in: In) {}`,
+		"2:5: entry point 'fs': @builtin(global_invocation_id) cannot be an input of a @fragment entry point")
+
+	// The same struct may be a vertex output and a fragment input.
+	mustCompile(t, `struct V {
+    @builtin(position) pos: vec4<f32>,
+    @location(0) uv: vec2<f32>,
+}
+@vertex
+fn vs(@builtin(vertex_index) i: u32) -> V { return V(vec4<f32>(0.0), vec2<f32>(0.0)); }
+@fragment
+fn fs(in: V) -> @builtin(frag_depth) f32 { return in.pos.z; }`)
+}
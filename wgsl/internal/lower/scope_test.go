@@ -0,0 +1,245 @@
+package lower
+
+import (
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/gogpu/naga/ir"
+)
+
+func TestScopeShadowsModuleDeclarations(t *testing.T) {
+	// Mirrors globals.wgsl: function-scope variables named after a module
+	// constant and a workgroup variable.
+	module := mustCompile(t, `
+const Foo: bool = true;
+var<workgroup> at: atomic<u32>;
+
+@compute @workgroup_size(1)
+fn main() {
+    var Foo: f32 = 1.0;
+    var at: bool = true;
+    if at {
+        Foo = 2.0;
+    }
+}
+`)
+	fn := &module.EntryPoints[0].Function
+	want := map[string]ir.ScalarKind{"Foo": ir.ScalarFloat, "at": ir.ScalarBool}
+	if len(fn.LocalVars) != len(want) {
+		t.Fatalf("got %d local variables, want %d", len(fn.LocalVars), len(want))
+	}
+	for _, v := range fn.LocalVars {
+		scalar, ok := module.Types[v.Type].Inner.(ir.ScalarType)
+		if !ok || scalar.Kind != want[v.Name] {
+			t.Errorf("local %q has type %+v, want scalar kind %v", v.Name, module.Types[v.Type].Inner, want[v.Name])
+		}
+	}
+	for _, expr := range fn.Expressions {
+		switch expr.Kind.(type) {
+		case ir.ExprGlobalVariable, ir.ExprConstant:
+			t.Errorf("shadowed name resolved to module scope: %T", expr.Kind)
+		}
+	}
+}
+
+func TestScopeLocalConstShadowsArraySize(t *testing.T) {
+	module := mustCompile(t, `
+const N = 4;
+
+@compute @workgroup_size(1)
+fn main() {
+    var outer: array<f32, N>;
+    {
+        const N = 3u;
+        var inner: array<f32, N>;
+        inner[0] = outer[0];
+    }
+}
+`)
+	fn := &module.EntryPoints[0].Function
+	want := map[string]uint32{"outer": 4, "inner": 3}
+	for _, v := range fn.LocalVars {
+		arr, ok := module.Types[v.Type].Inner.(ir.ArrayType)
+		if !ok || arr.Size.Constant == nil {
+			t.Fatalf("local %q is not a fixed-size array", v.Name)
+		}
+		if *arr.Size.Constant != want[v.Name] {
+			t.Errorf("local %q has %d elements, want %d", v.Name, *arr.Size.Constant, want[v.Name])
+		}
+	}
+}
+
+func TestScopeShadowing(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+	}{
+		{"param in body", `fn f(x: i32) -> i32 { let x = 2.0; return i32(x); }`},
+		{"nested block", `fn f() -> f32 { var x = 1; { var x = 2.0; x += 1.0; } return f32(x); }`},
+		{"initializer sees outer", `fn f() -> i32 { let x = 1; { let x = x + 1; return x; } }`},
+		{"if and else", `fn f(c: bool) -> i32 { let x = 1; if c { let x = 2; return x; } else { let x = 3u; return i32(x); } }`},
+		{"for init in body", `fn f() { for (var i = 0; i < 2; i++) { let i = 5.0; _ = i; } }`},
+		{"continuing shadows body", `fn f() { var i = 0; loop { let j = i; continuing { let j = 1u; i += i32(j); break if i > 3; } } }`},
+		{"switch cases", `fn f(s: i32) -> i32 { switch s { case 0: { let v = 1; return v; } default: { let v = 2.0; return i32(v); } } }`},
+		{"abstract const", `fn f() -> f32 { const k = 2; { let k = 0.5; return k; } }`},
+		{"let shadows var", `fn f() -> i32 { var x = 1; { let x = 2; return x; } }`},
+		{"var restored after block", `fn f() -> i32 { var x = 1; { let x = 2; _ = x; } x = 3; return x; }`},
+		{"function name", `fn g() {} fn f() { let g = 1; _ = g; }`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mustCompile(t, tt.body)
+		})
+	}
+}
+
+func TestScopeEndsWithBlock(t *testing.T) {
+	tests := []struct {
+		src  string
+		want string
+	}{
+		{`fn f() -> i32 { { const M = 2; } return M; }`, "1:41: function f body: 'M' is used outside of its scope (declared at 1:19)"},
+		{`fn f(c: bool) -> i32 { if c { let y = 1; } return y; }`, "1:51: function f body: 'y' is used outside of its scope (declared at 1:31)"},
+		{`fn f() { for (var i = 0; i < 2; i++) {} i = 3; }`, "'i' is used outside of its scope (declared at 1:15)"},
+		{`fn f() { loop { var v = 1; continuing { break if v > 0; } } v = 2; }`, "'v' is used outside of its scope"},
+		{`fn f() -> i32 { return z; }`, "1:24: function f body: unresolved identifier: z"},
+	}
+	for _, tt := range tests {
+		expectError(t, tt.src, tt.want)
+	}
+}
+
+func TestScopeUnusedVariableWarnings(t *testing.T) {
+	// Each declaration is tracked on its own: a used shadowing variable does
+	// not hide an unused shadowed one, and the other way around.
+	warnings := lowerWarnings(t, `
+fn f(p: i32) -> i32 {
+    var a = 1;
+    var b = 2;
+    {
+        var a = 3;
+        a += 1;
+        var b = 4;
+    }
+    {
+        var p = 5;
+    }
+    return b;
+}
+`)
+	type loc struct{ line, col int }
+	want := []struct {
+		msg string
+		loc loc
+	}{
+		{"unused variable 'a' in function 'f'", loc{3, 5}},
+		{"unused variable 'b' in function 'f'", loc{8, 9}},
+		{"unused variable 'p' in function 'f'", loc{11, 9}},
+		{"unused parameter 'p' in function 'f'", loc{2, 6}},
+	}
+	if len(warnings) != len(want) {
+		t.Fatalf("got warnings %q, want %d", warningMessages(warnings), len(want))
+	}
+	for i, w := range want {
+		got := warnings[i]
+		if got.Message != w.msg || got.Span.Start.Line != w.loc.line || got.Span.Start.Column != w.loc.col {
+			t.Errorf("warning %d = %q at %d:%d, want %q at %d:%d", i, got.Message,
+				got.Span.Start.Line, got.Span.Start.Column, w.msg, w.loc.line, w.loc.col)
+		}
+	}
+}
+
+func TestScopeRedefinition(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+	}{
+		{"var twice", `fn f() { var x = 1; var x = 2; }`},
+		{"let after var", `fn f() { var x = 1; let x = 2; }`},
+		{"const after let", `fn f() { let x = 1; const x = 2; }`},
+		{"in block", `fn f() { { let x = 1; let x = 2; } }`},
+		{"in for body", `fn f() { for (var i = 0; i < 2; i++) { let x = 1; var x = 2; } }`},
+		{"for init", `fn f() { for (var i = 0; i < 2; i++) { } var i = 0; var i = 1; }`},
+		{"parameters", `fn f(x: i32, x: f32) {}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			expectError(t, tt.src, "redefinition of '")
+		})
+	}
+	expectError(t, `fn f() { var x = 1; var x = 2; }`,
+		"1:21: function f body: redefinition of 'x' (previously declared at 1:10)")
+}
+
+func TestModuleScopeRedefinition(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+	}{
+		{"function", "fn f() {}\nfn f() {}"},
+		{"struct", "struct S { a: f32 }\nstruct S { b: u32 }"},
+		{"global", "var<private> g: f32;\nvar<private> g: u32;"},
+		{"const", "const c = 1;\nconst c = 2;"},
+		{"override", "override o: u32;\noverride o: f32;"},
+		{"alias", "alias A = f32;\nalias A = u32;"},
+		{"function and global", "fn g() {}\nvar<private> g: f32;"},
+		{"struct and const", "struct S { a: f32 }\nconst S = 1;"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			expectError(t, tt.src, "2:1: redefinition of '")
+			expectError(t, tt.src, "(previously declared at 1:1)")
+		})
+	}
+
+	// References resolve to the first declaration, so the redefinition is
+	// the only error.
+	_, err := compileWGSL(t, "const c: u32 = 1u;\nconst c: f32 = 2.0;\nfn f() -> u32 { return c; }")
+	if err == nil || strings.Contains(err.Error(), "more error") {
+		t.Errorf("got %v, want the redefinition alone", err)
+	}
+}
+
+func TestModuleScopeForwardReferences(t *testing.T) {
+	module := mustCompile(t, `
+var<private> g: S;
+const A = B * 2u;
+const B = C + 1u;
+var<private> arr: array<f32, C>;
+struct S { t: T, v: V }
+alias V = vec2<f32>;
+struct T { x: f32 }
+const C = 3u;
+`)
+	i := slices.IndexFunc(module.Constants, func(c ir.Constant) bool { return c.Name == "A" })
+	if i < 0 {
+		t.Fatal("no constant A")
+	}
+	init := module.GlobalExpressions[module.Constants[i].Init].Kind
+	if lit, ok := init.(ir.Literal); !ok || lit.Value != ir.LiteralU32(8) {
+		t.Errorf("A = %#v, want 8u", init)
+	}
+}
+
+func TestModuleScopeCycles(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		want string
+	}{
+		{"const", "const A = B;\nconst B = A;", "1:1: declaration of 'A' is cyclic: A -> B -> A"},
+		{"struct", "struct S { a: S }", "1:1: declaration of 'S' is recursive"},
+		{"structs", "struct A { b: B }\nstruct B { a: array<A, 2> }", "1:1: declaration of 'A' is cyclic: A -> B -> A"},
+		{"aliases", "alias X = Y;\nalias Y = X;", "1:1: declaration of 'X' is cyclic: X -> Y -> X"},
+		{"override", "override O: u32 = O + 1u;", "1:1: declaration of 'O' is recursive"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := compileWGSL(t, tt.src)
+			if err == nil || err.Error() != tt.want {
+				t.Errorf("error = %v, want %q alone", err, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,108 @@
+package lower
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/gogpu/naga/wgsl/internal/parser"
+)
+
+func TestStrict(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		want string
+	}{
+		{
+			name: "unknown builtin",
+			src: `@vertex fn main(@builtin(bogus) p: u32) -> @builtin(position) vec4<f32> {
+    return vec4<f32>(f32(p));
+}`,
+			want: "unknown builtin 'bogus'; treated as position",
+		},
+		{
+			name: "no address space",
+			src: `var x: f32;
+@compute @workgroup_size(1) fn main() { x = 1.0; }`,
+			want: "global variable 'x' has no address space; treated as function",
+		},
+		{
+			name: "unknown address space",
+			src: `var<bogus> x: f32;
+@compute @workgroup_size(1) fn main() { x = 1.0; }`,
+			want: "unknown address space 'bogus' for global variable 'x'; treated as function",
+		},
+		{
+			name: "unknown pointer address space",
+			src: `fn f(p: ptr<bogus, f32>) -> f32 { return *p; }
+@compute @workgroup_size(1) fn main() { var x = 1.0; _ = f(&x); }`,
+			want: "unknown address space 'bogus' in pointer type; treated as function",
+		},
+		{
+			name: "untyped override",
+			src: `override o;
+@compute @workgroup_size(1) fn main() { _ = o; }`,
+			want: "override 'o' has neither a type nor an initializer; treated as f32",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tokens, err := parser.NewLexer(tt.src).Tokenize()
+			if err != nil {
+				t.Fatal(err)
+			}
+			ast, err := parser.NewParser(tokens).Parse()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			result, err := LowerWithOptions(context.Background(), ast, tt.src, Options{})
+			if err != nil {
+				t.Fatalf("permissive: %v", err)
+			}
+			if msgs := warningMessages(result.Warnings); len(msgs) != 1 || msgs[0] != tt.want {
+				t.Errorf("permissive warnings %q, want [%q]", msgs, tt.want)
+			} else if result.Warnings[0].Span.Start.Line != 1 {
+				t.Errorf("warning on line %d, want 1", result.Warnings[0].Span.Start.Line)
+			}
+
+			_, err = LowerWithOptions(context.Background(), ast, tt.src, Options{Strict: true})
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("strict: got error %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
+
+// TestStrictAcceptsSpec checks that strict mode accepts the forms the
+// spec defines, including handle variables declared without an address
+// space.
+func TestStrictAcceptsSpec(t *testing.T) {
+	src := `@group(0) @binding(0) var t: texture_2d<f32>;
+@group(0) @binding(1) var s: sampler;
+var<private> p: f32;
+override scale: f32 = 1.0;
+
+fn read(v: ptr<private, f32>) -> f32 { return *v; }
+
+@fragment
+fn main(@builtin(position) pos: vec4<f32>) -> @location(0) vec4<f32> {
+    return textureSample(t, s, pos.xy) * scale * read(&p);
+}`
+	tokens, err := parser.NewLexer(src).Tokenize()
+	if err != nil {
+		t.Fatal(err)
+	}
+	ast, err := parser.NewParser(tokens).Parse()
+	if err != nil {
+		t.Fatal(err)
+	}
+	result, err := LowerWithOptions(context.Background(), ast, src, Options{Strict: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Warnings) != 0 {
+		t.Errorf("unexpected warnings %q", warningMessages(result.Warnings))
+	}
+}
@@ -0,0 +1,205 @@
+package lower
+
+import (
+	"testing"
+
+	"github.com/gogpu/naga/ir"
+	"github.com/gogpu/naga/wgsl/internal/parser"
+)
+
+func lowerWarnings(t *testing.T, src string) []Warning {
+	t.Helper()
+	tokens, err := parser.NewLexer(src).Tokenize()
+	if err != nil {
+		t.Fatal(err)
+	}
+	ast, err := parser.NewParser(tokens).Parse()
+	if err != nil {
+		t.Fatal(err)
+	}
+	result, err := LowerWithWarnings(ast, src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return result.Warnings
+}
+
+func warningMessages(warnings []Warning) []string {
+	msgs := make([]string, len(warnings))
+	for i, w := range warnings {
+		msgs[i] = w.Message
+	}
+	return msgs
+}
+
+func TestWarnUnusedParameter(t *testing.T) {
+	warnings := lowerWarnings(t, `
+fn helper(a: f32, b: f32, _c: f32) -> f32 {
+    return a;
+}
+
+@fragment
+fn main(@location(0) unused_input: f32) -> @location(0) vec4<f32> {
+    return vec4<f32>(helper(1.0, 2.0, 3.0));
+}
+`)
+	if len(warnings) != 1 {
+		t.Fatalf("got warnings %q, want one", warningMessages(warnings))
+	}
+	if got, want := warnings[0].Message, "unused parameter 'b' in function 'helper'"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+	if warnings[0].Span.Start.Line != 2 || warnings[0].Span.Start.Column != 19 {
+		t.Errorf("span: got %d:%d, want 2:19", warnings[0].Span.Start.Line, warnings[0].Span.Start.Column)
+	}
+}
+
+func TestWarnUnusedPrivateGlobal(t *testing.T) {
+	warnings := lowerWarnings(t, `
+var<private> used: f32;
+var<private> unused: f32;
+var<private> _ignored: f32;
+var<workgroup> shared_data: array<u32, 4>;
+
+@compute @workgroup_size(1)
+fn main() {
+    used = 1.0;
+}
+`)
+	msgs := warningMessages(warnings)
+	if len(msgs) != 1 || msgs[0] != "unused private variable 'unused'" {
+		t.Errorf("got %q, want only the unused private variable", msgs)
+	}
+}
+
+func TestWarnUnusedFunction(t *testing.T) {
+	src := `
+fn used() -> f32 { return 1.0; }
+fn unused() -> f32 { return 2.0; }
+fn _ignored() -> f32 { return 3.0; }
+
+@compute @workgroup_size(1)
+fn main() {
+    _ = used();
+}
+`
+	warnings := lowerWarnings(t, src)
+	msgs := warningMessages(warnings)
+	if len(msgs) != 1 || msgs[0] != "unused function 'unused'" {
+		t.Fatalf("got %q, want only the unused function", msgs)
+	}
+	if warnings[0].Span.Start.Line != 3 || warnings[0].Span.Start.Column != 1 {
+		t.Errorf("span: got %d:%d, want 3:1", warnings[0].Span.Start.Line, warnings[0].Span.Start.Column)
+	}
+
+	// Without entry points the functions are a library's interface.
+	if msgs := warningMessages(lowerWarnings(t, "fn helper() -> f32 { return 1.0; }\n")); len(msgs) != 0 {
+		t.Errorf("library module: got %q, want no warnings", msgs)
+	}
+}
+
+func TestWarnUnreachableCode(t *testing.T) {
+	warnings := lowerWarnings(t, `
+@fragment
+fn main(@location(0) v: f32) -> @location(0) vec4<f32> {
+    if v > 0.5 {
+        discard;
+        var a = 1.0;
+        a += 1.0;
+    }
+    return vec4<f32>(v);
+    var b = 2.0;
+    b += 1.0;
+}
+`)
+	var got []string
+	for _, w := range warnings {
+		if w.Message == "unreachable code after discard" || w.Message == "unreachable code after return" {
+			got = append(got, w.Message)
+		}
+	}
+	want := []string{"unreachable code after discard", "unreachable code after return"}
+	if len(got) != len(want) || got[0] != want[0] || got[1] != want[1] {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestWarnUnusedVariablesInDeclarationOrder(t *testing.T) {
+	warnings := lowerWarnings(t, `
+fn f() {
+    var c: f32;
+    var a: f32;
+    var b: f32;
+}
+`)
+	want := []string{
+		"unused variable 'c' in function 'f'",
+		"unused variable 'a' in function 'f'",
+		"unused variable 'b' in function 'f'",
+	}
+	got := warningMessages(warnings)
+	if len(got) != len(want) {
+		t.Fatalf("got %q, want %q", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("warning %d: got %q, want %q", i, got[i], want[i])
+		}
+	}
+}
+
+func TestWarnDiscardedExpressionStatement(t *testing.T) {
+	src := `
+var<private> g: f32;
+fn bump() -> f32 { g += 1.0; return g; }
+
+@fragment
+fn main(@location(0) v: f32) -> @location(0) vec4<f32> {
+    v + 1.0;
+    sin(v) * g;
+    bump() + 1.0;
+    return vec4<f32>(g);
+}
+`
+	warnings := lowerWarnings(t, src)
+	want := []string{
+		"expression statement has no effect",
+		"expression statement has no effect",
+		"value of expression statement is discarded",
+	}
+	got := warningMessages(warnings)
+	if len(got) != len(want) {
+		t.Fatalf("got %q, want %q", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("warning %d: got %q, want %q", i, got[i], want[i])
+		}
+	}
+
+	module := mustCompile(t, src)
+	fn := module.EntryPoints[0].Function
+	calls := 0
+	for _, st := range fn.Body {
+		if _, ok := st.Kind.(ir.StmtCall); ok {
+			calls++
+		}
+	}
+	if calls != 1 {
+		t.Errorf("got %d calls, want the bump() call kept", calls)
+	}
+	for _, e := range fn.Expressions {
+		if m, ok := e.Kind.(ir.ExprMath); ok && m.Fun == ir.MathSin {
+			t.Error("pure expression statement sin(v) * g was not discarded")
+		}
+	}
+}
+
+func TestDiscardedExpressionStillChecked(t *testing.T) {
+	expectError(t, `
+@compute @workgroup_size(1)
+fn main() {
+    undefined_name + 1;
+}
+`, "unresolved identifier: undefined_name")
+}
@@ -1,8 +1,11 @@
 package parser
 
+import "fmt"
+
 // Module represents a WGSL module (translation unit).
 type Module struct {
 	Enables     []Enable
+	Requires    []Requires
 	Diagnostics []Diagnostic
 	Structs     []*StructDecl
 	Functions   []*FunctionDecl
@@ -24,7 +27,32 @@ type Enable struct {
 	Span       Span
 }
 
-// Diagnostic represents a diagnostic directive.
+// Requires represents a requires directive: the language features the
+// shader depends on, in source order.
+type Requires struct {
+	Features []string
+	Span     Span
+}
+
+// LanguageFeatures maps every WGSL language feature a requires directive
+// may name to whether this compiler implements it. Matches Rust naga's
+// implemented/unimplemented language extension split.
+var LanguageFeatures = map[string]bool{
+	"readonly_and_readwrite_storage_textures": true,
+	"packed_4x8_integer_dot_product":          true,
+	"pointer_composite_access":                true,
+	"unrestricted_pointer_parameters":         false,
+	"uniform_buffer_standard_layout":          false,
+	"subgroup_id":                             false,
+	"subgroup_uniformity":                     false,
+	"texture_and_sampler_let":                 false,
+	"texture_formats_tier1":                   false,
+}
+
+// Diagnostic represents a diagnostic directive or @diagnostic attribute:
+// a severity ("off", "info", "warning", "error") and a triggering rule,
+// either a single name ("derivative_uniformity") or a dotted pair
+// ("vendor.rule").
 type Diagnostic struct {
 	Severity string
 	Rule     string
@@ -214,8 +242,9 @@ func (p *PtrType) typeNode() {}
 
 // BlockStmt represents a block statement.
 type BlockStmt struct {
-	Statements []Stmt
-	Span       Span
+	Statements  []Stmt
+	Diagnostics []Diagnostic // @diagnostic attributes on the statement
+	Span        Span
 }
 
 func (b *BlockStmt) Pos() Span { return b.Span }
@@ -232,10 +261,11 @@ func (r *ReturnStmt) stmtNode() {}
 
 // IfStmt represents an if statement.
 type IfStmt struct {
-	Condition Expr
-	Body      *BlockStmt
-	Else      Stmt // *BlockStmt or *IfStmt
-	Span      Span
+	Condition   Expr
+	Body        *BlockStmt
+	Else        Stmt         // *BlockStmt or *IfStmt
+	Diagnostics []Diagnostic // @diagnostic attributes on the statement
+	Span        Span
 }
 
 func (i *IfStmt) Pos() Span { return i.Span }
@@ -243,11 +273,12 @@ func (i *IfStmt) stmtNode() {}
 
 // ForStmt represents a for loop.
 type ForStmt struct {
-	Init      Stmt
-	Condition Expr
-	Update    Stmt
-	Body      *BlockStmt
-	Span      Span
+	Init        Stmt
+	Condition   Expr
+	Update      Stmt
+	Body        *BlockStmt
+	Diagnostics []Diagnostic // @diagnostic attributes on the statement
+	Span        Span
 }
 
 func (f *ForStmt) Pos() Span { return f.Span }
@@ -255,9 +286,10 @@ func (f *ForStmt) stmtNode() {}
 
 // WhileStmt represents a while loop.
 type WhileStmt struct {
-	Condition Expr
-	Body      *BlockStmt
-	Span      Span
+	Condition   Expr
+	Body        *BlockStmt
+	Diagnostics []Diagnostic // @diagnostic attributes on the statement
+	Span        Span
 }
 
 func (w *WhileStmt) Pos() Span { return w.Span }
@@ -265,9 +297,10 @@ func (w *WhileStmt) stmtNode() {}
 
 // LoopStmt represents a loop statement.
 type LoopStmt struct {
-	Body       *BlockStmt
-	Continuing *BlockStmt
-	Span       Span
+	Body        *BlockStmt
+	Continuing  *BlockStmt
+	Diagnostics []Diagnostic // @diagnostic attributes on the statement
+	Span        Span
 }
 
 func (l *LoopStmt) Pos() Span { return l.Span }
@@ -329,9 +362,10 @@ func (e *ExprStmt) stmtNode() {}
 
 // SwitchStmt represents a switch statement.
 type SwitchStmt struct {
-	Selector Expr
-	Cases    []*SwitchCaseClause
-	Span     Span
+	Selector    Expr
+	Cases       []*SwitchCaseClause
+	Diagnostics []Diagnostic // @diagnostic attributes on the statement
+	Span        Span
 }
 
 func (s *SwitchStmt) Pos() Span { return s.Span }
@@ -437,3 +471,56 @@ type BitcastExpr struct {
 
 func (b *BitcastExpr) Pos() Span { return b.Span }
 func (b *BitcastExpr) exprNode() {}
+
+// Diagnostic severities accepted by diagnostic directives and attributes.
+const (
+	SeverityOff     = "off"
+	SeverityInfo    = "info"
+	SeverityWarning = "warning"
+	SeverityError   = "error"
+)
+
+// Diagnostic converts a @diagnostic(severity, rule) attribute.
+func (a Attribute) Diagnostic() (Diagnostic, error) {
+	if a.Name != "diagnostic" {
+		return Diagnostic{}, fmt.Errorf("@%s is not a diagnostic attribute", a.Name)
+	}
+	d, err := diagnosticFromArgs(a.Args)
+	if err != nil {
+		return Diagnostic{}, err
+	}
+	d.Span = a.Span
+	return d, nil
+}
+
+// diagnosticFromArgs validates the (severity, rule) arguments shared by
+// the diagnostic directive and attribute.
+func diagnosticFromArgs(args []Expr) (Diagnostic, error) {
+	if len(args) != 2 {
+		return Diagnostic{}, fmt.Errorf("diagnostic control takes a severity and a rule name, got %d arguments", len(args))
+	}
+
+	sev, ok := args[0].(*Ident)
+	if !ok {
+		return Diagnostic{}, fmt.Errorf("expected diagnostic severity")
+	}
+	switch sev.Name {
+	case SeverityOff, SeverityInfo, SeverityWarning, SeverityError:
+	default:
+		return Diagnostic{}, fmt.Errorf("unknown diagnostic severity %q (want off, info, warning, or error)", sev.Name)
+	}
+
+	var rule string
+	switch r := args[1].(type) {
+	case *Ident:
+		rule = r.Name
+	case *MemberExpr:
+		if base, ok := r.Expr.(*Ident); ok {
+			rule = base.Name + "." + r.Member
+		}
+	}
+	if rule == "" {
+		return Diagnostic{}, fmt.Errorf("expected diagnostic rule name")
+	}
+	return Diagnostic{Severity: sev.Name, Rule: rule}, nil
+}
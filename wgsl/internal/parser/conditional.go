@@ -0,0 +1,79 @@
+package parser
+
+import "fmt"
+
+// conditional removes the @if attributes from attrs and reports whether
+// the declaration, member, or statement they annotate is kept: every
+// condition must hold for the parser's features. A feature the parser
+// was not given is false. Excluded code is still parsed, so it must be
+// well-formed WGSL.
+func (p *Parser) conditional(attrs []Attribute) ([]Attribute, bool, *ParseError) {
+	n := 0
+	for _, attr := range attrs {
+		if attr.Name == "if" {
+			n++
+		}
+	}
+	if n == 0 {
+		return attrs, true, nil
+	}
+
+	kept := make([]Attribute, 0, len(attrs)-n)
+	enabled := true
+	for _, attr := range attrs {
+		if attr.Name != "if" {
+			kept = append(kept, attr)
+			continue
+		}
+		if len(attr.Args) != 1 {
+			return nil, false, &ParseError{
+				Message: fmt.Sprintf("@if takes one condition, got %d arguments", len(attr.Args)),
+				Token:   Token{Line: attr.Span.Start.Line, Column: attr.Span.Start.Column},
+			}
+		}
+		ok, err := p.featureCondition(attr.Args[0])
+		if err != nil {
+			return nil, false, err
+		}
+		enabled = enabled && ok
+	}
+	return kept, enabled, nil
+}
+
+// featureCondition evaluates an @if condition. Conditions are built from
+// feature names, true, false, parentheses, !, &&, and ||.
+func (p *Parser) featureCondition(e Expr) (bool, *ParseError) {
+	switch e := e.(type) {
+	case *Ident:
+		return p.features[e.Name], nil
+	case *Literal:
+		if e.Kind == TokenBoolLiteral {
+			return e.Value == "true", nil
+		}
+	case *UnaryExpr:
+		if e.Op == TokenBang {
+			v, err := p.featureCondition(e.Operand)
+			return !v, err
+		}
+	case *BinaryExpr:
+		if e.Op == TokenAmpAmp || e.Op == TokenPipePipe {
+			left, err := p.featureCondition(e.Left)
+			if err != nil {
+				return false, err
+			}
+			right, err := p.featureCondition(e.Right)
+			if err != nil {
+				return false, err
+			}
+			if e.Op == TokenAmpAmp {
+				return left && right, nil
+			}
+			return left || right, nil
+		}
+	}
+	start := e.Pos().Start
+	return false, &ParseError{
+		Message: "@if condition must use only feature names, true, false, !, &&, and ||",
+		Token:   Token{Line: start.Line, Column: start.Column, Offset: start.Offset},
+	}
+}
@@ -0,0 +1,124 @@
+package parser
+
+import (
+	"strings"
+	"testing"
+)
+
+func parseWithFeatures(t *testing.T, source string, features map[string]bool) (*Module, error) {
+	t.Helper()
+	tokens, err := NewLexer(source).Tokenize()
+	if err != nil {
+		t.Fatalf("Lexer error: %v", err)
+	}
+	return NewParserWithFeatures(tokens, features).Parse()
+}
+
+const conditionalSource = `
+@if(SHADOWS)
+fn shadow() -> f32 { return 0.5; }
+
+@if(!SHADOWS)
+fn shadow() -> f32 { return 1.0; }
+
+struct Light {
+    color: vec3<f32>,
+    @if(SHADOWS && !LOW) cascades: u32,
+}
+
+@if(FOG || (SHADOWS && LOW)) const FOG_DENSITY = 0.1;
+
+@fragment
+fn main() -> @location(0) vec4<f32> {
+    var light = shadow();
+    @if(FOG) light = light * 0.5;
+    @if(true) {
+        light = light + 0.1;
+    }
+    @if(false) @diagnostic(off, derivative_uniformity) if light > 1.0 {
+        light = 1.0;
+    }
+    return vec4<f32>(light);
+}
+`
+
+func TestParseConditionalAttributes(t *testing.T) {
+	tests := []struct {
+		name       string
+		features   map[string]bool
+		shadowRet  string
+		members    int
+		fogConst   bool
+		statements int
+	}{
+		{"none", nil, "1.0", 1, false, 3},
+		{"shadows", map[string]bool{"SHADOWS": true}, "0.5", 2, false, 3},
+		{"shadows low", map[string]bool{"SHADOWS": true, "LOW": true}, "0.5", 1, true, 3},
+		{"fog", map[string]bool{"FOG": true, "SHADOWS": false}, "1.0", 1, true, 4},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			module, err := parseWithFeatures(t, conditionalSource, tt.features)
+			if err != nil {
+				t.Fatalf("Parse error: %v", err)
+			}
+
+			var shadows []*FunctionDecl
+			var main *FunctionDecl
+			for _, fn := range module.Functions {
+				switch fn.Name {
+				case "shadow":
+					shadows = append(shadows, fn)
+				case "main":
+					main = fn
+				}
+			}
+			if len(shadows) != 1 {
+				t.Fatalf("got %d shadow functions, want 1", len(shadows))
+			}
+			ret := shadows[0].Body.Statements[0].(*ReturnStmt)
+			if got := ret.Value.(*Literal).Value; got != tt.shadowRet {
+				t.Errorf("shadow returns %s, want %s", got, tt.shadowRet)
+			}
+
+			if got := len(module.Structs[0].Members); got != tt.members {
+				t.Errorf("Light has %d members, want %d", got, tt.members)
+			}
+			if got := len(module.Constants) == 1; got != tt.fogConst {
+				t.Errorf("FOG_DENSITY declared = %v, want %v", got, tt.fogConst)
+			}
+			if got := len(main.Body.Statements); got != tt.statements {
+				t.Errorf("main has %d statements, want %d", got, tt.statements)
+			}
+			for _, fn := range module.Functions {
+				for _, attr := range fn.Attributes {
+					if attr.Name == "if" {
+						t.Errorf("function %s keeps its @if attribute", fn.Name)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestParseConditionalErrors(t *testing.T) {
+	tests := []struct {
+		source string
+		want   string
+	}{
+		{"@if(A, B) fn f() {}", "@if takes one condition, got 2 arguments"},
+		{"@if(A == B) fn f() {}", "@if condition must use only feature names"},
+		{"@if(1) const X = 1;", "@if condition must use only feature names"},
+		{"@if(false) fn f() { let x = ; }", "unexpected token ; in expression"},
+	}
+	for _, tt := range tests {
+		_, err := parseWithFeatures(t, tt.source, nil)
+		if err == nil {
+			t.Errorf("%q: expected error containing %q, got success", tt.source, tt.want)
+			continue
+		}
+		if !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%q: error = %q, want containing %q", tt.source, err, tt.want)
+		}
+	}
+}
@@ -1,6 +1,7 @@
 package parser
 
 import (
+	"reflect"
 	"strings"
 	"testing"
 )
@@ -378,6 +379,32 @@ func TestDependencyOrderCycle(t *testing.T) {
 	}
 }
 
+func TestDependencyOrderCycles(t *testing.T) {
+	decls := []Decl{
+		&ConstDecl{Name: "A", Init: &Ident{Name: "B"}},
+		&ConstDecl{Name: "B", Init: &BinaryExpr{Left: &Ident{Name: "C"}, Right: &Ident{Name: "A"}}},
+		&ConstDecl{Name: "C", Init: &Ident{Name: "D"}},
+		&ConstDecl{Name: "D", Init: &Literal{Kind: TokenIntLiteral, Value: "1"}},
+		&StructDecl{Name: "S", Members: []*StructMember{{Name: "s", Type: &NamedType{Name: "S"}}}},
+	}
+	ordered, cycles := DependencyOrderCycles(decls)
+	if len(ordered) != len(decls) {
+		t.Fatalf("expected %d decls, got %d", len(decls), len(ordered))
+	}
+	var got []string
+	for _, cycle := range cycles {
+		var names []string
+		for _, d := range cycle {
+			names = append(names, DeclName(d))
+		}
+		got = append(got, strings.Join(names, " "))
+	}
+	want := []string{"S", "A B"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("cycles = %q, want %q", got, want)
+	}
+}
+
 func TestDependencyOrderEmpty(t *testing.T) {
 	ordered := DependencyOrder(nil)
 	if len(ordered) != 0 {
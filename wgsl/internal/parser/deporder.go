@@ -8,26 +8,43 @@ package parser
 // When there are no dependencies between declarations, they appear
 // in source order (the outer DFS loop iterates in original order).
 func DependencyOrder(decls []Decl) []Decl {
+	ordered, _ := DependencyOrderCycles(decls)
+	return ordered
+}
+
+// DependencyOrderCycles is DependencyOrder that also returns the cycles
+// among the declarations, which WGSL forbids. Each cycle lists the
+// declarations along it, each referencing the next and the last
+// referencing the first; a declaration referencing itself is a cycle of
+// one. Declarations on a cycle are still ordered, with the reference that
+// closes the cycle ignored.
+func DependencyOrderCycles(decls []Decl) ([]Decl, [][]Decl) {
 	n := len(decls)
 	if n == 0 {
-		return decls
+		return decls, nil
 	}
 
 	// Build name → index map for global declarations.
 	nameToIdx := make(map[string]int, n)
 	for i, d := range decls {
-		name := declName(d)
+		name := DeclName(d)
 		if name != "" {
 			nameToIdx[name] = i
 		}
 	}
 
 	// Collect dependencies for each declaration.
+	var cycles [][]Decl
 	deps := make([][]int, n)
 	for i, d := range decls {
 		refs := collectDeclDependencies(d)
 		for _, ref := range refs {
-			if j, ok := nameToIdx[ref]; ok && j != i {
+			j, ok := nameToIdx[ref]
+			switch {
+			case !ok:
+			case j == i:
+				cycles = append(cycles, []Decl{d})
+			default:
 				deps[i] = append(deps[i], j)
 			}
 		}
@@ -37,6 +54,7 @@ func DependencyOrder(decls []Decl) []Decl {
 	// Matches Rust naga's DependencySolver::dfs.
 	visited := make([]bool, n)
 	onStack := make([]bool, n)
+	path := make([]int, 0, n)
 	result := make([]Decl, 0, n)
 
 	var dfs func(i int)
@@ -45,16 +63,29 @@ func DependencyOrder(decls []Decl) []Decl {
 			return
 		}
 		onStack[i] = true
+		path = append(path, i)
 
 		for _, j := range deps[i] {
 			if onStack[j] {
-				continue // cycle — skip (Rust reports error, we tolerate)
+				// A reference back to a declaration on the path closes a
+				// cycle running from it to i.
+				start := len(path) - 1
+				for path[start] != j {
+					start--
+				}
+				cycle := make([]Decl, 0, len(path)-start)
+				for _, k := range path[start:] {
+					cycle = append(cycle, decls[k])
+				}
+				cycles = append(cycles, cycle)
+				continue
 			}
 			if !visited[j] {
 				dfs(j)
 			}
 		}
 
+		path = path[:len(path)-1]
 		onStack[i] = false
 		visited[i] = true
 		result = append(result, decls[i])
@@ -67,11 +98,11 @@ func DependencyOrder(decls []Decl) []Decl {
 		}
 	}
 
-	return result
+	return result, cycles
 }
 
-// declName returns the name of a declaration, or "" if unnamed.
-func declName(d Decl) string {
+// DeclName returns the name of a declaration, or "" if unnamed.
+func DeclName(d Decl) string {
 	switch d := d.(type) {
 	case *StructDecl:
 		return d.Name
@@ -106,14 +137,18 @@ func collectDeclDependencies(d Decl) []string {
 	case *StructDecl:
 		for _, m := range d.Members {
 			collectTypeRefs(m.Type, add)
+			collectAttributeDeps(m.Attributes, add)
 		}
 	case *FunctionDecl:
+		collectAttributeDeps(d.Attributes, add)
 		for _, p := range d.Params {
 			collectTypeRefs(p.Type, add)
+			collectAttributeDeps(p.Attributes, add)
 		}
 		if d.ReturnType != nil {
 			collectTypeRefs(d.ReturnType, add)
 		}
+		collectAttributeDeps(d.ReturnAttrs, add)
 		// Body: track locals to avoid false dependencies
 		locals := make(map[string]bool)
 		for _, p := range d.Params {
@@ -124,6 +159,7 @@ func collectDeclDependencies(d Decl) []string {
 		}
 	case *VarDecl:
 		collectTypeRefs(d.Type, add)
+		collectAttributeDeps(d.Attributes, add)
 		if d.Init != nil {
 			collectExprDeps(d.Init, nil, add)
 		}
@@ -134,6 +170,7 @@ func collectDeclDependencies(d Decl) []string {
 		}
 	case *OverrideDecl:
 		collectTypeRefs(d.Type, add)
+		collectAttributeDeps(d.Attributes, add)
 		if d.Init != nil {
 			collectExprDeps(d.Init, nil, add)
 		}
@@ -143,6 +180,20 @@ func collectDeclDependencies(d Decl) []string {
 	return refs
 }
 
+// collectAttributeDeps extracts references from attribute arguments that
+// are const-expressions, such as @workgroup_size(WG_X) or @location(BASE).
+// Keyword arguments like @builtin(position) are not references.
+func collectAttributeDeps(attrs []Attribute, add func(string)) {
+	for _, attr := range attrs {
+		switch attr.Name {
+		case "group", "binding", "location", "blend_src", "id", "align", "size", "workgroup_size":
+			for _, arg := range attr.Args {
+				collectExprDeps(arg, nil, add)
+			}
+		}
+	}
+}
+
 // collectTypeRefs extracts type name references from a type AST.
 func collectTypeRefs(t Type, add func(string)) {
 	if t == nil {
@@ -46,9 +46,10 @@ func (l *Lexer) Tokenize() ([]Token, error) {
 		Kind:   TokenEOF,
 		Line:   l.line,
 		Column: l.column,
+		Offset: l.pos,
 	})
 
-	return l.tokens, nil
+	return discoverTemplateLists(l.tokens), nil
 }
 
 func (l *Lexer) scanToken() error {
@@ -336,80 +337,147 @@ func (l *Lexer) identifier() {
 	l.addToken(kind)
 }
 
-var keywords = map[string]TokenKind{
-	"alias":        TokenAlias,
-	"break":        TokenBreak,
-	"case":         TokenCase,
-	"const":        TokenConst,
-	"const_assert": TokenConstAssert,
-	"continue":     TokenContinue,
-	"continuing":   TokenContinuing,
-	"default":      TokenDefault,
-	"diagnostic":   TokenDiagnostic,
-	"discard":      TokenDiscard,
-	"else":         TokenElse,
-	"enable":       TokenEnable,
-	"false":        TokenFalse,
-	"fn":           TokenFn,
-	"for":          TokenFor,
-	"if":           TokenIf,
-	"let":          TokenLet,
-	"loop":         TokenLoop,
-	"override":     TokenOverride,
-	"return":       TokenReturn,
-	"struct":       TokenStruct,
-	"switch":       TokenSwitch,
-	"true":         TokenTrue,
-	"var":          TokenVar,
-	"while":        TokenWhile,
-
-	// Types
-	"bool":                          TokenBool,
-	"f16":                           TokenF16,
-	"f32":                           TokenF32,
-	"f64":                           TokenF64,
-	"i32":                           TokenI32,
-	"i64":                           TokenI64,
-	"u32":                           TokenU32,
-	"u64":                           TokenU64,
-	"vec2":                          TokenVec2,
-	"vec3":                          TokenVec3,
-	"vec4":                          TokenVec4,
-	"mat2x2":                        TokenMat2x2,
-	"mat2x3":                        TokenMat2x3,
-	"mat2x4":                        TokenMat2x4,
-	"mat3x2":                        TokenMat3x2,
-	"mat3x3":                        TokenMat3x3,
-	"mat3x4":                        TokenMat3x4,
-	"mat4x2":                        TokenMat4x2,
-	"mat4x3":                        TokenMat4x3,
-	"mat4x4":                        TokenMat4x4,
-	"array":                         TokenArray,
-	"atomic":                        TokenAtomic,
-	"ptr":                           TokenPtr,
-	"sampler":                       TokenSampler,
-	"sampler_comparison":            TokenSamplerComparison,
-	"texture_1d":                    TokenTexture1d,
-	"texture_2d":                    TokenTexture2d,
-	"texture_2d_array":              TokenTexture2dArray,
-	"texture_3d":                    TokenTexture3d,
-	"texture_cube":                  TokenTextureCube,
-	"texture_cube_array":            TokenTextureCubeArray,
-	"texture_multisampled_2d":       TokenTextureMultisampled2d,
-	"texture_storage_1d":            TokenTextureStorage1d,
-	"texture_storage_2d":            TokenTextureStorage2d,
-	"texture_storage_2d_array":      TokenTextureStorage2dArray,
-	"texture_storage_3d":            TokenTextureStorage3d,
-	"texture_depth_2d":              TokenTextureDepth2d,
-	"texture_depth_2d_array":        TokenTextureDepth2dArray,
-	"texture_depth_cube":            TokenTextureDepthCube,
-	"texture_depth_cube_array":      TokenTextureDepthCubeArray,
-	"texture_depth_multisampled_2d": TokenTextureDepthMultisampled2d,
-}
-
+// lookupKeyword returns the keyword token text spells, or TokenIdent.
+// The compiler turns a switch on strings into a search by length and
+// then content, several times faster than hashing every identifier for a
+// map lookup.
 func (l *Lexer) lookupKeyword(text string) TokenKind {
-	if kind, ok := keywords[text]; ok {
-		return kind
+	switch text {
+	case "alias":
+		return TokenAlias
+	case "break":
+		return TokenBreak
+	case "case":
+		return TokenCase
+	case "const":
+		return TokenConst
+	case "const_assert":
+		return TokenConstAssert
+	case "continue":
+		return TokenContinue
+	case "continuing":
+		return TokenContinuing
+	case "default":
+		return TokenDefault
+	case "diagnostic":
+		return TokenDiagnostic
+	case "discard":
+		return TokenDiscard
+	case "else":
+		return TokenElse
+	case "enable":
+		return TokenEnable
+	case "false":
+		return TokenFalse
+	case "fn":
+		return TokenFn
+	case "for":
+		return TokenFor
+	case "if":
+		return TokenIf
+	case "let":
+		return TokenLet
+	case "loop":
+		return TokenLoop
+	case "override":
+		return TokenOverride
+	case "requires":
+		return TokenRequires
+	case "return":
+		return TokenReturn
+	case "struct":
+		return TokenStruct
+	case "switch":
+		return TokenSwitch
+	case "true":
+		return TokenTrue
+	case "var":
+		return TokenVar
+	case "while":
+		return TokenWhile
+	// Types
+	case "bool":
+		return TokenBool
+	case "f16":
+		return TokenF16
+	case "f32":
+		return TokenF32
+	case "f64":
+		return TokenF64
+	case "i32":
+		return TokenI32
+	case "i64":
+		return TokenI64
+	case "u32":
+		return TokenU32
+	case "u64":
+		return TokenU64
+	case "vec2":
+		return TokenVec2
+	case "vec3":
+		return TokenVec3
+	case "vec4":
+		return TokenVec4
+	case "mat2x2":
+		return TokenMat2x2
+	case "mat2x3":
+		return TokenMat2x3
+	case "mat2x4":
+		return TokenMat2x4
+	case "mat3x2":
+		return TokenMat3x2
+	case "mat3x3":
+		return TokenMat3x3
+	case "mat3x4":
+		return TokenMat3x4
+	case "mat4x2":
+		return TokenMat4x2
+	case "mat4x3":
+		return TokenMat4x3
+	case "mat4x4":
+		return TokenMat4x4
+	case "array":
+		return TokenArray
+	case "atomic":
+		return TokenAtomic
+	case "ptr":
+		return TokenPtr
+	case "sampler":
+		return TokenSampler
+	case "sampler_comparison":
+		return TokenSamplerComparison
+	case "texture_1d":
+		return TokenTexture1d
+	case "texture_2d":
+		return TokenTexture2d
+	case "texture_2d_array":
+		return TokenTexture2dArray
+	case "texture_3d":
+		return TokenTexture3d
+	case "texture_cube":
+		return TokenTextureCube
+	case "texture_cube_array":
+		return TokenTextureCubeArray
+	case "texture_multisampled_2d":
+		return TokenTextureMultisampled2d
+	case "texture_storage_1d":
+		return TokenTextureStorage1d
+	case "texture_storage_2d":
+		return TokenTextureStorage2d
+	case "texture_storage_2d_array":
+		return TokenTextureStorage2dArray
+	case "texture_storage_3d":
+		return TokenTextureStorage3d
+	case "texture_depth_2d":
+		return TokenTextureDepth2d
+	case "texture_depth_2d_array":
+		return TokenTextureDepth2dArray
+	case "texture_depth_cube":
+		return TokenTextureDepthCube
+	case "texture_depth_cube_array":
+		return TokenTextureDepthCubeArray
+	case "texture_depth_multisampled_2d":
+		return TokenTextureDepthMultisampled2d
 	}
 	return TokenIdent
 }
@@ -420,6 +488,7 @@ func (l *Lexer) addToken(kind TokenKind) {
 		Lexeme: l.source[l.start:l.pos],
 		Line:   l.line,
 		Column: l.column - (l.pos - l.start),
+		Offset: l.start,
 	})
 }
 
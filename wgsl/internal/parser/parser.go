@@ -18,6 +18,10 @@ type Parser struct {
 	current     int
 	errors      []ParseError
 	inForHeader bool // true when parsing for-loop init/update (no trailing semicolon)
+
+	// features are the feature names @if conditions may test. A name
+	// not in the map is false.
+	features map[string]bool
 }
 
 // ParseError represents a parsing error.
@@ -38,6 +42,16 @@ func NewParser(tokens []Token) *Parser {
 	}
 }
 
+// NewParserWithFeatures creates a parser that keeps the declarations,
+// struct members, and statements whose @if conditions hold for features
+// and drops the rest.
+func NewParserWithFeatures(tokens []Token, features map[string]bool) *Parser {
+	return &Parser{
+		tokens:   tokens,
+		features: features,
+	}
+}
+
 // Parse parses the tokens and returns a Module AST.
 func (p *Parser) Parse() (*Module, error) {
 	// Estimate declaration counts from token count for pre-allocation.
@@ -60,6 +74,27 @@ func (p *Parser) Parse() (*Module, error) {
 			break
 		}
 
+		if p.check(TokenDiagnostic) {
+			d, err := p.diagnosticDirective()
+			if err != nil {
+				p.errors = append(p.errors, *err)
+				p.synchronize()
+				continue
+			}
+			module.Diagnostics = append(module.Diagnostics, d)
+			continue
+		}
+		if p.check(TokenRequires) {
+			r, err := p.requiresDirective()
+			if err != nil {
+				p.errors = append(p.errors, *err)
+				p.synchronize()
+				continue
+			}
+			module.Requires = append(module.Requires, r)
+			continue
+		}
+
 		decl, err := p.declaration()
 		if err != nil {
 			p.errors = append(p.errors, *err)
@@ -96,8 +131,23 @@ func (p *Parser) Parse() (*Module, error) {
 // declaration parses a top-level declaration.
 func (p *Parser) declaration() (Decl, *ParseError) {
 	// Parse attributes first
-	attrs := p.attributes()
+	attrs, err := p.attributes()
+	if err != nil {
+		return nil, err
+	}
+	attrs, enabled, err := p.conditional(attrs)
+	if err != nil {
+		return nil, err
+	}
+	decl, err := p.declarationBody(attrs)
+	if err != nil || enabled {
+		return decl, err
+	}
+	return nil, nil
+}
 
+// declarationBody parses a top-level declaration after its attributes.
+func (p *Parser) declarationBody(attrs []Attribute) (Decl, *ParseError) {
 	switch {
 	case p.check(TokenFn):
 		return p.functionDecl(attrs)
@@ -123,16 +173,6 @@ func (p *Parser) declaration() (Decl, *ParseError) {
 			p.advance()
 		}
 		return nil, nil
-	case p.check(TokenDiagnostic):
-		// Skip diagnostic directives for now
-		p.advance()
-		for !p.check(TokenSemicolon) && !p.isAtEnd() {
-			p.advance()
-		}
-		if p.check(TokenSemicolon) {
-			p.advance()
-		}
-		return nil, nil
 	case p.check(TokenOverride):
 		return p.overrideDecl(attrs)
 	case p.check(TokenEOF):
@@ -147,7 +187,7 @@ func (p *Parser) declaration() (Decl, *ParseError) {
 }
 
 // attributes parses a list of attributes (@location(0), @vertex, etc.)
-func (p *Parser) attributes() []Attribute {
+func (p *Parser) attributes() ([]Attribute, *ParseError) {
 	var attrs []Attribute
 
 	for p.check(TokenAt) {
@@ -155,9 +195,10 @@ func (p *Parser) attributes() []Attribute {
 		p.advance() // consume @
 
 		// Accept both identifiers and keyword tokens as attribute names.
-		// E.g., @diagnostic(...) — "diagnostic" is a keyword but valid as attr name.
-		if !p.check(TokenIdent) && !p.check(TokenDiagnostic) {
-			continue
+		// E.g., @diagnostic(...) and @if(...) — "diagnostic" and "if" are
+		// keywords but valid as attr names.
+		if !p.check(TokenIdent) && !p.check(TokenDiagnostic) && !p.check(TokenIf) {
+			return nil, &ParseError{Message: "expected attribute name after '@'", Token: p.peek()}
 		}
 
 		name := p.advance()
@@ -168,12 +209,14 @@ func (p *Parser) attributes() []Attribute {
 			},
 		}
 
-		// Check for arguments
+		// Arguments are expressions: @location(BASE + 1u) and
+		// @workgroup_size(SIZE_X, SIZE_Y) are evaluated when lowering.
+		// A trailing comma is allowed.
 		if p.match(TokenLeftParen) {
 			for !p.check(TokenRightParen) && !p.isAtEnd() {
 				arg, err := p.expression()
 				if err != nil {
-					break
+					return nil, err
 				}
 				attr.Args = append(attr.Args, arg)
 
@@ -181,13 +224,15 @@ func (p *Parser) attributes() []Attribute {
 					break
 				}
 			}
-			p.expect(TokenRightParen)
+			if !p.match(TokenRightParen) {
+				return nil, &ParseError{Message: fmt.Sprintf("expected ',' or ')' in @%s arguments", attr.Name), Token: p.peek()}
+			}
 		}
 
 		attrs = append(attrs, attr)
 	}
 
-	return attrs
+	return attrs, nil
 }
 
 // functionDecl parses a function declaration.
@@ -229,7 +274,11 @@ func (p *Parser) functionDecl(attrs []Attribute) (*FunctionDecl, *ParseError) {
 	var returnType Type
 	var returnAttrs []Attribute
 	if p.match(TokenArrow) {
-		returnAttrs = p.attributes()
+		attrs, err := p.attributes()
+		if err != nil {
+			return nil, err
+		}
+		returnAttrs = attrs
 		rt, err := p.typeSpec()
 		if err != nil {
 			return nil, err
@@ -258,7 +307,10 @@ func (p *Parser) functionDecl(attrs []Attribute) (*FunctionDecl, *ParseError) {
 
 // parameter parses a function parameter.
 func (p *Parser) parameter() (*Parameter, *ParseError) {
-	attrs := p.attributes()
+	attrs, err := p.attributes()
+	if err != nil {
+		return nil, err
+	}
 
 	if !p.check(TokenIdent) {
 		return nil, &ParseError{Message: errExpectedParameterName, Token: p.peek()}
@@ -306,7 +358,9 @@ func (p *Parser) structDecl(_ []Attribute) (*StructDecl, *ParseError) {
 		if err != nil {
 			return nil, err
 		}
-		members = append(members, member)
+		if member != nil {
+			members = append(members, member)
+		}
 
 		// Optional comma between members
 		p.match(TokenComma)
@@ -325,9 +379,17 @@ func (p *Parser) structDecl(_ []Attribute) (*StructDecl, *ParseError) {
 	}, nil
 }
 
-// structMember parses a struct member.
+// structMember parses a struct member. It returns nil for a member an
+// @if attribute excludes.
 func (p *Parser) structMember() (*StructMember, *ParseError) {
-	attrs := p.attributes()
+	attrs, err := p.attributes()
+	if err != nil {
+		return nil, err
+	}
+	attrs, enabled, err := p.conditional(attrs)
+	if err != nil {
+		return nil, err
+	}
 
 	if !p.check(TokenIdent) {
 		return nil, &ParseError{Message: errExpectedMemberName, Token: p.peek()}
@@ -342,6 +404,9 @@ func (p *Parser) structMember() (*StructMember, *ParseError) {
 	if err != nil {
 		return nil, err
 	}
+	if !enabled {
+		return nil, nil
+	}
 
 	return &StructMember{
 		Name:       name.Lexeme,
@@ -363,7 +428,11 @@ func (p *Parser) varDecl(attrs []Attribute) (*VarDecl, *ParseError) {
 	// Optional address space and access mode: var<storage, read_write>
 	var addressSpace string
 	var accessMode string
-	if p.match(TokenLess) {
+	hasTemplate, err := p.matchTemplateArgsStart()
+	if err != nil {
+		return nil, err
+	}
+	if hasTemplate {
 		if p.check(TokenIdent) {
 			addressSpace = p.advance().Lexeme
 		}
@@ -373,7 +442,9 @@ func (p *Parser) varDecl(attrs []Attribute) (*VarDecl, *ParseError) {
 				accessMode = p.advance().Lexeme
 			}
 		}
-		p.expect(TokenGreater)
+		if err := p.expectErr(TokenTemplateArgsEnd); err != nil {
+			return nil, err
+		}
 	}
 
 	if !p.check(TokenIdent) {
@@ -631,9 +702,11 @@ func (p *Parser) typeSpec() (Type, *ParseError) {
 	// Array type: array<f32, 4> or array (without template args for inferred type)
 	if p.check(TokenArray) {
 		p.advance() // consume 'array'
-		if p.check(TokenLess) {
-			p.advance() // consume '<'
-
+		hasTemplate, err := p.matchTemplateArgsStart()
+		if err != nil {
+			return nil, err
+		}
+		if hasTemplate {
 			elemType, err := p.typeSpec()
 			if err != nil {
 				return nil, err
@@ -642,11 +715,8 @@ func (p *Parser) typeSpec() (Type, *ParseError) {
 			var size Expr
 			if p.match(TokenComma) {
 				// Trailing comma without size: array<u32,>
-				if !p.check(TokenGreater) {
-					// Parse a template argument expression for size.
-					// This uses templateArgExpr which handles shift/arithmetic but
-					// stops before > or >= to avoid consuming the template closing >.
-					size, err = p.templateArgExpr()
+				if !p.check(TokenTemplateArgsEnd) {
+					size, err = p.expression()
 					if err != nil {
 						return nil, err
 					}
@@ -655,7 +725,7 @@ func (p *Parser) typeSpec() (Type, *ParseError) {
 				}
 			}
 
-			if err := p.expectTemplateClose(); err != nil {
+			if err := p.expectErr(TokenTemplateArgsEnd); err != nil {
 				return nil, err
 			}
 
@@ -679,7 +749,7 @@ func (p *Parser) typeSpec() (Type, *ParseError) {
 	// Binding array type: binding_array<texture_2d<f32>> or binding_array<texture_2d<f32>, 5>
 	if p.check(TokenIdent) && tok.Lexeme == "binding_array" {
 		p.advance() // consume 'binding_array'
-		if err := p.expectErr(TokenLess); err != nil {
+		if err := p.expectTemplateArgsStart(); err != nil {
 			return nil, err
 		}
 
@@ -690,13 +760,13 @@ func (p *Parser) typeSpec() (Type, *ParseError) {
 
 		var size Expr
 		if p.match(TokenComma) {
-			size, err = p.primary()
+			size, err = p.expression()
 			if err != nil {
 				return nil, err
 			}
 		}
 
-		if err := p.expectErr(TokenGreater); err != nil {
+		if err := p.expectErr(TokenTemplateArgsEnd); err != nil {
 			return nil, err
 		}
 
@@ -711,7 +781,7 @@ func (p *Parser) typeSpec() (Type, *ParseError) {
 
 	// Pointer type: ptr<function, f32>
 	if p.match(TokenPtr) {
-		if err := p.expectErr(TokenLess); err != nil {
+		if err := p.expectTemplateArgsStart(); err != nil {
 			return nil, err
 		}
 
@@ -736,7 +806,7 @@ func (p *Parser) typeSpec() (Type, *ParseError) {
 			}
 		}
 
-		if err := p.expectErr(TokenGreater); err != nil {
+		if err := p.expectErr(TokenTemplateArgsEnd); err != nil {
 			return nil, err
 		}
 
@@ -751,7 +821,7 @@ func (p *Parser) typeSpec() (Type, *ParseError) {
 	}
 
 	// Check for type keywords or identifiers (named types)
-	if p.isTypeKeyword(tok.Kind) || p.check(TokenIdent) {
+	if isTypeKeyword(tok.Kind) || p.check(TokenIdent) {
 		name := p.advance()
 		namedType := &NamedType{
 			Name: name.Lexeme,
@@ -761,8 +831,12 @@ func (p *Parser) typeSpec() (Type, *ParseError) {
 		}
 
 		// Check for generic parameters: vec3<f32>
-		if p.match(TokenLess) {
-			for !p.check(TokenGreater) && !p.isAtEnd() {
+		hasTemplate, err := p.matchTemplateArgsStart()
+		if err != nil {
+			return nil, err
+		}
+		if hasTemplate {
+			for !p.check(TokenTemplateArgsEnd) && !p.isAtEnd() {
 				paramType, err := p.typeSpec()
 				if err != nil {
 					return nil, err
@@ -773,7 +847,9 @@ func (p *Parser) typeSpec() (Type, *ParseError) {
 					break
 				}
 			}
-			p.expect(TokenGreater)
+			if err := p.expectErr(TokenTemplateArgsEnd); err != nil {
+				return nil, err
+			}
 		}
 
 		return namedType, nil
@@ -814,6 +890,10 @@ func (p *Parser) block() (*BlockStmt, *ParseError) {
 
 // statement parses a statement.
 func (p *Parser) statement() (Stmt, *ParseError) {
+	if p.check(TokenAt) {
+		return p.attributedStatement()
+	}
+
 	switch {
 	case p.check(TokenReturn):
 		return p.returnStmt()
@@ -848,6 +928,163 @@ func (p *Parser) statement() (Stmt, *ParseError) {
 	}
 }
 
+// attributedStatement parses a statement preceded by attributes. @if may
+// precede any statement; it returns nil when the condition does not hold.
+// WGSL allows @diagnostic only on compound statements, if, switch, loop,
+// while, and for.
+func (p *Parser) attributedStatement() (Stmt, *ParseError) {
+	at := p.peek()
+	attrs, err := p.attributes()
+	if err != nil {
+		return nil, err
+	}
+	attrs, enabled, err := p.conditional(attrs)
+	if err != nil {
+		return nil, err
+	}
+	var s Stmt
+	if len(attrs) == 0 {
+		s, err = p.statement()
+	} else {
+		s, err = p.diagnosticStatement(at, attrs)
+	}
+	if err != nil || !enabled {
+		return nil, err
+	}
+	return s, nil
+}
+
+// diagnosticStatement parses the statement after its @diagnostic
+// attributes.
+func (p *Parser) diagnosticStatement(at Token, attrs []Attribute) (Stmt, *ParseError) {
+	diagnostics := make([]Diagnostic, 0, len(attrs))
+	for _, attr := range attrs {
+		if attr.Name != "diagnostic" {
+			return nil, &ParseError{Message: fmt.Sprintf("attribute @%s is not valid on a statement", attr.Name), Token: at}
+		}
+		d, err := attr.Diagnostic()
+		if err != nil {
+			return nil, &ParseError{Message: err.Error(), Token: at}
+		}
+		diagnostics = append(diagnostics, d)
+	}
+
+	switch {
+	case p.check(TokenIf):
+		s, err := p.ifStmt()
+		if err != nil {
+			return nil, err
+		}
+		s.Diagnostics = diagnostics
+		return s, nil
+	case p.check(TokenSwitch):
+		s, err := p.switchStmt()
+		if err != nil {
+			return nil, err
+		}
+		s.Diagnostics = diagnostics
+		return s, nil
+	case p.check(TokenLoop):
+		s, err := p.loopStmt()
+		if err != nil {
+			return nil, err
+		}
+		s.Diagnostics = diagnostics
+		return s, nil
+	case p.check(TokenWhile):
+		s, err := p.whileStmt()
+		if err != nil {
+			return nil, err
+		}
+		s.Diagnostics = diagnostics
+		return s, nil
+	case p.check(TokenFor):
+		s, err := p.forStmt()
+		if err != nil {
+			return nil, err
+		}
+		s.Diagnostics = diagnostics
+		return s, nil
+	case p.check(TokenLeftBrace):
+		s, err := p.block()
+		if err != nil {
+			return nil, err
+		}
+		s.Diagnostics = diagnostics
+		return s, nil
+	default:
+		return nil, &ParseError{
+			Message: "@diagnostic is only valid on compound, if, switch, loop, while, and for statements",
+			Token:   p.peek(),
+		}
+	}
+}
+
+// diagnosticDirective parses a module-scope `diagnostic(severity, rule);`.
+func (p *Parser) diagnosticDirective() (Diagnostic, *ParseError) {
+	start := p.advance() // consume 'diagnostic'
+
+	if err := p.expectErr(TokenLeftParen); err != nil {
+		return Diagnostic{}, err
+	}
+	var args []Expr
+	for !p.check(TokenRightParen) && !p.isAtEnd() {
+		arg, err := p.expression()
+		if err != nil {
+			return Diagnostic{}, err
+		}
+		args = append(args, arg)
+		if !p.match(TokenComma) {
+			break
+		}
+	}
+	if err := p.expectErr(TokenRightParen); err != nil {
+		return Diagnostic{}, err
+	}
+	if err := p.expectErr(TokenSemicolon); err != nil {
+		return Diagnostic{}, err
+	}
+
+	d, err := diagnosticFromArgs(args)
+	if err != nil {
+		return Diagnostic{}, &ParseError{Message: err.Error(), Token: start}
+	}
+	d.Span = Span{Start: Position{Line: start.Line, Column: start.Column}}
+	return d, nil
+}
+
+// requiresDirective parses a module-scope `requires feature, ...;`.
+// Unknown features and features this compiler does not implement are
+// errors.
+func (p *Parser) requiresDirective() (Requires, *ParseError) {
+	start := p.advance() // consume 'requires'
+
+	r := Requires{Span: Span{Start: Position{Line: start.Line, Column: start.Column}}}
+	for {
+		tok := p.peek()
+		if !p.check(TokenIdent) {
+			return Requires{}, &ParseError{Message: "expected language feature name", Token: tok}
+		}
+		p.advance()
+		implemented, known := LanguageFeatures[tok.Lexeme]
+		switch {
+		case !known:
+			return Requires{}, &ParseError{Message: fmt.Sprintf("unknown language feature '%s'", tok.Lexeme), Token: tok}
+		case !implemented:
+			return Requires{}, &ParseError{Message: fmt.Sprintf("language feature '%s' is not yet implemented", tok.Lexeme), Token: tok}
+		}
+		r.Features = append(r.Features, tok.Lexeme)
+		// A trailing comma before the semicolon is allowed.
+		if !p.match(TokenComma) || p.check(TokenSemicolon) {
+			break
+		}
+	}
+	if err := p.expectErr(TokenSemicolon); err != nil {
+		return Requires{}, err
+	}
+	return r, nil
+}
+
 // returnStmt parses a return statement.
 func (p *Parser) returnStmt() (*ReturnStmt, *ParseError) {
 	start := p.advance() // consume 'return'
@@ -1329,39 +1566,9 @@ func (p *Parser) expression() (Expr, *ParseError) {
 	return p.logicalOr()
 }
 
-// templateArgExpr parses an expression inside a template argument list.
-// It handles shift/arithmetic operators but stops at > and >= tokens
-// to avoid consuming the template-closing >.
-func (p *Parser) templateArgExpr() (Expr, *ParseError) {
-	return p.templateShift()
-}
-
-// templateShift parses << expressions inside template args (>> would be template close).
-func (p *Parser) templateShift() (Expr, *ParseError) {
-	left, err := p.additive()
-	if err != nil {
-		return nil, err
-	}
-
-	// Only allow left-shift (<<) inside template args, not right-shift (>>)
-	for p.check(TokenLessLess) {
-		op := p.advance()
-		right, err := p.additive()
-		if err != nil {
-			return nil, err
-		}
-		left = &BinaryExpr{
-			Left:  left,
-			Op:    op.Kind,
-			Right: right,
-		}
-	}
-
-	return left, nil
-}
-
 // logicalOr parses || expressions.
 func (p *Parser) logicalOr() (Expr, *ParseError) {
+	start := p.peek()
 	left, err := p.logicalAnd()
 	if err != nil {
 		return nil, err
@@ -1377,6 +1584,7 @@ func (p *Parser) logicalOr() (Expr, *ParseError) {
 			Op:    TokenPipePipe,
 			Right: right,
 		}
+		p.finishExpr(left, start)
 	}
 
 	return left, nil
@@ -1384,6 +1592,7 @@ func (p *Parser) logicalOr() (Expr, *ParseError) {
 
 // logicalAnd parses && expressions.
 func (p *Parser) logicalAnd() (Expr, *ParseError) {
+	start := p.peek()
 	left, err := p.bitwiseOr()
 	if err != nil {
 		return nil, err
@@ -1399,6 +1608,7 @@ func (p *Parser) logicalAnd() (Expr, *ParseError) {
 			Op:    TokenAmpAmp,
 			Right: right,
 		}
+		p.finishExpr(left, start)
 	}
 
 	return left, nil
@@ -1406,6 +1616,7 @@ func (p *Parser) logicalAnd() (Expr, *ParseError) {
 
 // bitwiseOr parses | expressions.
 func (p *Parser) bitwiseOr() (Expr, *ParseError) {
+	start := p.peek()
 	left, err := p.bitwiseXor()
 	if err != nil {
 		return nil, err
@@ -1421,6 +1632,7 @@ func (p *Parser) bitwiseOr() (Expr, *ParseError) {
 			Op:    TokenPipe,
 			Right: right,
 		}
+		p.finishExpr(left, start)
 	}
 
 	return left, nil
@@ -1428,6 +1640,7 @@ func (p *Parser) bitwiseOr() (Expr, *ParseError) {
 
 // bitwiseXor parses ^ expressions.
 func (p *Parser) bitwiseXor() (Expr, *ParseError) {
+	start := p.peek()
 	left, err := p.bitwiseAnd()
 	if err != nil {
 		return nil, err
@@ -1443,6 +1656,7 @@ func (p *Parser) bitwiseXor() (Expr, *ParseError) {
 			Op:    TokenCaret,
 			Right: right,
 		}
+		p.finishExpr(left, start)
 	}
 
 	return left, nil
@@ -1450,6 +1664,7 @@ func (p *Parser) bitwiseXor() (Expr, *ParseError) {
 
 // bitwiseAnd parses & expressions.
 func (p *Parser) bitwiseAnd() (Expr, *ParseError) {
+	start := p.peek()
 	left, err := p.equality()
 	if err != nil {
 		return nil, err
@@ -1465,6 +1680,7 @@ func (p *Parser) bitwiseAnd() (Expr, *ParseError) {
 			Op:    TokenAmpersand,
 			Right: right,
 		}
+		p.finishExpr(left, start)
 	}
 
 	return left, nil
@@ -1472,6 +1688,7 @@ func (p *Parser) bitwiseAnd() (Expr, *ParseError) {
 
 // equality parses == and != expressions.
 func (p *Parser) equality() (Expr, *ParseError) {
+	start := p.peek()
 	left, err := p.comparison()
 	if err != nil {
 		return nil, err
@@ -1488,6 +1705,7 @@ func (p *Parser) equality() (Expr, *ParseError) {
 			Op:    op.Kind,
 			Right: right,
 		}
+		p.finishExpr(left, start)
 	}
 
 	return left, nil
@@ -1495,6 +1713,7 @@ func (p *Parser) equality() (Expr, *ParseError) {
 
 // comparison parses <, >, <=, >= expressions.
 func (p *Parser) comparison() (Expr, *ParseError) {
+	start := p.peek()
 	left, err := p.shift()
 	if err != nil {
 		return nil, err
@@ -1512,6 +1731,7 @@ func (p *Parser) comparison() (Expr, *ParseError) {
 			Op:    op.Kind,
 			Right: right,
 		}
+		p.finishExpr(left, start)
 	}
 
 	return left, nil
@@ -1519,6 +1739,7 @@ func (p *Parser) comparison() (Expr, *ParseError) {
 
 // shift parses << and >> expressions.
 func (p *Parser) shift() (Expr, *ParseError) {
+	start := p.peek()
 	left, err := p.additive()
 	if err != nil {
 		return nil, err
@@ -1535,6 +1756,7 @@ func (p *Parser) shift() (Expr, *ParseError) {
 			Op:    op.Kind,
 			Right: right,
 		}
+		p.finishExpr(left, start)
 	}
 
 	return left, nil
@@ -1542,6 +1764,7 @@ func (p *Parser) shift() (Expr, *ParseError) {
 
 // additive parses + and - expressions.
 func (p *Parser) additive() (Expr, *ParseError) {
+	start := p.peek()
 	left, err := p.multiplicative()
 	if err != nil {
 		return nil, err
@@ -1558,6 +1781,7 @@ func (p *Parser) additive() (Expr, *ParseError) {
 			Op:    op.Kind,
 			Right: right,
 		}
+		p.finishExpr(left, start)
 	}
 
 	return left, nil
@@ -1565,6 +1789,7 @@ func (p *Parser) additive() (Expr, *ParseError) {
 
 // multiplicative parses *, /, % expressions.
 func (p *Parser) multiplicative() (Expr, *ParseError) {
+	start := p.peek()
 	left, err := p.unary()
 	if err != nil {
 		return nil, err
@@ -1581,6 +1806,7 @@ func (p *Parser) multiplicative() (Expr, *ParseError) {
 			Op:    op.Kind,
 			Right: right,
 		}
+		p.finishExpr(left, start)
 	}
 
 	return left, nil
@@ -1595,13 +1821,15 @@ func (p *Parser) unary() (Expr, *ParseError) {
 		if err != nil {
 			return nil, err
 		}
-		return &UnaryExpr{
+		expr := &UnaryExpr{
 			Op:      op.Kind,
 			Operand: operand,
 			Span: Span{
 				Start: Position{Line: op.Line, Column: op.Column},
 			},
-		}, nil
+		}
+		p.finishExpr(expr, op)
+		return expr, nil
 	}
 
 	return p.postfix()
@@ -1609,12 +1837,13 @@ func (p *Parser) unary() (Expr, *ParseError) {
 
 // postfix parses postfix expressions (calls, indexing, member access).
 func (p *Parser) postfix() (Expr, *ParseError) {
+	start := p.peek()
 	expr, err := p.primary()
 	if err != nil {
 		return nil, err
 	}
 
-	for {
+	for ; ; p.finishExpr(expr, start) {
 		if p.match(TokenLeftParen) {
 			// Function call
 			args := make([]Expr, 0, 4)
@@ -1672,6 +1901,16 @@ func (p *Parser) postfix() (Expr, *ParseError) {
 
 // primary parses primary expressions.
 func (p *Parser) primary() (Expr, *ParseError) {
+	start := p.peek()
+	expr, err := p.primaryExpr()
+	if err == nil {
+		p.finishExpr(expr, start)
+	}
+	return expr, err
+}
+
+// primaryExpr parses a primary expression for primary.
+func (p *Parser) primaryExpr() (Expr, *ParseError) {
 	tok := p.peek()
 
 	switch tok.Kind {
@@ -1699,14 +1938,14 @@ func (p *Parser) primary() (Expr, *ParseError) {
 		// Handle bitcast<Type>(expr) — special syntax
 		if tok.Lexeme == "bitcast" {
 			p.advance() // consume 'bitcast'
-			if err := p.expectErr(TokenLess); err != nil {
+			if err := p.expectTemplateArgsStart(); err != nil {
 				return nil, err
 			}
 			targetType, err := p.typeSpec()
 			if err != nil {
 				return nil, err
 			}
-			if err := p.expectErr(TokenGreater); err != nil {
+			if err := p.expectErr(TokenTemplateArgsEnd); err != nil {
 				return nil, err
 			}
 			if err := p.expectErr(TokenLeftParen); err != nil {
@@ -1727,6 +1966,20 @@ func (p *Parser) primary() (Expr, *ParseError) {
 				},
 			}, nil
 		}
+		// A template-elaborated identifier such as binding_array<T, 4>
+		// or a<b, c>(d) is a type; resolving it is up to the lowerer.
+		if p.peekNext().Kind == TokenTemplateArgsStart {
+			typeExpr, err := p.typeSpec()
+			if err != nil {
+				return nil, err
+			}
+			return &ConstructExpr{
+				Type: typeExpr,
+				Span: Span{
+					Start: Position{Line: tok.Line, Column: tok.Column},
+				},
+			}, nil
+		}
 		p.advance()
 		return &Ident{
 			Name: tok.Lexeme,
@@ -1748,7 +2001,7 @@ func (p *Parser) primary() (Expr, *ParseError) {
 
 	default:
 		// Check for type constructors: vec3<f32>(1.0, 2.0, 3.0)
-		if p.isTypeKeyword(tok.Kind) {
+		if isTypeKeyword(tok.Kind) {
 			typeExpr, err := p.typeSpec()
 			if err != nil {
 				return nil, err
@@ -1770,6 +2023,46 @@ func (p *Parser) primary() (Expr, *ParseError) {
 
 // Helper methods
 
+// finishExpr extends the span of e from start through the last consumed
+// token, so the span covers the expression's source text. A start line
+// and column the production already recorded are kept.
+func (p *Parser) finishExpr(e Expr, start Token) {
+	var span *Span
+	switch e := e.(type) {
+	case *Ident:
+		span = &e.Span
+	case *Literal:
+		span = &e.Span
+	case *BinaryExpr:
+		span = &e.Span
+	case *UnaryExpr:
+		span = &e.Span
+	case *CallExpr:
+		span = &e.Span
+	case *IndexExpr:
+		span = &e.Span
+	case *MemberExpr:
+		span = &e.Span
+	case *ConstructExpr:
+		span = &e.Span
+	case *BitcastExpr:
+		span = &e.Span
+	default:
+		return
+	}
+	if span.End.Offset != 0 && span.Start.Offset != start.Offset {
+		return // finished by an inner production, as inside parentheses
+	}
+	if span.Start.Line == 0 {
+		span.Start.Line, span.Start.Column = start.Line, start.Column
+	}
+	span.Start.Offset = start.Offset
+	if p.current > 0 {
+		last := p.previous()
+		span.End = Position{Line: last.Line, Column: last.Column + len(last.Lexeme), Offset: last.Offset + len(last.Lexeme)}
+	}
+}
+
 func (p *Parser) advance() Token {
 	if !p.isAtEnd() {
 		p.current++
@@ -1781,6 +2074,14 @@ func (p *Parser) peek() Token {
 	return p.tokens[p.current]
 }
 
+// peekNext returns the token after the current one.
+func (p *Parser) peekNext() Token {
+	if p.isAtEnd() {
+		return p.peek()
+	}
+	return p.tokens[p.current+1]
+}
+
 func (p *Parser) previous() Token {
 	return p.tokens[p.current-1]
 }
@@ -1816,11 +2117,6 @@ func (p *Parser) match(kind TokenKind) bool {
 func (p *Parser) expect(kind TokenKind) {
 	if p.check(kind) {
 		p.advance()
-		return
-	}
-	// Handle >> splitting: when expecting >, accept >> and split it
-	if kind == TokenGreater && p.check(TokenGreaterGreater) {
-		p.splitGreaterGreater()
 	}
 }
 
@@ -1829,82 +2125,35 @@ func (p *Parser) expectErr(kind TokenKind) *ParseError {
 		p.advance()
 		return nil
 	}
-	// Handle >> splitting: when expecting >, accept >> and split it
-	if kind == TokenGreater && p.check(TokenGreaterGreater) {
-		p.splitGreaterGreater()
-		return nil
-	}
 	return &ParseError{
 		Message: fmt.Sprintf("expected %s, got %s", kind, p.peek().Kind),
 		Token:   p.peek(),
 	}
 }
 
-// expectTemplateClose expects a > token to close a template argument list.
-// Handles >= disambiguation: splits >= into > and = tokens per WGSL spec.
-// Also handles >> splitting like expectErr(TokenGreater).
-func (p *Parser) expectTemplateClose() *ParseError {
-	if p.check(TokenGreater) {
-		p.advance()
-		return nil
-	}
-	if p.check(TokenGreaterGreater) {
-		p.splitGreaterGreater()
-		return nil
-	}
-	if p.check(TokenGreaterEqual) {
-		p.splitGreaterEqual()
-		return nil
-	}
-	if p.check(TokenGreaterGreaterEqual) {
-		p.splitGreaterGreaterEqual()
-		return nil
-	}
-	return &ParseError{
-		Message: fmt.Sprintf("expected >, got %s", p.peek().Kind),
-		Token:   p.peek(),
-	}
-}
-
-// splitGreaterGreater splits a >> token into two > tokens, consuming the first.
-// This handles the WGSL angle bracket ambiguity in nested template args (e.g., vec3<f32>>).
-func (p *Parser) splitGreaterGreater() {
-	tok := p.tokens[p.current]
-	// Replace >> with a single > at position+1
-	p.tokens[p.current] = Token{
-		Kind:   TokenGreater,
-		Lexeme: ">",
-		Line:   tok.Line,
-		Column: tok.Column + 1,
+// matchTemplateArgsStart consumes the < opening a template list. A < that
+// template list discovery left as a comparison has no matching >, which is
+// reported here instead of as an unexpected token further on.
+func (p *Parser) matchTemplateArgsStart() (bool, *ParseError) {
+	if p.match(TokenTemplateArgsStart) {
+		return true, nil
 	}
-	// Don't advance — the remaining > stays for the outer template close
-}
-
-// splitGreaterEqual splits a >= token into > and = tokens, consuming the >.
-// This handles the WGSL template disambiguation: array<i32, 1 << 1>=...
-func (p *Parser) splitGreaterEqual() {
-	tok := p.tokens[p.current]
-	// Replace >= with = at position+1
-	p.tokens[p.current] = Token{
-		Kind:   TokenEqual,
-		Lexeme: "=",
-		Line:   tok.Line,
-		Column: tok.Column + 1,
+	if p.check(TokenLess) {
+		return false, &ParseError{Message: "expected > to close template list", Token: p.peek()}
 	}
-	// Don't advance — the = stays for the next parse
+	return false, nil
 }
 
-// splitGreaterGreaterEqual splits a >>= token into > and >= tokens, consuming the >.
-func (p *Parser) splitGreaterGreaterEqual() {
-	tok := p.tokens[p.current]
-	// Replace >>= with >= at position+1
-	p.tokens[p.current] = Token{
-		Kind:   TokenGreaterEqual,
-		Lexeme: ">=",
-		Line:   tok.Line,
-		Column: tok.Column + 1,
+// expectTemplateArgsStart requires a template list to follow.
+func (p *Parser) expectTemplateArgsStart() *ParseError {
+	ok, err := p.matchTemplateArgsStart()
+	if err == nil && !ok {
+		err = &ParseError{
+			Message: fmt.Sprintf("expected <, got %s", p.peek().Kind),
+			Token:   p.peek(),
+		}
 	}
-	// Don't advance — the >= stays for the next parse
+	return err
 }
 
 func (p *Parser) synchronize() {
@@ -1921,7 +2170,8 @@ func (p *Parser) synchronize() {
 	}
 }
 
-func (p *Parser) isTypeKeyword(kind TokenKind) bool {
+// isTypeKeyword reports whether kind is a predeclared type keyword.
+func isTypeKeyword(kind TokenKind) bool {
 	switch kind {
 	case TokenBool, TokenF16, TokenF32, TokenF64, TokenI32, TokenI64, TokenU32, TokenU64,
 		TokenVec2, TokenVec3, TokenVec4,
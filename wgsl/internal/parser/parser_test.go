@@ -745,3 +745,136 @@ fn main() {}`
 		t.Fatalf("expected 1 function, got %d", len(module.Functions))
 	}
 }
+
+func TestParseDiagnosticDirectiveStored(t *testing.T) {
+	module := parseSource(t, `diagnostic(off, derivative_uniformity);
+diagnostic(info, vendor.rule);
+fn main() {}`)
+	want := []Diagnostic{
+		{Severity: SeverityOff, Rule: "derivative_uniformity"},
+		{Severity: SeverityInfo, Rule: "vendor.rule"},
+	}
+	if len(module.Diagnostics) != len(want) {
+		t.Fatalf("directives: got %+v, want %+v", module.Diagnostics, want)
+	}
+	for i, d := range module.Diagnostics {
+		if d.Severity != want[i].Severity || d.Rule != want[i].Rule {
+			t.Errorf("directive %d: got %s/%s, want %s/%s", i, d.Severity, d.Rule, want[i].Severity, want[i].Rule)
+		}
+	}
+}
+
+func TestParseDiagnosticStatementAttribute(t *testing.T) {
+	module := parseSource(t, `fn main() {
+    @diagnostic(off, derivative_uniformity) if true {}
+    @diagnostic(error, derivative_uniformity) @diagnostic(off, subgroup_uniformity) {}
+    @diagnostic(warning, derivative_uniformity) loop { break; }
+}`)
+	stmts := module.Functions[0].Body.Statements
+	if len(stmts) != 3 {
+		t.Fatalf("statements: got %d, want 3", len(stmts))
+	}
+	ifStmt, ok := stmts[0].(*IfStmt)
+	if !ok || len(ifStmt.Diagnostics) != 1 || ifStmt.Diagnostics[0].Severity != SeverityOff {
+		t.Errorf("if statement: got %#v", stmts[0])
+	}
+	block, ok := stmts[1].(*BlockStmt)
+	if !ok || len(block.Diagnostics) != 2 || block.Diagnostics[1].Rule != "subgroup_uniformity" {
+		t.Errorf("block statement: got %#v", stmts[1])
+	}
+	loop, ok := stmts[2].(*LoopStmt)
+	if !ok || len(loop.Diagnostics) != 1 || loop.Diagnostics[0].Severity != SeverityWarning {
+		t.Errorf("loop statement: got %#v", stmts[2])
+	}
+}
+
+func TestParseDiagnosticErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		source string
+	}{
+		{"bad_severity", "diagnostic(loud, derivative_uniformity);"},
+		{"missing_rule", "diagnostic(off);"},
+		{"missing_semicolon", "diagnostic(off, derivative_uniformity)"},
+		{"bad_statement_severity", "fn main() { @diagnostic(loud, derivative_uniformity) {} }"},
+		{"unsupported_statement", "fn main() { @diagnostic(off, derivative_uniformity) let x = 1; }"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := tryParseSource(t, tt.source); err == nil {
+				t.Error("expected parse error")
+			}
+		})
+	}
+}
+
+func TestParseAttributeArguments(t *testing.T) {
+	module := parseSource(t, `@compute @workgroup_size(SIZE_X, SIZE_Y * 2,)
+fn main(@builtin(local_invocation_index) i: u32) {}`)
+	args := module.Functions[0].Attributes[1].Args
+	if len(args) != 2 {
+		t.Fatalf("got %d @workgroup_size arguments, want 2", len(args))
+	}
+	if _, ok := args[1].(*BinaryExpr); !ok {
+		t.Errorf("second argument is %T, want *BinaryExpr", args[1])
+	}
+
+	for _, source := range []string{
+		"@vertex fn vs(@location(1 +) p: vec4f) {}",
+		"@vertex fn vs(@location(1 2) p: vec4f) {}",
+		"@vertex fn vs(@location(1 p: vec4f) {}",
+		"@ fn f() {}",
+	} {
+		if _, err := tryParseSource(t, source); err == nil {
+			t.Errorf("%q: expected parse error", source)
+		}
+	}
+}
+
+func TestParseRequiresDirective(t *testing.T) {
+	module := parseSource(t, `requires readonly_and_readwrite_storage_textures;
+requires packed_4x8_integer_dot_product, pointer_composite_access,;
+fn main() {}`)
+	if len(module.Requires) != 2 {
+		t.Fatalf("directives: got %d, want 2", len(module.Requires))
+	}
+	if got := module.Requires[1].Features; len(got) != 2 || got[1] != "pointer_composite_access" {
+		t.Errorf("features: got %v", got)
+	}
+	if module.Requires[1].Span.Start.Line != 2 {
+		t.Errorf("span: got line %d, want 2", module.Requires[1].Span.Start.Line)
+	}
+	if len(module.Functions) != 1 {
+		t.Errorf("functions: got %d, want 1", len(module.Functions))
+	}
+}
+
+func TestParseExpressionSpans(t *testing.T) {
+	const source = "const x = (a + f(b))[2].y * -vec2<f32>(c).x;"
+	module := parseSource(t, source)
+	init := module.Constants[0].Init
+	text := func(e Expr) string {
+		s := e.Pos()
+		return source[s.Start.Offset:s.End.Offset]
+	}
+	mul, ok := init.(*BinaryExpr)
+	if !ok {
+		t.Fatalf("init is %T, want *BinaryExpr", init)
+	}
+	want := map[string]Expr{
+		"(a + f(b))[2].y * -vec2<f32>(c).x": mul,
+		"(a + f(b))[2].y":                   mul.Left,
+		"(a + f(b))[2]":                     mul.Left.(*MemberExpr).Expr,
+		"a + f(b)":                          mul.Left.(*MemberExpr).Expr.(*IndexExpr).Expr,
+		"-vec2<f32>(c).x":                   mul.Right,
+		"vec2<f32>(c)":                      mul.Right.(*UnaryExpr).Operand.(*MemberExpr).Expr,
+	}
+	for w, e := range want {
+		if got := text(e); got != w {
+			t.Errorf("span text = %q, want %q", got, w)
+		}
+	}
+	if got := mul.Span.Start; got.Line != 1 || got.Column != 11 {
+		t.Errorf("start = %d:%d, want 1:11", got.Line, got.Column)
+	}
+}
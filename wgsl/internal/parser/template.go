@@ -0,0 +1,110 @@
+package parser
+
+import "slices"
+
+// Template list discovery.
+//
+// WGSL uses < and > both as comparison operators and as template list
+// delimiters (vec3<f32>, array<vec2<f32>, 3>). The spec resolves the
+// ambiguity before parsing with the template list discovery algorithm
+// (WGSL §3.9): a < directly after an identifier starts a candidate list,
+// and the candidate becomes a template list when a > is found at the same
+// bracket nesting depth before anything that cannot appear inside a
+// template argument (an assignment, ';', '{', ':', or a '&&'/'||' at the
+// same depth).
+//
+// discoverTemplateLists applies the algorithm to the token stream, turning
+// matched delimiters into TokenTemplateArgsStart and TokenTemplateArgsEnd.
+// A >>, >= or >>= whose first character closes a list is split, so
+// array<vec2<f32>> and var<private> x: vec2<f32>= ... parse as written. All
+// remaining < and > tokens are comparisons.
+
+// templateCandidate is a < token that may start a template list.
+type templateCandidate struct {
+	index int // token index of the <
+	depth int // bracket nesting depth at the <
+}
+
+// discoverTemplateLists marks template list delimiters in tokens and
+// returns the updated slice, which may be longer when tokens were split.
+func discoverTemplateLists(tokens []Token) []Token {
+	var pending []templateCandidate
+	depth := 0
+
+	// popWhile discards candidates that can no longer be closed.
+	popWhile := func(cond func(c templateCandidate) bool) {
+		for len(pending) > 0 && cond(pending[len(pending)-1]) {
+			pending = pending[:len(pending)-1]
+		}
+	}
+
+	for i := 0; i < len(tokens); i++ {
+		switch tokens[i].Kind {
+		case TokenLess:
+			if i > 0 && isTemplateName(tokens[i-1].Kind) {
+				pending = append(pending, templateCandidate{index: i, depth: depth})
+			}
+
+		case TokenGreater, TokenGreaterEqual, TokenGreaterGreater, TokenGreaterGreaterEqual:
+			if n := len(pending); n > 0 && pending[n-1].depth == depth {
+				tokens[pending[n-1].index].Kind = TokenTemplateArgsStart
+				pending = pending[:n-1]
+				tokens = splitTemplateArgsEnd(tokens, i)
+			} else if tokens[i].Kind == TokenGreaterGreaterEqual {
+				// Compound assignment: no template list spans it.
+				depth = 0
+				pending = pending[:0]
+			}
+
+		case TokenLeftParen, TokenLeftBracket:
+			depth++
+
+		case TokenRightParen, TokenRightBracket:
+			popWhile(func(c templateCandidate) bool { return c.depth >= depth })
+			depth = max(0, depth-1)
+
+		case TokenEqual, TokenPlusEqual, TokenMinusEqual, TokenStarEqual,
+			TokenSlashEqual, TokenPercentEqual, TokenAmpEqual, TokenPipeEqual,
+			TokenCaretEqual, TokenLessLessEqual,
+			TokenSemicolon, TokenLeftBrace, TokenColon:
+			depth = 0
+			pending = pending[:0]
+
+		case TokenAmpAmp, TokenPipePipe:
+			// A short-circuit operator ends the expression a candidate
+			// at this depth could have been a template argument of.
+			popWhile(func(c templateCandidate) bool { return c.depth == depth })
+		}
+	}
+	return tokens
+}
+
+// splitTemplateArgsEnd turns the > that starts tokens[i] into a
+// TokenTemplateArgsEnd and inserts the rest of the operator, if any, as the
+// next token so it is examined on its own.
+func splitTemplateArgsEnd(tokens []Token, i int) []Token {
+	tok := tokens[i]
+	tokens[i] = Token{Kind: TokenTemplateArgsEnd, Lexeme: ">", Line: tok.Line, Column: tok.Column, Offset: tok.Offset}
+
+	var rest Token
+	switch tok.Kind {
+	case TokenGreaterEqual:
+		rest = Token{Kind: TokenEqual, Lexeme: "="}
+	case TokenGreaterGreater:
+		rest = Token{Kind: TokenGreater, Lexeme: ">"}
+	case TokenGreaterGreaterEqual:
+		rest = Token{Kind: TokenGreaterEqual, Lexeme: ">="}
+	default:
+		return tokens
+	}
+	rest.Line = tok.Line
+	rest.Column = tok.Column + 1
+	rest.Offset = tok.Offset + 1
+	return slices.Insert(tokens, i+1, rest)
+}
+
+// isTemplateName reports whether a token of this kind may be followed by a
+// template list: identifiers, 'var', and the predeclared type keywords.
+func isTemplateName(kind TokenKind) bool {
+	return kind == TokenIdent || kind == TokenVar || isTypeKeyword(kind)
+}
@@ -0,0 +1,130 @@
+package parser
+
+import (
+	"strings"
+	"testing"
+)
+
+// templateKinds tokenizes source and returns the kinds of its <, > and
+// template delimiter tokens, in order, as a compact string: "(" and ")" for
+// template list delimiters, "<", ">", "<=", ">=", "<<" and ">>" for operators.
+func templateKinds(t *testing.T, source string) string {
+	t.Helper()
+	tokens, err := NewLexer(source).Tokenize()
+	if err != nil {
+		t.Fatalf("tokenize %q: %v", source, err)
+	}
+	var b strings.Builder
+	for _, tok := range tokens {
+		switch tok.Kind {
+		case TokenTemplateArgsStart:
+			b.WriteString("(")
+		case TokenTemplateArgsEnd:
+			b.WriteString(")")
+		case TokenLess, TokenGreater, TokenLessEqual, TokenGreaterEqual,
+			TokenLessLess, TokenGreaterGreater, TokenGreaterGreaterEqual:
+			b.WriteString(" " + tok.Kind.String() + " ")
+		case TokenEqual:
+			b.WriteString("=")
+		}
+	}
+	return strings.Join(strings.Fields(b.String()), " ")
+}
+
+func TestDiscoverTemplateLists(t *testing.T) {
+	tests := []struct {
+		src  string
+		want string
+	}{
+		{"vec3<f32>(1.0)", "()"},
+		{"array<vec2<f32>, 3>", "(())"},
+		{"array<vec2<f32>>", "(())"},
+		{"a < b", "<"},
+		{"a > b", ">"},
+		{"a < b, c > (d)", "()"},
+		{"a < b || c > d", "< >"},
+		{"a < b && c > d", "< >"},
+		{"(a < b) > c", "< >"},
+		{"a[i < 2] > b", "< >"},
+		{"array<f32, 1 << 2>", "( << )"},
+		{"array<f32, (4 >> 1)>", "( >> )"},
+		{"array<f32, (3 > 2)>", "( > )"},
+		{"var x: vec2<f32>=vec2(1.0);", "()="},
+		{"x >>= 1u;", ">>="},
+		{"x <= y && y >= z", "<= >="},
+		{"for (var i = 0; i < n; i++) {}", "= <"},
+		{"let m = a < b; let n = c > d;", "= < = >"},
+		{"bitcast<u32>(x) < y", "() <"},
+		{"ptr<function, array<i32, 4>>", "(())"},
+		// The spec gotcha: x < y, z > w inside call arguments is a template list.
+		{"vec2<bool>(x < y, z > w)", "()()"},
+	}
+	for _, tt := range tests {
+		if got := templateKinds(t, tt.src); got != tt.want {
+			t.Errorf("%q: got %q, want %q", tt.src, got, tt.want)
+		}
+	}
+}
+
+func TestDiscoverTemplateListsSplitPositions(t *testing.T) {
+	tokens, err := NewLexer("a: vec2<vec2<f32>>=b").Tokenize()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var cols []int
+	for _, tok := range tokens {
+		if tok.Kind == TokenTemplateArgsEnd || tok.Kind == TokenEqual {
+			cols = append(cols, tok.Column)
+		}
+	}
+	if want := []int{17, 18, 19}; len(cols) != 3 || cols[0] != want[0] || cols[1] != want[1] || cols[2] != want[2] {
+		t.Errorf("split token columns = %v, want %v", cols, want)
+	}
+}
+
+func TestParseTemplateDisambiguation(t *testing.T) {
+	sources := []string{
+		`var<private> a: array<vec2<f32>, 3>;`,
+		`var<private> b: array<array<i32, 2>>;`,
+		`var<private> c: array<f32, (4 >> 1)>;`,
+		`var<private> d: array<f32, 1 << 2>;`,
+		`var<private> e: vec2<f32>=vec2(1.0);`,
+		`fn f(x: i32, y: i32, z: i32) -> bool { return x < y && y > z; }`,
+		`fn g(x: i32, y: i32) -> bool { return (x < y) == (y > x); }`,
+		`fn h(x: u32) -> u32 { var v = x; v >>= 1u; return v; }`,
+		`fn k(x: f32) -> vec2<bool> { return vec2<bool>((x < 1.0), (x > 0.0)); }`,
+	}
+	for _, src := range sources {
+		if _, err := tryParseSource(t, src); err != nil {
+			t.Errorf("%s: %v", src, err)
+		}
+	}
+}
+
+func TestParseTemplateElaboratedCall(t *testing.T) {
+	// Per the spec, a < b, c > (d) is a call of the template-elaborated
+	// identifier a<b, c>, not two comparisons.
+	module := parseSource(t, `fn f() { var x = a < b, c > (d); }`)
+	decl := module.Functions[0].Body.Statements[0].(*VarDecl)
+	construct, ok := decl.Init.(*ConstructExpr)
+	if !ok {
+		t.Fatalf("initializer is %T, want *ConstructExpr", decl.Init)
+	}
+	named, ok := construct.Type.(*NamedType)
+	if !ok || named.Name != "a" || len(named.TypeParams) != 2 || len(construct.Args) != 1 {
+		t.Errorf("got %+v with %d args, want a<b, c>(d)", construct.Type, len(construct.Args))
+	}
+}
+
+func TestParseUnclosedTemplateList(t *testing.T) {
+	for _, src := range []string{
+		`fn f(x: array<f32) {}`,
+		`var<private x: f32;`,
+		`fn f(p: ptr<function, f32) {}`,
+	} {
+		_, err := tryParseSource(t, src)
+		if err == nil || !strings.Contains(err.Error(), "expected > to close template list") {
+			t.Errorf("%s: got %v, want unclosed template list error", src, err)
+		}
+	}
+}
@@ -62,6 +62,11 @@ const (
 	TokenLeftBracket  // [
 	TokenRightBracket // ]
 
+	// Template list delimiters, produced from < and > by template list
+	// discovery (see discoverTemplateLists).
+	TokenTemplateArgsStart // < opening a template list, as in vec3<f32>
+	TokenTemplateArgsEnd   // > closing a template list
+
 	// Keywords
 	TokenAlias
 	TokenBreak
@@ -82,6 +87,7 @@ const (
 	TokenLet
 	TokenLoop
 	TokenOverride
+	TokenRequires
 	TokenReturn
 	TokenStruct
 	TokenSwitch
@@ -199,6 +205,10 @@ var tokenNames = map[TokenKind]string{
 	TokenLeftBracket:  "[",
 	TokenRightBracket: "]",
 
+	// Template list delimiters
+	TokenTemplateArgsStart: "<",
+	TokenTemplateArgsEnd:   ">",
+
 	// Keywords
 	TokenAlias:       "alias",
 	TokenBreak:       "break",
@@ -219,6 +229,7 @@ var tokenNames = map[TokenKind]string{
 	TokenLet:         "let",
 	TokenLoop:        "loop",
 	TokenOverride:    "override",
+	TokenRequires:    "requires",
 	TokenReturn:      "return",
 	TokenStruct:      "struct",
 	TokenSwitch:      "switch",
@@ -241,6 +252,7 @@ type Token struct {
 	Lexeme string
 	Line   int
 	Column int
+	Offset int // byte offset of the token in the source
 }
 
 // Span represents a source code location span.
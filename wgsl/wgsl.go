@@ -1,6 +1,11 @@
 package wgsl
 
 import (
+	"context"
+	"errors"
+	"sort"
+	"unicode/utf8"
+
 	"github.com/gogpu/naga/ir"
 	"github.com/gogpu/naga/wgsl/internal/lower"
 	"github.com/gogpu/naga/wgsl/internal/parser"
@@ -48,6 +53,37 @@ type Warning struct {
 	Span    Span
 }
 
+// Error is a parse or lowering error located in the source.
+type Error struct {
+	Message string
+	Span    Span
+}
+
+// Errors returns the located errors an error from Tokenize, Parse, or
+// the Lower functions carries, or nil if it carries none (for example a
+// canceled context). Lowering reports every failing declaration; parsing
+// reports the first syntax error.
+func Errors(err error) []Error {
+	var list *parser.SourceErrors
+	if errors.As(err, &list) {
+		out := make([]Error, len(*list))
+		for i, e := range *list {
+			out[i] = Error{Message: e.Message, Span: spanFromParser(e.Span)}
+		}
+		return out
+	}
+	var single *parser.SourceError
+	if errors.As(err, &single) {
+		return []Error{{Message: single.Message, Span: spanFromParser(single.Span)}}
+	}
+	var pe parser.ParseError
+	if errors.As(err, &pe) {
+		pos := Position{Line: pe.Token.Line, Column: pe.Token.Column}
+		return []Error{{Message: pe.Message, Span: Span{Start: pos, End: pos}}}
+	}
+	return nil
+}
+
 // LowerResult contains the result of lowering, including any warnings.
 type LowerResult struct {
 	Module   *ir.Module
@@ -68,6 +104,46 @@ type Position struct {
 	Offset int
 }
 
+// RequiredFeature is a language feature named by a requires directive.
+type RequiredFeature struct {
+	Name string
+	Span Span
+}
+
+// Requires returns the language features named by the module's requires
+// directives, in source order. Each feature carries the span of its
+// directive.
+func (m *Module) Requires() []RequiredFeature {
+	var features []RequiredFeature
+	for _, r := range m.inner.Requires {
+		for _, name := range r.Features {
+			features = append(features, RequiredFeature{
+				Name: name,
+				Span: Span{Start: Position{
+					Line:   r.Span.Start.Line,
+					Column: r.Span.Start.Column,
+					Offset: r.Span.Start.Offset,
+				}},
+			})
+		}
+	}
+	return features
+}
+
+// ImplementedLanguageFeatures returns, sorted, the WGSL language features
+// a requires directive may name with this compiler. Requiring any other
+// feature is a parse error.
+func ImplementedLanguageFeatures() []string {
+	var names []string
+	for name, implemented := range parser.LanguageFeatures {
+		if implemented {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
 // NewLexer creates a new lexer for the given source.
 func NewLexer(source string) *Lexer {
 	return &Lexer{inner: parser.NewLexer(source)}
@@ -87,6 +163,18 @@ func NewParser(tokens *Tokens) *Parser {
 	return &Parser{inner: parser.NewParser(tokens.inner)}
 }
 
+// NewParserWithFeatures creates a parser for conditional compilation.
+// Declarations, struct members, and statements marked @if(condition) are
+// kept only when the condition holds; a condition combines feature names
+// with true, false, !, &&, and ||, and a name missing from features is
+// false:
+//
+//	@if(SHADOWS && !LOW_QUALITY)
+//	fn shadow(uv: vec2<f32>) -> f32 { ... }
+func NewParserWithFeatures(tokens *Tokens, features map[string]bool) *Parser {
+	return &Parser{inner: parser.NewParserWithFeatures(tokens.inner, features)}
+}
+
 // Parse parses the tokens and returns a Module AST.
 func (p *Parser) Parse() (*Module, error) {
 	m, err := p.inner.Parse()
@@ -114,7 +202,42 @@ func LowerWithSource(ast *Module, source string) (*ir.Module, error) {
 // LowerWithWarnings converts a WGSL AST module to Naga IR,
 // returning warnings alongside the module.
 func LowerWithWarnings(ast *Module, source string) (*LowerResult, error) {
-	lr, err := lower.LowerWithWarnings(ast.inner, source)
+	return LowerWithWarningsContext(context.Background(), ast, source)
+}
+
+// LowerWithWarningsContext is like LowerWithWarnings but returns ctx.Err()
+// once ctx is canceled, so a stale lowering of a large module can be
+// abandoned between declarations.
+func LowerWithWarningsContext(ctx context.Context, ast *Module, source string) (*LowerResult, error) {
+	return LowerWithOptions(ctx, ast, source, LowerOptions{})
+}
+
+// LowerOptions configures LowerWithOptions.
+type LowerOptions struct {
+	// Strict rejects source the WGSL spec does not allow but lowering
+	// otherwise accepts, keeping shaders portable to other WGSL
+	// implementations: unknown builtins, module-scope variables without an
+	// address space, unknown address spaces, and overrides with neither a
+	// type nor an initializer. Without Strict each is lowered to a default
+	// (position, function, f32) and reported as a warning.
+	Strict bool
+
+	// Constants gives the named module constants other values, replacing
+	// their initializers, for building shader variants from one source:
+	// {"SHADOWS": 0} lowers `const SHADOWS: bool = true;` as false. Each
+	// must be a scalar; booleans use 1 and 0. Every const-expression
+	// reading a replaced constant, in function bodies too, is folded with
+	// the new value. Overrides are fixed after lowering with
+	// ir.Specialize.
+	Constants ir.PipelineConstants
+}
+
+// LowerWithOptions is like LowerWithWarningsContext, configured by options.
+func LowerWithOptions(ctx context.Context, ast *Module, source string, options LowerOptions) (*LowerResult, error) {
+	lr, err := lower.LowerWithOptions(ctx, ast.inner, source, lower.Options{
+		Strict:    options.Strict,
+		Constants: options.Constants,
+	})
 	if err != nil {
 		return nil, err
 	}
@@ -124,19 +247,7 @@ func LowerWithWarnings(ast *Module, source string) (*LowerResult, error) {
 	for i, w := range lr.Warnings {
 		warnings[i] = Warning{
 			Message: w.Message,
-			Span: Span{
-				Start: Position{
-					Line:   w.Span.Start.Line,
-					Column: w.Span.Start.Column,
-					Offset: w.Span.Start.Offset,
-				},
-				End: Position{
-					Line:   w.Span.End.Line,
-					Column: w.Span.End.Column,
-					Offset: w.Span.End.Offset,
-				},
-				Source: w.Span.Source,
-			},
+			Span:    spanFromParser(w.Span),
 		}
 	}
 
@@ -145,3 +256,99 @@ func LowerWithWarnings(ast *Module, source string) (*LowerResult, error) {
 		Warnings: warnings,
 	}, nil
 }
+
+// StageIOSpan returns the source span of the declaration carrying an
+// entry point input or output: the struct member, the entry point
+// parameter, or the return type. It reports false if the declaration is
+// not in this module.
+func (m *Module) StageIOSpan(io ir.StageIO) (Span, bool) {
+	if m == nil {
+		return Span{}, false
+	}
+	if io.Struct != "" {
+		for _, st := range m.inner.Structs {
+			if st.Name != io.Struct {
+				continue
+			}
+			for _, member := range st.Members {
+				if member.Name == io.Member {
+					return spanFromParser(member.Span), true
+				}
+			}
+		}
+		return Span{}, false
+	}
+	for _, fn := range m.inner.Functions {
+		if fn.Name != io.EntryPoint {
+			continue
+		}
+		if io.Argument == "" {
+			if fn.ReturnType == nil {
+				return Span{}, false
+			}
+			return spanFromParser(fn.ReturnType.Pos()), true
+		}
+		for _, param := range fn.Params {
+			if param.Name == io.Argument {
+				return spanFromParser(param.Span), true
+			}
+		}
+	}
+	return Span{}, false
+}
+
+// SourceSpan returns the lines and columns of span, an IR span of byte
+// offsets into source, such as the spans of an ir.ValidationError. Columns
+// count runes. It returns the zero Span when span is unset or does not fit
+// in source.
+func SourceSpan(source string, span ir.Span) Span {
+	start, end := int(span.Start), int(span.End)
+	if span.IsZero() || end > len(source) || start > end {
+		return Span{}
+	}
+	return Span{Start: sourcePosition(source, start), End: sourcePosition(source, end)}
+}
+
+// sourcePosition returns the line and rune column of byte offset in
+// source.
+func sourcePosition(source string, offset int) Position {
+	line, lineStart := 1, 0
+	for i := 0; i < offset; i++ {
+		if source[i] == '\n' {
+			line++
+			lineStart = i + 1
+		}
+	}
+	return Position{Line: line, Column: utf8.RuneCountInString(source[lineStart:offset]) + 1, Offset: offset}
+}
+
+// GlobalSpan returns the source span of the module-scope var declaration
+// named name. It reports false if there is none.
+func (m *Module) GlobalSpan(name string) (Span, bool) {
+	if m == nil {
+		return Span{}, false
+	}
+	for _, v := range m.inner.GlobalVars {
+		if v.Name == name {
+			return spanFromParser(v.Span), true
+		}
+	}
+	return Span{}, false
+}
+
+// spanFromParser converts a parser span to the public Span type.
+func spanFromParser(s parser.Span) Span {
+	return Span{
+		Start: Position{
+			Line:   s.Start.Line,
+			Column: s.Start.Column,
+			Offset: s.Start.Offset,
+		},
+		End: Position{
+			Line:   s.End.Line,
+			Column: s.End.Column,
+			Offset: s.End.Offset,
+		},
+		Source: s.Source,
+	}
+}